@@ -17,6 +17,7 @@ package main
 import (
 	"fmt"
 	"net"
+	"path/filepath"
 	"time"
 
 	"k8s.io/client-go/informers"
@@ -25,19 +26,28 @@ import (
 	"github.com/vmware-tanzu/antrea/pkg/agent"
 	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver"
 	"github.com/vmware-tanzu/antrea/pkg/agent/cniserver"
-	_ "github.com/vmware-tanzu/antrea/pkg/agent/cniserver/ipam"
+	"github.com/vmware-tanzu/antrea/pkg/agent/cniserver/ipam"
 	"github.com/vmware-tanzu/antrea/pkg/agent/config"
+	"github.com/vmware-tanzu/antrea/pkg/agent/controller/egress"
+	"github.com/vmware-tanzu/antrea/pkg/agent/controller/ipsec"
 	"github.com/vmware-tanzu/antrea/pkg/agent/controller/networkpolicy"
 	"github.com/vmware-tanzu/antrea/pkg/agent/controller/noderoute"
 	"github.com/vmware-tanzu/antrea/pkg/agent/controller/traceflow"
+	"github.com/vmware-tanzu/antrea/pkg/agent/datapathmonitor"
+	"github.com/vmware-tanzu/antrea/pkg/agent/flowexporter"
 	"github.com/vmware-tanzu/antrea/pkg/agent/flowexporter/connections"
+	"github.com/vmware-tanzu/antrea/pkg/agent/flowexporter/metadata"
 	"github.com/vmware-tanzu/antrea/pkg/agent/interfacestore"
+	"github.com/vmware-tanzu/antrea/pkg/agent/ippool"
 	"github.com/vmware-tanzu/antrea/pkg/agent/metrics"
 	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/agent/ovsresourcemonitor"
 	"github.com/vmware-tanzu/antrea/pkg/agent/proxy"
 	"github.com/vmware-tanzu/antrea/pkg/agent/querier"
 	"github.com/vmware-tanzu/antrea/pkg/agent/route"
+	"github.com/vmware-tanzu/antrea/pkg/agent/wireguard"
 	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/loglevel"
 	crdinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions"
 	"github.com/vmware-tanzu/antrea/pkg/features"
 	"github.com/vmware-tanzu/antrea/pkg/k8s"
@@ -45,7 +55,10 @@ import (
 	"github.com/vmware-tanzu/antrea/pkg/monitor"
 	ofconfig "github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
 	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsconfig"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
+	antreaquerier "github.com/vmware-tanzu/antrea/pkg/querier"
 	"github.com/vmware-tanzu/antrea/pkg/signals"
+	"github.com/vmware-tanzu/antrea/pkg/util/env"
 	"github.com/vmware-tanzu/antrea/pkg/version"
 )
 
@@ -57,6 +70,11 @@ const informerDefaultResync = 12 * time.Hour
 // run starts Antrea agent with the given options and waits for termination signal.
 func run(o *Options) error {
 	klog.Infof("Starting Antrea agent (version %s)", version.GetFullVersion())
+	// The log verbosity provided with the --v flag is restored if logVerbosity is
+	// removed from the configuration.
+	flagLogLevel := loglevel.CurrentLevel()
+	setLogVerbosity(o.config.LogVerbosity, flagLogLevel)
+
 	// Create K8s Clientset, CRD Clientset and SharedInformerFactory for the given config.
 	k8sClient, _, crdClient, err := k8s.CreateClients(o.config.ClientConnection)
 	if err != nil {
@@ -92,10 +110,12 @@ func run(o *Options) error {
 
 	_, serviceCIDRNet, _ := net.ParseCIDR(o.config.ServiceCIDR)
 	_, encapMode := config.GetTrafficEncapModeFromStr(o.config.TrafficEncapMode)
+	_, encryptionMode := config.GetTrafficEncryptionModeFromStr(o.config.TrafficEncryptionMode)
 	networkConfig := &config.NetworkConfig{
-		TunnelType:        ovsconfig.TunnelType(o.config.TunnelType),
-		TrafficEncapMode:  encapMode,
-		EnableIPSecTunnel: o.config.EnableIPSecTunnel}
+		TunnelType:            ovsconfig.TunnelType(o.config.TunnelType),
+		TrafficEncapMode:      encapMode,
+		EnableIPSecTunnel:     o.config.EnableIPSecTunnel || encryptionMode == config.TrafficEncryptionModeIPSec,
+		TrafficEncryptionMode: encryptionMode}
 
 	routeClient, err := route.NewClient(serviceCIDRNet, encapMode)
 	if err != nil {
@@ -117,13 +137,32 @@ func run(o *Options) error {
 		o.config.DefaultMTU,
 		serviceCIDRNet,
 		networkConfig,
-		features.DefaultFeatureGate.Enabled(features.AntreaProxy))
+		features.DefaultFeatureGate.Enabled(features.AntreaProxy),
+		o.config.HWOffloadMode,
+		o.skipPipelineFlush)
 	err = agentInitializer.Initialize()
 	if err != nil {
 		return fmt.Errorf("error initializing agent: %v", err)
 	}
 	nodeConfig := agentInitializer.GetNodeConfig()
 
+	// wireGuardClient must stay a nil interface when the traffic encryption
+	// mode is not WireGuard.
+	var wireGuardClient wireguard.Interface
+	if networkConfig.TrafficEncryptionMode == config.TrafficEncryptionModeWireGuard {
+		nodeConfig.WireGuardConfig = &config.WireGuardConfig{
+			Name: wireguard.DefaultDeviceName,
+			Port: o.config.WireGuardPort,
+		}
+		// The rotation interval has been validated when the options were validated.
+		keyRotationInterval, _ := time.ParseDuration(o.config.WireGuardKeyRotationInterval)
+		client := wireguard.NewClient(k8sClient, nodeConfig, keyRotationInterval)
+		if err := client.Init(); err != nil {
+			return fmt.Errorf("error initializing WireGuard: %v", err)
+		}
+		wireGuardClient = client
+	}
+
 	nodeRouteController := noderoute.NewNodeRouteController(
 		k8sClient,
 		informerFactory,
@@ -132,10 +171,65 @@ func run(o *Options) error {
 		routeClient,
 		ifaceStore,
 		networkConfig,
-		nodeConfig)
+		nodeConfig,
+		wireGuardClient)
+
+	// podUpdates is a channel for receiving Pod updates from CNIServer and
+	// notifying NetworkPolicyController to reconcile rules related to the
+	// updated Pods.
+	podUpdates := make(chan v1beta1.PodReference, 100)
+	ovsCtlClient := ovsctl.NewClient(o.config.OVSBridge)
+	networkPolicyController := networkpolicy.NewNetworkPolicyController(antreaClientProvider, ofClient, ifaceStore, ovsCtlClient, nodeConfig.Name, podUpdates)
+	// NetworkPolicyController logs the packets sent to the controller by the
+	// NetworkPolicy rules which enable logging.
+	ofClient.RegisterPacketInHandler(uint8(openflow.PacketInReasonNP), "networkpolicy", networkPolicyController)
+	var networkPolicyStatsCollector *networkpolicy.StatsCollector
+	// The poll interval has been validated when the options were validated.
+	networkPolicyStatsPollInterval, _ := time.ParseDuration(o.config.NetworkPolicyStatsPollInterval)
+	if networkPolicyStatsPollInterval > 0 {
+		networkPolicyStatsCollector = networkpolicy.NewStatsCollector(networkPolicyController, ovsCtlClient, networkPolicyStatsPollInterval, networkpolicy.StatsCheckpointPath)
+	}
+	isChaining := false
+	if networkConfig.TrafficEncapMode.IsNetworkPolicyOnly() {
+		isChaining = true
+	}
+	var proxier *proxy.Proxier
+	var proxyStatsCollector *proxy.StatsCollector
+	if features.DefaultFeatureGate.Enabled(features.AntreaProxy) {
+		enableEndpointSlice := false
+		if features.DefaultFeatureGate.Enabled(features.EndpointSlice) {
+			enableEndpointSlice, err = proxy.IsEndpointSliceAPIAvailable(k8sClient)
+			if err != nil {
+				return fmt.Errorf("error when checking if EndpointSlice API is available: %v", err)
+			}
+			if !enableEndpointSlice {
+				klog.Warning("EndpointSlice API is not available, Endpoints API will be used by AntreaProxy")
+			}
+		}
+		// The drain period has been validated when the options were validated.
+		endpointDrainPeriod, _ := time.ParseDuration(o.config.EndpointDrainPeriod)
+		proxier = proxy.New(nodeConfig.Name, informerFactory, ofClient, enableEndpointSlice, endpointDrainPeriod)
+		if o.config.FastFailoverGroups {
+			proxier.EnableFastFailoverGroups(ovsBridgeClient, ovsCtlClient)
+		}
+		// The Endpoints are only probed for the Services which request it with
+		// an annotation.
+		proxier.EnableEndpointHealthCheck(nodeConfig.GatewayConfig.IP)
+		// The poll interval has been validated when the options were validated.
+		statsPollInterval, _ := time.ParseDuration(o.config.ProxyStatsPollInterval)
+		if statsPollInterval > 0 {
+			proxyStatsCollector = proxy.NewStatsCollector(proxier, ovsctl.NewClient(o.config.OVSBridge), statsPollInterval)
+		}
+	}
 
 	var traceflowController *traceflow.Controller
 	if features.DefaultFeatureGate.Enabled(features.Traceflow) {
+		// serviceQuerier must stay a nil interface when AntreaProxy is
+		// disabled, as Services cannot be traced then.
+		var serviceQuerier proxy.ServiceQuerier
+		if proxier != nil {
+			serviceQuerier = proxier
+		}
 		traceflowController = traceflow.NewTraceflowController(
 			k8sClient,
 			crdClient,
@@ -144,36 +238,96 @@ func run(o *Options) error {
 			ovsBridgeClient,
 			ifaceStore,
 			networkConfig,
-			nodeConfig)
+			nodeConfig,
+			serviceQuerier)
 	}
-
-	// podUpdates is a channel for receiving Pod updates from CNIServer and
-	// notifying NetworkPolicyController to reconcile rules related to the
-	// updated Pods.
-	podUpdates := make(chan v1beta1.PodReference, 100)
-	networkPolicyController := networkpolicy.NewNetworkPolicyController(antreaClientProvider, ofClient, ifaceStore, nodeConfig.Name, podUpdates)
-	isChaining := false
-	if networkConfig.TrafficEncapMode.IsNetworkPolicyOnly() {
-		isChaining = true
+	// secondaryIPAllocator must stay a nil interface if the secondary networks
+	// are disabled.
+	var secondaryIPAllocator ipam.SecondaryIPAllocator
+	var ipPoolAllocator *ippool.Allocator
+	if features.DefaultFeatureGate.Enabled(features.AntreaIPAM) {
+		// The Pods matching an IPPool get their IPs from the pool instead of
+		// the PodCIDR of the Node, which is still used for the other Pods.
+		ipPoolAllocator = ippool.NewAllocator(
+			nodeConfig.Name,
+			k8sClient,
+			crdClient,
+			crdInformerFactory.Core().V1alpha1().IPPools(),
+			informerFactory.Core().V1().Namespaces(),
+			informerFactory.Core().V1().Nodes(),
+			ippool.StatePath)
+		if err := ipam.RegisterAntreaIPAM(ipPoolAllocator); err != nil {
+			return fmt.Errorf("error registering Antrea IPAM driver: %v", err)
+		}
+		if features.DefaultFeatureGate.Enabled(features.SecondaryNetwork) {
+			secondaryIPAllocator = ipPoolAllocator
+		}
 	}
-	var proxier *proxy.Proxier
-	if features.DefaultFeatureGate.Enabled(features.AntreaProxy) {
-		proxier = proxy.New(nodeConfig.Name, informerFactory, ofClient)
+	var egressController *egress.Controller
+	if features.DefaultFeatureGate.Enabled(features.Egress) {
+		// The egress traffic is SNATed on the Egress Node after being tunneled
+		// to it, which requires the encap mode.
+		if networkConfig.TrafficEncapMode != config.TrafficEncapModeEncap {
+			klog.Warningf("Egress is only supported in %s mode, ignoring the Egresses", config.TrafficEncapModeEncap)
+		} else {
+			if err := ofClient.InstallEgressNATBypassFlows(*serviceCIDRNet); err != nil {
+				return fmt.Errorf("error installing Egress flows: %v", err)
+			}
+			egressController, err = egress.NewEgressController(
+				nodeConfig.Name,
+				ofClient,
+				routeClient,
+				ifaceStore,
+				nodeConfig.NodeIPAddr.IP,
+				crdInformerFactory.Core().V1alpha1().Egresses(),
+				informerFactory.Core().V1().Pods(),
+				informerFactory.Core().V1().Namespaces())
+			if err != nil {
+				return fmt.Errorf("error creating Egress controller: %v", err)
+			}
+		}
+	}
+	// The metadata cache must be created before the informers are started, as
+	// it registers the event handlers of the Pod and Service informers.
+	var flowMetadataCache *metadata.Cache
+	if features.DefaultFeatureGate.Enabled(features.FlowExporter) {
+		flowMetadataCache = metadata.NewCache(
+			informerFactory.Core().V1().Pods(),
+			informerFactory.Core().V1().Services())
 	}
+
+	datapathMonitor := datapathmonitor.NewMonitor(
+		nodeConfig.Name,
+		ovsctl.NewClient(o.config.OVSBridge),
+		k8sClient,
+		o.config.OVSFlowTableWarningThreshold,
+		o.config.OVSFlowTableBlockThreshold)
+
 	cniServer := cniserver.New(
 		o.config.CNISocket,
 		o.config.HostProcPathPrefix,
-		o.config.DefaultMTU,
+		nodeConfig.NodeMTU,
 		nodeConfig,
 		k8sClient,
 		podUpdates,
 		isChaining,
-		routeClient)
+		routeClient,
+		datapathMonitor,
+		secondaryIPAllocator)
 	err = cniServer.Initialize(ovsBridgeClient, ofClient, ifaceStore, o.config.OVSDatapathType)
 	if err != nil {
 		return fmt.Errorf("error initializing CNI server: %v", err)
 	}
 
+	var ipsecTunnelStatusReporter *ipsec.TunnelStatusReporter
+	if networkConfig.EnableIPSecTunnel {
+		ipsecTunnelStatusReporter = ipsec.NewTunnelStatusReporter(
+			crdClient,
+			nodeConfig.Name,
+			nodeConfig.NodeIPAddr.IP,
+			informerFactory.Core().V1().Nodes())
+	}
+
 	// TODO: we should call this after installing flows for initial node routes
 	//  and initial NetworkPolicies so that no packets will be mishandled.
 	if err := agentInitializer.FlowRestoreComplete(); err != nil {
@@ -190,6 +344,27 @@ func run(o *Options) error {
 
 	log.StartLogFileNumberMonitor(stopCh)
 
+	go agentInitializer.MonitorTransportInterfaceMTU(stopCh)
+
+	go datapathMonitor.Run(stopCh)
+
+	// The limits have been validated when the options were validated. When no
+	// limit is set, the OVS daemons are neither limited nor monitored.
+	ovsResourceLimits, _ := ovsresourcemonitor.ParseLimits(o.config.OVSResourceLimits.CPU, o.config.OVSResourceLimits.Memory)
+	if !ovsResourceLimits.IsEmpty() {
+		ovsResourceMonitor, err := ovsresourcemonitor.NewMonitor(
+			nodeConfig.Name,
+			o.config.HostProcPathPrefix,
+			k8sClient,
+			ovsResourceLimits,
+			o.config.OVSResourceLimits.Enforce,
+			o.config.OVSResourceWarningThreshold)
+		if err != nil {
+			return fmt.Errorf("error creating OVS resource monitor: %v", err)
+		}
+		go ovsResourceMonitor.Run(stopCh)
+	}
+
 	go cniServer.Run(stopCh)
 
 	informerFactory.Start(stopCh)
@@ -199,8 +374,43 @@ func run(o *Options) error {
 
 	go nodeRouteController.Run(stopCh)
 
+	if networkConfig.EnableIPSecTunnel {
+		// The IPSec PSK may be rotated by antrea-controller.
+		ipsecKeyController := ipsec.NewKeyController(k8sClient, nodeConfig.Name, env.GetPodNamespace(), nodeRouteController)
+		go ipsecKeyController.Run(stopCh)
+		go ipsecTunnelStatusReporter.Run(stopCh)
+	}
+
+	if wireGuardClient != nil {
+		go wireGuardClient.Run(stopCh)
+	}
+
 	go networkPolicyController.Run(stopCh)
 
+	if ipPoolAllocator != nil {
+		go ipPoolAllocator.Run(stopCh)
+	}
+
+	if egressController != nil {
+		go egressController.Run(stopCh)
+	}
+
+	// networkPolicyStatsQuerier must stay a nil interface when the statistics
+	// are not collected.
+	var networkPolicyStatsQuerier antreaquerier.AgentNetworkPolicyStatsQuerier
+	if networkPolicyStatsCollector != nil {
+		go networkPolicyStatsCollector.Run(stopCh)
+		networkPolicyStatsQuerier = networkPolicyStatsCollector
+	}
+
+	if o.config.EnablePrometheusMetrics {
+		// The poll interval has been validated when the options were validated.
+		tableStatsPollInterval, _ := time.ParseDuration(o.config.OVSTableStatsPollInterval)
+		if tableStatsPollInterval > 0 {
+			go openflow.NewTableStatsCollector(ofClient, tableStatsPollInterval).Run(stopCh)
+		}
+	}
+
 	if features.DefaultFeatureGate.Enabled(features.Traceflow) {
 		go traceflowController.Run(stopCh)
 	}
@@ -212,19 +422,43 @@ func run(o *Options) error {
 		ofClient,
 		ovsBridgeClient,
 		networkPolicyController,
+		networkPolicyStatsQuerier,
 		o.config.APIPort)
 
 	agentMonitor := monitor.NewAgentMonitor(crdClient, agentQuerier)
 
 	go agentMonitor.Run(stopCh)
 
+	// proxyStatsQuerier must stay a nil interface when the statistics are not
+	// collected, and proxyStatusQuerier when AntreaProxy is disabled.
+	var proxyStatsQuerier proxy.StatsQuerier
+	var proxyStatusQuerier proxy.StatusQuerier
 	if features.DefaultFeatureGate.Enabled(features.AntreaProxy) {
 		go proxier.Run(stopCh)
+		proxyStatusQuerier = proxy.NewStatusChecker(proxier, ovsctl.NewClient(o.config.OVSBridge))
+		if proxyStatsCollector != nil {
+			go proxyStatsCollector.Run(stopCh)
+			proxyStatsQuerier = proxyStatsCollector
+		}
+	}
+
+	// Delete the flows left by the previous agent instance once the flows of the
+	// existing NetworkPolicies, Nodes and Services have been re-installed, so that
+	// the existing connections are not disrupted by the restart.
+	hasSyncedFuncs := []func() bool{networkPolicyController.HasSynced, nodeRouteController.HasSynced}
+	if proxier != nil {
+		hasSyncedFuncs = append(hasSyncedFuncs, proxier.SyncedOnce)
 	}
+	// The timeout has been validated when the options were validated.
+	reconcileTimeout, _ := time.ParseDuration(o.config.ReconcileTimeout)
+	go agentInitializer.DeleteStaleFlows(reconcileTimeout, hasSyncedFuncs...)
 
 	apiServer, err := apiserver.New(
 		agentQuerier,
 		networkPolicyController,
+		networkPolicyStatsQuerier,
+		proxyStatsQuerier,
+		proxyStatusQuerier,
 		o.config.APIPort,
 		o.config.EnablePrometheusMetrics)
 	if err != nil {
@@ -232,16 +466,30 @@ func run(o *Options) error {
 	}
 	go apiServer.Run(stopCh)
 
-	if features.DefaultFeatureGate.Enabled(features.Traceflow) {
-		go ofClient.StartPacketInHandler(stopCh)
-	}
+	go ofClient.StartPacketInHandler(stopCh)
+
 	// Create connection store that polls conntrack flows with a given polling interval.
+	var sampler *flowexporter.Sampler
 	if features.DefaultFeatureGate.Enabled(features.FlowExporter) {
 		ctDumper := connections.NewConnTrackDumper(nodeConfig, serviceCIDRNet, connections.NewConnTrackInterfacer())
-		connStore := connections.NewConnectionStore(ctDumper, ifaceStore)
+		// The sampling rate has been validated by Options.validate.
+		samplingRate, _ := flowexporter.ParseSamplingRate(o.config.FlowSamplingRate)
+		sampler = flowexporter.NewSampler(samplingRate, o.config.FlowSamplingHashSeed)
+		var podPairTracker *flowexporter.PodPairTracker
+		if o.config.EnablePrometheusMetrics {
+			podPairTracker = flowexporter.NewPodPairTracker(o.config.MaxTrackedPodPairs)
+		}
+		connStore := connections.NewConnectionStore(ctDumper, ifaceStore, flowMetadataCache, sampler, networkPolicyController, podPairTracker)
 		go connStore.Run(stopCh)
 	}
 
+	// The hot-reloadable settings are only watched when the agent runs in a Pod
+	// with its configuration file mounted from a ConfigMap.
+	if podName := env.GetPodName(); podName != "" && o.configFile != "" {
+		configReloader := newConfigReloader(k8sClient, env.GetPodNamespace(), podName, filepath.Base(o.configFile), o.config, flagLogLevel, sampler)
+		go configReloader.Run(stopCh)
+	}
+
 	<-stopCh
 	klog.Info("Stopping Antrea agent")
 	return nil
@@ -55,8 +55,8 @@ type AgentConfig struct {
 	// - stt
 	TunnelType string `yaml:"tunnelType,omitempty"`
 	// Default MTU to use for the host gateway interface and the network interface of each
-	// Pod. If omitted, antrea-agent will default this value to 1450 to accommodate for tunnel
-	// encapsulate overhead.
+	// Pod. If omitted, antrea-agent will discover the MTU of the Node's transport interface
+	// and subtract the tunnel encapsulation overhead from it.
 	DefaultMTU int `yaml:"defaultMTU,omitempty"`
 	// Mount location of the /proc directory. The default is "/host", which is appropriate when
 	// antrea-agent is run as part of the Antrea DaemonSet (and the host's /proc directory is mounted
@@ -80,10 +80,122 @@ type AgentConfig struct {
 	// Hybrid: noEncap if worker Nodes on same subnet, otherwise encap.
 	// NetworkPolicyOnly: Antrea enforces NetworkPolicy only, and utilizes CNI chaining and delegates Pod IPAM and connectivity to primary CNI.
 	TrafficEncapMode string `yaml:"trafficEncapMode,omitempty"`
+	// Determines how inter-Node Pod traffic is encrypted. It has the following options:
+	// None(default): Inter-Node Pod traffic is not encrypted.
+	// IPSec: Inter-Node Pod traffic is encrypted with IPSec (ESP), same as enableIPSecTunnel.
+	//        It is supported only for the GRE tunnel type on Encap mode.
+	// WireGuard: Inter-Node Pod traffic is encrypted with WireGuard. It is supported only on
+	//            NoEncap mode, and requires the WireGuard kernel module on the Nodes.
+	TrafficEncryptionMode string `yaml:"trafficEncryptionMode,omitempty"`
+	// The UDP port used by WireGuard for inter-Node Pod traffic. It must be the same on all Nodes.
+	// Defaults to 51820.
+	WireGuardPort int `yaml:"wireGuardPort,omitempty"`
+	// How often the WireGuard key pair of the Node is rotated. Established connections are not
+	// affected by the rotation. Valid time units are "ns", "us" (or "µs"), "ms", "s", "m", "h".
+	// Set it to 0 to disable the rotation.
+	// Defaults to 24h.
+	WireGuardKeyRotationInterval string `yaml:"wireGuardKeyRotationInterval,omitempty"`
 	// APIPort is the port for the antrea-agent APIServer to serve on.
 	// Defaults to 10350.
 	APIPort int `yaml:"apiPort,omitempty"`
 	// Enable metrics exposure via Prometheus. Initializes Prometheus metrics listener
 	// Defaults to false.
 	EnablePrometheusMetrics bool `yaml:"enablePrometheusMetrics,omitempty"`
+	// How often the agent polls the statistics of the OVS flow tables, which are exposed as the
+	// antrea_agent_ovs_table_packets_total and antrea_agent_ovs_table_bytes_total Prometheus
+	// metrics. Only used when enablePrometheusMetrics is true. Valid time units are "ns", "us"
+	// (or "µs"), "ms", "s", "m", "h". Set it to 0 to disable the collection.
+	// Defaults to 30s.
+	OVSTableStatsPollInterval string `yaml:"ovsTableStatsPollInterval,omitempty"`
+	// The utilization of the OVS datapath flow table, in percent of the maximum number of flows
+	// ovs-vswitchd installs in the datapath, above which an OVSFlowTableFull Warning event is
+	// emitted on the Node.
+	// Defaults to 80.
+	OVSFlowTableWarningThreshold int `yaml:"ovsFlowTableWarningThreshold,omitempty"`
+	// The utilization of the OVS datapath flow table, in percent of the maximum number of flows
+	// ovs-vswitchd installs in the datapath, above which the CNI ADD requests of new Pods are
+	// refused.
+	// Defaults to 95.
+	OVSFlowTableBlockThreshold int `yaml:"ovsFlowTableBlockThreshold,omitempty"`
+	// How long AntreaProxy keeps forwarding the existing connections to a Service Endpoint
+	// after the Endpoint has been removed, e.g. because its Pod is terminating. No new
+	// connection is sent to the Endpoint during this period. Valid time units are "ns", "us"
+	// (or "µs"), "ms", "s", "m", "h". Set it to 0 to remove the Endpoint immediately.
+	// Defaults to 30s.
+	EndpointDrainPeriod string `yaml:"endpointDrainPeriod,omitempty"`
+	// Make AntreaProxy program OpenFlow fast-failover groups instead of select groups for the
+	// Services. Each Endpoint gets an OVS port which is brought down as soon as the Endpoint is
+	// removed, e.g. because its Pod has been deleted or is not ready, so that OVS fails over to
+	// the next Endpoint immediately. Note that a fast-failover group sends all the traffic of a
+	// Service to its first live Endpoint, the traffic is not load-balanced.
+	// Defaults to false.
+	FastFailoverGroups bool `yaml:"fastFailoverGroups,omitempty"`
+	// How often AntreaProxy polls the OVS flow counters to collect the traffic statistics
+	// of the Services, which can be queried with "antctl get proxystats". Valid time units
+	// are "ns", "us" (or "µs"), "ms", "s", "m", "h". Set it to 0 to disable the collection.
+	// Defaults to 10s.
+	ProxyStatsPollInterval string `yaml:"proxyStatsPollInterval,omitempty"`
+	// How often the agent polls the OVS flow counters to collect the traffic statistics of
+	// the NetworkPolicies, which can be queried with "antctl get networkpolicystats". Valid
+	// time units are "ns", "us" (or "µs"), "ms", "s", "m", "h". Set it to 0 to disable the
+	// collection.
+	// Defaults to 10s.
+	NetworkPolicyStatsPollInterval string `yaml:"networkPolicyStatsPollInterval,omitempty"`
+	// Enable OVS hardware offload with TC flower. OVS is configured with "hw-offload=true" if the
+	// NIC backing the Node's transport interface is in switchdev mode and exposes representor
+	// ports, otherwise the agent falls back to the software datapath. The agent does not change
+	// the eswitch mode of the NIC. Only supported on Linux.
+	// Defaults to false.
+	HWOffloadMode bool `yaml:"hwOffloadMode,omitempty"`
+	// The rate at which the flow exporter samples the connections, of the form "1:N" meaning that
+	// 1 in every N connections is exported. The connections are selected with a hash of their
+	// 5-tuple, so that both directions of a connection are either exported or dropped.
+	// Changes to it are applied without restarting antrea-agent.
+	// Defaults to "1:1", i.e. all the connections are exported.
+	FlowSamplingRate string `yaml:"flowSamplingRate,omitempty"`
+	// The seed of the hash used to sample the connections. Clusters with different seeds sample
+	// different connections.
+	// Defaults to 0.
+	FlowSamplingHashSeed uint32 `yaml:"flowSamplingHashSeed,omitempty"`
+	// The maximum number of Pod pairs which get their own series in the antrea_pod_to_pod_bytes_total
+	// Prometheus metric, computed from the connections polled by the flow exporter. When a new pair
+	// must be tracked, the least recently active one is dropped. Only used when
+	// enablePrometheusMetrics is true.
+	// Defaults to 1000.
+	MaxTrackedPodPairs int `yaml:"maxTrackedPodPairs,omitempty"`
+	// The verbosity of the logs of antrea-agent, between 0 and 10. Changes to it are applied
+	// without restarting antrea-agent.
+	// Defaults to the verbosity provided with the "--v" flag.
+	LogVerbosity *int `yaml:"logVerbosity,omitempty"`
+	// How long the agent waits after a restart for the NetworkPolicies, the Node routes and the
+	// Services to be realized before deleting the OpenFlow flows left by its previous instance.
+	// The stale flows are deleted after this timeout even if the realization is not complete.
+	// Valid time units are "ns", "us" (or "µs"), "ms", "s", "m", "h".
+	// Defaults to 60s.
+	ReconcileTimeout string `yaml:"reconcileTimeout,omitempty"`
+	// The CPU and memory limits of the OVS daemons, ovsdb-server and ovs-vswitchd. When a limit is
+	// set, the agent monitors the usage of the daemons and emits an OVSHighResourceUsage Warning
+	// event on the Node when it exceeds ovsResourceWarningThreshold. The limits are only applied
+	// to the cgroups of the daemons when enforce is true. Daemons in the same cgroup, e.g. in the
+	// antrea-ovs container, share the limits. Not supported on Windows.
+	// Defaults to no limits.
+	OVSResourceLimits OVSResourceLimits `yaml:"ovsResourceLimits,omitempty"`
+	// The usage of the OVS daemons, in percent of ovsResourceLimits, above which an
+	// OVSHighResourceUsage Warning event is emitted on the Node.
+	// Defaults to 80.
+	OVSResourceWarningThreshold int `yaml:"ovsResourceWarningThreshold,omitempty"`
+}
+
+type OVSResourceLimits struct {
+	// The CPU limit, as a K8s resource quantity, e.g. "2" or "500m".
+	CPU string `yaml:"cpu,omitempty"`
+	// The memory limit, as a K8s resource quantity, e.g. "512Mi".
+	Memory string `yaml:"memory,omitempty"`
+	// Apply the limits to the cgroups of the OVS daemons: with systemd when a daemon runs in a
+	// systemd service, by writing to /sys/fs/cgroup otherwise. When a daemon runs in a container,
+	// the cgroup filesystem of the host must be mounted at /sys/fs/cgroup in the antrea-agent
+	// container. When it is false, or if the limits cannot be applied, the usage is only
+	// monitored.
+	// Defaults to false.
+	Enforce bool `yaml:"enforce,omitempty"`
 }
@@ -0,0 +1,263 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/flowexporter"
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/loglevel"
+)
+
+const (
+	configReloaderName = "AntreaAgentConfigReloader"
+	// The volume of the antrea-agent Pod which holds the antrea-config ConfigMap.
+	antreaConfigVolume = "antrea-config"
+	// Set resyncPeriod to 0 to disable resyncing.
+	configResyncPeriod time.Duration = 0
+	// How long to wait before retrying to get the ConfigMap name from the Pod.
+	configMapNameRetryInterval = 5 * time.Second
+	// How long to wait before retrying the processing of the ConfigMap.
+	configMinRetryDelay = 5 * time.Second
+	configMaxRetryDelay = 300 * time.Second
+)
+
+// hotReloadableConfigFields are the YAML keys of the AgentConfig fields which
+// are applied without restarting antrea-agent.
+var hotReloadableConfigFields = sets.NewString("logVerbosity", "flowSamplingRate")
+
+// configReloader watches the ConfigMap which antrea-agent's configuration file
+// comes from, and applies the hot-reloadable settings when it's updated. The
+// file is mounted with a subPath, so it's never updated by kubelet and the
+// ConfigMap must be watched instead.
+type configReloader struct {
+	k8sClient clientset.Interface
+	namespace string
+	podName   string
+	// configKey is the key of the configuration file in the ConfigMap.
+	configKey string
+	// config is the configuration currently applied. It's only accessed by
+	// the single worker.
+	config *AgentConfig
+	// flagLogLevel is the log verbosity used when logVerbosity is not set.
+	flagLogLevel int
+	// sampler is nil if the FlowExporter feature is disabled.
+	sampler *flowexporter.Sampler
+	queue   workqueue.RateLimitingInterface
+}
+
+func newConfigReloader(k8sClient clientset.Interface, namespace, podName, configKey string, config *AgentConfig, flagLogLevel int, sampler *flowexporter.Sampler) *configReloader {
+	return &configReloader{
+		k8sClient:    k8sClient,
+		namespace:    namespace,
+		podName:      podName,
+		configKey:    configKey,
+		config:       config,
+		flagLogLevel: flagLogLevel,
+		sampler:      sampler,
+		queue:        workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(configMinRetryDelay, configMaxRetryDelay), "agentconfig"),
+	}
+}
+
+func (r *configReloader) Run(stopCh <-chan struct{}) {
+	defer r.queue.ShutDown()
+
+	klog.Infof("Starting %s", configReloaderName)
+	defer klog.Infof("Shutting down %s", configReloaderName)
+
+	var configMapName string
+	if err := wait.PollImmediateUntil(configMapNameRetryInterval, func() (bool, error) {
+		var err error
+		configMapName, err = r.getConfigMapName()
+		if err != nil {
+			klog.Errorf("Failed to get the name of the agent ConfigMap: %v", err)
+			return false, nil
+		}
+		return true, nil
+	}, stopCh); err != nil {
+		return
+	}
+
+	// Only the ConfigMap of antrea-agent is watched, the agent is not allowed
+	// to access the other ConfigMaps.
+	configMapInformer := coreinformers.NewFilteredConfigMapInformer(r.k8sClient, r.namespace, configResyncPeriod, cache.Indexers{}, func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", configMapName).String()
+	})
+	configMapLister := corelisters.NewConfigMapLister(configMapInformer.GetIndexer())
+	configMapInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			r.queue.Add(configMapName)
+		},
+		UpdateFunc: func(oldObj, curObj interface{}) {
+			r.queue.Add(configMapName)
+		},
+	})
+	go configMapInformer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, configMapInformer.HasSynced) {
+		klog.Errorf("Unable to sync caches for %s", configReloaderName)
+		return
+	}
+
+	// The configuration must be applied in order, a single worker is used.
+	go wait.Until(func() {
+		for r.processNextWorkItem(configMapLister) {
+		}
+	}, time.Second, stopCh)
+	<-stopCh
+}
+
+// getConfigMapName returns the name of the ConfigMap mounted in the
+// antrea-agent Pod, which includes a hash of its content.
+func (r *configReloader) getConfigMapName() (string, error) {
+	pod, err := r.k8sClient.CoreV1().Pods(r.namespace).Get(context.TODO(), r.podName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == antreaConfigVolume && volume.ConfigMap != nil {
+			return volume.ConfigMap.Name, nil
+		}
+	}
+	return "", fmt.Errorf("Pod %s/%s has no ConfigMap volume %s", r.namespace, r.podName, antreaConfigVolume)
+}
+
+func (r *configReloader) processNextWorkItem(configMapLister corelisters.ConfigMapLister) bool {
+	key, quit := r.queue.Get()
+	if quit {
+		return false
+	}
+	defer r.queue.Done(key)
+
+	if err := r.syncConfigMap(configMapLister, key.(string)); err == nil {
+		r.queue.Forget(key)
+	} else {
+		r.queue.AddRateLimited(key)
+		klog.Errorf("Error syncing agent ConfigMap %s, requeuing. Error: %v", key, err)
+	}
+	return true
+}
+
+func (r *configReloader) syncConfigMap(configMapLister corelisters.ConfigMapLister, configMapName string) error {
+	configMap, err := configMapLister.ConfigMaps(r.namespace).Get(configMapName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			klog.Warningf("Agent ConfigMap %s/%s not found", r.namespace, configMapName)
+			return nil
+		}
+		return err
+	}
+	data, ok := configMap.Data[r.configKey]
+	if !ok {
+		klog.Warningf("Agent ConfigMap %s/%s has no key %s", r.namespace, configMapName, r.configKey)
+		return nil
+	}
+	// An invalid configuration is not retried, it must be fixed by updating
+	// the ConfigMap again.
+	newConfig, err := parseConfig([]byte(data))
+	if err != nil {
+		klog.Errorf("Ignoring invalid configuration in agent ConfigMap %s/%s: %v", r.namespace, configMapName, err)
+		return nil
+	}
+	o := &Options{config: newConfig}
+	o.setDefaults()
+	if err := o.validate(nil); err != nil {
+		klog.Errorf("Ignoring invalid configuration in agent ConfigMap %s/%s: %v", r.namespace, configMapName, err)
+		return nil
+	}
+	r.applyConfig(newConfig)
+	return nil
+}
+
+// applyConfig applies the hot-reloadable settings of a validated configuration
+// all together, and logs a warning for the other settings which changed.
+func (r *configReloader) applyConfig(newConfig *AgentConfig) {
+	var restartRequired []string
+	reloaded := false
+	for _, field := range changedConfigFields(r.config, newConfig) {
+		if hotReloadableConfigFields.Has(field) {
+			reloaded = true
+		} else {
+			restartRequired = append(restartRequired, field)
+		}
+	}
+	if len(restartRequired) > 0 {
+		klog.Warningf("Changes to %s require restarting antrea-agent to take effect", strings.Join(restartRequired, ", "))
+	}
+	if !reloaded {
+		return
+	}
+
+	applied := *r.config
+	applied.LogVerbosity = newConfig.LogVerbosity
+	applied.FlowSamplingRate = newConfig.FlowSamplingRate
+	setLogVerbosity(applied.LogVerbosity, r.flagLogLevel)
+	if r.sampler != nil {
+		// The sampling rate has been validated by Options.validate.
+		samplingRate, _ := flowexporter.ParseSamplingRate(applied.FlowSamplingRate)
+		r.sampler.SetRate(samplingRate)
+	}
+	r.config = &applied
+	klog.Infof("Applied the updated agent configuration: log verbosity %d, flow sampling rate %s", loglevel.CurrentLevel(), applied.FlowSamplingRate)
+}
+
+// changedConfigFields returns the YAML keys of the fields which differ between
+// two configurations.
+func changedConfigFields(oldConfig, newConfig *AgentConfig) []string {
+	var changed []string
+	oldValue, newValue := reflect.ValueOf(*oldConfig), reflect.ValueOf(*newConfig)
+	for i := 0; i < oldValue.NumField(); i++ {
+		if reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			continue
+		}
+		field := oldValue.Type().Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		changed = append(changed, name)
+	}
+	return changed
+}
+
+// setLogVerbosity sets the log verbosity of antrea-agent to the configured
+// value, or to the value of the --v flag if it's not configured.
+func setLogVerbosity(verbosity *int, flagLogLevel int) {
+	level := flagLogLevel
+	if verbosity != nil {
+		level = *verbosity
+	}
+	if level == loglevel.CurrentLevel() {
+		return
+	}
+	if err := loglevel.SetLevel(level); err != nil {
+		klog.Errorf("Failed to set log verbosity to %d: %v", level, err)
+	}
+}
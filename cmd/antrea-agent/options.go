@@ -18,31 +18,37 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"time"
 
 	"github.com/spf13/pflag"
 	"gopkg.in/yaml.v2"
 
 	"github.com/vmware-tanzu/antrea/pkg/agent/config"
+	"github.com/vmware-tanzu/antrea/pkg/agent/flowexporter"
+	"github.com/vmware-tanzu/antrea/pkg/agent/ovsresourcemonitor"
 	"github.com/vmware-tanzu/antrea/pkg/apis"
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/loglevel"
 	"github.com/vmware-tanzu/antrea/pkg/cni"
 	"github.com/vmware-tanzu/antrea/pkg/features"
 	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsconfig"
 )
 
 const (
-	defaultOVSBridge          = "br-int"
-	defaultHostGateway        = "antrea-gw0"
-	defaultHostProcPathPrefix = "/host"
-	defaultServiceCIDR        = "10.96.0.0/12"
-	defaultTunnelType         = ovsconfig.GeneveTunnel
-	defaultMTUGeneve          = 1450
-	defaultMTUVXLAN           = 1450
-	defaultMTUGRE             = 1462
-	defaultMTUSTT             = 1500
-	defaultMTU                = 1500
-	// IPsec ESP can add a maximum of 38 bytes to the packet including the ESP
-	// header and trailer.
-	ipsecESPOverhead = 38
+	defaultOVSBridge                      = "br-int"
+	defaultHostGateway                    = "antrea-gw0"
+	defaultHostProcPathPrefix             = "/host"
+	defaultServiceCIDR                    = "10.96.0.0/12"
+	defaultTunnelType                     = ovsconfig.GeneveTunnel
+	defaultEndpointDrainPeriod            = "30s"
+	defaultProxyStatsPollInterval         = "10s"
+	defaultNetworkPolicyStatsPollInterval = "10s"
+	defaultOVSTableStatsPollInterval      = "30s"
+	defaultOVSFlowTableWarningThreshold   = 80
+	defaultOVSFlowTableBlockThreshold     = 95
+	defaultWireGuardPort                  = 51820
+	defaultWireGuardKeyRotationInterval   = "24h"
+	defaultReconcileTimeout               = "60s"
+	defaultOVSResourceWarningThreshold    = 80
 )
 
 type Options struct {
@@ -50,6 +56,8 @@ type Options struct {
 	configFile string
 	// The configuration object
 	config *AgentConfig
+	// Keep the existing flows when the OpenFlow pipeline has changed.
+	skipPipelineFlush bool
 }
 
 func newOptions() *Options {
@@ -61,6 +69,7 @@ func newOptions() *Options {
 // addFlags adds flags to fs and binds them to options.
 func (o *Options) addFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.configFile, "config", o.configFile, "The path to the configuration file")
+	fs.BoolVar(&o.skipPipelineFlush, "skip-pipeline-flush", o.skipPipelineFlush, "Do not delete the existing flows when they were installed with a different OpenFlow pipeline version. For debugging only")
 }
 
 // complete completes all the required options.
@@ -90,7 +99,15 @@ func (o *Options) validate(args []string) error {
 		o.config.TunnelType != ovsconfig.GRETunnel && o.config.TunnelType != ovsconfig.STTTunnel {
 		return fmt.Errorf("tunnel type %s is invalid", o.config.TunnelType)
 	}
-	if o.config.EnableIPSecTunnel && o.config.TunnelType != ovsconfig.GRETunnel {
+	ok, encryptionMode := config.GetTrafficEncryptionModeFromStr(o.config.TrafficEncryptionMode)
+	if !ok {
+		return fmt.Errorf("TrafficEncryptionMode %s is unknown", o.config.TrafficEncryptionMode)
+	}
+	if o.config.EnableIPSecTunnel && encryptionMode == config.TrafficEncryptionModeWireGuard {
+		return fmt.Errorf("IPSec tunnel cannot be enabled with %s encryption mode", config.TrafficEncryptionModeWireGuard)
+	}
+	enableIPSecTunnel := o.config.EnableIPSecTunnel || encryptionMode == config.TrafficEncryptionModeIPSec
+	if enableIPSecTunnel && o.config.TunnelType != ovsconfig.GRETunnel {
 		return fmt.Errorf("IPSec encyption is supported only for GRE tunnel")
 	}
 	if o.config.OVSDatapathType != ovsconfig.OVSDatapathSystem && o.config.OVSDatapathType != ovsconfig.OVSDatapathNetdev {
@@ -100,12 +117,77 @@ func (o *Options) validate(args []string) error {
 	if !ok {
 		return fmt.Errorf("TrafficEncapMode %s is unknown", o.config.TrafficEncapMode)
 	}
-	if encapMode.SupportsNoEncap() && o.config.EnableIPSecTunnel {
+	if encapMode.SupportsNoEncap() && enableIPSecTunnel {
 		return fmt.Errorf("IPSec tunnel may only be enabled on %s mode", config.TrafficEncapModeEncap)
 	}
+	if encryptionMode == config.TrafficEncryptionModeWireGuard {
+		if encapMode != config.TrafficEncapModeNoEncap {
+			return fmt.Errorf("%s encryption mode may only be enabled on %s mode", config.TrafficEncryptionModeWireGuard, config.TrafficEncapModeNoEncap)
+		}
+		if o.config.WireGuardPort <= 0 || o.config.WireGuardPort > 65535 {
+			return fmt.Errorf("WireGuardPort %d is invalid", o.config.WireGuardPort)
+		}
+		if rotationInterval, err := time.ParseDuration(o.config.WireGuardKeyRotationInterval); err != nil {
+			return fmt.Errorf("WireGuardKeyRotationInterval %s is invalid: %v", o.config.WireGuardKeyRotationInterval, err)
+		} else if rotationInterval < 0 {
+			return fmt.Errorf("WireGuardKeyRotationInterval %s must not be negative", o.config.WireGuardKeyRotationInterval)
+		}
+	}
+	if features.DefaultFeatureGate.Enabled(features.SecondaryNetwork) && !features.DefaultFeatureGate.Enabled(features.AntreaIPAM) {
+		return fmt.Errorf("SecondaryNetwork feature requires AntreaIPAM feature to be enabled")
+	}
 	if o.config.OVSDatapathType == ovsconfig.OVSDatapathNetdev && features.DefaultFeatureGate.Enabled(features.FlowExporter) {
 		return fmt.Errorf("FlowExporter feature is not supported for OVS datapath type %s", o.config.OVSDatapathType)
 	}
+	if _, err := flowexporter.ParseSamplingRate(o.config.FlowSamplingRate); err != nil {
+		return err
+	}
+	if o.config.LogVerbosity != nil && (*o.config.LogVerbosity < loglevel.MinLevel || *o.config.LogVerbosity > loglevel.MaxLevel) {
+		return fmt.Errorf("LogVerbosity %d must be between %d and %d", *o.config.LogVerbosity, loglevel.MinLevel, loglevel.MaxLevel)
+	}
+	if o.config.MaxTrackedPodPairs < 0 {
+		return fmt.Errorf("MaxTrackedPodPairs %d must not be negative", o.config.MaxTrackedPodPairs)
+	}
+	if o.config.DefaultMTU < 0 {
+		return fmt.Errorf("DefaultMTU %d must not be negative", o.config.DefaultMTU)
+	}
+	if drainPeriod, err := time.ParseDuration(o.config.EndpointDrainPeriod); err != nil {
+		return fmt.Errorf("EndpointDrainPeriod %s is invalid: %v", o.config.EndpointDrainPeriod, err)
+	} else if drainPeriod < 0 {
+		return fmt.Errorf("EndpointDrainPeriod %s must not be negative", o.config.EndpointDrainPeriod)
+	}
+	if pollInterval, err := time.ParseDuration(o.config.ProxyStatsPollInterval); err != nil {
+		return fmt.Errorf("ProxyStatsPollInterval %s is invalid: %v", o.config.ProxyStatsPollInterval, err)
+	} else if pollInterval < 0 {
+		return fmt.Errorf("ProxyStatsPollInterval %s must not be negative", o.config.ProxyStatsPollInterval)
+	}
+	if pollInterval, err := time.ParseDuration(o.config.NetworkPolicyStatsPollInterval); err != nil {
+		return fmt.Errorf("NetworkPolicyStatsPollInterval %s is invalid: %v", o.config.NetworkPolicyStatsPollInterval, err)
+	} else if pollInterval < 0 {
+		return fmt.Errorf("NetworkPolicyStatsPollInterval %s must not be negative", o.config.NetworkPolicyStatsPollInterval)
+	}
+	if pollInterval, err := time.ParseDuration(o.config.OVSTableStatsPollInterval); err != nil {
+		return fmt.Errorf("OVSTableStatsPollInterval %s is invalid: %v", o.config.OVSTableStatsPollInterval, err)
+	} else if pollInterval < 0 {
+		return fmt.Errorf("OVSTableStatsPollInterval %s must not be negative", o.config.OVSTableStatsPollInterval)
+	}
+	if o.config.OVSFlowTableWarningThreshold < 0 || o.config.OVSFlowTableWarningThreshold > 100 {
+		return fmt.Errorf("OVSFlowTableWarningThreshold %d must be between 0 and 100", o.config.OVSFlowTableWarningThreshold)
+	}
+	if o.config.OVSFlowTableBlockThreshold < 0 || o.config.OVSFlowTableBlockThreshold > 100 {
+		return fmt.Errorf("OVSFlowTableBlockThreshold %d must be between 0 and 100", o.config.OVSFlowTableBlockThreshold)
+	}
+	if timeout, err := time.ParseDuration(o.config.ReconcileTimeout); err != nil {
+		return fmt.Errorf("ReconcileTimeout %s is invalid: %v", o.config.ReconcileTimeout, err)
+	} else if timeout <= 0 {
+		return fmt.Errorf("ReconcileTimeout %s must be positive", o.config.ReconcileTimeout)
+	}
+	if _, err := ovsresourcemonitor.ParseLimits(o.config.OVSResourceLimits.CPU, o.config.OVSResourceLimits.Memory); err != nil {
+		return fmt.Errorf("OVSResourceLimits is invalid: %v", err)
+	}
+	if o.config.OVSResourceWarningThreshold < 0 || o.config.OVSResourceWarningThreshold > 100 {
+		return fmt.Errorf("OVSResourceWarningThreshold %d must be between 0 and 100", o.config.OVSResourceWarningThreshold)
+	}
 	return nil
 }
 
@@ -114,9 +196,12 @@ func (o *Options) loadConfigFromFile(file string) (*AgentConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	return parseConfig(data)
+}
 
+func parseConfig(data []byte) (*AgentConfig, error) {
 	var c AgentConfig
-	err = yaml.UnmarshalStrict(data, &c)
+	err := yaml.UnmarshalStrict(data, &c)
 	if err != nil {
 		return nil, err
 	}
@@ -151,27 +236,47 @@ func (o *Options) setDefaults() {
 	if o.config.TrafficEncapMode == "" {
 		o.config.TrafficEncapMode = config.TrafficEncapModeEncap.String()
 	}
-
-	if o.config.DefaultMTU == 0 {
-		ok, encapMode := config.GetTrafficEncapModeFromStr(o.config.TrafficEncapMode)
-		if ok && !encapMode.SupportsEncap() {
-			o.config.DefaultMTU = defaultMTU
-		} else if o.config.TunnelType == ovsconfig.VXLANTunnel {
-			o.config.DefaultMTU = defaultMTUVXLAN
-		} else if o.config.TunnelType == ovsconfig.GeneveTunnel {
-			o.config.DefaultMTU = defaultMTUGeneve
-		} else if o.config.TunnelType == ovsconfig.GRETunnel {
-			o.config.DefaultMTU = defaultMTUGRE
-		} else if o.config.TunnelType == ovsconfig.STTTunnel {
-			o.config.DefaultMTU = defaultMTUSTT
-		}
-
-		if o.config.EnableIPSecTunnel {
-			o.config.DefaultMTU -= ipsecESPOverhead
-		}
+	if o.config.TrafficEncryptionMode == "" {
+		o.config.TrafficEncryptionMode = config.TrafficEncryptionModeNone.String()
+	}
+	if o.config.WireGuardPort == 0 {
+		o.config.WireGuardPort = defaultWireGuardPort
+	}
+	if o.config.WireGuardKeyRotationInterval == "" {
+		o.config.WireGuardKeyRotationInterval = defaultWireGuardKeyRotationInterval
 	}
 
 	if o.config.APIPort == 0 {
 		o.config.APIPort = apis.AntreaAgentAPIPort
 	}
+	if o.config.EndpointDrainPeriod == "" {
+		o.config.EndpointDrainPeriod = defaultEndpointDrainPeriod
+	}
+	if o.config.ProxyStatsPollInterval == "" {
+		o.config.ProxyStatsPollInterval = defaultProxyStatsPollInterval
+	}
+	if o.config.NetworkPolicyStatsPollInterval == "" {
+		o.config.NetworkPolicyStatsPollInterval = defaultNetworkPolicyStatsPollInterval
+	}
+	if o.config.OVSTableStatsPollInterval == "" {
+		o.config.OVSTableStatsPollInterval = defaultOVSTableStatsPollInterval
+	}
+	if o.config.OVSFlowTableWarningThreshold == 0 {
+		o.config.OVSFlowTableWarningThreshold = defaultOVSFlowTableWarningThreshold
+	}
+	if o.config.OVSFlowTableBlockThreshold == 0 {
+		o.config.OVSFlowTableBlockThreshold = defaultOVSFlowTableBlockThreshold
+	}
+	if o.config.FlowSamplingRate == "" {
+		o.config.FlowSamplingRate = flowexporter.DefaultSamplingRate
+	}
+	if o.config.MaxTrackedPodPairs == 0 {
+		o.config.MaxTrackedPodPairs = flowexporter.DefaultMaxTrackedPodPairs
+	}
+	if o.config.ReconcileTimeout == "" {
+		o.config.ReconcileTimeout = defaultReconcileTimeout
+	}
+	if o.config.OVSResourceWarningThreshold == 0 {
+		o.config.OVSResourceWarningThreshold = defaultOVSResourceWarningThreshold
+	}
 }
@@ -39,4 +39,29 @@ type ControllerConfig struct {
 	// antrea-controller container.
 	// Defaults to true.
 	SelfSignedCert bool `yaml:"selfSignedCert,omitempty"`
+	// How often the FQDNs referenced by the egress rules of ClusterNetworkPolicies are resolved.
+	// Valid time units are "ns", "us" (or "µs"), "ms", "s", "m", "h".
+	// Defaults to 30s.
+	FQDNResolveInterval string `yaml:"fqdnResolveInterval,omitempty"`
+	// The max number of completed Traceflows whose histories are kept. The histories of the Traceflows which
+	// completed first are evicted first.
+	// Defaults to 100.
+	TraceflowHistorySize int `yaml:"traceflowHistorySize,omitempty"`
+	// Whether or not to rotate the IPSec PSK stored in the antrea-ipsec Secret. It should only be
+	// enabled when the IPSec tunnel is enabled on the agents.
+	// Defaults to false.
+	EnableIPSecKeyRotation bool `yaml:"enableIPSecKeyRotation,omitempty"`
+	// How often the IPSec PSK is rotated, when enableIPSecKeyRotation is true. A rotation can also
+	// be triggered manually with "antctl rotate-ipsec-key". Valid time units are "ns", "us" (or "µs"),
+	// "ms", "s", "m", "h".
+	// Defaults to 168h (7 days).
+	IPSecKeyRotationInterval string `yaml:"ipsecKeyRotationInterval,omitempty"`
+	// The max number of Pod events per second which trigger the reconciliation of the
+	// NetworkPolicies. The events exceeding the rate are delayed, never dropped.
+	// Defaults to 100.
+	PodEventRateLimit int `yaml:"podEventRateLimit,omitempty"`
+	// The max number of NetworkPolicy and ClusterNetworkPolicy events per second which trigger
+	// their reconciliation. The events exceeding the rate are delayed, never dropped.
+	// Defaults to 200.
+	NetworkPolicyEventRateLimit int `yaml:"networkPolicyEventRateLimit,omitempty"`
 }
@@ -32,9 +32,15 @@ import (
 
 	"github.com/vmware-tanzu/antrea/pkg/apiserver"
 	"github.com/vmware-tanzu/antrea/pkg/apiserver/certificate"
+	traceflowhandler "github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/traceflow"
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/webhook"
 	"github.com/vmware-tanzu/antrea/pkg/apiserver/openapi"
 	"github.com/vmware-tanzu/antrea/pkg/apiserver/storage"
+	crdclientset "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
 	crdinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions"
+	"github.com/vmware-tanzu/antrea/pkg/controller/egress"
+	"github.com/vmware-tanzu/antrea/pkg/controller/ippool"
+	"github.com/vmware-tanzu/antrea/pkg/controller/ipsec"
 	"github.com/vmware-tanzu/antrea/pkg/controller/metrics"
 	"github.com/vmware-tanzu/antrea/pkg/controller/networkpolicy"
 	"github.com/vmware-tanzu/antrea/pkg/controller/networkpolicy/store"
@@ -45,6 +51,7 @@ import (
 	"github.com/vmware-tanzu/antrea/pkg/log"
 	"github.com/vmware-tanzu/antrea/pkg/monitor"
 	"github.com/vmware-tanzu/antrea/pkg/signals"
+	"github.com/vmware-tanzu/antrea/pkg/util/env"
 	"github.com/vmware-tanzu/antrea/pkg/version"
 )
 
@@ -71,31 +78,74 @@ func run(o *Options) error {
 	nodeInformer := informerFactory.Core().V1().Nodes()
 	cnpInformer := crdInformerFactory.Security().V1alpha1().ClusterNetworkPolicies()
 	traceflowInformer := crdInformerFactory.Ops().V1alpha1().Traceflows()
+	traceflowHistoryInformer := crdInformerFactory.Ops().V1alpha1().TraceflowHistories()
 
 	// Create Antrea object storage.
 	addressGroupStore := store.NewAddressGroupStore()
 	appliedToGroupStore := store.NewAppliedToGroupStore()
 	networkPolicyStore := store.NewNetworkPolicyStore()
 
+	// The resolve interval has been validated when the options were validated.
+	fqdnResolveInterval, _ := time.ParseDuration(o.config.FQDNResolveInterval)
 	networkPolicyController := networkpolicy.NewNetworkPolicyController(client,
 		crdClient,
 		podInformer,
 		namespaceInformer,
 		networkPolicyInformer,
 		cnpInformer,
+		nodeInformer,
 		addressGroupStore,
 		appliedToGroupStore,
-		networkPolicyStore)
+		networkPolicyStore,
+		fqdnResolveInterval,
+		o.config.PodEventRateLimit,
+		o.config.NetworkPolicyEventRateLimit)
 
 	controllerQuerier := querier.NewControllerQuerier(networkPolicyController, o.config.APIPort)
 
 	controllerMonitor := monitor.NewControllerMonitor(crdClient, nodeInformer, controllerQuerier)
 
 	var traceflowController *traceflow.Controller
+	var traceflowGC *traceflowhandler.GarbageCollector
 	if features.DefaultFeatureGate.Enabled(features.Traceflow) {
-		traceflowController = traceflow.NewTraceflowController(crdClient, traceflowInformer)
+		traceflowController = traceflow.NewTraceflowController(crdClient, traceflowInformer, traceflowHistoryInformer, o.config.TraceflowHistorySize)
+		traceflowGC = traceflowhandler.NewGarbageCollector(crdClient, traceflowhandler.DefaultTTL)
+	}
+
+	var ipPoolController *ippool.Controller
+	// staticIPValidator is left nil when AntreaIPAM is disabled, in which case
+	// all the Pods are admitted.
+	var staticIPValidator webhook.Validator
+	if features.DefaultFeatureGate.Enabled(features.AntreaIPAM) {
+		ipPoolController = ippool.NewIPPoolController(client, crdClient, crdInformerFactory.Core().V1alpha1().IPPools())
+		staticIPValidator = ippool.NewStaticIPValidator(informerFactory.Core().V1().Pods(), crdInformerFactory.Core().V1alpha1().IPPools())
 	}
 
+	var egressController *egress.Controller
+	if features.DefaultFeatureGate.Enabled(features.Egress) {
+		egressController = egress.NewEgressController(crdClient, crdInformerFactory.Core().V1alpha1().Egresses(), nodeInformer)
+	}
+
+	// networkPolicyValidator is left nil when ClusterNetworkPolicy is disabled,
+	// in which case all the policies are admitted.
+	var networkPolicyValidator webhook.Validator
+	var conflictDetector *networkpolicy.ConflictDetector
+	if features.DefaultFeatureGate.Enabled(features.ClusterNetworkPolicy) {
+		networkPolicyValidator = networkpolicy.NewNetworkPolicyValidator()
+		conflictDetector = networkpolicy.NewConflictDetector(client, cnpInformer)
+	}
+
+	var ipsecKeyRotationController *ipsec.KeyRotationController
+	if o.config.EnableIPSecKeyRotation {
+		// The rotation interval has been validated when the options were validated.
+		rotationInterval, _ := time.ParseDuration(o.config.IPSecKeyRotationInterval)
+		ipsecKeyRotationController = ipsec.NewKeyRotationController(client, nodeInformer, env.GetPodNamespace(), rotationInterval)
+	}
+
+	// IPSec is configured on the agents, the aggregator only maintains the
+	// ClusterIPSecStatus when they report tunnels.
+	ipsecStatusAggregator := ipsec.NewStatusAggregator(client, crdClient, crdInformerFactory.Ops().V1alpha1().IPSecTunnelStatuses())
+
 	apiServerConfig, err := createAPIServerConfig(o.config.ClientConnection.Kubeconfig,
 		client,
 		aggregatorClient,
@@ -105,6 +155,9 @@ func run(o *Options) error {
 		appliedToGroupStore,
 		networkPolicyStore,
 		controllerQuerier,
+		crdClient,
+		staticIPValidator,
+		networkPolicyValidator,
 		o.config.EnablePrometheusMetrics)
 	if err != nil {
 		return fmt.Errorf("error creating API server config: %v", err)
@@ -128,6 +181,10 @@ func run(o *Options) error {
 
 	go networkPolicyController.Run(stopCh)
 
+	if features.DefaultFeatureGate.Enabled(features.ClusterNetworkPolicy) {
+		go conflictDetector.Run(stopCh)
+	}
+
 	go apiServer.Run(stopCh)
 
 	if o.config.EnablePrometheusMetrics {
@@ -136,8 +193,23 @@ func run(o *Options) error {
 
 	if features.DefaultFeatureGate.Enabled(features.Traceflow) {
 		go traceflowController.Run(stopCh)
+		go traceflowGC.Run(stopCh)
+	}
+
+	if features.DefaultFeatureGate.Enabled(features.AntreaIPAM) {
+		go ipPoolController.Run(stopCh)
+	}
+
+	if features.DefaultFeatureGate.Enabled(features.Egress) {
+		go egressController.Run(stopCh)
 	}
 
+	if o.config.EnableIPSecKeyRotation {
+		go ipsecKeyRotationController.Run(stopCh)
+	}
+
+	go ipsecStatusAggregator.Run(stopCh)
+
 	<-stopCh
 	klog.Info("Stopping Antrea controller")
 	return nil
@@ -152,10 +224,13 @@ func createAPIServerConfig(kubeconfig string,
 	appliedToGroupStore storage.Interface,
 	networkPolicyStore storage.Interface,
 	controllerQuerier querier.ControllerQuerier,
+	crdClient crdclientset.Interface,
+	staticIPValidator webhook.Validator,
+	networkPolicyValidator webhook.Validator,
 	enableMetrics bool) (*apiserver.Config, error) {
 	secureServing := genericoptions.NewSecureServingOptions().WithLoopback()
 	authentication := genericoptions.NewDelegatingAuthenticationOptions()
-	authorization := genericoptions.NewDelegatingAuthorizationOptions().WithAlwaysAllowPaths("/healthz")
+	authorization := genericoptions.NewDelegatingAuthorizationOptions().WithAlwaysAllowPaths("/healthz", "/validate/staticip", "/validate/networkpolicy")
 
 	caCertController, err := certificate.ApplyServerCert(selfSignedCert, client, aggregatorClient, secureServing)
 	if err != nil {
@@ -199,5 +274,8 @@ func createAPIServerConfig(kubeconfig string,
 		appliedToGroupStore,
 		networkPolicyStore,
 		caCertController,
-		controllerQuerier), nil
+		controllerQuerier,
+		crdClient,
+		staticIPValidator,
+		networkPolicyValidator), nil
 }
@@ -16,15 +16,23 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"time"
 
 	"github.com/spf13/pflag"
 	"gopkg.in/yaml.v2"
 
 	"github.com/vmware-tanzu/antrea/pkg/apis"
+	"github.com/vmware-tanzu/antrea/pkg/controller/networkpolicy"
+	"github.com/vmware-tanzu/antrea/pkg/controller/traceflow"
 	"github.com/vmware-tanzu/antrea/pkg/features"
 )
 
+const (
+	defaultIPSecKeyRotationInterval = "168h"
+)
+
 type Options struct {
 	// The path of configuration file.
 	configFile string
@@ -61,6 +69,25 @@ func (o *Options) validate(args []string) error {
 	if len(args) != 0 {
 		return errors.New("no positional arguments are supported")
 	}
+	if interval, err := time.ParseDuration(o.config.FQDNResolveInterval); err != nil {
+		return fmt.Errorf("FQDNResolveInterval %s is invalid: %v", o.config.FQDNResolveInterval, err)
+	} else if interval <= 0 {
+		return fmt.Errorf("FQDNResolveInterval %s must be positive", o.config.FQDNResolveInterval)
+	}
+	if o.config.TraceflowHistorySize < 0 {
+		return fmt.Errorf("TraceflowHistorySize %d must not be negative", o.config.TraceflowHistorySize)
+	}
+	if interval, err := time.ParseDuration(o.config.IPSecKeyRotationInterval); err != nil {
+		return fmt.Errorf("IPSecKeyRotationInterval %s is invalid: %v", o.config.IPSecKeyRotationInterval, err)
+	} else if interval <= 0 {
+		return fmt.Errorf("IPSecKeyRotationInterval %s must be positive", o.config.IPSecKeyRotationInterval)
+	}
+	if o.config.PodEventRateLimit < 0 {
+		return fmt.Errorf("PodEventRateLimit %d must not be negative", o.config.PodEventRateLimit)
+	}
+	if o.config.NetworkPolicyEventRateLimit < 0 {
+		return fmt.Errorf("NetworkPolicyEventRateLimit %d must not be negative", o.config.NetworkPolicyEventRateLimit)
+	}
 	return nil
 }
 
@@ -84,4 +111,19 @@ func (o *Options) setDefaults() {
 	if o.config.APIPort == 0 {
 		o.config.APIPort = apis.AntreaControllerAPIPort
 	}
+	if o.config.FQDNResolveInterval == "" {
+		o.config.FQDNResolveInterval = networkpolicy.DefaultFQDNResolveInterval.String()
+	}
+	if o.config.TraceflowHistorySize == 0 {
+		o.config.TraceflowHistorySize = traceflow.DefaultHistorySize
+	}
+	if o.config.IPSecKeyRotationInterval == "" {
+		o.config.IPSecKeyRotationInterval = defaultIPSecKeyRotationInterval
+	}
+	if o.config.PodEventRateLimit == 0 {
+		o.config.PodEventRateLimit = networkpolicy.DefaultPodEventRateLimit
+	}
+	if o.config.NetworkPolicyEventRateLimit == 0 {
+		o.config.NetworkPolicyEventRateLimit = networkpolicy.DefaultNetworkPolicyEventRateLimit
+	}
 }
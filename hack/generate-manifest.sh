@@ -168,6 +168,7 @@ if $IPSEC; then
     sed -i.bak -E "s/^[[:space:]]*#[[:space:]]*enableIPSecTunnel[[:space:]]*:[[:space:]]*[a-z]+[[:space:]]*$/enableIPSecTunnel: true/" antrea-agent.conf
     # change the tunnel type to GRE which works better with IPSec encryption than other types.
     sed -i.bak -E "s/^[[:space:]]*#[[:space:]]*tunnelType[[:space:]]*:[[:space:]]*[a-z]+[[:space:]]*$/tunnelType: gre/" antrea-agent.conf
+    sed -i.bak -E "s/^[[:space:]]*#[[:space:]]*enableIPSecKeyRotation[[:space:]]*:[[:space:]]*[a-z]+[[:space:]]*$/enableIPSecKeyRotation: true/" antrea-controller.conf
 fi
 
 if $PROXY; then
@@ -202,6 +203,8 @@ if $IPSEC; then
     $KUSTOMIZE edit add base $BASE
     # create a K8s Secret to save the PSK (pre-shared key) for IKE authentication.
     $KUSTOMIZE edit add resource ipsecSecret.yml
+    # allow the Agent and the Controller to access the Secret, so that the PSK can be rotated.
+    $KUSTOMIZE edit add resource ipsecRbac.yml
     # add a container to the Agent DaemonSet that runs the OVS IPSec and strongSwan daemons.
     $KUSTOMIZE edit add patch ipsecContainer.yml
     # add an environment variable to the antrea-agent container for passing the PSK to Agent.
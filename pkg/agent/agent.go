@@ -51,6 +51,7 @@ const (
 	roundNumKey             = "roundNum" // round number key in externalIDs.
 	initialRoundNum         = 1
 	maxRetryForRoundNumSave = 5
+	pipelineVersionKey      = "pipelineVersion" // OpenFlow pipeline version key in externalIDs.
 )
 
 // Initializer knows how to setup host networking, OpenVSwitch, and Openflow.
@@ -62,11 +63,18 @@ type Initializer struct {
 	ifaceStore      interfacestore.InterfaceStore
 	ovsBridge       string
 	hostGateway     string     // name of gateway port on the OVS bridge
-	mtu             int        // Pod network interface MTU
+	mtu             int        // Pod network interface MTU, 0 means it is derived from the transport interface
 	serviceCIDR     *net.IPNet // K8s Service ClusterIP CIDR
 	networkConfig   *config.NetworkConfig
 	nodeConfig      *config.NodeConfig
 	enableProxy     bool
+	// hwOffload requests OVS hardware offload on the Node's uplink NIC.
+	hwOffload bool
+	// roundInfo is the round of the flows installed by this agent run.
+	roundInfo types.RoundInfo
+	// skipPipelineFlush keeps the existing flows even if they were installed
+	// with a different OpenFlow pipeline version. Only meant for debugging.
+	skipPipelineFlush bool
 }
 
 func NewInitializer(
@@ -80,19 +88,23 @@ func NewInitializer(
 	mtu int,
 	serviceCIDR *net.IPNet,
 	networkConfig *config.NetworkConfig,
-	enableProxy bool) *Initializer {
+	enableProxy bool,
+	hwOffload bool,
+	skipPipelineFlush bool) *Initializer {
 	return &Initializer{
-		ovsBridgeClient: ovsBridgeClient,
-		client:          k8sClient,
-		ifaceStore:      ifaceStore,
-		ofClient:        ofClient,
-		routeClient:     routeClient,
-		ovsBridge:       ovsBridge,
-		hostGateway:     hostGateway,
-		mtu:             mtu,
-		serviceCIDR:     serviceCIDR,
-		networkConfig:   networkConfig,
-		enableProxy:     enableProxy,
+		ovsBridgeClient:   ovsBridgeClient,
+		client:            k8sClient,
+		ifaceStore:        ifaceStore,
+		ofClient:          ofClient,
+		routeClient:       routeClient,
+		ovsBridge:         ovsBridge,
+		hostGateway:       hostGateway,
+		mtu:               mtu,
+		serviceCIDR:       serviceCIDR,
+		networkConfig:     networkConfig,
+		enableProxy:       enableProxy,
+		hwOffload:         hwOffload,
+		skipPipelineFlush: skipPipelineFlush,
 	}
 }
 
@@ -206,6 +218,9 @@ func (i *Initializer) Initialize() error {
 	if err := i.prepareHostNetwork(); err != nil {
 		return err
 	}
+	if err := i.setupHWOffload(); err != nil {
+		return err
+	}
 	if err := i.setupOVSBridge(); err != nil {
 		return err
 	}
@@ -255,15 +270,29 @@ func persistRoundNum(num uint64, bridgeClient ovsconfig.OVSBridgeClient, interva
 //   is deleted.
 //   3. all required flows are installed, using the round number obtained from step 1.
 //   4. after convergence, all existing flows for which the round number matches the previous round
-//   number (i.e. the round number which was persisted in OVSDB, if any) are deleted.
+//   number (i.e. the round number which was persisted in OVSDB, if any) are deleted, see
+//   DeleteStaleFlows.
 //   5. the new round number obtained from step 1 is persisted to OVSDB.
 // The rationale for not persisting the new round number until after all previous flows have been
 // deleted is to avoid a situation in which some stale flows are never deleted because of successive
 // agent restarts (with the agent crashing before step 4 can be completed). With the sequence
 // described above, We guarantee that at most two rounds of flows exist in the switch at any given
 // time.
+// As the flows which are still required are re-installed in place, the existing connections are not
+// disrupted by an agent restart.
+// The only exception is when the OpenFlow pipeline version persisted in OVSDB differs from the one
+// of this agent, typically after an upgrade: the flows of the previous round could then conflict
+// with the new ones, so all the existing flows are deleted in step 2, unless skipPipelineFlush is
+// set. The new pipeline version is persisted to OVSDB once the basic flows are installed.
 func (i *Initializer) initOpenFlowPipeline() error {
-	roundInfo := getRoundInfo(i.ovsBridgeClient)
+	i.roundInfo = getRoundInfo(i.ovsBridgeClient)
+	pipelineVersion := openflow.PipelineVersion()
+	lastPipelineVersion, err := getLastPipelineVersion(i.ovsBridgeClient)
+	if err != nil {
+		klog.Errorf("Failed to get last OpenFlow pipeline version, not deleting existing flows: %v", err)
+	} else {
+		i.roundInfo.FlushFlows = needFlushFlows(lastPipelineVersion, pipelineVersion, i.skipPipelineFlush)
+	}
 	gateway, ok := i.ifaceStore.GetInterface(i.hostGateway)
 	if !ok {
 		return fmt.Errorf("cannot find local gateway %s from interface store", i.hostGateway)
@@ -271,11 +300,18 @@ func (i *Initializer) initOpenFlowPipeline() error {
 	gatewayOFPort := uint32(gateway.OFPort)
 
 	// Set up all basic flows.
-	ofConnCh, err := i.ofClient.Initialize(roundInfo, i.nodeConfig, i.networkConfig.TrafficEncapMode, gatewayOFPort)
+	ofConnCh, err := i.ofClient.Initialize(i.roundInfo, i.nodeConfig, i.networkConfig.TrafficEncapMode, gatewayOFPort)
 	if err != nil {
 		klog.Errorf("Failed to initialize openflow client: %v", err)
 		return err
 	}
+	if lastPipelineVersion != pipelineVersion {
+		// Failing to persist the version only causes the flows to be deleted again at the next
+		// restart.
+		if err := savePipelineVersion(pipelineVersion, i.ovsBridgeClient); err != nil {
+			klog.Errorf("Failed to persist OpenFlow pipeline version %s to OVSDB: %v", pipelineVersion, err)
+		}
+	}
 
 	// On windows platform, host network flows are needed for host traffic.
 	if err := i.initHostNetworkFlows(); err != nil {
@@ -327,24 +363,6 @@ func (i *Initializer) initOpenFlowPipeline() error {
 		}
 	}
 
-	go func() {
-		// Delete stale flows from previous round. We need to wait long enough to ensure
-		// that all the flow which are still required have received an updated cookie (with
-		// the new round number), otherwise we would disrupt the dataplane. Unfortunately,
-		// the time required for convergence may be large and there is no simple way to
-		// determine when is a right time to perform the cleanup task.
-		// TODO: introduce a deterministic mechanism through which the different entities
-		// responsible for installing flows can notify the agent that this deletion
-		// operation can take place.
-		time.Sleep(10 * time.Second)
-		klog.Info("Deleting stale flows from previous round if any")
-		if err := i.ofClient.DeleteStaleFlows(); err != nil {
-			klog.Errorf("Error when deleting stale flows from previous round: %v", err)
-			return
-		}
-		persistRoundNum(roundInfo.RoundNum, i.ovsBridgeClient, 1*time.Second, maxRetryForRoundNumSave)
-	}()
-
 	go func() {
 		for {
 			if _, ok := <-ofConnCh; !ok {
@@ -375,6 +393,36 @@ func (i *Initializer) initOpenFlowPipeline() error {
 	return nil
 }
 
+// DeleteStaleFlows deletes the flows from the previous round once all the flows which are still
+// required have received an updated cookie (with the new round number), otherwise we would disrupt
+// the dataplane. The entities responsible for installing flows report it with the provided
+// functions, which return true once the flows they were responsible for when the agent started
+// have been installed. If they haven't converged within the timeout, the stale flows are deleted
+// anyway. The new round number is persisted to OVSDB afterwards.
+func (i *Initializer) DeleteStaleFlows(timeout time.Duration, hasSyncedFuncs ...func() bool) {
+	i.deleteStaleFlows(1*time.Second, timeout, hasSyncedFuncs...)
+}
+
+func (i *Initializer) deleteStaleFlows(interval, timeout time.Duration, hasSyncedFuncs ...func() bool) {
+	klog.Info("Waiting for the flows to be installed before deleting stale flows from previous round")
+	if err := wait.PollImmediate(interval, timeout, func() (bool, error) {
+		for _, hasSynced := range hasSyncedFuncs {
+			if !hasSynced() {
+				return false, nil
+			}
+		}
+		return true, nil
+	}); err != nil {
+		klog.Warningf("The flows were not all installed within %v, deleting stale flows from previous round anyway", timeout)
+	}
+	klog.Info("Deleting stale flows from previous round if any")
+	if err := i.ofClient.DeleteStaleFlows(); err != nil {
+		klog.Errorf("Error when deleting stale flows from previous round: %v", err)
+		return
+	}
+	persistRoundNum(i.roundInfo.RoundNum, i.ovsBridgeClient, 1*time.Second, maxRetryForRoundNumSave)
+}
+
 func (i *Initializer) FlowRestoreComplete() error {
 	// ovs-vswitchd is started with flow-restore-wait set to true for the following reasons:
 	// 1. It prevents packets from being mishandled by ovs-vswitchd in its default fashion,
@@ -412,9 +460,9 @@ func (i *Initializer) setupGatewayInterface() error {
 	// Idempotent operation to set the gateway's MTU: we perform this operation regardless of
 	// whether or not the gateway interface already exists, as the desired MTU may change across
 	// restarts.
-	klog.V(4).Infof("Setting gateway interface %s MTU to %d", i.hostGateway, i.mtu)
+	klog.V(4).Infof("Setting gateway interface %s MTU to %d", i.hostGateway, i.nodeConfig.NodeMTU)
 
-	i.ovsBridgeClient.SetInterfaceMTU(i.hostGateway, i.mtu)
+	i.ovsBridgeClient.SetInterfaceMTU(i.hostGateway, i.nodeConfig.NodeMTU)
 	if err := i.configureGatewayInterface(gatewayIface); err != nil {
 		return err
 	}
@@ -491,7 +539,8 @@ func (i *Initializer) setupDefaultTunnelInterface() error {
 			tunnelIface.TunnelInterfaceConfig.Type == i.networkConfig.TunnelType &&
 			tunnelIface.TunnelInterfaceConfig.LocalIP.Equal(localIP) {
 			klog.V(2).Infof("Tunnel port %s already exists on OVS bridge", tunnelPortName)
-			return nil
+			// The desired MTU may change across restarts.
+			return i.setTunnelInterfaceMTU(tunnelPortName)
 		}
 
 		if err := i.ovsBridgeClient.DeletePort(tunnelIface.PortUUID); err != nil {
@@ -522,10 +571,38 @@ func (i *Initializer) setupDefaultTunnelInterface() error {
 		tunnelIface = interfacestore.NewTunnelInterface(tunnelPortName, i.networkConfig.TunnelType, localIP)
 		tunnelIface.OVSPortConfig = &interfacestore.OVSPortConfig{tunnelPortUUID, config.DefaultTunOFPort}
 		i.ifaceStore.AddInterface(tunnelIface)
+		return i.setTunnelInterfaceMTU(tunnelPortName)
+	}
+	return nil
+}
+
+// setTunnelInterfaceMTU sets the MTU of the tunnel interface to the Pod MTU, so that the packets
+// forwarded to the tunnel are not larger than what the transport interface can carry once
+// encapsulated.
+func (i *Initializer) setTunnelInterfaceMTU(tunnelPortName string) error {
+	klog.V(4).Infof("Setting tunnel interface %s MTU to %d", tunnelPortName, i.nodeConfig.NodeMTU)
+	if err := i.ovsBridgeClient.SetInterfaceMTU(tunnelPortName, i.nodeConfig.NodeMTU); err != nil {
+		return fmt.Errorf("failed to set MTU of tunnel interface %s: %v", tunnelPortName, err)
 	}
 	return nil
 }
 
+// getNodeMTU returns the MTU to use for the gateway interface, the tunnel interface and the Pod
+// interfaces. If no MTU has been configured, it is derived from the MTU of the Node's transport
+// interface, which may use jumbo frames, by subtracting the encapsulation overhead. Every Node
+// derives its own MTU, so in a cluster where the transport interfaces of the Nodes have different
+// MTUs, the MTU should be configured explicitly to the smallest value.
+func (i *Initializer) getNodeMTU(transportMTU int) (int, error) {
+	if i.mtu != 0 {
+		return i.mtu, nil
+	}
+	mtu := transportMTU - i.networkConfig.CalculateMTUDeduction()
+	if mtu <= 0 {
+		return 0, fmt.Errorf("MTU %d of the transport interface is too small for the encapsulation overhead", transportMTU)
+	}
+	return mtu, nil
+}
+
 // initNodeLocalConfig retrieves node's subnet CIDR from node.spec.PodCIDR, which is used for IPAM and setup
 // host gateway interface.
 func (i *Initializer) initNodeLocalConfig() error {
@@ -547,13 +624,23 @@ func (i *Initializer) initNodeLocalConfig() error {
 	if err != nil {
 		return fmt.Errorf("failed to get local IPNet:  %v", err)
 	}
+	transportMTU, err := getTransportInterfaceMTU(ipAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get MTU of the transport interface: %v", err)
+	}
+	nodeMTU, err := i.getNodeMTU(transportMTU)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Using MTU %d for Pod interfaces (transport interface MTU %d)", nodeMTU, transportMTU)
 
 	i.nodeConfig = &config.NodeConfig{
 		Name:            nodeName,
 		OVSBridge:       i.ovsBridge,
 		DefaultTunName:  defaultTunInterfaceName,
 		NodeIPAddr:      localAddr,
-		UplinkNetConfig: new(config.AdapterNetConfig)}
+		UplinkNetConfig: new(config.AdapterNetConfig),
+		NodeMTU:         nodeMTU}
 
 	if i.networkConfig.TrafficEncapMode.IsNetworkPolicyOnly() {
 		return nil
@@ -608,6 +695,25 @@ func getLastRoundNum(bridgeClient ovsconfig.OVSBridgeClient) (uint64, error) {
 }
 
 func saveRoundNum(num uint64, bridgeClient ovsconfig.OVSBridgeClient) error {
+	return saveExternalID(roundNumKey, fmt.Sprint(num), bridgeClient)
+}
+
+// getLastPipelineVersion returns the OpenFlow pipeline version persisted in OVSDB, or an empty
+// string if there is none, e.g. because the flows were installed by an older agent.
+func getLastPipelineVersion(bridgeClient ovsconfig.OVSBridgeClient) (string, error) {
+	extIDs, ovsCfgErr := bridgeClient.GetExternalIDs()
+	if ovsCfgErr != nil {
+		return "", fmt.Errorf("error getting external IDs: %w", ovsCfgErr)
+	}
+	return extIDs[pipelineVersionKey], nil
+}
+
+func savePipelineVersion(version string, bridgeClient ovsconfig.OVSBridgeClient) error {
+	return saveExternalID(pipelineVersionKey, version, bridgeClient)
+}
+
+// saveExternalID sets the provided key in the external IDs of the bridge, keeping the other keys.
+func saveExternalID(key, value string, bridgeClient ovsconfig.OVSBridgeClient) error {
 	extIDs, ovsCfgErr := bridgeClient.GetExternalIDs()
 	if ovsCfgErr != nil {
 		return fmt.Errorf("error getting external IDs: %w", ovsCfgErr)
@@ -616,10 +722,25 @@ func saveRoundNum(num uint64, bridgeClient ovsconfig.OVSBridgeClient) error {
 	for k, v := range extIDs {
 		updatedExtIDs[k] = v
 	}
-	updatedExtIDs[roundNumKey] = fmt.Sprint(num)
+	updatedExtIDs[key] = value
 	return bridgeClient.SetExternalIDs(updatedExtIDs)
 }
 
+// needFlushFlows returns true if all the existing flows must be deleted before installing the
+// flows of this round, i.e. if they were installed with a different OpenFlow pipeline version and
+// skipPipelineFlush is not set.
+func needFlushFlows(lastPipelineVersion, pipelineVersion string, skipPipelineFlush bool) bool {
+	if lastPipelineVersion == pipelineVersion {
+		return false
+	}
+	if skipPipelineFlush {
+		klog.Warningf("OpenFlow pipeline version changed from %q to %q, keeping existing flows as requested", lastPipelineVersion, pipelineVersion)
+		return false
+	}
+	klog.Infof("OpenFlow pipeline version changed from %q to %q, deleting all existing flows", lastPipelineVersion, pipelineVersion)
+	return true
+}
+
 func getRoundInfo(bridgeClient ovsconfig.OVSBridgeClient) types.RoundInfo {
 	roundInfo := types.RoundInfo{}
 	num, err := getLastRoundNum(bridgeClient)
@@ -18,8 +18,18 @@ package agent
 
 import (
 	"net"
+
+	"github.com/vishvananda/netlink"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/util"
+	"github.com/vmware-tanzu/antrea/pkg/agent/util/hwoffload"
 )
 
+// ovsHWOffloadKey is the key in the "other_config" column of the Open_vSwitch table which enables
+// offloading datapath flows to the NIC with TC flower.
+const ovsHWOffloadKey = "hw-offload"
+
 // setupExternalConnectivity returns immediately on Linux. The corresponding functions are provided in routeClient.
 func (i *Initializer) setupExternalConnectivity() error {
 	return nil
@@ -45,3 +55,95 @@ func (i *Initializer) initHostNetworkFlows() error {
 func (i *Initializer) getTunnelPortLocalIP() net.IP {
 	return nil
 }
+
+// setupHWOffload enables hardware offload in OVS if the NIC backing the Node's uplink is in
+// switchdev mode and exposes representor ports. The NIC is not reconfigured by the agent. If it is
+// not ready for offload, the agent falls back to the software datapath.
+func (i *Initializer) setupHWOffload() error {
+	if !i.hwOffload {
+		return nil
+	}
+	_, link, err := util.GetIPNetDeviceFromIP(i.nodeConfig.NodeIPAddr.IP)
+	if err != nil {
+		return err
+	}
+	uplink := link.Attrs().Name
+	if err := hwoffload.CheckSwitchdev(uplink); err != nil {
+		klog.Warningf("Hardware offload is not supported on uplink %s, falling back to software datapath: %v", uplink, err)
+		return nil
+	}
+	otherConfig, ovsErr := i.ovsBridgeClient.GetOVSOtherConfig()
+	if ovsErr != nil {
+		return ovsErr
+	}
+	if value, ok := otherConfig[ovsHWOffloadKey]; ok {
+		if value == "true" {
+			return nil
+		}
+		// AddOVSOtherConfig does not overwrite existing keys.
+		if ovsErr := i.ovsBridgeClient.DeleteOVSOtherConfig(map[string]interface{}{ovsHWOffloadKey: value}); ovsErr != nil {
+			return ovsErr
+		}
+	}
+	if ovsErr := i.ovsBridgeClient.AddOVSOtherConfig(map[string]interface{}{ovsHWOffloadKey: "true"}); ovsErr != nil {
+		return ovsErr
+	}
+	// ovs-vswitchd only reads hw-offload at startup.
+	klog.Warningf("Enabled hardware offload on uplink %s, ovs-vswitchd must be restarted for it to take effect", uplink)
+	return nil
+}
+
+// getTransportInterfaceMTU returns the MTU of the interface which has the provided Node IP.
+func getTransportInterfaceMTU(nodeIP net.IP) (int, error) {
+	_, link, err := util.GetIPNetDeviceFromIP(nodeIP)
+	if err != nil {
+		return 0, err
+	}
+	return link.Attrs().MTU, nil
+}
+
+// MonitorTransportInterfaceMTU watches the netlink link updates of the Node's transport interface
+// and warns when its MTU changes, as the MTU derived from it at startup is then no longer correct.
+// The MTU of existing interfaces is not changed on the fly: the agent must be restarted, and the
+// existing Pods recreated, to use the new MTU. It returns immediately if the MTU was configured.
+func (i *Initializer) MonitorTransportInterfaceMTU(stopCh <-chan struct{}) {
+	if i.mtu != 0 {
+		return
+	}
+	_, link, err := util.GetIPNetDeviceFromIP(i.nodeConfig.NodeIPAddr.IP)
+	if err != nil {
+		klog.Errorf("Failed to get the transport interface, its MTU changes will not be detected: %v", err)
+		return
+	}
+	linkIndex, transportMTU := link.Attrs().Index, link.Attrs().MTU
+	updateCh := make(chan netlink.LinkUpdate)
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	if err := netlink.LinkSubscribe(updateCh, doneCh); err != nil {
+		klog.Errorf("Failed to subscribe to link updates, MTU changes of the transport interface will not be detected: %v", err)
+		return
+	}
+	for {
+		select {
+		case <-stopCh:
+			return
+		case update, ok := <-updateCh:
+			if !ok {
+				klog.Errorf("Link update channel closed, MTU changes of the transport interface will not be detected")
+				return
+			}
+			attrs := update.Link.Attrs()
+			if attrs.Index != linkIndex || attrs.MTU == transportMTU {
+				continue
+			}
+			newNodeMTU, err := i.getNodeMTU(attrs.MTU)
+			if err != nil {
+				klog.Errorf("MTU of transport interface %s changed from %d to %d: %v", attrs.Name, transportMTU, attrs.MTU, err)
+			} else {
+				klog.Warningf("MTU of transport interface %s changed from %d to %d, antrea-agent must be restarted to use MTU %d instead of %d for the gateway, tunnel and Pod interfaces",
+					attrs.Name, transportMTU, attrs.MTU, newNodeMTU, i.nodeConfig.NodeMTU)
+			}
+			transportMTU = attrs.MTU
+		}
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"net"
 	"testing"
+	"time"
 
 	mock "github.com/golang/mock/gomock"
 	"github.com/google/uuid"
@@ -26,6 +27,8 @@ import (
 	"github.com/vmware-tanzu/antrea/pkg/agent/cniserver"
 	"github.com/vmware-tanzu/antrea/pkg/agent/config"
 	"github.com/vmware-tanzu/antrea/pkg/agent/interfacestore"
+	openflowtest "github.com/vmware-tanzu/antrea/pkg/agent/openflow/testing"
+	"github.com/vmware-tanzu/antrea/pkg/agent/types"
 	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsconfig"
 	ovsconfigtest "github.com/vmware-tanzu/antrea/pkg/ovs/ovsconfig/testing"
 )
@@ -136,3 +139,219 @@ func TestGetRoundInfo(t *testing.T) {
 	roundInfo = getRoundInfo(mockOVSBridgeClient)
 	assert.Equal(t, uint64(initialRoundNum), roundInfo.RoundNum, "Unexpected round number")
 }
+
+func TestNeedFlushFlows(t *testing.T) {
+	tests := []struct {
+		name                string
+		lastPipelineVersion string
+		skipPipelineFlush   bool
+		expectedFlush       bool
+	}{
+		{
+			name:                "same version",
+			lastPipelineVersion: "v0.13.0-abcdef-0123456789abcdef",
+			expectedFlush:       false,
+		},
+		{
+			name:                "different version",
+			lastPipelineVersion: "v0.12.0-abcdef-0123456789abcdef",
+			expectedFlush:       true,
+		},
+		{
+			name:                "no persisted version",
+			lastPipelineVersion: "",
+			expectedFlush:       true,
+		},
+		{
+			name:                "different version with skip",
+			lastPipelineVersion: "v0.12.0-abcdef-0123456789abcdef",
+			skipPipelineFlush:   true,
+			expectedFlush:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedFlush, needFlushFlows(tt.lastPipelineVersion, "v0.13.0-abcdef-0123456789abcdef", tt.skipPipelineFlush))
+		})
+	}
+}
+
+func TestPipelineVersion(t *testing.T) {
+	const pipelineVersion = "v0.13.0-abcdef-0123456789abcdef"
+
+	controller := mock.NewController(t)
+	defer controller.Finish()
+	mockOVSBridgeClient := ovsconfigtest.NewMockOVSBridgeClient(controller)
+
+	mockOVSBridgeClient.EXPECT().GetExternalIDs().Return(map[string]string{roundNumKey: "1"}, nil)
+	version, err := getLastPipelineVersion(mockOVSBridgeClient)
+	assert.NoError(t, err)
+	assert.Empty(t, version)
+
+	// The other external IDs must be kept when the version is persisted.
+	mockOVSBridgeClient.EXPECT().GetExternalIDs().Return(map[string]string{roundNumKey: "1"}, nil)
+	newExternalIDs := map[string]interface{}{roundNumKey: "1", pipelineVersionKey: pipelineVersion}
+	mockOVSBridgeClient.EXPECT().SetExternalIDs(mock.Eq(newExternalIDs)).Return(nil)
+	assert.NoError(t, savePipelineVersion(pipelineVersion, mockOVSBridgeClient))
+
+	mockOVSBridgeClient.EXPECT().GetExternalIDs().Return(convertExternalIDMap(newExternalIDs), nil)
+	version, err = getLastPipelineVersion(mockOVSBridgeClient)
+	assert.NoError(t, err)
+	assert.Equal(t, pipelineVersion, version)
+
+	mockOVSBridgeClient.EXPECT().GetExternalIDs().Return(nil, ovsconfig.NewTransactionError(fmt.Errorf("Failed to get external IDs"), true))
+	_, err = getLastPipelineVersion(mockOVSBridgeClient)
+	assert.Error(t, err)
+}
+
+func TestDeleteStaleFlows(t *testing.T) {
+	const roundNum uint64 = 5555
+
+	tests := []struct {
+		name string
+		// syncedAfter is the number of calls after which hasSynced returns true,
+		// -1 means that it never returns true.
+		syncedAfter int
+	}{
+		{
+			name:        "synced",
+			syncedAfter: 3,
+		},
+		{
+			name:        "timeout",
+			syncedAfter: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := mock.NewController(t)
+			defer controller.Finish()
+			mockOVSBridgeClient := ovsconfigtest.NewMockOVSBridgeClient(controller)
+			mockOFClient := openflowtest.NewMockClient(controller)
+			initializer := &Initializer{
+				ovsBridgeClient: mockOVSBridgeClient,
+				ofClient:        mockOFClient,
+				roundInfo:       types.RoundInfo{RoundNum: roundNum},
+			}
+
+			calls := 0
+			hasSynced := func() bool {
+				calls++
+				return tt.syncedAfter >= 0 && calls >= tt.syncedAfter
+			}
+			// The stale flows must not be deleted before all the flows have been
+			// installed, or before the timeout expires.
+			mockOFClient.EXPECT().DeleteStaleFlows().Do(func() {
+				if tt.syncedAfter >= 0 {
+					assert.Equal(t, tt.syncedAfter, calls)
+				}
+			}).Return(nil).Times(1)
+			mockOVSBridgeClient.EXPECT().GetExternalIDs().Return(map[string]string{}, nil)
+			newExternalIDs := map[string]interface{}{roundNumKey: fmt.Sprint(roundNum)}
+			mockOVSBridgeClient.EXPECT().SetExternalIDs(mock.Eq(newExternalIDs)).Times(1)
+
+			initializer.deleteStaleFlows(10*time.Millisecond, 100*time.Millisecond, func() bool { return true }, hasSynced)
+		})
+	}
+}
+
+func TestGetNodeMTU(t *testing.T) {
+	tests := []struct {
+		name          string
+		configuredMTU int
+		networkConfig *config.NetworkConfig
+		transportMTU  int
+		expectedMTU   int
+		expectedErr   bool
+	}{
+		{
+			name:          "geneve",
+			networkConfig: &config.NetworkConfig{TrafficEncapMode: config.TrafficEncapModeEncap, TunnelType: ovsconfig.GeneveTunnel},
+			transportMTU:  1500,
+			expectedMTU:   1450,
+		},
+		{
+			name:          "vxlan",
+			networkConfig: &config.NetworkConfig{TrafficEncapMode: config.TrafficEncapModeEncap, TunnelType: ovsconfig.VXLANTunnel},
+			transportMTU:  1500,
+			expectedMTU:   1450,
+		},
+		{
+			name:          "gre-ipsec",
+			networkConfig: &config.NetworkConfig{TrafficEncapMode: config.TrafficEncapModeEncap, TunnelType: ovsconfig.GRETunnel, EnableIPSecTunnel: true},
+			transportMTU:  1500,
+			expectedMTU:   1424,
+		},
+		{
+			name:          "stt",
+			networkConfig: &config.NetworkConfig{TrafficEncapMode: config.TrafficEncapModeEncap, TunnelType: ovsconfig.STTTunnel},
+			transportMTU:  1500,
+			expectedMTU:   1500,
+		},
+		{
+			name:          "jumbo-frames",
+			networkConfig: &config.NetworkConfig{TrafficEncapMode: config.TrafficEncapModeEncap, TunnelType: ovsconfig.GeneveTunnel},
+			transportMTU:  9216,
+			expectedMTU:   9166,
+		},
+		{
+			name:          "hybrid",
+			networkConfig: &config.NetworkConfig{TrafficEncapMode: config.TrafficEncapModeHybrid, TunnelType: ovsconfig.VXLANTunnel},
+			transportMTU:  1500,
+			expectedMTU:   1450,
+		},
+		{
+			name:          "noencap",
+			networkConfig: &config.NetworkConfig{TrafficEncapMode: config.TrafficEncapModeNoEncap, TunnelType: ovsconfig.GeneveTunnel},
+			transportMTU:  1500,
+			expectedMTU:   1500,
+		},
+		{
+			name:          "configured",
+			configuredMTU: 1400,
+			networkConfig: &config.NetworkConfig{TrafficEncapMode: config.TrafficEncapModeEncap, TunnelType: ovsconfig.GeneveTunnel},
+			transportMTU:  9000,
+			expectedMTU:   1400,
+		},
+		{
+			name:          "too-small",
+			networkConfig: &config.NetworkConfig{TrafficEncapMode: config.TrafficEncapModeEncap, TunnelType: ovsconfig.GeneveTunnel},
+			transportMTU:  50,
+			expectedErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initializer := &Initializer{mtu: tt.configuredMTU, networkConfig: tt.networkConfig}
+			mtu, err := initializer.getNodeMTU(tt.transportMTU)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedMTU, mtu)
+		})
+	}
+}
+
+func TestSetupDefaultTunnelInterfaceMTU(t *testing.T) {
+	controller := mock.NewController(t)
+	defer controller.Finish()
+	mockOVSBridgeClient := ovsconfigtest.NewMockOVSBridgeClient(controller)
+
+	store := interfacestore.NewInterfaceStore()
+	initializer := newAgentInitializer(mockOVSBridgeClient, store)
+	initializer.networkConfig = &config.NetworkConfig{TrafficEncapMode: config.TrafficEncapModeEncap, TunnelType: ovsconfig.GeneveTunnel}
+	initializer.nodeConfig = &config.NodeConfig{
+		DefaultTunName: defaultTunInterfaceName,
+		NodeIPAddr:     &net.IPNet{IP: net.ParseIP("192.168.10.10"), Mask: net.CIDRMask(24, 32)},
+		NodeMTU:        8950,
+	}
+	tunnelIface := interfacestore.NewTunnelInterface(defaultTunInterfaceName, ovsconfig.GeneveTunnel, initializer.getTunnelPortLocalIP())
+	tunnelIface.OVSPortConfig = &interfacestore.OVSPortConfig{PortUUID: uuid.New().String(), OFPort: config.DefaultTunOFPort}
+	store.AddInterface(tunnelIface)
+
+	// The MTU must be set even if the tunnel port already exists, as it may change across restarts.
+	mockOVSBridgeClient.EXPECT().SetInterfaceMTU(defaultTunInterfaceName, 8950).Return(nil)
+	assert.NoError(t, initializer.setupDefaultTunnelInterface())
+}
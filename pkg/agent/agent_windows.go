@@ -187,3 +187,24 @@ func (i *Initializer) initHostNetworkFlows() error {
 func (i *Initializer) getTunnelPortLocalIP() net.IP {
 	return i.nodeConfig.NodeIPAddr.IP
 }
+
+// setupHWOffload only logs a warning on Windows, where OVS hardware offload is not supported.
+func (i *Initializer) setupHWOffload() error {
+	if i.hwOffload {
+		klog.Warning("Hardware offload is not supported on Windows, falling back to software datapath")
+	}
+	return nil
+}
+
+// getTransportInterfaceMTU returns the MTU of the interface which has the provided Node IP.
+func getTransportInterfaceMTU(nodeIP net.IP) (int, error) {
+	_, adapter, err := util.GetIPNetDeviceFromIP(nodeIP)
+	if err != nil {
+		return 0, err
+	}
+	return adapter.MTU, nil
+}
+
+// MonitorTransportInterfaceMTU returns immediately on Windows.
+func (i *Initializer) MonitorTransportInterfaceMTU(stopCh <-chan struct{}) {
+}
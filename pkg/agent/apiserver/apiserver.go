@@ -33,14 +33,23 @@ import (
 	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/addressgroup"
 	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/agentinfo"
 	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/appliedtogroup"
+	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/desiredflows"
 	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/networkpolicy"
+	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/networkpolicystats"
 	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/ovsflows"
 	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/ovstracing"
+	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/podflows"
 	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/podinterface"
+	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/proxystats"
+	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/servicestatus"
+	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/ovshealth"
+	"github.com/vmware-tanzu/antrea/pkg/agent/proxy"
 	agentquerier "github.com/vmware-tanzu/antrea/pkg/agent/querier"
 	systeminstall "github.com/vmware-tanzu/antrea/pkg/apis/system/install"
 	systemv1beta1 "github.com/vmware-tanzu/antrea/pkg/apis/system/v1beta1"
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/loglevel"
 	"github.com/vmware-tanzu/antrea/pkg/apiserver/registry/system/supportbundle"
+	"github.com/vmware-tanzu/antrea/pkg/features"
 	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
 	"github.com/vmware-tanzu/antrea/pkg/querier"
 	antreaversion "github.com/vmware-tanzu/antrea/pkg/version"
@@ -67,14 +76,20 @@ func (s *agentAPIServer) Run(stopCh <-chan struct{}) error {
 	return s.GenericAPIServer.PrepareRun().Run(stopCh)
 }
 
-func installHandlers(aq agentquerier.AgentQuerier, npq querier.AgentNetworkPolicyInfoQuerier, s *genericapiserver.GenericAPIServer) {
+func installHandlers(aq agentquerier.AgentQuerier, npq querier.AgentNetworkPolicyInfoQuerier, npsq querier.AgentNetworkPolicyStatsQuerier, psq proxy.StatsQuerier, ssq proxy.StatusQuerier, s *genericapiserver.GenericAPIServer) {
 	s.Handler.NonGoRestfulMux.HandleFunc("/agentinfo", agentinfo.HandleFunc(aq))
 	s.Handler.NonGoRestfulMux.HandleFunc("/podinterfaces", podinterface.HandleFunc(aq))
 	s.Handler.NonGoRestfulMux.HandleFunc("/networkpolicies", networkpolicy.HandleFunc(aq))
 	s.Handler.NonGoRestfulMux.HandleFunc("/appliedtogroups", appliedtogroup.HandleFunc(npq))
 	s.Handler.NonGoRestfulMux.HandleFunc("/addressgroups", addressgroup.HandleFunc(npq))
+	s.Handler.NonGoRestfulMux.HandleFunc("/networkpolicystats", networkpolicystats.HandleFunc(npsq))
 	s.Handler.NonGoRestfulMux.HandleFunc("/ovsflows", ovsflows.HandleFunc(aq))
 	s.Handler.NonGoRestfulMux.HandleFunc("/ovstracing", ovstracing.HandleFunc(aq))
+	s.Handler.NonGoRestfulMux.HandleFunc("/podflows", podflows.HandleFunc(aq))
+	s.Handler.NonGoRestfulMux.HandleFunc("/proxystats", proxystats.HandleFunc(psq))
+	s.Handler.NonGoRestfulMux.HandleFunc("/desiredflows", desiredflows.HandleFunc(aq))
+	s.Handler.NonGoRestfulMux.HandleFunc("/servicestatus", servicestatus.HandleFunc(ssq))
+	s.Handler.NonGoRestfulMux.HandleFunc("/debug/log-level", loglevel.HandleFunc())
 }
 
 func installAPIGroup(s *genericapiserver.GenericAPIServer, aq agentquerier.AgentQuerier, npq querier.AgentNetworkPolicyInfoQuerier) error {
@@ -87,8 +102,10 @@ func installAPIGroup(s *genericapiserver.GenericAPIServer, aq agentquerier.Agent
 	return s.InstallAPIGroup(&systemGroup)
 }
 
-// New creates an APIServer for running in antrea agent.
-func New(aq agentquerier.AgentQuerier, npq querier.AgentNetworkPolicyInfoQuerier, bindPort int,
+// New creates an APIServer for running in antrea agent. npsq and psq are nil if
+// the statistics of NetworkPolicies and AntreaProxy are not collected, and ssq
+// is nil if AntreaProxy is disabled.
+func New(aq agentquerier.AgentQuerier, npq querier.AgentNetworkPolicyInfoQuerier, npsq querier.AgentNetworkPolicyStatsQuerier, psq proxy.StatsQuerier, ssq proxy.StatusQuerier, bindPort int,
 	enableMetrics bool) (*agentAPIServer, error) {
 	cfg, err := newConfig(bindPort, enableMetrics)
 	if err != nil {
@@ -101,14 +118,19 @@ func New(aq agentquerier.AgentQuerier, npq querier.AgentNetworkPolicyInfoQuerier
 	if err := installAPIGroup(s, aq, npq); err != nil {
 		return nil, err
 	}
-	installHandlers(aq, npq, s)
+	installHandlers(aq, npq, npsq, psq, ssq, s)
+	// The OVS health check is served at "/healthz/ovs" and is also part of "/healthz".
+	ovsChecker := ovshealth.NewChecker(aq.GetOVSCtlClient(), aq.GetNodeConfig().OVSBridge, features.DefaultFeatureGate.Enabled(features.AntreaProxy))
+	if err := s.AddHealthChecks(ovsChecker); err != nil {
+		return nil, err
+	}
 	return &agentAPIServer{GenericAPIServer: s}, nil
 }
 
 func newConfig(bindPort int, enableMetrics bool) (*genericapiserver.CompletedConfig, error) {
 	secureServing := genericoptions.NewSecureServingOptions().WithLoopback()
 	authentication := genericoptions.NewDelegatingAuthenticationOptions()
-	authorization := genericoptions.NewDelegatingAuthorizationOptions().WithAlwaysAllowPaths("/healthz")
+	authorization := genericoptions.NewDelegatingAuthorizationOptions().WithAlwaysAllowPaths("/healthz", "/healthz/"+ovshealth.Name)
 
 	// Set the PairName but leave certificate directory blank to generate in-memory by default.
 	secureServing.ServerCert.CertDirectory = ""
@@ -0,0 +1,219 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package desiredflows
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/querier"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
+)
+
+var ctStateFlagRegexp = regexp.MustCompile(`[+-][a-z]+`)
+
+// Response is the response of the "/desiredflows" API.
+type Response struct {
+	// DesiredFlows are the flows the agent maintains for the current state,
+	// represented by their table, priority and match conditions.
+	DesiredFlows []string `json:"desiredFlows"`
+	// ActualFlows are the flows dumped from OVS, as printed by
+	// "ovs-ofctl dump-flows".
+	ActualFlows []string `json:"actualFlows"`
+	// Fixed is set if the diverged flows have been reconciled. The
+	// flows are dumped before the reconciliation.
+	Fixed bool `json:"fixed,omitempty"`
+}
+
+// FlowDiff is the difference between the desired flows and the actual flows.
+// The flows are normalized.
+type FlowDiff struct {
+	// Missing are the desired flows which are not in OVS.
+	Missing []string
+	// Unexpected are the flows in OVS which are not desired.
+	Unexpected []string
+}
+
+// normalizeValue returns the canonical form of a match value: numbers are
+// printed in hexadecimal, with their mask if any, and ct_state flags are
+// sorted. Other values, e.g. IP and MAC addresses, are returned unchanged.
+func normalizeValue(key, value string) string {
+	if key == "ct_state" {
+		flags := ctStateFlagRegexp.FindAllString(value, -1)
+		sort.Strings(flags)
+		return strings.Join(flags, "")
+	}
+	parts := strings.Split(value, "/")
+	if len(parts) > 2 {
+		return value
+	}
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 0, 64)
+		if err != nil {
+			return value
+		}
+		parts[i] = fmt.Sprintf("0x%x", n)
+	}
+	return strings.Join(parts, "/")
+}
+
+// NormalizeFlow returns the canonical form of a flow, given by its table,
+// priority and match conditions, or as printed by "ovs-ofctl dump-flows": the
+// table and priority come first, followed by the sorted match conditions. The
+// actions and the statistics are ignored. false is returned for the transient
+// flows which have a timeout, and for the flows which cannot be parsed.
+func NormalizeFlow(flow string) (string, bool) {
+	if i := strings.Index(flow, "actions="); i >= 0 {
+		flow = flow[:i]
+	}
+	parsed, err := ovsctl.ParseFlowMatch(flow)
+	if err != nil || parsed.IdleTimeout != 0 || parsed.HardTimeout != 0 {
+		return "", false
+	}
+	matches := make([]string, 0, len(parsed.Match))
+	for _, field := range parsed.Match {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) == 1 {
+			matches = append(matches, field)
+		} else {
+			matches = append(matches, kv[0]+"="+normalizeValue(kv[0], kv[1]))
+		}
+	}
+	sort.Strings(matches)
+	header := []string{fmt.Sprintf("table=%d", parsed.Table), fmt.Sprintf("priority=%d", parsed.Priority)}
+	return strings.Join(append(header, matches...), ","), true
+}
+
+// normalizeFlows returns the set of the normalized non-transient flows.
+func normalizeFlows(flows []string) map[string]bool {
+	set := make(map[string]bool, len(flows))
+	for _, flow := range flows {
+		if normalized, ok := NormalizeFlow(flow); ok {
+			set[normalized] = true
+		}
+	}
+	return set
+}
+
+// sortFlows sorts normalized flows by table, then by decreasing priority, then
+// by match conditions.
+func sortFlows(flows []string) {
+	key := func(flow string) (int, int, string) {
+		var table, priority int
+		fields := strings.SplitN(flow, ",", 3)
+		fmt.Sscanf(fields[0], "table=%d", &table)
+		fmt.Sscanf(fields[1], "priority=%d", &priority)
+		if len(fields) == 3 {
+			return table, priority, fields[2]
+		}
+		return table, priority, ""
+	}
+	sort.Slice(flows, func(i, j int) bool {
+		ti, pi, mi := key(flows[i])
+		tj, pj, mj := key(flows[j])
+		if ti != tj {
+			return ti < tj
+		}
+		if pi != pj {
+			return pi > pj
+		}
+		return mi < mj
+	})
+}
+
+// Diff compares the desired flows with the actual flows after normalizing them.
+func Diff(desiredFlows, actualFlows []string) *FlowDiff {
+	desired := normalizeFlows(desiredFlows)
+	actual := normalizeFlows(actualFlows)
+	diff := &FlowDiff{}
+	for flow := range desired {
+		if !actual[flow] {
+			diff.Missing = append(diff.Missing, flow)
+		}
+	}
+	for flow := range actual {
+		if !desired[flow] {
+			diff.Unexpected = append(diff.Unexpected, flow)
+		}
+	}
+	sortFlows(diff.Missing)
+	sortFlows(diff.Unexpected)
+	return diff
+}
+
+// dumpFlows returns the flows dumped from OVS.
+func dumpFlows(aq querier.AgentQuerier) ([]string, error) {
+	// Port numbers are printed instead of port names so that they can be
+	// compared with the desired flows.
+	flowDump, err := aq.GetOVSCtlClient().RunOfctlCmd("dump-flows", "--no-names")
+	if err != nil {
+		return nil, err
+	}
+	return ovsctl.SplitFlowDump(flowDump), nil
+}
+
+// fix reconciles the diverged flows: the missing flows are installed by
+// replaying all the desired flows, and the unexpected flows are deleted.
+func fix(aq querier.AgentQuerier, diff *FlowDiff) error {
+	if len(diff.Missing) > 0 {
+		aq.GetOpenflowClient().ReplayFlows()
+	}
+	for _, flow := range diff.Unexpected {
+		if _, err := aq.GetOVSCtlClient().RunOfctlCmd("del-flows", "--strict", flow); err != nil {
+			return fmt.Errorf("error when deleting flow %s: %w", flow, err)
+		}
+	}
+	return nil
+}
+
+// HandleFunc returns the function which can handle API requests to
+// "/desiredflows". A GET request returns the desired and the actual flows.
+// A POST request also reconciles the flows which diverged.
+func HandleFunc(aq querier.AgentQuerier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		actualFlows, err := dumpFlows(aq)
+		if err != nil {
+			klog.Errorf("Failed to dump flows: %v", err)
+			http.Error(w, "OVS flow dumping failed", http.StatusInternalServerError)
+			return
+		}
+		resp := Response{
+			DesiredFlows: aq.GetOpenflowClient().GetDesiredFlows(),
+			ActualFlows:  actualFlows,
+		}
+		if r.Method == http.MethodPost {
+			if err := fix(aq, Diff(resp.DesiredFlows, resp.ActualFlows)); err != nil {
+				klog.Errorf("Failed to reconcile flows: %v", err)
+				http.Error(w, "OVS flow reconciliation failed", http.StatusInternalServerError)
+				return
+			}
+			resp.Fixed = true
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package desiredflows
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	oftest "github.com/vmware-tanzu/antrea/pkg/agent/openflow/testing"
+	aqtest "github.com/vmware-tanzu/antrea/pkg/agent/querier/testing"
+	ovsctltest "github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl/testing"
+)
+
+const testFlowDump = `OFPST_FLOW reply (OF1.3) (xid=0x2):
+ cookie=0x1000000000000, duration=103.542s, table=0, n_packets=12, n_bytes=1024, reset_counts priority=190,in_port=3 actions=load:0x2->NXM_NX_REG0[0..15],resubmit(,10)
+ cookie=0x1000000000000, duration=103.540s, table=31, n_packets=0, n_bytes=0, reset_counts priority=190,ct_state=+inv+trk,ip actions=drop
+ cookie=0x1000000000000, duration=103.530s, table=70, n_packets=7, n_bytes=686, reset_counts priority=200,ip,reg0=0/0x80000,nw_dst=10.10.1.2 actions=dec_ttl,resubmit(,80)
+ cookie=0x1000000000000, duration=103.520s, table=105, n_packets=0, n_bytes=0, reset_counts priority=200,ct_mark=0x20,ip actions=resubmit(,110)
+ cookie=0x1000000000000, duration=103.520s, table=110, n_packets=0, n_bytes=0, reset_counts ip,nw_dst=10.10.1.99 actions=output:7
+ cookie=0x1000000000000, duration=3.5s, table=40, n_packets=0, n_bytes=0, hard_timeout=300, idle_age=3, priority=200,tcp,nw_src=10.10.1.2,nw_dst=10.96.0.10,tp_dst=80 actions=load:0xa0a0103->NXM_NX_REG3[],resubmit(,42)
+`
+
+var testDesiredFlows = []string{
+	"table=0,priority=190,in_port=3",
+	"table=31,priority=190,ct_state=+trk+inv,ip",
+	"table=70,priority=200,ip,reg0=0x0/0x80000,nw_dst=10.10.1.2",
+	"table=105,priority=200,ip,ct_mark=32",
+	"table=110,priority=200,ip,nw_dst=10.10.1.3",
+}
+
+func TestNormalizeFlow(t *testing.T) {
+	tests := []struct {
+		flow     string
+		expected string
+	}{
+		{"table=31,priority=190,ct_state=+trk+inv,ip", "table=31,priority=190,ct_state=+inv+trk,ip"},
+		{" cookie=0x1, duration=1.5s, table=31, n_packets=5, n_bytes=210, idle_age=8, priority=190,ct_state=+inv+trk,ip actions=drop", "table=31,priority=190,ct_state=+inv+trk,ip"},
+		{"table=105,priority=200,ip,ct_mark=32", "table=105,priority=200,ct_mark=0x20,ip"},
+		{"table=70,priority=200,reg0=0/0x80000", "table=70,priority=200,reg0=0x0/0x80000"},
+		{"table=70,priority=200,ip,nw_dst=10.10.0.0/24", "table=70,priority=200,ip,nw_dst=10.10.0.0/24"},
+		{" cookie=0x1, n_packets=0, ip actions=drop", "table=0,priority=32768,ip"},
+	}
+	for _, tt := range tests {
+		normalized, ok := NormalizeFlow(tt.flow)
+		assert.True(t, ok)
+		assert.Equal(t, tt.expected, normalized, tt.flow)
+	}
+	_, ok := NormalizeFlow(" cookie=0x1, table=40, hard_timeout=300, priority=200,tcp actions=drop")
+	assert.False(t, ok, "Transient flows should be skipped")
+}
+
+func TestDiff(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	actualFlows, err := dumpFlows(newQuerierWithDump(ctrl, testFlowDump))
+	require.NoError(t, err)
+	assert.Len(t, actualFlows, 6)
+	diff := Diff(testDesiredFlows, actualFlows)
+	assert.Equal(t, []string{"table=110,priority=200,ip,nw_dst=10.10.1.3"}, diff.Missing)
+	assert.Equal(t, []string{"table=110,priority=32768,ip,nw_dst=10.10.1.99"}, diff.Unexpected)
+
+	diff = Diff(testDesiredFlows, testDesiredFlows)
+	assert.Empty(t, diff.Missing)
+	assert.Empty(t, diff.Unexpected)
+}
+
+func TestSortFlows(t *testing.T) {
+	flows := []string{
+		"table=110,priority=200,ip",
+		"table=31,priority=190,ip",
+		"table=31,priority=200,ip",
+		"table=31,priority=200,arp",
+		"table=5,priority=0",
+	}
+	sortFlows(flows)
+	assert.Equal(t, []string{
+		"table=5,priority=0",
+		"table=31,priority=200,arp",
+		"table=31,priority=200,ip",
+		"table=31,priority=190,ip",
+		"table=110,priority=200,ip",
+	}, flows)
+}
+
+func newQuerierWithDump(ctrl *gomock.Controller, dump string) *aqtest.MockAgentQuerier {
+	q := aqtest.NewMockAgentQuerier(ctrl)
+	ovsctl := ovsctltest.NewMockOVSCtlClient(ctrl)
+	q.EXPECT().GetOVSCtlClient().Return(ovsctl).AnyTimes()
+	ovsctl.EXPECT().RunOfctlCmd("dump-flows", "--no-names").Return([]byte(dump), nil)
+	return q
+}
+
+func TestHandleFunc(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	q := newQuerierWithDump(ctrl, testFlowDump)
+	ofClient := oftest.NewMockClient(ctrl)
+	q.EXPECT().GetOpenflowClient().Return(ofClient).AnyTimes()
+	ofClient.EXPECT().GetDesiredFlows().Return(testDesiredFlows)
+
+	req, err := http.NewRequest(http.MethodGet, "", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	HandleFunc(q).ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp Response
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Equal(t, testDesiredFlows, resp.DesiredFlows)
+	assert.Len(t, resp.ActualFlows, 6)
+	assert.False(t, resp.Fixed)
+}
+
+func TestHandleFuncFix(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	q := aqtest.NewMockAgentQuerier(ctrl)
+	ovsctl := ovsctltest.NewMockOVSCtlClient(ctrl)
+	ofClient := oftest.NewMockClient(ctrl)
+	q.EXPECT().GetOVSCtlClient().Return(ovsctl).AnyTimes()
+	q.EXPECT().GetOpenflowClient().Return(ofClient).AnyTimes()
+	ovsctl.EXPECT().RunOfctlCmd("dump-flows", "--no-names").Return([]byte(testFlowDump), nil)
+	ofClient.EXPECT().GetDesiredFlows().Return(testDesiredFlows)
+	ofClient.EXPECT().ReplayFlows()
+	ovsctl.EXPECT().RunOfctlCmd("del-flows", "--strict", "table=110,priority=32768,ip,nw_dst=10.10.1.99").Return(nil, nil)
+
+	req, err := http.NewRequest(http.MethodPost, "", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	HandleFunc(q).ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp Response
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.True(t, resp.Fixed)
+
+	req, err = http.NewRequest(http.MethodDelete, "", nil)
+	require.NoError(t, err)
+	recorder = httptest.NewRecorder()
+	HandleFunc(q).ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicystats
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/vmware-tanzu/antrea/pkg/antctl/transform/networkpolicystats"
+	"github.com/vmware-tanzu/antrea/pkg/querier"
+)
+
+// HandleFunc returns the function which can handle queries issued by the
+// networkpolicystats command. sq is nil if the NetworkPolicy statistics are
+// not collected.
+func HandleFunc(sq querier.AgentNetworkPolicyStatsQuerier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if sq == nil {
+			http.Error(w, "NetworkPolicy statistics are not enabled", http.StatusNotFound)
+			return
+		}
+		name := r.URL.Query().Get("name")
+		ns := r.URL.Query().Get("namespace")
+
+		stats := []networkpolicystats.Response{}
+		for _, s := range sq.GetNetworkPolicyStats() {
+			if (len(name) == 0 || name == s.PolicyName) && (len(ns) == 0 || ns == s.PolicyNamespace) {
+				stats = append(stats, networkpolicystats.FromAgentStats(&s))
+			}
+		}
+
+		if len(name) > 0 && len(stats) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		err := json.NewEncoder(w).Encode(stats)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicystats
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware-tanzu/antrea/pkg/antctl/transform/networkpolicystats"
+	"github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1"
+)
+
+type fakeStatsQuerier struct {
+	stats []v1beta1.NetworkPolicyStats
+}
+
+func (q *fakeStatsQuerier) GetNetworkPolicyStats() []v1beta1.NetworkPolicyStats {
+	return q.stats
+}
+
+var testStats = []v1beta1.NetworkPolicyStats{
+	{
+		PolicyUID:       "uid1",
+		PolicyName:      "np1",
+		PolicyNamespace: "namespaceA",
+		Ingress:         v1beta1.TrafficStats{PacketCount: 10, ByteCount: 1000, SessionCount: 10},
+	},
+	{
+		PolicyUID:       "uid2",
+		PolicyName:      "np1",
+		PolicyNamespace: "namespaceB",
+		Egress:          v1beta1.TrafficStats{PacketCount: 5, ByteCount: 500},
+	},
+	{
+		PolicyUID:  "uid3",
+		PolicyName: "cnp1",
+		Ingress:    v1beta1.TrafficStats{PacketCount: 1, ByteCount: 100, SessionCount: 1},
+	},
+}
+
+var responses = []networkpolicystats.Response{
+	{
+		UID:       "uid1",
+		Namespace: "namespaceA",
+		Name:      "np1",
+		Ingress:   v1beta1.TrafficStats{PacketCount: 10, ByteCount: 1000, SessionCount: 10},
+	},
+	{
+		UID:       "uid2",
+		Namespace: "namespaceB",
+		Name:      "np1",
+		Egress:    v1beta1.TrafficStats{PacketCount: 5, ByteCount: 500},
+	},
+	{
+		UID:     "uid3",
+		Name:    "cnp1",
+		Ingress: v1beta1.TrafficStats{PacketCount: 1, ByteCount: 100, SessionCount: 1},
+	},
+}
+
+func TestNetworkPolicyStatsQuery(t *testing.T) {
+	testcases := map[string]struct {
+		query           string
+		expectedStatus  int
+		expectedContent []networkpolicystats.Response
+	}{
+		"List all NetworkPolicies": {
+			query:           "",
+			expectedStatus:  http.StatusOK,
+			expectedContent: responses,
+		},
+		"Hit NetworkPolicy query, namespace provided": {
+			query:           "?name=np1&&namespace=namespaceB",
+			expectedStatus:  http.StatusOK,
+			expectedContent: []networkpolicystats.Response{responses[1]},
+		},
+		"Hit NetworkPolicy query, namespace not provided": {
+			query:           "?name=np1",
+			expectedStatus:  http.StatusOK,
+			expectedContent: responses[:2],
+		},
+		"Miss NetworkPolicy query": {
+			query:          "?name=np2",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	handler := HandleFunc(&fakeStatsQuerier{stats: testStats})
+	for k, tc := range testcases {
+		req, err := http.NewRequest(http.MethodGet, tc.query, nil)
+		assert.Nil(t, err)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, tc.expectedStatus, recorder.Code, k)
+
+		if tc.expectedStatus == http.StatusOK {
+			var received []networkpolicystats.Response
+			err = json.Unmarshal(recorder.Body.Bytes(), &received)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedContent, received, k)
+		}
+	}
+}
+
+func TestNetworkPolicyStatsDisabled(t *testing.T) {
+	handler := HandleFunc(nil)
+	req, err := http.NewRequest(http.MethodGet, "", nil)
+	assert.Nil(t, err)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
@@ -0,0 +1,171 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podflows
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/agent/querier"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/transform/common"
+	binding "github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
+)
+
+// Response describes an OVS flow matching the OpenFlow port of a Pod.
+type Response struct {
+	Table    uint8  `json:"table"`
+	Priority uint16 `json:"priority"`
+	Match    string `json:"match,omitempty"`
+	Actions  string `json:"actions"`
+	Packets  uint64 `json:"packets"`
+	Bytes    uint64 `json:"bytes"`
+}
+
+// parseFlow parses a flow printed by "ovs-ofctl dump-flows".
+func parseFlow(flowStr string) (*Response, error) {
+	flow, err := ovsctl.ParseDumpedFlow(flowStr)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		Table:    flow.Table,
+		Priority: flow.Priority,
+		Match:    strings.Join(flow.Match, ","),
+		Actions:  flow.Actions,
+		Packets:  flow.Packets,
+		Bytes:    flow.Bytes,
+	}, nil
+}
+
+// matchesPort returns true if the flow matches packets received from the port,
+// or outputs packets to the port.
+func (r *Response) matchesPort(inPortMatch string, outputRegexp *regexp.Regexp) bool {
+	for _, m := range strings.Split(r.Match, ",") {
+		if m == inPortMatch {
+			return true
+		}
+	}
+	return outputRegexp.MatchString(r.Actions)
+}
+
+// getPodFlows returns the flows of the table matching the OpenFlow port of the
+// Pod. nil is returned if the Pod is not found.
+func getPodFlows(aq querier.AgentQuerier, namespace, name string, table binding.TableIDType) ([]Response, error) {
+	interfaces := aq.GetInterfaceStore().GetContainerInterfacesByPod(name, namespace)
+	if len(interfaces) == 0 || interfaces[0].OVSPortConfig == nil {
+		return nil, nil
+	}
+	ofPort := interfaces[0].OFPort
+	inPortMatch := fmt.Sprintf("in_port=%d", ofPort)
+	outputRegexp := regexp.MustCompile(fmt.Sprintf(`(^|[,(])output:%d($|[,)])`, ofPort))
+
+	// Port numbers are printed instead of port names so that they can be
+	// matched.
+	args := []string{"--no-names"}
+	if table != binding.TableIDAll {
+		args = append(args, fmt.Sprintf("table=%d", table))
+	}
+	flowDump, err := aq.GetOVSCtlClient().RunOfctlCmd("dump-flows", args...)
+	if err != nil {
+		return nil, err
+	}
+	flows := []Response{}
+	for _, line := range ovsctl.SplitFlowDump(flowDump) {
+		flow, err := parseFlow(line)
+		if err != nil {
+			return nil, err
+		}
+		if flow.matchesPort(inPortMatch, outputRegexp) {
+			flows = append(flows, *flow)
+		}
+	}
+	return flows, nil
+}
+
+// parseTable returns the number of a table given by name or number, or
+// TableIDAll if the table does not exist.
+func parseTable(table string) binding.TableIDType {
+	if n, err := strconv.ParseUint(table, 10, 8); err == nil {
+		if openflow.GetFlowTableName(binding.TableIDType(n)) == "" {
+			return binding.TableIDAll
+		}
+		return binding.TableIDType(n)
+	}
+	return openflow.GetFlowTableNumber(table)
+}
+
+// HandleFunc returns the function which can handle API requests to "/podflows".
+func HandleFunc(aq querier.AgentQuerier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pod := r.URL.Query().Get("pod")
+		table := r.URL.Query().Get("table")
+
+		podRef := strings.Split(pod, "/")
+		if len(podRef) != 2 || podRef[0] == "" || podRef[1] == "" {
+			http.Error(w, "pod must be provided as <namespace>/<name>", http.StatusBadRequest)
+			return
+		}
+		tableID := binding.TableIDAll
+		if table != "" {
+			if tableID = parseTable(table); tableID == binding.TableIDAll {
+				http.Error(w, "invalid table name or number", http.StatusBadRequest)
+				return
+			}
+		}
+
+		flows, err := getPodFlows(aq, podRef[0], podRef[1], tableID)
+		if err != nil {
+			klog.Errorf("Failed to dump flows of Pod %s: %v", pod, err)
+			http.Error(w, "OVS flow dumping failed", http.StatusInternalServerError)
+			return
+		}
+		if flows == nil {
+			http.Error(w, fmt.Sprintf("Pod %s not found on this Node", pod), http.StatusNotFound)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(flows); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+}
+
+var _ common.TableOutput = new(Response)
+
+func (r Response) GetTableHeader() []string {
+	return []string{"TABLE", "PRIORITY", "MATCH", "ACTIONS", "PACKETS", "BYTES"}
+}
+
+func (r Response) GetTableRow(maxColumnLength int) []string {
+	return []string{
+		strconv.Itoa(int(r.Table)),
+		strconv.Itoa(int(r.Priority)),
+		r.Match,
+		r.Actions,
+		strconv.FormatUint(r.Packets, 10),
+		strconv.FormatUint(r.Bytes, 10),
+	}
+}
+
+func (r Response) SortRows() bool {
+	return false
+}
@@ -0,0 +1,156 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podflows
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/interfacestore"
+	interfacestoretest "github.com/vmware-tanzu/antrea/pkg/agent/interfacestore/testing"
+	aqtest "github.com/vmware-tanzu/antrea/pkg/agent/querier/testing"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
+	ovsctltest "github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl/testing"
+)
+
+const testFlowDump = ` cookie=0x1000000000000, duration=103.542s, table=0, n_packets=12, n_bytes=1024, idle_age=3, priority=190,in_port=3 actions=load:0x2->NXM_NX_REG0[0..15],resubmit(,10)
+ cookie=0x1000000000000, duration=103.542s, table=0, n_packets=0, n_bytes=0, idle_age=103, priority=190,in_port=31 actions=resubmit(,10)
+ cookie=0x1000000000000, duration=103.540s, table=10, n_packets=5, n_bytes=210, idle_age=8, priority=200,arp,in_port=3,arp_spa=10.10.1.2,arp_sha=aa:bb:cc:dd:ee:ff actions=resubmit(,20)
+ cookie=0x1000000000000, duration=103.530s, table=80, n_packets=7, n_bytes=686, idle_age=3, priority=200,dl_dst=aa:bb:cc:dd:ee:ff actions=load:0x3->NXM_NX_REG1[],load:0x1->NXM_NX_REG0[16],resubmit(,90)
+ cookie=0x1000000000000, duration=103.520s, table=110, n_packets=0, n_bytes=0, idle_age=103, ip,nw_dst=10.10.1.2 actions=output:3
+ cookie=0x1000000000000, duration=103.520s, table=110, n_packets=0, n_bytes=0, idle_age=103, priority=200,ip actions=output:33
+`
+
+const testTable0FlowDump = ` cookie=0x1000000000000, duration=103.542s, table=0, n_packets=12, n_bytes=1024, idle_age=3, priority=190,in_port=3 actions=load:0x2->NXM_NX_REG0[0..15],resubmit(,10)
+ cookie=0x1000000000000, duration=103.542s, table=0, n_packets=0, n_bytes=0, idle_age=103, priority=190,in_port=31 actions=resubmit(,10)
+`
+
+func TestParseFlow(t *testing.T) {
+	flow, err := parseFlow(" cookie=0x1, duration=1.5s, table=10, n_packets=5, n_bytes=210, idle_age=8, priority=200,arp,in_port=3 actions=ct(commit,table=20),output:3")
+	require.NoError(t, err)
+	assert.Equal(t, &Response{
+		Table:    10,
+		Priority: 200,
+		Match:    "arp,in_port=3",
+		Actions:  "ct(commit,table=20),output:3",
+		Packets:  5,
+		Bytes:    210,
+	}, flow)
+
+	_, err = parseFlow(" cookie=0x1, table=10, priority=200,arp")
+	assert.Error(t, err)
+	_, err = parseFlow(" cookie=0x1, table=300, priority=200,arp actions=drop")
+	assert.Error(t, err)
+}
+
+func TestPodFlows(t *testing.T) {
+	testInterface := &interfacestore.InterfaceConfig{
+		InterfaceName: "pod1-6d3a5e",
+		OVSPortConfig: &interfacestore.OVSPortConfig{OFPort: 3},
+	}
+	tests := []struct {
+		name           string
+		query          string
+		podName        string
+		interfaces     []*interfacestore.InterfaceConfig
+		dumpArgs       []interface{}
+		dump           string
+		expectedStatus int
+		expectedFlows  []Response
+	}{
+		{
+			name:           "all tables",
+			query:          "?pod=ns1/pod1",
+			podName:        "pod1",
+			interfaces:     []*interfacestore.InterfaceConfig{testInterface},
+			dumpArgs:       []interface{}{"--no-names"},
+			dump:           testFlowDump,
+			expectedStatus: http.StatusOK,
+			expectedFlows: []Response{
+				{Table: 0, Priority: 190, Match: "in_port=3", Actions: "load:0x2->NXM_NX_REG0[0..15],resubmit(,10)", Packets: 12, Bytes: 1024},
+				{Table: 10, Priority: 200, Match: "arp,in_port=3,arp_spa=10.10.1.2,arp_sha=aa:bb:cc:dd:ee:ff", Actions: "resubmit(,20)", Packets: 5, Bytes: 210},
+				{Table: 110, Priority: ovsctl.DefaultFlowPriority, Match: "ip,nw_dst=10.10.1.2", Actions: "output:3"},
+			},
+		},
+		{
+			name:           "table name",
+			query:          "?pod=ns1/pod1&table=Classification",
+			podName:        "pod1",
+			interfaces:     []*interfacestore.InterfaceConfig{testInterface},
+			dumpArgs:       []interface{}{"--no-names", "table=0"},
+			dump:           testTable0FlowDump,
+			expectedStatus: http.StatusOK,
+			expectedFlows: []Response{
+				{Table: 0, Priority: 190, Match: "in_port=3", Actions: "load:0x2->NXM_NX_REG0[0..15],resubmit(,10)", Packets: 12, Bytes: 1024},
+			},
+		},
+		{
+			name:           "non-existing Pod",
+			query:          "?pod=ns1/pod2",
+			podName:        "pod2",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			i := interfacestoretest.NewMockInterfaceStore(ctrl)
+			q := aqtest.NewMockAgentQuerier(ctrl)
+			q.EXPECT().GetInterfaceStore().Return(i)
+			i.EXPECT().GetContainerInterfacesByPod(tt.podName, "ns1").Return(tt.interfaces)
+			if tt.dumpArgs != nil {
+				ovsctl := ovsctltest.NewMockOVSCtlClient(ctrl)
+				q.EXPECT().GetOVSCtlClient().Return(ovsctl)
+				ovsctl.EXPECT().RunOfctlCmd("dump-flows", tt.dumpArgs...).Return([]byte(tt.dump), nil)
+			}
+
+			req, err := http.NewRequest(http.MethodGet, tt.query, nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			HandleFunc(q).ServeHTTP(recorder, req)
+			require.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var flows []Response
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &flows))
+				assert.Equal(t, tt.expectedFlows, flows)
+			}
+		})
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	badRequests := map[string]string{
+		"No Pod":                    "",
+		"Pod without Namespace":     "?pod=pod1",
+		"Empty Pod name":            "?pod=ns1/",
+		"Non-existing table number": "?pod=ns1/pod1&table=123",
+		"Non-existing table name":   "?pod=ns1/pod1&table=notexist",
+	}
+	handler := HandleFunc(nil)
+	for k, r := range badRequests {
+		req, err := http.NewRequest(http.MethodGet, r, nil)
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, k)
+	}
+}
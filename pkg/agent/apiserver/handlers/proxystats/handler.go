@@ -0,0 +1,122 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxystats
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/proxy"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/transform/common"
+)
+
+// EndpointResponse describes the statistics of an Endpoint of a Service port.
+type EndpointResponse struct {
+	Endpoint        string `json:"endpoint"`
+	Packets         uint64 `json:"packets"`
+	Bytes           uint64 `json:"bytes"`
+	CumulativeBytes uint64 `json:"cumulativeBytes"`
+}
+
+// Response describes the response struct of proxystats command.
+type Response struct {
+	Namespace       string             `json:"namespace"`
+	Name            string             `json:"name" antctl:"name,Name of the Service"`
+	Port            string             `json:"port,omitempty"`
+	Protocol        string             `json:"protocol"`
+	Packets         uint64             `json:"packets"`
+	Bytes           uint64             `json:"bytes"`
+	CumulativeBytes uint64             `json:"cumulativeBytes"`
+	Endpoints       []EndpointResponse `json:"endpoints,omitempty"`
+}
+
+func generateResponse(s *proxy.ServiceStats) Response {
+	r := Response{
+		Namespace:       s.Namespace,
+		Name:            s.Name,
+		Port:            s.Port,
+		Protocol:        s.Protocol,
+		Packets:         s.Packets,
+		Bytes:           s.Bytes,
+		CumulativeBytes: s.CumulativeBytes,
+	}
+	for _, e := range s.Endpoints {
+		r.Endpoints = append(r.Endpoints, EndpointResponse{
+			Endpoint:        e.Endpoint,
+			Packets:         e.Packets,
+			Bytes:           e.Bytes,
+			CumulativeBytes: e.CumulativeBytes,
+		})
+	}
+	return r
+}
+
+// HandleFunc returns the function which can handle queries issued by the
+// proxystats command. sq is nil if AntreaProxy or its statistics collection
+// is disabled.
+func HandleFunc(sq proxy.StatsQuerier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if sq == nil {
+			http.Error(w, "AntreaProxy statistics are not enabled", http.StatusNotFound)
+			return
+		}
+		name := r.URL.Query().Get("name")
+		ns := r.URL.Query().Get("namespace")
+
+		stats := []Response{}
+		for _, s := range sq.GetServiceStats() {
+			if (len(name) == 0 || name == s.Name) && (len(ns) == 0 || ns == s.Namespace) {
+				stats = append(stats, generateResponse(&s))
+			}
+		}
+
+		if len(name) > 0 && len(stats) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		err := json.NewEncoder(w).Encode(stats)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+}
+
+var _ common.TableOutput = new(Response)
+
+func (r Response) GetTableHeader() []string {
+	return []string{"NAMESPACE", "NAME", "PORT", "PROTOCOL", "PACKETS", "BYTES", "CUMULATIVE-BYTES", "ENDPOINTS"}
+}
+
+func (r Response) GetTableRow(maxColumnLength int) []string {
+	endpoints := make([]string, 0, len(r.Endpoints))
+	for _, e := range r.Endpoints {
+		endpoints = append(endpoints, e.Endpoint)
+	}
+	return []string{
+		r.Namespace,
+		r.Name,
+		r.Port,
+		r.Protocol,
+		strconv.FormatUint(r.Packets, 10),
+		strconv.FormatUint(r.Bytes, 10),
+		strconv.FormatUint(r.CumulativeBytes, 10),
+		common.GenerateTableElementWithSummary(endpoints, maxColumnLength),
+	}
+}
+
+func (r Response) SortRows() bool {
+	return true
+}
@@ -0,0 +1,139 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxystats
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/proxy"
+)
+
+type fakeStatsQuerier struct {
+	stats []proxy.ServiceStats
+}
+
+func (q *fakeStatsQuerier) GetServiceStats() []proxy.ServiceStats {
+	return q.stats
+}
+
+var testStats = []proxy.ServiceStats{
+	{
+		Namespace:       "namespaceA",
+		Name:            "svc0",
+		Port:            "http",
+		Protocol:        "TCP",
+		Packets:         5,
+		Bytes:           500,
+		CumulativeBytes: 800,
+		Endpoints: []proxy.EndpointStats{
+			{Endpoint: "10.10.0.1:80", Packets: 3, Bytes: 300, CumulativeBytes: 600},
+			{Endpoint: "10.10.0.2:80", Packets: 2, Bytes: 200, CumulativeBytes: 200},
+		},
+	},
+	{
+		Namespace: "namespaceB",
+		Name:      "svc0",
+		Port:      "dns",
+		Protocol:  "UDP",
+		Endpoints: []proxy.EndpointStats{
+			{Endpoint: "10.10.1.1:53"},
+		},
+	},
+}
+
+var responses = []Response{
+	{
+		Namespace:       "namespaceA",
+		Name:            "svc0",
+		Port:            "http",
+		Protocol:        "TCP",
+		Packets:         5,
+		Bytes:           500,
+		CumulativeBytes: 800,
+		Endpoints: []EndpointResponse{
+			{Endpoint: "10.10.0.1:80", Packets: 3, Bytes: 300, CumulativeBytes: 600},
+			{Endpoint: "10.10.0.2:80", Packets: 2, Bytes: 200, CumulativeBytes: 200},
+		},
+	},
+	{
+		Namespace: "namespaceB",
+		Name:      "svc0",
+		Port:      "dns",
+		Protocol:  "UDP",
+		Endpoints: []EndpointResponse{
+			{Endpoint: "10.10.1.1:53"},
+		},
+	},
+}
+
+func TestProxyStatsQuery(t *testing.T) {
+	testcases := map[string]struct {
+		query           string
+		expectedStatus  int
+		expectedContent []Response
+	}{
+		"List all Services": {
+			query:           "",
+			expectedStatus:  http.StatusOK,
+			expectedContent: responses,
+		},
+		"Hit Service query, namespace provided": {
+			query:           "?name=svc0&&namespace=namespaceB",
+			expectedStatus:  http.StatusOK,
+			expectedContent: []Response{responses[1]},
+		},
+		"Hit Service query, namespace not provided": {
+			query:           "?name=svc0",
+			expectedStatus:  http.StatusOK,
+			expectedContent: responses,
+		},
+		"Miss Service query": {
+			query:          "?name=svc1",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	handler := HandleFunc(&fakeStatsQuerier{stats: testStats})
+	for k, tc := range testcases {
+		req, err := http.NewRequest(http.MethodGet, tc.query, nil)
+		assert.Nil(t, err)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, tc.expectedStatus, recorder.Code, k)
+
+		if tc.expectedStatus == http.StatusOK {
+			var received []Response
+			err = json.Unmarshal(recorder.Body.Bytes(), &received)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedContent, received, k)
+		}
+	}
+}
+
+func TestProxyStatsDisabled(t *testing.T) {
+	handler := HandleFunc(nil)
+	req, err := http.NewRequest(http.MethodGet, "", nil)
+	assert.Nil(t, err)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
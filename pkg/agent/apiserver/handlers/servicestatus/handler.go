@@ -0,0 +1,127 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicestatus
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/proxy"
+)
+
+// EndpointResponse describes the state of an Endpoint of a Service port.
+type EndpointResponse struct {
+	IP           string `json:"endpointIP"`
+	Port         int    `json:"port"`
+	Protocol     string `json:"protocol"`
+	Node         string `json:"node,omitempty"`
+	Ready        bool   `json:"ready"`
+	OwnedBySlice bool   `json:"ownedBySlice"`
+	// Installed is true if the flows of the Endpoint are installed according
+	// to the caches of AntreaProxy, and NATFlowInstalled if its DNAT flow is
+	// found in OVS.
+	Installed        bool `json:"installed"`
+	NATFlowInstalled bool `json:"natFlowInstalled"`
+}
+
+// GroupResponse describes an OVS group of a Service port.
+type GroupResponse struct {
+	GroupID   uint32   `json:"groupID"`
+	NodeLocal bool     `json:"nodeLocal,omitempty"`
+	Entry     string   `json:"entry,omitempty"`
+	Buckets   []string `json:"buckets,omitempty"`
+}
+
+// Response describes the response struct of the servicestatus API.
+type Response struct {
+	Namespace string             `json:"namespace"`
+	Name      string             `json:"name"`
+	Port      string             `json:"port,omitempty"`
+	Protocol  string             `json:"protocol"`
+	ClusterIP string             `json:"clusterIP"`
+	Installed bool               `json:"installed"`
+	Groups    []GroupResponse    `json:"groups,omitempty"`
+	Endpoints []EndpointResponse `json:"endpoints,omitempty"`
+}
+
+func generateResponse(s *proxy.ServicePortStatus) Response {
+	r := Response{
+		Namespace: s.Namespace,
+		Name:      s.Name,
+		Port:      s.Port,
+		Protocol:  s.Protocol,
+		ClusterIP: s.ClusterIP,
+		Installed: s.Installed,
+	}
+	for _, g := range s.Groups {
+		r.Groups = append(r.Groups, GroupResponse{
+			GroupID:   uint32(g.GroupID),
+			NodeLocal: g.NodeLocal,
+			Entry:     g.Entry,
+			Buckets:   g.Buckets,
+		})
+	}
+	for _, e := range s.Endpoints {
+		r.Endpoints = append(r.Endpoints, EndpointResponse{
+			IP:               e.IP,
+			Port:             e.Port,
+			Protocol:         e.Protocol,
+			Node:             e.Node,
+			Ready:            e.Ready,
+			OwnedBySlice:     e.OwnedBySlice,
+			Installed:        e.Installed,
+			NATFlowInstalled: e.NATFlowInstalled,
+		})
+	}
+	return r
+}
+
+// HandleFunc returns the function which can handle queries issued by the
+// endpoint-status and service-lb-status commands. sq is nil if AntreaProxy is
+// disabled.
+func HandleFunc(sq proxy.StatusQuerier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if sq == nil {
+			http.Error(w, "AntreaProxy is not enabled", http.StatusNotFound)
+			return
+		}
+		name := r.URL.Query().Get("name")
+		ns := r.URL.Query().Get("namespace")
+		if name == "" || ns == "" {
+			http.Error(w, "the Namespace and name of the Service must be provided", http.StatusBadRequest)
+			return
+		}
+
+		statuses, err := sq.GetServiceStatus(ns, name)
+		if err != nil {
+			klog.Errorf("Error when getting the status of Service %s/%s: %v", ns, name, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(statuses) == 0 {
+			http.Error(w, "Service "+ns+"/"+name+" is not known by AntreaProxy", http.StatusNotFound)
+			return
+		}
+		resp := make([]Response, 0, len(statuses))
+		for i := range statuses {
+			resp = append(resp, generateResponse(&statuses[i]))
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicestatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/proxy"
+)
+
+type fakeStatusQuerier struct {
+	statuses map[string][]proxy.ServicePortStatus
+	err      error
+}
+
+func (q *fakeStatusQuerier) GetServiceStatus(namespace, name string) ([]proxy.ServicePortStatus, error) {
+	return q.statuses[namespace+"/"+name], q.err
+}
+
+var testStatuses = map[string][]proxy.ServicePortStatus{
+	"namespaceA/svc0": {
+		{
+			Namespace: "namespaceA",
+			Name:      "svc0",
+			Port:      "http",
+			Protocol:  "TCP",
+			ClusterIP: "10.96.0.10",
+			Installed: true,
+			Groups: []proxy.GroupStatus{
+				{
+					GroupID: 3,
+					Entry:   "group_id=3,type=select",
+					Buckets: []string{"bucket_id:0,weight:100,actions=load:0xa0a0001->NXM_NX_REG3[],load:0x50->NXM_NX_REG4[0..15],resubmit(,42)"},
+				},
+			},
+			Endpoints: []proxy.EndpointStatus{
+				{IP: "10.10.0.1", Port: 80, Protocol: "TCP", Node: "node1", Ready: true, Installed: true, NATFlowInstalled: true},
+				{IP: "10.10.1.1", Port: 80, Protocol: "TCP", Node: "node2", Ready: true, Installed: true},
+			},
+		},
+	},
+}
+
+var testResponses = []Response{
+	{
+		Namespace: "namespaceA",
+		Name:      "svc0",
+		Port:      "http",
+		Protocol:  "TCP",
+		ClusterIP: "10.96.0.10",
+		Installed: true,
+		Groups: []GroupResponse{
+			{
+				GroupID: 3,
+				Entry:   "group_id=3,type=select",
+				Buckets: []string{"bucket_id:0,weight:100,actions=load:0xa0a0001->NXM_NX_REG3[],load:0x50->NXM_NX_REG4[0..15],resubmit(,42)"},
+			},
+		},
+		Endpoints: []EndpointResponse{
+			{IP: "10.10.0.1", Port: 80, Protocol: "TCP", Node: "node1", Ready: true, Installed: true, NATFlowInstalled: true},
+			{IP: "10.10.1.1", Port: 80, Protocol: "TCP", Node: "node2", Ready: true, Installed: true},
+		},
+	},
+}
+
+func TestServiceStatusQuery(t *testing.T) {
+	testcases := map[string]struct {
+		query           string
+		err             error
+		expectedStatus  int
+		expectedContent []Response
+	}{
+		"Hit Service query": {
+			query:           "?name=svc0&namespace=namespaceA",
+			expectedStatus:  http.StatusOK,
+			expectedContent: testResponses,
+		},
+		"Miss Service query": {
+			query:          "?name=svc1&namespace=namespaceA",
+			expectedStatus: http.StatusNotFound,
+		},
+		"Namespace not provided": {
+			query:          "?name=svc0",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"OVS error": {
+			query:          "?name=svc0&namespace=namespaceA",
+			err:            fmt.Errorf("error when dumping the Endpoint DNAT flows"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for k, tc := range testcases {
+		handler := HandleFunc(&fakeStatusQuerier{statuses: testStatuses, err: tc.err})
+		req, err := http.NewRequest(http.MethodGet, tc.query, nil)
+		assert.Nil(t, err)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, tc.expectedStatus, recorder.Code, k)
+
+		if tc.expectedStatus == http.StatusOK {
+			var received []Response
+			err = json.Unmarshal(recorder.Body.Bytes(), &received)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedContent, received, k)
+		}
+	}
+}
+
+func TestServiceStatusProxyDisabled(t *testing.T) {
+	handler := HandleFunc(nil)
+	req, err := http.NewRequest(http.MethodGet, "?name=svc0&namespace=namespaceA", nil)
+	assert.Nil(t, err)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
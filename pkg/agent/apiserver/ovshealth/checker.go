@@ -0,0 +1,96 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovshealth
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"k8s.io/apiserver/pkg/server/healthz"
+
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
+)
+
+// Name is the name of the health check, which is served at "/healthz/ovs".
+const Name = "ovs"
+
+var (
+	// requiredTables are the OpenFlow tables which contain at least one flow
+	// once the pipeline has been initialized: Classifier (0), ConntrackState
+	// (31) and DNAT (40).
+	requiredTables = []uint8{0, 31, 40}
+	// proxyTables are the OpenFlow tables which are only part of the pipeline
+	// when AntreaProxy is enabled: ServiceLB (41) and EndpointDNAT (42).
+	proxyTables = []uint8{41, 42}
+)
+
+type checker struct {
+	ovsCtlClient ovsctl.OVSCtlClient
+	bridge       string
+	tables       []uint8
+}
+
+// NewChecker returns a health check which fails if the OVS bridge of the agent
+// is not functional: the bridge does not exist in OVSDB, ovs-vswitchd does not
+// answer OpenFlow requests on it, or a table of the pipeline has no flow.
+func NewChecker(ovsCtlClient ovsctl.OVSCtlClient, bridge string, enableProxy bool) healthz.HealthChecker {
+	tables := requiredTables
+	if enableProxy {
+		tables = append(append([]uint8{}, requiredTables...), proxyTables...)
+	}
+	return &checker{ovsCtlClient: ovsCtlClient, bridge: bridge, tables: tables}
+}
+
+func (c *checker) Name() string {
+	return Name
+}
+
+func (c *checker) Check(_ *http.Request) error {
+	out, err := c.ovsCtlClient.RunVsctlCmd("show")
+	if err != nil {
+		return fmt.Errorf("error when running ovs-vsctl show: %v", err)
+	}
+	if !hasBridge(string(out), c.bridge) {
+		return fmt.Errorf("bridge %s not found in OVSDB", c.bridge)
+	}
+	if _, err := c.ovsCtlClient.RunOfctlCmd("ping"); err != nil {
+		return fmt.Errorf("error when pinging bridge %s: %v", c.bridge, err)
+	}
+	for _, table := range c.tables {
+		flows, err := c.ovsCtlClient.DumpTableFlows(table)
+		if err != nil {
+			return fmt.Errorf("error when dumping flows of table %d: %v", table, err)
+		}
+		if len(flows) == 0 {
+			return fmt.Errorf("no flow found in table %d", table)
+		}
+	}
+	return nil
+}
+
+// hasBridge returns true if the output of "ovs-vsctl show" includes the
+// bridge. The bridge name is quoted by some OVS versions.
+func hasBridge(show, bridge string) bool {
+	scanner := bufio.NewScanner(strings.NewReader(show))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "Bridge" && strings.Trim(fields[1], `"`) == bridge {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovshealth
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	ovsctltest "github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl/testing"
+)
+
+const testShowOutput = `3b3ea9d4-7c8e-4cb8-9b4e-0a1d6a54c6e2
+    Bridge br-int
+        datapath_type: system
+        Port antrea-gw0
+            Interface antrea-gw0
+                type: internal
+    ovs_version: "2.14.0"
+`
+
+func TestCheck(t *testing.T) {
+	flows := []string{"table=0, priority=0 actions=drop"}
+	tests := []struct {
+		name        string
+		enableProxy bool
+		prepare     func(client *ovsctltest.MockOVSCtlClient)
+		expectedErr string
+	}{
+		{
+			name: "healthy",
+			prepare: func(client *ovsctltest.MockOVSCtlClient) {
+				client.EXPECT().RunVsctlCmd("show").Return([]byte(testShowOutput), nil)
+				client.EXPECT().RunOfctlCmd("ping").Return([]byte{}, nil)
+				for _, table := range requiredTables {
+					client.EXPECT().DumpTableFlows(table).Return(flows, nil)
+				}
+			},
+		},
+		{
+			name:        "healthy with AntreaProxy",
+			enableProxy: true,
+			prepare: func(client *ovsctltest.MockOVSCtlClient) {
+				client.EXPECT().RunVsctlCmd("show").Return([]byte(testShowOutput), nil)
+				client.EXPECT().RunOfctlCmd("ping").Return([]byte{}, nil)
+				for _, table := range []uint8{0, 31, 40, 41, 42} {
+					client.EXPECT().DumpTableFlows(table).Return(flows, nil)
+				}
+			},
+		},
+		{
+			name: "healthy with quoted bridge name",
+			prepare: func(client *ovsctltest.MockOVSCtlClient) {
+				client.EXPECT().RunVsctlCmd("show").Return([]byte("    Bridge \"br-int\"\n"), nil)
+				client.EXPECT().RunOfctlCmd("ping").Return([]byte{}, nil)
+				client.EXPECT().DumpTableFlows(gomock.Any()).Return(flows, nil).Times(len(requiredTables))
+			},
+		},
+		{
+			name: "OVSDB unavailable",
+			prepare: func(client *ovsctltest.MockOVSCtlClient) {
+				client.EXPECT().RunVsctlCmd("show").Return(nil, fmt.Errorf("database connection failed"))
+			},
+			expectedErr: "error when running ovs-vsctl show: database connection failed",
+		},
+		{
+			name: "bridge not found",
+			prepare: func(client *ovsctltest.MockOVSCtlClient) {
+				client.EXPECT().RunVsctlCmd("show").Return([]byte("    Bridge br-ext\n    Bridge br-int0\n"), nil)
+			},
+			expectedErr: "bridge br-int not found in OVSDB",
+		},
+		{
+			name: "ovs-vswitchd unavailable",
+			prepare: func(client *ovsctltest.MockOVSCtlClient) {
+				client.EXPECT().RunVsctlCmd("show").Return([]byte(testShowOutput), nil)
+				client.EXPECT().RunOfctlCmd("ping").Return(nil, fmt.Errorf("connection refused"))
+			},
+			expectedErr: "error when pinging bridge br-int: connection refused",
+		},
+		{
+			name: "flow dump failure",
+			prepare: func(client *ovsctltest.MockOVSCtlClient) {
+				client.EXPECT().RunVsctlCmd("show").Return([]byte(testShowOutput), nil)
+				client.EXPECT().RunOfctlCmd("ping").Return([]byte{}, nil)
+				client.EXPECT().DumpTableFlows(uint8(0)).Return(nil, fmt.Errorf("timeout"))
+			},
+			expectedErr: "error when dumping flows of table 0: timeout",
+		},
+		{
+			name: "empty table",
+			prepare: func(client *ovsctltest.MockOVSCtlClient) {
+				client.EXPECT().RunVsctlCmd("show").Return([]byte(testShowOutput), nil)
+				client.EXPECT().RunOfctlCmd("ping").Return([]byte{}, nil)
+				client.EXPECT().DumpTableFlows(uint8(0)).Return(flows, nil)
+				client.EXPECT().DumpTableFlows(uint8(31)).Return([]string{}, nil)
+			},
+			expectedErr: "no flow found in table 31",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := ovsctltest.NewMockOVSCtlClient(ctrl)
+			tt.prepare(client)
+
+			c := NewChecker(client, "br-int", tt.enableProxy)
+			assert.Equal(t, Name, c.Name())
+			err := c.Check(nil)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
@@ -100,7 +100,33 @@ func (ic *ifConfigurator) configureContainerLink(
 	result *current.Result,
 ) error {
 	hostIfaceName := util.GenerateContainerInterfaceName(podName, podNamespace, containerID)
+	return ic.configureVethPair(hostIfaceName, containerID, containerNetNS, containerIfaceName, mtu, result)
+}
 
+// configureSecondaryContainerLink creates a veth pair for a secondary network interface of the container, in the
+// same way as configureContainerLink. The name of the host veth is generated from the container interface name
+// too, so that it does not conflict with the host veth of the primary interface.
+func (ic *ifConfigurator) configureSecondaryContainerLink(
+	podName string,
+	podNamespace string,
+	containerID string,
+	containerNetNS string,
+	containerIfaceName string,
+	mtu int,
+	result *current.Result,
+) error {
+	hostIfaceName := util.GenerateSecondaryInterfaceName(podName, podNamespace, containerID, containerIfaceName)
+	return ic.configureVethPair(hostIfaceName, containerID, containerNetNS, containerIfaceName, mtu, result)
+}
+
+func (ic *ifConfigurator) configureVethPair(
+	hostIfaceName string,
+	containerID string,
+	containerNetNS string,
+	containerIfaceName string,
+	mtu int,
+	result *current.Result,
+) error {
 	hostIface := &current.Interface{Name: hostIfaceName}
 	containerIface := &current.Interface{Name: containerIfaceName, Sandbox: containerNetNS}
 	result.Interfaces = []*current.Interface{hostIface, containerIface}
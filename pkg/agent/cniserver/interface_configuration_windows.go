@@ -140,6 +140,19 @@ func (ic *ifConfigurator) configureContainerLink(
 	return nil
 }
 
+// configureSecondaryContainerLink returns an error as the secondary network interfaces are not supported on Windows.
+func (ic *ifConfigurator) configureSecondaryContainerLink(
+	podName string,
+	podNameSpace string,
+	containerID string,
+	containerNetNS string,
+	containerIFDev string,
+	mtu int,
+	result *current.Result,
+) error {
+	return fmt.Errorf("secondary network interfaces are not supported on Windows")
+}
+
 // createContainerLink creates HNSEndpoint using the IP configuration in the IPAM result.
 func (ic *ifConfigurator) createContainerLink(endpointName string, result *current.Result) (hostLink *hcsshim.HNSEndpoint, err error) {
 	// Create a new Endpoint if not found.
@@ -0,0 +1,105 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"fmt"
+
+	"github.com/containernetworking/cni/pkg/invoke"
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/types/current"
+	"k8s.io/klog"
+)
+
+// IPPoolAllocator allocates the IPs of the Pods from the IPPools.
+type IPPoolAllocator interface {
+	// AllocateIP allocates an IP to the Pod from the IPPool matching it. It
+	// returns a nil result if no IPPool matches the Pod.
+	AllocateIP(podNamespace, podName, containerID string) (*current.Result, error)
+	// ReleaseIP releases the IP allocated to the container, and returns false
+	// if no IP was allocated to it from an IPPool.
+	ReleaseIP(containerID string) (bool, error)
+	// HasIP returns whether an IP was allocated to the container from an
+	// IPPool.
+	HasIP(containerID string) bool
+}
+
+// SecondaryIPAllocator allocates the IPs of the secondary network interfaces of
+// the Pods from the IPPools specified in the network configurations.
+type SecondaryIPAllocator interface {
+	// AllocateIPFromPool allocates an IP to the interface ifName of the
+	// container from the IPPool poolName.
+	AllocateIPFromPool(poolName, podNamespace, podName, containerID, ifName string) (*current.Result, error)
+	// ReleaseIPFromPool releases the IP allocated to the interface ifName of
+	// the container. It does nothing if no IP was allocated to it.
+	ReleaseIPFromPool(containerID, ifName string) error
+}
+
+type k8sArgs struct {
+	cnitypes.CommonArgs
+	K8S_POD_NAME      cnitypes.UnmarshallableString
+	K8S_POD_NAMESPACE cnitypes.UnmarshallableString
+}
+
+// antreaIPAM allocates the IPs of the Pods matching an IPPool from the pool,
+// and delegates the IPAM requests of the other Pods to the host-local driver.
+type antreaIPAM struct {
+	allocator IPPoolAllocator
+	delegate  IPAMDriver
+}
+
+// RegisterAntreaIPAM makes the IPAM requests of the host-local type handled by
+// the Antrea IPAM driver, which allocates the IPs of the Pods from the IPPools
+// matching them and delegates the requests of the other Pods to host-local.
+func RegisterAntreaIPAM(allocator IPPoolAllocator) error {
+	delegate, ok := ipamDrivers[ipamHostLocal]
+	if !ok {
+		return fmt.Errorf("IPAM with type %s is not registered", ipamHostLocal)
+	}
+	ipamDrivers[ipamHostLocal] = &antreaIPAM{allocator: allocator, delegate: delegate}
+	return nil
+}
+
+func (d *antreaIPAM) Add(args *invoke.Args, networkConfig []byte) (*current.Result, error) {
+	podArgs := &k8sArgs{}
+	podArgs.IgnoreUnknown = true
+	if err := cnitypes.LoadArgs(args.PluginArgsStr, podArgs); err != nil {
+		return nil, err
+	}
+	result, err := d.allocator.AllocateIP(string(podArgs.K8S_POD_NAMESPACE), string(podArgs.K8S_POD_NAME), args.ContainerID)
+	if err != nil {
+		return nil, err
+	}
+	if result != nil {
+		klog.Infof("Allocated IP from IPPool to Pod %s/%s", podArgs.K8S_POD_NAMESPACE, podArgs.K8S_POD_NAME)
+		return result, nil
+	}
+	return d.delegate.Add(args, networkConfig)
+}
+
+func (d *antreaIPAM) Del(args *invoke.Args, networkConfig []byte) error {
+	released, err := d.allocator.ReleaseIP(args.ContainerID)
+	if err != nil || released {
+		return err
+	}
+	return d.delegate.Del(args, networkConfig)
+}
+
+func (d *antreaIPAM) Check(args *invoke.Args, networkConfig []byte) error {
+	if d.allocator.HasIP(args.ContainerID) {
+		return nil
+	}
+	return d.delegate.Check(args, networkConfig)
+}
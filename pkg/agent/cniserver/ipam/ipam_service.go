@@ -56,6 +56,9 @@ func argsFromEnv(cniArgs *cnipb.CniCmdArgs) *invoke.Args {
 		NetNS:       cniArgs.Netns,
 		IfName:      cniArgs.Ifname,
 		Path:        cniArgs.Path,
+		// The K8s args identify the Pod for the Antrea IPAM driver. host-local
+		// ignores them, as the runtime passes them with IgnoreUnknown=1.
+		PluginArgsStr: cniArgs.Args,
 	}
 }
 
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"strconv"
 
 	cnitypes "github.com/containernetworking/cni/pkg/types"
 	"github.com/containernetworking/cni/pkg/types/current"
@@ -53,6 +54,9 @@ const (
 	ovsExternalIDContainerID  = "container-id"
 	ovsExternalIDPodName      = "pod-name"
 	ovsExternalIDPodNamespace = "pod-namespace"
+	// The external_ids of the OVS ports of the secondary network interfaces.
+	ovsExternalIDSecondaryIfName = "secondary-if-name"
+	ovsExternalIDSecondaryVLAN   = "secondary-vlan"
 )
 
 const (
@@ -62,6 +66,7 @@ const (
 
 type interfaceConfigurator interface {
 	configureContainerLink(podName, podNameSpace, containerID, containerNetNS, containerIFDev string, mtu int, result *current.Result) error
+	configureSecondaryContainerLink(podName, podNameSpace, containerID, containerNetNS, containerIFDev string, mtu int, result *current.Result) error
 	advertiseContainerAddr(containerNetNS string, containerIfaceName string, result *current.Result) error
 	removeContainerLink(containerID, hostInterfaceName string) error
 	checkContainerInterface(containerNetns, containerID string, containerIface *current.Interface, containerIPs []*current.IPConfig, containerRoutes []*cnitypes.Route) (*vethPair, error)
@@ -146,6 +151,10 @@ func BuildOVSPortExternalIDs(containerConfig *interfacestore.InterfaceConfig) ma
 	externalIDs[ovsExternalIDIP] = containerConfig.IP.String()
 	externalIDs[ovsExternalIDPodName] = containerConfig.PodName
 	externalIDs[ovsExternalIDPodNamespace] = containerConfig.PodNamespace
+	if containerConfig.SecondaryInterfaceConfig != nil {
+		externalIDs[ovsExternalIDSecondaryIfName] = containerConfig.IfName
+		externalIDs[ovsExternalIDSecondaryVLAN] = strconv.Itoa(int(containerConfig.VLAN))
+	}
 	return externalIDs
 }
 
@@ -173,6 +182,25 @@ func ParseOVSPortInterfaceConfig(portData *ovsconfig.OVSPortData, portConfig *in
 	podName, _ := portData.ExternalIDs[ovsExternalIDPodName]
 	podNamespace, _ := portData.ExternalIDs[ovsExternalIDPodNamespace]
 
+	if ifName, found := portData.ExternalIDs[ovsExternalIDSecondaryIfName]; found {
+		vlan, err := strconv.ParseUint(portData.ExternalIDs[ovsExternalIDSecondaryVLAN], 10, 16)
+		if err != nil {
+			klog.Errorf("Failed to parse VLAN from OVS external config %s: %v",
+				portData.ExternalIDs[ovsExternalIDSecondaryVLAN], err)
+		}
+		interfaceConfig := interfacestore.NewSecondaryInterface(
+			portData.Name,
+			containerID,
+			podName,
+			podNamespace,
+			ifName,
+			uint16(vlan),
+			containerMAC,
+			containerIP)
+		interfaceConfig.OVSPortConfig = portConfig
+		return interfaceConfig
+	}
+
 	interfaceConfig := interfacestore.NewContainerInterface(
 		portData.Name,
 		containerID,
@@ -247,6 +275,100 @@ func (pc *podConfigurator) configureInterfaces(
 	return nil
 }
 
+// configureSecondaryInterface creates the secondary network interface
+// containerIFDev of the container, and connects it to an access port of the VLAN
+// on the OVS bridge. The traffic of the interface is switched in the VLAN and
+// bypasses the Pod network pipeline, so no NetworkPolicy is enforced on it.
+func (pc *podConfigurator) configureSecondaryInterface(
+	podName string,
+	podNameSpace string,
+	containerID string,
+	containerNetNS string,
+	containerIFDev string,
+	mtu int,
+	vlan uint16,
+	result *current.Result,
+) error {
+	err := pc.ifConfigurator.configureSecondaryContainerLink(podName, podNameSpace, containerID, containerNetNS, containerIFDev, mtu, result)
+	if err != nil {
+		return err
+	}
+	hostIface := result.Interfaces[0]
+	containerIface := result.Interfaces[1]
+
+	// Delete veth pair and OVS port if any failure occurs in later manipulation.
+	success := false
+	defer func() {
+		if !success {
+			_ = pc.ifConfigurator.removeContainerLink(containerID, hostIface.Name)
+		}
+	}()
+
+	// Use the outer veth interface name as the OVS port name.
+	ovsPortName := hostIface.Name
+	containerIP, err := parseContainerIP(result.IPs)
+	if err != nil {
+		klog.Errorf("Failed to find IP of interface %s of container %s", containerIFDev, containerID)
+	}
+	containerMAC, _ := net.ParseMAC(containerIface.Mac)
+	ifConfig := interfacestore.NewSecondaryInterface(ovsPortName, containerID, podName, podNameSpace, containerIFDev, vlan, containerMAC, containerIP)
+	klog.V(2).Infof("Adding OVS port %s in VLAN %d for interface %s of container %s", ovsPortName, vlan, containerIFDev, containerID)
+	portUUID, err := pc.ovsBridgeClient.CreateAccessPort(ovsPortName, ovsPortName, BuildOVSPortExternalIDs(ifConfig), vlan)
+	if err != nil {
+		return fmt.Errorf("failed to add OVS port for interface %s of container %s: %v", containerIFDev, containerID, err)
+	}
+	defer func() {
+		if !success {
+			_ = pc.ovsBridgeClient.DeletePort(portUUID)
+		}
+	}()
+
+	// GetOFPort will wait for up to 1 second for OVSDB to report the OFPort number.
+	ofPort, err := pc.ovsBridgeClient.GetOFPort(ovsPortName)
+	if err != nil {
+		return fmt.Errorf("failed to get of_port of OVS port %s: %v", ovsPortName, err)
+	}
+	if err := pc.ofClient.InstallSecondaryInterfaceFlows(ovsPortName, uint32(ofPort)); err != nil {
+		return fmt.Errorf("failed to add Openflow entries for interface %s of container %s: %v", containerIFDev, containerID, err)
+	}
+	ifConfig.OVSPortConfig = &interfacestore.OVSPortConfig{PortUUID: portUUID, OFPort: ofPort}
+	pc.ifaceStore.AddInterface(ifConfig)
+
+	if err := pc.ifConfigurator.advertiseContainerAddr(containerNetNS, containerIface.Name, result); err != nil {
+		klog.Errorf("Failed to advertise IP address for interface %s of container %s: %v", containerIFDev, containerID, err)
+	}
+	success = true
+	klog.Infof("Configured secondary interface %s for container %s", containerIFDev, containerID)
+	return nil
+}
+
+// removeSecondaryInterface removes the secondary network interface
+// containerIFDev of the container, and its OVS port and flows.
+func (pc *podConfigurator) removeSecondaryInterface(podName, podNamespace, containerID, containerIFDev string) error {
+	interfaceName := util.GenerateSecondaryInterfaceName(podName, podNamespace, containerID, containerIFDev)
+	ifConfig, found := pc.ifaceStore.GetInterfaceByName(interfaceName)
+	if !found || ifConfig.Type != interfacestore.SecondaryInterface {
+		klog.V(2).Infof("Did not find the port for interface %s of container %s in local cache", containerIFDev, containerID)
+		return nil
+	}
+	// As for the primary interface, the flows must be uninstalled before the
+	// OVS port is deleted and its ofport released.
+	if err := pc.disconnectInterfaceFromOVS(ifConfig); err != nil {
+		return err
+	}
+	return pc.ifConfigurator.removeContainerLink(containerID, interfaceName)
+}
+
+// checkSecondaryInterface checks that the secondary network interface
+// containerIFDev of the container is connected to the OVS bridge.
+func (pc *podConfigurator) checkSecondaryInterface(podName, podNamespace, containerID, containerIFDev string) error {
+	interfaceName := util.GenerateSecondaryInterfaceName(podName, podNamespace, containerID, containerIFDev)
+	if ifConfig, found := pc.ifaceStore.GetInterfaceByName(interfaceName); !found || ifConfig.Type != interfacestore.SecondaryInterface {
+		return fmt.Errorf("interface %s of container %s not found from local cache", containerIFDev, containerID)
+	}
+	return nil
+}
+
 func (pc *podConfigurator) createOVSPort(ovsPortName string, ovsAttachInfo map[string]interface{}) (string, error) {
 	var portUUID string
 	var err error
@@ -417,6 +539,23 @@ func (pc *podConfigurator) reconcile(pods []corev1.Pod) error {
 		}
 	}
 
+	// The secondary network interfaces of the Pods are reconciled in the same
+	// way.
+	for _, ifConfig := range pc.ifaceStore.GetInterfacesByType(interfacestore.SecondaryInterface) {
+		namespacedName := k8s.NamespacedName(ifConfig.PodNamespace, ifConfig.PodName)
+		if desiredPods.Has(namespacedName) {
+			klog.V(4).Infof("Syncing secondary interface %s for Pod %s", ifConfig.InterfaceName, namespacedName)
+			if err := pc.ofClient.InstallSecondaryInterfaceFlows(ifConfig.InterfaceName, uint32(ifConfig.OFPort)); err != nil {
+				klog.Errorf("Error when re-installing flows for secondary interface %s of Pod %s", ifConfig.IfName, namespacedName)
+			}
+		} else {
+			klog.V(4).Infof("Deleting secondary interface %s", ifConfig.InterfaceName)
+			if err := pc.removeSecondaryInterface(ifConfig.PodName, ifConfig.PodNamespace, ifConfig.ContainerID, ifConfig.IfName); err != nil {
+				klog.Errorf("Failed to delete secondary interface %s: %v", ifConfig.InterfaceName, err)
+			}
+		}
+	}
+
 	for pod := range desiredPods.Difference(actualPods) {
 		// This should not happen since OVSDB is persisted on the Node.
 		// TODO: is there anything else we should be doing? Assuming that the Pod's
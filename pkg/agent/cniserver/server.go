@@ -101,10 +101,34 @@ type CNIServer struct {
 	podUpdates  chan<- v1beta1.PodReference
 	isChaining  bool
 	routeClient route.Interface
+	// flowTableChecker is used to refuse new Pods when the OVS datapath flow
+	// table is full. It is nil if the check is disabled.
+	flowTableChecker FlowTableChecker
+	// secondaryIPAllocator allocates the IPs of the secondary network
+	// interfaces. It is nil if the secondary networks are disabled.
+	secondaryIPAllocator ipam.SecondaryIPAllocator
+}
+
+// FlowTableChecker checks whether the OVS datapath has room for the flows of
+// new Pods.
+type FlowTableChecker interface {
+	// CheckFlowTableCapacity returns an error describing the utilization of
+	// the flow table if new Pods must not be created.
+	CheckFlowTableCapacity() error
 }
 
 var supportedCNIVersionSet map[string]bool
 
+const (
+	// attachmentTypePrimary is the attachment type of the Pod network, which
+	// is the default network of the Pods.
+	attachmentTypePrimary = "primary"
+	// attachmentTypeSecondary is the attachment type of a secondary network,
+	// to which Multus delegates the additional interfaces of the Pods
+	// requested with NetworkAttachmentDefinitions.
+	attachmentTypeSecondary = "secondary"
+)
+
 type RuntimeDNS struct {
 	Nameservers []string `json:"servers,omitempty"`
 	Search      []string `json:"searches,omitempty"`
@@ -123,6 +147,13 @@ type NetworkConfig struct {
 	IPAM       ipam.IPAMConfig `json:"ipam,omitempty"`
 	// Options to be passed in by the runtime.
 	RuntimeConfig RuntimeConfig `json:"runtimeConfig"`
+	// AttachmentType is either primary (the default) or secondary.
+	AttachmentType string `json:"attachmentType,omitempty"`
+	// SecondaryIPPool is the IPPool the IPs of a secondary attachment are
+	// allocated from.
+	SecondaryIPPool string `json:"secondaryIPPool,omitempty"`
+	// VLAN is the VLAN of a secondary attachment on the OVS bridge.
+	VLAN uint16 `json:"vlan,omitempty"`
 
 	RawPrevResult map[string]interface{} `json:"prevResult,omitempty"`
 	PrevResult    cnitypes.Result        `json:"-"`
@@ -134,6 +165,10 @@ type CNIConfig struct {
 	*k8sArgs
 }
 
+func (c *NetworkConfig) isSecondaryAttachment() bool {
+	return c.AttachmentType == attachmentTypeSecondary
+}
+
 // updateResultIfaceConfig processes the result from the IPAM plugin and does the following:
 //   * updates the IP configuration for each assigned IP address: this includes computing the
 //     gateway (if missing) based on the subnet and setting the interface pointer to the container
@@ -178,7 +213,7 @@ func (s *CNIServer) loadNetworkConfig(request *cnipb.CniCmdRequest) (*CNIConfig,
 	if err := cnitypes.LoadArgs(request.CniArgs.Args, cniConfig.k8sArgs); err != nil {
 		return cniConfig, err
 	}
-	if !s.isChaining {
+	if !s.isChaining && !cniConfig.isSecondaryAttachment() {
 		s.updateLocalIPAMSubnet(cniConfig)
 	}
 	if cniConfig.MTU == 0 {
@@ -205,6 +240,14 @@ func (s *CNIServer) checkRequestMessage(request *cnipb.CniCmdRequest) (*CNIConfi
 		klog.Errorf(fmt.Sprintf("Unsupported CNI version [%s], supported CNI versions %s", cniVersion, version.All.SupportedVersions()))
 		return cniConfig, s.incompatibleCniVersionResponse(cniVersion)
 	}
+	switch cniConfig.AttachmentType {
+	case "", attachmentTypePrimary:
+	case attachmentTypeSecondary:
+		return cniConfig, s.checkSecondaryAttachment(cniConfig)
+	default:
+		klog.Errorf("Unsupported attachment type %s", cniConfig.AttachmentType)
+		return cniConfig, s.unsupportedFieldResponse("attachmentType", cniConfig.AttachmentType)
+	}
 	if s.isChaining {
 		return cniConfig, nil
 	}
@@ -218,6 +261,23 @@ func (s *CNIServer) checkRequestMessage(request *cnipb.CniCmdRequest) (*CNIConfi
 	return cniConfig, nil
 }
 
+// checkSecondaryAttachment validates the network configuration of a secondary
+// attachment. The IPAM configuration is ignored, as the IPs are allocated from
+// the IPPool of the attachment.
+func (s *CNIServer) checkSecondaryAttachment(cniConfig *CNIConfig) *cnipb.CniCmdResponse {
+	if s.isChaining || s.secondaryIPAllocator == nil {
+		klog.Errorf("Secondary attachments are not supported, make sure the SecondaryNetwork feature is enabled and the agent is not in chaining mode")
+		return s.unsupportedFieldResponse("attachmentType", cniConfig.AttachmentType)
+	}
+	if cniConfig.SecondaryIPPool == "" {
+		return s.invalidNetworkConfigResponse("secondaryIPPool must be specified for a secondary attachment")
+	}
+	if cniConfig.VLAN == 0 || cniConfig.VLAN > 4094 {
+		return s.invalidNetworkConfigResponse(fmt.Sprintf("invalid VLAN %d for a secondary attachment, it must be between 1 and 4094", cniConfig.VLAN))
+	}
+	return nil
+}
+
 func (s *CNIServer) updateLocalIPAMSubnet(cniConfig *CNIConfig) {
 	cniConfig.NetworkConfig.IPAM.Gateway = s.nodeConfig.GatewayConfig.IP.String()
 	cniConfig.NetworkConfig.IPAM.Subnet = s.nodeConfig.PodCIDR.String()
@@ -264,6 +324,12 @@ func (s *CNIServer) tryAgainLaterResponse() *cnipb.CniCmdResponse {
 	return s.generateCNIErrorResponse(cniErrorCode, cniErrorMsg)
 }
 
+func (s *CNIServer) flowTableFullResponse(err error) *cnipb.CniCmdResponse {
+	cniErrorCode := cnipb.ErrorCode_TRY_AGAIN_LATER
+	cniErrorMsg := err.Error()
+	return s.generateCNIErrorResponse(cniErrorCode, cniErrorMsg)
+}
+
 func (s *CNIServer) ipamFailureResponse(err error) *cnipb.CniCmdResponse {
 	cniErrorCode := cnipb.ErrorCode_IPAM_FAILURE
 	cniErrorMsg := err.Error()
@@ -352,6 +418,14 @@ func (s *CNIServer) CmdAdd(ctx context.Context, request *cnipb.CniCmdRequest) (*
 	result := &current.Result{CNIVersion: cniVersion}
 	netNS := s.hostNetNsPath(cniConfig.Netns)
 	isInfraContainer := isInfraContainer(netNS)
+	// Only refuse new Pods, not the other containers of a Pod whose network
+	// has already been configured.
+	if isInfraContainer && s.flowTableChecker != nil {
+		if err := s.flowTableChecker.CheckFlowTableCapacity(); err != nil {
+			klog.Errorf("Refusing CmdAdd request for container %s: %v", cniConfig.ContainerId, err)
+			return s.flowTableFullResponse(err), nil
+		}
+	}
 
 	success := false
 	defer func() {
@@ -379,6 +453,13 @@ func (s *CNIServer) CmdAdd(ctx context.Context, request *cnipb.CniCmdRequest) (*
 		}
 		return resp, err
 	}
+	if cniConfig.isSecondaryAttachment() {
+		resp := s.addSecondaryInterface(cniConfig, netNS)
+		if resp.Error == nil {
+			success = true
+		}
+		return resp, nil
+	}
 
 	var ipamResult *current.Result
 	var err error
@@ -446,6 +527,9 @@ func (s *CNIServer) CmdDel(_ context.Context, request *cnipb.CniCmdRequest) (
 	if s.isChaining {
 		return s.interceptDel(cniConfig)
 	}
+	if cniConfig.isSecondaryAttachment() {
+		return s.delSecondaryInterface(cniConfig), nil
+	}
 	// Release IP to IPAM driver
 	if err := ipam.ExecIPAMDelete(cniConfig.CniCmdArgs, cniConfig.IPAM.Type, infraContainer); err != nil {
 		klog.Errorf("Failed to delete IP addresses by IPAM driver: %v", err)
@@ -476,6 +560,16 @@ func (s *CNIServer) CmdCheck(_ context.Context, request *cnipb.CniCmdRequest) (
 	if s.isChaining {
 		return s.interceptCheck(cniConfig)
 	}
+	if cniConfig.isSecondaryAttachment() {
+		if err := s.podConfigurator.checkSecondaryInterface(
+			string(cniConfig.K8S_POD_NAME),
+			string(cniConfig.K8S_POD_NAMESPACE),
+			cniConfig.ContainerId,
+			cniConfig.Ifname); err != nil {
+			return s.checkInterfaceFailureResponse(err), nil
+		}
+		return &cnipb.CniCmdResponse{CniResult: []byte("")}, nil
+	}
 
 	if err := ipam.ExecIPAMCheck(cniConfig.CniCmdArgs, cniConfig.IPAM.Type); err != nil {
 		klog.Errorf("Failed to check IPAM configuration: %v", err)
@@ -502,6 +596,8 @@ func New(
 	podUpdates chan<- v1beta1.PodReference,
 	isChaining bool,
 	routeClient route.Interface,
+	flowTableChecker FlowTableChecker,
+	secondaryIPAllocator ipam.SecondaryIPAllocator,
 ) *CNIServer {
 	return &CNIServer{
 		cniSocket:            cniSocket,
@@ -515,6 +611,8 @@ func New(
 		podUpdates:           podUpdates,
 		isChaining:           isChaining,
 		routeClient:          routeClient,
+		flowTableChecker:     flowTableChecker,
+		secondaryIPAllocator: secondaryIPAllocator,
 	}
 }
 
@@ -597,6 +695,59 @@ func (s *CNIServer) interceptCheck(_ *CNIConfig) (*cnipb.CniCmdResponse, error)
 	return &cnipb.CniCmdResponse{CniResult: make([]byte, 0, 0)}, nil
 }
 
+// addSecondaryInterface handles Add request for a secondary attachment. The IP
+// of the interface is allocated from the IPPool of the attachment, and the
+// interface is connected to the VLAN of the attachment. The Pod update is not
+// notified, as no NetworkPolicy applies to the secondary networks.
+func (s *CNIServer) addSecondaryInterface(cniConfig *CNIConfig, netNS string) *cnipb.CniCmdResponse {
+	podName := string(cniConfig.K8S_POD_NAME)
+	podNamespace := string(cniConfig.K8S_POD_NAMESPACE)
+	ipamResult, err := s.secondaryIPAllocator.AllocateIPFromPool(cniConfig.SecondaryIPPool, podNamespace, podName, cniConfig.ContainerId, cniConfig.Ifname)
+	if err != nil {
+		klog.Errorf("Failed to allocate IP from IPPool %s: %v", cniConfig.SecondaryIPPool, err)
+		return s.ipamFailureResponse(err)
+	}
+	result := &current.Result{CNIVersion: cniConfig.CNIVersion, IPs: ipamResult.IPs}
+	for _, ipc := range result.IPs {
+		// result.Interfaces[0] is host interface, and result.Interfaces[1] is container interface
+		ipc.Interface = current.Int(1)
+	}
+	if err := s.podConfigurator.configureSecondaryInterface(
+		podName,
+		podNamespace,
+		cniConfig.ContainerId,
+		netNS,
+		cniConfig.Ifname,
+		cniConfig.MTU,
+		cniConfig.VLAN,
+		result,
+	); err != nil {
+		klog.Errorf("Failed to configure secondary interface %s for container %s: %v", cniConfig.Ifname, cniConfig.ContainerId, err)
+		return s.configInterfaceFailureResponse(err)
+	}
+	var resultBytes bytes.Buffer
+	_ = result.PrintTo(&resultBytes)
+	klog.Infof("CmdAdd for secondary interface %s succeeded", cniConfig.Ifname)
+	return &cnipb.CniCmdResponse{CniResult: resultBytes.Bytes()}
+}
+
+// delSecondaryInterface handles Del request for a secondary attachment.
+func (s *CNIServer) delSecondaryInterface(cniConfig *CNIConfig) *cnipb.CniCmdResponse {
+	if err := s.secondaryIPAllocator.ReleaseIPFromPool(cniConfig.ContainerId, cniConfig.Ifname); err != nil {
+		klog.Errorf("Failed to release IP to IPPool %s: %v", cniConfig.SecondaryIPPool, err)
+		return s.ipamFailureResponse(err)
+	}
+	if err := s.podConfigurator.removeSecondaryInterface(
+		string(cniConfig.K8S_POD_NAME),
+		string(cniConfig.K8S_POD_NAMESPACE),
+		cniConfig.ContainerId,
+		cniConfig.Ifname); err != nil {
+		klog.Errorf("Failed to remove secondary interface %s for container %s: %v", cniConfig.Ifname, cniConfig.ContainerId, err)
+		return s.configInterfaceFailureResponse(err)
+	}
+	return &cnipb.CniCmdResponse{CniResult: []byte("")}
+}
+
 // reconcile performs startup reconciliation for the CNI server. The CNI server is in charge of
 // installing Pod flows, so as part of this reconciliation process we retrieve the Pod list from the
 // K8s apiserver and replay the necessary flows.
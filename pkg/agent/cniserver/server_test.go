@@ -217,6 +217,111 @@ func TestCheckRequestMessage(t *testing.T) {
 		_, response := cniServer.checkRequestMessage(&requestMsg)
 		checkErrorResponse(t, response, cnipb.ErrorCode_UNSUPPORTED_FIELD, "")
 	})
+
+	t.Run("Unknown attachment type", func(t *testing.T) {
+		networkCfg := generateNetworkConfiguration("testCfg", supportedCNIVersion)
+		networkCfg.AttachmentType = "unknown"
+		requestMsg, _ := newRequest(args, networkCfg, "", t)
+		_, response := cniServer.checkRequestMessage(&requestMsg)
+		checkErrorResponse(t, response, cnipb.ErrorCode_UNSUPPORTED_FIELD, "attachmentType")
+	})
+
+	t.Run("Secondary attachment disabled", func(t *testing.T) {
+		networkCfg := generateSecondaryNetworkConfiguration("testCfg", supportedCNIVersion)
+		requestMsg, _ := newRequest(args, networkCfg, "", t)
+		_, response := cniServer.checkRequestMessage(&requestMsg)
+		checkErrorResponse(t, response, cnipb.ErrorCode_UNSUPPORTED_FIELD, "attachmentType")
+	})
+
+	t.Run("Invalid secondary attachment", func(t *testing.T) {
+		cniServer := newCNIServer(t)
+		cniServer.secondaryIPAllocator = &fakeSecondaryIPAllocator{}
+		networkCfg := generateSecondaryNetworkConfiguration("testCfg", supportedCNIVersion)
+		// The IPAM configuration is ignored for a secondary attachment.
+		networkCfg.IPAM.Type = "unknown"
+		requestMsg, _ := newRequest(args, networkCfg, "", t)
+		_, response := cniServer.checkRequestMessage(&requestMsg)
+		assert.Nil(t, response)
+
+		networkCfg.SecondaryIPPool = ""
+		requestMsg, _ = newRequest(args, networkCfg, "", t)
+		_, response = cniServer.checkRequestMessage(&requestMsg)
+		checkErrorResponse(t, response, cnipb.ErrorCode_INVALID_NETWORK_CONFIG, "secondaryIPPool")
+
+		for _, vlan := range []uint16{0, 4095} {
+			networkCfg := generateSecondaryNetworkConfiguration("testCfg", supportedCNIVersion)
+			networkCfg.VLAN = vlan
+			requestMsg, _ := newRequest(args, networkCfg, "", t)
+			_, response := cniServer.checkRequestMessage(&requestMsg)
+			checkErrorResponse(t, response, cnipb.ErrorCode_INVALID_NETWORK_CONFIG, "VLAN")
+		}
+	})
+}
+
+type fakeSecondaryIPAllocator struct {
+	allocateErr error
+	// released is the list of the keys of the released interfaces.
+	released []string
+}
+
+func (a *fakeSecondaryIPAllocator) AllocateIPFromPool(poolName, podNamespace, podName, containerID, ifName string) (*current.Result, error) {
+	return nil, a.allocateErr
+}
+
+func (a *fakeSecondaryIPAllocator) ReleaseIPFromPool(containerID, ifName string) error {
+	a.released = append(a.released, containerID+"/"+ifName)
+	return nil
+}
+
+func TestSecondaryInterface(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+	mockOVSBridgeClient := ovsconfigtest.NewMockOVSBridgeClient(controller)
+	mockOFClient := openflowtest.NewMockClient(controller)
+	ifaceStore := interfacestore.NewInterfaceStore()
+	allocator := &fakeSecondaryIPAllocator{}
+	cniServer := newCNIServer(t)
+	cniServer.secondaryIPAllocator = allocator
+	cniServer.podConfigurator, _ = newPodConfigurator(mockOVSBridgeClient, mockOFClient, nil, ifaceStore, nil, "system")
+
+	networkCfg := generateSecondaryNetworkConfiguration("testCfg", supportedCNIVersion)
+	requestMsg, containerID := newRequest(args, networkCfg, "", t)
+	requestMsg.CniArgs.Ifname = "net1"
+	hostIfaceName := util.GenerateSecondaryInterfaceName(testPodName, testPodNamespace, containerID, "net1")
+
+	t.Run("Error on ADD", func(t *testing.T) {
+		allocator.allocateErr = fmt.Errorf("IPPool exhausted")
+		allocator.released = nil
+		response, err := cniServer.CmdAdd(context.Background(), &requestMsg)
+		require.Nil(t, err, "expected no rpc error")
+		checkErrorResponse(t, response, cnipb.ErrorCode_IPAM_FAILURE, "IPPool exhausted")
+		// The failed ADD is rolled back.
+		assert.Equal(t, []string{containerID + "/net1"}, allocator.released)
+	})
+
+	t.Run("CHECK and DEL", func(t *testing.T) {
+		response, err := cniServer.CmdCheck(context.Background(), &requestMsg)
+		require.Nil(t, err, "expected no rpc error")
+		checkErrorResponse(t, response, cnipb.ErrorCode_CHECK_INTERFACE_FAILURE, "net1")
+
+		containerMAC, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+		ifConfig := interfacestore.NewSecondaryInterface(hostIfaceName, containerID, testPodName, testPodNamespace, "net1", 100, containerMAC, net.ParseIP("192.168.100.2"))
+		ifConfig.OVSPortConfig = &interfacestore.OVSPortConfig{PortUUID: "port-uuid", OFPort: 10}
+		ifaceStore.AddInterface(ifConfig)
+		response, err = cniServer.CmdCheck(context.Background(), &requestMsg)
+		require.Nil(t, err, "expected no rpc error")
+		assert.Nil(t, response.Error)
+
+		allocator.released = nil
+		mockOFClient.EXPECT().UninstallPodFlows(hostIfaceName).Return(nil)
+		mockOVSBridgeClient.EXPECT().DeletePort("port-uuid").Return(nil)
+		response, err = cniServer.CmdDel(context.Background(), &requestMsg)
+		require.Nil(t, err, "expected no rpc error")
+		assert.Nil(t, response.Error)
+		assert.Equal(t, []string{containerID + "/net1"}, allocator.released)
+		_, found := ifaceStore.GetInterfaceByName(hostIfaceName)
+		assert.False(t, found, "Interface should not be in the local cache anymore")
+	})
 }
 
 func TestValidatePrevResult(t *testing.T) {
@@ -470,6 +575,21 @@ func TestBuildOVSPortExternalIDs(t *testing.T) {
 	if !existed || parsedID != containerID {
 		t.Errorf("Failed to parse container configuration")
 	}
+	_, existed = externalIds[ovsExternalIDSecondaryIfName]
+	assert.False(t, existed)
+
+	// The configuration of a secondary interface is restored from the
+	// external_ids.
+	secondaryConfig := interfacestore.NewSecondaryInterface("pod1-efgh", containerID, "test-1", "t1", "net1", 100, containerMAC, containerIP)
+	portConfig := &interfacestore.OVSPortConfig{PortUUID: "port-uuid", OFPort: 10}
+	externalIds = BuildOVSPortExternalIDs(secondaryConfig)
+	externalIDStrings := make(map[string]string, len(externalIds))
+	for k, v := range externalIds {
+		externalIDStrings[k] = v.(string)
+	}
+	parsedConfig := ParseOVSPortInterfaceConfig(&ovsconfig.OVSPortData{Name: "pod1-efgh", ExternalIDs: externalIDStrings}, portConfig)
+	secondaryConfig.OVSPortConfig = portConfig
+	assert.Equal(t, secondaryConfig, parsedConfig)
 }
 
 func translateRawPrevResult(prevResult *current.Result, cniVersion string) (map[string]interface{}, error) {
@@ -490,6 +610,31 @@ func translateRawPrevResult(prevResult *current.Result, cniVersion string) (map[
 	return conf.RawPrevResult, nil
 }
 
+type fakeFlowTableChecker struct {
+	err error
+}
+
+func (c *fakeFlowTableChecker) CheckFlowTableCapacity() error {
+	return c.err
+}
+
+func TestCmdAddFlowTableFull(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+	ipamMock := ipamtest.NewMockIPAMDriver(controller)
+	_ = ipam.RegisterIPAMDriver(testIpamType, ipamMock)
+	cniServer := newCNIServer(t)
+	cniServer.flowTableChecker = &fakeFlowTableChecker{err: fmt.Errorf("the OVS datapath flow table is 96%% full")}
+
+	networkCfg := generateNetworkConfiguration("testCfg", "0.4.0")
+	requestMsg, _ := newRequest(args, networkCfg, "", t)
+	// The request must be refused before any IP is allocated, so no call to
+	// the IPAM driver is expected.
+	response, err := cniServer.CmdAdd(context.Background(), &requestMsg)
+	require.Nil(t, err, "expected no rpc error")
+	checkErrorResponse(t, response, cnipb.ErrorCode_TRY_AGAIN_LATER, "flow table is 96% full")
+}
+
 func newCNIServer(t *testing.T) *CNIServer {
 	cniServer := &CNIServer{
 		cniSocket:       testSocket,
@@ -510,6 +655,14 @@ func generateNetworkConfiguration(name string, cniVersion string) *NetworkConfig
 	return netCfg
 }
 
+func generateSecondaryNetworkConfiguration(name string, cniVersion string) *NetworkConfig {
+	netCfg := generateNetworkConfiguration(name, cniVersion)
+	netCfg.AttachmentType = attachmentTypeSecondary
+	netCfg.SecondaryIPPool = "secondary-pool"
+	netCfg.VLAN = 100
+	return netCfg
+}
+
 func newRequest(args string, netCfg *NetworkConfig, path string, t *testing.T) (cnipb.CniCmdRequest, string) {
 	containerID := generateUUID(t)
 	networkConfig, err := json.Marshal(netCfg)
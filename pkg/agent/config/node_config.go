@@ -33,6 +33,18 @@ const (
 	BridgeOFPort = 0xfffffffe
 )
 
+const (
+	vxlanOverhead  = 50
+	geneveOverhead = 50
+	greOverhead    = 38
+	// IPsec ESP can add a maximum of 38 bytes to the packet including the ESP
+	// header and trailer.
+	ipsecESPOverhead = 38
+	// WireGuard adds an outer IP header, a UDP header and 32 bytes of WireGuard
+	// header and authentication tag. 80 bytes accommodates IPv6 outer headers.
+	wireGuardOverhead = 80
+)
+
 type GatewayConfig struct {
 	// Name is the name of host gateway, e.g. antrea-gw0.
 	Name string
@@ -46,6 +58,19 @@ func (g *GatewayConfig) String() string {
 	return fmt.Sprintf("Name %s: IP %s, MAC %s", g.Name, g.IP, g.MAC)
 }
 
+type WireGuardConfig struct {
+	// Name is the name of the WireGuard device, e.g. antrea-wg0.
+	Name string
+	// Port is the UDP port the WireGuard device listens on.
+	Port int
+	// LinkIndex is the link index of the WireGuard device.
+	LinkIndex int
+}
+
+func (w *WireGuardConfig) String() string {
+	return fmt.Sprintf("Name %s: Port %d", w.Name, w.Port)
+}
+
 type AdapterNetConfig struct {
 	Name       string
 	Index      int
@@ -73,6 +98,12 @@ type NodeConfig struct {
 	GatewayConfig *GatewayConfig
 	// The config of the OVS bridge uplink interface. Only for Windows Node.
 	UplinkNetConfig *AdapterNetConfig
+	// The config of the WireGuard device. It's nil unless the traffic encryption
+	// mode is WireGuard.
+	WireGuardConfig *WireGuardConfig
+	// The MTU of the gateway interface, the tunnel interface and the network interface of
+	// each Pod.
+	NodeMTU int
 }
 
 func (n *NodeConfig) String() string {
@@ -82,8 +113,32 @@ func (n *NodeConfig) String() string {
 
 // User provided network configuration parameters.
 type NetworkConfig struct {
-	TrafficEncapMode  TrafficEncapModeType
-	TunnelType        ovsconfig.TunnelType
-	EnableIPSecTunnel bool
-	IPSecPSK          string
+	TrafficEncapMode      TrafficEncapModeType
+	TunnelType            ovsconfig.TunnelType
+	EnableIPSecTunnel     bool
+	IPSecPSK              string
+	TrafficEncryptionMode TrafficEncryptionModeType
+}
+
+// CalculateMTUDeduction returns the number of bytes which must be subtracted from the MTU of the
+// Node's transport interface to accommodate for the encapsulation overhead.
+func (nc *NetworkConfig) CalculateMTUDeduction() int {
+	var mtuDeduction int
+	if nc.TrafficEncapMode.SupportsEncap() {
+		switch nc.TunnelType {
+		case ovsconfig.VXLANTunnel:
+			mtuDeduction = vxlanOverhead
+		case ovsconfig.GeneveTunnel:
+			mtuDeduction = geneveOverhead
+		case ovsconfig.GRETunnel:
+			mtuDeduction = greOverhead
+		}
+	}
+	if nc.EnableIPSecTunnel {
+		mtuDeduction += ipsecESPOverhead
+	}
+	if nc.TrafficEncryptionMode == TrafficEncryptionModeWireGuard {
+		mtuDeduction += wireGuardOverhead
+	}
+	return mtuDeduction
 }
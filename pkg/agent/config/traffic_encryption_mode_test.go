@@ -0,0 +1,48 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTrafficEncryptionModeFromStr(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		expBool bool
+		expMode TrafficEncryptionModeType
+	}{
+		{"none-mode-valid", "none", true, TrafficEncryptionModeNone},
+		{"ipsec-mode-valid", "IPsec", true, TrafficEncryptionModeIPSec},
+		{"wireguard-mode-valid", "WireGuard", true, TrafficEncryptionModeWireGuard},
+		{"invalid-str", "wire guard", false, TrafficEncryptionModeInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actualBool, actualMode := GetTrafficEncryptionModeFromStr(tt.mode)
+			assert.Equal(t, tt.expBool, actualBool, "GetTrafficEncryptionModeFromStr did not return correct boolean")
+			assert.Equal(t, tt.expMode, actualMode, "GetTrafficEncryptionModeFromStr did not return correct encryption mode")
+		})
+	}
+}
+
+func TestTrafficEncryptionModeTypeString(t *testing.T) {
+	assert.Equal(t, "None", TrafficEncryptionModeNone.String())
+	assert.Equal(t, "IPSec", TrafficEncryptionModeIPSec.String())
+	assert.Equal(t, "WireGuard", TrafficEncryptionModeWireGuard.String())
+}
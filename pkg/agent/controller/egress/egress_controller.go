@@ -0,0 +1,486 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package egress
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/interfacestore"
+	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/agent/route"
+	corev1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/core/v1alpha1"
+	crdinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions/core/v1alpha1"
+	crdlisters "github.com/vmware-tanzu/antrea/pkg/client/listers/core/v1alpha1"
+)
+
+const (
+	controllerName = "AntreaAgentEgressController"
+	// Set resyncPeriod to 0 to disable resyncing.
+	resyncPeriod time.Duration = 0
+	// How long to wait before retrying the sync of the Egresses.
+	minRetryDelay = 5 * time.Second
+	maxRetryDelay = 300 * time.Second
+	// syncKey is the only key of the work queue: all the Egresses are synced
+	// together, as a Pod is SNATed to the IP of the first Egress selecting it.
+	syncKey = "egresses"
+	// maxMark is the largest packet mark of the local Egress IPs, which must fit
+	// in the 8 bits of pkt_mark matched by the SNAT rules.
+	maxMark = 0xff
+)
+
+// ipAssigner assigns the Egress IPs to the transport interface of the Node.
+type ipAssigner interface {
+	// AssignIP assigns the IP to the transport interface and announces it with
+	// a gratuitous ARP. It does nothing if the IP is already assigned.
+	AssignIP(ip net.IP) error
+	// UnassignIP removes the IP from the transport interface if it was
+	// assigned by AssignIP, possibly by a previous agent instance. It does
+	// nothing otherwise, e.g. when the IP is the IP of the Node.
+	UnassignIP(ip net.IP) error
+}
+
+// Controller SNATs the egress traffic of the local Pods selected by Egresses to
+// the Egress IPs. When an Egress IP is assigned to this Node by
+// antrea-controller, the IP is configured on the transport interface, and the
+// packets tunneled to it by the other Nodes are SNATed by an iptables rule
+// matching the mark set by OVS, which identifies them by the IPs of the remote
+// Pods selected by the Egress. The traffic of the local Pods is marked if the
+// Egress IP is assigned to this Node, and tunneled to the Egress IP otherwise.
+type Controller struct {
+	nodeName        string
+	ofClient        openflow.Client
+	routeClient     route.Interface
+	ifaceStore      interfacestore.InterfaceStore
+	ipAssigner      ipAssigner
+	egressLister    crdlisters.EgressLister
+	podLister       corelisters.PodLister
+	namespaceLister corelisters.NamespaceLister
+	listersSynced   []cache.InformerSynced
+	queue           workqueue.RateLimitingInterface
+	// The following fields are only accessed by the single worker.
+	// localEgressIPs maps the Egress IPs assigned to this Node to their marks.
+	localEgressIPs map[string]uint32
+	// podEgresses maps the OVS ports of the local Pods to the Egress IPs and
+	// marks their flows were installed with.
+	podEgresses map[uint32]podEgress
+	// remotePodMarks maps the IPs of the remote Pods whose Egress IP is
+	// assigned to this Node to the marks their flows were installed with.
+	remotePodMarks map[string]uint32
+	// staleIPsRemoved is set once the Egress IPs left on the transport
+	// interface by a previous agent instance have been removed.
+	staleIPsRemoved bool
+}
+
+type podEgress struct {
+	egressIP string
+	// mark is 0 if the Egress IP is not assigned to this Node.
+	mark uint32
+}
+
+// NewEgressController creates a new Egress controller.
+func NewEgressController(
+	nodeName string,
+	ofClient openflow.Client,
+	routeClient route.Interface,
+	ifaceStore interfacestore.InterfaceStore,
+	nodeIP net.IP,
+	egressInformer crdinformers.EgressInformer,
+	podInformer coreinformers.PodInformer,
+	namespaceInformer coreinformers.NamespaceInformer) (*Controller, error) {
+	assigner, err := newIPAssigner(nodeIP)
+	if err != nil {
+		return nil, fmt.Errorf("error creating IP assigner: %v", err)
+	}
+	return newEgressController(nodeName, ofClient, routeClient, ifaceStore, assigner, egressInformer, podInformer, namespaceInformer), nil
+}
+
+func newEgressController(
+	nodeName string,
+	ofClient openflow.Client,
+	routeClient route.Interface,
+	ifaceStore interfacestore.InterfaceStore,
+	assigner ipAssigner,
+	egressInformer crdinformers.EgressInformer,
+	podInformer coreinformers.PodInformer,
+	namespaceInformer coreinformers.NamespaceInformer) *Controller {
+	c := &Controller{
+		nodeName:        nodeName,
+		ofClient:        ofClient,
+		routeClient:     routeClient,
+		ifaceStore:      ifaceStore,
+		ipAssigner:      assigner,
+		egressLister:    egressInformer.Lister(),
+		podLister:       podInformer.Lister(),
+		namespaceLister: namespaceInformer.Lister(),
+		listersSynced: []cache.InformerSynced{
+			egressInformer.Informer().HasSynced,
+			podInformer.Informer().HasSynced,
+			namespaceInformer.Informer().HasSynced,
+		},
+		queue:          workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "egress"),
+		localEgressIPs: map[string]uint32{},
+		podEgresses:    map[uint32]podEgress{},
+		remotePodMarks: map[string]uint32{},
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(old, cur interface{}) { c.enqueue(cur) },
+		DeleteFunc: c.enqueue,
+	}
+	egressInformer.Informer().AddEventHandlerWithResyncPeriod(handler, resyncPeriod)
+	podInformer.Informer().AddEventHandlerWithResyncPeriod(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.enqueuePod,
+			UpdateFunc: func(old, cur interface{}) { c.enqueuePod(cur) },
+			DeleteFunc: c.enqueuePod,
+		},
+		resyncPeriod,
+	)
+	namespaceInformer.Informer().AddEventHandlerWithResyncPeriod(handler, resyncPeriod)
+	return c
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	c.queue.Add(syncKey)
+}
+
+// enqueuePod enqueues the sync for the Pods which are not in the host network:
+// the egress traffic of the local Pods is handled by this Node, and the one of
+// the remote Pods when their Egress IP is assigned to this Node.
+func (c *Controller) enqueuePod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			klog.Errorf("Received unexpected object: %v", obj)
+			return
+		}
+		pod, ok = tombstone.Obj.(*corev1.Pod)
+		if !ok {
+			klog.Errorf("DeletedFinalStateUnknown contains non-Pod object: %v", tombstone.Obj)
+			return
+		}
+	}
+	if pod.Spec.HostNetwork {
+		return
+	}
+	c.queue.Add(syncKey)
+}
+
+// Run runs the controller with a single worker, until stopCh is closed.
+func (c *Controller) Run(stopCh <-chan struct{}) {
+	defer c.queue.ShutDown()
+
+	klog.Infof("Starting %s", controllerName)
+	defer klog.Infof("Shutting down %s", controllerName)
+
+	if !cache.WaitForNamedCacheSync(controllerName, stopCh, c.listersSynced...) {
+		return
+	}
+	// Sync once even if there is no Egress, to remove the stale Egress IPs.
+	c.queue.Add(syncKey)
+
+	go wait.Until(c.worker, time.Second, stopCh)
+	<-stopCh
+}
+
+func (c *Controller) worker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *Controller) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.syncEgresses(); err == nil {
+		c.queue.Forget(key)
+	} else {
+		c.queue.AddRateLimited(key)
+		klog.Errorf("Error syncing Egresses, requeuing. Error: %v", err)
+	}
+	return true
+}
+
+func selectorMatches(selector *metav1.LabelSelector, objLabels map[string]string) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return s.Matches(labels.Set(objLabels)), nil
+}
+
+// egressSelectsPod returns whether the Egress selects the Pod.
+func (c *Controller) egressSelectsPod(egress *corev1alpha1.Egress, pod *corev1.Pod) (bool, error) {
+	if egress.Spec.AppliedTo.NamespaceSelector != nil {
+		ns, err := c.namespaceLister.Get(pod.Namespace)
+		if err != nil {
+			return false, err
+		}
+		if matches, err := selectorMatches(egress.Spec.AppliedTo.NamespaceSelector, ns.Labels); err != nil || !matches {
+			return false, err
+		}
+	}
+	return selectorMatches(egress.Spec.AppliedTo.PodSelector, pod.Labels)
+}
+
+// desiredState returns the Egress IPs which must be assigned to this Node, the
+// Egress IPs of the OVS ports of the local Pods, and the Egress IPs assigned to
+// this Node of the IPs of the remote Pods. A Pod selected by several Egresses
+// is SNATed to the IP of the Egress whose name comes first. The Egresses which
+// have not been assigned to a Node yet are ignored. It also returns the IPs of
+// all the Egresses.
+func (c *Controller) desiredState() (localEgressIPs map[string]bool, podEgressIPs map[uint32]string, remotePodEgressIPs map[string]string, allEgressIPs []string, err error) {
+	egresses, err := c.egressLister.List(labels.Everything())
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	sort.Slice(egresses, func(i, j int) bool { return egresses[i].Name < egresses[j].Name })
+	pods, err := c.podLister.List(labels.Everything())
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	var localPods, remotePods []*corev1.Pod
+	for _, pod := range pods {
+		if pod.Spec.HostNetwork {
+			continue
+		}
+		if pod.Spec.NodeName == c.nodeName {
+			localPods = append(localPods, pod)
+		} else if net.ParseIP(pod.Status.PodIP).To4() != nil {
+			remotePods = append(remotePods, pod)
+		}
+	}
+
+	localEgressIPs = map[string]bool{}
+	podEgressIPs = map[uint32]string{}
+	remotePodEgressIPs = map[string]string{}
+	// remotePodsWithEgress are the remote Pods already selected by an Egress,
+	// whether its IP is assigned to this Node or not.
+	remotePodsWithEgress := map[*corev1.Pod]bool{}
+	for _, egress := range egresses {
+		egressIP := net.ParseIP(egress.Spec.EgressIP).To4()
+		if egressIP == nil {
+			klog.Warningf("Ignoring Egress %s with invalid IPv4 egressIP %q", egress.Name, egress.Spec.EgressIP)
+			continue
+		}
+		allEgressIPs = append(allEgressIPs, egressIP.String())
+		if egress.Status.EgressNode == "" {
+			continue
+		}
+		if egress.Status.EgressNode == c.nodeName {
+			localEgressIPs[egressIP.String()] = true
+		}
+		for _, pod := range localPods {
+			interfaces := c.ifaceStore.GetContainerInterfacesByPod(pod.Name, pod.Namespace)
+			// The Pod is synced again when its IP is set, once its interface has
+			// been created.
+			if len(interfaces) == 0 {
+				continue
+			}
+			ofPort := uint32(interfaces[0].OFPort)
+			if _, exists := podEgressIPs[ofPort]; exists {
+				continue
+			}
+			selected, err := c.egressSelectsPod(egress, pod)
+			if err != nil {
+				klog.Errorf("Failed to check if Egress %s selects Pod %s/%s: %v", egress.Name, pod.Namespace, pod.Name, err)
+				continue
+			}
+			if selected {
+				podEgressIPs[ofPort] = egressIP.String()
+			}
+		}
+		// The remote Nodes tunnel the traffic of their Pods to the Egress IP,
+		// and only the Node it is assigned to needs to mark it.
+		for _, pod := range remotePods {
+			if remotePodsWithEgress[pod] {
+				continue
+			}
+			selected, err := c.egressSelectsPod(egress, pod)
+			if err != nil {
+				klog.Errorf("Failed to check if Egress %s selects Pod %s/%s: %v", egress.Name, pod.Namespace, pod.Name, err)
+				continue
+			}
+			if !selected {
+				continue
+			}
+			remotePodsWithEgress[pod] = true
+			if egress.Status.EgressNode == c.nodeName {
+				remotePodEgressIPs[pod.Status.PodIP] = egressIP.String()
+			}
+		}
+	}
+	return localEgressIPs, podEgressIPs, remotePodEgressIPs, allEgressIPs, nil
+}
+
+// allocateMark returns the lowest mark which is not used by a local Egress IP.
+func (c *Controller) allocateMark() (uint32, error) {
+	used := make(map[uint32]bool, len(c.localEgressIPs))
+	for _, mark := range c.localEgressIPs {
+		used[mark] = true
+	}
+	for mark := uint32(1); mark <= maxMark; mark++ {
+		if !used[mark] {
+			return mark, nil
+		}
+	}
+	return 0, fmt.Errorf("no mark available, at most %d Egress IPs can be assigned to a Node", maxMark)
+}
+
+// installRemotePodFlows installs the flows of the remote Pods whose Egress IP is
+// the provided local Egress IP, unless they are already installed with the
+// mark.
+func (c *Controller) installRemotePodFlows(egressIP string, mark uint32, remotePodEgressIPs map[string]string) error {
+	for podIP, ip := range remotePodEgressIPs {
+		if ip != egressIP {
+			continue
+		}
+		if installedMark, exists := c.remotePodMarks[podIP]; exists && installedMark == mark {
+			continue
+		}
+		if err := c.ofClient.InstallRemotePodEgressNATFlow(net.ParseIP(podIP), mark); err != nil {
+			return err
+		}
+		c.remotePodMarks[podIP] = mark
+	}
+	return nil
+}
+
+// syncEgresses reconciles the Egress IPs assigned to this Node and the flows of
+// the local and remote Pods with the Egresses.
+func (c *Controller) syncEgresses() error {
+	startTime := time.Now()
+	defer func() {
+		klog.V(4).Infof("Finished syncing Egresses. (%v)", time.Since(startTime))
+	}()
+
+	localEgressIPs, podEgressIPs, remotePodEgressIPs, allEgressIPs, err := c.desiredState()
+	if err != nil {
+		return err
+	}
+
+	if !c.staleIPsRemoved {
+		for _, ip := range allEgressIPs {
+			if localEgressIPs[ip] {
+				continue
+			}
+			if err := c.ipAssigner.UnassignIP(net.ParseIP(ip)); err != nil {
+				return err
+			}
+		}
+		c.staleIPsRemoved = true
+	}
+
+	for ip := range localEgressIPs {
+		if _, exists := c.localEgressIPs[ip]; exists {
+			continue
+		}
+		mark, err := c.allocateMark()
+		if err != nil {
+			return err
+		}
+		egressIP := net.ParseIP(ip)
+		if err := c.routeClient.AddSNATRule(egressIP, mark); err != nil {
+			return err
+		}
+		if err := c.installRemotePodFlows(ip, mark, remotePodEgressIPs); err != nil {
+			return err
+		}
+		// The IP is assigned last, so that the traffic tunneled to it is SNATed
+		// as soon as the peer Nodes learn it.
+		if err := c.ipAssigner.AssignIP(egressIP); err != nil {
+			return err
+		}
+		klog.Infof("Assigned Egress IP %s to this Node with mark %#x", ip, mark)
+		c.localEgressIPs[ip] = mark
+	}
+
+	// The flows of the remote Pods are updated for the Egress IPs which were
+	// already assigned to this Node.
+	for ip, mark := range c.localEgressIPs {
+		if err := c.installRemotePodFlows(ip, mark, remotePodEgressIPs); err != nil {
+			return err
+		}
+	}
+	for podIP := range c.remotePodMarks {
+		if _, exists := remotePodEgressIPs[podIP]; exists {
+			continue
+		}
+		if err := c.ofClient.UninstallRemotePodEgressNATFlow(net.ParseIP(podIP)); err != nil {
+			return err
+		}
+		delete(c.remotePodMarks, podIP)
+	}
+
+	for ofPort, ip := range podEgressIPs {
+		desired := podEgress{egressIP: ip}
+		if localEgressIPs[ip] {
+			desired.mark = c.localEgressIPs[ip]
+		}
+		if c.podEgresses[ofPort] == desired {
+			continue
+		}
+		if err := c.ofClient.InstallPodEgressNATFlow(ofPort, net.ParseIP(ip), desired.mark); err != nil {
+			return err
+		}
+		c.podEgresses[ofPort] = desired
+	}
+	for ofPort := range c.podEgresses {
+		if _, exists := podEgressIPs[ofPort]; exists {
+			continue
+		}
+		if err := c.ofClient.UninstallPodEgressNATFlow(ofPort); err != nil {
+			return err
+		}
+		delete(c.podEgresses, ofPort)
+	}
+
+	for ip, mark := range c.localEgressIPs {
+		if localEgressIPs[ip] {
+			continue
+		}
+		egressIP := net.ParseIP(ip)
+		if err := c.ipAssigner.UnassignIP(egressIP); err != nil {
+			return err
+		}
+		// The flows of the remote Pods of the Egress IP have been uninstalled
+		// above, as they are not desired anymore.
+		if err := c.routeClient.DeleteSNATRule(mark); err != nil {
+			return err
+		}
+		klog.Infof("Unassigned Egress IP %s from this Node", ip)
+		delete(c.localEgressIPs, ip)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package egress
+
+import (
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/interfacestore"
+	openflowtest "github.com/vmware-tanzu/antrea/pkg/agent/openflow/testing"
+	routetest "github.com/vmware-tanzu/antrea/pkg/agent/route/testing"
+	corev1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/core/v1alpha1"
+	fakeversioned "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+	crdinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions"
+)
+
+const (
+	localNodeName  = "node1"
+	remoteNodeName = "node2"
+)
+
+var (
+	egressIP1 = net.ParseIP("10.10.10.1")
+	egressIP2 = net.ParseIP("10.10.10.2")
+	// remotePodIP is the IP of pod3, which runs on the remote Node.
+	remotePodIP = net.ParseIP("10.10.20.3")
+)
+
+type fakeIPAssigner struct {
+	assignedIPs map[string]bool
+}
+
+func (a *fakeIPAssigner) AssignIP(ip net.IP) error {
+	a.assignedIPs[ip.String()] = true
+	return nil
+}
+
+func (a *fakeIPAssigner) UnassignIP(ip net.IP) error {
+	delete(a.assignedIPs, ip.String())
+	return nil
+}
+
+type fakeController struct {
+	*Controller
+	mockOFClient    *openflowtest.MockClient
+	mockRouteClient *routetest.MockInterface
+	ipAssigner      *fakeIPAssigner
+	crdInformers    crdinformers.SharedInformerFactory
+	informers       informers.SharedInformerFactory
+}
+
+func newFakeController(t *testing.T, ctrl *gomock.Controller) *fakeController {
+	mockOFClient := openflowtest.NewMockClient(ctrl)
+	mockRouteClient := routetest.NewMockInterface(ctrl)
+	assigner := &fakeIPAssigner{assignedIPs: map[string]bool{}}
+
+	ifaceStore := interfacestore.NewInterfaceStore()
+	for i, name := range []string{"pod1", "pod2"} {
+		iface := interfacestore.NewContainerInterface(name, name, name, "ns1", nil, nil)
+		iface.OVSPortConfig = &interfacestore.OVSPortConfig{OFPort: int32(i + 1)}
+		ifaceStore.AddInterface(iface)
+	}
+
+	crdInformerFactory := crdinformers.NewSharedInformerFactory(fakeversioned.NewSimpleClientset(), 0)
+	informerFactory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	c := newEgressController(localNodeName, mockOFClient, mockRouteClient, ifaceStore, assigner,
+		crdInformerFactory.Core().V1alpha1().Egresses(),
+		informerFactory.Core().V1().Pods(),
+		informerFactory.Core().V1().Namespaces())
+
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1", Labels: map[string]string{"env": "test"}}}
+	require.NoError(t, informerFactory.Core().V1().Namespaces().Informer().GetIndexer().Add(namespace))
+	for _, name := range []string{"pod1", "pod2"} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: name, Labels: map[string]string{"app": name}},
+			Spec:       corev1.PodSpec{NodeName: localNodeName},
+		}
+		require.NoError(t, informerFactory.Core().V1().Pods().Informer().GetIndexer().Add(pod))
+	}
+	remotePod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "pod3", Labels: map[string]string{"app": "pod3"}},
+		Spec:       corev1.PodSpec{NodeName: remoteNodeName},
+		Status:     corev1.PodStatus{PodIP: remotePodIP.String()},
+	}
+	require.NoError(t, informerFactory.Core().V1().Pods().Informer().GetIndexer().Add(remotePod))
+	return &fakeController{
+		Controller:      c,
+		mockOFClient:    mockOFClient,
+		mockRouteClient: mockRouteClient,
+		ipAssigner:      assigner,
+		crdInformers:    crdInformerFactory,
+		informers:       informerFactory,
+	}
+}
+
+func (c *fakeController) setEgress(t *testing.T, egress *corev1alpha1.Egress) {
+	require.NoError(t, c.crdInformers.Core().V1alpha1().Egresses().Informer().GetIndexer().Update(egress))
+}
+
+func newEgress(name string, egressIP net.IP, egressNode string, podSelector *metav1.LabelSelector) *corev1alpha1.Egress {
+	return &corev1alpha1.Egress{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: corev1alpha1.EgressSpec{
+			AppliedTo: corev1alpha1.EgressAppliedTo{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "test"}},
+				PodSelector:       podSelector,
+			},
+			EgressIP: egressIP.String(),
+		},
+		Status: corev1alpha1.EgressStatus{EgressNode: egressNode},
+	}
+}
+
+func TestSyncLocalEgress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	c := newFakeController(t, ctrl)
+
+	// egress1 selects all the Pods and is assigned to this Node.
+	c.setEgress(t, newEgress("egress1", egressIP1, localNodeName, nil))
+	c.mockRouteClient.EXPECT().AddSNATRule(egressIP1, uint32(1))
+	c.mockOFClient.EXPECT().InstallRemotePodEgressNATFlow(remotePodIP, uint32(1))
+	c.mockOFClient.EXPECT().InstallPodEgressNATFlow(uint32(1), egressIP1, uint32(1))
+	c.mockOFClient.EXPECT().InstallPodEgressNATFlow(uint32(2), egressIP1, uint32(1))
+	require.NoError(t, c.syncEgresses())
+	assert.Equal(t, map[string]bool{egressIP1.String(): true}, c.ipAssigner.assignedIPs)
+
+	// Syncing again without changes is a no-op.
+	require.NoError(t, c.syncEgresses())
+
+	// egress0 comes first and takes over pod2, with an Egress IP assigned to
+	// another Node.
+	c.setEgress(t, newEgress("egress0", egressIP2, remoteNodeName, &metav1.LabelSelector{MatchLabels: map[string]string{"app": "pod2"}}))
+	c.mockOFClient.EXPECT().InstallPodEgressNATFlow(uint32(2), egressIP2, uint32(0))
+	require.NoError(t, c.syncEgresses())
+
+	// egress1 fails over to another Node.
+	c.setEgress(t, newEgress("egress1", egressIP1, remoteNodeName, nil))
+	c.mockOFClient.EXPECT().InstallPodEgressNATFlow(uint32(1), egressIP1, uint32(0))
+	c.mockOFClient.EXPECT().UninstallRemotePodEgressNATFlow(remotePodIP)
+	c.mockRouteClient.EXPECT().DeleteSNATRule(uint32(1))
+	require.NoError(t, c.syncEgresses())
+	assert.Empty(t, c.ipAssigner.assignedIPs)
+
+	// Both Egresses are deleted.
+	require.NoError(t, c.crdInformers.Core().V1alpha1().Egresses().Informer().GetIndexer().Delete(newEgress("egress0", egressIP2, "", nil)))
+	require.NoError(t, c.crdInformers.Core().V1alpha1().Egresses().Informer().GetIndexer().Delete(newEgress("egress1", egressIP1, "", nil)))
+	c.mockOFClient.EXPECT().UninstallPodEgressNATFlow(uint32(1))
+	c.mockOFClient.EXPECT().UninstallPodEgressNATFlow(uint32(2))
+	require.NoError(t, c.syncEgresses())
+}
+
+func TestSyncRemovesStaleEgressIPs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	c := newFakeController(t, ctrl)
+
+	// egressIP1 was assigned to this Node by a previous agent instance, and
+	// the Egress is not assigned to any Node now.
+	c.ipAssigner.assignedIPs[egressIP1.String()] = true
+	c.setEgress(t, newEgress("egress1", egressIP1, "", nil))
+	require.NoError(t, c.syncEgresses())
+	assert.Empty(t, c.ipAssigner.assignedIPs)
+}
+
+func TestAllocateMark(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	c := newFakeController(t, ctrl)
+
+	c.localEgressIPs = map[string]uint32{"10.10.10.1": 1, "10.10.10.3": 3}
+	mark, err := c.allocateMark()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), mark)
+
+	for i := uint32(1); i <= maxMark; i++ {
+		c.localEgressIPs[net.IPv4(10, 10, 11, byte(i)).String()] = i
+	}
+	_, err = c.allocateMark()
+	assert.Error(t, err)
+}
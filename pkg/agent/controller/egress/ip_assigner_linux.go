@@ -0,0 +1,98 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package egress
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/util"
+	"github.com/vmware-tanzu/antrea/pkg/agent/util/arping"
+)
+
+// linuxIPAssigner assigns the Egress IPs as /32 addresses of the transport
+// interface. As the addresses configured by users usually have the prefix
+// length of the Node subnet, only the /32 addresses are considered to have
+// been assigned by Antrea.
+type linuxIPAssigner struct {
+	link netlink.Link
+}
+
+func newIPAssigner(nodeIP net.IP) (*linuxIPAssigner, error) {
+	_, link, err := util.GetIPNetDeviceFromIP(nodeIP)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the transport interface of IP %s: %v", nodeIP, err)
+	}
+	return &linuxIPAssigner{link: link}, nil
+}
+
+// getAddr returns the IPv4 address of the transport interface which is equal
+// to ip, or nil if there is none.
+func (a *linuxIPAssigner) getAddr(ip net.IP) (*netlink.Addr, error) {
+	addrs, err := netlink.AddrList(a.link, netlink.FAMILY_V4)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the addresses of interface %s: %v", a.link.Attrs().Name, err)
+	}
+	for i := range addrs {
+		if addrs[i].IP.Equal(ip) {
+			return &addrs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (a *linuxIPAssigner) AssignIP(ip net.IP) error {
+	addr, err := a.getAddr(ip)
+	if err != nil {
+		return err
+	}
+	if addr != nil {
+		return nil
+	}
+	addr = &netlink.Addr{IPNet: &net.IPNet{IP: ip, Mask: net.CIDRMask(32, 32)}}
+	if err := netlink.AddrAdd(a.link, addr); err != nil {
+		return fmt.Errorf("error adding IP %s to interface %s: %v", ip, a.link.Attrs().Name, err)
+	}
+	iface, err := net.InterfaceByIndex(a.link.Attrs().Index)
+	if err != nil {
+		return err
+	}
+	// Announce the new owner of the IP, in case it was previously assigned to
+	// another Node.
+	if err := arping.GratuitousARPOverIface(ip, iface); err != nil {
+		klog.Warningf("Failed to send gratuitous ARP for IP %s: %v", ip, err)
+	}
+	return nil
+}
+
+func (a *linuxIPAssigner) UnassignIP(ip net.IP) error {
+	addr, err := a.getAddr(ip)
+	if err != nil {
+		return err
+	}
+	if addr == nil {
+		return nil
+	}
+	if ones, _ := addr.Mask.Size(); ones != 32 {
+		klog.Warningf("Not removing IP %s from interface %s as it was not assigned by Antrea", addr.IPNet, a.link.Attrs().Name)
+		return nil
+	}
+	if err := netlink.AddrDel(a.link, addr); err != nil {
+		return fmt.Errorf("error removing IP %s from interface %s: %v", ip, a.link.Attrs().Name, err)
+	}
+	return nil
+}
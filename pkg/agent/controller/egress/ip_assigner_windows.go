@@ -0,0 +1,35 @@
+// +build windows
+
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package egress
+
+import (
+	"errors"
+	"net"
+)
+
+type windowsIPAssigner struct{}
+
+func newIPAssigner(nodeIP net.IP) (*windowsIPAssigner, error) {
+	return nil, errors.New("Egress is not supported on Windows")
+}
+
+func (a *windowsIPAssigner) AssignIP(ip net.IP) error {
+	return errors.New("Egress is not supported on Windows")
+}
+
+func (a *windowsIPAssigner) UnassignIP(ip net.IP) error {
+	return errors.New("Egress is not supported on Windows")
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	utilipsec "github.com/vmware-tanzu/antrea/pkg/util/ipsec"
+)
+
+const (
+	controllerName = "AntreaAgentIPSecKeyController"
+	// Set resyncPeriod to 0 to disable resyncing.
+	resyncPeriod time.Duration = 0
+	// How long to wait before retrying the processing of the Secret.
+	minRetryDelay = 5 * time.Second
+	maxRetryDelay = 300 * time.Second
+)
+
+// PSKUpdater updates the PSK used by the IPSec tunnels of the Node.
+type PSKUpdater interface {
+	UpdateIPSecPSK(psk string) error
+}
+
+// KeyController watches the antrea-ipsec Secret, applies the current PSK to the
+// IPSec tunnels whenever it's rotated by antrea-controller, and acknowledges the
+// generation of the applied PSK with an annotation of the Node.
+type KeyController struct {
+	k8sClient          clientset.Interface
+	nodeName           string
+	namespace          string
+	pskUpdater         PSKUpdater
+	secretInformer     cache.SharedIndexInformer
+	secretLister       corelisters.SecretLister
+	secretListerSynced cache.InformerSynced
+	queue              workqueue.RateLimitingInterface
+	// appliedGeneration is the generation of the PSK applied to the IPSec
+	// tunnels. It's only accessed by the single worker.
+	appliedGeneration int64
+}
+
+// NewKeyController creates a new KeyController for the antrea-ipsec Secret in
+// the provided Namespace.
+func NewKeyController(k8sClient clientset.Interface, nodeName, namespace string, pskUpdater PSKUpdater) *KeyController {
+	// Only the antrea-ipsec Secret is watched, the agent is not allowed to
+	// access the other Secrets.
+	secretInformer := coreinformers.NewFilteredSecretInformer(k8sClient, namespace, resyncPeriod, cache.Indexers{}, func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", utilipsec.SecretName).String()
+	})
+	c := &KeyController{
+		k8sClient:          k8sClient,
+		nodeName:           nodeName,
+		namespace:          namespace,
+		pskUpdater:         pskUpdater,
+		secretInformer:     secretInformer,
+		secretLister:       corelisters.NewSecretLister(secretInformer.GetIndexer()),
+		secretListerSynced: secretInformer.HasSynced,
+		queue:              workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "ipseckey"),
+		appliedGeneration:  -1,
+	}
+	secretInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.queue.Add(utilipsec.SecretName)
+		},
+		UpdateFunc: func(oldObj, curObj interface{}) {
+			c.queue.Add(utilipsec.SecretName)
+		},
+	})
+	return c
+}
+
+func (c *KeyController) Run(stopCh <-chan struct{}) {
+	defer c.queue.ShutDown()
+
+	klog.Infof("Starting %s", controllerName)
+	defer klog.Infof("Shutting down %s", controllerName)
+
+	go c.secretInformer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, c.secretListerSynced) {
+		klog.Errorf("Unable to sync caches for %s", controllerName)
+		return
+	}
+
+	// The PSK must be applied in order, a single worker is used.
+	go wait.Until(c.worker, time.Second, stopCh)
+	<-stopCh
+}
+
+func (c *KeyController) worker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *KeyController) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.syncSecret(); err == nil {
+		c.queue.Forget(key)
+	} else {
+		c.queue.AddRateLimited(key)
+		klog.Errorf("Error syncing IPSec Secret %s, requeuing. Error: %v", key, err)
+	}
+	return true
+}
+
+func (c *KeyController) syncSecret() error {
+	secret, err := c.secretLister.Secrets(c.namespace).Get(utilipsec.SecretName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			klog.Warningf("IPSec Secret %s/%s not found", c.namespace, utilipsec.SecretName)
+			return nil
+		}
+		return err
+	}
+	generation := utilipsec.GetKeyGeneration(secret.Annotations)
+	if generation == c.appliedGeneration {
+		return nil
+	}
+	psk := string(secret.Data[utilipsec.PSKKey])
+	if psk == "" {
+		klog.Errorf("IPSec Secret %s/%s has no PSK", c.namespace, utilipsec.SecretName)
+		return nil
+	}
+	if err := c.pskUpdater.UpdateIPSecPSK(psk); err != nil {
+		return err
+	}
+	if err := c.acknowledge(generation); err != nil {
+		return err
+	}
+	c.appliedGeneration = generation
+	klog.Infof("Applied IPSec PSK of generation %d", generation)
+	return nil
+}
+
+// acknowledge sets the generation of the applied PSK in the annotation of the
+// Node, so that antrea-controller can complete the key rotation once all the
+// Nodes have applied the new PSK.
+func (c *KeyController) acknowledge(generation int64) error {
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				utilipsec.KeyGenerationAnnotationKey: strconv.FormatInt(generation, 10),
+			},
+		},
+	})
+	_, err := c.k8sClient.CoreV1().Nodes().Patch(context.TODO(), c.nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
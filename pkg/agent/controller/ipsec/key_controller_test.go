@@ -0,0 +1,80 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	utilipsec "github.com/vmware-tanzu/antrea/pkg/util/ipsec"
+)
+
+type fakePSKUpdater struct {
+	psks []string
+}
+
+func (u *fakePSKUpdater) UpdateIPSecPSK(psk string) error {
+	u.psks = append(u.psks, psk)
+	return nil
+}
+
+func newSecret(psk string, generation string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        utilipsec.SecretName,
+			Namespace:   "kube-system",
+			Annotations: map[string]string{utilipsec.KeyGenerationAnnotationKey: generation},
+		},
+		Data: map[string][]byte{utilipsec.PSKKey: []byte(psk)},
+	}
+}
+
+func TestSyncSecret(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	client := fake.NewSimpleClientset(node)
+	updater := &fakePSKUpdater{}
+	c := NewKeyController(client, "node1", "kube-system", updater)
+
+	getAcknowledgedGeneration := func() string {
+		node, err := client.CoreV1().Nodes().Get(context.TODO(), "node1", metav1.GetOptions{})
+		require.NoError(t, err)
+		return node.Annotations[utilipsec.KeyGenerationAnnotationKey]
+	}
+
+	// The initial PSK is applied and acknowledged.
+	secret := newSecret("psk0", "")
+	require.NoError(t, c.secretInformer.GetIndexer().Add(secret))
+	require.NoError(t, c.syncSecret())
+	assert.Equal(t, []string{"psk0"}, updater.psks)
+	assert.Equal(t, "0", getAcknowledgedGeneration())
+
+	// The PSK is not applied again if its generation doesn't change.
+	require.NoError(t, c.syncSecret())
+	assert.Equal(t, []string{"psk0"}, updater.psks)
+
+	// The rotated PSK is applied and acknowledged.
+	secret = newSecret("psk1", "1")
+	secret.Data[utilipsec.PreviousPSKKey] = []byte("psk0")
+	require.NoError(t, c.secretInformer.GetIndexer().Update(secret))
+	require.NoError(t, c.syncSecret())
+	assert.Equal(t, []string{"psk0", "psk1"}, updater.psks)
+	assert.Equal(t, "1", getAcknowledgedGeneration())
+}
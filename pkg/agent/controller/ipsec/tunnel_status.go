@@ -0,0 +1,313 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/controller/noderoute"
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	clientset "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+)
+
+const (
+	statusReporterName = "AntreaAgentIPSecTunnelStatusReporter"
+	// LocalNodeLabelKey is the label of the IPSecTunnelStatuses whose value
+	// is the Node reporting them.
+	LocalNodeLabelKey = "ops.antrea.tanzu.vmware.com/local-node"
+	// defaultReportInterval is how often the SAs are read from the kernel.
+	defaultReportInterval = 60 * time.Second
+	// defaultFailureTimeout is how long the SAs of a tunnel can be missing
+	// before the tunnel is considered failed.
+	defaultFailureTimeout = 3 * time.Minute
+)
+
+// xfrmRunner runs "ip xfrm" with the provided arguments and returns its output.
+type xfrmRunner func(args ...string) ([]byte, error)
+
+func runIPXfrm(args ...string) ([]byte, error) {
+	return exec.Command("ip", append([]string{"xfrm"}, args...)...).Output()
+}
+
+// tunnelRecord keeps the state of a tunnel between two reports.
+type tunnelRecord struct {
+	// outboundSPIs are the SPIs of the outbound SAs seen so far, which are
+	// used to count the rekeys.
+	outboundSPIs sets.String
+	rekeys       int64
+	// missingSince is when the SAs of the tunnel were found missing, it's
+	// zero when the tunnel is established.
+	missingSince time.Time
+}
+
+// TunnelStatusReporter periodically reads the IPSec SAs and policies of the
+// kernel with "ip xfrm", and reports the status of the tunnel to each other
+// Node as an IPSecTunnelStatus named "<localNode>.<remoteNode>".
+type TunnelStatusReporter struct {
+	crdClient        clientset.Interface
+	nodeName         string
+	nodeIP           net.IP
+	nodeLister       corelisters.NodeLister
+	nodeListerSynced cache.InformerSynced
+	runXfrm          xfrmRunner
+	clock            clock.Clock
+	reportInterval   time.Duration
+	failureTimeout   time.Duration
+	// tunnels is keyed by the name of the remote Node. It's only accessed by
+	// the report loop.
+	tunnels map[string]*tunnelRecord
+}
+
+// NewTunnelStatusReporter creates a TunnelStatusReporter for the Node.
+func NewTunnelStatusReporter(crdClient clientset.Interface, nodeName string, nodeIP net.IP, nodeInformer coreinformers.NodeInformer) *TunnelStatusReporter {
+	return newTunnelStatusReporter(crdClient, nodeName, nodeIP, nodeInformer, runIPXfrm, clock.RealClock{})
+}
+
+func newTunnelStatusReporter(crdClient clientset.Interface, nodeName string, nodeIP net.IP, nodeInformer coreinformers.NodeInformer, runXfrm xfrmRunner, clock clock.Clock) *TunnelStatusReporter {
+	return &TunnelStatusReporter{
+		crdClient:        crdClient,
+		nodeName:         nodeName,
+		nodeIP:           nodeIP,
+		nodeLister:       nodeInformer.Lister(),
+		nodeListerSynced: nodeInformer.Informer().HasSynced,
+		runXfrm:          runXfrm,
+		clock:            clock,
+		reportInterval:   defaultReportInterval,
+		failureTimeout:   defaultFailureTimeout,
+		tunnels:          map[string]*tunnelRecord{},
+	}
+}
+
+func (r *TunnelStatusReporter) Run(stopCh <-chan struct{}) {
+	klog.Infof("Starting %s", statusReporterName)
+	defer klog.Infof("Shutting down %s", statusReporterName)
+
+	if !cache.WaitForNamedCacheSync(statusReporterName, stopCh, r.nodeListerSynced) {
+		return
+	}
+
+	wait.Until(func() {
+		if err := r.report(); err != nil {
+			klog.Errorf("Error reporting IPSec tunnel status: %v", err)
+		}
+	}, r.reportInterval, stopCh)
+}
+
+// tunnelStatusName returns the name of the IPSecTunnelStatus of the tunnel
+// between the two Nodes.
+func tunnelStatusName(localNode, remoteNode string) string {
+	return fmt.Sprintf("%s.%s", localNode, remoteNode)
+}
+
+// computeStatuses computes the status of the tunnel to each remote Node from
+// the SAs and policies of the kernel.
+func (r *TunnelStatusReporter) computeStatuses(remoteNodeIPs map[string]net.IP, states []*xfrmState, policies []*xfrmPolicy) []*opsv1alpha1.IPSecTunnelStatus {
+	now := r.clock.Now()
+	var statuses []*opsv1alpha1.IPSecTunnelStatus
+	for remoteNode, remoteIP := range remoteNodeIPs {
+		record, ok := r.tunnels[remoteNode]
+		if !ok {
+			record = &tunnelRecord{outboundSPIs: sets.NewString()}
+			r.tunnels[remoteNode] = record
+		}
+		status := &opsv1alpha1.IPSecTunnelStatus{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   tunnelStatusName(r.nodeName, remoteNode),
+				Labels: map[string]string{LocalNodeLabelKey: r.nodeName},
+			},
+			LocalNode:  r.nodeName,
+			RemoteNode: remoteNode,
+		}
+		var lastRekey time.Time
+		hasInbound, hasOutbound := false, false
+		newSPIs := sets.NewString()
+		for _, s := range states {
+			switch {
+			case s.src.Equal(r.nodeIP) && s.dst.Equal(remoteIP):
+				hasOutbound = true
+				status.BytesOut += s.bytes
+				if s.spi != "" {
+					newSPIs.Insert(s.spi)
+				}
+			case s.src.Equal(remoteIP) && s.dst.Equal(r.nodeIP):
+				hasInbound = true
+				status.BytesIn += s.bytes
+			default:
+				continue
+			}
+			if s.addTime.After(lastRekey) {
+				lastRekey = s.addTime
+			}
+		}
+		// The outbound SAs of the first report are the initial ones, any
+		// SPI seen afterwards is the result of a rekey.
+		if record.outboundSPIs.Len() > 0 {
+			record.rekeys += int64(newSPIs.Difference(record.outboundSPIs).Len())
+		}
+		record.outboundSPIs = record.outboundSPIs.Union(newSPIs)
+		status.RekeysTotal = record.rekeys
+		if !lastRekey.IsZero() {
+			status.LastRekeyTimestamp = metav1.NewTime(lastRekey)
+		}
+
+		if hasInbound && hasOutbound {
+			record.missingSince = time.Time{}
+			status.State = opsv1alpha1.IPSecTunnelEstablished
+		} else {
+			if record.missingSince.IsZero() {
+				record.missingSince = now
+			}
+			if now.Sub(record.missingSince) >= r.failureTimeout {
+				status.State = opsv1alpha1.IPSecTunnelFailed
+			} else {
+				status.State = opsv1alpha1.IPSecTunnelInitializing
+			}
+			if !hasPolicy(policies, r.nodeIP, remoteIP) {
+				klog.V(2).Infof("No outbound IPSec policy to Node %s (%s)", remoteNode, remoteIP)
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	// Forget the Nodes which have been removed.
+	for remoteNode := range r.tunnels {
+		if _, ok := remoteNodeIPs[remoteNode]; !ok {
+			delete(r.tunnels, remoteNode)
+		}
+	}
+	return statuses
+}
+
+func hasPolicy(policies []*xfrmPolicy, src, dst net.IP) bool {
+	for _, p := range policies {
+		if p.dir == "out" && p.src.Equal(src) && p.dst.Equal(dst) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteNodeIPs returns the transport IPs of the other Nodes, keyed by Node
+// name.
+func (r *TunnelStatusReporter) remoteNodeIPs() (map[string]net.IP, error) {
+	nodes, err := r.nodeLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	ips := make(map[string]net.IP, len(nodes))
+	for _, node := range nodes {
+		if node.Name == r.nodeName {
+			continue
+		}
+		ip, err := noderoute.GetNodeAddr(node)
+		if err != nil {
+			klog.Warningf("Skipping IPSec tunnel status of Node %s: %v", node.Name, err)
+			continue
+		}
+		ips[node.Name] = ip
+	}
+	return ips, nil
+}
+
+func (r *TunnelStatusReporter) report() error {
+	stateOutput, err := r.runXfrm("-s", "state")
+	if err != nil {
+		return fmt.Errorf("error running 'ip -s xfrm state': %v", err)
+	}
+	states, err := parseXfrmState(stateOutput)
+	if err != nil {
+		return err
+	}
+	policyOutput, err := r.runXfrm("policy")
+	if err != nil {
+		return fmt.Errorf("error running 'ip xfrm policy': %v", err)
+	}
+	policies, err := parseXfrmPolicy(policyOutput)
+	if err != nil {
+		return err
+	}
+	remoteNodeIPs, err := r.remoteNodeIPs()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	statuses := r.computeStatuses(remoteNodeIPs, states, policies)
+	for _, status := range statuses {
+		if err := r.updateStatus(status); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := r.deleteStaleStatuses(remoteNodeIPs); err != nil {
+		errs = append(errs, err)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (r *TunnelStatusReporter) updateStatus(status *opsv1alpha1.IPSecTunnelStatus) error {
+	client := r.crdClient.OpsV1alpha1().IPSecTunnelStatuses()
+	existing, err := client.Get(context.TODO(), status.Name, metav1.GetOptions{})
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return err
+		}
+		_, err = client.Create(context.TODO(), status, metav1.CreateOptions{})
+		return err
+	}
+	if existing.State == status.State && existing.BytesIn == status.BytesIn && existing.BytesOut == status.BytesOut &&
+		existing.RekeysTotal == status.RekeysTotal && existing.LastRekeyTimestamp.Equal(&status.LastRekeyTimestamp) {
+		return nil
+	}
+	status.ResourceVersion = existing.ResourceVersion
+	_, err = client.Update(context.TODO(), status, metav1.UpdateOptions{})
+	return err
+}
+
+// deleteStaleStatuses deletes the IPSecTunnelStatuses reported by this Node
+// for the Nodes which don't exist anymore.
+func (r *TunnelStatusReporter) deleteStaleStatuses(remoteNodeIPs map[string]net.IP) error {
+	client := r.crdClient.OpsV1alpha1().IPSecTunnelStatuses()
+	selector := labels.SelectorFromSet(labels.Set{LocalNodeLabelKey: r.nodeName})
+	list, err := client.List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range list.Items {
+		status := &list.Items[i]
+		if _, ok := remoteNodeIPs[status.RemoteNode]; ok {
+			continue
+		}
+		if err := client.Delete(context.TODO(), status.Name, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
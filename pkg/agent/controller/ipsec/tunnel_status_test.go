@@ -0,0 +1,133 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	fakeversioned "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+)
+
+func newNode(name, ip string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}},
+		},
+	}
+}
+
+type fakeXfrm struct {
+	state  string
+	policy string
+}
+
+func (f *fakeXfrm) run(args ...string) ([]byte, error) {
+	if args[len(args)-1] == "policy" {
+		return []byte(f.policy), nil
+	}
+	return []byte(f.state), nil
+}
+
+func newTestReporter(t *testing.T, xfrm *fakeXfrm, fakeClock clock.Clock, nodes ...*corev1.Node) (*TunnelStatusReporter, *fakeversioned.Clientset, cache.Indexer) {
+	k8sClient := fake.NewSimpleClientset()
+	crdClient := fakeversioned.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(k8sClient, 0)
+	nodeInformer := informerFactory.Core().V1().Nodes()
+	nodeIndexer := nodeInformer.Informer().GetIndexer()
+	for _, n := range nodes {
+		require.NoError(t, nodeIndexer.Add(n))
+	}
+	r := newTunnelStatusReporter(crdClient, "node1", net.ParseIP("192.168.1.2"), nodeInformer, xfrm.run, fakeClock)
+	return r, crdClient, nodeIndexer
+}
+
+func getTunnelStatus(t *testing.T, client *fakeversioned.Clientset, name string) *opsv1alpha1.IPSecTunnelStatus {
+	status, err := client.OpsV1alpha1().IPSecTunnelStatuses().Get(context.TODO(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+	return status
+}
+
+func TestReportEstablishedTunnel(t *testing.T) {
+	xfrm := &fakeXfrm{state: sampleXfrmState, policy: sampleXfrmPolicy}
+	r, client, _ := newTestReporter(t, xfrm, clock.NewFakeClock(time.Now()),
+		newNode("node1", "192.168.1.2"), newNode("node2", "192.168.1.3"))
+
+	require.NoError(t, r.report())
+	status := getTunnelStatus(t, client, "node1.node2")
+	assert.Equal(t, "node1", status.LocalNode)
+	assert.Equal(t, "node2", status.RemoteNode)
+	assert.Equal(t, "node1", status.Labels[LocalNodeLabelKey])
+	assert.Equal(t, opsv1alpha1.IPSecTunnelEstablished, status.State)
+	assert.Equal(t, int64(2080), status.BytesIn)
+	assert.Equal(t, int64(1040), status.BytesOut)
+	assert.Equal(t, int64(0), status.RekeysTotal)
+	assert.True(t, status.LastRekeyTimestamp.Time.Equal(time.Date(2020, 11, 10, 10, 0, 1, 0, time.Local)))
+
+	// A new outbound SA is installed by a rekey.
+	xfrm.state = strings.Replace(sampleXfrmState, "0xc1b4a3f2(3249841138)", "0xaabbccdd(2864434397)", 1)
+	require.NoError(t, r.report())
+	status = getTunnelStatus(t, client, "node1.node2")
+	assert.Equal(t, int64(1), status.RekeysTotal)
+}
+
+func TestReportMissingSAs(t *testing.T) {
+	xfrm := &fakeXfrm{policy: sampleXfrmPolicy}
+	fakeClock := clock.NewFakeClock(time.Now())
+	r, client, _ := newTestReporter(t, xfrm, fakeClock,
+		newNode("node1", "192.168.1.2"), newNode("node2", "192.168.1.3"))
+
+	require.NoError(t, r.report())
+	assert.Equal(t, opsv1alpha1.IPSecTunnelInitializing, getTunnelStatus(t, client, "node1.node2").State)
+
+	fakeClock.Step(defaultFailureTimeout)
+	require.NoError(t, r.report())
+	assert.Equal(t, opsv1alpha1.IPSecTunnelFailed, getTunnelStatus(t, client, "node1.node2").State)
+
+	// The tunnel recovers once the SAs are installed.
+	xfrm.state = sampleXfrmState
+	require.NoError(t, r.report())
+	assert.Equal(t, opsv1alpha1.IPSecTunnelEstablished, getTunnelStatus(t, client, "node1.node2").State)
+}
+
+func TestReportDeletesStaleStatuses(t *testing.T) {
+	xfrm := &fakeXfrm{state: sampleXfrmState, policy: sampleXfrmPolicy}
+	node2 := newNode("node2", "192.168.1.3")
+	r, client, nodeIndexer := newTestReporter(t, xfrm, clock.NewFakeClock(time.Now()), newNode("node1", "192.168.1.2"), node2)
+
+	require.NoError(t, r.report())
+	getTunnelStatus(t, client, "node1.node2")
+
+	require.NoError(t, nodeIndexer.Delete(node2))
+	require.NoError(t, r.report())
+	_, err := client.OpsV1alpha1().IPSecTunnelStatuses().Get(context.TODO(), "node1.node2", metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+	assert.Empty(t, r.tunnels)
+}
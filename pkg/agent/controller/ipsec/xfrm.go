@@ -0,0 +1,155 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xfrmTimeLayout is the layout of the timestamps printed by "ip -s xfrm state",
+// which are in the local time zone.
+const xfrmTimeLayout = "2006-01-02 15:04:05"
+
+var (
+	xfrmBytesRegexp   = regexp.MustCompile(`^(\d+)\(bytes\)`)
+	xfrmAddTimeRegexp = regexp.MustCompile(`^add (\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})`)
+)
+
+// xfrmState is an IPSec Security Association (SA) parsed from the output of
+// "ip -s xfrm state".
+type xfrmState struct {
+	src     net.IP
+	dst     net.IP
+	spi     string
+	bytes   int64
+	addTime time.Time
+}
+
+// xfrmPolicy is an IPSec policy parsed from the output of "ip xfrm policy".
+type xfrmPolicy struct {
+	src net.IP
+	dst net.IP
+	dir string
+}
+
+// parseXfrmState parses the output of "ip -s xfrm state". Each SA starts with a
+// "src <ip> dst <ip>" line, followed by indented lines describing it.
+func parseXfrmState(output []byte) ([]*xfrmState, error) {
+	var states []*xfrmState
+	var current *xfrmState
+	inLifetimeCurrent := false
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "src ") {
+			src, dst, err := parseXfrmSelector(line)
+			if err != nil {
+				return nil, err
+			}
+			current = &xfrmState{src: src, dst: dst}
+			states = append(states, current)
+			inLifetimeCurrent = false
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		fields := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(fields, "proto "):
+			parts := strings.Fields(fields)
+			for i := 0; i < len(parts)-1; i++ {
+				if parts[i] == "spi" {
+					// The SPI is printed as "0xc1b4a3f2(3249841138)".
+					current.spi = strings.SplitN(parts[i+1], "(", 2)[0]
+				}
+			}
+		case fields == "lifetime current:":
+			inLifetimeCurrent = true
+		case inLifetimeCurrent && xfrmBytesRegexp.MatchString(fields):
+			n, err := strconv.ParseInt(xfrmBytesRegexp.FindStringSubmatch(fields)[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid byte counter in xfrm state: %q", fields)
+			}
+			current.bytes = n
+		case inLifetimeCurrent && xfrmAddTimeRegexp.MatchString(fields):
+			t, err := time.ParseInLocation(xfrmTimeLayout, xfrmAddTimeRegexp.FindStringSubmatch(fields)[1], time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("invalid add time in xfrm state: %q", fields)
+			}
+			current.addTime = t
+		case strings.HasSuffix(fields, ":"):
+			inLifetimeCurrent = false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// parseXfrmPolicy parses the output of "ip xfrm policy". Each policy starts
+// with a "src <cidr> dst <cidr> ..." line, followed by indented lines
+// describing it. The templates of the policies are ignored.
+func parseXfrmPolicy(output []byte) ([]*xfrmPolicy, error) {
+	var policies []*xfrmPolicy
+	var current *xfrmPolicy
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "src ") {
+			src, dst, err := parseXfrmSelector(line)
+			if err != nil {
+				return nil, err
+			}
+			current = &xfrmPolicy{src: src, dst: dst}
+			policies = append(policies, current)
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		parts := strings.Fields(line)
+		if len(parts) >= 2 && parts[0] == "dir" {
+			current.dir = parts[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// parseXfrmSelector parses the source and destination of a
+// "src <ip>[/<prefix>] dst <ip>[/<prefix>] ..." line.
+func parseXfrmSelector(line string) (net.IP, net.IP, error) {
+	parts := strings.Fields(line)
+	if len(parts) < 4 || parts[0] != "src" || parts[2] != "dst" {
+		return nil, nil, fmt.Errorf("invalid xfrm line: %q", line)
+	}
+	src := net.ParseIP(strings.SplitN(parts[1], "/", 2)[0])
+	dst := net.ParseIP(strings.SplitN(parts[3], "/", 2)[0])
+	if src == nil || dst == nil {
+		return nil, nil, fmt.Errorf("invalid addresses in xfrm line: %q", line)
+	}
+	return src, dst, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleXfrmState = `src 192.168.1.2 dst 192.168.1.3
+	proto esp spi 0xc1b4a3f2(3249841138) reqid 1(0x00000001) mode transport
+	replay-window 0 seq 0x00000000 flag af-unspec (0x00100000)
+	aead rfc4106(gcm(aes)) 0x0102030405060708090a0b0c0d0e0f1011121314 (160 bits) 128
+	anti-replay context: seq 0x0, oseq 0x5, bitmap 0x00000000
+	sel src 0.0.0.0/0 dst 0.0.0.0/0 uid 0
+	lifetime config:
+	  limit: soft (INF)(bytes), hard (INF)(bytes)
+	  limit: soft (INF)(packets), hard (INF)(packets)
+	  expire add: soft 13838(sec), hard 14400(sec)
+	  expire use: soft 0(sec), hard 0(sec)
+	lifetime current:
+	  1040(bytes), 5(packets)
+	  add 2020-11-10 10:00:00 use 2020-11-10 10:00:05
+	stats:
+	  replay-window 0 replay 0 failed 0
+src 192.168.1.3 dst 192.168.1.2
+	proto esp spi 0x0d2e3f41(221134657) reqid 1(0x00000001) mode transport
+	replay-window 0 seq 0x00000000 flag af-unspec (0x00100000)
+	aead rfc4106(gcm(aes)) 0x0102030405060708090a0b0c0d0e0f1011121314 (160 bits) 128
+	anti-replay context: seq 0x7, oseq 0x0, bitmap 0x0000007f
+	sel src 0.0.0.0/0 dst 0.0.0.0/0 uid 0
+	lifetime config:
+	  limit: soft (INF)(bytes), hard (INF)(bytes)
+	  limit: soft (INF)(packets), hard (INF)(packets)
+	  expire add: soft 13838(sec), hard 14400(sec)
+	  expire use: soft 0(sec), hard 0(sec)
+	lifetime current:
+	  2080(bytes), 7(packets)
+	  add 2020-11-10 10:00:01 use 2020-11-10 10:00:06
+	stats:
+	  replay-window 0 replay 0 failed 0
+`
+
+const sampleXfrmPolicy = `src 192.168.1.2/32 dst 192.168.1.3/32 proto gre
+	dir out priority 366847 ptype main
+	tmpl src 192.168.1.2 dst 192.168.1.3
+		proto esp reqid 1 mode transport
+src 192.168.1.3/32 dst 192.168.1.2/32 proto gre
+	dir in priority 366847 ptype main
+	tmpl src 192.168.1.3 dst 192.168.1.2
+		proto esp reqid 1 mode transport
+src 0.0.0.0/0 dst 0.0.0.0/0
+	socket out priority 0 ptype main
+`
+
+func TestParseXfrmState(t *testing.T) {
+	states, err := parseXfrmState([]byte(sampleXfrmState))
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+
+	assert.Equal(t, net.ParseIP("192.168.1.2").To4(), states[0].src.To4())
+	assert.Equal(t, net.ParseIP("192.168.1.3").To4(), states[0].dst.To4())
+	assert.Equal(t, "0xc1b4a3f2", states[0].spi)
+	assert.Equal(t, int64(1040), states[0].bytes)
+	assert.Equal(t, time.Date(2020, 11, 10, 10, 0, 0, 0, time.Local), states[0].addTime)
+
+	assert.Equal(t, "0x0d2e3f41", states[1].spi)
+	assert.Equal(t, int64(2080), states[1].bytes)
+	assert.Equal(t, time.Date(2020, 11, 10, 10, 0, 1, 0, time.Local), states[1].addTime)
+}
+
+func TestParseXfrmStateEmpty(t *testing.T) {
+	states, err := parseXfrmState(nil)
+	require.NoError(t, err)
+	assert.Empty(t, states)
+}
+
+func TestParseXfrmStateInvalid(t *testing.T) {
+	_, err := parseXfrmState([]byte("src 192.168.1.2 dst invalid\n"))
+	assert.Error(t, err)
+}
+
+func TestParseXfrmPolicy(t *testing.T) {
+	policies, err := parseXfrmPolicy([]byte(sampleXfrmPolicy))
+	require.NoError(t, err)
+	require.Len(t, policies, 3)
+	assert.Equal(t, "out", policies[0].dir)
+	assert.True(t, policies[0].src.Equal(net.ParseIP("192.168.1.2")))
+	assert.True(t, policies[0].dst.Equal(net.ParseIP("192.168.1.3")))
+	assert.Equal(t, "in", policies[1].dir)
+	assert.Equal(t, "", policies[2].dir)
+}
@@ -16,10 +16,15 @@ package networkpolicy
 
 import (
 	"fmt"
-	"math"
 	"sync"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
 )
 
+// maxAllocatedID is the highest ID which can be allocated. The IDs are used as conjunction IDs, the higher ones are
+// reserved for the catch-all flows of the baseline tier.
+const maxAllocatedID = openflow.BaselineDropConjIDIn - 1
+
 // idAllocator provides interfaces to allocate and release uint32 IDs. It's thread-safe.
 // It caches the last allocated ID and the IDs that have been released.
 // If no IDs that have been released, the next allocated IP will be lastAllocatedID+1.
@@ -76,7 +81,7 @@ func (a *idAllocator) allocate() (uint32, error) {
 		delete(a.availableSet, id)
 		return id, nil
 	}
-	if a.lastAllocatedID == math.MaxUint32 {
+	if a.lastAllocatedID == maxAllocatedID {
 		return 0, fmt.Errorf("no ID available")
 	}
 	a.lastAllocatedID++
@@ -0,0 +1,194 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/interfacestore"
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
+)
+
+// bandwidthLimit is the bandwidth limit of an interface in one direction, with
+// the rate in bits per second and the burst size in bits.
+type bandwidthLimit struct {
+	rate  int64
+	burst int64
+}
+
+// ruleBandwidth is the bandwidth limit a rule applies to the interfaces of its
+// target Pods.
+type ruleBandwidth struct {
+	direction v1beta1.Direction
+	limit     bandwidthLimit
+	ifaces    sets.String
+}
+
+// bandwidthLimiter enforces the bandwidth limits of the rules on the OVS
+// interfaces of their target Pods. The traffic received by a Pod (ingress
+// rules) is shaped by a QoS on its OVS port, and the traffic sent by a Pod
+// (egress rules) is policed on its OVS interface. If multiple rules limit an
+// interface in the same direction, the lowest rate and burst size apply.
+type bandwidthLimiter struct {
+	ovsCtlClient ovsctl.OVSCtlClient
+	ifaceStore   interfacestore.InterfaceStore
+
+	mutex sync.Mutex
+	// rules is a mapping from ruleID to the *ruleBandwidth of the rules with a
+	// bandwidth limit.
+	rules map[string]*ruleBandwidth
+	// realized are the limits configured on the interfaces, keyed by
+	// direction and interface name.
+	realized map[v1beta1.Direction]map[string]bandwidthLimit
+	// synced is false if the last sync failed to enforce some limits.
+	synced bool
+}
+
+func newBandwidthLimiter(ovsCtlClient ovsctl.OVSCtlClient, ifaceStore interfacestore.InterfaceStore) *bandwidthLimiter {
+	return &bandwidthLimiter{
+		ovsCtlClient: ovsCtlClient,
+		ifaceStore:   ifaceStore,
+		rules:        map[string]*ruleBandwidth{},
+		realized: map[v1beta1.Direction]map[string]bandwidthLimit{
+			v1beta1.DirectionIn:  {},
+			v1beta1.DirectionOut: {},
+		},
+		synced: true,
+	}
+}
+
+// reconcile updates the bandwidth limit of the rule and enforces the resulting
+// limits of the interfaces.
+func (l *bandwidthLimiter) reconcile(rule *CompletedRule) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if rule.Bandwidth == nil {
+		if _, exists := l.rules[rule.ID]; !exists && l.synced {
+			return nil
+		}
+		delete(l.rules, rule.ID)
+	} else {
+		ifaces := sets.NewString()
+		for _, pod := range rule.Pods {
+			for _, iface := range l.ifaceStore.GetContainerInterfacesByPod(pod.Pod.Name, pod.Pod.Namespace) {
+				ifaces.Insert(iface.InterfaceName)
+			}
+		}
+		l.rules[rule.ID] = &ruleBandwidth{
+			direction: rule.Direction,
+			limit:     bandwidthLimit{rate: rule.Bandwidth.Rate, burst: rule.Bandwidth.Burst},
+			ifaces:    ifaces,
+		}
+	}
+	return l.sync()
+}
+
+// forget removes the bandwidth limit of the rule and enforces the resulting
+// limits of the interfaces.
+func (l *bandwidthLimiter) forget(ruleID string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, exists := l.rules[ruleID]; !exists && l.synced {
+		return nil
+	}
+	delete(l.rules, ruleID)
+	return l.sync()
+}
+
+// sync computes the limits of the interfaces from the rules and enforces the
+// ones which differ from the realized limits. The realized limits are updated
+// only on success, so that failed updates are retried by the next sync, which
+// is triggered by any rule until it succeeds.
+func (l *bandwidthLimiter) sync() error {
+	desired := map[v1beta1.Direction]map[string]bandwidthLimit{
+		v1beta1.DirectionIn:  {},
+		v1beta1.DirectionOut: {},
+	}
+	for _, r := range l.rules {
+		for iface := range r.ifaces {
+			limit, exists := desired[r.direction][iface]
+			if !exists || r.limit.rate < limit.rate {
+				limit.rate = r.limit.rate
+			}
+			if !exists || r.limit.burst < limit.burst {
+				limit.burst = r.limit.burst
+			}
+			desired[r.direction][iface] = limit
+		}
+	}
+	var lastErr error
+	for direction, limits := range desired {
+		realized := l.realized[direction]
+		for iface, limit := range limits {
+			if realizedLimit, exists := realized[iface]; exists && realizedLimit == limit {
+				continue
+			}
+			if err := l.setLimit(direction, iface, &limit); err != nil {
+				lastErr = err
+				continue
+			}
+			realized[iface] = limit
+		}
+		for iface := range realized {
+			if _, exists := limits[iface]; exists {
+				continue
+			}
+			if err := l.setLimit(direction, iface, nil); err != nil {
+				lastErr = err
+				continue
+			}
+			delete(realized, iface)
+		}
+	}
+	l.synced = lastErr == nil
+	return lastErr
+}
+
+// setLimit configures the limit of the interface in the direction, or removes
+// it if limit is nil.
+func (l *bandwidthLimiter) setLimit(direction v1beta1.Direction, iface string, limit *bandwidthLimit) error {
+	var err error
+	if direction == v1beta1.DirectionIn {
+		if limit == nil {
+			err = l.ovsCtlClient.ClearPortQoS(iface)
+		} else {
+			err = l.ovsCtlClient.SetPortQoS(iface, limit.rate, limit.burst)
+		}
+	} else {
+		// The ingress policing rate and burst size of an OVS interface are in
+		// kbps and kb, a rate of 0 disabling the policing.
+		var rate, burst int64
+		if limit != nil {
+			rate, burst = toKilobits(limit.rate), toKilobits(limit.burst)
+		}
+		err = l.ovsCtlClient.SetInterfaceIngressPolicing(iface, rate, burst)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set bandwidth limit of interface %s in direction %s: %v", iface, direction, err)
+	}
+	klog.V(2).Infof("Set bandwidth limit of interface %s in direction %s to %+v", iface, direction, limit)
+	return nil
+}
+
+// toKilobits converts a number of bits to kilobits, rounding up so that a
+// positive limit is never converted to 0.
+func toKilobits(bits int64) int64 {
+	return (bits + 999) / 1000
+}
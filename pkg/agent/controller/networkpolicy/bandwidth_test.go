@@ -0,0 +1,100 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/interfacestore"
+	"github.com/vmware-tanzu/antrea/pkg/agent/util"
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
+	ovsctltest "github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl/testing"
+)
+
+func newBandwidthRule(id string, direction v1beta1.Direction, rate, burst int64, pods v1beta1.GroupMemberPodSet) *CompletedRule {
+	return &CompletedRule{
+		rule: &rule{ID: id, Direction: direction, Bandwidth: &v1beta1.Bandwidth{Rate: rate, Burst: burst}},
+		Pods: pods,
+	}
+}
+
+func newBandwidthTestIfaceStore() interfacestore.InterfaceStore {
+	ifaceStore := interfacestore.NewInterfaceStore()
+	for _, pod := range []string{"pod1", "pod2"} {
+		ifaceStore.AddInterface(&interfacestore.InterfaceConfig{
+			InterfaceName:            util.GenerateContainerInterfaceName(pod, "ns1", "container-"+pod),
+			ContainerInterfaceConfig: &interfacestore.ContainerInterfaceConfig{PodName: pod, PodNamespace: "ns1", ContainerID: "container-" + pod},
+		})
+	}
+	return ifaceStore
+}
+
+func TestBandwidthLimiterIngress(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+	mockOVSCtlClient := ovsctltest.NewMockOVSCtlClient(controller)
+	l := newBandwidthLimiter(mockOVSCtlClient, newBandwidthTestIfaceStore())
+	iface1 := util.GenerateContainerInterfaceName("pod1", "ns1", "container-pod1")
+	iface2 := util.GenerateContainerInterfaceName("pod2", "ns1", "container-pod2")
+	bothPods := v1beta1.NewGroupMemberPodSet(newAppliedToGroupMember("pod1", "ns1"), newAppliedToGroupMember("pod2", "ns1"))
+
+	mockOVSCtlClient.EXPECT().SetPortQoS(iface1, int64(100000000), int64(10000000))
+	require.NoError(t, l.reconcile(newBandwidthRule("rule1", v1beta1.DirectionIn, 100000000, 10000000, appliedToGroup1)))
+
+	// The lowest limit applies to pod1, and pod2 is limited by rule2 only.
+	mockOVSCtlClient.EXPECT().SetPortQoS(iface1, int64(50000000), int64(10000000))
+	mockOVSCtlClient.EXPECT().SetPortQoS(iface2, int64(50000000), int64(20000000))
+	require.NoError(t, l.reconcile(newBandwidthRule("rule2", v1beta1.DirectionIn, 50000000, 20000000, bothPods)))
+
+	// Reconciling an unchanged rule doesn't update the interfaces.
+	require.NoError(t, l.reconcile(newBandwidthRule("rule2", v1beta1.DirectionIn, 50000000, 20000000, bothPods)))
+
+	mockOVSCtlClient.EXPECT().SetPortQoS(iface1, int64(100000000), int64(10000000))
+	mockOVSCtlClient.EXPECT().ClearPortQoS(iface2)
+	require.NoError(t, l.forget("rule2"))
+
+	mockOVSCtlClient.EXPECT().ClearPortQoS(iface1)
+	require.NoError(t, l.reconcile(&CompletedRule{rule: &rule{ID: "rule1", Direction: v1beta1.DirectionIn}, Pods: appliedToGroup1}))
+	assert.Empty(t, l.rules)
+	assert.Empty(t, l.realized[v1beta1.DirectionIn])
+
+	// Rules without bandwidth limit are ignored.
+	require.NoError(t, l.forget("rule3"))
+}
+
+func TestBandwidthLimiterEgress(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+	mockOVSCtlClient := ovsctltest.NewMockOVSCtlClient(controller)
+	l := newBandwidthLimiter(mockOVSCtlClient, newBandwidthTestIfaceStore())
+	iface1 := util.GenerateContainerInterfaceName("pod1", "ns1", "container-pod1")
+
+	// The policing rate and burst size are rounded up to kbps and kb.
+	mockOVSCtlClient.EXPECT().SetInterfaceIngressPolicing(iface1, int64(10000), int64(2))
+	require.NoError(t, l.reconcile(newBandwidthRule("rule1", v1beta1.DirectionOut, 10000000, 1500, appliedToGroup1)))
+
+	// A failed update is retried by the next reconciliation.
+	mockOVSCtlClient.EXPECT().SetInterfaceIngressPolicing(iface1, int64(0), int64(0)).Return(errors.New("error"))
+	assert.Error(t, l.forget("rule1"))
+	mockOVSCtlClient.EXPECT().SetInterfaceIngressPolicing(iface1, int64(0), int64(0))
+	require.NoError(t, l.forget("rule1"))
+	assert.True(t, l.synced)
+	require.NoError(t, l.reconcile(&CompletedRule{rule: &rule{ID: "rule2", Direction: v1beta1.DirectionOut}}))
+}
@@ -66,6 +66,23 @@ type rule struct {
 	Priority int32
 	// Priority of the NetworkPolicy to which this rule belong. nil for k8s NetworkPolicy.
 	PolicyPriority *float64
+	// Priority of the tier of the NetworkPolicy to which this rule belong. nil for
+	// k8s NetworkPolicy.
+	TierPriority *int32
+	// Creation timestamp of the NetworkPolicy to which this rule belong. It
+	// orders the rules of the NetworkPolicies with the same priorities.
+	PolicyCreationTimestamp metav1.Time
+	// EnableLogging indicates whether the packets matching this rule should be logged.
+	EnableLogging bool
+	// HTTPMatches restricts this rule to the HTTP requests matching any of
+	// them. Empty for k8s NetworkPolicy.
+	HTTPMatches []v1beta1.HTTPMatch
+	// Bandwidth limits the bandwidth of the target Pods in the direction of
+	// this rule. nil for k8s NetworkPolicy.
+	Bandwidth *v1beta1.Bandwidth
+	// PreserveSourceIP indicates that the traffic matched by this egress rule
+	// must not be SNATed when it leaves the Node. false for k8s NetworkPolicy.
+	PreserveSourceIP bool
 	// Targets of this rule.
 	AppliedToGroups []string
 	// The parent Policy ID. Used to identify rules belong to a specified
@@ -116,6 +133,20 @@ func (r *CompletedRule) isAntreaNetworkPolicyRule() bool {
 	return r.PolicyPriority != nil
 }
 
+// tierPriority returns the priority of the tier of the rule, which defaults to
+// the priority of the default tier.
+func (r *CompletedRule) tierPriority() int32 {
+	if r.TierPriority == nil {
+		return v1beta1.DefaultTierPriority
+	}
+	return *r.TierPriority
+}
+
+// isBaselineRule returns true if the rule is part of a baseline ClusterNetworkPolicy.
+func (r *CompletedRule) isBaselineRule() bool {
+	return r.isAntreaNetworkPolicyRule() && r.tierPriority() == v1beta1.BaselineTierPriority
+}
+
 // ruleCache caches Antrea AddressGroups, AppliedToGroups and NetworkPolicies,
 // can construct complete rules that can be used by reconciler to enforce.
 type ruleCache struct {
@@ -532,19 +563,25 @@ func (c *ruleCache) DeleteAppliedToGroup(group *v1beta1.AppliedToGroup) error {
 // toRule converts v1beta1.NetworkPolicyRule to *rule.
 func toRule(r *v1beta1.NetworkPolicyRule, policy *v1beta1.NetworkPolicy) *rule {
 	rule := &rule{
-		Direction:       r.Direction,
-		From:            r.From,
-		To:              r.To,
-		Services:        r.Services,
-		Action:          r.Action,
-		Priority:        r.Priority,
-		AppliedToGroups: policy.AppliedToGroups,
-		PolicyUID:       policy.UID,
+		Direction:        r.Direction,
+		From:             r.From,
+		To:               r.To,
+		Services:         r.Services,
+		Action:           r.Action,
+		Priority:         r.Priority,
+		EnableLogging:    r.EnableLogging,
+		HTTPMatches:      r.HTTPMatches,
+		Bandwidth:        r.Bandwidth,
+		PreserveSourceIP: r.PreserveSourceIP,
+		TierPriority:     policy.TierPriority,
+		AppliedToGroups:  policy.AppliedToGroups,
+		PolicyUID:        policy.UID,
 	}
 	rule.ID = hashRule(rule)
 	rule.PolicyNamespace = policy.Namespace
 	rule.PolicyName = policy.Name
 	rule.PolicyPriority = policy.Priority
+	rule.PolicyCreationTimestamp = policy.CreationTimestamp
 	return rule
 }
 
@@ -556,6 +593,16 @@ func (c *ruleCache) GetNetworkPolicyNum() int {
 	return len(c.policyMap)
 }
 
+// hasNetworkPolicy returns whether the NetworkPolicy with the given UID is
+// cached.
+func (c *ruleCache) hasNetworkPolicy(uid string) bool {
+	c.policyMapLock.RLock()
+	defer c.policyMapLock.RUnlock()
+
+	_, exists := c.policyMap[uid]
+	return exists
+}
+
 // ReplaceNetworkPolicies atomically adds the given policies to the cache and deletes
 // the pre-existing policies that are not in the given policies from the cache.
 // It makes the cache in sync with the apiserver when restarting a watch.
@@ -0,0 +1,534 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/contiv/libOpenflow/protocol"
+	"github.com/contiv/ofnet/ofctrl"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+)
+
+const (
+	// maxHTTPHeaderSize is the maximum size of the request line and the
+	// headers of an HTTP request. The connections sending larger requests
+	// are denied.
+	maxHTTPHeaderSize = 64 * 1024
+
+	// l7ConnIdleTimeout is the time after which the state of an idle TCP
+	// connection is forgotten by the agent.
+	l7ConnIdleTimeout = 10 * time.Minute
+	// l7ConnGCInterval is the interval at which the states of the idle TCP
+	// connections are forgotten.
+	l7ConnGCInterval = time.Minute
+
+	tcpFlagRST = 0x04
+)
+
+// httpRequest is the request line and the headers of an HTTP request. The
+// header names are in lower case.
+type httpRequest struct {
+	method  string
+	path    string
+	headers map[string]string
+}
+
+// httpParserState is the part of an HTTP request the next bytes of the stream
+// belong to.
+type httpParserState int
+
+const (
+	httpStateHeader httpParserState = iota
+	httpStateBody
+	httpStateChunkSize
+	httpStateChunkData
+	httpStateChunkDataEnd
+	httpStateTrailer
+)
+
+// httpStreamParser parses the HTTP requests sent by the client of a TCP
+// connection, fed in the order of the stream.
+type httpStreamParser struct {
+	state httpParserState
+	// line buffers the incomplete line at the end of the bytes fed so far.
+	line []byte
+	// header buffers the complete lines of the header of the current request.
+	header []byte
+	// remaining is the number of bytes of the body or of the chunk which
+	// have not been fed yet.
+	remaining uint64
+}
+
+// feed parses the bytes following the ones fed so far, and returns the
+// requests whose headers are completed by them. An error is returned if the
+// bytes are not a valid HTTP request stream, or if the length of the body of
+// a request is ambiguous, in which case the following requests cannot be
+// found.
+func (p *httpStreamParser) feed(data []byte) ([]*httpRequest, error) {
+	var requests []*httpRequest
+	for len(data) > 0 {
+		if p.state == httpStateBody || p.state == httpStateChunkData {
+			n := uint64(len(data))
+			if n > p.remaining {
+				n = p.remaining
+			}
+			data = data[n:]
+			p.remaining -= n
+			if p.remaining == 0 {
+				if p.state == httpStateBody {
+					p.state = httpStateHeader
+				} else {
+					p.state = httpStateChunkDataEnd
+				}
+			}
+			continue
+		}
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			p.line = append(p.line, data...)
+			if len(p.header)+len(p.line) > maxHTTPHeaderSize {
+				return requests, errors.New("HTTP header is too large")
+			}
+			if p.state == httpStateHeader && len(p.header) == 0 && !isRequestLinePrefix(bytes.TrimSuffix(p.line, []byte("\r"))) {
+				return requests, errors.New("invalid HTTP request line")
+			}
+			break
+		}
+		line := bytes.TrimSuffix(append(p.line, data[:i]...), []byte("\r"))
+		p.line = nil
+		data = data[i+1:]
+		if len(p.header)+len(line) > maxHTTPHeaderSize {
+			return requests, errors.New("HTTP header is too large")
+		}
+		req, err := p.parseLine(line)
+		if req != nil {
+			requests = append(requests, req)
+		}
+		if err != nil {
+			return requests, err
+		}
+	}
+	return requests, nil
+}
+
+// parseLine parses a complete line of the stream, without its line
+// terminator. It returns the request whose header is completed by the line.
+func (p *httpStreamParser) parseLine(line []byte) (*httpRequest, error) {
+	switch p.state {
+	case httpStateHeader:
+		if len(line) > 0 {
+			if len(p.header) == 0 && parseHTTPRequest(line) == nil {
+				return nil, errors.New("invalid HTTP request line")
+			}
+			p.header = append(append(p.header, line...), '\r', '\n')
+			return nil, nil
+		}
+		// Empty lines preceding the request line are ignored.
+		if len(p.header) == 0 {
+			return nil, nil
+		}
+		req := parseHTTPRequest(p.header)
+		p.header = nil
+		return req, p.startBody(req)
+	case httpStateChunkSize:
+		if i := bytes.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		size, err := strconv.ParseUint(strings.TrimSpace(string(line)), 16, 63)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HTTP chunk size: %v", err)
+		}
+		if size == 0 {
+			p.state = httpStateTrailer
+		} else {
+			p.state, p.remaining = httpStateChunkData, size
+		}
+	case httpStateChunkDataEnd:
+		if len(line) > 0 {
+			return nil, errors.New("invalid HTTP chunk")
+		}
+		p.state = httpStateChunkSize
+	case httpStateTrailer:
+		if len(line) == 0 {
+			p.state = httpStateHeader
+		}
+	}
+	return nil, nil
+}
+
+// startBody sets the state of the parser for the body of the request, as
+// described in RFC 7230 section 3.3.3. The requests with both a
+// Transfer-Encoding and a Content-Length header are rejected, as the servers
+// may not agree on the length of their bodies.
+func (p *httpStreamParser) startBody(req *httpRequest) error {
+	transferEncoding, chunked := req.headers["transfer-encoding"]
+	contentLength, hasLength := req.headers["content-length"]
+	switch {
+	case chunked && hasLength:
+		return errors.New("HTTP request has both Transfer-Encoding and Content-Length")
+	case chunked:
+		codings := strings.Split(transferEncoding, ",")
+		if !strings.EqualFold(strings.TrimSpace(codings[len(codings)-1]), "chunked") {
+			return fmt.Errorf("invalid HTTP Transfer-Encoding %q", transferEncoding)
+		}
+		p.state = httpStateChunkSize
+	case hasLength:
+		length, err := strconv.ParseUint(contentLength, 10, 63)
+		if err != nil {
+			return fmt.Errorf("invalid HTTP Content-Length %q", contentLength)
+		}
+		if length > 0 {
+			p.state, p.remaining = httpStateBody, length
+		}
+	}
+	return nil
+}
+
+// isRequestLinePrefix returns whether the bytes can start an HTTP request
+// line, i.e. whether the method in them is made of upper case letters. It lets
+// the streams of other protocols be rejected before their first line ends.
+func isRequestLinePrefix(b []byte) bool {
+	for _, c := range b {
+		if c == ' ' {
+			return true
+		}
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// tcpSegment is the part of a TCP segment the L7 connections are tracked with.
+type tcpSegment struct {
+	srcPort uint16
+	dstPort uint16
+	seq     uint32
+	flags   uint8
+	payload []byte
+}
+
+// l7ConnKey identifies the direction of a TCP connection from its client to
+// its server, in which the HTTP requests of the connection are evaluated with
+// a rule.
+type l7ConnKey struct {
+	ruleID  string
+	srcIP   string
+	dstIP   string
+	srcPort uint16
+	dstPort uint16
+}
+
+// l7Conn is the state of the stream of a TCP connection from its client.
+type l7Conn struct {
+	// baseSeq is the sequence number of the first packet of the connection
+	// sent to the agent. The bytes before it are not evaluated.
+	baseSeq uint32
+	// nextSeq is the sequence number following the bytes evaluated so far.
+	nextSeq  uint32
+	parser   httpStreamParser
+	lastSeen time.Time
+}
+
+// l7SegmentAction is what is done with a packet sent to the agent by the L7
+// verdict tables.
+type l7SegmentAction int
+
+const (
+	// l7SegmentReinject sends the packet back to the OVS pipeline, once the
+	// requests it completes have been allowed.
+	l7SegmentReinject l7SegmentAction = iota
+	// l7SegmentDrop drops the packet, which is retransmitted by the client.
+	l7SegmentDrop
+	// l7SegmentDeny denies the connection.
+	l7SegmentDeny
+)
+
+// l7ConnTracker tracks the streams of the TCP connections matching the rules
+// with HTTP matches.
+type l7ConnTracker struct {
+	mutex sync.Mutex
+	conns map[l7ConnKey]*l7Conn
+}
+
+func newL7ConnTracker() *l7ConnTracker {
+	return &l7ConnTracker{conns: map[l7ConnKey]*l7Conn{}}
+}
+
+// handleSegment feeds the payload of the segment to the stream of the
+// connection, and returns what must be done with its packet, with the HTTP
+// requests it completes. The bytes which have already been fed are skipped,
+// and a segment following a gap in the stream is dropped, so that the stream
+// is fed in order. The connection is denied if a segment carries bytes
+// preceding the first packet of the connection seen by the agent, as they
+// may start a request which cannot be evaluated.
+func (t *l7ConnTracker) handleSegment(key l7ConnKey, segment *tcpSegment, now time.Time) (l7SegmentAction, []*httpRequest) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	conn, exists := t.conns[key]
+	if segment.flags&tcpFlagRST != 0 {
+		delete(t.conns, key)
+		return l7SegmentReinject, nil
+	}
+	if !exists {
+		conn = &l7Conn{baseSeq: segment.seq, nextSeq: segment.seq}
+		t.conns[key] = conn
+	}
+	conn.lastSeen = now
+	if len(segment.payload) == 0 {
+		return l7SegmentReinject, nil
+	}
+	if int32(segment.seq-conn.baseSeq) < 0 {
+		delete(t.conns, key)
+		return l7SegmentDeny, nil
+	}
+	// The number of bytes of the payload which have already been fed.
+	fed := int64(int32(conn.nextSeq - segment.seq))
+	if fed < 0 {
+		return l7SegmentDrop, nil
+	}
+	if fed >= int64(len(segment.payload)) {
+		return l7SegmentReinject, nil
+	}
+	requests, err := conn.parser.feed(segment.payload[fed:])
+	if err != nil {
+		klog.V(2).Infof("Denying L7 connection %s:%d->%s:%d of rule %s: %v", key.srcIP, key.srcPort, key.dstIP, key.dstPort, key.ruleID, err)
+		delete(t.conns, key)
+		return l7SegmentDeny, requests
+	}
+	conn.nextSeq = segment.seq + uint32(len(segment.payload))
+	return l7SegmentReinject, requests
+}
+
+// forget removes the state of the connection.
+func (t *l7ConnTracker) forget(key l7ConnKey) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.conns, key)
+}
+
+// forgetRule removes the states of the connections of the rule.
+func (t *l7ConnTracker) forgetRule(ruleID string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for key := range t.conns {
+		if key.ruleID == ruleID {
+			delete(t.conns, key)
+		}
+	}
+}
+
+// gc removes the states of the connections which have been idle for longer
+// than l7ConnIdleTimeout. If a packet of such a connection is received later,
+// its stream is evaluated from that packet.
+func (t *l7ConnTracker) gc(now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for key, conn := range t.conns {
+		if now.Sub(conn.lastSeen) > l7ConnIdleTimeout {
+			delete(t.conns, key)
+		}
+	}
+}
+
+// handleL7PacketIn evaluates the HTTP requests carried by a packet sent by the
+// L7 verdict tables. The packets of the connections matching a rule with HTTP
+// matches which are sent by the client are all sent to the agent: the packet
+// is re-injected into the OVS pipeline if every request it completes is
+// allowed by the rule. Otherwise the packet is dropped, and a flow is installed
+// to deny the following packets of the connection.
+func (c *Controller) handleL7PacketIn(ofID uint32, r *rule, pktIn *ofctrl.PacketIn) error {
+	ipPacket, ok := pktIn.Data.Data.(*protocol.IPv4)
+	if !ok || ipPacket.Protocol != protocol.Type_TCP || ipPacket.Data == nil {
+		return errors.New("invalid TCP packet")
+	}
+	data, err := ipPacket.Data.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	segment, err := parseTCPSegment(data)
+	if err != nil {
+		return err
+	}
+	key := l7ConnKey{
+		ruleID:  r.ID,
+		srcIP:   ipPacket.NWSrc.String(),
+		dstIP:   ipPacket.NWDst.String(),
+		srcPort: segment.srcPort,
+		dstPort: segment.dstPort,
+	}
+	action, requests := c.l7Conns.handleSegment(key, segment, time.Now())
+	for _, req := range requests {
+		allow := matchHTTPRequest(req, r.HTTPMatches)
+		if r.Action != nil && *r.Action == secv1alpha1.RuleActionDrop {
+			allow = !allow
+		}
+		klog.V(4).Infof("Evaluated HTTP request %s %s of L7 connection %s:%d->%s:%d with rule %s: allow=%t", req.method, req.path, key.srcIP, key.srcPort, key.dstIP, key.dstPort, r.ID, allow)
+		if r.EnableLogging {
+			decision := decisionDeny
+			if allow {
+				decision = decisionAllow
+			}
+			if err := c.logPacket(ofID, r, pktIn, decision); err != nil {
+				klog.Errorf("Failed to log HTTP request of rule %s: %v", r.ID, err)
+			}
+		}
+		if !allow {
+			c.l7Conns.forget(key)
+			action = l7SegmentDeny
+			break
+		}
+	}
+	switch action {
+	case l7SegmentDeny:
+		klog.V(2).Infof("Denied L7 connection %s:%d->%s:%d with rule %s", key.srcIP, key.srcPort, key.dstIP, key.dstPort, r.ID)
+		if err := c.ofClient.InstallL7ConnDenyFlow(ofID, r.Direction, ipPacket.NWSrc, ipPacket.NWDst, segment.srcPort, segment.dstPort); err != nil {
+			return fmt.Errorf("failed to install L7 deny flow of rule %s: %v", r.ID, err)
+		}
+	case l7SegmentReinject:
+		if err := c.ofClient.ReinjectL7Packet(r.Direction, pktIn); err != nil {
+			return fmt.Errorf("failed to re-inject packet of rule %s: %v", r.ID, err)
+		}
+	}
+	return nil
+}
+
+// runL7ConnGC forgets the states of the idle L7 connections periodically
+// until stopCh is closed.
+func (c *Controller) runL7ConnGC(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(l7ConnGCInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			c.l7Conns.gc(now)
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// parseTCPSegment returns the ports, the sequence number, the flags and the
+// payload of a TCP segment.
+func parseTCPSegment(data []byte) (*tcpSegment, error) {
+	if len(data) < 20 {
+		return nil, errors.New("TCP segment is too short")
+	}
+	// The data offset is the length of the header in 32-bit words.
+	dataOffset := int(data[12]>>4) * 4
+	if dataOffset < 20 || dataOffset > len(data) {
+		return nil, fmt.Errorf("invalid TCP data offset %d", dataOffset)
+	}
+	return &tcpSegment{
+		srcPort: uint16(data[0])<<8 | uint16(data[1]),
+		dstPort: uint16(data[2])<<8 | uint16(data[3]),
+		seq:     uint32(data[4])<<24 | uint32(data[5])<<16 | uint32(data[6])<<8 | uint32(data[7]),
+		flags:   data[13],
+		payload: data[dataOffset:],
+	}, nil
+}
+
+// parseHTTPRequest parses the request line and the headers of an HTTP request.
+// The values of the headers which appear more than once are joined with
+// commas. The incomplete header lines are ignored. nil is returned if the
+// payload doesn't start with an HTTP request line.
+func parseHTTPRequest(payload []byte) *httpRequest {
+	lines := bytes.Split(payload, []byte("\r\n"))
+	fields := strings.Fields(string(lines[0]))
+	if len(fields) != 3 || !strings.HasPrefix(fields[2], "HTTP/") {
+		return nil
+	}
+	path := fields[1]
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	req := &httpRequest{method: fields[0], path: path, headers: map[string]string{}}
+	for _, line := range lines[1:] {
+		if len(line) == 0 {
+			break
+		}
+		i := bytes.IndexByte(line, ':')
+		if i <= 0 {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(string(line[:i])))
+		value := strings.TrimSpace(string(line[i+1:]))
+		if existing, exists := req.headers[name]; exists {
+			value = existing + ", " + value
+		}
+		req.headers[name] = value
+	}
+	return req
+}
+
+// matchHTTPRequest returns whether the request matches any of the HTTP matches.
+// A nil request, i.e. a payload which is not an HTTP request, doesn't match.
+func matchHTTPRequest(req *httpRequest, httpMatches []v1beta1.HTTPMatch) bool {
+	if req == nil {
+		return false
+	}
+	for _, m := range httpMatches {
+		if m.Method != "" && m.Method != "*" && !strings.EqualFold(m.Method, req.method) {
+			continue
+		}
+		if m.Path != "" && !matchPattern(m.Path, req.path) {
+			continue
+		}
+		headersMatched := true
+		for name, pattern := range m.Headers {
+			value, exists := req.headers[strings.ToLower(name)]
+			if !exists || !matchPattern(pattern, value) {
+				headersMatched = false
+				break
+			}
+		}
+		if headersMatched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern returns whether s matches the pattern, in which "*" matches any
+// sequence of characters, including "/".
+func matchPattern(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
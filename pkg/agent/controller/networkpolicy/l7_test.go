@@ -0,0 +1,342 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/contiv/libOpenflow/protocol"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	openflowtest "github.com/vmware-tanzu/antrea/pkg/agent/openflow/testing"
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+)
+
+func TestParseTCPSegment(t *testing.T) {
+	payload := []byte("GET / HTTP/1.1\r\n\r\n")
+	// The TCP header with 12 bytes of options.
+	data, err := (&protocol.TCP{PortSrc: 34567, PortDst: 80, SeqNum: 1000, HdrLen: 8, Code: tcpFlagRST, Data: append(make([]byte, 12), payload...)}).MarshalBinary()
+	require.NoError(t, err)
+	segment, err := parseTCPSegment(data)
+	require.NoError(t, err)
+	assert.Equal(t, &tcpSegment{srcPort: 34567, dstPort: 80, seq: 1000, flags: tcpFlagRST, payload: payload}, segment)
+
+	_, err = parseTCPSegment(data[:12])
+	assert.Error(t, err)
+}
+
+func TestParseHTTPRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		payload         string
+		expectedRequest *httpRequest
+	}{
+		{
+			name:    "request with headers",
+			payload: "GET /api/v1/pods?limit=10 HTTP/1.1\r\nHost: foo.bar\r\nUser-Agent : curl/7.58.0\r\n\r\n",
+			expectedRequest: &httpRequest{
+				method:  "GET",
+				path:    "/api/v1/pods",
+				headers: map[string]string{"host": "foo.bar", "user-agent": "curl/7.58.0"},
+			},
+		},
+		{
+			name:    "request with truncated headers",
+			payload: "DELETE /admin HTTP/1.0\r\nHost: foo.bar\r\nX-Toke",
+			expectedRequest: &httpRequest{
+				method:  "DELETE",
+				path:    "/admin",
+				headers: map[string]string{"host": "foo.bar"},
+			},
+		},
+		{
+			name:    "not HTTP",
+			payload: "SSH-2.0-OpenSSH_7.6p1\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedRequest, parseHTTPRequest([]byte(tt.payload)))
+		})
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		s        string
+		expected bool
+	}{
+		{"/api", "/api", true},
+		{"/api", "/api/v1", false},
+		{"/api/*", "/api/v1/pods", true},
+		{"/api/*", "/apis", false},
+		{"*.example.com", "www.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"/api/*/pods", "/api/v1/pods", true},
+		{"/api/*/pods", "/api/v1/services", false},
+		{"a*a", "a", false},
+		{"*", "", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, matchPattern(tt.pattern, tt.s), "pattern %q, string %q", tt.pattern, tt.s)
+	}
+}
+
+func TestMatchHTTPRequest(t *testing.T) {
+	req := &httpRequest{method: "DELETE", path: "/api/admin/users", headers: map[string]string{"host": "foo.bar"}}
+	tests := []struct {
+		name        string
+		httpMatches []v1beta1.HTTPMatch
+		expected    bool
+	}{
+		{"any method", []v1beta1.HTTPMatch{{Method: "*", Path: "/api/*"}}, true},
+		{"case-insensitive method", []v1beta1.HTTPMatch{{Method: "delete"}}, true},
+		{"different method", []v1beta1.HTTPMatch{{Method: "GET"}}, false},
+		{"exact path", []v1beta1.HTTPMatch{{Path: "/api/admin"}}, false},
+		{"header", []v1beta1.HTTPMatch{{Headers: map[string]string{"Host": "*.bar"}}}, true},
+		{"missing header", []v1beta1.HTTPMatch{{Headers: map[string]string{"X-Token": "*"}}}, false},
+		{"any of matches", []v1beta1.HTTPMatch{{Method: "GET"}, {Method: "DELETE", Path: "/api/admin*"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, matchHTTPRequest(req, tt.httpMatches))
+		})
+	}
+	assert.False(t, matchHTTPRequest(nil, []v1beta1.HTTPMatch{{Method: "*"}}))
+}
+
+func TestHTTPStreamParser(t *testing.T) {
+	tests := []struct {
+		name             string
+		chunks           []string
+		expectedRequests []string
+		expectErr        bool
+	}{
+		{
+			name:             "pipelined requests",
+			chunks:           []string{"GET /api HTTP/1.1\r\nHost: foo.bar\r\n\r\nDELETE /api/admin HTTP/1.1\r\n\r\n"},
+			expectedRequests: []string{"GET /api", "DELETE /api/admin"},
+		},
+		{
+			name:             "split lines",
+			chunks:           []string{"\r\nGE", "T /api HTTP/1.1\r", "\nHost: foo.bar\r\n", "\r\nDEL"},
+			expectedRequests: []string{"GET /api"},
+		},
+		{
+			name:             "body with Content-Length",
+			chunks:           []string{"POST /api HTTP/1.1\r\nContent-Length: 28\r\n\r\nDELETE /api/admin", " HTTP/1.1\r\nDELETE /api/admin HTTP/1.1\r\n\r\n"},
+			expectedRequests: []string{"POST /api", "DELETE /api/admin"},
+		},
+		{
+			name:             "chunked body",
+			chunks:           []string{"POST /api HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n1c;ext=1\r\nDELETE /api/admin HTTP/1.1\r\n\r\n0\r\nX-Trailer: 1\r\n\r\n", "DELETE /api/admin HTTP/1.1\r\n\r\n"},
+			expectedRequests: []string{"POST /api", "DELETE /api/admin"},
+		},
+		{
+			name:             "invalid chunk",
+			chunks:           []string{"POST /api HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n1\r\nab\r\n"},
+			expectedRequests: []string{"POST /api"},
+			expectErr:        true,
+		},
+		{
+			name:             "Transfer-Encoding and Content-Length",
+			chunks:           []string{"POST /api HTTP/1.1\r\nTransfer-Encoding: chunked\r\nContent-Length: 3\r\n\r\n"},
+			expectedRequests: []string{"POST /api"},
+			expectErr:        true,
+		},
+		{
+			name:             "invalid Content-Length",
+			chunks:           []string{"POST /api HTTP/1.1\r\nContent-Length: 3, 4\r\n\r\n"},
+			expectedRequests: []string{"POST /api"},
+			expectErr:        true,
+		},
+		{
+			name:             "non-chunked Transfer-Encoding",
+			chunks:           []string{"POST /api HTTP/1.1\r\nTransfer-Encoding: gzip\r\n\r\n"},
+			expectedRequests: []string{"POST /api"},
+			expectErr:        true,
+		},
+		{
+			name:      "incomplete non-HTTP line",
+			chunks:    []string{"\x16\x03\x01\x02\x00"},
+			expectErr: true,
+		},
+		{
+			name:      "non-HTTP line",
+			chunks:    []string{"SSH-2.0-OpenSSH_7.6p1\r\n"},
+			expectErr: true,
+		},
+		{
+			name:      "too large header",
+			chunks:    []string{"GET /api HTTP/1.1\r\nX-Foo: " + strings.Repeat("a", maxHTTPHeaderSize)},
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &httpStreamParser{}
+			var requests []string
+			var err error
+			for _, chunk := range tt.chunks {
+				var reqs []*httpRequest
+				reqs, err = p.feed([]byte(chunk))
+				for _, req := range reqs {
+					requests = append(requests, req.method+" "+req.path)
+				}
+				if err != nil {
+					break
+				}
+			}
+			assert.Equal(t, tt.expectedRequests, requests)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestL7ConnTrackerHandleSegment(t *testing.T) {
+	key := l7ConnKey{ruleID: "rule1", srcIP: "10.10.0.2", dstIP: "10.10.1.3", srcPort: 34567, dstPort: 80}
+	request := "GET /api HTTP/1.1\r\n\r\n"
+	now := time.Now()
+	segment := func(seq uint32, payload string) *tcpSegment {
+		return &tcpSegment{srcPort: 34567, dstPort: 80, seq: seq, payload: []byte(payload)}
+	}
+
+	tracker := newL7ConnTracker()
+	action, requests := tracker.handleSegment(key, segment(1000, ""), now)
+	assert.Equal(t, l7SegmentReinject, action)
+	assert.Empty(t, requests)
+	// The segment following a gap is dropped.
+	action, _ = tracker.handleSegment(key, segment(1010, request[10:]), now)
+	assert.Equal(t, l7SegmentDrop, action)
+	action, requests = tracker.handleSegment(key, segment(1000, request[:10]), now)
+	assert.Equal(t, l7SegmentReinject, action)
+	assert.Empty(t, requests)
+	action, requests = tracker.handleSegment(key, segment(1010, request[10:]), now)
+	assert.Equal(t, l7SegmentReinject, action)
+	require.Len(t, requests, 1)
+	assert.Equal(t, "GET", requests[0].method)
+	// The retransmitted segments are not fed again.
+	action, requests = tracker.handleSegment(key, segment(1000, request), now)
+	assert.Equal(t, l7SegmentReinject, action)
+	assert.Empty(t, requests)
+	// The segment overlapping the bytes fed so far is fed from the first new byte.
+	action, requests = tracker.handleSegment(key, segment(1010, request[10:]+request), now)
+	assert.Equal(t, l7SegmentReinject, action)
+	assert.Len(t, requests, 1)
+	// The bytes preceding the first packet seen by the agent cannot be evaluated.
+	action, _ = tracker.handleSegment(key, segment(999, "x"+request), now)
+	assert.Equal(t, l7SegmentDeny, action)
+	assert.Empty(t, tracker.conns)
+
+	action, _ = tracker.handleSegment(key, segment(1000, "hello\r\n"), now)
+	assert.Equal(t, l7SegmentDeny, action)
+	assert.Empty(t, tracker.conns)
+
+	tracker.handleSegment(key, segment(1000, request), now)
+	action, _ = tracker.handleSegment(key, &tcpSegment{seq: 1020, flags: tcpFlagRST}, now)
+	assert.Equal(t, l7SegmentReinject, action)
+	assert.Empty(t, tracker.conns)
+}
+
+func TestL7ConnTrackerForget(t *testing.T) {
+	key1 := l7ConnKey{ruleID: "rule1", srcIP: "10.10.0.2", dstIP: "10.10.1.3", srcPort: 34567, dstPort: 80}
+	key2 := l7ConnKey{ruleID: "rule1", srcIP: "10.10.0.2", dstIP: "10.10.1.3", srcPort: 34568, dstPort: 80}
+	key3 := l7ConnKey{ruleID: "rule2", srcIP: "10.10.0.2", dstIP: "10.10.1.3", srcPort: 34567, dstPort: 80}
+	now := time.Now()
+	tracker := newL7ConnTracker()
+	tracker.handleSegment(key1, &tcpSegment{seq: 1000}, now.Add(-2*l7ConnIdleTimeout))
+	tracker.handleSegment(key2, &tcpSegment{seq: 1000}, now)
+	tracker.handleSegment(key3, &tcpSegment{seq: 1000}, now)
+
+	tracker.gc(now)
+	assert.Len(t, tracker.conns, 2)
+	assert.NotContains(t, tracker.conns, key1)
+	tracker.forgetRule("rule1")
+	assert.Len(t, tracker.conns, 1)
+	assert.Contains(t, tracker.conns, key3)
+}
+
+func TestHandleL7PacketIn(t *testing.T) {
+	allowAction := secv1alpha1.RuleActionAllow
+	dropAction := secv1alpha1.RuleActionDrop
+	httpMatches := []v1beta1.HTTPMatch{{Method: "DELETE", Path: "/api/admin*"}}
+	srcIP := net.ParseIP("10.10.0.2").To4()
+	dstIP := net.ParseIP("10.10.1.3").To4()
+	type packet struct {
+		payload    string
+		expectDeny bool
+	}
+	tests := []struct {
+		name    string
+		action  *secv1alpha1.RuleAction
+		packets []packet
+	}{
+		{"drop rule matching request", &dropAction, []packet{{"DELETE /api/admin/users HTTP/1.1\r\n\r\n", true}}},
+		{"drop rule not matching request", &dropAction, []packet{{"GET /api/admin/users HTTP/1.1\r\n\r\n", false}}},
+		{"allow rule matching request", &allowAction, []packet{{"DELETE /api/admin HTTP/1.1\r\n\r\n", false}}},
+		{"allow rule with non-HTTP payload", &allowAction, []packet{{"hello", true}}},
+		{"ACK without payload", &allowAction, []packet{{"", false}}},
+		{
+			name:   "drop rule matching second request",
+			action: &dropAction,
+			packets: []packet{
+				{"", false},
+				{"GET /api/admin/users HTTP/1.1\r\n\r\n", false},
+				{"DELETE /api/admin/users HTTP/1.1\r\n\r\n", true},
+			},
+		},
+		{
+			name:    "allow rule not matching pipelined request",
+			action:  &allowAction,
+			packets: []packet{{"DELETE /api/admin HTTP/1.1\r\n\r\nGET /api/admin HTTP/1.1\r\n\r\n", true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockOFClient := openflowtest.NewMockClient(ctrl)
+			c, _, _ := newTestController()
+			c.ofClient = mockOFClient
+			r := &rule{ID: "rule1", Direction: v1beta1.DirectionIn, Action: tt.action, HTTPMatches: httpMatches}
+			seq := uint32(1000)
+			for _, p := range tt.packets {
+				var data []byte
+				if p.payload != "" {
+					data = []byte(p.payload)
+				}
+				pktIn := newPacketIn(protocol.Type_TCP, &protocol.TCP{PortSrc: 34567, PortDst: 80, SeqNum: seq, HdrLen: 5, Data: data})
+				if p.expectDeny {
+					mockOFClient.EXPECT().InstallL7ConnDenyFlow(uint32(1), v1beta1.DirectionIn, srcIP, dstIP, uint16(34567), uint16(80))
+				} else {
+					mockOFClient.EXPECT().ReinjectL7Packet(v1beta1.DirectionIn, pktIn)
+				}
+				assert.NoError(t, c.handleL7PacketIn(1, r, pktIn))
+				seq += uint32(len(data))
+			}
+		})
+	}
+}
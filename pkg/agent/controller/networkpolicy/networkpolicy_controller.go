@@ -23,6 +23,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/util/workqueue"
@@ -31,7 +32,9 @@ import (
 	"github.com/vmware-tanzu/antrea/pkg/agent"
 	"github.com/vmware-tanzu/antrea/pkg/agent/interfacestore"
 	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/agent/types"
 	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
 )
 
 const (
@@ -69,6 +72,24 @@ type Controller struct {
 	// reconciler provides interfaces to reconcile the desired state of
 	// NetworkPolicy rules with the actual state of Openflow entries.
 	reconciler Reconciler
+	// ofClient re-injects the allowed packets and denies the TCP connections
+	// matching the rules with HTTP matches.
+	ofClient openflow.Client
+	// l7Conns tracks the HTTP requests of the TCP connections matching the
+	// rules with HTTP matches.
+	l7Conns *l7ConnTracker
+	// ifaceStore provides the local Pods of the IP addresses in the logged
+	// packets.
+	ifaceStore interfacestore.InterfaceStore
+	// auditLogger writes the packets matching the rules which enable logging
+	// to the audit log file.
+	auditLogger *auditLogger
+
+	// realizedRules are the IDs of the rules which have been realized at
+	// least once since the agent started. It's used to determine when the
+	// initial rules have been realized.
+	realizedRules     sets.String
+	realizedRulesLock sync.RWMutex
 
 	networkPolicyWatcher  *watcher
 	appliedToGroupWatcher *watcher
@@ -79,12 +100,18 @@ type Controller struct {
 func NewNetworkPolicyController(antreaClientGetter agent.AntreaClientProvider,
 	ofClient openflow.Client,
 	ifaceStore interfacestore.InterfaceStore,
+	ovsCtlClient ovsctl.OVSCtlClient,
 	nodeName string,
 	podUpdates <-chan v1beta1.PodReference) *Controller {
 	c := &Controller{
 		antreaClientProvider: antreaClientGetter,
 		queue:                workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "networkpolicyrule"),
-		reconciler:           newReconciler(ofClient, ifaceStore),
+		reconciler:           newReconciler(ofClient, ifaceStore, ovsCtlClient),
+		realizedRules:        sets.NewString(),
+		ofClient:             ofClient,
+		ifaceStore:           ifaceStore,
+		auditLogger:          newAuditLogger(AuditLogPath),
+		l7Conns:              newL7ConnTracker(),
 	}
 	c.ruleCache = newRuleCache(c.enqueueRule, podUpdates)
 
@@ -278,6 +305,47 @@ func (c *Controller) GetAppliedToGroups() []v1beta1.AppliedToGroup {
 	return c.ruleCache.GetAppliedToGroups()
 }
 
+// GetRuleByFlowID returns the NetworkPolicy rule which the Openflow rule with
+// the provided ID is installed for. nil is returned if the Openflow rule is not
+// found.
+func (c *Controller) GetRuleByFlowID(ruleFlowID uint32) *types.PolicyRuleRef {
+	rule, exists := c.reconciler.GetRuleByFlowID(ruleFlowID)
+	if !exists {
+		return nil
+	}
+	ref := &types.PolicyRuleRef{
+		PolicyName:      rule.PolicyName,
+		PolicyNamespace: rule.PolicyNamespace,
+		PolicyType:      types.K8sNetworkPolicy,
+		RulePriority:    rule.Priority,
+	}
+	if rule.PolicyPriority != nil {
+		if rule.PolicyNamespace == "" {
+			ref.PolicyType = types.AntreaClusterNetworkPolicy
+		} else {
+			ref.PolicyType = types.AntreaNetworkPolicy
+		}
+	}
+	return ref
+}
+
+// HasSynced returns true once the NetworkPolicies received from
+// antrea-controller after the agent started have been realized, i.e. all their
+// rules have been reconciled at least once.
+func (c *Controller) HasSynced() bool {
+	if !c.networkPolicyWatcher.isSynced() || !c.appliedToGroupWatcher.isSynced() || !c.addressGroupWatcher.isSynced() {
+		return false
+	}
+	c.realizedRulesLock.RLock()
+	defer c.realizedRulesLock.RUnlock()
+	for _, ruleID := range c.ruleCache.rules.ListKeys() {
+		if !c.realizedRules.Has(ruleID) {
+			return false
+		}
+	}
+	return true
+}
+
 func (c *Controller) GetControllerConnectionStatus() bool {
 	// When the watchers are connected, controller connection status is true. Otherwise, it is false.
 	return c.addressGroupWatcher.isConnected() && c.appliedToGroupWatcher.isConnected() && c.networkPolicyWatcher.isConnected()
@@ -297,6 +365,7 @@ func (c *Controller) Run(stopCh <-chan struct{}) error {
 	for i := 0; i < defaultWorkers; i++ {
 		go wait.Until(c.worker, time.Second, stopCh)
 	}
+	go c.runL7ConnGC(stopCh)
 
 	<-stopCh
 	return nil
@@ -340,6 +409,10 @@ func (c *Controller) syncRule(key string) error {
 		if err := c.reconciler.Forget(key); err != nil {
 			return err
 		}
+		c.l7Conns.forgetRule(key)
+		c.realizedRulesLock.Lock()
+		c.realizedRules.Delete(key)
+		c.realizedRulesLock.Unlock()
 		return nil
 	}
 	// If the rule is not complete, we can simply skip it as it will be marked as dirty
@@ -351,6 +424,9 @@ func (c *Controller) syncRule(key string) error {
 	if err := c.reconciler.Reconcile(rule); err != nil {
 		return err
 	}
+	c.realizedRulesLock.Lock()
+	c.realizedRules.Insert(key)
+	c.realizedRulesLock.Unlock()
 	return nil
 }
 
@@ -381,7 +457,10 @@ type watcher struct {
 	ReplaceFunc func(objs []runtime.Object) error
 	// connected represents whether the watch has connected to apiserver successfully.
 	connected bool
-	// lock protects connected.
+	// synced represents whether the init events have ever been handled, i.e.
+	// whether the objects have been in sync with apiserver at least once.
+	synced bool
+	// lock protects connected and synced.
 	lock sync.RWMutex
 }
 
@@ -397,6 +476,18 @@ func (w *watcher) setConnected(connected bool) {
 	w.connected = connected
 }
 
+func (w *watcher) isSynced() bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.synced
+}
+
+func (w *watcher) setSynced() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.synced = true
+}
+
 func (w *watcher) watch() {
 	klog.Infof("Starting watch for %s", w.objectType)
 	watcher, err := w.watchFunc()
@@ -442,6 +533,7 @@ loop:
 		klog.Errorf("Failed to handle init events: %v", err)
 		return
 	}
+	w.setSynced()
 
 	for {
 		select {
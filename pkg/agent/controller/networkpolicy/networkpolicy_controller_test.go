@@ -26,6 +26,7 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 	k8stesting "k8s.io/client-go/testing"
 
+	agenttypes "github.com/vmware-tanzu/antrea/pkg/agent/types"
 	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
 	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
 	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
@@ -44,7 +45,7 @@ func (g *antreaClientGetter) GetAntreaClient() (versioned.Interface, error) {
 func newTestController() (*Controller, *fake.Clientset, *mockReconciler) {
 	clientset := &fake.Clientset{}
 	ch := make(chan v1beta1.PodReference, 100)
-	controller := NewNetworkPolicyController(&antreaClientGetter{clientset}, nil, nil, "node1", ch)
+	controller := NewNetworkPolicyController(&antreaClientGetter{clientset}, nil, nil, nil, "node1", ch)
 	reconciler := newMockReconciler()
 	controller.reconciler = reconciler
 	return controller, clientset, reconciler
@@ -56,6 +57,7 @@ func newTestController() (*Controller, *fake.Clientset, *mockReconciler) {
 type mockReconciler struct {
 	sync.Mutex
 	lastRealized map[string]*CompletedRule
+	ofIDRules    map[uint32]*rule
 	updated      chan string
 	deleted      chan string
 }
@@ -63,6 +65,7 @@ type mockReconciler struct {
 func newMockReconciler() *mockReconciler {
 	return &mockReconciler{
 		lastRealized: map[string]*CompletedRule{},
+		ofIDRules:    map[uint32]*rule{},
 		updated:      make(chan string, 10),
 		deleted:      make(chan string, 10),
 	}
@@ -91,6 +94,13 @@ func (r *mockReconciler) getLastRealized(ruleID string) (*CompletedRule, bool) {
 	return lastRealized, exists
 }
 
+func (r *mockReconciler) GetRuleByFlowID(ofID uint32) (*rule, bool) {
+	r.Lock()
+	defer r.Unlock()
+	rule, exists := r.ofIDRules[ofID]
+	return rule, exists
+}
+
 var _ Reconciler = &mockReconciler{}
 
 func newAddressGroup(name string, addresses []v1beta1.GroupMemberPod) *v1beta1.AddressGroup {
@@ -426,3 +436,60 @@ func TestAddNetworkPolicyWithMultipleRules(t *testing.T) {
 	assert.Equal(t, 2, controller.GetAddressGroupNum())
 	assert.Equal(t, 1, controller.GetAppliedToGroupNum())
 }
+
+func TestGetRuleByFlowID(t *testing.T) {
+	controller, _, reconciler := newTestController()
+	policyPriority := 1.0
+	reconciler.ofIDRules[1] = &rule{ID: "rule1", Priority: -1, PolicyName: "np1", PolicyNamespace: "ns1"}
+	reconciler.ofIDRules[2] = &rule{ID: "rule2", Priority: 2, PolicyPriority: &policyPriority, PolicyName: "anp1", PolicyNamespace: "ns1"}
+	reconciler.ofIDRules[3] = &rule{ID: "rule3", Priority: 0, PolicyPriority: &policyPriority, PolicyName: "cnp1"}
+
+	tests := []struct {
+		ruleFlowID  uint32
+		expectedRef *agenttypes.PolicyRuleRef
+	}{
+		{1, &agenttypes.PolicyRuleRef{PolicyName: "np1", PolicyNamespace: "ns1", PolicyType: agenttypes.K8sNetworkPolicy, RulePriority: -1}},
+		{2, &agenttypes.PolicyRuleRef{PolicyName: "anp1", PolicyNamespace: "ns1", PolicyType: agenttypes.AntreaNetworkPolicy, RulePriority: 2}},
+		{3, &agenttypes.PolicyRuleRef{PolicyName: "cnp1", PolicyType: agenttypes.AntreaClusterNetworkPolicy, RulePriority: 0}},
+		{4, nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expectedRef, controller.GetRuleByFlowID(tt.ruleFlowID), "Unexpected rule for flow ID %d", tt.ruleFlowID)
+	}
+}
+
+func TestHasSynced(t *testing.T) {
+	controller, clientset, reconciler := newTestController()
+	addressGroupWatcher := watch.NewFake()
+	appliedToGroupWatcher := watch.NewFake()
+	networkPolicyWatcher := watch.NewFake()
+	clientset.AddWatchReactor("addressgroups", k8stesting.DefaultWatchReactor(addressGroupWatcher, nil))
+	clientset.AddWatchReactor("appliedtogroups", k8stesting.DefaultWatchReactor(appliedToGroupWatcher, nil))
+	clientset.AddWatchReactor("networkpolicies", k8stesting.DefaultWatchReactor(networkPolicyWatcher, nil))
+
+	protocolTCP := v1beta1.ProtocolTCP
+	port := intstr.FromInt(80)
+	services := []v1beta1.Service{{Protocol: &protocolTCP, Port: &port}}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go controller.Run(stopCh)
+
+	assert.False(t, controller.HasSynced())
+	policy1 := newNetworkPolicy("policy1", []string{"addressGroup1"}, []string{}, []string{"appliedToGroup1"}, services)
+	networkPolicyWatcher.Add(policy1)
+	networkPolicyWatcher.Action(watch.Bookmark, nil)
+	addressGroupWatcher.Add(newAddressGroup("addressGroup1", []v1beta1.GroupMemberPod{*newAddressGroupMember("1.1.1.1")}))
+	addressGroupWatcher.Action(watch.Bookmark, nil)
+	// The rule of policy1 can't be realized without appliedToGroup1.
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, controller.HasSynced())
+
+	appliedToGroupWatcher.Add(newAppliedToGroup("appliedToGroup1", []v1beta1.GroupMemberPod{*newAppliedToGroupMember("pod1", "ns1")}))
+	appliedToGroupWatcher.Action(watch.Bookmark, nil)
+	select {
+	case <-reconciler.updated:
+	case <-time.After(time.Millisecond * 100):
+		t.Fatal("Expected one update, got none")
+	}
+	assert.Eventually(t, controller.HasSynced, time.Second, 10*time.Millisecond)
+}
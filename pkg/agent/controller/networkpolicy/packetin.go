@@ -0,0 +1,245 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/contiv/libOpenflow/protocol"
+	"github.com/contiv/ofnet/ofctrl"
+	"golang.org/x/time/rate"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+	binding "github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
+)
+
+const (
+	// AuditLogPath is the file to which the packets matching the
+	// NetworkPolicy rules which enable logging are logged.
+	AuditLogPath = "/var/log/antrea/networkpolicy.log"
+
+	// auditLogRate and auditLogBurst limit the number of packets logged per
+	// second for each rule, so that a flood of packets matching a rule cannot
+	// flood the audit log.
+	auditLogRate  = 10
+	auditLogBurst = 20
+
+	decisionAllow = "allow"
+	decisionDeny  = "deny"
+
+	sctpProtocolNumber uint8 = 132
+)
+
+// auditLogEntry is the JSON object logged for each packet.
+type auditLogEntry struct {
+	Timestamp       string `json:"timestamp"`
+	PolicyUID       string `json:"policyUID"`
+	PolicyName      string `json:"policyName"`
+	PolicyNamespace string `json:"policyNamespace,omitempty"`
+	RuleID          string `json:"ruleID"`
+	Direction       string `json:"direction"`
+	Decision        string `json:"decision"`
+	Protocol        string `json:"protocol"`
+	SourceIP        string `json:"sourceIP"`
+	SourcePort      uint16 `json:"sourcePort,omitempty"`
+	SourcePod       string `json:"sourcePod,omitempty"`
+	DestinationIP   string `json:"destinationIP"`
+	DestinationPort uint16 `json:"destinationPort,omitempty"`
+	DestinationPod  string `json:"destinationPod,omitempty"`
+}
+
+// auditLogger writes the audit log entries as JSON lines, rate-limited per
+// Openflow rule ID.
+type auditLogger struct {
+	mutex sync.Mutex
+	path  string
+	// writer is opened lazily, so that the file is not created on the Nodes
+	// where no rule enables logging.
+	writer   io.Writer
+	limiters map[uint32]*rate.Limiter
+}
+
+func newAuditLogger(path string) *auditLogger {
+	return &auditLogger{
+		path:     path,
+		limiters: map[uint32]*rate.Limiter{},
+	}
+}
+
+// allow returns whether a packet matching the provided Openflow rule can be
+// logged without exceeding the rate limit of the rule.
+func (l *auditLogger) allow(ofID uint32) bool {
+	limiter, exists := l.limiters[ofID]
+	if !exists {
+		limiter = rate.NewLimiter(auditLogRate, auditLogBurst)
+		l.limiters[ofID] = limiter
+	}
+	return limiter.Allow()
+}
+
+// log writes the entry if the rate limit of the Openflow rule allows it.
+func (l *auditLogger) log(ofID uint32, entry *auditLogEntry) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if !l.allow(ofID) {
+		klog.V(4).Infof("Dropped audit log entry of rule %s as the rate limit is exceeded", entry.RuleID)
+		return nil
+	}
+	if l.writer == nil {
+		if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		l.writer = f
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = l.writer.Write(append(data, '\n'))
+	return err
+}
+
+// HandlePacketIn handles the packets sent to the controller by the flows of the
+// NetworkPolicy rules. The packets sent by the L7 verdict tables are used to
+// decide the verdicts of the TCP connections matching the rules with HTTP
+// matches, and the packets sent by the policy logging table, to which the action
+// flows of the rules enabling logging resubmit the packets, are logged.
+func (c *Controller) HandlePacketIn(pktIn *ofctrl.PacketIn) error {
+	tableID := binding.TableIDType(pktIn.TableId)
+	if !openflow.IsL7VerdictTable(tableID) && !openflow.IsPolicyLoggingTable(tableID) {
+		return fmt.Errorf("unexpected NetworkPolicy packetIn from table %d", tableID)
+	}
+	ofID, err := getConjunctionID(pktIn)
+	if err != nil {
+		return err
+	}
+	r, exists := c.reconciler.GetRuleByFlowID(ofID)
+	if !exists {
+		return fmt.Errorf("rule of Openflow ID %d not found", ofID)
+	}
+	if openflow.IsL7VerdictTable(tableID) {
+		return c.handleL7PacketIn(ofID, r, pktIn)
+	}
+	decision := decisionAllow
+	if r.Action != nil && *r.Action == secv1alpha1.RuleActionDrop {
+		decision = decisionDeny
+	}
+	return c.logPacket(ofID, r, pktIn, decision)
+}
+
+// logPacket writes the packet matching the rule to the audit log.
+func (c *Controller) logPacket(ofID uint32, r *rule, pktIn *ofctrl.PacketIn, decision string) error {
+	entry, err := parseAuditLogPacket(pktIn)
+	if err != nil {
+		return err
+	}
+	entry.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	entry.PolicyUID = string(r.PolicyUID)
+	entry.PolicyName = r.PolicyName
+	entry.PolicyNamespace = r.PolicyNamespace
+	entry.RuleID = r.ID
+	entry.Direction = string(r.Direction)
+	entry.Decision = decision
+	entry.SourcePod = c.getPodName(entry.SourceIP)
+	entry.DestinationPod = c.getPodName(entry.DestinationIP)
+	return c.auditLogger.log(ofID, entry)
+}
+
+// getPodName returns the Namespace and name of the local Pod which has the
+// provided IP, or an empty string if the IP is not a local Pod's.
+func (c *Controller) getPodName(ip string) string {
+	if c.ifaceStore == nil {
+		return ""
+	}
+	iface, exists := c.ifaceStore.GetInterfaceByIP(ip)
+	if !exists || iface.ContainerInterfaceConfig == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s", iface.PodNamespace, iface.PodName)
+}
+
+// getConjunctionID returns the conjunction ID loaded in the register by the
+// action flow which sent the packet.
+func getConjunctionID(pktIn *ofctrl.PacketIn) (uint32, error) {
+	reg := openflow.GetConjunctionIDReg(binding.TableIDType(pktIn.TableId))
+	match := pktIn.GetMatches().GetMatchByName(fmt.Sprintf("NXM_NX_REG%d", reg))
+	if match == nil {
+		return 0, errors.New("conjunction ID not found in packetIn")
+	}
+	regValue, ok := match.GetValue().(*ofctrl.NXRegister)
+	if !ok {
+		return 0, errors.New("register value cannot be got")
+	}
+	return regValue.Data, nil
+}
+
+// parseAuditLogPacket returns an auditLogEntry filled with the addresses, the
+// ports and the protocol of the IPv4 packet.
+func parseAuditLogPacket(pktIn *ofctrl.PacketIn) (*auditLogEntry, error) {
+	ipPacket, ok := pktIn.Data.Data.(*protocol.IPv4)
+	if !ok {
+		return nil, errors.New("invalid IPv4 packet")
+	}
+	entry := &auditLogEntry{
+		Protocol:      protocolName(ipPacket.Protocol),
+		SourceIP:      ipPacket.NWSrc.String(),
+		DestinationIP: ipPacket.NWDst.String(),
+	}
+	switch ipPacket.Protocol {
+	case protocol.Type_TCP, protocol.Type_UDP, sctpProtocolNumber:
+		if ipPacket.Data == nil {
+			break
+		}
+		// The source and destination ports are the first 4 bytes of the
+		// TCP, UDP and SCTP headers.
+		data, err := ipPacket.Data.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) >= 4 {
+			entry.SourcePort = binary.BigEndian.Uint16(data[0:2])
+			entry.DestinationPort = binary.BigEndian.Uint16(data[2:4])
+		}
+	}
+	return entry, nil
+}
+
+func protocolName(proto uint8) string {
+	switch proto {
+	case protocol.Type_ICMP:
+		return "ICMP"
+	case protocol.Type_TCP:
+		return "TCP"
+	case protocol.Type_UDP:
+		return "UDP"
+	case sctpProtocolNumber:
+		return "SCTP"
+	}
+	return fmt.Sprintf("%d", proto)
+}
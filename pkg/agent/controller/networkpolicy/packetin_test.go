@@ -0,0 +1,107 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/contiv/libOpenflow/protocol"
+	"github.com/contiv/libOpenflow/util"
+	"github.com/contiv/ofnet/ofctrl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPacketIn(proto uint8, data util.Message) *ofctrl.PacketIn {
+	ipPacket := &protocol.IPv4{
+		Protocol: proto,
+		NWSrc:    net.ParseIP("10.10.0.2").To4(),
+		NWDst:    net.ParseIP("10.10.1.3").To4(),
+		Data:     data,
+	}
+	return &ofctrl.PacketIn{Data: protocol.Ethernet{Ethertype: protocol.IPv4_MSG, Data: ipPacket}}
+}
+
+func TestParseAuditLogPacket(t *testing.T) {
+	tests := []struct {
+		name          string
+		pktIn         *ofctrl.PacketIn
+		expectedEntry *auditLogEntry
+	}{
+		{
+			name:  "tcp",
+			pktIn: newPacketIn(protocol.Type_TCP, &protocol.TCP{PortSrc: 34567, PortDst: 80, HdrLen: 5}),
+			expectedEntry: &auditLogEntry{
+				Protocol:        "TCP",
+				SourceIP:        "10.10.0.2",
+				SourcePort:      34567,
+				DestinationIP:   "10.10.1.3",
+				DestinationPort: 80,
+			},
+		},
+		{
+			name:  "udp",
+			pktIn: newPacketIn(protocol.Type_UDP, &protocol.UDP{PortSrc: 34567, PortDst: 53}),
+			expectedEntry: &auditLogEntry{
+				Protocol:        "UDP",
+				SourceIP:        "10.10.0.2",
+				SourcePort:      34567,
+				DestinationIP:   "10.10.1.3",
+				DestinationPort: 53,
+			},
+		},
+		{
+			name:  "icmp",
+			pktIn: newPacketIn(protocol.Type_ICMP, &protocol.ICMP{Type: 8}),
+			expectedEntry: &auditLogEntry{
+				Protocol:      "ICMP",
+				SourceIP:      "10.10.0.2",
+				DestinationIP: "10.10.1.3",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := parseAuditLogPacket(tt.pktIn)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedEntry, entry)
+		})
+	}
+}
+
+func TestAuditLoggerRateLimit(t *testing.T) {
+	logger := newAuditLogger("")
+	buf := &bytes.Buffer{}
+	logger.writer = buf
+	entry := &auditLogEntry{RuleID: "rule1", Decision: decisionDeny, Protocol: "TCP", SourceIP: "10.10.0.2", DestinationIP: "10.10.1.3"}
+	for i := 0; i < auditLogBurst*2; i++ {
+		require.NoError(t, logger.log(1, entry))
+	}
+	// The rate limit is per rule.
+	require.NoError(t, logger.log(2, entry))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	// A few more entries may be logged if the tokens are replenished during the
+	// loop.
+	assert.GreaterOrEqual(t, len(lines), auditLogBurst+1)
+	assert.Less(t, len(lines), auditLogBurst*2+1)
+	var logged auditLogEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &logged))
+	assert.Equal(t, *entry, logged)
+}
@@ -22,6 +22,7 @@ import (
 	"k8s.io/klog"
 
 	"github.com/vmware-tanzu/antrea/pkg/agent/types"
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
 )
 
 const (
@@ -29,6 +30,14 @@ const (
 	InitialPriorityOffset = uint16(130)
 	InitialPriorityZones  = 100
 	DefaultTierStart      = uint16(13100)
+	// BaselineTierStart is the highest OF priority of the rules of the baseline
+	// tier, which are all installed in a single priority zone below the rules
+	// of the default tier.
+	BaselineTierStart = PriorityBottomCNP - 1
+	// PriorityBottomBaseline is the lowest OF priority of the rules of the
+	// baseline tier. The OF priorities below it are used by the catch-all
+	// flows of the baseline tier.
+	PriorityBottomBaseline = uint16(3)
 )
 
 // priorityAssigner is a struct that maintains the current boundaries of
@@ -54,10 +63,19 @@ func newPriorityAssigner() *priorityAssigner {
 	return pa
 }
 
+// isBaseline returns true if the priority belongs to the baseline tier.
+func isBaseline(p types.Priority) bool {
+	return p.TierPriority == v1beta1.BaselineTierPriority
+}
+
 // getPriorityZoneIndex returns the priorityZone index for the given priority.
 // It maps policyPriority [0.0-1.0) to 0, [1.0-2.0) to 1 and so on so forth.
 // policyPriorities over 99.0 will be mapped to zone 99, without zone expansion for now.
+// The baseline tier has a single priorityZone, whose index is 0.
 func (pa *priorityAssigner) getPriorityZoneIndex(p types.Priority) int32 {
+	if isBaseline(p) {
+		return 0
+	}
 	floorPriority := int32(math.Floor(p.PolicyPriority))
 	if floorPriority > pa.numPriorityZones-1 {
 		floorPriority = pa.numPriorityZones - 1
@@ -67,12 +85,18 @@ func (pa *priorityAssigner) getPriorityZoneIndex(p types.Priority) int32 {
 
 // getPriorityZoneStart returns the starting OF priority for the priorityZone for the input.
 func (pa *priorityAssigner) getPriorityZoneStart(p types.Priority) uint16 {
+	if isBaseline(p) {
+		return BaselineTierStart
+	}
 	priorityIndex := pa.getPriorityZoneIndex(p)
 	return DefaultTierStart - pa.priorityOffset*uint16(priorityIndex)
 }
 
 // getPriorityZoneSize returns the size of the priorityZone for the input.
 func (pa *priorityAssigner) getPriorityZoneSize(p types.Priority) uint16 {
+	if isBaseline(p) {
+		return BaselineTierStart - PriorityBottomBaseline + 1
+	}
 	zoneStart := pa.getPriorityZoneStart(p)
 	if zoneStart-pa.priorityOffset < PriorityBottomCNP {
 		return zoneStart - PriorityBottomCNP
@@ -80,13 +104,24 @@ func (pa *priorityAssigner) getPriorityZoneSize(p types.Priority) uint16 {
 	return pa.priorityOffset
 }
 
-// sortPriorities sorts a list of priorities.
+// sortPriorities sorts a list of priorities. The priorities of the policies with
+// the same priority are sorted by the creation timestamps of the policies, the
+// older policy first, then by the names of the policies.
 func (pa *priorityAssigner) sortPriorities(priorities []types.Priority) {
 	sort.Slice(priorities, func(i, j int) bool {
-		if priorities[i].PolicyPriority == priorities[j].PolicyPriority {
-			return priorities[i].RulePriority < priorities[j].RulePriority
+		if priorities[i].TierPriority != priorities[j].TierPriority {
+			return priorities[i].TierPriority < priorities[j].TierPriority
+		}
+		if priorities[i].PolicyPriority != priorities[j].PolicyPriority {
+			return priorities[i].PolicyPriority < priorities[j].PolicyPriority
+		}
+		if priorities[i].PolicyCreationTimestamp != priorities[j].PolicyCreationTimestamp {
+			return priorities[i].PolicyCreationTimestamp < priorities[j].PolicyCreationTimestamp
+		}
+		if priorities[i].PolicyName != priorities[j].PolicyName {
+			return priorities[i].PolicyName < priorities[j].PolicyName
 		}
-		return priorities[i].PolicyPriority < priorities[j].PolicyPriority
+		return priorities[i].RulePriority < priorities[j].RulePriority
 	})
 }
 
@@ -0,0 +1,109 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/types"
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
+)
+
+func TestGetOFPriorityBaselineTier(t *testing.T) {
+	pa := newPriorityAssigner()
+	defaultPriority := types.Priority{TierPriority: v1beta1.DefaultTierPriority, PolicyPriority: 99.5}
+	baselinePriority1 := types.Priority{TierPriority: v1beta1.BaselineTierPriority, PolicyPriority: 1}
+	baselinePriority2 := types.Priority{TierPriority: v1beta1.BaselineTierPriority, PolicyPriority: 2}
+
+	// Even the lowest priority of the default tier is above the baseline tier.
+	ofPriority, updates, err := pa.GetOFPriority(defaultPriority)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTierStart-InitialPriorityOffset*99, *ofPriority)
+	assert.Empty(t, updates)
+
+	ofPriority, updates, err = pa.GetOFPriority(baselinePriority2)
+	require.NoError(t, err)
+	assert.Equal(t, BaselineTierStart, *ofPriority)
+	assert.Empty(t, updates)
+
+	// A baseline policy with a higher priority takes the top of the baseline
+	// tier, the existing one is moved down.
+	ofPriority, updates, err = pa.GetOFPriority(baselinePriority1)
+	require.NoError(t, err)
+	assert.Equal(t, BaselineTierStart, *ofPriority)
+	assert.Equal(t, map[uint16]uint16{BaselineTierStart: BaselineTierStart - 1}, updates)
+	assert.Equal(t, BaselineTierStart-1, pa.priorityMap[baselinePriority2])
+	assert.Less(t, uint64(BaselineTierStart), uint64(PriorityBottomCNP))
+
+	require.NoError(t, pa.Release(BaselineTierStart))
+	_, exists := pa.priorityMap[baselinePriority1]
+	assert.False(t, exists)
+}
+
+func TestGetOFPriorityTiebreaker(t *testing.T) {
+	// Three ClusterNetworkPolicies with the same priority: cnp-c is the oldest
+	// one, cnp-a and cnp-b were created at the same time.
+	cnpA := types.Priority{TierPriority: v1beta1.DefaultTierPriority, PolicyPriority: 5, PolicyCreationTimestamp: 1000, PolicyName: "cnp-a"}
+	cnpB := types.Priority{TierPriority: v1beta1.DefaultTierPriority, PolicyPriority: 5, PolicyCreationTimestamp: 1000, PolicyName: "cnp-b"}
+	cnpC := types.Priority{TierPriority: v1beta1.DefaultTierPriority, PolicyPriority: 5, PolicyCreationTimestamp: 900, PolicyName: "cnp-c"}
+	cnpC1 := cnpC
+	cnpC1.RulePriority = 1
+	zoneStart := DefaultTierStart - InitialPriorityOffset*5
+
+	pa := newPriorityAssigner()
+	ofPriority, updates, err := pa.GetOFPriority(cnpA)
+	require.NoError(t, err)
+	assert.Equal(t, zoneStart, *ofPriority)
+	assert.Empty(t, updates)
+
+	ofPriority, updates, err = pa.GetOFPriority(cnpB)
+	require.NoError(t, err)
+	assert.Equal(t, zoneStart-1, *ofPriority)
+	assert.Empty(t, updates)
+
+	// The rules of the oldest policy take precedence over the other ones.
+	ofPriority, updates, err = pa.GetOFPriority(cnpC1)
+	require.NoError(t, err)
+	assert.Equal(t, zoneStart, *ofPriority)
+	assert.Equal(t, map[uint16]uint16{zoneStart: zoneStart - 1, zoneStart - 1: zoneStart - 2}, updates)
+	ofPriority, updates, err = pa.GetOFPriority(cnpC)
+	require.NoError(t, err)
+	assert.Equal(t, zoneStart, *ofPriority)
+	assert.Equal(t, map[uint16]uint16{zoneStart: zoneStart - 1, zoneStart - 1: zoneStart - 2, zoneStart - 2: zoneStart - 3}, updates)
+
+	expectedPriorityMap := map[types.Priority]uint16{
+		cnpC:  zoneStart,
+		cnpC1: zoneStart - 1,
+		cnpA:  zoneStart - 2,
+		cnpB:  zoneStart - 3,
+	}
+	assert.Equal(t, expectedPriorityMap, pa.priorityMap)
+
+	// The OF priorities don't depend on the order in which the rules are added.
+	for _, order := range [][]types.Priority{
+		{cnpB, cnpC, cnpA, cnpC1},
+		{cnpC1, cnpB, cnpA, cnpC},
+	} {
+		pa := newPriorityAssigner()
+		for _, p := range order {
+			_, _, err := pa.GetOFPriority(p)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, expectedPriorityMap, pa.priorityMap)
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
 	"github.com/vmware-tanzu/antrea/pkg/agent/types"
 	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
 	"github.com/vmware-tanzu/antrea/pkg/util/ip"
 )
 
@@ -52,6 +53,10 @@ type Reconciler interface {
 
 	// Forget cleanups the actual state of Openflow entries of the specified ruleID.
 	Forget(ruleID string) error
+
+	// GetRuleByFlowID returns the rule which the specified Openflow rule is
+	// installed for.
+	GetRuleByFlowID(ofID uint32) (*rule, bool)
 }
 
 // servicesHash is used to uniquely identify Services.
@@ -153,6 +158,11 @@ type reconciler struct {
 	// It's a mapping from ruleID to *lastRealized.
 	lastRealizeds sync.Map
 
+	// ofIDRules caches the rules of the installed Openflow rules, so that
+	// the statistics of an Openflow rule can be attributed to its policy.
+	// It's a mapping from ofID to *rule.
+	ofIDRules sync.Map
+
 	// idAllocator provides interfaces to allocate and release uint32 id.
 	idAllocator *idAllocator
 
@@ -161,16 +171,21 @@ type reconciler struct {
 
 	// priorityMutex prevents concurrent priority re-assignments
 	priorityMutex sync.RWMutex
+
+	// bandwidthLimiter enforces the bandwidth limits of the rules.
+	bandwidthLimiter *bandwidthLimiter
 }
 
 // newReconciler returns a new *reconciler.
-func newReconciler(ofClient openflow.Client, ifaceStore interfacestore.InterfaceStore) *reconciler {
+func newReconciler(ofClient openflow.Client, ifaceStore interfacestore.InterfaceStore, ovsCtlClient ovsctl.OVSCtlClient) *reconciler {
 	reconciler := &reconciler{
 		ofClient:         ofClient,
 		ifaceStore:       ifaceStore,
 		lastRealizeds:    sync.Map{},
+		ofIDRules:        sync.Map{},
 		idAllocator:      newIDAllocator(),
 		priorityAssigner: newPriorityAssigner(),
+		bandwidthLimiter: newBandwidthLimiter(ovsCtlClient, ifaceStore),
 	}
 	return reconciler
 }
@@ -200,10 +215,13 @@ func (r *reconciler) Reconcile(rule *CompletedRule) error {
 	} else {
 		ofRuleInstallErr = r.update(value.(*lastRealized), rule, ofPriority)
 	}
-	if ofRuleInstallErr != nil && ofPriority != nil {
-		r.priorityAssigner.Release(*ofPriority)
+	if ofRuleInstallErr != nil {
+		if ofPriority != nil {
+			r.priorityAssigner.Release(*ofPriority)
+		}
+		return ofRuleInstallErr
 	}
-	return ofRuleInstallErr
+	return r.bandwidthLimiter.reconcile(rule)
 }
 
 // getOFPriority retrieves the OFPriority for the input CompletedRule to be installed,
@@ -213,7 +231,13 @@ func (r *reconciler) getOFPriority(rule *CompletedRule) (*uint16, error) {
 		klog.V(2).Infof("Assigning default priority for k8s NetworkPolicy.")
 		return nil, nil
 	}
-	p := types.Priority{PolicyPriority: *rule.PolicyPriority, RulePriority: rule.Priority}
+	p := types.Priority{
+		TierPriority:            rule.tierPriority(),
+		PolicyPriority:          *rule.PolicyPriority,
+		PolicyCreationTimestamp: rule.PolicyCreationTimestamp.Unix(),
+		PolicyName:              rule.PolicyName,
+		RulePriority:            rule.Priority,
+	}
 	ofPriority, priorityUpdates, err := r.priorityAssigner.GetOFPriority(p)
 	if err != nil {
 		return nil, err
@@ -252,12 +276,15 @@ func (r *reconciler) add(rule *CompletedRule, ofPriority *uint16) error {
 			ofPorts := r.getPodOFPorts(pods)
 			lastRealized.podOFPorts[svcHash] = ofPorts
 			ofRuleByServicesMap[svcHash] = &types.PolicyRule{
-				Direction: v1beta1.DirectionIn,
-				From:      append(from1, from2...),
-				To:        ofPortsToOFAddresses(ofPorts),
-				Service:   filterUnresolvablePort(servicesMap[svcHash]),
-				Action:    rule.Action,
-				Priority:  ofPriority,
+				Direction:     v1beta1.DirectionIn,
+				From:          append(from1, from2...),
+				To:            ofPortsToOFAddresses(ofPorts),
+				Service:       filterUnresolvablePort(servicesMap[svcHash]),
+				Action:        rule.Action,
+				Priority:      ofPriority,
+				EnableLogging: rule.EnableLogging,
+				HTTPMatches:   rule.HTTPMatches,
+				Baseline:      rule.isBaselineRule(),
 			}
 		}
 	} else {
@@ -268,12 +295,16 @@ func (r *reconciler) add(rule *CompletedRule, ofPriority *uint16) error {
 		podsByServicesMap, servicesMap := groupPodsByServices(rule.Services, rule.ToAddresses)
 		for svcHash, pods := range podsByServicesMap {
 			ofRuleByServicesMap[svcHash] = &types.PolicyRule{
-				Direction: v1beta1.DirectionOut,
-				From:      from,
-				To:        podsToOFAddresses(pods),
-				Service:   filterUnresolvablePort(servicesMap[svcHash]),
-				Action:    rule.Action,
-				Priority:  ofPriority,
+				Direction:        v1beta1.DirectionOut,
+				From:             from,
+				To:               podsToOFAddresses(pods),
+				Service:          filterUnresolvablePort(servicesMap[svcHash]),
+				Action:           rule.Action,
+				Priority:         ofPriority,
+				EnableLogging:    rule.EnableLogging,
+				HTTPMatches:      rule.HTTPMatches,
+				PreserveSourceIP: rule.PreserveSourceIP,
+				Baseline:         rule.isBaselineRule(),
 			}
 		}
 
@@ -288,12 +319,15 @@ func (r *reconciler) add(rule *CompletedRule, ofPriority *uint16) error {
 			// Create a new Openflow rule if the group doesn't exist.
 			if !exists {
 				ofRule = &types.PolicyRule{
-					Direction: v1beta1.DirectionOut,
-					From:      from,
-					To:        []types.Address{},
-					Service:   filterUnresolvablePort(rule.Services),
-					Action:    rule.Action,
-					Priority:  nil,
+					Direction:        v1beta1.DirectionOut,
+					From:             from,
+					To:               []types.Address{},
+					Service:          filterUnresolvablePort(rule.Services),
+					Action:           rule.Action,
+					Priority:         nil,
+					EnableLogging:    rule.EnableLogging,
+					HTTPMatches:      rule.HTTPMatches,
+					PreserveSourceIP: rule.PreserveSourceIP,
 				}
 				ofRuleByServicesMap[svcHash] = ofRule
 			}
@@ -306,9 +340,7 @@ func (r *reconciler) add(rule *CompletedRule, ofPriority *uint16) error {
 	}
 
 	for svcHash, ofRule := range ofRuleByServicesMap {
-		npName := lastRealized.CompletedRule.PolicyName
-		npNamespace := lastRealized.CompletedRule.PolicyNamespace
-		ofID, err := r.installOFRule(ofRule, npName, npNamespace)
+		ofID, err := r.installOFRule(ofRule, lastRealized.CompletedRule)
 		if err != nil {
 			return err
 		}
@@ -345,14 +377,17 @@ func (r *reconciler) update(lastRealized *lastRealized, newRule *CompletedRule,
 			// Install a new Openflow rule if this group doesn't exist, otherwise do incremental update.
 			if !exists {
 				ofRule := &types.PolicyRule{
-					Direction: v1beta1.DirectionIn,
-					From:      append(from1, from2...),
-					To:        ofPortsToOFAddresses(newOFPorts),
-					Service:   filterUnresolvablePort(servicesMap[svcHash]),
-					Action:    newRule.Action,
-					Priority:  ofPriority,
+					Direction:     v1beta1.DirectionIn,
+					From:          append(from1, from2...),
+					To:            ofPortsToOFAddresses(newOFPorts),
+					Service:       filterUnresolvablePort(servicesMap[svcHash]),
+					Action:        newRule.Action,
+					Priority:      ofPriority,
+					EnableLogging: newRule.EnableLogging,
+					HTTPMatches:   newRule.HTTPMatches,
+					Baseline:      newRule.isBaselineRule(),
 				}
-				ofID, err := r.installOFRule(ofRule, newRule.PolicyName, newRule.PolicyNamespace)
+				ofID, err := r.installOFRule(ofRule, newRule)
 				if err != nil {
 					return err
 				}
@@ -387,14 +422,18 @@ func (r *reconciler) update(lastRealized *lastRealized, newRule *CompletedRule,
 			ofID, exists := lastRealized.ofIDs[svcHash]
 			if !exists {
 				ofRule := &types.PolicyRule{
-					Direction: v1beta1.DirectionOut,
-					From:      from,
-					To:        podsToOFAddresses(pods),
-					Service:   filterUnresolvablePort(servicesMap[svcHash]),
-					Action:    newRule.Action,
-					Priority:  ofPriority,
+					Direction:        v1beta1.DirectionOut,
+					From:             from,
+					To:               podsToOFAddresses(pods),
+					Service:          filterUnresolvablePort(servicesMap[svcHash]),
+					Action:           newRule.Action,
+					Priority:         ofPriority,
+					EnableLogging:    newRule.EnableLogging,
+					HTTPMatches:      newRule.HTTPMatches,
+					PreserveSourceIP: newRule.PreserveSourceIP,
+					Baseline:         newRule.isBaselineRule(),
 				}
-				ofID, err := r.installOFRule(ofRule, newRule.PolicyName, newRule.PolicyNamespace)
+				ofID, err := r.installOFRule(ofRule, newRule)
 				if err != nil {
 					return err
 				}
@@ -423,7 +462,7 @@ func (r *reconciler) update(lastRealized *lastRealized, newRule *CompletedRule,
 	return nil
 }
 
-func (r *reconciler) installOFRule(ofRule *types.PolicyRule, npName, npNamespace string) (uint32, error) {
+func (r *reconciler) installOFRule(ofRule *types.PolicyRule, rule *CompletedRule) (uint32, error) {
 	// Each pod group gets an Openflow ID.
 	ofID, err := r.idAllocator.allocate()
 	if err != nil {
@@ -431,10 +470,11 @@ func (r *reconciler) installOFRule(ofRule *types.PolicyRule, npName, npNamespace
 	}
 	klog.V(2).Infof("Installing ofRule %d (Direction: %v, From: %d, To: %d, Service: %d)",
 		ofID, ofRule.Direction, len(ofRule.From), len(ofRule.To), len(ofRule.Service))
-	if err := r.ofClient.InstallPolicyRuleFlows(ofID, ofRule, npName, npNamespace); err != nil {
+	if err := r.ofClient.InstallPolicyRuleFlows(ofID, ofRule, rule.PolicyName, rule.PolicyNamespace); err != nil {
 		r.idAllocator.release(ofID)
 		return 0, fmt.Errorf("error installing ofRule %v: %v", ofID, err)
 	}
+	r.ofIDRules.Store(ofID, rule.rule)
 	return ofID, nil
 }
 
@@ -473,6 +513,7 @@ func (r *reconciler) uninstallOFRule(ofID uint32) error {
 	if err != nil {
 		return fmt.Errorf("error uninstalling ofRule %v: %v", ofID, err)
 	}
+	r.ofIDRules.Delete(ofID)
 	if len(stalePriorities) > 0 {
 		for _, p := range stalePriorities {
 			klog.V(2).Infof("Releasing stale priority %v", p)
@@ -512,10 +553,25 @@ func (r *reconciler) Forget(ruleID string) error {
 		delete(lastRealized.podOFPorts, svcHash)
 	}
 
+	// The bandwidth limit is removed before the rule is deleted from
+	// lastRealizeds, so that it's retried if it fails.
+	if err := r.bandwidthLimiter.forget(ruleID); err != nil {
+		return err
+	}
 	r.lastRealizeds.Delete(ruleID)
 	return nil
 }
 
+// GetRuleByFlowID returns the rule which the specified Openflow rule is
+// installed for. It's thread-safe.
+func (r *reconciler) GetRuleByFlowID(ofID uint32) (*rule, bool) {
+	value, exists := r.ofIDRules.Load(ofID)
+	if !exists {
+		return nil, false
+	}
+	return value.(*rule), true
+}
+
 func (r *reconciler) getPodOFPorts(pods v1beta1.GroupMemberPodSet) sets.Int32 {
 	ofPorts := sets.NewInt32()
 	for _, pod := range pods {
@@ -109,7 +109,7 @@ func TestReconcilerForget(t *testing.T) {
 					mockOFClient.EXPECT().UninstallPolicyRuleFlows(ofID)
 				}
 			}
-			r := newReconciler(mockOFClient, ifaceStore)
+			r := newReconciler(mockOFClient, ifaceStore, nil)
 			for key, value := range tt.lastRealizeds {
 				r.lastRealizeds.Store(key, value)
 			}
@@ -431,7 +431,7 @@ func TestReconcilerReconcile(t *testing.T) {
 			for _, ofRule := range tt.expectedOFRules {
 				mockOFClient.EXPECT().InstallPolicyRuleFlows(gomock.Any(), gomock.Eq(ofRule), "", "")
 			}
-			r := newReconciler(mockOFClient, ifaceStore)
+			r := newReconciler(mockOFClient, ifaceStore, nil)
 			if err := r.Reconcile(tt.args); (err != nil) != tt.wantErr {
 				t.Fatalf("Reconcile() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -572,7 +572,7 @@ func TestReconcilerUpdate(t *testing.T) {
 			if len(tt.expectedDeletedTo) > 0 {
 				mockOFClient.EXPECT().DeletePolicyRuleAddress(gomock.Any(), types.DstAddress, gomock.Eq(tt.expectedDeletedTo), nil)
 			}
-			r := newReconciler(mockOFClient, ifaceStore)
+			r := newReconciler(mockOFClient, ifaceStore, nil)
 			if err := r.Reconcile(tt.originalRule); (err != nil) != tt.wantErr {
 				t.Fatalf("Reconcile() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -582,3 +582,71 @@ func TestReconcilerUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcilerUpdateNamedPort(t *testing.T) {
+	ifaceStore := interfacestore.NewInterfaceStore()
+	ifaceStore.AddInterface(&interfacestore.InterfaceConfig{
+		InterfaceName:            util.GenerateContainerInterfaceName("pod1", "ns1", "container1"),
+		IP:                       net.ParseIP("2.2.2.2"),
+		ContainerInterfaceConfig: &interfacestore.ContainerInterfaceConfig{PodName: "pod1", PodNamespace: "ns1", ContainerID: "container1"},
+		OVSPortConfig:            &interfacestore.OVSPortConfig{OFPort: 1},
+	})
+	ifaceStore.AddInterface(&interfacestore.InterfaceConfig{
+		InterfaceName:            util.GenerateContainerInterfaceName("pod3", "ns1", "container3"),
+		IP:                       net.ParseIP("3.3.3.3"),
+		ContainerInterfaceConfig: &interfacestore.ContainerInterfaceConfig{PodName: "pod3", PodNamespace: "ns1", ContainerID: "container3"},
+		OVSPortConfig:            &interfacestore.OVSPortConfig{OFPort: 3},
+	})
+	newRule := func(pods v1beta1.GroupMemberPodSet) *CompletedRule {
+		return &CompletedRule{
+			rule: &rule{
+				ID:        "ingress-rule",
+				Direction: v1beta1.DirectionIn,
+				Services:  []v1beta1.Service{serviceHTTP},
+			},
+			Pods: pods,
+		}
+	}
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+	mockOFClient := openflowtest.NewMockClient(controller)
+	r := newReconciler(mockOFClient, ifaceStore, nil)
+
+	mockOFClient.EXPECT().InstallPolicyRuleFlows(gomock.Any(), gomock.Eq(&types.PolicyRule{
+		Direction: v1beta1.DirectionIn,
+		From:      []types.Address{},
+		To:        ofPortsToOFAddresses(sets.NewInt32(1)),
+		Service:   []v1beta1.Service{serviceTCP80},
+	}), "", "")
+	pods := v1beta1.NewGroupMemberPodSet(newAppliedToGroupMember("pod1", "ns1", v1beta1.NamedPort{Name: "http", Protocol: v1beta1.ProtocolTCP, Port: 80}))
+	if err := r.Reconcile(newRule(pods)); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	// A Pod that declares the named port with another port number joins the
+	// AppliedToGroup, a new Openflow rule must be installed for the port.
+	var ofID443 uint32
+	mockOFClient.EXPECT().InstallPolicyRuleFlows(gomock.Any(), gomock.Eq(&types.PolicyRule{
+		Direction: v1beta1.DirectionIn,
+		From:      []types.Address{},
+		To:        ofPortsToOFAddresses(sets.NewInt32(3)),
+		Service:   []v1beta1.Service{serviceTCP443},
+	}), "", "").Do(func(ofID uint32, _ *types.PolicyRule, _, _ string) {
+		ofID443 = ofID
+	})
+	if err := r.Reconcile(newRule(appliedToGroupWithDiffContainerPort)); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	// The Pod leaves the AppliedToGroup, the Openflow rule of the port must be
+	// uninstalled.
+	mockOFClient.EXPECT().UninstallPolicyRuleFlows(gomock.Any()).DoAndReturn(func(ofID uint32) ([]string, error) {
+		if ofID != ofID443 {
+			t.Errorf("UninstallPolicyRuleFlows() got ofID %d, want %d", ofID, ofID443)
+		}
+		return nil, nil
+	})
+	if err := r.Reconcile(newRule(pods)); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
+	clusterinfov1beta1 "github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1"
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
+	"github.com/vmware-tanzu/antrea/pkg/querier"
+)
+
+// StatsCheckpointPath is the file in which StatsCollector persists the
+// statistics, so that they survive agent restarts.
+const StatsCheckpointPath = "/var/run/antrea/networkpolicy-stats.json"
+
+// policyRuleTables are the tables in which the action flows of the
+// NetworkPolicy rules are installed.
+var policyRuleTables = []string{"CNPEgressRule", "EgressRule", "CNPIngressRule", "IngressRule"}
+
+// ruleActionFlowRegexp matches the counters, the conjunction ID and the actions
+// of an action flow of a NetworkPolicy rule, e.g. "table=90, n_packets=3,
+// n_bytes=222, idle_age=5, priority=190,conj_id=2,ip actions=load:0x2->
+// NXM_NX_REG6[],goto_table:105".
+var ruleActionFlowRegexp = regexp.MustCompile(`n_packets=(\d+), n_bytes=(\d+),.*[ ,]conj_id=(\d+)[^ ]* actions=(\S+)`)
+
+type flowStatsKey struct {
+	ofID      uint32
+	policyUID types.UID
+}
+
+type flowCounters struct {
+	packets uint64
+	bytes   uint64
+	drop    bool
+}
+
+// StatsCollector polls the counters of the action flows of the NetworkPolicy
+// rules periodically and accumulates them per NetworkPolicy and direction.
+//
+// Packets of established connections skip the NetworkPolicy rules, so only the
+// first packet of each allowed connection and every denied packet are counted.
+// The first packets counted by the flows of allowing rules are the sessions.
+type StatsCollector struct {
+	controller     *Controller
+	ovsCtlClient   ovsctl.OVSCtlClient
+	pollInterval   time.Duration
+	checkpointPath string
+	// statsMutex protects lastCounters and policyStats.
+	statsMutex sync.RWMutex
+	// lastCounters stores the counters of the action flows in the last poll.
+	lastCounters map[flowStatsKey]*flowCounters
+	// policyStats stores the cumulative statistics keyed by NetworkPolicy UID.
+	policyStats map[types.UID]*clusterinfov1beta1.NetworkPolicyStats
+}
+
+var _ querier.AgentNetworkPolicyStatsQuerier = new(StatsCollector)
+
+// NewStatsCollector returns a StatsCollector which polls the OVS flow counters
+// every pollInterval, and restores the statistics persisted in checkpointPath.
+func NewStatsCollector(controller *Controller, ovsCtlClient ovsctl.OVSCtlClient, pollInterval time.Duration, checkpointPath string) *StatsCollector {
+	c := &StatsCollector{
+		controller:     controller,
+		ovsCtlClient:   ovsCtlClient,
+		pollInterval:   pollInterval,
+		checkpointPath: checkpointPath,
+		lastCounters:   map[flowStatsKey]*flowCounters{},
+		policyStats:    map[types.UID]*clusterinfov1beta1.NetworkPolicyStats{},
+	}
+	if err := c.restore(); err != nil {
+		klog.Warningf("Failed to restore NetworkPolicy statistics from %s: %v", checkpointPath, err)
+	}
+	return c
+}
+
+// parseRuleActionFlow returns the Openflow rule ID and the counters of an
+// action flow of a NetworkPolicy rule. ok is false if the flow is not an action
+// flow.
+func parseRuleActionFlow(flow string) (ofID uint32, counters *flowCounters, ok bool) {
+	matches := ruleActionFlowRegexp.FindStringSubmatch(flow)
+	if matches == nil {
+		return 0, nil, false
+	}
+	packets, err := strconv.ParseUint(matches[1], 10, 64)
+	if err != nil {
+		return 0, nil, false
+	}
+	bytes, err := strconv.ParseUint(matches[2], 10, 64)
+	if err != nil {
+		return 0, nil, false
+	}
+	id, err := strconv.ParseUint(matches[3], 10, 32)
+	if err != nil {
+		return 0, nil, false
+	}
+	return uint32(id), &flowCounters{packets: packets, bytes: bytes, drop: matches[4] == "drop"}, true
+}
+
+// collect dumps the action flows of the NetworkPolicy rules and accumulates
+// their counters since the last poll. A flow whose counters decreased since
+// the last poll has been reinstalled, in which case its new counters are
+// accumulated as a whole.
+func (c *StatsCollector) collect() error {
+	current := map[flowStatsKey]*flowCounters{}
+	rules := map[flowStatsKey]*rule{}
+	for _, table := range policyRuleTables {
+		flows, err := c.ovsCtlClient.DumpTableFlows(uint8(openflow.GetFlowTableNumber(table)))
+		if err != nil {
+			return err
+		}
+		for _, flow := range flows {
+			ofID, counters, ok := parseRuleActionFlow(flow)
+			if !ok {
+				continue
+			}
+			r, exists := c.controller.reconciler.GetRuleByFlowID(ofID)
+			if !exists {
+				continue
+			}
+			key := flowStatsKey{ofID: ofID, policyUID: r.PolicyUID}
+			// A rule can have multiple action flows with different
+			// priorities while its priority is being updated.
+			if sum, exists := current[key]; exists {
+				sum.packets += counters.packets
+				sum.bytes += counters.bytes
+				continue
+			}
+			current[key] = counters
+			rules[key] = r
+		}
+	}
+
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+	updated := false
+	for key, counters := range current {
+		packets, bytes := counters.packets, counters.bytes
+		if last, exists := c.lastCounters[key]; exists && counters.packets >= last.packets && counters.bytes >= last.bytes {
+			packets -= last.packets
+			bytes -= last.bytes
+		}
+		if packets == 0 && bytes == 0 {
+			continue
+		}
+		r := rules[key]
+		stats, exists := c.policyStats[r.PolicyUID]
+		if !exists {
+			stats = &clusterinfov1beta1.NetworkPolicyStats{
+				PolicyUID:       r.PolicyUID,
+				PolicyName:      r.PolicyName,
+				PolicyNamespace: r.PolicyNamespace,
+			}
+			c.policyStats[r.PolicyUID] = stats
+		}
+		traffic := &stats.Egress
+		if r.Direction == v1beta1.DirectionIn {
+			traffic = &stats.Ingress
+		}
+		traffic.PacketCount += int64(packets)
+		traffic.ByteCount += int64(bytes)
+		if !counters.drop {
+			traffic.SessionCount += int64(packets)
+		}
+		stats.RuleHitCount += int64(packets)
+		updated = true
+	}
+	c.lastCounters = current
+
+	// The statistics restored from the checkpoint must not be removed before
+	// the NetworkPolicies have been received from antrea-controller.
+	if c.controller.networkPolicyWatcher.isSynced() {
+		for uid := range c.policyStats {
+			if !c.controller.ruleCache.hasNetworkPolicy(string(uid)) {
+				delete(c.policyStats, uid)
+				updated = true
+			}
+		}
+	}
+	if updated {
+		if err := c.persist(); err != nil {
+			klog.Errorf("Failed to persist NetworkPolicy statistics to %s: %v", c.checkpointPath, err)
+		}
+	}
+	return nil
+}
+
+// restore loads the statistics persisted in the checkpoint file. The flows are
+// reinstalled with zero counters after the agent restarts, so the counters of
+// the last poll needn't be restored.
+func (c *StatsCollector) restore() error {
+	data, err := ioutil.ReadFile(c.checkpointPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var statsList []clusterinfov1beta1.NetworkPolicyStats
+	if err := json.Unmarshal(data, &statsList); err != nil {
+		return err
+	}
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+	for i := range statsList {
+		c.policyStats[statsList[i].PolicyUID] = &statsList[i]
+	}
+	klog.Infof("Restored statistics of %d NetworkPolicies from %s", len(statsList), c.checkpointPath)
+	return nil
+}
+
+// persist writes the statistics to the checkpoint file. The caller must hold
+// statsMutex.
+func (c *StatsCollector) persist() error {
+	statsList := make([]clusterinfov1beta1.NetworkPolicyStats, 0, len(c.policyStats))
+	for _, stats := range c.policyStats {
+		statsList = append(statsList, *stats)
+	}
+	data, err := json.Marshal(statsList)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.checkpointPath), 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that an agent crash cannot leave
+	// a truncated checkpoint behind.
+	tmpPath := c.checkpointPath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, c.checkpointPath)
+}
+
+func trafficBytes(stats *clusterinfov1beta1.NetworkPolicyStats) int64 {
+	return stats.Ingress.ByteCount + stats.Egress.ByteCount
+}
+
+// GetNetworkPolicyStats returns the statistics of all the NetworkPolicies which
+// have matched traffic, sorted by the number of bytes in descending order.
+func (c *StatsCollector) GetNetworkPolicyStats() []clusterinfov1beta1.NetworkPolicyStats {
+	c.statsMutex.RLock()
+	defer c.statsMutex.RUnlock()
+
+	statsList := make([]clusterinfov1beta1.NetworkPolicyStats, 0, len(c.policyStats))
+	for _, stats := range c.policyStats {
+		statsList = append(statsList, *stats)
+	}
+	sortNetworkPolicyStats(statsList)
+	return statsList
+}
+
+// sortNetworkPolicyStats sorts the statistics by the number of bytes in
+// descending order, then by the Namespace and the name of the NetworkPolicies.
+func sortNetworkPolicyStats(statsList []clusterinfov1beta1.NetworkPolicyStats) {
+	sort.Slice(statsList, func(i, j int) bool {
+		if bi, bj := trafficBytes(&statsList[i]), trafficBytes(&statsList[j]); bi != bj {
+			return bi > bj
+		}
+		if statsList[i].PolicyNamespace != statsList[j].PolicyNamespace {
+			return statsList[i].PolicyNamespace < statsList[j].PolicyNamespace
+		}
+		return statsList[i].PolicyName < statsList[j].PolicyName
+	})
+}
+
+// Run polls the counters of the action flows until stopCh is closed.
+func (c *StatsCollector) Run(stopCh <-chan struct{}) {
+	klog.Infof("Starting NetworkPolicy statistics collector with poll interval %v", c.pollInterval)
+	wait.Until(func() {
+		if err := c.collect(); err != nil {
+			klog.Errorf("Error when collecting NetworkPolicy statistics: %v", err)
+		}
+	}, c.pollInterval, stopCh)
+}
@@ -0,0 +1,223 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clusterinfov1beta1 "github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1"
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
+	ovsctltest "github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl/testing"
+)
+
+const (
+	cnpEgressRuleTableID  uint8 = 45
+	egressRuleTableID     uint8 = 50
+	cnpIngressRuleTableID uint8 = 85
+	ingressRuleTableID    uint8 = 90
+)
+
+func ruleActionFlow(table uint8, conjID uint32, packets, bytes uint64, actions string) string {
+	return fmt.Sprintf("table=%d, n_packets=%d, n_bytes=%d, idle_age=5, priority=190,conj_id=%d,ip actions=%s", table, packets, bytes, conjID, actions)
+}
+
+// tableFlows maps table IDs to the flows dumped from them.
+type tableFlows map[uint8][]string
+
+func expectDumpFlows(mockOVSCtlClient *ovsctltest.MockOVSCtlClient, flows tableFlows) {
+	for _, table := range []uint8{cnpEgressRuleTableID, egressRuleTableID, cnpIngressRuleTableID, ingressRuleTableID} {
+		mockOVSCtlClient.EXPECT().DumpTableFlows(table).Return(flows[table], nil)
+	}
+}
+
+func newTestStatsCollector(t *testing.T, ctrl *gomock.Controller, checkpointPath string) (*StatsCollector, *mockReconciler, *ovsctltest.MockOVSCtlClient) {
+	controller, _, reconciler := newTestController()
+	for _, uid := range []string{"uid1", "uid2"} {
+		require.NoError(t, controller.ruleCache.AddNetworkPolicy(newNetworkPolicy(uid, []string{"addressGroup1"}, nil, []string{"appliedToGroup1"}, nil)))
+	}
+	reconciler.ofIDRules[1] = &rule{ID: "rule1", Direction: v1beta1.DirectionIn, PolicyUID: "uid1", PolicyName: "uid1", PolicyNamespace: testNamespace}
+	reconciler.ofIDRules[2] = &rule{ID: "rule2", Direction: v1beta1.DirectionOut, PolicyUID: "uid1", PolicyName: "uid1", PolicyNamespace: testNamespace}
+	reconciler.ofIDRules[3] = &rule{ID: "rule3", Direction: v1beta1.DirectionIn, PolicyUID: "uid2", PolicyName: "uid2", PolicyNamespace: testNamespace}
+	mockOVSCtlClient := ovsctltest.NewMockOVSCtlClient(ctrl)
+	return NewStatsCollector(controller, mockOVSCtlClient, 0, checkpointPath), reconciler, mockOVSCtlClient
+}
+
+func TestParseRuleActionFlow(t *testing.T) {
+	tests := []struct {
+		name             string
+		flow             string
+		expectedOFID     uint32
+		expectedCounters *flowCounters
+		expectedOK       bool
+	}{
+		{
+			name:             "allow action flow",
+			flow:             ruleActionFlow(ingressRuleTableID, 2, 3, 222, "load:0x2->NXM_NX_REG6[],goto_table:105"),
+			expectedOFID:     2,
+			expectedCounters: &flowCounters{packets: 3, bytes: 222},
+			expectedOK:       true,
+		},
+		{
+			name:             "drop action flow",
+			flow:             "table=85, n_packets=10, n_bytes=740, priority=64990,conj_id=7,ip actions=drop",
+			expectedOFID:     7,
+			expectedCounters: &flowCounters{packets: 10, bytes: 740, drop: true},
+			expectedOK:       true,
+		},
+		{
+			name:       "conjunction match flow",
+			flow:       "table=90, n_packets=3, n_bytes=222, priority=190,ip,nw_src=10.10.1.2 actions=conjunction(2,1/2)",
+			expectedOK: false,
+		},
+		{
+			name:       "established connection flow",
+			flow:       "table=90, n_packets=100, n_bytes=7400, priority=210,ct_state=-new+est,ip actions=goto_table:105",
+			expectedOK: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ofID, counters, ok := parseRuleActionFlow(tt.flow)
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedOFID, ofID)
+			assert.Equal(t, tt.expectedCounters, counters)
+		})
+	}
+}
+
+func TestStatsCollectorCollect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dir, err := ioutil.TempDir("", "networkpolicy-stats")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c, _, mockOVSCtlClient := newTestStatsCollector(t, ctrl, filepath.Join(dir, "stats.json"))
+
+	expectDumpFlows(mockOVSCtlClient, tableFlows{
+		ingressRuleTableID: {
+			ruleActionFlow(ingressRuleTableID, 1, 2, 148, "goto_table:105"),
+			ruleActionFlow(ingressRuleTableID, 3, 1, 74, "goto_table:105"),
+			// Flows of unknown rules are ignored.
+			ruleActionFlow(ingressRuleTableID, 4, 1000, 74000, "goto_table:105"),
+		},
+		cnpEgressRuleTableID: {ruleActionFlow(cnpEgressRuleTableID, 2, 5, 370, "drop")},
+	})
+	require.NoError(t, c.collect())
+	assert.Equal(t, []clusterinfov1beta1.NetworkPolicyStats{
+		{
+			PolicyUID:       "uid1",
+			PolicyName:      "uid1",
+			PolicyNamespace: testNamespace,
+			Ingress:         clusterinfov1beta1.TrafficStats{PacketCount: 2, ByteCount: 148, SessionCount: 2},
+			Egress:          clusterinfov1beta1.TrafficStats{PacketCount: 5, ByteCount: 370},
+			RuleHitCount:    7,
+		},
+		{
+			PolicyUID:       "uid2",
+			PolicyName:      "uid2",
+			PolicyNamespace: testNamespace,
+			Ingress:         clusterinfov1beta1.TrafficStats{PacketCount: 1, ByteCount: 74, SessionCount: 1},
+			RuleHitCount:    1,
+		},
+	}, c.GetNetworkPolicyStats())
+
+	// The flow of rule1 has been reinstalled, so its counters are
+	// accumulated as a whole. The flow of rule2 is installed with two
+	// priorities.
+	expectDumpFlows(mockOVSCtlClient, tableFlows{
+		ingressRuleTableID: {
+			ruleActionFlow(ingressRuleTableID, 1, 1, 74, "goto_table:105"),
+			ruleActionFlow(ingressRuleTableID, 3, 101, 74074, "goto_table:105"),
+		},
+		cnpEgressRuleTableID: {
+			ruleActionFlow(cnpEgressRuleTableID, 2, 5, 370, "drop"),
+			ruleActionFlow(cnpEgressRuleTableID, 2, 1, 74, "drop"),
+		},
+	})
+	require.NoError(t, c.collect())
+	assert.Equal(t, []clusterinfov1beta1.NetworkPolicyStats{
+		{
+			PolicyUID:       "uid2",
+			PolicyName:      "uid2",
+			PolicyNamespace: testNamespace,
+			Ingress:         clusterinfov1beta1.TrafficStats{PacketCount: 101, ByteCount: 74074, SessionCount: 101},
+			RuleHitCount:    101,
+		},
+		{
+			PolicyUID:       "uid1",
+			PolicyName:      "uid1",
+			PolicyNamespace: testNamespace,
+			Ingress:         clusterinfov1beta1.TrafficStats{PacketCount: 3, ByteCount: 222, SessionCount: 3},
+			Egress:          clusterinfov1beta1.TrafficStats{PacketCount: 6, ByteCount: 444},
+			RuleHitCount:    9,
+		},
+	}, c.GetNetworkPolicyStats())
+}
+
+func TestStatsCollectorCheckpoint(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dir, err := ioutil.TempDir("", "networkpolicy-stats")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	checkpointPath := filepath.Join(dir, "stats.json")
+
+	c, _, mockOVSCtlClient := newTestStatsCollector(t, ctrl, checkpointPath)
+	expectDumpFlows(mockOVSCtlClient, tableFlows{
+		ingressRuleTableID: {
+			ruleActionFlow(ingressRuleTableID, 1, 2, 148, "goto_table:105"),
+			ruleActionFlow(ingressRuleTableID, 3, 1, 74, "goto_table:105"),
+		},
+	})
+	require.NoError(t, c.collect())
+	expectedStats := c.GetNetworkPolicyStats()
+
+	// The restarted agent restores the statistics and accumulates the
+	// counters of the reinstalled flows on top of them.
+	c, _, mockOVSCtlClient = newTestStatsCollector(t, ctrl, checkpointPath)
+	assert.Equal(t, expectedStats, c.GetNetworkPolicyStats())
+	expectDumpFlows(mockOVSCtlClient, tableFlows{
+		ingressRuleTableID: {ruleActionFlow(ingressRuleTableID, 1, 1, 74, "goto_table:105")},
+	})
+	require.NoError(t, c.collect())
+	stats := c.GetNetworkPolicyStats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, clusterinfov1beta1.TrafficStats{PacketCount: 3, ByteCount: 222, SessionCount: 3}, stats[0].Ingress)
+
+	// The statistics of the NetworkPolicies which are no longer applied to
+	// this Node are removed once the NetworkPolicies are in sync.
+	require.NoError(t, c.controller.ruleCache.DeleteNetworkPolicy(newNetworkPolicy("uid2", []string{"addressGroup1"}, nil, []string{"appliedToGroup1"}, nil)))
+	expectDumpFlows(mockOVSCtlClient, tableFlows{})
+	require.NoError(t, c.collect())
+	assert.Len(t, c.GetNetworkPolicyStats(), 2)
+
+	c.controller.networkPolicyWatcher.setSynced()
+	expectDumpFlows(mockOVSCtlClient, tableFlows{})
+	require.NoError(t, c.collect())
+	stats = c.GetNetworkPolicyStats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, "uid1", stats[0].PolicyName)
+
+	c, _, _ = newTestStatsCollector(t, ctrl, checkpointPath)
+	assert.Equal(t, stats, c.GetNetworkPolicyStats())
+}
@@ -37,6 +37,7 @@ import (
 	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
 	"github.com/vmware-tanzu/antrea/pkg/agent/route"
 	"github.com/vmware-tanzu/antrea/pkg/agent/util"
+	"github.com/vmware-tanzu/antrea/pkg/agent/wireguard"
 	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsconfig"
 )
 
@@ -70,6 +71,12 @@ type Controller struct {
 	// The key is the host name of the Node, the value is the podCIDR of the Node.
 	// A node will be in the map after its flows and routes are installed successfully.
 	installedNodes *sync.Map
+	// wireGuardClient configures the peer Nodes on the WireGuard device. It's nil
+	// unless the traffic encryption mode is WireGuard.
+	wireGuardClient wireguard.Interface
+	// ipsecPSKMutex protects the IPSec PSK in networkConfig, which is updated
+	// when the PSK is rotated, and the PSK of the IPSec tunnel ports.
+	ipsecPSKMutex sync.RWMutex
 }
 
 // NewNodeRouteController instantiates a new Controller object which will process Node events
@@ -82,7 +89,8 @@ func NewNodeRouteController(
 	routeClient route.Interface,
 	interfaceStore interfacestore.InterfaceStore,
 	networkConfig *config.NetworkConfig,
-	nodeConfig *config.NodeConfig) *Controller {
+	nodeConfig *config.NodeConfig,
+	wireGuardClient wireguard.Interface) *Controller {
 	nodeInformer := informerFactory.Core().V1().Nodes()
 	controller := &Controller{
 		kubeClient:       kubeClient,
@@ -96,7 +104,8 @@ func NewNodeRouteController(
 		nodeLister:       nodeInformer.Lister(),
 		nodeListerSynced: nodeInformer.Informer().HasSynced,
 		queue:            workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "noderoute"),
-		installedNodes:   &sync.Map{}}
+		installedNodes:   &sync.Map{},
+		wireGuardClient:  wireGuardClient}
 	nodeInformer.Informer().AddEventHandlerWithResyncPeriod(
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(cur interface{}) {
@@ -183,6 +192,8 @@ func (c *Controller) removeStaleTunnelPorts() error {
 	knownInterfaces := c.interfaceStore.GetInterfaceKeysByType(interfacestore.TunnelInterface)
 
 	if c.networkConfig.EnableIPSecTunnel {
+		c.ipsecPSKMutex.RLock()
+		defer c.ipsecPSKMutex.RUnlock()
 		for _, node := range nodes {
 			interfaceConfig, found := c.interfaceStore.GetNodeTunnelInterface(node.Name)
 			if !found {
@@ -249,6 +260,30 @@ func (c *Controller) reconcile() error {
 
 // Run will create defaultWorkers workers (go routines) which will process the Node events from the
 // workqueue.
+// HasSynced returns true once the routes and flows to all the other Nodes
+// with a PodCIDR have been installed.
+func (c *Controller) HasSynced() bool {
+	if c.networkConfig.TrafficEncapMode.IsNetworkPolicyOnly() {
+		return true
+	}
+	if !c.nodeListerSynced() {
+		return false
+	}
+	nodes, err := c.nodeLister.List(labels.Everything())
+	if err != nil {
+		return false
+	}
+	for _, node := range nodes {
+		if node.Name == c.nodeConfig.Name || node.Spec.PodCIDR == "" {
+			continue
+		}
+		if _, installed := c.installedNodes.Load(node.Name); !installed {
+			return false
+		}
+	}
+	return true
+}
+
 func (c *Controller) Run(stopCh <-chan struct{}) {
 	defer c.queue.ShutDown()
 
@@ -347,12 +382,55 @@ func (c *Controller) syncNodeRoute(nodeName string) error {
 	if err != nil {
 		return c.deleteNodeRoute(nodeName)
 	}
+	if c.wireGuardClient != nil {
+		// The peer must be synced even if the routes are already installed, as
+		// the public key of the Node is rotated periodically.
+		if err := c.syncWireGuardPeer(nodeName, node); err != nil {
+			return err
+		}
+	}
 	return c.addNodeRoute(nodeName, node)
 }
 
+// syncWireGuardPeer adds or updates the Node as a peer of the WireGuard device,
+// with the public key published in the Node's annotations. While the key pair
+// of the Node is rotated, the Node is enqueued again at the time it switches to
+// its next public key, so that the peer is updated at the same time.
+func (c *Controller) syncWireGuardPeer(nodeName string, node *v1.Node) error {
+	publicKey, switchAfter, ok := wireguard.PeerPublicKey(node.Annotations, time.Now())
+	if !ok {
+		// The Node will be enqueued again once its agent publishes the public key.
+		klog.V(2).Infof("WireGuard public key of Node %s is not published yet", nodeName)
+		return nil
+	}
+	if switchAfter > 0 {
+		c.queue.AddAfter(nodeName, switchAfter)
+	}
+	_, peerPodCIDR, err := net.ParseCIDR(node.Spec.PodCIDR)
+	if err != nil {
+		klog.Errorf("Failed to parse PodCIDR %s for Node %s", node.Spec.PodCIDR, nodeName)
+		return nil
+	}
+	peerNodeIP, err := GetNodeAddr(node)
+	if err != nil {
+		klog.Errorf("Failed to retrieve IP address of Node %s: %v", nodeName, err)
+		return nil
+	}
+	if err := c.wireGuardClient.UpdatePeer(nodeName, publicKey, peerNodeIP, peerPodCIDR); err != nil {
+		return fmt.Errorf("failed to update WireGuard peer for Node %s: %v", nodeName, err)
+	}
+	return nil
+}
+
 func (c *Controller) deleteNodeRoute(nodeName string) error {
 	klog.Infof("Deleting routes and flows to Node %s", nodeName)
 
+	if c.wireGuardClient != nil {
+		if err := c.wireGuardClient.DeletePeer(nodeName); err != nil {
+			return fmt.Errorf("failed to delete WireGuard peer for Node %s: %v", nodeName, err)
+		}
+	}
+
 	podCIDR, installed := c.installedNodes.Load(nodeName)
 	if !installed {
 		// Route is not added for this Node.
@@ -442,6 +520,9 @@ func (c *Controller) addNodeRoute(nodeName string, node *v1.Node) error {
 // createIPSecTunnelPort creates an IPSec tunnel port for the remote Node if the
 // tunnel does not exist, and returns the ofport number.
 func (c *Controller) createIPSecTunnelPort(nodeName string, nodeIP net.IP) (int32, error) {
+	c.ipsecPSKMutex.RLock()
+	defer c.ipsecPSKMutex.RUnlock()
+
 	interfaceConfig, ok := c.interfaceStore.GetNodeTunnelInterface(nodeName)
 	if ok {
 		// TODO: check if Node IP, PSK, or tunnel type changes. This can
@@ -488,6 +569,32 @@ func (c *Controller) createIPSecTunnelPort(nodeName string, nodeIP net.IP) (int3
 	return ofPort, nil
 }
 
+// UpdateIPSecPSK updates the PSK of the IPSec tunnel ports in place. The PSK is
+// only used by IKE to authenticate new SAs, so the SAs established with the
+// previous PSK and the traffic going through them are not affected.
+func (c *Controller) UpdateIPSecPSK(psk string) error {
+	c.ipsecPSKMutex.Lock()
+	defer c.ipsecPSKMutex.Unlock()
+
+	c.networkConfig.IPSecPSK = psk
+	for _, interfaceConfig := range c.interfaceStore.GetInterfacesByType(interfacestore.TunnelInterface) {
+		// The default tunnel port is not used for IPSec.
+		if interfaceConfig.PSK == "" || interfaceConfig.PSK == psk {
+			continue
+		}
+		options := map[string]interface{}{
+			"remote_ip": interfaceConfig.RemoteIP.String(),
+			"psk":       psk,
+		}
+		if err := c.ovsBridgeClient.SetInterfaceOptions(interfaceConfig.InterfaceName, options); err != nil {
+			return fmt.Errorf("failed to update PSK of IPSec tunnel port %s: %v", interfaceConfig.InterfaceName, err)
+		}
+		interfaceConfig.PSK = psk
+	}
+	klog.Info("Updated PSK of IPSec tunnel ports")
+	return nil
+}
+
 // ParseTunnelInterfaceConfig initializes and returns an InterfaceConfig struct
 // for a tunnel interface. It reads tunnel type, remote IP, IPSec PSK from the
 // OVS interface options, and NodeName from the OVS port external_ids.
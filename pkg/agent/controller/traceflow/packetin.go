@@ -41,6 +41,9 @@ func (c *Controller) HandlePacketIn(pktIn *ofctrl.PacketIn) error {
 		klog.Errorf("parsePacketIn error: %+v", err)
 		return err
 	}
+	if oldTf.Spec.Live && c.isSender(oldTf.Status.DataplaneTag) {
+		c.countCapturedPacket(oldTf)
+	}
 	// Retry when update CRD conflict which caused by multiple agents updating one CRD at same time.
 	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		tf, err := c.traceflowInformer.Lister().Get(oldTf.Name)
@@ -92,6 +95,17 @@ func (c *Controller) parsePacketIn(pktIn *ofctrl.PacketIn) (*opsv1alpha1.Tracefl
 		ob.Component = opsv1alpha1.SpoofGuard
 		ob.Action = opsv1alpha1.Forwarded
 		obs = append(obs, *ob)
+		// The Service Endpoint selected by AntreaProxy is stored in the registers until the packet leaves
+		// the Sender Node, which DNATs the packet to the Endpoint.
+		if endpointIP, endpointPort, ok := openflow.GetServiceEndpointFromPacketIn(pktIn); ok {
+			ob := new(opsv1alpha1.Observation)
+			ob.Component = opsv1alpha1.LB
+			ob.ComponentInfo = openflow.GetFlowTableName(openflow.EndpointDNATTable)
+			ob.Action = opsv1alpha1.Forwarded
+			ob.TranslatedDstIP = endpointIP.String()
+			klog.V(2).Infof("Traceflow %s selected Service Endpoint %s:%d", tf.Name, endpointIP, endpointPort)
+			obs = append(obs, *ob)
+		}
 	} else {
 		ob := new(opsv1alpha1.Observation)
 		ob.Component = opsv1alpha1.Forwarding
@@ -135,6 +149,14 @@ func (c *Controller) parsePacketIn(pktIn *ofctrl.PacketIn) (*opsv1alpha1.Tracefl
 	}
 
 	// Get drop table.
+	if binding.TableIDType(tableID) == openflow.ServiceLBTable {
+		ob := new(opsv1alpha1.Observation)
+		ob.Action = opsv1alpha1.Dropped
+		ob.Component = opsv1alpha1.LB
+		ob.ComponentInfo = openflow.GetFlowTableName(openflow.ServiceLBTable)
+		ob.Reason = opsv1alpha1.ReasonNoEndpoints
+		obs = append(obs, *ob)
+	}
 	if tableID == 60 || tableID == 100 {
 		ob := new(opsv1alpha1.Observation)
 		ob.Action = opsv1alpha1.Dropped
@@ -19,9 +19,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"sync"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -33,10 +35,12 @@ import (
 	"github.com/vmware-tanzu/antrea/pkg/agent/config"
 	"github.com/vmware-tanzu/antrea/pkg/agent/interfacestore"
 	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/agent/proxy"
 	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
 	clientsetversioned "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
 	opsinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions/ops/v1alpha1"
 	opslisters "github.com/vmware-tanzu/antrea/pkg/client/listers/ops/v1alpha1"
+	binding "github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
 	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsconfig"
 )
 
@@ -52,6 +56,13 @@ const (
 	// Seconds delay before injecting packet into OVS. The time of different nodes may not be completely
 	// synchronized, which requires a delay before inject packet.
 	injectPacketDelay = 5
+
+	protocolICMP = 1
+	protocolTCP  = 6
+	protocolUDP  = 17
+	// tcpFlagSYN is set in the synthetic TCP packets sent to a Service by default, so that they are tracked as
+	// the first packet of a connection and can be DNATed to the selected Endpoint.
+	tcpFlagSYN = 0x2
 )
 
 // Controller is responsible for setting up Openflow entries and injecting traceflow packet into
@@ -67,11 +78,13 @@ type Controller struct {
 	interfaceStore         interfacestore.InterfaceStore
 	networkConfig          *config.NetworkConfig
 	nodeConfig             *config.NodeConfig
+	serviceQuerier         proxy.ServiceQuerier // nil if AntreaProxy is disabled.
 	queue                  workqueue.RateLimitingInterface
 	runningTraceflowsMutex sync.RWMutex
 	runningTraceflows      map[uint8]string // tag->traceflowName if tf.Status.Phase is Running.
 	injectedTagsMutex      sync.RWMutex
 	injectedTags           map[uint8]string // tag->traceflowName if this Node is sender.
+	capturedPackets        map[uint8]int32  // tag->number of captured packets if this Node is sender of a live traceflow.
 }
 
 // NewTraceflowController instantiates a new Controller object which will process Traceflow
@@ -84,7 +97,8 @@ func NewTraceflowController(
 	ovsBridgeClient ovsconfig.OVSBridgeClient,
 	interfaceStore interfacestore.InterfaceStore,
 	networkConfig *config.NetworkConfig,
-	nodeConfig *config.NodeConfig) *Controller {
+	nodeConfig *config.NodeConfig,
+	serviceQuerier proxy.ServiceQuerier) *Controller {
 	c := &Controller{
 		kubeClient:            kubeClient,
 		traceflowClient:       traceflowClient,
@@ -96,9 +110,11 @@ func NewTraceflowController(
 		interfaceStore:        interfaceStore,
 		networkConfig:         networkConfig,
 		nodeConfig:            nodeConfig,
+		serviceQuerier:        serviceQuerier,
 		queue:                 workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "traceflow"),
 		runningTraceflows:     make(map[uint8]string),
-		injectedTags:          make(map[uint8]string)}
+		injectedTags:          make(map[uint8]string),
+		capturedPackets:       make(map[uint8]int32)}
 
 	// Add handlers for ClusterNetworkPolicy events.
 	traceflowInformer.Informer().AddEventHandlerWithResyncPeriod(
@@ -110,7 +126,7 @@ func NewTraceflowController(
 		resyncPeriod,
 	)
 	// Register packetInHandler
-	c.ofClient.RegisterPacketInHandler("traceflow", c)
+	c.ofClient.RegisterPacketInHandler(uint8(openflow.PacketInReasonTF), "traceflow", c)
 	return c
 }
 
@@ -235,11 +251,16 @@ func (c *Controller) syncTraceflow(traceflowName string) error {
 }
 
 // startTraceflow deploys OVS flow entries for Traceflow and inject packet if current Node
-// is Sender Node.
+// is Sender Node. For live traceflow, the Sender Node captures the live packets instead.
 func (c *Controller) startTraceflow(tf *opsv1alpha1.Traceflow) error {
 	// Deploy flow entries for traceflow
 	klog.V(2).Infof("Deploy flow entries for Traceflow %s", tf.Name)
-	err := c.ofClient.InstallTraceflowFlows(tf.Status.DataplaneTag)
+	var err error
+	if tf.Spec.Live {
+		err = c.ofClient.InstallTraceflowLiveFlows(tf.Status.DataplaneTag, liveTimeout(tf))
+	} else {
+		err = c.ofClient.InstallTraceflowFlows(tf.Status.DataplaneTag)
+	}
 	defer func() {
 		if err != nil {
 			c.errorTraceflowCRD(tf, fmt.Sprintf("Node: %s, error: %+v", tf.Name, err))
@@ -257,22 +278,90 @@ func (c *Controller) startTraceflow(tf *opsv1alpha1.Traceflow) error {
 	if len(podInterfaces) == 0 {
 		return nil
 	}
-	err = c.injectPacket(tf)
+	var svcDst *serviceDestination
+	if tf.Spec.Destination.Service != "" {
+		if svcDst, err = c.getServiceDestination(tf); err != nil {
+			return err
+		}
+		if !svcDst.hasEndpoints {
+			// The packets to a Service without Endpoints are dropped by the ServiceLB table, where no flow
+			// sends them to Antrea Agent.
+			timeout := openflow.TraceflowTimeout
+			if tf.Spec.Live {
+				timeout = liveTimeout(tf)
+			}
+			if err = c.ofClient.InstallTraceflowServiceNoEndpointsFlow(tf.Status.DataplaneTag, svcDst.ip, svcDst.port, svcDst.protocol, timeout); err != nil {
+				return err
+			}
+		}
+	}
+	if tf.Spec.Live {
+		err = c.captureLiveTraffic(tf, svcDst)
+	} else {
+		err = c.injectPacket(tf, svcDst)
+	}
 	return err
 }
 
-func (c *Controller) injectPacket(tf *opsv1alpha1.Traceflow) error {
-	podInterfaces := c.interfaceStore.GetContainerInterfacesByPod(tf.Spec.Source.Pod, tf.Spec.Source.Namespace)
-	// Update Traceflow phase to Running.
-	klog.V(2).Infof("Injecting packet for Traceflow %s", tf.Name)
-	c.injectedTagsMutex.Lock()
-	c.injectedTags[tf.Status.DataplaneTag] = tf.Name
-	c.injectedTagsMutex.Unlock()
+// serviceDestination is the Service port which the traced packets are sent to.
+type serviceDestination struct {
+	ip           net.IP
+	port         uint16
+	ipProtocol   uint8
+	protocol     binding.Protocol
+	hasEndpoints bool
+}
+
+// getServiceDestination resolves the ClusterIP and port of the destination Service of the traceflow, and whether
+// the Service port has Endpoints, from AntreaProxy. The destination port in the transport header can be omitted if
+// the Service has a single port for the protocol. The protocol defaults to TCP.
+func (c *Controller) getServiceDestination(tf *opsv1alpha1.Traceflow) (*serviceDestination, error) {
+	if c.serviceQuerier == nil {
+		return nil, errors.New("tracing Services requires AntreaProxy")
+	}
+	var protocol corev1.Protocol
+	var port int32
+	ipProtocol := uint8(tf.Spec.Packet.IPHeader.Protocol)
+	switch ipProtocol {
+	case 0, protocolTCP:
+		ipProtocol, protocol = protocolTCP, corev1.ProtocolTCP
+		if tf.Spec.Packet.TransportHeader.TCP != nil {
+			port = tf.Spec.Packet.TransportHeader.TCP.DstPort
+		}
+	case protocolUDP:
+		protocol = corev1.ProtocolUDP
+		if tf.Spec.Packet.TransportHeader.UDP != nil {
+			port = tf.Spec.Packet.TransportHeader.UDP.DstPort
+		}
+	default:
+		return nil, fmt.Errorf("IP protocol %d is not supported for destination Service, only TCP and UDP are supported", ipProtocol)
+	}
+	svcInfo, endpoints, err := c.serviceQuerier.GetServiceEndpoints(tf.Spec.Destination.Namespace, tf.Spec.Destination.Service, protocol, port)
+	if err != nil {
+		return nil, err
+	}
+	// The source Pod IP is always IPv4, as the Pod network doesn't support IPv6 yet.
+	if svcInfo.ClusterIP().To4() == nil {
+		return nil, fmt.Errorf("ClusterIP %s is not in the IP family of the source Pod", svcInfo.ClusterIP())
+	}
+	return &serviceDestination{
+		ip:           svcInfo.ClusterIP(),
+		port:         uint16(svcInfo.Port()),
+		ipProtocol:   ipProtocol,
+		protocol:     svcInfo.OFProtocol,
+		hasEndpoints: len(endpoints) > 0,
+	}, nil
+}
 
-	// Calculate destination MAC/IP.
-	dstMAC := ""
-	dstIP := tf.Spec.Destination.IP
-	isInterNode := true
+// getDestination calculates the destination MAC and IP of the traceflow. The destination MAC is empty if the
+// destination is not a Pod on the current Node. The destination IP is the ClusterIP if the destination is a Service,
+// in which case the traceflow is considered inter-node as the selected Endpoint may run on another Node.
+func (c *Controller) getDestination(tf *opsv1alpha1.Traceflow, svcDst *serviceDestination) (dstMAC string, dstIP string, isInterNode bool, err error) {
+	dstIP = tf.Spec.Destination.IP
+	isInterNode = true
+	if svcDst != nil {
+		dstIP = svcDst.ip.String()
+	}
 	// TODO: Find MAC by dstIP
 	if dstIP == "" {
 		dstPodInterfaces := c.interfaceStore.GetContainerInterfacesByPod(tf.Spec.Destination.Pod, tf.Spec.Destination.Namespace)
@@ -283,26 +372,49 @@ func (c *Controller) injectPacket(tf *opsv1alpha1.Traceflow) error {
 		} else {
 			dstPod, err := c.kubeClient.CoreV1().Pods(tf.Spec.Destination.Namespace).Get(context.TODO(), tf.Spec.Destination.Pod, v1.GetOptions{})
 			if err != nil {
-				return err
+				return "", "", false, err
 			}
-			// dstMAC is "" here, will be set to Gateway MAC in ofClient.SendTraceflowPacket
 			dstIP = dstPod.Status.PodIP
 		}
 	}
+	// The source Pod IP is always IPv4, as the Pod network doesn't support IPv6 yet.
+	if parsedDstIP := net.ParseIP(dstIP); parsedDstIP != nil && parsedDstIP.To4() == nil {
+		return "", "", false, fmt.Errorf("destination IP %s is not in the IP family of the source Pod", dstIP)
+	}
 	if isInterNode {
 		if c.networkConfig.TunnelType != ovsconfig.GeneveTunnel {
 			// Inter-node traceflow is only available in Geneve tunnel.
-			return errors.New(fmt.Sprintf("inter-node traceflow is only available in Geneve tunnel, current mode: %s", c.networkConfig.TunnelType))
-		} else {
-			// Wait a small period for other Nodes.
-			time.Sleep(time.Duration(injectPacketDelay) * time.Second)
+			return "", "", false, errors.New(fmt.Sprintf("inter-node traceflow is only available in Geneve tunnel, current mode: %s", c.networkConfig.TunnelType))
 		}
 	}
+	return dstMAC, dstIP, isInterNode, nil
+}
+
+func (c *Controller) injectPacket(tf *opsv1alpha1.Traceflow, svcDst *serviceDestination) error {
+	podInterfaces := c.interfaceStore.GetContainerInterfacesByPod(tf.Spec.Source.Pod, tf.Spec.Source.Namespace)
+	// Update Traceflow phase to Running.
+	klog.V(2).Infof("Injecting packet for Traceflow %s", tf.Name)
+	c.injectedTagsMutex.Lock()
+	c.injectedTags[tf.Status.DataplaneTag] = tf.Name
+	c.injectedTagsMutex.Unlock()
+
+	// Calculate destination MAC/IP. dstMAC is "" for inter-node traceflow, will be set to Gateway MAC in
+	// ofClient.SendTraceflowPacket.
+	dstMAC, dstIP, isInterNode, err := c.getDestination(tf, svcDst)
+	if err != nil {
+		return err
+	}
+	if isInterNode {
+		// Wait a small period for other Nodes.
+		time.Sleep(time.Duration(injectPacketDelay) * time.Second)
+	}
 
 	// Protocol is 0 (IPv6 Hop-by-Hop Option) if not set in CRD, which is not supported by Traceflow
-	// Use Protocol=1 (ICMP) as default.
-	if tf.Spec.Packet.IPHeader.Protocol == 0 {
-		tf.Spec.Packet.IPHeader.Protocol = 1
+	// Use Protocol=1 (ICMP) as default, or the protocol of the Service port for destination Service.
+	if svcDst != nil {
+		tf.Spec.Packet.IPHeader.Protocol = int32(svcDst.ipProtocol)
+	} else if tf.Spec.Packet.IPHeader.Protocol == 0 {
+		tf.Spec.Packet.IPHeader.Protocol = protocolICMP
 	}
 	TCPSrcPort := uint16(0)
 	TCPDstPort := uint16(0)
@@ -324,6 +436,16 @@ func (c *Controller) injectPacket(tf *opsv1alpha1.Traceflow) error {
 		ICMPID = uint16(tf.Spec.Packet.TransportHeader.ICMP.ID)
 		ICMPSequence = uint16(tf.Spec.Packet.TransportHeader.ICMP.Sequence)
 	}
+	if svcDst != nil {
+		if svcDst.ipProtocol == protocolTCP {
+			TCPDstPort = svcDst.port
+			if TCPFlags == 0 {
+				TCPFlags = tcpFlagSYN
+			}
+		} else {
+			UDPDstPort = svcDst.port
+		}
+	}
 	return c.ofClient.SendTraceflowPacket(
 		tf.Status.DataplaneTag,
 		podInterfaces[0].MAC.String(),
@@ -346,6 +468,87 @@ func (c *Controller) injectPacket(tf *opsv1alpha1.Traceflow) error {
 		-1)
 }
 
+// captureLiveTraffic installs the flow marking the live packets sent from the source Pod to the destination with the
+// data plane tag. The packets sent before the flow is installed are not captured.
+func (c *Controller) captureLiveTraffic(tf *opsv1alpha1.Traceflow, svcDst *serviceDestination) error {
+	podInterfaces := c.interfaceStore.GetContainerInterfacesByPod(tf.Spec.Source.Pod, tf.Spec.Source.Namespace)
+	_, dstIP, isInterNode, err := c.getDestination(tf, svcDst)
+	if err != nil {
+		return err
+	}
+	parsedDstIP := net.ParseIP(dstIP)
+	if parsedDstIP == nil {
+		return fmt.Errorf("invalid destination IP %s", dstIP)
+	}
+	if isInterNode {
+		// Wait a small period for other Nodes, otherwise they cannot observe the captured packets.
+		time.Sleep(time.Duration(injectPacketDelay) * time.Second)
+	}
+
+	klog.V(2).Infof("Capturing live packets for Traceflow %s", tf.Name)
+	c.injectedTagsMutex.Lock()
+	c.injectedTags[tf.Status.DataplaneTag] = tf.Name
+	c.capturedPackets[tf.Status.DataplaneTag] = 0
+	c.injectedTagsMutex.Unlock()
+
+	srcPort := uint16(0)
+	dstPort := uint16(0)
+	if tf.Spec.Packet.TransportHeader.TCP != nil {
+		srcPort = uint16(tf.Spec.Packet.TransportHeader.TCP.SrcPort)
+		dstPort = uint16(tf.Spec.Packet.TransportHeader.TCP.DstPort)
+	} else if tf.Spec.Packet.TransportHeader.UDP != nil {
+		srcPort = uint16(tf.Spec.Packet.TransportHeader.UDP.SrcPort)
+		dstPort = uint16(tf.Spec.Packet.TransportHeader.UDP.DstPort)
+	}
+	// Protocol 0 matches the live packets of any IP protocol.
+	ipProtocol := uint8(tf.Spec.Packet.IPHeader.Protocol)
+	if svcDst != nil {
+		ipProtocol = svcDst.ipProtocol
+		dstPort = svcDst.port
+	}
+	return c.ofClient.InstallTraceflowLiveCaptureFlow(
+		tf.Status.DataplaneTag,
+		uint32(podInterfaces[0].OFPort),
+		podInterfaces[0].MAC,
+		podInterfaces[0].IP,
+		parsedDstIP,
+		ipProtocol,
+		srcPort,
+		dstPort,
+		liveTimeout(tf))
+}
+
+// countCapturedPacket counts the live packets captured by the Sender Node, and removes the live traceflow flows
+// once the requested number of packets are captured. Each captured packet is sent to the Antrea Agent of the Sender
+// Node once, when it's output or dropped.
+func (c *Controller) countCapturedPacket(tf *opsv1alpha1.Traceflow) {
+	c.injectedTagsMutex.Lock()
+	c.capturedPackets[tf.Status.DataplaneTag]++
+	captured := c.capturedPackets[tf.Status.DataplaneTag]
+	c.injectedTagsMutex.Unlock()
+	if captured != livePacketCount(tf) {
+		return
+	}
+	klog.V(2).Infof("Captured %d live packets for Traceflow %s", captured, tf.Name)
+	if err := c.ofClient.UninstallTraceflowLiveFlows(tf.Status.DataplaneTag); err != nil {
+		klog.Errorf("Failed to uninstall flows for Traceflow %s: %v", tf.Name, err)
+	}
+}
+
+func livePacketCount(tf *opsv1alpha1.Traceflow) int32 {
+	if tf.Spec.PacketCount == 0 {
+		return opsv1alpha1.DefaultLivePacketCount
+	}
+	return tf.Spec.PacketCount
+}
+
+func liveTimeout(tf *opsv1alpha1.Traceflow) uint16 {
+	if tf.Spec.Timeout == 0 {
+		return uint16(opsv1alpha1.DefaultLiveTimeout)
+	}
+	return uint16(tf.Spec.Timeout)
+}
+
 func (c *Controller) errorTraceflowCRD(tf *opsv1alpha1.Traceflow, reason string) (*opsv1alpha1.Traceflow, error) {
 	tf.Status.Phase = opsv1alpha1.Failed
 
@@ -366,6 +569,7 @@ func (c *Controller) deallocateTag(tf *opsv1alpha1.Traceflow) {
 	if existingTraceflowName, ok := c.injectedTags[tf.Status.DataplaneTag]; ok {
 		if tf.Name == existingTraceflowName {
 			delete(c.injectedTags, tf.Status.DataplaneTag)
+			delete(c.capturedPackets, tf.Status.DataplaneTag)
 		} else {
 			klog.Warningf("injectedTags cache mismatch tag: %d name: %s existingName: %s",
 				tf.Status.DataplaneTag, tf.Name, existingTraceflowName)
@@ -373,15 +577,25 @@ func (c *Controller) deallocateTag(tf *opsv1alpha1.Traceflow) {
 	}
 	c.injectedTagsMutex.Unlock()
 	c.runningTraceflowsMutex.Lock()
+	uninstall := false
 	if existingTraceflowName, ok := c.runningTraceflows[tf.Status.DataplaneTag]; ok {
 		if tf.Name == existingTraceflowName {
 			delete(c.runningTraceflows, tf.Status.DataplaneTag)
+			uninstall = tf.Spec.Live
 		} else {
 			klog.Warningf("runningTraceflows cache mismatch tag: %d name: %s existingName: %s",
 				tf.Status.DataplaneTag, tf.Name, existingTraceflowName)
 		}
 	}
 	c.runningTraceflowsMutex.Unlock()
+	// The flows of live traceflow are removed when it completes or is deleted, e.g. the requesting client
+	// disconnects, so that the live packets stop being sent to the Antrea Agent. The flows of synthetic
+	// packets are left to expire as they are not hit by other packets.
+	if uninstall {
+		if err := c.ofClient.UninstallTraceflowLiveFlows(tf.Status.DataplaneTag); err != nil {
+			klog.Errorf("Failed to uninstall flows for Traceflow %s: %v", tf.Name, err)
+		}
+	}
 }
 
 func (c *Controller) isSender(tag uint8) bool {
@@ -0,0 +1,151 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datapathmonitor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/metrics"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
+)
+
+const (
+	componentName = "antrea-agent"
+	// reasonFlowTableFull is the reason of the Node events emitted when the
+	// utilization of the datapath flow table exceeds a threshold.
+	reasonFlowTableFull = "OVSFlowTableFull"
+	pollInterval        = 30 * time.Second
+)
+
+// flowStatsGetter is implemented by ovsctl.OVSCtlClient.
+type flowStatsGetter interface {
+	GetDatapathFlowStats() (*ovsctl.DatapathFlowStats, error)
+}
+
+// Monitor periodically computes the utilization of the OVS datapath flow
+// table, i.e. the ratio of the number of installed flows to the maximum number
+// of flows ovs-vswitchd installs in the datapath, and exports it as a
+// Prometheus gauge. When the utilization exceeds the warning threshold, a
+// Warning event is emitted on the Node. When it exceeds the block threshold,
+// CheckFlowTableCapacity returns an error, so that the CNI server refuses the
+// new Pods.
+type Monitor struct {
+	nodeName string
+	client   flowStatsGetter
+	recorder record.EventRecorder
+	// The thresholds are ratios between 0 and 1.
+	warningThreshold float64
+	blockThreshold   float64
+	// lastStats stores the statistics of the last successful poll. It is nil
+	// until the first successful poll.
+	lastStats *ovsctl.DatapathFlowStats
+	mutex     sync.RWMutex
+}
+
+// NewMonitor creates a Monitor for the Node. The thresholds are percentages of
+// the maximum number of datapath flows.
+func NewMonitor(nodeName string, client ovsctl.OVSCtlClient, k8sClient kubernetes.Interface, warningThreshold, blockThreshold int) *Monitor {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sClient.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: componentName, Host: nodeName})
+	return newMonitor(nodeName, client, recorder, warningThreshold, blockThreshold)
+}
+
+func newMonitor(nodeName string, client flowStatsGetter, recorder record.EventRecorder, warningThreshold, blockThreshold int) *Monitor {
+	return &Monitor{
+		nodeName:         nodeName,
+		client:           client,
+		recorder:         recorder,
+		warningThreshold: float64(warningThreshold) / 100,
+		blockThreshold:   float64(blockThreshold) / 100,
+	}
+}
+
+func utilization(stats *ovsctl.DatapathFlowStats) float64 {
+	return float64(stats.Flows) / float64(stats.Limit)
+}
+
+func (m *Monitor) nodeRef() *corev1.ObjectReference {
+	// Events are attached to the Node the same way as kubelet does.
+	return &corev1.ObjectReference{
+		Kind: "Node",
+		Name: m.nodeName,
+		UID:  types.UID(m.nodeName),
+	}
+}
+
+func (m *Monitor) check() error {
+	stats, err := m.client.GetDatapathFlowStats()
+	if err != nil {
+		return err
+	}
+	ratio := utilization(stats)
+	metrics.OVSDatapathFlowUtilization.Set(ratio)
+
+	m.mutex.Lock()
+	lastStats := m.lastStats
+	m.lastStats = stats
+	m.mutex.Unlock()
+
+	// An event is only emitted when a threshold is crossed, not at every poll.
+	var lastRatio float64
+	if lastStats != nil {
+		lastRatio = utilization(lastStats)
+	}
+	if ratio > m.blockThreshold && lastRatio <= m.blockThreshold {
+		m.recorder.Eventf(m.nodeRef(), corev1.EventTypeWarning, reasonFlowTableFull,
+			"The OVS datapath flow table is %.0f%% full (%d/%d flows), new Pods are refused", ratio*100, stats.Flows, stats.Limit)
+	} else if ratio > m.warningThreshold && lastRatio <= m.warningThreshold {
+		m.recorder.Eventf(m.nodeRef(), corev1.EventTypeWarning, reasonFlowTableFull,
+			"The OVS datapath flow table is %.0f%% full (%d/%d flows)", ratio*100, stats.Flows, stats.Limit)
+	}
+	return nil
+}
+
+// CheckFlowTableCapacity returns an error if the utilization of the datapath
+// flow table exceeded the block threshold at the last poll.
+func (m *Monitor) CheckFlowTableCapacity() error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if m.lastStats == nil {
+		return nil
+	}
+	if ratio := utilization(m.lastStats); ratio > m.blockThreshold {
+		return fmt.Errorf("the OVS datapath flow table is %.0f%% full (%d/%d flows), which exceeds the threshold of %.0f%% for creating new Pods",
+			ratio*100, m.lastStats.Flows, m.lastStats.Limit, m.blockThreshold*100)
+	}
+	return nil
+}
+
+// Run polls the utilization of the datapath flow table until stopCh is closed.
+func (m *Monitor) Run(stopCh <-chan struct{}) {
+	klog.Infof("Starting OVS datapath flow table monitor with poll interval %v", pollInterval)
+	wait.Until(func() {
+		if err := m.check(); err != nil {
+			klog.Errorf("Error when checking OVS datapath flow table utilization: %v", err)
+		}
+	}, pollInterval, stopCh)
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datapathmonitor
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/metrics"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
+	ovsctltest "github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl/testing"
+)
+
+func init() {
+	legacyregistry.MustRegister(metrics.OVSDatapathFlowUtilization)
+}
+
+func TestMonitor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := ovsctltest.NewMockOVSCtlClient(ctrl)
+	recorder := record.NewFakeRecorder(10)
+	m := newMonitor("node1", client, recorder, 80, 95)
+
+	// No Pod is refused before the first poll.
+	assert.NoError(t, m.CheckFlowTableCapacity())
+
+	tests := []struct {
+		name          string
+		flows         int
+		expectedEvent string
+		expectedBlock bool
+	}{
+		{"below warning threshold", 500, "", false},
+		{"above warning threshold", 850, "Warning OVSFlowTableFull The OVS datapath flow table is 85% full (850/1000 flows)", false},
+		{"still above warning threshold", 900, "", false},
+		{"above block threshold", 960, "Warning OVSFlowTableFull The OVS datapath flow table is 96% full (960/1000 flows), new Pods are refused", true},
+		{"back below warning threshold", 100, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.EXPECT().GetDatapathFlowStats().Return(&ovsctl.DatapathFlowStats{Flows: tt.flows, Limit: 1000}, nil)
+			require.NoError(t, m.check())
+
+			value, err := testutil.GetGaugeMetricValue(metrics.OVSDatapathFlowUtilization)
+			require.NoError(t, err)
+			assert.Equal(t, float64(tt.flows)/1000, value)
+
+			select {
+			case event := <-recorder.Events:
+				assert.Equal(t, tt.expectedEvent, event)
+			default:
+				assert.Empty(t, tt.expectedEvent, "Expected an event")
+			}
+
+			err = m.CheckFlowTableCapacity()
+			if tt.expectedBlock {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
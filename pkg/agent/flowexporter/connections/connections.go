@@ -21,8 +21,11 @@ import (
 	"k8s.io/klog"
 
 	"github.com/vmware-tanzu/antrea/pkg/agent/flowexporter"
+	"github.com/vmware-tanzu/antrea/pkg/agent/flowexporter/metadata"
 	"github.com/vmware-tanzu/antrea/pkg/agent/interfacestore"
+	"github.com/vmware-tanzu/antrea/pkg/agent/metrics"
 	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/agent/types"
 )
 
 var _ ConnectionStore = new(connectionStore)
@@ -31,18 +34,40 @@ type ConnectionStore interface {
 	Run(stopCh <-chan struct{})
 }
 
+// NetworkPolicyRuleQuerier resolves the Openflow IDs of the NetworkPolicy rules stored in the
+// conntrack labels to the rules.
+type NetworkPolicyRuleQuerier interface {
+	GetRuleByFlowID(ruleFlowID uint32) *types.PolicyRuleRef
+}
+
 type connectionStore struct {
 	connections map[flowexporter.ConnectionKey]flowexporter.Connection // Add 5-tuple as string array
 	connDumper  ConnTrackDumper
 	ifaceStore  interfacestore.InterfaceStore
-	mutex       sync.Mutex
+	// metadataCache maps the IPs of the connections to the Pods and Services of the cluster. It's used
+	// to add the metadata of the remote Pods, and of the local Pods whose IPs have been reused.
+	metadataCache *metadata.Cache
+	// sampler selects the connections to export. The keys of the connections which are not selected
+	// are kept in droppedConnections, so that they are counted only once.
+	sampler            *flowexporter.Sampler
+	droppedConnections map[flowexporter.ConnectionKey]struct{}
+	policyRuleQuerier  NetworkPolicyRuleQuerier
+	// podPairTracker accounts the bytes of the connections between Pods to the
+	// antrea_pod_to_pod_bytes_total metric. It is nil if the metric is disabled.
+	podPairTracker *flowexporter.PodPairTracker
+	mutex          sync.Mutex
 }
 
-func NewConnectionStore(ctDumper ConnTrackDumper, ifaceStore interfacestore.InterfaceStore) *connectionStore {
+func NewConnectionStore(ctDumper ConnTrackDumper, ifaceStore interfacestore.InterfaceStore, metadataCache *metadata.Cache, sampler *flowexporter.Sampler, policyRuleQuerier NetworkPolicyRuleQuerier, podPairTracker *flowexporter.PodPairTracker) *connectionStore {
 	return &connectionStore{
-		connections: make(map[flowexporter.ConnectionKey]flowexporter.Connection),
-		connDumper:  ctDumper,
-		ifaceStore:  ifaceStore,
+		connections:        make(map[flowexporter.ConnectionKey]flowexporter.Connection),
+		connDumper:         ctDumper,
+		ifaceStore:         ifaceStore,
+		metadataCache:      metadataCache,
+		sampler:            sampler,
+		droppedConnections: make(map[flowexporter.ConnectionKey]struct{}),
+		policyRuleQuerier:  policyRuleQuerier,
+		podPairTracker:     podPairTracker,
 	}
 }
 
@@ -78,6 +103,7 @@ func (cs *connectionStore) addOrUpdateConn(conn *flowexporter.Connection) {
 	cs.mutex.Lock()
 	defer cs.mutex.Unlock()
 	if exists {
+		cs.addPodPairBytes(existingConn, counterDelta(existingConn.OriginalBytes, conn.OriginalBytes), counterDelta(existingConn.ReverseBytes, conn.ReverseBytes))
 		// Update the necessary fields that are used in generating flow records.
 		// Can same 5-tuple flow get deleted and added to conntrack table? If so use ID.
 		existingConn.StopTime = conn.StopTime
@@ -89,6 +115,9 @@ func (cs *connectionStore) addOrUpdateConn(conn *flowexporter.Connection) {
 		cs.connections[connKey] = *existingConn
 		klog.V(2).Infof("Antrea flow updated: %v", existingConn)
 	} else {
+		if !cs.sampleConn(connKey, conn) {
+			return
+		}
 		var srcFound, dstFound bool
 		sIface, srcFound := cs.ifaceStore.GetInterfaceByIP(conn.TupleOrig.SourceAddress.String())
 		dIface, dstFound := cs.ifaceStore.GetInterfaceByIP(conn.TupleReply.SourceAddress.String())
@@ -98,17 +127,117 @@ func (cs *connectionStore) addOrUpdateConn(conn *flowexporter.Connection) {
 		if srcFound && sIface.Type == interfacestore.ContainerInterface {
 			conn.SourcePodName = sIface.ContainerInterfaceConfig.PodName
 			conn.SourcePodNamespace = sIface.ContainerInterfaceConfig.PodNamespace
+			conn.IsSourcePodLocal = true
 		}
 		if dstFound && dIface.Type == interfacestore.ContainerInterface {
 			conn.DestinationPodName = dIface.ContainerInterfaceConfig.PodName
 			conn.DestinationPodNamespace = dIface.ContainerInterfaceConfig.PodNamespace
 		}
+		if cs.metadataCache != nil {
+			cs.addMetadata(conn)
+		}
+		if cs.policyRuleQuerier != nil {
+			cs.addPolicyRules(conn)
+		}
+		cs.addPodPairBytes(conn, conn.OriginalBytes, conn.ReverseBytes)
 		klog.V(2).Infof("New Antrea flow added: %v", conn)
 		// Add new antrea connection to connection store
 		cs.connections[connKey] = *conn
 	}
 }
 
+// counterDelta returns the increase of a conntrack counter since the previous poll. If the counter
+// decreased, the conntrack entry has been replaced by a new connection with the same 5-tuple.
+func counterDelta(previous, current uint64) uint64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+// addPodPairBytes accounts the bytes sent in both directions of a connection between two Pods to
+// the Pod pairs. A connection is only accounted by the Node of its source Pod, so that the
+// connections between Pods running on different Nodes are not accounted twice. It must be called
+// with the mutex held.
+func (cs *connectionStore) addPodPairBytes(conn *flowexporter.Connection, originalBytes, reverseBytes uint64) {
+	if cs.podPairTracker == nil || !conn.IsSourcePodLocal || conn.DestinationPodName == "" {
+		return
+	}
+	pair := flowexporter.PodPair{
+		SourcePodNamespace:      conn.SourcePodNamespace,
+		SourcePodName:           conn.SourcePodName,
+		DestinationPodNamespace: conn.DestinationPodNamespace,
+		DestinationPodName:      conn.DestinationPodName,
+	}
+	cs.podPairTracker.Add(pair, originalBytes)
+	reversePair := flowexporter.PodPair{
+		SourcePodNamespace:      conn.DestinationPodNamespace,
+		SourcePodName:           conn.DestinationPodName,
+		DestinationPodNamespace: conn.SourcePodNamespace,
+		DestinationPodName:      conn.SourcePodName,
+	}
+	cs.podPairTracker.Add(reversePair, reverseBytes)
+}
+
+// sampleConn returns true if the new connection must be added to the connection store. It must be
+// called with the mutex held.
+func (cs *connectionStore) sampleConn(connKey flowexporter.ConnectionKey, conn *flowexporter.Connection) bool {
+	if cs.sampler == nil {
+		return true
+	}
+	if _, dropped := cs.droppedConnections[connKey]; dropped {
+		return false
+	}
+	if !cs.sampler.Sample(conn) {
+		cs.droppedConnections[connKey] = struct{}{}
+		metrics.FlowExportDroppedCount.Inc()
+		klog.V(4).Infof("Antrea flow dropped by sampling: %v", conn)
+		return false
+	}
+	metrics.FlowExportSampledCount.Inc()
+	return true
+}
+
+// addMetadata adds the Kubernetes metadata of the Pods and the Service of the connection. The Pods are
+// the ones which owned the IPs when the connection started, as the IPs may have been reused since then.
+func (cs *connectionStore) addMetadata(conn *flowexporter.Connection) {
+	if pod, found := cs.metadataCache.GetPodByIP(conn.TupleOrig.SourceAddress.String(), conn.StartTime); found {
+		conn.SourcePodName = pod.Name
+		conn.SourcePodNamespace = pod.Namespace
+		conn.SourceWorkloadKind = pod.WorkloadKind
+	}
+	if pod, found := cs.metadataCache.GetPodByIP(conn.TupleReply.SourceAddress.String(), conn.StartTime); found {
+		conn.DestinationPodName = pod.Name
+		conn.DestinationPodNamespace = pod.Namespace
+	}
+	// The original destination of a connection to a Service is its ClusterIP.
+	if service, found := cs.metadataCache.GetServiceByIP(conn.TupleOrig.DestinationAddress.String()); found {
+		conn.DestinationServiceName = service.Name
+		conn.DestinationServiceNamespace = service.Namespace
+	}
+}
+
+// addPolicyRules adds the NetworkPolicy rules which allowed the connection. They are resolved when the
+// connection is added, as the rules may be deleted and their IDs reused later.
+func (cs *connectionStore) addPolicyRules(conn *flowexporter.Connection) {
+	if conn.IngressRuleFlowID != 0 {
+		if rule := cs.policyRuleQuerier.GetRuleByFlowID(conn.IngressRuleFlowID); rule != nil {
+			conn.IngressNetworkPolicyName = rule.PolicyName
+			conn.IngressNetworkPolicyNamespace = rule.PolicyNamespace
+			conn.IngressNetworkPolicyType = string(rule.PolicyType)
+			conn.IngressNetworkPolicyRulePriority = rule.RulePriority
+		}
+	}
+	if conn.EgressRuleFlowID != 0 {
+		if rule := cs.policyRuleQuerier.GetRuleByFlowID(conn.EgressRuleFlowID); rule != nil {
+			conn.EgressNetworkPolicyName = rule.PolicyName
+			conn.EgressNetworkPolicyNamespace = rule.PolicyNamespace
+			conn.EgressNetworkPolicyType = string(rule.PolicyType)
+			conn.EgressNetworkPolicyRulePriority = rule.RulePriority
+		}
+	}
+}
+
 func (cs *connectionStore) getConnByKey(flowTuple flowexporter.ConnectionKey) (*flowexporter.Connection, bool) {
 	cs.mutex.Lock()
 	defer cs.mutex.Unlock()
@@ -129,6 +258,11 @@ func (cs *connectionStore) poll() (int, error) {
 	for _, conn := range filteredConns {
 		cs.addOrUpdateConn(conn)
 	}
+	if cs.podPairTracker != nil {
+		cs.mutex.Lock()
+		cs.podPairTracker.Flush(time.Now())
+		cs.mutex.Unlock()
+	}
 	klog.V(2).Infof("Conntrack polling successful")
 
 	return len(filteredConns), nil
@@ -21,11 +21,18 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/vmware-tanzu/antrea/pkg/agent/flowexporter"
 	connectionstest "github.com/vmware-tanzu/antrea/pkg/agent/flowexporter/connections/testing"
+	"github.com/vmware-tanzu/antrea/pkg/agent/flowexporter/metadata"
 	"github.com/vmware-tanzu/antrea/pkg/agent/interfacestore"
 	interfacestoretest "github.com/vmware-tanzu/antrea/pkg/agent/interfacestore/testing"
+	"github.com/vmware-tanzu/antrea/pkg/agent/types"
 )
 
 func makeTuple(srcIP *net.IP, dstIP *net.IP, protoID uint8, srcPort uint16, dstPort uint16) (*flowexporter.Tuple, *flowexporter.Tuple) {
@@ -139,3 +146,164 @@ func TestConnectionStore_addAndUpdateConn(t *testing.T) {
 		assert.Equal(t, expConn, *actualConn, "Connections should be equal")
 	}
 }
+
+func TestConnectionStore_addConnWithMetadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	refTime := time.Now()
+	controller := true
+	// The source Pod runs on another Node, the destination is a Service whose endpoint is a local Pod.
+	srcPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "ns1",
+			Name:              "pod1",
+			UID:               "uid1",
+			CreationTimestamp: metav1.NewTime(refTime.Add(-time.Hour)),
+			OwnerReferences:   []metav1.OwnerReference{{Kind: "StatefulSet", Name: "sts1", Controller: &controller}},
+		},
+		Status: corev1.PodStatus{PodIP: "1.2.3.4", Phase: corev1.PodRunning},
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns2", Name: "svc2"},
+		Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.2"},
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informerFactory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(srcPod, service), 0)
+	metadataCache := metadata.NewCache(informerFactory.Core().V1().Pods(), informerFactory.Core().V1().Services())
+	informerFactory.Start(stopCh)
+	cache.WaitForCacheSync(stopCh, metadataCache.HasSynced)
+
+	tuple, _ := makeTuple(&net.IP{1, 2, 3, 4}, &net.IP{10, 96, 0, 2}, 6, 65280, 80)
+	_, revTuple := makeTuple(&net.IP{1, 2, 3, 4}, &net.IP{8, 7, 6, 5}, 6, 65280, 8080)
+	testFlow := flowexporter.Connection{
+		StartTime:  refTime.Add(-(time.Second * 20)),
+		StopTime:   refTime,
+		TupleOrig:  *tuple,
+		TupleReply: *revTuple,
+	}
+	interfaceFlow := &interfacestore.InterfaceConfig{
+		InterfaceName: "interface2",
+		IP:            net.IP{8, 7, 6, 5},
+		ContainerInterfaceConfig: &interfacestore.ContainerInterfaceConfig{
+			ContainerID:  "2",
+			PodName:      "pod2",
+			PodNamespace: "ns2",
+		},
+	}
+	iStore := interfacestoretest.NewMockInterfaceStore(ctrl)
+	iStore.EXPECT().GetInterfaceByIP(testFlow.TupleOrig.SourceAddress.String()).Return(nil, false)
+	iStore.EXPECT().GetInterfaceByIP(testFlow.TupleReply.SourceAddress.String()).Return(interfaceFlow, true)
+	connStore := NewConnectionStore(connectionstest.NewMockConnTrackDumper(ctrl), iStore, metadataCache, nil, nil, nil)
+
+	expConn := testFlow
+	expConn.SourcePodNamespace = "ns1"
+	expConn.SourcePodName = "pod1"
+	expConn.SourceWorkloadKind = "StatefulSet"
+	expConn.DestinationPodNamespace = "ns2"
+	expConn.DestinationPodName = "pod2"
+	expConn.DestinationServiceNamespace = "ns2"
+	expConn.DestinationServiceName = "svc2"
+	connStore.addOrUpdateConn(&testFlow)
+	actualConn, _ := connStore.getConnByKey(flowexporter.NewConnectionKey(&testFlow))
+	assert.Equal(t, expConn, *actualConn, "Connections should be equal")
+}
+
+func TestConnectionStore_addConnWithSampling(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	iStore := interfacestoretest.NewMockInterfaceStore(ctrl)
+	iStore.EXPECT().GetInterfaceByIP(gomock.Any()).Return(nil, false).AnyTimes()
+	connStore := NewConnectionStore(connectionstest.NewMockConnTrackDumper(ctrl), iStore, nil, flowexporter.NewSampler(10, 0), nil, nil)
+
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		tuple, revTuple := makeTuple(&net.IP{10, 10, byte(i >> 8), byte(i)}, &net.IP{10, 20, 0, 1}, 6, 30000, 80)
+		conn := flowexporter.Connection{TupleOrig: *tuple, TupleReply: *revTuple}
+		// Polling the same connection again must not change the sampling decision.
+		for j := 0; j < 2; j++ {
+			c := conn
+			connStore.addOrUpdateConn(&c)
+		}
+		_, stored := connStore.getConnByKey(flowexporter.NewConnectionKey(&conn))
+		_, dropped := connStore.droppedConnections[flowexporter.NewConnectionKey(&conn)]
+		assert.NotEqual(t, stored, dropped)
+		if stored {
+			sampled++
+		}
+	}
+	assert.Equal(t, sampled, len(connStore.connections))
+	assert.InDelta(t, 100, sampled, 30)
+}
+
+type fakePolicyRuleQuerier map[uint32]*types.PolicyRuleRef
+
+func (q fakePolicyRuleQuerier) GetRuleByFlowID(ruleFlowID uint32) *types.PolicyRuleRef {
+	return q[ruleFlowID]
+}
+
+func TestConnectionStore_addConnWithPolicyRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	iStore := interfacestoretest.NewMockInterfaceStore(ctrl)
+	iStore.EXPECT().GetInterfaceByIP(gomock.Any()).Return(nil, false).AnyTimes()
+	querier := fakePolicyRuleQuerier{
+		1: {PolicyName: "np1", PolicyNamespace: "ns1", PolicyType: types.K8sNetworkPolicy, RulePriority: -1},
+		2: {PolicyName: "cnp1", PolicyType: types.AntreaClusterNetworkPolicy, RulePriority: 3},
+	}
+	connStore := NewConnectionStore(connectionstest.NewMockConnTrackDumper(ctrl), iStore, nil, nil, querier, nil)
+
+	tests := []struct {
+		name                string
+		ingressRuleFlowID   uint32
+		egressRuleFlowID    uint32
+		expectedIngressName string
+		expectedIngressType string
+		expectedEgressName  string
+		expectedEgressType  string
+		expectedEgressPrio  int32
+	}{
+		{
+			name:                "ingress rule",
+			ingressRuleFlowID:   1,
+			expectedIngressName: "np1",
+			expectedIngressType: "K8sNetworkPolicy",
+		},
+		{
+			name:                "ingress and egress rules",
+			ingressRuleFlowID:   1,
+			egressRuleFlowID:    2,
+			expectedIngressName: "np1",
+			expectedIngressType: "K8sNetworkPolicy",
+			expectedEgressName:  "cnp1",
+			expectedEgressType:  "AntreaClusterNetworkPolicy",
+			expectedEgressPrio:  3,
+		},
+		{
+			name:             "unknown rule",
+			egressRuleFlowID: 10,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tuple, revTuple := makeTuple(&net.IP{10, 10, 0, byte(i)}, &net.IP{10, 20, 0, 1}, 6, 30000, 80)
+			conn := flowexporter.Connection{
+				TupleOrig:         *tuple,
+				TupleReply:        *revTuple,
+				IngressRuleFlowID: tt.ingressRuleFlowID,
+				EgressRuleFlowID:  tt.egressRuleFlowID,
+			}
+			connStore.addOrUpdateConn(&conn)
+			actualConn, _ := connStore.getConnByKey(flowexporter.NewConnectionKey(&conn))
+			assert.Equal(t, tt.expectedIngressName, actualConn.IngressNetworkPolicyName)
+			assert.Equal(t, tt.expectedIngressType, actualConn.IngressNetworkPolicyType)
+			assert.Equal(t, tt.expectedEgressName, actualConn.EgressNetworkPolicyName)
+			assert.Equal(t, tt.expectedEgressType, actualConn.EgressNetworkPolicyType)
+			assert.Equal(t, tt.expectedEgressPrio, actualConn.EgressNetworkPolicyRulePriority)
+			if tt.ingressRuleFlowID == 1 {
+				assert.Equal(t, "ns1", actualConn.IngressNetworkPolicyNamespace)
+				assert.Equal(t, int32(-1), actualConn.IngressNetworkPolicyRulePriority)
+			}
+		})
+	}
+}
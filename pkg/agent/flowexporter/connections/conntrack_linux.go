@@ -17,6 +17,7 @@
 package connections
 
 import (
+	"encoding/binary"
 	"net"
 
 	"github.com/ti-mo/conntrack"
@@ -159,22 +160,24 @@ func createAntreaConn(conn *conntrack.Flow) *flowexporter.Connection {
 	}
 	// Assign all the applicable fields
 	newConn := flowexporter.Connection{
-		conn.ID,
-		conn.Timeout,
-		conn.Timestamp.Start,
-		conn.Timestamp.Stop,
-		conn.Zone,
-		uint32(conn.Status.Value),
-		tupleOrig,
-		tupleReply,
-		conn.CountersOrig.Packets,
-		conn.CountersOrig.Bytes,
-		conn.CountersReply.Packets,
-		conn.CountersReply.Bytes,
-		"",
-		"",
-		"",
-		"",
+		ID:              conn.ID,
+		Timeout:         conn.Timeout,
+		StartTime:       conn.Timestamp.Start,
+		StopTime:        conn.Timestamp.Stop,
+		Zone:            conn.Zone,
+		StatusFlag:      uint32(conn.Status.Value),
+		TupleOrig:       tupleOrig,
+		TupleReply:      tupleReply,
+		OriginalPackets: conn.CountersOrig.Packets,
+		OriginalBytes:   conn.CountersOrig.Bytes,
+		ReversePackets:  conn.CountersReply.Packets,
+		ReverseBytes:    conn.CountersReply.Bytes,
+	}
+	// The IDs of the NetworkPolicy rules are stored in the ct_label by the conntrackCommitTable, see
+	// openflow.IngressRuleCTLabel and openflow.EgressRuleCTLabel.
+	if len(conn.Labels) >= 8 {
+		newConn.IngressRuleFlowID = binary.LittleEndian.Uint32(conn.Labels[:4])
+		newConn.EgressRuleFlowID = binary.LittleEndian.Uint32(conn.Labels[4:8])
 	}
 
 	return &newConn
@@ -152,3 +152,21 @@ func TestConnTrack_DumpFilter(t *testing.T) {
 	}
 	assert.Equal(t, 1, len(conns), "number of filtered connections should be equal")
 }
+
+func TestCreateAntreaConnWithRuleLabels(t *testing.T) {
+	flow := conntrack.Flow{
+		TupleOrig:  tuple3,
+		TupleReply: revTuple3,
+		Zone:       openflow.CtZone,
+		// ct_label with the ingress rule ID 5 in bits 0..31 and the egress rule ID 258 in bits 32..63.
+		Labels: []byte{5, 0, 0, 0, 2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	}
+	conn := createAntreaConn(&flow)
+	assert.Equal(t, uint32(5), conn.IngressRuleFlowID)
+	assert.Equal(t, uint32(258), conn.EgressRuleFlowID)
+
+	flow.Labels = nil
+	conn = createAntreaConn(&flow)
+	assert.Equal(t, uint32(0), conn.IngressRuleFlowID)
+	assert.Equal(t, uint32(0), conn.EgressRuleFlowID)
+}
@@ -0,0 +1,274 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+const (
+	// releasedIPRetention is how long the Pod which owned an IP is remembered
+	// after releasing it, so that the connections it started before the IP was
+	// reused can still be mapped to it.
+	releasedIPRetention = 10 * time.Minute
+)
+
+// PodMeta is the Kubernetes metadata of a Pod.
+type PodMeta struct {
+	Namespace string
+	Name      string
+	// WorkloadKind is the kind of the controller of the Pod, e.g. ReplicaSet.
+	// It's empty if the Pod has no controller.
+	WorkloadKind string
+}
+
+// ServiceMeta is the Kubernetes metadata of a Service.
+type ServiceMeta struct {
+	Namespace string
+	Name      string
+}
+
+// podIPOwner is a Pod which owned an IP during a period of time.
+type podIPOwner struct {
+	uid types.UID
+	PodMeta
+	// start is the time the Pod was created. end is the time the Pod released
+	// the IP, it's zero while the Pod owns the IP.
+	start time.Time
+	end   time.Time
+}
+
+// Cache maps the IPs of the connections to the Pods and the Services of the
+// cluster, populated by watching all the Pods and Services. As Pod IPs can be
+// reused, it remembers the Pods which owned an IP recently, and maps a
+// connection to the Pod which owned the IP when the connection started.
+type Cache struct {
+	mutex sync.RWMutex
+	// podIPOwners maps an IP to the Pods which owned it, the oldest first.
+	podIPOwners map[string][]*podIPOwner
+	// podIPs maps the UID of a Pod to its IP.
+	podIPs map[types.UID]string
+	// services maps a ClusterIP to its Service.
+	services      map[string]ServiceMeta
+	podSynced     cache.InformerSynced
+	serviceSynced cache.InformerSynced
+	clock         clock.Clock
+}
+
+// NewCache creates a Cache populated by the provided informers.
+func NewCache(podInformer coreinformers.PodInformer, serviceInformer coreinformers.ServiceInformer) *Cache {
+	return newCache(podInformer, serviceInformer, clock.RealClock{})
+}
+
+func newCache(podInformer coreinformers.PodInformer, serviceInformer coreinformers.ServiceInformer, clock clock.Clock) *Cache {
+	c := &Cache{
+		podIPOwners:   map[string][]*podIPOwner{},
+		podIPs:        map[types.UID]string{},
+		services:      map[string]ServiceMeta{},
+		podSynced:     podInformer.Informer().HasSynced,
+		serviceSynced: serviceInformer.Informer().HasSynced,
+		clock:         clock,
+	}
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.addPod,
+		UpdateFunc: func(oldObj, curObj interface{}) { c.addPod(curObj) },
+		DeleteFunc: c.deletePod,
+	})
+	serviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.addService,
+		UpdateFunc: c.updateService,
+		DeleteFunc: c.deleteService,
+	})
+	return c
+}
+
+// HasSynced returns true once the Pods and Services have been synced.
+func (c *Cache) HasSynced() bool {
+	return c.podSynced() && c.serviceSynced()
+}
+
+// GetPodByIP returns the Pod which owned the IP at the provided time, i.e. the
+// start time of a connection. If no Pod owned the IP at that time, e.g. because
+// of clock skew between the Node and the Kubernetes apiserver, the Pod which
+// currently owns the IP is returned.
+func (c *Cache) GetPodByIP(ip string, t time.Time) (*PodMeta, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	owners := c.podIPOwners[ip]
+	for i := len(owners) - 1; i >= 0; i-- {
+		owner := owners[i]
+		if !t.Before(owner.start) && (owner.end.IsZero() || !t.After(owner.end)) {
+			return &owner.PodMeta, true
+		}
+	}
+	if len(owners) > 0 && owners[len(owners)-1].end.IsZero() {
+		return &owners[len(owners)-1].PodMeta, true
+	}
+	return nil, false
+}
+
+// GetServiceByIP returns the Service whose ClusterIP is the provided IP.
+func (c *Cache) GetServiceByIP(ip string) (*ServiceMeta, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	service, exists := c.services[ip]
+	if !exists {
+		return nil, false
+	}
+	return &service, true
+}
+
+func (c *Cache) addPod(obj interface{}) {
+	pod := obj.(*corev1.Pod)
+	// The IPs of the Pods in the host network are the Node IPs.
+	if pod.Spec.HostNetwork {
+		return
+	}
+	ip := pod.Status.PodIP
+	// The IPs of the terminated Pods can be reused by other Pods.
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		ip = ""
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	oldIP, exists := c.podIPs[pod.UID]
+	if exists && oldIP == ip {
+		return
+	}
+	if exists {
+		c.releaseIP(pod.UID, oldIP)
+	}
+	if ip == "" {
+		return
+	}
+	owner := &podIPOwner{
+		uid:     pod.UID,
+		PodMeta: PodMeta{Namespace: pod.Namespace, Name: pod.Name},
+		start:   pod.CreationTimestamp.Time,
+	}
+	if controller := metav1.GetControllerOf(pod); controller != nil {
+		owner.WorkloadKind = controller.Kind
+	}
+	c.podIPOwners[ip] = append(c.podIPOwners[ip], owner)
+	c.podIPs[pod.UID] = ip
+	klog.V(4).Infof("IP %s is owned by Pod %s/%s", ip, pod.Namespace, pod.Name)
+}
+
+func (c *Cache) deletePod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			klog.Errorf("Error decoding object when deleting Pod, invalid type: %v", obj)
+			return
+		}
+		pod, ok = tombstone.Obj.(*corev1.Pod)
+		if !ok {
+			klog.Errorf("Error decoding object tombstone when deleting Pod, invalid type: %v", tombstone.Obj)
+			return
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if ip, exists := c.podIPs[pod.UID]; exists {
+		c.releaseIP(pod.UID, ip)
+	}
+}
+
+// releaseIP records the time the Pod released the IP, and forgets the Pods
+// which released it more than releasedIPRetention ago. It must be called with
+// the mutex held.
+func (c *Cache) releaseIP(uid types.UID, ip string) {
+	delete(c.podIPs, uid)
+	now := c.clock.Now()
+	var owners []*podIPOwner
+	for _, owner := range c.podIPOwners[ip] {
+		if owner.uid == uid {
+			owner.end = now
+		}
+		if owner.end.IsZero() || now.Sub(owner.end) <= releasedIPRetention {
+			owners = append(owners, owner)
+		}
+	}
+	if len(owners) == 0 {
+		delete(c.podIPOwners, ip)
+	} else {
+		c.podIPOwners[ip] = owners
+	}
+}
+
+func isClusterIPSet(service *corev1.Service) bool {
+	return service.Spec.ClusterIP != "" && service.Spec.ClusterIP != corev1.ClusterIPNone
+}
+
+func (c *Cache) addService(obj interface{}) {
+	service := obj.(*corev1.Service)
+	if !isClusterIPSet(service) {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.services[service.Spec.ClusterIP] = ServiceMeta{Namespace: service.Namespace, Name: service.Name}
+}
+
+func (c *Cache) updateService(oldObj, curObj interface{}) {
+	oldService := oldObj.(*corev1.Service)
+	curService := curObj.(*corev1.Service)
+	if oldService.Spec.ClusterIP != curService.Spec.ClusterIP {
+		c.deleteService(oldService)
+	}
+	c.addService(curService)
+}
+
+func (c *Cache) deleteService(obj interface{}) {
+	service, ok := obj.(*corev1.Service)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			klog.Errorf("Error decoding object when deleting Service, invalid type: %v", obj)
+			return
+		}
+		service, ok = tombstone.Obj.(*corev1.Service)
+		if !ok {
+			klog.Errorf("Error decoding object tombstone when deleting Service, invalid type: %v", tombstone.Obj)
+			return
+		}
+	}
+	if !isClusterIPSet(service) {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if existing, exists := c.services[service.Spec.ClusterIP]; exists && existing.Namespace == service.Namespace && existing.Name == service.Name {
+		delete(c.services, service.Spec.ClusterIP)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestCache(clock clock.Clock) *Cache {
+	informerFactory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	return newCache(informerFactory.Core().V1().Pods(), informerFactory.Core().V1().Services(), clock)
+}
+
+func newPod(uid, name, ip string, created time.Time, owner *metav1.OwnerReference) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "ns1",
+			Name:              name,
+			UID:               types.UID(uid),
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Status: corev1.PodStatus{PodIP: ip, Phase: corev1.PodRunning},
+	}
+	if owner != nil {
+		pod.OwnerReferences = []metav1.OwnerReference{*owner}
+	}
+	return pod
+}
+
+func TestGetPodByIPWithIPReuse(t *testing.T) {
+	startTime := time.Now().Add(-time.Hour)
+	fakeClock := clock.NewFakeClock(startTime)
+	c := newTestCache(fakeClock)
+	controller := true
+	owner := &metav1.OwnerReference{Kind: "ReplicaSet", Name: "rs1", Controller: &controller}
+
+	oldPod := newPod("uid1", "pod1", "10.10.1.2", startTime, owner)
+	c.addPod(oldPod)
+	fakeClock.Step(time.Minute)
+	c.deletePod(oldPod)
+	deletionTime := fakeClock.Now()
+	fakeClock.Step(time.Second)
+	pod2 := newPod("uid2", "pod2", "10.10.1.2", fakeClock.Now(), nil)
+	c.addPod(pod2)
+
+	pod, found := c.GetPodByIP("10.10.1.2", startTime.Add(30*time.Second))
+	assert.True(t, found)
+	assert.Equal(t, PodMeta{Namespace: "ns1", Name: "pod1", WorkloadKind: "ReplicaSet"}, *pod)
+
+	pod, found = c.GetPodByIP("10.10.1.2", deletionTime)
+	assert.True(t, found)
+	assert.Equal(t, "pod1", pod.Name)
+
+	pod, found = c.GetPodByIP("10.10.1.2", fakeClock.Now().Add(time.Second))
+	assert.True(t, found)
+	assert.Equal(t, PodMeta{Namespace: "ns1", Name: "pod2"}, *pod)
+
+	// A connection started before the Pod creation, e.g. because of clock skew, is mapped to the current owner.
+	pod, found = c.GetPodByIP("10.10.1.2", startTime.Add(-time.Second))
+	assert.True(t, found)
+	assert.Equal(t, "pod2", pod.Name)
+
+	// The deleted Pod is forgotten once the retention period has elapsed.
+	fakeClock.Step(releasedIPRetention + time.Second)
+	c.deletePod(pod2)
+	_, found = c.GetPodByIP("10.10.1.2", startTime.Add(30*time.Second))
+	assert.False(t, found)
+	pod, found = c.GetPodByIP("10.10.1.2", fakeClock.Now())
+	assert.True(t, found)
+	assert.Equal(t, "pod2", pod.Name)
+	assert.Len(t, c.podIPOwners["10.10.1.2"], 1)
+}
+
+func TestTerminatedPodReleasesIP(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	c := newTestCache(fakeClock)
+
+	pod := newPod("uid1", "pod1", "10.10.1.2", fakeClock.Now(), nil)
+	c.addPod(pod)
+	fakeClock.Step(time.Minute)
+	pod.Status.Phase = corev1.PodSucceeded
+	c.addPod(pod)
+	fakeClock.Step(time.Minute)
+
+	_, found := c.GetPodByIP("10.10.1.2", fakeClock.Now())
+	assert.False(t, found)
+	assert.Empty(t, c.podIPs)
+}
+
+func TestHostNetworkPodIgnored(t *testing.T) {
+	c := newTestCache(clock.NewFakeClock(time.Now()))
+	pod := newPod("uid1", "pod1", "192.168.1.1", time.Now(), nil)
+	pod.Spec.HostNetwork = true
+	c.addPod(pod)
+
+	_, found := c.GetPodByIP("192.168.1.1", time.Now())
+	assert.False(t, found)
+}
+
+func TestGetServiceByIP(t *testing.T) {
+	c := newTestCache(clock.NewFakeClock(time.Now()))
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "svc1"},
+		Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.10"},
+	}
+	headlessService := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "svc2"},
+		Spec:       corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone},
+	}
+	c.addService(service)
+	c.addService(headlessService)
+
+	svc, found := c.GetServiceByIP("10.96.0.10")
+	assert.True(t, found)
+	assert.Equal(t, ServiceMeta{Namespace: "ns1", Name: "svc1"}, *svc)
+	assert.Len(t, c.services, 1)
+
+	c.deleteService(service)
+	_, found = c.GetServiceByIP("10.96.0.10")
+	assert.False(t, found)
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowexporter
+
+import (
+	"time"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/metrics"
+)
+
+const (
+	// DefaultMaxTrackedPodPairs is the default number of Pod pairs which get
+	// their own antrea_pod_to_pod_bytes_total series.
+	DefaultMaxTrackedPodPairs = 1000
+	// smallPodPairBytes is the number of bytes under which the traffic of a Pod
+	// pair during a poll period is accounted to the "other" series.
+	smallPodPairBytes = 1024
+	otherPodPairLabel = "other"
+)
+
+// PodPair identifies the traffic sent from a source Pod to a destination Pod.
+type PodPair struct {
+	SourcePodNamespace      string
+	SourcePodName           string
+	DestinationPodNamespace string
+	DestinationPodName      string
+}
+
+var otherPodPair = PodPair{otherPodPairLabel, otherPodPairLabel, otherPodPairLabel, otherPodPairLabel}
+
+func (p PodPair) labels() map[string]string {
+	return map[string]string{
+		"src_pod": p.SourcePodName,
+		"src_ns":  p.SourcePodNamespace,
+		"dst_pod": p.DestinationPodName,
+		"dst_ns":  p.DestinationPodNamespace,
+	}
+}
+
+// PodPairTracker accumulates the bytes sent between Pods during a poll period,
+// and adds them to the antrea_pod_to_pod_bytes_total metric when the period is
+// flushed. To bound the cardinality of the metric, the Pod pairs which sent
+// less than 1 KiB during the period are accounted to a single "other" series,
+// and at most maxTrackedPairs Pod pairs get their own series: when a new pair
+// must be tracked, the series of the least recently active pair is deleted.
+// It is not safe for concurrent use.
+type PodPairTracker struct {
+	maxTrackedPairs int
+	periodBytes     map[PodPair]uint64
+	// lastActive stores the last time the tracked pairs sent more than
+	// smallPodPairBytes during a period.
+	lastActive map[PodPair]time.Time
+}
+
+// NewPodPairTracker creates a PodPairTracker which tracks at most
+// maxTrackedPairs Pod pairs.
+func NewPodPairTracker(maxTrackedPairs int) *PodPairTracker {
+	return &PodPairTracker{
+		maxTrackedPairs: maxTrackedPairs,
+		periodBytes:     map[PodPair]uint64{},
+		lastActive:      map[PodPair]time.Time{},
+	}
+}
+
+// Add accounts bytes sent by the source Pod to the destination Pod of the pair
+// during the current period.
+func (t *PodPairTracker) Add(pair PodPair, bytes uint64) {
+	if bytes == 0 {
+		return
+	}
+	t.periodBytes[pair] += bytes
+}
+
+// Flush adds the bytes of the current period to the metric, and starts a new
+// period.
+func (t *PodPairTracker) Flush(now time.Time) {
+	var otherBytes uint64
+	for pair, bytes := range t.periodBytes {
+		if bytes < smallPodPairBytes {
+			otherBytes += bytes
+			continue
+		}
+		if _, tracked := t.lastActive[pair]; !tracked {
+			if t.maxTrackedPairs <= 0 {
+				otherBytes += bytes
+				continue
+			}
+			if len(t.lastActive) >= t.maxTrackedPairs {
+				t.evictLeastRecentlyActive()
+			}
+		}
+		t.lastActive[pair] = now
+		metrics.PodToPodBytes.With(pair.labels()).Add(float64(bytes))
+	}
+	if otherBytes > 0 {
+		metrics.PodToPodBytes.With(otherPodPair.labels()).Add(float64(otherBytes))
+	}
+	t.periodBytes = map[PodPair]uint64{}
+}
+
+func (t *PodPairTracker) evictLeastRecentlyActive() {
+	var oldestPair PodPair
+	var oldestTime time.Time
+	first := true
+	for pair, lastActive := range t.lastActive {
+		if first || lastActive.Before(oldestTime) {
+			oldestPair, oldestTime = pair, lastActive
+			first = false
+		}
+	}
+	if first {
+		return
+	}
+	delete(t.lastActive, oldestPair)
+	metrics.PodToPodBytes.Delete(oldestPair.labels())
+}
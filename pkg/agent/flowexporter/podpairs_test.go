@@ -0,0 +1,73 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowexporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/metrics"
+)
+
+func init() {
+	legacyregistry.MustRegister(metrics.PodToPodBytes)
+}
+
+func podPairBytes(t *testing.T, pair PodPair) float64 {
+	value, err := testutil.GetCounterMetricValue(metrics.PodToPodBytes.With(pair.labels()))
+	assert.NoError(t, err)
+	return value
+}
+
+func TestPodPairTracker(t *testing.T) {
+	metrics.PodToPodBytes.Reset()
+	pair1 := PodPair{"ns1", "pod1", "ns2", "pod2"}
+	pair2 := PodPair{"ns1", "pod1", "ns2", "pod3"}
+	pair3 := PodPair{"ns1", "pod4", "ns2", "pod2"}
+	smallPair := PodPair{"ns1", "pod5", "ns2", "pod6"}
+	tracker := NewPodPairTracker(2)
+	start := time.Now()
+
+	tracker.Add(pair1, 2000)
+	tracker.Add(pair1, 1000)
+	tracker.Add(smallPair, 100)
+	tracker.Flush(start)
+	assert.Equal(t, float64(3000), podPairBytes(t, pair1))
+	assert.Equal(t, float64(100), podPairBytes(t, otherPodPair))
+
+	tracker.Add(pair2, 4000)
+	tracker.Flush(start.Add(time.Second))
+	assert.Equal(t, float64(4000), podPairBytes(t, pair2))
+
+	// A pair which sends less than 1 KiB during a period is not tracked, even
+	// if it already has its own series.
+	tracker.Add(pair1, 500)
+	tracker.Flush(start.Add(2 * time.Second))
+	assert.Equal(t, float64(3000), podPairBytes(t, pair1))
+	assert.Equal(t, float64(600), podPairBytes(t, otherPodPair))
+
+	// pair1 is the least recently active pair and its series is deleted when
+	// pair3 starts being tracked.
+	tracker.Add(pair3, 5000)
+	tracker.Flush(start.Add(3 * time.Second))
+	assert.Equal(t, float64(5000), podPairBytes(t, pair3))
+	assert.Equal(t, float64(4000), podPairBytes(t, pair2))
+	assert.Equal(t, float64(0), podPairBytes(t, pair1))
+	assert.Len(t, tracker.lastActive, 2)
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowexporter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultSamplingRate exports all the flows.
+const DefaultSamplingRate = "1:1"
+
+// ParseSamplingRate parses a sampling rate of the form "1:N", meaning that 1
+// in every N flows is exported, and returns N.
+func ParseSamplingRate(rate string) (uint32, error) {
+	parts := strings.Split(rate, ":")
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) != "1" {
+		return 0, fmt.Errorf("invalid flow sampling rate %q, it must be of the form 1:N", rate)
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 32)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid flow sampling rate %q, N must be a positive integer", rate)
+	}
+	return uint32(n), nil
+}
+
+// Sampler selects 1 in every N connections based on a hash of their 5-tuple.
+// The hash doesn't depend on the direction of the 5-tuple, so that both the
+// forward and the reverse flow of a connection are either sampled or dropped.
+type Sampler struct {
+	// n is accessed atomically as it can be changed while the connections
+	// are sampled.
+	n    uint32
+	seed uint32
+}
+
+// NewSampler creates a Sampler which selects 1 in every n connections. The seed
+// changes the connections which are selected, so that different clusters can
+// sample different connections.
+func NewSampler(n uint32, seed uint32) *Sampler {
+	return &Sampler{n: n, seed: seed}
+}
+
+// SetRate changes the Sampler to select 1 in every n connections. It is safe
+// to call it while connections are sampled.
+func (s *Sampler) SetRate(n uint32) {
+	atomic.StoreUint32(&s.n, n)
+}
+
+// Rate returns N, the Sampler selecting 1 in every N connections.
+func (s *Sampler) Rate() uint32 {
+	return atomic.LoadUint32(&s.n)
+}
+
+// Sample returns true if the connection must be exported.
+func (s *Sampler) Sample(conn *Connection) bool {
+	n := s.Rate()
+	if n <= 1 {
+		return true
+	}
+	return s.hash(&conn.TupleOrig)%n == 0
+}
+
+func (s *Sampler) hash(tuple *Tuple) uint32 {
+	srcIP, dstIP := tuple.SourceAddress.To16(), tuple.DestinationAddress.To16()
+	srcPort, dstPort := tuple.SourcePort, tuple.DestinationPort
+	// Order the endpoints so that the hash of the reverse tuple is the same.
+	if c := bytes.Compare(srcIP, dstIP); c > 0 || (c == 0 && srcPort > dstPort) {
+		srcIP, dstIP = dstIP, srcIP
+		srcPort, dstPort = dstPort, srcPort
+	}
+	h := fnv.New32a()
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, s.seed)
+	h.Write(buf)
+	h.Write(srcIP)
+	h.Write(dstIP)
+	binary.BigEndian.PutUint16(buf, srcPort)
+	h.Write(buf[:2])
+	binary.BigEndian.PutUint16(buf, dstPort)
+	h.Write(buf[:2])
+	h.Write([]byte{tuple.Protocol})
+	// The low bits of FNV-1a are poorly mixed, which would bias small sampling
+	// rates, so the hash is finalized like MurmurHash3.
+	sum := h.Sum32()
+	sum ^= sum >> 16
+	sum *= 0x85ebca6b
+	sum ^= sum >> 13
+	sum *= 0xc2b2ae35
+	sum ^= sum >> 16
+	return sum
+}
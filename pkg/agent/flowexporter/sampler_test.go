@@ -0,0 +1,142 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowexporter
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSamplingRate(t *testing.T) {
+	tests := []struct {
+		rate      string
+		expectedN uint32
+		expectErr bool
+	}{
+		{rate: "1:1", expectedN: 1},
+		{rate: "1:100", expectedN: 100},
+		{rate: "1: 10", expectedN: 10},
+		{rate: "2:10", expectErr: true},
+		{rate: "1:0", expectErr: true},
+		{rate: "1:-1", expectErr: true},
+		{rate: "10", expectErr: true},
+		{rate: "1:10:1", expectErr: true},
+	}
+	for _, tt := range tests {
+		n, err := ParseSamplingRate(tt.rate)
+		if tt.expectErr {
+			assert.Error(t, err, "rate %s", tt.rate)
+		} else {
+			require.NoError(t, err, "rate %s", tt.rate)
+			assert.Equal(t, tt.expectedN, n)
+		}
+	}
+}
+
+func newTestConnection(srcIP, dstIP net.IP, srcPort, dstPort uint16) *Connection {
+	return &Connection{
+		TupleOrig: Tuple{
+			SourceAddress:      srcIP,
+			DestinationAddress: dstIP,
+			Protocol:           6,
+			SourcePort:         srcPort,
+			DestinationPort:    dstPort,
+		},
+	}
+}
+
+func TestSamplerDeterministic(t *testing.T) {
+	sampler := NewSampler(4, 0)
+	otherSampler := NewSampler(4, 0)
+	for i := 0; i < 1000; i++ {
+		srcIP := net.IP{10, 10, byte(i >> 8), byte(i)}
+		dstIP := net.IP{10, 20, 0, 1}
+		srcPort := uint16(30000 + i)
+		conn := newTestConnection(srcIP, dstIP, srcPort, 80)
+		reverseConn := newTestConnection(dstIP, srcIP, 80, srcPort)
+		sampled := sampler.Sample(conn)
+		assert.Equal(t, sampled, sampler.Sample(conn))
+		assert.Equal(t, sampled, otherSampler.Sample(conn))
+		assert.Equal(t, sampled, sampler.Sample(reverseConn), "Forward and reverse flows must be sampled consistently")
+	}
+}
+
+func TestSamplerAllFlows(t *testing.T) {
+	sampler := NewSampler(1, 1234)
+	for i := 0; i < 100; i++ {
+		assert.True(t, sampler.Sample(newTestConnection(net.IP{10, 10, 0, byte(i)}, net.IP{10, 20, 0, 1}, 30000, 80)))
+	}
+}
+
+func TestSamplerRatio(t *testing.T) {
+	const flows = 100000
+	for _, n := range []uint32{2, 10, 100} {
+		for _, seed := range []uint32{0, 42} {
+			sampler := NewSampler(n, seed)
+			sampled := 0
+			for i := 0; i < flows; i++ {
+				srcIP := net.IP{10, byte(i >> 16), byte(i >> 8), byte(i)}
+				if sampler.Sample(newTestConnection(srcIP, net.IP{10, 96, 0, 1}, uint16(32768+i%1000), 443)) {
+					sampled++
+				}
+			}
+			expected := float64(flows) / float64(n)
+			assert.InDelta(t, expected, float64(sampled), expected*0.1, "Sampling rate 1:%d with seed %d", n, seed)
+		}
+	}
+}
+
+func TestSamplerSeed(t *testing.T) {
+	sampler1 := NewSampler(10, 1)
+	sampler2 := NewSampler(10, 2)
+	different := false
+	for i := 0; i < 1000 && !different; i++ {
+		conn := newTestConnection(net.IP{10, 10, byte(i >> 8), byte(i)}, net.IP{10, 20, 0, 1}, 30000, 80)
+		different = sampler1.Sample(conn) != sampler2.Sample(conn)
+	}
+	assert.True(t, different, "Samplers with different seeds should sample different flows")
+}
+
+func TestSamplerSetRate(t *testing.T) {
+	sampler := NewSampler(1, 0)
+	conns := make([]*Connection, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		conns = append(conns, newTestConnection(net.IP{10, 10, byte(i >> 8), byte(i)}, net.IP{10, 20, 0, 1}, 30000, 80))
+	}
+	countSampled := func() int {
+		sampled := 0
+		for _, conn := range conns {
+			if sampler.Sample(conn) {
+				sampled++
+			}
+		}
+		return sampled
+	}
+	assert.Equal(t, len(conns), countSampled())
+
+	sampler.SetRate(10)
+	assert.Equal(t, uint32(10), sampler.Rate())
+	expected := NewSampler(10, 0)
+	for _, conn := range conns {
+		assert.Equal(t, expected.Sample(conn), sampler.Sample(conn))
+	}
+	assert.Less(t, countSampled(), len(conns))
+
+	sampler.SetRate(1)
+	assert.Equal(t, len(conns), countSampled())
+}
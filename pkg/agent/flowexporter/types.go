@@ -51,4 +51,23 @@ type Connection struct {
 	SourcePodName           string
 	DestinationPodNamespace string
 	DestinationPodName      string
+	// IsSourcePodLocal is true if the source of the connection is a Pod running on this Node.
+	IsSourcePodLocal bool
+	// Fields from the Kubernetes metadata of the cluster
+	SourceWorkloadKind          string
+	DestinationServiceNamespace string
+	DestinationServiceName      string
+	// Openflow IDs of the ingress and egress NetworkPolicy rules which allowed the connection, read
+	// from the conntrack label. They are 0 if no rule applied to the connection.
+	IngressRuleFlowID uint32
+	EgressRuleFlowID  uint32
+	// Fields of the NetworkPolicy rules which allowed the connection
+	IngressNetworkPolicyName         string
+	IngressNetworkPolicyNamespace    string
+	IngressNetworkPolicyType         string
+	IngressNetworkPolicyRulePriority int32
+	EgressNetworkPolicyName          string
+	EgressNetworkPolicyNamespace     string
+	EgressNetworkPolicyType          string
+	EgressNetworkPolicyRulePriority  int32
 }
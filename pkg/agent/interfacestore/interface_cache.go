@@ -50,6 +50,8 @@ const (
 //     configurations.
 //  3) For tunnel port, the fields include: name and tunnel type; and for an IPSec tunnel,
 //     additionally: remoteIP, PSK and remote Node name.
+//  4) For secondary network interface, the fields include the ones of container interface,
+//     and additionally: name of the interface in the container and VLAN.
 // OVS Port configurations include PortUUID and OFPort.
 // Container interface is added into cache after invocation of cniserver.CmdAdd, and removed
 // from cache after invocation of cniserver.CmdDel. For cniserver.CmdCheck, the server would
@@ -249,6 +251,11 @@ func interfaceIPIndexFunc(obj interface{}) ([]string, error) {
 		// If interfaceConfig IP is not set, we return empty key.
 		return []string{}, nil
 	}
+	if interfaceConfig.Type == SecondaryInterface {
+		// The IPs of the secondary network interfaces are not in the Pod
+		// network, they must not be mistaken for Pod IPs.
+		return []string{}, nil
+	}
 	return []string{interfaceConfig.IP.String()}, nil
 }
 
@@ -30,6 +30,8 @@ const (
 	TunnelInterface
 	// UplinkInterface is used to mark current interface is for uplink port
 	UplinkInterface
+	// SecondaryInterface is used to mark current interface is for a secondary network interface of a container
+	SecondaryInterface
 )
 
 type InterfaceType uint8
@@ -49,6 +51,15 @@ type ContainerInterfaceConfig struct {
 	PodNamespace string
 }
 
+// SecondaryInterfaceConfig is the configuration specific to a secondary network
+// interface of a container, which is connected to a VLAN instead of the Pod
+// network.
+type SecondaryInterfaceConfig struct {
+	// Name of the interface in the container network namespace.
+	IfName string
+	VLAN   uint16
+}
+
 type TunnelInterfaceConfig struct {
 	Type ovsconfig.TunnelType
 	// Name of the remote Node.
@@ -68,6 +79,7 @@ type InterfaceConfig struct {
 	MAC           net.HardwareAddr
 	*OVSPortConfig
 	*ContainerInterfaceConfig
+	*SecondaryInterfaceConfig
 	*TunnelInterfaceConfig
 }
 
@@ -109,6 +121,30 @@ func NewContainerInterface(
 		ContainerInterfaceConfig: containerConfig}
 }
 
+// NewSecondaryInterface creates InterfaceConfig for a secondary network
+// interface of a Pod.
+func NewSecondaryInterface(
+	interfaceName string,
+	containerID string,
+	podName string,
+	podNamespace string,
+	ifName string,
+	vlan uint16,
+	mac net.HardwareAddr,
+	ip net.IP) *InterfaceConfig {
+	containerConfig := &ContainerInterfaceConfig{
+		ContainerID:  containerID,
+		PodName:      podName,
+		PodNamespace: podNamespace}
+	return &InterfaceConfig{
+		InterfaceName:            interfaceName,
+		Type:                     SecondaryInterface,
+		IP:                       ip,
+		MAC:                      mac,
+		ContainerInterfaceConfig: containerConfig,
+		SecondaryInterfaceConfig: &SecondaryInterfaceConfig{IfName: ifName, VLAN: vlan}}
+}
+
 // NewGatewayInterface creates InterfaceConfig for the host gateway interface.
 func NewGatewayInterface(gatewayName string) *InterfaceConfig {
 	gatewayConfig := &InterfaceConfig{InterfaceName: gatewayName, Type: GatewayInterface}
@@ -0,0 +1,772 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ippool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/types/current"
+	"github.com/containernetworking/plugins/pkg/ip"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/cniserver/ipam"
+	"github.com/vmware-tanzu/antrea/pkg/agent/metrics"
+	corev1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/core/v1alpha1"
+	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/scheme"
+	crdinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions/core/v1alpha1"
+	crdlisters "github.com/vmware-tanzu/antrea/pkg/client/listers/core/v1alpha1"
+	utilippool "github.com/vmware-tanzu/antrea/pkg/util/ippool"
+)
+
+const (
+	componentName = "antrea-agent"
+
+	// reasonPoolExhausted is the reason of the PoolExhausted condition and of
+	// the event emitted when an IPPool is exhausted.
+	reasonPoolExhausted = "PoolExhausted"
+	reasonIPReleased    = "IPReleased"
+
+	// StatePath is the file in which the Allocator persists the IPs allocated
+	// to the Pods of the Node. It must not be under /var/run, which does not
+	// survive Node reboots.
+	StatePath = "/var/lib/antrea/ipam-state.json"
+)
+
+var errCachesNotSynced = errors.New("IPPool caches are not synced yet")
+
+// Allocator allocates the IPs of the Pods running on the Node from the IPPools
+// selecting the Node and the Namespaces of the Pods. The allocations are
+// recorded in the status of the IPPools, so that the Nodes sharing an IPPool do
+// not allocate the same IPs: an allocation fails if the IPPool has been updated
+// concurrently, and is retried.
+type Allocator struct {
+	nodeName        string
+	k8sClient       kubernetes.Interface
+	crdClient       versioned.Interface
+	ipPoolLister    crdlisters.IPPoolLister
+	namespaceLister corelisters.NamespaceLister
+	nodeLister      corelisters.NodeLister
+	listersSynced   []cache.InformerSynced
+	recorder        record.EventRecorder
+	// mutex serializes the allocations and releases of the Node, and protects
+	// allocatedPools.
+	mutex sync.Mutex
+	// allocatedPools maps the keys of the interfaces of the containers to the
+	// names of the IPPools their IPs were allocated from.
+	allocatedPools map[string]string
+	// statePath is the file in which podAllocations is persisted. The state
+	// is not persisted if it is empty.
+	statePath string
+	// podAllocations maps the keys of the interfaces of the Pods to the last
+	// IPs allocated to them. An entry is kept after the IP is released, so
+	// that the same IP is allocated to the new sandbox of the Pod, e.g. after
+	// the Node reboots, and is removed once the Pod no longer exists.
+	podAllocations map[string]*podAllocation
+}
+
+// podAllocation is the last IP allocated to an interface of a Pod, as persisted
+// in the state file.
+type podAllocation struct {
+	Pool        string `json:"pool"`
+	IP          string `json:"ip"`
+	Namespace   string `json:"namespace"`
+	Pod         string `json:"pod"`
+	Interface   string `json:"interface,omitempty"`
+	ContainerID string `json:"containerID"`
+}
+
+var _ ipam.IPPoolAllocator = new(Allocator)
+var _ ipam.SecondaryIPAllocator = new(Allocator)
+
+// NewAllocator creates a new Allocator for the Node, and restores the IPs
+// allocated to the Pods from statePath. The Allocator also keeps the IPAM
+// metrics of the IPPools matching the Node up to date.
+func NewAllocator(
+	nodeName string,
+	k8sClient kubernetes.Interface,
+	crdClient versioned.Interface,
+	ipPoolInformer crdinformers.IPPoolInformer,
+	namespaceInformer coreinformers.NamespaceInformer,
+	nodeInformer coreinformers.NodeInformer,
+	statePath string) *Allocator {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sClient.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: componentName, Host: nodeName})
+	a := &Allocator{
+		nodeName:        nodeName,
+		k8sClient:       k8sClient,
+		crdClient:       crdClient,
+		ipPoolLister:    ipPoolInformer.Lister(),
+		namespaceLister: namespaceInformer.Lister(),
+		nodeLister:      nodeInformer.Lister(),
+		listersSynced: []cache.InformerSynced{
+			ipPoolInformer.Informer().HasSynced,
+			namespaceInformer.Informer().HasSynced,
+			nodeInformer.Informer().HasSynced,
+		},
+		recorder:       recorder,
+		allocatedPools: map[string]string{},
+		statePath:      statePath,
+		podAllocations: map[string]*podAllocation{},
+	}
+	if err := a.restoreState(); err != nil {
+		klog.Warningf("Failed to restore IPAM state from %s: %v", statePath, err)
+	}
+	ipPoolInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: a.updateMetrics,
+		UpdateFunc: func(_, curObj interface{}) {
+			a.updateMetrics(curObj)
+		},
+		DeleteFunc: a.deleteMetrics,
+	})
+	// The IPPools matching the Node change with its labels.
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: a.updateNodeMetrics,
+		UpdateFunc: func(_, curObj interface{}) {
+			a.updateNodeMetrics(curObj)
+		},
+	})
+	return a
+}
+
+func (a *Allocator) listersHaveSynced() bool {
+	for _, synced := range a.listersSynced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+func selectorMatches(selector *metav1.LabelSelector, objLabels map[string]string) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return s.Matches(labels.Set(objLabels)), nil
+}
+
+// matchIPPool returns the valid IPPool selecting the Node and the Namespace, or
+// nil if there is none. If several IPPools match, the one whose name comes
+// first is returned.
+func (a *Allocator) matchIPPool(namespace string) (*corev1alpha1.IPPool, error) {
+	node, err := a.nodeLister.Get(a.nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("error when getting Node %s: %v", a.nodeName, err)
+	}
+	ns, err := a.namespaceLister.Get(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error when getting Namespace %s: %v", namespace, err)
+	}
+	pools, err := a.ipPoolLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].Name < pools[j].Name
+	})
+	for _, pool := range pools {
+		if !utilippool.IsValid(pool) {
+			continue
+		}
+		nodeMatches, err := selectorMatches(pool.Spec.NodeSelector, node.Labels)
+		if err != nil {
+			klog.Errorf("Invalid Node selector of IPPool %s: %v", pool.Name, err)
+			continue
+		}
+		namespaceMatches, err := selectorMatches(pool.Spec.NamespaceSelector, ns.Labels)
+		if err != nil {
+			klog.Errorf("Invalid Namespace selector of IPPool %s: %v", pool.Name, err)
+			continue
+		}
+		if nodeMatches && namespaceMatches {
+			return pool, nil
+		}
+	}
+	return nil, nil
+}
+
+// updateMetrics updates the IPAM metrics of the IPPool. The metrics are only
+// exposed for the valid IPPools matching the Node, and account for the IPs
+// allocated by all the Nodes sharing the IPPool.
+func (a *Allocator) updateMetrics(obj interface{}) {
+	pool := obj.(*corev1alpha1.IPPool)
+	node, err := a.nodeLister.Get(a.nodeName)
+	if err != nil {
+		// The metrics are updated once the Node is added to the cache.
+		klog.V(2).Infof("Failed to get Node %s when updating the metrics of IPPool %s: %v", a.nodeName, pool.Name, err)
+		return
+	}
+	nodeMatches, err := selectorMatches(pool.Spec.NodeSelector, node.Labels)
+	if err != nil || !nodeMatches || !utilippool.IsValid(pool) {
+		a.deleteMetrics(pool)
+		return
+	}
+	ranges, err := utilippool.ParseRanges(pool)
+	if err != nil {
+		a.deleteMetrics(pool)
+		return
+	}
+	total := utilippool.Capacity(ranges)
+	used := float64(len(pool.Status.Allocations))
+	metrics.IPAMTotalAddresses.WithLabelValues(pool.Name, a.nodeName).Set(total)
+	metrics.IPAMUsedAddresses.WithLabelValues(pool.Name, a.nodeName).Set(used)
+	metrics.IPAMAvailableAddresses.WithLabelValues(pool.Name, a.nodeName).Set(total - used)
+}
+
+// updateNodeMetrics updates the IPAM metrics of all the IPPools when the Node is
+// added or its labels are updated.
+func (a *Allocator) updateNodeMetrics(obj interface{}) {
+	if obj.(*corev1.Node).Name != a.nodeName {
+		return
+	}
+	pools, _ := a.ipPoolLister.List(labels.Everything())
+	for _, pool := range pools {
+		a.updateMetrics(pool)
+	}
+}
+
+// deleteMetrics deletes the IPAM metrics of the IPPool.
+func (a *Allocator) deleteMetrics(obj interface{}) {
+	pool, ok := obj.(*corev1alpha1.IPPool)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			klog.Errorf("Error decoding object when deleting IPPool, invalid type: %v", obj)
+			return
+		}
+		pool, ok = tombstone.Obj.(*corev1alpha1.IPPool)
+		if !ok {
+			klog.Errorf("Error decoding object tombstone when deleting IPPool, invalid type: %v", tombstone.Obj)
+			return
+		}
+	}
+	metricLabels := map[string]string{"pool": pool.Name, "node": a.nodeName}
+	metrics.IPAMTotalAddresses.Delete(metricLabels)
+	metrics.IPAMUsedAddresses.Delete(metricLabels)
+	metrics.IPAMAvailableAddresses.Delete(metricLabels)
+}
+
+// getStaticIP returns the IP requested by the static IP annotation of the Pod,
+// or nil if the Pod has no such annotation.
+func (a *Allocator) getStaticIP(podNamespace, podName string) (net.IP, error) {
+	pod, err := a.k8sClient.CoreV1().Pods(podNamespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error when getting Pod %s/%s: %v", podNamespace, podName, err)
+	}
+	value, ok := pod.Annotations[utilippool.StaticIPAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+	staticIP := net.ParseIP(value)
+	if staticIP == nil {
+		return nil, fmt.Errorf("invalid static IP %s of Pod %s/%s", value, podNamespace, podName)
+	}
+	return staticIP, nil
+}
+
+// nextFreeIP returns the first IP of the ranges which is not allocated, and the
+// range it belongs to. The network IPs, the last IPs and the gateways of the
+// ranges are never allocated.
+func nextFreeIP(ranges []utilippool.Range, allocated map[string]bool) (net.IP, *utilippool.Range) {
+	for i := range ranges {
+		r := &ranges[i]
+		for candidate := ip.NextIP(r.CIDR.IP); r.CIDR.Contains(candidate); candidate = ip.NextIP(candidate) {
+			if utilippool.IsAllocatable(r, candidate) && !allocated[candidate.String()] {
+				return candidate, r
+			}
+		}
+	}
+	return nil, nil
+}
+
+// reserveStaticIP records the allocation of a static IP, or of the IP previously
+// allocated to the Pod, in the status of the IPPool. The IP may still be allocated to a previous container of the same Pod,
+// e.g. when the Pod has been recreated before the IP of its previous sandbox was
+// released, in which case the allocation is transferred to the new container.
+func reserveStaticIP(status *corev1alpha1.IPPoolStatus, allocation corev1alpha1.IPAllocation) error {
+	for i, existing := range status.Allocations {
+		if existing.IP != allocation.IP {
+			continue
+		}
+		if existing.Namespace != allocation.Namespace || existing.Pod != allocation.Pod || existing.Interface != allocation.Interface {
+			return fmt.Errorf("static IP %s is already allocated to Pod %s/%s", allocation.IP, existing.Namespace, existing.Pod)
+		}
+		status.Allocations[i] = allocation
+		return nil
+	}
+	status.Allocations = append(status.Allocations, allocation)
+	return nil
+}
+
+func findRange(ranges []utilippool.Range, allocatedIP net.IP) *utilippool.Range {
+	for i := range ranges {
+		if ranges[i].CIDR.Contains(allocatedIP) {
+			return &ranges[i]
+		}
+	}
+	return nil
+}
+
+func newResult(allocatedIP net.IP, r *utilippool.Range) *current.Result {
+	version, defaultDst := "4", &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)}
+	if allocatedIP.To4() == nil {
+		version, defaultDst = "6", &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+	}
+	return &current.Result{
+		IPs: []*current.IPConfig{{
+			Version: version,
+			Address: net.IPNet{IP: allocatedIP, Mask: r.CIDR.Mask},
+			Gateway: r.Gateway,
+		}},
+		Routes: []*cnitypes.Route{{Dst: *defaultDst, GW: r.Gateway}},
+	}
+}
+
+// AllocateIP allocates an IP to the Pod from the IPPool matching it. If an IP
+// has already been allocated to the container, the same IP is returned. If the
+// Pod requests a static IP with the ipam.antrea.io/static-ip annotation, the IP
+// is allocated if it is in the ranges of the IPPool and is not allocated to
+// another Pod. When the IPPool is exhausted, its PoolExhausted condition is set
+// and a Warning event is emitted.
+func (a *Allocator) AllocateIP(podNamespace, podName, containerID string) (*current.Result, error) {
+	if !a.listersHaveSynced() {
+		return nil, errCachesNotSynced
+	}
+	pool, err := a.matchIPPool(podNamespace)
+	if err != nil {
+		return nil, err
+	}
+	staticIP, err := a.getStaticIP(podNamespace, podName)
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		if staticIP != nil {
+			return nil, fmt.Errorf("no IPPool matches Pod %s/%s to allocate static IP %s from", podNamespace, podName, staticIP.String())
+		}
+		return nil, nil
+	}
+	return a.allocate(pool.Name, podNamespace, podName, containerID, "", staticIP)
+}
+
+// AllocateIPFromPool allocates an IP to the secondary network interface ifName
+// of the Pod from the IPPool poolName, which does not need to select the Pod. If
+// an IP has already been allocated to the interface, the same IP is returned.
+// The result has no routes, as the secondary network is not the default network
+// of the Pod.
+func (a *Allocator) AllocateIPFromPool(poolName, podNamespace, podName, containerID, ifName string) (*current.Result, error) {
+	if !a.listersHaveSynced() {
+		return nil, errCachesNotSynced
+	}
+	pool, err := a.ipPoolLister.Get(poolName)
+	if err != nil {
+		return nil, fmt.Errorf("error when getting IPPool %s: %v", poolName, err)
+	}
+	if !utilippool.IsValid(pool) {
+		return nil, fmt.Errorf("IPPool %s is not valid", poolName)
+	}
+	result, err := a.allocate(poolName, podNamespace, podName, containerID, ifName, nil)
+	if err != nil {
+		return nil, err
+	}
+	result.Routes = nil
+	return result, nil
+}
+
+// allocationKey returns the key of the interface ifName of the container in
+// allocatedPools. The primary interface, whose ifName is empty, is keyed by the
+// ID of the container.
+func allocationKey(containerID, ifName string) string {
+	if ifName == "" {
+		return containerID
+	}
+	return containerID + "/" + ifName
+}
+
+// allocate allocates an IP to the interface ifName of the container from the
+// IPPool, or the static IP if it is not nil.
+func (a *Allocator) allocate(name, podNamespace, podName, containerID, ifName string, staticIP net.IP) (*current.Result, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	var pool *corev1alpha1.IPPool
+	var allocatedIP net.IP
+	var allocatedRange *utilippool.Range
+	exhausted := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		allocatedIP, allocatedRange, exhausted = nil, nil, false
+		var err error
+		pool, err = a.crdClient.CoreV1alpha1().IPPools().Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		ranges, err := utilippool.ParseRanges(pool)
+		if err != nil {
+			return err
+		}
+		allocated := make(map[string]bool, len(pool.Status.Allocations))
+		for _, allocation := range pool.Status.Allocations {
+			if allocation.ContainerID == containerID && allocation.Interface == ifName {
+				allocatedIP = net.ParseIP(allocation.IP)
+				if allocatedRange = findRange(ranges, allocatedIP); allocatedRange != nil {
+					return nil
+				}
+			}
+			allocated[allocation.IP] = true
+		}
+		// A Pod without static IP is allocated the IP it was allocated before
+		// again, e.g. before the Node rebooted, if it is still free.
+		requestedIP := staticIP
+		if requestedIP == nil {
+			requestedIP = a.previousIP(name, podNamespace, podName, ifName, ranges, &pool.Status)
+		}
+		if requestedIP != nil {
+			allocatedIP = requestedIP
+			if allocatedRange = findRange(ranges, requestedIP); allocatedRange == nil || !utilippool.IsAllocatable(allocatedRange, requestedIP) {
+				return fmt.Errorf("static IP %s cannot be allocated from the ranges of the IPPool", requestedIP.String())
+			}
+			if err := reserveStaticIP(&pool.Status, corev1alpha1.IPAllocation{
+				IP:          requestedIP.String(),
+				Namespace:   podNamespace,
+				Pod:         podName,
+				ContainerID: containerID,
+				Interface:   ifName,
+				Node:        a.nodeName,
+			}); err != nil {
+				return err
+			}
+			_, err = a.crdClient.CoreV1alpha1().IPPools().Update(context.TODO(), pool, metav1.UpdateOptions{})
+			return err
+		}
+		exhaustedCondition := corev1alpha1.IPPoolCondition{
+			Type:    corev1alpha1.IPPoolConditionPoolExhausted,
+			Status:  corev1.ConditionTrue,
+			Reason:  reasonPoolExhausted,
+			Message: "All the IPs of the IPPool have been allocated",
+		}
+		allocatedIP, allocatedRange = nextFreeIP(ranges, allocated)
+		if allocatedIP == nil {
+			exhausted = true
+			if !utilippool.SetCondition(&pool.Status, exhaustedCondition) {
+				return nil
+			}
+		} else {
+			pool.Status.Allocations = append(pool.Status.Allocations, corev1alpha1.IPAllocation{
+				IP:          allocatedIP.String(),
+				Namespace:   podNamespace,
+				Pod:         podName,
+				ContainerID: containerID,
+				Interface:   ifName,
+				Node:        a.nodeName,
+			})
+			// The IPPool is not exhausted anymore if its ranges have been
+			// extended.
+			if utilippool.GetCondition(&pool.Status, corev1alpha1.IPPoolConditionPoolExhausted) != nil {
+				exhaustedCondition.Status = corev1.ConditionFalse
+				exhaustedCondition.Message = ""
+				utilippool.SetCondition(&pool.Status, exhaustedCondition)
+			}
+		}
+		_, err = a.crdClient.CoreV1alpha1().IPPools().Update(context.TODO(), pool, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when allocating IP from IPPool %s: %v", name, err)
+	}
+	if exhausted {
+		a.recorder.Eventf(pool, corev1.EventTypeWarning, reasonPoolExhausted, "Failed to allocate an IP to Pod %s/%s: all the IPs of the IPPool have been allocated", podNamespace, podName)
+		return nil, fmt.Errorf("IPPool %s is exhausted", name)
+	}
+	a.allocatedPools[allocationKey(containerID, ifName)] = name
+	a.podAllocations[podAllocationKey(podNamespace, podName, ifName)] = &podAllocation{
+		Pool:        name,
+		IP:          allocatedIP.String(),
+		Namespace:   podNamespace,
+		Pod:         podName,
+		Interface:   ifName,
+		ContainerID: containerID,
+	}
+	if err := a.persistState(); err != nil {
+		klog.Errorf("Failed to persist IPAM state to %s: %v", a.statePath, err)
+	}
+	klog.Infof("Allocated IP %s from IPPool %s to Pod %s/%s", allocatedIP.String(), name, podNamespace, podName)
+	return newResult(allocatedIP, allocatedRange), nil
+}
+
+// getAllocatedPool returns the name of the IPPool the IP of the interface ifName
+// of the container was allocated from, or an empty string if there is none. The
+// allocations which were made before the agent restarted are found from the
+// IPPool cache.
+func (a *Allocator) getAllocatedPool(containerID, ifName string) string {
+	if name, ok := a.allocatedPools[allocationKey(containerID, ifName)]; ok {
+		return name
+	}
+	pools, _ := a.ipPoolLister.List(labels.Everything())
+	for _, pool := range pools {
+		for _, allocation := range pool.Status.Allocations {
+			if allocation.ContainerID == containerID && allocation.Interface == ifName {
+				return pool.Name
+			}
+		}
+	}
+	return ""
+}
+
+// ReleaseIP releases the IP allocated to the container, and clears the
+// PoolExhausted condition of its IPPool.
+func (a *Allocator) ReleaseIP(containerID string) (bool, error) {
+	return a.release(containerID, "")
+}
+
+// ReleaseIPFromPool releases the IP allocated to the secondary network interface
+// ifName of the container. It does nothing if no IP was allocated to it.
+func (a *Allocator) ReleaseIPFromPool(containerID, ifName string) error {
+	_, err := a.release(containerID, ifName)
+	return err
+}
+
+func (a *Allocator) release(containerID, ifName string) (bool, error) {
+	if !a.listersHaveSynced() {
+		return false, errCachesNotSynced
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.releaseLocked(containerID, ifName)
+}
+
+// releaseLocked releases the IP allocated to the interface ifName of the
+// container. The caller must hold mutex.
+func (a *Allocator) releaseLocked(containerID, ifName string) (bool, error) {
+	name := a.getAllocatedPool(containerID, ifName)
+	if name == "" {
+		return false, nil
+	}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pool, err := a.crdClient.CoreV1alpha1().IPPools().Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			// The IPs of a deleted IPPool do not need to be released.
+			if k8serrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		allocations := make([]corev1alpha1.IPAllocation, 0, len(pool.Status.Allocations))
+		for _, allocation := range pool.Status.Allocations {
+			if allocation.ContainerID != containerID || allocation.Interface != ifName {
+				allocations = append(allocations, allocation)
+			}
+		}
+		if len(allocations) == len(pool.Status.Allocations) {
+			return nil
+		}
+		pool.Status.Allocations = allocations
+		if condition := utilippool.GetCondition(&pool.Status, corev1alpha1.IPPoolConditionPoolExhausted); condition != nil {
+			utilippool.SetCondition(&pool.Status, corev1alpha1.IPPoolCondition{
+				Type:   corev1alpha1.IPPoolConditionPoolExhausted,
+				Status: corev1.ConditionFalse,
+				Reason: reasonIPReleased,
+			})
+		}
+		_, err = a.crdClient.CoreV1alpha1().IPPools().Update(context.TODO(), pool, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return true, fmt.Errorf("error when releasing IP from IPPool %s: %v", name, err)
+	}
+	delete(a.allocatedPools, allocationKey(containerID, ifName))
+	if ifName != "" {
+		klog.Infof("Released IP of interface %s of container %s to IPPool %s", ifName, containerID, name)
+	} else {
+		klog.Infof("Released IP of container %s to IPPool %s", containerID, name)
+	}
+	return true, nil
+}
+
+// HasIP returns whether an IP was allocated to the container from an IPPool.
+func (a *Allocator) HasIP(containerID string) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.getAllocatedPool(containerID, "") != ""
+}
+
+func podAllocationKey(podNamespace, podName, ifName string) string {
+	return podNamespace + "/" + podName + "/" + ifName
+}
+
+// previousIP returns the IP last allocated to the interface ifName of the Pod
+// from the IPPool, if it can be allocated to it again, i.e. it is still in the
+// ranges of the IPPool and is not allocated to another Pod.
+func (a *Allocator) previousIP(poolName, podNamespace, podName, ifName string, ranges []utilippool.Range, status *corev1alpha1.IPPoolStatus) net.IP {
+	previous, ok := a.podAllocations[podAllocationKey(podNamespace, podName, ifName)]
+	if !ok || previous.Pool != poolName {
+		return nil
+	}
+	previousIP := net.ParseIP(previous.IP)
+	if previousIP == nil {
+		return nil
+	}
+	if r := findRange(ranges, previousIP); r == nil || !utilippool.IsAllocatable(r, previousIP) {
+		return nil
+	}
+	for _, allocation := range status.Allocations {
+		if allocation.IP == previous.IP && (allocation.Namespace != podNamespace || allocation.Pod != podName || allocation.Interface != ifName) {
+			return nil
+		}
+	}
+	return previousIP
+}
+
+// restoreState loads the IPs allocated to the Pods from the state file.
+func (a *Allocator) restoreState() error {
+	if a.statePath == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(a.statePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var allocations []podAllocation
+	if err := json.Unmarshal(data, &allocations); err != nil {
+		return err
+	}
+	for i := range allocations {
+		allocation := &allocations[i]
+		a.podAllocations[podAllocationKey(allocation.Namespace, allocation.Pod, allocation.Interface)] = allocation
+	}
+	klog.Infof("Restored IPs of %d Pod interfaces from %s", len(allocations), a.statePath)
+	return nil
+}
+
+// persistState writes the IPs allocated to the Pods to the state file. The
+// caller must hold mutex.
+func (a *Allocator) persistState() error {
+	if a.statePath == "" {
+		return nil
+	}
+	allocations := make([]podAllocation, 0, len(a.podAllocations))
+	for _, allocation := range a.podAllocations {
+		allocations = append(allocations, *allocation)
+	}
+	sort.Slice(allocations, func(i, j int) bool {
+		return podAllocationKey(allocations[i].Namespace, allocations[i].Pod, allocations[i].Interface) <
+			podAllocationKey(allocations[j].Namespace, allocations[j].Pod, allocations[j].Interface)
+	})
+	data, err := json.Marshal(allocations)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.statePath), 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that an agent crash cannot leave
+	// a truncated state file behind.
+	tmpPath := a.statePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, a.statePath)
+}
+
+// Run reconciles the IPs allocated by the Node with the Pods running on it once
+// the caches have synced.
+func (a *Allocator) Run(stopCh <-chan struct{}) {
+	if !cache.WaitForNamedCacheSync("IPPoolAllocator", stopCh, a.listersSynced...) {
+		return
+	}
+	if err := a.reconcileAllocations(); err != nil {
+		klog.Errorf("Failed to reconcile IP allocations with the Pods of the Node: %v", err)
+	}
+}
+
+// reconcileAllocations releases the IPs allocated by the Node to the Pods which
+// no longer exist, e.g. because they were deleted while the Node was down, and
+// forgets the IPs previously allocated to them. The Pods are listed while
+// holding mutex, so that all the allocations seen afterwards belong to Pods
+// which existed when they were listed.
+func (a *Allocator) reconcileAllocations() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	pods, err := a.k8sClient.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", a.nodeName).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("error when listing the Pods of Node %s: %v", a.nodeName, err)
+	}
+	existingPods := sets.NewString()
+	for i := range pods.Items {
+		existingPods.Insert(pods.Items[i].Namespace + "/" + pods.Items[i].Name)
+	}
+
+	for key, allocation := range a.podAllocations {
+		if !existingPods.Has(allocation.Namespace + "/" + allocation.Pod) {
+			delete(a.podAllocations, key)
+		}
+	}
+	if err := a.persistState(); err != nil {
+		klog.Errorf("Failed to persist IPAM state to %s: %v", a.statePath, err)
+	}
+
+	pools, err := a.ipPoolLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	var staleAllocations []corev1alpha1.IPAllocation
+	for _, pool := range pools {
+		for _, allocation := range pool.Status.Allocations {
+			if allocation.Node == a.nodeName && !existingPods.Has(allocation.Namespace+"/"+allocation.Pod) {
+				staleAllocations = append(staleAllocations, allocation)
+			}
+		}
+	}
+	var errs []error
+	for _, allocation := range staleAllocations {
+		klog.Infof("Reclaiming IP %s allocated to Pod %s/%s which no longer exists", allocation.IP, allocation.Namespace, allocation.Pod)
+		if _, err := a.releaseLocked(allocation.ContainerID, allocation.Interface); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
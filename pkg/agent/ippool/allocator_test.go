@@ -0,0 +1,389 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ippool
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8smetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/metrics"
+	corev1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/core/v1alpha1"
+	fakeversioned "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+	crdinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions"
+	utilippool "github.com/vmware-tanzu/antrea/pkg/util/ippool"
+)
+
+const nodeName = "node1"
+
+func newIPPool(name string, valid bool, cidr string, namespaceSelector *metav1.LabelSelector) *corev1alpha1.IPPool {
+	pool := &corev1alpha1.IPPool{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: corev1alpha1.IPPoolSpec{
+			Ranges:            []corev1alpha1.IPRange{{CIDR: cidr}},
+			NodeSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"ipam": "pool"}},
+			NamespaceSelector: namespaceSelector,
+		},
+	}
+	status := corev1.ConditionFalse
+	if valid {
+		status = corev1.ConditionTrue
+	}
+	utilippool.SetCondition(&pool.Status, corev1alpha1.IPPoolCondition{Type: corev1alpha1.IPPoolConditionValid, Status: status})
+	return pool
+}
+
+func newPod(namespace, name, staticIP string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	if staticIP != "" {
+		pod.Annotations = map[string]string{utilippool.StaticIPAnnotationKey: staticIP}
+	}
+	return pod
+}
+
+func newAllocator(t *testing.T, stopCh <-chan struct{}, statePath string, pods []*corev1.Pod, pools ...*corev1alpha1.IPPool) (*Allocator, *fakeversioned.Clientset) {
+	k8sObjects := []runtime.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName, Labels: map[string]string{"ipam": "pool"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1", Labels: map[string]string{"env": "prod"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns2"}},
+	}
+	for _, pod := range pods {
+		k8sObjects = append(k8sObjects, pod)
+	}
+	k8sClient := fake.NewSimpleClientset(k8sObjects...)
+	var objects []runtime.Object
+	for _, pool := range pools {
+		objects = append(objects, pool)
+	}
+	crdClient := fakeversioned.NewSimpleClientset(objects...)
+	informerFactory := informers.NewSharedInformerFactory(k8sClient, 0)
+	crdInformerFactory := crdinformers.NewSharedInformerFactory(crdClient, 0)
+	a := NewAllocator(nodeName, k8sClient, crdClient, crdInformerFactory.Core().V1alpha1().IPPools(),
+		informerFactory.Core().V1().Namespaces(), informerFactory.Core().V1().Nodes(), statePath)
+	informerFactory.Start(stopCh)
+	crdInformerFactory.Start(stopCh)
+	informerFactory.WaitForCacheSync(stopCh)
+	crdInformerFactory.WaitForCacheSync(stopCh)
+	require.True(t, a.listersHaveSynced())
+	return a, crdClient
+}
+
+func getIPPool(t *testing.T, client *fakeversioned.Clientset, name string) *corev1alpha1.IPPool {
+	pool, err := client.CoreV1alpha1().IPPools().Get(context.TODO(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+	return pool
+}
+
+func TestAllocateIP(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	prodSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	pods := []*corev1.Pod{newPod("ns2", "pod", "")}
+	for i := 2; i <= 7; i++ {
+		pods = append(pods, newPod("ns1", fmt.Sprintf("pod%d", i), ""))
+	}
+	a, client := newAllocator(t, stopCh, "", pods,
+		newIPPool("pool1", true, "10.10.0.0/29", prodSelector),
+		newIPPool("pool0", false, "10.10.1.0/24", prodSelector))
+
+	// The Pods in ns2 do not match any IPPool.
+	result, err := a.AllocateIP("ns2", "pod", "container")
+	require.NoError(t, err)
+	assert.Nil(t, result)
+
+	// The IPs of 10.10.0.0/29 except the network IP, the gateway and the
+	// broadcast IP are allocated.
+	for i := 2; i <= 6; i++ {
+		result, err := a.AllocateIP("ns1", fmt.Sprintf("pod%d", i), fmt.Sprintf("container%d", i))
+		require.NoError(t, err)
+		require.Len(t, result.IPs, 1)
+		assert.Equal(t, fmt.Sprintf("10.10.0.%d/29", i), result.IPs[0].Address.String())
+		assert.Equal(t, "10.10.0.1", result.IPs[0].Gateway.String())
+		require.Len(t, result.Routes, 1)
+		assert.Equal(t, "0.0.0.0/0", result.Routes[0].Dst.String())
+	}
+	// The same IP is returned for the same container.
+	result, err = a.AllocateIP("ns1", "pod2", "container2")
+	require.NoError(t, err)
+	assert.Equal(t, "10.10.0.2/29", result.IPs[0].Address.String())
+	assert.True(t, a.HasIP("container2"))
+	assert.False(t, a.HasIP("container7"))
+
+	_, err = a.AllocateIP("ns1", "pod7", "container7")
+	assert.Error(t, err)
+	pool := getIPPool(t, client, "pool1")
+	assert.Len(t, pool.Status.Allocations, 5)
+	assert.Equal(t, corev1alpha1.IPAllocation{IP: "10.10.0.2", Namespace: "ns1", Pod: "pod2", ContainerID: "container2", Node: nodeName}, pool.Status.Allocations[0])
+	assert.Equal(t, corev1.ConditionTrue, utilippool.GetCondition(&pool.Status, corev1alpha1.IPPoolConditionPoolExhausted).Status)
+
+	released, err := a.ReleaseIP("container4")
+	require.NoError(t, err)
+	assert.True(t, released)
+	pool = getIPPool(t, client, "pool1")
+	assert.Len(t, pool.Status.Allocations, 4)
+	assert.Equal(t, corev1.ConditionFalse, utilippool.GetCondition(&pool.Status, corev1alpha1.IPPoolConditionPoolExhausted).Status)
+
+	result, err = a.AllocateIP("ns1", "pod7", "container7")
+	require.NoError(t, err)
+	assert.Equal(t, "10.10.0.4/29", result.IPs[0].Address.String())
+
+	// The containers whose IPs were not allocated from an IPPool are ignored.
+	released, err = a.ReleaseIP("container")
+	require.NoError(t, err)
+	assert.False(t, released)
+}
+
+func TestAllocateStaticIP(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	prodSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	a, client := newAllocator(t, stopCh, "",
+		[]*corev1.Pod{
+			newPod("ns1", "db", "10.10.0.5"),
+			newPod("ns1", "conflicting", "10.10.0.5"),
+			newPod("ns1", "gateway", "10.10.0.1"),
+			newPod("ns1", "outside", "10.10.1.5"),
+			newPod("ns1", "invalid", "10.10.0"),
+			newPod("ns1", "dynamic", ""),
+			newPod("ns2", "db", "10.10.0.5"),
+		},
+		newIPPool("pool1", true, "10.10.0.0/29", prodSelector))
+
+	result, err := a.AllocateIP("ns1", "db", "container1")
+	require.NoError(t, err)
+	assert.Equal(t, "10.10.0.5/29", result.IPs[0].Address.String())
+
+	// The static IP is reserved and not allocated to other Pods.
+	result, err = a.AllocateIP("ns1", "dynamic", "container2")
+	require.NoError(t, err)
+	assert.Equal(t, "10.10.0.2/29", result.IPs[0].Address.String())
+	_, err = a.AllocateIP("ns1", "conflicting", "container3")
+	assert.Error(t, err)
+
+	// The IPs which cannot be allocated from the IPPool are rejected.
+	for _, pod := range []string{"gateway", "outside", "invalid"} {
+		_, err = a.AllocateIP("ns1", pod, "container-"+pod)
+		assert.Error(t, err, pod)
+	}
+	// A static IP cannot be allocated to a Pod which no IPPool matches.
+	_, err = a.AllocateIP("ns2", "db", "container4")
+	assert.Error(t, err)
+
+	// The static IP is allocated to the new container of the same Pod, even if
+	// it was not released for the previous one.
+	result, err = a.AllocateIP("ns1", "db", "container5")
+	require.NoError(t, err)
+	assert.Equal(t, "10.10.0.5/29", result.IPs[0].Address.String())
+	pool := getIPPool(t, client, "pool1")
+	assert.ElementsMatch(t, []corev1alpha1.IPAllocation{
+		{IP: "10.10.0.5", Namespace: "ns1", Pod: "db", ContainerID: "container5", Node: nodeName},
+		{IP: "10.10.0.2", Namespace: "ns1", Pod: "dynamic", ContainerID: "container2", Node: nodeName},
+	}, pool.Status.Allocations)
+
+	released, err := a.ReleaseIP("container5")
+	require.NoError(t, err)
+	assert.True(t, released)
+	result, err = a.AllocateIP("ns1", "db", "container6")
+	require.NoError(t, err)
+	assert.Equal(t, "10.10.0.5/29", result.IPs[0].Address.String())
+}
+
+func TestAllocateIPFromPool(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	prodSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	a, client := newAllocator(t, stopCh, "", []*corev1.Pod{newPod("ns1", "pod1", ""), newPod("ns2", "pod2", "")},
+		newIPPool("pool1", true, "10.10.0.0/29", prodSelector),
+		newIPPool("secondary", true, "192.168.10.0/29", prodSelector),
+		newIPPool("invalid", false, "192.168.11.0/29", nil))
+
+	result, err := a.AllocateIP("ns1", "pod1", "container1")
+	require.NoError(t, err)
+	assert.Equal(t, "10.10.0.2/29", result.IPs[0].Address.String())
+	// The IPs of the secondary interfaces are allocated from the specified
+	// IPPool, even if it does not select the Namespace of the Pod, and the
+	// results have no default route.
+	for i, podInfo := range []struct{ namespace, name, containerID string }{
+		{"ns1", "pod1", "container1"},
+		{"ns2", "pod2", "container2"},
+	} {
+		result, err = a.AllocateIPFromPool("secondary", podInfo.namespace, podInfo.name, podInfo.containerID, "net1")
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("192.168.10.%d/29", i+2), result.IPs[0].Address.String())
+		assert.Empty(t, result.Routes)
+	}
+	result, err = a.AllocateIPFromPool("secondary", "ns1", "pod1", "container1", "net2")
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.10.4/29", result.IPs[0].Address.String())
+	// The same IP is returned for the same interface.
+	result, err = a.AllocateIPFromPool("secondary", "ns1", "pod1", "container1", "net1")
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.10.2/29", result.IPs[0].Address.String())
+	assert.False(t, a.HasIP("container2"))
+
+	_, err = a.AllocateIPFromPool("invalid", "ns1", "pod1", "container1", "net3")
+	assert.Error(t, err)
+	_, err = a.AllocateIPFromPool("missing", "ns1", "pod1", "container1", "net3")
+	assert.Error(t, err)
+
+	// Releasing the primary interface does not release the secondary ones.
+	released, err := a.ReleaseIP("container1")
+	require.NoError(t, err)
+	assert.True(t, released)
+	require.NoError(t, a.ReleaseIPFromPool("container1", "net1"))
+	require.NoError(t, a.ReleaseIPFromPool("container1", "net1"))
+	assert.Empty(t, getIPPool(t, client, "pool1").Status.Allocations)
+	assert.ElementsMatch(t, []corev1alpha1.IPAllocation{
+		{IP: "192.168.10.3", Namespace: "ns2", Pod: "pod2", ContainerID: "container2", Interface: "net1", Node: nodeName},
+		{IP: "192.168.10.4", Namespace: "ns1", Pod: "pod1", ContainerID: "container1", Interface: "net2", Node: nodeName},
+	}, getIPPool(t, client, "secondary").Status.Allocations)
+}
+
+func TestIPAMMetrics(t *testing.T) {
+	// The metrics do not record anything until they are registered.
+	legacyregistry.MustRegister(metrics.IPAMTotalAddresses, metrics.IPAMUsedAddresses, metrics.IPAMAvailableAddresses)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	prodSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	pool := newIPPool("pool1", true, "10.10.0.0/29", prodSelector)
+	pool.Status.Allocations = []corev1alpha1.IPAllocation{
+		{IP: "10.10.0.6", Namespace: "ns1", Pod: "remote", ContainerID: "remote", Node: "node2"},
+	}
+	otherNodePool := newIPPool("pool2", true, "10.10.1.0/29", prodSelector)
+	otherNodePool.Spec.NodeSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"ipam": "other"}}
+	var pods []*corev1.Pod
+	for i := 0; i < 3; i++ {
+		pods = append(pods, newPod("ns1", fmt.Sprintf("pod%d", i), ""))
+	}
+	a, _ := newAllocator(t, stopCh, "", pods, pool, otherNodePool)
+
+	assertMetrics := func(total, used, available float64) {
+		assert.Eventually(t, func() bool {
+			values := make([]float64, 0, 3)
+			for _, gauge := range []*k8smetrics.GaugeVec{metrics.IPAMTotalAddresses, metrics.IPAMUsedAddresses, metrics.IPAMAvailableAddresses} {
+				value, err := testutil.GetGaugeMetricValue(gauge.WithLabelValues("pool1", nodeName))
+				if err != nil {
+					return false
+				}
+				values = append(values, value)
+			}
+			return assert.ObjectsAreEqual([]float64{total, used, available}, values)
+		}, time.Second, 10*time.Millisecond)
+	}
+	// The IPs allocated by the other Nodes are accounted for.
+	assertMetrics(5, 1, 4)
+	for i := 0; i < 3; i++ {
+		_, err := a.AllocateIP("ns1", fmt.Sprintf("pod%d", i), fmt.Sprintf("container%d", i))
+		require.NoError(t, err)
+	}
+	assertMetrics(5, 4, 1)
+	_, err := a.ReleaseIP("container1")
+	require.NoError(t, err)
+	assertMetrics(5, 3, 2)
+	_, err = a.ReleaseIP("container0")
+	require.NoError(t, err)
+	assertMetrics(5, 2, 3)
+}
+
+func TestPodIPPersistence(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	dir, err := ioutil.TempDir("", "ipam")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	statePath := filepath.Join(dir, "ipam-state.json")
+	prodSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	pods := []*corev1.Pod{newPod("ns1", "pod2", ""), newPod("ns1", "pod3", ""), newPod("ns1", "pod4", "")}
+	a, client := newAllocator(t, stopCh, statePath, pods, newIPPool("pool1", true, "10.10.0.0/29", prodSelector))
+
+	for _, i := range []int{2, 3} {
+		result, err := a.AllocateIP("ns1", fmt.Sprintf("pod%d", i), fmt.Sprintf("container%d", i))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("10.10.0.%d/29", i), result.IPs[0].Address.String())
+	}
+	// The state is written atomically.
+	_, err = os.Stat(statePath)
+	require.NoError(t, err)
+	_, err = os.Stat(statePath + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+
+	// The sandboxes of the Pods are deleted when the Node reboots.
+	for _, containerID := range []string{"container2", "container3"} {
+		_, err := a.ReleaseIP(containerID)
+		require.NoError(t, err)
+	}
+	pool := getIPPool(t, client, "pool1")
+	require.Empty(t, pool.Status.Allocations)
+
+	// After the agent restarts, the new sandboxes of the Pods get the same IPs
+	// as before, whatever the order in which they are created.
+	a, _ = newAllocator(t, stopCh, statePath, pods, pool)
+	for _, i := range []int{3, 2} {
+		result, err := a.AllocateIP("ns1", fmt.Sprintf("pod%d", i), fmt.Sprintf("container1%d", i))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("10.10.0.%d/29", i), result.IPs[0].Address.String())
+	}
+	result, err := a.AllocateIP("ns1", "pod4", "container4")
+	require.NoError(t, err)
+	assert.Equal(t, "10.10.0.4/29", result.IPs[0].Address.String())
+}
+
+func TestReconcileAllocations(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	dir, err := ioutil.TempDir("", "ipam")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	statePath := filepath.Join(dir, "ipam-state.json")
+	require.NoError(t, ioutil.WriteFile(statePath, []byte(`[
+		{"pool":"pool1","ip":"10.10.0.2","namespace":"ns1","pod":"pod2","containerID":"container2"},
+		{"pool":"pool1","ip":"10.10.0.3","namespace":"ns1","pod":"deleted","containerID":"container3"}
+	]`), 0600))
+	prodSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	pool := newIPPool("pool1", true, "10.10.0.0/29", prodSelector)
+	pool.Status.Allocations = []corev1alpha1.IPAllocation{
+		{IP: "10.10.0.2", Namespace: "ns1", Pod: "pod2", ContainerID: "container2", Node: nodeName},
+		{IP: "10.10.0.3", Namespace: "ns1", Pod: "deleted", ContainerID: "container3", Node: nodeName},
+		{IP: "10.10.0.4", Namespace: "ns1", Pod: "remote", ContainerID: "container4", Node: "node2"},
+	}
+	a, client := newAllocator(t, stopCh, statePath, []*corev1.Pod{newPod("ns1", "pod2", "")}, pool)
+	require.Len(t, a.podAllocations, 2)
+
+	require.NoError(t, a.reconcileAllocations())
+	// The IP of the deleted Pod is reclaimed, while the IPs allocated by the
+	// other Nodes are left untouched.
+	assert.ElementsMatch(t, []corev1alpha1.IPAllocation{
+		{IP: "10.10.0.2", Namespace: "ns1", Pod: "pod2", ContainerID: "container2", Node: nodeName},
+		{IP: "10.10.0.4", Namespace: "ns1", Pod: "remote", ContainerID: "container4", Node: "node2"},
+	}, getIPPool(t, client, "pool1").Status.Allocations)
+	data, err := ioutil.ReadFile(statePath)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"pool":"pool1","ip":"10.10.0.2","namespace":"ns1","pod":"pod2","containerID":"container2"}]`, string(data))
+}
@@ -67,6 +67,90 @@ var (
 		Help:           "Flow count for each OVS flow table. The TableID is used as a label.",
 		StabilityLevel: metrics.STABLE,
 	}, []string{"table_id"})
+
+	OVSTablePacketCount = metrics.NewCounterVec(&metrics.CounterOpts{
+		Name:           "antrea_agent_ovs_table_packets_total",
+		Help:           "Number of packets which hit the flows of each OVS flow table. The TableID and the reason, \"match\" or \"miss\" for the table-miss flow, are used as labels.",
+		StabilityLevel: metrics.STABLE,
+	}, []string{"table_id", "reason"})
+
+	OVSTableByteCount = metrics.NewCounterVec(&metrics.CounterOpts{
+		Name:           "antrea_agent_ovs_table_bytes_total",
+		Help:           "Number of bytes which hit the flows of each OVS flow table. The TableID and the reason, \"match\" or \"miss\" for the table-miss flow, are used as labels.",
+		StabilityLevel: metrics.STABLE,
+	}, []string{"table_id", "reason"})
+
+	IPAMTotalAddresses = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Name:           "antrea_agent_ipam_total_addresses",
+		Help:           "Number of IPs which can be allocated from each IPPool matching the Node. The IPPool and the Node are used as labels.",
+		StabilityLevel: metrics.STABLE,
+	}, []string{"pool", "node"})
+
+	IPAMUsedAddresses = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Name:           "antrea_agent_ipam_used_addresses",
+		Help:           "Number of IPs allocated to Pods from each IPPool matching the Node. The IPPool and the Node are used as labels.",
+		StabilityLevel: metrics.STABLE,
+	}, []string{"pool", "node"})
+
+	IPAMAvailableAddresses = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Name:           "antrea_agent_ipam_available_addresses",
+		Help:           "Number of IPs which are still available in each IPPool matching the Node. The IPPool and the Node are used as labels.",
+		StabilityLevel: metrics.STABLE,
+	}, []string{"pool", "node"})
+
+	FlowExportSampledCount = metrics.NewCounter(&metrics.CounterOpts{
+		Name:           "antrea_agent_flow_export_sampled_total",
+		Help:           "Number of connections selected by the flow sampling of the flow exporter.",
+		StabilityLevel: metrics.STABLE,
+	})
+
+	FlowExportDroppedCount = metrics.NewCounter(&metrics.CounterOpts{
+		Name:           "antrea_agent_flow_export_dropped_total",
+		Help:           "Number of connections dropped by the flow sampling of the flow exporter.",
+		StabilityLevel: metrics.STABLE,
+	})
+
+	PodToPodBytes = metrics.NewCounterVec(&metrics.CounterOpts{
+		Name:           "antrea_pod_to_pod_bytes_total",
+		Help:           "Number of bytes sent from a source Pod to a destination Pod, accounted by the Node of the source Pod. The Pods are used as labels. The Pod pairs which are not tracked, or which sent less than 1 KiB in a flow exporter poll period, are accounted with the \"other\" label values.",
+		StabilityLevel: metrics.STABLE,
+	}, []string{"src_pod", "src_ns", "dst_pod", "dst_ns"})
+
+	// The buckets of the proxy sync durations range from 10ms to 10s, so that
+	// the P99 latency can be compared with the 1s alert threshold.
+	proxySyncDurationBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+	ProxyServiceSyncDuration = metrics.NewHistogramVec(&metrics.HistogramOpts{
+		Name:           "antrea_proxy_service_sync_duration_seconds",
+		Help:           "Duration in seconds from the dequeuing of a Service change by AntreaProxy to the installation of its last OVS flow. The event type, \"add\", \"update\" or \"delete\", and the Service type are used as labels.",
+		Buckets:        proxySyncDurationBuckets,
+		StabilityLevel: metrics.STABLE,
+	}, []string{"event_type", "service_type"})
+
+	ProxyEndpointSyncDuration = metrics.NewHistogramVec(&metrics.HistogramOpts{
+		Name:           "antrea_proxy_endpoint_sync_duration_seconds",
+		Help:           "Duration in seconds from the dequeuing of an Endpoints change by AntreaProxy to the installation of its OVS flows. The event type, \"add\", \"update\" or \"delete\", and the Service type are used as labels.",
+		Buckets:        proxySyncDurationBuckets,
+		StabilityLevel: metrics.STABLE,
+	}, []string{"event_type", "service_type"})
+
+	PolicyTablePacketCount = metrics.NewCounterVec(&metrics.CounterOpts{
+		Name:           "antrea_policy_table_packets_total",
+		Help:           "Number of packets which hit the flows of each NetworkPolicy OVS table. The table name and the action of the flows, \"allow\", \"deny\" or \"jump\" to the next table, are used as labels.",
+		StabilityLevel: metrics.STABLE,
+	}, []string{"table", "action"})
+
+	PolicyTableByteCount = metrics.NewCounterVec(&metrics.CounterOpts{
+		Name:           "antrea_policy_table_bytes_total",
+		Help:           "Number of bytes which hit the flows of each NetworkPolicy OVS table. The table name and the action of the flows, \"allow\", \"deny\" or \"jump\" to the next table, are used as labels.",
+		StabilityLevel: metrics.STABLE,
+	}, []string{"table", "action"})
+
+	OVSDatapathFlowUtilization = metrics.NewGauge(&metrics.GaugeOpts{
+		Name:           "antrea_ovs_datapath_flow_utilization_ratio",
+		Help:           "Ratio of the number of flows installed in the OVS datapath to the maximum number of flows ovs-vswitchd installs in it.",
+		StabilityLevel: metrics.STABLE,
+	})
 )
 
 func InitializePrometheusMetrics() {
@@ -112,4 +196,43 @@ func InitializePrometheusMetrics() {
 	if err := legacyregistry.Register(OVSFlowCount); err != nil {
 		klog.Error("Failed to register antrea_agent_ovs_flow_count with Prometheus")
 	}
+	if err := legacyregistry.Register(OVSTablePacketCount); err != nil {
+		klog.Error("Failed to register antrea_agent_ovs_table_packets_total with Prometheus")
+	}
+	if err := legacyregistry.Register(OVSTableByteCount); err != nil {
+		klog.Error("Failed to register antrea_agent_ovs_table_bytes_total with Prometheus")
+	}
+	if err := legacyregistry.Register(IPAMTotalAddresses); err != nil {
+		klog.Error("Failed to register antrea_agent_ipam_total_addresses with Prometheus")
+	}
+	if err := legacyregistry.Register(IPAMUsedAddresses); err != nil {
+		klog.Error("Failed to register antrea_agent_ipam_used_addresses with Prometheus")
+	}
+	if err := legacyregistry.Register(IPAMAvailableAddresses); err != nil {
+		klog.Error("Failed to register antrea_agent_ipam_available_addresses with Prometheus")
+	}
+	if err := legacyregistry.Register(FlowExportSampledCount); err != nil {
+		klog.Error("Failed to register antrea_agent_flow_export_sampled_total with Prometheus")
+	}
+	if err := legacyregistry.Register(FlowExportDroppedCount); err != nil {
+		klog.Error("Failed to register antrea_agent_flow_export_dropped_total with Prometheus")
+	}
+	if err := legacyregistry.Register(PodToPodBytes); err != nil {
+		klog.Error("Failed to register antrea_pod_to_pod_bytes_total with Prometheus")
+	}
+	if err := legacyregistry.Register(ProxyServiceSyncDuration); err != nil {
+		klog.Error("Failed to register antrea_proxy_service_sync_duration_seconds with Prometheus")
+	}
+	if err := legacyregistry.Register(ProxyEndpointSyncDuration); err != nil {
+		klog.Error("Failed to register antrea_proxy_endpoint_sync_duration_seconds with Prometheus")
+	}
+	if err := legacyregistry.Register(PolicyTablePacketCount); err != nil {
+		klog.Error("Failed to register antrea_policy_table_packets_total with Prometheus")
+	}
+	if err := legacyregistry.Register(PolicyTableByteCount); err != nil {
+		klog.Error("Failed to register antrea_policy_table_bytes_total with Prometheus")
+	}
+	if err := legacyregistry.Register(OVSDatapathFlowUtilization); err != nil {
+		klog.Error("Failed to register antrea_ovs_datapath_flow_utilization_ratio with Prometheus")
+	}
 }
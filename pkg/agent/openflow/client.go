@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"strings"
 
 	"github.com/contiv/ofnet/ofctrl"
 	"k8s.io/klog"
@@ -25,11 +26,16 @@ import (
 	"github.com/vmware-tanzu/antrea/pkg/agent/config"
 	"github.com/vmware-tanzu/antrea/pkg/agent/openflow/cookie"
 	"github.com/vmware-tanzu/antrea/pkg/agent/types"
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
 	binding "github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
 	"github.com/vmware-tanzu/antrea/third_party/proxy"
 )
 
-const maxRetryForOFSwitch = 5
+const (
+	maxRetryForOFSwitch = 5
+	// TraceflowTimeout is the hard timeout in seconds of the flows installed for the synthetic traceflow packets.
+	TraceflowTimeout = uint16(300)
+)
 
 // Client is the interface to program OVS flows for entity connectivity of Antrea.
 type Client interface {
@@ -90,12 +96,25 @@ type Client interface {
 	// interfaceName. UninstallPodFlows will do nothing if no connection to the Pod was established.
 	UninstallPodFlows(interfaceName string) error
 
+	// InstallSecondaryInterfaceFlows should be invoked when a secondary network interface of a
+	// Pod is connected to the bridge. The traffic of the interface is switched with the NORMAL
+	// action in the VLAN of its OVS port, and does not go through the Pod network pipeline. The
+	// flows are identified with the interfaceName, and are removed with UninstallPodFlows.
+	InstallSecondaryInterfaceFlows(interfaceName string, ofPort uint32) error
+
 	// InstallServiceGroup installs a group for Service LB. Each endpoint
 	// is a bucket of the group. For now, each bucket has the same weight.
 	InstallServiceGroup(groupID binding.GroupIDType, withSessionAffinity bool, endpoints []proxy.Endpoint) error
 	// UninstallServiceGroup removes the group and its buckets that are
 	// installed by InstallServiceGroup.
 	UninstallServiceGroup(groupID binding.GroupIDType) error
+	// EnableFastFailoverGroups makes the Service groups installed afterwards
+	// fast-failover groups instead of select groups. The bucket of an Endpoint
+	// watches the OpenFlow port returned by watchPort for the Endpoint IP, and
+	// OVS skips it as soon as the port is down. A fast-failover group sends all
+	// the packets to its first live bucket, the Endpoints are not
+	// load-balanced.
+	EnableFastFailoverGroups(watchPort func(endpointIP string) (uint32, bool))
 
 	// InstallEndpointFlows installs flows for accessing Endpoints.
 	// If an Endpoint is on the current Node, then flows for hairpin and endpoint
@@ -104,8 +123,16 @@ type Client interface {
 	// UninstallEndpointFlows removes flows of the Endpoint installed by
 	// InstallEndpointFlows.
 	UninstallEndpointFlows(protocol binding.Protocol, endpoint proxy.Endpoint) error
+	// UpdateServiceEndpoints updates the group installed by InstallServiceGroup
+	// with the provided endpoints, installs the flows of the endpoints which
+	// are not installed yet and removes the flows of the removedEndpoints, all
+	// in a single OpenFlow bundle. No packet is then sent to an Endpoint whose
+	// flows have been removed while the group is being updated.
+	UpdateServiceEndpoints(groupID binding.GroupIDType, withSessionAffinity bool, protocol binding.Protocol, endpoints []proxy.Endpoint, removedEndpoints []proxy.Endpoint) error
 
 	// InstallServiceFlows installs flows for accessing Service with clusterIP.
+	// It is also used for the ingress IPs of LoadBalancer Services, which are
+	// load-balanced like the clusterIP.
 	// It installs the flow that uses the group/bucket to do service LB. If the
 	// affinityTimeout is not zero, it also installs the flow which has a learn
 	// action to maintain the LB decision.
@@ -115,8 +142,27 @@ type Client interface {
 	// UninstallServiceFlows removes flows installed by InstallServiceFlows.
 	UninstallServiceFlows(svcIP net.IP, svcPort uint16, protocol binding.Protocol) error
 
+	// InstallNodePortFlows installs flows for accessing Service with NodePort.
+	// The flows match the traffic sent to the NodePort of the current Node's
+	// IP address and use the group/bucket to do service LB, like the flows
+	// installed by InstallServiceFlows for the clusterIP.
+	// The flows are in the Service LB table, so they only apply to the
+	// traffic sent by local Pods to the current Node's IP address. The
+	// traffic received on the uplink interface, or sent by Pods to the
+	// NodePort of other Nodes, is not processed by the flows.
+	// The group with the groupID must be installed before, otherwise the
+	// installation will fail.
+	InstallNodePortFlows(groupID binding.GroupIDType, nodePort uint16, protocol binding.Protocol, affinityTimeout uint16) error
+	// UninstallNodePortFlows removes flows installed by InstallNodePortFlows.
+	UninstallNodePortFlows(nodePort uint16, protocol binding.Protocol) error
+
 	// GetFlowTableStatus should return an array of flow table status, all existing flow tables should be included in the list.
 	GetFlowTableStatus() []binding.TableStatus
+	// GetTableTrafficStats returns the numbers of packets and bytes which hit the flows of each flow table.
+	GetTableTrafficStats() (map[binding.TableIDType]*binding.TableTrafficStats, error)
+	// GetPolicyTableTrafficStats returns the numbers of packets and bytes which hit the flows of each NetworkPolicy
+	// table, by the PolicyAction encoded in the cookies of the flows.
+	GetPolicyTableTrafficStats() (map[binding.TableIDType]map[cookie.PolicyAction]*binding.TrafficStats, error)
 
 	// InstallPolicyRuleFlows installs flows for a new NetworkPolicy rule. Rule should include all fields in the
 	// NetworkPolicy rule. Each ingress/egress policy rule installs Openflow entries on two tables, one for
@@ -142,6 +188,29 @@ type Client interface {
 	// in the connection tracking context, and 3) SNAT the packets with Node IP.
 	InstallExternalFlows(nodeIP net.IP, localSubnet net.IPNet) error
 
+	// InstallEgressNATBypassFlows installs the flows which exclude the traffic to the Service CIDR, the local
+	// gateway and the Node itself from Egress NAT. It must be called once before the other Egress NAT flows are
+	// installed.
+	InstallEgressNATBypassFlows(serviceCIDR net.IPNet) error
+
+	// InstallRemotePodEgressNATFlow installs the flow which marks the packets tunneled by the other Nodes from the
+	// remote Pod IP with the mark of the Egress IP assigned to this Node, so that they are SNATed to the Egress IP by
+	// the host. Calls to InstallRemotePodEgressNATFlow are idempotent, and replace the flow previously installed for
+	// the Pod IP.
+	InstallRemotePodEgressNATFlow(podIP net.IP, mark uint32) error
+
+	// UninstallRemotePodEgressNATFlow removes the flow installed by InstallRemotePodEgressNATFlow for the Pod IP.
+	UninstallRemotePodEgressNATFlow(podIP net.IP) error
+
+	// InstallPodEgressNATFlow installs the flow which SNATs the egress traffic of the local Pod of the specified OVS
+	// port to the Egress IP. If the mark is not 0, the Egress IP is assigned to this Node and the packets are marked
+	// like in InstallRemotePodEgressNATFlow, otherwise they are tunneled to the Egress IP. Calls to
+	// InstallPodEgressNATFlow are idempotent, and replace the flow previously installed for the port.
+	InstallPodEgressNATFlow(ofPort uint32, egressIP net.IP, mark uint32) error
+
+	// UninstallPodEgressNATFlow removes the flow installed by InstallPodEgressNATFlow for the OVS port.
+	UninstallPodEgressNATFlow(ofPort uint32) error
+
 	// Disconnect disconnects the connection between client and OFSwitch.
 	Disconnect() error
 
@@ -172,10 +241,25 @@ type Client interface {
 	// rules.
 	GetNetworkPolicyFlowKeys(npName, npNamespace string) []string
 
+	// GetDesiredFlows returns the flows the client maintains in OVS for the
+	// current state, excluding the transient Traceflow flows. A flow is
+	// represented by its table, priority and match conditions, in the format of
+	// ovs-ofctl, e.g. "table=31,priority=190,ct_state=+inv+trk,ip".
+	GetDesiredFlows() []string
+
 	// ReassignFlowPriorities takes a list of priority updates, and update the actionFlows to replace
 	// the old priority with the desired one, for each priority update.
 	ReassignFlowPriorities(updates map[uint16]uint16) error
 
+	// InstallL7ConnDenyFlow installs a flow to deny the TCP connection of the provided addresses and ports, which
+	// matches the ClusterNetworkPolicy rule with HTTP matches in the provided direction. The flows are uninstalled with
+	// the rule.
+	InstallL7ConnDenyFlow(ruleID uint32, direction v1beta1.Direction, srcIP, dstIP net.IP, srcPort, dstPort uint16) error
+
+	// ReinjectL7Packet sends a packet of a TCP connection matching a ClusterNetworkPolicy rule with HTTP matches in the
+	// provided direction back to the OVS pipeline, after the agent has allowed the HTTP requests it carries.
+	ReinjectL7Packet(direction v1beta1.Direction, pktIn *ofctrl.PacketIn) error
+
 	// SubscribePacketIn subscribes packet-in channel in Bridge.
 	SubscribePacketIn(reason uint8, ch chan *ofctrl.PacketIn) error
 
@@ -204,14 +288,40 @@ type Client interface {
 	// InstallTraceflowFlows installs flows for specific traceflow request.
 	InstallTraceflowFlows(dataplaneTag uint8) error
 
+	// InstallTraceflowLiveFlows installs flows for specific traceflow request which traces the live traffic. The
+	// flows expire after the timeout in seconds if they are not removed by UninstallTraceflowLiveFlows.
+	InstallTraceflowLiveFlows(dataplaneTag uint8, timeout uint16) error
+
+	// InstallTraceflowLiveCaptureFlow installs the flow which marks the live packets sent from the Pod of the specified
+	// OVS port to dstIP with the data plane tag. The ports are matched only for TCP and UDP, and only if they are not 0.
+	InstallTraceflowLiveCaptureFlow(
+		dataplaneTag uint8,
+		ofPort uint32,
+		srcMAC net.HardwareAddr,
+		srcIP net.IP,
+		dstIP net.IP,
+		IPProtocol uint8,
+		srcPort uint16,
+		dstPort uint16,
+		timeout uint16) error
+
+	// UninstallTraceflowLiveFlows removes the flows installed by InstallTraceflowLiveFlows and
+	// InstallTraceflowLiveCaptureFlow for the data plane tag.
+	UninstallTraceflowLiveFlows(dataplaneTag uint8) error
+
+	// InstallTraceflowServiceNoEndpointsFlow installs the flow which sends the traceflow packets destined to a
+	// Service port without Endpoints to Antrea Agent, before they are dropped by the ServiceLB table. The flow
+	// expires after the timeout in seconds.
+	InstallTraceflowServiceNoEndpointsFlow(dataplaneTag uint8, svcIP net.IP, svcPort uint16, protocol binding.Protocol, timeout uint16) error
+
 	// Initial tun_metadata0 in TLV map for Traceflow.
 	InitialTLVMap() error
 
 	// Find network policy and namespace by conjunction ID.
 	GetPolicyFromConjunction(ruleID uint32) (string, string)
 
-	// RegisterPacketInHandler registers PacketIn handler to process PacketIn event.
-	RegisterPacketInHandler(packetHandlerName string, packetInHandler interface{})
+	// RegisterPacketInHandler registers PacketIn handler to process PacketIn events with the specified reason.
+	RegisterPacketInHandler(packetHandlerReason uint8, packetHandlerName string, packetInHandler interface{})
 	// RegisterPacketInHandler uses SubscribePacketIn to get PacketIn message and process received
 	// packets through registered handlers.
 	StartPacketInHandler(stopCh <-chan struct{})
@@ -222,6 +332,43 @@ func (c *client) GetFlowTableStatus() []binding.TableStatus {
 	return c.bridge.DumpTableStatus()
 }
 
+// GetTableTrafficStats returns the traffic statistics of each flow table.
+func (c *client) GetTableTrafficStats() (map[binding.TableIDType]*binding.TableTrafficStats, error) {
+	return c.bridge.DumpTableTrafficStats()
+}
+
+// GetPolicyTableTrafficStats sums the counters of the flows of the NetworkPolicy
+// tables by table and PolicyAction. The flows which take no PolicyAction, e.g.
+// the conjunctive match flows, are ignored.
+func (c *client) GetPolicyTableTrafficStats() (map[binding.TableIDType]map[cookie.PolicyAction]*binding.TrafficStats, error) {
+	flowStats, err := c.bridge.DumpFlowTrafficStats()
+	if err != nil {
+		return nil, err
+	}
+	tableStats := make(map[binding.TableIDType]map[cookie.PolicyAction]*binding.TrafficStats)
+	for _, tableID := range PolicyTables {
+		tableStats[tableID] = make(map[cookie.PolicyAction]*binding.TrafficStats)
+	}
+	for _, stat := range flowStats {
+		actionStats, ok := tableStats[stat.TableID]
+		if !ok {
+			continue
+		}
+		action := cookie.ID(stat.Cookie).PolicyAction()
+		if action == cookie.PolicyActionNone {
+			continue
+		}
+		s, ok := actionStats[action]
+		if !ok {
+			s = &binding.TrafficStats{}
+			actionStats[action] = s
+		}
+		s.PacketCount += stat.PacketCount
+		s.ByteCount += stat.ByteCount
+	}
+	return tableStats, nil
+}
+
 // IsConnected returns the connection status between client and OFSwitch.
 func (c *client) IsConnected() bool {
 	return c.bridge.IsConnected()
@@ -251,6 +398,20 @@ func (c *client) addFlows(cache *flowCategoryCache, flowCacheKey string, flows [
 	return nil
 }
 
+// replaceFlows adds the flows to the switch like addFlows, but replaces the flows cached for the key if there are
+// any. The flows must have the same match conditions as the ones they replace, so that OVS overwrites them.
+func (c *client) replaceFlows(cache *flowCategoryCache, flowCacheKey string, flows []binding.Flow) error {
+	if err := c.ofEntryOperations.AddAll(flows); err != nil {
+		return err
+	}
+	fCache := flowCache{}
+	for _, flow := range flows {
+		fCache[flow.MatchString()] = flow
+	}
+	cache.Store(flowCacheKey, fCache)
+	return nil
+}
+
 // deleteFlows deletes all the flows in the flow cache indexed by the provided flowCacheKey.
 func (c *client) deleteFlows(cache *flowCategoryCache, flowCacheKey string) error {
 	fCacheI, ok := cache.Load(flowCacheKey)
@@ -329,6 +490,15 @@ func (c *client) UninstallPodFlows(interfaceName string) error {
 	return c.deleteFlows(c.podFlowCache, interfaceName)
 }
 
+func (c *client) InstallSecondaryInterfaceFlows(interfaceName string, ofPort uint32) error {
+	c.replayMutex.RLock()
+	defer c.replayMutex.RUnlock()
+	flows := []binding.Flow{
+		c.secondaryInterfaceClassifierFlow(ofPort, cookie.Pod),
+	}
+	return c.addFlows(c.podFlowCache, interfaceName, flows)
+}
+
 func (c *client) GetPodFlowKeys(interfaceName string) []string {
 	fCacheI, ok := c.podFlowCache.Load(interfaceName)
 	if !ok {
@@ -348,6 +518,55 @@ func (c *client) GetPodFlowKeys(interfaceName string) []string {
 	return flowKeys
 }
 
+// flowString returns the table, priority and match conditions of a flow.
+func flowString(flow binding.Flow) string {
+	// The match string starts with the table, e.g. "table=31,ip,reg0=0x1".
+	match := flow.MatchString()
+	if i := strings.Index(match, ","); i >= 0 {
+		return fmt.Sprintf("%s,priority=%d%s", match[:i], flow.FlowPriority(), match[i:])
+	}
+	return fmt.Sprintf("%s,priority=%d", match, flow.FlowPriority())
+}
+
+func (c *client) GetDesiredFlows() []string {
+	// Hold replayMutex write lock to prevent the flows from being updated while
+	// they are listed, including the policy flows which are otherwise protected
+	// by conjMatchFlowLock.
+	c.replayMutex.Lock()
+	defer c.replayMutex.Unlock()
+
+	var flows []binding.Flow
+	for _, group := range c.initialFlowGroups() {
+		flows = append(flows, group.flows...)
+	}
+	flows = append(flows, c.gatewayFlows...)
+	flows = append(flows, c.defaultServiceFlows...)
+	flows = append(flows, c.defaultTunnelFlows...)
+	flows = append(flows, c.hostNetworkingFlows...)
+	flows = append(flows, c.egressNATBypassFlowCache...)
+	addCachedFlows := func(key, value interface{}) bool {
+		for _, flow := range value.(flowCache) {
+			flows = append(flows, flow)
+		}
+		return true
+	}
+	c.nodeFlowCache.Range(addCachedFlows)
+	c.podFlowCache.Range(addCachedFlows)
+	c.serviceFlowCache.Range(addCachedFlows)
+	c.egressFlowCache.Range(addCachedFlows)
+	flows = append(flows, c.policyFlows()...)
+
+	desiredFlows := make([]string, 0, len(flows))
+	for _, flow := range flows {
+		desiredFlows = append(desiredFlows, flowString(flow))
+	}
+	return desiredFlows
+}
+
+func (c *client) EnableFastFailoverGroups(watchPort func(endpointIP string) (uint32, bool)) {
+	c.endpointWatchPort = watchPort
+}
+
 func (c *client) InstallServiceGroup(groupID binding.GroupIDType, withSessionAffinity bool, endpoints []proxy.Endpoint) error {
 	c.replayMutex.RLock()
 	defer c.replayMutex.RUnlock()
@@ -369,20 +588,38 @@ func (c *client) UninstallServiceGroup(groupID binding.GroupIDType) error {
 	return nil
 }
 
+// endpointFlowCacheKey returns the key of the flows of the Endpoint in
+// serviceFlowCache.
+func endpointFlowCacheKey(protocol binding.Protocol, endpointIP net.IP, endpointPort int) string {
+	return fmt.Sprintf("Endpoints:%s:%d:%s", endpointIP, endpointPort, protocol)
+}
+
+// endpointFlows returns the cache key and the flows for accessing the Endpoint.
+func (c *client) endpointFlows(protocol binding.Protocol, endpoint proxy.Endpoint) (string, []binding.Flow) {
+	var flows []binding.Flow
+	endpointPort, _ := endpoint.Port()
+	endpointIP := net.ParseIP(endpoint.IP())
+	isIPv6 := endpointIP.To4() == nil
+	if !isIPv6 {
+		endpointIP = endpointIP.To4()
+	}
+	portVal := uint16(endpointPort)
+	flows = append(flows, c.endpointDNATFlow(endpointIP, portVal, protocol))
+	// Hairpin traffic is not supported for IPv6 Endpoints: it would require a
+	// virtual IPv6 address to SNAT the packets. The proxier logs it when such
+	// an Endpoint is installed.
+	if endpoint.GetIsLocal() && !isIPv6 {
+		flows = append(flows, c.hairpinSNATFlow(endpointIP))
+	}
+	return endpointFlowCacheKey(protocol, endpointIP, endpointPort), flows
+}
+
 func (c *client) InstallEndpointFlows(protocol binding.Protocol, endpoints []proxy.Endpoint) error {
 	c.replayMutex.RLock()
 	defer c.replayMutex.RUnlock()
 
 	for _, endpoint := range endpoints {
-		var flows []binding.Flow
-		endpointPort, _ := endpoint.Port()
-		endpointIP := net.ParseIP(endpoint.IP()).To4()
-		portVal := uint16(endpointPort)
-		cacheKey := fmt.Sprintf("Endpoints:%s:%d:%s", endpointIP, endpointPort, protocol)
-		flows = append(flows, c.endpointDNATFlow(endpointIP, portVal, protocol))
-		if endpoint.GetIsLocal() {
-			flows = append(flows, c.hairpinSNATFlow(endpointIP))
-		}
+		cacheKey, flows := c.endpointFlows(protocol, endpoint)
 		if err := c.addFlows(c.serviceFlowCache, cacheKey, flows); err != nil {
 			return err
 		}
@@ -398,8 +635,80 @@ func (c *client) UninstallEndpointFlows(protocol binding.Protocol, endpoint prox
 	if err != nil {
 		return fmt.Errorf("error when getting port: %w", err)
 	}
-	cacheKey := fmt.Sprintf("Endpoints:%s:%d:%s", endpoint.IP(), port, protocol)
-	return c.deleteFlows(c.serviceFlowCache, cacheKey)
+	return c.deleteFlows(c.serviceFlowCache, endpointFlowCacheKey(protocol, net.ParseIP(endpoint.IP()), port))
+}
+
+func (c *client) UpdateServiceEndpoints(groupID binding.GroupIDType, withSessionAffinity bool, protocol binding.Protocol, endpoints []proxy.Endpoint, removedEndpoints []proxy.Endpoint) error {
+	c.replayMutex.RLock()
+	defer c.replayMutex.RUnlock()
+
+	// The group is modified in the bundle, so it must have been installed.
+	if _, ok := c.groupCache.Load(groupID); !ok {
+		return fmt.Errorf("group %d of Service Endpoints is not installed", groupID)
+	}
+	// An Endpoint may be both removed and added back, e.g. when it has been
+	// moved to the current Node. Its flows are then replaced.
+	removedCacheKeys := map[string]struct{}{}
+	for _, endpoint := range removedEndpoints {
+		port, err := endpoint.Port()
+		if err != nil {
+			return fmt.Errorf("error when getting port: %w", err)
+		}
+		removedCacheKeys[endpointFlowCacheKey(protocol, net.ParseIP(endpoint.IP()), port)] = struct{}{}
+	}
+	var addEntries, modEntries, delEntries []binding.OFEntry
+	updatedCaches := map[string]flowCache{}
+	for _, endpoint := range endpoints {
+		cacheKey, flows := c.endpointFlows(protocol, endpoint)
+		if _, ok := updatedCaches[cacheKey]; ok {
+			continue
+		}
+		var oldCache flowCache
+		if fCacheI, ok := c.serviceFlowCache.Load(cacheKey); ok {
+			if _, removed := removedCacheKeys[cacheKey]; !removed {
+				continue
+			}
+			oldCache = fCacheI.(flowCache)
+			delete(removedCacheKeys, cacheKey)
+		}
+		fCache := flowCache{}
+		for _, flow := range flows {
+			if _, ok := oldCache[flow.MatchString()]; ok {
+				modEntries = append(modEntries, flow)
+			} else {
+				addEntries = append(addEntries, flow)
+			}
+			fCache[flow.MatchString()] = flow
+		}
+		for matchString, flow := range oldCache {
+			if _, ok := fCache[matchString]; !ok {
+				delEntries = append(delEntries, flow)
+			}
+		}
+		updatedCaches[cacheKey] = fCache
+	}
+	for cacheKey := range removedCacheKeys {
+		fCacheI, ok := c.serviceFlowCache.Load(cacheKey)
+		if !ok {
+			continue
+		}
+		for _, flow := range fCacheI.(flowCache) {
+			delEntries = append(delEntries, flow)
+		}
+	}
+	group := c.serviceEndpointGroup(groupID, withSessionAffinity, endpoints...)
+	modEntries = append(modEntries, group)
+	if err := c.ofEntryOperations.BundleOFEntries(addEntries, modEntries, delEntries); err != nil {
+		return fmt.Errorf("error when updating Service Endpoints: %w", err)
+	}
+	c.groupCache.Store(groupID, group)
+	for cacheKey, fCache := range updatedCaches {
+		c.serviceFlowCache.Store(cacheKey, fCache)
+	}
+	for cacheKey := range removedCacheKeys {
+		c.serviceFlowCache.Delete(cacheKey)
+	}
+	return nil
 }
 
 func (c *client) InstallServiceFlows(groupID binding.GroupIDType, svcIP net.IP, svcPort uint16, protocol binding.Protocol, affinityTimeout uint16) error {
@@ -421,6 +730,26 @@ func (c *client) UninstallServiceFlows(svcIP net.IP, svcPort uint16, protocol bi
 	return c.deleteFlows(c.serviceFlowCache, cacheKey)
 }
 
+func (c *client) InstallNodePortFlows(groupID binding.GroupIDType, nodePort uint16, protocol binding.Protocol, affinityTimeout uint16) error {
+	c.replayMutex.RLock()
+	defer c.replayMutex.RUnlock()
+	nodeIP := c.nodeConfig.NodeIPAddr.IP
+	var flows []binding.Flow
+	flows = append(flows, c.serviceLBFlow(groupID, nodeIP, nodePort, protocol))
+	if affinityTimeout != 0 {
+		flows = append(flows, c.serviceLearnFlow(groupID, nodeIP, nodePort, protocol, affinityTimeout))
+	}
+	cacheKey := fmt.Sprintf("NodePort:%d:%s", nodePort, protocol)
+	return c.addFlows(c.serviceFlowCache, cacheKey, flows)
+}
+
+func (c *client) UninstallNodePortFlows(nodePort uint16, protocol binding.Protocol) error {
+	c.replayMutex.RLock()
+	defer c.replayMutex.RUnlock()
+	cacheKey := fmt.Sprintf("NodePort:%d:%s", nodePort, protocol)
+	return c.deleteFlows(c.serviceFlowCache, cacheKey)
+}
+
 func (c *client) InstallClusterServiceFlows() error {
 	flows := []binding.Flow{
 		c.l2ForwardOutputServiceHairpinFlow(),
@@ -490,30 +819,37 @@ func (c *client) InstallBridgeUplinkFlows(uplinkPort uint32, bridgeLocalPort uin
 	return nil
 }
 
-func (c *client) initialize() error {
-	if err := c.ofEntryOperations.AddAll(c.defaultFlows()); err != nil {
-		return fmt.Errorf("failed to install default flows: %v", err)
-	}
-	if err := c.ofEntryOperations.Add(c.arpNormalFlow(cookie.Default)); err != nil {
-		return fmt.Errorf("failed to install arp normal flow: %v", err)
-	}
-	if err := c.ofEntryOperations.Add(c.l2ForwardOutputFlow(cookie.Default)); err != nil {
-		return fmt.Errorf("failed to install L2 forward output flows: %v", err)
-	}
-	if err := c.ofEntryOperations.AddAll(c.connectionTrackFlows(cookie.Default)); err != nil {
-		return fmt.Errorf("failed to install connection track flows: %v", err)
-	}
-	if err := c.ofEntryOperations.AddAll(c.establishedConnectionFlows(cookie.Default)); err != nil {
-		return fmt.Errorf("failed to install flows to skip established connections: %v", err)
+// flowGroup is a group of flows installed together, with the description used
+// when they cannot be installed.
+type flowGroup struct {
+	description string
+	flows       []binding.Flow
+}
+
+// initialFlowGroups returns the flows installed when the client is initialized.
+func (c *client) initialFlowGroups() []flowGroup {
+	groups := []flowGroup{
+		{"default flows", c.defaultFlows()},
+		{"arp normal flow", []binding.Flow{c.arpNormalFlow(cookie.Default)}},
+		{"L2 forward output flows", []binding.Flow{c.l2ForwardOutputFlow(cookie.Default)}},
+		{"connection track flows", c.connectionTrackFlows(cookie.Default)},
+		{"flows to skip established connections", c.establishedConnectionFlows(cookie.Default)},
+		{"flows to check L7 states of connections", c.l7ConnectionFlows(cookie.Default)},
+		{"policy logging flow", []binding.Flow{c.policyLoggingFlow(cookie.Default)}},
 	}
 	if c.encapMode.SupportsNoEncap() {
-		if err := c.ofEntryOperations.Add(c.l2ForwardOutputReentInPortFlow(c.gatewayPort, cookie.Default)); err != nil {
-			return fmt.Errorf("failed to install L2 forward same in-port and out-port flow: %v", err)
-		}
+		groups = append(groups, flowGroup{"L2 forward same in-port and out-port flow", []binding.Flow{c.l2ForwardOutputReentInPortFlow(c.gatewayPort, cookie.Default)}})
 	}
 	if c.encapMode.IsNetworkPolicyOnly() {
-		if err := c.setupPolicyOnlyFlows(); err != nil {
-			return fmt.Errorf("failed to setup policy only flows: %w", err)
+		groups = append(groups, flowGroup{"policy-only flows", c.policyOnlyFlows()})
+	}
+	return groups
+}
+
+func (c *client) initialize() error {
+	for _, group := range c.initialFlowGroups() {
+		if err := c.ofEntryOperations.AddAll(group.flows); err != nil {
+			return fmt.Errorf("failed to install %s: %v", group.description, err)
 		}
 	}
 	return nil
@@ -536,6 +872,15 @@ func (c *client) Initialize(roundInfo types.RoundInfo, nodeConfig *config.NodeCo
 	c.roundInfo = roundInfo
 	c.cookieAllocator = cookie.NewAllocator(roundInfo.RoundNum)
 
+	// The flows installed with a different pipeline may conflict with the new ones, so they are
+	// all deleted upfront instead of after convergence, at the cost of disrupting the dataplane.
+	if roundInfo.FlushFlows {
+		klog.Info("Deleting all existing flows as the OpenFlow pipeline has changed")
+		if err := c.bridge.DeleteFlowsByCookie(0, 0); err != nil {
+			return nil, fmt.Errorf("error when deleting all existing flows: %v", err)
+		}
+	}
+
 	// In the normal case, there should be no existing flows with the current round number. This
 	// is needed in case the agent was restarted before we had a chance to increment the round
 	// number (incrementing the round number happens once we are satisfied that stale flows from
@@ -557,6 +902,45 @@ func (c *client) InstallExternalFlows(nodeIP net.IP, localSubnet net.IPNet) erro
 	return nil
 }
 
+func (c *client) InstallEgressNATBypassFlows(serviceCIDR net.IPNet) error {
+	flows := c.egressNATBypassFlows(serviceCIDR, cookie.Egress)
+	if err := c.ofEntryOperations.AddAll(flows); err != nil {
+		return fmt.Errorf("failed to install Egress NAT bypass flows: %v", err)
+	}
+	c.egressNATBypassFlowCache = flows
+	return nil
+}
+
+func (c *client) InstallRemotePodEgressNATFlow(podIP net.IP, mark uint32) error {
+	c.replayMutex.RLock()
+	defer c.replayMutex.RUnlock()
+	cacheKey := fmt.Sprintf("EgressRemotePod:%s", podIP)
+	return c.replaceFlows(c.egressFlowCache, cacheKey, []binding.Flow{c.remotePodEgressNATFlow(podIP, mark, cookie.Egress)})
+}
+
+func (c *client) UninstallRemotePodEgressNATFlow(podIP net.IP) error {
+	c.replayMutex.RLock()
+	defer c.replayMutex.RUnlock()
+	cacheKey := fmt.Sprintf("EgressRemotePod:%s", podIP)
+	return c.deleteFlows(c.egressFlowCache, cacheKey)
+}
+
+func (c *client) InstallPodEgressNATFlow(ofPort uint32, egressIP net.IP, mark uint32) error {
+	c.replayMutex.RLock()
+	defer c.replayMutex.RUnlock()
+	cacheKey := fmt.Sprintf("EgressPod:%d", ofPort)
+	// The flow of the port has the same match conditions whatever the Egress
+	// IP is, so adding it replaces the flow installed previously in OVS.
+	return c.replaceFlows(c.egressFlowCache, cacheKey, []binding.Flow{c.podEgressNATFlow(ofPort, egressIP, mark, cookie.Egress)})
+}
+
+func (c *client) UninstallPodEgressNATFlow(ofPort uint32) error {
+	c.replayMutex.RLock()
+	defer c.replayMutex.RUnlock()
+	cacheKey := fmt.Sprintf("EgressPod:%d", ofPort)
+	return c.deleteFlows(c.egressFlowCache, cacheKey)
+}
+
 func (c *client) ReplayFlows() {
 	c.replayMutex.Lock()
 	defer c.replayMutex.Unlock()
@@ -578,23 +962,18 @@ func (c *client) ReplayFlows() {
 	addFixedFlows(c.gatewayFlows)
 	addFixedFlows(c.defaultServiceFlows)
 	addFixedFlows(c.defaultTunnelFlows)
+	addFixedFlows(c.egressNATBypassFlowCache)
 	// hostNetworkingFlows is used only on Windows. Replay the flows only when there are flows in this cache.
 	if len(c.hostNetworkingFlows) > 0 {
 		addFixedFlows(c.hostNetworkingFlows)
 	}
 
-	installCachedFlows := func(key, value interface{}) bool {
-		fCache := value.(flowCache)
-		cachedFlows := make([]binding.Flow, 0)
-
-		for _, flow := range fCache {
+	var cachedFlows []binding.Flow
+	collectCachedFlows := func(key, value interface{}) bool {
+		for _, flow := range value.(flowCache) {
 			flow.Reset()
 			cachedFlows = append(cachedFlows, flow)
 		}
-
-		if err := c.ofEntryOperations.AddAll(cachedFlows); err != nil {
-			klog.Errorf("Error when replaying cached flows: %v", err)
-		}
 		return true
 	}
 
@@ -604,11 +983,17 @@ func (c *client) ReplayFlows() {
 		}
 		return true
 	})
-	c.nodeFlowCache.Range(installCachedFlows)
-	c.podFlowCache.Range(installCachedFlows)
-	c.serviceFlowCache.Range(installCachedFlows)
-
-	c.replayPolicyFlows()
+	c.nodeFlowCache.Range(collectCachedFlows)
+	c.podFlowCache.Range(collectCachedFlows)
+	c.serviceFlowCache.Range(collectCachedFlows)
+	c.egressFlowCache.Range(collectCachedFlows)
+	cachedFlows = append(cachedFlows, c.policyFlowsToReplay()...)
+
+	// The cached flows can number in the tens of thousands, they are installed
+	// with concurrent bundles instead of a bundle per cache entry.
+	if err := c.flowInstaller.Install(cachedFlows); err != nil {
+		klog.Errorf("Error when replaying cached flows: %v", err)
+	}
 }
 
 func (c *client) deleteFlowsByRoundNum(roundNum uint64) error {
@@ -624,8 +1009,8 @@ func (c *client) DeleteStaleFlows() error {
 	return c.deleteFlowsByRoundNum(*c.roundInfo.PrevRoundNum)
 }
 
-func (c *client) setupPolicyOnlyFlows() error {
-	flows := []binding.Flow{
+func (c *client) policyOnlyFlows() []binding.Flow {
+	return []binding.Flow{
 		// Bypasses remaining l3forwarding flows if the MAC is set via ctRewriteDstMACFlow.
 		c.l3BypassMACRewriteFlow(c.nodeConfig.GatewayConfig.MAC, cookie.Default),
 		// Rewrites MAC to gw port if the packet received is unmatched by local Pod flows.
@@ -633,10 +1018,6 @@ func (c *client) setupPolicyOnlyFlows() error {
 		// Replies any ARP request with the same global virtual MAC.
 		c.arpResponderStaticFlow(cookie.Default),
 	}
-	if err := c.ofEntryOperations.AddAll(flows); err != nil {
-		return fmt.Errorf("failed to setup policy-only flows: %w", err)
-	}
-	return nil
 }
 
 func (c *client) SubscribePacketIn(reason uint8, ch chan *ofctrl.PacketIn) error {
@@ -717,14 +1098,19 @@ func (c *client) SendTraceflowPacket(
 }
 
 func (c *client) InstallTraceflowFlows(dataplaneTag uint8) error {
-	flow := c.traceflowL2ForwardOutputFlow(dataplaneTag, cookie.Default)
+	flow := c.traceflowL2ForwardOutputFlow(dataplaneTag, TraceflowTimeout, cookie.Default)
 	if err := c.Add(flow); err != nil {
 		return err
 	}
-	flow = c.traceflowConnectionTrackFlows(dataplaneTag, cookie.Default)
-	if err := c.Add(flow); err != nil {
+	if err := c.AddAll(c.traceflowConnectionTrackFlows(dataplaneTag, TraceflowTimeout, cookie.Default)); err != nil {
 		return err
 	}
+	return c.AddAll(c.traceflowNetworkPolicyDropFlows(dataplaneTag, TraceflowTimeout))
+}
+
+// traceflowNetworkPolicyDropFlows copies the drop flows of the NetworkPolicy rules, so that the traceflow packets
+// with the data plane tag are sent to Antrea Agent before they are dropped.
+func (c *client) traceflowNetworkPolicyDropFlows(dataplaneTag uint8, timeout uint16) []binding.Flow {
 	flows := []binding.Flow{}
 	c.conjMatchFlowLock.Lock()
 	defer c.conjMatchFlowLock.Unlock()
@@ -734,12 +1120,46 @@ func (c *client) InstallTraceflowFlows(dataplaneTag uint8) error {
 				flows,
 				ctx.dropFlow.CopyToBuilder(priorityNormal+2).
 					MatchRegRange(int(TraceflowReg), uint32(dataplaneTag), OfTraceflowMarkRange).
-					SetHardTimeout(300).
+					SetHardTimeout(timeout).
 					Action().SendToController(1).
 					Done())
 		}
 	}
-	return c.AddAll(flows)
+	return flows
+}
+
+// The synthetic traceflow packets do not go through the connection tracking flows like the live packets, which
+// belong to a connection. Hence only the output and drop flows are installed for the live traffic.
+func (c *client) InstallTraceflowLiveFlows(dataplaneTag uint8, timeout uint16) error {
+	flows := []binding.Flow{c.traceflowL2ForwardOutputFlow(dataplaneTag, timeout, cookie.Default)}
+	flows = append(flows, c.traceflowNetworkPolicyDropFlows(dataplaneTag, timeout)...)
+	return c.addFlows(c.traceflowFlowCache, fmt.Sprintf("%d", dataplaneTag), flows)
+}
+
+func (c *client) InstallTraceflowLiveCaptureFlow(
+	dataplaneTag uint8,
+	ofPort uint32,
+	srcMAC net.HardwareAddr,
+	srcIP net.IP,
+	dstIP net.IP,
+	IPProtocol uint8,
+	srcPort uint16,
+	dstPort uint16,
+	timeout uint16) error {
+	flow := c.traceflowLiveCaptureFlow(dataplaneTag, srcIP, srcMAC, ofPort, dstIP, IPProtocol, srcPort, dstPort, timeout, cookie.Default)
+	return c.addFlows(c.traceflowFlowCache, fmt.Sprintf("%d-capture", dataplaneTag), []binding.Flow{flow})
+}
+
+func (c *client) UninstallTraceflowLiveFlows(dataplaneTag uint8) error {
+	// Remove the capture flow first to stop marking new packets.
+	if err := c.deleteFlows(c.traceflowFlowCache, fmt.Sprintf("%d-capture", dataplaneTag)); err != nil {
+		return err
+	}
+	return c.deleteFlows(c.traceflowFlowCache, fmt.Sprintf("%d", dataplaneTag))
+}
+
+func (c *client) InstallTraceflowServiceNoEndpointsFlow(dataplaneTag uint8, svcIP net.IP, svcPort uint16, protocol binding.Protocol, timeout uint16) error {
+	return c.Add(c.traceflowServiceNoEndpointsFlow(dataplaneTag, svcIP, svcPort, protocol, timeout, cookie.Default))
 }
 
 // Add TLV map optClass 0x0104, optType 0x80 optLength 4 tunMetadataIndex 0 to store data plane tag
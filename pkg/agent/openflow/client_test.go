@@ -237,3 +237,17 @@ func TestConcurrentFlowInstallation(t *testing.T) {
 	}
 
 }
+
+func TestPipelineVersion(t *testing.T) {
+	pipelineVersion := PipelineVersion()
+	assert.Equal(t, pipelineVersion, PipelineVersion(), "Pipeline version should be stable")
+
+	// Any change to the flow tables must change the pipeline version.
+	flowTables := FlowTables
+	defer func() { FlowTables = flowTables }()
+	FlowTables = flowTables[:len(flowTables)-1]
+	assert.NotEqual(t, pipelineVersion, PipelineVersion(), "Removing a table should change the pipeline version")
+	FlowTables = append(FlowTables[:0:0], flowTables...)
+	FlowTables[0].Number++
+	assert.NotEqual(t, pipelineVersion, PipelineVersion(), "Renumbering a table should change the pipeline version")
+}
@@ -24,6 +24,9 @@ const (
 	BitwidthReserved        = 64 - BitwidthCategory - BitwidthRound
 	RoundMask        uint64 = 0xffff_0000_0000_0000
 	CategoryMask     uint64 = 0x0000_ff00_0000_0000
+	PolicyActionMask uint64 = 0x0000_00ff_0000_0000
+	ObjectIDMask     uint64 = 0x0000_0000_ffff_ffff
+	BitwidthObjectID        = 32
 )
 
 // Category represents the flow entry category.
@@ -37,8 +40,43 @@ const (
 	Service
 	Policy
 	SNAT
+	Egress
 )
 
+// PolicyAction represents the action taken by a flow of the NetworkPolicy
+// tables on the packets it matches.
+type PolicyAction uint64
+
+const (
+	// PolicyActionNone is the action of the flows which are not NetworkPolicy
+	// flows, or which do not decide what happens to the packets, e.g. the
+	// conjunctive match flows.
+	PolicyActionNone PolicyAction = iota
+	// PolicyActionAllow is the action of the flows which let the packets go
+	// past the NetworkPolicy tables.
+	PolicyActionAllow
+	// PolicyActionDeny is the action of the flows which drop the packets.
+	PolicyActionDeny
+	// PolicyActionJump is the action of the flows which send the packets
+	// to the next NetworkPolicy table without any verdict.
+	PolicyActionJump
+)
+
+func (a PolicyAction) String() string {
+	switch a {
+	case PolicyActionNone:
+		return "none"
+	case PolicyActionAllow:
+		return "allow"
+	case PolicyActionDeny:
+		return "deny"
+	case PolicyActionJump:
+		return "jump"
+	default:
+		return "invalid"
+	}
+}
+
 func (c Category) String() string {
 	switch c {
 	case Default:
@@ -55,6 +93,8 @@ func (c Category) String() string {
 		return "Policy"
 	case SNAT:
 		return "SNAT"
+	case Egress:
+		return "Egress"
 	default:
 		return "Invalid"
 	}
@@ -65,6 +105,7 @@ func (c Category) String() string {
 //  |- round 16bits -|- category 8bits -|- reserved 8bits -|- objectID 32bits -|
 // The round segment represents the round id.
 // The category segment represents the category of flow this ID belongs.
+// The reserved segment stores the PolicyAction of the NetworkPolicy flows.
 type ID uint64
 
 func newID(round uint64, cat Category, objectID uint32) ID {
@@ -96,6 +137,18 @@ func (i ID) Category() Category {
 	return Category((i.Raw() & CategoryMask) >> BitwidthReserved)
 }
 
+// PolicyAction returns the PolicyAction encoded in the ID.
+func (i ID) PolicyAction() PolicyAction {
+	return PolicyAction((i.Raw() & PolicyActionMask) >> BitwidthObjectID)
+}
+
+// WithPolicyAction returns a copy of the ID which encodes the PolicyAction.
+func (i ID) WithPolicyAction(action PolicyAction) ID {
+	r := i.Raw() &^ PolicyActionMask
+	r |= (uint64(action) << BitwidthObjectID) & PolicyActionMask
+	return ID(r)
+}
+
 // String returns the string representation of the ID.
 func (i ID) String() string {
 	return fmt.Sprintf("<round:%d,category:%s>", i.Round(), i.Category().String())
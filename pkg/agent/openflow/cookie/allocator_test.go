@@ -73,3 +73,17 @@ func TestConcurrentAllocate(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestPolicyAction(t *testing.T) {
+	a := NewAllocator(0x1234)
+	id := a.RequestWithObjectID(Policy, 0xffffffff)
+	assert.Equal(t, PolicyActionNone, id.PolicyAction())
+
+	for _, action := range []PolicyAction{PolicyActionAllow, PolicyActionDeny, PolicyActionJump, PolicyActionNone} {
+		actionID := id.WithPolicyAction(action)
+		assert.Equal(t, action, actionID.PolicyAction(), actionID.String())
+		assert.Equal(t, uint64(0x1234), actionID.Round())
+		assert.Equal(t, Policy, actionID.Category())
+		assert.Equal(t, uint64(0xffffffff), actionID.Raw()&0xffffffff)
+	}
+}
@@ -15,12 +15,17 @@
 package openflow
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"net"
 	"strconv"
 
+	"github.com/contiv/libOpenflow/protocol"
+	"github.com/contiv/ofnet/ofctrl"
 	"k8s.io/klog"
 
+	"github.com/vmware-tanzu/antrea/pkg/agent/openflow/cookie"
 	"github.com/vmware-tanzu/antrea/pkg/agent/types"
 	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
 	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
@@ -453,6 +458,19 @@ type policyRuleConjunction struct {
 	// NetworkPolicy name and Namespace information for debugging usage.
 	npName      string
 	npNamespace string
+	// baselineDirection is the direction of the rule if it belongs to a baseline ClusterNetworkPolicy, nil otherwise.
+	baselineDirection *v1beta1.Direction
+	// httpMatches is true if the rule has HTTP matches, in which case the agent installs flows to deny the TCP
+	// connections whose HTTP requests it has denied with the rule.
+	httpMatches bool
+}
+
+// baselineAppliedTo is an address the rules of baseline ClusterNetworkPolicies of a direction are applied to.
+type baselineAppliedTo struct {
+	// flow is the conjunctive match flow of the address in the catch-all conjunction of the direction.
+	flow binding.Flow
+	// rules is the set of the IDs of the baseline rules applied to the address.
+	rules map[uint32]bool
 }
 
 // clause groups conjunctive match flows. Matches in a clause represent source addresses(for fromClause), or destination
@@ -734,10 +752,18 @@ func (c *client) InstallPolicyRuleFlows(ruleID uint32, rule *types.PolicyRule, n
 	if nClause > 1 {
 		// Install action flows.
 		var actionFlows []binding.Flow
-		if rule.IsAntreaNetworkPolicyRule() && *rule.Action == secv1alpha1.RuleActionDrop {
-			actionFlows = append(actionFlows, c.conjunctionActionDropFlow(ruleID, ruleTable.GetID(), rule.Priority))
+		nextTable := dropTable.GetNext()
+		if rule.Baseline {
+			nextTable = getBaselineNextTable(rule.Direction)
+		}
+		if rule.IsAntreaNetworkPolicyRule() && len(rule.HTTPMatches) > 0 {
+			// The HTTP requests of the connections matching a rule with HTTP matches are evaluated by the agent.
+			actionFlows = append(actionFlows, c.conjunctionL7ActionFlow(ruleID, ruleTable.GetID(), nextTable, rule.Priority))
+			conj.httpMatches = true
+		} else if rule.IsAntreaNetworkPolicyRule() && *rule.Action == secv1alpha1.RuleActionDrop {
+			actionFlows = append(actionFlows, c.conjunctionActionDropFlow(ruleID, ruleTable.GetID(), rule.Priority, rule.EnableLogging))
 		} else {
-			actionFlows = append(actionFlows, c.conjunctionActionFlow(ruleID, ruleTable.GetID(), dropTable.GetNext(), rule.Priority))
+			actionFlows = append(actionFlows, c.conjunctionActionFlow(ruleID, ruleTable.GetID(), nextTable, rule.Priority, rule.EnableLogging, rule.PreserveSourceIP))
 		}
 		if err := c.ofEntryOperations.AddAll(actionFlows); err != nil {
 			return nil
@@ -755,11 +781,210 @@ func (c *client) InstallPolicyRuleFlows(ruleID uint32, rule *types.PolicyRule, n
 	if err := c.applyConjunctiveMatchFlows(ctxChanges); err != nil {
 		return err
 	}
+	if rule.Baseline {
+		direction := getBaselineDirection(rule.Direction)
+		appliedTo := rule.To
+		if direction == v1beta1.DirectionOut {
+			appliedTo = rule.From
+		}
+		if err := c.addBaselineRule(ruleID, direction, appliedTo); err != nil {
+			return err
+		}
+		conj.baselineDirection = &direction
+	}
 	// Add the policyRuleConjunction into policyCache.
 	c.policyCache.Add(conj)
 	return nil
 }
 
+// getBaselineDirection returns the direction of a baseline rule, which defaults to ingress like the other rules.
+func getBaselineDirection(direction v1beta1.Direction) v1beta1.Direction {
+	if direction == v1beta1.DirectionOut {
+		return v1beta1.DirectionOut
+	}
+	return v1beta1.DirectionIn
+}
+
+// getBaselineAppliedToType returns the type of the addresses the baseline rules of the direction are applied to, i.e.
+// the addresses of the local Pods selected by their appliedTo.
+func getBaselineAppliedToType(direction v1beta1.Direction) types.AddressType {
+	if direction == v1beta1.DirectionOut {
+		return types.SrcAddress
+	}
+	return types.DstAddress
+}
+
+// getBaselineRuleTable returns the CNP rule table in which the catch-all flows of the direction are installed.
+func getBaselineRuleTable(direction v1beta1.Direction) binding.TableIDType {
+	if direction == v1beta1.DirectionOut {
+		return cnpEgressRuleTable
+	}
+	return cnpIngressRuleTable
+}
+
+// getBaselineDropConjID returns the reserved conjunction ID of the catch-all flows of the direction.
+func getBaselineDropConjID(direction v1beta1.Direction) uint32 {
+	if direction == v1beta1.DirectionOut {
+		return BaselineDropConjIDOut
+	}
+	return BaselineDropConjIDIn
+}
+
+// addBaselineRule counts a new rule of a baseline ClusterNetworkPolicy, installs the catch-all flows of its direction
+// if it is the first one, and adds the addresses it is applied to to the catch-all conjunction. The caller must hold
+// conjMatchFlowLock.
+func (c *client) addBaselineRule(ruleID uint32, direction v1beta1.Direction, appliedTo []types.Address) error {
+	if c.baselineRules[direction] == 0 {
+		flows := c.baselineDefaultFlows(direction)
+		if err := c.ofEntryOperations.AddAll(flows); err != nil {
+			return err
+		}
+		c.baselineDefaultFlowCache[direction] = flows
+	}
+	c.baselineRules[direction]++
+	return c.addBaselineAppliedTo(ruleID, direction, appliedTo)
+}
+
+// deleteBaselineRule stops counting a deleted rule of a baseline ClusterNetworkPolicy, removes the addresses it was
+// the last rule to be applied to from the catch-all conjunction, and uninstalls the catch-all flows of its direction
+// if it was the last rule. The caller must hold conjMatchFlowLock.
+func (c *client) deleteBaselineRule(ruleID uint32, direction v1beta1.Direction) error {
+	var keys []string
+	for key, appliedTo := range c.baselineAppliedToCache[direction] {
+		if appliedTo.rules[ruleID] {
+			keys = append(keys, key)
+		}
+	}
+	if err := c.releaseBaselineAppliedTo(ruleID, direction, keys); err != nil {
+		return err
+	}
+	if c.baselineRules[direction] == 1 {
+		if err := c.ofEntryOperations.DeleteAll(c.baselineDefaultFlowCache[direction]); err != nil {
+			return err
+		}
+		delete(c.baselineDefaultFlowCache, direction)
+	}
+	c.baselineRules[direction]--
+	return nil
+}
+
+// baselineAppliedToMatch generates the conjunctive match of an address in the catch-all conjunction of the direction.
+func baselineAppliedToMatch(direction v1beta1.Direction, addr types.Address) *conjunctiveMatch {
+	priority := priorityBaselineDrop
+	return &conjunctiveMatch{
+		tableID:    getBaselineRuleTable(direction),
+		matchKey:   addr.GetMatchKey(getBaselineAppliedToType(direction)),
+		matchValue: addr.GetValue(),
+		priority:   &priority,
+	}
+}
+
+// addBaselineAppliedTo adds the addresses a baseline rule is applied to to the catch-all conjunction of the
+// direction. The conjunctive match flow of an address is installed when the first rule is applied to it. The caller
+// must hold conjMatchFlowLock.
+func (c *client) addBaselineAppliedTo(ruleID uint32, direction v1beta1.Direction, addresses []types.Address) error {
+	cache, ok := c.baselineAppliedToCache[direction]
+	if !ok {
+		cache = map[string]*baselineAppliedTo{}
+		c.baselineAppliedToCache[direction] = cache
+	}
+	action := &conjunctiveAction{conjID: getBaselineDropConjID(direction), clauseID: 1, nClause: 2}
+	newAppliedTo := map[string]*baselineAppliedTo{}
+	var flows []binding.Flow
+	for _, addr := range addresses {
+		match := baselineAppliedToMatch(direction, addr)
+		key := match.generateGlobalMapKey()
+		if appliedTo, found := cache[key]; found {
+			appliedTo.rules[ruleID] = true
+			continue
+		}
+		if _, found := newAppliedTo[key]; found {
+			continue
+		}
+		flow := c.conjunctiveMatchFlow(match.tableID, match.matchKey, match.matchValue, match.priority, action)
+		newAppliedTo[key] = &baselineAppliedTo{flow: flow, rules: map[uint32]bool{ruleID: true}}
+		flows = append(flows, flow)
+	}
+	if len(flows) == 0 {
+		return nil
+	}
+	if err := c.ofEntryOperations.AddAll(flows); err != nil {
+		return err
+	}
+	for key, appliedTo := range newAppliedTo {
+		cache[key] = appliedTo
+	}
+	return nil
+}
+
+// deleteBaselineAppliedTo removes the addresses a baseline rule is not applied to anymore from the catch-all
+// conjunction of the direction. The caller must hold conjMatchFlowLock.
+func (c *client) deleteBaselineAppliedTo(ruleID uint32, direction v1beta1.Direction, addresses []types.Address) error {
+	keys := make([]string, 0, len(addresses))
+	for _, addr := range addresses {
+		keys = append(keys, baselineAppliedToMatch(direction, addr).generateGlobalMapKey())
+	}
+	return c.releaseBaselineAppliedTo(ruleID, direction, keys)
+}
+
+// releaseBaselineAppliedTo removes a baseline rule from the rules applied to the addresses with the provided keys, and
+// uninstalls the conjunctive match flows of the addresses no baseline rule is applied to anymore. The caller must hold
+// conjMatchFlowLock.
+func (c *client) releaseBaselineAppliedTo(ruleID uint32, direction v1beta1.Direction, keys []string) error {
+	cache := c.baselineAppliedToCache[direction]
+	var flows []binding.Flow
+	var staleKeys []string
+	for _, key := range keys {
+		appliedTo, found := cache[key]
+		if !found || !appliedTo.rules[ruleID] {
+			continue
+		}
+		if len(appliedTo.rules) == 1 {
+			flows = append(flows, appliedTo.flow)
+			staleKeys = append(staleKeys, key)
+		} else {
+			delete(appliedTo.rules, ruleID)
+		}
+	}
+	if len(flows) == 0 {
+		return nil
+	}
+	if err := c.ofEntryOperations.DeleteAll(flows); err != nil {
+		return err
+	}
+	for _, key := range staleKeys {
+		delete(cache, key)
+	}
+	return nil
+}
+
+// baselineDefaultFlows generates the catch-all flows of the CNP rule table of the direction, which drop the traffic
+// of the local Pods that no ClusterNetworkPolicy rule allowed, so that the baseline tier denies by default the traffic
+// it does not allow. The traffic is dropped with a conjunction of two clauses: the first one matches the addresses the
+// baseline rules of the direction are applied to, and is installed by addBaselineAppliedTo; the second one matches any
+// IP packet. For ingress, the probes sent by the kubelet through the gateway are not dropped.
+func (c *client) baselineDefaultFlows(direction v1beta1.Direction) []binding.Flow {
+	conjID := getBaselineDropConjID(direction)
+	tableID := getBaselineRuleTable(direction)
+	ruleTable := c.pipeline[tableID]
+	priority := priorityBaselineDrop
+	flows := []binding.Flow{
+		ruleTable.BuildFlow(priorityBaselineDrop).MatchProtocol(binding.ProtocolIP).
+			Action().Conjunction(conjID, 2, 2).
+			Cookie(c.cookieAllocator.Request(cookie.Policy).Raw()).
+			Done(),
+		c.conjunctionActionDropFlow(conjID, tableID, &priority, false),
+	}
+	if direction == v1beta1.DirectionIn {
+		flows = append(flows, ruleTable.BuildFlow(priorityBaselineBypass).MatchProtocol(binding.ProtocolIP).
+			MatchSrcIP(c.nodeConfig.GatewayConfig.IP).
+			Action().GotoTable(ruleTable.GetNext()).
+			Cookie(c.cookieAllocator.Request(cookie.Policy).WithPolicyAction(cookie.PolicyActionJump).Raw()).
+			Done())
+	}
+	return flows
+}
+
 // applyConjunctiveMatchFlows installs OpenFlow entries on the OVS bridge, and then updates the conjMatchFlowContext.
 func (c *client) applyConjunctiveMatchFlows(flowChanges []*conjMatchFlowContextChange) error {
 	// Send the OpenFlow entries to the OVS bridge.
@@ -822,6 +1047,17 @@ func (c *policyRuleConjunction) newClause(clauseID uint8, nClause uint8, ruleTab
 	}
 }
 
+// getBaselineNextTable returns the table to which the traffic allowed by a
+// baseline ClusterNetworkPolicy rule is forwarded. Unlike the other Antrea
+// NetworkPolicy rules, the K8s NetworkPolicy rule table of the same direction
+// is not skipped, so that K8s NetworkPolicies still apply to the traffic.
+func getBaselineNextTable(direction v1beta1.Direction) binding.TableIDType {
+	if direction == v1beta1.DirectionOut {
+		return EgressRuleTable
+	}
+	return IngressRuleTable
+}
+
 // calculateClauses configures the policyRuleConjunction's clauses according to the PolicyRule. The Openflow entries are
 // not installed on the OVS bridge when calculating the clauses.
 func (c *policyRuleConjunction) calculateClauses(rule *types.PolicyRule, clnt *client) (uint8, binding.Table, binding.Table) {
@@ -982,6 +1218,13 @@ func (c *client) UninstallPolicyRuleFlows(ruleID uint32) ([]string, error) {
 	if err := c.ofEntryOperations.DeleteAll(conj.actionFlows); err != nil {
 		return nil, err
 	}
+	// The flows learned by the action flow of a rule with HTTP matches are deleted with it, but the flows denying the
+	// connections of the rule must be deleted by their cookies.
+	if conj.httpMatches {
+		if err := c.uninstallL7ConnDenyFlows(ruleID); err != nil {
+			return nil, err
+		}
+	}
 
 	c.conjMatchFlowLock.Lock()
 	defer c.conjMatchFlowLock.Unlock()
@@ -991,6 +1234,11 @@ func (c *client) UninstallPolicyRuleFlows(ruleID uint32) ([]string, error) {
 	if err := c.applyConjunctiveMatchFlows(ctxChanges); err != nil {
 		return nil, err
 	}
+	if conj.baselineDirection != nil {
+		if err := c.deleteBaselineRule(ruleID, *conj.baselineDirection); err != nil {
+			return nil, err
+		}
+	}
 
 	for _, p := range ofPrioritiesToUninstallFlows {
 		conjsStalePriority, _ := c.policyCache.ByIndex(priorityIndex, p)
@@ -1003,36 +1251,41 @@ func (c *client) UninstallPolicyRuleFlows(ruleID uint32) ([]string, error) {
 	return staleOFPriorities, nil
 }
 
-func (c *client) replayPolicyFlows() {
+// policyFlows returns the flows of the policy rules: the action flows of the
+// rules, the conjunctive match flows and drop flows shared by the rules, and the
+// catch-all flows of the baseline tier.
+func (c *client) policyFlows() []binding.Flow {
 	var flows []binding.Flow
-	addActionFlows := func(conj *policyRuleConjunction) {
-		for _, flow := range conj.actionFlows {
-			flow.Reset()
-			flows = append(flows, flow)
-		}
-	}
-
 	for _, conj := range c.policyCache.List() {
-		addActionFlows(conj.(*policyRuleConjunction))
+		flows = append(flows, conj.(*policyRuleConjunction).actionFlows...)
 	}
-
-	addMatchFlows := func(ctx *conjMatchFlowContext) {
+	for _, baselineFlows := range c.baselineDefaultFlowCache {
+		flows = append(flows, baselineFlows...)
+	}
+	for _, cache := range c.baselineAppliedToCache {
+		for _, appliedTo := range cache {
+			flows = append(flows, appliedTo.flow)
+		}
+	}
+	for _, ctx := range c.globalConjMatchFlowCache {
 		if ctx.dropFlow != nil {
-			ctx.dropFlow.Reset()
 			flows = append(flows, ctx.dropFlow)
 		}
 		if ctx.flow != nil {
-			ctx.flow.Reset()
 			flows = append(flows, ctx.flow)
 		}
 	}
+	return flows
+}
 
-	for _, ctx := range c.globalConjMatchFlowCache {
-		addMatchFlows(ctx)
-	}
-	if err := c.ofEntryOperations.AddAll(flows); err != nil {
-		klog.Errorf("Error when replaying flows: %v", err)
+// policyFlowsToReplay returns all the NetworkPolicy flows, reset so that they
+// can be replayed.
+func (c *client) policyFlowsToReplay() []binding.Flow {
+	flows := c.policyFlows()
+	for _, flow := range flows {
+		flow.Reset()
 	}
+	return flows
 }
 
 // AddPolicyRuleAddress adds one or multiple addresses to the specified NetworkPolicy rule. If addrType is srcAddress, the
@@ -1057,7 +1310,13 @@ func (c *client) AddPolicyRuleAddress(ruleID uint32, addrType types.AddressType,
 	c.conjMatchFlowLock.Lock()
 	defer c.conjMatchFlowLock.Unlock()
 	flowChanges := clause.addAddrFlows(c, addrType, addresses, priority)
-	return c.applyConjunctiveMatchFlows(flowChanges)
+	if err := c.applyConjunctiveMatchFlows(flowChanges); err != nil {
+		return err
+	}
+	if conj.baselineDirection != nil && addrType == getBaselineAppliedToType(*conj.baselineDirection) {
+		return c.addBaselineAppliedTo(ruleID, *conj.baselineDirection, addresses)
+	}
+	return nil
 }
 
 // DeletePolicyRuleAddress removes addresses from the specified NetworkPolicy rule. If addrType is srcAddress, the addresses
@@ -1085,13 +1344,19 @@ func (c *client) DeletePolicyRuleAddress(ruleID uint32, addrType types.AddressTy
 	// Remove policyRuleConjunction to actions of conjunctive match using specific address.
 	changes := clause.deleteAddrFlows(addrType, addresses, priority)
 	// Update the Openflow entries on the OVS bridge, and update local cache.
-	return c.applyConjunctiveMatchFlows(changes)
+	if err := c.applyConjunctiveMatchFlows(changes); err != nil {
+		return err
+	}
+	if conj.baselineDirection != nil && addrType == getBaselineAppliedToType(*conj.baselineDirection) {
+		return c.deleteBaselineAppliedTo(ruleID, *conj.baselineDirection, addresses)
+	}
+	return nil
 }
 
 func (c *client) GetNetworkPolicyFlowKeys(npName, npNamespace string) []string {
 	flowKeys := []string{}
 	// Hold replayMutex write lock to protect flows from being modified by
-	// NetworkPolicy updates and replayPolicyFlows. This is more for logic
+	// NetworkPolicy updates and ReplayFlows. This is more for logic
 	// cleanliness, as: for now flow updates do not impact the matching string
 	// generation; NetworkPolicy updates do not change policyRuleConjunction.actionFlows;
 	// and last for protection of clause flows, conjMatchFlowLock is good enough.
@@ -1236,6 +1501,105 @@ func (c *client) calculateFlowUpdates(updates map[uint16]uint16) (addFlows, delF
 	return addFlows, delFlows, conjFlowUpdates
 }
 
+// InstallL7ConnDenyFlow installs the flow which denies a TCP connection in the L7 connection table of the provided
+// direction, after the agent has denied one of its HTTP requests with the rule.
+func (c *client) InstallL7ConnDenyFlow(ruleID uint32, direction v1beta1.Direction, srcIP, dstIP net.IP, srcPort, dstPort uint16) error {
+	connTable := cnpIngressL7ConnTable
+	if direction == v1beta1.DirectionOut {
+		connTable = cnpEgressL7ConnTable
+	}
+	return c.ofEntryOperations.Add(c.l7ConnDenyFlow(ruleID, connTable, srcIP, dstIP, srcPort, dstPort))
+}
+
+// uninstallL7ConnDenyFlows removes the flows installed by InstallL7ConnDenyFlow for the rule.
+func (c *client) uninstallL7ConnDenyFlows(ruleID uint32) error {
+	cookieID := c.cookieAllocator.RequestWithObjectID(cookie.Policy, ruleID).Raw()
+	return c.bridge.DeleteFlowsByCookie(cookieID, cookie.RoundMask|cookie.CategoryMask|cookie.PolicyActionMask|cookie.ObjectIDMask)
+}
+
+// ReinjectL7Packet sends a packet, which the L7 verdict table of the provided direction sent to the controller, back
+// to the OVS pipeline after the agent has allowed the HTTP requests it carries. The packet resumes at the table
+// following the verdict table with the registers it had when it was sent to the controller, except that its L7 state
+// is cleared and it is marked as re-injected. Packets with IP options are not supported.
+func (c *client) ReinjectL7Packet(direction v1beta1.Direction, pktIn *ofctrl.PacketIn) error {
+	ipPacket, ok := pktIn.Data.Data.(*protocol.IPv4)
+	if !ok || ipPacket.Protocol != protocol.Type_TCP || ipPacket.Data == nil {
+		return errors.New("invalid TCP packet")
+	}
+	if ipPacket.IHL != 5 {
+		return errors.New("IP options are not supported")
+	}
+	segment, err := ipPacket.Data.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	tcpHeader := new(protocol.TCP)
+	if err := tcpHeader.UnmarshalBinary(segment); err != nil {
+		return err
+	}
+	// The TCP header of libOpenflow doesn't keep the NS, CWR and ECE flags, whose removal must be reflected in the
+	// checksum of the segment.
+	oldWord := binary.BigEndian.Uint16(segment[12:14])
+	tcpHeader.Checksum = adjustChecksum(tcpHeader.Checksum, oldWord, oldWord&0xf03f)
+	ipHeader := *ipPacket
+	ipHeader.Data = nil
+
+	matches := pktIn.GetMatches()
+	inPortMatch := matches.GetMatchByName("OXM_OF_IN_PORT")
+	if inPortMatch == nil {
+		return errors.New("in_port not found in packetIn")
+	}
+	inPort, _ := inPortMatch.GetValue().(uint32)
+	pktOut := &ofctrl.PacketOut{
+		InPort:    inPort,
+		SrcMAC:    pktIn.Data.HWSrc,
+		DstMAC:    pktIn.Data.HWDst,
+		IPHeader:  &ipHeader,
+		TCPHeader: tcpHeader,
+	}
+	reinjectMark := l7ReinjectIngress
+	if direction == v1beta1.DirectionOut {
+		reinjectMark = l7ReinjectEgress
+	}
+	regRange := binding.Range{0, 31}
+	for reg := marksReg; reg <= TraceflowReg; reg++ {
+		var value uint32
+		if match := matches.GetMatchByName(reg.nxm()); match != nil {
+			regValue, ok := match.GetValue().(*ofctrl.NXRegister)
+			if !ok {
+				return errors.New("register value cannot be got")
+			}
+			value = regValue.Data
+		} else if reg != marksReg {
+			continue
+		}
+		if reg == marksReg {
+			value &^= rangeMask(l7StateRange) | rangeMask(l7ReinjectRange)
+			value |= reinjectMark << l7ReinjectRange[0]
+		}
+		loadAction, err := ofctrl.NewNXLoadAction(reg.nxm(), uint64(value), regRange.ToNXRange())
+		if err != nil {
+			return err
+		}
+		pktOut.Actions = append(pktOut.Actions, loadAction)
+	}
+	return c.bridge.SendPacketOut(pktOut)
+}
+
+// rangeMask returns the mask of the bits of the range in a register.
+func rangeMask(rng binding.Range) uint32 {
+	return uint32(1<<rng.Length()-1) << rng[0]
+}
+
+// adjustChecksum returns the Internet checksum updated after a 16-bit word of the checksummed data has changed from
+// oldWord to newWord, as described in RFC 1624.
+func adjustChecksum(checksum, oldWord, newWord uint16) uint16 {
+	sum := uint32(^checksum) + uint32(^oldWord) + uint32(newWord)
+	sum = (sum & 0xffff) + (sum >> 16)
+	sum = (sum & 0xffff) + (sum >> 16)
+	return ^uint16(sum)
+}
+
 // ReassignFlowPriorities takes a list of priority updates, and update the actionFlows to replace
 // the old priority with the desired one, for each priority update.
 func (c *client) ReassignFlowPriorities(updates map[uint16]uint16) error {
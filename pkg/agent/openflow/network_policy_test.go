@@ -1,12 +1,17 @@
 package openflow
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
 	"strconv"
 	"strings"
 	"testing"
 
+	"github.com/contiv/libOpenflow/openflow13"
+	"github.com/contiv/libOpenflow/protocol"
+	"github.com/contiv/libOpenflow/util"
+	"github.com/contiv/ofnet/ofctrl"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -434,6 +439,9 @@ func prepareClient(ctrl *gomock.Controller) *client {
 		},
 		policyCache:              policyCache,
 		globalConjMatchFlowCache: map[string]*conjMatchFlowContext{},
+		baselineRules:            map[v1beta1.Direction]int{},
+		baselineDefaultFlowCache: map[v1beta1.Direction][]binding.Flow{},
+		baselineAppliedToCache:   map[v1beta1.Direction]map[string]*baselineAppliedTo{},
 		bridge:                   bridge,
 	}
 	c.cookieAllocator = cookie.NewAllocator(0)
@@ -443,3 +451,325 @@ func prepareClient(ctrl *gomock.Controller) *client {
 	c.ofEntryOperations = m
 	return c
 }
+
+// tracedAction is an action recorded by the flow builders of
+// TestPolicyLoggingTable.
+type tracedAction struct {
+	name  string
+	table binding.TableIDType
+	reg   int
+	value uint32
+}
+
+// newTracingFlowBuilder returns a mock FlowBuilder which records the actions of
+// the flows of a table in tableActions.
+func newTracingFlowBuilder(ctrl *gomock.Controller, table binding.TableIDType, tableActions map[binding.TableIDType][]tracedAction) *mocks.MockFlowBuilder {
+	builder := mocks.NewMockFlowBuilder(ctrl)
+	builder.EXPECT().MatchProtocol(gomock.Any()).Return(builder).AnyTimes()
+	builder.EXPECT().MatchConjID(gomock.Any()).Return(builder).AnyTimes()
+	builder.EXPECT().MatchPriority(gomock.Any()).Return(builder).AnyTimes()
+	builder.EXPECT().Cookie(gomock.Any()).Return(builder).AnyTimes()
+	builder.EXPECT().Done().Return(mocks.NewMockFlow(ctrl)).AnyTimes()
+	record := func(action tracedAction) binding.FlowBuilder {
+		tableActions[table] = append(tableActions[table], action)
+		return builder
+	}
+	action := mocks.NewMockAction(ctrl)
+	builder.EXPECT().Action().Return(action).AnyTimes()
+	action.EXPECT().LoadRegRange(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(regID int, value uint32, rng binding.Range) binding.FlowBuilder {
+		return record(tracedAction{name: "load", reg: regID, value: value})
+	}).AnyTimes()
+	action.EXPECT().ResubmitToTable(gomock.Any()).DoAndReturn(func(table binding.TableIDType) binding.FlowBuilder {
+		return record(tracedAction{name: "resubmit", table: table})
+	}).AnyTimes()
+	action.EXPECT().GotoTable(gomock.Any()).DoAndReturn(func(table binding.TableIDType) binding.FlowBuilder {
+		return record(tracedAction{name: "goto", table: table})
+	}).AnyTimes()
+	action.EXPECT().Drop().DoAndReturn(func() binding.FlowBuilder {
+		return record(tracedAction{name: "drop"})
+	}).AnyTimes()
+	action.EXPECT().SendToController(gomock.Any()).DoAndReturn(func(reason uint8) binding.FlowBuilder {
+		return record(tracedAction{name: "controller", value: uint32(reason)})
+	}).AnyTimes()
+	return builder
+}
+
+// packetTrace is the result of tracePacket.
+type packetTrace struct {
+	tables []binding.TableIDType
+	// loggedConjID is the value of policyLoggingReg when the packet was sent
+	// to the controller, 0 if it was not.
+	loggedConjID uint32
+	dropped      bool
+}
+
+// tracePacket follows a packet matching the flow of each table whose actions are
+// recorded in tableActions, starting from the provided table, until it leaves
+// the recorded tables or is dropped.
+func tracePacket(tableActions map[binding.TableIDType][]tracedAction, table binding.TableIDType) *packetTrace {
+	trace := &packetTrace{}
+	regs := map[int]uint32{}
+	var execute func(table binding.TableIDType) (next binding.TableIDType, stop bool)
+	execute = func(table binding.TableIDType) (binding.TableIDType, bool) {
+		trace.tables = append(trace.tables, table)
+		for _, action := range tableActions[table] {
+			switch action.name {
+			case "load":
+				regs[action.reg] = action.value
+			case "resubmit":
+				// A resubmitted packet comes back to the current flow.
+				execute(action.table)
+			case "controller":
+				trace.loggedConjID = regs[int(policyLoggingReg)]
+			case "drop":
+				trace.dropped = true
+				return 0, true
+			case "goto":
+				return action.table, false
+			}
+		}
+		return 0, true
+	}
+	for {
+		next, stop := execute(table)
+		if stop {
+			return trace
+		}
+		if _, ok := tableActions[next]; !ok {
+			trace.tables = append(trace.tables, next)
+			return trace
+		}
+		table = next
+	}
+}
+
+func TestPolicyLoggingTable(t *testing.T) {
+	ruleID := uint32(101)
+	priority := uint16(100)
+	tests := []struct {
+		name          string
+		enableLogging bool
+		drop          bool
+		expectedTrace *packetTrace
+	}{
+		{
+			name:          "allow without logging",
+			expectedTrace: &packetTrace{tables: []binding.TableIDType{EgressRuleTable, l3ForwardingTable}},
+		},
+		{
+			name:          "allow with logging",
+			enableLogging: true,
+			expectedTrace: &packetTrace{tables: []binding.TableIDType{EgressRuleTable, policyLoggingTable, l3ForwardingTable}, loggedConjID: ruleID},
+		},
+		{
+			name:          "drop without logging",
+			drop:          true,
+			expectedTrace: &packetTrace{tables: []binding.TableIDType{EgressRuleTable}, dropped: true},
+		},
+		{
+			name:          "drop with logging",
+			enableLogging: true,
+			drop:          true,
+			expectedTrace: &packetTrace{tables: []binding.TableIDType{EgressRuleTable, policyLoggingTable}, loggedConjID: ruleID, dropped: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			c = prepareClient(ctrl)
+			tableActions := map[binding.TableIDType][]tracedAction{}
+			loggingTable := createMockTable(ctrl, policyLoggingTable, binding.LastTableID, binding.TableMissActionNone)
+			loggingTable.EXPECT().BuildFlow(gomock.Any()).Return(newTracingFlowBuilder(ctrl, policyLoggingTable, tableActions)).AnyTimes()
+			c.pipeline[policyLoggingTable] = loggingTable
+			outTable.EXPECT().BuildFlow(gomock.Any()).Return(newTracingFlowBuilder(ctrl, EgressRuleTable, tableActions)).AnyTimes()
+
+			c.policyLoggingFlow(cookie.Default)
+			if tt.drop {
+				c.conjunctionActionDropFlow(ruleID, EgressRuleTable, &priority, tt.enableLogging)
+			} else {
+				c.conjunctionActionFlow(ruleID, EgressRuleTable, l3ForwardingTable, &priority, tt.enableLogging, false)
+			}
+			assert.Equal(t, tt.expectedTrace, tracePacket(tableActions, EgressRuleTable))
+		})
+	}
+}
+
+func TestConjunctionActionFlowPreserveSourceIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c = prepareClient(ctrl)
+	ruleID := uint32(101)
+	priority := uint16(100)
+	builder := mocks.NewMockFlowBuilder(ctrl)
+	action := mocks.NewMockAction(ctrl)
+	outTable.EXPECT().BuildFlow(gomock.Any()).Return(builder)
+	builder.EXPECT().MatchProtocol(gomock.Any()).Return(builder)
+	builder.EXPECT().MatchConjID(ruleID).Return(builder)
+	builder.EXPECT().MatchPriority(priority).Return(builder)
+	builder.EXPECT().Action().Return(action).AnyTimes()
+	builder.EXPECT().Cookie(gomock.Any()).Return(builder)
+	builder.EXPECT().Done().Return(mocks.NewMockFlow(ctrl))
+	action.EXPECT().LoadRegRange(int(EgressReg), ruleID, binding.Range{0, 31}).Return(builder)
+	// The packet is marked both in marksReg, for the OVS SNAT flows, and in
+	// pkt_mark, for the iptables masquerade rules.
+	action.EXPECT().LoadRegRange(int(marksReg), uint32(preserveSourceIPMark), preserveSourceIPMarkRange).Return(builder)
+	action.EXPECT().LoadRange(binding.NxmFieldPktMark, uint64(preserveSourceIPMark), preserveSourceIPPktMarkRange).Return(builder)
+	action.EXPECT().GotoTable(l3ForwardingTable).Return(builder)
+
+	c.conjunctionActionFlow(ruleID, EgressRuleTable, l3ForwardingTable, &priority, false, true)
+	assert.Equal(t, PreserveSourceIPPktMark, uint32(preserveSourceIPMark)<<preserveSourceIPPktMarkRange[0])
+}
+
+func TestGetConjunctionIDReg(t *testing.T) {
+	assert.Equal(t, EgressReg, GetConjunctionIDReg(EgressRuleTable))
+	assert.Equal(t, IngressReg, GetConjunctionIDReg(IngressRuleTable))
+	assert.Equal(t, policyLoggingReg, GetConjunctionIDReg(policyLoggingTable))
+	assert.True(t, IsPolicyLoggingTable(policyLoggingTable))
+	assert.False(t, IsPolicyLoggingTable(EgressRuleTable))
+}
+
+func TestBaselineDefaultFlows(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c = prepareClient(ctrl)
+	cnpOutTable := createMockTable(ctrl, cnpEgressRuleTable, EgressRuleTable, binding.TableMissActionNext)
+	c.pipeline[cnpEgressRuleTable] = cnpOutTable
+	cnpOutTable.EXPECT().BuildFlow(gomock.Any()).Return(newMockRuleFlowBuilder(ctrl)).AnyTimes()
+	// The catch-all conjunction of egress has a clause matching any IP packet, and a clause matching the addresses
+	// the baseline rules are applied to, i.e. the 3 addresses used below.
+	ruleAction.EXPECT().Conjunction(BaselineDropConjIDOut, uint8(2), uint8(2)).Return(ruleFlowBuilder).Times(1)
+	ruleAction.EXPECT().Conjunction(BaselineDropConjIDOut, uint8(1), uint8(2)).Return(ruleFlowBuilder).Times(3)
+	ruleAction.EXPECT().Conjunction(gomock.Any(), gomock.Any(), gomock.Any()).Return(ruleFlowBuilder).AnyTimes()
+	ruleAction.EXPECT().Drop().Return(ruleFlowBuilder).AnyTimes()
+
+	allowAction := secv1alpha1.RuleActionAllow
+	newBaselineRule := func(priority uint16, from []string, to string) *types.PolicyRule {
+		return &types.PolicyRule{
+			Direction: v1beta1.DirectionOut,
+			From:      parseAddresses(from),
+			To:        parseAddresses([]string{to}),
+			Action:    &allowAction,
+			Priority:  &priority,
+			Baseline:  true,
+		}
+	}
+	getAppliedTo := func() map[string][]uint32 {
+		appliedTo := map[string][]uint32{}
+		for key, a := range c.baselineAppliedToCache[v1beta1.DirectionOut] {
+			for ruleID := range a.rules {
+				appliedTo[key] = append(appliedTo[key], ruleID)
+			}
+		}
+		return appliedTo
+	}
+	appliedToKey := func(addr string) string {
+		return baselineAppliedToMatch(v1beta1.DirectionOut, parseAddresses([]string{addr})[0]).generateGlobalMapKey()
+	}
+	ruleID1, ruleID2 := uint32(101), uint32(102)
+
+	require.NoError(t, c.InstallPolicyRuleFlows(ruleID1, newBaselineRule(99, []string{"192.168.1.30"}, "192.168.2.10"), "cnp1", ""))
+	assert.Len(t, c.baselineDefaultFlowCache[v1beta1.DirectionOut], 2)
+	assert.NotContains(t, c.baselineDefaultFlowCache, v1beta1.DirectionIn)
+	assert.Equal(t, map[string][]uint32{appliedToKey("192.168.1.30"): {ruleID1}}, getAppliedTo())
+
+	require.NoError(t, c.InstallPolicyRuleFlows(ruleID2, newBaselineRule(98, []string{"192.168.1.30", "192.168.1.31"}, "192.168.2.20"), "cnp2", ""))
+	assert.Equal(t, 2, c.baselineRules[v1beta1.DirectionOut])
+	appliedTo := getAppliedTo()
+	assert.ElementsMatch(t, []uint32{ruleID1, ruleID2}, appliedTo[appliedToKey("192.168.1.30")])
+	assert.Equal(t, []uint32{ruleID2}, appliedTo[appliedToKey("192.168.1.31")])
+
+	// The appliedTo addresses of a baseline rule are updated with the rule.
+	priority1 := uint16(99)
+	require.NoError(t, c.AddPolicyRuleAddress(ruleID1, types.SrcAddress, parseAddresses([]string{"192.168.1.32"}), &priority1))
+	assert.Equal(t, []uint32{ruleID1}, getAppliedTo()[appliedToKey("192.168.1.32")])
+	require.NoError(t, c.DeletePolicyRuleAddress(ruleID1, types.SrcAddress, parseAddresses([]string{"192.168.1.32"}), &priority1))
+	assert.NotContains(t, getAppliedTo(), appliedToKey("192.168.1.32"))
+
+	// The catch-all flows are kept as long as a baseline rule of the direction remains.
+	_, err := c.UninstallPolicyRuleFlows(ruleID1)
+	require.NoError(t, err)
+	assert.Len(t, c.baselineDefaultFlowCache[v1beta1.DirectionOut], 2)
+	assert.Equal(t, map[string][]uint32{appliedToKey("192.168.1.30"): {ruleID2}, appliedToKey("192.168.1.31"): {ruleID2}}, getAppliedTo())
+
+	_, err = c.UninstallPolicyRuleFlows(ruleID2)
+	require.NoError(t, err)
+	assert.NotContains(t, c.baselineDefaultFlowCache, v1beta1.DirectionOut)
+	assert.Empty(t, getAppliedTo())
+	assert.Equal(t, 0, c.baselineRules[v1beta1.DirectionOut])
+}
+
+// internetChecksum returns the checksum of the 16-bit words.
+func internetChecksum(words []uint16) uint16 {
+	var sum uint32
+	for _, w := range words {
+		sum += uint32(w)
+	}
+	for sum > 0xffff {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	return ^uint16(sum)
+}
+
+func TestAdjustChecksum(t *testing.T) {
+	words := []uint16{0x4500, 0x0073, 0x0000, 0x4000, 0x4011, 0xc0a8, 0x0001, 0xc0a8, 0x00c7, 0x50c2}
+	checksum := internetChecksum(words)
+	oldWord := words[4]
+	words[4] = 0x5002
+	assert.Equal(t, internetChecksum(words), adjustChecksum(checksum, oldWord, words[4]))
+}
+
+func TestReinjectL7Packet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	bridge := mocks.NewMockBridge(ctrl)
+	c = &client{bridge: bridge}
+
+	srcMAC, _ := net.ParseMAC("aa:bb:cc:dd:ee:01")
+	dstMAC, _ := net.ParseMAC("aa:bb:cc:dd:ee:02")
+	segment, err := (&protocol.TCP{PortSrc: 34567, PortDst: 80, SeqNum: 1000, HdrLen: 5, Code: 0x18, Checksum: 0x1234, Data: []byte("GET / HTTP/1.1\r\n\r\n")}).MarshalBinary()
+	require.NoError(t, err)
+	// Set the ECE flag, which is not kept by the TCP header of libOpenflow.
+	segment[13] |= 0x40
+	ipPacket := &protocol.IPv4{
+		Version:  4,
+		IHL:      5,
+		Protocol: protocol.Type_TCP,
+		NWSrc:    net.ParseIP("10.10.0.2").To4(),
+		NWDst:    net.ParseIP("10.10.1.3").To4(),
+		Data:     util.NewBuffer(segment),
+	}
+	reg0 := uint32(markTrafficFromLocal) | portFoundMark<<ofPortMarkRange[0] | l7StatePending<<l7StateRange[0]
+	pktIn := &ofctrl.PacketIn{
+		Match: openflow13.Match{Fields: []openflow13.MatchField{
+			*openflow13.NewInPortField(5),
+			*openflow13.NewRegMatchField(int(marksReg), reg0, nil),
+			*openflow13.NewRegMatchField(int(EgressReg), 12, nil),
+		}},
+		Data: protocol.Ethernet{HWSrc: srcMAC, HWDst: dstMAC, Ethertype: protocol.IPv4_MSG, Data: ipPacket},
+	}
+
+	var pktOut *ofctrl.PacketOut
+	bridge.EXPECT().SendPacketOut(gomock.Any()).DoAndReturn(func(p *ofctrl.PacketOut) error {
+		pktOut = p
+		return nil
+	})
+	require.NoError(t, c.ReinjectL7Packet(v1beta1.DirectionOut, pktIn))
+	assert.Equal(t, uint32(5), pktOut.InPort)
+	assert.Equal(t, uint32(0), pktOut.OutPort)
+	assert.Equal(t, srcMAC, pktOut.SrcMAC)
+	assert.Equal(t, dstMAC, pktOut.DstMAC)
+	assert.Equal(t, uint32(1000), pktOut.TCPHeader.SeqNum)
+	assert.Equal(t, adjustChecksum(0x1234, binary.BigEndian.Uint16(segment[12:14]), 0x5018), pktOut.TCPHeader.Checksum)
+	var loadedValues []uint64
+	for _, action := range pktOut.Actions {
+		loadAction, ok := action.(*ofctrl.NXLoadAction)
+		require.True(t, ok)
+		loadedValues = append(loadedValues, loadAction.Value)
+	}
+	expectedReg0 := uint32(markTrafficFromLocal) | portFoundMark<<ofPortMarkRange[0] | l7ReinjectEgress<<l7ReinjectRange[0]
+	assert.Equal(t, []uint64{uint64(expectedReg0), 12}, loadedValues)
+}
@@ -15,14 +15,16 @@
 package openflow
 
 import (
-	"time"
+	"encoding/binary"
+	"fmt"
+	"net"
 
+	"github.com/contiv/libOpenflow/openflow13"
 	"github.com/contiv/ofnet/ofctrl"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog"
 )
 
-type ofpPacketInReason uint
+type ofpPacketInReason uint8
 
 type PacketInHandler interface {
 	HandlePacketIn(pktIn *ofctrl.PacketIn) error
@@ -31,35 +33,85 @@ type PacketInHandler interface {
 const (
 	// Action explicitly output to controller.
 	ofprAction ofpPacketInReason = 1
+	// PacketInReasonTF is the reason of the PacketIn messages sent by the
+	// Traceflow flows.
+	PacketInReasonTF = ofprAction
+	// PacketInReasonNP is the reason of the PacketIn messages sent by the
+	// action flows of the NetworkPolicy rules which enable logging. The
+	// table-miss reason is reused, as no table sends its missed packets to
+	// the controller.
+	PacketInReasonNP ofpPacketInReason = 0
+	// packetInQueueSize is the number of PacketIn messages of a reason which
+	// can be queued before they are processed by the handlers.
+	packetInQueueSize = 256
 )
 
-func (c *client) RegisterPacketInHandler(packetHandlerName string, packetInHandler interface{}) {
+func (c *client) RegisterPacketInHandler(packetHandlerReason uint8, packetHandlerName string, packetInHandler interface{}) {
 	handler, ok := packetInHandler.(PacketInHandler)
 	if !ok {
-		klog.Errorf("Invalid Traceflow controller.")
+		klog.Errorf("Invalid PacketIn handler %s", packetHandlerName)
 		return
 	}
-	c.packetInHandlers[packetHandlerName] = handler
+	if c.packetInHandlers[packetHandlerReason] == nil {
+		c.packetInHandlers[packetHandlerReason] = map[string]PacketInHandler{}
+	}
+	c.packetInHandlers[packetHandlerReason][packetHandlerName] = handler
 }
 
 func (c *client) StartPacketInHandler(stopCh <-chan struct{}) {
 	if len(c.packetInHandlers) == 0 {
 		return
 	}
-	ch := make(chan *ofctrl.PacketIn)
-	err := c.SubscribePacketIn(uint8(ofprAction), ch)
-	if err != nil {
-		klog.Errorf("Subscribe PacketIn failed %+v", err)
+	for reason, handlers := range c.packetInHandlers {
+		ch := make(chan *ofctrl.PacketIn, packetInQueueSize)
+		if err := c.SubscribePacketIn(reason, ch); err != nil {
+			klog.Errorf("Subscribe PacketIn with reason %d failed %+v", reason, err)
+			continue
+		}
+		go c.parsePacketIn(ch, handlers, stopCh)
 	}
+	<-stopCh
+}
 
-	wait.PollUntil(time.Second, func() (done bool, err error) {
-		pktIn := <-ch
-		for name, handler := range c.packetInHandlers {
-			err = handler.HandlePacketIn(pktIn)
-			if err != nil {
-				klog.Errorf("PacketIn handler %s failed to process packet: %+v", name, err)
+func (c *client) parsePacketIn(ch chan *ofctrl.PacketIn, handlers map[string]PacketInHandler, stopCh <-chan struct{}) {
+	for {
+		select {
+		case pktIn := <-ch:
+			for name, handler := range handlers {
+				if err := handler.HandlePacketIn(pktIn); err != nil {
+					klog.Errorf("PacketIn handler %s failed to process packet: %+v", name, err)
+				}
 			}
+		case <-stopCh:
+			return
 		}
-		return false, err
-	}, stopCh)
+	}
+}
+
+// GetServiceEndpointFromPacketIn returns the IPv4 address and port of the
+// Service Endpoint selected by AntreaProxy for the packet, and whether an
+// Endpoint has been selected.
+func GetServiceEndpointFromPacketIn(pktIn *ofctrl.PacketIn) (net.IP, uint16, bool) {
+	matchers := pktIn.GetMatches()
+	learnMatch := matchers.GetMatchByName(fmt.Sprintf("NXM_NX_REG%d", serviceLearnReg))
+	ipMatch := matchers.GetMatchByName(fmt.Sprintf("NXM_NX_REG%d", endpointIPReg))
+	if learnMatch == nil || ipMatch == nil {
+		return nil, 0, false
+	}
+	learnValue, ok := learnMatch.GetValue().(*ofctrl.NXRegister)
+	if !ok {
+		return nil, 0, false
+	}
+	state := ofctrl.GetUint32ValueWithRange(learnValue.Data, openflow13.NewNXRange(int(serviceLearnRegRange[0]), int(serviceLearnRegRange[1])))
+	if state != marksRegServiceSelected {
+		return nil, 0, false
+	}
+	ipValue, ok := ipMatch.GetValue().(*ofctrl.NXRegister)
+	if !ok {
+		return nil, 0, false
+	}
+	port := ofctrl.GetUint32ValueWithRange(learnValue.Data, openflow13.NewNXRange(int(endpointPortRegRange[0]), int(endpointPortRegRange[1])))
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, ipValue.Data)
+	return ip, uint16(port), true
 }
@@ -15,8 +15,10 @@
 package openflow
 
 import (
+	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"net"
 	"strconv"
 	"strings"
@@ -27,42 +29,65 @@ import (
 	"github.com/vmware-tanzu/antrea/pkg/agent/config"
 	"github.com/vmware-tanzu/antrea/pkg/agent/openflow/cookie"
 	"github.com/vmware-tanzu/antrea/pkg/agent/types"
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
 	"github.com/vmware-tanzu/antrea/pkg/features"
 	binding "github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/version"
 	"github.com/vmware-tanzu/antrea/third_party/proxy"
 )
 
 const (
 	// Flow table id index
-	ClassifierTable       binding.TableIDType = 0
-	spoofGuardTable       binding.TableIDType = 10
-	arpResponderTable     binding.TableIDType = 20
-	serviceHairpinTable   binding.TableIDType = 29
-	conntrackTable        binding.TableIDType = 30
-	conntrackStateTable   binding.TableIDType = 31
-	sessionAffinityTable  binding.TableIDType = 40
-	dnatTable             binding.TableIDType = 40
-	serviceLBTable        binding.TableIDType = 41
-	endpointDNATTable     binding.TableIDType = 42
-	cnpEgressRuleTable    binding.TableIDType = 45
-	EgressRuleTable       binding.TableIDType = 50
-	egressDefaultTable    binding.TableIDType = 60
-	l3ForwardingTable     binding.TableIDType = 70
-	l2ForwardingCalcTable binding.TableIDType = 80
-	cnpIngressRuleTable   binding.TableIDType = 85
-	IngressRuleTable      binding.TableIDType = 90
-	ingressDefaultTable   binding.TableIDType = 100
-	conntrackCommitTable  binding.TableIDType = 105
-	hairpinSNATTable      binding.TableIDType = 106
-	l2ForwardingOutTable  binding.TableIDType = 110
+	ClassifierTable          binding.TableIDType = 0
+	spoofGuardTable          binding.TableIDType = 10
+	arpResponderTable        binding.TableIDType = 20
+	serviceHairpinTable      binding.TableIDType = 29
+	conntrackTable           binding.TableIDType = 30
+	conntrackStateTable      binding.TableIDType = 31
+	sessionAffinityTable     binding.TableIDType = 40
+	dnatTable                binding.TableIDType = 40
+	ServiceLBTable           binding.TableIDType = 41
+	EndpointDNATTable        binding.TableIDType = 42
+	cnpEgressRuleTable       binding.TableIDType = 45
+	cnpEgressL7ConnTable     binding.TableIDType = 46
+	cnpEgressL7VerdictTable  binding.TableIDType = 47
+	EgressRuleTable          binding.TableIDType = 50
+	egressDefaultTable       binding.TableIDType = 60
+	policyLoggingTable       binding.TableIDType = 65
+	l3ForwardingTable        binding.TableIDType = 70
+	egressNATTable           binding.TableIDType = 71
+	l2ForwardingCalcTable    binding.TableIDType = 80
+	cnpIngressRuleTable      binding.TableIDType = 85
+	cnpIngressL7ConnTable    binding.TableIDType = 86
+	cnpIngressL7VerdictTable binding.TableIDType = 87
+	IngressRuleTable         binding.TableIDType = 90
+	ingressDefaultTable      binding.TableIDType = 100
+	conntrackCommitTable     binding.TableIDType = 105
+	hairpinSNATTable         binding.TableIDType = 106
+	l2ForwardingOutTable     binding.TableIDType = 110
 
 	// Flow priority level
 	priorityHigh   = uint16(210)
 	priorityNormal = uint16(200)
 	priorityLow    = uint16(190)
 	prioritySNAT   = uint16(180)
+	priorityLowest = uint16(80)
 	priorityMiss   = uint16(0)
 	priorityTopCNP = uint16(64990)
+	// The flows of the packets re-injected by the agent after it has allowed
+	// their HTTP requests have a priority higher than the other flows of the
+	// classifier table.
+	priorityL7Reinject = uint16(220)
+	// The catch-all flows of the baseline tier of ClusterNetworkPolicies have
+	// priorities lower than the rules of the tier.
+	priorityBaselineBypass = uint16(2)
+	priorityBaselineDrop   = uint16(1)
+
+	// BaselineDropConjIDOut and BaselineDropConjIDIn are the conjunction IDs of
+	// the catch-all flows of the baseline tier. They are reserved, the IDs of the
+	// policy rules must be lower.
+	BaselineDropConjIDOut = uint32(math.MaxUint32)
+	BaselineDropConjIDIn  = BaselineDropConjIDOut - 1
 
 	// Index for priority cache
 	priorityIndex = "priority"
@@ -87,20 +112,41 @@ var (
 		{conntrackStateTable, "ConntrackState"},
 		{dnatTable, "DNAT(SessionAffinity)"},
 		{sessionAffinityTable, "SessionAffinity"},
-		{serviceLBTable, "ServiceLB"},
-		{endpointDNATTable, "EndpointDNAT"},
+		{ServiceLBTable, "ServiceLB"},
+		{EndpointDNATTable, "EndpointDNAT"},
 		{cnpEgressRuleTable, "CNPEgressRule"},
+		{cnpEgressL7ConnTable, "CNPEgressL7Conn"},
+		{cnpEgressL7VerdictTable, "CNPEgressL7Verdict"},
 		{EgressRuleTable, "EgressRule"},
 		{egressDefaultTable, "EgressDefaultRule"},
+		{policyLoggingTable, "PolicyLogging"},
 		{l3ForwardingTable, "l3Forwarding"},
+		{egressNATTable, "EgressNAT"},
 		{l2ForwardingCalcTable, "L2Forwarding"},
 		{cnpIngressRuleTable, "CNPIngressRule"},
+		{cnpIngressL7ConnTable, "CNPIngressL7Conn"},
+		{cnpIngressL7VerdictTable, "CNPIngressL7Verdict"},
 		{IngressRuleTable, "IngressRule"},
 		{ingressDefaultTable, "IngressDefaultRule"},
 		{conntrackCommitTable, "ConntrackCommit"},
 		{hairpinSNATTable, "HairpinSNATTable"},
 		{l2ForwardingOutTable, "Output"},
 	}
+
+	// PolicyTables are the tables in which the flows enforcing the
+	// NetworkPolicies encode their PolicyAction in their cookies. The L7
+	// connection tables are not included as their flows only load the L7
+	// states of the connections.
+	PolicyTables = []binding.TableIDType{
+		cnpEgressRuleTable,
+		cnpEgressL7VerdictTable,
+		EgressRuleTable,
+		egressDefaultTable,
+		cnpIngressRuleTable,
+		cnpIngressL7VerdictTable,
+		IngressRuleTable,
+		ingressDefaultTable,
+	}
 )
 
 // GetFlowTableName returns the flow table name given the table number. An empty
@@ -126,6 +172,17 @@ func GetFlowTableNumber(tableName string) binding.TableIDType {
 	return binding.TableIDAll
 }
 
+// PipelineVersion returns the version of the OpenFlow pipeline programmed by
+// this agent. It is made of the Antrea version and of a hash of the flow
+// tables, so that adding, removing or renumbering a table changes the version.
+func PipelineVersion() string {
+	h := sha256.New()
+	for _, t := range FlowTables {
+		fmt.Fprintf(h, "%d:%s;", t.Number, t.Name)
+	}
+	return fmt.Sprintf("%s-%x", version.GetFullVersion(), h.Sum(nil)[:8])
+}
+
 type regType uint
 
 func (rt regType) number() string {
@@ -146,12 +203,15 @@ const (
 	marksReg        regType = 0
 	portCacheReg    regType = 1
 	swapReg         regType = 2
-	endpointIPReg   regType = 3               // Use reg3 to store endpoint IP
+	endpointIPReg   regType = 3               // Use reg3 to store endpoint IP (the lower 32 bits for IPv6)
 	endpointPortReg regType = 4               // Use reg4[0..15] to store endpoint port
 	serviceLearnReg         = endpointPortReg // Use reg4[16..18] to store endpoint selection states.
 	EgressReg       regType = 5
 	IngressReg      regType = 6
-	TraceflowReg    regType = 9 // Use reg9[28..31] to store traceflow dataplaneTag.
+	// policyLoggingReg stores the conjunction ID of the NetworkPolicy rule
+	// whose action flow resubmits the packet to the policyLoggingTable.
+	policyLoggingReg regType = 7
+	TraceflowReg     regType = 9 // Use reg9[28..31] to store traceflow dataplaneTag.
 	// marksRegServiceNeedLB indicates a packet need to do service selection.
 	marksRegServiceNeedLB uint32 = 0b001
 	// marksRegServiceSelected indicates a packet has done service selection.
@@ -162,14 +222,41 @@ const (
 
 	CtZone = 0xfff0
 
-	portFoundMark    = 0b1
-	snatRequiredMark = 0b1
-	hairpinMark      = 0b1
-	macRewriteMark   = 0b1
+	portFoundMark        = 0b1
+	snatRequiredMark     = 0b1
+	hairpinMark          = 0b1
+	macRewriteMark       = 0b1
+	preserveSourceIPMark = 0b1
+
+	// PreserveSourceIPPktMark is the pkt_mark of the packets which must not be
+	// masqueraded by the iptables rules of the Node when they leave it.
+	PreserveSourceIPPktMark uint32 = 1 << 8
 
 	gatewayCTMark = 0x20
 	snatCTMark    = 0x40
 	serviceCTMark = 0x21
+
+	// L7 states of the TCP connections matching the ClusterNetworkPolicy
+	// rules with HTTP matches, stored in l7StateRange of marksReg.
+	// l7StatePending indicates the packets of the connection are sent to the
+	// agent, which evaluates the HTTP requests they carry. l7StateDeny
+	// indicates the agent has denied a request of the connection.
+	l7StatePending uint32 = 0b01
+	l7StateDeny    uint32 = 0b10
+
+	// Marks of the packets re-injected by the agent, stored in
+	// l7ReinjectRange of marksReg, which indicate the direction of the L7
+	// verdict table the packet was sent to the agent from.
+	l7ReinjectEgress  uint32 = 0b01
+	l7ReinjectIngress uint32 = 0b10
+
+	// l7ConnFinIdleTimeout is the idle timeout in seconds of the flows which
+	// store the L7 states of the TCP connections, after the connection has
+	// been closed by the client.
+	l7ConnFinIdleTimeout = uint16(60)
+	// l7DenyIdleTimeout is the idle timeout in seconds of the flows which
+	// deny the TCP connections.
+	l7DenyIdleTimeout = uint16(600)
 )
 
 var (
@@ -189,17 +276,58 @@ var (
 	// macRewriteMarkRange takes the 19th bit of register marksReg to indicate
 	// if the packet's MAC addresses need to be rewritten. Its value is 0x1 if yes.
 	macRewriteMarkRange = binding.Range{19, 19}
+	// egressNATMarkRange takes the lowest 8 bits of pkt_mark to store the mark
+	// of the Egress whose IP the packet is SNATed to by the iptables rule of the
+	// egress Node.
+	egressNATMarkRange = binding.Range{0, 7}
+	// l7StateRange takes the 20th and 21st bits of register marksReg to store
+	// the L7 state of the TCP connection of the packet.
+	l7StateRange = binding.Range{20, 21}
+	// l7ReinjectRange takes the 23rd and 24th bits of register marksReg to
+	// mark the packets re-injected by the agent after it has allowed the HTTP
+	// requests they carry.
+	l7ReinjectRange = binding.Range{23, 24}
+	// preserveSourceIPMarkRange takes the 22nd bit of register marksReg to
+	// indicate if the packet matches an egress rule with preserveSourceIP, in
+	// which case it must not be SNATed. Its value is 0x1 if yes.
+	preserveSourceIPMarkRange = binding.Range{22, 22}
+	// preserveSourceIPPktMarkRange takes the 9th bit of pkt_mark to indicate
+	// if the packet must not be masqueraded when it leaves the Node. It must
+	// match PreserveSourceIPPktMark.
+	preserveSourceIPPktMarkRange = binding.Range{8, 8}
 	// endpointIPRegRange takes a 32-bit range of register endpointIPReg to store
 	// the selected Service Endpoint IP.
 	endpointIPRegRange = binding.Range{0, 31}
 	// endpointPortRegRange takes a 16-bit range of register endpointPortReg to store
 	// the selected Service Endpoint port.
 	endpointPortRegRange = binding.Range{0, 15}
+	// endpointIPv6HighRegs store the upper 96 bits of the selected IPv6 Service
+	// Endpoint, from the most significant 32 bits to the least significant 32
+	// bits. The lower 32 bits are stored in endpointIPReg.
+	endpointIPv6HighRegs = []regType{12, 13, 14}
+	// serviceProtocols are the transport protocols of the Services that are
+	// load-balanced by AntreaProxy.
+	serviceProtocols = []binding.Protocol{
+		binding.ProtocolTCP, binding.ProtocolUDP, binding.ProtocolSCTP,
+		binding.ProtocolTCPv6, binding.ProtocolUDPv6, binding.ProtocolSCTPv6,
+	}
 	// serviceLearnRegRange takes a 3-bit range of register serviceLearnReg to
 	// indicate if the packet accessing a Service has already selected the Service
 	// Endpoint, still needs to select an Endpoint, or if an Endpoint has already
 	// been selected and the selection decision needs to be learned.
 	serviceLearnRegRange = binding.Range{16, 18}
+	// conjIDRegRange takes a 32-bit range of registers IngressReg and EgressReg
+	// to store the conjunction ID of the NetworkPolicy rule which the packet
+	// matched.
+	conjIDRegRange = binding.Range{0, 31}
+	// IngressRuleCTLabel and EgressRuleCTLabel take 32-bit ranges of ct_label to
+	// store the conjunction IDs of the ingress and egress NetworkPolicy rules which
+	// allowed the connection, so that the connection can be attributed to them by
+	// the flow exporter. They are 0 if no rule applied to the connection.
+	IngressRuleCTLabel = binding.Range{0, 31}
+	EgressRuleCTLabel  = binding.Range{32, 63}
+	ingressRegName     = fmt.Sprintf("%s%d", binding.NxmFieldReg, IngressReg)
+	egressRegName      = fmt.Sprintf("%s%d", binding.NxmFieldReg, EgressReg)
 
 	globalVirtualMAC, _ = net.ParseMAC("aa:bb:cc:dd:ee:ff")
 	ReentranceMAC, _    = net.ParseMAC("de:ad:be:ef:de:ad")
@@ -214,6 +342,7 @@ type OFEntryOperations interface {
 	DeleteAll(flows []binding.Flow) error
 	AddOFEntries(ofEntries []binding.OFEntry) error
 	DeleteOFEntries(ofEntries []binding.OFEntry) error
+	BundleOFEntries(addEntries, modEntries, delEntries []binding.OFEntry) error
 }
 
 type flowCache map[string]binding.Flow
@@ -229,12 +358,18 @@ type client struct {
 	bridge                                        binding.Bridge
 	pipeline                                      map[binding.TableIDType]binding.Table
 	nodeFlowCache, podFlowCache, serviceFlowCache *flowCategoryCache // cache for corresponding deletions
+	// traceflowFlowCache stores the flows installed for live Traceflows, which are deleted once the capture completes.
+	traceflowFlowCache *flowCategoryCache
+	// egressFlowCache stores the Egress NAT flows of the Egress IPs and of the local Pods.
+	egressFlowCache *flowCategoryCache
 	// "fixed" flows installed by the agent after initialization and which do not change during
 	// the lifetime of the client.
-	gatewayFlows, defaultServiceFlows, defaultTunnelFlows, hostNetworkingFlows []binding.Flow
+	gatewayFlows, defaultServiceFlows, defaultTunnelFlows, hostNetworkingFlows, egressNATBypassFlowCache []binding.Flow
 	// ofEntryOperations is a wrapper interface for OpenFlow entry Add / Modify / Delete operations. It
 	// enables convenient mocking in unit tests.
 	ofEntryOperations OFEntryOperations
+	// flowInstaller installs the cached flows concurrently when they are replayed.
+	flowInstaller *binding.FlowInstaller
 	// policyCache is a storage that supports listing policyRuleConjunction with different indexers.
 	// It's guaranteed that one policyRuleConjunction is processed by at most one goroutine at any given time.
 	policyCache       cache.Indexer
@@ -243,13 +378,23 @@ type client struct {
 	// globalConjMatchFlowCache is a global map for conjMatchFlowContext. The key is a string generated from the
 	// conjMatchFlowContext.
 	globalConjMatchFlowCache map[string]*conjMatchFlowContext
+	// baselineRules counts the installed rules of baseline ClusterNetworkPolicies of each direction, and
+	// baselineDefaultFlowCache stores the catch-all flows installed for a direction as long as it has such rules.
+	// baselineAppliedToCache stores the addresses the rules of each direction are applied to, keyed by the global
+	// map key of their conjunctive match. All are protected by conjMatchFlowLock.
+	baselineRules            map[v1beta1.Direction]int
+	baselineDefaultFlowCache map[v1beta1.Direction][]binding.Flow
+	baselineAppliedToCache   map[v1beta1.Direction]map[string]*baselineAppliedTo
 	// replayMutex provides exclusive access to the OFSwitch to the ReplayFlows method.
 	replayMutex sync.RWMutex
 	nodeConfig  *config.NodeConfig
 	encapMode   config.TrafficEncapModeType
 	gatewayPort uint32 // OVSOFPort number
-	// packetInHandlers stores handler to process PacketIn event
-	packetInHandlers map[string]PacketInHandler
+	// packetInHandlers stores the handlers to process PacketIn events, keyed by PacketIn reason.
+	packetInHandlers map[uint8]map[string]PacketInHandler
+	// endpointWatchPort returns the port watched by the bucket of an Endpoint
+	// in the fast-failover groups. It is nil when select groups are used.
+	endpointWatchPort func(endpointIP string) (uint32, bool)
 }
 
 func (c *client) GetTunnelVirtualMAC() net.HardwareAddr {
@@ -284,6 +429,10 @@ func (c *client) DeleteOFEntries(ofEntries []binding.OFEntry) error {
 	return c.bridge.AddOFEntriesInBundle(nil, nil, ofEntries)
 }
 
+func (c *client) BundleOFEntries(addEntries, modEntries, delEntries []binding.OFEntry) error {
+	return c.bridge.AddOFEntriesInBundle(addEntries, modEntries, delEntries)
+}
+
 // defaultFlows generates the default flows of all tables.
 func (c *client) defaultFlows() (flows []binding.Flow) {
 	for _, table := range c.pipeline {
@@ -300,11 +449,26 @@ func (c *client) defaultFlows() (flows []binding.Flow) {
 		default:
 			continue
 		}
-		flows = append(flows, flowBuilder.Cookie(c.cookieAllocator.Request(cookie.Default).Raw()).Done())
+		cookieID := c.cookieAllocator.Request(cookie.Default)
+		if table.GetMissAction() == binding.TableMissActionNext && isPolicyTable(table.GetID()) {
+			cookieID = cookieID.WithPolicyAction(cookie.PolicyActionJump)
+		}
+		flows = append(flows, flowBuilder.Cookie(cookieID.Raw()).Done())
 	}
 	return flows
 }
 
+// isPolicyTable returns whether the table is one of the NetworkPolicy tables,
+// whose flows encode their PolicyAction in their cookies.
+func isPolicyTable(tableID binding.TableIDType) bool {
+	for _, policyTable := range PolicyTables {
+		if tableID == policyTable {
+			return true
+		}
+	}
+	return false
+}
+
 // tunnelClassifierFlow generates the flow to mark traffic comes from the tunnelOFPort.
 func (c *client) tunnelClassifierFlow(tunnelOFPort uint32, category cookie.Category) binding.Flow {
 	flowBuilder := c.pipeline[ClassifierTable].BuildFlow(priorityNormal).
@@ -343,6 +507,18 @@ func (c *client) podClassifierFlow(podOFPort uint32, category cookie.Category) b
 		Done()
 }
 
+// secondaryInterfaceClassifierFlow generates the flow which forwards the packets
+// received from a secondary network interface of a Pod with the NORMAL action.
+// The packets bypass the Pod network pipeline, including the NetworkPolicies,
+// and are switched in the VLAN of the access port of the interface.
+func (c *client) secondaryInterfaceClassifierFlow(ofPort uint32, category cookie.Category) binding.Flow {
+	return c.pipeline[ClassifierTable].BuildFlow(priorityNormal).
+		MatchInPort(ofPort).
+		Action().Normal().
+		Cookie(c.cookieAllocator.Request(category).Raw()).
+		Done()
+}
+
 // hostBridgeUplinkFlows generates the flows that forward traffic between bridge local port and uplink port to support
 // host communicate with outside. These flows are only needed on windows platform.
 func (c *client) hostBridgeUplinkFlows(uplinkPort uint32, bridgeLocalPort uint32, category cookie.Category) (flows []binding.Flow) {
@@ -365,7 +541,7 @@ func (c *client) hostBridgeUplinkFlows(uplinkPort uint32, bridgeLocalPort uint32
 // 2) Add ct_mark on the packet if it is sent to the switch from the host gateway.
 // 3) Allow traffic if it hits ct_mark and is sent from the host gateway.
 // 4) Drop all invalid traffic.
-// 5) Let other traffic go to the sessionAffinityTable first and then the serviceLBTable.
+// 5) Let other TCP, UDP and SCTP traffic go to the sessionAffinityTable first and then the ServiceLBTable.
 //    The sessionAffinityTable is a side-effect table which means traffic will not
 //    be resubmitted to any table. serviceLB does Endpoint selection for traffic
 //    to a Service.
@@ -375,13 +551,20 @@ func (c *client) connectionTrackFlows(category cookie.Category) []binding.Flow {
 	connectionTrackCommitTable := c.pipeline[conntrackCommitTable]
 	var flows []binding.Flow
 	if c.enableProxy {
+		// Send the packets of each transport protocol supported by AntreaProxy to the
+		// SessionAffinity and ServiceLB tables. Other packets are not destined to a Service
+		// and go to the next table through the default flow. The flows have a priority higher
+		// than the default flow's, but lower than the one of the flow dropping invalid packets.
+		for _, protocol := range serviceProtocols {
+			flows = append(flows,
+				connectionTrackStateTable.BuildFlow(priorityLowest).MatchProtocol(protocol).
+					Cookie(c.cookieAllocator.Request(category).Raw()).
+					Action().ResubmitToTable(sessionAffinityTable).
+					Action().ResubmitToTable(ServiceLBTable).
+					Done(),
+			)
+		}
 		flows = append(flows,
-			// Replace the default flow with multiple resubmits actions.
-			connectionTrackStateTable.BuildFlow(priorityMiss).
-				Cookie(c.cookieAllocator.Request(category).Raw()).
-				Action().ResubmitToTable(sessionAffinityTable).
-				Action().ResubmitToTable(serviceLBTable).
-				Done(),
 			// Enable NAT.
 			connectionTrackTable.BuildFlow(priorityNormal).MatchProtocol(binding.ProtocolIP).
 				Action().CT(false, connectionTrackTable.GetNext(), CtZone).NAT().CTDone().
@@ -394,6 +577,19 @@ func (c *client) connectionTrackFlows(category cookie.Category) []binding.Flow {
 				Cookie(c.cookieAllocator.Request(category).Raw()).
 				Action().GotoTable(connectionTrackCommitTable.GetNext()).
 				Done(),
+			// Enable NAT for IPv6 Service traffic, so that the replies of the
+			// connections to IPv6 Services can be translated back.
+			connectionTrackTable.BuildFlow(priorityNormal).MatchProtocol(binding.ProtocolIPv6).
+				Action().CT(false, connectionTrackTable.GetNext(), CtZone).NAT().CTDone().
+				Cookie(c.cookieAllocator.Request(category).Raw()).
+				Done(),
+			connectionTrackCommitTable.BuildFlow(priorityLow).MatchProtocol(binding.ProtocolIPv6).
+				MatchCTStateTrk(true).
+				MatchCTMark(serviceCTMark).
+				MatchRegRange(int(serviceLearnReg), marksRegServiceSelected, serviceLearnRegRange).
+				Cookie(c.cookieAllocator.Request(category).Raw()).
+				Action().GotoTable(connectionTrackCommitTable.GetNext()).
+				Done(),
 		)
 	} else {
 		flows = append(flows,
@@ -419,12 +615,18 @@ func (c *client) connectionTrackFlows(category cookie.Category) []binding.Flow {
 		connectionTrackCommitTable.BuildFlow(priorityNormal).MatchProtocol(binding.ProtocolIP).
 			MatchRegRange(int(marksReg), markTrafficFromGateway, binding.Range{0, 15}).
 			MatchCTStateNew(true).MatchCTStateTrk(true).
-			Action().CT(true, connectionTrackCommitTable.GetNext(), CtZone).LoadToMark(gatewayCTMark).CTDone().
+			Action().CT(true, connectionTrackCommitTable.GetNext(), CtZone).LoadToMark(gatewayCTMark).
+			MoveToLabel(ingressRegName, &conjIDRegRange, &IngressRuleCTLabel).
+			MoveToLabel(egressRegName, &conjIDRegRange, &EgressRuleCTLabel).
+			CTDone().
 			Cookie(c.cookieAllocator.Request(category).Raw()).
 			Done(),
 		connectionTrackCommitTable.BuildFlow(priorityLow).MatchProtocol(binding.ProtocolIP).
 			MatchCTStateNew(true).MatchCTStateTrk(true).
-			Action().CT(true, connectionTrackCommitTable.GetNext(), CtZone).CTDone().
+			Action().CT(true, connectionTrackCommitTable.GetNext(), CtZone).
+			MoveToLabel(ingressRegName, &conjIDRegRange, &IngressRuleCTLabel).
+			MoveToLabel(egressRegName, &conjIDRegRange, &EgressRuleCTLabel).
+			CTDone().
 			Cookie(c.cookieAllocator.Request(category).Raw()).
 			Done(),
 	)
@@ -433,13 +635,49 @@ func (c *client) connectionTrackFlows(category cookie.Category) []binding.Flow {
 // TODO: Use DuplicateToBuilder or integrate this function into original one to avoid unexpected difference.
 // traceflowConnectionTrackFlows generate Traceflow specific flows that bypass the drop flow in connectionTrackFlows to
 // avoid unexpected packet drop in Traceflow.
-func (c *client) traceflowConnectionTrackFlows(dataplaneTag uint8, category cookie.Category) binding.Flow {
+func (c *client) traceflowConnectionTrackFlows(dataplaneTag uint8, timeout uint16, category cookie.Category) []binding.Flow {
 	connectionTrackStateTable := c.pipeline[conntrackStateTable]
-	return connectionTrackStateTable.BuildFlow(priorityNormal+2).
+	var flows []binding.Flow
+	if c.enableProxy {
+		// The traceflow packets destined to a Service are load-balanced by the SessionAffinity and ServiceLB
+		// tables like the other packets, so that the selected Endpoint is traced.
+		for _, protocol := range serviceProtocols {
+			flows = append(flows,
+				connectionTrackStateTable.BuildFlow(priorityNormal+3).MatchProtocol(protocol).
+					MatchRegRange(int(TraceflowReg), uint32(dataplaneTag), OfTraceflowMarkRange).
+					SetHardTimeout(timeout).
+					Action().ResubmitToTable(sessionAffinityTable).
+					Action().ResubmitToTable(ServiceLBTable).
+					Cookie(c.cookieAllocator.Request(category).Raw()).
+					Done(),
+			)
+		}
+	}
+	return append(flows, connectionTrackStateTable.BuildFlow(priorityNormal+2).
 		MatchRegRange(int(TraceflowReg), uint32(dataplaneTag), OfTraceflowMarkRange).
-		SetHardTimeout(300).
+		SetHardTimeout(timeout).
 		Action().ResubmitToTable(connectionTrackStateTable.GetNext()).
 		Cookie(c.cookieAllocator.Request(category).Raw()).
+		Done())
+}
+
+// traceflowServiceNoEndpointsFlow generates the flow which sends the traceflow packets destined to a Service port
+// without Endpoints to Antrea Agent. The packets are dropped afterwards, as they are by the ServiceLB table.
+func (c *client) traceflowServiceNoEndpointsFlow(dataplaneTag uint8, svcIP net.IP, svcPort uint16, protocol binding.Protocol, timeout uint16, category cookie.Category) binding.Flow {
+	flowBuilder := c.pipeline[ServiceLBTable].BuildFlow(priorityNormal + 1).MatchProtocol(protocol)
+	switch protocol {
+	case binding.ProtocolTCP, binding.ProtocolTCPv6:
+		flowBuilder = flowBuilder.MatchTCPDstPort(svcPort)
+	case binding.ProtocolUDP, binding.ProtocolUDPv6:
+		flowBuilder = flowBuilder.MatchUDPDstPort(svcPort)
+	case binding.ProtocolSCTP, binding.ProtocolSCTPv6:
+		flowBuilder = flowBuilder.MatchSCTPDstPort(svcPort)
+	}
+	return flowBuilder.MatchDstIP(svcIP).
+		MatchRegRange(int(TraceflowReg), uint32(dataplaneTag), OfTraceflowMarkRange).
+		SetHardTimeout(timeout).
+		Action().SendToController(uint8(PacketInReasonTF)).
+		Cookie(c.cookieAllocator.Request(category).Raw()).
 		Done()
 }
 
@@ -506,12 +744,12 @@ func (c *client) l2ForwardOutputFlow(category cookie.Category) binding.Flow {
 
 // traceflowL2ForwardOutputFlow generates Traceflow specific flow that outputs traceflow packets to OVS port and Antrea
 // Agent after L2forwarding calculation.
-func (c *client) traceflowL2ForwardOutputFlow(dataplaneTag uint8, category cookie.Category) binding.Flow {
+func (c *client) traceflowL2ForwardOutputFlow(dataplaneTag uint8, timeout uint16, category cookie.Category) binding.Flow {
 	regName := fmt.Sprintf("%s%d", binding.NxmFieldReg, TraceflowReg)
 	tunMetadataName := fmt.Sprintf("%s%d", binding.NxmFieldTunMetadata, 0)
 	return c.pipeline[l2ForwardingOutTable].BuildFlow(priorityNormal+2).
 		MatchRegRange(int(TraceflowReg), uint32(dataplaneTag), OfTraceflowMarkRange).
-		SetHardTimeout(300).
+		SetHardTimeout(timeout).
 		MatchProtocol(binding.ProtocolIP).
 		MatchRegRange(int(marksReg), portFoundMark, ofPortMarkRange).
 		Action().MoveRange(regName, tunMetadataName, OfTraceflowMarkRange, OfTraceflowMarkRange).
@@ -653,6 +891,83 @@ func (c *client) l3FwdFlowToRemoteViaGW(
 		Done()
 }
 
+// egressNATBypassFlows generates the flows which skip the egressNATTable for
+// the packets which must not be SNATed to an Egress IP: the packets of the
+// connections initiated from the gateway, the packets matching an egress rule
+// with preserveSourceIP, and the packets sent to the Service CIDR, the local
+// gateway or the Node itself.
+func (c *client) egressNATBypassFlows(serviceCIDR net.IPNet, category cookie.Category) []binding.Flow {
+	egressNATTable := c.pipeline[egressNATTable]
+	flows := []binding.Flow{
+		egressNATTable.BuildFlow(priorityHigh).MatchProtocol(binding.ProtocolIP).
+			MatchCTMark(gatewayCTMark).
+			Action().GotoTable(egressNATTable.GetNext()).
+			Cookie(c.cookieAllocator.Request(category).Raw()).
+			Done(),
+		egressNATTable.BuildFlow(priorityHigh).MatchProtocol(binding.ProtocolIP).
+			MatchRegRange(int(marksReg), preserveSourceIPMark, preserveSourceIPMarkRange).
+			Action().GotoTable(egressNATTable.GetNext()).
+			Cookie(c.cookieAllocator.Request(category).Raw()).
+			Done(),
+		egressNATTable.BuildFlow(priorityHigh).MatchProtocol(binding.ProtocolIP).
+			MatchDstIPNet(serviceCIDR).
+			Action().GotoTable(egressNATTable.GetNext()).
+			Cookie(c.cookieAllocator.Request(category).Raw()).
+			Done(),
+	}
+	for _, ip := range []net.IP{c.nodeConfig.GatewayConfig.IP, c.nodeConfig.NodeIPAddr.IP} {
+		flows = append(flows, egressNATTable.BuildFlow(priorityHigh).MatchProtocol(binding.ProtocolIP).
+			MatchDstIP(ip).
+			Action().GotoTable(egressNATTable.GetNext()).
+			Cookie(c.cookieAllocator.Request(category).Raw()).
+			Done())
+	}
+	return flows
+}
+
+// remotePodEgressNATFlow generates the flow which marks the packets tunneled by
+// the other Nodes from a remote Pod whose Egress IP is assigned to this Node,
+// and forwards them to the gateway, where they are SNATed to the Egress IP by
+// the iptables rule matching the mark. The packets are matched by their source
+// Pod IP, as the Egress IP they were tunneled to is only the tunnel
+// destination.
+func (c *client) remotePodEgressNATFlow(podIP net.IP, mark uint32, category cookie.Category) binding.Flow {
+	return c.pipeline[egressNATTable].BuildFlow(priorityNormal).MatchProtocol(binding.ProtocolIP).
+		MatchRegRange(int(marksReg), markTrafficFromTunnel, binding.Range{0, 15}).
+		MatchSrcIP(podIP).
+		Action().SetDstMAC(c.nodeConfig.GatewayConfig.MAC).
+		Action().LoadRange(binding.NxmFieldPktMark, uint64(mark), egressNATMarkRange).
+		Action().GotoTable(l2ForwardingCalcTable).
+		Cookie(c.cookieAllocator.Request(category).Raw()).
+		Done()
+}
+
+// podEgressNATFlow generates the flow for the packets sent to the gateway by a
+// local Pod selected by an Egress. If the Egress IP is assigned to this Node,
+// the packets are marked like in remotePodEgressNATFlow, otherwise they are tunneled
+// to the Egress IP, which is the IP of the tunnel peer since it is assigned to
+// the transport interface of the egress Node.
+func (c *client) podEgressNATFlow(ofPort uint32, egressIP net.IP, mark uint32, category cookie.Category) binding.Flow {
+	flowBuilder := c.pipeline[egressNATTable].BuildFlow(priorityNormal).MatchProtocol(binding.ProtocolIP).
+		MatchInPort(ofPort).
+		MatchDstMAC(c.nodeConfig.GatewayConfig.MAC)
+	if mark != 0 {
+		return flowBuilder.Action().LoadRange(binding.NxmFieldPktMark, uint64(mark), egressNATMarkRange).
+			Action().GotoTable(l2ForwardingCalcTable).
+			Cookie(c.cookieAllocator.Request(category).Raw()).
+			Done()
+	}
+	return flowBuilder.Action().SetDstMAC(globalVirtualMAC).
+		Action().LoadRegRange(int(portCacheReg), config.DefaultTunOFPort, ofPortRegRange).
+		Action().LoadRegRange(int(marksReg), portFoundMark, ofPortMarkRange).
+		Action().SetTunnelDst(egressIP).
+		// Bypass l2ForwardingCalcTable and tables for ingress rules (which won't
+		// apply to packets to remote Nodes).
+		Action().GotoTable(conntrackCommitTable).
+		Cookie(c.cookieAllocator.Request(category).Raw()).
+		Done()
+}
+
 // arpResponderFlow generates the ARP responder flow entry that replies request comes from local gateway for peer
 // gateway MAC.
 func (c *client) arpResponderFlow(peerGatewayIP net.IP, category cookie.Category) binding.Flow {
@@ -704,6 +1019,47 @@ func (c *client) podIPSpoofGuardFlow(ifIP net.IP, ifMAC net.HardwareAddr, ifOFPo
 		Done()
 }
 
+// traceflowLiveCaptureFlow generates the flow that marks the live packets sent from a local Pod with the Traceflow
+// data plane tag, so that they are sent to Antrea Agent by the Traceflow flows in the following tables. The flow
+// supersedes podIPSpoofGuardFlow for the matched packets. The ports are not matched if they are 0.
+func (c *client) traceflowLiveCaptureFlow(dataplaneTag uint8, ifIP net.IP, ifMAC net.HardwareAddr, ifOFPort uint32, dstIP net.IP, protocol uint8, srcPort, dstPort uint16, timeout uint16, category cookie.Category) binding.Flow {
+	ipSpoofGuardTable := c.pipeline[spoofGuardTable]
+	ipProtocol := binding.ProtocolIP
+	switch protocol {
+	case 1:
+		ipProtocol = binding.ProtocolICMP
+	case 6:
+		ipProtocol = binding.ProtocolTCP
+	case 17:
+		ipProtocol = binding.ProtocolUDP
+	}
+	flowBuilder := ipSpoofGuardTable.BuildFlow(priorityNormal + 2).MatchProtocol(ipProtocol).
+		MatchInPort(ifOFPort).
+		MatchSrcMAC(ifMAC).
+		MatchSrcIP(ifIP).
+		MatchDstIP(dstIP).
+		SetHardTimeout(timeout)
+	if ipProtocol == binding.ProtocolTCP {
+		if srcPort != 0 {
+			flowBuilder = flowBuilder.MatchTCPSrcPort(srcPort)
+		}
+		if dstPort != 0 {
+			flowBuilder = flowBuilder.MatchTCPDstPort(dstPort)
+		}
+	} else if ipProtocol == binding.ProtocolUDP {
+		if srcPort != 0 {
+			flowBuilder = flowBuilder.MatchUDPSrcPort(srcPort)
+		}
+		if dstPort != 0 {
+			flowBuilder = flowBuilder.MatchUDPDstPort(dstPort)
+		}
+	}
+	return flowBuilder.Action().LoadRegRange(int(TraceflowReg), uint32(dataplaneTag), OfTraceflowMarkRange).
+		Action().GotoTable(ipSpoofGuardTable.GetNext()).
+		Cookie(c.cookieAllocator.Request(category).Raw()).
+		Done()
+}
+
 // serviceHairpinResponseDNATFlow generates the flow which transforms destination
 // IP of the hairpin packet to the source IP.
 func (c *client) serviceHairpinResponseDNATFlow() binding.Flow {
@@ -750,14 +1106,14 @@ func (c *client) gatewayIPSpoofGuardFlow(gatewayOFPort uint32, category cookie.C
 }
 
 // sessionAffinityReselectFlow generates the flow which resubmits the service accessing
-// packet back to serviceLBTable if there is no endpointDNAT flow matched. This
+// packet back to ServiceLBTable if there is no endpointDNAT flow matched. This
 // case will occur if an Endpoint is removed and is the learned Endpoint
 // selection of the Service.
 func (c *client) sessionAffinityReselectFlow() binding.Flow {
-	return c.pipeline[endpointDNATTable].BuildFlow(priorityLow).
+	return c.pipeline[EndpointDNATTable].BuildFlow(priorityLow).
 		MatchRegRange(int(serviceLearnReg), marksRegServiceSelected, serviceLearnRegRange).
 		Action().LoadRegRange(int(serviceLearnReg), marksRegServiceNeedLB, serviceLearnRegRange).
-		Action().ResubmitToTable(serviceLBTable).
+		Action().ResubmitToTable(ServiceLBTable).
 		Cookie(c.cookieAllocator.Request(cookie.Service).Raw()).
 		Done()
 }
@@ -792,37 +1148,148 @@ func (c *client) arpNormalFlow(category cookie.Category) binding.Flow {
 
 // conjunctionActionFlow generates the flow to jump to a specific table if policyRuleConjunction ID is matched. Priority of
 // conjunctionActionFlow is created at priorityLow for k8s network policies, and *priority assigned by PriorityAssigner for CNP.
-func (c *client) conjunctionActionFlow(conjunctionID uint32, tableID binding.TableIDType, nextTable binding.TableIDType, priority *uint16) binding.Flow {
+// If preserveSourceIP is true, the packet is marked so that it is not SNATed when it leaves the Node: only the first
+// packet of a connection is matched by the rule, but the SNAT decision is also taken once per connection.
+func (c *client) conjunctionActionFlow(conjunctionID uint32, tableID binding.TableIDType, nextTable binding.TableIDType, priority *uint16, enableLogging bool, preserveSourceIP bool) binding.Flow {
 	var ofPriority uint16
 	if priority == nil {
 		ofPriority = priorityLow
 	} else {
 		ofPriority = *priority
 	}
-	conjReg := IngressReg
-	if tableID == EgressRuleTable {
-		conjReg = EgressReg
-	}
-	return c.pipeline[tableID].BuildFlow(ofPriority).MatchProtocol(binding.ProtocolIP).
+	conjReg := GetConjunctionIDReg(tableID)
+	flowBuilder := c.pipeline[tableID].BuildFlow(ofPriority).MatchProtocol(binding.ProtocolIP).
 		MatchConjID(conjunctionID).
 		MatchPriority(ofPriority).
-		Action().LoadRegRange(int(conjReg), conjunctionID, binding.Range{0, 31}). // Traceflow.
-		Action().GotoTable(nextTable).
-		Cookie(c.cookieAllocator.Request(cookie.Policy).Raw()).
+		Action().LoadRegRange(int(conjReg), conjunctionID, binding.Range{0, 31}) // Traceflow.
+	if enableLogging {
+		// Log the allowed connection in the policyLoggingTable, then go on
+		// with the enforcement actions.
+		flowBuilder = c.resubmitToPolicyLoggingTable(flowBuilder, conjunctionID)
+	}
+	if preserveSourceIP {
+		flowBuilder = flowBuilder.Action().LoadRegRange(int(marksReg), preserveSourceIPMark, preserveSourceIPMarkRange).
+			Action().LoadRange(binding.NxmFieldPktMark, preserveSourceIPMark, preserveSourceIPPktMarkRange)
+	}
+	return flowBuilder.Action().GotoTable(nextTable).
+		Cookie(c.cookieAllocator.Request(cookie.Policy).WithPolicyAction(cookie.PolicyActionAllow).Raw()).
 		Done()
 }
 
 // conjunctionActionFlow generates the flow to drop traffic if policyRuleConjunction ID is matched.
-func (c *client) conjunctionActionDropFlow(conjunctionID uint32, tableID binding.TableIDType, priority *uint16) binding.Flow {
+func (c *client) conjunctionActionDropFlow(conjunctionID uint32, tableID binding.TableIDType, priority *uint16, enableLogging bool) binding.Flow {
+	ofPriority := *priority
+	flowBuilder := c.pipeline[tableID].BuildFlow(ofPriority).MatchProtocol(binding.ProtocolIP).
+		MatchConjID(conjunctionID).
+		MatchPriority(ofPriority)
+	if enableLogging {
+		// Log the denied packet in the policyLoggingTable before dropping it.
+		flowBuilder = c.resubmitToPolicyLoggingTable(flowBuilder, conjunctionID)
+	}
+	return flowBuilder.Action().Drop().
+		Cookie(c.cookieAllocator.Request(cookie.Policy).WithPolicyAction(cookie.PolicyActionDeny).Raw()).
+		Done()
+}
+
+// resubmitToPolicyLoggingTable adds the actions which log the packet matching the
+// provided rule to an action flow: the conjunction ID is loaded into
+// policyLoggingReg, so that the agent can find the rule, and the packet is
+// resubmitted to the policyLoggingTable, which returns to the action flow once
+// the packet has been sent to the agent.
+func (c *client) resubmitToPolicyLoggingTable(flowBuilder binding.FlowBuilder, conjunctionID uint32) binding.FlowBuilder {
+	return flowBuilder.Action().LoadRegRange(int(policyLoggingReg), conjunctionID, conjIDRegRange).
+		Action().ResubmitToTable(policyLoggingTable)
+}
+
+// policyLoggingFlow generates the flow of the policyLoggingTable, which sends
+// the packets resubmitted by the action flows of the NetworkPolicy rules which
+// enable logging to the agent. The packets of the rules which do not enable
+// logging never enter the table.
+func (c *client) policyLoggingFlow(category cookie.Category) binding.Flow {
+	return c.pipeline[policyLoggingTable].BuildFlow(priorityNormal).
+		Action().SendToController(uint8(PacketInReasonNP)).
+		Cookie(c.cookieAllocator.Request(category).Raw()).
+		Done()
+}
+
+// conjunctionL7ActionFlow generates the action flow of a ClusterNetworkPolicy rule with HTTP matches. It lets the
+// first packet of a TCP connection matching the rule go, and learns a flow in the L7 connection table which loads the
+// pending L7 state and the conjunction ID for the following packets the client sends on the connection, so that they
+// are all sent to the agent, which evaluates every HTTP request of the connection. The learned flow is removed after
+// the client has closed the connection, or when the rule is deleted.
+func (c *client) conjunctionL7ActionFlow(conjunctionID uint32, tableID binding.TableIDType, nextTable binding.TableIDType, priority *uint16) binding.Flow {
 	ofPriority := *priority
-	return c.pipeline[tableID].BuildFlow(ofPriority).MatchProtocol(binding.ProtocolIP).
+	conjReg := GetConjunctionIDReg(tableID)
+	cookieID := c.cookieAllocator.Request(cookie.Policy)
+	return c.pipeline[tableID].BuildFlow(ofPriority).MatchProtocol(binding.ProtocolTCP).
 		MatchConjID(conjunctionID).
 		MatchPriority(ofPriority).
-		Action().Drop().
-		Cookie(c.cookieAllocator.Request(cookie.Policy).Raw()).
+		Action().LoadRegRange(int(conjReg), conjunctionID, binding.Range{0, 31}).
+		Action().LearnWithFinTimeout(getL7ConnTable(tableID), priorityNormal, l7ConnFinIdleTimeout, cookieID.Raw()).
+		DeleteLearned().
+		MatchLearnedTCPDstPort().
+		MatchLearnedTCPSrcPort().
+		MatchLearnedSrcIP().
+		MatchLearnedDstIP().
+		LoadReg(int(marksReg), l7StatePending, l7StateRange).
+		LoadRegToReg(int(conjReg), int(conjReg), binding.Range{0, 31}, binding.Range{0, 31}).
+		Done().
+		Action().GotoTable(nextTable).
+		Cookie(cookieID.WithPolicyAction(cookie.PolicyActionAllow).Raw()).
 		Done()
 }
 
+// l7ConnDenyFlow generates the flow which overrides the pending L7 state of a TCP connection after the agent has
+// denied one of its HTTP requests. The cookie of the flow carries the conjunction ID of the rule, so that the flows
+// of the rule can be removed with it. OVS removes the flow after the connection has been idle for l7DenyIdleTimeout.
+func (c *client) l7ConnDenyFlow(conjunctionID uint32, tableID binding.TableIDType, srcIP, dstIP net.IP, srcPort, dstPort uint16) binding.Flow {
+	return c.pipeline[tableID].BuildFlow(priorityHigh).MatchProtocol(binding.ProtocolTCP).
+		MatchSrcIP(srcIP).
+		MatchDstIP(dstIP).
+		MatchTCPSrcPort(srcPort).
+		MatchTCPDstPort(dstPort).
+		Action().LoadRegRange(int(marksReg), l7StateDeny, l7StateRange).
+		SetIdleTimeout(l7DenyIdleTimeout).
+		Cookie(c.cookieAllocator.RequestWithObjectID(cookie.Policy, conjunctionID).Raw()).
+		Done()
+}
+
+// GetConjunctionIDReg returns the register in which the conjunction action flows
+// of the provided table load the conjunction ID. For the policyLoggingTable, it
+// is the register storing the conjunction ID of the rule whose packet is logged.
+func GetConjunctionIDReg(tableID binding.TableIDType) regType {
+	switch tableID {
+	case EgressRuleTable, cnpEgressRuleTable, cnpEgressL7ConnTable, cnpEgressL7VerdictTable:
+		return EgressReg
+	case policyLoggingTable:
+		return policyLoggingReg
+	}
+	return IngressReg
+}
+
+// IsPolicyLoggingTable returns whether the provided table is the policy logging
+// table, which sends the packets of the NetworkPolicy rules enabling logging to
+// the controller.
+func IsPolicyLoggingTable(tableID binding.TableIDType) bool {
+	return tableID == policyLoggingTable
+}
+
+// IsL7VerdictTable returns whether the provided table is an L7 verdict table,
+// which sends the packets of the TCP connections whose HTTP requests are
+// evaluated by the agent to the controller.
+func IsL7VerdictTable(tableID binding.TableIDType) bool {
+	return tableID == cnpEgressL7VerdictTable || tableID == cnpIngressL7VerdictTable
+}
+
+// getL7ConnTable returns the L7 connection table of the provided
+// ClusterNetworkPolicy rule table.
+func getL7ConnTable(tableID binding.TableIDType) binding.TableIDType {
+	if tableID == cnpEgressRuleTable {
+		return cnpEgressL7ConnTable
+	}
+	return cnpIngressL7ConnTable
+}
+
 func (c *client) Disconnect() error {
 	return c.bridge.Disconnect()
 }
@@ -840,12 +1307,12 @@ func (c *client) establishedConnectionFlows(category cookie.Category) (flows []b
 	egressEstFlow := c.pipeline[EgressRuleTable].BuildFlow(priorityHigh).MatchProtocol(binding.ProtocolIP).
 		MatchCTStateNew(false).MatchCTStateEst(true).
 		Action().GotoTable(egressDropTable.GetNext()).
-		Cookie(c.cookieAllocator.Request(category).Raw()).
+		Cookie(c.cookieAllocator.Request(category).WithPolicyAction(cookie.PolicyActionAllow).Raw()).
 		Done()
 	cnpEgressEstFlow := c.pipeline[cnpEgressRuleTable].BuildFlow(priorityTopCNP).MatchProtocol(binding.ProtocolIP).
 		MatchCTStateNew(false).MatchCTStateEst(true).
 		Action().GotoTable(egressDropTable.GetNext()).
-		Cookie(c.cookieAllocator.Request(category).Raw()).
+		Cookie(c.cookieAllocator.Request(category).WithPolicyAction(cookie.PolicyActionAllow).Raw()).
 		Done()
 	// ingressDropTable checks the destination address of packets, and drops packets sent to the AppliedToGroup but not
 	// matching the NetworkPolicy rules. Packets in the established connections need not to be checked with the
@@ -854,16 +1321,66 @@ func (c *client) establishedConnectionFlows(category cookie.Category) (flows []b
 	ingressEstFlow := c.pipeline[IngressRuleTable].BuildFlow(priorityHigh).MatchProtocol(binding.ProtocolIP).
 		MatchCTStateNew(false).MatchCTStateEst(true).
 		Action().GotoTable(ingressDropTable.GetNext()).
-		Cookie(c.cookieAllocator.Request(category).Raw()).
+		Cookie(c.cookieAllocator.Request(category).WithPolicyAction(cookie.PolicyActionAllow).Raw()).
 		Done()
 	cnpIngressEstFlow := c.pipeline[cnpIngressRuleTable].BuildFlow(priorityTopCNP).MatchProtocol(binding.ProtocolIP).
 		MatchCTStateNew(false).MatchCTStateEst(true).
 		Action().GotoTable(ingressDropTable.GetNext()).
-		Cookie(c.cookieAllocator.Request(category).Raw()).
+		Cookie(c.cookieAllocator.Request(category).WithPolicyAction(cookie.PolicyActionAllow).Raw()).
 		Done()
 	return []binding.Flow{egressEstFlow, ingressEstFlow, cnpEgressEstFlow, cnpIngressEstFlow}
 }
 
+// l7ConnectionFlows generates flows to check the packets of the established TCP connections with the L7 connection
+// tables before they skip the ClusterNetworkPolicy rules. The L7 connection tables load the L7 states of the connections
+// matching the rules with HTTP matches, then the L7 verdict tables drop the packets of the denied connections, and send
+// the whole packets of the pending connections to the controller. The agent evaluates the HTTP requests they carry,
+// and re-injects the allowed packets with the mark of the direction of the verdict table, so that they resume at the
+// table following it. The packets re-injected after the egress verdict table are untracked when they reach the
+// ingressRuleTable, and are sent to the L7 connection table of the ingress direction regardless of their ct_state.
+func (c *client) l7ConnectionFlows(category cookie.Category) (flows []binding.Flow) {
+	for _, tables := range []struct {
+		ruleTable, connTable, verdictTable binding.TableIDType
+		reinjectMark                       uint32
+	}{
+		{cnpEgressRuleTable, cnpEgressL7ConnTable, cnpEgressL7VerdictTable, l7ReinjectEgress},
+		{cnpIngressRuleTable, cnpIngressL7ConnTable, cnpIngressL7VerdictTable, l7ReinjectIngress},
+	} {
+		verdictTable := c.pipeline[tables.verdictTable]
+		flows = append(flows,
+			c.pipeline[tables.ruleTable].BuildFlow(priorityTopCNP+1).MatchProtocol(binding.ProtocolTCP).
+				MatchCTStateNew(false).MatchCTStateEst(true).
+				Action().LoadRegRange(int(marksReg), 0, l7StateRange).
+				Action().ResubmitToTable(tables.connTable).
+				Action().GotoTable(tables.verdictTable).
+				Cookie(c.cookieAllocator.Request(category).WithPolicyAction(cookie.PolicyActionJump).Raw()).
+				Done(),
+			verdictTable.BuildFlow(priorityNormal).MatchProtocol(binding.ProtocolTCP).
+				MatchRegRange(int(marksReg), l7StatePending, l7StateRange).
+				Action().SendToControllerUntruncated(uint8(PacketInReasonNP)).
+				Cookie(c.cookieAllocator.Request(category).WithPolicyAction(cookie.PolicyActionDeny).Raw()).
+				Done(),
+			verdictTable.BuildFlow(priorityNormal).MatchProtocol(binding.ProtocolTCP).
+				MatchRegRange(int(marksReg), l7StateDeny, l7StateRange).
+				Action().Drop().
+				Cookie(c.cookieAllocator.Request(category).WithPolicyAction(cookie.PolicyActionDeny).Raw()).
+				Done(),
+			c.pipeline[ClassifierTable].BuildFlow(priorityL7Reinject).MatchProtocol(binding.ProtocolTCP).
+				MatchRegRange(int(marksReg), tables.reinjectMark, l7ReinjectRange).
+				Action().GotoTable(verdictTable.GetNext()).
+				Cookie(c.cookieAllocator.Request(category).Raw()).
+				Done(),
+		)
+	}
+	flows = append(flows, c.pipeline[cnpIngressRuleTable].BuildFlow(priorityTopCNP+1).MatchProtocol(binding.ProtocolTCP).
+		MatchRegRange(int(marksReg), l7ReinjectEgress, l7ReinjectRange).
+		Action().ResubmitToTable(cnpIngressL7ConnTable).
+		Action().GotoTable(cnpIngressL7VerdictTable).
+		Cookie(c.cookieAllocator.Request(category).WithPolicyAction(cookie.PolicyActionJump).Raw()).
+		Done())
+	return flows
+}
+
 func (c *client) addFlowMatch(fb binding.FlowBuilder, matchType int, matchValue interface{}) binding.FlowBuilder {
 	switch matchType {
 	case MatchDstIP:
@@ -937,7 +1454,7 @@ func (c *client) defaultDropFlow(tableID binding.TableIDType, matchKey int, matc
 	fb := c.pipeline[tableID].BuildFlow(priorityNormal)
 	return c.addFlowMatch(fb, matchKey, matchValue).
 		Action().Drop().
-		Cookie(c.cookieAllocator.Request(cookie.Default).Raw()).
+		Cookie(c.cookieAllocator.Request(cookie.Default).WithPolicyAction(cookie.PolicyActionDeny).Raw()).
 		Done()
 }
 
@@ -947,7 +1464,7 @@ func (c *client) localProbeFlow(localGatewayIP net.IP, category cookie.Category)
 		MatchProtocol(binding.ProtocolIP).
 		MatchSrcIP(localGatewayIP).
 		Action().GotoTable(conntrackCommitTable).
-		Cookie(c.cookieAllocator.Request(category).Raw()).
+		Cookie(c.cookieAllocator.Request(category).WithPolicyAction(cookie.PolicyActionAllow).Raw()).
 		Done()
 }
 
@@ -956,7 +1473,7 @@ func (c *client) bridgeAndUplinkFlows(uplinkOfport uint32, bridgeLocalPort uint3
 	vMACInt, _ := strconv.ParseUint(strings.Replace(globalVirtualMAC.String(), ":", "", -1), 16, 64)
 	ctStateNext := dnatTable
 	if c.enableProxy {
-		ctStateNext = endpointDNATTable
+		ctStateNext = EndpointDNATTable
 	}
 	flows := []binding.Flow{
 		// Forward the packet to conntrackTable if it enters the OVS pipeline from the uplink interface.
@@ -1009,6 +1526,16 @@ func (c *client) bridgeAndUplinkFlows(uplinkOfport uint32, bridgeLocalPort uint3
 			Action().Output(int(bridgeLocalPort)).
 			Cookie(c.cookieAllocator.Request(category).Raw()).
 			Done(),
+		// Commit the packet without SNAT if it matches an egress rule with preserveSourceIP, so that the destination
+		// sees the Pod IP. The packet is still output to the same port as the SNATed packets.
+		c.pipeline[conntrackCommitTable].BuildFlow(priorityHigh).
+			MatchProtocol(binding.ProtocolIP).
+			MatchCTStateNew(true).MatchCTStateTrk(true).
+			MatchRegRange(int(marksReg), snatRequiredMark, snatMarkRange).
+			MatchRegRange(int(marksReg), preserveSourceIPMark, preserveSourceIPMarkRange).
+			Action().CT(true, l2ForwardingOutTable, CtZone).CTDone().
+			Cookie(c.cookieAllocator.Request(category).Raw()).
+			Done(),
 		// Enforce the packet into L2ForwardingOutput table after the packet is SNATed. The "SNAT" packet has these
 		// characteristics: 1) the ct_state is "+new+trk", 2) reg0[17] is set to 1; 3) Node IP is used as the target
 		// source IP in NAT action, 4) ct_mark is set to 0x40 in the conn_track context.
@@ -1079,45 +1606,67 @@ func (c *client) l3ToExternalFlows(nodeIP net.IP, localSubnet net.IPNet, outputP
 func (c *client) serviceLearnFlow(groupID binding.GroupIDType, svcIP net.IP, svcPort uint16, protocol binding.Protocol, affinityTimeout uint16) binding.Flow {
 	// Using unique cookie ID here to avoid learned flow cascade deletion.
 	cookieID := c.cookieAllocator.RequestWithObjectID(cookie.Service, uint32(groupID)).Raw()
-	learnFlowBuilder := c.pipeline[serviceLBTable].BuildFlow(priorityLow).
+	learnFlowBuilder := c.pipeline[ServiceLBTable].BuildFlow(priorityLow).
 		MatchRegRange(int(serviceLearnReg), marksRegServiceNeedLearn, serviceLearnRegRange).
 		MatchDstIP(svcIP).
 		Cookie(cookieID)
 	learnFlowBuilderLearnAction := learnFlowBuilder.
 		Action().Learn(sessionAffinityTable, priorityNormal, affinityTimeout, 0, cookieID).
 		DeleteLearned()
-	if protocol == binding.ProtocolTCP {
+	switch protocol {
+	case binding.ProtocolTCP:
 		learnFlowBuilder = learnFlowBuilder.MatchTCPDstPort(svcPort)
 		learnFlowBuilderLearnAction = learnFlowBuilderLearnAction.MatchLearnedTCPDstPort()
-	} else if protocol == binding.ProtocolUDP {
+	case binding.ProtocolUDP:
 		learnFlowBuilder = learnFlowBuilder.MatchUDPDstPort(svcPort)
 		learnFlowBuilderLearnAction = learnFlowBuilderLearnAction.MatchLearnedUDPDstPort()
-	} else if protocol == binding.ProtocolSCTP {
+	case binding.ProtocolSCTP:
 		learnFlowBuilder = learnFlowBuilder.MatchSCTPDstPort(svcPort)
 		learnFlowBuilderLearnAction = learnFlowBuilderLearnAction.MatchLearnedSCTPDstPort()
+	case binding.ProtocolTCPv6:
+		learnFlowBuilder = learnFlowBuilder.MatchProtocol(protocol).MatchTCPDstPort(svcPort)
+		learnFlowBuilderLearnAction = learnFlowBuilderLearnAction.MatchTransportDst(protocol)
+	case binding.ProtocolUDPv6:
+		learnFlowBuilder = learnFlowBuilder.MatchProtocol(protocol).MatchUDPDstPort(svcPort)
+		learnFlowBuilderLearnAction = learnFlowBuilderLearnAction.MatchTransportDst(protocol)
+	case binding.ProtocolSCTPv6:
+		learnFlowBuilder = learnFlowBuilder.MatchProtocol(protocol).MatchSCTPDstPort(svcPort)
+		learnFlowBuilderLearnAction = learnFlowBuilderLearnAction.MatchTransportDst(protocol)
+	}
+	if svcIP.To4() == nil {
+		learnFlowBuilderLearnAction = learnFlowBuilderLearnAction.
+			MatchLearnedDstIPv6().
+			MatchLearnedSrcIPv6()
+		for _, reg := range endpointIPv6HighRegs {
+			learnFlowBuilderLearnAction = learnFlowBuilderLearnAction.
+				LoadRegToReg(int(reg), int(reg), endpointIPRegRange, endpointIPRegRange)
+		}
+	} else {
+		learnFlowBuilderLearnAction = learnFlowBuilderLearnAction.
+			MatchLearnedDstIP().
+			MatchLearnedSrcIP()
 	}
 	return learnFlowBuilderLearnAction.
-		MatchLearnedDstIP().
-		MatchLearnedSrcIP().
 		LoadRegToReg(int(endpointIPReg), int(endpointIPReg), endpointIPRegRange, endpointIPRegRange).
 		LoadRegToReg(int(endpointPortReg), int(endpointPortReg), endpointPortRegRange, endpointPortRegRange).
 		LoadReg(int(serviceLearnReg), marksRegServiceSelected, serviceLearnRegRange).
 		LoadReg(int(marksReg), macRewriteMark, macRewriteMarkRange).
 		Done().
 		Action().LoadRegRange(int(serviceLearnReg), marksRegServiceSelected, serviceLearnRegRange).
-		Action().GotoTable(endpointDNATTable).
+		Action().GotoTable(EndpointDNATTable).
 		Done()
 }
 
 // serviceLBFlow generates the flow which uses the specific group to do Endpoint
 // selection.
 func (c *client) serviceLBFlow(groupID binding.GroupIDType, svcIP net.IP, svcPort uint16, protocol binding.Protocol) binding.Flow {
-	lbFlowBuilder := c.pipeline[serviceLBTable].BuildFlow(priorityNormal)
-	if protocol == binding.ProtocolTCP {
+	lbFlowBuilder := c.pipeline[ServiceLBTable].BuildFlow(priorityNormal).MatchProtocol(protocol)
+	switch protocol {
+	case binding.ProtocolTCP, binding.ProtocolTCPv6:
 		lbFlowBuilder = lbFlowBuilder.MatchTCPDstPort(svcPort)
-	} else if protocol == binding.ProtocolUDP {
+	case binding.ProtocolUDP, binding.ProtocolUDPv6:
 		lbFlowBuilder = lbFlowBuilder.MatchUDPDstPort(svcPort)
-	} else if protocol == binding.ProtocolSCTP {
+	case binding.ProtocolSCTP, binding.ProtocolSCTPv6:
 		lbFlowBuilder = lbFlowBuilder.MatchSCTPDstPort(svcPort)
 	}
 	lbFlow := lbFlowBuilder.
@@ -1133,12 +1682,16 @@ func (c *client) serviceLBFlow(groupID binding.GroupIDType, svcIP net.IP, svcPor
 // to the Endpoint IP according to the Endpoint selection decision which is stored
 // in regs.
 func (c *client) endpointDNATFlow(endpointIP net.IP, endpointPort uint16, protocol binding.Protocol) binding.Flow {
-	ipVal := binary.BigEndian.Uint32(endpointIP)
+	ipVal, highIPVals := endpointIPRegValues(endpointIP)
 	unionVal := (marksRegServiceSelected << endpointPortRegRange.Length()) + uint32(endpointPort)
-	return c.pipeline[endpointDNATTable].BuildFlow(priorityNormal).
+	flowBuilder := c.pipeline[EndpointDNATTable].BuildFlow(priorityNormal).
 		Cookie(c.cookieAllocator.Request(cookie.Service).Raw()).
 		MatchProtocol(protocol).
-		MatchReg(int(endpointIPReg), ipVal).
+		MatchReg(int(endpointIPReg), ipVal)
+	for i, val := range highIPVals {
+		flowBuilder = flowBuilder.MatchReg(int(endpointIPv6HighRegs[i]), val)
+	}
+	return flowBuilder.
 		MatchRegRange(int(endpointPortReg), unionVal, binding.Range{0, 18}).
 		Action().CT(true, EgressRuleTable, CtZone).
 		DNAT(
@@ -1166,27 +1719,40 @@ func (c *client) hairpinSNATFlow(endpointIP net.IP) binding.Flow {
 
 // serviceEndpointGroup creates/modifies the group/buckets of Endpoints. If the
 // withSessionAffinity is true, then buckets will resubmit packets back to
-// serviceLBTable to trigger the learn flow, the learn flow will then send packets
-// to endpointDNATTable. Otherwise, buckets will resubmit packets to
-// endpointDNATTable directly.
+// ServiceLBTable to trigger the learn flow, the learn flow will then send packets
+// to EndpointDNATTable. Otherwise, buckets will resubmit packets to
+// EndpointDNATTable directly. When fast-failover groups are enabled, the group
+// is a fast-failover group and each bucket watches the port of its Endpoint.
 func (c *client) serviceEndpointGroup(groupID binding.GroupIDType, withSessionAffinity bool, endpoints ...proxy.Endpoint) binding.Group {
-	group := c.bridge.CreateGroup(groupID).ResetBuckets()
+	groupType := binding.GroupSelect
+	if c.endpointWatchPort != nil {
+		groupType = binding.GroupFastFailover
+	}
+	group := c.bridge.CreateGroupWithType(groupID, groupType).ResetBuckets()
 	var resubmitTableID binding.TableIDType
 	var lbResultMark uint32
 	if withSessionAffinity {
-		resubmitTableID = serviceLBTable
+		resubmitTableID = ServiceLBTable
 		lbResultMark = marksRegServiceNeedLearn
 	} else {
-		resubmitTableID = endpointDNATTable
+		resubmitTableID = EndpointDNATTable
 		lbResultMark = marksRegServiceSelected
 	}
 
 	for _, endpoint := range endpoints {
 		endpointPort, _ := endpoint.Port()
-		endpointIP := net.ParseIP(endpoint.IP()).To4()
-		ipVal := binary.BigEndian.Uint32(endpointIP)
+		ipVal, highIPVals := endpointIPRegValues(net.ParseIP(endpoint.IP()))
 		portVal := uint16(endpointPort)
-		group = group.Bucket().Weight(100).
+		bucketBuilder := group.Bucket()
+		if c.endpointWatchPort == nil {
+			bucketBuilder = bucketBuilder.Weight(100)
+		} else if watchPort, ok := c.endpointWatchPort(endpoint.IP()); ok {
+			bucketBuilder = bucketBuilder.WatchPort(watchPort)
+		}
+		for i, val := range highIPVals {
+			bucketBuilder = bucketBuilder.LoadReg(int(endpointIPv6HighRegs[i]), val)
+		}
+		group = bucketBuilder.
 			LoadReg(int(endpointIPReg), ipVal).
 			LoadRegRange(int(endpointPortReg), uint32(portVal), endpointPortRegRange).
 			LoadRegRange(int(serviceLearnReg), lbResultMark, serviceLearnRegRange).
@@ -1197,6 +1763,21 @@ func (c *client) serviceEndpointGroup(groupID binding.GroupIDType, withSessionAf
 	return group
 }
 
+// endpointIPRegValues returns the value to be stored in endpointIPReg for the
+// provided Endpoint IP. For an IPv6 address, it also returns the values of the
+// upper 96 bits which are to be stored in endpointIPv6HighRegs.
+func endpointIPRegValues(endpointIP net.IP) (uint32, []uint32) {
+	if ipv4 := endpointIP.To4(); ipv4 != nil {
+		return binary.BigEndian.Uint32(ipv4), nil
+	}
+	ipv6 := endpointIP.To16()
+	highVals := make([]uint32, len(endpointIPv6HighRegs))
+	for i := range highVals {
+		highVals[i] = binary.BigEndian.Uint32(ipv6[i*4 : (i+1)*4])
+	}
+	return binary.BigEndian.Uint32(ipv6[12:16]), highVals
+}
+
 // policyConjKeyFuncKeyFunc knows how to get key of a *policyRuleConjunction.
 func policyConjKeyFunc(obj interface{}) (string, error) {
 	conj := obj.(*policyRuleConjunction)
@@ -1213,45 +1794,57 @@ func priorityIndexFunc(obj interface{}) ([]string, error) {
 func generatePipeline(bridge binding.Bridge, enableProxy bool) map[binding.TableIDType]binding.Table {
 	if enableProxy {
 		return map[binding.TableIDType]binding.Table{
-			ClassifierTable:       bridge.CreateTable(ClassifierTable, spoofGuardTable, binding.TableMissActionDrop),
-			spoofGuardTable:       bridge.CreateTable(spoofGuardTable, serviceHairpinTable, binding.TableMissActionDrop),
-			arpResponderTable:     bridge.CreateTable(arpResponderTable, binding.LastTableID, binding.TableMissActionDrop),
-			serviceHairpinTable:   bridge.CreateTable(serviceHairpinTable, conntrackTable, binding.TableMissActionNext),
-			conntrackTable:        bridge.CreateTable(conntrackTable, conntrackStateTable, binding.TableMissActionNone),
-			conntrackStateTable:   bridge.CreateTable(conntrackStateTable, endpointDNATTable, binding.TableMissActionNext),
-			sessionAffinityTable:  bridge.CreateTable(sessionAffinityTable, binding.LastTableID, binding.TableMissActionNone),
-			serviceLBTable:        bridge.CreateTable(serviceLBTable, endpointDNATTable, binding.TableMissActionNext),
-			endpointDNATTable:     bridge.CreateTable(endpointDNATTable, cnpEgressRuleTable, binding.TableMissActionNext),
-			cnpEgressRuleTable:    bridge.CreateTable(cnpEgressRuleTable, EgressRuleTable, binding.TableMissActionNext),
-			EgressRuleTable:       bridge.CreateTable(EgressRuleTable, egressDefaultTable, binding.TableMissActionNext),
-			egressDefaultTable:    bridge.CreateTable(egressDefaultTable, l3ForwardingTable, binding.TableMissActionNext),
-			l3ForwardingTable:     bridge.CreateTable(l3ForwardingTable, l2ForwardingCalcTable, binding.TableMissActionNext),
-			l2ForwardingCalcTable: bridge.CreateTable(l2ForwardingCalcTable, cnpIngressRuleTable, binding.TableMissActionNext),
-			cnpIngressRuleTable:   bridge.CreateTable(cnpIngressRuleTable, IngressRuleTable, binding.TableMissActionNext),
-			IngressRuleTable:      bridge.CreateTable(IngressRuleTable, ingressDefaultTable, binding.TableMissActionNext),
-			ingressDefaultTable:   bridge.CreateTable(ingressDefaultTable, conntrackCommitTable, binding.TableMissActionNext),
-			conntrackCommitTable:  bridge.CreateTable(conntrackCommitTable, hairpinSNATTable, binding.TableMissActionNext),
-			hairpinSNATTable:      bridge.CreateTable(hairpinSNATTable, l2ForwardingOutTable, binding.TableMissActionNext),
-			l2ForwardingOutTable:  bridge.CreateTable(l2ForwardingOutTable, binding.LastTableID, binding.TableMissActionDrop),
+			ClassifierTable:          bridge.CreateTable(ClassifierTable, spoofGuardTable, binding.TableMissActionDrop),
+			spoofGuardTable:          bridge.CreateTable(spoofGuardTable, serviceHairpinTable, binding.TableMissActionDrop),
+			arpResponderTable:        bridge.CreateTable(arpResponderTable, binding.LastTableID, binding.TableMissActionDrop),
+			serviceHairpinTable:      bridge.CreateTable(serviceHairpinTable, conntrackTable, binding.TableMissActionNext),
+			conntrackTable:           bridge.CreateTable(conntrackTable, conntrackStateTable, binding.TableMissActionNone),
+			conntrackStateTable:      bridge.CreateTable(conntrackStateTable, EndpointDNATTable, binding.TableMissActionNext),
+			sessionAffinityTable:     bridge.CreateTable(sessionAffinityTable, binding.LastTableID, binding.TableMissActionNone),
+			ServiceLBTable:           bridge.CreateTable(ServiceLBTable, EndpointDNATTable, binding.TableMissActionNext),
+			EndpointDNATTable:        bridge.CreateTable(EndpointDNATTable, cnpEgressRuleTable, binding.TableMissActionNext),
+			cnpEgressRuleTable:       bridge.CreateTable(cnpEgressRuleTable, EgressRuleTable, binding.TableMissActionNext),
+			cnpEgressL7ConnTable:     bridge.CreateTable(cnpEgressL7ConnTable, binding.LastTableID, binding.TableMissActionNone),
+			cnpEgressL7VerdictTable:  bridge.CreateTable(cnpEgressL7VerdictTable, l3ForwardingTable, binding.TableMissActionNext),
+			EgressRuleTable:          bridge.CreateTable(EgressRuleTable, egressDefaultTable, binding.TableMissActionNext),
+			egressDefaultTable:       bridge.CreateTable(egressDefaultTable, l3ForwardingTable, binding.TableMissActionNext),
+			policyLoggingTable:       bridge.CreateTable(policyLoggingTable, binding.LastTableID, binding.TableMissActionNone),
+			l3ForwardingTable:        bridge.CreateTable(l3ForwardingTable, egressNATTable, binding.TableMissActionNext),
+			egressNATTable:           bridge.CreateTable(egressNATTable, l2ForwardingCalcTable, binding.TableMissActionNext),
+			l2ForwardingCalcTable:    bridge.CreateTable(l2ForwardingCalcTable, cnpIngressRuleTable, binding.TableMissActionNext),
+			cnpIngressRuleTable:      bridge.CreateTable(cnpIngressRuleTable, IngressRuleTable, binding.TableMissActionNext),
+			cnpIngressL7ConnTable:    bridge.CreateTable(cnpIngressL7ConnTable, binding.LastTableID, binding.TableMissActionNone),
+			cnpIngressL7VerdictTable: bridge.CreateTable(cnpIngressL7VerdictTable, conntrackCommitTable, binding.TableMissActionNext),
+			IngressRuleTable:         bridge.CreateTable(IngressRuleTable, ingressDefaultTable, binding.TableMissActionNext),
+			ingressDefaultTable:      bridge.CreateTable(ingressDefaultTable, conntrackCommitTable, binding.TableMissActionNext),
+			conntrackCommitTable:     bridge.CreateTable(conntrackCommitTable, hairpinSNATTable, binding.TableMissActionNext),
+			hairpinSNATTable:         bridge.CreateTable(hairpinSNATTable, l2ForwardingOutTable, binding.TableMissActionNext),
+			l2ForwardingOutTable:     bridge.CreateTable(l2ForwardingOutTable, binding.LastTableID, binding.TableMissActionDrop),
 		}
 	}
 	return map[binding.TableIDType]binding.Table{
-		ClassifierTable:       bridge.CreateTable(ClassifierTable, spoofGuardTable, binding.TableMissActionDrop),
-		spoofGuardTable:       bridge.CreateTable(spoofGuardTable, conntrackTable, binding.TableMissActionDrop),
-		arpResponderTable:     bridge.CreateTable(arpResponderTable, binding.LastTableID, binding.TableMissActionDrop),
-		conntrackTable:        bridge.CreateTable(conntrackTable, conntrackStateTable, binding.TableMissActionNone),
-		conntrackStateTable:   bridge.CreateTable(conntrackStateTable, dnatTable, binding.TableMissActionNext),
-		dnatTable:             bridge.CreateTable(dnatTable, cnpEgressRuleTable, binding.TableMissActionNext),
-		cnpEgressRuleTable:    bridge.CreateTable(cnpEgressRuleTable, EgressRuleTable, binding.TableMissActionNext),
-		EgressRuleTable:       bridge.CreateTable(EgressRuleTable, egressDefaultTable, binding.TableMissActionNext),
-		egressDefaultTable:    bridge.CreateTable(egressDefaultTable, l3ForwardingTable, binding.TableMissActionNext),
-		l3ForwardingTable:     bridge.CreateTable(l3ForwardingTable, l2ForwardingCalcTable, binding.TableMissActionNext),
-		l2ForwardingCalcTable: bridge.CreateTable(l2ForwardingCalcTable, cnpIngressRuleTable, binding.TableMissActionNext),
-		cnpIngressRuleTable:   bridge.CreateTable(cnpIngressRuleTable, IngressRuleTable, binding.TableMissActionNext),
-		IngressRuleTable:      bridge.CreateTable(IngressRuleTable, ingressDefaultTable, binding.TableMissActionNext),
-		ingressDefaultTable:   bridge.CreateTable(ingressDefaultTable, conntrackCommitTable, binding.TableMissActionNext),
-		conntrackCommitTable:  bridge.CreateTable(conntrackCommitTable, l2ForwardingOutTable, binding.TableMissActionNext),
-		l2ForwardingOutTable:  bridge.CreateTable(l2ForwardingOutTable, binding.LastTableID, binding.TableMissActionDrop),
+		ClassifierTable:          bridge.CreateTable(ClassifierTable, spoofGuardTable, binding.TableMissActionDrop),
+		spoofGuardTable:          bridge.CreateTable(spoofGuardTable, conntrackTable, binding.TableMissActionDrop),
+		arpResponderTable:        bridge.CreateTable(arpResponderTable, binding.LastTableID, binding.TableMissActionDrop),
+		conntrackTable:           bridge.CreateTable(conntrackTable, conntrackStateTable, binding.TableMissActionNone),
+		conntrackStateTable:      bridge.CreateTable(conntrackStateTable, dnatTable, binding.TableMissActionNext),
+		dnatTable:                bridge.CreateTable(dnatTable, cnpEgressRuleTable, binding.TableMissActionNext),
+		cnpEgressRuleTable:       bridge.CreateTable(cnpEgressRuleTable, EgressRuleTable, binding.TableMissActionNext),
+		cnpEgressL7ConnTable:     bridge.CreateTable(cnpEgressL7ConnTable, binding.LastTableID, binding.TableMissActionNone),
+		cnpEgressL7VerdictTable:  bridge.CreateTable(cnpEgressL7VerdictTable, l3ForwardingTable, binding.TableMissActionNext),
+		EgressRuleTable:          bridge.CreateTable(EgressRuleTable, egressDefaultTable, binding.TableMissActionNext),
+		egressDefaultTable:       bridge.CreateTable(egressDefaultTable, l3ForwardingTable, binding.TableMissActionNext),
+		policyLoggingTable:       bridge.CreateTable(policyLoggingTable, binding.LastTableID, binding.TableMissActionNone),
+		l3ForwardingTable:        bridge.CreateTable(l3ForwardingTable, egressNATTable, binding.TableMissActionNext),
+		egressNATTable:           bridge.CreateTable(egressNATTable, l2ForwardingCalcTable, binding.TableMissActionNext),
+		l2ForwardingCalcTable:    bridge.CreateTable(l2ForwardingCalcTable, cnpIngressRuleTable, binding.TableMissActionNext),
+		cnpIngressRuleTable:      bridge.CreateTable(cnpIngressRuleTable, IngressRuleTable, binding.TableMissActionNext),
+		cnpIngressL7ConnTable:    bridge.CreateTable(cnpIngressL7ConnTable, binding.LastTableID, binding.TableMissActionNone),
+		cnpIngressL7VerdictTable: bridge.CreateTable(cnpIngressL7VerdictTable, conntrackCommitTable, binding.TableMissActionNext),
+		IngressRuleTable:         bridge.CreateTable(IngressRuleTable, ingressDefaultTable, binding.TableMissActionNext),
+		ingressDefaultTable:      bridge.CreateTable(ingressDefaultTable, conntrackCommitTable, binding.TableMissActionNext),
+		conntrackCommitTable:     bridge.CreateTable(conntrackCommitTable, l2ForwardingOutTable, binding.TableMissActionNext),
+		l2ForwardingOutTable:     bridge.CreateTable(l2ForwardingOutTable, binding.LastTableID, binding.TableMissActionDrop),
 	}
 }
 
@@ -1268,10 +1861,16 @@ func NewClient(bridgeName, mgmtAddr string, enableProxy bool) Client {
 		nodeFlowCache:            newFlowCategoryCache(),
 		podFlowCache:             newFlowCategoryCache(),
 		serviceFlowCache:         newFlowCategoryCache(),
+		traceflowFlowCache:       newFlowCategoryCache(),
+		egressFlowCache:          newFlowCategoryCache(),
 		policyCache:              policyCache,
 		groupCache:               sync.Map{},
 		globalConjMatchFlowCache: map[string]*conjMatchFlowContext{},
-		packetInHandlers:         map[string]PacketInHandler{},
+		baselineRules:            map[v1beta1.Direction]int{},
+		baselineDefaultFlowCache: map[v1beta1.Direction][]binding.Flow{},
+		baselineAppliedToCache:   map[v1beta1.Direction]map[string]*baselineAppliedTo{},
+		packetInHandlers:         map[uint8]map[string]PacketInHandler{},
+		flowInstaller:            binding.NewFlowInstaller(bridge, binding.DefaultFlowInstallBatchSize, binding.DefaultFlowInstallWorkers),
 	}
 	c.ofEntryOperations = c
 	c.enableProxy = enableProxy
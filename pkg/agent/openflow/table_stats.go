@@ -0,0 +1,133 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openflow
+
+import (
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/metrics"
+	"github.com/vmware-tanzu/antrea/pkg/agent/openflow/cookie"
+	binding "github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
+)
+
+const (
+	tableStatsReasonMatch = "match"
+	tableStatsReasonMiss  = "miss"
+)
+
+// tableTrafficStatsGetter is implemented by Client.
+type tableTrafficStatsGetter interface {
+	GetTableTrafficStats() (map[binding.TableIDType]*binding.TableTrafficStats, error)
+	GetPolicyTableTrafficStats() (map[binding.TableIDType]map[cookie.PolicyAction]*binding.TrafficStats, error)
+}
+
+// TableStatsCollector periodically collects the numbers of packets and bytes
+// which hit the flows of each table of the OVS pipeline, and exports them as
+// Prometheus counters. The statistics are retrieved on the OpenFlow connection
+// of the agent, without running any OVS command. The traffic which hit the
+// NetworkPolicy tables is also exported by PolicyAction, which is encoded in
+// the cookies of the flows.
+type TableStatsCollector struct {
+	client       tableTrafficStatsGetter
+	pollInterval time.Duration
+	// lastStats stores the statistics of the previous poll. The counters are
+	// increased by the difference with the current statistics.
+	lastStats map[binding.TableIDType]*binding.TableTrafficStats
+	// lastPolicyStats stores the statistics of the NetworkPolicy tables of
+	// the previous poll.
+	lastPolicyStats map[binding.TableIDType]map[cookie.PolicyAction]*binding.TrafficStats
+}
+
+func NewTableStatsCollector(client Client, pollInterval time.Duration) *TableStatsCollector {
+	return newTableStatsCollector(client, pollInterval)
+}
+
+func newTableStatsCollector(client tableTrafficStatsGetter, pollInterval time.Duration) *TableStatsCollector {
+	return &TableStatsCollector{
+		client:          client,
+		pollInterval:    pollInterval,
+		lastStats:       map[binding.TableIDType]*binding.TableTrafficStats{},
+		lastPolicyStats: map[binding.TableIDType]map[cookie.PolicyAction]*binding.TrafficStats{},
+	}
+}
+
+// counterDelta returns how much a counter must be increased. The statistics of
+// a table decrease when flows are deleted, or when OVS restarts and the agent
+// reinstalls the flows with zero counters. The traffic which hit the remaining
+// flows since the previous poll is then not counted, and the current
+// statistics become the base of the next poll, so that the counters go on
+// accumulating without a spike.
+func counterDelta(current, last uint64) float64 {
+	if current < last {
+		return 0
+	}
+	return float64(current - last)
+}
+
+func (c *TableStatsCollector) collect() error {
+	stats, err := c.client.GetTableTrafficStats()
+	if err != nil {
+		return err
+	}
+	for tableID, s := range stats {
+		last, ok := c.lastStats[tableID]
+		if !ok {
+			last = &binding.TableTrafficStats{}
+		}
+		table := strconv.Itoa(int(tableID))
+		metrics.OVSTablePacketCount.WithLabelValues(table, tableStatsReasonMatch).Add(counterDelta(s.MatchPacketCount, last.MatchPacketCount))
+		metrics.OVSTablePacketCount.WithLabelValues(table, tableStatsReasonMiss).Add(counterDelta(s.MissPacketCount, last.MissPacketCount))
+		metrics.OVSTableByteCount.WithLabelValues(table, tableStatsReasonMatch).Add(counterDelta(s.MatchByteCount, last.MatchByteCount))
+		metrics.OVSTableByteCount.WithLabelValues(table, tableStatsReasonMiss).Add(counterDelta(s.MissByteCount, last.MissByteCount))
+	}
+	c.lastStats = stats
+	return nil
+}
+
+func (c *TableStatsCollector) collectPolicyStats() error {
+	stats, err := c.client.GetPolicyTableTrafficStats()
+	if err != nil {
+		return err
+	}
+	for tableID, actionStats := range stats {
+		table := GetFlowTableName(tableID)
+		for action, s := range actionStats {
+			last, ok := c.lastPolicyStats[tableID][action]
+			if !ok {
+				last = &binding.TrafficStats{}
+			}
+			metrics.PolicyTablePacketCount.WithLabelValues(table, action.String()).Add(counterDelta(s.PacketCount, last.PacketCount))
+			metrics.PolicyTableByteCount.WithLabelValues(table, action.String()).Add(counterDelta(s.ByteCount, last.ByteCount))
+		}
+	}
+	c.lastPolicyStats = stats
+	return nil
+}
+
+// Run polls the statistics of the flow tables until stopCh is closed.
+func (c *TableStatsCollector) Run(stopCh <-chan struct{}) {
+	klog.Infof("Starting OVS table statistics collector with poll interval %v", c.pollInterval)
+	wait.Until(func() {
+		if err := c.collect(); err != nil {
+			klog.Errorf("Error when collecting OVS table statistics: %v", err)
+		}
+		if err := c.collectPolicyStats(); err != nil {
+			klog.Errorf("Error when collecting NetworkPolicy table statistics: %v", err)
+		}
+	}, c.pollInterval, stopCh)
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openflow
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/metrics"
+	"github.com/vmware-tanzu/antrea/pkg/agent/openflow/cookie"
+	oftest "github.com/vmware-tanzu/antrea/pkg/agent/openflow/testing"
+	binding "github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
+)
+
+func TestTableStatsCollector(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	legacyregistry.MustRegister(metrics.OVSTablePacketCount, metrics.OVSTableByteCount)
+	mockClient := oftest.NewMockClient(ctrl)
+	collector := NewTableStatsCollector(mockClient, 0)
+
+	assertCounters := func(table, reason string, packets, bytes float64) {
+		value, err := testutil.GetCounterMetricValue(metrics.OVSTablePacketCount.WithLabelValues(table, reason))
+		require.NoError(t, err)
+		assert.Equal(t, packets, value, "Unexpected packet count for table %s and reason %s", table, reason)
+		value, err = testutil.GetCounterMetricValue(metrics.OVSTableByteCount.WithLabelValues(table, reason))
+		require.NoError(t, err)
+		assert.Equal(t, bytes, value, "Unexpected byte count for table %s and reason %s", table, reason)
+	}
+
+	mockClient.EXPECT().GetTableTrafficStats().Return(map[binding.TableIDType]*binding.TableTrafficStats{
+		ClassifierTable:   {MatchPacketCount: 10, MatchByteCount: 1000, MissPacketCount: 1, MissByteCount: 60},
+		EndpointDNATTable: {MatchPacketCount: 5, MatchByteCount: 500},
+	}, nil)
+	require.NoError(t, collector.collect())
+	assertCounters("0", "match", 10, 1000)
+	assertCounters("0", "miss", 1, 60)
+	assertCounters("42", "match", 5, 500)
+	assertCounters("42", "miss", 0, 0)
+
+	// The counters are increased by the difference with the previous poll. The
+	// statistics of table 42 decreased as a flow was deleted, its counters are
+	// not increased.
+	mockClient.EXPECT().GetTableTrafficStats().Return(map[binding.TableIDType]*binding.TableTrafficStats{
+		ClassifierTable:   {MatchPacketCount: 15, MatchByteCount: 1500, MissPacketCount: 1, MissByteCount: 60},
+		EndpointDNATTable: {MatchPacketCount: 2, MatchByteCount: 200},
+	}, nil)
+	require.NoError(t, collector.collect())
+	assertCounters("0", "match", 15, 1500)
+	assertCounters("0", "miss", 1, 60)
+	assertCounters("42", "match", 5, 500)
+
+	mockClient.EXPECT().GetTableTrafficStats().Return(map[binding.TableIDType]*binding.TableTrafficStats{
+		ClassifierTable:   {MatchPacketCount: 15, MatchByteCount: 1500, MissPacketCount: 1, MissByteCount: 60},
+		EndpointDNATTable: {MatchPacketCount: 4, MatchByteCount: 400},
+	}, nil)
+	require.NoError(t, collector.collect())
+	assertCounters("42", "match", 7, 700)
+}
+
+func TestTableStatsCollectorPolicyStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	legacyregistry.MustRegister(metrics.PolicyTablePacketCount, metrics.PolicyTableByteCount)
+	mockClient := oftest.NewMockClient(ctrl)
+	collector := NewTableStatsCollector(mockClient, 0)
+
+	assertCounters := func(table, action string, packets, bytes float64) {
+		value, err := testutil.GetCounterMetricValue(metrics.PolicyTablePacketCount.WithLabelValues(table, action))
+		require.NoError(t, err)
+		assert.Equal(t, packets, value, "Unexpected packet count for table %s and action %s", table, action)
+		value, err = testutil.GetCounterMetricValue(metrics.PolicyTableByteCount.WithLabelValues(table, action))
+		require.NoError(t, err)
+		assert.Equal(t, bytes, value, "Unexpected byte count for table %s and action %s", table, action)
+	}
+
+	mockClient.EXPECT().GetPolicyTableTrafficStats().Return(map[binding.TableIDType]map[cookie.PolicyAction]*binding.TrafficStats{
+		IngressRuleTable: {
+			cookie.PolicyActionAllow: {PacketCount: 10, ByteCount: 1000},
+			cookie.PolicyActionJump:  {PacketCount: 2, ByteCount: 120},
+		},
+		ingressDefaultTable: {
+			cookie.PolicyActionDeny: {PacketCount: 3, ByteCount: 180},
+		},
+	}, nil)
+	require.NoError(t, collector.collectPolicyStats())
+	assertCounters("IngressRule", "allow", 10, 1000)
+	assertCounters("IngressRule", "jump", 2, 120)
+	assertCounters("IngressDefaultRule", "deny", 3, 180)
+
+	// After OVS restarts, the flows are reinstalled with zero counters. The
+	// counters are not increased, and the new statistics become the base of
+	// the next poll.
+	mockClient.EXPECT().GetPolicyTableTrafficStats().Return(map[binding.TableIDType]map[cookie.PolicyAction]*binding.TrafficStats{
+		IngressRuleTable: {
+			cookie.PolicyActionAllow: {PacketCount: 1, ByteCount: 100},
+			cookie.PolicyActionJump:  {PacketCount: 0, ByteCount: 0},
+		},
+		ingressDefaultTable: {
+			cookie.PolicyActionDeny: {PacketCount: 0, ByteCount: 0},
+		},
+	}, nil)
+	require.NoError(t, collector.collectPolicyStats())
+	assertCounters("IngressRule", "allow", 10, 1000)
+	assertCounters("IngressRule", "jump", 2, 120)
+	assertCounters("IngressDefaultRule", "deny", 3, 180)
+
+	mockClient.EXPECT().GetPolicyTableTrafficStats().Return(map[binding.TableIDType]map[cookie.PolicyAction]*binding.TrafficStats{
+		IngressRuleTable: {
+			cookie.PolicyActionAllow: {PacketCount: 4, ByteCount: 400},
+			cookie.PolicyActionJump:  {PacketCount: 1, ByteCount: 60},
+		},
+		ingressDefaultTable: {
+			cookie.PolicyActionDeny: {PacketCount: 2, ByteCount: 120},
+		},
+	}, nil)
+	require.NoError(t, collector.collectPolicyStats())
+	assertCounters("IngressRule", "allow", 13, 1300)
+	assertCounters("IngressRule", "jump", 3, 180)
+	assertCounters("IngressDefaultRule", "deny", 5, 300)
+}
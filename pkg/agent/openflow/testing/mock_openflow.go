@@ -23,7 +23,9 @@ import (
 	ofctrl "github.com/contiv/ofnet/ofctrl"
 	gomock "github.com/golang/mock/gomock"
 	config "github.com/vmware-tanzu/antrea/pkg/agent/config"
+	cookie "github.com/vmware-tanzu/antrea/pkg/agent/openflow/cookie"
 	types "github.com/vmware-tanzu/antrea/pkg/agent/types"
+	v1beta1 "github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
 	openflow "github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
 	proxy "github.com/vmware-tanzu/antrea/third_party/proxy"
 	net "net"
@@ -109,6 +111,32 @@ func (mr *MockClientMockRecorder) Disconnect() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disconnect", reflect.TypeOf((*MockClient)(nil).Disconnect))
 }
 
+// EnableFastFailoverGroups mocks base method
+func (m *MockClient) EnableFastFailoverGroups(arg0 func(string) (uint32, bool)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "EnableFastFailoverGroups", arg0)
+}
+
+// EnableFastFailoverGroups indicates an expected call of EnableFastFailoverGroups
+func (mr *MockClientMockRecorder) EnableFastFailoverGroups(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableFastFailoverGroups", reflect.TypeOf((*MockClient)(nil).EnableFastFailoverGroups), arg0)
+}
+
+// GetDesiredFlows mocks base method
+func (m *MockClient) GetDesiredFlows() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDesiredFlows")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// GetDesiredFlows indicates an expected call of GetDesiredFlows
+func (mr *MockClientMockRecorder) GetDesiredFlows() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDesiredFlows", reflect.TypeOf((*MockClient)(nil).GetDesiredFlows))
+}
+
 // GetFlowTableStatus mocks base method
 func (m *MockClient) GetFlowTableStatus() []openflow.TableStatus {
 	m.ctrl.T.Helper()
@@ -166,6 +194,36 @@ func (mr *MockClientMockRecorder) GetPolicyFromConjunction(arg0 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPolicyFromConjunction", reflect.TypeOf((*MockClient)(nil).GetPolicyFromConjunction), arg0)
 }
 
+// GetPolicyTableTrafficStats mocks base method
+func (m *MockClient) GetPolicyTableTrafficStats() (map[openflow.TableIDType]map[cookie.PolicyAction]*openflow.TrafficStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPolicyTableTrafficStats")
+	ret0, _ := ret[0].(map[openflow.TableIDType]map[cookie.PolicyAction]*openflow.TrafficStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPolicyTableTrafficStats indicates an expected call of GetPolicyTableTrafficStats
+func (mr *MockClientMockRecorder) GetPolicyTableTrafficStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPolicyTableTrafficStats", reflect.TypeOf((*MockClient)(nil).GetPolicyTableTrafficStats))
+}
+
+// GetTableTrafficStats mocks base method
+func (m *MockClient) GetTableTrafficStats() (map[openflow.TableIDType]*openflow.TableTrafficStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTableTrafficStats")
+	ret0, _ := ret[0].(map[openflow.TableIDType]*openflow.TableTrafficStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTableTrafficStats indicates an expected call of GetTableTrafficStats
+func (mr *MockClientMockRecorder) GetTableTrafficStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTableTrafficStats", reflect.TypeOf((*MockClient)(nil).GetTableTrafficStats))
+}
+
 // GetTunnelVirtualMAC mocks base method
 func (m *MockClient) GetTunnelVirtualMAC() net.HardwareAddr {
 	m.ctrl.T.Helper()
@@ -265,6 +323,20 @@ func (mr *MockClientMockRecorder) InstallDefaultTunnelFlows(arg0 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallDefaultTunnelFlows", reflect.TypeOf((*MockClient)(nil).InstallDefaultTunnelFlows), arg0)
 }
 
+// InstallEgressNATBypassFlows mocks base method
+func (m *MockClient) InstallEgressNATBypassFlows(arg0 net.IPNet) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstallEgressNATBypassFlows", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InstallEgressNATBypassFlows indicates an expected call of InstallEgressNATBypassFlows
+func (mr *MockClientMockRecorder) InstallEgressNATBypassFlows(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallEgressNATBypassFlows", reflect.TypeOf((*MockClient)(nil).InstallEgressNATBypassFlows), arg0)
+}
+
 // InstallEndpointFlows mocks base method
 func (m *MockClient) InstallEndpointFlows(arg0 openflow.Protocol, arg1 []proxy.Endpoint) error {
 	m.ctrl.T.Helper()
@@ -307,6 +379,20 @@ func (mr *MockClientMockRecorder) InstallGatewayFlows(arg0, arg1, arg2 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallGatewayFlows", reflect.TypeOf((*MockClient)(nil).InstallGatewayFlows), arg0, arg1, arg2)
 }
 
+// InstallL7ConnDenyFlow mocks base method
+func (m *MockClient) InstallL7ConnDenyFlow(arg0 uint32, arg1 v1beta1.Direction, arg2, arg3 net.IP, arg4, arg5 uint16) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstallL7ConnDenyFlow", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InstallL7ConnDenyFlow indicates an expected call of InstallL7ConnDenyFlow
+func (mr *MockClientMockRecorder) InstallL7ConnDenyFlow(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallL7ConnDenyFlow", reflect.TypeOf((*MockClient)(nil).InstallL7ConnDenyFlow), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
 // InstallNodeFlows mocks base method
 func (m *MockClient) InstallNodeFlows(arg0 string, arg1 net.HardwareAddr, arg2 net.IPNet, arg3, arg4 net.IP, arg5, arg6 uint32) error {
 	m.ctrl.T.Helper()
@@ -321,6 +407,34 @@ func (mr *MockClientMockRecorder) InstallNodeFlows(arg0, arg1, arg2, arg3, arg4,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallNodeFlows", reflect.TypeOf((*MockClient)(nil).InstallNodeFlows), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
 }
 
+// InstallNodePortFlows mocks base method
+func (m *MockClient) InstallNodePortFlows(arg0 openflow.GroupIDType, arg1 uint16, arg2 openflow.Protocol, arg3 uint16) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstallNodePortFlows", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InstallNodePortFlows indicates an expected call of InstallNodePortFlows
+func (mr *MockClientMockRecorder) InstallNodePortFlows(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallNodePortFlows", reflect.TypeOf((*MockClient)(nil).InstallNodePortFlows), arg0, arg1, arg2, arg3)
+}
+
+// InstallPodEgressNATFlow mocks base method
+func (m *MockClient) InstallPodEgressNATFlow(arg0 uint32, arg1 net.IP, arg2 uint32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstallPodEgressNATFlow", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InstallPodEgressNATFlow indicates an expected call of InstallPodEgressNATFlow
+func (mr *MockClientMockRecorder) InstallPodEgressNATFlow(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallPodEgressNATFlow", reflect.TypeOf((*MockClient)(nil).InstallPodEgressNATFlow), arg0, arg1, arg2)
+}
+
 // InstallPodFlows mocks base method
 func (m *MockClient) InstallPodFlows(arg0 string, arg1 net.IP, arg2, arg3 net.HardwareAddr, arg4 uint32) error {
 	m.ctrl.T.Helper()
@@ -349,6 +463,34 @@ func (mr *MockClientMockRecorder) InstallPolicyRuleFlows(arg0, arg1, arg2, arg3
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallPolicyRuleFlows", reflect.TypeOf((*MockClient)(nil).InstallPolicyRuleFlows), arg0, arg1, arg2, arg3)
 }
 
+// InstallRemotePodEgressNATFlow mocks base method
+func (m *MockClient) InstallRemotePodEgressNATFlow(arg0 net.IP, arg1 uint32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstallRemotePodEgressNATFlow", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InstallRemotePodEgressNATFlow indicates an expected call of InstallRemotePodEgressNATFlow
+func (mr *MockClientMockRecorder) InstallRemotePodEgressNATFlow(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallRemotePodEgressNATFlow", reflect.TypeOf((*MockClient)(nil).InstallRemotePodEgressNATFlow), arg0, arg1)
+}
+
+// InstallSecondaryInterfaceFlows mocks base method
+func (m *MockClient) InstallSecondaryInterfaceFlows(arg0 string, arg1 uint32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstallSecondaryInterfaceFlows", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InstallSecondaryInterfaceFlows indicates an expected call of InstallSecondaryInterfaceFlows
+func (mr *MockClientMockRecorder) InstallSecondaryInterfaceFlows(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallSecondaryInterfaceFlows", reflect.TypeOf((*MockClient)(nil).InstallSecondaryInterfaceFlows), arg0, arg1)
+}
+
 // InstallServiceFlows mocks base method
 func (m *MockClient) InstallServiceFlows(arg0 openflow.GroupIDType, arg1 net.IP, arg2 uint16, arg3 openflow.Protocol, arg4 uint16) error {
 	m.ctrl.T.Helper()
@@ -391,6 +533,48 @@ func (mr *MockClientMockRecorder) InstallTraceflowFlows(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallTraceflowFlows", reflect.TypeOf((*MockClient)(nil).InstallTraceflowFlows), arg0)
 }
 
+// InstallTraceflowLiveCaptureFlow mocks base method
+func (m *MockClient) InstallTraceflowLiveCaptureFlow(arg0 byte, arg1 uint32, arg2 net.HardwareAddr, arg3, arg4 net.IP, arg5 byte, arg6, arg7, arg8 uint16) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstallTraceflowLiveCaptureFlow", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InstallTraceflowLiveCaptureFlow indicates an expected call of InstallTraceflowLiveCaptureFlow
+func (mr *MockClientMockRecorder) InstallTraceflowLiveCaptureFlow(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallTraceflowLiveCaptureFlow", reflect.TypeOf((*MockClient)(nil).InstallTraceflowLiveCaptureFlow), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+}
+
+// InstallTraceflowLiveFlows mocks base method
+func (m *MockClient) InstallTraceflowLiveFlows(arg0 byte, arg1 uint16) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstallTraceflowLiveFlows", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InstallTraceflowLiveFlows indicates an expected call of InstallTraceflowLiveFlows
+func (mr *MockClientMockRecorder) InstallTraceflowLiveFlows(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallTraceflowLiveFlows", reflect.TypeOf((*MockClient)(nil).InstallTraceflowLiveFlows), arg0, arg1)
+}
+
+// InstallTraceflowServiceNoEndpointsFlow mocks base method
+func (m *MockClient) InstallTraceflowServiceNoEndpointsFlow(arg0 uint8, arg1 net.IP, arg2 uint16, arg3 openflow.Protocol, arg4 uint16) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstallTraceflowServiceNoEndpointsFlow", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InstallTraceflowServiceNoEndpointsFlow indicates an expected call of InstallTraceflowServiceNoEndpointsFlow
+func (mr *MockClientMockRecorder) InstallTraceflowServiceNoEndpointsFlow(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallTraceflowServiceNoEndpointsFlow", reflect.TypeOf((*MockClient)(nil).InstallTraceflowServiceNoEndpointsFlow), arg0, arg1, arg2, arg3, arg4)
+}
+
 // IsConnected mocks base method
 func (m *MockClient) IsConnected() bool {
 	m.ctrl.T.Helper()
@@ -420,15 +604,29 @@ func (mr *MockClientMockRecorder) ReassignFlowPriorities(arg0 interface{}) *gomo
 }
 
 // RegisterPacketInHandler mocks base method
-func (m *MockClient) RegisterPacketInHandler(arg0 string, arg1 interface{}) {
+func (m *MockClient) RegisterPacketInHandler(arg0 byte, arg1 string, arg2 interface{}) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "RegisterPacketInHandler", arg0, arg1)
+	m.ctrl.Call(m, "RegisterPacketInHandler", arg0, arg1, arg2)
 }
 
 // RegisterPacketInHandler indicates an expected call of RegisterPacketInHandler
-func (mr *MockClientMockRecorder) RegisterPacketInHandler(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockClientMockRecorder) RegisterPacketInHandler(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterPacketInHandler", reflect.TypeOf((*MockClient)(nil).RegisterPacketInHandler), arg0, arg1, arg2)
+}
+
+// ReinjectL7Packet mocks base method
+func (m *MockClient) ReinjectL7Packet(arg0 v1beta1.Direction, arg1 *ofctrl.PacketIn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReinjectL7Packet", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReinjectL7Packet indicates an expected call of ReinjectL7Packet
+func (mr *MockClientMockRecorder) ReinjectL7Packet(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterPacketInHandler", reflect.TypeOf((*MockClient)(nil).RegisterPacketInHandler), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReinjectL7Packet", reflect.TypeOf((*MockClient)(nil).ReinjectL7Packet), arg0, arg1)
 }
 
 // ReplayFlows mocks base method
@@ -511,6 +709,34 @@ func (mr *MockClientMockRecorder) UninstallNodeFlows(arg0 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UninstallNodeFlows", reflect.TypeOf((*MockClient)(nil).UninstallNodeFlows), arg0)
 }
 
+// UninstallNodePortFlows mocks base method
+func (m *MockClient) UninstallNodePortFlows(arg0 uint16, arg1 openflow.Protocol) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UninstallNodePortFlows", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UninstallNodePortFlows indicates an expected call of UninstallNodePortFlows
+func (mr *MockClientMockRecorder) UninstallNodePortFlows(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UninstallNodePortFlows", reflect.TypeOf((*MockClient)(nil).UninstallNodePortFlows), arg0, arg1)
+}
+
+// UninstallPodEgressNATFlow mocks base method
+func (m *MockClient) UninstallPodEgressNATFlow(arg0 uint32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UninstallPodEgressNATFlow", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UninstallPodEgressNATFlow indicates an expected call of UninstallPodEgressNATFlow
+func (mr *MockClientMockRecorder) UninstallPodEgressNATFlow(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UninstallPodEgressNATFlow", reflect.TypeOf((*MockClient)(nil).UninstallPodEgressNATFlow), arg0)
+}
+
 // UninstallPodFlows mocks base method
 func (m *MockClient) UninstallPodFlows(arg0 string) error {
 	m.ctrl.T.Helper()
@@ -540,6 +766,20 @@ func (mr *MockClientMockRecorder) UninstallPolicyRuleFlows(arg0 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UninstallPolicyRuleFlows", reflect.TypeOf((*MockClient)(nil).UninstallPolicyRuleFlows), arg0)
 }
 
+// UninstallRemotePodEgressNATFlow mocks base method
+func (m *MockClient) UninstallRemotePodEgressNATFlow(arg0 net.IP) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UninstallRemotePodEgressNATFlow", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UninstallRemotePodEgressNATFlow indicates an expected call of UninstallRemotePodEgressNATFlow
+func (mr *MockClientMockRecorder) UninstallRemotePodEgressNATFlow(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UninstallRemotePodEgressNATFlow", reflect.TypeOf((*MockClient)(nil).UninstallRemotePodEgressNATFlow), arg0)
+}
+
 // UninstallServiceFlows mocks base method
 func (m *MockClient) UninstallServiceFlows(arg0 net.IP, arg1 uint16, arg2 openflow.Protocol) error {
 	m.ctrl.T.Helper()
@@ -568,6 +808,34 @@ func (mr *MockClientMockRecorder) UninstallServiceGroup(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UninstallServiceGroup", reflect.TypeOf((*MockClient)(nil).UninstallServiceGroup), arg0)
 }
 
+// UninstallTraceflowLiveFlows mocks base method
+func (m *MockClient) UninstallTraceflowLiveFlows(arg0 byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UninstallTraceflowLiveFlows", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UninstallTraceflowLiveFlows indicates an expected call of UninstallTraceflowLiveFlows
+func (mr *MockClientMockRecorder) UninstallTraceflowLiveFlows(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UninstallTraceflowLiveFlows", reflect.TypeOf((*MockClient)(nil).UninstallTraceflowLiveFlows), arg0)
+}
+
+// UpdateServiceEndpoints mocks base method
+func (m *MockClient) UpdateServiceEndpoints(arg0 openflow.GroupIDType, arg1 bool, arg2 openflow.Protocol, arg3, arg4 []proxy.Endpoint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateServiceEndpoints", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateServiceEndpoints indicates an expected call of UpdateServiceEndpoints
+func (mr *MockClientMockRecorder) UpdateServiceEndpoints(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateServiceEndpoints", reflect.TypeOf((*MockClient)(nil).UpdateServiceEndpoints), arg0, arg1, arg2, arg3, arg4)
+}
+
 // MockOFEntryOperations is a mock of OFEntryOperations interface
 type MockOFEntryOperations struct {
 	ctrl     *gomock.Controller
@@ -633,6 +901,20 @@ func (mr *MockOFEntryOperationsMockRecorder) AddOFEntries(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddOFEntries", reflect.TypeOf((*MockOFEntryOperations)(nil).AddOFEntries), arg0)
 }
 
+// BundleOFEntries mocks base method
+func (m *MockOFEntryOperations) BundleOFEntries(arg0, arg1, arg2 []openflow.OFEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BundleOFEntries", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BundleOFEntries indicates an expected call of BundleOFEntries
+func (mr *MockOFEntryOperationsMockRecorder) BundleOFEntries(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BundleOFEntries", reflect.TypeOf((*MockOFEntryOperations)(nil).BundleOFEntries), arg0, arg1, arg2)
+}
+
 // Delete mocks base method
 func (m *MockOFEntryOperations) Delete(arg0 openflow.Flow) error {
 	m.ctrl.T.Helper()
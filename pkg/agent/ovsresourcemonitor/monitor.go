@@ -0,0 +1,263 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsresourcemonitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+)
+
+const (
+	componentName = "antrea-agent"
+	// reasonHighResourceUsage is the reason of the Node events emitted when
+	// the usage of the OVS daemons exceeds the warning threshold.
+	reasonHighResourceUsage = "OVSHighResourceUsage"
+	pollInterval            = 30 * time.Second
+)
+
+// Limits are the resource limits of the OVS daemons. A zero value means that
+// the resource is not limited.
+type Limits struct {
+	MilliCPU int64
+	// Memory is in bytes.
+	Memory int64
+}
+
+// IsEmpty returns true if no resource is limited.
+func (l *Limits) IsEmpty() bool {
+	return l.MilliCPU == 0 && l.Memory == 0
+}
+
+// ParseLimits parses the CPU and memory limits, provided as K8s resource
+// quantities. An empty string means that the resource is not limited.
+func ParseLimits(cpu, memory string) (*Limits, error) {
+	limits := &Limits{}
+	if cpu != "" {
+		q, err := resource.ParseQuantity(cpu)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU limit %q: %v", cpu, err)
+		}
+		if q.MilliValue() <= 0 {
+			return nil, fmt.Errorf("CPU limit %q must be positive", cpu)
+		}
+		limits.MilliCPU = q.MilliValue()
+	}
+	if memory != "" {
+		q, err := resource.ParseQuantity(memory)
+		if err != nil {
+			return nil, fmt.Errorf("invalid memory limit %q: %v", memory, err)
+		}
+		if q.Value() <= 0 {
+			return nil, fmt.Errorf("memory limit %q must be positive", memory)
+		}
+		limits.Memory = q.Value()
+	}
+	return limits, nil
+}
+
+// ovsProcess is a process of an OVS daemon. A daemon may have several
+// processes, e.g. when it is started with --monitor.
+type ovsProcess struct {
+	name string
+	pid  int
+	// cgroup identifies the cgroup of the process. The processes in the same
+	// cgroup share the limits.
+	cgroup string
+}
+
+type processUsage struct {
+	// cpuTime is the CPU time consumed by the process since it started.
+	cpuTime time.Duration
+	// memory is the resident set size of the process in bytes.
+	memory int64
+}
+
+// ovsProcessManager finds the processes of the OVS daemons, reads their
+// resource usage and applies the limits to their cgroups.
+type ovsProcessManager interface {
+	listProcesses() ([]ovsProcess, error)
+	getUsage(pid int) (*processUsage, error)
+	setLimits(cgroup string, limits *Limits) error
+}
+
+// Monitor periodically computes the CPU and memory usage of each cgroup of
+// the OVS daemons, and emits a Warning event on the Node when the usage
+// exceeds the warning threshold of the limits. When enforce is true, the
+// limits are applied to the cgroups first. If they cannot be applied, the
+// usage is still monitored.
+type Monitor struct {
+	nodeName         string
+	manager          ovsProcessManager
+	recorder         record.EventRecorder
+	clock            clock.Clock
+	limits           Limits
+	enforce          bool
+	warningThreshold float64
+	// enforcedCgroups are the cgroups to which the limits have been applied,
+	// or failed to be applied, so that it is only attempted once per cgroup.
+	// The OVS daemons get new cgroups when their container is restarted, and
+	// the cgroups without OVS processes are forgotten.
+	enforcedCgroups sets.String
+	// lastCPUTimes are the CPU times of the processes at the last poll.
+	lastCPUTimes map[int]time.Duration
+	lastPollTime time.Time
+	// lastCPURatios and lastMemoryRatios are the usages of the cgroups at the
+	// last poll, relative to the limits.
+	lastCPURatios    map[string]float64
+	lastMemoryRatios map[string]float64
+}
+
+// NewMonitor creates a Monitor for the Node. The warning threshold is a
+// percentage of the limits. hostProcPathPrefix is the directory under which
+// the /proc directory of the host is mounted.
+func NewMonitor(nodeName string, hostProcPathPrefix string, k8sClient kubernetes.Interface, limits *Limits, enforce bool, warningThreshold int) (*Monitor, error) {
+	manager, err := newOVSProcessManager(hostProcPathPrefix)
+	if err != nil {
+		return nil, err
+	}
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sClient.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: componentName, Host: nodeName})
+	return newMonitor(nodeName, manager, recorder, clock.RealClock{}, limits, enforce, warningThreshold), nil
+}
+
+func newMonitor(nodeName string, manager ovsProcessManager, recorder record.EventRecorder, clock clock.Clock, limits *Limits, enforce bool, warningThreshold int) *Monitor {
+	return &Monitor{
+		nodeName:         nodeName,
+		manager:          manager,
+		recorder:         recorder,
+		clock:            clock,
+		limits:           *limits,
+		enforce:          enforce,
+		warningThreshold: float64(warningThreshold) / 100,
+		enforcedCgroups:  sets.NewString(),
+		lastCPUTimes:     map[int]time.Duration{},
+		lastCPURatios:    map[string]float64{},
+		lastMemoryRatios: map[string]float64{},
+	}
+}
+
+func (m *Monitor) nodeRef() *corev1.ObjectReference {
+	// Events are attached to the Node the same way as kubelet does.
+	return &corev1.ObjectReference{
+		Kind: "Node",
+		Name: m.nodeName,
+		UID:  types.UID(m.nodeName),
+	}
+}
+
+// cgroupUsage is the usage of the OVS processes in a cgroup.
+type cgroupUsage struct {
+	names sets.String
+	// cpuTime is the CPU time consumed since the last poll by the processes
+	// which existed at the last poll.
+	cpuTime time.Duration
+	memory  int64
+}
+
+func (m *Monitor) check() error {
+	processes, err := m.manager.listProcesses()
+	if err != nil {
+		return err
+	}
+	now := m.clock.Now()
+	cpuTimes := map[int]time.Duration{}
+	usages := map[string]*cgroupUsage{}
+	enforcedCgroups := sets.NewString()
+	for _, p := range processes {
+		if m.enforce && !enforcedCgroups.Has(p.cgroup) {
+			if !m.enforcedCgroups.Has(p.cgroup) {
+				if err := m.manager.setLimits(p.cgroup, &m.limits); err != nil {
+					klog.Errorf("Error when applying resource limits to %s (pid %d), only monitoring its usage: %v", p.name, p.pid, err)
+				} else {
+					klog.Infof("Applied resource limits to %s (pid %d)", p.name, p.pid)
+				}
+			}
+			enforcedCgroups.Insert(p.cgroup)
+		}
+		usage, err := m.manager.getUsage(p.pid)
+		if err != nil {
+			// The process may have exited since it was listed.
+			klog.Warningf("Error when getting resource usage of %s (pid %d): %v", p.name, p.pid, err)
+			continue
+		}
+		cu, exists := usages[p.cgroup]
+		if !exists {
+			cu = &cgroupUsage{names: sets.NewString()}
+			usages[p.cgroup] = cu
+		}
+		cu.names.Insert(p.name)
+		cu.memory += usage.memory
+		cpuTimes[p.pid] = usage.cpuTime
+		if lastCPUTime, exists := m.lastCPUTimes[p.pid]; exists {
+			cu.cpuTime += usage.cpuTime - lastCPUTime
+		}
+	}
+
+	cpuRatios := map[string]float64{}
+	memoryRatios := map[string]float64{}
+	// The CPU usage is computed from the CPU time consumed between two polls.
+	elapsed := now.Sub(m.lastPollTime)
+	for cgroup, cu := range usages {
+		names := strings.Join(cu.names.List(), ", ")
+		if m.limits.MilliCPU > 0 && !m.lastPollTime.IsZero() && elapsed > 0 {
+			milliCPU := int64(cu.cpuTime * 1000 / elapsed)
+			ratio := float64(milliCPU) / float64(m.limits.MilliCPU)
+			cpuRatios[cgroup] = ratio
+			if ratio > m.warningThreshold && m.lastCPURatios[cgroup] <= m.warningThreshold {
+				m.recorder.Eventf(m.nodeRef(), corev1.EventTypeWarning, reasonHighResourceUsage,
+					"The CPU usage of %s is %.0f%% of the limit (%dm/%dm)", names, ratio*100, milliCPU, m.limits.MilliCPU)
+			}
+		}
+		if m.limits.Memory > 0 {
+			ratio := float64(cu.memory) / float64(m.limits.Memory)
+			memoryRatios[cgroup] = ratio
+			if ratio > m.warningThreshold && m.lastMemoryRatios[cgroup] <= m.warningThreshold {
+				m.recorder.Eventf(m.nodeRef(), corev1.EventTypeWarning, reasonHighResourceUsage,
+					"The memory usage of %s is %.0f%% of the limit (%dMi/%dMi)", names, ratio*100, cu.memory>>20, m.limits.Memory>>20)
+			}
+		}
+	}
+	m.enforcedCgroups = enforcedCgroups
+	m.lastCPUTimes = cpuTimes
+	m.lastPollTime = now
+	m.lastCPURatios = cpuRatios
+	m.lastMemoryRatios = memoryRatios
+	return nil
+}
+
+// Run polls the resource usage of the OVS daemons until stopCh is closed.
+func (m *Monitor) Run(stopCh <-chan struct{}) {
+	klog.Infof("Starting OVS resource monitor with CPU limit %dm, memory limit %d bytes, enforced: %t",
+		m.limits.MilliCPU, m.limits.Memory, m.enforce)
+	wait.Until(func() {
+		if err := m.check(); err != nil {
+			klog.Errorf("Error when checking OVS resource usage: %v", err)
+		}
+	}, pollInterval, stopCh)
+}
@@ -0,0 +1,161 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsresourcemonitor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/tools/record"
+)
+
+type fakeManager struct {
+	processes    []ovsProcess
+	usages       map[int]*processUsage
+	setLimitsErr error
+	// limitedCgroups records the cgroups passed to setLimits.
+	limitedCgroups []string
+}
+
+func (m *fakeManager) listProcesses() ([]ovsProcess, error) {
+	return m.processes, nil
+}
+
+func (m *fakeManager) getUsage(pid int) (*processUsage, error) {
+	usage, exists := m.usages[pid]
+	if !exists {
+		return nil, fmt.Errorf("process %d not found", pid)
+	}
+	return usage, nil
+}
+
+func (m *fakeManager) setLimits(cgroup string, limits *Limits) error {
+	m.limitedCgroups = append(m.limitedCgroups, cgroup)
+	return m.setLimitsErr
+}
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		name           string
+		cpu            string
+		memory         string
+		expectedLimits *Limits
+		expectedErr    bool
+	}{
+		{"no limit", "", "", &Limits{}, false},
+		{"cores", "2", "512Mi", &Limits{MilliCPU: 2000, Memory: 512 << 20}, false},
+		{"millicores", "500m", "", &Limits{MilliCPU: 500}, false},
+		{"invalid CPU", "two", "", nil, true},
+		{"negative memory", "", "-1Gi", nil, true},
+		{"zero CPU", "0", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits, err := ParseLimits(tt.cpu, tt.memory)
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedLimits, limits)
+				assert.Equal(t, tt.cpu == "" && tt.memory == "", limits.IsEmpty())
+			}
+		})
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	manager := &fakeManager{
+		processes: []ovsProcess{
+			{name: "ovsdb-server", pid: 10, cgroup: "0::/ovs"},
+			{name: "ovs-vswitchd", pid: 20, cgroup: "0::/ovs"},
+		},
+		usages: map[int]*processUsage{},
+	}
+	recorder := record.NewFakeRecorder(10)
+	fakeClock := clock.NewFakeClock(time.Now())
+	m := newMonitor("node1", manager, recorder, fakeClock, &Limits{MilliCPU: 2000, Memory: 1000 << 20}, false, 80)
+
+	tests := []struct {
+		name string
+		// The CPU times and memory of the processes 10 and 20.
+		cpuTimes       [2]time.Duration
+		memory         [2]int64
+		expectedEvents []string
+	}{
+		// The CPU usage cannot be computed at the first poll.
+		{"first poll", [2]time.Duration{time.Second, 10 * time.Second}, [2]int64{100 << 20, 200 << 20}, nil},
+		// 10s of CPU time in 10s is 1 core, 50% of the limit.
+		{"below threshold", [2]time.Duration{2 * time.Second, 19 * time.Second}, [2]int64{100 << 20, 300 << 20}, nil},
+		{"CPU above threshold", [2]time.Duration{3 * time.Second, 36 * time.Second}, [2]int64{100 << 20, 300 << 20}, []string{
+			"Warning OVSHighResourceUsage The CPU usage of ovs-vswitchd, ovsdb-server is 90% of the limit (1800m/2000m)",
+		}},
+		{"memory above threshold", [2]time.Duration{4 * time.Second, 53 * time.Second}, [2]int64{100 << 20, 750 << 20}, []string{
+			"Warning OVSHighResourceUsage The memory usage of ovs-vswitchd, ovsdb-server is 85% of the limit (850Mi/1000Mi)",
+		}},
+		{"back below threshold", [2]time.Duration{4 * time.Second, 54 * time.Second}, [2]int64{100 << 20, 300 << 20}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager.usages[10] = &processUsage{cpuTime: tt.cpuTimes[0], memory: tt.memory[0]}
+			manager.usages[20] = &processUsage{cpuTime: tt.cpuTimes[1], memory: tt.memory[1]}
+			require.NoError(t, m.check())
+			fakeClock.Step(10 * time.Second)
+
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			assert.Equal(t, tt.expectedEvents, events)
+		})
+	}
+	// The limits are not enforced by default.
+	assert.Empty(t, manager.limitedCgroups)
+}
+
+func TestMonitorEnforce(t *testing.T) {
+	manager := &fakeManager{
+		processes: []ovsProcess{
+			{name: "ovsdb-server", pid: 10, cgroup: "0::/ovsdb-server.service"},
+			{name: "ovs-vswitchd", pid: 20, cgroup: "0::/ovs-vswitchd.service"},
+			{name: "ovs-vswitchd", pid: 21, cgroup: "0::/ovs-vswitchd.service"},
+		},
+		usages: map[int]*processUsage{
+			10: {},
+			20: {},
+			21: {},
+		},
+		setLimitsErr: fmt.Errorf("permission denied"),
+	}
+	m := newMonitor("node1", manager, record.NewFakeRecorder(10), clock.NewFakeClock(time.Now()), &Limits{Memory: 1 << 30}, true, 80)
+
+	// The limits are applied once per cgroup, even if it fails.
+	require.NoError(t, m.check())
+	assert.Equal(t, []string{"0::/ovsdb-server.service", "0::/ovs-vswitchd.service"}, manager.limitedCgroups)
+	require.NoError(t, m.check())
+	assert.Len(t, manager.limitedCgroups, 2)
+
+	// The restarted ovs-vswitchd gets the limits.
+	manager.processes = []ovsProcess{
+		{name: "ovsdb-server", pid: 10, cgroup: "0::/ovsdb-server.service"},
+		{name: "ovs-vswitchd", pid: 30, cgroup: "0::/ovs-vswitchd-2.service"},
+	}
+	manager.usages[30] = &processUsage{}
+	require.NoError(t, m.check())
+	assert.Equal(t, []string{"0::/ovsdb-server.service", "0::/ovs-vswitchd.service", "0::/ovs-vswitchd-2.service"}, manager.limitedCgroups)
+}
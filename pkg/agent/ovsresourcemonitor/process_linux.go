@@ -0,0 +1,275 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsresourcemonitor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	cgroupRoot = "/sys/fs/cgroup"
+	// userHZ is the unit of the CPU times in /proc/<pid>/stat, which is
+	// 1/100th of a second on all the supported architectures.
+	userHZ = 100
+	// cpuPeriod is the CFS period, in microseconds, used to apply the CPU
+	// limit.
+	cpuPeriod = 100000
+)
+
+// ovsDaemonNames are the process names of the OVS daemons.
+var ovsDaemonNames = map[string]bool{
+	"ovsdb-server": true,
+	"ovs-vswitchd": true,
+}
+
+// procfsManager finds the OVS processes and reads their usage from the /proc
+// directory of the host, and applies the limits to their cgroups, either with
+// systemd for the daemons running in a systemd service, or by writing to the
+// cgroup filesystem.
+type procfsManager struct {
+	procPath   string
+	cgroupRoot string
+	// runCommand runs a command and returns its combined output.
+	runCommand func(name string, args ...string) ([]byte, error)
+}
+
+func newOVSProcessManager(hostProcPathPrefix string) (ovsProcessManager, error) {
+	return &procfsManager{
+		procPath:   filepath.Join(hostProcPathPrefix, "proc"),
+		cgroupRoot: cgroupRoot,
+		runCommand: func(name string, args ...string) ([]byte, error) {
+			return exec.Command(name, args...).CombinedOutput()
+		},
+	}, nil
+}
+
+func (m *procfsManager) listProcesses() ([]ovsProcess, error) {
+	entries, err := ioutil.ReadDir(m.procPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", m.procPath, err)
+	}
+	var processes []ovsProcess
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		// The process may exit at any time, in which case it's skipped.
+		comm, err := ioutil.ReadFile(filepath.Join(m.procPath, entry.Name(), "comm"))
+		if err != nil {
+			continue
+		}
+		name := strings.TrimSpace(string(comm))
+		if !ovsDaemonNames[name] {
+			continue
+		}
+		cgroup, err := ioutil.ReadFile(filepath.Join(m.procPath, entry.Name(), "cgroup"))
+		if err != nil {
+			continue
+		}
+		processes = append(processes, ovsProcess{name: name, pid: pid, cgroup: strings.TrimSpace(string(cgroup))})
+	}
+	if len(processes) == 0 {
+		return nil, fmt.Errorf("no OVS process found in %s", m.procPath)
+	}
+	return processes, nil
+}
+
+func (m *procfsManager) getUsage(pid int) (*processUsage, error) {
+	statPath := filepath.Join(m.procPath, strconv.Itoa(pid), "stat")
+	stat, err := ioutil.ReadFile(statPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseProcessStat(string(stat), os.Getpagesize())
+}
+
+// parseProcessStat parses the content of /proc/<pid>/stat. See proc(5).
+func parseProcessStat(stat string, pageSize int) (*processUsage, error) {
+	// The process name, which is the second field, is between parentheses
+	// and may contain spaces.
+	i := strings.LastIndex(stat, ")")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid stat %q", stat)
+	}
+	// fields[0] is the third field, state.
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("invalid stat %q", stat)
+	}
+	var values [3]int64
+	// utime, stime and rss are the 14th, 15th and 24th fields.
+	for j, k := range []int{11, 12, 21} {
+		v, err := strconv.ParseInt(fields[k], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stat %q: %v", stat, err)
+		}
+		values[j] = v
+	}
+	return &processUsage{
+		cpuTime: time.Duration(values[0]+values[1]) * time.Second / userHZ,
+		memory:  values[2] * int64(pageSize),
+	}, nil
+}
+
+// cgroupEntry is a line of /proc/<pid>/cgroup, e.g.
+// "4:cpu,cpuacct:/kubepods/pod1/container1" for cgroup v1, or
+// "0::/system.slice/ovs-vswitchd.service" for cgroup v2.
+type cgroupEntry struct {
+	controllers []string
+	path        string
+}
+
+func parseCgroup(cgroup string) []cgroupEntry {
+	var entries []cgroupEntry
+	for _, line := range strings.Split(cgroup, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		var controllers []string
+		if parts[1] != "" {
+			controllers = strings.Split(parts[1], ",")
+		}
+		entries = append(entries, cgroupEntry{controllers: controllers, path: parts[2]})
+	}
+	return entries
+}
+
+// findController returns the cgroup v1 entry of the controller.
+func findController(entries []cgroupEntry, controller string) *cgroupEntry {
+	for i := range entries {
+		for _, c := range entries[i].controllers {
+			if c == controller {
+				return &entries[i]
+			}
+		}
+	}
+	return nil
+}
+
+// findUnifiedPath returns the cgroup v2 path, or an empty string if the
+// process is not in a cgroup v2 hierarchy.
+func findUnifiedPath(entries []cgroupEntry) string {
+	for _, e := range entries {
+		if len(e.controllers) == 0 && strings.HasPrefix(e.path, "/") {
+			return e.path
+		}
+	}
+	return ""
+}
+
+// findSystemdUnit returns the systemd service the process runs in, or an
+// empty string if it doesn't run in a systemd service.
+func findSystemdUnit(entries []cgroupEntry) string {
+	path := findUnifiedPath(entries)
+	if e := findController(entries, "name=systemd"); e != nil {
+		path = e.path
+	}
+	unit := filepath.Base(path)
+	if strings.HasSuffix(unit, ".service") {
+		return unit
+	}
+	return ""
+}
+
+func (m *procfsManager) setLimits(cgroup string, limits *Limits) error {
+	entries := parseCgroup(cgroup)
+	if unit := findSystemdUnit(entries); unit != "" {
+		return m.setSystemdLimits(unit, limits)
+	}
+	cpuEntry := findController(entries, "cpu")
+	memoryEntry := findController(entries, "memory")
+	if cpuEntry == nil && memoryEntry == nil {
+		path := findUnifiedPath(entries)
+		if path == "" {
+			return fmt.Errorf("unsupported cgroup %q", cgroup)
+		}
+		return m.setUnifiedLimits(filepath.Join(m.cgroupRoot, path), limits)
+	}
+	if limits.MilliCPU > 0 {
+		if cpuEntry == nil {
+			return fmt.Errorf("cpu controller not found in cgroup %q", cgroup)
+		}
+		dir := filepath.Join(m.cgroupRoot, strings.Join(cpuEntry.controllers, ","), cpuEntry.path)
+		if err := writeCgroupFile(dir, "cpu.cfs_period_us", strconv.Itoa(cpuPeriod)); err != nil {
+			return err
+		}
+		if err := writeCgroupFile(dir, "cpu.cfs_quota_us", strconv.FormatInt(cpuQuota(limits.MilliCPU), 10)); err != nil {
+			return err
+		}
+	}
+	if limits.Memory > 0 {
+		if memoryEntry == nil {
+			return fmt.Errorf("memory controller not found in cgroup %q", cgroup)
+		}
+		dir := filepath.Join(m.cgroupRoot, strings.Join(memoryEntry.controllers, ","), memoryEntry.path)
+		if err := writeCgroupFile(dir, "memory.limit_in_bytes", strconv.FormatInt(limits.Memory, 10)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cpuQuota returns the CFS quota in microseconds per cpuPeriod.
+func cpuQuota(milliCPU int64) int64 {
+	return milliCPU * cpuPeriod / 1000
+}
+
+func (m *procfsManager) setUnifiedLimits(dir string, limits *Limits) error {
+	if limits.MilliCPU > 0 {
+		if err := writeCgroupFile(dir, "cpu.max", fmt.Sprintf("%d %d", cpuQuota(limits.MilliCPU), cpuPeriod)); err != nil {
+			return err
+		}
+	}
+	if limits.Memory > 0 {
+		if err := writeCgroupFile(dir, "memory.max", strconv.FormatInt(limits.Memory, 10)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *procfsManager) setSystemdLimits(unit string, limits *Limits) error {
+	// The properties are not persisted, the agent applies them again when it
+	// is restarted.
+	args := []string{"set-property", "--runtime", unit}
+	if limits.MilliCPU > 0 {
+		// CPUQuota is a percentage of one CPU, rounded up so that it is not 0.
+		args = append(args, fmt.Sprintf("CPUQuota=%d%%", (limits.MilliCPU+9)/10))
+	}
+	if limits.Memory > 0 {
+		args = append(args, fmt.Sprintf("MemoryMax=%d", limits.Memory))
+	}
+	if output, err := m.runCommand("systemctl", args...); err != nil {
+		return fmt.Errorf("error setting properties of systemd unit %s: %v, output: %s", unit, err, output)
+	}
+	return nil
+}
+
+func writeCgroupFile(dir, file, value string) error {
+	path := filepath.Join(dir, file)
+	if err := ioutil.WriteFile(path, []byte(value), 0644); err != nil {
+		return fmt.Errorf("error writing %q to %s: %v", value, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsresourcemonitor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	cgroupV1 = `12:memory:/kubepods/pod1/ovs
+4:cpu,cpuacct:/kubepods/pod1/ovs
+1:name=systemd:/kubepods/pod1/ovs
+0::/`
+	cgroupV2        = "0::/kubepods.slice/pod1/ovs"
+	cgroupV1Systemd = `12:memory:/system.slice/ovs-vswitchd.service
+1:name=systemd:/system.slice/ovs-vswitchd.service`
+	cgroupV2Systemd = "0::/system.slice/ovsdb-server.service"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func readTestFile(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func newTestManager(t *testing.T) (*procfsManager, *[]string, func()) {
+	dir, err := ioutil.TempDir("", "ovsresourcemonitor")
+	require.NoError(t, err)
+	var commands []string
+	m := &procfsManager{
+		procPath:   filepath.Join(dir, "proc"),
+		cgroupRoot: filepath.Join(dir, "cgroup"),
+		runCommand: func(name string, args ...string) ([]byte, error) {
+			commands = append(commands, strings.Join(append([]string{name}, args...), " "))
+			return nil, nil
+		},
+	}
+	return m, &commands, func() { os.RemoveAll(dir) }
+}
+
+func TestListProcesses(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+
+	writeTestFile(t, filepath.Join(m.procPath, "1", "comm"), "systemd\n")
+	writeTestFile(t, filepath.Join(m.procPath, "1", "cgroup"), "0::/init.scope\n")
+	writeTestFile(t, filepath.Join(m.procPath, "100", "comm"), "ovsdb-server\n")
+	writeTestFile(t, filepath.Join(m.procPath, "100", "cgroup"), cgroupV2+"\n")
+	writeTestFile(t, filepath.Join(m.procPath, "101", "comm"), "ovs-vswitchd\n")
+	writeTestFile(t, filepath.Join(m.procPath, "101", "cgroup"), cgroupV2+"\n")
+	writeTestFile(t, filepath.Join(m.procPath, "meminfo"), "")
+
+	processes, err := m.listProcesses()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []ovsProcess{
+		{name: "ovsdb-server", pid: 100, cgroup: cgroupV2},
+		{name: "ovs-vswitchd", pid: 101, cgroup: cgroupV2},
+	}, processes)
+}
+
+func TestGetUsage(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+
+	// 150 ticks of utime, 50 ticks of stime and 1000 pages of rss.
+	stat := "101 (ovs-vswitchd) S 1 100 100 0 -1 4194560 1000 0 0 0 150 50 0 0 10 -10 5 0 2000 300000000 1000 18446744073709551615"
+	writeTestFile(t, filepath.Join(m.procPath, "101", "stat"), stat)
+	usage, err := m.getUsage(101)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, usage.cpuTime)
+	assert.Equal(t, int64(1000*os.Getpagesize()), usage.memory)
+
+	_, err = m.getUsage(102)
+	assert.Error(t, err)
+	_, err = parseProcessStat("101 (ovs-vswitchd) S 1", 4096)
+	assert.Error(t, err)
+}
+
+func TestSetLimits(t *testing.T) {
+	limits := &Limits{MilliCPU: 1500, Memory: 512 << 20}
+	tests := []struct {
+		name             string
+		cgroup           string
+		limits           *Limits
+		expectedFiles    map[string]string
+		expectedCommands []string
+	}{
+		{
+			name:   "cgroup v1",
+			cgroup: cgroupV1,
+			limits: limits,
+			expectedFiles: map[string]string{
+				"cpu,cpuacct/kubepods/pod1/ovs/cpu.cfs_period_us": "100000",
+				"cpu,cpuacct/kubepods/pod1/ovs/cpu.cfs_quota_us":  "150000",
+				"memory/kubepods/pod1/ovs/memory.limit_in_bytes":  "536870912",
+			},
+		},
+		{
+			name:   "cgroup v1 memory only",
+			cgroup: cgroupV1,
+			limits: &Limits{Memory: 512 << 20},
+			expectedFiles: map[string]string{
+				"memory/kubepods/pod1/ovs/memory.limit_in_bytes": "536870912",
+			},
+		},
+		{
+			name:   "cgroup v2",
+			cgroup: cgroupV2,
+			limits: limits,
+			expectedFiles: map[string]string{
+				"kubepods.slice/pod1/ovs/cpu.max":    "150000 100000",
+				"kubepods.slice/pod1/ovs/memory.max": "536870912",
+			},
+		},
+		{
+			name:             "systemd cgroup v1",
+			cgroup:           cgroupV1Systemd,
+			limits:           limits,
+			expectedCommands: []string{"systemctl set-property --runtime ovs-vswitchd.service CPUQuota=150% MemoryMax=536870912"},
+		},
+		{
+			name:             "systemd cgroup v2",
+			cgroup:           cgroupV2Systemd,
+			limits:           &Limits{MilliCPU: 1},
+			expectedCommands: []string{"systemctl set-property --runtime ovsdb-server.service CPUQuota=1%"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, commands, cleanup := newTestManager(t)
+			defer cleanup()
+			// The cgroup files exist before the limits are written.
+			for _, dir := range []string{"cpu,cpuacct/kubepods/pod1/ovs", "memory/kubepods/pod1/ovs", "kubepods.slice/pod1/ovs"} {
+				require.NoError(t, os.MkdirAll(filepath.Join(m.cgroupRoot, dir), 0755))
+			}
+
+			require.NoError(t, m.setLimits(tt.cgroup, tt.limits))
+			for file, value := range tt.expectedFiles {
+				assert.Equal(t, value, readTestFile(t, filepath.Join(m.cgroupRoot, file)), "Unexpected value in %s", file)
+			}
+			var files []string
+			filepath.Walk(m.cgroupRoot, func(path string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() {
+					files = append(files, path)
+				}
+				return nil
+			})
+			assert.Len(t, files, len(tt.expectedFiles))
+			assert.Equal(t, tt.expectedCommands, *commands)
+		})
+	}
+}
+
+func TestSetLimitsErrors(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+
+	// The cgroup directory doesn't exist.
+	assert.Error(t, m.setLimits(cgroupV2, &Limits{Memory: 1 << 30}))
+	assert.Error(t, m.setLimits("invalid", &Limits{Memory: 1 << 30}))
+
+	m.runCommand = func(name string, args ...string) ([]byte, error) {
+		return []byte("Failed to connect to bus"), fmt.Errorf("exit status 1")
+	}
+	err := m.setLimits(cgroupV2Systemd, &Limits{Memory: 1 << 30})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed to connect to bus")
+}
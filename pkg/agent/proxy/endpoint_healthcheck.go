@@ -0,0 +1,158 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+)
+
+const (
+	endpointProbeInterval = 5 * time.Second
+	endpointProbeTimeout  = 500 * time.Millisecond
+	// endpointProbeAttempts is the number of consecutive failed probes after
+	// which an Endpoint is considered unhealthy.
+	endpointProbeAttempts = 3
+)
+
+// endpointProber probes the Endpoint with the provided "<ip>:<port>" address.
+type endpointProber func(address string, timeout time.Duration) error
+
+// tcpProber returns an endpointProber which opens a TCP connection to the
+// Endpoint, i.e. which expects a SYN-ACK in reply to its SYN, and closes it
+// right away. The connections originate from sourceIP when the Endpoint has
+// the same IP family.
+func tcpProber(sourceIP net.IP) endpointProber {
+	return func(address string, timeout time.Duration) error {
+		dialer := net.Dialer{Timeout: timeout}
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			if ip := net.ParseIP(host); ip != nil && (ip.To4() == nil) == (sourceIP.To4() == nil) {
+				dialer.LocalAddr = &net.TCPAddr{IP: sourceIP}
+			}
+		}
+		conn, err := dialer.Dial("tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// endpointHealthChecker periodically probes the Endpoints of the Services
+// annotated with antrea.io/endpoint-healthcheck, and tracks the ones which do
+// not respond. AntreaProxy removes the unhealthy Endpoints from the groups of
+// the Services, and adds them back once they respond again.
+type endpointHealthChecker struct {
+	probe    endpointProber
+	interval time.Duration
+	// onChange is called when the health of an Endpoint changes, it triggers
+	// a sync of the rules.
+	onChange func()
+	// mutex protects targets and unhealthy, which are read by the Proxier.
+	mutex sync.RWMutex
+	// targets are the addresses of the Endpoints to probe.
+	targets sets.String
+	// unhealthy are the targets which did not respond to their last probes.
+	unhealthy sets.String
+}
+
+func newEndpointHealthChecker(probe endpointProber, onChange func()) *endpointHealthChecker {
+	return &endpointHealthChecker{
+		probe:     probe,
+		interval:  endpointProbeInterval,
+		onChange:  onChange,
+		targets:   sets.NewString(),
+		unhealthy: sets.NewString(),
+	}
+}
+
+// setTargets sets the Endpoints to probe. The Endpoints which are not probed
+// anymore are forgotten.
+func (c *endpointHealthChecker) setTargets(targets sets.String) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.targets = targets
+	c.unhealthy = c.unhealthy.Intersection(targets)
+}
+
+// isHealthy returns false if the Endpoint did not respond to its last probes.
+func (c *endpointHealthChecker) isHealthy(address string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return !c.unhealthy.Has(address)
+}
+
+// probeEndpoint returns true if the Endpoint responds to one of
+// endpointProbeAttempts probes.
+func (c *endpointHealthChecker) probeEndpoint(address string) bool {
+	var err error
+	for i := 0; i < endpointProbeAttempts; i++ {
+		if err = c.probe(address, endpointProbeTimeout); err == nil {
+			return true
+		}
+	}
+	klog.V(2).Infof("Endpoint %s did not respond to %d probes: %v", address, endpointProbeAttempts, err)
+	return false
+}
+
+// probeAll probes all the targets concurrently, and calls onChange if the
+// health of one of them changed.
+func (c *endpointHealthChecker) probeAll() {
+	c.mutex.RLock()
+	targets := c.targets.UnsortedList()
+	c.mutex.RUnlock()
+
+	results := make([]bool, len(targets))
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = c.probeEndpoint(targets[i])
+		}(i)
+	}
+	wg.Wait()
+
+	changed := false
+	c.mutex.Lock()
+	for i, address := range targets {
+		// The Endpoint may have been removed while it was probed.
+		if !c.targets.Has(address) {
+			continue
+		}
+		if results[i] && c.unhealthy.Has(address) {
+			klog.Infof("Endpoint %s is healthy again", address)
+			c.unhealthy.Delete(address)
+			changed = true
+		} else if !results[i] && !c.unhealthy.Has(address) {
+			klog.Warningf("Endpoint %s is unhealthy, it does not respond to TCP probes", address)
+			c.unhealthy.Insert(address)
+			changed = true
+		}
+	}
+	c.mutex.Unlock()
+	if changed {
+		c.onChange()
+	}
+}
+
+func (c *endpointHealthChecker) Run(stopCh <-chan struct{}) {
+	wait.Until(c.probeAll, c.interval, stopCh)
+}
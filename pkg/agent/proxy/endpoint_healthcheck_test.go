@@ -0,0 +1,103 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// fakeProber fails the probes of the addresses in failing, and counts the
+// probes of each address.
+type fakeProber struct {
+	mutex   sync.Mutex
+	failing sets.String
+	probes  map[string]int
+}
+
+func (p *fakeProber) probe(address string, _ time.Duration) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.probes == nil {
+		p.probes = map[string]int{}
+	}
+	p.probes[address]++
+	if p.failing.Has(address) {
+		return fmt.Errorf("connection to %s timed out", address)
+	}
+	return nil
+}
+
+func TestEndpointHealthCheckerProbeAll(t *testing.T) {
+	prober := &fakeProber{failing: sets.NewString("10.180.0.2:80")}
+	changes := 0
+	c := newEndpointHealthChecker(prober.probe, func() { changes++ })
+	c.setTargets(sets.NewString("10.180.0.1:80", "10.180.0.2:80"))
+
+	c.probeAll()
+	assert.True(t, c.isHealthy("10.180.0.1:80"))
+	assert.False(t, c.isHealthy("10.180.0.2:80"))
+	assert.Equal(t, 1, changes)
+	// A healthy Endpoint is probed once, an unhealthy one is retried.
+	assert.Equal(t, 1, prober.probes["10.180.0.1:80"])
+	assert.Equal(t, endpointProbeAttempts, prober.probes["10.180.0.2:80"])
+
+	c.probeAll()
+	assert.Equal(t, 1, changes)
+
+	prober.failing.Delete("10.180.0.2:80")
+	c.probeAll()
+	assert.True(t, c.isHealthy("10.180.0.2:80"))
+	assert.Equal(t, 2, changes)
+}
+
+func TestEndpointHealthCheckerSetTargets(t *testing.T) {
+	prober := &fakeProber{failing: sets.NewString("10.180.0.2:80")}
+	c := newEndpointHealthChecker(prober.probe, func() {})
+	c.setTargets(sets.NewString("10.180.0.1:80", "10.180.0.2:80"))
+	c.probeAll()
+	require.False(t, c.isHealthy("10.180.0.2:80"))
+
+	// An Endpoint which is not probed anymore is forgotten.
+	c.setTargets(sets.NewString("10.180.0.1:80"))
+	assert.True(t, c.isHealthy("10.180.0.2:80"))
+}
+
+func TestTCPProber(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	probe := tcpProber(net.ParseIP("127.0.0.1"))
+	assert.NoError(t, probe(address, endpointProbeTimeout))
+	listener.Close()
+	assert.Error(t, probe(address, endpointProbeTimeout))
+}
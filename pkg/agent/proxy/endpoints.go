@@ -21,6 +21,7 @@ import (
 	"sync"
 
 	corev1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
 	apimachinerytypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog"
 
@@ -45,13 +46,20 @@ type endpointsChangesTracker struct {
 	initialized bool
 	// changes contains endpoints changes since the last checkoutChanges call.
 	changes map[apimachinerytypes.NamespacedName]*endpointsChange
+	// endpointSliceCache merges the EndpointSlices of each Service. It is
+	// nil if Endpoints are tracked from the Endpoints resource.
+	endpointSliceCache *EndpointSliceCache
 }
 
-func newEndpointsChangesTracker(hostname string) *endpointsChangesTracker {
-	return &endpointsChangesTracker{
+func newEndpointsChangesTracker(hostname string, enableEndpointSlice bool) *endpointsChangesTracker {
+	t := &endpointsChangesTracker{
 		hostname: hostname,
 		changes:  map[apimachinerytypes.NamespacedName]*endpointsChange{},
 	}
+	if enableEndpointSlice {
+		t.endpointSliceCache = NewEndpointSliceCache(hostname)
+	}
+	return t
 }
 
 // OnEndpointUpdate updates given Service's Endpoints change map based on the
@@ -95,6 +103,43 @@ func (t *endpointsChangesTracker) OnEndpointUpdate(previous, current *corev1.End
 	return len(t.changes) > 0
 }
 
+// OnEndpointSliceUpdate updates the Endpoints change map of the Service which
+// owns the EndpointSlice, based on the Endpoints merged from all the
+// EndpointSlices of the Service before and after the EndpointSlice is updated,
+// or removed if removeSlice is true. It returns true if items changed,
+// otherwise it returns false.
+func (t *endpointsChangesTracker) OnEndpointSliceUpdate(endpointSlice *discovery.EndpointSlice, removeSlice bool) bool {
+	// This should never happen.
+	if endpointSlice == nil {
+		klog.Error("Nil EndpointSlice passed to OnEndpointSliceUpdate")
+		return false
+	}
+	namespacedName, err := serviceNameForEndpointSlice(endpointSlice)
+	if err != nil {
+		klog.Warningf("Ignoring EndpointSlice: %v", err)
+		return false
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	change, exists := t.changes[namespacedName]
+	if !exists {
+		change = &endpointsChange{}
+		change.previous = t.endpointSliceCache.EndpointsMap(namespacedName)
+		t.changes[namespacedName] = change
+	}
+	// The EndpointSlice cannot be rejected as its Service has been validated.
+	t.endpointSliceCache.Update(endpointSlice, removeSlice)
+	change.current = t.endpointSliceCache.EndpointsMap(namespacedName)
+	// If change.previous equals to change.current, it means no change.
+	if reflect.DeepEqual(change.previous, change.current) {
+		delete(t.changes, namespacedName)
+	}
+
+	return len(t.changes) > 0
+}
+
 func (t *endpointsChangesTracker) checkoutChanges() []*endpointsChange {
 	t.Lock()
 	defer t.Unlock()
@@ -153,9 +198,16 @@ func (t *endpointsChangesTracker) endpointsToEndpointsMap(endpoints *corev1.Endp
 					continue
 				}
 				isLocal := addr.NodeName != nil && *addr.NodeName == t.hostname
+				// The Node of the Endpoint is recorded in its topology, like
+				// for the Endpoints tracked from EndpointSlices.
+				var topology map[string]string
+				if addr.NodeName != nil {
+					topology = map[string]string{topologyHostnameKey: *addr.NodeName}
+				}
 				ei := types.NewEndpointInfo(&k8sproxy.BaseEndpointInfo{
 					Endpoint: net.JoinHostPort(addr.IP, fmt.Sprint(port.Port)),
 					IsLocal:  isLocal,
+					Topology: topology,
 				})
 				endpointsMap[svcPortName][ei.String()] = ei
 			}
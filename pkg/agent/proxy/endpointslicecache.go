@@ -0,0 +1,212 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimachinerytypes "k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/proxy/types"
+	k8sproxy "github.com/vmware-tanzu/antrea/third_party/proxy"
+)
+
+const (
+	// endpointSliceControllerName is the value of the managed-by label of
+	// the EndpointSlices created by the Kubernetes EndpointSlice controller.
+	endpointSliceControllerName = "endpointslice-controller.k8s.io"
+	// topologyHostnameKey is the topology key of an Endpoint which stores
+	// the name of the Node running the Endpoint.
+	topologyHostnameKey = "kubernetes.io/hostname"
+)
+
+// endpointSliceInfo contains the information of an EndpointSlice which is
+// relevant to AntreaProxy.
+type endpointSliceInfo struct {
+	managedBy string
+	ports     []discovery.EndpointPort
+	endpoints []*endpointSliceEndpoint
+}
+
+// endpointSliceEndpoint contains the information of an Endpoint of an
+// EndpointSlice which is relevant to AntreaProxy.
+type endpointSliceEndpoint struct {
+	addresses []string
+	topology  map[string]string
+}
+
+// EndpointSliceCache tracks the EndpointSlices of each Service, and merges the
+// Endpoints of all the EndpointSlices of a Service into a unified
+// types.EndpointsMap keyed by ServicePortName, which is the same view as the
+// one built from the Endpoints resource.
+type EndpointSliceCache struct {
+	// hostname is used to tell whether the Endpoint is located on current Node.
+	hostname string
+	// endpointSlices stores the EndpointSlices of each Service, keyed by
+	// the name of the EndpointSlice.
+	endpointSlices map[apimachinerytypes.NamespacedName]map[string]*endpointSliceInfo
+}
+
+// NewEndpointSliceCache returns a new EndpointSliceCache.
+func NewEndpointSliceCache(hostname string) *EndpointSliceCache {
+	return &EndpointSliceCache{
+		hostname:       hostname,
+		endpointSlices: map[apimachinerytypes.NamespacedName]map[string]*endpointSliceInfo{},
+	}
+}
+
+// IsEndpointSliceAPIAvailable returns true if the EndpointSlice API is served
+// by the K8s apiserver.
+func IsEndpointSliceAPIAvailable(k8sClient clientset.Interface) (bool, error) {
+	resources, err := k8sClient.Discovery().ServerResourcesForGroupVersion(discovery.SchemeGroupVersion.String())
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error when getting resources of %s: %v", discovery.SchemeGroupVersion, err)
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Kind == "EndpointSlice" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// serviceNameForEndpointSlice returns the NamespacedName of the Service which
+// owns the EndpointSlice.
+func serviceNameForEndpointSlice(endpointSlice *discovery.EndpointSlice) (apimachinerytypes.NamespacedName, error) {
+	serviceName, ok := endpointSlice.Labels[discovery.LabelServiceName]
+	if !ok || serviceName == "" {
+		return apimachinerytypes.NamespacedName{}, fmt.Errorf("EndpointSlice %s/%s has no %s label", endpointSlice.Namespace, endpointSlice.Name, discovery.LabelServiceName)
+	}
+	return apimachinerytypes.NamespacedName{Namespace: endpointSlice.Namespace, Name: serviceName}, nil
+}
+
+func newEndpointSliceInfo(endpointSlice *discovery.EndpointSlice) *endpointSliceInfo {
+	info := &endpointSliceInfo{
+		managedBy: endpointSlice.Labels[discovery.LabelManagedBy],
+		ports:     endpointSlice.Ports,
+	}
+	for _, endpoint := range endpointSlice.Endpoints {
+		// Endpoints which are not ready must not receive traffic. A nil
+		// Ready condition should be interpreted as "unknown" and is
+		// considered as ready.
+		if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+			continue
+		}
+		info.endpoints = append(info.endpoints, &endpointSliceEndpoint{
+			addresses: endpoint.Addresses,
+			topology:  endpoint.Topology,
+		})
+	}
+	return info
+}
+
+// Update updates the cache with the provided EndpointSlice, or removes it from
+// the cache if remove is true.
+func (c *EndpointSliceCache) Update(endpointSlice *discovery.EndpointSlice, remove bool) error {
+	serviceName, err := serviceNameForEndpointSlice(endpointSlice)
+	if err != nil {
+		return err
+	}
+	if remove {
+		delete(c.endpointSlices[serviceName], endpointSlice.Name)
+		if len(c.endpointSlices[serviceName]) == 0 {
+			delete(c.endpointSlices, serviceName)
+		}
+		return nil
+	}
+	// FQDN addresses cannot be load-balanced by AntreaProxy.
+	if endpointSlice.AddressType == discovery.AddressTypeFQDN {
+		klog.V(4).Infof("Ignoring EndpointSlice %s/%s with FQDN addresses", endpointSlice.Namespace, endpointSlice.Name)
+		return nil
+	}
+	if _, ok := c.endpointSlices[serviceName]; !ok {
+		c.endpointSlices[serviceName] = map[string]*endpointSliceInfo{}
+	}
+	c.endpointSlices[serviceName][endpointSlice.Name] = newEndpointSliceInfo(endpointSlice)
+	return nil
+}
+
+// EndpointsMap returns the merged Endpoints of all the EndpointSlices of the
+// Service. It returns nil if the Service has no EndpointSlice.
+func (c *EndpointSliceCache) EndpointsMap(serviceName apimachinerytypes.NamespacedName) types.EndpointsMap {
+	slices, ok := c.endpointSlices[serviceName]
+	if !ok {
+		return nil
+	}
+	// The same Endpoint may be present in multiple EndpointSlices, e.g. when
+	// an EndpointSlice is mirrored from a custom Endpoints resource by
+	// another controller. The EndpointSlices managed by the Kubernetes
+	// EndpointSlice controller are processed last so that they take
+	// precedence, then the order is made deterministic by the name.
+	sliceNames := make([]string, 0, len(slices))
+	for name := range slices {
+		sliceNames = append(sliceNames, name)
+	}
+	sort.Slice(sliceNames, func(i, j int) bool {
+		iManaged := slices[sliceNames[i]].managedBy == endpointSliceControllerName
+		jManaged := slices[sliceNames[j]].managedBy == endpointSliceControllerName
+		if iManaged != jManaged {
+			return jManaged
+		}
+		return sliceNames[i] < sliceNames[j]
+	})
+
+	endpointsMap := make(types.EndpointsMap)
+	for _, sliceName := range sliceNames {
+		slice := slices[sliceName]
+		for _, port := range slice.ports {
+			if port.Port == nil || *port.Port == 0 {
+				klog.Warningf("Ignoring invalid port of EndpointSlice %s/%s", serviceName.Namespace, sliceName)
+				continue
+			}
+			svcPortName := k8sproxy.ServicePortName{NamespacedName: serviceName}
+			if port.Name != nil {
+				svcPortName.Port = *port.Name
+			}
+			svcPortName.Protocol = corev1.ProtocolTCP
+			if port.Protocol != nil {
+				svcPortName.Protocol = *port.Protocol
+			}
+			if _, ok := endpointsMap[svcPortName]; !ok {
+				endpointsMap[svcPortName] = map[string]k8sproxy.Endpoint{}
+			}
+			for _, endpoint := range slice.endpoints {
+				// The addresses of an Endpoint are fungible, only the first
+				// one is used, like kube-proxy does.
+				if len(endpoint.addresses) == 0 {
+					continue
+				}
+				isLocal := endpoint.topology[topologyHostnameKey] == c.hostname
+				ei := types.NewEndpointInfo(&k8sproxy.BaseEndpointInfo{
+					Endpoint: net.JoinHostPort(endpoint.addresses[0], fmt.Sprint(*port.Port)),
+					IsLocal:  isLocal,
+					Topology: endpoint.topology,
+				})
+				endpointsMap[svcPortName][ei.String()] = ei
+			}
+		}
+	}
+	return endpointsMap
+}
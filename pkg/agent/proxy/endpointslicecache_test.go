@@ -0,0 +1,106 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	k8sproxy "github.com/vmware-tanzu/antrea/third_party/proxy"
+)
+
+func makeTestEndpointSlice(namespace, svcName, sliceName, managedBy string, port int32, addresses map[string]string) *discovery.EndpointSlice {
+	portName := "http"
+	protocol := corev1.ProtocolTCP
+	slice := &discovery.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sliceName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				discovery.LabelServiceName: svcName,
+				discovery.LabelManagedBy:   managedBy,
+			},
+		},
+		AddressType: discovery.AddressTypeIPv4,
+		Ports: []discovery.EndpointPort{{
+			Name:     &portName,
+			Port:     &port,
+			Protocol: &protocol,
+		}},
+	}
+	for address, nodeName := range addresses {
+		slice.Endpoints = append(slice.Endpoints, discovery.Endpoint{
+			Addresses: []string{address},
+			Topology:  map[string]string{topologyHostnameKey: nodeName},
+		})
+	}
+	return slice
+}
+
+func TestEndpointSliceCache(t *testing.T) {
+	cache := NewEndpointSliceCache("node1")
+	svcName := makeNamespaceName("ns1", "svc1")
+	svcPortName := k8sproxy.ServicePortName{NamespacedName: svcName, Port: "http", Protocol: corev1.ProtocolTCP}
+
+	slice1 := makeTestEndpointSlice("ns1", "svc1", "svc1-a", endpointSliceControllerName, 80, map[string]string{"10.0.0.1": "node1"})
+	slice2 := makeTestEndpointSlice("ns1", "svc1", "svc1-b", endpointSliceControllerName, 80, map[string]string{"10.0.1.1": "node2"})
+	assert.NoError(t, cache.Update(slice1, false))
+	assert.NoError(t, cache.Update(slice2, false))
+
+	endpointsMap := cache.EndpointsMap(svcName)
+	assert.Len(t, endpointsMap, 1)
+	endpoints := endpointsMap[svcPortName]
+	assert.Len(t, endpoints, 2)
+	assert.True(t, endpoints["10.0.0.1:80"].GetIsLocal())
+	assert.False(t, endpoints["10.0.1.1:80"].GetIsLocal())
+
+	// The Endpoints which are not ready are ignored.
+	notReady := false
+	slice2.Endpoints[0].Conditions.Ready = &notReady
+	assert.NoError(t, cache.Update(slice2, false))
+	assert.Len(t, cache.EndpointsMap(svcName)[svcPortName], 1)
+
+	assert.NoError(t, cache.Update(slice1, true))
+	assert.NoError(t, cache.Update(slice2, true))
+	assert.Nil(t, cache.EndpointsMap(svcName))
+}
+
+func TestEndpointSliceCacheManagedBy(t *testing.T) {
+	cache := NewEndpointSliceCache("node1")
+	svcName := makeNamespaceName("ns1", "svc1")
+	svcPortName := k8sproxy.ServicePortName{NamespacedName: svcName, Port: "http", Protocol: corev1.ProtocolTCP}
+
+	// The same Endpoint is present in a mirrored EndpointSlice, with stale
+	// topology, and in the EndpointSlice managed by the K8s controller.
+	mirrored := makeTestEndpointSlice("ns1", "svc1", "svc1-z", "custom-controller", 80, map[string]string{"10.0.0.1": "node2"})
+	managed := makeTestEndpointSlice("ns1", "svc1", "svc1-a", endpointSliceControllerName, 80, map[string]string{"10.0.0.1": "node1"})
+	assert.NoError(t, cache.Update(mirrored, false))
+	assert.NoError(t, cache.Update(managed, false))
+
+	endpoints := cache.EndpointsMap(svcName)[svcPortName]
+	assert.Len(t, endpoints, 1)
+	assert.True(t, endpoints["10.0.0.1:80"].GetIsLocal())
+}
+
+func TestEndpointSliceCacheWithoutService(t *testing.T) {
+	cache := NewEndpointSliceCache("node1")
+	slice := makeTestEndpointSlice("ns1", "svc1", "svc1-a", endpointSliceControllerName, 80, map[string]string{"10.0.0.1": "node1"})
+	delete(slice.Labels, discovery.LabelServiceName)
+	assert.Error(t, cache.Update(slice, false))
+}
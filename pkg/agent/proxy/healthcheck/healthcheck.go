@@ -0,0 +1,166 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+)
+
+// ServiceHealthServer serves the health check NodePorts of the Services whose
+// externalTrafficPolicy is Local. A health check NodePort responds with 200 if
+// the Service has at least one Endpoint on the current Node, and 503 otherwise,
+// so that external load balancers only send traffic to Nodes running Endpoints.
+type ServiceHealthServer interface {
+	// SyncServices makes the server listen on the provided health check
+	// NodePorts and stop listening on the ports of the Services which are not
+	// provided anymore.
+	SyncServices(newServices map[types.NamespacedName]uint16) error
+	// SyncEndpoints updates the number of local Endpoints of the Services.
+	SyncEndpoints(newEndpoints map[types.NamespacedName]int) error
+}
+
+type listenFunc func(network, address string) (net.Listener, error)
+
+type server struct {
+	listen listenFunc
+
+	lock     sync.RWMutex
+	services map[types.NamespacedName]*hcInstance
+	// failedPorts holds the health check NodePorts which could not be opened,
+	// so that the failure is only reported once per Service and port.
+	failedPorts map[types.NamespacedName]uint16
+}
+
+// hcInstance is the health check server of a Service.
+type hcInstance struct {
+	port      uint16
+	listener  net.Listener
+	server    *http.Server
+	endpoints int
+}
+
+// NewServiceHealthServer returns a ServiceHealthServer listening on all the
+// addresses of the Node.
+func NewServiceHealthServer() ServiceHealthServer {
+	return newServiceHealthServer(net.Listen)
+}
+
+func newServiceHealthServer(listen listenFunc) *server {
+	return &server{
+		listen:      listen,
+		services:    map[types.NamespacedName]*hcInstance{},
+		failedPorts: map[types.NamespacedName]uint16{},
+	}
+}
+
+func (s *server) SyncServices(newServices map[types.NamespacedName]uint16) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for nsn, hci := range s.services {
+		if port, ok := newServices[nsn]; ok && port == hci.port {
+			continue
+		}
+		klog.V(2).Infof("Closing health check server for Service %s on port %d", nsn, hci.port)
+		if err := hci.server.Close(); err != nil {
+			klog.Errorf("Error when closing health check server for Service %s: %v", nsn, err)
+		}
+		delete(s.services, nsn)
+	}
+	for nsn, failedPort := range s.failedPorts {
+		if port, ok := newServices[nsn]; !ok || port != failedPort {
+			delete(s.failedPorts, nsn)
+		}
+	}
+
+	for nsn, port := range newServices {
+		if _, ok := s.services[nsn]; ok {
+			continue
+		}
+		klog.V(2).Infof("Opening health check server for Service %s on port %d", nsn, port)
+		listener, err := s.listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			// The port may be used by another proxy serving the same
+			// Service, the Service is retried at the next sync. As syncs
+			// are frequent, the error is only logged the first time.
+			if failedPort, ok := s.failedPorts[nsn]; ok && failedPort == port {
+				klog.V(2).Infof("Failed to open health check port %d for Service %s: %v", port, nsn, err)
+			} else {
+				klog.Errorf("Failed to open health check port %d for Service %s: %v", port, nsn, err)
+				s.failedPorts[nsn] = port
+			}
+			continue
+		}
+		delete(s.failedPorts, nsn)
+		hci := &hcInstance{port: port, listener: listener}
+		hci.server = &http.Server{Handler: hcHandler{name: nsn, server: s}}
+		s.services[nsn] = hci
+		go func(nsn types.NamespacedName, hci *hcInstance) {
+			if err := hci.server.Serve(hci.listener); err != nil && err != http.ErrServerClosed {
+				klog.Errorf("Health check server for Service %s exited: %v", nsn, err)
+			}
+		}(nsn, hci)
+	}
+	return nil
+}
+
+func (s *server) SyncEndpoints(newEndpoints map[types.NamespacedName]int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for nsn, hci := range s.services {
+		hci.endpoints = newEndpoints[nsn]
+	}
+	return nil
+}
+
+// hcHandler handles the health check requests of a Service.
+type hcHandler struct {
+	name   types.NamespacedName
+	server *server
+}
+
+func (h hcHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	h.server.lock.RLock()
+	hci, ok := h.server.services[h.name]
+	if !ok {
+		h.server.lock.RUnlock()
+		resp.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	count := hci.endpoints
+	h.server.lock.RUnlock()
+
+	resp.Header().Set("Content-Type", "application/json")
+	if count == 0 {
+		resp.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		resp.WriteHeader(http.StatusOK)
+	}
+	body := struct {
+		Service        types.NamespacedName `json:"service"`
+		LocalEndpoints int                  `json:"localEndpoints"`
+	}{Service: h.name, LocalEndpoints: count}
+	if err := json.NewEncoder(resp).Encode(body); err != nil {
+		klog.Errorf("Error when writing health check response for Service %s: %v", h.name, err)
+	}
+}
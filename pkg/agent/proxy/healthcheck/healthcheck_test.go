@@ -0,0 +1,100 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// listenOnLoopback ignores the requested port and listens on a random loopback
+// port, so that the test does not depend on available NodePorts.
+func listenOnLoopback(network, address string) (net.Listener, error) {
+	return net.Listen(network, "127.0.0.1:0")
+}
+
+func checkHealth(t *testing.T, hs *server, nsn types.NamespacedName, expectedCode, expectedEndpoints int) {
+	hs.lock.RLock()
+	addr := hs.services[nsn].listener.Addr().String()
+	hs.lock.RUnlock()
+	resp, err := http.Get(fmt.Sprintf("http://%s/", addr))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, expectedCode, resp.StatusCode)
+	var body struct {
+		LocalEndpoints int `json:"localEndpoints"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, expectedEndpoints, body.LocalEndpoints)
+}
+
+func TestServiceHealthServer(t *testing.T) {
+	hs := newServiceHealthServer(listenOnLoopback)
+	nsn1 := types.NamespacedName{Namespace: "ns1", Name: "svc1"}
+	nsn2 := types.NamespacedName{Namespace: "ns1", Name: "svc2"}
+
+	require.NoError(t, hs.SyncServices(map[types.NamespacedName]uint16{nsn1: 30001, nsn2: 30002}))
+	assert.Len(t, hs.services, 2)
+	checkHealth(t, hs, nsn1, http.StatusServiceUnavailable, 0)
+
+	require.NoError(t, hs.SyncEndpoints(map[types.NamespacedName]int{nsn1: 2}))
+	checkHealth(t, hs, nsn1, http.StatusOK, 2)
+	checkHealth(t, hs, nsn2, http.StatusServiceUnavailable, 0)
+
+	require.NoError(t, hs.SyncServices(map[types.NamespacedName]uint16{nsn2: 30002}))
+	assert.Len(t, hs.services, 1)
+	assert.Contains(t, hs.services, nsn2)
+
+	require.NoError(t, hs.SyncServices(nil))
+	assert.Empty(t, hs.services)
+}
+
+func TestServiceHealthServerListenError(t *testing.T) {
+	listenErr := true
+	hs := newServiceHealthServer(func(network, address string) (net.Listener, error) {
+		if listenErr {
+			return nil, fmt.Errorf("address already in use")
+		}
+		return listenOnLoopback(network, address)
+	})
+	nsn := types.NamespacedName{Namespace: "ns1", Name: "svc1"}
+
+	require.NoError(t, hs.SyncServices(map[types.NamespacedName]uint16{nsn: 30001}))
+	assert.Empty(t, hs.services)
+	assert.Equal(t, map[types.NamespacedName]uint16{nsn: 30001}, hs.failedPorts)
+
+	// The failure is remembered as long as the Service keeps the same port.
+	require.NoError(t, hs.SyncServices(map[types.NamespacedName]uint16{nsn: 30001}))
+	assert.Equal(t, map[types.NamespacedName]uint16{nsn: 30001}, hs.failedPorts)
+
+	listenErr = false
+	require.NoError(t, hs.SyncServices(map[types.NamespacedName]uint16{nsn: 30001}))
+	assert.Contains(t, hs.services, nsn)
+	assert.Empty(t, hs.failedPorts)
+
+	require.NoError(t, hs.SyncServices(nil))
+	listenErr = true
+	require.NoError(t, hs.SyncServices(map[types.NamespacedName]uint16{nsn: 30002}))
+	assert.Equal(t, map[types.NamespacedName]uint16{nsn: 30002}, hs.failedPorts)
+	require.NoError(t, hs.SyncServices(nil))
+	assert.Empty(t, hs.failedPorts)
+}
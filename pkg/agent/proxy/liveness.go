@@ -0,0 +1,234 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package proxy
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/proxy/types"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsconfig"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
+)
+
+const (
+	// livenessPortTypeKey is the external ID which identifies the OVS ports
+	// created by livenessPortManager.
+	livenessPortTypeKey = "antrea-type"
+	livenessPortType    = "endpoint-liveness"
+	// livenessPortIPKey is the external ID storing the Endpoint IP of a
+	// liveness port.
+	livenessPortIPKey = "endpoint-ip"
+)
+
+// livenessPort is an OVS internal port watched by the buckets of an Endpoint
+// in the fast-failover groups of the Services.
+type livenessPort struct {
+	name   string
+	uuid   string
+	ofPort int32
+}
+
+// livenessPortManager manages the liveness ports of the Endpoints when
+// AntreaProxy programs fast-failover groups. Each Endpoint IP gets a liveness
+// port, which is brought down as soon as the Endpoint is not used by any
+// Service anymore, e.g. because its Pod has been deleted or is not ready. OVS
+// then fails over to the next live bucket without waiting for the groups to
+// be updated. A port which has been brought down is deleted during the next
+// sync, once the groups no longer watch it.
+type livenessPortManager struct {
+	ovsBridgeClient ovsconfig.OVSBridgeClient
+	ovsCtlClient    ovsctl.OVSCtlClient
+	// mutex protects ports, which is read by the OpenFlow client when the
+	// groups are built.
+	mutex sync.RWMutex
+	// ports stores the liveness ports which are up, keyed by Endpoint IP.
+	ports map[string]*livenessPort
+	// downPorts stores the liveness ports which have been brought down and
+	// must be deleted, keyed by Endpoint IP.
+	downPorts map[string]*livenessPort
+	// initialized is set once the ports left by a previous run of the agent
+	// have been restored.
+	initialized bool
+}
+
+func newLivenessPortManager(ovsBridgeClient ovsconfig.OVSBridgeClient, ovsCtlClient ovsctl.OVSCtlClient) *livenessPortManager {
+	return &livenessPortManager{
+		ovsBridgeClient: ovsBridgeClient,
+		ovsCtlClient:    ovsCtlClient,
+		ports:           map[string]*livenessPort{},
+		downPorts:       map[string]*livenessPort{},
+	}
+}
+
+// livenessPortName returns the name of the liveness port of an Endpoint IP. It
+// must fit in the 15 characters of a Linux interface name, so the IPv6
+// addresses are hashed.
+func livenessPortName(endpointIP string) string {
+	ip := net.ParseIP(endpointIP)
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return fmt.Sprintf("ep-%x", []byte(ipv4))
+	}
+	hash := fnv.New32a()
+	hash.Write(ip.To16())
+	return fmt.Sprintf("ep6-%08x", hash.Sum32())
+}
+
+// watchPort returns the OpenFlow port of the liveness port of an Endpoint IP.
+func (m *livenessPortManager) watchPort(endpointIP string) (uint32, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	port, ok := m.ports[endpointIP]
+	if !ok {
+		return 0, false
+	}
+	return uint32(port.ofPort), true
+}
+
+// endpointIPs returns the IPs of all the Endpoints in endpointsMap.
+func endpointIPs(endpointsMap types.EndpointsMap) sets.String {
+	ips := sets.NewString()
+	for _, endpoints := range endpointsMap {
+		for _, endpoint := range endpoints {
+			ips.Insert(endpoint.IP())
+		}
+	}
+	return ips
+}
+
+// sync deletes the ports brought down by the previous sync, brings down the
+// ports of the Endpoint IPs which are not in endpointIPs anymore, and creates
+// the ports of the new Endpoint IPs. It must be called before the groups are
+// updated.
+func (m *livenessPortManager) sync(endpointIPs sets.String) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if !m.initialized {
+		if err := m.restorePorts(); err != nil {
+			klog.Errorf("Error when restoring Endpoint liveness ports: %v", err)
+			return
+		}
+		m.initialized = true
+	}
+	for ip, port := range m.downPorts {
+		if endpointIPs.Has(ip) {
+			// The Endpoint has come back before its port was deleted.
+			if err := m.setPortState(port, true); err != nil {
+				klog.Errorf("Error when bringing up liveness port %s of Endpoint %s: %v", port.name, ip, err)
+				continue
+			}
+			m.ports[ip] = port
+			delete(m.downPorts, ip)
+			continue
+		}
+		if err := m.ovsBridgeClient.DeletePort(port.uuid); err != nil {
+			klog.Errorf("Error when deleting liveness port %s of Endpoint %s: %v", port.name, ip, err)
+			continue
+		}
+		delete(m.downPorts, ip)
+	}
+	for ip, port := range m.ports {
+		if endpointIPs.Has(ip) {
+			continue
+		}
+		if err := m.setPortState(port, false); err != nil {
+			klog.Errorf("Error when bringing down liveness port %s of Endpoint %s: %v", port.name, ip, err)
+		}
+		m.downPorts[ip] = port
+		delete(m.ports, ip)
+	}
+	for ip := range endpointIPs {
+		if _, ok := m.ports[ip]; ok {
+			continue
+		}
+		port, err := m.createPort(ip)
+		if err != nil {
+			// The buckets of the Endpoint do not watch any port then, and
+			// are always live.
+			klog.Errorf("Error when creating liveness port of Endpoint %s: %v", ip, err)
+			continue
+		}
+		m.ports[ip] = port
+	}
+}
+
+// restorePorts restores the liveness ports created by a previous run of the
+// agent. They are brought down, and are deleted or brought up again by sync.
+func (m *livenessPortManager) restorePorts() error {
+	ovsPorts, err := m.ovsBridgeClient.GetPortList()
+	if err != nil {
+		return err
+	}
+	for _, ovsPort := range ovsPorts {
+		if ovsPort.ExternalIDs[livenessPortTypeKey] != livenessPortType {
+			continue
+		}
+		m.downPorts[ovsPort.ExternalIDs[livenessPortIPKey]] = &livenessPort{
+			name:   ovsPort.Name,
+			uuid:   ovsPort.UUID,
+			ofPort: ovsPort.OFPort,
+		}
+	}
+	return nil
+}
+
+func (m *livenessPortManager) createPort(endpointIP string) (*livenessPort, error) {
+	name := livenessPortName(endpointIP)
+	externalIDs := map[string]interface{}{
+		livenessPortTypeKey: livenessPortType,
+		livenessPortIPKey:   endpointIP,
+	}
+	uuid, err := m.ovsBridgeClient.CreateInternalPort(name, 0, externalIDs)
+	if err != nil {
+		return nil, err
+	}
+	port := &livenessPort{name: name, uuid: uuid}
+	if err := m.initPort(port); err != nil {
+		if delErr := m.ovsBridgeClient.DeletePort(uuid); delErr != nil {
+			klog.Errorf("Error when deleting liveness port %s: %v", name, delErr)
+		}
+		return nil, err
+	}
+	return port, nil
+}
+
+// initPort retrieves the OpenFlow port of a new liveness port and brings it
+// up, as OVS internal ports are created down.
+func (m *livenessPortManager) initPort(port *livenessPort) error {
+	ofPort, err := m.ovsBridgeClient.GetOFPort(port.name)
+	if err != nil {
+		return err
+	}
+	if ofPort <= 0 {
+		return fmt.Errorf("no OpenFlow port assigned to %s", port.name)
+	}
+	port.ofPort = ofPort
+	return m.setPortState(port, true)
+}
+
+// setPortState brings a liveness port up or down. OVS considers a bucket
+// watching a port which is down as not live.
+func (m *livenessPortManager) setPortState(port *livenessPort, up bool) error {
+	state := "down"
+	if up {
+		state = "up"
+	}
+	_, err := m.ovsCtlClient.RunOfctlCmd("mod-port", port.name, state)
+	return err
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package proxy
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsconfig"
+	ovsconfigtest "github.com/vmware-tanzu/antrea/pkg/ovs/ovsconfig/testing"
+	ovsctltest "github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl/testing"
+)
+
+func TestLivenessPortName(t *testing.T) {
+	assert.Equal(t, "ep-0a0a0105", livenessPortName("10.10.1.5"))
+	name := livenessPortName("fd00:10:10::5")
+	assert.Regexp(t, `^ep6-[0-9a-f]{8}$`, name)
+	assert.LessOrEqual(t, len(name), 15)
+	assert.NotEqual(t, name, livenessPortName("fd00:10:10::6"))
+}
+
+func TestLivenessPortManagerSync(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOVSBridgeClient := ovsconfigtest.NewMockOVSBridgeClient(ctrl)
+	mockOVSCtlClient := ovsctltest.NewMockOVSCtlClient(ctrl)
+	m := newLivenessPortManager(mockOVSBridgeClient, mockOVSCtlClient)
+
+	// A port left by a previous run of the agent, whose Endpoint is gone.
+	mockOVSBridgeClient.EXPECT().GetPortList().Return([]ovsconfig.OVSPortData{
+		{UUID: "uuid-stale", Name: "ep-0a0a0109", OFPort: 9, ExternalIDs: map[string]string{livenessPortTypeKey: livenessPortType, livenessPortIPKey: "10.10.1.9"}},
+		{UUID: "uuid-pod", Name: "pod-abcd", OFPort: 3, ExternalIDs: map[string]string{"ip": "10.10.1.3"}},
+	}, nil)
+	mockOVSBridgeClient.EXPECT().DeletePort("uuid-stale").Return(nil)
+	mockOVSBridgeClient.EXPECT().CreateInternalPort("ep-0a0a0105", int32(0), gomock.Any()).Return("uuid-5", nil)
+	mockOVSBridgeClient.EXPECT().GetOFPort("ep-0a0a0105").Return(int32(5), nil)
+	mockOVSCtlClient.EXPECT().RunOfctlCmd("mod-port", "ep-0a0a0105", "up").Return(nil, nil)
+	m.sync(sets.NewString("10.10.1.5"))
+	port, ok := m.watchPort("10.10.1.5")
+	assert.True(t, ok)
+	assert.Equal(t, uint32(5), port)
+
+	// The port of a removed Endpoint is brought down, and is only deleted
+	// during the next sync.
+	mockOVSCtlClient.EXPECT().RunOfctlCmd("mod-port", "ep-0a0a0105", "down").Return(nil, nil)
+	m.sync(sets.NewString())
+	_, ok = m.watchPort("10.10.1.5")
+	assert.False(t, ok)
+
+	// The port is brought up again if the Endpoint comes back before it is
+	// deleted.
+	mockOVSCtlClient.EXPECT().RunOfctlCmd("mod-port", "ep-0a0a0105", "up").Return(nil, nil)
+	m.sync(sets.NewString("10.10.1.5"))
+	port, ok = m.watchPort("10.10.1.5")
+	assert.True(t, ok)
+	assert.Equal(t, uint32(5), port)
+
+	mockOVSCtlClient.EXPECT().RunOfctlCmd("mod-port", "ep-0a0a0105", "down").Return(nil, nil)
+	m.sync(sets.NewString())
+	mockOVSBridgeClient.EXPECT().DeletePort("uuid-5").Return(nil)
+	m.sync(sets.NewString())
+	assert.Empty(t, m.ports)
+	assert.Empty(t, m.downPorts)
+}
+
+func TestLivenessPortManagerCreatePortError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOVSBridgeClient := ovsconfigtest.NewMockOVSBridgeClient(ctrl)
+	mockOVSCtlClient := ovsctltest.NewMockOVSCtlClient(ctrl)
+	m := newLivenessPortManager(mockOVSBridgeClient, mockOVSCtlClient)
+	m.initialized = true
+
+	// The port is deleted if no OpenFlow port has been assigned to it.
+	mockOVSBridgeClient.EXPECT().CreateInternalPort("ep-0a0a0105", int32(0), gomock.Any()).Return("uuid-5", nil)
+	mockOVSBridgeClient.EXPECT().GetOFPort("ep-0a0a0105").Return(int32(0), nil)
+	mockOVSBridgeClient.EXPECT().DeletePort("uuid-5").Return(nil)
+	m.sync(sets.NewString("10.10.1.5"))
+	_, ok := m.watchPort("10.10.1.5")
+	assert.False(t, ok)
+}
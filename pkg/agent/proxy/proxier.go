@@ -15,19 +15,31 @@
 package proxy
 
 import (
+	"fmt"
+	"math"
+	"net"
 	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
+	apimachinerytypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
+	utilnet "k8s.io/utils/net"
 
+	"github.com/vmware-tanzu/antrea/pkg/agent/metrics"
 	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/agent/proxy/healthcheck"
 	"github.com/vmware-tanzu/antrea/pkg/agent/proxy/types"
 	"github.com/vmware-tanzu/antrea/pkg/agent/querier"
 	binding "github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsconfig"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
 	k8sproxy "github.com/vmware-tanzu/antrea/third_party/proxy"
 	"github.com/vmware-tanzu/antrea/third_party/proxy/config"
 )
@@ -37,11 +49,23 @@ const (
 	componentName = "antrea-agent-proxy"
 )
 
+// ServiceQuerier is the interface to query the Services and their Endpoints
+// known by AntreaProxy.
+type ServiceQuerier interface {
+	// GetServiceEndpoints returns the Service port with the provided protocol
+	// and port number of the Service, and its Endpoints. If port is 0, the
+	// Service must have exactly one port with the protocol.
+	GetServiceEndpoints(namespace, name string, protocol corev1.Protocol, port int32) (*types.ServiceInfo, []k8sproxy.Endpoint, error)
+}
+
 // TODO: Add metrics
 type Proxier struct {
 	once            sync.Once
 	endpointsConfig *config.EndpointsConfig
-	serviceConfig   *config.ServiceConfig
+	// endpointSliceConfig replaces endpointsConfig when Endpoints are tracked
+	// from the EndpointSlice resource. Only one of them is set.
+	endpointSliceConfig *config.EndpointSliceConfig
+	serviceConfig       *config.ServiceConfig
 	// endpointsChanges and serviceChanges contains all changes to endpoints and
 	// services that happened since last syncProxyRules call. For a single object,
 	// changes are accumulated. Once both endpointsChanges and serviceChanges
@@ -50,6 +74,9 @@ type Proxier struct {
 	serviceChanges   *serviceChangesTracker
 	// syncProxyRulesMutex protects internal caches and states.
 	syncProxyRulesMutex sync.Mutex
+	// syncedOnce is set after the first successful run of syncProxyRules, i.e.
+	// once the flows of all existing Services have been installed.
+	syncedOnce bool
 	// serviceMap stores services we expect to be installed.
 	serviceMap k8sproxy.ServiceMap
 	// serviceInstalledMap stores services we actually installed.
@@ -58,7 +85,30 @@ type Proxier struct {
 	endpointsMap types.EndpointsMap
 	// endpointInstalledMap stores endpoints we actually installed.
 	endpointInstalledMap map[k8sproxy.ServicePortName]map[string]struct{}
-	groupCounter         types.GroupCounter
+	// loadBalancerIPInstalledMap stores the ingress IPs of LoadBalancer
+	// Services for which flows have been installed.
+	loadBalancerIPInstalledMap map[k8sproxy.ServicePortName]sets.String
+	groupCounter               types.GroupCounter
+	// endpointDrainPeriod is how long the flows of a removed Endpoint are kept
+	// after it has been removed from the groups of its Service.
+	endpointDrainPeriod time.Duration
+	// drainingEndpoints stores the removed Endpoints whose flows are kept until
+	// the drain period expires.
+	drainingEndpoints map[string]*drainingEndpoint
+	clock             clock.Clock
+	// serviceHealthServer serves the health check NodePorts of the Services
+	// using only node-local Endpoints for external traffic.
+	serviceHealthServer healthcheck.ServiceHealthServer
+	// livenessPorts manages the ports watched by the fast-failover groups. It
+	// is nil when select groups are used.
+	livenessPorts *livenessPortManager
+	// endpointHealthChecker probes the Endpoints of the Services annotated
+	// with antrea.io/endpoint-healthcheck. It is nil when the health check is
+	// disabled.
+	endpointHealthChecker *endpointHealthChecker
+	// unhealthyEndpointsInstalled stores the unhealthy Endpoints which have
+	// been left out of the groups of the Services.
+	unhealthyEndpointsInstalled map[k8sproxy.ServicePortName]sets.String
 
 	runner       *k8sproxy.BoundedFrequencyRunner
 	stopChan     <-chan struct{}
@@ -66,6 +116,8 @@ type Proxier struct {
 	ofClient     openflow.Client
 }
 
+var _ ServiceQuerier = new(Proxier)
+
 func (p *Proxier) isInitialized() bool {
 	return p.endpointsChanges.Synced() && p.serviceChanges.Synced()
 }
@@ -80,34 +132,189 @@ func (p *Proxier) removeStaleServices() {
 			klog.Errorf("Failed to remove flows of Service %v: %v", svcPortName, err)
 			continue
 		}
+		if svcInfo.NodePort() != 0 {
+			if err := p.ofClient.UninstallNodePortFlows(uint16(svcInfo.NodePort()), svcInfo.OFProtocol); err != nil {
+				klog.Errorf("Failed to remove NodePort flows of Service %v: %v", svcPortName, err)
+				continue
+			}
+		}
+		if err := p.uninstallLoadBalancerFlows(svcPortName, svcInfo); err != nil {
+			klog.Errorf("Failed to remove LoadBalancer flows of Service %v: %v", svcPortName, err)
+			continue
+		}
+		delete(p.unhealthyEndpointsInstalled, svcPortName)
 		for _, endpoint := range p.endpointsMap[svcPortName] {
 			if err := p.ofClient.UninstallEndpointFlows(svcInfo.OFProtocol, endpoint); err != nil {
 				klog.Errorf("Failed to remove flows of Service Endpoints %v: %v", svcPortName, err)
 				continue
 			}
 		}
-		groupID, _ := p.groupCounter.Get(svcPortName)
+		groupID, _ := p.groupCounter.Get(svcPortName, false)
 		if err := p.ofClient.UninstallServiceGroup(groupID); err != nil {
 			klog.Errorf("Failed to remove flows of Service %v: %v", svcPortName, err)
 			continue
 		}
+		if svcInfo.OnlyNodeLocalEndpoints() {
+			if err := p.uninstallNodeLocalServiceGroup(svcPortName); err != nil {
+				klog.Errorf("Failed to remove node-local group of Service %v: %v", svcPortName, err)
+				continue
+			}
+		}
 		delete(p.serviceInstalledMap, svcPortName)
-		p.groupCounter.Recycle(svcPortName)
+		p.groupCounter.Recycle(svcPortName, false)
 	}
 }
 
-func (p *Proxier) removeStaleEndpoints(staleEndpoints map[k8sproxy.ServicePortName]map[string]k8sproxy.Endpoint) {
-	for svcPortName, endpoints := range staleEndpoints {
-		bindingProtocol := binding.ProtocolTCP
-		if svcPortName.Protocol == corev1.ProtocolUDP {
-			bindingProtocol = binding.ProtocolUDP
-		} else if svcPortName.Protocol == corev1.ProtocolSCTP {
-			bindingProtocol = binding.ProtocolSCTP
+// installNodeLocalServiceGroup installs the group of a Service whose
+// externalTrafficPolicy is Local. The group only contains the Endpoints running
+// on the current Node, and is used to load-balance the external traffic of the
+// Service. The group of the Service which contains all Endpoints is still used
+// for the traffic sent to the ClusterIP.
+// TODO: Use the node-local group for the ClusterIP traffic as well when the
+// internalTrafficPolicy of the Service is Local. The field was introduced in
+// K8s v1.21 and is not available in the K8s API version we depend on.
+func (p *Proxier) installNodeLocalServiceGroup(svcPortName k8sproxy.ServicePortName, svcInfo *types.ServiceInfo, endpoints []k8sproxy.Endpoint) error {
+	var localEndpoints []k8sproxy.Endpoint
+	for _, endpoint := range endpoints {
+		if endpoint.GetIsLocal() {
+			localEndpoints = append(localEndpoints, endpoint)
 		}
+	}
+	// The group is installed even if there is no local Endpoint, in which
+	// case it has no bucket and the external traffic is dropped.
+	groupID, _ := p.groupCounter.Get(svcPortName, true)
+	return p.ofClient.InstallServiceGroup(groupID, svcInfo.StickyMaxAgeSeconds() != 0, localEndpoints)
+}
+
+// externalGroupID returns the ID of the group used to load-balance the external
+// traffic of a Service, i.e. the traffic sent to its NodePort or ingress IPs.
+func (p *Proxier) externalGroupID(svcPortName k8sproxy.ServicePortName, svcInfo *types.ServiceInfo) binding.GroupIDType {
+	groupID, _ := p.groupCounter.Get(svcPortName, svcInfo.OnlyNodeLocalEndpoints())
+	return groupID
+}
+
+// syncLoadBalancerFlows installs the flows of the ingress IPs assigned to a
+// LoadBalancer Service, and removes the flows of the ingress IPs which are not
+// assigned to it anymore. When the externalTrafficPolicy of the Service is
+// Local, the flows are only installed if an Endpoint of the Service is running
+// on the current Node, otherwise the traffic sent to the ingress IPs is left to
+// the load balancer, which is expected to forward it to the right Nodes.
+func (p *Proxier) syncLoadBalancerFlows(svcPortName k8sproxy.ServicePortName, svcInfo *types.ServiceInfo, endpoints []k8sproxy.Endpoint) error {
+	expectedIPs := sets.NewString()
+	if !svcInfo.OnlyNodeLocalEndpoints() || hasLocalEndpoint(endpoints) {
+		for _, ip := range svcInfo.LoadBalancerIPStrings() {
+			if ip != "" {
+				expectedIPs.Insert(ip)
+			}
+		}
+	}
+	installedIPs, ok := p.loadBalancerIPInstalledMap[svcPortName]
+	if !ok {
+		installedIPs = sets.NewString()
+		p.loadBalancerIPInstalledMap[svcPortName] = installedIPs
+	}
+	for _, ip := range installedIPs.Difference(expectedIPs).UnsortedList() {
+		if err := p.ofClient.UninstallServiceFlows(net.ParseIP(ip), uint16(svcInfo.Port()), svcInfo.OFProtocol); err != nil {
+			return err
+		}
+		installedIPs.Delete(ip)
+	}
+	groupID := p.externalGroupID(svcPortName, svcInfo)
+	for _, ip := range expectedIPs.Difference(installedIPs).UnsortedList() {
+		if err := p.ofClient.InstallServiceFlows(groupID, net.ParseIP(ip), uint16(svcInfo.Port()), svcInfo.OFProtocol, affinityTimeout(svcPortName, svcInfo)); err != nil {
+			return err
+		}
+		installedIPs.Insert(ip)
+	}
+	if installedIPs.Len() == 0 {
+		delete(p.loadBalancerIPInstalledMap, svcPortName)
+	}
+	return nil
+}
+
+// uninstallLoadBalancerFlows removes the flows of all the ingress IPs of a
+// LoadBalancer Service. svcInfo must be the installed ServiceInfo.
+func (p *Proxier) uninstallLoadBalancerFlows(svcPortName k8sproxy.ServicePortName, svcInfo *types.ServiceInfo) error {
+	installedIPs := p.loadBalancerIPInstalledMap[svcPortName]
+	for _, ip := range installedIPs.UnsortedList() {
+		if err := p.ofClient.UninstallServiceFlows(net.ParseIP(ip), uint16(svcInfo.Port()), svcInfo.OFProtocol); err != nil {
+			return err
+		}
+		installedIPs.Delete(ip)
+	}
+	delete(p.loadBalancerIPInstalledMap, svcPortName)
+	return nil
+}
+
+func hasLocalEndpoint(endpoints []k8sproxy.Endpoint) bool {
+	for _, endpoint := range endpoints {
+		if endpoint.GetIsLocal() {
+			return true
+		}
+	}
+	return false
+}
+
+// uninstallNodeLocalServiceGroup removes the node-local group of a Service.
+func (p *Proxier) uninstallNodeLocalServiceGroup(svcPortName k8sproxy.ServicePortName) error {
+	groupID, _ := p.groupCounter.Get(svcPortName, true)
+	if err := p.ofClient.UninstallServiceGroup(groupID); err != nil {
+		return err
+	}
+	p.groupCounter.Recycle(svcPortName, true)
+	return nil
+}
+
+// affinityTimeout returns the ClientIP session affinity timeout of a Service,
+// which is used as the idle timeout of the learned flows. The idle timeout of
+// an OpenFlow flow is a 16-bit value, so larger timeouts are clamped to the
+// maximum value it can hold.
+func affinityTimeout(svcPortName k8sproxy.ServicePortName, svcInfo *types.ServiceInfo) uint16 {
+	timeout := svcInfo.StickyMaxAgeSeconds()
+	if timeout > math.MaxUint16 {
+		klog.Warningf("Session affinity timeout %ds of Service %v exceeds the maximum %ds supported, using %ds instead", timeout, svcPortName, math.MaxUint16, math.MaxUint16)
+		return math.MaxUint16
+	}
+	return uint16(timeout)
+}
+
+// drainingEndpoint is an Endpoint which has been removed from the groups of its
+// Service, but whose flows are kept until the drain period expires, so that
+// the existing connections to it are not interrupted.
+type drainingEndpoint struct {
+	svcPortName k8sproxy.ServicePortName
+	protocol    binding.Protocol
+	endpoint    k8sproxy.Endpoint
+	expiry      time.Time
+}
+
+func drainingEndpointKey(protocol binding.Protocol, endpoint k8sproxy.Endpoint) string {
+	return fmt.Sprintf("%s/%s", endpoint.String(), protocol)
+}
+
+// removeStaleEndpoints removes the stale Endpoints from the installed ones and
+// returns the Services whose groups must be updated, along with the stale
+// Endpoints whose flows must be removed. The flows are removed by
+// installServices once the groups no longer select the Endpoints. If a drain
+// period is configured, the flows of a stale Endpoint are only removed once it
+// expires, see removeDrainedEndpoints.
+func (p *Proxier) removeStaleEndpoints(staleEndpoints map[k8sproxy.ServicePortName]map[string]k8sproxy.Endpoint) map[k8sproxy.ServicePortName][]k8sproxy.Endpoint {
+	staleServices := map[k8sproxy.ServicePortName][]k8sproxy.Endpoint{}
+	for svcPortName, endpoints := range staleEndpoints {
 		for _, endpoint := range endpoints {
-			if err := p.ofClient.UninstallEndpointFlows(bindingProtocol, endpoint); err != nil {
-				klog.Errorf("Error when removing Endpoint %v for %v", endpoint, svcPortName)
-				continue
+			if p.endpointDrainPeriod == 0 {
+				staleServices[svcPortName] = append(staleServices[svcPortName], endpoint)
+			} else {
+				bindingProtocol := types.GetOFProtocol(svcPortName.Protocol, utilnet.IsIPv6String(endpoint.IP()))
+				p.drainingEndpoints[drainingEndpointKey(bindingProtocol, endpoint)] = &drainingEndpoint{
+					svcPortName: svcPortName,
+					protocol:    bindingProtocol,
+					endpoint:    endpoint,
+					expiry:      p.clock.Now().Add(p.endpointDrainPeriod),
+				}
+				if _, ok := staleServices[svcPortName]; !ok {
+					staleServices[svcPortName] = nil
+				}
 			}
 			if m, ok := p.endpointInstalledMap[svcPortName]; ok {
 				delete(m, endpoint.String())
@@ -117,14 +324,61 @@ func (p *Proxier) removeStaleEndpoints(staleEndpoints map[k8sproxy.ServicePortNa
 			}
 		}
 	}
+	return staleServices
 }
 
-func (p *Proxier) installServices() {
+// uninstallStaleEndpoints removes the flows of the stale Endpoints which have
+// not been removed together with the update of the groups of their Services.
+func (p *Proxier) uninstallStaleEndpoints(staleServices map[k8sproxy.ServicePortName][]k8sproxy.Endpoint) {
+	for svcPortName, endpoints := range staleServices {
+		for _, endpoint := range endpoints {
+			bindingProtocol := types.GetOFProtocol(svcPortName.Protocol, utilnet.IsIPv6String(endpoint.IP()))
+			if err := p.ofClient.UninstallEndpointFlows(bindingProtocol, endpoint); err != nil {
+				klog.Errorf("Error when removing Endpoint %v for %v", endpoint, svcPortName)
+			}
+		}
+	}
+}
+
+// removeDrainedEndpoints removes the flows of the stale Endpoints whose drain
+// period has expired. It relies on the periodic sync of the rules, so the
+// flows may be kept a little longer than the drain period.
+func (p *Proxier) removeDrainedEndpoints() {
+	now := p.clock.Now()
+	for key, drainingEndpoint := range p.drainingEndpoints {
+		if now.Before(drainingEndpoint.expiry) {
+			continue
+		}
+		if err := p.ofClient.UninstallEndpointFlows(drainingEndpoint.protocol, drainingEndpoint.endpoint); err != nil {
+			klog.Errorf("Error when removing drained Endpoint %v: %v", drainingEndpoint.endpoint, err)
+			continue
+		}
+		delete(p.drainingEndpoints, key)
+	}
+}
+
+// installServices installs the flows and groups of the Services. The stale
+// Endpoints of a Service whose flows are removed together with the update of
+// its group are deleted from staleServices.
+func (p *Proxier) installServices(staleServices map[k8sproxy.ServicePortName][]k8sproxy.Endpoint) {
 	for svcPortName, svcPort := range p.serviceMap {
 		svcInfo := svcPort.(*types.ServiceInfo)
-		groupID, _ := p.groupCounter.Get(svcPortName)
+		groupID, _ := p.groupCounter.Get(svcPortName, false)
+		removedEndpoints, hasStaleEndpoints := staleServices[svcPortName]
 		endpoints, ok := p.endpointsMap[svcPortName]
 		if !ok || len(endpoints) == 0 {
+			// Stop directing new connections to the removed Endpoints.
+			if _, installed := p.serviceInstalledMap[svcPortName]; installed && hasStaleEndpoints {
+				if err := p.ofClient.InstallServiceGroup(groupID, svcInfo.StickyMaxAgeSeconds() != 0, nil); err != nil {
+					klog.Errorf("Error when removing Endpoints from groups of Service %v: %v", svcPortName, err)
+					continue
+				}
+				if svcInfo.OnlyNodeLocalEndpoints() {
+					if err := p.installNodeLocalServiceGroup(svcPortName, svcInfo, nil); err != nil {
+						klog.Errorf("Error when removing Endpoints from node-local group of Service %v: %v", svcPortName, err)
+					}
+				}
+			}
 			continue
 		}
 
@@ -135,18 +389,82 @@ func (p *Proxier) installServices() {
 		}
 
 		installedSvcPort, ok := p.serviceInstalledMap[svcPortName]
-		needUpdate := !ok || !installedSvcPort.(*types.ServiceInfo).Equal(svcInfo)
+		// The groups must be updated when Endpoints have been removed, so that
+		// no new connection is directed to them.
+		needUpdate := !ok || !installedSvcPort.(*types.ServiceInfo).Equal(svcInfo) || hasStaleEndpoints
 
 		var endpointUpdateList []k8sproxy.Endpoint
 		for _, endpoint := range endpoints {
 			if _, ok := endpointInstalled[endpoint.String()]; !ok {
 				needUpdate = true
 				endpointInstalled[endpoint.String()] = struct{}{}
+				if endpoint.GetIsLocal() && utilnet.IsIPv6String(endpoint.IP()) {
+					klog.Infof("Hairpin traffic is not supported for IPv6 Endpoints, local Endpoint %s cannot reach itself through Service %v", endpoint.String(), svcPortName)
+				}
+				// The Endpoint may have come back before its drain period
+				// expired, its flows must be kept.
+				delete(p.drainingEndpoints, drainingEndpointKey(svcInfo.OFProtocol, endpoint))
 			}
 			endpointUpdateList = append(endpointUpdateList, endpoint)
 		}
+		// The unhealthy Endpoints keep their flows, but are left out of the
+		// groups until they respond again.
+		groupEndpoints, unhealthyEndpoints := p.healthyEndpoints(svcInfo, endpointUpdateList)
+		if !unhealthyEndpoints.Equal(p.unhealthyEndpointsInstalled[svcPortName]) {
+			needUpdate = true
+		}
+		// Remove the flows of the previous NodePort if it has been changed or
+		// removed, or if they must be installed again below: with the group
+		// used for the external traffic if the externalTrafficPolicy of the
+		// Service has been changed, or with the new protocol or session
+		// affinity timeout. They are removed with the installed ServiceInfo,
+		// which they were installed with.
+		if ok && installedSvcPort.NodePort() != 0 {
+			installedSvcInfo := installedSvcPort.(*types.ServiceInfo)
+			if installedSvcInfo.NodePort() != svcInfo.NodePort() ||
+				installedSvcInfo.OFProtocol != svcInfo.OFProtocol ||
+				installedSvcInfo.OnlyNodeLocalEndpoints() != svcInfo.OnlyNodeLocalEndpoints() ||
+				installedSvcInfo.StickyMaxAgeSeconds() != svcInfo.StickyMaxAgeSeconds() {
+				if err := p.ofClient.UninstallNodePortFlows(uint16(installedSvcInfo.NodePort()), installedSvcInfo.OFProtocol); err != nil {
+					klog.Errorf("Error when removing NodePort flows of Service %v: %v", svcPortName, err)
+					continue
+				}
+			}
+		}
+		// The session affinity timeout is encoded in the learn action of the
+		// Service flows, which must be removed so that they can be installed
+		// again with the new timeout.
+		if ok && installedSvcPort.StickyMaxAgeSeconds() != svcInfo.StickyMaxAgeSeconds() {
+			if err := p.ofClient.UninstallServiceFlows(installedSvcPort.ClusterIP(), uint16(installedSvcPort.Port()), svcInfo.OFProtocol); err != nil {
+				klog.Errorf("Error when removing flows of Service %v: %v", svcPortName, err)
+				continue
+			}
+		}
+
+		// The flows of the ingress IPs are installed again below with the
+		// current ServiceInfo.
+		if ok && !installedSvcPort.(*types.ServiceInfo).Equal(svcInfo) {
+			if err := p.uninstallLoadBalancerFlows(svcPortName, installedSvcPort.(*types.ServiceInfo)); err != nil {
+				klog.Errorf("Error when removing LoadBalancer flows of Service %v: %v", svcPortName, err)
+				continue
+			}
+		}
+		// The node-local group is not used anymore if the externalTrafficPolicy
+		// of the Service has been changed to Cluster. It is removed after the
+		// flows referencing it, as OVS would remove them together with it.
+		if ok && installedSvcPort.(*types.ServiceInfo).OnlyNodeLocalEndpoints() && !svcInfo.OnlyNodeLocalEndpoints() {
+			if err := p.uninstallNodeLocalServiceGroup(svcPortName); err != nil {
+				klog.Errorf("Error when removing node-local group of Service %v: %v", svcPortName, err)
+				continue
+			}
+		}
 
 		if !needUpdate {
+			// Whether the flows of the ingress IPs are installed may depend on
+			// the local Endpoints, which can change without a new Endpoint.
+			if err := p.syncLoadBalancerFlows(svcPortName, svcInfo, endpointUpdateList); err != nil {
+				klog.Errorf("Error when syncing LoadBalancer flows of Service %v: %v", svcPortName, err)
+			}
 			continue
 		}
 
@@ -154,20 +472,100 @@ func (p *Proxier) installServices() {
 			klog.Errorf("Error when installing Endpoints flows: %v", err)
 			continue
 		}
-		err := p.ofClient.InstallServiceGroup(groupID, svcInfo.StickyMaxAgeSeconds() != 0, endpointUpdateList)
-		if err != nil {
-			klog.Errorf("Error when installing Endpoints groups: %v", err)
-			p.endpointInstalledMap[svcPortName] = nil
-			continue
+		if svcInfo.OnlyNodeLocalEndpoints() {
+			if err := p.installNodeLocalServiceGroup(svcPortName, svcInfo, groupEndpoints); err != nil {
+				klog.Errorf("Error when installing node-local Endpoints groups: %v", err)
+				p.endpointInstalledMap[svcPortName] = nil
+				continue
+			}
+		}
+		if ok && len(removedEndpoints) > 0 {
+			// Swap the Endpoints of the group and remove the flows of the
+			// stale Endpoints atomically, so that no connection is sent to
+			// an Endpoint without flows.
+			err := p.ofClient.UpdateServiceEndpoints(groupID, svcInfo.StickyMaxAgeSeconds() != 0, svcInfo.OFProtocol, groupEndpoints, removedEndpoints)
+			if err != nil {
+				klog.Errorf("Error when updating Endpoints groups: %v", err)
+				p.endpointInstalledMap[svcPortName] = nil
+				continue
+			}
+			delete(staleServices, svcPortName)
+		} else {
+			err := p.ofClient.InstallServiceGroup(groupID, svcInfo.StickyMaxAgeSeconds() != 0, groupEndpoints)
+			if err != nil {
+				klog.Errorf("Error when installing Endpoints groups: %v", err)
+				p.endpointInstalledMap[svcPortName] = nil
+				continue
+			}
 		}
-		if err := p.ofClient.InstallServiceFlows(groupID, svcInfo.ClusterIP(), uint16(svcInfo.Port()), svcInfo.OFProtocol, uint16(svcInfo.StickyMaxAgeSeconds())); err != nil {
+		if unhealthyEndpoints.Len() > 0 {
+			p.unhealthyEndpointsInstalled[svcPortName] = unhealthyEndpoints
+		} else {
+			delete(p.unhealthyEndpointsInstalled, svcPortName)
+		}
+		if err := p.ofClient.InstallServiceFlows(groupID, svcInfo.ClusterIP(), uint16(svcInfo.Port()), svcInfo.OFProtocol, affinityTimeout(svcPortName, svcInfo)); err != nil {
 			klog.Errorf("Error when installing Service flows: %v", err)
 			continue
 		}
+		// AntreaProxy does not serve the NodePort of IPv6 Services, as the
+		// NodePort flows match the IPv4 address of the Node, which is the only
+		// one in the NodeConfig. Their NodePort traffic is left to kube-proxy.
+		if svcInfo.NodePort() != 0 && svcInfo.ClusterIP().To4() == nil {
+			if !ok || installedSvcPort.NodePort() != svcInfo.NodePort() {
+				klog.Warningf("NodePort %d of IPv6 Service %v is not supported by AntreaProxy, it must be served by kube-proxy", svcInfo.NodePort(), svcPortName)
+			}
+		} else if svcInfo.NodePort() != 0 {
+			if err := p.ofClient.InstallNodePortFlows(p.externalGroupID(svcPortName, svcInfo), uint16(svcInfo.NodePort()), svcInfo.OFProtocol, affinityTimeout(svcPortName, svcInfo)); err != nil {
+				klog.Errorf("Error when installing NodePort flows: %v", err)
+				continue
+			}
+		}
+		if err := p.syncLoadBalancerFlows(svcPortName, svcInfo, endpointUpdateList); err != nil {
+			klog.Errorf("Error when installing LoadBalancer flows: %v", err)
+			continue
+		}
 		p.serviceInstalledMap[svcPortName] = svcPort
 	}
 }
 
+// healthyEndpoints returns the Endpoints of the Service port to add to its
+// groups, and the unhealthy Endpoints which are left out of them. If none of
+// the Endpoints is healthy, they are all kept, so that the Service is not made
+// unreachable by a failure of the probes themselves.
+func (p *Proxier) healthyEndpoints(svcInfo *types.ServiceInfo, endpoints []k8sproxy.Endpoint) ([]k8sproxy.Endpoint, sets.String) {
+	unhealthy := sets.NewString()
+	if p.endpointHealthChecker == nil || !svcInfo.EndpointHealthCheck {
+		return endpoints, unhealthy
+	}
+	var healthy []k8sproxy.Endpoint
+	for _, endpoint := range endpoints {
+		if p.endpointHealthChecker.isHealthy(endpoint.String()) {
+			healthy = append(healthy, endpoint)
+		} else {
+			unhealthy.Insert(endpoint.String())
+		}
+	}
+	if len(healthy) == 0 {
+		return endpoints, sets.NewString()
+	}
+	return healthy, unhealthy
+}
+
+// endpointHealthCheckTargets returns the addresses of the Endpoints of the
+// Service ports whose Endpoints must be health-checked.
+func (p *Proxier) endpointHealthCheckTargets() sets.String {
+	targets := sets.NewString()
+	for svcPortName, svcPort := range p.serviceMap {
+		if !svcPort.(*types.ServiceInfo).EndpointHealthCheck {
+			continue
+		}
+		for _, endpoint := range p.endpointsMap[svcPortName] {
+			targets.Insert(endpoint.String())
+		}
+	}
+	return targets
+}
+
 // syncProxyRulesMutex applies current changes in change trackers and then updates
 // flows for services and endpoints. It will abort if either endpoints or services
 // resources is not synced.
@@ -184,12 +582,192 @@ func (p *Proxier) syncProxyRules() {
 		return
 	}
 
+	// The changes are dequeued from the trackers here, the sync duration of
+	// each change is measured from this point.
+	syncStart := p.clock.Now()
+	oldServiceMap := make(k8sproxy.ServiceMap, len(p.serviceMap))
+	for svcPortName, svcPort := range p.serviceMap {
+		oldServiceMap[svcPortName] = svcPort
+	}
+	oldEndpointsMap := make(types.EndpointsMap, len(p.endpointsMap))
+	for svcPortName, endpoints := range p.endpointsMap {
+		oldEndpointsMap[svcPortName] = endpoints
+	}
 	staleEndpoints := p.endpointsChanges.Update(p.endpointsMap)
-	p.serviceChanges.Update(p.serviceMap)
+	serviceUpdateResult := p.serviceChanges.Update(p.serviceMap)
 
-	p.removeStaleEndpoints(staleEndpoints)
+	staleServices := p.removeStaleEndpoints(staleEndpoints)
+	if p.livenessPorts != nil {
+		// Bring down the ports of the removed Endpoints before updating the
+		// groups, so that OVS stops selecting them right away.
+		p.livenessPorts.sync(endpointIPs(p.endpointsMap))
+	}
+	if p.endpointHealthChecker != nil {
+		p.endpointHealthChecker.setTargets(p.endpointHealthCheckTargets())
+	}
 	p.removeStaleServices()
-	p.installServices()
+	p.installServices(staleServices)
+	p.uninstallStaleEndpoints(staleServices)
+	p.removeDrainedEndpoints()
+
+	// The flows have been installed or have failed to be installed, the
+	// failed ones are retried by a later sync.
+	syncDuration := p.clock.Since(syncStart).Seconds()
+	for _, event := range serviceSyncEvents(oldServiceMap, p.serviceMap) {
+		metrics.ProxyServiceSyncDuration.WithLabelValues(event.eventType, string(event.serviceType)).Observe(syncDuration)
+	}
+	for _, event := range endpointsSyncEvents(oldEndpointsMap, p.endpointsMap, oldServiceMap, p.serviceMap) {
+		metrics.ProxyEndpointSyncDuration.WithLabelValues(event.eventType, string(event.serviceType)).Observe(syncDuration)
+	}
+
+	if p.serviceHealthServer != nil {
+		if err := p.serviceHealthServer.SyncServices(serviceUpdateResult.HCServiceNodePorts); err != nil {
+			klog.Errorf("Error when syncing health check Services: %v", err)
+		}
+		if err := p.serviceHealthServer.SyncEndpoints(p.localEndpointsCount()); err != nil {
+			klog.Errorf("Error when syncing health check Endpoints: %v", err)
+		}
+	}
+	p.syncedOnce = true
+}
+
+// Event types of the Service and Endpoints changes, used as labels of the sync
+// duration metrics.
+const (
+	syncEventAdd    = "add"
+	syncEventUpdate = "update"
+	syncEventDelete = "delete"
+)
+
+type syncEvent struct {
+	eventType   string
+	serviceType corev1.ServiceType
+}
+
+// serviceSyncEvents returns the events of the Service ports which have been
+// added, updated or deleted between oldServiceMap and newServiceMap. The
+// ServicePorts of a Service are replaced whenever it changes.
+func serviceSyncEvents(oldServiceMap, newServiceMap k8sproxy.ServiceMap) []syncEvent {
+	var events []syncEvent
+	for svcPortName, svcPort := range newServiceMap {
+		serviceType := svcPort.(*types.ServiceInfo).ServiceType
+		if oldSvcPort, ok := oldServiceMap[svcPortName]; !ok {
+			events = append(events, syncEvent{syncEventAdd, serviceType})
+		} else if oldSvcPort != svcPort {
+			events = append(events, syncEvent{syncEventUpdate, serviceType})
+		}
+	}
+	for svcPortName, svcPort := range oldServiceMap {
+		if _, ok := newServiceMap[svcPortName]; !ok {
+			events = append(events, syncEvent{syncEventDelete, svcPort.(*types.ServiceInfo).ServiceType})
+		}
+	}
+	return events
+}
+
+// endpointsSyncEvents returns the events of the Endpoints of the Service ports
+// which have been added, updated or deleted between oldEndpointsMap and
+// newEndpointsMap. The Endpoints of unknown Service ports are ignored, as no
+// flow is installed for them.
+func endpointsSyncEvents(oldEndpointsMap, newEndpointsMap types.EndpointsMap, oldServiceMap, newServiceMap k8sproxy.ServiceMap) []syncEvent {
+	serviceType := func(svcPortName k8sproxy.ServicePortName) (corev1.ServiceType, bool) {
+		svcPort, ok := newServiceMap[svcPortName]
+		if !ok {
+			svcPort, ok = oldServiceMap[svcPortName]
+		}
+		if !ok {
+			return "", false
+		}
+		return svcPort.(*types.ServiceInfo).ServiceType, true
+	}
+	endpointKeys := func(endpoints map[string]k8sproxy.Endpoint) sets.String {
+		keys := sets.NewString()
+		for key := range endpoints {
+			keys.Insert(key)
+		}
+		return keys
+	}
+	var events []syncEvent
+	for svcPortName, endpoints := range newEndpointsMap {
+		svcType, ok := serviceType(svcPortName)
+		if !ok {
+			continue
+		}
+		if oldEndpoints, ok := oldEndpointsMap[svcPortName]; !ok {
+			events = append(events, syncEvent{syncEventAdd, svcType})
+		} else if !endpointKeys(oldEndpoints).Equal(endpointKeys(endpoints)) {
+			events = append(events, syncEvent{syncEventUpdate, svcType})
+		}
+	}
+	for svcPortName := range oldEndpointsMap {
+		if _, ok := newEndpointsMap[svcPortName]; ok {
+			continue
+		}
+		if svcType, ok := serviceType(svcPortName); ok {
+			events = append(events, syncEvent{syncEventDelete, svcType})
+		}
+	}
+	return events
+}
+
+// SyncedOnce returns true if the flows of all Services known at startup have
+// been installed at least once.
+func (p *Proxier) SyncedOnce() bool {
+	p.syncProxyRulesMutex.Lock()
+	defer p.syncProxyRulesMutex.Unlock()
+	return p.syncedOnce
+}
+
+// localEndpointsCount returns the number of Endpoints running on the current
+// Node for each Service which has a health check NodePort.
+func (p *Proxier) localEndpointsCount() map[apimachinerytypes.NamespacedName]int {
+	counts := map[apimachinerytypes.NamespacedName]int{}
+	for svcPortName, svcPort := range p.serviceMap {
+		if svcPort.HealthCheckNodePort() == 0 {
+			continue
+		}
+		localIPs := sets.NewString()
+		for _, endpoint := range p.endpointsMap[svcPortName] {
+			if endpoint.GetIsLocal() {
+				localIPs.Insert(endpoint.IP())
+			}
+		}
+		if localIPs.Len() > counts[svcPortName.NamespacedName] {
+			counts[svcPortName.NamespacedName] = localIPs.Len()
+		}
+	}
+	return counts
+}
+
+func (p *Proxier) GetServiceEndpoints(namespace, name string, protocol corev1.Protocol, port int32) (*types.ServiceInfo, []k8sproxy.Endpoint, error) {
+	p.syncProxyRulesMutex.Lock()
+	defer p.syncProxyRulesMutex.Unlock()
+
+	var svcPortName k8sproxy.ServicePortName
+	var svcInfo *types.ServiceInfo
+	for spn, svcPort := range p.serviceMap {
+		if spn.Namespace != namespace || spn.Name != name || spn.Protocol != protocol {
+			continue
+		}
+		if port != 0 && svcPort.Port() != int(port) {
+			continue
+		}
+		if svcInfo != nil {
+			return nil, nil, fmt.Errorf("Service %s/%s has multiple %s ports, the port must be specified", namespace, name, protocol)
+		}
+		svcPortName, svcInfo = spn, svcPort.(*types.ServiceInfo)
+	}
+	if svcInfo == nil {
+		if port != 0 {
+			return nil, nil, fmt.Errorf("Service %s/%s has no %s port %d", namespace, name, protocol, port)
+		}
+		return nil, nil, fmt.Errorf("Service %s/%s has no %s port", namespace, name, protocol)
+	}
+	var endpoints []k8sproxy.Endpoint
+	for _, endpoint := range p.endpointsMap[svcPortName] {
+		endpoints = append(endpoints, endpoint)
+	}
+	return svcInfo, endpoints, nil
 }
 
 func (p *Proxier) SyncLoop() {
@@ -217,6 +795,31 @@ func (p *Proxier) OnEndpointsSynced() {
 	}
 }
 
+func (p *Proxier) OnEndpointSliceAdd(endpointSlice *discovery.EndpointSlice) {
+	if p.endpointsChanges.OnEndpointSliceUpdate(endpointSlice, false) && p.isInitialized() {
+		p.runner.Run()
+	}
+}
+
+func (p *Proxier) OnEndpointSliceUpdate(oldEndpointSlice, newEndpointSlice *discovery.EndpointSlice) {
+	if p.endpointsChanges.OnEndpointSliceUpdate(newEndpointSlice, false) && p.isInitialized() {
+		p.runner.Run()
+	}
+}
+
+func (p *Proxier) OnEndpointSliceDelete(endpointSlice *discovery.EndpointSlice) {
+	if p.endpointsChanges.OnEndpointSliceUpdate(endpointSlice, true) && p.isInitialized() {
+		p.runner.Run()
+	}
+}
+
+func (p *Proxier) OnEndpointSlicesSynced() {
+	p.endpointsChanges.OnEndpointsSynced()
+	if p.isInitialized() {
+		p.runner.Run()
+	}
+}
+
 func (p *Proxier) OnServiceAdd(service *corev1.Service) {
 	p.OnServiceUpdate(nil, service)
 }
@@ -241,31 +844,73 @@ func (p *Proxier) OnServiceSynced() {
 func (p *Proxier) Run(stopCh <-chan struct{}) {
 	p.once.Do(func() {
 		go p.serviceConfig.Run(stopCh)
-		go p.endpointsConfig.Run(stopCh)
+		if p.endpointSliceConfig != nil {
+			go p.endpointSliceConfig.Run(stopCh)
+		} else {
+			go p.endpointsConfig.Run(stopCh)
+		}
 		p.stopChan = stopCh
+		if p.endpointHealthChecker != nil {
+			go p.endpointHealthChecker.Run(stopCh)
+		}
 		p.SyncLoop()
 	})
 }
 
-func New(hostname string, informerFactory informers.SharedInformerFactory, ofClient openflow.Client) *Proxier {
+// EnableFastFailoverGroups makes the Proxier program fast-failover groups
+// instead of select groups for the Services. Each Endpoint IP gets an OVS port
+// watched by its buckets, which is brought down when the Endpoint is removed.
+// It must be called before Run.
+func (p *Proxier) EnableFastFailoverGroups(ovsBridgeClient ovsconfig.OVSBridgeClient, ovsCtlClient ovsctl.OVSCtlClient) {
+	p.livenessPorts = newLivenessPortManager(ovsBridgeClient, ovsCtlClient)
+	p.ofClient.EnableFastFailoverGroups(p.livenessPorts.watchPort)
+}
+
+// EnableEndpointHealthCheck makes the Proxier probe the Endpoints of the
+// Services annotated with antrea.io/endpoint-healthcheck: "true" with TCP
+// connections from sourceIP, which should be the IP of the gateway so that the
+// probes go through the same path as the Pod traffic. The Endpoints which do
+// not respond are removed from the groups of the Services until they respond
+// again. It must be called before Run.
+func (p *Proxier) EnableEndpointHealthCheck(sourceIP net.IP) {
+	p.endpointHealthChecker = newEndpointHealthChecker(tcpProber(sourceIP), p.runner.Run)
+}
+
+// New returns a new Proxier. If enableEndpointSlice is true, the Endpoints of
+// the Services are tracked from the EndpointSlice resource instead of the
+// Endpoints resource, which is not watched then to avoid programming the same
+// Endpoints twice. The flows of a removed Endpoint are kept for
+// endpointDrainPeriod after it has been removed from the groups of its Service.
+func New(hostname string, informerFactory informers.SharedInformerFactory, ofClient openflow.Client, enableEndpointSlice bool, endpointDrainPeriod time.Duration) *Proxier {
 	recorder := record.NewBroadcaster().NewRecorder(
 		runtime.NewScheme(),
 		corev1.EventSource{Component: componentName, Host: hostname},
 	)
 	p := &Proxier{
-		endpointsConfig:      config.NewEndpointsConfig(informerFactory.Core().V1().Endpoints(), resyncPeriod),
-		serviceConfig:        config.NewServiceConfig(informerFactory.Core().V1().Services(), resyncPeriod),
-		endpointsChanges:     newEndpointsChangesTracker(hostname),
-		serviceChanges:       newServiceChangesTracker(recorder),
-		serviceMap:           k8sproxy.ServiceMap{},
-		serviceInstalledMap:  k8sproxy.ServiceMap{},
-		endpointInstalledMap: map[k8sproxy.ServicePortName]map[string]struct{}{},
-		endpointsMap:         types.EndpointsMap{},
-		groupCounter:         types.NewGroupCounter(),
-		ofClient:             ofClient,
+		serviceConfig:               config.NewServiceConfig(informerFactory.Core().V1().Services(), resyncPeriod),
+		endpointsChanges:            newEndpointsChangesTracker(hostname, enableEndpointSlice),
+		serviceChanges:              newServiceChangesTracker(recorder),
+		serviceMap:                  k8sproxy.ServiceMap{},
+		serviceInstalledMap:         k8sproxy.ServiceMap{},
+		endpointInstalledMap:        map[k8sproxy.ServicePortName]map[string]struct{}{},
+		loadBalancerIPInstalledMap:  map[k8sproxy.ServicePortName]sets.String{},
+		endpointsMap:                types.EndpointsMap{},
+		groupCounter:                types.NewGroupCounter(),
+		endpointDrainPeriod:         endpointDrainPeriod,
+		drainingEndpoints:           map[string]*drainingEndpoint{},
+		unhealthyEndpointsInstalled: map[k8sproxy.ServicePortName]sets.String{},
+		clock:                       clock.RealClock{},
+		serviceHealthServer:         healthcheck.NewServiceHealthServer(),
+		ofClient:                    ofClient,
 	}
 	p.serviceConfig.RegisterEventHandler(p)
-	p.endpointsConfig.RegisterEventHandler(p)
+	if enableEndpointSlice {
+		p.endpointSliceConfig = config.NewEndpointSliceConfig(informerFactory.Discovery().V1beta1().EndpointSlices(), resyncPeriod)
+		p.endpointSliceConfig.RegisterEventHandler(p)
+	} else {
+		p.endpointsConfig = config.NewEndpointsConfig(informerFactory.Core().V1().Endpoints(), resyncPeriod)
+		p.endpointsConfig.RegisterEventHandler(p)
+	}
 	p.runner = k8sproxy.NewBoundedFrequencyRunner(componentName, p.syncProxyRules, 0, 30*time.Second, -1)
 	return p
 }
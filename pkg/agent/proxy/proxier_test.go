@@ -16,16 +16,26 @@ package proxy
 
 import (
 	"fmt"
+	"math"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	apimachinerytypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/record"
+	k8smetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
 
+	"github.com/vmware-tanzu/antrea/pkg/agent/metrics"
 	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
 	ofmock "github.com/vmware-tanzu/antrea/pkg/agent/openflow/testing"
 	"github.com/vmware-tanzu/antrea/pkg/agent/proxy/types"
@@ -77,33 +87,631 @@ func makeTestEndpoints(namespace, name string, eptFunc func(*corev1.Endpoints))
 }
 
 func NewFakeProxier(ofClient openflow.Client) *Proxier {
+	return newFakeProxier(ofClient, false)
+}
+
+func newFakeProxier(ofClient openflow.Client, enableEndpointSlice bool) *Proxier {
 	hostname := "localhost"
 	eventBroadcaster := record.NewBroadcaster()
 	recorder := eventBroadcaster.NewRecorder(
 		runtime.NewScheme(),
 		corev1.EventSource{Component: componentName, Host: hostname},
 	)
-	p := &Proxier{
-		endpointsChanges:     newEndpointsChangesTracker(hostname),
-		serviceChanges:       newServiceChangesTracker(recorder),
-		serviceMap:           k8sproxy.ServiceMap{},
-		serviceInstalledMap:  k8sproxy.ServiceMap{},
-		endpointInstalledMap: map[k8sproxy.ServicePortName]map[string]struct{}{},
-		endpointsMap:         types.EndpointsMap{},
-		groupCounter:         types.NewGroupCounter(),
-		ofClient:             ofClient,
-	}
-	return p
+	p := &Proxier{
+		endpointsChanges:            newEndpointsChangesTracker(hostname, enableEndpointSlice),
+		serviceChanges:              newServiceChangesTracker(recorder),
+		serviceMap:                  k8sproxy.ServiceMap{},
+		serviceInstalledMap:         k8sproxy.ServiceMap{},
+		endpointInstalledMap:        map[k8sproxy.ServicePortName]map[string]struct{}{},
+		loadBalancerIPInstalledMap:  map[k8sproxy.ServicePortName]sets.String{},
+		endpointsMap:                types.EndpointsMap{},
+		groupCounter:                types.NewGroupCounter(),
+		drainingEndpoints:           map[string]*drainingEndpoint{},
+		unhealthyEndpointsInstalled: map[k8sproxy.ServicePortName]sets.String{},
+		clock:                       clock.NewFakeClock(time.Now()),
+		ofClient:                    ofClient,
+	}
+	return p
+}
+
+func TestClusterIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+
+	svcIPv4 := net.ParseIP("10.20.30.41")
+	svcPort := 80
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	makeServiceMap(fp,
+		makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
+			svc.Spec.ClusterIP = svcIPv4.String()
+			svc.Spec.Ports = []corev1.ServicePort{{
+				Name:     svcPortName.Port,
+				Port:     int32(svcPort),
+				Protocol: corev1.ProtocolTCP,
+			}}
+		}),
+	)
+
+	epIP := net.ParseIP("10.180.0.1")
+	makeEndpointsMap(fp,
+		makeTestEndpoints(svcPortName.Namespace, svcPortName.Name, func(ept *corev1.Endpoints) {
+			ept.Subsets = []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{{
+					IP: epIP.String(),
+				}},
+				Ports: []corev1.EndpointPort{{
+					Name:     svcPortName.Port,
+					Port:     int32(svcPort),
+					Protocol: corev1.ProtocolTCP,
+				}},
+			}}
+		}),
+	)
+
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+
+	fp.syncProxyRules()
+}
+
+func TestEndpointHealthCheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+	prober := &fakeProber{failing: sets.NewString()}
+	fp.endpointHealthChecker = newEndpointHealthChecker(prober.probe, func() {})
+
+	svcIPv4 := net.ParseIP("10.20.30.41")
+	svcPort := 80
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	makeServiceMap(fp,
+		makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
+			svc.Annotations[types.EndpointHealthCheckAnnotationKey] = "true"
+			svc.Spec.ClusterIP = svcIPv4.String()
+			svc.Spec.Ports = []corev1.ServicePort{{
+				Name:     svcPortName.Port,
+				Port:     int32(svcPort),
+				Protocol: corev1.ProtocolTCP,
+			}}
+		}),
+	)
+	makeEndpointsMap(fp,
+		makeTestEndpoints(svcPortName.Namespace, svcPortName.Name, func(ept *corev1.Endpoints) {
+			ept.Subsets = []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{{IP: "10.180.0.1"}, {IP: "10.180.0.2"}},
+				Ports: []corev1.EndpointPort{{
+					Name:     svcPortName.Port,
+					Port:     int32(svcPort),
+					Protocol: corev1.ProtocolTCP,
+				}},
+			}}
+		}),
+	)
+
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	var groupEndpoints []string
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).DoAndReturn(
+		func(_ binding.GroupIDType, _ bool, endpoints []k8sproxy.Endpoint) error {
+			groupEndpoints = nil
+			for _, endpoint := range endpoints {
+				groupEndpoints = append(groupEndpoints, endpoint.String())
+			}
+			return nil
+		}).Times(3)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(3)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(3)
+
+	fp.syncProxyRules()
+	assert.ElementsMatch(t, []string{"10.180.0.1:80", "10.180.0.2:80"}, groupEndpoints)
+	assert.Equal(t, sets.NewString("10.180.0.1:80", "10.180.0.2:80"), fp.endpointHealthChecker.targets)
+
+	// The unresponsive Endpoint is removed from the group.
+	prober.failing.Insert("10.180.0.2:80")
+	fp.endpointHealthChecker.probeAll()
+	fp.syncProxyRules()
+	assert.Equal(t, []string{"10.180.0.1:80"}, groupEndpoints)
+
+	// Nothing is updated while the health does not change.
+	fp.endpointHealthChecker.probeAll()
+	fp.syncProxyRules()
+
+	// The Endpoint is added back once it responds again.
+	prober.failing.Delete("10.180.0.2:80")
+	fp.endpointHealthChecker.probeAll()
+	fp.syncProxyRules()
+	assert.ElementsMatch(t, []string{"10.180.0.1:80", "10.180.0.2:80"}, groupEndpoints)
+}
+
+func TestSyncedOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+
+	makeServiceMap(fp)
+	fp.syncProxyRules()
+	assert.False(t, fp.SyncedOnce(), "Proxier should not be synced before Endpoints are synced")
+
+	makeEndpointsMap(fp)
+	fp.syncProxyRules()
+	assert.True(t, fp.SyncedOnce(), "Proxier should be synced once Services and Endpoints are synced")
+}
+
+func TestClusterIPSCTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+
+	svcIPv4 := net.ParseIP("10.20.30.41")
+	svcPort := 80
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolSCTP,
+	}
+	makeServiceMap(fp,
+		makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
+			svc.Spec.ClusterIP = svcIPv4.String()
+			svc.Spec.Ports = []corev1.ServicePort{{
+				Name:     svcPortName.Port,
+				Port:     int32(svcPort),
+				Protocol: corev1.ProtocolSCTP,
+			}}
+		}),
+	)
+
+	epIP := net.ParseIP("10.180.0.1")
+	makeEndpointsMap(fp,
+		makeTestEndpoints(svcPortName.Namespace, svcPortName.Name, func(ept *corev1.Endpoints) {
+			ept.Subsets = []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{{
+					IP: epIP.String(),
+				}},
+				Ports: []corev1.EndpointPort{{
+					Name:     svcPortName.Port,
+					Port:     int32(svcPort),
+					Protocol: corev1.ProtocolSCTP,
+				}},
+			}}
+		}),
+	)
+
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolSCTP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolSCTP, uint16(0)).Times(1)
+
+	fp.syncProxyRules()
+}
+
+func TestClusterIPWithEndpointSlice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := newFakeProxier(mockOFClient, true)
+
+	svcIPv4 := net.ParseIP("10.20.30.41")
+	svcPort := 80
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "http",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	makeServiceMap(fp,
+		makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
+			svc.Spec.ClusterIP = svcIPv4.String()
+			svc.Spec.Ports = []corev1.ServicePort{{
+				Name:     svcPortName.Port,
+				Port:     int32(svcPort),
+				Protocol: corev1.ProtocolTCP,
+			}}
+		}),
+	)
+
+	slice1 := makeTestEndpointSlice(svcPortName.Namespace, svcPortName.Name, "svc1-a", endpointSliceControllerName, int32(svcPort), map[string]string{"10.180.0.1": "localhost"})
+	slice2 := makeTestEndpointSlice(svcPortName.Namespace, svcPortName.Name, "svc1-b", endpointSliceControllerName, int32(svcPort), map[string]string{"10.180.1.1": "remote"})
+	fp.endpointsChanges.OnEndpointSliceUpdate(slice1, false)
+	fp.endpointsChanges.OnEndpointSliceUpdate(slice2, false)
+	fp.endpointsChanges.OnEndpointsSynced()
+
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Do(
+		func(_ binding.GroupIDType, _ bool, endpoints []k8sproxy.Endpoint) {
+			assert.Len(t, endpoints, 2)
+		}).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.syncProxyRules()
+
+	// Removing an EndpointSlice only removes its own Endpoints.
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().UpdateServiceEndpoints(groupID, false, binding.ProtocolTCP, gomock.Any(), gomock.Any()).Do(
+		func(_ binding.GroupIDType, _ bool, _ binding.Protocol, endpoints, removedEndpoints []k8sproxy.Endpoint) {
+			require.Len(t, endpoints, 1)
+			assert.Equal(t, "10.180.0.1", endpoints[0].IP())
+			require.Len(t, removedEndpoints, 1)
+			assert.Equal(t, "10.180.1.1", removedEndpoints[0].IP())
+		}).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.endpointsChanges.OnEndpointSliceUpdate(slice2, true)
+	fp.syncProxyRules()
+}
+
+func TestClusterIPv6(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+
+	svcIPv6 := net.ParseIP("10:20::41")
+	svcPort := 80
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	makeServiceMap(fp,
+		makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
+			svc.Spec.ClusterIP = svcIPv6.String()
+			svc.Spec.Ports = []corev1.ServicePort{{
+				Name:     svcPortName.Port,
+				Port:     int32(svcPort),
+				Protocol: corev1.ProtocolTCP,
+			}}
+		}),
+	)
+
+	epIP := net.ParseIP("10:180::1")
+	makeEndpointsMap(fp,
+		makeTestEndpoints(svcPortName.Namespace, svcPortName.Name, func(ept *corev1.Endpoints) {
+			ept.Subsets = []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{{
+					IP: epIP.String(),
+				}},
+				Ports: []corev1.EndpointPort{{
+					Name:     svcPortName.Port,
+					Port:     int32(svcPort),
+					Protocol: corev1.ProtocolTCP,
+				}},
+			}}
+		}),
+	)
+
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCPv6, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv6, uint16(svcPort), binding.ProtocolTCPv6, uint16(0)).Times(1)
+
+	fp.syncProxyRules()
+}
+
+func TestExternalTrafficPolicyLocal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+
+	svcIPv4 := net.ParseIP("10.20.30.41")
+	svcPort := 80
+	svcNodePort := 30080
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	makeServiceMap(fp,
+		makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
+			svc.Spec.Type = corev1.ServiceTypeNodePort
+			svc.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeLocal
+			svc.Spec.ClusterIP = svcIPv4.String()
+			svc.Spec.Ports = []corev1.ServicePort{{
+				Name:     svcPortName.Port,
+				Port:     int32(svcPort),
+				NodePort: int32(svcNodePort),
+				Protocol: corev1.ProtocolTCP,
+			}}
+		}),
+	)
+
+	localNodeName := "localhost"
+	remoteNodeName := "remote"
+	localEpIP := net.ParseIP("10.180.0.1")
+	remoteEpIP := net.ParseIP("10.180.1.1")
+	makeEndpointsMap(fp,
+		makeTestEndpoints(svcPortName.Namespace, svcPortName.Name, func(ept *corev1.Endpoints) {
+			ept.Subsets = []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{
+					{IP: localEpIP.String(), NodeName: &localNodeName},
+					{IP: remoteEpIP.String(), NodeName: &remoteNodeName},
+				},
+				Ports: []corev1.EndpointPort{{
+					Name:     svcPortName.Port,
+					Port:     int32(svcPort),
+					Protocol: corev1.ProtocolTCP,
+				}},
+			}}
+		}),
+	)
+
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	localGroupID, _ := fp.groupCounter.Get(svcPortName, true)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Do(
+		func(_ binding.GroupIDType, _ bool, endpoints []k8sproxy.Endpoint) {
+			assert.Len(t, endpoints, 2)
+		}).Times(1)
+	mockOFClient.EXPECT().InstallServiceGroup(localGroupID, false, gomock.Any()).Do(
+		func(_ binding.GroupIDType, _ bool, endpoints []k8sproxy.Endpoint) {
+			require.Len(t, endpoints, 1)
+			assert.Equal(t, localEpIP.String(), endpoints[0].IP())
+		}).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	mockOFClient.EXPECT().InstallNodePortFlows(localGroupID, uint16(svcNodePort), binding.ProtocolTCP, uint16(0)).Times(1)
+
+	fp.syncProxyRules()
+}
+
+func TestExternalTrafficPolicyUpdate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+
+	svcIPv4 := net.ParseIP("10.20.30.41")
+	svcPort := 80
+	svcNodePort := 30080
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	makeService := func(externalTrafficPolicy corev1.ServiceExternalTrafficPolicyType) *corev1.Service {
+		return makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
+			svc.Spec.Type = corev1.ServiceTypeNodePort
+			svc.Spec.ExternalTrafficPolicy = externalTrafficPolicy
+			svc.Spec.ClusterIP = svcIPv4.String()
+			svc.Spec.Ports = []corev1.ServicePort{{
+				Name:     svcPortName.Port,
+				Port:     int32(svcPort),
+				NodePort: int32(svcNodePort),
+				Protocol: corev1.ProtocolTCP,
+			}}
+		})
+	}
+	service := makeService(corev1.ServiceExternalTrafficPolicyTypeLocal)
+	makeServiceMap(fp, service)
+	localNodeName := "localhost"
+	makeEndpointsMap(fp,
+		makeTestEndpoints(svcPortName.Namespace, svcPortName.Name, func(ept *corev1.Endpoints) {
+			ept.Subsets = []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{{IP: "10.180.0.1", NodeName: &localNodeName}},
+				Ports: []corev1.EndpointPort{{
+					Name:     svcPortName.Port,
+					Port:     int32(svcPort),
+					Protocol: corev1.ProtocolTCP,
+				}},
+			}}
+		}),
+	)
+
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	localGroupID, _ := fp.groupCounter.Get(svcPortName, true)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceGroup(localGroupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	mockOFClient.EXPECT().InstallNodePortFlows(localGroupID, uint16(svcNodePort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.syncProxyRules()
+
+	// The NodePort flows must be moved to the group of all the Endpoints, and
+	// be removed before the node-local group, which OVS removes them with.
+	fp.serviceChanges.OnServiceUpdate(service, makeService(corev1.ServiceExternalTrafficPolicyTypeCluster))
+	gomock.InOrder(
+		mockOFClient.EXPECT().UninstallNodePortFlows(uint16(svcNodePort), binding.ProtocolTCP).Times(1),
+		mockOFClient.EXPECT().UninstallServiceGroup(localGroupID).Times(1),
+		mockOFClient.EXPECT().InstallNodePortFlows(groupID, uint16(svcNodePort), binding.ProtocolTCP, uint16(0)).Times(1),
+	)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.syncProxyRules()
+}
+
+func makeTestLoadBalancerService(svcPortName k8sproxy.ServicePortName, svcIP net.IP, svcPort int, externalTrafficPolicy corev1.ServiceExternalTrafficPolicyType, ingressIPs ...net.IP) *corev1.Service {
+	return makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
+		svc.Spec.Type = corev1.ServiceTypeLoadBalancer
+		svc.Spec.ExternalTrafficPolicy = externalTrafficPolicy
+		svc.Spec.ClusterIP = svcIP.String()
+		svc.Spec.Ports = []corev1.ServicePort{{
+			Name:     svcPortName.Port,
+			Port:     int32(svcPort),
+			Protocol: corev1.ProtocolTCP,
+		}}
+		for _, ip := range ingressIPs {
+			svc.Status.LoadBalancer.Ingress = append(svc.Status.LoadBalancer.Ingress, corev1.LoadBalancerIngress{IP: ip.String()})
+		}
+	})
+}
+
+func TestLoadBalancer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+
+	svcIPv4 := net.ParseIP("10.20.30.41")
+	svcPort := 80
+	ingressIP := net.ParseIP("169.254.169.1")
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	// The ingress IP is not assigned yet.
+	service := makeTestLoadBalancerService(svcPortName, svcIPv4, svcPort, corev1.ServiceExternalTrafficPolicyTypeCluster)
+	makeServiceMap(fp, service)
+	makeEndpointsMap(fp, makeTestEndpointsWithIP(svcPortName, net.ParseIP("10.180.0.1"), svcPort))
+
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.syncProxyRules()
+
+	// The ingress IP is assigned by updating the status of the Service.
+	serviceWithIngressIP := makeTestLoadBalancerService(svcPortName, svcIPv4, svcPort, corev1.ServiceExternalTrafficPolicyTypeCluster, ingressIP)
+	fp.serviceChanges.OnServiceUpdate(service, serviceWithIngressIP)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, ingressIP, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.syncProxyRules()
+
+	mockOFClient.EXPECT().UninstallServiceFlows(svcIPv4, uint16(svcPort), binding.ProtocolTCP).Times(1)
+	mockOFClient.EXPECT().UninstallServiceFlows(ingressIP, uint16(svcPort), binding.ProtocolTCP).Times(1)
+	mockOFClient.EXPECT().UninstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().UninstallServiceGroup(groupID).Times(1)
+	fp.serviceChanges.OnServiceUpdate(serviceWithIngressIP, nil)
+	fp.syncProxyRules()
+	assert.Empty(t, fp.loadBalancerIPInstalledMap)
+}
+
+func TestLoadBalancerExternalTrafficPolicyLocal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+
+	svcIPv4 := net.ParseIP("10.20.30.41")
+	svcPort := 80
+	ingressIP := net.ParseIP("169.254.169.1")
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	makeServiceMap(fp, makeTestLoadBalancerService(svcPortName, svcIPv4, svcPort, corev1.ServiceExternalTrafficPolicyTypeLocal, ingressIP))
+
+	localNodeName := "localhost"
+	remoteNodeName := "remote"
+	makeEndpoints := func(addresses ...corev1.EndpointAddress) *corev1.Endpoints {
+		return makeTestEndpoints(svcPortName.Namespace, svcPortName.Name, func(ept *corev1.Endpoints) {
+			ept.Subsets = []corev1.EndpointSubset{{
+				Addresses: addresses,
+				Ports: []corev1.EndpointPort{{
+					Name:     svcPortName.Port,
+					Port:     int32(svcPort),
+					Protocol: corev1.ProtocolTCP,
+				}},
+			}}
+		})
+	}
+	remoteEndpoints := makeEndpoints(corev1.EndpointAddress{IP: "10.180.1.1", NodeName: &remoteNodeName})
+	makeEndpointsMap(fp, remoteEndpoints)
+
+	// The ingress IP flows are not installed as there is no local Endpoint.
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	localGroupID, _ := fp.groupCounter.Get(svcPortName, true)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceGroup(localGroupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.syncProxyRules()
+
+	// The ingress IP flows are installed with the node-local group once a
+	// local Endpoint is added.
+	fp.endpointsChanges.OnEndpointUpdate(remoteEndpoints, makeEndpoints(
+		corev1.EndpointAddress{IP: "10.180.1.1", NodeName: &remoteNodeName},
+		corev1.EndpointAddress{IP: "10.180.0.1", NodeName: &localNodeName},
+	))
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceGroup(localGroupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(localGroupID, ingressIP, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.syncProxyRules()
+}
+
+func TestNodePort(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+
+	svcIPv4 := net.ParseIP("10.20.30.41")
+	svcPort := 80
+	svcNodePort := 30080
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	service := makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
+		svc.Spec.Type = corev1.ServiceTypeNodePort
+		svc.Spec.ClusterIP = svcIPv4.String()
+		svc.Spec.Ports = []corev1.ServicePort{{
+			Name:     svcPortName.Port,
+			Port:     int32(svcPort),
+			NodePort: int32(svcNodePort),
+			Protocol: corev1.ProtocolTCP,
+		}}
+	})
+	makeServiceMap(fp, service)
+
+	epIP := net.ParseIP("10.180.0.1")
+	makeEndpointsMap(fp,
+		makeTestEndpoints(svcPortName.Namespace, svcPortName.Name, func(ept *corev1.Endpoints) {
+			ept.Subsets = []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{{
+					IP: epIP.String(),
+				}},
+				Ports: []corev1.EndpointPort{{
+					Name:     svcPortName.Port,
+					Port:     int32(svcPort),
+					Protocol: corev1.ProtocolTCP,
+				}},
+			}}
+		}),
+	)
+
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	mockOFClient.EXPECT().InstallNodePortFlows(groupID, uint16(svcNodePort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.syncProxyRules()
+
+	mockOFClient.EXPECT().UninstallServiceFlows(svcIPv4, uint16(svcPort), binding.ProtocolTCP).Times(1)
+	mockOFClient.EXPECT().UninstallNodePortFlows(uint16(svcNodePort), binding.ProtocolTCP).Times(1)
+	mockOFClient.EXPECT().UninstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().UninstallServiceGroup(groupID).Times(1)
+	fp.serviceChanges.OnServiceUpdate(service, nil)
+	fp.syncProxyRules()
 }
 
-func TestClusterIP(t *testing.T) {
+func TestNodePortIPv6(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockOFClient := ofmock.NewMockClient(ctrl)
 	fp := NewFakeProxier(mockOFClient)
 
-	svcIPv4 := net.ParseIP("10.20.30.41")
+	svcIPv6 := net.ParseIP("10:20::41")
 	svcPort := 80
+	svcNodePort := 30080
 	svcPortName := k8sproxy.ServicePortName{
 		NamespacedName: makeNamespaceName("ns1", "svc1"),
 		Port:           "80",
@@ -111,16 +719,18 @@ func TestClusterIP(t *testing.T) {
 	}
 	makeServiceMap(fp,
 		makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
-			svc.Spec.ClusterIP = svcIPv4.String()
+			svc.Spec.Type = corev1.ServiceTypeNodePort
+			svc.Spec.ClusterIP = svcIPv6.String()
 			svc.Spec.Ports = []corev1.ServicePort{{
 				Name:     svcPortName.Port,
 				Port:     int32(svcPort),
+				NodePort: int32(svcNodePort),
 				Protocol: corev1.ProtocolTCP,
 			}}
 		}),
 	)
 
-	epIP := net.ParseIP("10.180.0.1")
+	epIP := net.ParseIP("10:180::1")
 	makeEndpointsMap(fp,
 		makeTestEndpoints(svcPortName.Namespace, svcPortName.Name, func(ept *corev1.Endpoints) {
 			ept.Subsets = []corev1.EndpointSubset{{
@@ -136,10 +746,13 @@ func TestClusterIP(t *testing.T) {
 		}),
 	)
 
-	groupID, _ := fp.groupCounter.Get(svcPortName)
+	// The NodePort of an IPv6 Service is left to kube-proxy, so no NodePort
+	// flow is installed.
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
 	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
-	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
-	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCPv6, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv6, uint16(svcPort), binding.ProtocolTCPv6, uint16(0)).Times(1)
+	mockOFClient.EXPECT().InstallNodePortFlows(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
 	fp.syncProxyRules()
 }
@@ -182,7 +795,7 @@ func TestClusterIPRemoval(t *testing.T) {
 	}
 	ep := makeTestEndpoints(svcPortName.Namespace, svcPortName.Name, epFunc)
 	makeEndpointsMap(fp, ep)
-	groupID, _ := fp.groupCounter.Get(svcPortName)
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
 	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
 	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
 	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
@@ -291,17 +904,20 @@ func TestClusterIPRemoveSamePortEndpoint(t *testing.T) {
 	})
 	makeEndpointsMap(fp, ep, epUDP)
 
-	groupID, _ := fp.groupCounter.Get(svcPortName)
-	groupIDUDP, _ := fp.groupCounter.Get(svcPortNameUDP)
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	groupIDUDP, _ := fp.groupCounter.Get(svcPortNameUDP, false)
 	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
 	mockOFClient.EXPECT().InstallServiceGroup(groupIDUDP, false, gomock.Any()).Times(1)
 	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
 	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolUDP, gomock.Any()).Times(1)
 	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
 	mockOFClient.EXPECT().InstallServiceFlows(groupIDUDP, svcIPv4, uint16(svcPort), binding.ProtocolUDP, uint16(0)).Times(1)
-	mockOFClient.EXPECT().UninstallEndpointFlows(binding.ProtocolUDP, gomock.Any()).Times(1)
 	fp.syncProxyRules()
 
+	// The group of the Service must not have any bucket once its only
+	// Endpoint is removed.
+	mockOFClient.EXPECT().UninstallEndpointFlows(binding.ProtocolUDP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceGroup(groupIDUDP, false, gomock.Nil()).Times(1)
 	fp.endpointsChanges.OnEndpointUpdate(epUDP, nil)
 	fp.syncProxyRules()
 }
@@ -344,15 +960,198 @@ func TestClusterIPRemoveEndpoints(t *testing.T) {
 		}}
 	})
 	makeEndpointsMap(fp, ep)
-	groupID, _ := fp.groupCounter.Get(svcPortName)
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
 	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
 	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
 	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.syncProxyRules()
+
 	mockOFClient.EXPECT().UninstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Nil()).Times(1)
+	fp.endpointsChanges.OnEndpointUpdate(ep, nil)
+	fp.syncProxyRules()
+}
+
+func TestClusterIPReplaceEndpoint(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+
+	svcIPv4 := net.ParseIP("10.20.30.41")
+	svcPort := 80
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	makeServiceMap(fp,
+		makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
+			svc.Spec.ClusterIP = svcIPv4.String()
+			svc.Spec.Ports = []corev1.ServicePort{{
+				Name:     svcPortName.Port,
+				Port:     int32(svcPort),
+				Protocol: corev1.ProtocolTCP,
+			}}
+		}),
+	)
+	ep := makeTestEndpointsWithIP(svcPortName, net.ParseIP("10.180.0.1"), svcPort)
+	makeEndpointsMap(fp, ep)
+
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.syncProxyRules()
+
+	// The Pod of the Endpoint is recreated with a new IP. The flows of the
+	// new Endpoint are installed first, then the group is updated and the
+	// flows of the old Endpoint are removed in the same bundle.
+	newEp := makeTestEndpointsWithIP(svcPortName, net.ParseIP("10.180.0.2"), svcPort)
+	fp.endpointsChanges.OnEndpointUpdate(ep, newEp)
+	installEndpointFlows := mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Do(
+		func(_ binding.Protocol, endpoints []k8sproxy.Endpoint) {
+			require.Len(t, endpoints, 1)
+			assert.Equal(t, "10.180.0.2", endpoints[0].IP())
+		}).Times(1)
+	mockOFClient.EXPECT().UpdateServiceEndpoints(groupID, false, binding.ProtocolTCP, gomock.Any(), gomock.Any()).Do(
+		func(_ binding.GroupIDType, _ bool, _ binding.Protocol, endpoints, removedEndpoints []k8sproxy.Endpoint) {
+			require.Len(t, endpoints, 1)
+			assert.Equal(t, "10.180.0.2", endpoints[0].IP())
+			require.Len(t, removedEndpoints, 1)
+			assert.Equal(t, "10.180.0.1", removedEndpoints[0].IP())
+		}).After(installEndpointFlows).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.syncProxyRules()
+	assert.Contains(t, fp.endpointInstalledMap[svcPortName], "10.180.0.2:80")
+	assert.NotContains(t, fp.endpointInstalledMap[svcPortName], "10.180.0.1:80")
+}
+
+func TestEndpointGracefulTermination(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+	fp.endpointDrainPeriod = 30 * time.Second
+	fakeClock := fp.clock.(*clock.FakeClock)
+
+	svcIPv4 := net.ParseIP("10.20.30.41")
+	svcPort := 80
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	makeServiceMap(fp,
+		makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
+			svc.Spec.ClusterIP = svcIPv4.String()
+			svc.Spec.Ports = []corev1.ServicePort{{
+				Name:     svcPortName.Port,
+				Port:     int32(svcPort),
+				Protocol: corev1.ProtocolTCP,
+			}}
+		}),
+	)
+
+	makeEndpoints := func(addresses, notReadyAddresses []corev1.EndpointAddress) *corev1.Endpoints {
+		return makeTestEndpoints(svcPortName.Namespace, svcPortName.Name, func(ept *corev1.Endpoints) {
+			ept.Subsets = []corev1.EndpointSubset{{
+				Addresses:         addresses,
+				NotReadyAddresses: notReadyAddresses,
+				Ports: []corev1.EndpointPort{{
+					Name:     svcPortName.Port,
+					Port:     int32(svcPort),
+					Protocol: corev1.ProtocolTCP,
+				}},
+			}}
+		})
+	}
+	address1 := corev1.EndpointAddress{IP: "10.180.0.1"}
+	address2 := corev1.EndpointAddress{IP: "10.180.0.2"}
+	ep := makeEndpoints([]corev1.EndpointAddress{address1, address2}, nil)
+	makeEndpointsMap(fp, ep)
+
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.syncProxyRules()
+
+	// The terminating Endpoint is removed from the group, but its flows are
+	// kept until the drain period expires.
+	terminatingEp := makeEndpoints([]corev1.EndpointAddress{address1}, []corev1.EndpointAddress{address2})
+	fp.endpointsChanges.OnEndpointUpdate(ep, terminatingEp)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Do(
+		func(_ binding.GroupIDType, _ bool, endpoints []k8sproxy.Endpoint) {
+			require.Len(t, endpoints, 1)
+			assert.Equal(t, address1.IP, endpoints[0].IP())
+		}).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.syncProxyRules()
+
+	fakeClock.Step(10 * time.Second)
+	fp.syncProxyRules()
+
+	// The flows of the Endpoint are removed once the drain period expires.
+	mockOFClient.EXPECT().UninstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Do(
+		func(_ binding.Protocol, endpoint k8sproxy.Endpoint) {
+			assert.Equal(t, address2.IP, endpoint.IP())
+		}).Times(1)
+	fakeClock.Step(20 * time.Second)
+	fp.syncProxyRules()
+	assert.Empty(t, fp.drainingEndpoints)
+}
+
+func TestEndpointGracefulTerminationCanceled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+	fp.endpointDrainPeriod = 30 * time.Second
+	fakeClock := fp.clock.(*clock.FakeClock)
+
+	svcIPv4 := net.ParseIP("10.20.30.41")
+	svcPort := 80
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	makeServiceMap(fp,
+		makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
+			svc.Spec.ClusterIP = svcIPv4.String()
+			svc.Spec.Ports = []corev1.ServicePort{{
+				Name:     svcPortName.Port,
+				Port:     int32(svcPort),
+				Protocol: corev1.ProtocolTCP,
+			}}
+		}),
+	)
+	ep := makeTestEndpointsWithIP(svcPortName, net.ParseIP("10.180.0.1"), svcPort)
+	makeEndpointsMap(fp, ep)
+
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
 	fp.syncProxyRules()
 
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Nil()).Times(1)
 	fp.endpointsChanges.OnEndpointUpdate(ep, nil)
 	fp.syncProxyRules()
+
+	// The Endpoint comes back before the drain period expires, its flows must
+	// not be removed.
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, false, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIPv4, uint16(svcPort), binding.ProtocolTCP, uint16(0)).Times(1)
+	fp.endpointsChanges.OnEndpointUpdate(nil, ep)
+	fp.syncProxyRules()
+
+	fakeClock.Step(time.Minute)
+	fp.syncProxyRules()
+	assert.Empty(t, fp.drainingEndpoints)
 }
 
 func TestSessionAffinityNoEndpoint(t *testing.T) {
@@ -407,7 +1206,7 @@ func TestSessionAffinityNoEndpoint(t *testing.T) {
 		}),
 	)
 
-	groupID, _ := fp.groupCounter.Get(svcPortName)
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
 	mockOFClient.EXPECT().InstallServiceGroup(groupID, true, gomock.Any()).Times(1)
 	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
 	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIP, uint16(svcPort), binding.ProtocolTCP, uint16(corev1.DefaultClientIPServiceAffinitySeconds)).Times(1)
@@ -455,3 +1254,214 @@ func TestSessionAffinity(t *testing.T) {
 
 	fp.syncProxyRules()
 }
+
+func makeTestServiceWithAffinityTimeout(svcPortName k8sproxy.ServicePortName, svcIP net.IP, svcPort int, timeoutSeconds int32) *corev1.Service {
+	return makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
+		svc.Spec.ClusterIP = svcIP.String()
+		svc.Spec.SessionAffinity = corev1.ServiceAffinityClientIP
+		svc.Spec.SessionAffinityConfig = &corev1.SessionAffinityConfig{
+			ClientIP: &corev1.ClientIPConfig{
+				TimeoutSeconds: &timeoutSeconds,
+			},
+		}
+		svc.Spec.Ports = []corev1.ServicePort{{
+			Name:     svcPortName.Port,
+			Port:     int32(svcPort),
+			Protocol: corev1.ProtocolTCP,
+		}}
+	})
+}
+
+func makeTestEndpointsWithIP(svcPortName k8sproxy.ServicePortName, epIP net.IP, epPort int) *corev1.Endpoints {
+	return makeTestEndpoints(svcPortName.Namespace, svcPortName.Name, func(ept *corev1.Endpoints) {
+		ept.Subsets = []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{
+				IP: epIP.String(),
+			}},
+			Ports: []corev1.EndpointPort{{
+				Name:     svcPortName.Port,
+				Port:     int32(epPort),
+				Protocol: corev1.ProtocolTCP,
+			}},
+		}}
+	})
+}
+
+func TestSessionAffinityTimeoutUpdate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+
+	svcIP := net.ParseIP("10.20.30.41")
+	svcPort := 80
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	service := makeTestServiceWithAffinityTimeout(svcPortName, svcIP, svcPort, corev1.DefaultClientIPServiceAffinitySeconds)
+	makeServiceMap(fp, service)
+	makeEndpointsMap(fp, makeTestEndpointsWithIP(svcPortName, net.ParseIP("10.180.0.1"), svcPort))
+
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, true, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIP, uint16(svcPort), binding.ProtocolTCP, uint16(corev1.DefaultClientIPServiceAffinitySeconds)).Times(1)
+	fp.syncProxyRules()
+
+	// The Service flows must be reinstalled with the new timeout.
+	newService := makeTestServiceWithAffinityTimeout(svcPortName, svcIP, svcPort, 60)
+	fp.serviceChanges.OnServiceUpdate(service, newService)
+	gomock.InOrder(
+		mockOFClient.EXPECT().UninstallServiceFlows(svcIP, uint16(svcPort), binding.ProtocolTCP).Times(1),
+		mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIP, uint16(svcPort), binding.ProtocolTCP, uint16(60)).Times(1),
+	)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, true, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	fp.syncProxyRules()
+}
+
+func TestSessionAffinityTimeoutClamp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+
+	svcIP := net.ParseIP("10.20.30.41")
+	svcPort := 80
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	// The maximum timeout accepted by the Service API is one day, which
+	// doesn't fit in the idle timeout of a flow.
+	makeServiceMap(fp, makeTestServiceWithAffinityTimeout(svcPortName, svcIP, svcPort, 86400))
+	makeEndpointsMap(fp, makeTestEndpointsWithIP(svcPortName, net.ParseIP("10.180.0.1"), svcPort))
+
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	mockOFClient.EXPECT().InstallServiceGroup(groupID, true, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any()).Times(1)
+	mockOFClient.EXPECT().InstallServiceFlows(groupID, svcIP, uint16(svcPort), binding.ProtocolTCP, uint16(math.MaxUint16)).Times(1)
+	fp.syncProxyRules()
+}
+
+func TestGetServiceEndpoints(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+
+	svcIP := net.ParseIP("10.20.30.41")
+	epIP := net.ParseIP("10.180.0.1")
+	makeServiceMap(fp,
+		makeTestService("ns1", "svc1", func(svc *corev1.Service) {
+			svc.Spec.ClusterIP = svcIP.String()
+			svc.Spec.Ports = []corev1.ServicePort{
+				{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP},
+				{Name: "https", Port: 443, Protocol: corev1.ProtocolTCP},
+				{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP},
+			}
+		}),
+	)
+	makeEndpointsMap(fp,
+		makeTestEndpoints("ns1", "svc1", func(ept *corev1.Endpoints) {
+			ept.Subsets = []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{{IP: epIP.String()}},
+				Ports:     []corev1.EndpointPort{{Name: "http", Port: 8080, Protocol: corev1.ProtocolTCP}},
+			}}
+		}),
+	)
+	mockOFClient.EXPECT().InstallServiceGroup(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	mockOFClient.EXPECT().InstallEndpointFlows(gomock.Any(), gomock.Any()).AnyTimes()
+	mockOFClient.EXPECT().InstallServiceFlows(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	fp.syncProxyRules()
+
+	svcInfo, endpoints, err := fp.GetServiceEndpoints("ns1", "svc1", corev1.ProtocolTCP, 80)
+	require.NoError(t, err)
+	assert.True(t, svcIP.Equal(svcInfo.ClusterIP()))
+	assert.Equal(t, 80, svcInfo.Port())
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, "10.180.0.1:8080", endpoints[0].String())
+
+	svcInfo, endpoints, err = fp.GetServiceEndpoints("ns1", "svc1", corev1.ProtocolUDP, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 53, svcInfo.Port())
+	assert.Empty(t, endpoints)
+
+	_, _, err = fp.GetServiceEndpoints("ns1", "svc1", corev1.ProtocolTCP, 0)
+	assert.Error(t, err)
+	_, _, err = fp.GetServiceEndpoints("ns1", "svc1", corev1.ProtocolTCP, 8080)
+	assert.Error(t, err)
+	_, _, err = fp.GetServiceEndpoints("ns1", "svc2", corev1.ProtocolTCP, 80)
+	assert.Error(t, err)
+}
+
+func TestSyncDurationMetrics(t *testing.T) {
+	// The metrics do not record anything until they are registered.
+	legacyregistry.MustRegister(metrics.ProxyServiceSyncDuration, metrics.ProxyEndpointSyncDuration)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOFClient := ofmock.NewMockClient(ctrl)
+	fp := NewFakeProxier(mockOFClient)
+	fakeClock := fp.clock.(*clock.FakeClock)
+
+	svcIPv4 := net.ParseIP("10.20.30.41")
+	svc := makeTestService("ns1", "svc1", func(svc *corev1.Service) {
+		svc.Spec.Type = corev1.ServiceTypeNodePort
+		svc.Spec.ClusterIP = svcIPv4.String()
+		svc.Spec.Ports = []corev1.ServicePort{{
+			Name:     "80",
+			Port:     80,
+			NodePort: 30080,
+			Protocol: corev1.ProtocolTCP,
+		}}
+	})
+	ep := makeTestEndpoints("ns1", "svc1", func(ept *corev1.Endpoints) {
+		ept.Subsets = []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{IP: "10.180.0.1"}},
+			Ports:     []corev1.EndpointPort{{Name: "80", Port: 80, Protocol: corev1.ProtocolTCP}},
+		}}
+	})
+	makeServiceMap(fp, svc)
+	makeEndpointsMap(fp, ep)
+
+	// Installing the flows of the new Service takes 2 seconds.
+	mockOFClient.EXPECT().InstallEndpointFlows(binding.ProtocolTCP, gomock.Any())
+	mockOFClient.EXPECT().InstallServiceGroup(gomock.Any(), false, gomock.Any())
+	mockOFClient.EXPECT().InstallServiceFlows(gomock.Any(), svcIPv4, uint16(80), binding.ProtocolTCP, uint16(0)).DoAndReturn(
+		func(_ binding.GroupIDType, _ net.IP, _ uint16, _ binding.Protocol, _ uint16) error {
+			fakeClock.Step(2 * time.Second)
+			return nil
+		})
+	mockOFClient.EXPECT().InstallNodePortFlows(gomock.Any(), uint16(30080), binding.ProtocolTCP, uint16(0))
+	fp.syncProxyRules()
+
+	assertHistogramSum := func(histogram *k8smetrics.HistogramVec, eventType string, expected float64) {
+		value, err := testutil.GetHistogramMetricValue(histogram.WithLabelValues(eventType, string(corev1.ServiceTypeNodePort)))
+		require.NoError(t, err)
+		assert.Equal(t, expected, value)
+	}
+	assertHistogramSum(metrics.ProxyServiceSyncDuration, syncEventAdd, 2)
+	assertHistogramSum(metrics.ProxyEndpointSyncDuration, syncEventAdd, 2)
+
+	// A sync without changes does not record anything.
+	fp.syncProxyRules()
+	assertHistogramSum(metrics.ProxyServiceSyncDuration, syncEventAdd, 2)
+	assertHistogramSum(metrics.ProxyEndpointSyncDuration, syncEventAdd, 2)
+
+	// Removing the Service takes 500 milliseconds, even though it fails.
+	fp.serviceChanges.OnServiceUpdate(svc, nil)
+	fp.endpointsChanges.OnEndpointUpdate(ep, nil)
+	mockOFClient.EXPECT().UninstallServiceFlows(svcIPv4, uint16(80), binding.ProtocolTCP).DoAndReturn(
+		func(_ net.IP, _ uint16, _ binding.Protocol) error {
+			fakeClock.Step(500 * time.Millisecond)
+			return fmt.Errorf("error")
+		})
+	mockOFClient.EXPECT().UninstallEndpointFlows(binding.ProtocolTCP, gomock.Any())
+	fp.syncProxyRules()
+	assertHistogramSum(metrics.ProxyServiceSyncDuration, syncEventDelete, 0.5)
+	assertHistogramSum(metrics.ProxyEndpointSyncDuration, syncEventDelete, 0.5)
+	assertHistogramSum(metrics.ProxyServiceSyncDuration, syncEventUpdate, 0)
+}
@@ -32,8 +32,10 @@ type serviceChangesTracker struct {
 }
 
 func newServiceChangesTracker(recorder record.EventRecorder) *serviceChangesTracker {
-	enableIPV6 := false
-	return &serviceChangesTracker{tracker: k8sproxy.NewServiceChangeTracker(types.NewServiceInfo, &enableIPV6, recorder)}
+	// The IP family filter is disabled so that both IPv4 and IPv6 Services are
+	// tracked in dual-stack clusters. The IP family of each Service is handled
+	// by ServiceInfo.OFProtocol.
+	return &serviceChangesTracker{tracker: k8sproxy.NewServiceChangeTracker(types.NewServiceInfo, nil, recorder)}
 }
 
 func (sh *serviceChangesTracker) OnServiceSynced() {
@@ -0,0 +1,256 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/agent/proxy/types"
+	binding "github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
+)
+
+// endpointDNATFlowRegexp matches the counters, the protocol and the DNAT
+// destination of a flow dumped from the EndpointDNAT table, e.g.
+// "table=42, n_packets=5, n_bytes=370, priority=200,tcp,reg3=0xa0a0001,
+// reg4=0x20050/0x7ffff actions=ct(commit,table=50,zone=65520,
+// nat(dst=10.10.0.1:80),exec(load:0x21->NXM_NX_CT_MARK[]))".
+var endpointDNATFlowRegexp = regexp.MustCompile(`n_packets=(\d+), n_bytes=(\d+),.* priority=\d+,([a-z0-9]+),.*nat\(dst=([^)]+)\)`)
+
+// EndpointStats is the traffic statistics of an Endpoint of a Service port.
+type EndpointStats struct {
+	Endpoint string
+	// Packets and Bytes are the counters of the DNAT flow of the Endpoint,
+	// which are reset when the flow is reinstalled.
+	Packets uint64
+	Bytes   uint64
+	// CumulativeBytes accumulates the deltas of Bytes and is not reset
+	// when the flow is reinstalled.
+	CumulativeBytes uint64
+}
+
+// ServiceStats is the traffic statistics of a Service port, aggregated from
+// its Endpoints. The DNAT flow of an Endpoint is shared by all the Service
+// ports selecting it, so its statistics are counted for each of them.
+type ServiceStats struct {
+	Namespace       string
+	Name            string
+	Port            string
+	Protocol        string
+	Packets         uint64
+	Bytes           uint64
+	CumulativeBytes uint64
+	Endpoints       []EndpointStats
+}
+
+// StatsQuerier is the interface to query the traffic statistics of the
+// Services implemented by AntreaProxy.
+type StatsQuerier interface {
+	GetServiceStats() []ServiceStats
+}
+
+type endpointCounters struct {
+	packets         uint64
+	bytes           uint64
+	cumulativeBytes uint64
+}
+
+// StatsCollector polls the counters of the Endpoint DNAT flows periodically
+// and aggregates them per Service port.
+type StatsCollector struct {
+	proxier      *Proxier
+	ovsCtlClient ovsctl.OVSCtlClient
+	pollInterval time.Duration
+	// statsMutex protects endpointStats.
+	statsMutex sync.RWMutex
+	// endpointStats stores the counters of the Endpoint DNAT flows, keyed
+	// by the OpenFlow protocol and the Endpoint.
+	endpointStats map[string]*endpointCounters
+}
+
+var _ StatsQuerier = new(StatsCollector)
+
+// NewStatsCollector returns a StatsCollector which polls the OVS flow counters
+// every pollInterval.
+func NewStatsCollector(proxier *Proxier, ovsCtlClient ovsctl.OVSCtlClient, pollInterval time.Duration) *StatsCollector {
+	return &StatsCollector{
+		proxier:       proxier,
+		ovsCtlClient:  ovsCtlClient,
+		pollInterval:  pollInterval,
+		endpointStats: map[string]*endpointCounters{},
+	}
+}
+
+func endpointStatsKey(protocol binding.Protocol, endpoint string) string {
+	return fmt.Sprintf("%s/%s", protocol, endpoint)
+}
+
+// parseEndpointDNATFlow returns the key of the Endpoint and the counters of a
+// flow dumped from the EndpointDNAT table. ok is false if the flow is not an
+// Endpoint DNAT flow.
+func parseEndpointDNATFlow(flow string) (key string, packets, bytes uint64, ok bool) {
+	matches := endpointDNATFlowRegexp.FindStringSubmatch(flow)
+	if matches == nil {
+		return "", 0, 0, false
+	}
+	packets, err := strconv.ParseUint(matches[1], 10, 64)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	bytes, err = strconv.ParseUint(matches[2], 10, 64)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	return endpointStatsKey(binding.Protocol(matches[3]), matches[4]), packets, bytes, true
+}
+
+// referencedEndpoints returns the keys of the Endpoints of all the Service
+// ports AntreaProxy expects to be installed.
+func (c *StatsCollector) referencedEndpoints() map[string]struct{} {
+	c.proxier.syncProxyRulesMutex.Lock()
+	defer c.proxier.syncProxyRulesMutex.Unlock()
+	keys := map[string]struct{}{}
+	for svcPortName, endpoints := range c.proxier.endpointsMap {
+		svcPort, ok := c.proxier.serviceMap[svcPortName]
+		if !ok {
+			continue
+		}
+		protocol := svcPort.(*types.ServiceInfo).OFProtocol
+		for _, endpoint := range endpoints {
+			keys[endpointStatsKey(protocol, endpoint.String())] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// collect dumps the Endpoint DNAT flows and updates the counters of the
+// Endpoints. A flow whose counters decreased since the last poll has been
+// reinstalled, in which case its new counters are accumulated as a whole.
+func (c *StatsCollector) collect() error {
+	flows, err := c.ovsCtlClient.DumpTableFlows(uint8(openflow.GetFlowTableNumber("EndpointDNAT")))
+	if err != nil {
+		return err
+	}
+	current := map[string]*endpointCounters{}
+	for _, flow := range flows {
+		key, packets, bytes, ok := parseEndpointDNATFlow(flow)
+		if !ok {
+			continue
+		}
+		current[key] = &endpointCounters{packets: packets, bytes: bytes}
+	}
+	referenced := c.referencedEndpoints()
+
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+	for key, counters := range current {
+		stats, exists := c.endpointStats[key]
+		if !exists {
+			counters.cumulativeBytes = counters.bytes
+			c.endpointStats[key] = counters
+			continue
+		}
+		if counters.packets < stats.packets || counters.bytes < stats.bytes {
+			stats.cumulativeBytes += counters.bytes
+		} else {
+			stats.cumulativeBytes += counters.bytes - stats.bytes
+		}
+		stats.packets = counters.packets
+		stats.bytes = counters.bytes
+	}
+	// Keep the counters of a referenced Endpoint whose flow is missing, as
+	// the flow may be being reinstalled.
+	for key, stats := range c.endpointStats {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		if _, ok := referenced[key]; ok {
+			stats.packets = 0
+			stats.bytes = 0
+			continue
+		}
+		delete(c.endpointStats, key)
+	}
+	return nil
+}
+
+// GetServiceStats returns the statistics of all the Service ports which have
+// Endpoints, sorted by Namespace, name and port.
+func (c *StatsCollector) GetServiceStats() []ServiceStats {
+	c.proxier.syncProxyRulesMutex.Lock()
+	defer c.proxier.syncProxyRulesMutex.Unlock()
+	c.statsMutex.RLock()
+	defer c.statsMutex.RUnlock()
+
+	var serviceStats []ServiceStats
+	for svcPortName, endpoints := range c.proxier.endpointsMap {
+		svcPort, ok := c.proxier.serviceMap[svcPortName]
+		if !ok {
+			continue
+		}
+		protocol := svcPort.(*types.ServiceInfo).OFProtocol
+		stats := ServiceStats{
+			Namespace: svcPortName.Namespace,
+			Name:      svcPortName.Name,
+			Port:      svcPortName.Port,
+			Protocol:  string(svcPortName.Protocol),
+		}
+		for _, endpoint := range endpoints {
+			endpointStats := EndpointStats{Endpoint: endpoint.String()}
+			if counters, ok := c.endpointStats[endpointStatsKey(protocol, endpoint.String())]; ok {
+				endpointStats.Packets = counters.packets
+				endpointStats.Bytes = counters.bytes
+				endpointStats.CumulativeBytes = counters.cumulativeBytes
+			}
+			stats.Packets += endpointStats.Packets
+			stats.Bytes += endpointStats.Bytes
+			stats.CumulativeBytes += endpointStats.CumulativeBytes
+			stats.Endpoints = append(stats.Endpoints, endpointStats)
+		}
+		sort.Slice(stats.Endpoints, func(i, j int) bool {
+			return stats.Endpoints[i].Endpoint < stats.Endpoints[j].Endpoint
+		})
+		serviceStats = append(serviceStats, stats)
+	}
+	sort.Slice(serviceStats, func(i, j int) bool {
+		if serviceStats[i].Namespace != serviceStats[j].Namespace {
+			return serviceStats[i].Namespace < serviceStats[j].Namespace
+		}
+		if serviceStats[i].Name != serviceStats[j].Name {
+			return serviceStats[i].Name < serviceStats[j].Name
+		}
+		return serviceStats[i].Port < serviceStats[j].Port
+	})
+	return serviceStats
+}
+
+// Run polls the counters of the Endpoint DNAT flows until stopCh is closed.
+func (c *StatsCollector) Run(stopCh <-chan struct{}) {
+	klog.Infof("Starting AntreaProxy statistics collector with poll interval %v", c.pollInterval)
+	wait.Until(func() {
+		if err := c.collect(); err != nil {
+			klog.Errorf("Error when collecting AntreaProxy statistics: %v", err)
+		}
+	}, c.pollInterval, stopCh)
+}
@@ -0,0 +1,232 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	ofmock "github.com/vmware-tanzu/antrea/pkg/agent/openflow/testing"
+	binding "github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
+	ovsctltest "github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl/testing"
+	k8sproxy "github.com/vmware-tanzu/antrea/third_party/proxy"
+)
+
+const endpointDNATTableID uint8 = 42
+
+func endpointDNATFlow(protocol binding.Protocol, endpoint string, packets, bytes uint64) string {
+	return fmt.Sprintf("table=42, n_packets=%d, n_bytes=%d, idle_age=2, priority=200,%s,reg3=0xa0b40001,reg4=0x20050/0x7ffff actions=ct(commit,table=50,zone=65520,nat(dst=%s),exec(load:0x21->NXM_NX_CT_MARK[]))", packets, bytes, protocol, endpoint)
+}
+
+func newFakeStatsCollector(ctrl *gomock.Controller, svcPortName k8sproxy.ServicePortName, epIPs ...net.IP) (*StatsCollector, *ovsctltest.MockOVSCtlClient) {
+	fp := NewFakeProxier(ofmock.NewMockClient(ctrl))
+	makeServiceMap(fp,
+		makeTestService(svcPortName.Namespace, svcPortName.Name, func(svc *corev1.Service) {
+			svc.Spec.ClusterIP = "10.20.30.41"
+			svc.Spec.Ports = []corev1.ServicePort{{
+				Name:     svcPortName.Port,
+				Port:     80,
+				Protocol: svcPortName.Protocol,
+			}}
+		}),
+	)
+	makeEndpointsMap(fp,
+		makeTestEndpoints(svcPortName.Namespace, svcPortName.Name, func(ept *corev1.Endpoints) {
+			var addresses []corev1.EndpointAddress
+			for _, ip := range epIPs {
+				addresses = append(addresses, corev1.EndpointAddress{IP: ip.String()})
+			}
+			ept.Subsets = []corev1.EndpointSubset{{
+				Addresses: addresses,
+				Ports: []corev1.EndpointPort{{
+					Name:     svcPortName.Port,
+					Port:     80,
+					Protocol: svcPortName.Protocol,
+				}},
+			}}
+		}),
+	)
+	fp.endpointsChanges.Update(fp.endpointsMap)
+	fp.serviceChanges.Update(fp.serviceMap)
+	mockOVSCtlClient := ovsctltest.NewMockOVSCtlClient(ctrl)
+	return NewStatsCollector(fp, mockOVSCtlClient, 0), mockOVSCtlClient
+}
+
+func TestParseEndpointDNATFlow(t *testing.T) {
+	tests := []struct {
+		name            string
+		flow            string
+		expectedKey     string
+		expectedPackets uint64
+		expectedBytes   uint64
+		expectedOK      bool
+	}{
+		{
+			name:            "IPv4",
+			flow:            endpointDNATFlow(binding.ProtocolTCP, "10.180.0.1:80", 3, 222),
+			expectedKey:     "tcp/10.180.0.1:80",
+			expectedPackets: 3,
+			expectedBytes:   222,
+			expectedOK:      true,
+		},
+		{
+			name:            "IPv6",
+			flow:            endpointDNATFlow(binding.ProtocolUDPv6, "[fd00::1]:53", 10, 1000),
+			expectedKey:     "udp6/[fd00::1]:53",
+			expectedPackets: 10,
+			expectedBytes:   1000,
+			expectedOK:      true,
+		},
+		{
+			name:       "default flow",
+			flow:       "table=42, n_packets=8, n_bytes=592, priority=0 actions=resubmit(,50)",
+			expectedOK: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, packets, bytes, ok := parseEndpointDNATFlow(tt.flow)
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedKey, key)
+			assert.Equal(t, tt.expectedPackets, packets)
+			assert.Equal(t, tt.expectedBytes, bytes)
+		})
+	}
+}
+
+func TestStatsCollectorAggregation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	collector, mockOVSCtlClient := newFakeStatsCollector(ctrl, svcPortName, net.ParseIP("10.180.0.1"), net.ParseIP("10.180.0.2"))
+
+	mockOVSCtlClient.EXPECT().DumpTableFlows(endpointDNATTableID).Return([]string{
+		endpointDNATFlow(binding.ProtocolTCP, "10.180.0.1:80", 3, 300),
+		endpointDNATFlow(binding.ProtocolTCP, "10.180.0.2:80", 2, 200),
+		// The UDP flow of the same Endpoint must not be counted.
+		endpointDNATFlow(binding.ProtocolUDP, "10.180.0.2:80", 5, 500),
+		"table=42, n_packets=8, n_bytes=592, priority=0 actions=resubmit(,50)",
+	}, nil)
+	require.NoError(t, collector.collect())
+
+	expected := []ServiceStats{{
+		Namespace:       "ns1",
+		Name:            "svc1",
+		Port:            "80",
+		Protocol:        "TCP",
+		Packets:         5,
+		Bytes:           500,
+		CumulativeBytes: 500,
+		Endpoints: []EndpointStats{
+			{Endpoint: "10.180.0.1:80", Packets: 3, Bytes: 300, CumulativeBytes: 300},
+			{Endpoint: "10.180.0.2:80", Packets: 2, Bytes: 200, CumulativeBytes: 200},
+		},
+	}}
+	assert.Equal(t, expected, collector.GetServiceStats())
+}
+
+func TestStatsCollectorCumulativeBytes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	collector, mockOVSCtlClient := newFakeStatsCollector(ctrl, svcPortName, net.ParseIP("10.180.0.1"))
+
+	polls := []struct {
+		name                    string
+		flows                   []string
+		expectedBytes           uint64
+		expectedCumulativeBytes uint64
+	}{
+		{
+			name:                    "first poll",
+			flows:                   []string{endpointDNATFlow(binding.ProtocolTCP, "10.180.0.1:80", 1, 100)},
+			expectedBytes:           100,
+			expectedCumulativeBytes: 100,
+		},
+		{
+			name:                    "counters increased",
+			flows:                   []string{endpointDNATFlow(binding.ProtocolTCP, "10.180.0.1:80", 3, 250)},
+			expectedBytes:           250,
+			expectedCumulativeBytes: 250,
+		},
+		{
+			name:                    "flow reinstalled",
+			flows:                   []string{endpointDNATFlow(binding.ProtocolTCP, "10.180.0.1:80", 1, 30)},
+			expectedBytes:           30,
+			expectedCumulativeBytes: 280,
+		},
+		{
+			name:                    "flow missing",
+			flows:                   []string{},
+			expectedBytes:           0,
+			expectedCumulativeBytes: 280,
+		},
+		{
+			name:                    "flow installed again",
+			flows:                   []string{endpointDNATFlow(binding.ProtocolTCP, "10.180.0.1:80", 1, 50)},
+			expectedBytes:           50,
+			expectedCumulativeBytes: 330,
+		},
+	}
+	for _, poll := range polls {
+		mockOVSCtlClient.EXPECT().DumpTableFlows(endpointDNATTableID).Return(poll.flows, nil)
+		require.NoError(t, collector.collect(), poll.name)
+		stats := collector.GetServiceStats()
+		require.Len(t, stats, 1, poll.name)
+		assert.Equal(t, poll.expectedBytes, stats[0].Bytes, poll.name)
+		assert.Equal(t, poll.expectedCumulativeBytes, stats[0].CumulativeBytes, poll.name)
+	}
+}
+
+func TestStatsCollectorRemovedEndpoint(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	collector, mockOVSCtlClient := newFakeStatsCollector(ctrl, svcPortName, net.ParseIP("10.180.0.1"))
+
+	mockOVSCtlClient.EXPECT().DumpTableFlows(endpointDNATTableID).Return([]string{
+		endpointDNATFlow(binding.ProtocolTCP, "10.180.0.1:80", 1, 100),
+		endpointDNATFlow(binding.ProtocolTCP, "10.180.0.9:80", 1, 100),
+	}, nil)
+	require.NoError(t, collector.collect())
+	assert.Len(t, collector.endpointStats, 2)
+
+	// The counters of an Endpoint which is neither referenced by a Service
+	// nor installed anymore are discarded.
+	mockOVSCtlClient.EXPECT().DumpTableFlows(endpointDNATTableID).Return([]string{
+		endpointDNATFlow(binding.ProtocolTCP, "10.180.0.1:80", 1, 100),
+	}, nil)
+	require.NoError(t, collector.collect())
+	assert.Len(t, collector.endpointStats, 1)
+	assert.Contains(t, collector.endpointStats, "tcp/10.180.0.1:80")
+}
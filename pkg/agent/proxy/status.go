@@ -0,0 +1,220 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/agent/proxy/types"
+	binding "github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/ovs/ovsctl"
+	k8sproxy "github.com/vmware-tanzu/antrea/third_party/proxy"
+)
+
+// EndpointStatus is the state of an Endpoint of a Service port, as known by
+// AntreaProxy and as found in OVS.
+type EndpointStatus struct {
+	IP       string
+	Port     int
+	Protocol string
+	// Node is the name of the Node running the Endpoint, if known.
+	Node string
+	// Ready is false for an Endpoint which has been removed from the
+	// Service, e.g. because it is no longer ready, but whose flows are kept
+	// until the drain period expires.
+	Ready bool
+	// OwnedBySlice tells whether the Endpoint is tracked from an
+	// EndpointSlice rather than from an Endpoints resource.
+	OwnedBySlice bool
+	// Installed tells whether the flows of the Endpoint are installed
+	// according to the caches of AntreaProxy.
+	Installed bool
+	// NATFlowInstalled tells whether the DNAT flow of the Endpoint is found
+	// in OVS.
+	NATFlowInstalled bool
+	// natFlowKey identifies the DNAT flow of the Endpoint.
+	natFlowKey string
+}
+
+// GroupStatus is the OVS group used to load-balance the traffic of a Service
+// port.
+type GroupStatus struct {
+	GroupID binding.GroupIDType
+	// NodeLocal tells whether the group only selects the Endpoints running on
+	// the Node, for external traffic.
+	NodeLocal bool
+	// Entry is the group as dumped from OVS, without its buckets. It is
+	// empty if the group is not found in OVS.
+	Entry   string
+	Buckets []string
+}
+
+// ServicePortStatus is the state of a Service port implemented by AntreaProxy.
+type ServicePortStatus struct {
+	Namespace string
+	Name      string
+	Port      string
+	Protocol  string
+	ClusterIP string
+	// Installed tells whether the flows of the Service port are installed
+	// according to the caches of AntreaProxy.
+	Installed bool
+	Groups    []GroupStatus
+	Endpoints []EndpointStatus
+}
+
+// StatusQuerier is the interface to query the state of the Services
+// implemented by AntreaProxy, both in its caches and in OVS.
+type StatusQuerier interface {
+	// GetServiceStatus returns the state of the ports of a Service, sorted
+	// by port. No port is returned if the Service is unknown.
+	GetServiceStatus(namespace, name string) ([]ServicePortStatus, error)
+}
+
+// StatusChecker checks the state of the Services implemented by AntreaProxy
+// against the flows and groups installed in OVS.
+type StatusChecker struct {
+	proxier      *Proxier
+	ovsCtlClient ovsctl.OVSCtlClient
+}
+
+var _ StatusQuerier = new(StatusChecker)
+
+// NewStatusChecker returns a StatusChecker which uses ovsCtlClient to query
+// OVS.
+func NewStatusChecker(proxier *Proxier, ovsCtlClient ovsctl.OVSCtlClient) *StatusChecker {
+	return &StatusChecker{
+		proxier:      proxier,
+		ovsCtlClient: ovsCtlClient,
+	}
+}
+
+// endpointStatus returns the state of an Endpoint according to the caches of
+// AntreaProxy. NATFlowInstalled is set later.
+func (c *StatusChecker) endpointStatus(svcPortName k8sproxy.ServicePortName, protocol binding.Protocol, endpoint k8sproxy.Endpoint, ready bool) EndpointStatus {
+	port, _ := endpoint.Port()
+	_, installed := c.proxier.endpointInstalledMap[svcPortName][endpoint.String()]
+	return EndpointStatus{
+		IP:           endpoint.IP(),
+		Port:         port,
+		Protocol:     string(svcPortName.Protocol),
+		Node:         endpoint.GetTopology()[topologyHostnameKey],
+		Ready:        ready,
+		OwnedBySlice: c.proxier.endpointsChanges.endpointSliceCache != nil,
+		// The flows of a draining Endpoint are kept.
+		Installed:  installed || !ready,
+		natFlowKey: endpointStatsKey(protocol, endpoint.String()),
+	}
+}
+
+// cachedServiceStatus returns the state of the ports of a Service according to
+// the caches of AntreaProxy.
+func (c *StatusChecker) cachedServiceStatus(namespace, name string) []ServicePortStatus {
+	p := c.proxier
+	p.syncProxyRulesMutex.Lock()
+	defer p.syncProxyRulesMutex.Unlock()
+
+	var statuses []ServicePortStatus
+	for svcPortName, svcPort := range p.serviceMap {
+		if svcPortName.Namespace != namespace || svcPortName.Name != name {
+			continue
+		}
+		svcInfo := svcPort.(*types.ServiceInfo)
+		_, installed := p.serviceInstalledMap[svcPortName]
+		status := ServicePortStatus{
+			Namespace: namespace,
+			Name:      name,
+			Port:      svcPortName.Port,
+			Protocol:  string(svcPortName.Protocol),
+			ClusterIP: svcInfo.ClusterIP().String(),
+			Installed: installed,
+		}
+		// The groups of a Service port are allocated when its flows are
+		// installed.
+		if installed {
+			groupID, _ := p.groupCounter.Get(svcPortName, false)
+			status.Groups = append(status.Groups, GroupStatus{GroupID: groupID})
+			if svcInfo.OnlyNodeLocalEndpoints() {
+				groupID, _ := p.groupCounter.Get(svcPortName, true)
+				status.Groups = append(status.Groups, GroupStatus{GroupID: groupID, NodeLocal: true})
+			}
+		}
+		for _, endpoint := range p.endpointsMap[svcPortName] {
+			status.Endpoints = append(status.Endpoints, c.endpointStatus(svcPortName, svcInfo.OFProtocol, endpoint, true))
+		}
+		for _, drainingEndpoint := range p.drainingEndpoints {
+			if drainingEndpoint.svcPortName == svcPortName {
+				status.Endpoints = append(status.Endpoints, c.endpointStatus(svcPortName, drainingEndpoint.protocol, drainingEndpoint.endpoint, false))
+			}
+		}
+		sort.Slice(status.Endpoints, func(i, j int) bool {
+			if status.Endpoints[i].IP != status.Endpoints[j].IP {
+				return status.Endpoints[i].IP < status.Endpoints[j].IP
+			}
+			return status.Endpoints[i].Port < status.Endpoints[j].Port
+		})
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Port != statuses[j].Port {
+			return statuses[i].Port < statuses[j].Port
+		}
+		return statuses[i].Protocol < statuses[j].Protocol
+	})
+	return statuses
+}
+
+// GetServiceStatus returns the state of the ports of a Service. The DNAT flows
+// of the Endpoints and the groups are looked up in OVS, after the caches of
+// AntreaProxy have been read, so a Service being synced may appear to diverge
+// from OVS.
+func (c *StatusChecker) GetServiceStatus(namespace, name string) ([]ServicePortStatus, error) {
+	statuses := c.cachedServiceStatus(namespace, name)
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	flows, err := c.ovsCtlClient.DumpTableFlows(uint8(openflow.GetFlowTableNumber("EndpointDNAT")))
+	if err != nil {
+		return nil, fmt.Errorf("error when dumping the Endpoint DNAT flows: %v", err)
+	}
+	natFlows := map[string]bool{}
+	for _, flow := range flows {
+		if key, _, _, ok := parseEndpointDNATFlow(flow); ok {
+			natFlows[key] = true
+		}
+	}
+	for i := range statuses {
+		status := &statuses[i]
+		for j := range status.Endpoints {
+			endpoint := &status.Endpoints[j]
+			endpoint.NATFlowInstalled = natFlows[endpoint.natFlowKey]
+		}
+		for j := range status.Groups {
+			group := &status.Groups[j]
+			groups, err := c.ovsCtlClient.DumpGroups(fmt.Sprint(group.GroupID))
+			if err != nil {
+				return nil, fmt.Errorf("error when dumping group %d: %v", group.GroupID, err)
+			}
+			if len(groups) > 0 && len(groups[0]) > 0 {
+				group.Entry = groups[0][0]
+				group.Buckets = groups[0][1:]
+			}
+		}
+	}
+	return statuses, nil
+}
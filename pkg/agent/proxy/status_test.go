@@ -0,0 +1,95 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	binding "github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
+	k8sproxy "github.com/vmware-tanzu/antrea/third_party/proxy"
+)
+
+func TestGetServiceStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	svcPortName := k8sproxy.ServicePortName{
+		NamespacedName: makeNamespaceName("ns1", "svc1"),
+		Port:           "80",
+		Protocol:       corev1.ProtocolTCP,
+	}
+	epIP1, epIP2 := net.ParseIP("10.180.0.1"), net.ParseIP("10.180.1.1")
+	collector, mockOVSCtlClient := newFakeStatsCollector(ctrl, svcPortName, epIP1, epIP2)
+	fp := collector.proxier
+	// The flows of the first Endpoint are installed, and the ones of a removed
+	// Endpoint are being drained.
+	groupID, _ := fp.groupCounter.Get(svcPortName, false)
+	fp.serviceInstalledMap[svcPortName] = fp.serviceMap[svcPortName]
+	fp.endpointInstalledMap[svcPortName] = map[string]struct{}{"10.180.0.1:80": {}}
+	drainingEndpoint := &drainingEndpoint{
+		svcPortName: svcPortName,
+		protocol:    binding.ProtocolTCP,
+		endpoint:    &k8sproxy.BaseEndpointInfo{Endpoint: "10.180.2.1:80"},
+		expiry:      time.Now().Add(time.Minute),
+	}
+	fp.drainingEndpoints[drainingEndpointKey(binding.ProtocolTCP, drainingEndpoint.endpoint)] = drainingEndpoint
+
+	mockOVSCtlClient.EXPECT().DumpTableFlows(endpointDNATTableID).Return([]string{
+		endpointDNATFlow(binding.ProtocolTCP, "10.180.0.1:80", 0, 0),
+		endpointDNATFlow(binding.ProtocolTCP, "10.180.2.1:80", 0, 0),
+		"table=42, n_packets=8, n_bytes=592, priority=0 actions=resubmit(,50)",
+	}, nil)
+	mockOVSCtlClient.EXPECT().DumpGroups("1").Return([][]string{{
+		"group_id=1,type=select",
+		"bucket_id:0,weight:100,actions=load:0xa0b40001->NXM_NX_REG3[],load:0x50->NXM_NX_REG4[0..15],resubmit(,42)",
+	}}, nil)
+
+	checker := NewStatusChecker(fp, mockOVSCtlClient)
+	statuses, err := checker.GetServiceStatus("ns1", "svc1")
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	status := statuses[0]
+	assert.True(t, status.Installed)
+	assert.Equal(t, "10.20.30.41", status.ClusterIP)
+	assert.Equal(t, []GroupStatus{{
+		GroupID: groupID,
+		Entry:   "group_id=1,type=select",
+		Buckets: []string{"bucket_id:0,weight:100,actions=load:0xa0b40001->NXM_NX_REG3[],load:0x50->NXM_NX_REG4[0..15],resubmit(,42)"},
+	}}, status.Groups)
+	require.Len(t, status.Endpoints, 3)
+	for _, e := range status.Endpoints {
+		e.natFlowKey = ""
+		switch e.IP {
+		case "10.180.0.1":
+			assert.Equal(t, EndpointStatus{IP: e.IP, Port: 80, Protocol: "TCP", Ready: true, Installed: true, NATFlowInstalled: true}, e)
+		case "10.180.1.1":
+			// The flows of the Endpoint are missing both in the caches and
+			// in OVS.
+			assert.Equal(t, EndpointStatus{IP: e.IP, Port: 80, Protocol: "TCP", Ready: true}, e)
+		case "10.180.2.1":
+			assert.Equal(t, EndpointStatus{IP: e.IP, Port: 80, Protocol: "TCP", Installed: true, NATFlowInstalled: true}, e)
+		}
+	}
+
+	statuses, err = checker.GetServiceStatus("ns1", "svc2")
+	require.NoError(t, err)
+	assert.Empty(t, statuses)
+}
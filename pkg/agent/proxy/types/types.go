@@ -16,37 +16,76 @@ package types
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilnet "k8s.io/utils/net"
 
 	"github.com/vmware-tanzu/antrea/pkg/ovs/openflow"
 	k8sproxy "github.com/vmware-tanzu/antrea/third_party/proxy"
 )
 
+// EndpointHealthCheckAnnotationKey is the annotation of the Services whose
+// Endpoints are actively health-checked by AntreaProxy when set to "true".
+const EndpointHealthCheckAnnotationKey = "antrea.io/endpoint-healthcheck"
+
 // ServiceInfo is the internal struct for caching service information.
 type ServiceInfo struct {
 	*k8sproxy.BaseServiceInfo
 	// cache for performance
 	OFProtocol openflow.Protocol
+	// ServiceType is the type of the Service, it is only used as a label of
+	// the sync duration metrics.
+	ServiceType corev1.ServiceType
+	// EndpointHealthCheck is true if the Endpoints of the Service port are
+	// probed, so that the unresponsive ones are removed from its groups. It
+	// is only set for TCP ports.
+	EndpointHealthCheck bool
 }
 
 func (si *ServiceInfo) Equal(bSvcInfo *ServiceInfo) bool {
 	return si.SessionAffinityType() == bSvcInfo.SessionAffinityType() &&
 		si.StickyMaxAgeSeconds() == bSvcInfo.StickyMaxAgeSeconds() &&
 		si.OFProtocol == bSvcInfo.OFProtocol &&
-		si.Port() == bSvcInfo.Port()
+		si.Port() == bSvcInfo.Port() &&
+		si.NodePort() == bSvcInfo.NodePort() &&
+		si.OnlyNodeLocalEndpoints() == bSvcInfo.OnlyNodeLocalEndpoints() &&
+		si.EndpointHealthCheck == bSvcInfo.EndpointHealthCheck &&
+		sets.NewString(si.LoadBalancerIPStrings()...).Equal(sets.NewString(bSvcInfo.LoadBalancerIPStrings()...))
 }
 
 // NewServiceInfo returns a new k8sproxy.ServicePort which abstracts a serviceInfo.
 func NewServiceInfo(port *corev1.ServicePort, service *corev1.Service, baseInfo *k8sproxy.BaseServiceInfo) k8sproxy.ServicePort {
 	info := &ServiceInfo{BaseServiceInfo: baseInfo}
-	info.OFProtocol = openflow.ProtocolTCP
-	if port.Protocol == corev1.ProtocolUDP {
-		info.OFProtocol = openflow.ProtocolUDP
-	} else if port.Protocol == corev1.ProtocolSCTP {
-		info.OFProtocol = openflow.ProtocolSCTP
+	info.OFProtocol = GetOFProtocol(port.Protocol, utilnet.IsIPv6(baseInfo.ClusterIP()))
+	info.ServiceType = service.Spec.Type
+	if info.ServiceType == "" {
+		info.ServiceType = corev1.ServiceTypeClusterIP
 	}
+	info.EndpointHealthCheck = port.Protocol == corev1.ProtocolTCP && service.Annotations[EndpointHealthCheckAnnotationKey] == "true"
 	return info
 }
 
+// GetOFProtocol returns the OpenFlow protocol for the provided Service protocol
+// and IP family.
+func GetOFProtocol(protocol corev1.Protocol, isIPv6 bool) openflow.Protocol {
+	switch protocol {
+	case corev1.ProtocolUDP:
+		if isIPv6 {
+			return openflow.ProtocolUDPv6
+		}
+		return openflow.ProtocolUDP
+	case corev1.ProtocolSCTP:
+		if isIPv6 {
+			return openflow.ProtocolSCTPv6
+		}
+		return openflow.ProtocolSCTP
+	default:
+		if isIPv6 {
+			return openflow.ProtocolTCPv6
+		}
+		return openflow.ProtocolTCP
+	}
+}
+
 // NewEndpointInfo returns a new k8sproxy.Endpoint which abstracts an endpointsInfo.
 func NewEndpointInfo(baseInfo *k8sproxy.BaseEndpointInfo) k8sproxy.Endpoint {
 	return baseInfo
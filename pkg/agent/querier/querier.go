@@ -50,7 +50,10 @@ type agentQuerier struct {
 	ofClient                 openflow.Client
 	ovsBridgeClient          ovsconfig.OVSBridgeClient
 	networkPolicyInfoQuerier querier.AgentNetworkPolicyInfoQuerier
-	apiPort                  int
+	// networkPolicyStatsQuerier is nil if the NetworkPolicy statistics are
+	// not collected.
+	networkPolicyStatsQuerier querier.AgentNetworkPolicyStatsQuerier
+	apiPort                   int
 }
 
 func NewAgentQuerier(
@@ -60,16 +63,18 @@ func NewAgentQuerier(
 	ofClient openflow.Client,
 	ovsBridgeClient ovsconfig.OVSBridgeClient,
 	networkPolicyInfoQuerier querier.AgentNetworkPolicyInfoQuerier,
+	networkPolicyStatsQuerier querier.AgentNetworkPolicyStatsQuerier,
 	apiPort int,
 ) *agentQuerier {
 	return &agentQuerier{
-		nodeConfig:               nodeConfig,
-		interfaceStore:           interfaceStore,
-		k8sClient:                k8sClient,
-		ofClient:                 ofClient,
-		ovsBridgeClient:          ovsBridgeClient,
-		networkPolicyInfoQuerier: networkPolicyInfoQuerier,
-		apiPort:                  apiPort}
+		nodeConfig:                nodeConfig,
+		interfaceStore:            interfaceStore,
+		k8sClient:                 k8sClient,
+		ofClient:                  ofClient,
+		ovsBridgeClient:           ovsBridgeClient,
+		networkPolicyInfoQuerier:  networkPolicyInfoQuerier,
+		networkPolicyStatsQuerier: networkPolicyStatsQuerier,
+		apiPort:                   apiPort}
 }
 
 // GetNodeConfig returns NodeConfig.
@@ -173,7 +178,8 @@ func (aq agentQuerier) getNetworkPolicyControllerInfo() v1beta1.NetworkPolicyCon
 
 // GetAgentInfo gets current agent pod info.
 func (aq agentQuerier) GetAgentInfo(agentInfo *v1beta1.AntreaAgentInfo, partial bool) {
-	// LocalPodNum, FlowTable, NetworkPolicyControllerInfo, OVSVersion and AgentConditions can be changed, so reset these fields.
+	// LocalPodNum, FlowTable, NetworkPolicyControllerInfo, OVSVersion, AgentConditions and NetworkPolicyStats can be
+	// changed, so reset these fields.
 	// Only these fields are updated when partial is true.
 	agentInfo.Name = aq.nodeConfig.Name
 	agentInfo.LocalPodNum = int32(aq.interfaceStore.GetContainerInterfaceNum())
@@ -187,6 +193,9 @@ func (aq agentQuerier) GetAgentInfo(agentInfo *v1beta1.AntreaAgentInfo, partial
 		agentInfo.OVSInfo.Version = ovsVersion
 	}
 	agentInfo.AgentConditions = aq.getAgentConditions(ovsConnected)
+	if aq.networkPolicyStatsQuerier != nil {
+		agentInfo.NetworkPolicyStats = aq.networkPolicyStatsQuerier.GetNetworkPolicyStats()
+	}
 
 	// Some other fields are needed when partial if false.
 	if !partial {
@@ -43,4 +43,13 @@ type Interface interface {
 	// UnMigrateRoutesFromGw should move routes back from local gateway to original device linkName
 	// if linkName is nil, it should remove the routes.
 	UnMigrateRoutesFromGw(route *net.IPNet, linkName string) error
+
+	// AddSNATRule should add the rule which SNATs the packets with the provided mark to snatIP, when they leave
+	// the Node from another interface than the local gateway. It should override the rule of the mark if it
+	// already exists, without error.
+	AddSNATRule(snatIP net.IP, mark uint32) error
+
+	// DeleteSNATRule should delete the rule added by AddSNATRule for the mark.
+	// It should do nothing if the rule doesn't exist, without error.
+	DeleteSNATRule(mark uint32) error
 }
@@ -20,6 +20,7 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"sync"
 
@@ -56,6 +57,10 @@ const (
 	// antreaPodIPSet contains all Pod CIDRs of this cluster.
 	antreaPodIPSet = "ANTREA-POD-IP"
 
+	// snatMarkMask is the mask of the packet marks matched by the SNAT rules.
+	// The OVS flows of the Egresses set the lowest 8 bits of the marks.
+	snatMarkMask = 0xff
+
 	// Antrea managed iptables chains.
 	antreaForwardChain     = "ANTREA-FORWARD"
 	antreaPostRoutingChain = "ANTREA-POSTROUTING"
@@ -82,6 +87,11 @@ type Client struct {
 	serviceRtTable *serviceRtTableConfig
 	// nodeRoutes caches ip routes to remote Pods. It's a map of podCIDR to routes.
 	nodeRoutes sync.Map
+	// snatRules is a map of the packet marks to the IPs the packets are SNATed
+	// to, protected by snatMutex. The iptables rules are rewritten by
+	// initIPTables whenever it changes.
+	snatMutex sync.Mutex
+	snatRules map[uint32]net.IP
 }
 
 type serviceRtTableConfig struct {
@@ -115,6 +125,7 @@ func NewClient(serviceCIDR *net.IPNet, encapMode config.TrafficEncapModeType) (*
 		encapMode:      encapMode,
 		ipt:            ipt,
 		serviceRtTable: serviceRtTable,
+		snatRules:      map[uint32]net.IP{},
 	}, nil
 }
 
@@ -266,6 +277,17 @@ func (c *Client) initIPTables() error {
 	writeLine(iptablesData, "*nat")
 	writeLine(iptablesData, iptables.MakeChainLine(antreaPostRoutingChain))
 	if !c.encapMode.IsNetworkPolicyOnly() {
+		// The packets matching an egress rule with preserveSourceIP are
+		// marked by OVS and must be neither SNATed nor masqueraded.
+		writeLine(iptablesData, []string{
+			"-A", antreaPostRoutingChain,
+			"-m", "comment", "--comment", `"Antrea: preserve source IP of pod packets"`,
+			"-m", "mark", "--mark", fmt.Sprintf("%#x/%#x", openflow.PreserveSourceIPPktMark, openflow.PreserveSourceIPPktMark),
+			"-j", iptables.ReturnTarget,
+		}...)
+		// The SNAT rules must come before the masquerade rule, which also
+		// matches the packets of the local Pods.
+		c.writeSNATRules(iptablesData)
 		writeLine(iptablesData, []string{
 			"-A", antreaPostRoutingChain,
 			"-m", "comment", "--comment", `"Antrea: masquerade pod to external packets"`,
@@ -294,6 +316,25 @@ func (c *Client) initIPTables() error {
 	return nil
 }
 
+// writeSNATRules writes the rules which SNAT the marked packets to the
+// iptablesData buffer, ordered by mark. The packets are marked by the OVS flows
+// of the Egresses whose IP is assigned to this Node.
+func (c *Client) writeSNATRules(iptablesData *bytes.Buffer) {
+	marks := make([]uint32, 0, len(c.snatRules))
+	for mark := range c.snatRules {
+		marks = append(marks, mark)
+	}
+	sort.Slice(marks, func(i, j int) bool { return marks[i] < marks[j] })
+	for _, mark := range marks {
+		writeLine(iptablesData, []string{
+			"-A", antreaPostRoutingChain,
+			"-m", "comment", "--comment", `"Antrea: SNAT pod to external packets"`,
+			"!", "-o", c.nodeConfig.GatewayConfig.Name, "-m", "mark", "--mark", fmt.Sprintf("%#x/%#x", mark, snatMarkMask),
+			"-j", iptables.SNATTarget, "--to-source", c.snatRules[mark].String(),
+		}...)
+	}
+}
+
 func (c *Client) initIPRoutes() error {
 	if c.serviceRtTable.IsMainTable() {
 		_ = c.removeServiceRouting()
@@ -373,7 +414,14 @@ func (c *Client) AddRoutes(podCIDR *net.IPNet, nodeIP, nodeGwIP net.IP) error {
 	// If service route table and main route table is not the same , add
 	// peer CIDR to main route table too (i.e in NoEncap and hybrid mode)
 	if !c.serviceRtTable.IsMainTable() {
-		if c.encapMode.NeedsEncapToPeer(nodeIP, c.nodeConfig.NodeIPAddr) {
+		if c.nodeConfig.WireGuardConfig != nil {
+			// Pod traffic to the peer Node is encrypted by the WireGuard device.
+			routes = append(routes, &netlink.Route{
+				Dst:       podCIDR,
+				LinkIndex: c.nodeConfig.WireGuardConfig.LinkIndex,
+				Scope:     netlink.SCOPE_LINK,
+			})
+		} else if c.encapMode.NeedsEncapToPeer(nodeIP, c.nodeConfig.NodeIPAddr) {
 			// need overlay tunnel
 			routes = append(routes, &netlink.Route{
 				Dst:       podCIDR,
@@ -733,3 +781,32 @@ func (c *Client) UnMigrateRoutesFromGw(route *net.IPNet, linkName string) error
 	}
 	return nil
 }
+
+// AddSNATRule adds the iptables rule which SNATs the packets with the provided
+// mark to snatIP. It rewrites the Antrea chains with the new set of rules.
+func (c *Client) AddSNATRule(snatIP net.IP, mark uint32) error {
+	c.snatMutex.Lock()
+	defer c.snatMutex.Unlock()
+	c.snatRules[mark] = snatIP
+	if err := c.initIPTables(); err != nil {
+		delete(c.snatRules, mark)
+		return fmt.Errorf("failed to add SNAT rule for mark %#x: %v", mark, err)
+	}
+	return nil
+}
+
+// DeleteSNATRule deletes the iptables rule added by AddSNATRule for the mark.
+func (c *Client) DeleteSNATRule(mark uint32) error {
+	c.snatMutex.Lock()
+	defer c.snatMutex.Unlock()
+	snatIP, ok := c.snatRules[mark]
+	if !ok {
+		return nil
+	}
+	delete(c.snatRules, mark)
+	if err := c.initIPTables(); err != nil {
+		c.snatRules[mark] = snatIP
+		return fmt.Errorf("failed to delete SNAT rule for mark %#x: %v", mark, err)
+	}
+	return nil
+}
@@ -150,6 +150,16 @@ func (c *Client) UnMigrateRoutesFromGw(route *net.IPNet, linkName string) error
 	return errors.New("UnMigrateRoutesFromGw is unsupported on Windows")
 }
 
+// AddSNATRule is not supported on Windows.
+func (c *Client) AddSNATRule(snatIP net.IP, mark uint32) error {
+	return errors.New("AddSNATRule is unsupported on Windows")
+}
+
+// DeleteSNATRule is not supported on Windows.
+func (c *Client) DeleteSNATRule(mark uint32) error {
+	return errors.New("DeleteSNATRule is unsupported on Windows")
+}
+
 func (c *Client) listRoutes() (map[string]*netroute.Route, error) {
 	routes, err := c.nr.GetNetRoutesAll()
 	if err != nil {
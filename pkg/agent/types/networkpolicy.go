@@ -42,22 +42,56 @@ type Address interface {
 
 // PolicyRule groups configurations to set up conjunctive match for egress/ingress policy rules.
 type PolicyRule struct {
-	Direction v1beta1.Direction
-	From      []Address
-	To        []Address
-	Service   []v1beta1.Service
-	Action    *secv1alpha1.RuleAction
-	Priority  *uint16
+	Direction        v1beta1.Direction
+	From             []Address
+	To               []Address
+	Service          []v1beta1.Service
+	Action           *secv1alpha1.RuleAction
+	Priority         *uint16
+	EnableLogging    bool
+	HTTPMatches      []v1beta1.HTTPMatch
+	PreserveSourceIP bool
+	// Baseline indicates that the rule belongs to a baseline ClusterNetworkPolicy:
+	// the traffic it allows is still subject to K8s NetworkPolicies.
+	Baseline bool
 }
 
 func (r *PolicyRule) IsAntreaNetworkPolicyRule() bool {
 	return r.Priority != nil
 }
 
-// Priority is a struct that is composed of CNP priority, rule priority and
-// tier/category priority in the future. It is used as the basic unit for
-// priority sorting.
+// Priority is a struct that is composed of tier priority, CNP priority and
+// rule priority. It is used as the basic unit for priority sorting. The CNPs
+// with the same tier priority and CNP priority are ordered by their creation
+// timestamp, then by their name, so that no two rules of different CNPs share
+// the same Priority.
 type Priority struct {
+	TierPriority   int32
 	PolicyPriority float64
-	RulePriority   int32
+	// PolicyCreationTimestamp is the creation time of the CNP, in seconds
+	// since the Unix epoch.
+	PolicyCreationTimestamp int64
+	PolicyName              string
+	RulePriority            int32
+}
+
+// PolicyType is the type of a NetworkPolicy.
+type PolicyType string
+
+const (
+	K8sNetworkPolicy           PolicyType = "K8sNetworkPolicy"
+	AntreaNetworkPolicy        PolicyType = "AntreaNetworkPolicy"
+	AntreaClusterNetworkPolicy PolicyType = "AntreaClusterNetworkPolicy"
+)
+
+// PolicyRuleRef references a NetworkPolicy rule, it's used to attribute
+// traffic to the rule.
+type PolicyRuleRef struct {
+	PolicyName string
+	// PolicyNamespace is empty for ClusterNetworkPolicy.
+	PolicyNamespace string
+	PolicyType      PolicyType
+	// RulePriority is the priority of the rule within the NetworkPolicy. It's
+	// -1 for k8s NetworkPolicy.
+	RulePriority int32
 }
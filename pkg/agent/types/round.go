@@ -22,4 +22,8 @@ type RoundInfo struct {
 	// PrevRoundNum is nil if this is the first round or the previous round
 	// number could not be retrieved.
 	PrevRoundNum *uint64
+	// FlushFlows is true if all the existing flows, regardless of their round
+	// number, must be deleted before the flows of this round are installed. It
+	// is set when the flows were installed with a different OpenFlow pipeline.
+	FlushFlows bool
 }
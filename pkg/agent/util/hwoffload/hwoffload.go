@@ -0,0 +1,148 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hwoffload
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ESwitchModeLegacy is the default eswitch mode of a NIC, in which the NIC
+	// switches packets itself and no representor ports are exposed.
+	ESwitchModeLegacy = "legacy"
+	// ESwitchModeSwitchdev is the eswitch mode required for TC flower offload,
+	// in which the NIC exposes representor ports to the kernel.
+	ESwitchModeSwitchdev = "switchdev"
+)
+
+// GetPCIAddress returns the PCI address of the NIC backing the provided
+// interface, as reported by "ethtool -i".
+func GetPCIAddress(ifName string) (string, error) {
+	output, err := exec.Command("ethtool", "-i", ifName).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error getting driver information of interface %s: %v, output: %s", ifName, err, output)
+	}
+	busInfo, err := parseBusInfo(string(output))
+	if err != nil {
+		return "", fmt.Errorf("error getting PCI address of interface %s: %v", ifName, err)
+	}
+	return busInfo, nil
+}
+
+// GetESwitchMode returns the eswitch mode of the NIC with the provided PCI
+// address, as reported by "devlink dev eswitch show".
+func GetESwitchMode(pciAddr string) (string, error) {
+	dev := "pci/" + pciAddr
+	output, err := exec.Command("devlink", "dev", "eswitch", "show", dev).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error getting eswitch mode of device %s: %v, output: %s", dev, err, output)
+	}
+	return parseESwitchMode(string(output))
+}
+
+// sysClassNetPath is the sysfs directory of the network interfaces, it is a variable so that tests
+// can use a fake one.
+var sysClassNetPath = "/sys/class/net"
+
+// getSwitchID returns the ID of the eswitch the interface is a port of, or an empty string if the
+// interface is not an eswitch port. Reading the attribute fails for such interfaces.
+func getSwitchID(ifName string) string {
+	switchID, err := ioutil.ReadFile(filepath.Join(sysClassNetPath, ifName, "phys_switch_id"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(switchID))
+}
+
+// GetRepresentors returns the names of the representor ports of the eswitch the provided interface
+// belongs to, i.e. the other interfaces with the same switch ID.
+func GetRepresentors(ifName string) ([]string, error) {
+	switchID := getSwitchID(ifName)
+	if switchID == "" {
+		return nil, fmt.Errorf("interface %s is not a port of an eswitch", ifName)
+	}
+	entries, err := ioutil.ReadDir(sysClassNetPath)
+	if err != nil {
+		return nil, fmt.Errorf("error listing network interfaces: %v", err)
+	}
+	var representors []string
+	for _, entry := range entries {
+		if name := entry.Name(); name != ifName && getSwitchID(name) == switchID {
+			representors = append(representors, name)
+		}
+	}
+	return representors, nil
+}
+
+// CheckSwitchdev checks that the NIC backing the provided interface can be used for hardware
+// offload, i.e. that it is in switchdev mode and exposes representor ports. The eswitch mode is not
+// changed: switching the NIC and setting up its representors must be done before the agent starts,
+// as the Node's traffic would be disrupted otherwise. An error is returned if the NIC cannot be used.
+func CheckSwitchdev(ifName string) error {
+	pciAddr, err := GetPCIAddress(ifName)
+	if err != nil {
+		return err
+	}
+	mode, err := GetESwitchMode(pciAddr)
+	if err != nil {
+		return err
+	}
+	if mode != ESwitchModeSwitchdev {
+		return fmt.Errorf("eswitch of device %s is in %s mode, not %s", pciAddr, mode, ESwitchModeSwitchdev)
+	}
+	representors, err := GetRepresentors(ifName)
+	if err != nil {
+		return err
+	}
+	if len(representors) == 0 {
+		return fmt.Errorf("no representor port found for interface %s", ifName)
+	}
+	return nil
+}
+
+// parseBusInfo extracts the "bus-info" field from the output of "ethtool -i".
+// Virtual devices (e.g. veth, bridges) report an empty bus-info or "N/A".
+func parseBusInfo(output string) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ":", 2)
+		if len(fields) != 2 || strings.TrimSpace(fields[0]) != "bus-info" {
+			continue
+		}
+		busInfo := strings.TrimSpace(fields[1])
+		if busInfo == "" || busInfo == "N/A" {
+			return "", fmt.Errorf("interface is not backed by a PCI device")
+		}
+		return busInfo, nil
+	}
+	return "", fmt.Errorf("bus-info not found in ethtool output")
+}
+
+// parseESwitchMode extracts the mode from the output of "devlink dev eswitch
+// show", e.g. "pci/0000:03:00.0: mode switchdev inline-mode none encap enable".
+func parseESwitchMode(output string) (string, error) {
+	fields := strings.Fields(output)
+	for i := 0; i < len(fields)-1; i++ {
+		if fields[i] == "mode" {
+			return fields[i+1], nil
+		}
+	}
+	return "", fmt.Errorf("eswitch mode not found in devlink output: %q", strings.TrimSpace(output))
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hwoffload
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBusInfo(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      string
+		wantBusInfo string
+		wantErr     bool
+	}{
+		{
+			name: "pci-device",
+			output: `driver: mlx5_core
+version: 5.0-0
+firmware-version: 16.27.2008 (MT_0000000080)
+expansion-rom-version:
+bus-info: 0000:03:00.0
+supports-statistics: yes
+`,
+			wantBusInfo: "0000:03:00.0",
+		},
+		{
+			name: "virtual-device",
+			output: `driver: veth
+version: 1.0
+bus-info:
+supports-statistics: yes
+`,
+			wantErr: true,
+		},
+		{
+			name:    "not-applicable",
+			output:  "driver: virtio_net\nbus-info: N/A\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing",
+			output:  "driver: bridge\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			busInfo, err := parseBusInfo(tt.output)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantBusInfo, busInfo)
+		})
+	}
+}
+
+func TestParseESwitchMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		wantMode string
+		wantErr  bool
+	}{
+		{
+			name:     "switchdev",
+			output:   "pci/0000:03:00.0: mode switchdev inline-mode none encap enable\n",
+			wantMode: ESwitchModeSwitchdev,
+		},
+		{
+			name:     "legacy",
+			output:   "pci/0000:03:00.0: mode legacy inline-mode none encap disable\n",
+			wantMode: ESwitchModeLegacy,
+		},
+		{
+			name:    "no-mode",
+			output:  "pci/0000:03:00.0:\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := parseESwitchMode(tt.output)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMode, mode)
+		})
+	}
+}
+
+func TestGetRepresentors(t *testing.T) {
+	root, err := ioutil.TempDir("", "test-sys-class-net")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	defer func(path string) { sysClassNetPath = path }(sysClassNetPath)
+	sysClassNetPath = root
+
+	// ens1f0 is the uplink of the eswitch, eth0 and eth1 are its representors. ens2 is a NIC in
+	// legacy mode, which has no switch ID.
+	for name, switchID := range map[string]string{"ens1f0": "2a5e3b0003", "eth0": "2a5e3b0003", "eth1": "2a5e3b0003", "ens2": ""} {
+		require.NoError(t, os.Mkdir(filepath.Join(root, name), 0755))
+		if switchID != "" {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(root, name, "phys_switch_id"), []byte(switchID+"\n"), 0644))
+		}
+	}
+
+	representors, err := GetRepresentors("ens1f0")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"eth0", "eth1"}, representors)
+
+	_, err = GetRepresentors("ens2")
+	assert.Error(t, err)
+}
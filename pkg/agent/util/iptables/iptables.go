@@ -34,8 +34,10 @@ const (
 
 	AcceptTarget     = "ACCEPT"
 	MasqueradeTarget = "MASQUERADE"
+	SNATTarget       = "SNAT"
 	MarkTarget       = "MARK"
 	ConnTrackTarget  = "CT"
+	ReturnTarget     = "RETURN"
 
 	PreRoutingChain  = "PREROUTING"
 	ForwardChain     = "FORWARD"
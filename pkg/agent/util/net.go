@@ -72,6 +72,13 @@ func GenerateContainerInterfaceName(podName, podNamespace, containerID string) s
 	return generateInterfaceName(containerID, podName, true)
 }
 
+// GenerateSecondaryInterfaceName generates a unique interface name for the
+// secondary network interface ifName of a container, in the same way as
+// GenerateContainerInterfaceName.
+func GenerateSecondaryInterfaceName(podName, podNamespace, containerID, ifName string) string {
+	return generateInterfaceName(fmt.Sprintf("%s/%s", containerID, ifName), podName, true)
+}
+
 // GenerateNodeTunnelInterfaceName generates a unique interface name for the
 // tunnel to the Node, using the Node's name.
 func GenerateNodeTunnelInterfaceName(nodeName string) string {
@@ -47,6 +47,24 @@ func TestGenerateContainerInterfaceName(t *testing.T) {
 	}
 }
 
+func TestGenerateSecondaryInterfaceName(t *testing.T) {
+	podNamespace := "namespace1"
+	podName := "pod1-abcde-12345"
+	containerID := "container0"
+	primary := GenerateContainerInterfaceName(podName, podNamespace, containerID)
+	net1 := GenerateSecondaryInterfaceName(podName, podNamespace, containerID, "net1")
+	net2 := GenerateSecondaryInterfaceName(podName, podNamespace, containerID, "net2")
+	if len(net1) != interfaceNameLength {
+		t.Errorf("Failed to ensure length of interface name as %d", interfaceNameLength)
+	}
+	if !strings.HasPrefix(net1, "pod1-abc") {
+		t.Errorf("failed to use first 8 valid characters")
+	}
+	if net1 == primary || net1 == net2 {
+		t.Errorf("failed to differentiate the interfaces of the same container")
+	}
+}
+
 func TestGetDefaultLocalNodeAddr(t *testing.T) {
 	conn, err := net.Dial("udp", "8.8.8.8:80")
 	if err != nil {
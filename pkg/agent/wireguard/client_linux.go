@@ -0,0 +1,309 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/config"
+)
+
+const (
+	// keyRotationRetryInterval is the interval after which a failed key
+	// rotation is retried.
+	keyRotationRetryInterval = 1 * time.Minute
+	// keySwitchDelay is the time between the publication of the next public
+	// key of the Node and the switch to it. It lets the other agents receive
+	// the next public key before the switch, so that they update their peer
+	// at the time the device switches to the next private key.
+	keySwitchDelay = 30 * time.Second
+)
+
+// peer is the configuration of a peer Node applied to the WireGuard device.
+type peer struct {
+	publicKey  string
+	endpoint   string
+	allowedIPs string
+}
+
+// Client implements Interface with the kernel WireGuard module. The device is
+// configured with the wg command line tool.
+type Client struct {
+	k8sClient           clientset.Interface
+	nodeConfig          *config.NodeConfig
+	keyRotationInterval time.Duration
+	// mutex protects the WireGuard device configuration and peers.
+	mutex sync.Mutex
+	// peers caches the peers configured on the WireGuard device, keyed by
+	// the name of the peer Node.
+	peers map[string]*peer
+	// nextPrivateKey and nextPublicKey are the key pair the device switches
+	// to while the key pair is rotated. They are empty otherwise.
+	nextPrivateKey string
+	nextPublicKey  string
+}
+
+// NewClient returns a WireGuard client. nodeConfig.WireGuardConfig must be set.
+func NewClient(k8sClient clientset.Interface, nodeConfig *config.NodeConfig, keyRotationInterval time.Duration) *Client {
+	return &Client{
+		k8sClient:           k8sClient,
+		nodeConfig:          nodeConfig,
+		keyRotationInterval: keyRotationInterval,
+		peers:               map[string]*peer{},
+	}
+}
+
+// Init creates the WireGuard device if it doesn't exist and configures it with
+// a newly generated private key. Existing peers are removed from the device as
+// they are added back by the NodeRouteController.
+func (c *Client) Init() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	wgConfig := c.nodeConfig.WireGuardConfig
+	link := &netlink.GenericLink{
+		LinkAttrs: netlink.LinkAttrs{Name: wgConfig.Name},
+		LinkType:  "wireguard",
+	}
+	if err := netlink.LinkAdd(link); err != nil && err != unix.EEXIST {
+		return fmt.Errorf("error creating WireGuard device %s: %v", wgConfig.Name, err)
+	}
+	wgLink, err := netlink.LinkByName(wgConfig.Name)
+	if err != nil {
+		return fmt.Errorf("error getting WireGuard device %s: %v", wgConfig.Name, err)
+	}
+	// NodeMTU already accounts for the WireGuard overhead.
+	if err := netlink.LinkSetMTU(wgLink, c.nodeConfig.NodeMTU); err != nil {
+		return fmt.Errorf("error setting MTU of WireGuard device %s: %v", wgConfig.Name, err)
+	}
+	if err := netlink.LinkSetUp(wgLink); err != nil {
+		return fmt.Errorf("error setting WireGuard device %s up: %v", wgConfig.Name, err)
+	}
+	wgConfig.LinkIndex = wgLink.Attrs().Index
+
+	privateKey, publicKey, err := generateKeyPair()
+	if err != nil {
+		return err
+	}
+	// "wg syncconf" removes the peers which are not in the provided
+	// configuration, i.e. all the peers left by a previous run.
+	deviceConfig := fmt.Sprintf("[Interface]\nPrivateKey = %s\nListenPort = %d\n", privateKey, wgConfig.Port)
+	if err := runWG(deviceConfig, "syncconf", wgConfig.Name, "/dev/stdin"); err != nil {
+		return err
+	}
+	c.peers = map[string]*peer{}
+	c.nextPrivateKey, c.nextPublicKey = "", ""
+	// The next public key published by a previous run is removed, so that
+	// the peers don't switch to it.
+	if err := c.patchAnnotations(map[string]interface{}{
+		PublicKeyAnnotationKey:     publicKey,
+		NextPublicKeyAnnotationKey: nil,
+		KeySwitchTimeAnnotationKey: nil,
+	}); err != nil {
+		return err
+	}
+	klog.Infof("Initialized WireGuard device %s", wgConfig)
+	return nil
+}
+
+// UpdatePeer adds or updates the peer Node on the WireGuard device. Traffic to
+// the PodCIDR of the peer Node is encrypted with its public key and sent to its
+// Node IP.
+func (c *Client) UpdatePeer(nodeName, publicKey string, nodeIP net.IP, podCIDR *net.IPNet) error {
+	if err := validatePublicKey(publicKey); err != nil {
+		return fmt.Errorf("invalid WireGuard public key of Node %s: %v", nodeName, err)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	desiredPeer := &peer{
+		publicKey:  publicKey,
+		endpoint:   net.JoinHostPort(nodeIP.String(), strconv.Itoa(c.nodeConfig.WireGuardConfig.Port)),
+		allowedIPs: podCIDR.String(),
+	}
+	existingPeer, exists := c.peers[nodeName]
+	if exists && *existingPeer == *desiredPeer {
+		return nil
+	}
+	// The peer is identified by its public key on the device, so the peer
+	// with the previous key must be removed when the key is rotated.
+	if exists && existingPeer.publicKey != publicKey {
+		if err := c.removePeer(existingPeer.publicKey); err != nil {
+			return err
+		}
+		delete(c.peers, nodeName)
+	}
+	if err := runWG("", "set", c.nodeConfig.WireGuardConfig.Name, "peer", desiredPeer.publicKey,
+		"endpoint", desiredPeer.endpoint, "allowed-ips", desiredPeer.allowedIPs); err != nil {
+		return err
+	}
+	c.peers[nodeName] = desiredPeer
+	klog.V(2).Infof("Updated WireGuard peer of Node %s with endpoint %s and allowed IPs %s", nodeName, desiredPeer.endpoint, desiredPeer.allowedIPs)
+	return nil
+}
+
+// DeletePeer removes the peer Node from the WireGuard device.
+func (c *Client) DeletePeer(nodeName string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	existingPeer, exists := c.peers[nodeName]
+	if !exists {
+		return nil
+	}
+	if err := c.removePeer(existingPeer.publicKey); err != nil {
+		return err
+	}
+	delete(c.peers, nodeName)
+	klog.V(2).Infof("Deleted WireGuard peer of Node %s", nodeName)
+	return nil
+}
+
+// Run rotates the key pair of the Node every keyRotationInterval until stopCh
+// is closed. The rotation is done in two steps, as the WireGuard device has a
+// single private key and a peer which is configured with the previous public
+// key of the Node can no longer complete a handshake with it:
+//  1. the next public key is published in the annotations of the Node, with
+//     the time of the switch, keySwitchDelay later.
+//  2. at that time, the device switches to the next private key, and the next
+//     public key is published as the public key of the Node. The other agents
+//     update their peer at the same time, as they have received the next
+//     public key during the first step.
+//
+// Traffic to and from the peers can only be lost between the switch of the
+// device and the update of a peer, i.e. during the clock skew between the
+// Nodes. Neither the OVS flows nor the conntrack entries are touched, so
+// established connections are preserved.
+func (c *Client) Run(stopCh <-chan struct{}) {
+	if c.keyRotationInterval <= 0 {
+		return
+	}
+	timer := time.NewTimer(c.keyRotationInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-timer.C:
+			next, err := c.rotateKey()
+			if err != nil {
+				klog.Errorf("Failed to rotate WireGuard key, will retry in %v: %v", keyRotationRetryInterval, err)
+				next = keyRotationRetryInterval
+			}
+			timer.Reset(next)
+		}
+	}
+}
+
+// rotateKey runs the next step of the key rotation, and returns the time after
+// which rotateKey must be called again.
+func (c *Client) rotateKey() (time.Duration, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.nextPrivateKey == "" {
+		return c.publishNextKey()
+	}
+	if err := c.switchKey(); err != nil {
+		return 0, err
+	}
+	return c.keyRotationInterval, nil
+}
+
+// publishNextKey generates the next key pair, and publishes its public key with
+// the time of the switch to it. It returns the time left before the switch.
+func (c *Client) publishNextKey() (time.Duration, error) {
+	privateKey, publicKey, err := generateKeyPair()
+	if err != nil {
+		return 0, err
+	}
+	// The switch time is published with a precision of one second.
+	switchTime := time.Now().Add(keySwitchDelay).Truncate(time.Second)
+	if err := c.patchAnnotations(map[string]interface{}{
+		NextPublicKeyAnnotationKey: publicKey,
+		KeySwitchTimeAnnotationKey: switchTime.Format(time.RFC3339),
+	}); err != nil {
+		return 0, err
+	}
+	c.nextPrivateKey, c.nextPublicKey = privateKey, publicKey
+	klog.Infof("Published next WireGuard public key of device %s, switching to it at %v", c.nodeConfig.WireGuardConfig.Name, switchTime)
+	return time.Until(switchTime), nil
+}
+
+// switchKey sets the next private key on the device, and publishes the next
+// public key as the public key of the Node. The other agents switch to the next
+// public key at the switch time even if the annotations are not updated yet.
+func (c *Client) switchKey() error {
+	if err := runWG(c.nextPrivateKey, "set", c.nodeConfig.WireGuardConfig.Name, "private-key", "/dev/stdin"); err != nil {
+		return err
+	}
+	if err := c.patchAnnotations(map[string]interface{}{
+		PublicKeyAnnotationKey:     c.nextPublicKey,
+		NextPublicKeyAnnotationKey: nil,
+		KeySwitchTimeAnnotationKey: nil,
+	}); err != nil {
+		return err
+	}
+	c.nextPrivateKey, c.nextPublicKey = "", ""
+	klog.Infof("Rotated WireGuard key of device %s", c.nodeConfig.WireGuardConfig.Name)
+	return nil
+}
+
+func (c *Client) removePeer(publicKey string) error {
+	return runWG("", "set", c.nodeConfig.WireGuardConfig.Name, "peer", publicKey, "remove")
+}
+
+// patchAnnotations sets the WireGuard annotations of the Node, so that the
+// other Nodes can add this Node as a peer. The annotations with a nil value are
+// removed.
+func (c *Client) patchAnnotations(annotations map[string]interface{}) error {
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if _, err := c.k8sClient.CoreV1().Nodes().Patch(context.TODO(), c.nodeConfig.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("error publishing WireGuard public key of Node %s: %v", c.nodeConfig.Name, err)
+	}
+	return nil
+}
+
+// runWG runs the wg command with the provided arguments. Keys are passed via
+// stdin so that they don't show up in the process list.
+func runWG(stdin string, args ...string) error {
+	cmd := exec.Command("wg", args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error running wg %s: %v, output: %s", strings.Join(args, " "), err, string(output))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// +build windows
+
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/config"
+)
+
+var errNotSupported = errors.New("WireGuard is not supported on Windows")
+
+// Client is a placeholder, WireGuard traffic encryption is not supported on Windows.
+type Client struct{}
+
+// NewClient returns a WireGuard client.
+func NewClient(k8sClient clientset.Interface, nodeConfig *config.NodeConfig, keyRotationInterval time.Duration) *Client {
+	return &Client{}
+}
+
+func (c *Client) Init() error {
+	return errNotSupported
+}
+
+func (c *Client) UpdatePeer(nodeName, publicKey string, nodeIP net.IP, podCIDR *net.IPNet) error {
+	return errNotSupported
+}
+
+func (c *Client) DeletePeer(nodeName string) error {
+	return errNotSupported
+}
+
+func (c *Client) Run(stopCh <-chan struct{}) {
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"net"
+)
+
+const (
+	// DefaultDeviceName is the name of the WireGuard device created by the agent.
+	DefaultDeviceName = "antrea-wg0"
+	// PublicKeyAnnotationKey is the annotation of a Node which holds the
+	// WireGuard public key of the Node, encoded in base64.
+	PublicKeyAnnotationKey = "wireguard.antrea.io/public-key"
+	// NextPublicKeyAnnotationKey is the annotation of a Node which holds the
+	// public key the Node switches to at the time held by the
+	// KeySwitchTimeAnnotationKey annotation, while its key pair is rotated.
+	NextPublicKeyAnnotationKey = "wireguard.antrea.io/next-public-key"
+	// KeySwitchTimeAnnotationKey is the annotation of a Node which holds the
+	// time at which the Node and its peers switch to the next public key of
+	// the Node, in RFC 3339 format.
+	KeySwitchTimeAnnotationKey = "wireguard.antrea.io/key-switch-time"
+)
+
+// Interface is the interface for encrypting Pod traffic between Nodes with WireGuard.
+type Interface interface {
+	// Init should create and configure the WireGuard device, and publish the
+	// public key of the Node in its annotation, discarding any key rotation
+	// in progress.
+	// It should be idempotent and can be safely called on every startup.
+	Init() error
+
+	// UpdatePeer should add the peer Node to the WireGuard device, or update
+	// it if its public key, IP or PodCIDR has changed.
+	UpdatePeer(nodeName, publicKey string, nodeIP net.IP, podCIDR *net.IPNet) error
+
+	// DeletePeer should remove the peer Node from the WireGuard device.
+	// It should do nothing if the peer doesn't exist, without error.
+	DeletePeer(nodeName string) error
+
+	// Run should rotate the key pair of the Node periodically until stopCh is
+	// closed. The next public key must be published before the WireGuard
+	// device switches to it, so that the peers switch at the same time.
+	Run(stopCh <-chan struct{})
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+const keyLen = 32
+
+// generateKeyPair generates a Curve25519 key pair for WireGuard and returns
+// the private key and the public key, both encoded in base64.
+func generateKeyPair() (string, string, error) {
+	privateKey := make([]byte, keyLen)
+	if _, err := rand.Read(privateKey); err != nil {
+		return "", "", fmt.Errorf("error generating private key: %v", err)
+	}
+	// Clamp the private key as described in https://cr.yp.to/ecdh.html.
+	privateKey[0] &= 248
+	privateKey[31] &= 127
+	privateKey[31] |= 64
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	if err != nil {
+		return "", "", fmt.Errorf("error computing public key: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(privateKey), base64.StdEncoding.EncodeToString(publicKey), nil
+}
+
+// validatePublicKey checks that the provided public key is a base64 encoded
+// Curve25519 key.
+func validatePublicKey(publicKey string) error {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return fmt.Errorf("public key %s is not base64 encoded: %v", publicKey, err)
+	}
+	if len(key) != keyLen {
+		return fmt.Errorf("public key %s has %d bytes, expected %d", publicKey, len(key), keyLen)
+	}
+	return nil
+}
+
+// PeerPublicKey returns the public key a peer Node with the provided
+// annotations must be configured with at the provided time. While the key pair
+// of the Node is rotated, the time left before the Node switches to its next
+// public key is returned too, after which the peer must be configured again.
+// It is 0 otherwise. ok is false if the Node hasn't published its public key.
+func PeerPublicKey(annotations map[string]string, now time.Time) (publicKey string, switchAfter time.Duration, ok bool) {
+	publicKey, ok = annotations[PublicKeyAnnotationKey]
+	nextPublicKey, rotating := annotations[NextPublicKeyAnnotationKey]
+	if !rotating {
+		return publicKey, 0, ok
+	}
+	switchTime, err := time.Parse(time.RFC3339, annotations[KeySwitchTimeAnnotationKey])
+	if err != nil {
+		return publicKey, 0, ok
+	}
+	if switchAfter = switchTime.Sub(now); switchAfter > 0 && ok {
+		return publicKey, switchAfter, true
+	}
+	return nextPublicKey, 0, true
+}
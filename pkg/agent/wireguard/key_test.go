@@ -0,0 +1,126 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestGenerateKeyPair(t *testing.T) {
+	privateKey, publicKey, err := generateKeyPair()
+	require.NoError(t, err)
+	assert.NoError(t, validatePublicKey(publicKey))
+
+	privateKeyBytes, err := base64.StdEncoding.DecodeString(privateKey)
+	require.NoError(t, err)
+	require.Len(t, privateKeyBytes, keyLen)
+	expectedPublicKey, err := curve25519.X25519(privateKeyBytes, curve25519.Basepoint)
+	require.NoError(t, err)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(expectedPublicKey), publicKey)
+
+	_, otherPublicKey, err := generateKeyPair()
+	require.NoError(t, err)
+	assert.NotEqual(t, publicKey, otherPublicKey)
+}
+
+func TestValidatePublicKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		publicKey string
+		expectErr bool
+	}{
+		{"valid", base64.StdEncoding.EncodeToString(make([]byte, keyLen)), false},
+		{"not-base64", "not a key", true},
+		{"wrong-length", base64.StdEncoding.EncodeToString(make([]byte, keyLen-1)), true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePublicKey(tt.publicKey)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPeerPublicKey(t *testing.T) {
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                string
+		annotations         map[string]string
+		expectedPublicKey   string
+		expectedSwitchAfter time.Duration
+		expectedOK          bool
+	}{
+		{
+			name:        "not published",
+			annotations: map[string]string{},
+		},
+		{
+			name:              "not rotating",
+			annotations:       map[string]string{PublicKeyAnnotationKey: "key1"},
+			expectedPublicKey: "key1",
+			expectedOK:        true,
+		},
+		{
+			name: "before switch",
+			annotations: map[string]string{
+				PublicKeyAnnotationKey:     "key1",
+				NextPublicKeyAnnotationKey: "key2",
+				KeySwitchTimeAnnotationKey: "2020-10-01T12:00:30Z",
+			},
+			expectedPublicKey:   "key1",
+			expectedSwitchAfter: 30 * time.Second,
+			expectedOK:          true,
+		},
+		{
+			name: "at switch",
+			annotations: map[string]string{
+				PublicKeyAnnotationKey:     "key1",
+				NextPublicKeyAnnotationKey: "key2",
+				KeySwitchTimeAnnotationKey: "2020-10-01T12:00:00Z",
+			},
+			expectedPublicKey: "key2",
+			expectedOK:        true,
+		},
+		{
+			name: "invalid switch time",
+			annotations: map[string]string{
+				PublicKeyAnnotationKey:     "key1",
+				NextPublicKeyAnnotationKey: "key2",
+				KeySwitchTimeAnnotationKey: "soon",
+			},
+			expectedPublicKey: "key1",
+			expectedOK:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publicKey, switchAfter, ok := PeerPublicKey(tt.annotations, now)
+			assert.Equal(t, tt.expectedPublicKey, publicKey)
+			assert.Equal(t, tt.expectedSwitchAfter, switchAfter)
+			assert.Equal(t, tt.expectedOK, ok)
+		})
+	}
+}
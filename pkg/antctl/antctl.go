@@ -21,13 +21,26 @@ import (
 	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/agentinfo"
 	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/ovsflows"
 	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/ovstracing"
+	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/podflows"
 	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/podinterface"
+	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/proxystats"
 	"github.com/vmware-tanzu/antrea/pkg/agent/openflow"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/checkconnectivity"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/diffflows"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/ipsecstatus"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/loglevel"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/rotateipseckey"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/servicestatus"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/simulatepolicy"
 	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/supportbundle"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/traceflow"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/traceflowhistory"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/tracepacket"
 	"github.com/vmware-tanzu/antrea/pkg/antctl/transform/addressgroup"
 	"github.com/vmware-tanzu/antrea/pkg/antctl/transform/appliedtogroup"
 	"github.com/vmware-tanzu/antrea/pkg/antctl/transform/controllerinfo"
 	"github.com/vmware-tanzu/antrea/pkg/antctl/transform/networkpolicy"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/transform/networkpolicystats"
 	"github.com/vmware-tanzu/antrea/pkg/antctl/transform/version"
 	networkingv1beta1 "github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
 	systemv1beta1 "github.com/vmware-tanzu/antrea/pkg/apis/system/v1beta1"
@@ -268,6 +281,109 @@ var CommandList = &commandList{
 			commandGroup:        get,
 			transformedResponse: reflect.TypeOf(ovsflows.Response{}),
 		},
+		{
+			use:   "flows",
+			short: "Dump the OVS flows of a local Pod",
+			long:  "Dump the OVS flows which match packets received from the OpenFlow port of a local Pod (in_port) or output packets to it (output).",
+			example: `  Dump the OVS flows of a local Pod
+  $ antctl get flows --pod ns1/pod1
+  Dump the OVS flows of a local Pod in a flow Table
+  $ antctl get flows --pod ns1/pod1 -T Classification
+  Dump the OVS flows of a local Pod in JSON format
+  $ antctl get flows --pod ns1/pod1 -o json`,
+			agentEndpoint: &endpoint{
+				nonResourceEndpoint: &nonResourceEndpoint{
+					path: "/podflows",
+					params: []flagInfo{
+						{
+							name:      "pod",
+							usage:     "Namespace and name of a local Pod, in the form <namespace>/<name>",
+							shorthand: "p",
+						},
+						{
+							name:      "table",
+							usage:     "Antrea OVS flow table name or number",
+							shorthand: "T",
+						},
+					},
+					outputType: multiple,
+				},
+			},
+			commandGroup:        get,
+			transformedResponse: reflect.TypeOf(podflows.Response{}),
+		},
+		{
+			use:     "proxystats",
+			aliases: []string{"ps"},
+			short:   "Print AntreaProxy traffic statistics",
+			long:    "Print the traffic statistics of the Service ports implemented by AntreaProxy, aggregated from the OVS flow counters of their Endpoints.",
+			example: `  Get the statistics of a Service
+  $ antctl get proxystats svc1 -n ns1
+  Get the statistics of all Services in a Namespace
+  $ antctl get proxystats -n ns1
+  Get the statistics of all Services
+  $ antctl get proxystats`,
+			agentEndpoint: &endpoint{
+				nonResourceEndpoint: &nonResourceEndpoint{
+					path: "/proxystats",
+					params: []flagInfo{
+						{
+							name:  "name",
+							usage: "Retrieve the statistics of a Service by name.",
+							arg:   true,
+						},
+						{
+							name:      "namespace",
+							usage:     "Get the statistics of the Services in a specific Namespace",
+							shorthand: "n",
+						},
+					},
+					outputType: multiple,
+				},
+			},
+			commandGroup:        get,
+			transformedResponse: reflect.TypeOf(proxystats.Response{}),
+		},
+		{
+			use:     "networkpolicystats",
+			aliases: []string{"nps"},
+			short:   "Print NetworkPolicy traffic statistics",
+			long:    "Print the traffic statistics of the NetworkPolicies, collected from the OVS flow counters of their rules. The controller sums the statistics reported by all the agents, in which case a NetworkPolicy is retrieved by UID.",
+			example: `  Get the statistics of a NetworkPolicy (supported by agent only)
+  $ antctl get networkpolicystats np1 -n ns1
+  Get the statistics of all NetworkPolicies in a Namespace (supported by agent only)
+  $ antctl get networkpolicystats -n ns1
+  Get the statistics of a NetworkPolicy by UID (supported by controller only)
+  $ antctl get networkpolicystats 5bd2c3a5-fd7a-4d16-a5c6-aa2b1c16c1d9
+  Get the statistics of all NetworkPolicies
+  $ antctl get networkpolicystats`,
+			commandGroup: get,
+			controllerEndpoint: &endpoint{
+				resourceEndpoint: &resourceEndpoint{
+					groupVersionResource: &systemv1beta1.NetworkPolicyStatsVersionResource,
+				},
+				addonTransform: networkpolicystats.Transform,
+			},
+			agentEndpoint: &endpoint{
+				nonResourceEndpoint: &nonResourceEndpoint{
+					path: "/networkpolicystats",
+					params: []flagInfo{
+						{
+							name:  "name",
+							usage: "Retrieve the statistics of a NetworkPolicy by name. If present, Namespace must be provided for a K8s NetworkPolicy.",
+							arg:   true,
+						},
+						{
+							name:      "namespace",
+							usage:     "Get the statistics of the NetworkPolicies in a specific Namespace",
+							shorthand: "n",
+						},
+					},
+					outputType: multiple,
+				},
+			},
+			transformedResponse: reflect.TypeOf(networkpolicystats.Response{}),
+		},
 		{
 			use:   "trace-packet",
 			short: "OVS packet tracing",
@@ -324,6 +440,76 @@ var CommandList = &commandList{
 			supportAgent:      true,
 			supportController: true,
 		},
+		{
+			cobraCommand:      simulatepolicy.Command,
+			supportAgent:      false,
+			supportController: true,
+		},
+		{
+			cobraCommand:      rotateipseckey.Command,
+			supportAgent:      false,
+			supportController: true,
+		},
+		{
+			cobraCommand:      traceflowhistory.Command,
+			supportAgent:      false,
+			supportController: true,
+			commandGroup:      get,
+		},
+		{
+			cobraCommand:      ipsecstatus.Command,
+			supportAgent:      false,
+			supportController: true,
+			commandGroup:      get,
+		},
+		{
+			cobraCommand:      traceflow.Command,
+			supportAgent:      false,
+			supportController: true,
+			commandGroup:      flat,
+		},
+		{
+			cobraCommand:      tracepacket.Command,
+			supportAgent:      false,
+			supportController: true,
+			commandGroup:      flat,
+		},
+		{
+			cobraCommand:      checkconnectivity.Command,
+			supportAgent:      false,
+			supportController: true,
+			commandGroup:      flat,
+		},
+		{
+			cobraCommand:      diffflows.Command,
+			supportAgent:      true,
+			supportController: true,
+			commandGroup:      flat,
+		},
+		{
+			cobraCommand:      servicestatus.EndpointStatusCommand,
+			supportAgent:      true,
+			supportController: true,
+			commandGroup:      get,
+		},
+		{
+			cobraCommand:      servicestatus.ServiceLBStatusCommand,
+			supportAgent:      true,
+			supportController: true,
+			commandGroup:      get,
+		},
+		{
+			cobraCommand:      loglevel.SetCommand,
+			supportAgent:      true,
+			supportController: true,
+			commandGroup:      set,
+		},
+		{
+			cobraCommand:      loglevel.GetCommand,
+			supportAgent:      true,
+			supportController: true,
+			commandGroup:      get,
+		},
 	},
 	codec: scheme.Codecs,
 }
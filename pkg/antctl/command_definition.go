@@ -72,6 +72,7 @@ const (
 const (
 	flat commandGroup = iota
 	get
+	set
 )
 
 var groupCommands = map[commandGroup]*cobra.Command{
@@ -80,6 +81,11 @@ var groupCommands = map[commandGroup]*cobra.Command{
 		Short: "Get the status or resource of a topic",
 		Long:  "Get the status or resource of a topic",
 	},
+	set: {
+		Use:   "set",
+		Short: "Set the configuration of a topic",
+		Long:  "Set the configuration of a topic",
+	},
 }
 
 type endpointResponder interface {
@@ -161,6 +167,9 @@ type rawCommand struct {
 	cobraCommand      *cobra.Command
 	supportAgent      bool
 	supportController bool
+	// commandGroup represents the group of the command. The command is added to
+	// the root command if the group is flat.
+	commandGroup commandGroup
 }
 
 // commandDefinition defines options to create a cobra.Command for an antctl client.
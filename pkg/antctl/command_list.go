@@ -63,7 +63,11 @@ func (cl *commandList) ApplyToRootCommand(root *cobra.Command) {
 	for _, cmd := range cl.rawCommands {
 		if (runtime.Mode == runtime.ModeAgent && cmd.supportAgent) ||
 			(runtime.Mode == runtime.ModeController && cmd.supportController) {
-			root.AddCommand(cmd.cobraCommand)
+			if groupCommand, ok := groupCommands[cmd.commandGroup]; ok {
+				groupCommand.AddCommand(cmd.cobraCommand)
+			} else {
+				root.AddCommand(cmd.cobraCommand)
+			}
 		}
 	}
 
@@ -121,7 +125,12 @@ func (cl *commandList) GetDebugCommands(mode string) [][]string {
 	for i := range cl.rawCommands {
 		if mode == runtime.ModeController && cl.rawCommands[i].supportController ||
 			mode == runtime.ModeAgent && cl.rawCommands[i].supportAgent {
-			allCommands = append(allCommands, strings.Split(cl.rawCommands[i].cobraCommand.Use, " ")[:1])
+			var currentCommand []string
+			if group, ok := groupCommands[cl.rawCommands[i].commandGroup]; ok {
+				currentCommand = append(currentCommand, group.Use)
+			}
+			currentCommand = append(currentCommand, strings.Split(cl.rawCommands[i].cobraCommand.Use, " ")[0])
+			allCommands = append(allCommands, currentCommand)
 		}
 	}
 	return allCommands
@@ -0,0 +1,139 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package apiclient creates the clients used by the raw antctl commands to
+// access the non-resource APIs of the Antrea Agents and of the Antrea
+// Controller.
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+
+	agentapiserver "github.com/vmware-tanzu/antrea/pkg/agent/apiserver"
+	"github.com/vmware-tanzu/antrea/pkg/agent/controller/noderoute"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/runtime"
+	"github.com/vmware-tanzu/antrea/pkg/apis"
+	controllerapiserver "github.com/vmware-tanzu/antrea/pkg/apiserver"
+	antrea "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+)
+
+// agentHost returns the address of the API of the Antrea Agent running on the
+// Node.
+func agentHost(kubeconfig *rest.Config, nodeName string) (string, error) {
+	k8sClientset, err := kubernetes.NewForConfig(kubeconfig)
+	if err != nil {
+		return "", fmt.Errorf("error when creating K8s clientset: %w", err)
+	}
+	antreaClientset, err := antrea.NewForConfig(kubeconfig)
+	if err != nil {
+		return "", fmt.Errorf("error when creating antrea clientset: %w", err)
+	}
+	agentInfo, err := antreaClientset.ClusterinformationV1beta1().AntreaAgentInfos().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error when getting the Antrea Agent of Node %s: %w", nodeName, err)
+	}
+	node, err := k8sClientset.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error when getting Node %s: %w", nodeName, err)
+	}
+	ip, err := noderoute.GetNodeAddr(node)
+	if err != nil {
+		return "", fmt.Errorf("error when getting the IP of Node %s: %w", nodeName, err)
+	}
+	return net.JoinHostPort(ip.String(), fmt.Sprint(agentInfo.APIPort)), nil
+}
+
+// controllerHost returns the address of the API of the Antrea Controller.
+func controllerHost(kubeconfig *rest.Config) (string, error) {
+	k8sClientset, err := kubernetes.NewForConfig(kubeconfig)
+	if err != nil {
+		return "", fmt.Errorf("error when creating K8s clientset: %w", err)
+	}
+	antreaClientset, err := antrea.NewForConfig(kubeconfig)
+	if err != nil {
+		return "", fmt.Errorf("error when creating antrea clientset: %w", err)
+	}
+	controllerInfo, err := antreaClientset.ClusterinformationV1beta1().AntreaControllerInfos().Get(context.TODO(), "antrea-controller", metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error when getting the Antrea Controller: %w", err)
+	}
+	node, err := k8sClientset.CoreV1().Nodes().Get(context.TODO(), controllerInfo.NodeRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error when getting the Node of the Antrea Controller: %w", err)
+	}
+	ip, err := noderoute.GetNodeAddr(node)
+	if err != nil {
+		return "", fmt.Errorf("error when getting the IP of Node %s: %w", node.Name, err)
+	}
+	return net.JoinHostPort(ip.String(), fmt.Sprint(controllerInfo.APIPort)), nil
+}
+
+// newClient returns a REST client to access the non-resource API served at the
+// host of kubeconfig.
+// TODO: enable secure connection.
+func newClient(kubeconfig *rest.Config) (*rest.RESTClient, error) {
+	kubeconfig.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	kubeconfig.Insecure = true
+	kubeconfig.CAFile = ""
+	kubeconfig.CAData = nil
+	client, err := rest.UnversionedRESTClientFor(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("error when creating rest client: %w", err)
+	}
+	return client, nil
+}
+
+// NewAgentClient returns a REST client to access the non-resource API of an
+// Antrea Agent. If nodeName is not empty, the client accesses the Agent running
+// on that Node, otherwise it accesses the local Agent when running in the
+// antrea-agent Pod, or the server of kubeconfig.
+func NewAgentClient(kubeconfig *rest.Config, nodeName string) (*rest.RESTClient, error) {
+	kubeconfig = rest.CopyConfig(kubeconfig)
+	if nodeName != "" {
+		host, err := agentHost(kubeconfig, nodeName)
+		if err != nil {
+			return nil, err
+		}
+		kubeconfig.Host = host
+	} else if runtime.InPod && runtime.Mode == runtime.ModeAgent {
+		kubeconfig.Host = net.JoinHostPort("127.0.0.1", fmt.Sprint(apis.AntreaAgentAPIPort))
+		kubeconfig.BearerTokenFile = agentapiserver.TokenPath
+	}
+	return newClient(kubeconfig)
+}
+
+// NewControllerClient returns a REST client to access the non-resource API of
+// the Antrea Controller, which is accessed locally when running in the
+// antrea-controller Pod.
+func NewControllerClient(kubeconfig *rest.Config) (*rest.RESTClient, error) {
+	kubeconfig = rest.CopyConfig(kubeconfig)
+	if runtime.InPod && runtime.Mode == runtime.ModeController {
+		kubeconfig.Host = net.JoinHostPort("127.0.0.1", fmt.Sprint(apis.AntreaControllerAPIPort))
+		kubeconfig.BearerTokenFile = controllerapiserver.TokenPath
+	} else {
+		host, err := controllerHost(kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		kubeconfig.Host = host
+	}
+	return newClient(kubeconfig)
+}
@@ -0,0 +1,242 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkconnectivity
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1"
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+)
+
+// staleHeartbeatThreshold is the age after which the heartbeat of an Antrea
+// Agent is considered stale. Agents update their heartbeat every 60 seconds.
+const staleHeartbeatThreshold = 3 * time.Minute
+
+// analyze explains the result of a Traceflow.
+func (c *checker) analyze(ctx context.Context, tf *opsv1alpha1.Traceflow) TraceReport {
+	report := TraceReport{Traceflow: tf.Name}
+	var dropped, tunneled *Hop
+	delivered, received := false, false
+	for _, result := range tf.Status.Results {
+		for _, o := range result.Observations {
+			hop := &Hop{Node: result.Node, Role: result.Role, Observation: o}
+			report.Hops = append(report.Hops, *hop)
+			switch {
+			case o.Action == opsv1alpha1.Dropped && dropped == nil:
+				dropped = hop
+			case o.Action == opsv1alpha1.Delivered:
+				delivered = true
+			case o.Action == opsv1alpha1.Received:
+				received = true
+			case o.Action == opsv1alpha1.Forwarded && o.TunnelDstIP != "":
+				tunneled = hop
+			}
+		}
+	}
+
+	switch {
+	case dropped != nil:
+		report.Result = TraceDropped
+		switch dropped.Component {
+		case opsv1alpha1.NetworkPolicy:
+			report.Explanation = c.explainPolicyDrop(ctx, dropped)
+		case opsv1alpha1.LB:
+			report.Explanation = c.explainServiceDrop(ctx, dropped)
+		default:
+			explanation := fmt.Sprintf("The packet was dropped on Node %s by the %s component", dropped.Node, dropped.Component)
+			if dropped.ComponentInfo != "" {
+				explanation += fmt.Sprintf(" in table %s", dropped.ComponentInfo)
+			}
+			if dropped.Reason != "" {
+				explanation += fmt.Sprintf(": %s", dropped.Reason)
+			}
+			report.Explanation = []string{explanation}
+		}
+	case delivered:
+		report.Result = TraceDelivered
+	case tunneled != nil && !received:
+		// The packet left the source Node through the tunnel but was never
+		// received by the destination Node.
+		report.Result = TraceDropped
+		report.Explanation = c.explainTunnelDrop(ctx, tunneled)
+	case tf.Status.Phase == opsv1alpha1.Failed:
+		report.Result = TraceError
+		report.Explanation = []string{fmt.Sprintf("The Traceflow failed: %s", tf.Status.Reason)}
+	default:
+		report.Result = TraceIncomplete
+		report.Explanation = []string{"The Traceflow did not complete in time"}
+	}
+	return report
+}
+
+// explainPolicyDrop explains a drop by the NetworkPolicy component.
+func (c *checker) explainPolicyDrop(ctx context.Context, hop *Hop) []string {
+	direction, pod := networkingv1.PolicyTypeIngress, c.dst
+	if strings.HasPrefix(hop.ComponentInfo, "Egress") {
+		direction, pod = networkingv1.PolicyTypeEgress, c.src
+	}
+	if hop.NetworkPolicy != "" {
+		return []string{fmt.Sprintf("The packet was dropped on Node %s by an %s rule of NetworkPolicy %s (table %s)", hop.Node, strings.ToLower(string(direction)), hop.NetworkPolicy, hop.ComponentInfo)}
+	}
+	explanation := []string{fmt.Sprintf("The packet was dropped on Node %s by the default %s rule (table %s): %s is isolated for %s and no rule of its NetworkPolicies allows the packet",
+		hop.Node, strings.ToLower(string(direction)), hop.ComponentInfo, pod, strings.ToLower(string(direction)))}
+	if c.isService && direction == networkingv1.PolicyTypeIngress {
+		// The destination Pod is the selected Endpoint, which is not known.
+		return explanation
+	}
+	policies, err := c.policiesApplyingTo(ctx, pod, direction)
+	if err != nil {
+		return append(explanation, fmt.Sprintf("Cannot get the NetworkPolicies applied to %s: %v", pod, err))
+	}
+	if len(policies) > 0 {
+		explanation = append(explanation, fmt.Sprintf("NetworkPolicies applied to %s for %s: %s", pod, strings.ToLower(string(direction)), strings.Join(policies, ", ")))
+	}
+	return explanation
+}
+
+// policiesApplyingTo returns the names of the K8s NetworkPolicies which select
+// the Pod and apply to the direction.
+func (c *checker) policiesApplyingTo(ctx context.Context, e *endpoint, direction networkingv1.PolicyType) ([]string, error) {
+	pod, err := c.k8sClient.CoreV1().Pods(e.namespace).Get(ctx, e.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	policies, err := c.k8sClient.NetworkingV1().NetworkPolicies(e.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, np := range policies.Items {
+		selector, err := metav1.LabelSelectorAsSelector(&np.Spec.PodSelector)
+		if err != nil || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if policyAppliesTo(&np, direction) {
+			names = append(names, np.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// policyAppliesTo returns true if the NetworkPolicy applies to the direction,
+// following the defaulting of policyTypes by K8s.
+func policyAppliesTo(np *networkingv1.NetworkPolicy, direction networkingv1.PolicyType) bool {
+	if len(np.Spec.PolicyTypes) == 0 {
+		return direction == networkingv1.PolicyTypeIngress || len(np.Spec.Egress) > 0
+	}
+	for _, t := range np.Spec.PolicyTypes {
+		if t == direction {
+			return true
+		}
+	}
+	return false
+}
+
+// explainServiceDrop explains a drop by the LB component.
+func (c *checker) explainServiceDrop(ctx context.Context, hop *Hop) []string {
+	if !c.isService {
+		return []string{fmt.Sprintf("The packet was dropped on Node %s by the load balancer (table %s): %s", hop.Node, hop.ComponentInfo, hop.Reason)}
+	}
+	explanation := []string{fmt.Sprintf("The packet was dropped on Node %s by the load balancer (table %s) as Service %s has no Endpoints", hop.Node, hop.ComponentInfo, c.dst)}
+	endpoints, err := c.k8sClient.CoreV1().Endpoints(c.dst.namespace).Get(ctx, c.dst.name, metav1.GetOptions{})
+	if err != nil {
+		return append(explanation, fmt.Sprintf("Cannot get the Endpoints of Service %s: %v", c.dst, err))
+	}
+	ready, notReady := 0, 0
+	for _, subset := range endpoints.Subsets {
+		ready += len(subset.Addresses)
+		notReady += len(subset.NotReadyAddresses)
+	}
+	switch {
+	case ready > 0:
+		explanation = append(explanation, fmt.Sprintf("Service %s now has %d ready Endpoints, which may have been added after the trace", c.dst, ready))
+	case notReady > 0:
+		explanation = append(explanation, fmt.Sprintf("Service %s has no ready Endpoints, %d Endpoints are not ready", c.dst, notReady))
+	default:
+		explanation = append(explanation, fmt.Sprintf("Service %s has no Endpoints, check its selector matches running Pods", c.dst))
+	}
+	return explanation
+}
+
+// explainTunnelDrop explains a packet sent through the tunnel but not received
+// by the destination Node.
+func (c *checker) explainTunnelDrop(ctx context.Context, hop *Hop) []string {
+	explanation := []string{fmt.Sprintf("The packet was sent by Node %s through the tunnel to %s but was not received", hop.Node, hop.TunnelDstIP)}
+	nodeName, err := c.nodeWithIP(ctx, hop.TunnelDstIP)
+	if err != nil {
+		return append(explanation, fmt.Sprintf("Cannot get the destination Node: %v", err))
+	}
+	if nodeName == "" {
+		return append(explanation, fmt.Sprintf("No Node has IP %s, the tunnel destination may be stale", hop.TunnelDstIP))
+	}
+	explanation = append(explanation, fmt.Sprintf("The destination Node is %s", nodeName))
+	agentInfo, err := c.antreaClient.ClusterinformationV1beta1().AntreaAgentInfos().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return append(explanation, fmt.Sprintf("Cannot get the state of the Antrea Agent on Node %s: %v", nodeName, err))
+	}
+	return append(explanation, tunnelState(nodeName, agentInfo, time.Now()))
+}
+
+// tunnelState describes the state of the tunnel interface of a Node, as
+// reported by the Antrea Agent running on it.
+func tunnelState(nodeName string, agentInfo *v1beta1.AntreaAgentInfo, now time.Time) string {
+	var down []string
+	var lastHeartbeat time.Time
+	for _, condition := range agentInfo.AgentConditions {
+		switch condition.Type {
+		case v1beta1.AgentHealthy:
+			lastHeartbeat = condition.LastHeartbeatTime.Time
+		case v1beta1.OVSDBConnectionUp, v1beta1.OpenflowConnectionUp:
+			if condition.Status != v1.ConditionTrue {
+				down = append(down, string(condition.Type))
+			}
+		}
+	}
+	if len(down) > 0 {
+		return fmt.Sprintf("The tunnel interface on Node %s is down: the Antrea Agent reports %s not true", nodeName, strings.Join(down, " and "))
+	}
+	if lastHeartbeat.IsZero() || now.Sub(lastHeartbeat) > staleHeartbeatThreshold {
+		return fmt.Sprintf("The state of the tunnel interface on Node %s is unknown: the Antrea Agent has not reported its state since %s", nodeName, lastHeartbeat.UTC().Format(time.RFC3339))
+	}
+	return fmt.Sprintf("The tunnel interface on Node %s is up: check the network between the Nodes allows the tunnel traffic", nodeName)
+}
+
+// nodeWithIP returns the name of the Node which has the IP, or an empty string
+// if no Node has it.
+func (c *checker) nodeWithIP(ctx context.Context, ip string) (string, error) {
+	nodes, err := c.k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+	for _, node := range nodes.Items {
+		for _, address := range node.Status.Addresses {
+			if address.Address == ip && (address.Type == v1.NodeInternalIP || address.Type == v1.NodeExternalIP) {
+				return node.Name, nil
+			}
+		}
+	}
+	return "", nil
+}
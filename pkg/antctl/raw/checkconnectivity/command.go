@@ -0,0 +1,511 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkconnectivity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+
+	antctlruntime "github.com/vmware-tanzu/antrea/pkg/antctl/runtime"
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	antrea "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+)
+
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+
+	protocolICMP = 1
+	protocolTCP  = 6
+	protocolUDP  = 17
+	tcpFlagSYN   = 2
+
+	// checkTimeout bounds the runtime of the whole command.
+	checkTimeout = 9 * time.Second
+	// traceTimeout is how long to wait for the Traceflow to complete, leaving
+	// the rest of checkTimeout to explain its result.
+	traceTimeout = 7 * time.Second
+	// liveCheckTimeout is the timeout in seconds of the test connection made
+	// from the source Pod.
+	liveCheckTimeout = 3
+	// tracePollInterval is the interval between two polls of the Traceflow.
+	tracePollInterval = 200 * time.Millisecond
+)
+
+// Command is the check-connectivity command implementation.
+var Command *cobra.Command
+
+var option = &struct {
+	src        string
+	dst        string
+	dstService string
+	protocol   string
+	port       int32
+	output     string
+}{}
+
+var checkConnectivityLongDescription = strings.TrimSpace(`
+Check the connectivity from a source Pod to a destination Pod or Service, and explain why the connection succeeds or fails.
+The path of a packet is traced with Traceflow, and a test connection is made from the source Pod at the same time. When the
+traced packet is dropped, the report shows the NetworkPolicy which dropped it, the state of the tunnel to the destination Node,
+or the Endpoints of the destination Service, depending on where it was dropped. The command completes in less than 10 seconds.
+The test connection uses 'nc' for TCP and 'ping' for ICMP, which must be available in the source Pod; it is skipped for UDP.
+`)
+
+var checkConnectivityExample = strings.Trim(`
+  Check the connectivity from Pod web to TCP port 8080 of Pod db in Namespace default
+  $ antctl check-connectivity --src default/web --dst default/db --port 8080 --protocol tcp
+  Check the connectivity from Pod web to TCP port 80 of Service db, and output the report in JSON
+  $ antctl check-connectivity --src default/web --dst-service default/db --port 80 -o json
+`, "\n")
+
+func init() {
+	Command = &cobra.Command{
+		Use:     "check-connectivity",
+		Short:   "Check the connectivity between two Pods and explain the result",
+		Long:    checkConnectivityLongDescription,
+		Example: checkConnectivityExample,
+		Args:    cobra.NoArgs,
+		RunE:    runE,
+	}
+	Command.Flags().StringVar(&option.src, "src", "", "source Pod, specified by <Namespace>/<name>")
+	Command.Flags().StringVar(&option.dst, "dst", "", "destination Pod, specified by <Namespace>/<name>")
+	Command.Flags().StringVar(&option.dstService, "dst-service", "", "destination Service, specified by <Namespace>/<name>; cannot be used with --dst")
+	Command.Flags().StringVar(&option.protocol, "protocol", "tcp", "protocol of the connection, supports 'tcp', 'udp' and 'icmp'")
+	Command.Flags().Int32Var(&option.port, "port", 0, "destination port of the TCP or UDP connection")
+	Command.Flags().StringVarP(&option.output, "output", "o", outputFormatText, "output format, supports 'text' and 'json'")
+}
+
+// TraceResult is the outcome of the data-plane trace.
+type TraceResult string
+
+// LiveResult is the outcome of the test connection.
+type LiveResult string
+
+const (
+	TraceDelivered  TraceResult = "Delivered"
+	TraceDropped    TraceResult = "Dropped"
+	TraceIncomplete TraceResult = "Incomplete"
+	TraceError      TraceResult = "Error"
+
+	LiveSuccess LiveResult = "Success"
+	LiveFailure LiveResult = "Failure"
+	LiveSkipped LiveResult = "Skipped"
+	LiveError   LiveResult = "Error"
+)
+
+// Hop is an observation of the traced packet on a Node.
+type Hop struct {
+	Node string `json:"node"`
+	Role string `json:"role,omitempty"`
+	opsv1alpha1.Observation
+}
+
+// TraceReport is the report of the data-plane trace.
+type TraceReport struct {
+	Traceflow string      `json:"traceflow,omitempty"`
+	Result    TraceResult `json:"result"`
+	Hops      []Hop       `json:"hops,omitempty"`
+	// Explanation explains where and why the packet was dropped.
+	Explanation []string `json:"explanation,omitempty"`
+}
+
+// LiveCheckReport is the report of the test connection.
+type LiveCheckReport struct {
+	Command string     `json:"command,omitempty"`
+	Result  LiveResult `json:"result"`
+	Output  string     `json:"output,omitempty"`
+}
+
+// Report is the report of a connectivity check.
+type Report struct {
+	Source      string          `json:"source"`
+	Destination string          `json:"destination"`
+	Protocol    string          `json:"protocol"`
+	Port        int32           `json:"port,omitempty"`
+	Trace       TraceReport     `json:"trace"`
+	LiveCheck   LiveCheckReport `json:"liveCheck"`
+	Verdict     string          `json:"verdict"`
+}
+
+// endpoint is a Pod or a Service.
+type endpoint struct {
+	namespace string
+	name      string
+}
+
+func (e *endpoint) String() string {
+	return e.namespace + "/" + e.name
+}
+
+func parseEndpoint(s string) (*endpoint, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("%q is not specified by <Namespace>/<name>", s)
+	}
+	return &endpoint{namespace: parts[0], name: parts[1]}, nil
+}
+
+// checker runs a connectivity check.
+type checker struct {
+	k8sClient    kubernetes.Interface
+	antreaClient antrea.Interface
+	// execInPod runs a command in a container of a Pod.
+	execInPod func(namespace, pod, container string, cmd []string) (string, error)
+
+	src       *endpoint
+	dst       *endpoint
+	isService bool
+	protocol  string
+	port      int32
+}
+
+// newTraceflowSpec returns the spec of the Traceflow tracing the connection.
+func (c *checker) newTraceflowSpec() (*opsv1alpha1.TraceflowSpec, error) {
+	spec := &opsv1alpha1.TraceflowSpec{
+		Source: opsv1alpha1.Source{Namespace: c.src.namespace, Pod: c.src.name},
+	}
+	spec.Destination.Namespace = c.dst.namespace
+	if c.isService {
+		spec.Destination.Service = c.dst.name
+	} else {
+		spec.Destination.Pod = c.dst.name
+	}
+	switch c.protocol {
+	case "tcp":
+		spec.Packet.IPHeader.Protocol = protocolTCP
+		spec.Packet.TransportHeader.TCP = &opsv1alpha1.TCPHeader{DstPort: c.port, Flags: tcpFlagSYN}
+	case "udp":
+		spec.Packet.IPHeader.Protocol = protocolUDP
+		spec.Packet.TransportHeader.UDP = &opsv1alpha1.UDPHeader{DstPort: c.port}
+	case "icmp":
+		spec.Packet.IPHeader.Protocol = protocolICMP
+	default:
+		return nil, fmt.Errorf("unsupported protocol %s", c.protocol)
+	}
+	return spec, nil
+}
+
+// trace traces the connection with a Traceflow and explains the result.
+func (c *checker) trace(ctx context.Context) TraceReport {
+	spec, err := c.newTraceflowSpec()
+	if err != nil {
+		return TraceReport{Result: TraceError, Explanation: []string{err.Error()}}
+	}
+	tf := &opsv1alpha1.Traceflow{
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("check-connectivity-%s", rand.String(8))},
+		Spec:       *spec,
+	}
+	tf, err = c.antreaClient.OpsV1alpha1().Traceflows().Create(ctx, tf, metav1.CreateOptions{})
+	if err != nil {
+		return TraceReport{Result: TraceError, Explanation: []string{fmt.Sprintf("Error when creating Traceflow: %v", err)}}
+	}
+	defer c.antreaClient.OpsV1alpha1().Traceflows().Delete(context.TODO(), tf.Name, metav1.DeleteOptions{})
+
+	name := tf.Name
+	pollCtx, cancel := context.WithTimeout(ctx, traceTimeout)
+	defer cancel()
+	// An error means the Traceflow did not complete in time, which is
+	// reported by analyze.
+	wait.PollImmediateUntil(tracePollInterval, func() (bool, error) {
+		updated, err := c.antreaClient.OpsV1alpha1().Traceflows().Get(pollCtx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		tf = updated
+		return tf.Status.Phase == opsv1alpha1.Succeeded || tf.Status.Phase == opsv1alpha1.Failed, nil
+	}, pollCtx.Done())
+	return c.analyze(ctx, tf)
+}
+
+// liveCheckCommand returns the command making a test connection to the IP, or
+// nil if the protocol is not supported.
+func (c *checker) liveCheckCommand(ip string) []string {
+	switch c.protocol {
+	case "tcp":
+		return []string{"nc", "-z", "-w", fmt.Sprint(liveCheckTimeout), ip, fmt.Sprint(c.port)}
+	case "icmp":
+		return []string{"ping", "-c", "1", "-W", fmt.Sprint(liveCheckTimeout), ip}
+	}
+	return nil
+}
+
+// destinationIP returns the IP of the destination Pod, or the ClusterIP of the
+// destination Service.
+func (c *checker) destinationIP(ctx context.Context) (string, error) {
+	if c.isService {
+		svc, err := c.k8sClient.CoreV1().Services(c.dst.namespace).Get(ctx, c.dst.name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("error when getting Service %s: %w", c.dst, err)
+		}
+		if svc.Spec.ClusterIP == "" || svc.Spec.ClusterIP == v1.ClusterIPNone {
+			return "", fmt.Errorf("Service %s has no ClusterIP", c.dst)
+		}
+		return svc.Spec.ClusterIP, nil
+	}
+	pod, err := c.k8sClient.CoreV1().Pods(c.dst.namespace).Get(ctx, c.dst.name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error when getting Pod %s: %w", c.dst, err)
+	}
+	if pod.Status.PodIP == "" {
+		return "", fmt.Errorf("Pod %s has no IP", c.dst)
+	}
+	return pod.Status.PodIP, nil
+}
+
+// liveCheck makes a test connection from the source Pod to the destination.
+func (c *checker) liveCheck(ctx context.Context) LiveCheckReport {
+	if c.protocol == "udp" {
+		return LiveCheckReport{Result: LiveSkipped, Output: "UDP connections cannot be tested reliably"}
+	}
+	srcPod, err := c.k8sClient.CoreV1().Pods(c.src.namespace).Get(ctx, c.src.name, metav1.GetOptions{})
+	if err != nil {
+		return LiveCheckReport{Result: LiveError, Output: fmt.Sprintf("Error when getting Pod %s: %v", c.src, err)}
+	}
+	if len(srcPod.Spec.Containers) == 0 {
+		return LiveCheckReport{Result: LiveError, Output: fmt.Sprintf("Pod %s has no container", c.src)}
+	}
+	ip, err := c.destinationIP(ctx)
+	if err != nil {
+		return LiveCheckReport{Result: LiveError, Output: err.Error()}
+	}
+	cmd := c.liveCheckCommand(ip)
+	report := LiveCheckReport{Command: strings.Join(cmd, " ")}
+
+	type execResult struct {
+		output string
+		err    error
+	}
+	ch := make(chan execResult, 1)
+	go func() {
+		output, err := c.execInPod(c.src.namespace, c.src.name, srcPod.Spec.Containers[0].Name, cmd)
+		ch <- execResult{output, err}
+	}()
+	select {
+	case <-ctx.Done():
+		report.Result = LiveError
+		report.Output = "The test connection did not complete in time"
+	case r := <-ch:
+		report.Output = strings.TrimSpace(r.output)
+		if r.err == nil {
+			report.Result = LiveSuccess
+		} else if exitErr, ok := r.err.(utilexec.ExitError); ok && exitErr.ExitStatus() != 126 && exitErr.ExitStatus() != 127 {
+			// 126 and 127 mean the command cannot be run in the container.
+			report.Result = LiveFailure
+		} else {
+			report.Result = LiveError
+			if report.Output == "" {
+				report.Output = r.err.Error()
+			}
+		}
+	}
+	return report
+}
+
+// verdict combines the results of the trace and the test connection.
+func verdict(trace TraceResult, live LiveResult) string {
+	switch {
+	case trace == TraceDelivered && live == LiveSuccess:
+		return "Connected: the packet is delivered and the test connection succeeded"
+	case trace == TraceDelivered && live == LiveFailure:
+		return "Not connected: the packet is delivered to the destination but the test connection failed, check the destination is listening on the port"
+	case trace == TraceDelivered:
+		return "Probably connected: the packet is delivered but the connection could not be tested"
+	case trace == TraceDropped && live == LiveSuccess:
+		return "Inconsistent: the traced packet is dropped but the test connection succeeded, the NetworkPolicies may have changed during the check"
+	case trace == TraceDropped:
+		return "Not connected: the packet is dropped"
+	case live == LiveSuccess:
+		return "Connected: the test connection succeeded but the packet could not be traced"
+	case live == LiveFailure:
+		return "Not connected: the test connection failed and the packet could not be traced"
+	}
+	return "Unknown: neither the packet could be traced nor the connection tested"
+}
+
+// check runs the data-plane trace and the test connection in parallel, and
+// combines their results into a report.
+func (c *checker) check(ctx context.Context) *Report {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	report := &Report{
+		Source:      c.src.String(),
+		Destination: c.dst.String(),
+		Protocol:    strings.ToUpper(c.protocol),
+		Port:        c.port,
+	}
+	if c.isService {
+		report.Destination = "Service " + report.Destination
+	}
+	liveCh := make(chan LiveCheckReport, 1)
+	go func() {
+		liveCh <- c.liveCheck(ctx)
+	}()
+	report.Trace = c.trace(ctx)
+	report.LiveCheck = <-liveCh
+	report.Verdict = verdict(report.Trace.Result, report.LiveCheck.Result)
+	return report
+}
+
+func output(report *Report, format string, out io.Writer) error {
+	switch format {
+	case outputFormatJSON:
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	case outputFormatText:
+		var buf bytes.Buffer
+		destination := report.Destination
+		if report.Port != 0 {
+			destination = fmt.Sprintf("%s, %s port %d", destination, report.Protocol, report.Port)
+		} else {
+			destination = fmt.Sprintf("%s, %s", destination, report.Protocol)
+		}
+		fmt.Fprintf(&buf, "Checking connectivity from %s to %s\n\n", report.Source, destination)
+		trace := report.Trace
+		if trace.Traceflow != "" {
+			fmt.Fprintf(&buf, "Data-plane trace (Traceflow %s): %s\n", trace.Traceflow, trace.Result)
+		} else {
+			fmt.Fprintf(&buf, "Data-plane trace: %s\n", trace.Result)
+		}
+		for _, h := range trace.Hops {
+			component := string(h.Component)
+			if h.ComponentInfo != "" {
+				component += "/" + h.ComponentInfo
+			}
+			fmt.Fprintf(&buf, "  %s %s %s %s\n", h.Node, h.Role, component, h.Action)
+		}
+		for _, e := range trace.Explanation {
+			fmt.Fprintf(&buf, "  => %s\n", e)
+		}
+		live := report.LiveCheck
+		if live.Command != "" {
+			fmt.Fprintf(&buf, "Live check (%s): %s\n", live.Command, live.Result)
+		} else {
+			fmt.Fprintf(&buf, "Live check: %s\n", live.Result)
+		}
+		for _, line := range strings.Split(live.Output, "\n") {
+			if line != "" {
+				fmt.Fprintf(&buf, "  %s\n", line)
+			}
+		}
+		fmt.Fprintf(&buf, "\nVerdict: %s\n", report.Verdict)
+		_, err := buf.WriteTo(out)
+		return err
+	default:
+		return fmt.Errorf("unsupported output format %s", format)
+	}
+}
+
+// newExecInPod returns a function running a command in a container of a Pod
+// and returning its combined output.
+func newExecInPod(kubeconfig *rest.Config, client kubernetes.Interface) func(namespace, pod, container string, cmd []string) (string, error) {
+	return func(namespace, pod, container string, cmd []string) (string, error) {
+		request := client.CoreV1().RESTClient().Post().
+			Namespace(namespace).
+			Resource("pods").
+			Name(pod).
+			SubResource("exec").
+			Param("container", container).
+			VersionedParams(&v1.PodExecOptions{
+				Command: cmd,
+				Stdout:  true,
+				Stderr:  true,
+			}, scheme.ParameterCodec)
+		exec, err := remotecommand.NewSPDYExecutor(kubeconfig, "POST", request.URL())
+		if err != nil {
+			return "", err
+		}
+		var out bytes.Buffer
+		err = exec.Stream(remotecommand.StreamOptions{Stdout: &out, Stderr: &out})
+		return out.String(), err
+	}
+}
+
+func runE(cmd *cobra.Command, _ []string) error {
+	if option.output != outputFormatText && option.output != outputFormatJSON {
+		return fmt.Errorf("unsupported output format %s", option.output)
+	}
+	src, err := parseEndpoint(option.src)
+	if err != nil {
+		return fmt.Errorf("invalid source: %w", err)
+	}
+	if (option.dst == "") == (option.dstService == "") {
+		return fmt.Errorf("exactly one of --dst and --dst-service must be specified")
+	}
+	c := &checker{src: src, protocol: strings.ToLower(option.protocol), port: option.port}
+	if option.dst != "" {
+		c.dst, err = parseEndpoint(option.dst)
+	} else {
+		c.dst, err = parseEndpoint(option.dstService)
+		c.isService = true
+	}
+	if err != nil {
+		return fmt.Errorf("invalid destination: %w", err)
+	}
+	switch c.protocol {
+	case "tcp", "udp":
+		if c.port <= 0 || c.port > 65535 {
+			return fmt.Errorf("a valid destination port must be specified for %s", option.protocol)
+		}
+	case "icmp":
+		if c.port != 0 {
+			return fmt.Errorf("a destination port cannot be specified for ICMP")
+		}
+	default:
+		return fmt.Errorf("unsupported protocol %s", option.protocol)
+	}
+
+	kubeconfigPath, err := cmd.Flags().GetString("kubeconfig")
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := antctlruntime.ResolveKubeconfig(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	k8sClientset, err := kubernetes.NewForConfig(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error when creating K8s clientset: %w", err)
+	}
+	antreaClientset, err := antrea.NewForConfig(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error when creating antrea clientset: %w", err)
+	}
+	c.k8sClient = k8sClientset
+	c.antreaClient = antreaClientset
+	c.execInPod = newExecInPod(kubeconfig, k8sClientset)
+	return output(c.check(context.Background()), option.output, cmd.OutOrStdout())
+}
@@ -0,0 +1,386 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkconnectivity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	utilexec "k8s.io/client-go/util/exec"
+
+	"github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1"
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	antreafake "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+)
+
+var (
+	webPod = &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", Labels: map[string]string{"app": "web"}},
+		Spec:       v1.PodSpec{NodeName: "node1", Containers: []v1.Container{{Name: "web"}}},
+		Status:     v1.PodStatus{PodIP: "10.10.1.2"},
+	}
+	dbPod = &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db", Labels: map[string]string{"app": "db"}},
+		Spec:       v1.PodSpec{NodeName: "node2", Containers: []v1.Container{{Name: "db"}}},
+		Status:     v1.PodStatus{PodIP: "10.10.2.3"},
+	}
+	node2 = &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node2"},
+		Status:     v1.NodeStatus{Addresses: []v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "172.18.0.3"}}},
+	}
+	dbService = &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"},
+		Spec:       v1.ServiceSpec{ClusterIP: "10.96.0.10"},
+	}
+
+	senderResult = opsv1alpha1.NodeResult{
+		Node: "node1",
+		Role: "Sender",
+		Observations: []opsv1alpha1.Observation{
+			{Component: opsv1alpha1.SpoofGuard, Action: opsv1alpha1.Forwarded},
+			{Component: opsv1alpha1.Forwarding, ComponentInfo: "L2ForwardingOutput", Action: opsv1alpha1.Forwarded, TunnelDstIP: "172.18.0.3"},
+		},
+	}
+	receiverResult = opsv1alpha1.NodeResult{
+		Node: "node2",
+		Role: "Receiver",
+		Observations: []opsv1alpha1.Observation{
+			{Component: opsv1alpha1.Forwarding, ComponentInfo: "Classification", Action: opsv1alpha1.Received},
+			{Component: opsv1alpha1.Forwarding, ComponentInfo: "L2ForwardingOutput", Action: opsv1alpha1.Delivered},
+		},
+	}
+)
+
+func newChecker(isService bool, k8sObjects []runtime.Object, antreaObjects []runtime.Object) *checker {
+	return &checker{
+		k8sClient:    k8sfake.NewSimpleClientset(k8sObjects...),
+		antreaClient: antreafake.NewSimpleClientset(antreaObjects...),
+		src:          &endpoint{namespace: "default", name: "web"},
+		dst:          &endpoint{namespace: "default", name: "db"},
+		isService:    isService,
+		protocol:     "tcp",
+		port:         8080,
+	}
+}
+
+func newTraceflow(phase opsv1alpha1.TraceflowPhase, results ...opsv1alpha1.NodeResult) *opsv1alpha1.Traceflow {
+	return &opsv1alpha1.Traceflow{
+		ObjectMeta: metav1.ObjectMeta{Name: "tf"},
+		Status:     opsv1alpha1.TraceflowStatus{Phase: phase, Results: results},
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	policyDroppedResult := opsv1alpha1.NodeResult{
+		Node: "node2",
+		Role: "Receiver",
+		Observations: []opsv1alpha1.Observation{
+			{Component: opsv1alpha1.Forwarding, ComponentInfo: "Classification", Action: opsv1alpha1.Received},
+			{Component: opsv1alpha1.NetworkPolicy, ComponentInfo: "IngressDefaultRule", Action: opsv1alpha1.Dropped},
+		},
+	}
+	lbDroppedResult := opsv1alpha1.NodeResult{
+		Node: "node1",
+		Role: "Sender",
+		Observations: []opsv1alpha1.Observation{
+			{Component: opsv1alpha1.SpoofGuard, Action: opsv1alpha1.Forwarded},
+			{Component: opsv1alpha1.LB, ComponentInfo: "ServiceLB", Action: opsv1alpha1.Dropped, Reason: opsv1alpha1.ReasonNoEndpoints},
+		},
+	}
+	denyPolicy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "deny-db"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+		},
+	}
+	egressPolicy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "egress-db"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		},
+	}
+	otherPolicy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "deny-web"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+	}
+	notReadyEndpoints := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"},
+		Subsets:    []v1.EndpointSubset{{NotReadyAddresses: []v1.EndpointAddress{{IP: "10.10.2.3"}}}},
+	}
+	ovsDownAgentInfo := &v1beta1.AntreaAgentInfo{
+		ObjectMeta: metav1.ObjectMeta{Name: "node2"},
+		AgentConditions: []v1beta1.AgentCondition{
+			{Type: v1beta1.AgentHealthy, Status: v1.ConditionTrue, LastHeartbeatTime: metav1.Now()},
+			{Type: v1beta1.OpenflowConnectionUp, Status: v1.ConditionFalse},
+		},
+	}
+
+	tests := []struct {
+		name                string
+		isService           bool
+		k8sObjects          []runtime.Object
+		antreaObjects       []runtime.Object
+		traceflow           *opsv1alpha1.Traceflow
+		expectedResult      TraceResult
+		expectedHops        int
+		expectedExplanation []string
+	}{
+		{
+			name:           "delivered",
+			traceflow:      newTraceflow(opsv1alpha1.Succeeded, senderResult, receiverResult),
+			expectedResult: TraceDelivered,
+			expectedHops:   4,
+		},
+		{
+			name:           "dropped-by-default-rule",
+			k8sObjects:     []runtime.Object{webPod, dbPod, denyPolicy, egressPolicy, otherPolicy},
+			traceflow:      newTraceflow(opsv1alpha1.Succeeded, senderResult, policyDroppedResult),
+			expectedResult: TraceDropped,
+			expectedHops:   4,
+			expectedExplanation: []string{
+				"The packet was dropped on Node node2 by the default ingress rule (table IngressDefaultRule): default/db is isolated for ingress and no rule of its NetworkPolicies allows the packet",
+				"NetworkPolicies applied to default/db for ingress: deny-db",
+			},
+		},
+		{
+			name: "dropped-by-named-policy",
+			traceflow: newTraceflow(opsv1alpha1.Succeeded, opsv1alpha1.NodeResult{
+				Node: "node1",
+				Role: "Sender",
+				Observations: []opsv1alpha1.Observation{
+					{Component: opsv1alpha1.NetworkPolicy, ComponentInfo: "EgressRule", Action: opsv1alpha1.Dropped, NetworkPolicy: "default/cnp1"},
+				},
+			}),
+			expectedResult:      TraceDropped,
+			expectedHops:        1,
+			expectedExplanation: []string{"The packet was dropped on Node node1 by an egress rule of NetworkPolicy default/cnp1 (table EgressRule)"},
+		},
+		{
+			name:           "dropped-by-lb",
+			isService:      true,
+			k8sObjects:     []runtime.Object{dbService, notReadyEndpoints},
+			traceflow:      newTraceflow(opsv1alpha1.Succeeded, lbDroppedResult),
+			expectedResult: TraceDropped,
+			expectedHops:   2,
+			expectedExplanation: []string{
+				"The packet was dropped on Node node1 by the load balancer (table ServiceLB) as Service default/db has no Endpoints",
+				"Service default/db has no ready Endpoints, 1 Endpoints are not ready",
+			},
+		},
+		{
+			name:           "dropped-in-tunnel",
+			k8sObjects:     []runtime.Object{node2},
+			antreaObjects:  []runtime.Object{ovsDownAgentInfo},
+			traceflow:      newTraceflow(opsv1alpha1.Failed, senderResult),
+			expectedResult: TraceDropped,
+			expectedHops:   2,
+			expectedExplanation: []string{
+				"The packet was sent by Node node1 through the tunnel to 172.18.0.3 but was not received",
+				"The destination Node is node2",
+				"The tunnel interface on Node node2 is down: the Antrea Agent reports OpenflowConnectionUp not true",
+			},
+		},
+		{
+			name:                "failed",
+			traceflow:           &opsv1alpha1.Traceflow{Status: opsv1alpha1.TraceflowStatus{Phase: opsv1alpha1.Failed, Reason: "invalid destination"}},
+			expectedResult:      TraceError,
+			expectedExplanation: []string{"The Traceflow failed: invalid destination"},
+		},
+		{
+			name:                "incomplete",
+			traceflow:           newTraceflow(opsv1alpha1.Running),
+			expectedResult:      TraceIncomplete,
+			expectedExplanation: []string{"The Traceflow did not complete in time"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newChecker(tt.isService, tt.k8sObjects, tt.antreaObjects)
+			report := c.analyze(context.TODO(), tt.traceflow)
+			assert.Equal(t, tt.expectedResult, report.Result)
+			assert.Len(t, report.Hops, tt.expectedHops)
+			assert.Equal(t, tt.expectedExplanation, report.Explanation)
+		})
+	}
+}
+
+func TestTunnelState(t *testing.T) {
+	now := time.Date(2020, 7, 1, 10, 0, 0, 0, time.UTC)
+	agentInfo := &v1beta1.AntreaAgentInfo{
+		AgentConditions: []v1beta1.AgentCondition{
+			{Type: v1beta1.AgentHealthy, Status: v1.ConditionTrue, LastHeartbeatTime: metav1.NewTime(now.Add(-time.Minute))},
+			{Type: v1beta1.OVSDBConnectionUp, Status: v1.ConditionTrue},
+			{Type: v1beta1.OpenflowConnectionUp, Status: v1.ConditionTrue},
+		},
+	}
+	assert.Equal(t, "The tunnel interface on Node node2 is up: check the network between the Nodes allows the tunnel traffic", tunnelState("node2", agentInfo, now))
+	assert.Contains(t, tunnelState("node2", agentInfo, now.Add(10*time.Minute)), "is unknown")
+	agentInfo.AgentConditions[1].Status = v1.ConditionFalse
+	assert.Contains(t, tunnelState("node2", agentInfo, now), "is down")
+}
+
+func TestVerdict(t *testing.T) {
+	assert.True(t, strings.HasPrefix(verdict(TraceDelivered, LiveSuccess), "Connected"))
+	assert.True(t, strings.HasPrefix(verdict(TraceDelivered, LiveFailure), "Not connected"))
+	assert.True(t, strings.HasPrefix(verdict(TraceDelivered, LiveSkipped), "Probably connected"))
+	assert.True(t, strings.HasPrefix(verdict(TraceDropped, LiveFailure), "Not connected"))
+	assert.True(t, strings.HasPrefix(verdict(TraceDropped, LiveSuccess), "Inconsistent"))
+	assert.True(t, strings.HasPrefix(verdict(TraceIncomplete, LiveSuccess), "Connected"))
+	assert.True(t, strings.HasPrefix(verdict(TraceError, LiveError), "Unknown"))
+}
+
+// completeTraceflow plays the role of the Antrea agents: it completes the
+// Traceflow created by the checker with the results.
+func completeTraceflow(t *testing.T, c *checker, results ...opsv1alpha1.NodeResult) {
+	var tf *opsv1alpha1.Traceflow
+	err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		list, err := c.antreaClient.OpsV1alpha1().Traceflows().List(context.TODO(), metav1.ListOptions{})
+		if err != nil || len(list.Items) == 0 {
+			return false, err
+		}
+		tf = &list.Items[0]
+		return true, nil
+	})
+	if !assert.NoError(t, err, "Traceflow was not created") {
+		return
+	}
+	tf.Status.Phase = opsv1alpha1.Succeeded
+	tf.Status.Results = results
+	_, err = c.antreaClient.OpsV1alpha1().Traceflows().UpdateStatus(context.TODO(), tf, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name               string
+		protocol           string
+		port               int32
+		execErr            error
+		expectedCommand    []string
+		expectedLiveResult LiveResult
+		expectedVerdict    string
+	}{
+		{
+			name:               "tcp-connected",
+			protocol:           "tcp",
+			port:               8080,
+			expectedCommand:    []string{"nc", "-z", "-w", "3", "10.10.2.3", "8080"},
+			expectedLiveResult: LiveSuccess,
+			expectedVerdict:    "Connected",
+		},
+		{
+			name:               "tcp-refused",
+			protocol:           "tcp",
+			port:               8080,
+			execErr:            utilexec.CodeExitError{Err: fmt.Errorf("command terminated with exit code 1"), Code: 1},
+			expectedCommand:    []string{"nc", "-z", "-w", "3", "10.10.2.3", "8080"},
+			expectedLiveResult: LiveFailure,
+			expectedVerdict:    "Not connected",
+		},
+		{
+			name:               "icmp-without-ping",
+			protocol:           "icmp",
+			execErr:            utilexec.CodeExitError{Err: fmt.Errorf("command terminated with exit code 127"), Code: 127},
+			expectedCommand:    []string{"ping", "-c", "1", "-W", "3", "10.10.2.3"},
+			expectedLiveResult: LiveError,
+			expectedVerdict:    "Probably connected",
+		},
+		{
+			name:               "udp",
+			protocol:           "udp",
+			port:               53,
+			expectedLiveResult: LiveSkipped,
+			expectedVerdict:    "Probably connected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newChecker(false, []runtime.Object{webPod, dbPod}, nil)
+			c.protocol = tt.protocol
+			c.port = tt.port
+			var executed []string
+			c.execInPod = func(namespace, pod, container string, cmd []string) (string, error) {
+				assert.Equal(t, "default/web/web", namespace+"/"+pod+"/"+container)
+				executed = cmd
+				return "", tt.execErr
+			}
+			go completeTraceflow(t, c, senderResult, receiverResult)
+
+			start := time.Now()
+			report := c.check(context.TODO())
+			assert.Less(t, int64(time.Since(start)), int64(10*time.Second))
+			assert.Equal(t, tt.expectedCommand, executed)
+			assert.Equal(t, TraceDelivered, report.Trace.Result)
+			assert.Equal(t, tt.expectedLiveResult, report.LiveCheck.Result)
+			assert.True(t, strings.HasPrefix(report.Verdict, tt.expectedVerdict), "Unexpected verdict %s", report.Verdict)
+
+			list, err := c.antreaClient.OpsV1alpha1().Traceflows().List(context.TODO(), metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Empty(t, list.Items, "Traceflow should be deleted")
+		})
+	}
+}
+
+func TestOutput(t *testing.T) {
+	report := &Report{
+		Source:      "default/web",
+		Destination: "default/db",
+		Protocol:    "TCP",
+		Port:        8080,
+		Trace: TraceReport{
+			Traceflow:   "tf",
+			Result:      TraceDropped,
+			Hops:        []Hop{{Node: "node1", Role: "Sender", Observation: opsv1alpha1.Observation{Component: opsv1alpha1.NetworkPolicy, ComponentInfo: "EgressDefaultRule", Action: opsv1alpha1.Dropped}}},
+			Explanation: []string{"dropped"},
+		},
+		LiveCheck: LiveCheckReport{Command: "nc -z -w 3 10.10.2.3 8080", Result: LiveFailure},
+		Verdict:   "Not connected: the packet is dropped",
+	}
+	var buf bytes.Buffer
+	require.NoError(t, output(report, outputFormatText, &buf))
+	assert.Equal(t, `Checking connectivity from default/web to default/db, TCP port 8080
+
+Data-plane trace (Traceflow tf): Dropped
+  node1 Sender NetworkPolicy/EgressDefaultRule Dropped
+  => dropped
+Live check (nc -z -w 3 10.10.2.3 8080): Failure
+
+Verdict: Not connected: the packet is dropped
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, output(report, outputFormatJSON, &buf))
+	var decoded Report
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, *report, decoded)
+
+	assert.Error(t, output(report, "yaml", &buf))
+}
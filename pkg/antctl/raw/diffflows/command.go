@@ -0,0 +1,166 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diffflows
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/rest"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/desiredflows"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/apiclient"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/runtime"
+)
+
+const (
+	desiredFlowsPath = "/desiredflows"
+	requestTimeout   = 30 * time.Second
+)
+
+// Command is the diff-flows command implementation.
+var Command *cobra.Command
+
+var option = &struct {
+	node string
+	fix  bool
+}{}
+
+var diffFlowsLongDescription = strings.TrimSpace(`
+Compare the flows an Antrea Agent maintains for the current state with the flows installed in OVS, and print the
+difference in unified diff format: the lines prefixed with '-' are flows present in OVS but not desired, and the lines
+prefixed with '+' are desired flows not present in OVS. Flows are compared by table, priority and match conditions,
+regardless of the order in which OVS prints the match conditions; their actions are not compared, and the transient
+flows with a timeout are ignored.
+`)
+
+var diffFlowsExample = strings.Trim(`
+  Compare the desired flows and the OVS flows of the local Antrea Agent
+  $ antctl diff-flows
+  Compare the desired flows and the OVS flows of the Antrea Agent running on Node node1
+  $ antctl diff-flows --node node1
+  Compare the flows of the Antrea Agent running on Node node1, and reconcile the diverged flows
+  $ antctl diff-flows --node node1 --fix
+`, "\n")
+
+func init() {
+	Command = &cobra.Command{
+		Use:     "diff-flows",
+		Short:   "Compare the desired flows of an Antrea Agent with the OVS flows",
+		Long:    diffFlowsLongDescription,
+		Example: diffFlowsExample,
+		Args:    cobra.NoArgs,
+		RunE:    runE,
+	}
+	if runtime.Mode == runtime.ModeController {
+		Command.Flags().StringVar(&option.node, "node", "", "name of the Node whose Antrea Agent is checked")
+	}
+	Command.Flags().BoolVar(&option.fix, "fix", false, "reconcile the diverged flows: install the missing flows and delete the unexpected ones")
+}
+
+// requestDesiredFlows gets the desired and actual flows from the agent API, and
+// reconciles the diverged flows if fix is true.
+func requestDesiredFlows(client rest.Interface, fix bool) (*desiredflows.Response, error) {
+	request := client.Get()
+	if fix {
+		request = client.Post()
+	}
+	data, err := request.AbsPath(desiredFlowsPath).Timeout(requestTimeout).DoRaw(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("error when requesting the desired flows: %w", err)
+	}
+	var resp desiredflows.Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("error when decoding the desired flows: %w", err)
+	}
+	return &resp, nil
+}
+
+// tableOf returns the table number of a normalized flow.
+func tableOf(flow string) int {
+	var table int
+	fmt.Sscanf(flow, "table=%d", &table)
+	return table
+}
+
+// printDiff prints the difference between the flows in unified diff format,
+// with a hunk per table. The unexpected flows are printed before the missing
+// flows of the same table.
+func printDiff(diff *desiredflows.FlowDiff, component string, out io.Writer) {
+	if len(diff.Missing) == 0 && len(diff.Unexpected) == 0 {
+		fmt.Fprintf(out, "The OVS flows of %s are in sync with the desired flows\n", component)
+		return
+	}
+	var lines []string
+	for _, flow := range diff.Unexpected {
+		lines = append(lines, "-"+flow)
+	}
+	for _, flow := range diff.Missing {
+		lines = append(lines, "+"+flow)
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return tableOf(lines[i][1:]) < tableOf(lines[j][1:])
+	})
+	fmt.Fprintf(out, "--- OVS flows of %s\n", component)
+	fmt.Fprintf(out, "+++ desired flows of %s\n", component)
+	table := -1
+	for _, line := range lines {
+		if t := tableOf(line[1:]); t != table {
+			table = t
+			fmt.Fprintf(out, "@@ table=%d @@\n", table)
+		}
+		fmt.Fprintln(out, line)
+	}
+}
+
+func runE(cmd *cobra.Command, _ []string) error {
+	kubeconfigPath, err := cmd.Flags().GetString("kubeconfig")
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := runtime.ResolveKubeconfig(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	component := "the local Antrea Agent"
+	if runtime.Mode == runtime.ModeController {
+		if option.node == "" {
+			return fmt.Errorf("the Node must be specified with --node")
+		}
+		component = "the Antrea Agent of Node " + option.node
+	}
+	client, err := apiclient.NewAgentClient(kubeconfig, option.node)
+	if err != nil {
+		return err
+	}
+
+	resp, err := requestDesiredFlows(client, option.fix)
+	if err != nil {
+		return err
+	}
+	diff := desiredflows.Diff(resp.DesiredFlows, resp.ActualFlows)
+	out := cmd.OutOrStdout()
+	printDiff(diff, component, out)
+	if resp.Fixed && (len(diff.Missing) > 0 || len(diff.Unexpected) > 0) {
+		fmt.Fprintf(out, "Reconciled %d missing and %d unexpected flows\n", len(diff.Missing), len(diff.Unexpected))
+	}
+	return nil
+}
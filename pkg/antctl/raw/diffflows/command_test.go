@@ -0,0 +1,103 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diffflows
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/desiredflows"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/apiclient"
+)
+
+func TestPrintDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		diff     *desiredflows.FlowDiff
+		expected string
+	}{
+		{
+			name:     "in sync",
+			diff:     &desiredflows.FlowDiff{},
+			expected: "The OVS flows of agent are in sync with the desired flows\n",
+		},
+		{
+			name: "diverged",
+			diff: &desiredflows.FlowDiff{
+				Missing: []string{
+					"table=31,priority=190,ct_state=+inv+trk,ip",
+					"table=70,priority=200,ip,nw_dst=10.10.0.2",
+				},
+				Unexpected: []string{
+					"table=10,priority=200,in_port=0x2",
+					"table=70,priority=200,ip,nw_dst=10.10.0.3",
+				},
+			},
+			expected: `--- OVS flows of agent
++++ desired flows of agent
+@@ table=10 @@
+-table=10,priority=200,in_port=0x2
+@@ table=31 @@
++table=31,priority=190,ct_state=+inv+trk,ip
+@@ table=70 @@
+-table=70,priority=200,ip,nw_dst=10.10.0.3
++table=70,priority=200,ip,nw_dst=10.10.0.2
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			printDiff(tt.diff, "agent", out)
+			assert.Equal(t, tt.expected, out.String())
+		})
+	}
+}
+
+func TestRequestDesiredFlows(t *testing.T) {
+	expected := desiredflows.Response{
+		DesiredFlows: []string{"table=0,priority=0"},
+		ActualFlows:  []string{"table=0, priority=0 actions=resubmit(,10)"},
+	}
+	var method string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, desiredFlowsPath, r.URL.Path)
+		method = r.Method
+		resp := expected
+		resp.Fixed = r.Method == http.MethodPost
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client, err := apiclient.NewAgentClient(&rest.Config{Host: server.URL}, "")
+	require.NoError(t, err)
+
+	resp, err := requestDesiredFlows(client, false)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodGet, method)
+	assert.Equal(t, expected, *resp)
+
+	resp, err = requestDesiredFlows(client, true)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPost, method)
+	assert.True(t, resp.Fixed)
+}
@@ -0,0 +1,145 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsecstatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	antctlruntime "github.com/vmware-tanzu/antrea/pkg/antctl/runtime"
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	antrea "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+)
+
+const (
+	outputFormatTable = "table"
+	outputFormatJSON  = "json"
+)
+
+// Command is the get ipsec-status command implementation.
+var Command *cobra.Command
+
+var option = &struct {
+	node   string
+	output string
+}{}
+
+var ipsecStatusLongDescription = strings.TrimSpace(`
+Get the status of the IPSec tunnels between the Nodes, as reported by the Antrea Agents from the IPSec Security
+Associations of the kernel. A tunnel is "established" when the Security Associations of both directions are installed,
+"initializing" while they are being negotiated, and "failed" when they could not be negotiated in time.
+`)
+
+var ipsecStatusExample = strings.Trim(`
+  Get the status of all the IPSec tunnels
+  $ antctl get ipsec-status
+  Get the status of the IPSec tunnels from Node node1, and output it in JSON
+  $ antctl get ipsec-status -N node1 -o json
+`, "\n")
+
+func init() {
+	Command = &cobra.Command{
+		Use:     "ipsec-status",
+		Aliases: []string{"ipsecstatus", "ipsects"},
+		Short:   "Get the status of the IPSec tunnels",
+		Long:    ipsecStatusLongDescription,
+		Example: ipsecStatusExample,
+		Args:    cobra.NoArgs,
+		RunE:    runE,
+	}
+	Command.Flags().StringVarP(&option.node, "node", "N", "", "only get the tunnels from this Node")
+	Command.Flags().StringVarP(&option.output, "output", "o", outputFormatTable, "output format, supports 'table' and 'json'")
+}
+
+// filter returns the statuses reported by the Node, or all of them if node is
+// empty, sorted by local Node and remote Node.
+func filter(statuses []opsv1alpha1.IPSecTunnelStatus, node string) []opsv1alpha1.IPSecTunnelStatus {
+	var matched []opsv1alpha1.IPSecTunnelStatus
+	for _, s := range statuses {
+		if node != "" && s.LocalNode != node {
+			continue
+		}
+		matched = append(matched, s)
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].LocalNode != matched[j].LocalNode {
+			return matched[i].LocalNode < matched[j].LocalNode
+		}
+		return matched[i].RemoteNode < matched[j].RemoteNode
+	})
+	return matched
+}
+
+func output(statuses []opsv1alpha1.IPSecTunnelStatus, format string, writer io.Writer) error {
+	switch format {
+	case outputFormatJSON:
+		if statuses == nil {
+			statuses = []opsv1alpha1.IPSecTunnelStatus{}
+		}
+		data, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error when encoding the IPSec tunnel statuses: %w", err)
+		}
+		_, err = fmt.Fprintln(writer, string(data))
+		return err
+	case outputFormatTable:
+		w := tabwriter.NewWriter(writer, 15, 0, 1, ' ', 0)
+		fmt.Fprintln(w, "LOCAL\tREMOTE\tSTATE\tBYTES-IN\tBYTES-OUT\tREKEYS\tLAST-REKEY\t")
+		for _, s := range statuses {
+			lastRekey := "<none>"
+			if !s.LastRekeyTimestamp.IsZero() {
+				lastRekey = s.LastRekeyTimestamp.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%s\t\n", s.LocalNode, s.RemoteNode, s.State, s.BytesIn, s.BytesOut,
+				s.RekeysTotal, lastRekey)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %s", format)
+	}
+}
+
+func runE(cmd *cobra.Command, _ []string) error {
+	if option.output != outputFormatTable && option.output != outputFormatJSON {
+		return fmt.Errorf("unsupported output format %s", option.output)
+	}
+
+	kubeconfigPath, err := cmd.Flags().GetString("kubeconfig")
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := antctlruntime.ResolveKubeconfig(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	antreaClientset, err := antrea.NewForConfig(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error when creating antrea clientset: %w", err)
+	}
+	statuses, err := antreaClientset.OpsV1alpha1().IPSecTunnelStatuses().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error when listing IPSec tunnel statuses: %w", err)
+	}
+	return output(filter(statuses.Items, option.node), option.output, cmd.OutOrStdout())
+}
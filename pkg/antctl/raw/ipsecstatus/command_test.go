@@ -0,0 +1,89 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsecstatus
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+)
+
+func newTunnelStatus(localNode, remoteNode string, state opsv1alpha1.IPSecTunnelState) opsv1alpha1.IPSecTunnelStatus {
+	return opsv1alpha1.IPSecTunnelStatus{
+		ObjectMeta: metav1.ObjectMeta{Name: localNode + "." + remoteNode},
+		LocalNode:  localNode,
+		RemoteNode: remoteNode,
+		State:      state,
+	}
+}
+
+func TestFilter(t *testing.T) {
+	statuses := []opsv1alpha1.IPSecTunnelStatus{
+		newTunnelStatus("node2", "node1", opsv1alpha1.IPSecTunnelEstablished),
+		newTunnelStatus("node1", "node3", opsv1alpha1.IPSecTunnelFailed),
+		newTunnelStatus("node1", "node2", opsv1alpha1.IPSecTunnelEstablished),
+	}
+	names := func(statuses []opsv1alpha1.IPSecTunnelStatus) []string {
+		var names []string
+		for _, s := range statuses {
+			names = append(names, s.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"node1.node2", "node1.node3", "node2.node1"}, names(filter(statuses, "")))
+	assert.Equal(t, []string{"node2.node1"}, names(filter(statuses, "node2")))
+	assert.Empty(t, filter(statuses, "node4"))
+}
+
+func TestOutput(t *testing.T) {
+	statuses := []opsv1alpha1.IPSecTunnelStatus{
+		newTunnelStatus("node1", "node2", opsv1alpha1.IPSecTunnelEstablished),
+		newTunnelStatus("node1", "node3", opsv1alpha1.IPSecTunnelInitializing),
+	}
+	statuses[0].BytesIn = 2080
+	statuses[0].BytesOut = 1040
+	statuses[0].RekeysTotal = 3
+	statuses[0].LastRekeyTimestamp = metav1.NewTime(time.Date(2020, 11, 10, 10, 0, 0, 0, time.UTC))
+
+	var buf bytes.Buffer
+	require.NoError(t, output(statuses, outputFormatJSON, &buf))
+	var decoded []opsv1alpha1.IPSecTunnelStatus
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Len(t, decoded, 2)
+	assert.Equal(t, int64(2080), decoded[0].BytesIn)
+	assert.Equal(t, opsv1alpha1.IPSecTunnelInitializing, decoded[1].State)
+
+	buf.Reset()
+	require.NoError(t, output(nil, outputFormatJSON, &buf))
+	assert.Equal(t, "[]", strings.TrimSpace(buf.String()))
+
+	buf.Reset()
+	require.NoError(t, output(statuses, outputFormatTable, &buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, []string{"LOCAL", "REMOTE", "STATE", "BYTES-IN", "BYTES-OUT", "REKEYS", "LAST-REKEY"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"node1", "node2", "established", "2080", "1040", "3", "2020-11-10T10:00:00Z"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"node1", "node3", "initializing", "0", "0", "0", "<none>"}, strings.Fields(lines[2]))
+
+	assert.Error(t, output(statuses, "yaml", &buf))
+}
@@ -0,0 +1,192 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loglevel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/rest"
+
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/apiclient"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/runtime"
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/loglevel"
+)
+
+const (
+	logLevelPath   = "/debug/log-level"
+	requestTimeout = 10 * time.Second
+
+	componentAgent      = "agent"
+	componentController = "controller"
+)
+
+var (
+	// SetCommand is the set log-level command implementation.
+	SetCommand *cobra.Command
+	// GetCommand is the get log-level command implementation.
+	GetCommand *cobra.Command
+)
+
+type options struct {
+	component string
+	node      string
+	level     int
+}
+
+var (
+	setOption = &options{}
+	getOption = &options{}
+)
+
+var setLogLevelLongDescription = strings.TrimSpace(fmt.Sprintf(`
+Set the log verbosity of the Antrea Agent or of the Antrea Controller at runtime, without restarting it. The level
+must be between %d and %d, and it is effective until the Pod restarts or the level is set again.
+`, loglevel.MinLevel, loglevel.MaxLevel))
+
+var setLogLevelExample = strings.Trim(`
+  Set the log level of the Antrea Agent running on Node node1 to 5
+  $ antctl set log-level --component agent --node node1 --level 5
+  Set the log level of the Antrea Controller to 4
+  $ antctl set log-level --component controller --level 4
+`, "\n")
+
+var getLogLevelLongDescription = strings.TrimSpace(`
+Get the current log verbosity of the Antrea Agent or of the Antrea Controller.
+`)
+
+var getLogLevelExample = strings.Trim(`
+  Get the log level of the Antrea Agent running on Node node1
+  $ antctl get log-level --component agent --node node1
+  Get the log level of the Antrea Controller
+  $ antctl get log-level --component controller
+`, "\n")
+
+func addFlags(cmd *cobra.Command, o *options) {
+	if runtime.Mode == runtime.ModeController {
+		cmd.Flags().StringVar(&o.component, "component", componentController, "the component whose log level is changed, 'agent' or 'controller'")
+		cmd.Flags().StringVar(&o.node, "node", "", "name of the Node whose Antrea Agent is targeted, required for the 'agent' component")
+	} else {
+		o.component = componentAgent
+	}
+}
+
+func init() {
+	SetCommand = &cobra.Command{
+		Use:     "log-level",
+		Short:   "Set the log level of an Antrea component",
+		Long:    setLogLevelLongDescription,
+		Example: setLogLevelExample,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if !cmd.Flags().Changed("level") {
+				return fmt.Errorf("the log level must be specified with --level")
+			}
+			return runE(cmd, setOption, true)
+		},
+	}
+	addFlags(SetCommand, setOption)
+	SetCommand.Flags().IntVar(&setOption.level, "level", 0, fmt.Sprintf("the log level, between %d and %d", loglevel.MinLevel, loglevel.MaxLevel))
+
+	GetCommand = &cobra.Command{
+		Use:     "log-level",
+		Short:   "Get the log level of an Antrea component",
+		Long:    getLogLevelLongDescription,
+		Example: getLogLevelExample,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runE(cmd, getOption, false)
+		},
+	}
+	addFlags(GetCommand, getOption)
+}
+
+// newClient returns the client to access the API of the component.
+func newClient(kubeconfig *rest.Config, o *options) (*rest.RESTClient, string, error) {
+	switch o.component {
+	case componentAgent:
+		if runtime.Mode == runtime.ModeController && o.node == "" {
+			return nil, "", fmt.Errorf("the Node must be specified with --node for the 'agent' component")
+		}
+		client, err := apiclient.NewAgentClient(kubeconfig, o.node)
+		if o.node == "" {
+			return client, "the Antrea Agent", err
+		}
+		return client, "the Antrea Agent of Node " + o.node, err
+	case componentController:
+		if o.node != "" {
+			return nil, "", fmt.Errorf("--node is not supported for the 'controller' component")
+		}
+		client, err := apiclient.NewControllerClient(kubeconfig)
+		return client, "the Antrea Controller", err
+	default:
+		return nil, "", fmt.Errorf("unknown component %s, it must be 'agent' or 'controller'", o.component)
+	}
+}
+
+// requestLogLevel gets the log level of a component, or sets it if set is true.
+func requestLogLevel(client rest.Interface, set bool, level int) (int, error) {
+	request := client.Get()
+	if set {
+		request = client.Put().Param("level", strconv.Itoa(level))
+	}
+	data, err := request.AbsPath(logLevelPath).Timeout(requestTimeout).DoRaw(context.TODO())
+	if err != nil {
+		return 0, err
+	}
+	var resp loglevel.Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return 0, fmt.Errorf("error when decoding the log level: %w", err)
+	}
+	return resp.Level, nil
+}
+
+func printLevel(out io.Writer, component string, level int, set bool) {
+	if set {
+		fmt.Fprintf(out, "Set the log level of %s to %d\n", component, level)
+		return
+	}
+	fmt.Fprintf(out, "The log level of %s is %d\n", component, level)
+}
+
+func runE(cmd *cobra.Command, o *options, set bool) error {
+	if set && (o.level < loglevel.MinLevel || o.level > loglevel.MaxLevel) {
+		return fmt.Errorf("invalid log level %d, it must be between %d and %d", o.level, loglevel.MinLevel, loglevel.MaxLevel)
+	}
+	kubeconfigPath, err := cmd.Flags().GetString("kubeconfig")
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := runtime.ResolveKubeconfig(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	client, component, err := newClient(kubeconfig, o)
+	if err != nil {
+		return err
+	}
+	level, err := requestLogLevel(client, set, o.level)
+	if err != nil {
+		return fmt.Errorf("error when requesting the log level of %s: %w", component, err)
+	}
+	printLevel(cmd.OutOrStdout(), component, level, set)
+	return nil
+}
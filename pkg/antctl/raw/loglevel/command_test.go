@@ -0,0 +1,62 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loglevel
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/apiclient"
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/loglevel"
+)
+
+func TestRequestLogLevel(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(logLevelPath, loglevel.HandleFunc())
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client, err := apiclient.NewAgentClient(&rest.Config{Host: server.URL}, "")
+	require.NoError(t, err)
+
+	initialLevel, err := requestLogLevel(client, false, 0)
+	require.NoError(t, err)
+	defer requestLogLevel(client, true, initialLevel)
+
+	level, err := requestLogLevel(client, true, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 4, level)
+	level, err = requestLogLevel(client, false, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 4, level)
+
+	_, err = requestLogLevel(client, true, 11)
+	assert.Error(t, err)
+	level, err = requestLogLevel(client, false, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 4, level)
+}
+
+func TestPrintLevel(t *testing.T) {
+	out := new(bytes.Buffer)
+	printLevel(out, "the Antrea Controller", 2, false)
+	printLevel(out, "the Antrea Agent of Node node1", 5, true)
+	assert.Equal(t, "The log level of the Antrea Controller is 2\nSet the log level of the Antrea Agent of Node node1 to 5\n", out.String())
+}
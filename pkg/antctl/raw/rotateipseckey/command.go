@@ -0,0 +1,145 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rotateipseckey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	antctlruntime "github.com/vmware-tanzu/antrea/pkg/antctl/runtime"
+	utilipsec "github.com/vmware-tanzu/antrea/pkg/util/ipsec"
+)
+
+const (
+	defaultNamespace = "kube-system"
+	defaultTimeout   = 5 * time.Minute
+	pollInterval     = time.Second
+)
+
+// Command is the rotate-ipsec-key command implementation.
+var Command *cobra.Command
+
+var option = &struct {
+	namespace string
+	wait      bool
+	timeout   time.Duration
+}{}
+
+var rotateIPSecKeyLongDescription = strings.TrimSpace(`
+Rotate the IPSec PSK stored in the antrea-ipsec Secret. antrea-controller generates a new PSK and keeps the
+previous one until all the agents have applied the new PSK, so that established connections are not disrupted.
+The key rotation must be enabled in the antrea-controller configuration with enableIPSecKeyRotation.
+`)
+
+var rotateIPSecKeyExample = strings.Trim(`
+  Request a rotation of the IPSec PSK
+  $ antctl rotate-ipsec-key
+  Rotate the IPSec PSK and wait until all the agents have applied the new PSK
+  $ antctl rotate-ipsec-key --wait
+`, "\n")
+
+func init() {
+	Command = &cobra.Command{
+		Use:     "rotate-ipsec-key",
+		Short:   "Rotate the IPSec PSK",
+		Long:    rotateIPSecKeyLongDescription,
+		Example: rotateIPSecKeyExample,
+		Args:    cobra.NoArgs,
+		RunE:    runE,
+	}
+	Command.Flags().StringVarP(&option.namespace, "namespace", "n", defaultNamespace, "Namespace of the antrea-ipsec Secret")
+	Command.Flags().BoolVar(&option.wait, "wait", false, "wait until all the agents have applied the new PSK")
+	Command.Flags().DurationVar(&option.timeout, "timeout", defaultTimeout, "how long to wait for the rotation to complete, used with --wait")
+}
+
+// requestRotation sets the rotation-requested annotation of the Secret and
+// returns the generation the PSK will have once rotated.
+func requestRotation(client kubernetes.Interface, namespace string) (int64, error) {
+	secret, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), utilipsec.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("error when getting Secret %s/%s: %w", namespace, utilipsec.SecretName, err)
+	}
+	generation := utilipsec.GetKeyGeneration(secret.Annotations) + 1
+	// A rotation requested during an ongoing rotation starts once the ongoing
+	// one has completed.
+	if utilipsec.GetRotationPhase(secret.Annotations) == utilipsec.RotationPhaseBoth {
+		generation++
+	}
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				utilipsec.RotationRequestedAnnotationKey: time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+	})
+	if _, err := client.CoreV1().Secrets(namespace).Patch(context.TODO(), utilipsec.SecretName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return 0, fmt.Errorf("error when requesting IPSec key rotation: %w", err)
+	}
+	return generation, nil
+}
+
+// waitForRotation waits until the PSK of the provided generation has been
+// applied by all the agents.
+func waitForRotation(client kubernetes.Interface, namespace string, generation int64, timeout time.Duration) error {
+	err := wait.PollImmediate(pollInterval, timeout, func() (bool, error) {
+		secret, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), utilipsec.SecretName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return utilipsec.GetKeyGeneration(secret.Annotations) >= generation &&
+			utilipsec.GetRotationPhase(secret.Annotations) == utilipsec.RotationPhaseNewOnly, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for IPSec key rotation to complete")
+	}
+	return err
+}
+
+func runE(cmd *cobra.Command, _ []string) error {
+	kubeconfigPath, err := cmd.Flags().GetString("kubeconfig")
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := antctlruntime.ResolveKubeconfig(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error when creating K8s clientset: %w", err)
+	}
+	generation, err := requestRotation(client, option.namespace)
+	if err != nil {
+		return err
+	}
+	if !option.wait {
+		fmt.Fprintf(cmd.OutOrStdout(), "IPSec key rotation to generation %d requested\n", generation)
+		return nil
+	}
+	if err := waitForRotation(client, option.namespace, generation, option.timeout); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "IPSec key rotated to generation %d\n", generation)
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rotateipseckey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	utilipsec "github.com/vmware-tanzu/antrea/pkg/util/ipsec"
+)
+
+func TestRequestRotation(t *testing.T) {
+	tests := []struct {
+		name               string
+		annotations        map[string]string
+		expectedGeneration int64
+	}{
+		{
+			name:               "never-rotated",
+			expectedGeneration: 1,
+		},
+		{
+			name: "rotated",
+			annotations: map[string]string{
+				utilipsec.KeyGenerationAnnotationKey: "2",
+				utilipsec.RotationPhaseAnnotationKey: string(utilipsec.RotationPhaseNewOnly),
+			},
+			expectedGeneration: 3,
+		},
+		{
+			name: "rotating",
+			annotations: map[string]string{
+				utilipsec.KeyGenerationAnnotationKey: "2",
+				utilipsec.RotationPhaseAnnotationKey: string(utilipsec.RotationPhaseBoth),
+			},
+			expectedGeneration: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: utilipsec.SecretName, Namespace: defaultNamespace, Annotations: tt.annotations},
+			})
+			generation, err := requestRotation(client, defaultNamespace)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedGeneration, generation)
+			secret, err := client.CoreV1().Secrets(defaultNamespace).Get(context.TODO(), utilipsec.SecretName, metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Contains(t, secret.Annotations, utilipsec.RotationRequestedAnnotationKey)
+		})
+	}
+}
+
+func TestRequestRotationSecretNotFound(t *testing.T) {
+	_, err := requestRotation(fake.NewSimpleClientset(), defaultNamespace)
+	assert.Error(t, err)
+}
+
+func TestWaitForRotation(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utilipsec.SecretName,
+			Namespace: defaultNamespace,
+			Annotations: map[string]string{
+				utilipsec.KeyGenerationAnnotationKey: "1",
+				utilipsec.RotationPhaseAnnotationKey: string(utilipsec.RotationPhaseNewOnly),
+			},
+		},
+	})
+	assert.NoError(t, waitForRotation(client, defaultNamespace, 1, time.Second))
+	assert.Error(t, waitForRotation(client, defaultNamespace, 2, time.Second))
+}
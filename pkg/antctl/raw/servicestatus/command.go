@@ -0,0 +1,275 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/rest"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/servicestatus"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/apiclient"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/runtime"
+)
+
+const (
+	serviceStatusPath = "/servicestatus"
+	requestTimeout    = 30 * time.Second
+
+	outputFormatTable = "table"
+	outputFormatJSON  = "json"
+
+	colorReset  = "\033[0m"
+	colorYellow = "\033[33m"
+)
+
+var (
+	// EndpointStatusCommand is the get endpoint-status command implementation.
+	EndpointStatusCommand *cobra.Command
+	// ServiceLBStatusCommand is the get service-lb-status command
+	// implementation.
+	ServiceLBStatusCommand *cobra.Command
+)
+
+type options struct {
+	service string
+	node    string
+	output  string
+}
+
+var (
+	endpointStatusOption  = &options{}
+	serviceLBStatusOption = &options{}
+)
+
+var endpointStatusLongDescription = strings.TrimSpace(`
+Get the Endpoints AntreaProxy has programmed for a Service on a Node. For each Endpoint of each Service port, the
+command reports whether it is ready, whether it is tracked from an EndpointSlice, and whether its DNAT flow is
+installed in the EndpointDNAT table of OVS (table 42). The Endpoints whose flows are installed according to the
+caches of AntreaProxy but not in OVS, or the other way around, are highlighted in yellow.
+`)
+
+var endpointStatusExample = strings.Trim(`
+  Get the Endpoints of Service kube-dns in Namespace kube-system programmed by the local Antrea Agent
+  $ antctl get endpoint-status --service kube-system/kube-dns
+  Get the Endpoints of Service kube-dns programmed by the Antrea Agent running on Node node1
+  $ antctl get endpoint-status --service kube-system/kube-dns --node node1
+`, "\n")
+
+var serviceLBStatusLongDescription = strings.TrimSpace(`
+Get the OVS groups AntreaProxy uses to load-balance the traffic of a Service on a Node. For each Service port, the
+command prints the group entry and its buckets, as dumped from OVS, one bucket per selected Endpoint. A Service
+using only node-local Endpoints for external traffic has a second group.
+`)
+
+var serviceLBStatusExample = strings.Trim(`
+  Get the groups of Service kube-dns in Namespace kube-system installed by the local Antrea Agent
+  $ antctl get service-lb-status --service kube-system/kube-dns
+  Get the groups of Service kube-dns installed by the Antrea Agent running on Node node1
+  $ antctl get service-lb-status --service kube-system/kube-dns --node node1
+`, "\n")
+
+func addFlags(cmd *cobra.Command, o *options) {
+	cmd.Flags().StringVar(&o.service, "service", "", "the Service specified by <Namespace>/<name>")
+	if runtime.Mode == runtime.ModeController {
+		cmd.Flags().StringVar(&o.node, "node", "", "name of the Node whose Antrea Agent is queried")
+	}
+	cmd.Flags().StringVarP(&o.output, "output", "o", outputFormatTable, "output format, supports 'table' and 'json'")
+}
+
+func init() {
+	EndpointStatusCommand = &cobra.Command{
+		Use:     "endpoint-status",
+		Short:   "Get the Endpoints AntreaProxy has programmed for a Service",
+		Long:    endpointStatusLongDescription,
+		Example: endpointStatusExample,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runE(cmd, endpointStatusOption, printEndpointStatus)
+		},
+	}
+	addFlags(EndpointStatusCommand, endpointStatusOption)
+	ServiceLBStatusCommand = &cobra.Command{
+		Use:     "service-lb-status",
+		Short:   "Get the OVS groups AntreaProxy has installed for a Service",
+		Long:    serviceLBStatusLongDescription,
+		Example: serviceLBStatusExample,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runE(cmd, serviceLBStatusOption, printServiceLBStatus)
+		},
+	}
+	addFlags(ServiceLBStatusCommand, serviceLBStatusOption)
+}
+
+// isTerminal returns true if the writer is a terminal, in which case the
+// diverged Endpoints are colored.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// requestServiceStatus gets the state of the ports of a Service from the agent
+// API.
+func requestServiceStatus(client rest.Interface, namespace, name string) ([]servicestatus.Response, error) {
+	data, err := client.Get().
+		AbsPath(serviceStatusPath).
+		Param("namespace", namespace).
+		Param("name", name).
+		Timeout(requestTimeout).
+		DoRaw(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("error when requesting the status of Service %s/%s: %w", namespace, name, err)
+	}
+	var resp []servicestatus.Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("error when decoding the status of Service %s/%s: %w", namespace, name, err)
+	}
+	return resp, nil
+}
+
+// diverged returns true if the caches of AntreaProxy and OVS disagree on the
+// DNAT flow of an Endpoint.
+func diverged(e *servicestatus.EndpointResponse) bool {
+	return e.Installed != e.NATFlowInstalled
+}
+
+func printEndpointStatus(statuses []servicestatus.Response, color bool, out io.Writer) error {
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE-PORT\tENDPOINT-IP\tPORT\tPROTOCOL\tNODE\tREADY\tOWNED-BY-SLICE\tNAT-FLOW-INSTALLED")
+	// highlighted stores the indexes of the lines to highlight, the header
+	// being the line 0.
+	highlighted := map[int]bool{}
+	line := 0
+	for _, s := range statuses {
+		for i := range s.Endpoints {
+			e := &s.Endpoints[i]
+			line++
+			node := e.Node
+			if node == "" {
+				node = "<unknown>"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%t\t%t\t%t\n", s.Port, e.IP, e.Port, e.Protocol, node, e.Ready, e.OwnedBySlice, e.NATFlowInstalled)
+			highlighted[line] = diverged(e)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	divergedCount := 0
+	for i, l := range lines {
+		if highlighted[i] {
+			divergedCount++
+			if color {
+				l = colorYellow + l + colorReset
+			}
+		}
+		fmt.Fprintln(out, l)
+	}
+	if divergedCount > 0 {
+		fmt.Fprintf(out, "\n%d Endpoint(s) whose DNAT flow in OVS disagrees with the caches of AntreaProxy\n", divergedCount)
+	}
+	return nil
+}
+
+func printServiceLBStatus(statuses []servicestatus.Response, _ bool, out io.Writer) error {
+	for i, s := range statuses {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		port := s.Port
+		if port == "" {
+			port = "<unnamed>"
+		}
+		fmt.Fprintf(out, "Service %s/%s, port %s (%s), ClusterIP %s\n", s.Namespace, s.Name, port, s.Protocol, s.ClusterIP)
+		if !s.Installed {
+			fmt.Fprintln(out, "  The flows of the Service port are not installed")
+			continue
+		}
+		for _, g := range s.Groups {
+			kind := "Group"
+			if g.NodeLocal {
+				kind = "Node-local group"
+			}
+			if g.Entry == "" {
+				fmt.Fprintf(out, "  %s %d: not found in OVS\n", kind, g.GroupID)
+				continue
+			}
+			fmt.Fprintf(out, "  %s %d: %s\n", kind, g.GroupID, g.Entry)
+			if len(g.Buckets) == 0 {
+				fmt.Fprintln(out, "    No bucket")
+			}
+			for j, b := range g.Buckets {
+				fmt.Fprintf(out, "    bucket %d: %s\n", j, b)
+			}
+		}
+	}
+	return nil
+}
+
+func runE(cmd *cobra.Command, o *options, print func([]servicestatus.Response, bool, io.Writer) error) error {
+	if o.output != outputFormatTable && o.output != outputFormatJSON {
+		return fmt.Errorf("unsupported output format %s", o.output)
+	}
+	parts := strings.Split(o.service, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("the Service must be specified by <Namespace>/<name> with --service")
+	}
+	if runtime.Mode == runtime.ModeController && o.node == "" {
+		return fmt.Errorf("the Node must be specified with --node")
+	}
+
+	kubeconfigPath, err := cmd.Flags().GetString("kubeconfig")
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := runtime.ResolveKubeconfig(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	client, err := apiclient.NewAgentClient(kubeconfig, o.node)
+	if err != nil {
+		return err
+	}
+	statuses, err := requestServiceStatus(client, parts[0], parts[1])
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if o.output == outputFormatJSON {
+		data, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error when encoding the status: %w", err)
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	}
+	return print(statuses, isTerminal(out), out)
+}
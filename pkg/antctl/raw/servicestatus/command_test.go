@@ -0,0 +1,102 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicestatus
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/apiserver/handlers/servicestatus"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/apiclient"
+)
+
+var testStatuses = []servicestatus.Response{
+	{
+		Namespace: "ns1",
+		Name:      "svc1",
+		Port:      "http",
+		Protocol:  "TCP",
+		ClusterIP: "10.96.0.10",
+		Installed: true,
+		Groups: []servicestatus.GroupResponse{
+			{
+				GroupID: 3,
+				Entry:   "group_id=3,type=select",
+				Buckets: []string{
+					"bucket_id:0,weight:100,actions=load:0xa0a0001->NXM_NX_REG3[],load:0x50->NXM_NX_REG4[0..15],resubmit(,42)",
+				},
+			},
+			{
+				GroupID:   4,
+				NodeLocal: true,
+			},
+		},
+		Endpoints: []servicestatus.EndpointResponse{
+			{IP: "10.10.0.1", Port: 80, Protocol: "TCP", Node: "node1", Ready: true, OwnedBySlice: true, Installed: true, NATFlowInstalled: true},
+			{IP: "10.10.1.1", Port: 80, Protocol: "TCP", Ready: true, OwnedBySlice: true, Installed: true},
+		},
+	},
+}
+
+func TestPrintEndpointStatus(t *testing.T) {
+	expected := `SERVICE-PORT  ENDPOINT-IP  PORT  PROTOCOL  NODE       READY  OWNED-BY-SLICE  NAT-FLOW-INSTALLED
+http          10.10.0.1    80    TCP       node1      true   true            true
+http          10.10.1.1    80    TCP       <unknown>  true   true            false
+
+1 Endpoint(s) whose DNAT flow in OVS disagrees with the caches of AntreaProxy
+`
+	out := new(bytes.Buffer)
+	require.NoError(t, printEndpointStatus(testStatuses, false, out))
+	assert.Equal(t, expected, out.String())
+
+	out.Reset()
+	require.NoError(t, printEndpointStatus(testStatuses, true, out))
+	assert.Contains(t, out.String(), colorYellow+"http          10.10.1.1    80    TCP       <unknown>  true   true            false"+colorReset+"\n")
+	assert.NotContains(t, out.String(), colorYellow+"http          10.10.0.1")
+}
+
+func TestPrintServiceLBStatus(t *testing.T) {
+	expected := `Service ns1/svc1, port http (TCP), ClusterIP 10.96.0.10
+  Group 3: group_id=3,type=select
+    bucket 0: bucket_id:0,weight:100,actions=load:0xa0a0001->NXM_NX_REG3[],load:0x50->NXM_NX_REG4[0..15],resubmit(,42)
+  Node-local group 4: not found in OVS
+`
+	out := new(bytes.Buffer)
+	require.NoError(t, printServiceLBStatus(testStatuses, false, out))
+	assert.Equal(t, expected, out.String())
+}
+
+func TestRequestServiceStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, serviceStatusPath, r.URL.Path)
+		assert.Equal(t, "ns1", r.URL.Query().Get("namespace"))
+		assert.Equal(t, "svc1", r.URL.Query().Get("name"))
+		json.NewEncoder(w).Encode(testStatuses)
+	}))
+	defer server.Close()
+
+	client, err := apiclient.NewAgentClient(&rest.Config{Host: server.URL}, "")
+	require.NoError(t, err)
+	statuses, err := requestServiceStatus(client, "ns1", "svc1")
+	require.NoError(t, err)
+	assert.Equal(t, testStatuses, statuses)
+}
@@ -0,0 +1,361 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulatepolicy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
+
+	antctlruntime "github.com/vmware-tanzu/antrea/pkg/antctl/runtime"
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking"
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+	antrea "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+	"github.com/vmware-tanzu/antrea/pkg/controller/networkpolicy"
+)
+
+const (
+	outputFormatTable = "table"
+	outputFormatJSON  = "json"
+)
+
+// Result is the change of a connection caused by the simulated policies.
+type Result string
+
+const (
+	ResultNewlyAllowed Result = "NewlyAllowed"
+	ResultNewlyDenied  Result = "NewlyDenied"
+	ResultUnchanged    Result = "Unchanged"
+)
+
+// Command is the simulate-policy command implementation.
+var Command *cobra.Command
+
+var option = &struct {
+	namespace     string
+	protocol      string
+	port          int32
+	output        string
+	showUnchanged bool
+}{}
+
+var simulatePolicyLongDescription = strings.TrimSpace(`
+Simulate the NetworkPolicies and ClusterNetworkPolicies read from stdin against the current Pods of the cluster, without applying them.
+The connections between all pairs of Pods are evaluated with the existing policies, and with the existing policies updated with the
+simulated ones. A simulated policy replaces the existing policy with the same name. The connections which would be newly allowed
+and newly denied are reported.
+`)
+
+var simulatePolicyExample = strings.Trim(`
+  Simulate the NetworkPolicies in a manifest for the connections to TCP port 80
+  $ antctl simulate-policy < policy.yaml
+  Simulate the NetworkPolicies in a manifest for the connections to UDP port 53, and output the report in JSON
+  $ cat policy.yaml | antctl simulate-policy --protocol UDP --port 53 -o json
+`, "\n")
+
+func init() {
+	Command = &cobra.Command{
+		Use:     "simulate-policy",
+		Short:   "Simulate NetworkPolicies without applying them",
+		Long:    simulatePolicyLongDescription,
+		Example: simulatePolicyExample,
+		Args:    cobra.NoArgs,
+		RunE:    runE,
+	}
+	Command.Flags().StringVarP(&option.namespace, "namespace", "n", metav1.NamespaceDefault, "namespace of the NetworkPolicies which do not specify one")
+	Command.Flags().StringVar(&option.protocol, "protocol", string(networking.ProtocolTCP), "protocol of the simulated connections, supports 'TCP', 'UDP' and 'SCTP'")
+	Command.Flags().Int32Var(&option.port, "port", 80, "destination port of the simulated connections")
+	Command.Flags().StringVarP(&option.output, "output", "o", outputFormatTable, "output format, supports 'table' and 'json'")
+	Command.Flags().BoolVar(&option.showUnchanged, "show-unchanged", false, "also report the connections which are not changed")
+}
+
+// Connection is the simulated result of the connection between two Pods.
+type Connection struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Result      Result `json:"result"`
+	// Allowed tells whether the connection is allowed with the simulated
+	// policies.
+	Allowed bool `json:"allowed"`
+}
+
+// Report is the report of a policy simulation.
+type Report struct {
+	Protocol     string       `json:"protocol"`
+	Port         int32        `json:"port"`
+	NewlyAllowed int          `json:"newlyAllowed"`
+	NewlyDenied  int          `json:"newlyDenied"`
+	Unchanged    int          `json:"unchanged"`
+	Connections  []Connection `json:"connections"`
+}
+
+// clusterState is the current state of the cluster the policies are simulated
+// against.
+type clusterState struct {
+	pods       []*v1.Pod
+	namespaces []*v1.Namespace
+	nps        []*networkingv1.NetworkPolicy
+	cnps       []*secv1alpha1.ClusterNetworkPolicy
+}
+
+// simulatedPolicies are the policies read from the manifest.
+type simulatedPolicies struct {
+	nps  []*networkingv1.NetworkPolicy
+	cnps []*secv1alpha1.ClusterNetworkPolicy
+}
+
+func newDecoder() runtime.Decoder {
+	scheme := runtime.NewScheme()
+	networkingv1.AddToScheme(scheme)
+	secv1alpha1.AddToScheme(scheme)
+	return serializer.NewCodecFactory(scheme).UniversalDeserializer()
+}
+
+// parsePolicies reads the policies from a YAML or JSON manifest, which may
+// contain multiple documents.
+func parsePolicies(reader io.Reader, defaultNamespace string) (*simulatedPolicies, error) {
+	decoder := newDecoder()
+	yamlReader := yaml.NewYAMLReader(bufio.NewReader(reader))
+	policies := &simulatedPolicies{}
+	for {
+		doc, err := yamlReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error when reading the manifest: %w", err)
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		obj, gvk, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error when decoding the manifest: %w", err)
+		}
+		switch policy := obj.(type) {
+		case *networkingv1.NetworkPolicy:
+			if policy.Namespace == "" {
+				policy.Namespace = defaultNamespace
+			}
+			setDefaultPolicyTypes(policy)
+			policies.nps = append(policies.nps, policy)
+		case *secv1alpha1.ClusterNetworkPolicy:
+			policies.cnps = append(policies.cnps, policy)
+		case *secv1alpha1.NetworkPolicy:
+			return nil, fmt.Errorf("%s %s/%s cannot be simulated as it is not enforced by Antrea yet", gvk.Kind, policy.Namespace, policy.Name)
+		default:
+			return nil, fmt.Errorf("unsupported kind %s in the manifest", gvk.Kind)
+		}
+	}
+	if len(policies.nps) == 0 && len(policies.cnps) == 0 {
+		return nil, fmt.Errorf("no policy found in the manifest")
+	}
+	return policies, nil
+}
+
+// setDefaultPolicyTypes sets the PolicyTypes of a NetworkPolicy which does not
+// specify them as the K8s apiserver would: the Pods are always isolated for
+// ingress, and also isolated for egress if the policy has egress rules.
+func setDefaultPolicyTypes(np *networkingv1.NetworkPolicy) {
+	if len(np.Spec.PolicyTypes) != 0 {
+		return
+	}
+	np.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	if len(np.Spec.Egress) != 0 {
+		np.Spec.PolicyTypes = append(np.Spec.PolicyTypes, networkingv1.PolicyTypeEgress)
+	}
+}
+
+func getClusterState(k8sClientset kubernetes.Interface, antreaClientset antrea.Interface) (*clusterState, error) {
+	ctx := context.TODO()
+	state := &clusterState{}
+	podList, err := k8sClientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error when listing Pods: %w", err)
+	}
+	for i := range podList.Items {
+		state.pods = append(state.pods, &podList.Items[i])
+	}
+	nsList, err := k8sClientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error when listing Namespaces: %w", err)
+	}
+	for i := range nsList.Items {
+		state.namespaces = append(state.namespaces, &nsList.Items[i])
+	}
+	npList, err := k8sClientset.NetworkingV1().NetworkPolicies("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error when listing NetworkPolicies: %w", err)
+	}
+	for i := range npList.Items {
+		state.nps = append(state.nps, &npList.Items[i])
+	}
+	cnpList, err := antreaClientset.SecurityV1alpha1().ClusterNetworkPolicies().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error when listing ClusterNetworkPolicies: %w", err)
+	}
+	for i := range cnpList.Items {
+		state.cnps = append(state.cnps, &cnpList.Items[i])
+	}
+	return state, nil
+}
+
+// simulate evaluates the connections between all pairs of Pods with the
+// existing policies and with the simulated policies, and reports the changes.
+func simulate(state *clusterState, policies *simulatedPolicies, protocol networking.Protocol, port int32, showUnchanged bool) *Report {
+	replacedNPs := map[string]bool{}
+	for _, np := range policies.nps {
+		replacedNPs[np.Namespace+"/"+np.Name] = true
+	}
+	replacedCNPs := map[string]bool{}
+	for _, cnp := range policies.cnps {
+		replacedCNPs[cnp.Name] = true
+	}
+
+	current := networkpolicy.NewSimulator(state.pods, state.namespaces)
+	simulated := networkpolicy.NewSimulator(state.pods, state.namespaces)
+	for _, np := range state.nps {
+		current.AddNetworkPolicy(np)
+		if !replacedNPs[np.Namespace+"/"+np.Name] {
+			simulated.AddNetworkPolicy(np)
+		}
+	}
+	for _, cnp := range state.cnps {
+		current.AddClusterNetworkPolicy(cnp)
+		if !replacedCNPs[cnp.Name] {
+			simulated.AddClusterNetworkPolicy(cnp)
+		}
+	}
+	for _, np := range policies.nps {
+		simulated.AddNetworkPolicy(np)
+	}
+	for _, cnp := range policies.cnps {
+		simulated.AddClusterNetworkPolicy(cnp)
+	}
+
+	currentAllowed := current.Simulate(protocol, port)
+	simulatedAllowed := simulated.Simulate(protocol, port)
+	report := &Report{Protocol: string(protocol), Port: port, Connections: []Connection{}}
+	pods := simulated.Pods()
+	for src := range pods {
+		for dst := range pods {
+			if src == dst {
+				continue
+			}
+			allowed := simulatedAllowed[src][dst]
+			result := ResultUnchanged
+			if allowed != currentAllowed[src][dst] {
+				if allowed {
+					result = ResultNewlyAllowed
+				} else {
+					result = ResultNewlyDenied
+				}
+			}
+			switch result {
+			case ResultNewlyAllowed:
+				report.NewlyAllowed++
+			case ResultNewlyDenied:
+				report.NewlyDenied++
+			case ResultUnchanged:
+				report.Unchanged++
+				if !showUnchanged {
+					continue
+				}
+			}
+			report.Connections = append(report.Connections, Connection{
+				Source:      pods[src].Namespace + "/" + pods[src].Name,
+				Destination: pods[dst].Namespace + "/" + pods[dst].Name,
+				Result:      result,
+				Allowed:     allowed,
+			})
+		}
+	}
+	return report
+}
+
+func output(report *Report, format string, writer io.Writer) error {
+	switch format {
+	case outputFormatJSON:
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error when encoding the report: %w", err)
+		}
+		_, err = fmt.Fprintln(writer, string(data))
+		return err
+	case outputFormatTable:
+		w := tabwriter.NewWriter(writer, 15, 0, 1, ' ', 0)
+		fmt.Fprintln(w, "SOURCE\tDESTINATION\tRESULT\tALLOWED\t")
+		for _, c := range report.Connections {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\t\n", c.Source, c.Destination, c.Result, c.Allowed)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(writer, "\n%d newly allowed, %d newly denied, %d unchanged connections to %s port %d\n",
+			report.NewlyAllowed, report.NewlyDenied, report.Unchanged, report.Protocol, report.Port)
+		return err
+	default:
+		return fmt.Errorf("unsupported output format %s", format)
+	}
+}
+
+func runE(cmd *cobra.Command, _ []string) error {
+	protocol := networking.Protocol(strings.ToUpper(option.protocol))
+	if protocol != networking.ProtocolTCP && protocol != networking.ProtocolUDP && protocol != networking.ProtocolSCTP {
+		return fmt.Errorf("unsupported protocol %s", option.protocol)
+	}
+	if option.output != outputFormatTable && option.output != outputFormatJSON {
+		return fmt.Errorf("unsupported output format %s", option.output)
+	}
+	policies, err := parsePolicies(cmd.InOrStdin(), option.namespace)
+	if err != nil {
+		return err
+	}
+
+	kubeconfigPath, err := cmd.Flags().GetString("kubeconfig")
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := antctlruntime.ResolveKubeconfig(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	k8sClientset, err := kubernetes.NewForConfig(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error when creating K8s clientset: %w", err)
+	}
+	antreaClientset, err := antrea.NewForConfig(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error when creating antrea clientset: %w", err)
+	}
+	state, err := getClusterState(k8sClientset, antreaClientset)
+	if err != nil {
+		return err
+	}
+	return output(simulate(state, policies, protocol, option.port, option.showUnchanged), option.output, cmd.OutOrStdout())
+}
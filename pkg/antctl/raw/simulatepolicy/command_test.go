@@ -0,0 +1,188 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulatepolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking"
+	fakeversioned "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+)
+
+const policyManifest = `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-web
+spec:
+  podSelector:
+    matchLabels:
+      app: web
+  ingress:
+  - from:
+    - namespaceSelector:
+        matchLabels:
+          env: dev
+---
+apiVersion: security.antrea.tanzu.vmware.com/v1alpha1
+kind: ClusterNetworkPolicy
+metadata:
+  name: drop-db
+spec:
+  priority: 5
+  appliedTo:
+  - podSelector:
+      matchLabels:
+        app: db
+  ingress:
+  - action: Drop
+    from:
+    - namespaceSelector:
+        matchLabels:
+          env: dev
+`
+
+func newPod(namespace, name, ip string, labels map[string]string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		Status:     v1.PodStatus{PodIP: ip},
+	}
+}
+
+func newNamespace(name string, labels map[string]string) *v1.Namespace {
+	return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := parsePolicies(strings.NewReader(policyManifest), "ns1")
+	require.NoError(t, err)
+	require.Len(t, policies.nps, 1)
+	assert.Equal(t, "ns1", policies.nps[0].Namespace)
+	assert.Equal(t, "allow-web", policies.nps[0].Name)
+	assert.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}, policies.nps[0].Spec.PolicyTypes)
+	require.Len(t, policies.cnps, 1)
+	assert.Equal(t, "drop-db", policies.cnps[0].Name)
+	assert.Equal(t, float64(5), policies.cnps[0].Spec.Priority)
+
+	for name, manifest := range map[string]string{
+		"empty":           "---\n",
+		"unsupported":     "apiVersion: v1\nkind: Service\nmetadata:\n  name: svc\n",
+		"antrea-np":       "apiVersion: security.antrea.tanzu.vmware.com/v1alpha1\nkind: NetworkPolicy\nmetadata:\n  name: np\n  namespace: ns1\n",
+		"invalid-yaml":    "kind: [",
+		"unknown-version": "apiVersion: networking.k8s.io/v2\nkind: NetworkPolicy\n",
+	} {
+		_, err := parsePolicies(strings.NewReader(manifest), "ns1")
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSetDefaultPolicyTypes(t *testing.T) {
+	np := &networkingv1.NetworkPolicy{}
+	setDefaultPolicyTypes(np)
+	assert.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}, np.Spec.PolicyTypes)
+
+	np = &networkingv1.NetworkPolicy{Spec: networkingv1.NetworkPolicySpec{Egress: []networkingv1.NetworkPolicyEgressRule{{}}}}
+	setDefaultPolicyTypes(np)
+	assert.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress}, np.Spec.PolicyTypes)
+
+	np = &networkingv1.NetworkPolicy{Spec: networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}}}
+	setDefaultPolicyTypes(np)
+	assert.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}, np.Spec.PolicyTypes)
+}
+
+func TestSimulate(t *testing.T) {
+	// The existing NetworkPolicy isolates all Pods in ns1 and allows the
+	// ingress traffic from the same Namespace. It is replaced by the simulated
+	// NetworkPolicy with the same name.
+	existingNP := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "allow-web"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}},
+			}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+	k8sClientset := fake.NewSimpleClientset(
+		newNamespace("ns1", map[string]string{"env": "prod"}),
+		newNamespace("ns2", map[string]string{"env": "dev"}),
+		newPod("ns1", "web", "10.0.0.1", map[string]string{"app": "web"}),
+		newPod("ns1", "db", "10.0.0.2", map[string]string{"app": "db"}),
+		newPod("ns2", "client", "10.0.1.1", map[string]string{"app": "client"}),
+		existingNP,
+	)
+	state, err := getClusterState(k8sClientset, fakeversioned.NewSimpleClientset())
+	require.NoError(t, err)
+	policies, err := parsePolicies(strings.NewReader(policyManifest), "ns1")
+	require.NoError(t, err)
+
+	report := simulate(state, policies, networking.ProtocolTCP, 80, false)
+	assert.Equal(t, 1, report.NewlyAllowed)
+	assert.Equal(t, 1, report.NewlyDenied)
+	assert.Equal(t, 4, report.Unchanged)
+	assert.ElementsMatch(t, []Connection{
+		{Source: "ns1/db", Destination: "ns1/web", Result: ResultNewlyDenied, Allowed: false},
+		{Source: "ns2/client", Destination: "ns1/web", Result: ResultNewlyAllowed, Allowed: true},
+	}, report.Connections)
+
+	report = simulate(state, policies, networking.ProtocolTCP, 80, true)
+	assert.ElementsMatch(t, []Connection{
+		{Source: "ns1/web", Destination: "ns1/db", Result: ResultUnchanged, Allowed: true},
+		{Source: "ns1/web", Destination: "ns2/client", Result: ResultUnchanged, Allowed: true},
+		{Source: "ns1/db", Destination: "ns1/web", Result: ResultNewlyDenied, Allowed: false},
+		{Source: "ns1/db", Destination: "ns2/client", Result: ResultUnchanged, Allowed: true},
+		{Source: "ns2/client", Destination: "ns1/web", Result: ResultNewlyAllowed, Allowed: true},
+		{Source: "ns2/client", Destination: "ns1/db", Result: ResultUnchanged, Allowed: false},
+	}, report.Connections)
+}
+
+func TestOutput(t *testing.T) {
+	report := &Report{
+		Protocol:     "TCP",
+		Port:         80,
+		NewlyAllowed: 1,
+		Unchanged:    4,
+		Connections: []Connection{
+			{Source: "ns2/client", Destination: "ns1/web", Result: ResultNewlyAllowed, Allowed: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, output(report, outputFormatJSON, &buf))
+	decoded := &Report{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), decoded))
+	assert.Equal(t, report, decoded)
+
+	buf.Reset()
+	require.NoError(t, output(report, outputFormatTable, &buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, []string{"SOURCE", "DESTINATION", "RESULT", "ALLOWED"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"ns2/client", "ns1/web", "NewlyAllowed", "true"}, strings.Fields(lines[1]))
+	assert.Equal(t, "1 newly allowed, 0 newly denied, 4 unchanged connections to TCP port 80", lines[3])
+
+	assert.Error(t, output(report, "yaml", &buf))
+}
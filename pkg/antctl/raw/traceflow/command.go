@@ -0,0 +1,223 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/rest"
+
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/apiclient"
+	antctlruntime "github.com/vmware-tanzu/antrea/pkg/antctl/runtime"
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/traceflow"
+)
+
+const (
+	traceflowPath = "/traceflows"
+
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+
+	protocolICMP = 1
+	protocolTCP  = 6
+	protocolUDP  = 17
+	tcpFlagSYN   = 2
+
+	// requestTimeoutMargin is added to the timeout of the trace to get the
+	// timeout of the request, so that the Controller can report the trace
+	// timing out.
+	requestTimeoutMargin = 5 * time.Second
+)
+
+// Command is the traceflow command implementation.
+var Command *cobra.Command
+
+var option = &struct {
+	srcPod   string
+	dstPod   string
+	protocol string
+	dstPort  int32
+	timeout  time.Duration
+	output   string
+}{}
+
+var traceflowLongDescription = strings.TrimSpace(`
+Trace a packet from a source Pod to a destination Pod with Traceflow, and print the result once the trace has
+completed. The trace is requested from the Antrea Controller API, which creates the Traceflow, waits for it to
+complete and returns its status. The Traceflow is deleted by the Antrea Controller after some time.
+`)
+
+var traceflowExample = strings.Trim(`
+  Trace a TCP packet to port 80 from Pod web to Pod db in Namespace default
+  $ antctl traceflow --src-pod default/web --dst-pod default/db --protocol tcp --dst-port 80
+  Trace an ICMP packet, wait up to 40 seconds for the result, and output the status of the Traceflow in JSON
+  $ antctl traceflow --src-pod default/web --dst-pod default/db --protocol icmp --timeout 40s -o json
+`, "\n")
+
+func init() {
+	Command = &cobra.Command{
+		Use:     "traceflow",
+		Aliases: []string{"tf"},
+		Short:   "Trace a packet between two Pods through the Antrea Controller API",
+		Long:    traceflowLongDescription,
+		Example: traceflowExample,
+		Args:    cobra.NoArgs,
+		RunE:    runE,
+	}
+	Command.Flags().StringVar(&option.srcPod, "src-pod", "", "source Pod of the packet, specified by <Namespace>/<name>")
+	Command.Flags().StringVar(&option.dstPod, "dst-pod", "", "destination Pod of the packet, specified by <Namespace>/<name>")
+	Command.Flags().StringVar(&option.protocol, "protocol", "tcp", "protocol of the packet, supports 'tcp', 'udp' and 'icmp'")
+	Command.Flags().Int32Var(&option.dstPort, "dst-port", 0, "destination port of the TCP or UDP packet")
+	Command.Flags().DurationVar(&option.timeout, "timeout", traceflow.DefaultTimeout, fmt.Sprintf("how long to wait for the trace to complete, at most %v", traceflow.MaxTimeout))
+	Command.Flags().StringVarP(&option.output, "output", "o", outputFormatText, "output format, supports 'text' and 'json'")
+}
+
+func parsePod(s string) (namespace, name string, err error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("%q is not a Pod specified by <Namespace>/<name>", s)
+	}
+	return parts[0], parts[1], nil
+}
+
+// newTraceflowSpec returns the spec of the Traceflow requested by the options.
+func newTraceflowSpec() (*opsv1alpha1.TraceflowSpec, error) {
+	srcNamespace, srcPod, err := parsePod(option.srcPod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source: %w", err)
+	}
+	dstNamespace, dstPod, err := parsePod(option.dstPod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination: %w", err)
+	}
+	spec := &opsv1alpha1.TraceflowSpec{
+		Source:      opsv1alpha1.Source{Namespace: srcNamespace, Pod: srcPod},
+		Destination: opsv1alpha1.Destination{Namespace: dstNamespace, Pod: dstPod},
+	}
+	if option.dstPort < 0 || option.dstPort > 65535 {
+		return nil, fmt.Errorf("invalid destination port %d", option.dstPort)
+	}
+	switch strings.ToLower(option.protocol) {
+	case "tcp":
+		spec.Packet.IPHeader.Protocol = protocolTCP
+		spec.Packet.TransportHeader.TCP = &opsv1alpha1.TCPHeader{DstPort: option.dstPort, Flags: tcpFlagSYN}
+	case "udp":
+		spec.Packet.IPHeader.Protocol = protocolUDP
+		spec.Packet.TransportHeader.UDP = &opsv1alpha1.UDPHeader{DstPort: option.dstPort}
+	case "icmp":
+		if option.dstPort != 0 {
+			return nil, fmt.Errorf("a destination port cannot be set for ICMP")
+		}
+		spec.Packet.IPHeader.Protocol = protocolICMP
+	default:
+		return nil, fmt.Errorf("unsupported protocol %s", option.protocol)
+	}
+	return spec, nil
+}
+
+// requestTraceflow requests a trace with the spec from the Controller API, and
+// returns the status of the completed Traceflow.
+func requestTraceflow(client rest.Interface, spec *opsv1alpha1.TraceflowSpec, timeout time.Duration) (*opsv1alpha1.TraceflowStatus, error) {
+	body, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	data, err := client.Post().
+		AbsPath(traceflowPath).
+		Param("timeout", timeout.String()).
+		Body(body).
+		Timeout(timeout + requestTimeoutMargin).
+		DoRaw(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	var status opsv1alpha1.TraceflowStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("error when decoding the Traceflow status: %w", err)
+	}
+	return &status, nil
+}
+
+func printStatus(out io.Writer, status *opsv1alpha1.TraceflowStatus, format string) error {
+	if format == outputFormatJSON {
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	}
+	msg := fmt.Sprintf("Phase: %s", status.Phase)
+	if status.Reason != "" {
+		msg += " (" + status.Reason + ")"
+	}
+	fmt.Fprintln(out, msg)
+	for _, result := range status.Results {
+		fmt.Fprintf(out, "Node %s %s:\n", result.Node, result.Role)
+		for _, o := range result.Observations {
+			component := string(o.Component)
+			if o.ComponentInfo != "" {
+				component += "/" + o.ComponentInfo
+			}
+			line := fmt.Sprintf("  %s %s", component, o.Action)
+			if o.NetworkPolicy != "" {
+				line += " networkPolicy=" + o.NetworkPolicy
+			}
+			if o.Reason != "" {
+				line += " reason=" + o.Reason
+			}
+			fmt.Fprintln(out, line)
+		}
+	}
+	return nil
+}
+
+func runE(cmd *cobra.Command, _ []string) error {
+	if option.output != outputFormatText && option.output != outputFormatJSON {
+		return fmt.Errorf("unsupported output format %s", option.output)
+	}
+	if option.timeout <= 0 || option.timeout > traceflow.MaxTimeout {
+		return fmt.Errorf("invalid timeout %v, it must be positive and not greater than %v", option.timeout, traceflow.MaxTimeout)
+	}
+	spec, err := newTraceflowSpec()
+	if err != nil {
+		return err
+	}
+
+	kubeconfigPath, err := cmd.Flags().GetString("kubeconfig")
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := antctlruntime.ResolveKubeconfig(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	client, err := apiclient.NewControllerClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	status, err := requestTraceflow(client, spec, option.timeout)
+	if err != nil {
+		return fmt.Errorf("error when requesting the Traceflow: %w", err)
+	}
+	return printStatus(cmd.OutOrStdout(), status, option.output)
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceflow
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+
+	"github.com/vmware-tanzu/antrea/pkg/antctl/raw/apiclient"
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/traceflow"
+	fakeversioned "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+)
+
+func TestNewTraceflowSpec(t *testing.T) {
+	option.srcPod, option.dstPod, option.protocol, option.dstPort = "default/web", "default/db", "udp", 53
+	spec, err := newTraceflowSpec()
+	require.NoError(t, err)
+	assert.Equal(t, opsv1alpha1.Source{Namespace: "default", Pod: "web"}, spec.Source)
+	assert.Equal(t, opsv1alpha1.Destination{Namespace: "default", Pod: "db"}, spec.Destination)
+	assert.Equal(t, int32(protocolUDP), spec.Packet.IPHeader.Protocol)
+	assert.Equal(t, int32(53), spec.Packet.TransportHeader.UDP.DstPort)
+
+	option.protocol = "icmp"
+	_, err = newTraceflowSpec()
+	assert.Error(t, err)
+	option.dstPod = "db"
+	_, err = newTraceflowSpec()
+	assert.Error(t, err)
+}
+
+func TestRequestTraceflow(t *testing.T) {
+	crdClient := fakeversioned.NewSimpleClientset()
+	mux := http.NewServeMux()
+	mux.HandleFunc(traceflowPath, traceflow.HandleFunc(crdClient))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client, err := apiclient.NewAgentClient(&rest.Config{Host: server.URL}, "")
+	require.NoError(t, err)
+
+	// Complete the Traceflow created by the request.
+	go func() {
+		wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+			list, err := crdClient.OpsV1alpha1().Traceflows().List(context.TODO(), metav1.ListOptions{})
+			if err != nil || len(list.Items) == 0 {
+				return false, nil
+			}
+			tf := &list.Items[0]
+			tf.Status = opsv1alpha1.TraceflowStatus{
+				Phase: opsv1alpha1.Succeeded,
+				Results: []opsv1alpha1.NodeResult{{
+					Node:         "node1",
+					Role:         "sender",
+					Observations: []opsv1alpha1.Observation{{Component: opsv1alpha1.SpoofGuard, Action: opsv1alpha1.Forwarded}},
+				}},
+			}
+			_, err = crdClient.OpsV1alpha1().Traceflows().UpdateStatus(context.TODO(), tf, metav1.UpdateOptions{})
+			return err == nil, nil
+		})
+	}()
+	spec := &opsv1alpha1.TraceflowSpec{
+		Source:      opsv1alpha1.Source{Namespace: "default", Pod: "web"},
+		Destination: opsv1alpha1.Destination{Namespace: "default", Pod: "db"},
+	}
+	status, err := requestTraceflow(client, spec, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, opsv1alpha1.Succeeded, status.Phase)
+
+	out := new(bytes.Buffer)
+	require.NoError(t, printStatus(out, status, outputFormatText))
+	assert.Equal(t, "Phase: Succeeded\nNode node1 sender:\n  SpoofGuard Forwarded\n", out.String())
+
+	_, err = requestTraceflow(client, spec, time.Hour)
+	assert.Error(t, err)
+}
@@ -0,0 +1,208 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceflowhistory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	antctlruntime "github.com/vmware-tanzu/antrea/pkg/antctl/runtime"
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	antrea "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+)
+
+const (
+	outputFormatTable = "table"
+	outputFormatJSON  = "json"
+)
+
+// Command is the get traceflowhistory command implementation.
+var Command *cobra.Command
+
+var option = &struct {
+	source      string
+	destination string
+	output      string
+}{}
+
+var traceflowHistoryLongDescription = strings.TrimSpace(`
+Get the histories of the most recently completed Traceflows, including the Traceflows which have been deleted.
+The histories are sorted by completion time, the most recent first. They can be filtered by Traceflow name, by
+source and by destination. A source or destination is either a Pod specified by <Namespace>/<name>, or an IP
+address, which matches the source IP of the traced packet or the destination IP of the Traceflow respectively.
+`)
+
+var traceflowHistoryExample = strings.Trim(`
+  Get the histories of all completed Traceflows
+  $ antctl get traceflowhistory
+  Get the histories of the Traceflows named tf1
+  $ antctl get traceflowhistory tf1
+  Get the histories of the Traceflows from Pod web in Namespace default to IP 10.10.1.2, and output them in JSON
+  $ antctl get traceflowhistory -S default/web -D 10.10.1.2 -o json
+`, "\n")
+
+func init() {
+	Command = &cobra.Command{
+		Use:     "traceflowhistory [name]",
+		Aliases: []string{"traceflowhistories", "tfh"},
+		Short:   "Get the histories of completed Traceflows",
+		Long:    traceflowHistoryLongDescription,
+		Example: traceflowHistoryExample,
+		Args:    cobra.MaximumNArgs(1),
+		RunE:    runE,
+	}
+	Command.Flags().StringVarP(&option.source, "source", "S", "", "only get the Traceflows from this source, which can be a Pod specified by <Namespace>/<name> or an IP address")
+	Command.Flags().StringVarP(&option.destination, "destination", "D", "", "only get the Traceflows to this destination, which can be a Pod specified by <Namespace>/<name> or an IP address")
+	Command.Flags().StringVarP(&option.output, "output", "o", outputFormatTable, "output format, supports 'table' and 'json'")
+}
+
+// endpoint is a source or destination filter.
+type endpoint struct {
+	namespace string
+	pod       string
+	ip        string
+}
+
+func parseEndpoint(s string) (*endpoint, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if ip := net.ParseIP(s); ip != nil {
+		return &endpoint{ip: ip.String()}, nil
+	}
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("%s is neither a Pod specified by <Namespace>/<name> nor an IP address", s)
+	}
+	return &endpoint{namespace: parts[0], pod: parts[1]}, nil
+}
+
+func (e *endpoint) matches(namespace, pod, ip string) bool {
+	if e == nil {
+		return true
+	}
+	if e.ip != "" {
+		parsedIP := net.ParseIP(ip)
+		return parsedIP != nil && parsedIP.String() == e.ip
+	}
+	return e.namespace == namespace && e.pod == pod
+}
+
+// filter returns the histories which match the Traceflow name, the source and the
+// destination, sorted by completion time, the most recent first.
+func filter(histories []opsv1alpha1.TraceflowHistory, name string, source, destination *endpoint) []opsv1alpha1.TraceflowHistory {
+	var matched []opsv1alpha1.TraceflowHistory
+	for _, h := range histories {
+		if name != "" && h.TraceflowName != name {
+			continue
+		}
+		if !source.matches(h.Spec.Source.Namespace, h.Spec.Source.Pod, h.Spec.Packet.IPHeader.SrcIP) {
+			continue
+		}
+		if !destination.matches(h.Spec.Destination.Namespace, h.Spec.Destination.Pod, h.Spec.Destination.IP) {
+			continue
+		}
+		matched = append(matched, h)
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[j].CompletionTime.Before(&matched[i].CompletionTime)
+	})
+	return matched
+}
+
+func destinationString(d *opsv1alpha1.Destination) string {
+	if d.IP != "" {
+		return d.IP
+	}
+	if d.Service != "" {
+		return d.Namespace + "/" + d.Service
+	}
+	return d.Namespace + "/" + d.Pod
+}
+
+func output(histories []opsv1alpha1.TraceflowHistory, format string, writer io.Writer) error {
+	switch format {
+	case outputFormatJSON:
+		if histories == nil {
+			histories = []opsv1alpha1.TraceflowHistory{}
+		}
+		data, err := json.MarshalIndent(histories, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error when encoding the histories: %w", err)
+		}
+		_, err = fmt.Fprintln(writer, string(data))
+		return err
+	case outputFormatTable:
+		w := tabwriter.NewWriter(writer, 15, 0, 1, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSOURCE\tDESTINATION\tPHASE\tCREATOR\tCOMPLETED\t")
+		for _, h := range histories {
+			creator := h.Creator
+			if creator == "" {
+				creator = "<unknown>"
+			}
+			fmt.Fprintf(w, "%s\t%s/%s\t%s\t%s\t%s\t%s\t\n", h.TraceflowName, h.Spec.Source.Namespace, h.Spec.Source.Pod,
+				destinationString(&h.Spec.Destination), h.Status.Phase, creator, h.CompletionTime.UTC().Format(time.RFC3339))
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %s", format)
+	}
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	if option.output != outputFormatTable && option.output != outputFormatJSON {
+		return fmt.Errorf("unsupported output format %s", option.output)
+	}
+	source, err := parseEndpoint(option.source)
+	if err != nil {
+		return fmt.Errorf("invalid source: %w", err)
+	}
+	destination, err := parseEndpoint(option.destination)
+	if err != nil {
+		return fmt.Errorf("invalid destination: %w", err)
+	}
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	kubeconfigPath, err := cmd.Flags().GetString("kubeconfig")
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := antctlruntime.ResolveKubeconfig(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	antreaClientset, err := antrea.NewForConfig(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error when creating antrea clientset: %w", err)
+	}
+	histories, err := antreaClientset.OpsV1alpha1().TraceflowHistories().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error when listing Traceflow histories: %w", err)
+	}
+	return output(filter(histories.Items, name, source, destination), option.output, cmd.OutOrStdout())
+}
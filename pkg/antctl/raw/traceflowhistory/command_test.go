@@ -0,0 +1,117 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceflowhistory
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+)
+
+var completionTime = time.Date(2020, 7, 1, 10, 0, 0, 0, time.UTC)
+
+func newHistory(name string, srcPod, srcIP string, dst opsv1alpha1.Destination, completed time.Duration) opsv1alpha1.TraceflowHistory {
+	return opsv1alpha1.TraceflowHistory{
+		ObjectMeta:     metav1.ObjectMeta{Name: name + "-uid"},
+		TraceflowName:  name,
+		CompletionTime: metav1.NewTime(completionTime.Add(completed)),
+		Spec: opsv1alpha1.TraceflowSpec{
+			Source:      opsv1alpha1.Source{Namespace: "default", Pod: srcPod},
+			Destination: dst,
+			Packet:      opsv1alpha1.Packet{IPHeader: opsv1alpha1.IPHeader{SrcIP: srcIP}},
+		},
+		Status: opsv1alpha1.TraceflowStatus{Phase: opsv1alpha1.Succeeded},
+	}
+}
+
+func TestParseEndpoint(t *testing.T) {
+	e, err := parseEndpoint("")
+	require.NoError(t, err)
+	assert.Nil(t, e)
+
+	e, err = parseEndpoint("default/web")
+	require.NoError(t, err)
+	assert.Equal(t, &endpoint{namespace: "default", pod: "web"}, e)
+
+	e, err = parseEndpoint("10.10.1.2")
+	require.NoError(t, err)
+	assert.Equal(t, &endpoint{ip: "10.10.1.2"}, e)
+
+	for _, s := range []string{"web", "default/", "/web", "default/web/1"} {
+		_, err := parseEndpoint(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	histories := []opsv1alpha1.TraceflowHistory{
+		newHistory("tf1", "web", "", opsv1alpha1.Destination{Namespace: "default", Pod: "db"}, 0),
+		newHistory("tf2", "web", "", opsv1alpha1.Destination{IP: "10.10.1.2"}, time.Minute),
+		newHistory("tf3", "client", "10.10.0.3", opsv1alpha1.Destination{Namespace: "default", Pod: "db"}, 2*time.Minute),
+		newHistory("tf1", "client", "", opsv1alpha1.Destination{Namespace: "default", Pod: "web"}, 3*time.Minute),
+	}
+	names := func(histories []opsv1alpha1.TraceflowHistory) []string {
+		var names []string
+		for _, h := range histories {
+			names = append(names, h.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"tf1-uid", "tf3-uid", "tf2-uid", "tf1-uid"}, names(filter(histories, "", nil, nil)))
+	assert.Equal(t, []string{"tf1-uid", "tf1-uid"}, names(filter(histories, "tf1", nil, nil)))
+	assert.Equal(t, []string{"tf2-uid", "tf1-uid"}, names(filter(histories, "", &endpoint{namespace: "default", pod: "web"}, nil)))
+	assert.Equal(t, []string{"tf3-uid"}, names(filter(histories, "", &endpoint{ip: "10.10.0.3"}, nil)))
+	assert.Equal(t, []string{"tf3-uid", "tf1-uid"}, names(filter(histories, "", nil, &endpoint{namespace: "default", pod: "db"})))
+	assert.Equal(t, []string{"tf2-uid"}, names(filter(histories, "", &endpoint{namespace: "default", pod: "web"}, &endpoint{ip: "10.10.1.2"})))
+	assert.Empty(t, filter(histories, "tf2", nil, &endpoint{namespace: "default", pod: "db"}))
+}
+
+func TestOutput(t *testing.T) {
+	histories := []opsv1alpha1.TraceflowHistory{
+		newHistory("tf1", "web", "", opsv1alpha1.Destination{IP: "10.10.1.2"}, 0),
+	}
+	histories[0].Creator = "kubectl"
+
+	var buf bytes.Buffer
+	require.NoError(t, output(histories, outputFormatJSON, &buf))
+	var decoded []opsv1alpha1.TraceflowHistory
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Len(t, decoded, 1)
+	assert.Equal(t, histories[0].Spec, decoded[0].Spec)
+	assert.Equal(t, "kubectl", decoded[0].Creator)
+	assert.True(t, histories[0].CompletionTime.Equal(&decoded[0].CompletionTime))
+
+	buf.Reset()
+	require.NoError(t, output(nil, outputFormatJSON, &buf))
+	assert.Equal(t, "[]", strings.TrimSpace(buf.String()))
+
+	buf.Reset()
+	require.NoError(t, output(histories, outputFormatTable, &buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"NAME", "SOURCE", "DESTINATION", "PHASE", "CREATOR", "COMPLETED"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"tf1", "default/web", "10.10.1.2", "Succeeded", "kubectl", "2020-07-01T10:00:00Z"}, strings.Fields(lines[1]))
+
+	assert.Error(t, output(histories, "yaml", &buf))
+}
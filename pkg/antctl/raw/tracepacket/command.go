@@ -0,0 +1,493 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracepacket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/watch"
+
+	antctlruntime "github.com/vmware-tanzu/antrea/pkg/antctl/runtime"
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	antrea "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+)
+
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+
+	protocolICMP = 1
+	protocolTCP  = 6
+	protocolUDP  = 17
+	tcpFlagSYN   = 2
+
+	// The outcomes of a trace.
+	outcomeDelivered    = "delivered"
+	outcomeDropped      = "dropped"
+	outcomePolicyDenied = "policy-denied"
+	outcomeFailed       = "failed"
+
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+// Command is the trace-packet command implementation.
+var Command *cobra.Command
+
+var option = &struct {
+	srcPod   string
+	dstPod   string
+	protocol string
+	dstPort  int32
+	repeat   int
+	interval time.Duration
+	timeout  time.Duration
+	output   string
+}{}
+
+var tracePacketLongDescription = strings.TrimSpace(`
+Trace a packet from a source Pod to a destination Pod with Traceflow, and print each hop of the packet as soon as
+it is observed. The trace can be repeated to debug intermittent issues, in which case a summary of the hops which
+dropped the packet is printed at the end. Hops are colored when the output is a terminal: green when the packet is
+forwarded, red when it is dropped, and yellow when it is denied by a NetworkPolicy.
+`)
+
+var tracePacketExample = strings.Trim(`
+  Trace a TCP packet to port 80 from Pod web to Pod db in Namespace default
+  $ antctl trace-packet --src-pod default/web --dst-pod default/db --protocol tcp --dst-port 80
+  Trace the same packet 5 times, every 2 seconds
+  $ antctl trace-packet --src-pod default/web --dst-pod default/db --protocol tcp --dst-port 80 --repeat 5 --interval 2s
+  Trace an ICMP packet and output newline-delimited JSON records
+  $ antctl trace-packet --src-pod default/web --dst-pod default/db --protocol icmp -o json
+`, "\n")
+
+func init() {
+	Command = &cobra.Command{
+		Use:     "trace-packet",
+		Short:   "Trace a packet between two Pods and print its hops as they are observed",
+		Long:    tracePacketLongDescription,
+		Example: tracePacketExample,
+		Args:    cobra.NoArgs,
+		RunE:    runE,
+	}
+	Command.Flags().StringVar(&option.srcPod, "src-pod", "", "source Pod of the packet, specified by <Namespace>/<name>")
+	Command.Flags().StringVar(&option.dstPod, "dst-pod", "", "destination Pod of the packet, specified by <Namespace>/<name>")
+	Command.Flags().StringVar(&option.protocol, "protocol", "tcp", "protocol of the packet, supports 'tcp', 'udp' and 'icmp'")
+	Command.Flags().Int32Var(&option.dstPort, "dst-port", 0, "destination port of the TCP or UDP packet")
+	Command.Flags().IntVar(&option.repeat, "repeat", 1, "number of times the packet is traced")
+	Command.Flags().DurationVar(&option.interval, "interval", 2*time.Second, "interval between two traces")
+	Command.Flags().DurationVar(&option.timeout, "timeout", 30*time.Second, "how long to wait for each trace to complete")
+	Command.Flags().StringVarP(&option.output, "output", "o", outputFormatText, "output format, supports 'text' and 'json'")
+}
+
+func parsePod(s string) (namespace, name string, err error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("%q is not a Pod specified by <Namespace>/<name>", s)
+	}
+	return parts[0], parts[1], nil
+}
+
+// newTraceflowSpec returns the spec of the Traceflows created for the options.
+func newTraceflowSpec() (*opsv1alpha1.TraceflowSpec, error) {
+	srcNamespace, srcPod, err := parsePod(option.srcPod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source: %w", err)
+	}
+	dstNamespace, dstPod, err := parsePod(option.dstPod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination: %w", err)
+	}
+	spec := &opsv1alpha1.TraceflowSpec{
+		Source:      opsv1alpha1.Source{Namespace: srcNamespace, Pod: srcPod},
+		Destination: opsv1alpha1.Destination{Namespace: dstNamespace, Pod: dstPod},
+	}
+	if option.dstPort < 0 || option.dstPort > 65535 {
+		return nil, fmt.Errorf("invalid destination port %d", option.dstPort)
+	}
+	switch strings.ToLower(option.protocol) {
+	case "tcp":
+		spec.Packet.IPHeader.Protocol = protocolTCP
+		spec.Packet.TransportHeader.TCP = &opsv1alpha1.TCPHeader{DstPort: option.dstPort, Flags: tcpFlagSYN}
+	case "udp":
+		spec.Packet.IPHeader.Protocol = protocolUDP
+		spec.Packet.TransportHeader.UDP = &opsv1alpha1.UDPHeader{DstPort: option.dstPort}
+	case "icmp":
+		if option.dstPort != 0 {
+			return nil, fmt.Errorf("a destination port cannot be set for ICMP")
+		}
+		spec.Packet.IPHeader.Protocol = protocolICMP
+	default:
+		return nil, fmt.Errorf("unsupported protocol %s", option.protocol)
+	}
+	return spec, nil
+}
+
+// hopRecord is an observation of a traced packet on a Node.
+type hopRecord struct {
+	Type      string `json:"type"`
+	Trace     int    `json:"trace"`
+	Traceflow string `json:"traceflow"`
+	Node      string `json:"node"`
+	Role      string `json:"role,omitempty"`
+	opsv1alpha1.Observation
+}
+
+// resultRecord is the outcome of a trace.
+type resultRecord struct {
+	Type      string `json:"type"`
+	Trace     int    `json:"trace"`
+	Traceflow string `json:"traceflow"`
+	Outcome   string `json:"outcome"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// failingHop is a hop which dropped the traced packet in some traces.
+type failingHop struct {
+	Node      string                         `json:"node"`
+	Component opsv1alpha1.TraceflowComponent `json:"component"`
+	Count     int                            `json:"count"`
+	// Consistent is true if the hop dropped the packet in all the traces.
+	Consistent bool `json:"consistent"`
+}
+
+// summaryRecord aggregates the outcomes of all the traces.
+type summaryRecord struct {
+	Type        string         `json:"type"`
+	Traces      int            `json:"traces"`
+	Outcomes    map[string]int `json:"outcomes"`
+	FailingHops []failingHop   `json:"failingHops,omitempty"`
+}
+
+type printer struct {
+	out    io.Writer
+	format string
+	color  bool
+}
+
+// isTerminal returns true if the writer is a terminal, in which case the hops are
+// colored.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (p *printer) colorize(s, color string) string {
+	if !p.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func (p *printer) printJSON(record interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(p.out, string(data))
+	return err
+}
+
+func (p *printer) printHop(h *hopRecord) error {
+	if p.format == outputFormatJSON {
+		return p.printJSON(h)
+	}
+	action := string(h.Action)
+	switch {
+	case h.Action == opsv1alpha1.Dropped && h.Component == opsv1alpha1.NetworkPolicy:
+		action = p.colorize(action, colorYellow)
+	case h.Action == opsv1alpha1.Dropped:
+		action = p.colorize(action, colorRed)
+	case h.Action != "":
+		action = p.colorize(action, colorGreen)
+	}
+	details := []string{}
+	for _, d := range []struct{ name, value string }{
+		{"pod", h.Pod},
+		{"networkPolicy", h.NetworkPolicy},
+		{"translatedSrcIP", h.TranslatedSrcIP},
+		{"translatedDstIP", h.TranslatedDstIP},
+		{"tunnelDstIP", h.TunnelDstIP},
+		{"reason", h.Reason},
+	} {
+		if d.value != "" {
+			details = append(details, d.name+"="+d.value)
+		}
+	}
+	component := string(h.Component)
+	if h.ComponentInfo != "" {
+		component += "/" + h.ComponentInfo
+	}
+	_, err := fmt.Fprintf(p.out, "[%d] %s %s %s %s %s\n", h.Trace, h.Node, h.Role, component, action, strings.Join(details, " "))
+	return err
+}
+
+func (p *printer) printResult(r *resultRecord) error {
+	if p.format == outputFormatJSON {
+		return p.printJSON(r)
+	}
+	outcome := r.Outcome
+	switch r.Outcome {
+	case outcomeDelivered:
+		outcome = p.colorize(outcome, colorGreen)
+	case outcomePolicyDenied:
+		outcome = p.colorize(outcome, colorYellow)
+	default:
+		outcome = p.colorize(outcome, colorRed)
+	}
+	msg := fmt.Sprintf("[%d] Traceflow %s: %s", r.Trace, r.Traceflow, outcome)
+	if r.Reason != "" {
+		msg += " (" + r.Reason + ")"
+	}
+	_, err := fmt.Fprintln(p.out, msg)
+	return err
+}
+
+func (p *printer) printSummary(s *summaryRecord) error {
+	if p.format == outputFormatJSON {
+		return p.printJSON(s)
+	}
+	outcomes := make([]string, 0, len(s.Outcomes))
+	for outcome, count := range s.Outcomes {
+		outcomes = append(outcomes, fmt.Sprintf("%d %s", count, outcome))
+	}
+	sort.Strings(outcomes)
+	fmt.Fprintf(p.out, "Summary of %d traces: %s\n", s.Traces, strings.Join(outcomes, ", "))
+	for _, h := range s.FailingHops {
+		consistency := "intermittently"
+		if h.Consistent {
+			consistency = "consistently"
+		}
+		fmt.Fprintf(p.out, "  %s %s dropped the packet %s (%d/%d)\n", h.Node, h.Component, consistency, h.Count, s.Traces)
+	}
+	return nil
+}
+
+// traceState tracks the observations of a Traceflow which have been printed.
+type traceState struct {
+	trace   int
+	printer *printer
+	// printed is the number of printed observations of each NodeResult,
+	// identified by Node and role.
+	printed map[string]int
+}
+
+// update prints the new observations of the Traceflow and returns true if it
+// has completed.
+func (s *traceState) update(tf *opsv1alpha1.Traceflow) (bool, error) {
+	for _, result := range tf.Status.Results {
+		key := result.Node + "/" + result.Role
+		for i := s.printed[key]; i < len(result.Observations); i++ {
+			h := &hopRecord{
+				Type:        "hop",
+				Trace:       s.trace,
+				Traceflow:   tf.Name,
+				Node:        result.Node,
+				Role:        result.Role,
+				Observation: result.Observations[i],
+			}
+			if err := s.printer.printHop(h); err != nil {
+				return false, err
+			}
+		}
+		s.printed[key] = len(result.Observations)
+	}
+	return tf.Status.Phase == opsv1alpha1.Succeeded || tf.Status.Phase == opsv1alpha1.Failed, nil
+}
+
+// trace creates a Traceflow with the spec, prints its observations as soon as
+// they are recorded, and returns it once it has completed. The Traceflow is
+// deleted afterwards.
+func trace(ctx context.Context, client antrea.Interface, spec *opsv1alpha1.TraceflowSpec, index int, p *printer) (*opsv1alpha1.Traceflow, error) {
+	tf := &opsv1alpha1.Traceflow{
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("trace-packet-%s", rand.String(8))},
+		Spec:       *spec,
+	}
+	tf, err := client.OpsV1alpha1().Traceflows().Create(ctx, tf, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error when creating Traceflow: %w", err)
+	}
+	defer client.OpsV1alpha1().Traceflows().Delete(context.TODO(), tf.Name, metav1.DeleteOptions{})
+
+	w, err := client.OpsV1alpha1().Traceflows().Watch(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", tf.Name).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when watching Traceflow %s: %w", tf.Name, err)
+	}
+	defer w.Stop()
+
+	state := &traceState{trace: index, printer: p, printed: map[string]int{}}
+	// The Traceflow may have been updated before the watch started.
+	if tf, err = client.OpsV1alpha1().Traceflows().Get(ctx, tf.Name, metav1.GetOptions{}); err != nil {
+		return nil, fmt.Errorf("error when getting Traceflow: %w", err)
+	}
+	if done, err := state.update(tf); done || err != nil {
+		return tf, err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("Traceflow %s did not complete in time", tf.Name)
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return nil, fmt.Errorf("watch of Traceflow %s closed", tf.Name)
+			}
+			updated, ok := event.Object.(*opsv1alpha1.Traceflow)
+			if !ok || updated.Name != tf.Name {
+				continue
+			}
+			if event.Type == watch.Deleted {
+				return nil, fmt.Errorf("Traceflow %s was deleted", tf.Name)
+			}
+			if done, err := state.update(updated); done || err != nil {
+				return updated, err
+			}
+		}
+	}
+}
+
+// getResult returns the outcome of a completed Traceflow.
+func getResult(tf *opsv1alpha1.Traceflow, index int) *resultRecord {
+	r := &resultRecord{Type: "result", Trace: index, Traceflow: tf.Name, Outcome: outcomeDelivered}
+	if tf.Status.Phase == opsv1alpha1.Failed {
+		r.Outcome, r.Reason = outcomeFailed, tf.Status.Reason
+		return r
+	}
+	for _, result := range tf.Status.Results {
+		for _, o := range result.Observations {
+			if o.Action != opsv1alpha1.Dropped {
+				continue
+			}
+			r.Outcome, r.Reason = outcomeDropped, o.Reason
+			if o.Component == opsv1alpha1.NetworkPolicy {
+				r.Outcome, r.Reason = outcomePolicyDenied, o.NetworkPolicy
+			}
+			return r
+		}
+	}
+	return r
+}
+
+// summarize aggregates the outcomes of the completed Traceflows.
+func summarize(traceflows []*opsv1alpha1.Traceflow, results []*resultRecord, traces int) *summaryRecord {
+	s := &summaryRecord{Type: "summary", Traces: traces, Outcomes: map[string]int{}}
+	for _, r := range results {
+		s.Outcomes[r.Outcome]++
+	}
+	// Traces which timed out are counted as failed.
+	if timedOut := traces - len(results); timedOut > 0 {
+		s.Outcomes[outcomeFailed] += timedOut
+	}
+	counts := map[failingHop]int{}
+	for _, tf := range traceflows {
+		for _, result := range tf.Status.Results {
+			for _, o := range result.Observations {
+				if o.Action == opsv1alpha1.Dropped {
+					counts[failingHop{Node: result.Node, Component: o.Component}]++
+				}
+			}
+		}
+	}
+	for h, count := range counts {
+		h.Count, h.Consistent = count, count == traces
+		s.FailingHops = append(s.FailingHops, h)
+	}
+	sort.Slice(s.FailingHops, func(i, j int) bool {
+		if s.FailingHops[i].Count != s.FailingHops[j].Count {
+			return s.FailingHops[i].Count > s.FailingHops[j].Count
+		}
+		return s.FailingHops[i].Node+string(s.FailingHops[i].Component) < s.FailingHops[j].Node+string(s.FailingHops[j].Component)
+	})
+	return s
+}
+
+// run traces the packet the requested number of times, and prints a summary if
+// it is traced more than once.
+func run(client antrea.Interface, spec *opsv1alpha1.TraceflowSpec, p *printer) error {
+	var traceflows []*opsv1alpha1.Traceflow
+	var results []*resultRecord
+	for i := 1; i <= option.repeat; i++ {
+		if i > 1 {
+			time.Sleep(option.interval)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), option.timeout)
+		tf, err := trace(ctx, client, spec, i, p)
+		cancel()
+		if err != nil {
+			if option.repeat == 1 {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Trace %d failed: %v\n", i, err)
+			continue
+		}
+		result := getResult(tf, i)
+		if err := p.printResult(result); err != nil {
+			return err
+		}
+		traceflows = append(traceflows, tf)
+		results = append(results, result)
+	}
+	if option.repeat > 1 {
+		return p.printSummary(summarize(traceflows, results, option.repeat))
+	}
+	return nil
+}
+
+func runE(cmd *cobra.Command, _ []string) error {
+	if option.output != outputFormatText && option.output != outputFormatJSON {
+		return fmt.Errorf("unsupported output format %s", option.output)
+	}
+	if option.repeat < 1 {
+		return fmt.Errorf("repeat must be at least 1")
+	}
+	spec, err := newTraceflowSpec()
+	if err != nil {
+		return err
+	}
+
+	kubeconfigPath, err := cmd.Flags().GetString("kubeconfig")
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := antctlruntime.ResolveKubeconfig(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	antreaClientset, err := antrea.NewForConfig(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error when creating antrea clientset: %w", err)
+	}
+	out := cmd.OutOrStdout()
+	p := &printer{out: out, format: option.output, color: option.output == outputFormatText && isTerminal(out)}
+	return run(antreaClientset, spec, p)
+}
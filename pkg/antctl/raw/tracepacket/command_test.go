@@ -0,0 +1,242 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracepacket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+)
+
+var (
+	senderResult = opsv1alpha1.NodeResult{
+		Node: "node1",
+		Role: "Sender",
+		Observations: []opsv1alpha1.Observation{
+			{Component: opsv1alpha1.SpoofGuard, Action: opsv1alpha1.Forwarded},
+			{Component: opsv1alpha1.Forwarding, Action: opsv1alpha1.Forwarded, TunnelDstIP: "172.18.0.3"},
+		},
+	}
+	deliveredResult = opsv1alpha1.NodeResult{
+		Node: "node2",
+		Role: "Receiver",
+		Observations: []opsv1alpha1.Observation{
+			{Component: opsv1alpha1.Forwarding, Action: opsv1alpha1.Received},
+			{Component: opsv1alpha1.Forwarding, Action: opsv1alpha1.Delivered, Pod: "default/db"},
+		},
+	}
+	deniedResult = opsv1alpha1.NodeResult{
+		Node: "node2",
+		Role: "Receiver",
+		Observations: []opsv1alpha1.Observation{
+			{Component: opsv1alpha1.Forwarding, Action: opsv1alpha1.Received},
+			{Component: opsv1alpha1.NetworkPolicy, ComponentInfo: "IngressRule", Action: opsv1alpha1.Dropped, NetworkPolicy: "default/deny-all"},
+		},
+	}
+)
+
+// setOptions sets the command options and returns a function restoring them.
+func setOptions(protocol string, dstPort int32, repeat int) func() {
+	oldOption := *option
+	option.srcPod = "default/web"
+	option.dstPod = "default/db"
+	option.protocol = protocol
+	option.dstPort = dstPort
+	option.repeat = repeat
+	option.interval = 0
+	option.timeout = 5 * time.Second
+	return func() { *option = oldOption }
+}
+
+// respond plays the role of the Antrea agents: it completes the Traceflows
+// created by the command one after the other, first recording the sender
+// observations, then the receiver ones.
+func respond(t *testing.T, client *fake.Clientset, receiverResults []opsv1alpha1.NodeResult) {
+	handled := map[string]bool{}
+	for _, receiverResult := range receiverResults {
+		var tf *opsv1alpha1.Traceflow
+		err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+			list, err := client.OpsV1alpha1().Traceflows().List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return false, err
+			}
+			for i := range list.Items {
+				if !handled[list.Items[i].Name] {
+					tf = &list.Items[i]
+					return true, nil
+				}
+			}
+			return false, nil
+		})
+		if !assert.NoError(t, err, "Traceflow was not created") {
+			return
+		}
+		handled[tf.Name] = true
+		tf.Status.Phase = opsv1alpha1.Running
+		tf.Status.Results = []opsv1alpha1.NodeResult{senderResult}
+		tf, err = client.OpsV1alpha1().Traceflows().UpdateStatus(context.TODO(), tf, metav1.UpdateOptions{})
+		if !assert.NoError(t, err) {
+			return
+		}
+		tf.Status.Phase = opsv1alpha1.Succeeded
+		tf.Status.Results = append(tf.Status.Results, receiverResult)
+		_, err = client.OpsV1alpha1().Traceflows().UpdateStatus(context.TODO(), tf, metav1.UpdateOptions{})
+		assert.NoError(t, err)
+	}
+}
+
+func TestNewTraceflowSpec(t *testing.T) {
+	defer setOptions("tcp", 80, 1)()
+	spec, err := newTraceflowSpec()
+	require.NoError(t, err)
+	assert.Equal(t, opsv1alpha1.Source{Namespace: "default", Pod: "web"}, spec.Source)
+	assert.Equal(t, opsv1alpha1.Destination{Namespace: "default", Pod: "db"}, spec.Destination)
+	assert.Equal(t, int32(protocolTCP), spec.Packet.IPHeader.Protocol)
+	assert.Equal(t, &opsv1alpha1.TCPHeader{DstPort: 80, Flags: tcpFlagSYN}, spec.Packet.TransportHeader.TCP)
+
+	option.protocol = "udp"
+	spec, err = newTraceflowSpec()
+	require.NoError(t, err)
+	assert.Equal(t, int32(protocolUDP), spec.Packet.IPHeader.Protocol)
+	assert.Equal(t, &opsv1alpha1.UDPHeader{DstPort: 80}, spec.Packet.TransportHeader.UDP)
+
+	option.protocol = "icmp"
+	_, err = newTraceflowSpec()
+	assert.Error(t, err, "ICMP packets have no port")
+	option.dstPort = 0
+	spec, err = newTraceflowSpec()
+	require.NoError(t, err)
+	assert.Equal(t, int32(protocolICMP), spec.Packet.IPHeader.Protocol)
+
+	option.protocol = "sctp"
+	_, err = newTraceflowSpec()
+	assert.Error(t, err)
+
+	option.protocol = "tcp"
+	for _, pod := range []string{"", "web", "default/", "/web", "default/web/1"} {
+		option.dstPod = pod
+		_, err = newTraceflowSpec()
+		assert.Error(t, err, "Pod %q should be invalid", pod)
+	}
+}
+
+func TestRunText(t *testing.T) {
+	defer setOptions("tcp", 80, 1)()
+	spec, err := newTraceflowSpec()
+	require.NoError(t, err)
+	client := fake.NewSimpleClientset()
+	go respond(t, client, []opsv1alpha1.NodeResult{deliveredResult})
+
+	var buf bytes.Buffer
+	require.NoError(t, run(client, spec, &printer{out: &buf, format: outputFormatText}))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.Equal(t, []string{"[1]", "node1", "Sender", "SpoofGuard", "Forwarded"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"[1]", "node1", "Sender", "Forwarding", "Forwarded", "tunnelDstIP=172.18.0.3"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"[1]", "node2", "Receiver", "Forwarding", "Received"}, strings.Fields(lines[2]))
+	assert.Equal(t, []string{"[1]", "node2", "Receiver", "Forwarding", "Delivered", "pod=default/db"}, strings.Fields(lines[3]))
+	assert.Contains(t, lines[4], outcomeDelivered)
+
+	list, err := client.OpsV1alpha1().Traceflows().List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, list.Items, "Traceflow should be deleted once completed")
+}
+
+func TestRunColor(t *testing.T) {
+	defer setOptions("tcp", 80, 1)()
+	spec, err := newTraceflowSpec()
+	require.NoError(t, err)
+	client := fake.NewSimpleClientset()
+	go respond(t, client, []opsv1alpha1.NodeResult{deniedResult})
+
+	var buf bytes.Buffer
+	require.NoError(t, run(client, spec, &printer{out: &buf, format: outputFormatText, color: true}))
+	output := buf.String()
+	assert.Contains(t, output, colorGreen+string(opsv1alpha1.Forwarded)+colorReset)
+	assert.Contains(t, output, colorYellow+string(opsv1alpha1.Dropped)+colorReset)
+	assert.Contains(t, output, colorYellow+outcomePolicyDenied+colorReset)
+}
+
+func TestRunJSONWithRepeat(t *testing.T) {
+	defer setOptions("udp", 53, 3)()
+	spec, err := newTraceflowSpec()
+	require.NoError(t, err)
+	client := fake.NewSimpleClientset()
+	go respond(t, client, []opsv1alpha1.NodeResult{deniedResult, deliveredResult, deniedResult})
+
+	var buf bytes.Buffer
+	require.NoError(t, run(client, spec, &printer{out: &buf, format: outputFormatJSON}))
+	var results []resultRecord
+	var summary summaryRecord
+	hops := 0
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var record map[string]interface{}
+		require.NoError(t, decoder.Decode(&record))
+		data, _ := json.Marshal(record)
+		switch record["type"] {
+		case "hop":
+			hops++
+		case "result":
+			var r resultRecord
+			require.NoError(t, json.Unmarshal(data, &r))
+			results = append(results, r)
+		case "summary":
+			require.NoError(t, json.Unmarshal(data, &summary))
+		default:
+			t.Fatalf("Unexpected record %s", data)
+		}
+	}
+	assert.Equal(t, 12, hops)
+	require.Len(t, results, 3)
+	assert.Equal(t, outcomePolicyDenied, results[0].Outcome)
+	assert.Equal(t, "default/deny-all", results[0].Reason)
+	assert.Equal(t, outcomeDelivered, results[1].Outcome)
+	assert.Equal(t, 3, summary.Traces)
+	assert.Equal(t, map[string]int{outcomePolicyDenied: 2, outcomeDelivered: 1}, summary.Outcomes)
+	assert.Equal(t, []failingHop{{Node: "node2", Component: opsv1alpha1.NetworkPolicy, Count: 2, Consistent: false}}, summary.FailingHops)
+}
+
+func TestSummarize(t *testing.T) {
+	dropped := &opsv1alpha1.Traceflow{Status: opsv1alpha1.TraceflowStatus{
+		Phase: opsv1alpha1.Succeeded,
+		Results: []opsv1alpha1.NodeResult{{
+			Node:         "node1",
+			Observations: []opsv1alpha1.Observation{{Component: opsv1alpha1.Routing, Action: opsv1alpha1.Dropped}},
+		}},
+	}}
+	results := []*resultRecord{getResult(dropped, 1), getResult(dropped, 2)}
+	assert.Equal(t, outcomeDropped, results[0].Outcome)
+
+	s := summarize([]*opsv1alpha1.Traceflow{dropped, dropped}, results, 2)
+	assert.Equal(t, map[string]int{outcomeDropped: 2}, s.Outcomes)
+	assert.Equal(t, []failingHop{{Node: "node1", Component: opsv1alpha1.Routing, Count: 2, Consistent: true}}, s.FailingHops)
+
+	// A trace which did not complete is counted as failed.
+	s = summarize([]*opsv1alpha1.Traceflow{dropped}, results[:1], 2)
+	assert.Equal(t, map[string]int{outcomeDropped: 1, outcomeFailed: 1}, s.Outcomes)
+	assert.False(t, s.FailingHops[0].Consistent)
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicystats
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/vmware-tanzu/antrea/pkg/antctl/transform"
+	"github.com/vmware-tanzu/antrea/pkg/antctl/transform/common"
+	clusterinfov1beta1 "github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1"
+	systemv1beta1 "github.com/vmware-tanzu/antrea/pkg/apis/system/v1beta1"
+)
+
+// Response describes the response struct of networkpolicystats command.
+type Response struct {
+	UID          string                          `json:"uid"`
+	Namespace    string                          `json:"namespace,omitempty"`
+	Name         string                          `json:"name"`
+	Ingress      clusterinfov1beta1.TrafficStats `json:"ingress"`
+	Egress       clusterinfov1beta1.TrafficStats `json:"egress"`
+	RuleHitCount int64                           `json:"ruleHitCount"`
+}
+
+// FromAgentStats converts the statistics collected by an agent to Response.
+func FromAgentStats(stats *clusterinfov1beta1.NetworkPolicyStats) Response {
+	return Response{
+		UID:          string(stats.PolicyUID),
+		Namespace:    stats.PolicyNamespace,
+		Name:         stats.PolicyName,
+		Ingress:      stats.Ingress,
+		Egress:       stats.Egress,
+		RuleHitCount: stats.RuleHitCount,
+	}
+}
+
+func listTransform(l interface{}) (interface{}, error) {
+	statsList := l.(*systemv1beta1.NetworkPolicyStatsList)
+	result := []interface{}{}
+	// The items are already sorted by traffic volume.
+	for i := range statsList.Items {
+		o, _ := objectTransform(&statsList.Items[i])
+		result = append(result, o.(Response))
+	}
+	return result, nil
+}
+
+func objectTransform(o interface{}) (interface{}, error) {
+	stats := o.(*systemv1beta1.NetworkPolicyStats)
+	return Response{
+		UID:          stats.Name,
+		Namespace:    stats.PolicyNamespace,
+		Name:         stats.PolicyName,
+		Ingress:      stats.Ingress,
+		Egress:       stats.Egress,
+		RuleHitCount: stats.RuleHitCount,
+	}, nil
+}
+
+func Transform(reader io.Reader, single bool) (interface{}, error) {
+	return transform.GenericFactory(
+		reflect.TypeOf(systemv1beta1.NetworkPolicyStats{}),
+		reflect.TypeOf(systemv1beta1.NetworkPolicyStatsList{}),
+		objectTransform,
+		listTransform,
+	)(reader, single)
+}
+
+var _ common.TableOutput = new(Response)
+
+func (r Response) GetTableHeader() []string {
+	return []string{"NAMESPACE", "NAME", "INGRESS-SESSIONS", "INGRESS-PACKETS", "INGRESS-BYTES", "EGRESS-SESSIONS", "EGRESS-PACKETS", "EGRESS-BYTES", "RULE-HITS"}
+}
+
+func (r Response) GetTableRow(maxColumnLength int) []string {
+	return []string{
+		r.Namespace,
+		r.Name,
+		strconv.FormatInt(r.Ingress.SessionCount, 10),
+		strconv.FormatInt(r.Ingress.PacketCount, 10),
+		strconv.FormatInt(r.Ingress.ByteCount, 10),
+		strconv.FormatInt(r.Egress.SessionCount, 10),
+		strconv.FormatInt(r.Egress.PacketCount, 10),
+		strconv.FormatInt(r.Egress.ByteCount, 10),
+		strconv.FormatInt(r.RuleHitCount, 10),
+	}
+}
+
+// SortRows returns false as the rows are sorted by traffic volume by the
+// server.
+func (r Response) SortRows() bool {
+	return false
+}
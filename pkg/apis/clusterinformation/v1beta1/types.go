@@ -17,6 +17,7 @@ package v1beta1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // +genclient
@@ -35,6 +36,7 @@ type AntreaAgentInfo struct {
 	LocalPodNum                 int32                       `json:"localPodNum,omitempty"`                 // The number of Pods which the agent is in charge of
 	AgentConditions             []AgentCondition            `json:"agentConditions,omitempty"`             // Agent condition contains types like AgentHealthy
 	APIPort                     int                         `json:"apiPort,omitempty"`                     // The port of antrea agent API Server
+	NetworkPolicyStats          []NetworkPolicyStats        `json:"networkPolicyStats,omitempty"`          // Traffic statistics of the NetworkPolicies enforced by the agent
 }
 
 type OVSInfo struct {
@@ -91,6 +93,25 @@ type NetworkPolicyControllerInfo struct {
 	AppliedToGroupNum int32 `json:"appliedToGroupNum,omitempty"`
 }
 
+// TrafficStats is the traffic matched by the rules of a NetworkPolicy in one
+// direction. Packets of established connections skip the NetworkPolicy rules,
+// so PacketCount and ByteCount only include the packets evaluated by the rules:
+// the first packet of each allowed connection and every denied packet.
+type TrafficStats struct {
+	PacketCount  int64 `json:"packetCount"`  // Number of packets matched by the rules
+	ByteCount    int64 `json:"byteCount"`    // Number of bytes matched by the rules
+	SessionCount int64 `json:"sessionCount"` // Number of connections allowed by the rules
+}
+
+type NetworkPolicyStats struct {
+	PolicyUID       types.UID    `json:"policyUID"`                 // UID of the NetworkPolicy
+	PolicyName      string       `json:"policyName"`                // Name of the NetworkPolicy
+	PolicyNamespace string       `json:"policyNamespace,omitempty"` // Namespace of the NetworkPolicy, empty for ClusterNetworkPolicy
+	Ingress         TrafficStats `json:"ingress"`                   // Traffic matched by the ingress rules
+	Egress          TrafficStats `json:"egress"`                    // Traffic matched by the egress rules
+	RuleHitCount    int64        `json:"ruleHitCount"`              // Number of packets which hit the allow or deny action flows of the rules in both directions
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type AntreaControllerInfoList struct {
 	metav1.TypeMeta `json:",inline"`
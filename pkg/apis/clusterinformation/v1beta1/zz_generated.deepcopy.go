@@ -60,6 +60,11 @@ func (in *AntreaAgentInfo) DeepCopyInto(out *AntreaAgentInfo) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.NetworkPolicyStats != nil {
+		in, out := &in.NetworkPolicyStats, &out.NetworkPolicyStats
+		*out = make([]NetworkPolicyStats, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -217,6 +222,24 @@ func (in *NetworkPolicyControllerInfo) DeepCopy() *NetworkPolicyControllerInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkPolicyStats) DeepCopyInto(out *NetworkPolicyStats) {
+	*out = *in
+	out.Ingress = in.Ingress
+	out.Egress = in.Egress
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkPolicyStats.
+func (in *NetworkPolicyStats) DeepCopy() *NetworkPolicyStats {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkPolicyStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OVSInfo) DeepCopyInto(out *OVSInfo) {
 	*out = *in
@@ -239,3 +262,19 @@ func (in *OVSInfo) DeepCopy() *OVSInfo {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficStats) DeepCopyInto(out *TrafficStats) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficStats.
+func (in *TrafficStats) DeepCopy() *TrafficStats {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficStats)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -44,8 +44,12 @@ var (
 // Adds the list of known types to the given scheme.
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
+		&Egress{},
+		&EgressList{},
 		&ExternalEntity{},
 		&ExternalEntityList{},
+		&IPPool{},
+		&IPPoolList{},
 	)
 
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
@@ -74,3 +74,173 @@ type ExternalEntityList struct {
 
 	Items []ExternalEntity `json:"items,omitempty"`
 }
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// IPPool is a pool of IPs which are allocated to the Pods running on the
+// selected Nodes or in the selected Namespaces, instead of the IPs in the
+// PodCIDR of the Node.
+type IPPool struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard metadata of the object.
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	// Desired state of the IP pool.
+	Spec IPPoolSpec `json:"spec,omitempty"`
+	// Most recently observed status of the IP pool.
+	Status IPPoolStatus `json:"status,omitempty"`
+}
+
+// IPPoolSpec defines the desired state for IPPool.
+type IPPoolSpec struct {
+	// Ranges is a list of IP ranges the IPs are allocated from. The ranges
+	// must not overlap with each other, or with the ranges of other IPPools.
+	Ranges []IPRange `json:"ranges"`
+	// NodeSelector selects the Nodes whose Pods get their IPs from this pool.
+	// If not set, the Pods on all Nodes may get their IPs from this pool.
+	// +optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	// NamespaceSelector selects the Namespaces whose Pods get their IPs from
+	// this pool. If not set, the Pods in all Namespaces may get their IPs from
+	// this pool.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// IPRange is a range of IPs in CIDR notation.
+type IPRange struct {
+	// CIDR of the range, e.g. 10.10.0.0/24.
+	CIDR string `json:"cidr"`
+	// Gateway of the Pods which get their IPs from this range. It must be in
+	// the CIDR. If not set, it defaults to the first IP of the CIDR.
+	// +optional
+	Gateway string `json:"gateway,omitempty"`
+	// VLAN ID of the range. 0 means no VLAN.
+	// +optional
+	VLAN int32 `json:"vlan,omitempty"`
+}
+
+type IPPoolConditionType string
+
+const (
+	// IPPoolConditionValid means the IPPool has valid ranges which do not
+	// overlap with any other IPPool. It is set by antrea-controller, and the
+	// agents only allocate IPs from the valid IPPools.
+	IPPoolConditionValid IPPoolConditionType = "Valid"
+	// IPPoolConditionPoolExhausted means all the IPs of the IPPool have been
+	// allocated. It is set by the antrea-agent failing to allocate an IP.
+	IPPoolConditionPoolExhausted IPPoolConditionType = "PoolExhausted"
+)
+
+// IPPoolCondition describes the state of an IPPool at a certain point.
+type IPPoolCondition struct {
+	// Type of the condition.
+	Type IPPoolConditionType `json:"type"`
+	// Status of the condition, one of True, False or Unknown.
+	Status v1.ConditionStatus `json:"status"`
+	// Last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// The reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// A human readable message indicating details about the transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// IPAllocation is an IP of the IPPool allocated to a Pod.
+type IPAllocation struct {
+	// The allocated IP.
+	IP string `json:"ip"`
+	// Namespace of the Pod.
+	Namespace string `json:"namespace"`
+	// Name of the Pod.
+	Pod string `json:"pod"`
+	// ID of the infra container of the Pod.
+	ContainerID string `json:"containerID"`
+	// Name of the interface in the Pod the IP is allocated to. It is empty for
+	// the primary interface.
+	Interface string `json:"interface,omitempty"`
+	// Name of the Node running the Pod.
+	Node string `json:"node"`
+}
+
+// IPPoolStatus is the status of an IPPool.
+type IPPoolStatus struct {
+	// Conditions of the IPPool.
+	// +optional
+	Conditions []IPPoolCondition `json:"conditions,omitempty"`
+	// Allocations is the list of the IPs currently allocated from the IPPool.
+	// +optional
+	Allocations []IPAllocation `json:"allocations,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type IPPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []IPPool `json:"items,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Egress makes the egress traffic of the selected Pods leave the cluster with a
+// stable IP, instead of the IP of the Node running the Pods.
+type Egress struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard metadata of the object.
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	// Desired state of the Egress.
+	Spec EgressSpec `json:"spec"`
+	// Most recently observed status of the Egress.
+	Status EgressStatus `json:"status,omitempty"`
+}
+
+// EgressSpec defines the desired state for Egress.
+type EgressSpec struct {
+	// AppliedTo selects the Pods whose egress traffic is SNATed to EgressIP.
+	AppliedTo EgressAppliedTo `json:"appliedTo"`
+	// EgressIP is the source IP of the egress traffic of the selected Pods.
+	// It must be in the subnet of the transport interfaces of the Nodes, as
+	// it is assigned to the Node selected by antrea-controller.
+	EgressIP string `json:"egressIP"`
+}
+
+// EgressAppliedTo selects the Pods an Egress applies to. The Pods must match
+// both selectors, and an empty selector matches everything.
+type EgressAppliedTo struct {
+	// Select Pods from the Namespaces matching NamespaceSelector. If not set,
+	// Pods are selected from all Namespaces.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// Select Pods matching PodSelector. If not set, all the Pods of the
+	// selected Namespaces are selected.
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+}
+
+// EgressStatus is the status of an Egress.
+type EgressStatus struct {
+	// EgressNode is the name of the Node the EgressIP is assigned to. It is
+	// set by antrea-controller, and the egress traffic of the selected Pods
+	// is tunneled to this Node to be SNATed.
+	// +optional
+	EgressNode string `json:"egressNode,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type EgressList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Egress `json:"items,omitempty"`
+}
@@ -158,8 +158,20 @@ type NetworkPolicy struct {
 	// Priority represents the relative priority of this Network Policy as compared to
 	// other Network Policies. Priority will be unset (nil) for K8s Network Policy.
 	Priority *float64
+	// TierPriority represents the priority of the tier of this Network Policy. Policies
+	// of tiers with a lower TierPriority are enforced first. TierPriority will be unset
+	// (nil) for K8s Network Policy.
+	TierPriority *int32
 }
 
+const (
+	// DefaultTierPriority is the TierPriority of the Network Policies of the default tier.
+	DefaultTierPriority int32 = 250
+	// BaselineTierPriority is the TierPriority of the Network Policies of the baseline
+	// tier, which are enforced after all the other Antrea Network Policies.
+	BaselineTierPriority int32 = 253
+)
+
 // Direction defines traffic direction of NetworkPolicyRule.
 type Direction string
 
@@ -187,6 +199,19 @@ type NetworkPolicyRule struct {
 	// action “nil” defaults to Allow action, which would be the case for rules created for
 	// K8s Network Policy.
 	Action *secv1alpha1.RuleAction
+	// EnableLogging indicates whether or not to generate logs when rules are matched.
+	EnableLogging bool
+	// HTTPMatches restricts the rule to the HTTP requests matching any of the
+	// conditions, each request of a TCP connection being evaluated. Empty for
+	// K8s NetworkPolicy.
+	HTTPMatches []HTTPMatch
+	// Bandwidth limits the bandwidth of each Pod to which the rule is applied,
+	// in the direction of the rule. Nil for K8s NetworkPolicy.
+	Bandwidth *Bandwidth
+	// PreserveSourceIP indicates that the traffic matched by an egress rule
+	// must not be SNATed when it leaves the Node, so that the destination sees
+	// the IP of the source Pod. Always false for K8s NetworkPolicy.
+	PreserveSourceIP bool
 }
 
 // Protocol defines network protocols supported for things like container ports.
@@ -212,6 +237,28 @@ type Service struct {
 	Port *intstr.IntOrString
 }
 
+// Bandwidth describes the bandwidth limit of a rule.
+type Bandwidth struct {
+	// Rate is the maximum rate in bits per second.
+	Rate int64
+	// Burst is the maximum burst size in bits.
+	Burst int64
+}
+
+// HTTPMatch describes the HTTP requests matched by a rule.
+type HTTPMatch struct {
+	// Method is the method of the request. If it is empty or "*", all methods
+	// are matched.
+	Method string
+	// Path is the pattern matched against the path of the request, in which
+	// "*" matches any sequence of characters. If it is empty, all paths are
+	// matched.
+	Path string
+	// Headers are the headers which the request must have, keyed by header
+	// name. Values are patterns matched in the same way as Path.
+	Headers map[string]string
+}
+
 // NetworkPolicyPeer describes a peer of NetworkPolicyRules.
 // It could be a list of names of AddressGroups and/or a list of IPBlock.
 type NetworkPolicyPeer struct {
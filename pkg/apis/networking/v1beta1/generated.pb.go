@@ -23,6 +23,7 @@ import (
 	io "io"
 
 	proto "github.com/gogo/protobuf/proto"
+	github_com_gogo_protobuf_sortkeys "github.com/gogo/protobuf/sortkeys"
 	github_com_vmware_tanzu_antrea_pkg_apis_security_v1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
 
 	math "math"
@@ -212,10 +213,38 @@ func (m *AppliedToGroupPatch) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_AppliedToGroupPatch proto.InternalMessageInfo
 
+func (m *Bandwidth) Reset()      { *m = Bandwidth{} }
+func (*Bandwidth) ProtoMessage() {}
+func (*Bandwidth) Descriptor() ([]byte, []int) {
+	return fileDescriptor_da8f95e0f1c69434, []int{6}
+}
+func (m *Bandwidth) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Bandwidth) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	b = b[:cap(b)]
+	n, err := m.MarshalToSizedBuffer(b)
+	if err != nil {
+		return nil, err
+	}
+	return b[:n], nil
+}
+func (m *Bandwidth) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Bandwidth.Merge(m, src)
+}
+func (m *Bandwidth) XXX_Size() int {
+	return m.Size()
+}
+func (m *Bandwidth) XXX_DiscardUnknown() {
+	xxx_messageInfo_Bandwidth.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Bandwidth proto.InternalMessageInfo
+
 func (m *Endpoint) Reset()      { *m = Endpoint{} }
 func (*Endpoint) ProtoMessage() {}
 func (*Endpoint) Descriptor() ([]byte, []int) {
-	return fileDescriptor_da8f95e0f1c69434, []int{6}
+	return fileDescriptor_da8f95e0f1c69434, []int{7}
 }
 func (m *Endpoint) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -243,7 +272,7 @@ var xxx_messageInfo_Endpoint proto.InternalMessageInfo
 func (m *ExternalEntityReference) Reset()      { *m = ExternalEntityReference{} }
 func (*ExternalEntityReference) ProtoMessage() {}
 func (*ExternalEntityReference) Descriptor() ([]byte, []int) {
-	return fileDescriptor_da8f95e0f1c69434, []int{7}
+	return fileDescriptor_da8f95e0f1c69434, []int{8}
 }
 func (m *ExternalEntityReference) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -271,7 +300,7 @@ var xxx_messageInfo_ExternalEntityReference proto.InternalMessageInfo
 func (m *GroupMember) Reset()      { *m = GroupMember{} }
 func (*GroupMember) ProtoMessage() {}
 func (*GroupMember) Descriptor() ([]byte, []int) {
-	return fileDescriptor_da8f95e0f1c69434, []int{8}
+	return fileDescriptor_da8f95e0f1c69434, []int{9}
 }
 func (m *GroupMember) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -299,7 +328,7 @@ var xxx_messageInfo_GroupMember proto.InternalMessageInfo
 func (m *GroupMemberPod) Reset()      { *m = GroupMemberPod{} }
 func (*GroupMemberPod) ProtoMessage() {}
 func (*GroupMemberPod) Descriptor() ([]byte, []int) {
-	return fileDescriptor_da8f95e0f1c69434, []int{9}
+	return fileDescriptor_da8f95e0f1c69434, []int{10}
 }
 func (m *GroupMemberPod) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -324,10 +353,38 @@ func (m *GroupMemberPod) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_GroupMemberPod proto.InternalMessageInfo
 
+func (m *HTTPMatch) Reset()      { *m = HTTPMatch{} }
+func (*HTTPMatch) ProtoMessage() {}
+func (*HTTPMatch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_da8f95e0f1c69434, []int{11}
+}
+func (m *HTTPMatch) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *HTTPMatch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	b = b[:cap(b)]
+	n, err := m.MarshalToSizedBuffer(b)
+	if err != nil {
+		return nil, err
+	}
+	return b[:n], nil
+}
+func (m *HTTPMatch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HTTPMatch.Merge(m, src)
+}
+func (m *HTTPMatch) XXX_Size() int {
+	return m.Size()
+}
+func (m *HTTPMatch) XXX_DiscardUnknown() {
+	xxx_messageInfo_HTTPMatch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HTTPMatch proto.InternalMessageInfo
+
 func (m *IPBlock) Reset()      { *m = IPBlock{} }
 func (*IPBlock) ProtoMessage() {}
 func (*IPBlock) Descriptor() ([]byte, []int) {
-	return fileDescriptor_da8f95e0f1c69434, []int{10}
+	return fileDescriptor_da8f95e0f1c69434, []int{12}
 }
 func (m *IPBlock) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -355,7 +412,7 @@ var xxx_messageInfo_IPBlock proto.InternalMessageInfo
 func (m *IPNet) Reset()      { *m = IPNet{} }
 func (*IPNet) ProtoMessage() {}
 func (*IPNet) Descriptor() ([]byte, []int) {
-	return fileDescriptor_da8f95e0f1c69434, []int{11}
+	return fileDescriptor_da8f95e0f1c69434, []int{13}
 }
 func (m *IPNet) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -383,7 +440,7 @@ var xxx_messageInfo_IPNet proto.InternalMessageInfo
 func (m *NamedPort) Reset()      { *m = NamedPort{} }
 func (*NamedPort) ProtoMessage() {}
 func (*NamedPort) Descriptor() ([]byte, []int) {
-	return fileDescriptor_da8f95e0f1c69434, []int{12}
+	return fileDescriptor_da8f95e0f1c69434, []int{14}
 }
 func (m *NamedPort) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -411,7 +468,7 @@ var xxx_messageInfo_NamedPort proto.InternalMessageInfo
 func (m *NetworkPolicy) Reset()      { *m = NetworkPolicy{} }
 func (*NetworkPolicy) ProtoMessage() {}
 func (*NetworkPolicy) Descriptor() ([]byte, []int) {
-	return fileDescriptor_da8f95e0f1c69434, []int{13}
+	return fileDescriptor_da8f95e0f1c69434, []int{15}
 }
 func (m *NetworkPolicy) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -439,7 +496,7 @@ var xxx_messageInfo_NetworkPolicy proto.InternalMessageInfo
 func (m *NetworkPolicyList) Reset()      { *m = NetworkPolicyList{} }
 func (*NetworkPolicyList) ProtoMessage() {}
 func (*NetworkPolicyList) Descriptor() ([]byte, []int) {
-	return fileDescriptor_da8f95e0f1c69434, []int{14}
+	return fileDescriptor_da8f95e0f1c69434, []int{16}
 }
 func (m *NetworkPolicyList) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -467,7 +524,7 @@ var xxx_messageInfo_NetworkPolicyList proto.InternalMessageInfo
 func (m *NetworkPolicyPeer) Reset()      { *m = NetworkPolicyPeer{} }
 func (*NetworkPolicyPeer) ProtoMessage() {}
 func (*NetworkPolicyPeer) Descriptor() ([]byte, []int) {
-	return fileDescriptor_da8f95e0f1c69434, []int{15}
+	return fileDescriptor_da8f95e0f1c69434, []int{17}
 }
 func (m *NetworkPolicyPeer) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -495,7 +552,7 @@ var xxx_messageInfo_NetworkPolicyPeer proto.InternalMessageInfo
 func (m *NetworkPolicyRule) Reset()      { *m = NetworkPolicyRule{} }
 func (*NetworkPolicyRule) ProtoMessage() {}
 func (*NetworkPolicyRule) Descriptor() ([]byte, []int) {
-	return fileDescriptor_da8f95e0f1c69434, []int{16}
+	return fileDescriptor_da8f95e0f1c69434, []int{18}
 }
 func (m *NetworkPolicyRule) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -523,7 +580,7 @@ var xxx_messageInfo_NetworkPolicyRule proto.InternalMessageInfo
 func (m *PodReference) Reset()      { *m = PodReference{} }
 func (*PodReference) ProtoMessage() {}
 func (*PodReference) Descriptor() ([]byte, []int) {
-	return fileDescriptor_da8f95e0f1c69434, []int{17}
+	return fileDescriptor_da8f95e0f1c69434, []int{19}
 }
 func (m *PodReference) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -551,7 +608,7 @@ var xxx_messageInfo_PodReference proto.InternalMessageInfo
 func (m *Service) Reset()      { *m = Service{} }
 func (*Service) ProtoMessage() {}
 func (*Service) Descriptor() ([]byte, []int) {
-	return fileDescriptor_da8f95e0f1c69434, []int{18}
+	return fileDescriptor_da8f95e0f1c69434, []int{20}
 }
 func (m *Service) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -583,10 +640,13 @@ func init() {
 	proto.RegisterType((*AppliedToGroup)(nil), "github.com.vmware_tanzu.antrea.pkg.apis.networking.v1beta1.AppliedToGroup")
 	proto.RegisterType((*AppliedToGroupList)(nil), "github.com.vmware_tanzu.antrea.pkg.apis.networking.v1beta1.AppliedToGroupList")
 	proto.RegisterType((*AppliedToGroupPatch)(nil), "github.com.vmware_tanzu.antrea.pkg.apis.networking.v1beta1.AppliedToGroupPatch")
+	proto.RegisterType((*Bandwidth)(nil), "github.com.vmware_tanzu.antrea.pkg.apis.networking.v1beta1.Bandwidth")
 	proto.RegisterType((*Endpoint)(nil), "github.com.vmware_tanzu.antrea.pkg.apis.networking.v1beta1.Endpoint")
 	proto.RegisterType((*ExternalEntityReference)(nil), "github.com.vmware_tanzu.antrea.pkg.apis.networking.v1beta1.ExternalEntityReference")
 	proto.RegisterType((*GroupMember)(nil), "github.com.vmware_tanzu.antrea.pkg.apis.networking.v1beta1.GroupMember")
 	proto.RegisterType((*GroupMemberPod)(nil), "github.com.vmware_tanzu.antrea.pkg.apis.networking.v1beta1.GroupMemberPod")
+	proto.RegisterType((*HTTPMatch)(nil), "github.com.vmware_tanzu.antrea.pkg.apis.networking.v1beta1.HTTPMatch")
+	proto.RegisterMapType((map[string]string)(nil), "github.com.vmware_tanzu.antrea.pkg.apis.networking.v1beta1.HTTPMatch.HeadersEntry")
 	proto.RegisterType((*IPBlock)(nil), "github.com.vmware_tanzu.antrea.pkg.apis.networking.v1beta1.IPBlock")
 	proto.RegisterType((*IPNet)(nil), "github.com.vmware_tanzu.antrea.pkg.apis.networking.v1beta1.IPNet")
 	proto.RegisterType((*NamedPort)(nil), "github.com.vmware_tanzu.antrea.pkg.apis.networking.v1beta1.NamedPort")
@@ -603,90 +663,106 @@ func init() {
 }
 
 var fileDescriptor_da8f95e0f1c69434 = []byte{
-	// 1324 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xec, 0x58, 0xc1, 0x6f, 0x1b, 0xc5,
-	0x17, 0xce, 0xae, 0xed, 0x24, 0x9e, 0x38, 0x69, 0x33, 0xf9, 0x49, 0x3f, 0x53, 0x90, 0x1d, 0x2d,
-	0x97, 0x1c, 0xe8, 0x2e, 0x29, 0x15, 0x54, 0x08, 0x0e, 0x71, 0x13, 0x8a, 0xab, 0x36, 0x5d, 0x4d,
-	0x7b, 0x42, 0x48, 0x30, 0xd9, 0x9d, 0x38, 0xd3, 0x78, 0x77, 0x96, 0xd9, 0xb1, 0xdb, 0xc2, 0x05,
-	0x2e, 0x48, 0x9c, 0xda, 0x13, 0x17, 0x6e, 0x88, 0xbf, 0x82, 0x0b, 0xd7, 0x9e, 0x50, 0x8f, 0xe5,
-	0x62, 0x88, 0xcb, 0x5f, 0x51, 0x2e, 0x68, 0x66, 0x67, 0xbd, 0xbb, 0x0e, 0x56, 0x23, 0xec, 0x44,
-	0x1c, 0x72, 0x4a, 0x66, 0xe6, 0xcd, 0xfb, 0xde, 0x7c, 0xf3, 0xde, 0x37, 0x6f, 0x0d, 0x6e, 0x76,
-	0xa8, 0x38, 0xe8, 0xed, 0xd9, 0x1e, 0x0b, 0x9c, 0x7e, 0xf0, 0x00, 0x73, 0x72, 0x59, 0xe0, 0xf0,
-	0xcb, 0x9e, 0x83, 0x43, 0xc1, 0x09, 0x76, 0xa2, 0xc3, 0x8e, 0x83, 0x23, 0x1a, 0x3b, 0x21, 0x11,
-	0x0f, 0x18, 0x3f, 0xa4, 0x61, 0xc7, 0xe9, 0x6f, 0xee, 0x11, 0x81, 0x37, 0x9d, 0x0e, 0x09, 0x09,
-	0xc7, 0x82, 0xf8, 0x76, 0xc4, 0x99, 0x60, 0xf0, 0xfd, 0xcc, 0x97, 0x9d, 0xf8, 0xfa, 0x4c, 0xf9,
-	0xb2, 0x13, 0x5f, 0x76, 0x74, 0xd8, 0xb1, 0xa5, 0x2f, 0x3b, 0xf3, 0x65, 0x6b, 0x5f, 0x97, 0x2e,
-	0xe7, 0xe2, 0xe8, 0xb0, 0x0e, 0x73, 0x94, 0xcb, 0xbd, 0xde, 0xbe, 0x1a, 0xa9, 0x81, 0xfa, 0x2f,
-	0x81, 0xba, 0x74, 0xf5, 0xf0, 0x5a, 0x6c, 0x53, 0x26, 0x43, 0x0b, 0xb0, 0x77, 0x40, 0x43, 0xc2,
-	0x1f, 0x65, 0xb1, 0x06, 0x44, 0x60, 0xa7, 0x7f, 0x2c, 0xc0, 0x4b, 0xce, 0xa4, 0x5d, 0xbc, 0x17,
-	0x0a, 0x1a, 0x90, 0x63, 0x1b, 0xde, 0x7d, 0xd5, 0x86, 0xd8, 0x3b, 0x20, 0x01, 0x3e, 0xb6, 0xef,
-	0x9d, 0x49, 0xfb, 0x7a, 0x82, 0x76, 0x1d, 0x1a, 0x8a, 0x58, 0xf0, 0xf1, 0x4d, 0xd6, 0xd0, 0x04,
-	0xb5, 0x2d, 0xdf, 0xe7, 0x24, 0x8e, 0x6f, 0x70, 0xd6, 0x8b, 0xe0, 0xe7, 0x60, 0x51, 0x9e, 0xc4,
-	0xc7, 0x02, 0xd7, 0x8d, 0x75, 0x63, 0x63, 0xe9, 0xca, 0xdb, 0x76, 0xe2, 0xd8, 0xce, 0x3b, 0xce,
-	0x78, 0x95, 0xd6, 0x76, 0x7f, 0xd3, 0xbe, 0xb3, 0x77, 0x9f, 0x78, 0xe2, 0x36, 0x11, 0xb8, 0x05,
-	0x9f, 0x0e, 0x9a, 0x73, 0xc3, 0x41, 0x13, 0x64, 0x73, 0x68, 0xe4, 0x15, 0x76, 0x41, 0x39, 0x62,
-	0x7e, 0x5c, 0x37, 0xd7, 0x4b, 0x1b, 0x4b, 0x57, 0x6e, 0xda, 0xff, 0xfe, 0x02, 0x6d, 0x15, 0xf2,
-	0x6d, 0x12, 0xec, 0x11, 0xee, 0x32, 0xbf, 0x55, 0xd3, 0xb8, 0x65, 0x97, 0xf9, 0x31, 0x52, 0x28,
-	0xf0, 0x1b, 0x03, 0xd4, 0x3a, 0x99, 0x59, 0x5c, 0x2f, 0x29, 0xd8, 0x1b, 0x33, 0x82, 0x6d, 0xfd,
-	0x4f, 0x63, 0xd6, 0x72, 0x93, 0x31, 0x2a, 0x40, 0x5a, 0xbf, 0x1b, 0xe0, 0x62, 0x9e, 0xe4, 0x5b,
-	0x34, 0x16, 0xf0, 0xd3, 0x63, 0x44, 0xdb, 0x27, 0x23, 0x5a, 0xee, 0x56, 0x34, 0x5f, 0xd4, 0xd0,
-	0x8b, 0xe9, 0x4c, 0x8e, 0xe4, 0x00, 0x54, 0xa8, 0x20, 0x41, 0xca, 0xf2, 0xc7, 0xd3, 0x1c, 0x37,
-	0x1f, 0x7a, 0x6b, 0x59, 0x83, 0x56, 0xda, 0xd2, 0x3d, 0x4a, 0x50, 0xac, 0x1f, 0x2b, 0x60, 0x35,
-	0x6f, 0xe6, 0x62, 0xe1, 0x1d, 0x9c, 0x41, 0x2e, 0x7d, 0x05, 0xaa, 0xd8, 0xf7, 0x89, 0xef, 0x9e,
-	0x4e, 0x42, 0xad, 0x6a, 0xf0, 0xea, 0x56, 0x0a, 0x82, 0x32, 0x3c, 0x99, 0x5a, 0x4b, 0x9c, 0x04,
-	0xac, 0xaf, 0xf1, 0x4b, 0x33, 0xc7, 0x5f, 0xd3, 0xf8, 0x4b, 0x28, 0x83, 0x41, 0x79, 0x4c, 0xf8,
-	0xc4, 0x00, 0xab, 0x2a, 0xa2, 0x7c, 0xfa, 0xd5, 0xcb, 0xb3, 0xcd, 0xf1, 0xd7, 0x74, 0x18, 0xab,
-	0x5b, 0xe3, 0x48, 0xe8, 0x38, 0x38, 0xfc, 0xde, 0x00, 0x6b, 0x3a, 0xc4, 0x42, 0x50, 0x95, 0xd9,
-	0x06, 0xf5, 0xba, 0x0e, 0x6a, 0x0d, 0x1d, 0xc7, 0x42, 0xff, 0x14, 0x80, 0xf5, 0xa7, 0x09, 0x56,
-	0xb6, 0xa2, 0xa8, 0x4b, 0x89, 0x7f, 0x8f, 0x9d, 0xab, 0xdd, 0x69, 0xa9, 0xdd, 0x0b, 0x03, 0xc0,
-	0x22, 0xcd, 0x67, 0xa0, 0x77, 0xac, 0xa8, 0x77, 0x53, 0xf1, 0x5c, 0x0c, 0x7e, 0x82, 0xe2, 0xfd,
-	0x54, 0x01, 0x6b, 0x45, 0xc3, 0x73, 0xcd, 0x3b, 0xd7, 0xbc, 0xff, 0x9c, 0xe6, 0xfd, 0x60, 0x80,
-	0xc5, 0x9d, 0xd0, 0x8f, 0x18, 0x0d, 0x05, 0x7c, 0x13, 0x98, 0x34, 0x52, 0x59, 0x59, 0x6b, 0xad,
-	0x0d, 0x07, 0x4d, 0xb3, 0xed, 0xbe, 0x1c, 0x34, 0xab, 0x6d, 0x57, 0x3f, 0xdd, 0xc8, 0xa4, 0x11,
-	0xbc, 0x0f, 0x2a, 0x11, 0xe3, 0x22, 0x4d, 0xad, 0x9d, 0x69, 0x62, 0xdf, 0xc5, 0x81, 0xbc, 0x33,
-	0x2e, 0xb2, 0x22, 0x92, 0xa3, 0x18, 0x25, 0x10, 0x56, 0x17, 0xfc, 0x7f, 0xe7, 0xa1, 0x20, 0x3c,
-	0xc4, 0xdd, 0x9d, 0x50, 0x50, 0xf1, 0x08, 0x91, 0x7d, 0xc2, 0x49, 0xe8, 0x11, 0xb8, 0x0e, 0xca,
-	0x21, 0x0e, 0x88, 0x8a, 0xb6, 0x9a, 0x69, 0x9d, 0xf4, 0x88, 0xd4, 0x0a, 0x74, 0x40, 0x55, 0xfe,
-	0x8d, 0x23, 0xec, 0x91, 0xba, 0xa9, 0xcc, 0x46, 0xb9, 0xbb, 0x9b, 0x2e, 0xa0, 0xcc, 0xc6, 0xfa,
-	0xcb, 0x04, 0x4b, 0x39, 0x72, 0xe0, 0x63, 0x03, 0xac, 0x90, 0x02, 0xbc, 0xae, 0xd8, 0xbb, 0xd3,
-	0x9c, 0x79, 0xc2, 0x81, 0x5a, 0x70, 0x38, 0x68, 0xae, 0x8c, 0x2d, 0x8e, 0xc1, 0x43, 0x0f, 0x94,
-	0x22, 0xe6, 0xab, 0xc3, 0x4c, 0xd9, 0xb3, 0xb9, 0xcc, 0xcf, 0xa0, 0x17, 0x86, 0x83, 0x66, 0x49,
-	0xce, 0x48, 0xef, 0xb0, 0x07, 0xaa, 0x44, 0x67, 0x44, 0x5a, 0xbf, 0xdb, 0x53, 0x1d, 0x58, 0x3b,
-	0xcb, 0xd8, 0x4f, 0x67, 0x62, 0x94, 0x21, 0x59, 0xdf, 0x9a, 0x60, 0xa5, 0x58, 0xea, 0xe9, 0x71,
-	0x8d, 0x53, 0x3d, 0x6e, 0x92, 0xf4, 0xe6, 0x09, 0x93, 0xbe, 0x74, 0xfa, 0x49, 0xff, 0x9b, 0x01,
-	0x16, 0xda, 0x6e, 0xab, 0xcb, 0xbc, 0x43, 0xe8, 0x81, 0xb2, 0x47, 0x7d, 0xae, 0x29, 0xd8, 0x9a,
-	0x06, 0xb6, 0xed, 0xee, 0x12, 0x91, 0x15, 0xca, 0xf5, 0xf6, 0x36, 0x42, 0xca, 0x39, 0xa4, 0x60,
-	0x9e, 0x3c, 0xf4, 0x48, 0x24, 0x74, 0x49, 0xcf, 0x00, 0x66, 0x45, 0xc3, 0xcc, 0xef, 0x28, 0xc7,
-	0x48, 0x03, 0x58, 0xfb, 0xa0, 0xa2, 0x0c, 0x4e, 0x26, 0x35, 0xd7, 0x40, 0x2d, 0xe2, 0x64, 0x9f,
-	0x3e, 0xbc, 0x45, 0xc2, 0x8e, 0x38, 0x50, 0x97, 0x54, 0xc9, 0x7a, 0x0c, 0x37, 0xb7, 0x86, 0x0a,
-	0x96, 0xd6, 0x77, 0x06, 0xa8, 0x8e, 0x78, 0x96, 0x5a, 0x21, 0xa9, 0x55, 0x70, 0x95, 0x7c, 0x5f,
-	0xc4, 0x05, 0x52, 0x2b, 0x23, 0x35, 0x31, 0x27, 0xaa, 0xc9, 0x35, 0xb0, 0xa8, 0xbe, 0x88, 0x3d,
-	0xd6, 0xad, 0x97, 0x94, 0xd5, 0x1b, 0x69, 0xbb, 0xe1, 0xea, 0xf9, 0x97, 0xb9, 0xff, 0xd1, 0xc8,
-	0xda, 0xfa, 0xd5, 0x04, 0xcb, 0xbb, 0x09, 0x51, 0x2e, 0xeb, 0x52, 0xef, 0xd1, 0x19, 0xf4, 0x00,
-	0x1c, 0x54, 0x78, 0xaf, 0x4b, 0x52, 0x91, 0xbe, 0x3d, 0x55, 0xbe, 0xe6, 0x63, 0x47, 0xbd, 0x2e,
-	0xc9, 0xf2, 0x56, 0x8e, 0x62, 0x94, 0x40, 0xc1, 0x0f, 0xc1, 0x05, 0x5c, 0x68, 0x78, 0x92, 0x6a,
-	0xa9, 0xaa, 0xfb, 0xbd, 0x50, 0xec, 0x85, 0x62, 0x34, 0x6e, 0x0b, 0x37, 0x24, 0xc1, 0x94, 0x71,
-	0x29, 0xb3, 0xe5, 0x75, 0x63, 0xc3, 0x68, 0xd5, 0x12, 0x72, 0x93, 0x39, 0x34, 0x5a, 0xb5, 0x8e,
-	0x0c, 0xb0, 0x5a, 0x08, 0xea, 0x0c, 0xfa, 0xc7, 0xb0, 0xd8, 0x3f, 0xb6, 0x67, 0x46, 0xe8, 0x84,
-	0xf6, 0xf1, 0x97, 0xf1, 0x33, 0xba, 0x84, 0x70, 0xf8, 0x1e, 0x58, 0xc6, 0xb9, 0xaf, 0xe8, 0xb8,
-	0x6e, 0x28, 0x82, 0x57, 0x87, 0x83, 0xe6, 0x72, 0xfe, 0xf3, 0x3a, 0x46, 0x45, 0x3b, 0xf8, 0x05,
-	0x58, 0xa4, 0x91, 0x92, 0x94, 0xf4, 0x04, 0xd7, 0xa7, 0x2b, 0x72, 0xe5, 0x2b, 0x63, 0x4c, 0x4f,
-	0xc4, 0x68, 0x04, 0x63, 0xfd, 0x5c, 0x1e, 0x3b, 0x81, 0x4c, 0x16, 0xf8, 0x01, 0xa8, 0xfa, 0x94,
-	0x13, 0x4f, 0x50, 0x16, 0xea, 0xb7, 0xbb, 0x91, 0x3e, 0x0b, 0xdb, 0xe9, 0xc2, 0xcb, 0xfc, 0x00,
-	0x65, 0x1b, 0x20, 0x03, 0xe5, 0x7d, 0xce, 0x02, 0xfd, 0x00, 0xce, 0x2e, 0xab, 0x25, 0xb9, 0x59,
-	0xd5, 0x7f, 0xc4, 0x59, 0x80, 0x14, 0x10, 0xa4, 0xc0, 0x14, 0x4c, 0xd5, 0xfb, 0xcc, 0xe1, 0x80,
-	0x86, 0x33, 0xef, 0x31, 0x64, 0x0a, 0x26, 0xaf, 0x28, 0x26, 0xbc, 0x4f, 0x3d, 0x92, 0xf6, 0xaa,
-	0x53, 0x5d, 0xd1, 0xdd, 0xc4, 0x57, 0x76, 0x45, 0x7a, 0x22, 0x46, 0x23, 0x18, 0xf8, 0x56, 0xae,
-	0xe4, 0x2a, 0x4a, 0x1b, 0x2f, 0x66, 0x9a, 0x36, 0x5e, 0x76, 0xf0, 0x3e, 0x98, 0xc7, 0xc9, 0xbd,
-	0xcd, 0xab, 0x7b, 0x43, 0x52, 0xdf, 0xb7, 0xd2, 0x0b, 0xdb, 0x3e, 0xe9, 0x6f, 0xb6, 0x31, 0xf1,
-	0x7a, 0xd2, 0x9f, 0xd3, 0xdf, 0xc4, 0xdd, 0xe8, 0x00, 0x6f, 0xda, 0x32, 0x31, 0x12, 0x3f, 0x48,
-	0x23, 0x58, 0x18, 0xd4, 0xf2, 0x4f, 0xf6, 0x69, 0x74, 0x7b, 0x8f, 0x0d, 0xb0, 0xa0, 0x39, 0x81,
-	0x57, 0x73, 0xe2, 0x9e, 0x40, 0xd4, 0x5f, 0x2d, 0xec, 0x70, 0x57, 0x3f, 0x2b, 0xe6, 0x2b, 0x24,
-	0xbc, 0x27, 0x68, 0xd7, 0x4e, 0x7e, 0x5f, 0xb5, 0xdb, 0xa1, 0xb8, 0xc3, 0xef, 0x0a, 0x4e, 0xc3,
-	0x4e, 0x6b, 0xb1, 0xf8, 0x08, 0xb5, 0x2e, 0x3f, 0x3d, 0x6a, 0xcc, 0x3d, 0x3b, 0x6a, 0xcc, 0x3d,
-	0x3f, 0x6a, 0xcc, 0x7d, 0x3d, 0x6c, 0x18, 0x4f, 0x87, 0x0d, 0xe3, 0xd9, 0xb0, 0x61, 0x3c, 0x1f,
-	0x36, 0x8c, 0x3f, 0x86, 0x0d, 0xe3, 0xc9, 0x8b, 0xc6, 0xdc, 0x27, 0x0b, 0xfa, 0x86, 0xff, 0x0e,
-	0x00, 0x00, 0xff, 0xff, 0x5f, 0x0e, 0xc9, 0x67, 0x26, 0x17, 0x00, 0x00,
+	// 1576 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xed, 0x59, 0xcd, 0x6f, 0x1b, 0x45,
+	0x14, 0xef, 0xfa, 0x23, 0xf6, 0x4e, 0x9c, 0x34, 0x99, 0x14, 0x61, 0x02, 0x4a, 0xab, 0x45, 0x42,
+	0x3d, 0xd0, 0x35, 0x85, 0x0a, 0xa2, 0x02, 0x87, 0x6c, 0x13, 0x5a, 0x57, 0x4d, 0x6a, 0x4d, 0x72,
+	0x42, 0x48, 0xb0, 0xde, 0x9d, 0xd8, 0xdb, 0xd8, 0xbb, 0xcb, 0xec, 0x38, 0x6d, 0x80, 0x03, 0x48,
+	0x08, 0x09, 0x09, 0x89, 0x9e, 0xb8, 0x70, 0x43, 0xdc, 0xf9, 0x13, 0xb8, 0xf6, 0xd8, 0x63, 0xb9,
+	0x14, 0xda, 0xf2, 0x57, 0xb4, 0x17, 0xde, 0xcc, 0xce, 0x7a, 0x77, 0x6d, 0xa2, 0x46, 0xd8, 0x89,
+	0x38, 0xf4, 0xb0, 0xb2, 0xe7, 0xcd, 0x9b, 0xf7, 0x7b, 0xdf, 0xf3, 0xd6, 0x46, 0xd7, 0x3b, 0x1e,
+	0xef, 0x0e, 0xda, 0xa6, 0x13, 0xf4, 0x1b, 0xfb, 0xfd, 0xdb, 0x36, 0xa3, 0x17, 0xb8, 0xed, 0x7f,
+	0x31, 0x68, 0xd8, 0x3e, 0x67, 0xd4, 0x6e, 0x84, 0x7b, 0x9d, 0x86, 0x1d, 0x7a, 0x51, 0xc3, 0xa7,
+	0xfc, 0x76, 0xc0, 0xf6, 0x3c, 0xbf, 0xd3, 0xd8, 0xbf, 0xd8, 0xa6, 0xdc, 0xbe, 0xd8, 0xe8, 0x50,
+	0x9f, 0x32, 0x9b, 0x53, 0xd7, 0x0c, 0x59, 0xc0, 0x03, 0x7c, 0x39, 0x95, 0x65, 0xc6, 0xb2, 0x3e,
+	0x95, 0xb2, 0xcc, 0x58, 0x96, 0x09, 0xb2, 0x4c, 0x21, 0xcb, 0x4c, 0x65, 0x99, 0x4a, 0xd6, 0xf2,
+	0x85, 0x8c, 0x1e, 0x9d, 0xa0, 0x13, 0x34, 0xa4, 0xc8, 0xf6, 0x60, 0x57, 0xae, 0xe4, 0x42, 0x7e,
+	0x8b, 0xa1, 0x96, 0x2f, 0xed, 0xad, 0x46, 0xa6, 0x17, 0x08, 0xd5, 0xfa, 0xb6, 0xd3, 0xf5, 0x40,
+	0x91, 0x83, 0x54, 0xd7, 0x3e, 0x88, 0x04, 0x2d, 0x47, 0x15, 0x5c, 0x6e, 0x1c, 0x76, 0x8a, 0x0d,
+	0x7c, 0xee, 0xf5, 0xe9, 0xd8, 0x81, 0x77, 0x9f, 0x77, 0x20, 0x72, 0xba, 0xb4, 0x6f, 0x8f, 0x9d,
+	0x7b, 0xe7, 0xb0, 0x73, 0x03, 0xee, 0xf5, 0x1a, 0x9e, 0xcf, 0x23, 0xce, 0x46, 0x0f, 0x19, 0x8f,
+	0x0b, 0xa8, 0xb6, 0xe6, 0xba, 0x8c, 0x46, 0xd1, 0x55, 0x16, 0x0c, 0x42, 0xfc, 0x19, 0xaa, 0x0a,
+	0x4b, 0x5c, 0x9b, 0xdb, 0x75, 0xed, 0x9c, 0x76, 0x7e, 0xf6, 0xed, 0xb7, 0xcc, 0x58, 0xb0, 0x99,
+	0x15, 0x9c, 0xfa, 0x55, 0x70, 0x83, 0x47, 0xcd, 0x9b, 0xed, 0x5b, 0xd4, 0xe1, 0x9b, 0xb0, 0xb2,
+	0xf0, 0xbd, 0x87, 0x67, 0x4f, 0x3d, 0x7e, 0x78, 0x16, 0xa5, 0x34, 0x32, 0x94, 0x8a, 0x7b, 0xa8,
+	0x14, 0x06, 0x6e, 0x54, 0x2f, 0x9c, 0x2b, 0x82, 0xf4, 0xeb, 0xe6, 0x7f, 0x0f, 0xa0, 0x29, 0x55,
+	0xde, 0xa4, 0xfd, 0x36, 0x65, 0xad, 0xc0, 0xb5, 0x6a, 0x0a, 0xb7, 0x04, 0x8b, 0x88, 0x48, 0x14,
+	0xfc, 0x8d, 0x86, 0x6a, 0x9d, 0x94, 0x2d, 0xaa, 0x17, 0x25, 0xec, 0xd5, 0x29, 0xc1, 0x5a, 0x67,
+	0x14, 0x66, 0x2d, 0x43, 0x8c, 0x48, 0x0e, 0xd2, 0xf8, 0x53, 0x43, 0x0b, 0x59, 0x27, 0xdf, 0xf0,
+	0x22, 0x8e, 0x3f, 0x19, 0x73, 0xb4, 0x79, 0x34, 0x47, 0x8b, 0xd3, 0xd2, 0xcd, 0x0b, 0x0a, 0xba,
+	0x9a, 0x50, 0x32, 0x4e, 0xee, 0xa3, 0xb2, 0xc7, 0x69, 0x3f, 0xf1, 0xf2, 0xb5, 0x49, 0xcc, 0xcd,
+	0xaa, 0x6e, 0xcd, 0x29, 0xd0, 0x72, 0x53, 0x88, 0x27, 0x31, 0x8a, 0xf1, 0x4b, 0x19, 0x2d, 0x66,
+	0xd9, 0x5a, 0x36, 0x77, 0xba, 0x27, 0x90, 0x4b, 0x5f, 0x22, 0xdd, 0x76, 0x5d, 0xea, 0xb6, 0x8e,
+	0x27, 0xa1, 0x16, 0x15, 0xb8, 0xbe, 0x96, 0x80, 0x90, 0x14, 0x4f, 0xa4, 0xd6, 0x2c, 0xa3, 0xfd,
+	0x60, 0x5f, 0xe1, 0x17, 0xa7, 0x8e, 0xbf, 0xa4, 0xf0, 0x67, 0x49, 0x0a, 0x43, 0xb2, 0x98, 0xf8,
+	0xae, 0x86, 0x16, 0xa5, 0x46, 0xd9, 0xf4, 0xab, 0x97, 0xa6, 0x9b, 0xe3, 0xaf, 0x28, 0x35, 0x16,
+	0xd7, 0x46, 0x91, 0xc8, 0x38, 0x38, 0xfe, 0x49, 0x43, 0x4b, 0x4a, 0xc5, 0x9c, 0x52, 0xe5, 0xe9,
+	0x2a, 0xf5, 0xaa, 0x52, 0x6a, 0x89, 0x8c, 0x63, 0x91, 0x7f, 0x53, 0xc0, 0xf8, 0xbb, 0x80, 0xe6,
+	0xd7, 0xc2, 0xb0, 0xe7, 0x51, 0x77, 0x27, 0x78, 0xd1, 0xed, 0x8e, 0xab, 0xdb, 0x3d, 0xd1, 0x10,
+	0xce, 0xbb, 0xf9, 0x04, 0xfa, 0x5d, 0x90, 0xef, 0x77, 0x13, 0xf9, 0x39, 0xaf, 0xfc, 0x21, 0x1d,
+	0xef, 0xd7, 0x32, 0x5a, 0xca, 0x33, 0xbe, 0xe8, 0x79, 0x2f, 0x7a, 0xde, 0xff, 0xae, 0xe7, 0x11,
+	0xa4, 0x5b, 0xb6, 0xef, 0xde, 0xf6, 0x5c, 0xde, 0xc5, 0xe7, 0x50, 0x49, 0xcc, 0x7e, 0x32, 0x2f,
+	0x8b, 0x69, 0xff, 0x20, 0x40, 0x23, 0x72, 0x07, 0xbf, 0x8e, 0xca, 0xed, 0x01, 0x8b, 0x38, 0xe4,
+	0x95, 0x60, 0x19, 0xa6, 0xbe, 0x25, 0x88, 0x24, 0xde, 0x33, 0x7e, 0xd6, 0x50, 0x75, 0xc3, 0x77,
+	0xc3, 0x00, 0x66, 0x4a, 0x38, 0x51, 0xf0, 0x42, 0x29, 0xb1, 0x66, 0x2d, 0x01, 0x6b, 0xa1, 0xd9,
+	0x7a, 0x0a, 0xc9, 0xd3, 0x6c, 0xa9, 0x71, 0x80, 0xc0, 0x36, 0xbe, 0x85, 0xca, 0x61, 0xc0, 0x78,
+	0x92, 0xae, 0x1b, 0x93, 0xf8, 0x63, 0xcb, 0xee, 0x8b, 0x3c, 0x60, 0x3c, 0xd5, 0x4e, 0xac, 0xa0,
+	0x30, 0x25, 0x84, 0xd1, 0x43, 0x2f, 0x6f, 0xdc, 0xe1, 0x94, 0xf9, 0x76, 0x6f, 0x03, 0xe6, 0x65,
+	0x7e, 0x40, 0xe8, 0x2e, 0x65, 0xd4, 0x77, 0xa8, 0xb0, 0xdf, 0x87, 0xd3, 0x52, 0x5b, 0x3d, 0xb5,
+	0x5f, 0x48, 0x24, 0x72, 0x07, 0x37, 0x90, 0x2e, 0x3e, 0xa3, 0xd0, 0x76, 0xa8, 0xf4, 0x81, 0x9e,
+	0xd6, 0xc3, 0x56, 0xb2, 0x41, 0x52, 0x1e, 0xe3, 0x59, 0x01, 0xcd, 0x66, 0x1c, 0x8e, 0x7f, 0xd4,
+	0xd0, 0x3c, 0xcd, 0xc1, 0xab, 0x2e, 0xb0, 0x3d, 0x89, 0xcd, 0x87, 0x18, 0x64, 0x61, 0xd0, 0x6b,
+	0x7e, 0x64, 0x73, 0x04, 0x1e, 0x3b, 0xa8, 0x08, 0x57, 0x83, 0x34, 0x66, 0xc2, 0x39, 0x10, 0x8a,
+	0x2f, 0x85, 0xae, 0x00, 0x74, 0x51, 0x50, 0x84, 0x74, 0x3c, 0x40, 0x3a, 0x55, 0x19, 0x91, 0xf4,
+	0x84, 0xf5, 0x89, 0x0c, 0x56, 0xc2, 0x52, 0xef, 0x27, 0x14, 0xe8, 0x46, 0x43, 0x24, 0xe3, 0x3b,
+	0xb8, 0xd1, 0xf3, 0xed, 0x23, 0x31, 0x57, 0x3b, 0x56, 0x73, 0xe3, 0xa4, 0x2f, 0x1c, 0x31, 0xe9,
+	0x8b, 0xc7, 0x9f, 0xf4, 0x3f, 0x14, 0x90, 0x7e, 0x6d, 0x67, 0xa7, 0xb5, 0x29, 0xef, 0xa0, 0x37,
+	0xd0, 0x0c, 0xdc, 0x16, 0x5d, 0xe5, 0x06, 0xdd, 0x9a, 0x57, 0x67, 0x66, 0x36, 0x25, 0x95, 0xa8,
+	0x5d, 0x51, 0x0f, 0xa1, 0xcd, 0xbb, 0x2a, 0xd1, 0xd3, 0x79, 0x02, 0x68, 0x44, 0xee, 0xe0, 0x03,
+	0x54, 0xe9, 0x52, 0xdb, 0x4d, 0x27, 0x09, 0x32, 0x89, 0x15, 0x43, 0x0d, 0xcd, 0x6b, 0xb1, 0x50,
+	0x48, 0x51, 0x76, 0x60, 0xcd, 0x02, 0x68, 0x45, 0x51, 0x48, 0x82, 0xb7, 0x7c, 0x19, 0xd5, 0xb2,
+	0x5c, 0x78, 0x01, 0x15, 0xf7, 0x68, 0x5c, 0x4d, 0x3a, 0x11, 0x5f, 0xf1, 0x19, 0x54, 0xde, 0xb7,
+	0x7b, 0x03, 0x55, 0xa8, 0x24, 0x5e, 0x5c, 0x2e, 0xac, 0x6a, 0xc6, 0x1f, 0x1a, 0xaa, 0x34, 0x5b,
+	0x56, 0x2f, 0x70, 0xf6, 0x20, 0x21, 0x4a, 0x8e, 0xe7, 0x32, 0x95, 0x11, 0x6b, 0x93, 0xe8, 0xdf,
+	0x6c, 0x6d, 0x51, 0x9e, 0xfa, 0xe9, 0x4a, 0x73, 0x9d, 0x10, 0x29, 0x1c, 0x7b, 0x68, 0x86, 0xde,
+	0x71, 0x68, 0xc8, 0x55, 0x87, 0x9b, 0x02, 0xcc, 0x30, 0x68, 0x1b, 0x52, 0x30, 0x51, 0x00, 0xc6,
+	0x2e, 0x2a, 0x4b, 0x86, 0xa3, 0x75, 0xde, 0x55, 0x54, 0x0b, 0x19, 0xdd, 0xf5, 0xee, 0xdc, 0xa0,
+	0x7e, 0x47, 0x85, 0xba, 0x9c, 0x8e, 0x71, 0xad, 0xcc, 0x1e, 0xc9, 0x71, 0x1a, 0xdf, 0x6b, 0x48,
+	0x1f, 0xa6, 0x9d, 0x4c, 0x15, 0xf8, 0x94, 0x70, 0xe5, 0xec, 0xe8, 0xc9, 0x38, 0x91, 0x3b, 0xc3,
+	0xe6, 0x5a, 0x38, 0xb4, 0xb9, 0xae, 0xa2, 0xaa, 0xfc, 0xd1, 0xc1, 0x09, 0x7a, 0x90, 0x4d, 0x82,
+	0xeb, 0xb5, 0x64, 0xa2, 0x6b, 0x29, 0xfa, 0xd3, 0xcc, 0x77, 0x32, 0xe4, 0x36, 0xbe, 0x2d, 0xa2,
+	0xb9, 0xad, 0xd8, 0x51, 0xad, 0xa0, 0xe7, 0x39, 0x07, 0x27, 0x30, 0x66, 0x31, 0x54, 0x66, 0x83,
+	0x1e, 0x4d, 0xee, 0xac, 0xcd, 0x89, 0xca, 0x37, 0xab, 0x3b, 0x01, 0xa9, 0x69, 0x19, 0x8b, 0x15,
+	0x94, 0xb1, 0x84, 0xc2, 0x1f, 0xa2, 0xd3, 0x76, 0x6e, 0xa6, 0x8c, 0xcb, 0x4e, 0x97, 0xf1, 0x3d,
+	0x9d, 0x1f, 0x37, 0x23, 0x32, 0xca, 0x8b, 0xcf, 0x0b, 0x07, 0x7b, 0x01, 0x13, 0xb7, 0x4e, 0x09,
+	0x9c, 0xa2, 0x59, 0xb5, 0xd8, 0xb9, 0x31, 0x8d, 0x0c, 0x77, 0xf1, 0x25, 0x54, 0xe3, 0x1e, 0x34,
+	0xcc, 0x84, 0xbb, 0x2c, 0xc3, 0xba, 0x20, 0x52, 0x62, 0x27, 0x43, 0x27, 0x39, 0x2e, 0xe3, 0x11,
+	0x0c, 0x5e, 0x39, 0x53, 0x4e, 0x60, 0xb0, 0xf7, 0xf3, 0x83, 0x7d, 0x73, 0x6a, 0x61, 0x38, 0x64,
+	0xae, 0xff, 0x7d, 0xd4, 0xc6, 0x16, 0x85, 0x6b, 0xfd, 0x3d, 0x34, 0x67, 0x67, 0x7e, 0xde, 0x88,
+	0xc0, 0x50, 0x11, 0x96, 0x45, 0x38, 0x3e, 0x97, 0xfd, 0xdd, 0x23, 0x22, 0x79, 0x3e, 0xfc, 0x39,
+	0xaa, 0x7a, 0xa1, 0x6c, 0x44, 0x89, 0x05, 0x57, 0x26, 0x6b, 0x0d, 0x52, 0x56, 0xea, 0x31, 0x45,
+	0x88, 0xc8, 0x10, 0xc6, 0xf8, 0xad, 0x32, 0x62, 0x81, 0x48, 0x31, 0xfc, 0x01, 0xd2, 0x5d, 0x8f,
+	0x41, 0x9a, 0x7b, 0x81, 0xaf, 0xae, 0x85, 0x95, 0xe4, 0x6e, 0x5d, 0x4f, 0x36, 0x9e, 0x66, 0x17,
+	0x24, 0x3d, 0x00, 0xaf, 0x57, 0xa5, 0x5d, 0x16, 0xf4, 0xd5, 0x14, 0x31, 0xbd, 0x5a, 0x10, 0xce,
+	0x4d, 0x7b, 0xc5, 0x47, 0x00, 0x41, 0x24, 0x10, 0x34, 0xd4, 0x02, 0x0f, 0x64, 0x97, 0x98, 0x3a,
+	0x1c, 0x52, 0x70, 0x85, 0x9d, 0x80, 0x00, 0x88, 0x08, 0x51, 0x44, 0xd9, 0xbe, 0xe7, 0xd0, 0xe4,
+	0x25, 0x62, 0xa2, 0x10, 0x6d, 0xc7, 0xb2, 0xd2, 0x10, 0x29, 0x02, 0x84, 0x28, 0x81, 0xc1, 0x6f,
+	0x66, 0x0a, 0x55, 0x95, 0x5e, 0xda, 0x09, 0xc7, 0x8a, 0xf5, 0x16, 0x9a, 0xb1, 0xe3, 0xb8, 0xcd,
+	0xc8, 0xb8, 0x11, 0x71, 0x2b, 0xac, 0x25, 0x01, 0x5b, 0x3f, 0xea, 0x8f, 0xe9, 0x11, 0x75, 0x06,
+	0x42, 0x5e, 0x63, 0xff, 0xa2, 0xdd, 0x0b, 0xbb, 0xa0, 0xaa, 0x48, 0x8c, 0x58, 0x0e, 0x51, 0x08,
+	0xf8, 0x7d, 0x34, 0x47, 0x7d, 0xbb, 0xdd, 0xa3, 0x37, 0x82, 0x4e, 0x07, 0xcc, 0xaa, 0x57, 0x00,
+	0xb2, 0x6a, 0xbd, 0xa4, 0xd4, 0x9b, 0xdb, 0xc8, 0x6e, 0x92, 0x3c, 0x2f, 0xfe, 0x0a, 0xcd, 0x76,
+	0x39, 0x0f, 0xe5, 0x15, 0x0f, 0xce, 0xac, 0x4e, 0x3e, 0xf7, 0x0c, 0x27, 0x86, 0xf4, 0xb5, 0x70,
+	0x48, 0x02, 0x8f, 0x66, 0xe1, 0xa0, 0x61, 0xeb, 0xed, 0xe4, 0x55, 0xa7, 0xae, 0xcb, 0xcc, 0x99,
+	0x08, 0x7b, 0xf8, 0xde, 0x64, 0xcd, 0x89, 0x22, 0x19, 0x2e, 0x49, 0x0a, 0x83, 0xd7, 0xd1, 0x02,
+	0x5c, 0x9a, 0x22, 0xae, 0x74, 0x3b, 0x18, 0x30, 0x87, 0x36, 0x5b, 0x75, 0x24, 0x3d, 0x56, 0x57,
+	0xfa, 0x2e, 0xb4, 0x46, 0xf6, 0xc9, 0xd8, 0x09, 0xc3, 0x46, 0xb5, 0xec, 0xb0, 0x79, 0x1c, 0xef,
+	0x29, 0xf0, 0x5e, 0x52, 0x51, 0x89, 0x08, 0xcd, 0x3f, 0xbd, 0x87, 0x63, 0x88, 0xfa, 0xf3, 0xef,
+	0x60, 0xbc, 0xa5, 0x26, 0x80, 0xc2, 0x73, 0x6e, 0x5b, 0xf1, 0x6f, 0x83, 0x19, 0xff, 0xdb, 0x60,
+	0x36, 0x7d, 0x7e, 0x93, 0x6d, 0x73, 0x06, 0x6e, 0xb5, 0xaa, 0xf9, 0x79, 0xc1, 0xba, 0x70, 0xef,
+	0xd1, 0xca, 0xa9, 0xfb, 0xf0, 0x3c, 0x80, 0xe7, 0xeb, 0xc7, 0x2b, 0xda, 0x3d, 0x78, 0xee, 0xc3,
+	0xf3, 0x00, 0x9e, 0xbf, 0xe0, 0xb9, 0xfb, 0x64, 0xe5, 0xd4, 0xc7, 0x15, 0x15, 0x8d, 0x7f, 0x00,
+	0x77, 0x9c, 0x94, 0xa4, 0x34, 0x1a, 0x00, 0x00,
 }
 
 func (m *AddressGroup) Marshal() (dAtA []byte, err error) {
@@ -1083,6 +1159,35 @@ func (m *AppliedToGroupPatch) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *Bandwidth) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Bandwidth) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Bandwidth) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	i = encodeVarintGenerated(dAtA, i, uint64(m.Burst))
+	i--
+	dAtA[i] = 0x10
+	i = encodeVarintGenerated(dAtA, i, uint64(m.Rate))
+	i--
+	dAtA[i] = 0x8
+	return len(dAtA) - i, nil
+}
+
 func (m *Endpoint) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -1277,6 +1382,63 @@ func (m *GroupMemberPod) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *HTTPMatch) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *HTTPMatch) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *HTTPMatch) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Headers) > 0 {
+		keysForHeaders := make([]string, 0, len(m.Headers))
+		for k := range m.Headers {
+			keysForHeaders = append(keysForHeaders, string(k))
+		}
+		github_com_gogo_protobuf_sortkeys.Strings(keysForHeaders)
+		for iNdEx := len(keysForHeaders) - 1; iNdEx >= 0; iNdEx-- {
+			v := m.Headers[string(keysForHeaders[iNdEx])]
+			baseI := i
+			i -= len(v)
+			copy(dAtA[i:], v)
+			i = encodeVarintGenerated(dAtA, i, uint64(len(v)))
+			i--
+			dAtA[i] = 0x12
+			i -= len(keysForHeaders[iNdEx])
+			copy(dAtA[i:], keysForHeaders[iNdEx])
+			i = encodeVarintGenerated(dAtA, i, uint64(len(keysForHeaders[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+			i = encodeVarintGenerated(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	i -= len(m.Path)
+	copy(dAtA[i:], m.Path)
+	i = encodeVarintGenerated(dAtA, i, uint64(len(m.Path)))
+	i--
+	dAtA[i] = 0x12
+	i -= len(m.Method)
+	copy(dAtA[i:], m.Method)
+	i = encodeVarintGenerated(dAtA, i, uint64(len(m.Method)))
+	i--
+	dAtA[i] = 0xa
+	return len(dAtA) - i, nil
+}
+
 func (m *IPBlock) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -1413,6 +1575,11 @@ func (m *NetworkPolicy) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.TierPriority != nil {
+		i = encodeVarintGenerated(dAtA, i, uint64(*m.TierPriority))
+		i--
+		dAtA[i] = 0x28
+	}
 	if m.Priority != nil {
 		i -= 8
 		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(*m.Priority))))
@@ -1568,6 +1735,48 @@ func (m *NetworkPolicyRule) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	i--
+	if m.PreserveSourceIP {
+		dAtA[i] = 1
+	} else {
+		dAtA[i] = 0
+	}
+	i--
+	dAtA[i] = 0x50
+	if m.Bandwidth != nil {
+		{
+			size, err := m.Bandwidth.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintGenerated(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x4a
+	}
+	if len(m.HTTPMatches) > 0 {
+		for iNdEx := len(m.HTTPMatches) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.HTTPMatches[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintGenerated(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x42
+		}
+	}
+	i--
+	if m.EnableLogging {
+		dAtA[i] = 1
+	} else {
+		dAtA[i] = 0
+	}
+	i--
+	dAtA[i] = 0x38
 	if m.Action != nil {
 		i -= len(*m.Action)
 		copy(dAtA[i:], *m.Action)
@@ -1856,6 +2065,17 @@ func (m *AppliedToGroupPatch) Size() (n int) {
 	return n
 }
 
+func (m *Bandwidth) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	n += 1 + sovGenerated(uint64(m.Rate))
+	n += 1 + sovGenerated(uint64(m.Burst))
+	return n
+}
+
 func (m *Endpoint) Size() (n int) {
 	if m == nil {
 		return 0
@@ -1934,6 +2154,27 @@ func (m *GroupMemberPod) Size() (n int) {
 	return n
 }
 
+func (m *HTTPMatch) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Method)
+	n += 1 + l + sovGenerated(uint64(l))
+	l = len(m.Path)
+	n += 1 + l + sovGenerated(uint64(l))
+	if len(m.Headers) > 0 {
+		for k, v := range m.Headers {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + len(k) + sovGenerated(uint64(len(k))) + 1 + len(v) + sovGenerated(uint64(len(v)))
+			n += mapEntrySize + 1 + sovGenerated(uint64(mapEntrySize))
+		}
+	}
+	return n
+}
+
 func (m *IPBlock) Size() (n int) {
 	if m == nil {
 		return 0
@@ -2002,6 +2243,9 @@ func (m *NetworkPolicy) Size() (n int) {
 	if m.Priority != nil {
 		n += 9
 	}
+	if m.TierPriority != nil {
+		n += 1 + sovGenerated(uint64(*m.TierPriority))
+	}
 	return n
 }
 
@@ -2066,6 +2310,18 @@ func (m *NetworkPolicyRule) Size() (n int) {
 		l = len(*m.Action)
 		n += 1 + l + sovGenerated(uint64(l))
 	}
+	n += 2
+	if len(m.HTTPMatches) > 0 {
+		for _, e := range m.HTTPMatches {
+			l = e.Size()
+			n += 1 + l + sovGenerated(uint64(l))
+		}
+	}
+	if m.Bandwidth != nil {
+		l = m.Bandwidth.Size()
+		n += 1 + l + sovGenerated(uint64(l))
+	}
+	n += 2
 	return n
 }
 
@@ -2249,6 +2505,17 @@ func (this *AppliedToGroupPatch) String() string {
 	}, "")
 	return s
 }
+func (this *Bandwidth) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Bandwidth{`,
+		`Rate:` + fmt.Sprintf("%v", this.Rate) + `,`,
+		`Burst:` + fmt.Sprintf("%v", this.Burst) + `,`,
+		`}`,
+	}, "")
+	return s
+}
 func (this *Endpoint) String() string {
 	if this == nil {
 		return "nil"
@@ -2310,6 +2577,28 @@ func (this *GroupMemberPod) String() string {
 	}, "")
 	return s
 }
+func (this *HTTPMatch) String() string {
+	if this == nil {
+		return "nil"
+	}
+	keysForHeaders := make([]string, 0, len(this.Headers))
+	for k := range this.Headers {
+		keysForHeaders = append(keysForHeaders, k)
+	}
+	github_com_gogo_protobuf_sortkeys.Strings(keysForHeaders)
+	mapStringForHeaders := "map[string]string{"
+	for _, k := range keysForHeaders {
+		mapStringForHeaders += fmt.Sprintf("%v: %v,", k, this.Headers[k])
+	}
+	mapStringForHeaders += "}"
+	s := strings.Join([]string{`&HTTPMatch{`,
+		`Method:` + fmt.Sprintf("%v", this.Method) + `,`,
+		`Path:` + fmt.Sprintf("%v", this.Path) + `,`,
+		`Headers:` + mapStringForHeaders + `,`,
+		`}`,
+	}, "")
+	return s
+}
 func (this *IPBlock) String() string {
 	if this == nil {
 		return "nil"
@@ -2363,6 +2652,7 @@ func (this *NetworkPolicy) String() string {
 		`Rules:` + repeatedStringForRules + `,`,
 		`AppliedToGroups:` + fmt.Sprintf("%v", this.AppliedToGroups) + `,`,
 		`Priority:` + valueToStringGenerated(this.Priority) + `,`,
+		`TierPriority:` + valueToStringGenerated(this.TierPriority) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -2408,6 +2698,11 @@ func (this *NetworkPolicyRule) String() string {
 		repeatedStringForServices += strings.Replace(strings.Replace(f.String(), "Service", "Service", 1), `&`, ``, 1) + ","
 	}
 	repeatedStringForServices += "}"
+	repeatedStringForHTTPMatches := "[]HTTPMatch{"
+	for _, f := range this.HTTPMatches {
+		repeatedStringForHTTPMatches += strings.Replace(strings.Replace(f.String(), "HTTPMatch", "HTTPMatch", 1), `&`, ``, 1) + ","
+	}
+	repeatedStringForHTTPMatches += "}"
 	s := strings.Join([]string{`&NetworkPolicyRule{`,
 		`Direction:` + fmt.Sprintf("%v", this.Direction) + `,`,
 		`From:` + strings.Replace(strings.Replace(this.From.String(), "NetworkPolicyPeer", "NetworkPolicyPeer", 1), `&`, ``, 1) + `,`,
@@ -2415,6 +2710,10 @@ func (this *NetworkPolicyRule) String() string {
 		`Services:` + repeatedStringForServices + `,`,
 		`Priority:` + fmt.Sprintf("%v", this.Priority) + `,`,
 		`Action:` + valueToStringGenerated(this.Action) + `,`,
+		`EnableLogging:` + fmt.Sprintf("%v", this.EnableLogging) + `,`,
+		`HTTPMatches:` + repeatedStringForHTTPMatches + `,`,
+		`Bandwidth:` + strings.Replace(this.Bandwidth.String(), "Bandwidth", "Bandwidth", 1) + `,`,
+		`PreserveSourceIP:` + fmt.Sprintf("%v", this.PreserveSourceIP) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -3441,7 +3740,7 @@ func (m *AppliedToGroupPatch) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Endpoint) Unmarshal(dAtA []byte) error {
+func (m *Bandwidth) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -3464,17 +3763,17 @@ func (m *Endpoint) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Endpoint: wiretype end group for non-group")
+			return fmt.Errorf("proto: Bandwidth: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Endpoint: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Bandwidth: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field IP", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Rate", wireType)
 			}
-			var byteLen int
+			m.Rate = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowGenerated
@@ -3484,31 +3783,16 @@ func (m *Endpoint) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= int(b&0x7F) << shift
+				m.Rate |= int64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
-				return ErrInvalidLengthGenerated
-			}
-			postIndex := iNdEx + byteLen
-			if postIndex < 0 {
-				return ErrInvalidLengthGenerated
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.IP = append(m.IP[:0], dAtA[iNdEx:postIndex]...)
-			if m.IP == nil {
-				m.IP = []byte{}
-			}
-			iNdEx = postIndex
 		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Ports", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Burst", wireType)
 			}
-			var msglen int
+			m.Burst = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowGenerated
@@ -3518,26 +3802,11 @@ func (m *Endpoint) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				m.Burst |= int64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthGenerated
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthGenerated
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Ports = append(m.Ports, NamedPort{})
-			if err := m.Ports[len(m.Ports)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipGenerated(dAtA[iNdEx:])
@@ -3562,7 +3831,7 @@ func (m *Endpoint) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *ExternalEntityReference) Unmarshal(dAtA []byte) error {
+func (m *Endpoint) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -3585,17 +3854,17 @@ func (m *ExternalEntityReference) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: ExternalEntityReference: wiretype end group for non-group")
+			return fmt.Errorf("proto: Endpoint: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: ExternalEntityReference: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Endpoint: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field IP", wireType)
 			}
-			var stringLen uint64
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowGenerated
@@ -3605,29 +3874,31 @@ func (m *ExternalEntityReference) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthGenerated
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + byteLen
 			if postIndex < 0 {
 				return ErrInvalidLengthGenerated
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Name = string(dAtA[iNdEx:postIndex])
+			m.IP = append(m.IP[:0], dAtA[iNdEx:postIndex]...)
+			if m.IP == nil {
+				m.IP = []byte{}
+			}
 			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Namespace", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Ports", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowGenerated
@@ -3637,7 +3908,126 @@ func (m *ExternalEntityReference) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Ports = append(m.Ports, NamedPort{})
+			if err := m.Ports[len(m.Ports)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipGenerated(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ExternalEntityReference) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowGenerated
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ExternalEntityReference: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ExternalEntityReference: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Namespace", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3995,6 +4385,250 @@ func (m *GroupMemberPod) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *HTTPMatch) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowGenerated
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: HTTPMatch: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: HTTPMatch: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Method", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Method = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Path", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Path = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Headers", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Headers == nil {
+				m.Headers = make(map[string]string)
+			}
+			var mapkey string
+			var mapvalue string
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowGenerated
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowGenerated
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var stringLenmapvalue uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowGenerated
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapvalue := int(stringLenmapvalue)
+					if intStringLenmapvalue < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					postStringIndexmapvalue := iNdEx + intStringLenmapvalue
+					if postStringIndexmapvalue < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					if postStringIndexmapvalue > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = string(dAtA[iNdEx:postStringIndexmapvalue])
+					iNdEx = postStringIndexmapvalue
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipGenerated(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.Headers[mapkey] = mapvalue
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipGenerated(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func (m *IPBlock) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
@@ -4497,6 +5131,26 @@ func (m *NetworkPolicy) Unmarshal(dAtA []byte) error {
 			iNdEx += 8
 			v2 := float64(math.Float64frombits(v))
 			m.Priority = &v2
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TierPriority", wireType)
+			}
+			var v int32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.TierPriority = &v
 		default:
 			iNdEx = preIndex
 			skippy, err := skipGenerated(dAtA[iNdEx:])
@@ -4973,6 +5627,116 @@ func (m *NetworkPolicyRule) Unmarshal(dAtA []byte) error {
 			s := github_com_vmware_tanzu_antrea_pkg_apis_security_v1alpha1.RuleAction(dAtA[iNdEx:postIndex])
 			m.Action = &s
 			iNdEx = postIndex
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EnableLogging", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.EnableLogging = bool(v != 0)
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field HTTPMatches", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.HTTPMatches = append(m.HTTPMatches, HTTPMatch{})
+			if err := m.HTTPMatches[len(m.HTTPMatches)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Bandwidth", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Bandwidth == nil {
+				m.Bandwidth = &Bandwidth{}
+			}
+			if err := m.Bandwidth.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PreserveSourceIP", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.PreserveSourceIP = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipGenerated(dAtA[iNdEx:])
@@ -165,8 +165,20 @@ type NetworkPolicy struct {
 	// Priority represents the relative priority of this Network Policy as compared to
 	// other Network Policies. Priority will be unset (nil) for K8s Network Policy.
 	Priority *float64 `json:"priority,omitempty" protobuf:"fixed64,4,opt,name=priority"`
+	// TierPriority represents the priority of the tier of this Network Policy. Policies
+	// of tiers with a lower TierPriority are enforced first. TierPriority will be unset
+	// (nil) for K8s Network Policy.
+	TierPriority *int32 `json:"tierPriority,omitempty" protobuf:"varint,5,opt,name=tierPriority"`
 }
 
+const (
+	// DefaultTierPriority is the TierPriority of the Network Policies of the default tier.
+	DefaultTierPriority int32 = 250
+	// BaselineTierPriority is the TierPriority of the Network Policies of the baseline
+	// tier, which are enforced after all the other Antrea Network Policies.
+	BaselineTierPriority int32 = 253
+)
+
 // Direction defines traffic direction of NetworkPolicyRule.
 type Direction string
 
@@ -194,6 +206,19 @@ type NetworkPolicyRule struct {
 	// action “nil” defaults to Allow action, which would be the case for rules created for
 	// K8s Network Policy.
 	Action *secv1alpha1.RuleAction `json:"action,omitempty" protobuf:"bytes,6,opt,name=action,casttype=github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1.RuleAction"`
+	// EnableLogging indicates whether or not to generate logs when rules are matched.
+	EnableLogging bool `json:"enableLogging,omitempty" protobuf:"varint,7,opt,name=enableLogging"`
+	// HTTPMatches restricts the rule to the HTTP requests matching any of the
+	// conditions, each request of a TCP connection being evaluated. Empty for
+	// K8s NetworkPolicy.
+	HTTPMatches []HTTPMatch `json:"httpMatches,omitempty" protobuf:"bytes,8,rep,name=httpMatches"`
+	// Bandwidth limits the bandwidth of each Pod to which the rule is applied,
+	// in the direction of the rule. Nil for K8s NetworkPolicy.
+	Bandwidth *Bandwidth `json:"bandwidth,omitempty" protobuf:"bytes,9,opt,name=bandwidth"`
+	// PreserveSourceIP indicates that the traffic matched by an egress rule
+	// must not be SNATed when it leaves the Node, so that the destination sees
+	// the IP of the source Pod. Always false for K8s NetworkPolicy.
+	PreserveSourceIP bool `json:"preserveSourceIP,omitempty" protobuf:"varint,10,opt,name=preserveSourceIP"`
 }
 
 // Protocol defines network protocols supported for things like container ports.
@@ -219,6 +244,28 @@ type Service struct {
 	Port *intstr.IntOrString `json:"port,omitempty" protobuf:"bytes,2,opt,name=port"`
 }
 
+// Bandwidth describes the bandwidth limit of a rule.
+type Bandwidth struct {
+	// Rate is the maximum rate in bits per second.
+	Rate int64 `json:"rate" protobuf:"varint,1,opt,name=rate"`
+	// Burst is the maximum burst size in bits.
+	Burst int64 `json:"burst" protobuf:"varint,2,opt,name=burst"`
+}
+
+// HTTPMatch describes the HTTP requests matched by a rule.
+type HTTPMatch struct {
+	// Method is the method of the request. If it is empty or "*", all methods
+	// are matched.
+	Method string `json:"method,omitempty" protobuf:"bytes,1,opt,name=method"`
+	// Path is the pattern matched against the path of the request, in which
+	// "*" matches any sequence of characters. If it is empty, all paths are
+	// matched.
+	Path string `json:"path,omitempty" protobuf:"bytes,2,opt,name=path"`
+	// Headers are the headers which the request must have, keyed by header
+	// name. Values are patterns matched in the same way as Path.
+	Headers map[string]string `json:"headers,omitempty" protobuf:"bytes,3,rep,name=headers"`
+}
+
 // NetworkPolicyPeer describes a peer of NetworkPolicyRules.
 // It could be a list of names of AddressGroups and/or a list of IPBlock.
 type NetworkPolicyPeer struct {
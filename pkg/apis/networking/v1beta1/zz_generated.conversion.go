@@ -95,6 +95,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*Bandwidth)(nil), (*networking.Bandwidth)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_Bandwidth_To_networking_Bandwidth(a.(*Bandwidth), b.(*networking.Bandwidth), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*networking.Bandwidth)(nil), (*Bandwidth)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_networking_Bandwidth_To_v1beta1_Bandwidth(a.(*networking.Bandwidth), b.(*Bandwidth), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*Endpoint)(nil), (*networking.Endpoint)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta1_Endpoint_To_networking_Endpoint(a.(*Endpoint), b.(*networking.Endpoint), scope)
 	}); err != nil {
@@ -135,6 +145,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*HTTPMatch)(nil), (*networking.HTTPMatch)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_HTTPMatch_To_networking_HTTPMatch(a.(*HTTPMatch), b.(*networking.HTTPMatch), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*networking.HTTPMatch)(nil), (*HTTPMatch)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_networking_HTTPMatch_To_v1beta1_HTTPMatch(a.(*networking.HTTPMatch), b.(*HTTPMatch), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*IPBlock)(nil), (*networking.IPBlock)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta1_IPBlock_To_networking_IPBlock(a.(*IPBlock), b.(*networking.IPBlock), scope)
 	}); err != nil {
@@ -376,6 +396,28 @@ func Convert_networking_AppliedToGroupPatch_To_v1beta1_AppliedToGroupPatch(in *n
 	return autoConvert_networking_AppliedToGroupPatch_To_v1beta1_AppliedToGroupPatch(in, out, s)
 }
 
+func autoConvert_v1beta1_Bandwidth_To_networking_Bandwidth(in *Bandwidth, out *networking.Bandwidth, s conversion.Scope) error {
+	out.Rate = in.Rate
+	out.Burst = in.Burst
+	return nil
+}
+
+// Convert_v1beta1_Bandwidth_To_networking_Bandwidth is an autogenerated conversion function.
+func Convert_v1beta1_Bandwidth_To_networking_Bandwidth(in *Bandwidth, out *networking.Bandwidth, s conversion.Scope) error {
+	return autoConvert_v1beta1_Bandwidth_To_networking_Bandwidth(in, out, s)
+}
+
+func autoConvert_networking_Bandwidth_To_v1beta1_Bandwidth(in *networking.Bandwidth, out *Bandwidth, s conversion.Scope) error {
+	out.Rate = in.Rate
+	out.Burst = in.Burst
+	return nil
+}
+
+// Convert_networking_Bandwidth_To_v1beta1_Bandwidth is an autogenerated conversion function.
+func Convert_networking_Bandwidth_To_v1beta1_Bandwidth(in *networking.Bandwidth, out *Bandwidth, s conversion.Scope) error {
+	return autoConvert_networking_Bandwidth_To_v1beta1_Bandwidth(in, out, s)
+}
+
 func autoConvert_v1beta1_Endpoint_To_networking_Endpoint(in *Endpoint, out *networking.Endpoint, s conversion.Scope) error {
 	out.IP = *(*networking.IPAddress)(unsafe.Pointer(&in.IP))
 	out.Ports = *(*[]networking.NamedPort)(unsafe.Pointer(&in.Ports))
@@ -468,6 +510,30 @@ func Convert_networking_GroupMemberPod_To_v1beta1_GroupMemberPod(in *networking.
 	return autoConvert_networking_GroupMemberPod_To_v1beta1_GroupMemberPod(in, out, s)
 }
 
+func autoConvert_v1beta1_HTTPMatch_To_networking_HTTPMatch(in *HTTPMatch, out *networking.HTTPMatch, s conversion.Scope) error {
+	out.Method = in.Method
+	out.Path = in.Path
+	out.Headers = *(*map[string]string)(unsafe.Pointer(&in.Headers))
+	return nil
+}
+
+// Convert_v1beta1_HTTPMatch_To_networking_HTTPMatch is an autogenerated conversion function.
+func Convert_v1beta1_HTTPMatch_To_networking_HTTPMatch(in *HTTPMatch, out *networking.HTTPMatch, s conversion.Scope) error {
+	return autoConvert_v1beta1_HTTPMatch_To_networking_HTTPMatch(in, out, s)
+}
+
+func autoConvert_networking_HTTPMatch_To_v1beta1_HTTPMatch(in *networking.HTTPMatch, out *HTTPMatch, s conversion.Scope) error {
+	out.Method = in.Method
+	out.Path = in.Path
+	out.Headers = *(*map[string]string)(unsafe.Pointer(&in.Headers))
+	return nil
+}
+
+// Convert_networking_HTTPMatch_To_v1beta1_HTTPMatch is an autogenerated conversion function.
+func Convert_networking_HTTPMatch_To_v1beta1_HTTPMatch(in *networking.HTTPMatch, out *HTTPMatch, s conversion.Scope) error {
+	return autoConvert_networking_HTTPMatch_To_v1beta1_HTTPMatch(in, out, s)
+}
+
 func autoConvert_v1beta1_IPBlock_To_networking_IPBlock(in *IPBlock, out *networking.IPBlock, s conversion.Scope) error {
 	if err := Convert_v1beta1_IPNet_To_networking_IPNet(&in.CIDR, &out.CIDR, s); err != nil {
 		return err
@@ -545,6 +611,7 @@ func autoConvert_v1beta1_NetworkPolicy_To_networking_NetworkPolicy(in *NetworkPo
 	out.Rules = *(*[]networking.NetworkPolicyRule)(unsafe.Pointer(&in.Rules))
 	out.AppliedToGroups = *(*[]string)(unsafe.Pointer(&in.AppliedToGroups))
 	out.Priority = (*float64)(unsafe.Pointer(in.Priority))
+	out.TierPriority = (*int32)(unsafe.Pointer(in.TierPriority))
 	return nil
 }
 
@@ -558,6 +625,7 @@ func autoConvert_networking_NetworkPolicy_To_v1beta1_NetworkPolicy(in *networkin
 	out.Rules = *(*[]NetworkPolicyRule)(unsafe.Pointer(&in.Rules))
 	out.AppliedToGroups = *(*[]string)(unsafe.Pointer(&in.AppliedToGroups))
 	out.Priority = (*float64)(unsafe.Pointer(in.Priority))
+	out.TierPriority = (*int32)(unsafe.Pointer(in.TierPriority))
 	return nil
 }
 
@@ -621,6 +689,10 @@ func autoConvert_v1beta1_NetworkPolicyRule_To_networking_NetworkPolicyRule(in *N
 	out.Services = *(*[]networking.Service)(unsafe.Pointer(&in.Services))
 	out.Priority = in.Priority
 	out.Action = (*v1alpha1.RuleAction)(unsafe.Pointer(in.Action))
+	out.EnableLogging = in.EnableLogging
+	out.HTTPMatches = *(*[]networking.HTTPMatch)(unsafe.Pointer(&in.HTTPMatches))
+	out.Bandwidth = (*networking.Bandwidth)(unsafe.Pointer(in.Bandwidth))
+	out.PreserveSourceIP = in.PreserveSourceIP
 	return nil
 }
 
@@ -640,6 +712,10 @@ func autoConvert_networking_NetworkPolicyRule_To_v1beta1_NetworkPolicyRule(in *n
 	out.Services = *(*[]Service)(unsafe.Pointer(&in.Services))
 	out.Priority = in.Priority
 	out.Action = (*v1alpha1.RuleAction)(unsafe.Pointer(in.Action))
+	out.EnableLogging = in.EnableLogging
+	out.HTTPMatches = *(*[]HTTPMatch)(unsafe.Pointer(&in.HTTPMatches))
+	out.Bandwidth = (*Bandwidth)(unsafe.Pointer(in.Bandwidth))
+	out.PreserveSourceIP = in.PreserveSourceIP
 	return nil
 }
 
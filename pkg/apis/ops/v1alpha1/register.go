@@ -44,6 +44,12 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		SchemeGroupVersion,
 		&Traceflow{},
 		&TraceflowList{},
+		&TraceflowHistory{},
+		&TraceflowHistoryList{},
+		&IPSecTunnelStatus{},
+		&IPSecTunnelStatusList{},
+		&ClusterIPSecStatus{},
+		&ClusterIPSecStatusList{},
 	)
 
 	metav1.AddToGroupVersion(
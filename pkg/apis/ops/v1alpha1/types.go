@@ -46,6 +46,19 @@ const (
 	Dropped   TraceflowAction = "Dropped"
 )
 
+const (
+	// ReasonNoEndpoints indicates the traced packet is dropped by the LB component, as the destination Service has
+	// no Endpoints.
+	ReasonNoEndpoints = "no_endpoints"
+)
+
+const (
+	// DefaultLivePacketCount is the default number of packets captured by a live Traceflow.
+	DefaultLivePacketCount int32 = 1
+	// DefaultLiveTimeout is the default timeout in seconds of a live Traceflow.
+	DefaultLiveTimeout int32 = 60
+)
+
 // +genclient
 // +genclient:nonNamespaced
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -62,6 +75,13 @@ type TraceflowSpec struct {
 	Source      Source      `json:"source,omitempty"`
 	Destination Destination `json:"destination,omitempty"`
 	Packet      Packet      `json:"packet,omitempty"`
+	// Live indicates to trace the live traffic matching the source, destination and packet headers, instead of
+	// injecting a synthetic packet. Only the packets sent after the capture starts are traced.
+	Live bool `json:"live,omitempty"`
+	// PacketCount is the number of live packets to capture. Defaults to DefaultLivePacketCount.
+	PacketCount int32 `json:"packetCount,omitempty"`
+	// Timeout is the number of seconds after which the live traffic capture stops. Defaults to DefaultLiveTimeout.
+	Timeout int32 `json:"timeout,omitempty"`
 }
 
 // Source describes the source spec of the traceflow.
@@ -76,9 +96,11 @@ type Source struct {
 type Destination struct {
 	// Namespace is the destination namespace.
 	Namespace string `json:"namespace,omitempty"`
-	// Pod is the destination pod, exclusive with destination service.
+	// Pod is the destination pod, exclusive with destination service and IP.
 	Pod string `json:"pod,omitempty"`
-	// Service is the destination service, exclusive with destination pod.
+	// Service is the destination service, exclusive with destination pod and IP. The traced packet is sent to the
+	// ClusterIP of the Service and the destination port in the transport header, which can be omitted if the Service
+	// has a single port for the protocol.
 	Service string `json:"service,omitempty"`
 	// IP is the destination IP.
 	IP string `json:"IP,omitempty"`
@@ -180,6 +202,8 @@ type Observation struct {
 	TranslatedDstIP string `json:"translatedDstIP,omitempty"`
 	// TunnelDstIP is the tunnel destination IP.
 	TunnelDstIP string `json:"tunnelDstIP,omitempty"`
+	// Reason is the reason why the packet is dropped, e.g. ReasonNoEndpoints.
+	Reason string `json:"reason,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -189,3 +213,115 @@ type TraceflowList struct {
 
 	Items []Traceflow `json:"items"`
 }
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// TraceflowHistory is the record of a completed Traceflow. The Antrea Controller keeps the records of the most
+// recently completed Traceflows, so that they can be retrieved after the Traceflows are deleted.
+type TraceflowHistory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// TraceflowName is the name of the Traceflow.
+	TraceflowName string `json:"traceflowName,omitempty"`
+	// Creator is the field manager which created the Traceflow, e.g. "kubectl" or "antrea-octant-plugin". The
+	// requesting user is not known to the Antrea Controller, and can only be found in the audit log of the K8s
+	// apiserver.
+	Creator string `json:"creator,omitempty"`
+	// StartTime is the creation time of the Traceflow.
+	StartTime metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time at which the Traceflow completed.
+	CompletionTime metav1.Time `json:"completionTime,omitempty"`
+
+	Spec   TraceflowSpec   `json:"spec,omitempty"`
+	Status TraceflowStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type TraceflowHistoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []TraceflowHistory `json:"items"`
+}
+
+type IPSecTunnelState string
+
+const (
+	// IPSecTunnelEstablished means that the Security Associations of both directions of the tunnel are installed.
+	IPSecTunnelEstablished IPSecTunnelState = "established"
+	// IPSecTunnelInitializing means that the Security Associations of the tunnel are being negotiated.
+	IPSecTunnelInitializing IPSecTunnelState = "initializing"
+	// IPSecTunnelFailed means that the Security Associations of the tunnel could not be negotiated in time.
+	IPSecTunnelFailed IPSecTunnelState = "failed"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// IPSecTunnelStatus is the status of the IPSec tunnel from a Node to another Node, as observed by the Antrea Agent of
+// the local Node from the IPSec Security Associations (SAs) and policies of the kernel. It is named
+// "<localNode>.<remoteNode>".
+type IPSecTunnelStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// LocalNode is the Node which reports the status.
+	LocalNode string `json:"localNode"`
+	// RemoteNode is the other end of the tunnel.
+	RemoteNode string `json:"remoteNode"`
+	// State is the state of the tunnel.
+	State IPSecTunnelState `json:"state"`
+	// BytesIn is the number of bytes received through the current inbound SAs.
+	BytesIn int64 `json:"bytesIn"`
+	// BytesOut is the number of bytes sent through the current outbound SAs.
+	BytesOut int64 `json:"bytesOut"`
+	// RekeysTotal is the number of times the SAs have been renegotiated since the Antrea Agent started.
+	RekeysTotal int64 `json:"rekeysTotal"`
+	// LastRekeyTimestamp is the time at which the current SAs were installed.
+	LastRekeyTimestamp metav1.Time `json:"lastRekeyTimestamp,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type IPSecTunnelStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []IPSecTunnelStatus `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterIPSecStatus aggregates the IPSecTunnelStatuses reported by all the Nodes. The Antrea Controller maintains a
+// single ClusterIPSecStatus named "cluster".
+type ClusterIPSecStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Tunnels is the number of reported tunnels.
+	Tunnels int32 `json:"tunnels"`
+	// EstablishedTunnels is the number of tunnels in the established state.
+	EstablishedTunnels int32 `json:"establishedTunnels"`
+	// InitializingTunnels is the number of tunnels in the initializing state.
+	InitializingTunnels int32 `json:"initializingTunnels"`
+	// FailedTunnels is the number of tunnels in the failed state.
+	FailedTunnels int32 `json:"failedTunnels"`
+	// FailedTunnelNames are the names of the IPSecTunnelStatuses in the failed state.
+	FailedTunnelNames []string `json:"failedTunnelNames,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ClusterIPSecStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterIPSecStatus `json:"items"`
+}
@@ -16,6 +16,7 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -71,6 +72,67 @@ type Rule struct {
 	// destinations.
 	// +optional
 	To []NetworkPolicyPeer `json:"to"`
+	// HTTPMatches restricts the rule to the HTTP requests matching any of the
+	// conditions, each request of a TCP connection being evaluated. If a rule
+	// with Allow action matches the connection but not one of its requests,
+	// the connection is dropped from that request. If a rule with Drop action
+	// matches the connection and one of its requests, the connection is
+	// dropped from that request. If this field is empty, the rule applies to
+	// all the traffic it matches.
+	// +optional
+	HTTPMatches []HTTPMatch `json:"httpMatches,omitempty"`
+	// Bandwidth limits the bandwidth of each Pod selected by AppliedTo in
+	// the direction of the rule: the traffic received by the Pods for an
+	// ingress rule, and the traffic sent by the Pods for an egress rule. The
+	// limit applies to all the traffic of the Pods in that direction, not
+	// only to the traffic matched by the rule. If multiple rules limit the
+	// bandwidth of a Pod in the same direction, the lowest rate applies.
+	// +optional
+	Bandwidth *Bandwidth `json:"bandwidth,omitempty"`
+	// PreserveSourceIP disables the SNAT of the traffic matched by an egress
+	// rule with Allow action when it leaves the Node, e.g. the masquerading of
+	// the traffic sent to other Nodes or to CIDRs outside of the cluster, so
+	// that the destination sees the IP of the source Pod. The CIDRs which must
+	// be excluded from SNAT are selected with the ipBlocks of the rule. The
+	// destinations must be able to route the replies back to the Pod IPs.
+	// Only supported in egress rules.
+	// +optional
+	PreserveSourceIP bool `json:"preserveSourceIP,omitempty"`
+	// EnableLogging is used to indicate if agent should generate logs
+	// when rules are matched. Should be default to false.
+	// +optional
+	EnableLogging bool `json:"enableLogging"`
+}
+
+// HTTPMatch describes the HTTP requests matched by a rule. A request matches
+// if all the fields which are set match.
+type HTTPMatch struct {
+	// Method is the method of the request, e.g. GET. It is matched
+	// case-insensitively. If it is empty or "*", all methods are matched.
+	// +optional
+	Method string `json:"method,omitempty"`
+	// Path is the pattern matched against the path of the request, in which
+	// "*" matches any sequence of characters, e.g. "/api/*" matches all the
+	// paths starting with "/api/". A pattern without "*" is matched exactly.
+	// If it is empty, all paths are matched.
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Headers are the headers which the request must have, keyed by header
+	// name. Names are matched case-insensitively, and values are patterns
+	// matched in the same way as Path.
+	// +optional
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Bandwidth describes a bandwidth limit, in standard Kubernetes quantity
+// format, e.g. "100M" for 100 Mbps.
+type Bandwidth struct {
+	// Rate is the maximum rate in bits per second.
+	Rate resource.Quantity `json:"rate"`
+	// Burst is the maximum burst size in bits. If it is unset, the burst
+	// size is a tenth of Rate.
+	// +optional
+	Burst *resource.Quantity `json:"burst,omitempty"`
 }
 
 // NetworkPolicyPeer describes the grouping selector of workloads.
@@ -99,6 +161,22 @@ type NetworkPolicyPeer struct {
 	// NamespaceSelector.
 	// Cannot be set with any other selector except NamespaceSelector.
 	ExternalEntitySelector *metav1.LabelSelector `json:"externalEntitySelector,omitempty"`
+	// FQDN is a fully qualified domain name, e.g. "www.example.com", whose IPs
+	// are matched in the To field of egress rules. The FQDN is resolved
+	// periodically by the Antrea Controller. Wildcard FQDNs are not resolved
+	// and do not match any IP.
+	// Cannot be set with any other field, and cannot be set in AppliedTo or
+	// From fields.
+	// +optional
+	FQDN string `json:"fqdn,omitempty"`
+	// Select Nodes matched by this selector, as workloads in From fields.
+	// The IPs of the matched Nodes, including the gateway IP of their Pod
+	// subnet, are matched so that traffic sent by the Nodes themselves to
+	// the Pods can be filtered.
+	// Cannot be set with any other field, and cannot be set in AppliedTo or
+	// To fields.
+	// +optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
 }
 
 // IPBlock describes a particular CIDR (Ex. "192.168.1.1/24") that is allowed
@@ -162,6 +240,14 @@ type ClusterNetworkPolicySpec struct {
 	// Priority specfies the order of the ClusterNetworkPolicy relative to
 	// other ClusterNetworkPolicies.
 	Priority float64 `json:"priority"`
+	// Tier specifies the tier of the ClusterNetworkPolicy. It is empty for the
+	// default tier, whose policies are enforced before K8s NetworkPolicies.
+	// Policies of the baseline tier are enforced after all the policies of
+	// the default tier: their Drop rules cannot be overridden by K8s
+	// NetworkPolicies, but the traffic allowed by their Allow rules is still
+	// subject to K8s NetworkPolicies.
+	// +optional
+	Tier string `json:"tier,omitempty"`
 	// Select workloads on which the rules will be applied to.
 	AppliedTo []NetworkPolicyPeer `json:"appliedTo"`
 	// Set of ingress rules evaluated based on the order in which they are set.
@@ -176,6 +262,12 @@ type ClusterNetworkPolicySpec struct {
 	Egress []Rule `json:"egress"`
 }
 
+const (
+	// TierBaseline is the tier of the ClusterNetworkPolicies which are enforced
+	// after all the other ClusterNetworkPolicies.
+	TierBaseline = "baseline"
+)
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 type ClusterNetworkPolicyList struct {
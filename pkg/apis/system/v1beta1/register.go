@@ -33,6 +33,11 @@ var (
 		Group:    SchemeGroupVersion.Group,
 		Version:  SchemeGroupVersion.Version,
 		Resource: "controllerinfos"}
+
+	NetworkPolicyStatsVersionResource = schema.GroupVersionResource{
+		Group:    SchemeGroupVersion.Group,
+		Version:  SchemeGroupVersion.Version,
+		Resource: "networkpolicystats"}
 )
 
 var (
@@ -55,6 +60,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&clusterinfo.AntreaControllerInfo{},
 		&clusterinfo.AntreaControllerInfoList{},
 		&SupportBundle{},
+		&NetworkPolicyStats{},
+		&NetworkPolicyStatsList{},
 	)
 
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
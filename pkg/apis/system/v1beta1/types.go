@@ -14,7 +14,11 @@
 
 package v1beta1
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterinfo "github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1"
+)
 
 type BundleStatus string
 
@@ -37,3 +41,27 @@ type SupportBundle struct {
 	Size     uint32       `json:"size,omitempty"`
 	Filepath string       `json:"-"`
 }
+
+// NetworkPolicyStats is the traffic statistics of a NetworkPolicy summed across
+// all the Nodes it is enforced on. Its name is the UID of the NetworkPolicy.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type NetworkPolicyStats struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	PolicyName      string                   `json:"policyName"`
+	PolicyNamespace string                   `json:"policyNamespace,omitempty"`
+	Ingress         clusterinfo.TrafficStats `json:"ingress"`
+	Egress          clusterinfo.TrafficStats `json:"egress"`
+	// RuleHitCount is the number of packets which hit the allow or deny
+	// action flows of the rules of the NetworkPolicy in both directions.
+	RuleHitCount int64 `json:"ruleHitCount"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type NetworkPolicyStatsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []NetworkPolicyStats `json:"items"`
+}
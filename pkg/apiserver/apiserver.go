@@ -29,13 +29,19 @@ import (
 	systeminstall "github.com/vmware-tanzu/antrea/pkg/apis/system/install"
 	system "github.com/vmware-tanzu/antrea/pkg/apis/system/v1beta1"
 	"github.com/vmware-tanzu/antrea/pkg/apiserver/certificate"
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/loglevel"
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/traceflow"
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/webhook"
 	"github.com/vmware-tanzu/antrea/pkg/apiserver/registry/networkpolicy/addressgroup"
 	"github.com/vmware-tanzu/antrea/pkg/apiserver/registry/networkpolicy/appliedtogroup"
 	"github.com/vmware-tanzu/antrea/pkg/apiserver/registry/networkpolicy/networkpolicy"
 	"github.com/vmware-tanzu/antrea/pkg/apiserver/registry/system/controllerinfo"
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/registry/system/networkpolicystats"
 	"github.com/vmware-tanzu/antrea/pkg/apiserver/registry/system/supportbundle"
 	"github.com/vmware-tanzu/antrea/pkg/apiserver/storage"
+	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
 	"github.com/vmware-tanzu/antrea/pkg/controller/querier"
+	"github.com/vmware-tanzu/antrea/pkg/features"
 )
 
 var (
@@ -61,6 +67,10 @@ type ExtraConfig struct {
 	networkPolicyStore  storage.Interface
 	controllerQuerier   querier.ControllerQuerier
 	caCertController    *certificate.CACertController
+	crdClient           versioned.Interface
+	staticIPValidator   webhook.Validator
+	// networkPolicyValidator is nil if ClusterNetworkPolicy is disabled.
+	networkPolicyValidator webhook.Validator
 }
 
 // Config defines the config for Antrea apiserver.
@@ -94,15 +104,20 @@ func NewConfig(
 	genericConfig *genericapiserver.Config,
 	addressGroupStore, appliedToGroupStore, networkPolicyStore storage.Interface,
 	caCertController *certificate.CACertController,
-	controllerQuerier querier.ControllerQuerier) *Config {
+	controllerQuerier querier.ControllerQuerier,
+	crdClient versioned.Interface,
+	staticIPValidator, networkPolicyValidator webhook.Validator) *Config {
 	return &Config{
 		genericConfig: genericConfig,
 		extraConfig: ExtraConfig{
-			addressGroupStore:   addressGroupStore,
-			appliedToGroupStore: appliedToGroupStore,
-			networkPolicyStore:  networkPolicyStore,
-			caCertController:    caCertController,
-			controllerQuerier:   controllerQuerier,
+			addressGroupStore:      addressGroupStore,
+			appliedToGroupStore:    appliedToGroupStore,
+			networkPolicyStore:     networkPolicyStore,
+			caCertController:       caCertController,
+			controllerQuerier:      controllerQuerier,
+			crdClient:              crdClient,
+			staticIPValidator:      staticIPValidator,
+			networkPolicyValidator: networkPolicyValidator,
 		},
 	}
 }
@@ -132,6 +147,7 @@ func (c completedConfig) New() (*APIServer, error) {
 	systemGroup := genericapiserver.NewDefaultAPIGroupInfo(system.GroupName, Scheme, metav1.ParameterCodec, Codecs)
 	systemStorage := map[string]rest.Storage{}
 	systemStorage["controllerinfos"] = controllerinfo.NewREST(c.extraConfig.controllerQuerier)
+	systemStorage["networkpolicystats"] = networkpolicystats.NewREST(c.extraConfig.crdClient)
 	bundleStorage := supportbundle.NewControllerStorage()
 	systemStorage["supportbundles"] = bundleStorage.SupportBundle
 	systemStorage["supportbundles/download"] = bundleStorage.Download
@@ -144,5 +160,12 @@ func (c completedConfig) New() (*APIServer, error) {
 		}
 	}
 
+	s.GenericAPIServer.Handler.NonGoRestfulMux.HandleFunc("/validate/staticip", webhook.HandleFunc(c.extraConfig.staticIPValidator))
+	s.GenericAPIServer.Handler.NonGoRestfulMux.HandleFunc("/validate/networkpolicy", webhook.HandleFunc(c.extraConfig.networkPolicyValidator))
+	s.GenericAPIServer.Handler.NonGoRestfulMux.HandleFunc("/debug/log-level", loglevel.HandleFunc())
+	if features.DefaultFeatureGate.Enabled(features.Traceflow) {
+		s.GenericAPIServer.Handler.NonGoRestfulMux.HandleFunc("/traceflows", traceflow.HandleFunc(c.extraConfig.crdClient))
+	}
+
 	return s, nil
 }
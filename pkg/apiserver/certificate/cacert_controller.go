@@ -44,10 +44,17 @@ var (
 		"v1beta1.networking.antrea.tanzu.vmware.com",
 		"v1beta1.system.antrea.tanzu.vmware.com",
 	}
+	// validatingWebhookConfigurationNames contains all the
+	// ValidatingWebhookConfigurations whose webhooks are served by
+	// antrea-controller.
+	validatingWebhookConfigurationNames = []string{
+		"antrea-controller",
+	}
 )
 
 // CACertController is responsible for taking the CA certificate from the
-// caContentProvider and publishing it to the ConfigMap and the APIServices.
+// caContentProvider and publishing it to the ConfigMap, the APIServices and the
+// ValidatingWebhookConfigurations.
 type CACertController struct {
 	// caContentProvider provides the very latest content of the ca bundle.
 	caContentProvider dynamiccertificates.CAContentProvider
@@ -102,6 +109,36 @@ func (c *CACertController) syncCACert() error {
 	if err := c.syncAPIServices(caCert); err != nil {
 		return err
 	}
+
+	if err := c.syncValidatingWebhooks(caCert); err != nil {
+		return err
+	}
+	return nil
+}
+
+// syncValidatingWebhooks updates the CABundle of the webhooks served by antrea-controller.
+func (c *CACertController) syncValidatingWebhooks(caCert []byte) error {
+	klog.Info("Syncing CA certificate with ValidatingWebhookConfigurations")
+	for _, name := range validatingWebhookConfigurationNames {
+		webhookConfig, err := c.client.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Get(context.TODO(), name, v1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("error getting ValidatingWebhookConfiguration %s: %v", name, err)
+		}
+		updated := false
+		for i := range webhookConfig.Webhooks {
+			if bytes.Equal(webhookConfig.Webhooks[i].ClientConfig.CABundle, caCert) {
+				continue
+			}
+			webhookConfig.Webhooks[i].ClientConfig.CABundle = caCert
+			updated = true
+		}
+		if !updated {
+			continue
+		}
+		if _, err := c.client.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Update(context.TODO(), webhookConfig, v1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("error updating antrea CA cert of ValidatingWebhookConfiguration %s: %v", name, err)
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,85 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loglevel
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"k8s.io/klog"
+)
+
+const (
+	// MinLevel and MaxLevel are the bounds of the log levels which can be set
+	// at runtime.
+	MinLevel = 0
+	MaxLevel = 10
+)
+
+// Response is the response of the "/debug/log-level" API.
+type Response struct {
+	Level int `json:"level"`
+}
+
+// CurrentLevel returns the current verbosity of klog, i.e. the highest level
+// whose logs are enabled.
+func CurrentLevel() int {
+	return sort.Search(math.MaxInt32, func(level int) bool {
+		return !bool(klog.V(klog.Level(level + 1)))
+	})
+}
+
+// SetLevel sets the verbosity of klog. It takes effect until the process
+// restarts or the verbosity is set again.
+func SetLevel(level int) error {
+	var l klog.Level
+	return l.Set(strconv.Itoa(level))
+}
+
+// HandleFunc returns the function which handles the API requests to
+// "/debug/log-level". A GET request returns the current log level, and a PUT
+// request sets the log level to the value of the "level" query parameter, which
+// must be between MinLevel and MaxLevel.
+func HandleFunc() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			value := r.URL.Query().Get("level")
+			level, err := strconv.Atoi(value)
+			if err != nil || level < MinLevel || level > MaxLevel {
+				http.Error(w, fmt.Sprintf("invalid log level %q: it must be an integer between %d and %d", value, MinLevel, MaxLevel), http.StatusBadRequest)
+				return
+			}
+			previous := CurrentLevel()
+			if err := SetLevel(level); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			klog.Infof("Changed log level from %d to %d", previous, level)
+		default:
+			http.Error(w, fmt.Sprintf("method %s is not supported", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(Response{Level: CurrentLevel()}); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			klog.Errorf("Error when encoding the log level to json: %v", err)
+		}
+	}
+}
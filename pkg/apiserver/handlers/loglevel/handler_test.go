@@ -0,0 +1,116 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loglevel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleFunc(t *testing.T) {
+	initialLevel := CurrentLevel()
+	defer SetLevel(initialLevel)
+	require.NoError(t, SetLevel(2))
+
+	// The test cases are run in order, as they change the log level.
+	testcases := []struct {
+		name           string
+		method         string
+		query          string
+		expectedStatus int
+		expectedLevel  int
+	}{
+		{
+			name:           "Get level",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedLevel:  2,
+		},
+		{
+			name:           "Set level",
+			method:         http.MethodPut,
+			query:          "?level=5",
+			expectedStatus: http.StatusOK,
+			expectedLevel:  5,
+		},
+		{
+			name:           "Set minimum level",
+			method:         http.MethodPut,
+			query:          "?level=0",
+			expectedStatus: http.StatusOK,
+			expectedLevel:  0,
+		},
+		{
+			name:           "Set maximum level",
+			method:         http.MethodPut,
+			query:          "?level=10",
+			expectedStatus: http.StatusOK,
+			expectedLevel:  10,
+		},
+		{
+			name:           "Negative level",
+			method:         http.MethodPut,
+			query:          "?level=-1",
+			expectedStatus: http.StatusBadRequest,
+			expectedLevel:  10,
+		},
+		{
+			name:           "Level too high",
+			method:         http.MethodPut,
+			query:          "?level=11",
+			expectedStatus: http.StatusBadRequest,
+			expectedLevel:  10,
+		},
+		{
+			name:           "Level not an integer",
+			method:         http.MethodPut,
+			query:          "?level=debug",
+			expectedStatus: http.StatusBadRequest,
+			expectedLevel:  10,
+		},
+		{
+			name:           "Level missing",
+			method:         http.MethodPut,
+			expectedStatus: http.StatusBadRequest,
+			expectedLevel:  10,
+		},
+		{
+			name:           "Unsupported method",
+			method:         http.MethodDelete,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedLevel:  10,
+		},
+	}
+
+	handler := HandleFunc()
+	for _, tc := range testcases {
+		req, err := http.NewRequest(tc.method, tc.query, nil)
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, tc.expectedStatus, recorder.Code, tc.name)
+		if tc.expectedStatus == http.StatusOK {
+			var resp Response
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+			assert.Equal(t, tc.expectedLevel, resp.Level, tc.name)
+		}
+		assert.Equal(t, tc.expectedLevel, CurrentLevel(), tc.name)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceflow
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+)
+
+const (
+	// DefaultTTL is how long a Traceflow created by the "/traceflows" API is
+	// kept after its creation.
+	DefaultTTL = 10 * time.Minute
+
+	gcInterval = time.Minute
+)
+
+// GarbageCollector deletes the Traceflows created by the "/traceflows" API
+// once their TTL has expired. The Traceflows created by users are never
+// deleted.
+type GarbageCollector struct {
+	client versioned.Interface
+	ttl    time.Duration
+	clock  clock.Clock
+}
+
+// NewGarbageCollector returns a GarbageCollector deleting the Traceflows created
+// by the API ttl after their creation.
+func NewGarbageCollector(client versioned.Interface, ttl time.Duration) *GarbageCollector {
+	return &GarbageCollector{client: client, ttl: ttl, clock: clock.RealClock{}}
+}
+
+// Run deletes the expired Traceflows periodically until stopCh is closed.
+func (c *GarbageCollector) Run(stopCh <-chan struct{}) {
+	klog.Infof("Starting garbage collector of the Traceflows created by API, TTL: %v", c.ttl)
+	defer klog.Infof("Shutting down garbage collector of the Traceflows created by API")
+	wait.Until(c.collect, gcInterval, stopCh)
+}
+
+func (c *GarbageCollector) collect() {
+	selector := labels.SelectorFromSet(labels.Set{CreatedByLabelKey: createdByAPI})
+	list, err := c.client.OpsV1alpha1().Traceflows().List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		klog.Errorf("Error when listing the Traceflows created by API: %v", err)
+		return
+	}
+	for i := range list.Items {
+		tf := &list.Items[i]
+		if c.clock.Since(tf.CreationTimestamp.Time) < c.ttl {
+			continue
+		}
+		if err := c.client.OpsV1alpha1().Traceflows().Delete(context.TODO(), tf.Name, metav1.DeleteOptions{}); err != nil {
+			klog.Errorf("Error when deleting expired Traceflow %s: %v", tf.Name, err)
+			continue
+		}
+		klog.V(2).Infof("Deleted expired Traceflow %s", tf.Name)
+	}
+}
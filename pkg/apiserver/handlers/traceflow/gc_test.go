@@ -0,0 +1,59 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	fakeversioned "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+)
+
+func newTraceflow(name string, createdByAPI bool, created time.Time) *opsv1alpha1.Traceflow {
+	tf := &opsv1alpha1.Traceflow{
+		ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)},
+	}
+	if createdByAPI {
+		tf.Labels = map[string]string{CreatedByLabelKey: "api"}
+	}
+	return tf
+}
+
+func TestGarbageCollector(t *testing.T) {
+	now := time.Now()
+	client := fakeversioned.NewSimpleClientset(
+		newTraceflow("api-expired", true, now.Add(-DefaultTTL-time.Second)),
+		newTraceflow("api-recent", true, now.Add(-time.Second)),
+		newTraceflow("user-old", false, now.Add(-2*DefaultTTL)),
+	)
+	gc := NewGarbageCollector(client, DefaultTTL)
+	gc.clock = clock.NewFakeClock(now)
+
+	gc.collect()
+	list, err := client.OpsV1alpha1().Traceflows().List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	var names []string
+	for _, tf := range list.Items {
+		names = append(names, tf.Name)
+	}
+	assert.ElementsMatch(t, []string{"api-recent", "user-old"}, names)
+}
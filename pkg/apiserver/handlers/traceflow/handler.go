@@ -0,0 +1,122 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+)
+
+const (
+	// DefaultTimeout is how long a request waits for the Traceflow to complete
+	// when the "timeout" query parameter is not set.
+	DefaultTimeout = 20 * time.Second
+	// MaxTimeout is the maximum timeout of a request, which is kept below the
+	// 60s timeout the API server applies to non long-running requests.
+	MaxTimeout = 50 * time.Second
+
+	// CreatedByLabelKey is the label set on the Traceflows created by the
+	// "/traceflows" API, so that they can be garbage collected.
+	CreatedByLabelKey = "traceflow.antrea.io/created-by"
+	createdByAPI      = "api"
+
+	namePrefix   = "api-traceflow-"
+	pollInterval = 200 * time.Millisecond
+)
+
+// HandleFunc returns the function which handles the API requests to
+// "/traceflows". A POST request with a TraceflowSpec in its body creates a
+// Traceflow, waits for it to complete, and returns its TraceflowStatus. The
+// optional "timeout" query parameter is a duration which bounds the wait, and
+// defaults to DefaultTimeout. The Traceflow is not deleted after the request,
+// so that it can still be inspected, and is garbage collected after its TTL by
+// the GarbageCollector.
+func HandleFunc(client versioned.Interface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("method %s is not supported", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		timeout := DefaultTimeout
+		if value := r.URL.Query().Get("timeout"); value != "" {
+			var err error
+			timeout, err = time.ParseDuration(value)
+			if err != nil || timeout <= 0 || timeout > MaxTimeout {
+				http.Error(w, fmt.Sprintf("invalid timeout %q: it must be a positive duration not greater than %v", value, MaxTimeout), http.StatusBadRequest)
+				return
+			}
+		}
+		var spec opsv1alpha1.TraceflowSpec
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			http.Error(w, fmt.Sprintf("invalid TraceflowSpec: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		tf := &opsv1alpha1.Traceflow{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   namePrefix + rand.String(8),
+				Labels: map[string]string{CreatedByLabelKey: createdByAPI},
+			},
+			Spec: spec,
+		}
+		tf, err := client.OpsV1alpha1().Traceflows().Create(ctx, tf, metav1.CreateOptions{})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error when creating Traceflow: %v", err), http.StatusInternalServerError)
+			return
+		}
+		klog.V(2).Infof("Created Traceflow %s for API request", tf.Name)
+
+		tf, err = waitForCompletion(ctx, client, tf.Name)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Traceflow %s did not complete in %v", tf.Name, timeout), http.StatusGatewayTimeout)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(tf.Status); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			klog.Errorf("Error when encoding the status of Traceflow %s to json: %v", tf.Name, err)
+		}
+	}
+}
+
+// waitForCompletion polls the Traceflow until it has succeeded or failed, and
+// returns its last observed state. An error is returned if the context is done
+// before the Traceflow completes.
+func waitForCompletion(ctx context.Context, client versioned.Interface, name string) (*opsv1alpha1.Traceflow, error) {
+	tf := &opsv1alpha1.Traceflow{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	err := wait.PollImmediateUntil(pollInterval, func() (bool, error) {
+		updated, err := client.OpsV1alpha1().Traceflows().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			// The error is transient or the context is done, in which
+			// case the poll stops.
+			return false, nil
+		}
+		tf = updated
+		return tf.Status.Phase == opsv1alpha1.Succeeded || tf.Status.Phase == opsv1alpha1.Failed, nil
+	}, ctx.Done())
+	return tf, err
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceflow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+	fakeversioned "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+)
+
+const specJSON = `{"source":{"namespace":"default","pod":"web"},"destination":{"namespace":"default","pod":"db"}}`
+
+// completeTraceflow waits for a Traceflow to be created and sets its phase.
+func completeTraceflow(t *testing.T, client versioned.Interface, phase opsv1alpha1.TraceflowPhase) {
+	var tf *opsv1alpha1.Traceflow
+	err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		list, err := client.OpsV1alpha1().Traceflows().List(context.TODO(), metav1.ListOptions{})
+		if err != nil || len(list.Items) == 0 {
+			return false, err
+		}
+		tf = &list.Items[0]
+		return true, nil
+	})
+	require.NoError(t, err)
+	tf.Status.Phase = phase
+	_, err = client.OpsV1alpha1().Traceflows().UpdateStatus(context.TODO(), tf, metav1.UpdateOptions{})
+	require.NoError(t, err)
+}
+
+func TestHandleFunc(t *testing.T) {
+	testcases := []struct {
+		name           string
+		method         string
+		query          string
+		body           string
+		phase          opsv1alpha1.TraceflowPhase
+		expectedStatus int
+	}{
+		{
+			name:           "Succeeded",
+			method:         http.MethodPost,
+			body:           specJSON,
+			phase:          opsv1alpha1.Succeeded,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Failed",
+			method:         http.MethodPost,
+			query:          "?timeout=5s",
+			body:           specJSON,
+			phase:          opsv1alpha1.Failed,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Timed out",
+			method:         http.MethodPost,
+			query:          "?timeout=500ms",
+			body:           specJSON,
+			phase:          opsv1alpha1.Running,
+			expectedStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:           "Invalid timeout",
+			method:         http.MethodPost,
+			query:          "?timeout=10m",
+			body:           specJSON,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid spec",
+			method:         http.MethodPost,
+			body:           "{",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unsupported method",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakeversioned.NewSimpleClientset()
+			if tc.phase != "" {
+				go completeTraceflow(t, client, tc.phase)
+			}
+			req, err := http.NewRequest(tc.method, "/traceflows"+tc.query, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			HandleFunc(client).ServeHTTP(recorder, req)
+			require.Equal(t, tc.expectedStatus, recorder.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			var status opsv1alpha1.TraceflowStatus
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+			assert.Equal(t, tc.phase, status.Phase)
+
+			list, err := client.OpsV1alpha1().Traceflows().List(context.TODO(), metav1.ListOptions{})
+			require.NoError(t, err)
+			require.Len(t, list.Items, 1)
+			tf := list.Items[0]
+			assert.True(t, strings.HasPrefix(tf.Name, namePrefix))
+			assert.Equal(t, createdByAPI, tf.Labels[CreatedByLabelKey])
+			assert.Equal(t, "web", tf.Spec.Source.Pod)
+			assert.Equal(t, "db", tf.Spec.Destination.Pod)
+		})
+	}
+}
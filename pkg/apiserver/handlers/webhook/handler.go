@@ -0,0 +1,74 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	admv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// Validator validates the objects of the admission requests sent to a
+// validating webhook.
+type Validator interface {
+	Validate(request *admv1beta1.AdmissionRequest) *admv1beta1.AdmissionResponse
+}
+
+// Deny returns an AdmissionResponse which rejects the request with the message.
+func Deny(format string, args ...interface{}) *admv1beta1.AdmissionResponse {
+	return &admv1beta1.AdmissionResponse{
+		Allowed: false,
+		Result:  &metav1.Status{Message: fmt.Sprintf(format, args...)},
+	}
+}
+
+// Allow returns an AdmissionResponse which admits the request.
+func Allow() *admv1beta1.AdmissionResponse {
+	return &admv1beta1.AdmissionResponse{Allowed: true}
+}
+
+// HandleFunc returns the function which handles the AdmissionReviews sent by the
+// Kubernetes apiserver to a validating webhook. All the requests are admitted if
+// the validator is nil, i.e. if the feature it belongs to is disabled.
+func HandleFunc(v Validator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		review := admv1beta1.AdmissionReview{}
+		if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+			http.Error(w, "invalid AdmissionReview", http.StatusBadRequest)
+			return
+		}
+		response := Allow()
+		if v != nil {
+			response = v.Validate(review.Request)
+		}
+		response.UID = review.Request.UID
+		review.Response = response
+		review.Request = nil
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			klog.Errorf("Error when encoding AdmissionReview to json: %v", err)
+		}
+	}
+}
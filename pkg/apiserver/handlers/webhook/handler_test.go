@@ -0,0 +1,65 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type denyAll struct{}
+
+func (denyAll) Validate(request *admv1beta1.AdmissionRequest) *admv1beta1.AdmissionResponse {
+	return Deny("%s is denied", request.Name)
+}
+
+func TestHandleFunc(t *testing.T) {
+	review, err := json.Marshal(admv1beta1.AdmissionReview{
+		Request: &admv1beta1.AdmissionRequest{UID: types.UID("uid1"), Name: "pod1"},
+	})
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		validator   Validator
+		wantAllowed bool
+	}{
+		"no validator": {nil, true},
+		"denied":       {denyAll{}, false},
+	} {
+		recorder := httptest.NewRecorder()
+		HandleFunc(tc.validator).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(review)))
+		require.Equal(t, http.StatusOK, recorder.Code, name)
+		var got admv1beta1.AdmissionReview
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got), name)
+		require.NotNil(t, got.Response, name)
+		assert.Equal(t, types.UID("uid1"), got.Response.UID, name)
+		assert.Equal(t, tc.wantAllowed, got.Response.Allowed, name)
+		if !tc.wantAllowed {
+			assert.Equal(t, "pod1 is denied", got.Response.Result.Message, name)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	HandleFunc(nil).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader([]byte("{}"))))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
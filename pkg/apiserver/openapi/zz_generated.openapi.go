@@ -36,17 +36,21 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.AntreaControllerInfoList":    schema_pkg_apis_clusterinformation_v1beta1_AntreaControllerInfoList(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.ControllerCondition":         schema_pkg_apis_clusterinformation_v1beta1_ControllerCondition(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.NetworkPolicyControllerInfo": schema_pkg_apis_clusterinformation_v1beta1_NetworkPolicyControllerInfo(ref),
+		"github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.NetworkPolicyStats":          schema_pkg_apis_clusterinformation_v1beta1_NetworkPolicyStats(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.OVSInfo":                     schema_pkg_apis_clusterinformation_v1beta1_OVSInfo(ref),
+		"github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.TrafficStats":                schema_pkg_apis_clusterinformation_v1beta1_TrafficStats(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.AddressGroup":                        schema_pkg_apis_networking_v1beta1_AddressGroup(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.AddressGroupList":                    schema_pkg_apis_networking_v1beta1_AddressGroupList(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.AddressGroupPatch":                   schema_pkg_apis_networking_v1beta1_AddressGroupPatch(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.AppliedToGroup":                      schema_pkg_apis_networking_v1beta1_AppliedToGroup(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.AppliedToGroupList":                  schema_pkg_apis_networking_v1beta1_AppliedToGroupList(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.AppliedToGroupPatch":                 schema_pkg_apis_networking_v1beta1_AppliedToGroupPatch(ref),
+		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.Bandwidth":                           schema_pkg_apis_networking_v1beta1_Bandwidth(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.Endpoint":                            schema_pkg_apis_networking_v1beta1_Endpoint(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.ExternalEntityReference":             schema_pkg_apis_networking_v1beta1_ExternalEntityReference(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.GroupMember":                         schema_pkg_apis_networking_v1beta1_GroupMember(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.GroupMemberPod":                      schema_pkg_apis_networking_v1beta1_GroupMemberPod(ref),
+		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.HTTPMatch":                           schema_pkg_apis_networking_v1beta1_HTTPMatch(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.IPBlock":                             schema_pkg_apis_networking_v1beta1_IPBlock(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.IPNet":                               schema_pkg_apis_networking_v1beta1_IPNet(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.NamedPort":                           schema_pkg_apis_networking_v1beta1_NamedPort(ref),
@@ -56,6 +60,8 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.NetworkPolicyRule":                   schema_pkg_apis_networking_v1beta1_NetworkPolicyRule(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.PodReference":                        schema_pkg_apis_networking_v1beta1_PodReference(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.Service":                             schema_pkg_apis_networking_v1beta1_Service(ref),
+		"github.com/vmware-tanzu/antrea/pkg/apis/system/v1beta1.NetworkPolicyStats":                      schema_pkg_apis_system_v1beta1_NetworkPolicyStats(ref),
+		"github.com/vmware-tanzu/antrea/pkg/apis/system/v1beta1.NetworkPolicyStatsList":                  schema_pkg_apis_system_v1beta1_NetworkPolicyStatsList(ref),
 		"github.com/vmware-tanzu/antrea/pkg/apis/system/v1beta1.SupportBundle":                           schema_pkg_apis_system_v1beta1_SupportBundle(ref),
 		"k8s.io/api/core/v1.AWSElasticBlockStoreVolumeSource":                                            schema_k8sio_api_core_v1_AWSElasticBlockStoreVolumeSource(ref),
 		"k8s.io/api/core/v1.Affinity":                                    schema_k8sio_api_core_v1_Affinity(ref),
@@ -458,11 +464,24 @@ func schema_pkg_apis_clusterinformation_v1beta1_AntreaAgentInfo(ref common.Refer
 							Format:      "int32",
 						},
 					},
+					"networkPolicyStats": {
+						SchemaProps: spec.SchemaProps{
+							Description: "The port of antrea agent API Server",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.NetworkPolicyStats"),
+									},
+								},
+							},
+						},
+					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.AgentCondition", "github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.NetworkPolicyControllerInfo", "github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.OVSInfo", "k8s.io/api/core/v1.ObjectReference", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
+			"github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.AgentCondition", "github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.NetworkPolicyControllerInfo", "github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.NetworkPolicyStats", "github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.OVSInfo", "k8s.io/api/core/v1.ObjectReference", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
 	}
 }
 
@@ -726,6 +745,60 @@ func schema_pkg_apis_clusterinformation_v1beta1_NetworkPolicyControllerInfo(ref
 	}
 }
 
+func schema_pkg_apis_clusterinformation_v1beta1_NetworkPolicyStats(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Type: []string{"object"},
+				Properties: map[string]spec.Schema{
+					"policyUID": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+					"policyName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UID of the NetworkPolicy",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"policyNamespace": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the NetworkPolicy",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"ingress": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespace of the NetworkPolicy, empty for ClusterNetworkPolicy",
+							Ref:         ref("github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.TrafficStats"),
+						},
+					},
+					"egress": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Traffic matched by the ingress rules",
+							Ref:         ref("github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.TrafficStats"),
+						},
+					},
+					"ruleHitCount": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Traffic matched by the egress rules",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+				},
+				Required: []string{"policyUID", "policyName", "ingress", "egress", "ruleHitCount"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.TrafficStats"},
+	}
+}
+
 func schema_pkg_apis_clusterinformation_v1beta1_OVSInfo(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -764,6 +837,40 @@ func schema_pkg_apis_clusterinformation_v1beta1_OVSInfo(ref common.ReferenceCall
 	}
 }
 
+func schema_pkg_apis_clusterinformation_v1beta1_TrafficStats(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "TrafficStats is the traffic matched by the rules of a NetworkPolicy in one direction. Packets of established connections skip the NetworkPolicy rules, so PacketCount and ByteCount only include the packets evaluated by the rules: the first packet of each allowed connection and every denied packet.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"packetCount": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"integer"},
+							Format: "int64",
+						},
+					},
+					"byteCount": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Number of packets matched by the rules",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"sessionCount": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Number of bytes matched by the rules",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+				},
+				Required: []string{"packetCount", "byteCount", "sessionCount"},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_networking_v1beta1_AddressGroup(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -1140,6 +1247,34 @@ func schema_pkg_apis_networking_v1beta1_AppliedToGroupPatch(ref common.Reference
 	}
 }
 
+func schema_pkg_apis_networking_v1beta1_Bandwidth(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "Bandwidth describes the bandwidth limit of a rule.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"rate": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Rate is the maximum rate in bits per second.",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"burst": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Burst is the maximum burst size in bits.",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+				},
+				Required: []string{"rate", "burst"},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_networking_v1beta1_Endpoint(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -1283,6 +1418,48 @@ func schema_pkg_apis_networking_v1beta1_GroupMemberPod(ref common.ReferenceCallb
 	}
 }
 
+func schema_pkg_apis_networking_v1beta1_HTTPMatch(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "HTTPMatch describes the HTTP requests matched by a rule.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"method": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Method is the method of the request. If it is empty or \"*\", all methods are matched.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"path": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Path is the pattern matched against the path of the request, in which \"*\" matches any sequence of characters. If it is empty, all paths are matched.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"headers": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Headers are the headers which the request must have, keyed by header name. Values are patterns matched in the same way as Path.",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_networking_v1beta1_IPBlock(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -1437,6 +1614,13 @@ func schema_pkg_apis_networking_v1beta1_NetworkPolicy(ref common.ReferenceCallba
 							Format:      "double",
 						},
 					},
+					"tierPriority": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TierPriority represents the priority of the tier of this Network Policy. Policies of tiers with a lower TierPriority are enforced first. TierPriority will be unset (nil) for K8s Network Policy.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
 				},
 			},
 		},
@@ -1587,11 +1771,44 @@ func schema_pkg_apis_networking_v1beta1_NetworkPolicyRule(ref common.ReferenceCa
 							Format:      "",
 						},
 					},
+					"enableLogging": {
+						SchemaProps: spec.SchemaProps{
+							Description: "EnableLogging indicates whether or not to generate logs when rules are matched.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"httpMatches": {
+						SchemaProps: spec.SchemaProps{
+							Description: "HTTPMatches restricts the rule to the HTTP requests matching any of the conditions, each request of a TCP connection being evaluated. Empty for K8s NetworkPolicy.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.HTTPMatch"),
+									},
+								},
+							},
+						},
+					},
+					"bandwidth": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Bandwidth limits the bandwidth of each Pod to which the rule is applied, in the direction of the rule. Nil for K8s NetworkPolicy.",
+							Ref:         ref("github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.Bandwidth"),
+						},
+					},
+					"preserveSourceIP": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PreserveSourceIP indicates that the traffic matched by an egress rule must not be SNATed when it leaves the Node, so that the destination sees the IP of the source Pod. Always false for K8s NetworkPolicy.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.NetworkPolicyPeer", "github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.Service"},
+			"github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.Bandwidth", "github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.HTTPMatch", "github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.NetworkPolicyPeer", "github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1.Service"},
 	}
 }
 
@@ -1650,6 +1867,116 @@ func schema_pkg_apis_networking_v1beta1_Service(ref common.ReferenceCallback) co
 	}
 }
 
+func schema_pkg_apis_system_v1beta1_NetworkPolicyStats(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "NetworkPolicyStats is the traffic statistics of a NetworkPolicy summed across all the Nodes it is enforced on. Its name is the UID of the NetworkPolicy.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"),
+						},
+					},
+					"policyName": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+					"policyNamespace": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+					"ingress": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.TrafficStats"),
+						},
+					},
+					"egress": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.TrafficStats"),
+						},
+					},
+					"ruleHitCount": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RuleHitCount is the number of packets which hit the allow or deny action flows of the rules of the NetworkPolicy in both directions.",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+				},
+				Required: []string{"policyName", "ingress", "egress", "ruleHitCount"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1.TrafficStats", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
+	}
+}
+
+func schema_pkg_apis_system_v1beta1_NetworkPolicyStatsList(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Type: []string{"object"},
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("k8s.io/apimachinery/pkg/apis/meta/v1.ListMeta"),
+						},
+					},
+					"items": {
+						SchemaProps: spec.SchemaProps{
+							Type: []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/vmware-tanzu/antrea/pkg/apis/system/v1beta1.NetworkPolicyStats"),
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"items"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/vmware-tanzu/antrea/pkg/apis/system/v1beta1.NetworkPolicyStats", "k8s.io/apimachinery/pkg/apis/meta/v1.ListMeta"},
+	}
+}
+
 func schema_pkg_apis_system_v1beta1_SupportBundle(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
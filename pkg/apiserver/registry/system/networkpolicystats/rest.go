@@ -0,0 +1,133 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicystats
+
+import (
+	"context"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/registry/rest"
+
+	clusterinfo "github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1"
+	system "github.com/vmware-tanzu/antrea/pkg/apis/system/v1beta1"
+	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+)
+
+// REST implements rest.Storage for NetworkPolicyStats. The statistics are
+// reported by the agents in their AntreaAgentInfos and summed on each request.
+type REST struct {
+	crdClient versioned.Interface
+}
+
+var (
+	_ rest.Scoper = &REST{}
+	_ rest.Getter = &REST{}
+	_ rest.Lister = &REST{}
+)
+
+// NewREST returns a REST object that will work against API services.
+func NewREST(crdClient versioned.Interface) *REST {
+	return &REST{crdClient}
+}
+
+func (r *REST) New() runtime.Object {
+	return &system.NetworkPolicyStats{}
+}
+
+// aggregate sums the statistics reported by all the agents, sorted by the
+// number of bytes in descending order.
+func (r *REST) aggregate(ctx context.Context) ([]system.NetworkPolicyStats, error) {
+	agentInfos, err := r.crdClient.ClusterinformationV1beta1().AntreaAgentInfos().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	statsMap := map[types.UID]*system.NetworkPolicyStats{}
+	for i := range agentInfos.Items {
+		for _, agentStats := range agentInfos.Items[i].NetworkPolicyStats {
+			stats, exists := statsMap[agentStats.PolicyUID]
+			if !exists {
+				stats = &system.NetworkPolicyStats{
+					ObjectMeta:      metav1.ObjectMeta{Name: string(agentStats.PolicyUID)},
+					PolicyName:      agentStats.PolicyName,
+					PolicyNamespace: agentStats.PolicyNamespace,
+				}
+				statsMap[agentStats.PolicyUID] = stats
+			}
+			addTrafficStats(&stats.Ingress, &agentStats.Ingress)
+			addTrafficStats(&stats.Egress, &agentStats.Egress)
+			stats.RuleHitCount += agentStats.RuleHitCount
+		}
+	}
+	statsList := make([]system.NetworkPolicyStats, 0, len(statsMap))
+	for _, stats := range statsMap {
+		statsList = append(statsList, *stats)
+	}
+	sort.Slice(statsList, func(i, j int) bool {
+		bi := statsList[i].Ingress.ByteCount + statsList[i].Egress.ByteCount
+		bj := statsList[j].Ingress.ByteCount + statsList[j].Egress.ByteCount
+		if bi != bj {
+			return bi > bj
+		}
+		if statsList[i].PolicyNamespace != statsList[j].PolicyNamespace {
+			return statsList[i].PolicyNamespace < statsList[j].PolicyNamespace
+		}
+		return statsList[i].PolicyName < statsList[j].PolicyName
+	})
+	return statsList, nil
+}
+
+func addTrafficStats(sum, stats *clusterinfo.TrafficStats) {
+	sum.PacketCount += stats.PacketCount
+	sum.ByteCount += stats.ByteCount
+	sum.SessionCount += stats.SessionCount
+}
+
+func (r *REST) Get(ctx context.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
+	statsList, err := r.aggregate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range statsList {
+		if statsList[i].Name == name {
+			return &statsList[i], nil
+		}
+	}
+	return nil, errors.NewNotFound(system.Resource("networkpolicystats"), name)
+}
+
+func (r *REST) NewList() runtime.Object {
+	return &system.NetworkPolicyStatsList{}
+}
+
+func (r *REST) List(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
+	statsList, err := r.aggregate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &system.NetworkPolicyStatsList{Items: statsList}, nil
+}
+
+func (r *REST) NamespaceScoped() bool {
+	return false
+}
+
+func (r *REST) ConvertToTable(ctx context.Context, obj runtime.Object, tableOptions runtime.Object) (*metav1.Table, error) {
+	return rest.NewDefaultTableConvertor(system.Resource("networkpolicystats")).ConvertToTable(ctx, obj, tableOptions)
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicystats
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterinfo "github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1"
+	system "github.com/vmware-tanzu/antrea/pkg/apis/system/v1beta1"
+	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+)
+
+func newAgentInfo(node string, stats ...clusterinfo.NetworkPolicyStats) *clusterinfo.AntreaAgentInfo {
+	return &clusterinfo.AntreaAgentInfo{
+		ObjectMeta:         metav1.ObjectMeta{Name: node},
+		NetworkPolicyStats: stats,
+	}
+}
+
+func TestREST(t *testing.T) {
+	crdClient := fake.NewSimpleClientset(
+		newAgentInfo("node1",
+			clusterinfo.NetworkPolicyStats{
+				PolicyUID:       "uid1",
+				PolicyName:      "np1",
+				PolicyNamespace: "ns1",
+				Ingress:         clusterinfo.TrafficStats{PacketCount: 2, ByteCount: 148, SessionCount: 2},
+				RuleHitCount:    2,
+			},
+			clusterinfo.NetworkPolicyStats{
+				PolicyUID:    "uid2",
+				PolicyName:   "cnp1",
+				Egress:       clusterinfo.TrafficStats{PacketCount: 10, ByteCount: 740},
+				RuleHitCount: 10,
+			},
+		),
+		newAgentInfo("node2",
+			clusterinfo.NetworkPolicyStats{
+				PolicyUID:       "uid1",
+				PolicyName:      "np1",
+				PolicyNamespace: "ns1",
+				Ingress:         clusterinfo.TrafficStats{PacketCount: 1, ByteCount: 74, SessionCount: 1},
+				Egress:          clusterinfo.TrafficStats{PacketCount: 20, ByteCount: 1480, SessionCount: 20},
+				RuleHitCount:    21,
+			},
+		),
+		newAgentInfo("node3"),
+	)
+	r := NewREST(crdClient)
+	expectedNP1 := system.NetworkPolicyStats{
+		ObjectMeta:      metav1.ObjectMeta{Name: "uid1"},
+		PolicyName:      "np1",
+		PolicyNamespace: "ns1",
+		Ingress:         clusterinfo.TrafficStats{PacketCount: 3, ByteCount: 222, SessionCount: 3},
+		Egress:          clusterinfo.TrafficStats{PacketCount: 20, ByteCount: 1480, SessionCount: 20},
+		RuleHitCount:    23,
+	}
+	expectedCNP1 := system.NetworkPolicyStats{
+		ObjectMeta:   metav1.ObjectMeta{Name: "uid2"},
+		PolicyName:   "cnp1",
+		Egress:       clusterinfo.TrafficStats{PacketCount: 10, ByteCount: 740},
+		RuleHitCount: 10,
+	}
+
+	obj, err := r.List(context.TODO(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, &system.NetworkPolicyStatsList{Items: []system.NetworkPolicyStats{expectedNP1, expectedCNP1}}, obj)
+
+	obj, err = r.Get(context.TODO(), "uid2", &metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, &expectedCNP1, obj)
+
+	_, err = r.Get(context.TODO(), "uid3", &metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
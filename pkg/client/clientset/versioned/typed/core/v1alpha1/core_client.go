@@ -24,7 +24,9 @@ import (
 
 type CoreV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	EgressesGetter
 	ExternalEntitiesGetter
+	IPPoolsGetter
 }
 
 // CoreV1alpha1Client is used to interact with features provided by the core.antrea.tanzu.vmware.com group.
@@ -32,10 +34,18 @@ type CoreV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *CoreV1alpha1Client) Egresses() EgressInterface {
+	return newEgresses(c)
+}
+
 func (c *CoreV1alpha1Client) ExternalEntities(namespace string) ExternalEntityInterface {
 	return newExternalEntities(c, namespace)
 }
 
+func (c *CoreV1alpha1Client) IPPools() IPPoolInterface {
+	return newIPPools(c)
+}
+
 // NewForConfig creates a new CoreV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*CoreV1alpha1Client, error) {
 	config := *c
@@ -16,4 +16,8 @@
 
 package v1alpha1
 
+type EgressExpansion interface{}
+
 type ExternalEntityExpansion interface{}
+
+type IPPoolExpansion interface{}
@@ -26,10 +26,22 @@ type FakeOpsV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeOpsV1alpha1) ClusterIPSecStatuses() v1alpha1.ClusterIPSecStatusInterface {
+	return &FakeClusterIPSecStatuses{c}
+}
+
+func (c *FakeOpsV1alpha1) IPSecTunnelStatuses() v1alpha1.IPSecTunnelStatusInterface {
+	return &FakeIPSecTunnelStatuses{c}
+}
+
 func (c *FakeOpsV1alpha1) Traceflows() v1alpha1.TraceflowInterface {
 	return &FakeTraceflows{c}
 }
 
+func (c *FakeOpsV1alpha1) TraceflowHistories() v1alpha1.TraceflowHistoryInterface {
+	return &FakeTraceflowHistories{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeOpsV1alpha1) RESTClient() rest.Interface {
@@ -16,4 +16,10 @@
 
 package v1alpha1
 
+type ClusterIPSecStatusExpansion interface{}
+
+type IPSecTunnelStatusExpansion interface{}
+
 type TraceflowExpansion interface{}
+
+type TraceflowHistoryExpansion interface{}
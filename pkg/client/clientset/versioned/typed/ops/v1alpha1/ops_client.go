@@ -24,7 +24,10 @@ import (
 
 type OpsV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	ClusterIPSecStatusesGetter
+	IPSecTunnelStatusesGetter
 	TraceflowsGetter
+	TraceflowHistoriesGetter
 }
 
 // OpsV1alpha1Client is used to interact with features provided by the ops.antrea.tanzu.vmware.com group.
@@ -32,10 +35,22 @@ type OpsV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *OpsV1alpha1Client) ClusterIPSecStatuses() ClusterIPSecStatusInterface {
+	return newClusterIPSecStatuses(c)
+}
+
+func (c *OpsV1alpha1Client) IPSecTunnelStatuses() IPSecTunnelStatusInterface {
+	return newIPSecTunnelStatuses(c)
+}
+
 func (c *OpsV1alpha1Client) Traceflows() TraceflowInterface {
 	return newTraceflows(c)
 }
 
+func (c *OpsV1alpha1Client) TraceflowHistories() TraceflowHistoryInterface {
+	return newTraceflowHistories(c)
+}
+
 // NewForConfig creates a new OpsV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*OpsV1alpha1Client, error) {
 	config := *c
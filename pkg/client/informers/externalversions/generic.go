@@ -53,12 +53,22 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=core.antrea.tanzu.vmware.com, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("egresses"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Core().V1alpha1().Egresses().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("externalentities"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Core().V1alpha1().ExternalEntities().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("ippools"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Core().V1alpha1().IPPools().Informer()}, nil
 
 		// Group=ops.antrea.tanzu.vmware.com, Version=v1alpha1
+	case opsv1alpha1.SchemeGroupVersion.WithResource("clusteripsecstatuses"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Ops().V1alpha1().ClusterIPSecStatuses().Informer()}, nil
+	case opsv1alpha1.SchemeGroupVersion.WithResource("ipsectunnelstatuses"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Ops().V1alpha1().IPSecTunnelStatuses().Informer()}, nil
 	case opsv1alpha1.SchemeGroupVersion.WithResource("traceflows"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Ops().V1alpha1().Traceflows().Informer()}, nil
+	case opsv1alpha1.SchemeGroupVersion.WithResource("traceflowhistories"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Ops().V1alpha1().TraceflowHistories().Informer()}, nil
 
 		// Group=security.antrea.tanzu.vmware.com, Version=v1alpha1
 	case securityv1alpha1.SchemeGroupVersion.WithResource("clusternetworkpolicies"):
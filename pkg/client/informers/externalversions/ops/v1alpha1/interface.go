@@ -22,8 +22,14 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// ClusterIPSecStatuses returns a ClusterIPSecStatusInformer.
+	ClusterIPSecStatuses() ClusterIPSecStatusInformer
+	// IPSecTunnelStatuses returns a IPSecTunnelStatusInformer.
+	IPSecTunnelStatuses() IPSecTunnelStatusInformer
 	// Traceflows returns a TraceflowInformer.
 	Traceflows() TraceflowInformer
+	// TraceflowHistories returns a TraceflowHistoryInformer.
+	TraceflowHistories() TraceflowHistoryInformer
 }
 
 type version struct {
@@ -37,7 +43,22 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// ClusterIPSecStatuses returns a ClusterIPSecStatusInformer.
+func (v *version) ClusterIPSecStatuses() ClusterIPSecStatusInformer {
+	return &clusterIPSecStatusInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// IPSecTunnelStatuses returns a IPSecTunnelStatusInformer.
+func (v *version) IPSecTunnelStatuses() IPSecTunnelStatusInformer {
+	return &iPSecTunnelStatusInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // Traceflows returns a TraceflowInformer.
 func (v *version) Traceflows() TraceflowInformer {
 	return &traceflowInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
+
+// TraceflowHistories returns a TraceflowHistoryInformer.
+func (v *version) TraceflowHistories() TraceflowHistoryInformer {
+	return &traceflowHistoryInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
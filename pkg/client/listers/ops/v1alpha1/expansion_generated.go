@@ -16,6 +16,18 @@
 
 package v1alpha1
 
+// ClusterIPSecStatusListerExpansion allows custom methods to be added to
+// ClusterIPSecStatusLister.
+type ClusterIPSecStatusListerExpansion interface{}
+
+// IPSecTunnelStatusListerExpansion allows custom methods to be added to
+// IPSecTunnelStatusLister.
+type IPSecTunnelStatusListerExpansion interface{}
+
 // TraceflowListerExpansion allows custom methods to be added to
 // TraceflowLister.
 type TraceflowListerExpansion interface{}
+
+// TraceflowHistoryListerExpansion allows custom methods to be added to
+// TraceflowHistoryLister.
+type TraceflowHistoryListerExpansion interface{}
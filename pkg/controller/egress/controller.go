@@ -0,0 +1,268 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package egress
+
+import (
+	"context"
+	"hash/fnv"
+	"net"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	corev1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/core/v1alpha1"
+	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+	crdinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions/core/v1alpha1"
+	crdlisters "github.com/vmware-tanzu/antrea/pkg/client/listers/core/v1alpha1"
+)
+
+const (
+	// Set resyncPeriod to 0 to disable resyncing.
+	resyncPeriod time.Duration = 0
+	// How long to wait before retrying the processing of an Egress.
+	minRetryDelay = 5 * time.Second
+	maxRetryDelay = 300 * time.Second
+	// Default number of workers processing Egresses.
+	defaultWorkers = 2
+)
+
+// Controller assigns the IPs of the Egresses to the Nodes. The Node an Egress
+// IP is assigned to is reported with the egressNode status of the Egresses, and
+// antrea-agent configures the IP on the Node and SNATs the egress traffic of the
+// selected Pods to it. When the Node becomes NotReady, the IP is assigned to
+// another Node.
+type Controller struct {
+	client             versioned.Interface
+	egressLister       crdlisters.EgressLister
+	egressListerSynced cache.InformerSynced
+	nodeLister         corelisters.NodeLister
+	nodeListerSynced   cache.InformerSynced
+	queue              workqueue.RateLimitingInterface
+}
+
+// NewEgressController creates a new Egress controller.
+func NewEgressController(client versioned.Interface, egressInformer crdinformers.EgressInformer, nodeInformer coreinformers.NodeInformer) *Controller {
+	c := &Controller{
+		client:             client,
+		egressLister:       egressInformer.Lister(),
+		egressListerSynced: egressInformer.Informer().HasSynced,
+		nodeLister:         nodeInformer.Lister(),
+		nodeListerSynced:   nodeInformer.Informer().HasSynced,
+		queue:              workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "egress"),
+	}
+	egressInformer.Informer().AddEventHandlerWithResyncPeriod(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.addEgress,
+			UpdateFunc: c.updateEgress,
+		},
+		resyncPeriod,
+	)
+	nodeInformer.Informer().AddEventHandlerWithResyncPeriod(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.addNode,
+			UpdateFunc: c.updateNode,
+			DeleteFunc: c.deleteNode,
+		},
+		resyncPeriod,
+	)
+	return c
+}
+
+func (c *Controller) addEgress(obj interface{}) {
+	egress := obj.(*corev1alpha1.Egress)
+	klog.Infof("Processing Egress %s ADD event", egress.Name)
+	c.queue.Add(egress.Name)
+}
+
+func (c *Controller) updateEgress(oldObj, curObj interface{}) {
+	oldEgress := oldObj.(*corev1alpha1.Egress)
+	curEgress := curObj.(*corev1alpha1.Egress)
+	// The status is reset when it is updated by another client.
+	if oldEgress.Spec.EgressIP == curEgress.Spec.EgressIP && oldEgress.Status == curEgress.Status {
+		return
+	}
+	klog.Infof("Processing Egress %s UPDATE event", curEgress.Name)
+	c.queue.Add(curEgress.Name)
+}
+
+// enqueueAllEgresses adds all the Egresses to the work queue, as a change to
+// the Nodes may change the Node their IPs are assigned to.
+func (c *Controller) enqueueAllEgresses() {
+	egresses, err := c.egressLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list Egresses: %v", err)
+		return
+	}
+	for _, egress := range egresses {
+		c.queue.Add(egress.Name)
+	}
+}
+
+func (c *Controller) addNode(obj interface{}) {
+	c.enqueueAllEgresses()
+}
+
+func (c *Controller) updateNode(oldObj, curObj interface{}) {
+	oldNode := oldObj.(*corev1.Node)
+	curNode := curObj.(*corev1.Node)
+	// The Nodes are updated by their heartbeats, which only matter when the
+	// readiness of the Nodes changes.
+	if isNodeReady(oldNode) == isNodeReady(curNode) && nodeInternalIP(oldNode) == nodeInternalIP(curNode) {
+		return
+	}
+	klog.Infof("Processing Node %s UPDATE event", curNode.Name)
+	c.enqueueAllEgresses()
+}
+
+func (c *Controller) deleteNode(obj interface{}) {
+	c.enqueueAllEgresses()
+}
+
+func (c *Controller) Run(stopCh <-chan struct{}) {
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting Egress controller")
+	defer klog.Info("Shutting down Egress controller")
+
+	klog.Info("Waiting for caches to sync for Egress controller")
+	if !cache.WaitForCacheSync(stopCh, c.egressListerSynced, c.nodeListerSynced) {
+		klog.Error("Unable to sync caches for Egress controller")
+		return
+	}
+	klog.Info("Caches are synced for Egress controller")
+
+	for i := 0; i < defaultWorkers; i++ {
+		go wait.Until(c.worker, time.Second, stopCh)
+	}
+	<-stopCh
+}
+
+// worker is a long-running function that will continually call the processNextWorkItem function
+// in order to read and process a message on the workqueue.
+func (c *Controller) worker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *Controller) processNextWorkItem() bool {
+	obj, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(obj)
+
+	// We expect strings (Egress name) to come off the workqueue.
+	if key, ok := obj.(string); !ok {
+		c.queue.Forget(obj)
+		klog.Errorf("Expected string in work queue but got %#v", obj)
+		return true
+	} else if err := c.syncEgress(key); err == nil {
+		c.queue.Forget(key)
+	} else {
+		c.queue.AddRateLimited(key)
+		klog.Errorf("Error syncing Egress %s, requeuing. Error: %v", key, err)
+	}
+	return true
+}
+
+func (c *Controller) syncEgress(name string) error {
+	startTime := time.Now()
+	defer func() {
+		klog.V(4).Infof("Finished syncing Egress %s. (%v)", name, time.Since(startTime))
+	}()
+
+	egress, err := c.egressLister.Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	nodes, err := c.nodeLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	egressNode := selectEgressNode(egress, nodes)
+	if egressNode == egress.Status.EgressNode {
+		return nil
+	}
+	if egressNode == "" {
+		klog.Warningf("No Node is Ready to be assigned the IP of Egress %s", name)
+	} else {
+		klog.Infof("Assigning the IP of Egress %s to Node %s", name, egressNode)
+	}
+	egress = egress.DeepCopy()
+	egress.Status.EgressNode = egressNode
+	_, err = c.client.CoreV1alpha1().Egresses().Update(context.TODO(), egress, metav1.UpdateOptions{})
+	return err
+}
+
+// selectEgressNode returns the name of the Ready Node the IP of the Egress
+// should be assigned to, or an empty string if no Node is Ready. The Node whose
+// InternalIP is the Egress IP is always selected, as the IP is already assigned
+// to it. Otherwise the current Node is kept while it is Ready, and a new Node is
+// selected by hashing the Egress IP, so that the Egresses sharing an IP are
+// assigned to the same Node.
+func selectEgressNode(egress *corev1alpha1.Egress, nodes []*corev1.Node) string {
+	var readyNodes []string
+	for _, node := range nodes {
+		if !isNodeReady(node) {
+			continue
+		}
+		if nodeInternalIP(node) == egress.Spec.EgressIP {
+			return node.Name
+		}
+		readyNodes = append(readyNodes, node.Name)
+	}
+	if len(readyNodes) == 0 {
+		return ""
+	}
+	for _, node := range readyNodes {
+		if node == egress.Status.EgressNode {
+			return node
+		}
+	}
+	sort.Strings(readyNodes)
+	hash := fnv.New32a()
+	hash.Write(net.ParseIP(egress.Spec.EgressIP))
+	return readyNodes[hash.Sum32()%uint32(len(readyNodes))]
+}
+
+func isNodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func nodeInternalIP(node *corev1.Node) string {
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			return address.Address
+		}
+	}
+	return ""
+}
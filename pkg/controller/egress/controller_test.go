@@ -0,0 +1,147 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package egress
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	corev1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/core/v1alpha1"
+	fakeversioned "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+	crdinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions"
+)
+
+func newNode(name, ip string, ready bool) *corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+			Addresses:  []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}},
+		},
+	}
+}
+
+func newEgress(name, egressIP, egressNode string) *corev1alpha1.Egress {
+	return &corev1alpha1.Egress{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1alpha1.EgressSpec{EgressIP: egressIP},
+		Status:     corev1alpha1.EgressStatus{EgressNode: egressNode},
+	}
+}
+
+func TestSelectEgressNode(t *testing.T) {
+	tests := []struct {
+		name         string
+		egress       *corev1alpha1.Egress
+		nodes        []*corev1.Node
+		expectedNode string
+	}{
+		{
+			name:         "no ready Node",
+			egress:       newEgress("egress", "10.10.0.100", "node1"),
+			nodes:        []*corev1.Node{newNode("node1", "10.10.0.1", false)},
+			expectedNode: "",
+		},
+		{
+			name:   "Node owning the Egress IP",
+			egress: newEgress("egress", "10.10.0.2", "node1"),
+			nodes: []*corev1.Node{
+				newNode("node1", "10.10.0.1", true),
+				newNode("node2", "10.10.0.2", true),
+			},
+			expectedNode: "node2",
+		},
+		{
+			name:   "not ready Node owning the Egress IP",
+			egress: newEgress("egress", "10.10.0.2", "node1"),
+			nodes: []*corev1.Node{
+				newNode("node1", "10.10.0.1", true),
+				newNode("node2", "10.10.0.2", false),
+			},
+			expectedNode: "node1",
+		},
+		{
+			name:   "current Node still ready",
+			egress: newEgress("egress", "10.10.0.100", "node2"),
+			nodes: []*corev1.Node{
+				newNode("node1", "10.10.0.1", true),
+				newNode("node2", "10.10.0.2", true),
+			},
+			expectedNode: "node2",
+		},
+		{
+			name:   "current Node not ready",
+			egress: newEgress("egress", "10.10.0.100", "node2"),
+			nodes: []*corev1.Node{
+				newNode("node1", "10.10.0.1", true),
+				newNode("node2", "10.10.0.2", false),
+			},
+			expectedNode: "node1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedNode, selectEgressNode(tt.egress, tt.nodes))
+		})
+	}
+}
+
+func TestSelectEgressNodeIsStable(t *testing.T) {
+	egress := newEgress("egress", "10.10.0.100", "")
+	nodes := []*corev1.Node{
+		newNode("node1", "10.10.0.1", true),
+		newNode("node2", "10.10.0.2", true),
+		newNode("node3", "10.10.0.3", true),
+	}
+	expectedNode := selectEgressNode(egress, nodes)
+	require.NotEmpty(t, expectedNode)
+	// The selection must not depend on the order of the Nodes.
+	reversed := []*corev1.Node{nodes[2], nodes[1], nodes[0]}
+	assert.Equal(t, expectedNode, selectEgressNode(egress, reversed))
+}
+
+func TestSyncEgress(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	egress := newEgress("egress", "10.10.0.100", "")
+	crdClient := fakeversioned.NewSimpleClientset(egress)
+	k8sClient := fake.NewSimpleClientset(newNode("node1", "10.10.0.1", true))
+	crdInformerFactory := crdinformers.NewSharedInformerFactory(crdClient, 0)
+	informerFactory := informers.NewSharedInformerFactory(k8sClient, 0)
+	c := NewEgressController(crdClient, crdInformerFactory.Core().V1alpha1().Egresses(), informerFactory.Core().V1().Nodes())
+	crdInformerFactory.Start(stopCh)
+	informerFactory.Start(stopCh)
+	crdInformerFactory.WaitForCacheSync(stopCh)
+	informerFactory.WaitForCacheSync(stopCh)
+
+	require.NoError(t, c.syncEgress("egress"))
+	updated, err := crdClient.CoreV1alpha1().Egresses().Get(context.TODO(), "egress", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "node1", updated.Status.EgressNode)
+
+	// A deleted Egress is ignored.
+	require.NoError(t, c.syncEgress("deleted-egress"))
+}
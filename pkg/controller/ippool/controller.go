@@ -0,0 +1,305 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ippool
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	corev1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/core/v1alpha1"
+	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/scheme"
+	coreinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions/core/v1alpha1"
+	corelisters "github.com/vmware-tanzu/antrea/pkg/client/listers/core/v1alpha1"
+	"github.com/vmware-tanzu/antrea/pkg/controller/metrics"
+	utilippool "github.com/vmware-tanzu/antrea/pkg/util/ippool"
+)
+
+const (
+	// Set resyncPeriod to 0 to disable resyncing.
+	resyncPeriod time.Duration = 0
+	// How long to wait before retrying the processing of an IPPool.
+	minRetryDelay = 5 * time.Second
+	maxRetryDelay = 300 * time.Second
+	// Default number of workers processing IPPools.
+	defaultWorkers = 2
+
+	// Reasons of the Valid condition.
+	reasonValid         = "Valid"
+	reasonInvalidRanges = "InvalidRanges"
+	reasonOverlapping   = "Overlapping"
+
+	// A HighIPAMUtilization Warning event is emitted for an IPPool when the
+	// ratio of its allocated IPs exceeds highUtilizationThreshold.
+	reasonHighIPAMUtilization = "HighIPAMUtilization"
+	highUtilizationThreshold  = 0.8
+
+	componentName = "antrea-controller"
+)
+
+// Controller validates the IPPools. The ranges of an IPPool are valid if they
+// are well-formed, and if they do not overlap with each other or with the ranges
+// of the IPPools created before it. The result is reported with the Valid
+// condition of the IPPools, and antrea-agents only allocate IPs from the valid
+// IPPools.
+type Controller struct {
+	client             versioned.Interface
+	ipPoolLister       corelisters.IPPoolLister
+	ipPoolListerSynced cache.InformerSynced
+	queue              workqueue.RateLimitingInterface
+	recorder           record.EventRecorder
+	// highUtilizationPools contains the names of the IPPools whose utilization
+	// exceeds highUtilizationThreshold, so that a single event is emitted each
+	// time the threshold is exceeded. It is only accessed by the IPPool event
+	// handlers, which are not called concurrently.
+	highUtilizationPools sets.String
+}
+
+// NewIPPoolController creates a new IPPool controller.
+func NewIPPoolController(k8sClient kubernetes.Interface, client versioned.Interface, ipPoolInformer coreinformers.IPPoolInformer) *Controller {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sClient.CoreV1().Events("")})
+	c := &Controller{
+		client:               client,
+		ipPoolLister:         ipPoolInformer.Lister(),
+		ipPoolListerSynced:   ipPoolInformer.Informer().HasSynced,
+		queue:                workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "ippool"),
+		recorder:             broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: componentName}),
+		highUtilizationPools: sets.NewString(),
+	}
+	ipPoolInformer.Informer().AddEventHandlerWithResyncPeriod(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.addIPPool,
+			UpdateFunc: c.updateIPPool,
+			DeleteFunc: c.deleteIPPool,
+		},
+		resyncPeriod,
+	)
+	return c
+}
+
+// enqueueAllIPPools adds all the IPPools to the work queue, as a change to the
+// ranges of an IPPool may change the validity of the others.
+func (c *Controller) enqueueAllIPPools() {
+	pools, err := c.ipPoolLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list IPPools: %v", err)
+		return
+	}
+	for _, pool := range pools {
+		c.queue.Add(pool.Name)
+	}
+}
+
+func (c *Controller) addIPPool(obj interface{}) {
+	pool := obj.(*corev1alpha1.IPPool)
+	klog.Infof("Processing IPPool %s ADD event", pool.Name)
+	c.updateUtilization(pool)
+	c.enqueueAllIPPools()
+}
+
+func (c *Controller) updateIPPool(oldObj, curObj interface{}) {
+	oldPool := oldObj.(*corev1alpha1.IPPool)
+	curPool := curObj.(*corev1alpha1.IPPool)
+	c.updateUtilization(curPool)
+	// The allocations of the IPPools are updated by the agents whenever a Pod
+	// gets an IP from them, and do not affect their validity.
+	if reflect.DeepEqual(oldPool.Spec.Ranges, curPool.Spec.Ranges) {
+		return
+	}
+	klog.Infof("Processing IPPool %s UPDATE event", curPool.Name)
+	c.enqueueAllIPPools()
+}
+
+func (c *Controller) deleteIPPool(old interface{}) {
+	pool, ok := old.(*corev1alpha1.IPPool)
+	if !ok {
+		tombstone, ok := old.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			klog.Errorf("Error decoding object when deleting IPPool, invalid type: %v", old)
+			return
+		}
+		pool, ok = tombstone.Obj.(*corev1alpha1.IPPool)
+		if !ok {
+			klog.Errorf("Error decoding object tombstone when deleting IPPool, invalid type: %v", tombstone.Obj)
+			return
+		}
+	}
+	klog.Infof("Processing IPPool %s DELETE event", pool.Name)
+	c.deleteUtilization(pool.Name)
+	c.enqueueAllIPPools()
+}
+
+// updateUtilization updates the utilization metric of the IPPool, and emits a
+// HighIPAMUtilization Warning event when its utilization exceeds
+// highUtilizationThreshold.
+func (c *Controller) updateUtilization(pool *corev1alpha1.IPPool) {
+	ranges, err := utilippool.ParseRanges(pool)
+	if err != nil || !utilippool.IsValid(pool) {
+		c.deleteUtilization(pool.Name)
+		return
+	}
+	capacity := utilippool.Capacity(ranges)
+	allocated := len(pool.Status.Allocations)
+	utilization := 0.0
+	if capacity > 0 {
+		utilization = float64(allocated) / capacity
+	}
+	metrics.IPAMPoolUtilizationRatio.WithLabelValues(pool.Name).Set(utilization)
+	if utilization <= highUtilizationThreshold {
+		c.highUtilizationPools.Delete(pool.Name)
+		return
+	}
+	if c.highUtilizationPools.Has(pool.Name) {
+		return
+	}
+	c.highUtilizationPools.Insert(pool.Name)
+	c.recorder.Eventf(pool, corev1.EventTypeWarning, reasonHighIPAMUtilization, "%d of the %.0f IPs of the IPPool have been allocated", allocated, capacity)
+}
+
+func (c *Controller) deleteUtilization(name string) {
+	metrics.IPAMPoolUtilizationRatio.Delete(map[string]string{"pool": name})
+	c.highUtilizationPools.Delete(name)
+}
+
+func (c *Controller) Run(stopCh <-chan struct{}) {
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting IPPool controller")
+	defer klog.Info("Shutting down IPPool controller")
+
+	klog.Info("Waiting for caches to sync for IPPool controller")
+	if !cache.WaitForCacheSync(stopCh, c.ipPoolListerSynced) {
+		klog.Error("Unable to sync caches for IPPool controller")
+		return
+	}
+	klog.Info("Caches are synced for IPPool controller")
+
+	for i := 0; i < defaultWorkers; i++ {
+		go wait.Until(c.worker, time.Second, stopCh)
+	}
+	<-stopCh
+}
+
+// worker is a long-running function that will continually call the processNextWorkItem function
+// in order to read and process a message on the workqueue.
+func (c *Controller) worker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *Controller) processNextWorkItem() bool {
+	obj, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(obj)
+
+	// We expect strings (IPPool name) to come off the workqueue.
+	if key, ok := obj.(string); !ok {
+		c.queue.Forget(obj)
+		klog.Errorf("Expected string in work queue but got %#v", obj)
+		return true
+	} else if err := c.syncIPPool(key); err == nil {
+		c.queue.Forget(key)
+	} else {
+		c.queue.AddRateLimited(key)
+		klog.Errorf("Error syncing IPPool %s, requeuing. Error: %v", key, err)
+	}
+	return true
+}
+
+func (c *Controller) syncIPPool(name string) error {
+	startTime := time.Now()
+	defer func() {
+		klog.V(4).Infof("Finished syncing IPPool %s. (%v)", name, time.Since(startTime))
+	}()
+
+	pool, err := c.ipPoolLister.Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	pools, err := c.ipPoolLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	condition := corev1alpha1.IPPoolCondition{Type: corev1alpha1.IPPoolConditionValid, Status: corev1.ConditionTrue, Reason: reasonValid}
+	if reason, err := validate(pool, pools); err != nil {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = reason
+		condition.Message = err.Error()
+		klog.Warningf("IPPool %s is invalid: %v", name, err)
+	}
+	pool = pool.DeepCopy()
+	if !utilippool.SetCondition(&pool.Status, condition) {
+		return nil
+	}
+	_, err = c.client.CoreV1alpha1().IPPools().Update(context.TODO(), pool, metav1.UpdateOptions{})
+	return err
+}
+
+// validate checks the ranges of the IPPool, and returns the reason and the
+// error if they are invalid. When the ranges of two IPPools overlap, the IPPool
+// created first remains valid, or the one whose name comes first if they were
+// created at the same time. The IPPools whose ranges are not well-formed are
+// ignored.
+func validate(pool *corev1alpha1.IPPool, pools []*corev1alpha1.IPPool) (string, error) {
+	ranges, err := utilippool.ParseRanges(pool)
+	if err != nil {
+		return reasonInvalidRanges, err
+	}
+	for _, other := range pools {
+		if other.Name == pool.Name || !createdBefore(other, pool) {
+			continue
+		}
+		otherRanges, err := utilippool.ParseRanges(other)
+		if err != nil {
+			continue
+		}
+		for _, r := range ranges {
+			for _, otherRange := range otherRanges {
+				if utilippool.Overlaps(r.CIDR, otherRange.CIDR) {
+					return reasonOverlapping, fmt.Errorf("CIDR %s overlaps with CIDR %s of IPPool %s", r.CIDR.String(), otherRange.CIDR.String(), other.Name)
+				}
+			}
+		}
+	}
+	return "", nil
+}
+
+func createdBefore(a, b *corev1alpha1.IPPool) bool {
+	if a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.Name < b.Name
+	}
+	return a.CreationTimestamp.Before(&b.CreationTimestamp)
+}
@@ -0,0 +1,164 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ippool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+
+	corev1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/core/v1alpha1"
+	fakeversioned "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+	crdinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions"
+	"github.com/vmware-tanzu/antrea/pkg/controller/metrics"
+	utilippool "github.com/vmware-tanzu/antrea/pkg/util/ippool"
+)
+
+var created = time.Date(2020, 7, 1, 10, 0, 0, 0, time.UTC)
+
+func newIPPool(name string, createdAfter time.Duration, cidrs ...string) *corev1alpha1.IPPool {
+	pool := &corev1alpha1.IPPool{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created.Add(createdAfter))}}
+	for _, cidr := range cidrs {
+		pool.Spec.Ranges = append(pool.Spec.Ranges, corev1alpha1.IPRange{CIDR: cidr})
+	}
+	return pool
+}
+
+func TestValidate(t *testing.T) {
+	pool1 := newIPPool("pool1", 0, "10.10.0.0/24")
+	pool2 := newIPPool("pool2", time.Minute, "10.10.1.0/24", "10.10.0.128/25")
+	pool3 := newIPPool("pool3", time.Minute, "10.10.2.0/24")
+	pool4 := newIPPool("pool4", 2*time.Minute, "10.10.2.0/25")
+	invalid := newIPPool("invalid", 0, "10.10.3.0/24", "10.10.3.0/25")
+	pools := []*corev1alpha1.IPPool{pool1, pool2, pool3, pool4, invalid}
+
+	for _, tc := range []struct {
+		pool   *corev1alpha1.IPPool
+		reason string
+	}{
+		{pool1, ""},
+		{pool2, reasonOverlapping},
+		{pool3, ""},
+		{pool4, reasonOverlapping},
+		{invalid, reasonInvalidRanges},
+		// The IPPools whose ranges are not well-formed do not reserve their ranges.
+		{newIPPool("pool5", 3*time.Minute, "10.10.3.0/24"), ""},
+		// The IPPools created at the same time are ordered by name.
+		{newIPPool("pool0", time.Minute, "10.10.2.0/24"), ""},
+	} {
+		reason, err := validate(tc.pool, pools)
+		assert.Equal(t, tc.reason, reason, tc.pool.Name)
+		assert.Equal(t, tc.reason != "", err != nil, tc.pool.Name)
+	}
+}
+
+func TestSyncIPPool(t *testing.T) {
+	pool1 := newIPPool("pool1", 0, "10.10.0.0/24")
+	pool2 := newIPPool("pool2", time.Minute, "10.10.0.0/16")
+	client := fakeversioned.NewSimpleClientset(pool1, pool2)
+	informerFactory := crdinformers.NewSharedInformerFactory(client, 0)
+	c := NewIPPoolController(fake.NewSimpleClientset(), client, informerFactory.Core().V1alpha1().IPPools())
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informerFactory.Start(stopCh)
+	require.True(t, cache.WaitForCacheSync(stopCh, c.ipPoolListerSynced))
+
+	getCondition := func(name string) *corev1alpha1.IPPoolCondition {
+		pool, err := client.CoreV1alpha1().IPPools().Get(context.TODO(), name, metav1.GetOptions{})
+		require.NoError(t, err)
+		return utilippool.GetCondition(&pool.Status, corev1alpha1.IPPoolConditionValid)
+	}
+	require.NoError(t, c.syncIPPool("pool1"))
+	require.NoError(t, c.syncIPPool("pool2"))
+	assert.Equal(t, corev1.ConditionTrue, getCondition("pool1").Status)
+	condition := getCondition("pool2")
+	assert.Equal(t, corev1.ConditionFalse, condition.Status)
+	assert.Equal(t, reasonOverlapping, condition.Reason)
+
+	// pool2 becomes valid once pool1 is deleted.
+	require.NoError(t, client.CoreV1alpha1().IPPools().Delete(context.TODO(), "pool1", metav1.DeleteOptions{}))
+	assert.Eventually(t, func() bool {
+		_, err := c.ipPoolLister.Get("pool1")
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return c.syncIPPool("pool2") == nil && getCondition("pool2").Status == corev1.ConditionTrue
+	}, time.Second, 10*time.Millisecond)
+	// Deleted IPPools are ignored.
+	assert.NoError(t, c.syncIPPool("pool1"))
+}
+
+func TestUpdateUtilization(t *testing.T) {
+	// The metrics do not record anything until they are registered.
+	legacyregistry.MustRegister(metrics.IPAMPoolUtilizationRatio)
+	client := fakeversioned.NewSimpleClientset()
+	informerFactory := crdinformers.NewSharedInformerFactory(client, 0)
+	c := NewIPPoolController(fake.NewSimpleClientset(), client, informerFactory.Core().V1alpha1().IPPools())
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+
+	// 5 IPs of the /29 CIDR can be allocated.
+	pool := newIPPool("pool1", 0, "10.10.0.0/29")
+	utilippool.SetCondition(&pool.Status, corev1alpha1.IPPoolCondition{Type: corev1alpha1.IPPoolConditionValid, Status: corev1.ConditionTrue})
+	allocate := func(n int) {
+		pool.Status.Allocations = nil
+		for i := 0; i < n; i++ {
+			pool.Status.Allocations = append(pool.Status.Allocations, corev1alpha1.IPAllocation{ContainerID: fmt.Sprintf("container%d", i)})
+		}
+		c.updateUtilization(pool)
+	}
+	getUtilization := func() float64 {
+		value, err := testutil.GetGaugeMetricValue(metrics.IPAMPoolUtilizationRatio.WithLabelValues("pool1"))
+		require.NoError(t, err)
+		return value
+	}
+
+	for _, tc := range []struct {
+		allocated     int
+		utilization   float64
+		expectedEvent bool
+	}{
+		{2, 0.4, false},
+		{4, 0.8, false},
+		{5, 1, true},
+		// A single event is emitted while the utilization stays high.
+		{5, 1, false},
+		{3, 0.6, false},
+		{5, 1, true},
+	} {
+		allocate(tc.allocated)
+		assert.InDelta(t, tc.utilization, getUtilization(), 1e-9)
+		if tc.expectedEvent {
+			require.Len(t, recorder.Events, 1)
+			assert.Contains(t, <-recorder.Events, reasonHighIPAMUtilization)
+		} else {
+			assert.Empty(t, recorder.Events)
+		}
+	}
+
+	c.deleteUtilization("pool1")
+	assert.False(t, c.highUtilizationPools.Has("pool1"))
+}
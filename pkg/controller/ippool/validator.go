@@ -0,0 +1,109 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ippool
+
+import (
+	"encoding/json"
+	"net"
+
+	admv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8scoreinformers "k8s.io/client-go/informers/core/v1"
+	k8scorelisters "k8s.io/client-go/listers/core/v1"
+
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/webhook"
+	coreinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions/core/v1alpha1"
+	corelisters "github.com/vmware-tanzu/antrea/pkg/client/listers/core/v1alpha1"
+	utilippool "github.com/vmware-tanzu/antrea/pkg/util/ippool"
+)
+
+// StaticIPValidator validates the static IPs requested by the Pods with the
+// ipam.antrea.io/static-ip annotation when they are created. A Pod is rejected
+// if the IP is not in the ranges of a valid IPPool, or if it is already used or
+// requested by another Pod. Whether the IPPool of the IP matches the Node of the
+// Pod can only be checked by antrea-agent once the Pod is scheduled.
+type StaticIPValidator struct {
+	podLister    k8scorelisters.PodLister
+	ipPoolLister corelisters.IPPoolLister
+}
+
+var _ webhook.Validator = new(StaticIPValidator)
+
+// NewStaticIPValidator creates a new StaticIPValidator.
+func NewStaticIPValidator(podInformer k8scoreinformers.PodInformer, ipPoolInformer coreinformers.IPPoolInformer) *StaticIPValidator {
+	return &StaticIPValidator{
+		podLister:    podInformer.Lister(),
+		ipPoolLister: ipPoolInformer.Lister(),
+	}
+}
+
+// Validate validates the static IP of the Pod of the admission request.
+func (v *StaticIPValidator) Validate(request *admv1beta1.AdmissionRequest) *admv1beta1.AdmissionResponse {
+	if request.Kind.Kind != "Pod" || request.Operation != admv1beta1.Create {
+		return webhook.Allow()
+	}
+	var pod corev1.Pod
+	if err := json.Unmarshal(request.Object.Raw, &pod); err != nil {
+		return webhook.Deny("Invalid Pod: %v", err)
+	}
+	value, ok := pod.Annotations[utilippool.StaticIPAnnotationKey]
+	if !ok {
+		return webhook.Allow()
+	}
+	staticIP := net.ParseIP(value)
+	if staticIP == nil {
+		return webhook.Deny("Invalid static IP %s", value)
+	}
+	// The name of the Pod is only known here if it is not generated.
+	namespace, name := request.Namespace, pod.Name
+
+	found := false
+	pools, _ := v.ipPoolLister.List(labels.Everything())
+	for _, pool := range pools {
+		if !utilippool.IsValid(pool) {
+			continue
+		}
+		ranges, err := utilippool.ParseRanges(pool)
+		if err != nil {
+			continue
+		}
+		for i := range ranges {
+			if utilippool.IsAllocatable(&ranges[i], staticIP) {
+				found = true
+				break
+			}
+		}
+		for _, allocation := range pool.Status.Allocations {
+			if allocation.IP == staticIP.String() && (allocation.Namespace != namespace || allocation.Pod != name) {
+				return webhook.Deny("Static IP %s is already allocated to Pod %s/%s", value, allocation.Namespace, allocation.Pod)
+			}
+		}
+	}
+	if !found {
+		return webhook.Deny("Static IP %s is not in the ranges of any valid IPPool", value)
+	}
+
+	pods, _ := v.podLister.List(labels.Everything())
+	for _, other := range pods {
+		if other.Namespace == namespace && other.Name == name {
+			continue
+		}
+		if other.Status.PodIP == staticIP.String() || other.Annotations[utilippool.StaticIPAnnotationKey] == value {
+			return webhook.Deny("Static IP %s is already used by Pod %s/%s", value, other.Namespace, other.Name)
+		}
+	}
+	return webhook.Allow()
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ippool
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	corev1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/core/v1alpha1"
+	fakeversioned "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+	crdinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions"
+	utilippool "github.com/vmware-tanzu/antrea/pkg/util/ippool"
+)
+
+func newStaticIPPod(name, staticIP, podIP string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: name},
+		Status:     corev1.PodStatus{PodIP: podIP},
+	}
+	if staticIP != "" {
+		pod.Annotations = map[string]string{utilippool.StaticIPAnnotationKey: staticIP}
+	}
+	return pod
+}
+
+func newPodRequest(t *testing.T, pod *corev1.Pod) *admv1beta1.AdmissionRequest {
+	raw, err := json.Marshal(pod)
+	require.NoError(t, err)
+	return &admv1beta1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Operation: admv1beta1.Create,
+		Namespace: pod.Namespace,
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+}
+
+func TestStaticIPValidator(t *testing.T) {
+	valid := newIPPool("valid", 0, "10.10.0.0/24")
+	utilippool.SetCondition(&valid.Status, corev1alpha1.IPPoolCondition{Type: corev1alpha1.IPPoolConditionValid, Status: corev1.ConditionTrue})
+	valid.Status.Allocations = []corev1alpha1.IPAllocation{
+		{IP: "10.10.0.10", Namespace: "ns1", Pod: "db", ContainerID: "container1", Node: "node1"},
+	}
+	invalid := newIPPool("invalid", 0, "10.10.1.0/24")
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	k8sClient := fake.NewSimpleClientset(
+		newStaticIPPod("db", "10.10.0.10", ""),
+		newStaticIPPod("web", "", "10.10.0.11"),
+		newStaticIPPod("cache", "10.10.0.12", ""),
+	)
+	crdClient := fakeversioned.NewSimpleClientset(valid, invalid)
+	informerFactory := informers.NewSharedInformerFactory(k8sClient, 0)
+	crdInformerFactory := crdinformers.NewSharedInformerFactory(crdClient, 0)
+	v := NewStaticIPValidator(informerFactory.Core().V1().Pods(), crdInformerFactory.Core().V1alpha1().IPPools())
+	informerFactory.Start(stopCh)
+	crdInformerFactory.Start(stopCh)
+	informerFactory.WaitForCacheSync(stopCh)
+	crdInformerFactory.WaitForCacheSync(stopCh)
+
+	for _, tc := range []struct {
+		name        string
+		pod         *corev1.Pod
+		wantAllowed bool
+	}{
+		{"no annotation", newStaticIPPod("new", "", ""), true},
+		{"free IP", newStaticIPPod("new", "10.10.0.20", ""), true},
+		{"recreated Pod", newStaticIPPod("db", "10.10.0.10", ""), true},
+		{"invalid IP", newStaticIPPod("new", "10.10.0", ""), false},
+		{"gateway", newStaticIPPod("new", "10.10.0.1", ""), false},
+		{"invalid IPPool", newStaticIPPod("new", "10.10.1.20", ""), false},
+		{"allocated IP", newStaticIPPod("new", "10.10.0.10", ""), false},
+		{"IP of running Pod", newStaticIPPod("new", "10.10.0.11", ""), false},
+		{"requested IP", newStaticIPPod("new", "10.10.0.12", ""), false},
+	} {
+		response := v.Validate(newPodRequest(t, tc.pod))
+		assert.Equal(t, tc.wantAllowed, response.Allowed, tc.name)
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	utilipsec "github.com/vmware-tanzu/antrea/pkg/util/ipsec"
+)
+
+const (
+	controllerName = "IPSecKeyRotationController"
+	// Set resyncPeriod to 0 to disable resyncing.
+	resyncPeriod time.Duration = 0
+	// How long to wait before retrying the processing of the Secret.
+	minRetryDelay = 5 * time.Second
+	maxRetryDelay = 300 * time.Second
+
+	// IPSec is not supported on Windows Nodes, whose agents never apply the PSK.
+	osLabelKey     = "kubernetes.io/os"
+	osLabelWindows = "windows"
+)
+
+// KeyRotationController rotates the IPSec PSK stored in the antrea-ipsec Secret.
+// A rotation is started every rotationInterval, or when requested with
+// "antctl rotate-ipsec-key". It moves the Secret through the following phases:
+//   - old_only or new_only: the Secret holds a single PSK, used by all the agents.
+//   - both: a new PSK has been generated and the Secret holds both the previous
+//     and the new PSK. The agents apply the new PSK to authenticate new SAs, while
+//     the SAs established with the previous PSK remain active, so that no packet
+//     is dropped. The phase ends when all the Nodes have acknowledged the new PSK,
+//     after which the previous PSK is removed and the phase becomes new_only.
+type KeyRotationController struct {
+	client             kubernetes.Interface
+	namespace          string
+	rotationInterval   time.Duration
+	secretInformer     cache.SharedIndexInformer
+	secretLister       corelisters.SecretLister
+	secretListerSynced cache.InformerSynced
+	nodeLister         corelisters.NodeLister
+	nodeListerSynced   cache.InformerSynced
+	queue              workqueue.RateLimitingInterface
+	clock              clock.Clock
+}
+
+// NewKeyRotationController creates a new KeyRotationController for the
+// antrea-ipsec Secret in the provided Namespace.
+func NewKeyRotationController(client kubernetes.Interface, nodeInformer coreinformers.NodeInformer, namespace string, rotationInterval time.Duration) *KeyRotationController {
+	return newKeyRotationController(client, nodeInformer, namespace, rotationInterval, clock.RealClock{})
+}
+
+func newKeyRotationController(client kubernetes.Interface, nodeInformer coreinformers.NodeInformer, namespace string, rotationInterval time.Duration, clock clock.Clock) *KeyRotationController {
+	secretInformer := coreinformers.NewFilteredSecretInformer(client, namespace, resyncPeriod, cache.Indexers{}, func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", utilipsec.SecretName).String()
+	})
+	c := &KeyRotationController{
+		client:             client,
+		namespace:          namespace,
+		rotationInterval:   rotationInterval,
+		secretInformer:     secretInformer,
+		secretLister:       corelisters.NewSecretLister(secretInformer.GetIndexer()),
+		secretListerSynced: secretInformer.HasSynced,
+		nodeLister:         nodeInformer.Lister(),
+		nodeListerSynced:   nodeInformer.Informer().HasSynced,
+		queue:              workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "ipseckeyrotation"),
+		clock:              clock,
+	}
+	secretInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.queue.Add(utilipsec.SecretName)
+		},
+		UpdateFunc: func(oldObj, curObj interface{}) {
+			c.queue.Add(utilipsec.SecretName)
+		},
+	})
+	// The Nodes acknowledge the new PSK with an annotation, and a new or deleted
+	// Node may complete the rotation.
+	nodeInformer.Informer().AddEventHandlerWithResyncPeriod(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				c.queue.Add(utilipsec.SecretName)
+			},
+			UpdateFunc: func(oldObj, curObj interface{}) {
+				oldNode := oldObj.(*corev1.Node)
+				curNode := curObj.(*corev1.Node)
+				if utilipsec.GetKeyGeneration(oldNode.Annotations) != utilipsec.GetKeyGeneration(curNode.Annotations) {
+					c.queue.Add(utilipsec.SecretName)
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				c.queue.Add(utilipsec.SecretName)
+			},
+		},
+		resyncPeriod,
+	)
+	return c
+}
+
+func (c *KeyRotationController) Run(stopCh <-chan struct{}) {
+	defer c.queue.ShutDown()
+
+	klog.Infof("Starting %s", controllerName)
+	defer klog.Infof("Shutting down %s", controllerName)
+
+	go c.secretInformer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, c.secretListerSynced, c.nodeListerSynced) {
+		klog.Errorf("Unable to sync caches for %s", controllerName)
+		return
+	}
+
+	// There is a single Secret, a single worker is used.
+	go wait.Until(c.worker, time.Second, stopCh)
+	<-stopCh
+}
+
+func (c *KeyRotationController) worker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *KeyRotationController) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.syncSecret(); err == nil {
+		c.queue.Forget(key)
+	} else {
+		c.queue.AddRateLimited(key)
+		klog.Errorf("Error syncing IPSec Secret %s, requeuing. Error: %v", key, err)
+	}
+	return true
+}
+
+func (c *KeyRotationController) syncSecret() error {
+	secret, err := c.secretLister.Secrets(c.namespace).Get(utilipsec.SecretName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if utilipsec.GetRotationPhase(secret.Annotations) == utilipsec.RotationPhaseBoth {
+		return c.completeRotation(secret)
+	}
+	if _, requested := secret.Annotations[utilipsec.RotationRequestedAnnotationKey]; !requested {
+		nextRotationTime := c.lastRotationTime(secret).Add(c.rotationInterval)
+		if now := c.clock.Now(); now.Before(nextRotationTime) {
+			c.queue.AddAfter(utilipsec.SecretName, nextRotationTime.Sub(now))
+			return nil
+		}
+	}
+	return c.startRotation(secret)
+}
+
+// lastRotationTime returns the time the last rotation started, or the creation
+// time of the Secret if it has never been rotated.
+func (c *KeyRotationController) lastRotationTime(secret *corev1.Secret) time.Time {
+	if t, err := time.Parse(time.RFC3339, secret.Annotations[utilipsec.LastRotationTimeAnnotationKey]); err == nil {
+		return t
+	}
+	return secret.CreationTimestamp.Time
+}
+
+// startRotation generates a new PSK and moves the Secret to the both phase.
+func (c *KeyRotationController) startRotation(secret *corev1.Secret) error {
+	psk, err := utilipsec.GeneratePSK()
+	if err != nil {
+		return err
+	}
+	generation := utilipsec.GetKeyGeneration(secret.Annotations) + 1
+	secret = secret.DeepCopy()
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Data[utilipsec.PreviousPSKKey] = secret.Data[utilipsec.PSKKey]
+	secret.Data[utilipsec.PSKKey] = []byte(psk)
+	secret.Annotations[utilipsec.RotationPhaseAnnotationKey] = string(utilipsec.RotationPhaseBoth)
+	secret.Annotations[utilipsec.KeyGenerationAnnotationKey] = strconv.FormatInt(generation, 10)
+	secret.Annotations[utilipsec.LastRotationTimeAnnotationKey] = c.clock.Now().UTC().Format(time.RFC3339)
+	delete(secret.Annotations, utilipsec.RotationRequestedAnnotationKey)
+	if _, err := c.client.CoreV1().Secrets(c.namespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	klog.Infof("Started rotation of IPSec PSK to generation %d", generation)
+	return nil
+}
+
+// completeRotation removes the previous PSK from the Secret and moves it to the
+// new_only phase once all the Nodes have acknowledged the new PSK.
+func (c *KeyRotationController) completeRotation(secret *corev1.Secret) error {
+	generation := utilipsec.GetKeyGeneration(secret.Annotations)
+	nodes, err := c.nodeLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if node.Labels[osLabelKey] == osLabelWindows {
+			continue
+		}
+		if utilipsec.GetKeyGeneration(node.Annotations) < generation {
+			klog.V(2).Infof("Waiting for Node %s to apply IPSec PSK of generation %d", node.Name, generation)
+			return nil
+		}
+	}
+	secret = secret.DeepCopy()
+	delete(secret.Data, utilipsec.PreviousPSKKey)
+	secret.Annotations[utilipsec.RotationPhaseAnnotationKey] = string(utilipsec.RotationPhaseNewOnly)
+	if _, err := c.client.CoreV1().Secrets(c.namespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	klog.Infof("Completed rotation of IPSec PSK to generation %d", generation)
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	utilipsec "github.com/vmware-tanzu/antrea/pkg/util/ipsec"
+)
+
+const (
+	testNamespace        = "kube-system"
+	testRotationInterval = 7 * 24 * time.Hour
+)
+
+func newNode(name string, generation string, labels map[string]string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      labels,
+			Annotations: map[string]string{utilipsec.KeyGenerationAnnotationKey: generation},
+		},
+	}
+}
+
+type keyRotationControllerTest struct {
+	*KeyRotationController
+	client   *fake.Clientset
+	informer informers.SharedInformerFactory
+}
+
+func newTestController(t *testing.T, fakeClock clock.Clock, secret *corev1.Secret, nodes ...*corev1.Node) *keyRotationControllerTest {
+	client := fake.NewSimpleClientset(secret)
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	c := newKeyRotationController(client, informerFactory.Core().V1().Nodes(), testNamespace, testRotationInterval, fakeClock)
+	for _, node := range nodes {
+		require.NoError(t, informerFactory.Core().V1().Nodes().Informer().GetIndexer().Add(node))
+	}
+	require.NoError(t, c.secretInformer.GetIndexer().Add(secret))
+	return &keyRotationControllerTest{KeyRotationController: c, client: client, informer: informerFactory}
+}
+
+// sync runs syncSecret and updates the Secret in the informer cache with the
+// result.
+func (c *keyRotationControllerTest) sync(t *testing.T) *corev1.Secret {
+	require.NoError(t, c.syncSecret())
+	secret, err := c.client.CoreV1().Secrets(testNamespace).Get(context.TODO(), utilipsec.SecretName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NoError(t, c.secretInformer.GetIndexer().Update(secret))
+	return secret
+}
+
+func TestKeyRotation(t *testing.T) {
+	creationTime := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := clock.NewFakeClock(creationTime.Add(time.Hour))
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              utilipsec.SecretName,
+			Namespace:         testNamespace,
+			CreationTimestamp: metav1.NewTime(creationTime),
+		},
+		Data: map[string][]byte{utilipsec.PSKKey: []byte("changeme")},
+	}
+	windowsNode := newNode("node3", "", map[string]string{osLabelKey: osLabelWindows})
+	c := newTestController(t, fakeClock, secret, newNode("node1", "", nil), newNode("node2", "", nil), windowsNode)
+
+	// No rotation before the rotation interval has elapsed.
+	secret = c.sync(t)
+	assert.Equal(t, "changeme", string(secret.Data[utilipsec.PSKKey]))
+	assert.Equal(t, utilipsec.RotationPhaseOldOnly, utilipsec.GetRotationPhase(secret.Annotations))
+
+	// The rotation starts once the rotation interval has elapsed.
+	fakeClock.Step(testRotationInterval)
+	secret = c.sync(t)
+	assert.Equal(t, utilipsec.RotationPhaseBoth, utilipsec.GetRotationPhase(secret.Annotations))
+	assert.Equal(t, int64(1), utilipsec.GetKeyGeneration(secret.Annotations))
+	assert.Equal(t, "changeme", string(secret.Data[utilipsec.PreviousPSKKey]))
+	newPSK := string(secret.Data[utilipsec.PSKKey])
+	assert.NotEqual(t, "changeme", newPSK)
+
+	// The rotation is not completed until all the Nodes have acknowledged the new PSK.
+	nodeIndexer := c.informer.Core().V1().Nodes().Informer().GetIndexer()
+	require.NoError(t, nodeIndexer.Update(newNode("node1", "1", nil)))
+	secret = c.sync(t)
+	assert.Equal(t, utilipsec.RotationPhaseBoth, utilipsec.GetRotationPhase(secret.Annotations))
+
+	// Windows Nodes are ignored.
+	require.NoError(t, nodeIndexer.Update(newNode("node2", "1", nil)))
+	secret = c.sync(t)
+	assert.Equal(t, utilipsec.RotationPhaseNewOnly, utilipsec.GetRotationPhase(secret.Annotations))
+	assert.Equal(t, newPSK, string(secret.Data[utilipsec.PSKKey]))
+	assert.NotContains(t, secret.Data, utilipsec.PreviousPSKKey)
+
+	// A manual rotation starts immediately.
+	secret.Annotations[utilipsec.RotationRequestedAnnotationKey] = "true"
+	require.NoError(t, c.secretInformer.GetIndexer().Update(secret))
+	secret = c.sync(t)
+	assert.Equal(t, utilipsec.RotationPhaseBoth, utilipsec.GetRotationPhase(secret.Annotations))
+	assert.Equal(t, int64(2), utilipsec.GetKeyGeneration(secret.Annotations))
+	assert.Equal(t, newPSK, string(secret.Data[utilipsec.PreviousPSKKey]))
+	assert.NotContains(t, secret.Annotations, utilipsec.RotationRequestedAnnotationKey)
+}
@@ -0,0 +1,212 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	clientset "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
+	opsinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions/ops/v1alpha1"
+	opslisters "github.com/vmware-tanzu/antrea/pkg/client/listers/ops/v1alpha1"
+)
+
+const (
+	statusAggregatorName = "IPSecStatusAggregator"
+	// ClusterIPSecStatusName is the name of the single ClusterIPSecStatus.
+	ClusterIPSecStatusName = "cluster"
+	// reasonTunnelFailed is the reason of the Node events emitted when an
+	// IPSec tunnel of the Node enters the failed state.
+	reasonTunnelFailed = "IPSecTunnelFailed"
+)
+
+// StatusAggregator aggregates the IPSecTunnelStatuses reported by the agents
+// into the ClusterIPSecStatus, and emits a Warning event on the reporting Node
+// when one of its tunnels enters the failed state.
+type StatusAggregator struct {
+	crdClient                clientset.Interface
+	tunnelStatusLister       opslisters.IPSecTunnelStatusLister
+	tunnelStatusListerSynced cache.InformerSynced
+	recorder                 record.EventRecorder
+	queue                    workqueue.RateLimitingInterface
+}
+
+// NewStatusAggregator creates a new StatusAggregator.
+func NewStatusAggregator(client kubernetes.Interface, crdClient clientset.Interface, tunnelStatusInformer opsinformers.IPSecTunnelStatusInformer) *StatusAggregator {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "antrea-controller"})
+	return newStatusAggregator(crdClient, tunnelStatusInformer, recorder)
+}
+
+func newStatusAggregator(crdClient clientset.Interface, tunnelStatusInformer opsinformers.IPSecTunnelStatusInformer, recorder record.EventRecorder) *StatusAggregator {
+	a := &StatusAggregator{
+		crdClient:                crdClient,
+		tunnelStatusLister:       tunnelStatusInformer.Lister(),
+		tunnelStatusListerSynced: tunnelStatusInformer.Informer().HasSynced,
+		recorder:                 recorder,
+		queue:                    workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "ipsecstatus"),
+	}
+	tunnelStatusInformer.Informer().AddEventHandlerWithResyncPeriod(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				a.queue.Add(ClusterIPSecStatusName)
+			},
+			UpdateFunc: func(oldObj, curObj interface{}) {
+				oldStatus := oldObj.(*opsv1alpha1.IPSecTunnelStatus)
+				curStatus := curObj.(*opsv1alpha1.IPSecTunnelStatus)
+				// The event is only emitted on the transition, so that
+				// the counter updates of a failed tunnel don't repeat it.
+				if curStatus.State == opsv1alpha1.IPSecTunnelFailed && oldStatus.State != opsv1alpha1.IPSecTunnelFailed {
+					a.recordTunnelFailure(curStatus)
+				}
+				if curStatus.State != oldStatus.State {
+					a.queue.Add(ClusterIPSecStatusName)
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				a.queue.Add(ClusterIPSecStatusName)
+			},
+		},
+		resyncPeriod,
+	)
+	return a
+}
+
+func (a *StatusAggregator) recordTunnelFailure(status *opsv1alpha1.IPSecTunnelStatus) {
+	// Events are attached to the Node the same way as kubelet does.
+	nodeRef := &corev1.ObjectReference{
+		Kind: "Node",
+		Name: status.LocalNode,
+		UID:  types.UID(status.LocalNode),
+	}
+	a.recorder.Eventf(nodeRef, corev1.EventTypeWarning, reasonTunnelFailed,
+		"The IPSec tunnel from Node %s to Node %s failed", status.LocalNode, status.RemoteNode)
+}
+
+func (a *StatusAggregator) Run(stopCh <-chan struct{}) {
+	defer a.queue.ShutDown()
+
+	klog.Infof("Starting %s", statusAggregatorName)
+	defer klog.Infof("Shutting down %s", statusAggregatorName)
+
+	if !cache.WaitForCacheSync(stopCh, a.tunnelStatusListerSynced) {
+		klog.Errorf("Unable to sync caches for %s", statusAggregatorName)
+		return
+	}
+
+	// There is a single ClusterIPSecStatus, a single worker is used.
+	go wait.Until(a.worker, time.Second, stopCh)
+	<-stopCh
+}
+
+func (a *StatusAggregator) worker() {
+	for a.processNextWorkItem() {
+	}
+}
+
+func (a *StatusAggregator) processNextWorkItem() bool {
+	key, quit := a.queue.Get()
+	if quit {
+		return false
+	}
+	defer a.queue.Done(key)
+
+	if err := a.syncClusterStatus(); err == nil {
+		a.queue.Forget(key)
+	} else {
+		a.queue.AddRateLimited(key)
+		klog.Errorf("Error syncing ClusterIPSecStatus %s, requeuing. Error: %v", key, err)
+	}
+	return true
+}
+
+// aggregate computes the ClusterIPSecStatus from the IPSecTunnelStatuses.
+func aggregate(statuses []*opsv1alpha1.IPSecTunnelStatus) *opsv1alpha1.ClusterIPSecStatus {
+	cluster := &opsv1alpha1.ClusterIPSecStatus{
+		ObjectMeta: metav1.ObjectMeta{Name: ClusterIPSecStatusName},
+		Tunnels:    int32(len(statuses)),
+	}
+	for _, s := range statuses {
+		switch s.State {
+		case opsv1alpha1.IPSecTunnelEstablished:
+			cluster.EstablishedTunnels++
+		case opsv1alpha1.IPSecTunnelInitializing:
+			cluster.InitializingTunnels++
+		case opsv1alpha1.IPSecTunnelFailed:
+			cluster.FailedTunnels++
+			cluster.FailedTunnelNames = append(cluster.FailedTunnelNames, s.Name)
+		}
+	}
+	sort.Strings(cluster.FailedTunnelNames)
+	return cluster
+}
+
+func (a *StatusAggregator) syncClusterStatus() error {
+	statuses, err := a.tunnelStatusLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	client := a.crdClient.OpsV1alpha1().ClusterIPSecStatuses()
+	existing, err := client.Get(context.TODO(), ClusterIPSecStatusName, metav1.GetOptions{})
+	found := true
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return err
+		}
+		found = false
+	}
+	// The ClusterIPSecStatus is only maintained when IPSec is used, i.e.
+	// when at least one agent reports its tunnels.
+	if len(statuses) == 0 {
+		if !found {
+			return nil
+		}
+		if err := client.Delete(context.TODO(), ClusterIPSecStatusName, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	cluster := aggregate(statuses)
+	if !found {
+		_, err = client.Create(context.TODO(), cluster, metav1.CreateOptions{})
+		return err
+	}
+	if existing.Tunnels == cluster.Tunnels && existing.EstablishedTunnels == cluster.EstablishedTunnels &&
+		existing.InitializingTunnels == cluster.InitializingTunnels && existing.FailedTunnels == cluster.FailedTunnels &&
+		reflect.DeepEqual(existing.FailedTunnelNames, cluster.FailedTunnelNames) {
+		return nil
+	}
+	cluster.ResourceVersion = existing.ResourceVersion
+	_, err = client.Update(context.TODO(), cluster, metav1.UpdateOptions{})
+	return err
+}
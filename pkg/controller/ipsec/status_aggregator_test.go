@@ -0,0 +1,118 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	fakeversioned "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+	crdinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions"
+)
+
+func newTunnelStatus(localNode, remoteNode string, state opsv1alpha1.IPSecTunnelState) *opsv1alpha1.IPSecTunnelStatus {
+	return &opsv1alpha1.IPSecTunnelStatus{
+		ObjectMeta: metav1.ObjectMeta{Name: localNode + "." + remoteNode},
+		LocalNode:  localNode,
+		RemoteNode: remoteNode,
+		State:      state,
+	}
+}
+
+type statusAggregatorTest struct {
+	*StatusAggregator
+	crdClient *fakeversioned.Clientset
+	indexer   cache.Indexer
+	recorder  *record.FakeRecorder
+}
+
+func newTestAggregator(t *testing.T, statuses ...*opsv1alpha1.IPSecTunnelStatus) *statusAggregatorTest {
+	crdClient := fakeversioned.NewSimpleClientset()
+	informerFactory := crdinformers.NewSharedInformerFactory(crdClient, 0)
+	tunnelStatusInformer := informerFactory.Ops().V1alpha1().IPSecTunnelStatuses()
+	recorder := record.NewFakeRecorder(10)
+	a := newStatusAggregator(crdClient, tunnelStatusInformer, recorder)
+	indexer := tunnelStatusInformer.Informer().GetIndexer()
+	for _, s := range statuses {
+		require.NoError(t, indexer.Add(s))
+	}
+	return &statusAggregatorTest{StatusAggregator: a, crdClient: crdClient, indexer: indexer, recorder: recorder}
+}
+
+func (a *statusAggregatorTest) getClusterStatus(t *testing.T) *opsv1alpha1.ClusterIPSecStatus {
+	cluster, err := a.crdClient.OpsV1alpha1().ClusterIPSecStatuses().Get(context.TODO(), ClusterIPSecStatusName, metav1.GetOptions{})
+	require.NoError(t, err)
+	return cluster
+}
+
+func TestAggregate(t *testing.T) {
+	cluster := aggregate([]*opsv1alpha1.IPSecTunnelStatus{
+		newTunnelStatus("node1", "node2", opsv1alpha1.IPSecTunnelEstablished),
+		newTunnelStatus("node2", "node1", opsv1alpha1.IPSecTunnelEstablished),
+		newTunnelStatus("node3", "node1", opsv1alpha1.IPSecTunnelFailed),
+		newTunnelStatus("node1", "node3", opsv1alpha1.IPSecTunnelFailed),
+		newTunnelStatus("node3", "node2", opsv1alpha1.IPSecTunnelInitializing),
+	})
+	assert.Equal(t, ClusterIPSecStatusName, cluster.Name)
+	assert.Equal(t, int32(5), cluster.Tunnels)
+	assert.Equal(t, int32(2), cluster.EstablishedTunnels)
+	assert.Equal(t, int32(1), cluster.InitializingTunnels)
+	assert.Equal(t, int32(2), cluster.FailedTunnels)
+	assert.Equal(t, []string{"node1.node3", "node3.node1"}, cluster.FailedTunnelNames)
+}
+
+func TestSyncClusterStatus(t *testing.T) {
+	a := newTestAggregator(t)
+	// No ClusterIPSecStatus is created when there is no tunnel.
+	require.NoError(t, a.syncClusterStatus())
+	_, err := a.crdClient.OpsV1alpha1().ClusterIPSecStatuses().Get(context.TODO(), ClusterIPSecStatusName, metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	tunnel := newTunnelStatus("node1", "node2", opsv1alpha1.IPSecTunnelEstablished)
+	require.NoError(t, a.indexer.Add(tunnel))
+	require.NoError(t, a.syncClusterStatus())
+	cluster := a.getClusterStatus(t)
+	assert.Equal(t, int32(1), cluster.Tunnels)
+	assert.Equal(t, int32(1), cluster.EstablishedTunnels)
+
+	failedTunnel := newTunnelStatus("node1", "node2", opsv1alpha1.IPSecTunnelFailed)
+	require.NoError(t, a.indexer.Update(failedTunnel))
+	require.NoError(t, a.syncClusterStatus())
+	cluster = a.getClusterStatus(t)
+	assert.Equal(t, int32(0), cluster.EstablishedTunnels)
+	assert.Equal(t, int32(1), cluster.FailedTunnels)
+	assert.Equal(t, []string{"node1.node2"}, cluster.FailedTunnelNames)
+
+	// The ClusterIPSecStatus is deleted with the last tunnel.
+	require.NoError(t, a.indexer.Delete(failedTunnel))
+	require.NoError(t, a.syncClusterStatus())
+	_, err = a.crdClient.OpsV1alpha1().ClusterIPSecStatuses().Get(context.TODO(), ClusterIPSecStatusName, metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+}
+
+func TestRecordTunnelFailure(t *testing.T) {
+	a := newTestAggregator(t)
+	a.recordTunnelFailure(newTunnelStatus("node1", "node2", opsv1alpha1.IPSecTunnelFailed))
+	require.Len(t, a.recorder.Events, 1)
+	assert.Equal(t, "Warning IPSecTunnelFailed The IPSec tunnel from Node node1 to Node node2 failed", <-a.recorder.Events)
+}
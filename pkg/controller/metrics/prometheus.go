@@ -68,6 +68,16 @@ var (
 		Help:           "The length of InternalNetworkPolicyQueue",
 		StabilityLevel: metrics.STABLE,
 	})
+	ReconcileQueueDepth = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Name:           "antrea_controller_reconcile_queue_depth",
+		Help:           "The number of events delayed by the rate limit of their type before triggering reconciliations. The event type is used as a label.",
+		StabilityLevel: metrics.STABLE,
+	}, []string{"event_type"})
+	IPAMPoolUtilizationRatio = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Name:           "antrea_controller_ipam_pool_utilization_ratio",
+		Help:           "The ratio of the allocated IPs to the IPs which can be allocated for each IPPool. The IPPool is used as a label.",
+		StabilityLevel: metrics.STABLE,
+	}, []string{"pool"})
 )
 
 // Initialize Prometheus metrics collection.
@@ -117,4 +127,10 @@ func InitializePrometheusMetrics() {
 	if err := legacyregistry.Register(LengthInternalNetworkPolicyQueue); err != nil {
 		klog.Errorf("Failed to register antrea_controller_length_network_policy_queue with Prometheus: %s", err.Error())
 	}
+	if err := legacyregistry.Register(ReconcileQueueDepth); err != nil {
+		klog.Errorf("Failed to register antrea_controller_reconcile_queue_depth with Prometheus: %s", err.Error())
+	}
+	if err := legacyregistry.Register(IPAMPoolUtilizationRatio); err != nil {
+		klog.Errorf("Failed to register antrea_controller_ipam_pool_utilization_ratio with Prometheus: %s", err.Error())
+	}
 }
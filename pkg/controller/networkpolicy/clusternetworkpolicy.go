@@ -17,6 +17,7 @@ package networkpolicy
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog"
 
@@ -45,7 +46,9 @@ func (n *NetworkPolicyController) addCNP(obj interface{}) {
 	klog.Infof("Creating new internal NetworkPolicy %#v", internalNP)
 	n.internalNetworkPolicyStore.Create(internalNP)
 	key, _ := keyFunc(cnp)
-	n.enqueueInternalNetworkPolicy(key)
+	n.dispatchEvent(n.networkPolicyEventLimiter, n.cnpListerSynced, func() {
+		n.enqueueInternalNetworkPolicy(key)
+	})
 }
 
 // updateCNP receives ClusterNetworkPolicy UPDATE events and updates resources
@@ -75,16 +78,18 @@ func (n *NetworkPolicyController) updateCNP(old, cur interface{}) {
 	n.internalNetworkPolicyStore.Update(curInternalNP)
 	// Unlock the internal NetworkPolicy store.
 	n.internalNetworkPolicyMutex.Unlock()
-	// Enqueue addressGroup keys to update their Node span.
-	for _, rule := range curInternalNP.Rules {
-		for _, addrGroupName := range rule.From.AddressGroups {
-			n.enqueueAddressGroup(addrGroupName)
-		}
-		for _, addrGroupName := range rule.To.AddressGroups {
-			n.enqueueAddressGroup(addrGroupName)
+	n.dispatchEvent(n.networkPolicyEventLimiter, n.cnpListerSynced, func() {
+		// Enqueue addressGroup keys to update their Node span.
+		for _, rule := range curInternalNP.Rules {
+			for _, addrGroupName := range rule.From.AddressGroups {
+				n.enqueueAddressGroup(addrGroupName)
+			}
+			for _, addrGroupName := range rule.To.AddressGroups {
+				n.enqueueAddressGroup(addrGroupName)
+			}
 		}
-	}
-	n.enqueueInternalNetworkPolicy(key)
+		n.enqueueInternalNetworkPolicy(key)
+	})
 	for _, atg := range oldInternalNP.AppliedToGroups {
 		// Delete the old AppliedToGroup object if it is not referenced
 		// by any internal NetworkPolicy.
@@ -112,6 +117,7 @@ func (n *NetworkPolicyController) deleteCNP(old interface{}) {
 	defer n.heartbeat("deleteCNP")
 	klog.Infof("Processing ClusterNetworkPolicy %s DELETE event", cnp.Name)
 	key, _ := keyFunc(cnp)
+	n.fqdnResolver.setPolicyFQDNs(key, sets.NewString())
 	oldInternalNPObj, _, _ := n.internalNetworkPolicyStore.Get(key)
 	oldInternalNP := oldInternalNPObj.(*antreatypes.NetworkPolicy)
 	klog.Infof("Old internal NetworkPolicy %#v", oldInternalNP)
@@ -140,6 +146,34 @@ func toAntreaServicesForCRD(npPorts []secv1alpha1.NetworkPolicyPort) []networkin
 	return antreaServices
 }
 
+// toAntreaHTTPMatchesForCRD converts a slice of secv1alpha1.HTTPMatch objects
+// to a slice of Antrea HTTPMatch objects.
+func toAntreaHTTPMatchesForCRD(httpMatches []secv1alpha1.HTTPMatch) []networking.HTTPMatch {
+	var antreaHTTPMatches []networking.HTTPMatch
+	for _, httpMatch := range httpMatches {
+		antreaHTTPMatches = append(antreaHTTPMatches, networking.HTTPMatch{
+			Method:  httpMatch.Method,
+			Path:    httpMatch.Path,
+			Headers: httpMatch.Headers,
+		})
+	}
+	return antreaHTTPMatches
+}
+
+// toAntreaBandwidthForCRD converts a secv1alpha1.Bandwidth to an Antrea
+// Bandwidth. The burst size defaults to a tenth of the rate when it is unset.
+func toAntreaBandwidthForCRD(bandwidth *secv1alpha1.Bandwidth) *networking.Bandwidth {
+	if bandwidth == nil {
+		return nil
+	}
+	rate := bandwidth.Rate.Value()
+	burst := rate / 10
+	if bandwidth.Burst != nil {
+		burst = bandwidth.Burst.Value()
+	}
+	return &networking.Bandwidth{Rate: rate, Burst: burst}
+}
+
 // toAntreaIPBlockForCRD converts a secv1alpha1.IPBlock to an Antrea IPBlock.
 func toAntreaIPBlockForCRD(ipBlock *secv1alpha1.IPBlock) (*networking.IPBlock, error) {
 	// Convert the allowed IPBlock to networkpolicy.IPNet.
@@ -186,6 +220,27 @@ func (n *NetworkPolicyController) toAntreaPeerForCRD(peers []secv1alpha1.Network
 				continue
 			}
 			ipBlocks = append(ipBlocks, *ipBlock)
+		} else if peer.FQDN != "" {
+			if dir == networking.DirectionIn {
+				klog.Errorf("Failure processing ClusterNetworkPolicy %s: FQDN %s is only supported in egress rules", cnp.Name, peer.FQDN)
+				continue
+			}
+			// The FQDN is matched by its last-known IPs, which are updated by the
+			// fqdnResolver.
+			ipBlocks = append(ipBlocks, n.fqdnResolver.getIPBlocks(normalizeFQDN(peer.FQDN))...)
+		} else if peer.NodeSelector != nil {
+			if dir == networking.DirectionOut {
+				klog.Errorf("Failure processing ClusterNetworkPolicy %s: nodeSelector is only supported in ingress rules", cnp.Name)
+				continue
+			}
+			// The Nodes are matched by their current IPs, which are updated when
+			// the Nodes change.
+			nodeIPBlocks, err := n.getNodeIPBlocks(peer.NodeSelector)
+			if err != nil {
+				klog.Errorf("Failure processing ClusterNetworkPolicy %s nodeSelector %v: %v", cnp.Name, peer.NodeSelector, err)
+				continue
+			}
+			ipBlocks = append(ipBlocks, nodeIPBlocks...)
 		} else {
 			normalizedUID := n.createAddressGroupForCRD(peer, cnp)
 			addressGroups = append(addressGroups, normalizedUID)
@@ -224,6 +279,10 @@ func (n *NetworkPolicyController) createAddressGroupForCRD(peer secv1alpha1.Netw
 // in case of ADD event or modified and store the updated instance, in case
 // of an UPDATE event.
 func (n *NetworkPolicyController) processClusterNetworkPolicy(cnp *secv1alpha1.ClusterNetworkPolicy) *antreatypes.NetworkPolicy {
+	// Record the FQDNs referenced by the egress rules first, so that they get resolved
+	// and the ClusterNetworkPolicy is processed again once their IPs are known.
+	key, _ := keyFunc(cnp)
+	n.fqdnResolver.setPolicyFQDNs(key, getEgressFQDNs(cnp))
 	appliedToGroupNames := make([]string, 0, len(cnp.Spec.AppliedTo))
 	// Create AppliedToGroup for each AppliedTo present in
 	// ClusterNetworkPolicy spec.
@@ -235,31 +294,81 @@ func (n *NetworkPolicyController) processClusterNetworkPolicy(cnp *secv1alpha1.C
 	for idx, ingressRule := range cnp.Spec.Ingress {
 		// Set default action to ALLOW to allow traffic.
 		rules = append(rules, networking.NetworkPolicyRule{
-			Direction: networking.DirectionIn,
-			From:      *n.toAntreaPeerForCRD(ingressRule.From, cnp, networking.DirectionIn),
-			Services:  toAntreaServicesForCRD(ingressRule.Ports),
-			Action:    ingressRule.Action,
-			Priority:  int32(idx),
+			Direction:     networking.DirectionIn,
+			From:          *n.toAntreaPeerForCRD(ingressRule.From, cnp, networking.DirectionIn),
+			Services:      toAntreaServicesForCRD(ingressRule.Ports),
+			Action:        ingressRule.Action,
+			Priority:      int32(idx),
+			EnableLogging: ingressRule.EnableLogging,
+			HTTPMatches:   toAntreaHTTPMatchesForCRD(ingressRule.HTTPMatches),
+			Bandwidth:     toAntreaBandwidthForCRD(ingressRule.Bandwidth),
 		})
 	}
 	// Compute NetworkPolicyRule for Egress Rule.
 	for idx, egressRule := range cnp.Spec.Egress {
 		// Set default action to ALLOW to allow traffic.
 		rules = append(rules, networking.NetworkPolicyRule{
-			Direction: networking.DirectionOut,
-			To:        *n.toAntreaPeerForCRD(egressRule.To, cnp, networking.DirectionOut),
-			Services:  toAntreaServicesForCRD(egressRule.Ports),
-			Action:    egressRule.Action,
-			Priority:  int32(idx),
+			Direction:        networking.DirectionOut,
+			To:               *n.toAntreaPeerForCRD(egressRule.To, cnp, networking.DirectionOut),
+			Services:         toAntreaServicesForCRD(egressRule.Ports),
+			Action:           egressRule.Action,
+			Priority:         int32(idx),
+			EnableLogging:    egressRule.EnableLogging,
+			HTTPMatches:      toAntreaHTTPMatchesForCRD(egressRule.HTTPMatches),
+			Bandwidth:        toAntreaBandwidthForCRD(egressRule.Bandwidth),
+			PreserveSourceIP: egressRule.PreserveSourceIP,
 		})
 	}
+	tierPriority := getTierPriority(cnp.Spec.Tier)
 	internalNetworkPolicy := &antreatypes.NetworkPolicy{
-		Name:            cnp.Name,
-		Namespace:       "",
-		UID:             cnp.UID,
-		AppliedToGroups: appliedToGroupNames,
-		Rules:           rules,
-		Priority:        &cnp.Spec.Priority,
+		Name:              cnp.Name,
+		Namespace:         "",
+		UID:               cnp.UID,
+		CreationTimestamp: cnp.CreationTimestamp,
+		AppliedToGroups:   appliedToGroupNames,
+		Rules:             rules,
+		Priority:          &cnp.Spec.Priority,
+		TierPriority:      &tierPriority,
 	}
 	return internalNetworkPolicy
 }
+
+// getTierPriority returns the TierPriority of the NetworkPolicies of the
+// provided tier.
+func getTierPriority(tier string) int32 {
+	if tier == secv1alpha1.TierBaseline {
+		return networking.BaselineTierPriority
+	}
+	return networking.DefaultTierPriority
+}
+
+// getEgressFQDNs returns the normalized FQDNs referenced by the egress rules of the
+// ClusterNetworkPolicy.
+func getEgressFQDNs(cnp *secv1alpha1.ClusterNetworkPolicy) sets.String {
+	fqdns := sets.NewString()
+	for _, egressRule := range cnp.Spec.Egress {
+		for _, peer := range egressRule.To {
+			if peer.FQDN != "" {
+				fqdns.Insert(normalizeFQDN(peer.FQDN))
+			}
+		}
+	}
+	return fqdns
+}
+
+// onFQDNUpdate processes the ClusterNetworkPolicies referencing the FQDN again after its
+// IPs changed, so that the agents receive the updated IPBlocks.
+func (n *NetworkPolicyController) onFQDNUpdate(fqdn string) {
+	for _, key := range n.fqdnResolver.getPolicies(fqdn) {
+		cnp, err := n.cnpLister.Get(key)
+		if err != nil {
+			// The ClusterNetworkPolicy has been deleted.
+			continue
+		}
+		if _, exists, _ := n.internalNetworkPolicyStore.Get(key); !exists {
+			continue
+		}
+		klog.V(2).Infof("Processing ClusterNetworkPolicy %s after IPs of FQDN %s changed", key, fqdn)
+		n.updateCNP(cnp, cnp)
+	}
+}
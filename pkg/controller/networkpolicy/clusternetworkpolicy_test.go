@@ -19,9 +19,11 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -60,6 +62,36 @@ func TestToAntreaServicesForCRD(t *testing.T) {
 	}
 }
 
+func TestToAntreaBandwidthForCRD(t *testing.T) {
+	burst := resource.MustParse("2M")
+	tests := []struct {
+		name      string
+		bandwidth *secv1alpha1.Bandwidth
+		expected  *networking.Bandwidth
+	}{
+		{
+			name:      "no-bandwidth",
+			bandwidth: nil,
+			expected:  nil,
+		},
+		{
+			name:      "default-burst",
+			bandwidth: &secv1alpha1.Bandwidth{Rate: resource.MustParse("100M")},
+			expected:  &networking.Bandwidth{Rate: 100000000, Burst: 10000000},
+		},
+		{
+			name:      "explicit-burst",
+			bandwidth: &secv1alpha1.Bandwidth{Rate: resource.MustParse("1Gi"), Burst: &burst},
+			expected:  &networking.Bandwidth{Rate: 1073741824, Burst: 2000000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, toAntreaBandwidthForCRD(tt.bandwidth))
+		})
+	}
+}
+
 func TestToAntreaIPBlockForCRD(t *testing.T) {
 	expIPNet := networking.IPNet{
 		IP:           ipStrToIPAddress("10.0.0.0"),
@@ -232,7 +264,9 @@ func TestToAntreaPeerForCRD(t *testing.T) {
 
 func TestProcessClusterNetworkPolicy(t *testing.T) {
 	p10 := float64(10)
+	defaultTierPriority := networking.DefaultTierPriority
 	allowAction := secv1alpha1.RuleActionAllow
+	dropAction := secv1alpha1.RuleActionDrop
 	protocolTCP := networking.ProtocolTCP
 	intstr80, intstr81 := intstr.FromInt(80), intstr.FromInt(81)
 	selectorA := metav1.LabelSelector{MatchLabels: map[string]string{"foo1": "bar1"}}
@@ -289,10 +323,11 @@ func TestProcessClusterNetworkPolicy(t *testing.T) {
 				},
 			},
 			expectedPolicy: &antreatypes.NetworkPolicy{
-				UID:       "uidA",
-				Name:      "cnpA",
-				Namespace: "",
-				Priority:  &p10,
+				UID:          "uidA",
+				Name:         "cnpA",
+				Namespace:    "",
+				Priority:     &p10,
+				TierPriority: &defaultTierPriority,
 				Rules: []networking.NetworkPolicyRule{
 					{
 						Direction: networking.DirectionIn,
@@ -328,6 +363,128 @@ func TestProcessClusterNetworkPolicy(t *testing.T) {
 			expectedAppliedToGroups: 1,
 			expectedAddressGroups:   1,
 		},
+		{
+			name: "rule-with-logging-enabled",
+			inputPolicy: &secv1alpha1.ClusterNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "cnpA", UID: "uidA"},
+				Spec: secv1alpha1.ClusterNetworkPolicySpec{
+					AppliedTo: []secv1alpha1.NetworkPolicyPeer{
+						{PodSelector: &selectorA},
+					},
+					Priority: p10,
+					Ingress: []secv1alpha1.Rule{
+						{
+							Ports: []secv1alpha1.NetworkPolicyPort{
+								{
+									Port: &intstr80,
+								},
+							},
+							From: []secv1alpha1.NetworkPolicyPeer{
+								{
+									PodSelector: &selectorB,
+								},
+							},
+							Action:        &dropAction,
+							EnableLogging: true,
+						},
+					},
+				},
+			},
+			expectedPolicy: &antreatypes.NetworkPolicy{
+				UID:          "uidA",
+				Name:         "cnpA",
+				Namespace:    "",
+				Priority:     &p10,
+				TierPriority: &defaultTierPriority,
+				Rules: []networking.NetworkPolicyRule{
+					{
+						Direction: networking.DirectionIn,
+						From: networking.NetworkPolicyPeer{
+							AddressGroups: []string{getNormalizedUID(toGroupSelector("", &selectorB, nil).NormalizedName)},
+						},
+						Services: []networking.Service{
+							{
+								Protocol: &protocolTCP,
+								Port:     &intstr80,
+							},
+						},
+						Priority:      0,
+						Action:        &dropAction,
+						EnableLogging: true,
+					},
+				},
+				AppliedToGroups: []string{getNormalizedUID(toGroupSelector("", &selectorA, nil).NormalizedName)},
+			},
+			expectedAppliedToGroups: 1,
+			expectedAddressGroups:   1,
+		},
+		{
+			name: "rule-with-http-matches",
+			inputPolicy: &secv1alpha1.ClusterNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "cnpA", UID: "uidA"},
+				Spec: secv1alpha1.ClusterNetworkPolicySpec{
+					AppliedTo: []secv1alpha1.NetworkPolicyPeer{
+						{PodSelector: &selectorA},
+					},
+					Priority: p10,
+					Ingress: []secv1alpha1.Rule{
+						{
+							Ports: []secv1alpha1.NetworkPolicyPort{
+								{
+									Port: &intstr80,
+								},
+							},
+							From: []secv1alpha1.NetworkPolicyPeer{
+								{
+									PodSelector: &selectorB,
+								},
+							},
+							Action: &dropAction,
+							HTTPMatches: []secv1alpha1.HTTPMatch{
+								{
+									Method:  "DELETE",
+									Path:    "/api/*",
+									Headers: map[string]string{"Host": "foo.bar"},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedPolicy: &antreatypes.NetworkPolicy{
+				UID:          "uidA",
+				Name:         "cnpA",
+				Namespace:    "",
+				Priority:     &p10,
+				TierPriority: &defaultTierPriority,
+				Rules: []networking.NetworkPolicyRule{
+					{
+						Direction: networking.DirectionIn,
+						From: networking.NetworkPolicyPeer{
+							AddressGroups: []string{getNormalizedUID(toGroupSelector("", &selectorB, nil).NormalizedName)},
+						},
+						Services: []networking.Service{
+							{
+								Protocol: &protocolTCP,
+								Port:     &intstr80,
+							},
+						},
+						Priority: 0,
+						Action:   &dropAction,
+						HTTPMatches: []networking.HTTPMatch{
+							{
+								Method:  "DELETE",
+								Path:    "/api/*",
+								Headers: map[string]string{"Host": "foo.bar"},
+							},
+						},
+					},
+				},
+				AppliedToGroups: []string{getNormalizedUID(toGroupSelector("", &selectorA, nil).NormalizedName)},
+			},
+			expectedAppliedToGroups: 1,
+			expectedAddressGroups:   1,
+		},
 		{
 			name: "rules-with-different-selectors",
 			inputPolicy: &secv1alpha1.ClusterNetworkPolicy{
@@ -368,10 +525,11 @@ func TestProcessClusterNetworkPolicy(t *testing.T) {
 				},
 			},
 			expectedPolicy: &antreatypes.NetworkPolicy{
-				UID:       "uidA",
-				Name:      "cnpA",
-				Namespace: "",
-				Priority:  &p10,
+				UID:          "uidA",
+				Name:         "cnpA",
+				Namespace:    "",
+				Priority:     &p10,
+				TierPriority: &defaultTierPriority,
 				Rules: []networking.NetworkPolicyRule{
 					{
 						Direction: networking.DirectionIn,
@@ -427,8 +585,59 @@ func TestProcessClusterNetworkPolicy(t *testing.T) {
 	}
 }
 
+func TestProcessClusterNetworkPolicyTier(t *testing.T) {
+	tests := []struct {
+		name                 string
+		tier                 string
+		expectedTierPriority int32
+	}{
+		{"default-tier", "", networking.DefaultTierPriority},
+		{"baseline-tier", secv1alpha1.TierBaseline, networking.BaselineTierPriority},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, c := newController()
+			selectorA := metav1.LabelSelector{MatchLabels: map[string]string{"foo1": "bar1"}}
+			cnp := &secv1alpha1.ClusterNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "cnpA", UID: "uidA"},
+				Spec: secv1alpha1.ClusterNetworkPolicySpec{
+					AppliedTo: []secv1alpha1.NetworkPolicyPeer{
+						{PodSelector: &selectorA},
+					},
+					Priority: 10,
+					Tier:     tt.tier,
+				},
+			}
+			actualPolicy := c.processClusterNetworkPolicy(cnp)
+			if assert.NotNil(t, actualPolicy.TierPriority) {
+				assert.Equal(t, tt.expectedTierPriority, *actualPolicy.TierPriority)
+			}
+		})
+	}
+}
+
+func TestProcessClusterNetworkPolicyCreationTimestamp(t *testing.T) {
+	_, c := newController()
+	selectorA := metav1.LabelSelector{MatchLabels: map[string]string{"foo1": "bar1"}}
+	creationTimestamp := metav1.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	cnp := &secv1alpha1.ClusterNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "cnpA", UID: "uidA", CreationTimestamp: creationTimestamp},
+		Spec: secv1alpha1.ClusterNetworkPolicySpec{
+			AppliedTo: []secv1alpha1.NetworkPolicyPeer{
+				{PodSelector: &selectorA},
+			},
+			Priority: 10,
+		},
+	}
+	// The agents order the policies with the same priority by their creation
+	// timestamps.
+	actualPolicy := c.processClusterNetworkPolicy(cnp)
+	assert.Equal(t, creationTimestamp, actualPolicy.CreationTimestamp)
+}
+
 func TestAddCNP(t *testing.T) {
 	p10 := float64(10)
+	defaultTierPriority := networking.DefaultTierPriority
 	allowAction := secv1alpha1.RuleActionAllow
 	protocolTCP := networking.ProtocolTCP
 	intstr80, intstr81 := intstr.FromInt(80), intstr.FromInt(81)
@@ -490,10 +699,11 @@ func TestAddCNP(t *testing.T) {
 				},
 			},
 			expPolicy: &antreatypes.NetworkPolicy{
-				UID:       "uidE",
-				Name:      "npE",
-				Namespace: "",
-				Priority:  &p10,
+				UID:          "uidE",
+				Name:         "npE",
+				Namespace:    "",
+				Priority:     &p10,
+				TierPriority: &defaultTierPriority,
 				Rules: []networking.NetworkPolicyRule{
 					{
 						Direction: networking.DirectionIn,
@@ -569,10 +779,11 @@ func TestAddCNP(t *testing.T) {
 				},
 			},
 			expPolicy: &antreatypes.NetworkPolicy{
-				UID:       "uidF",
-				Name:      "npF",
-				Namespace: "",
-				Priority:  &p10,
+				UID:          "uidF",
+				Name:         "npF",
+				Namespace:    "",
+				Priority:     &p10,
+				TierPriority: &defaultTierPriority,
 				Rules: []networking.NetworkPolicyRule{
 					{
 						Direction: networking.DirectionIn,
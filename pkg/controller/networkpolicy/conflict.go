@@ -0,0 +1,301 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+	"github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/scheme"
+	secinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions/security/v1alpha1"
+	seclisters "github.com/vmware-tanzu/antrea/pkg/client/listers/security/v1alpha1"
+)
+
+const (
+	// A PolicyConflict Warning event is emitted on both ClusterNetworkPolicies
+	// when a rule of one of them allows some traffic that a rule of the other
+	// drops.
+	reasonPolicyConflict = "PolicyConflict"
+
+	conflictDetectorComponentName = "antrea-controller"
+)
+
+// ConflictDetector detects the conflicting rules of ClusterNetworkPolicies.
+// Two rules conflict if they have the same direction and different actions,
+// and if their policies' appliedTo, their peers and their ports may match the
+// same traffic. The rule which takes precedence is decided by the priorities of
+// the policies, so a conflict is not an error, but it is often unintended.
+//
+// The overlap of the selectors is decided from the selectors only, regardless
+// of the Pods that exist: two selectors overlap unless they require different
+// values for the same label in their matchLabels, and match expressions are
+// assumed to overlap. The detection runs in its own worker after a policy is
+// created or its spec is updated, so it never delays the computation of the
+// rules for the agents.
+type ConflictDetector struct {
+	cnpLister       seclisters.ClusterNetworkPolicyLister
+	cnpListerSynced cache.InformerSynced
+	queue           workqueue.RateLimitingInterface
+	recorder        record.EventRecorder
+}
+
+// NewConflictDetector creates a new ConflictDetector.
+func NewConflictDetector(k8sClient clientset.Interface, cnpInformer secinformers.ClusterNetworkPolicyInformer) *ConflictDetector {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sClient.CoreV1().Events("")})
+	d := &ConflictDetector{
+		cnpLister:       cnpInformer.Lister(),
+		cnpListerSynced: cnpInformer.Informer().HasSynced,
+		queue:           workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "policyConflict"),
+		recorder:        broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: conflictDetectorComponentName}),
+	}
+	cnpInformer.Informer().AddEventHandlerWithResyncPeriod(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    d.addCNP,
+			UpdateFunc: d.updateCNP,
+		},
+		resyncPeriod,
+	)
+	return d
+}
+
+func (d *ConflictDetector) addCNP(obj interface{}) {
+	cnp := obj.(*secv1alpha1.ClusterNetworkPolicy)
+	d.queue.Add(cnp.Name)
+}
+
+func (d *ConflictDetector) updateCNP(oldObj, curObj interface{}) {
+	oldCNP := oldObj.(*secv1alpha1.ClusterNetworkPolicy)
+	curCNP := curObj.(*secv1alpha1.ClusterNetworkPolicy)
+	// The generation is only increased when the spec changes.
+	if oldCNP.Generation == curCNP.Generation {
+		return
+	}
+	d.queue.Add(curCNP.Name)
+}
+
+// Run begins detecting the conflicts of ClusterNetworkPolicies.
+func (d *ConflictDetector) Run(stopCh <-chan struct{}) {
+	defer d.queue.ShutDown()
+
+	klog.Info("Starting ClusterNetworkPolicy conflict detector")
+	defer klog.Info("Shutting down ClusterNetworkPolicy conflict detector")
+
+	if !cache.WaitForCacheSync(stopCh, d.cnpListerSynced) {
+		klog.Error("Unable to sync caches for ClusterNetworkPolicy conflict detector")
+		return
+	}
+	go wait.Until(d.worker, time.Second, stopCh)
+	<-stopCh
+}
+
+func (d *ConflictDetector) worker() {
+	for d.processNextWorkItem() {
+	}
+}
+
+func (d *ConflictDetector) processNextWorkItem() bool {
+	obj, quit := d.queue.Get()
+	if quit {
+		return false
+	}
+	defer d.queue.Done(obj)
+
+	// We expect strings (ClusterNetworkPolicy name) to come off the workqueue.
+	if key, ok := obj.(string); !ok {
+		d.queue.Forget(obj)
+		klog.Errorf("Expected string in work queue but got %#v", obj)
+		return true
+	} else if err := d.detectConflicts(key); err == nil {
+		d.queue.Forget(key)
+	} else {
+		d.queue.AddRateLimited(key)
+		klog.Errorf("Error detecting conflicts of ClusterNetworkPolicy %s, requeuing. Error: %v", key, err)
+	}
+	return true
+}
+
+// detectConflicts compares the rules of the ClusterNetworkPolicy with the rules
+// of all the other ClusterNetworkPolicies, and emits a PolicyConflict event on
+// both policies for each pair of conflicting rules.
+func (d *ConflictDetector) detectConflicts(name string) error {
+	startTime := time.Now()
+	defer func() {
+		klog.V(2).Infof("Finished detecting conflicts of ClusterNetworkPolicy %s. (%v)", name, time.Since(startTime))
+	}()
+	cnp, err := d.cnpLister.Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	cnps, err := d.cnpLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, other := range cnps {
+		if other.Name == cnp.Name {
+			continue
+		}
+		for _, c := range findRuleConflicts(cnp, other) {
+			d.recorder.Eventf(cnp, v1.EventTypeWarning, reasonPolicyConflict, "Rule %s (%s) conflicts with rule %s (%s) of ClusterNetworkPolicy %s",
+				c.rule, c.action, c.otherRule, c.otherAction, other.Name)
+			d.recorder.Eventf(other, v1.EventTypeWarning, reasonPolicyConflict, "Rule %s (%s) conflicts with rule %s (%s) of ClusterNetworkPolicy %s",
+				c.otherRule, c.otherAction, c.rule, c.action, cnp.Name)
+		}
+	}
+	return nil
+}
+
+// ruleConflict is a pair of conflicting rules, identified by their path in the
+// spec of their policy, e.g. "ingress[0]".
+type ruleConflict struct {
+	rule        string
+	action      secv1alpha1.RuleAction
+	otherRule   string
+	otherAction secv1alpha1.RuleAction
+}
+
+// findRuleConflicts returns the pairs of conflicting rules of two
+// ClusterNetworkPolicies.
+func findRuleConflicts(cnp, other *secv1alpha1.ClusterNetworkPolicy) []ruleConflict {
+	if !peerListsOverlap(cnp.Spec.AppliedTo, other.Spec.AppliedTo) {
+		return nil
+	}
+	var conflicts []ruleConflict
+	findConflicts := func(direction string, rules, otherRules []secv1alpha1.Rule, peers func(rule *secv1alpha1.Rule) []secv1alpha1.NetworkPolicyPeer) {
+		for i := range rules {
+			for j := range otherRules {
+				action, otherAction := ruleAction(&rules[i]), ruleAction(&otherRules[j])
+				if action == otherAction {
+					continue
+				}
+				if !portListsOverlap(rules[i].Ports, otherRules[j].Ports) || !peerListsOverlap(peers(&rules[i]), peers(&otherRules[j])) {
+					continue
+				}
+				conflicts = append(conflicts, ruleConflict{
+					rule:        fmt.Sprintf("%s[%d]", direction, i),
+					action:      action,
+					otherRule:   fmt.Sprintf("%s[%d]", direction, j),
+					otherAction: otherAction,
+				})
+			}
+		}
+	}
+	fromPeers := func(rule *secv1alpha1.Rule) []secv1alpha1.NetworkPolicyPeer { return rule.From }
+	toPeers := func(rule *secv1alpha1.Rule) []secv1alpha1.NetworkPolicyPeer { return rule.To }
+	findConflicts("ingress", cnp.Spec.Ingress, other.Spec.Ingress, fromPeers)
+	findConflicts("egress", cnp.Spec.Egress, other.Spec.Egress, toPeers)
+	return conflicts
+}
+
+// ruleAction returns the action of the rule, which defaults to Allow.
+func ruleAction(rule *secv1alpha1.Rule) secv1alpha1.RuleAction {
+	if rule.Action == nil {
+		return secv1alpha1.RuleActionAllow
+	}
+	return *rule.Action
+}
+
+// portListsOverlap returns true if some traffic matches both lists of ports. An
+// empty list matches all the ports.
+func portListsOverlap(a, b []secv1alpha1.NetworkPolicyPort) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for i := range a {
+		for j := range b {
+			if portsOverlap(a[i], b[j]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// peerListsOverlap returns true if a peer of both lists may match the same
+// workloads or IPs. An empty list matches everything.
+func peerListsOverlap(a, b []secv1alpha1.NetworkPolicyPeer) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for i := range a {
+		for j := range b {
+			if peersOverlap(&a[i], &b[j]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// peersOverlap returns true if both peers may match the same workloads or IPs.
+// Peers of different kinds, e.g. an ipBlock and a podSelector, are considered
+// not to overlap.
+func peersOverlap(a, b *secv1alpha1.NetworkPolicyPeer) bool {
+	switch {
+	case a.IPBlock != nil || b.IPBlock != nil:
+		return a.IPBlock != nil && b.IPBlock != nil && cidrsOverlap(a.IPBlock.CIDR, b.IPBlock.CIDR)
+	case a.FQDN != "" || b.FQDN != "":
+		return normalizeFQDN(a.FQDN) == normalizeFQDN(b.FQDN)
+	case a.NodeSelector != nil || b.NodeSelector != nil:
+		return a.NodeSelector != nil && b.NodeSelector != nil && selectorsOverlap(a.NodeSelector, b.NodeSelector)
+	default:
+		return selectorsOverlap(a.PodSelector, b.PodSelector) && selectorsOverlap(a.NamespaceSelector, b.NamespaceSelector)
+	}
+}
+
+// cidrsOverlap returns true if both CIDRs share some IPs. Invalid CIDRs do not
+// overlap.
+func cidrsOverlap(a, b string) bool {
+	_, netA, err := net.ParseCIDR(a)
+	if err != nil {
+		return false
+	}
+	_, netB, err := net.ParseCIDR(b)
+	if err != nil {
+		return false
+	}
+	return netA.Contains(netB.IP) || netB.Contains(netA.IP)
+}
+
+// selectorsOverlap returns true unless both selectors require different values
+// for the same label in their matchLabels. A nil selector matches everything.
+func selectorsOverlap(a, b *metav1.LabelSelector) bool {
+	if a == nil || b == nil {
+		return true
+	}
+	for key, value := range a.MatchLabels {
+		if otherValue, exists := b.MatchLabels[key]; exists && otherValue != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,195 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+	fakeversioned "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+	crdinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions"
+)
+
+func newTestConflictDetector() (*ConflictDetector, cache.Store, *record.FakeRecorder) {
+	crdInformerFactory := crdinformers.NewSharedInformerFactory(fakeversioned.NewSimpleClientset(), informerDefaultResync)
+	cnpInformer := crdInformerFactory.Security().V1alpha1().ClusterNetworkPolicies()
+	d := NewConflictDetector(fake.NewSimpleClientset(), cnpInformer)
+	recorder := record.NewFakeRecorder(10)
+	d.recorder = recorder
+	return d, cnpInformer.Informer().GetStore(), recorder
+}
+
+func newConflictTestCNP(name string, appliedTo map[string]string, action secv1alpha1.RuleAction, port int, from map[string]string) *secv1alpha1.ClusterNetworkPolicy {
+	p := intstr.FromInt(port)
+	return &secv1alpha1.ClusterNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: secv1alpha1.ClusterNetworkPolicySpec{
+			Priority:  10,
+			AppliedTo: []secv1alpha1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: appliedTo}}},
+			Ingress: []secv1alpha1.Rule{
+				{
+					Action: &action,
+					Ports:  []secv1alpha1.NetworkPolicyPort{{Port: &p}},
+					From:   []secv1alpha1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: from}}},
+				},
+			},
+		},
+	}
+}
+
+func TestPolicyConflictDetection(t *testing.T) {
+	web := map[string]string{"app": "web"}
+	db := map[string]string{"app": "db"}
+	client := map[string]string{"role": "client"}
+	tests := []struct {
+		name           string
+		other          *secv1alpha1.ClusterNetworkPolicy
+		expectedEvents []string
+	}{
+		{
+			name:  "allow and drop same traffic",
+			other: newConflictTestCNP("cnp2", web, secv1alpha1.RuleActionDrop, 80, client),
+			expectedEvents: []string{
+				"Warning PolicyConflict Rule ingress[0] (Allow) conflicts with rule ingress[0] (Drop) of ClusterNetworkPolicy cnp2",
+				"Warning PolicyConflict Rule ingress[0] (Drop) conflicts with rule ingress[0] (Allow) of ClusterNetworkPolicy cnp1",
+			},
+		},
+		{
+			name:  "drop all sources",
+			other: newConflictTestCNP("cnp2", nil, secv1alpha1.RuleActionDrop, 80, nil),
+			expectedEvents: []string{
+				"Warning PolicyConflict Rule ingress[0] (Allow) conflicts with rule ingress[0] (Drop) of ClusterNetworkPolicy cnp2",
+				"Warning PolicyConflict Rule ingress[0] (Drop) conflicts with rule ingress[0] (Allow) of ClusterNetworkPolicy cnp1",
+			},
+		},
+		{
+			name:  "same action",
+			other: newConflictTestCNP("cnp2", web, secv1alpha1.RuleActionAllow, 80, client),
+		},
+		{
+			name:  "different appliedTo",
+			other: newConflictTestCNP("cnp2", db, secv1alpha1.RuleActionDrop, 80, client),
+		},
+		{
+			name:  "different ports",
+			other: newConflictTestCNP("cnp2", web, secv1alpha1.RuleActionDrop, 443, client),
+		},
+		{
+			name:  "different peers",
+			other: newConflictTestCNP("cnp2", web, secv1alpha1.RuleActionDrop, 80, map[string]string{"role": "admin"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, store, recorder := newTestConflictDetector()
+			store.Add(newConflictTestCNP("cnp1", web, secv1alpha1.RuleActionAllow, 80, client))
+			store.Add(tt.other)
+			require.NoError(t, d.detectConflicts("cnp1"))
+			require.Len(t, recorder.Events, len(tt.expectedEvents))
+			for _, expectedEvent := range tt.expectedEvents {
+				assert.Equal(t, expectedEvent, <-recorder.Events)
+			}
+		})
+	}
+}
+
+func TestPolicyConflictDetectionDeletedPolicy(t *testing.T) {
+	d, _, recorder := newTestConflictDetector()
+	assert.NoError(t, d.detectConflicts("cnp1"))
+	assert.Empty(t, recorder.Events)
+}
+
+func TestPeersOverlap(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     secv1alpha1.NetworkPolicyPeer
+		expected bool
+	}{
+		{
+			name:     "overlapping CIDRs",
+			a:        secv1alpha1.NetworkPolicyPeer{IPBlock: &secv1alpha1.IPBlock{CIDR: "10.0.0.0/8"}},
+			b:        secv1alpha1.NetworkPolicyPeer{IPBlock: &secv1alpha1.IPBlock{CIDR: "10.0.1.0/24"}},
+			expected: true,
+		},
+		{
+			name: "disjoint CIDRs",
+			a:    secv1alpha1.NetworkPolicyPeer{IPBlock: &secv1alpha1.IPBlock{CIDR: "10.0.0.0/24"}},
+			b:    secv1alpha1.NetworkPolicyPeer{IPBlock: &secv1alpha1.IPBlock{CIDR: "10.0.1.0/24"}},
+		},
+		{
+			name: "CIDR and selector",
+			a:    secv1alpha1.NetworkPolicyPeer{IPBlock: &secv1alpha1.IPBlock{CIDR: "10.0.0.0/8"}},
+			b:    secv1alpha1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{}},
+		},
+		{
+			name:     "same FQDN",
+			a:        secv1alpha1.NetworkPolicyPeer{FQDN: "www.example.com"},
+			b:        secv1alpha1.NetworkPolicyPeer{FQDN: "WWW.example.com."},
+			expected: true,
+		},
+		{
+			name:     "Namespace selector and Pod selector",
+			a:        secv1alpha1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}},
+			b:        secv1alpha1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+			expected: true,
+		},
+		{
+			name: "different Namespace labels",
+			a:    secv1alpha1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}},
+			b:    secv1alpha1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, peersOverlap(&tt.a, &tt.b))
+			assert.Equal(t, tt.expected, peersOverlap(&tt.b, &tt.a))
+		})
+	}
+}
+
+func TestPolicyConflictDetectionScale(t *testing.T) {
+	d, store, recorder := newTestConflictDetector()
+	protocolUDP := v1.ProtocolUDP
+	for i := 0; i < 1000; i++ {
+		cnp := newConflictTestCNP(fmt.Sprintf("cnp%d", i), nil, secv1alpha1.RuleActionAllow, 80, nil)
+		cnp.Spec.Egress = cnp.Spec.Ingress
+		store.Add(cnp)
+	}
+	// The appliedTo and the peers of the policy overlap with the ones of all the
+	// others, but its UDP port only conflicts with the ingress rule of cnp0.
+	cnp := newConflictTestCNP("cnp", nil, secv1alpha1.RuleActionDrop, 80, nil)
+	cnp.Spec.Ingress[0].Ports[0].Protocol = &protocolUDP
+	cnp.Spec.Egress = cnp.Spec.Ingress
+	store.Add(cnp)
+	conflicting := newConflictTestCNP("cnp0", nil, secv1alpha1.RuleActionAllow, 80, nil)
+	conflicting.Spec.Ingress[0].Ports[0].Protocol = &protocolUDP
+	store.Update(conflicting)
+
+	start := time.Now()
+	require.NoError(t, d.detectConflicts("cnp"))
+	assert.Less(t, time.Since(start).Nanoseconds(), time.Second.Nanoseconds())
+	assert.Len(t, recorder.Events, 2)
+}
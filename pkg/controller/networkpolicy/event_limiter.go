@@ -0,0 +1,90 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/vmware-tanzu/antrea/pkg/controller/metrics"
+)
+
+const (
+	// DefaultPodEventRateLimit is the default number of Pod events per
+	// second which trigger reconciliations.
+	DefaultPodEventRateLimit = 100
+	// DefaultNetworkPolicyEventRateLimit is the default number of
+	// NetworkPolicy and ClusterNetworkPolicy events per second which trigger
+	// reconciliations.
+	DefaultNetworkPolicyEventRateLimit = 200
+
+	podEventType           = "pod"
+	networkPolicyEventType = "networkpolicy"
+)
+
+// eventRateLimiter limits the rate at which the events of a type enqueue
+// reconciliations, with a token bucket which holds one second of events. The
+// events exceeding the rate are delayed until a token is available, they are
+// never dropped.
+type eventRateLimiter struct {
+	eventType string
+	// limiter is nil if the events are not rate limited.
+	limiter *rate.Limiter
+}
+
+// newEventRateLimiter creates an eventRateLimiter which allows eventsPerSecond
+// events of the given type per second. The events are not rate limited if
+// eventsPerSecond is 0.
+func newEventRateLimiter(eventType string, eventsPerSecond int) *eventRateLimiter {
+	l := &eventRateLimiter{eventType: eventType}
+	if eventsPerSecond > 0 {
+		l.limiter = rate.NewLimiter(rate.Limit(eventsPerSecond), eventsPerSecond)
+	}
+	return l
+}
+
+// dispatch calls enqueue immediately if the event is within the rate limit, or
+// after the delay required by the rate limit otherwise. The number of delayed
+// events is tracked by the antrea_controller_reconcile_queue_depth metric.
+func (l *eventRateLimiter) dispatch(enqueue func()) {
+	if l.limiter == nil {
+		enqueue()
+		return
+	}
+	delay := l.limiter.Reserve().Delay()
+	if delay == 0 {
+		enqueue()
+		return
+	}
+	metrics.ReconcileQueueDepth.WithLabelValues(l.eventType).Inc()
+	time.AfterFunc(delay, func() {
+		enqueue()
+		metrics.ReconcileQueueDepth.WithLabelValues(l.eventType).Dec()
+	})
+}
+
+// dispatchEvent enqueues the reconciliations triggered by an event, once the
+// rate limit of its type allows it. The events received while the informer
+// lists the existing objects are not rate limited, so that the controller
+// starts without delay.
+func (n *NetworkPolicyController) dispatchEvent(limiter *eventRateLimiter, synced cache.InformerSynced, enqueue func()) {
+	if !synced() {
+		enqueue()
+		return
+	}
+	limiter.dispatch(enqueue)
+}
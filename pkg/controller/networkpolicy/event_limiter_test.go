@@ -0,0 +1,61 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestEventRateLimiterBurst(t *testing.T) {
+	const events = 1000
+	const eventsPerSecond = 500
+	limiter := newEventRateLimiter(podEventType, eventsPerSecond)
+	var enqueued int32
+	start := time.Now()
+	for i := 0; i < events; i++ {
+		limiter.dispatch(func() {
+			atomic.AddInt32(&enqueued, 1)
+		})
+	}
+	// The token bucket holds one second of events, the other events are delayed.
+	assert.GreaterOrEqual(t, int(atomic.LoadInt32(&enqueued)), eventsPerSecond)
+	assert.Less(t, int(atomic.LoadInt32(&enqueued)), events)
+
+	// All the events must be enqueued, at the configured rate.
+	err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return atomic.LoadInt32(&enqueued) == events, nil
+	})
+	assert.NoError(t, err, "Some events were not enqueued")
+	elapsed := time.Since(start)
+	expected := time.Duration(events-eventsPerSecond) * time.Second / eventsPerSecond
+	assert.GreaterOrEqual(t, int64(elapsed), int64(expected*9/10), "Events were enqueued faster than the rate limit")
+	assert.Less(t, int64(elapsed), int64(expected*3), "Events were enqueued slower than the rate limit")
+}
+
+func TestEventRateLimiterUnlimited(t *testing.T) {
+	limiter := newEventRateLimiter(networkPolicyEventType, 0)
+	enqueued := 0
+	for i := 0; i < 1000; i++ {
+		limiter.dispatch(func() {
+			enqueued++
+		})
+	}
+	assert.Equal(t, 1000, enqueued)
+}
@@ -0,0 +1,255 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking"
+)
+
+const (
+	// DefaultFQDNResolveInterval is the default interval at which the FQDNs referenced by
+	// ClusterNetworkPolicies are resolved.
+	DefaultFQDNResolveInterval = 30 * time.Second
+	// fqdnStaleTimeout is how long the last-known IPs of an FQDN are kept when it can no
+	// longer be resolved, so that a short DNS outage does not change the enforced rules.
+	fqdnStaleTimeout = 5 * time.Minute
+	// fqdnLookupTimeout is the timeout of a single DNS lookup.
+	fqdnLookupTimeout = 10 * time.Second
+	// Default number of workers resolving FQDNs.
+	defaultFQDNWorkers = 2
+)
+
+// fqdnEntry is the resolution state of an FQDN.
+type fqdnEntry struct {
+	// ips are the last-known IPs of the FQDN, sorted.
+	ips []net.IP
+	// lastResolved is the last time the FQDN was resolved successfully.
+	lastResolved time.Time
+	// policies are the keys of the ClusterNetworkPolicies referencing the FQDN.
+	policies sets.String
+}
+
+// fqdnResolver periodically resolves the FQDNs referenced by ClusterNetworkPolicies and calls
+// onUpdate with the FQDN whenever its set of IPs changes. CNAMEs are followed by the lookup.
+// Wildcard FQDNs cannot be resolved as DNS does not allow to enumerate the subdomains, so they
+// never match any IP. The TTLs of the DNS records are not known, so IP changes happening faster
+// than the resolve interval may be missed.
+type fqdnResolver struct {
+	lookupIP        func(ctx context.Context, host string) ([]net.IP, error)
+	resolveInterval time.Duration
+	clock           clock.Clock
+	onUpdate        func(fqdn string)
+	queue           workqueue.DelayingInterface
+
+	mutex sync.RWMutex
+	// entries maps an FQDN to its resolution state.
+	entries map[string]*fqdnEntry
+	// policyFQDNs maps the key of a ClusterNetworkPolicy to the FQDNs it references.
+	policyFQDNs map[string]sets.String
+}
+
+func newFQDNResolver(resolveInterval time.Duration, onUpdate func(fqdn string)) *fqdnResolver {
+	return &fqdnResolver{
+		lookupIP:        lookupIP,
+		resolveInterval: resolveInterval,
+		clock:           clock.RealClock{},
+		onUpdate:        onUpdate,
+		queue:           workqueue.NewNamedDelayingQueue("fqdn"),
+		entries:         map[string]*fqdnEntry{},
+		policyFQDNs:     map[string]sets.String{},
+	}
+}
+
+func lookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+// isWildcardFQDN returns true if the FQDN matches all the subdomains of a domain, e.g. "*.example.com".
+func isWildcardFQDN(fqdn string) bool {
+	return strings.HasPrefix(fqdn, "*")
+}
+
+// normalizeFQDN lower-cases the FQDN and removes its trailing dot if any.
+func normalizeFQDN(fqdn string) string {
+	return strings.TrimSuffix(strings.ToLower(fqdn), ".")
+}
+
+// setPolicyFQDNs records the FQDNs referenced by a ClusterNetworkPolicy. FQDNs which were not
+// referenced by any policy yet are resolved asynchronously, and FQDNs which are no longer
+// referenced by any policy are forgotten.
+func (r *fqdnResolver) setPolicyFQDNs(policyKey string, fqdns sets.String) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	oldFQDNs := r.policyFQDNs[policyKey]
+	for fqdn := range oldFQDNs.Difference(fqdns) {
+		entry := r.entries[fqdn]
+		entry.policies.Delete(policyKey)
+		if entry.policies.Len() == 0 {
+			delete(r.entries, fqdn)
+		}
+	}
+	for fqdn := range fqdns.Difference(oldFQDNs) {
+		entry, exists := r.entries[fqdn]
+		if !exists {
+			entry = &fqdnEntry{policies: sets.NewString()}
+			r.entries[fqdn] = entry
+			if isWildcardFQDN(fqdn) {
+				klog.Warningf("Wildcard FQDN %s referenced by ClusterNetworkPolicy %s cannot be resolved", fqdn, policyKey)
+			} else {
+				r.queue.Add(fqdn)
+			}
+		}
+		entry.policies.Insert(policyKey)
+	}
+	if fqdns.Len() == 0 {
+		delete(r.policyFQDNs, policyKey)
+	} else {
+		r.policyFQDNs[policyKey] = fqdns
+	}
+}
+
+// getIPBlocks returns an IPBlock for each last-known IP of the FQDN.
+func (r *fqdnResolver) getIPBlocks(fqdn string) []networking.IPBlock {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	entry, exists := r.entries[fqdn]
+	if !exists {
+		return nil
+	}
+	ipBlocks := make([]networking.IPBlock, 0, len(entry.ips))
+	for _, ip := range entry.ips {
+		prefixLength := int32(net.IPv6len * 8)
+		if ip.To4() != nil {
+			prefixLength = net.IPv4len * 8
+		}
+		ipBlocks = append(ipBlocks, networking.IPBlock{
+			CIDR:   networking.IPNet{IP: ipStrToIPAddress(ip.String()), PrefixLength: prefixLength},
+			Except: []networking.IPNet{},
+		})
+	}
+	return ipBlocks
+}
+
+// getPolicies returns the keys of the ClusterNetworkPolicies referencing the FQDN.
+func (r *fqdnResolver) getPolicies(fqdn string) []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	entry, exists := r.entries[fqdn]
+	if !exists {
+		return nil
+	}
+	return entry.policies.List()
+}
+
+// resolve resolves the FQDN and updates its last-known IPs. It returns true if they changed.
+// If the FQDN cannot be resolved, the last-known IPs are kept until fqdnStaleTimeout elapses.
+func (r *fqdnResolver) resolve(fqdn string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), fqdnLookupTimeout)
+	defer cancel()
+	ips, err := r.lookupIP(ctx, fqdn)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	entry, exists := r.entries[fqdn]
+	if !exists {
+		return false
+	}
+	if err != nil {
+		if len(entry.ips) > 0 && r.clock.Since(entry.lastResolved) > fqdnStaleTimeout {
+			klog.Warningf("Failed to resolve FQDN %s for more than %v, removing its IPs: %v", fqdn, fqdnStaleTimeout, err)
+			entry.ips = nil
+			return true
+		}
+		klog.Warningf("Failed to resolve FQDN %s, keeping its last-known IPs: %v", fqdn, err)
+		return false
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		return ips[i].String() < ips[j].String()
+	})
+	entry.lastResolved = r.clock.Now()
+	if ipsEqual(entry.ips, ips) {
+		return false
+	}
+	klog.V(2).Infof("FQDN %s resolved to %v", fqdn, ips)
+	entry.ips = ips
+	return true
+}
+
+func ipsEqual(a, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *fqdnResolver) worker() {
+	for r.processNextWorkItem() {
+	}
+}
+
+func (r *fqdnResolver) processNextWorkItem() bool {
+	obj, quit := r.queue.Get()
+	if quit {
+		return false
+	}
+	defer r.queue.Done(obj)
+	fqdn := obj.(string)
+	if r.resolve(fqdn) {
+		r.onUpdate(fqdn)
+	}
+	r.mutex.RLock()
+	_, exists := r.entries[fqdn]
+	r.mutex.RUnlock()
+	// Resolve the FQDN again after the interval as long as it is referenced.
+	if exists {
+		r.queue.AddAfter(fqdn, r.resolveInterval)
+	}
+	return true
+}
+
+// Run starts the workers resolving the FQDNs until stopCh is closed.
+func (r *fqdnResolver) Run(stopCh <-chan struct{}) {
+	defer r.queue.ShutDown()
+	for i := 0; i < defaultFQDNWorkers; i++ {
+		go wait.Until(r.worker, time.Second, stopCh)
+	}
+	<-stopCh
+}
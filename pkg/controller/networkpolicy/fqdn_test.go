@@ -0,0 +1,144 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking"
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+)
+
+// fakeLookup is a lookup function returning the IPs set for each host, or an error if
+// there is none.
+type fakeLookup map[string][]net.IP
+
+func (f fakeLookup) lookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	ips, ok := f[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+	return ips, nil
+}
+
+func newTestFQDNResolver(lookup fakeLookup, fakeClock clock.Clock) *fqdnResolver {
+	r := newFQDNResolver(DefaultFQDNResolveInterval, func(string) {})
+	r.lookupIP = lookup.lookupIP
+	r.clock = fakeClock
+	return r
+}
+
+func TestFQDNResolverResolve(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	lookup := fakeLookup{"www.example.com": {net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.1")}}
+	r := newTestFQDNResolver(lookup, fakeClock)
+	r.setPolicyFQDNs("cnpA", sets.NewString("www.example.com"))
+
+	assert.True(t, r.resolve("www.example.com"), "First resolution should change the IPs")
+	assert.Equal(t, []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}, r.entries["www.example.com"].ips)
+	assert.False(t, r.resolve("www.example.com"), "Same IPs should not be reported as a change")
+
+	// The DNS record has been updated.
+	lookup["www.example.com"] = []net.IP{net.ParseIP("10.0.0.3")}
+	assert.True(t, r.resolve("www.example.com"))
+	assert.Equal(t, []net.IP{net.ParseIP("10.0.0.3")}, r.entries["www.example.com"].ips)
+
+	// The last-known IPs are kept during a short DNS outage.
+	delete(lookup, "www.example.com")
+	fakeClock.Step(fqdnStaleTimeout / 2)
+	assert.False(t, r.resolve("www.example.com"))
+	assert.Equal(t, []net.IP{net.ParseIP("10.0.0.3")}, r.entries["www.example.com"].ips)
+
+	// They are removed once the outage lasts longer than fqdnStaleTimeout.
+	fakeClock.Step(fqdnStaleTimeout)
+	assert.True(t, r.resolve("www.example.com"))
+	assert.Empty(t, r.entries["www.example.com"].ips)
+	assert.Empty(t, r.getIPBlocks("www.example.com"))
+}
+
+func TestFQDNResolverSetPolicyFQDNs(t *testing.T) {
+	r := newTestFQDNResolver(fakeLookup{}, clock.NewFakeClock(time.Now()))
+	r.setPolicyFQDNs("cnpA", sets.NewString("a.example.com", "b.example.com"))
+	r.setPolicyFQDNs("cnpB", sets.NewString("b.example.com"))
+	assert.Equal(t, []string{"cnpA"}, r.getPolicies("a.example.com"))
+	assert.Equal(t, []string{"cnpA", "cnpB"}, r.getPolicies("b.example.com"))
+
+	// FQDNs which are no longer referenced are forgotten.
+	r.setPolicyFQDNs("cnpA", sets.NewString("c.example.com"))
+	assert.Nil(t, r.getPolicies("a.example.com"))
+	assert.Equal(t, []string{"cnpB"}, r.getPolicies("b.example.com"))
+	assert.Equal(t, []string{"cnpA"}, r.getPolicies("c.example.com"))
+
+	r.setPolicyFQDNs("cnpA", sets.NewString())
+	r.setPolicyFQDNs("cnpB", sets.NewString())
+	assert.Empty(t, r.entries)
+	assert.Empty(t, r.policyFQDNs)
+}
+
+func TestFQDNResolverWildcard(t *testing.T) {
+	r := newTestFQDNResolver(fakeLookup{}, clock.NewFakeClock(time.Now()))
+	r.setPolicyFQDNs("cnpA", sets.NewString("*.example.com", "www.example.com"))
+	// Only the FQDN which can be resolved is queued.
+	assert.Equal(t, 1, r.queue.Len())
+	assert.Equal(t, []string{"cnpA"}, r.getPolicies("*.example.com"))
+	assert.Empty(t, r.getIPBlocks("*.example.com"))
+}
+
+func TestProcessClusterNetworkPolicyWithFQDN(t *testing.T) {
+	_, npc := newController()
+	lookup := fakeLookup{"www.example.com": {net.ParseIP("10.0.0.1"), net.ParseIP("2001:db8::1")}}
+	npc.fqdnResolver.lookupIP = lookup.lookupIP
+	allowAction := secv1alpha1.RuleActionAllow
+	selectorA := metav1.LabelSelector{MatchLabels: map[string]string{"foo1": "bar1"}}
+	cnp := &secv1alpha1.ClusterNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "cnpA", UID: "uidA"},
+		Spec: secv1alpha1.ClusterNetworkPolicySpec{
+			AppliedTo: []secv1alpha1.NetworkPolicyPeer{
+				{PodSelector: &selectorA},
+			},
+			Priority: 10,
+			Egress: []secv1alpha1.Rule{
+				{
+					To:     []secv1alpha1.NetworkPolicyPeer{{FQDN: "WWW.example.com."}},
+					Action: &allowAction,
+				},
+			},
+		},
+	}
+
+	// The FQDN has not been resolved yet, so the rule does not match any IP.
+	policy := npc.processClusterNetworkPolicy(cnp)
+	require.Len(t, policy.Rules, 1)
+	assert.Empty(t, policy.Rules[0].To.IPBlocks)
+	assert.Equal(t, []string{"cnpA"}, npc.fqdnResolver.getPolicies("www.example.com"))
+
+	require.True(t, npc.fqdnResolver.resolve("www.example.com"))
+	policy = npc.processClusterNetworkPolicy(cnp)
+	expectedIPBlocks := []networking.IPBlock{
+		{CIDR: networking.IPNet{IP: ipStrToIPAddress("10.0.0.1"), PrefixLength: 32}, Except: []networking.IPNet{}},
+		{CIDR: networking.IPNet{IP: ipStrToIPAddress("2001:db8::1"), PrefixLength: 128}, Except: []networking.IPNet{}},
+	}
+	assert.Equal(t, expectedIPBlocks, policy.Rules[0].To.IPBlocks)
+}
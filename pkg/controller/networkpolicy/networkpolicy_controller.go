@@ -132,6 +132,13 @@ type NetworkPolicyController struct {
 	// cnpListerSynced is a function which returns true if the ClusterNetworkPolicies shared informer has been synced at least once.
 	cnpListerSynced cache.InformerSynced
 
+	nodeInformer coreinformers.NodeInformer
+	// nodeLister is able to list/get Nodes and is populated by the shared informer passed to
+	// NewNetworkPolicyController.
+	nodeLister corelisters.NodeLister
+	// nodeListerSynced is a function which returns true if the Node shared informer has been synced at least once.
+	nodeListerSynced cache.InformerSynced
+
 	// addressGroupStore is the storage where the populated Address Groups are stored.
 	addressGroupStore storage.Interface
 	// appliedToGroupStore is the storage where the populated AppliedTo Groups are stored.
@@ -149,10 +156,18 @@ type NetworkPolicyController struct {
 	// need to be synced.
 	internalNetworkPolicyQueue workqueue.RateLimitingInterface
 
+	// podEventLimiter and networkPolicyEventLimiter limit the rate at which
+	// the Pod and the NetworkPolicy events enqueue the objects to sync.
+	podEventLimiter           *eventRateLimiter
+	networkPolicyEventLimiter *eventRateLimiter
+
 	// internalNetworkPolicyMutex protects the internalNetworkPolicyStore from
 	// concurrent access during updates to the internal NetworkPolicy object.
 	internalNetworkPolicyMutex sync.RWMutex
 
+	// fqdnResolver resolves the FQDNs referenced by ClusterNetworkPolicies.
+	fqdnResolver *fqdnResolver
+
 	// heartbeatCh is an internal channel for testing. It's used to know whether all tasks have been
 	// processed, and to count executions of each function.
 	heartbeatCh chan heartbeat
@@ -170,9 +185,13 @@ func NewNetworkPolicyController(kubeClient clientset.Interface,
 	namespaceInformer coreinformers.NamespaceInformer,
 	networkPolicyInformer networkinginformers.NetworkPolicyInformer,
 	cnpInformer secinformers.ClusterNetworkPolicyInformer,
+	nodeInformer coreinformers.NodeInformer,
 	addressGroupStore storage.Interface,
 	appliedToGroupStore storage.Interface,
-	internalNetworkPolicyStore storage.Interface) *NetworkPolicyController {
+	internalNetworkPolicyStore storage.Interface,
+	fqdnResolveInterval time.Duration,
+	podEventRateLimit int,
+	networkPolicyEventRateLimit int) *NetworkPolicyController {
 	n := &NetworkPolicyController{
 		kubeClient:                 kubeClient,
 		crdClient:                  crdClient,
@@ -185,13 +204,19 @@ func NewNetworkPolicyController(kubeClient clientset.Interface,
 		networkPolicyInformer:      networkPolicyInformer,
 		networkPolicyLister:        networkPolicyInformer.Lister(),
 		networkPolicyListerSynced:  networkPolicyInformer.Informer().HasSynced,
+		nodeInformer:               nodeInformer,
+		nodeLister:                 nodeInformer.Lister(),
+		nodeListerSynced:           nodeInformer.Informer().HasSynced,
 		addressGroupStore:          addressGroupStore,
 		appliedToGroupStore:        appliedToGroupStore,
 		internalNetworkPolicyStore: internalNetworkPolicyStore,
 		appliedToGroupQueue:        workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "appliedToGroup"),
 		addressGroupQueue:          workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "addressGroup"),
 		internalNetworkPolicyQueue: workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "internalNetworkPolicy"),
+		podEventLimiter:            newEventRateLimiter(podEventType, podEventRateLimit),
+		networkPolicyEventLimiter:  newEventRateLimiter(networkPolicyEventType, networkPolicyEventRateLimit),
 	}
+	n.fqdnResolver = newFQDNResolver(fqdnResolveInterval, n.onFQDNUpdate)
 	// Add handlers for Pod events.
 	podInformer.Informer().AddEventHandlerWithResyncPeriod(
 		cache.ResourceEventHandlerFuncs{
@@ -232,6 +257,16 @@ func NewNetworkPolicyController(kubeClient clientset.Interface,
 			},
 			resyncPeriod,
 		)
+		// Add handlers for Node events, which may change the IPs matched by the
+		// nodeSelector peers of ClusterNetworkPolicies.
+		nodeInformer.Informer().AddEventHandlerWithResyncPeriod(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc:    n.addNode,
+				UpdateFunc: n.updateNode,
+				DeleteFunc: n.deleteNode,
+			},
+			resyncPeriod,
+		)
 	}
 	return n
 }
@@ -625,7 +660,9 @@ func (n *NetworkPolicyController) addNetworkPolicy(obj interface{}) {
 	klog.Infof("Creating new internal NetworkPolicy %s/%s", internalNP.Namespace, internalNP.Name)
 	n.internalNetworkPolicyStore.Create(internalNP)
 	key, _ := keyFunc(np)
-	n.enqueueInternalNetworkPolicy(key)
+	n.dispatchEvent(n.networkPolicyEventLimiter, n.networkPolicyListerSynced, func() {
+		n.enqueueInternalNetworkPolicy(key)
+	})
 }
 
 // updateNetworkPolicy receives NetworkPolicy UPDATE events and updates resources
@@ -657,16 +694,18 @@ func (n *NetworkPolicyController) updateNetworkPolicy(old, cur interface{}) {
 	n.internalNetworkPolicyStore.Update(curInternalNP)
 	// Unlock the internal NetworkPolicy store.
 	n.internalNetworkPolicyMutex.Unlock()
-	// Enqueue addressGroup keys to update their Node span.
-	for _, rule := range curInternalNP.Rules {
-		for _, addrGroupName := range rule.From.AddressGroups {
-			n.enqueueAddressGroup(addrGroupName)
-		}
-		for _, addrGroupName := range rule.To.AddressGroups {
-			n.enqueueAddressGroup(addrGroupName)
+	n.dispatchEvent(n.networkPolicyEventLimiter, n.networkPolicyListerSynced, func() {
+		// Enqueue addressGroup keys to update their Node span.
+		for _, rule := range curInternalNP.Rules {
+			for _, addrGroupName := range rule.From.AddressGroups {
+				n.enqueueAddressGroup(addrGroupName)
+			}
+			for _, addrGroupName := range rule.To.AddressGroups {
+				n.enqueueAddressGroup(addrGroupName)
+			}
 		}
-	}
-	n.enqueueInternalNetworkPolicy(key)
+		n.enqueueInternalNetworkPolicy(key)
+	})
 	// AppliedToGroups currently only supports a single member.
 	curAppliedToGroupUID := curInternalNP.AppliedToGroups[0]
 	// Delete the old AppliedToGroup object if it is not referenced by any
@@ -722,13 +761,7 @@ func (n *NetworkPolicyController) addPod(obj interface{}) {
 	appliedToGroupKeySet := n.filterAppliedToGroupsForPod(pod)
 	// Find all AddressGroup keys which match the Pod's labels.
 	addressGroupKeySet := n.filterAddressGroupsForPod(pod)
-	// Enqueue groups to their respective queues for group processing.
-	for group := range appliedToGroupKeySet {
-		n.enqueueAppliedToGroup(group)
-	}
-	for group := range addressGroupKeySet {
-		n.enqueueAddressGroup(group)
-	}
+	n.enqueuePodGroups(appliedToGroupKeySet, addressGroupKeySet)
 }
 
 // updatePod retrieves all AddressGroups and AppliedToGroups which match the
@@ -773,12 +806,7 @@ func (n *NetworkPolicyController) updatePod(oldObj, curObj interface{}) {
 		// information.
 		addressGroupKeys = oldAddressGroupKeySet.Difference(curAddressGroupKeySet).Union(curAddressGroupKeySet.Difference(oldAddressGroupKeySet))
 	}
-	for group := range appliedToGroupKeys {
-		n.enqueueAppliedToGroup(group)
-	}
-	for group := range addressGroupKeys {
-		n.enqueueAddressGroup(group)
-	}
+	n.enqueuePodGroups(appliedToGroupKeys, addressGroupKeys)
 }
 
 // deletePod retrieves all AddressGroups and AppliedToGroups which match the Pod's
@@ -804,13 +832,24 @@ func (n *NetworkPolicyController) deletePod(old interface{}) {
 	appliedToGroupKeys := n.filterAppliedToGroupsForPod(pod)
 	// Find all AddressGroup keys which match the Pod's labels.
 	addressGroupKeys := n.filterAddressGroupsForPod(pod)
-	// Enqueue groups to their respective queues for group processing.
-	for group := range appliedToGroupKeys {
-		n.enqueueAppliedToGroup(group)
-	}
-	for group := range addressGroupKeys {
-		n.enqueueAddressGroup(group)
+	n.enqueuePodGroups(appliedToGroupKeys, addressGroupKeys)
+}
+
+// enqueuePodGroups enqueues the groups affected by a Pod event to their
+// respective queues for group processing, once the Pod event rate limit allows
+// it.
+func (n *NetworkPolicyController) enqueuePodGroups(appliedToGroupKeys, addressGroupKeys sets.String) {
+	if len(appliedToGroupKeys) == 0 && len(addressGroupKeys) == 0 {
+		return
 	}
+	n.dispatchEvent(n.podEventLimiter, n.podListerSynced, func() {
+		for group := range appliedToGroupKeys {
+			n.enqueueAppliedToGroup(group)
+		}
+		for group := range addressGroupKeys {
+			n.enqueueAddressGroup(group)
+		}
+	})
 }
 
 // addNamespace retrieves all AddressGroups which match the Namespace
@@ -961,12 +1000,16 @@ func (n *NetworkPolicyController) Run(stopCh <-chan struct{}) {
 	}
 	// Only wait for CNPListerSynced when ClusterNetworkPolicy feature gate is enabled.
 	if features.DefaultFeatureGate.Enabled(features.ClusterNetworkPolicy) {
-		if !cache.WaitForCacheSync(stopCh, n.cnpListerSynced) {
+		if !cache.WaitForCacheSync(stopCh, n.cnpListerSynced, n.nodeListerSynced) {
 			klog.Error("Unable to sync CNP caches for NetworkPolicy controller")
 			return
 		}
+		go n.fqdnResolver.Run(stopCh)
 	}
 	klog.Info("Caches are synced for NetworkPolicy controller")
+	// Export the depth of the event queues before any event is delayed.
+	metrics.ReconcileQueueDepth.WithLabelValues(podEventType).Add(0)
+	metrics.ReconcileQueueDepth.WithLabelValues(networkPolicyEventType).Add(0)
 
 	for i := 0; i < defaultWorkers; i++ {
 		go wait.Until(n.appliedToGroupWorker, time.Second, stopCh)
@@ -1324,6 +1367,7 @@ func (n *NetworkPolicyController) syncInternalNetworkPolicy(key string) error {
 		Rules:           internalNP.Rules,
 		AppliedToGroups: internalNP.AppliedToGroups,
 		Priority:        internalNP.Priority,
+		TierPriority:    internalNP.TierPriority,
 		SpanMeta:        antreatypes.SpanMeta{NodeNames: nodeNames},
 	}
 	klog.V(4).Infof("Updating internal NetworkPolicy %s with %d Nodes", key, nodeNames.Len())
@@ -53,6 +53,7 @@ type networkPolicyController struct {
 	namespaceStore             cache.Store
 	networkPolicyStore         cache.Store
 	cnpStore                   cache.Store
+	nodeStore                  cache.Store
 	appliedToGroupStore        storage.Interface
 	addressGroupStore          storage.Interface
 	internalNetworkPolicyStore storage.Interface
@@ -75,19 +76,25 @@ func newController(objects ...runtime.Object) (*fake.Clientset, *networkPolicyCo
 		informerFactory.Core().V1().Namespaces(),
 		informerFactory.Networking().V1().NetworkPolicies(),
 		crdInformerFactory.Security().V1alpha1().ClusterNetworkPolicies(),
+		informerFactory.Core().V1().Nodes(),
 		addressGroupStore,
 		appliedToGroupStore,
-		internalNetworkPolicyStore)
+		internalNetworkPolicyStore,
+		DefaultFQDNResolveInterval,
+		0,
+		0)
 	npController.podListerSynced = alwaysReady
 	npController.namespaceListerSynced = alwaysReady
 	npController.networkPolicyListerSynced = alwaysReady
 	npController.cnpListerSynced = alwaysReady
+	npController.nodeListerSynced = alwaysReady
 	return client, &networkPolicyController{
 		npController,
 		informerFactory.Core().V1().Pods().Informer().GetStore(),
 		informerFactory.Core().V1().Namespaces().Informer().GetStore(),
 		informerFactory.Networking().V1().NetworkPolicies().Informer().GetStore(),
 		crdInformerFactory.Security().V1alpha1().ClusterNetworkPolicies().Informer().GetStore(),
+		informerFactory.Core().V1().Nodes().Informer().GetStore(),
 		appliedToGroupStore,
 		addressGroupStore,
 		internalNetworkPolicyStore,
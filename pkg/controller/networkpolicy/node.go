@@ -0,0 +1,175 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"net"
+	"reflect"
+	"sort"
+
+	"github.com/containernetworking/plugins/pkg/ip"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking"
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+)
+
+// getNodeIPBlocks returns the IPBlocks matching the Nodes selected by the
+// nodeSelector. Besides the InternalIPs of a Node, the gateway IP of its Pod
+// subnet is matched, as it is the source IP of the traffic sent by the Node to
+// its local Pods.
+func (n *NetworkPolicyController) getNodeIPBlocks(nodeSelector *metav1.LabelSelector) ([]networking.IPBlock, error) {
+	selector, err := metav1.LabelSelectorAsSelector(nodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := n.nodeLister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	// Sort the Nodes so that the generated rule does not change if the Nodes
+	// do not.
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	var ipBlocks []networking.IPBlock
+	for _, node := range nodes {
+		for _, nodeIP := range getNodeIPs(node) {
+			prefixLength := int32(net.IPv6len * 8)
+			if nodeIP.To4() != nil {
+				prefixLength = net.IPv4len * 8
+			}
+			ipBlocks = append(ipBlocks, networking.IPBlock{
+				CIDR:   networking.IPNet{IP: ipStrToIPAddress(nodeIP.String()), PrefixLength: prefixLength},
+				Except: []networking.IPNet{},
+			})
+		}
+	}
+	return ipBlocks, nil
+}
+
+// getNodeIPs returns the InternalIPs of the Node and the gateway IP of its Pod
+// subnet, which is the first address of Spec.PodCIDR.
+func getNodeIPs(node *v1.Node) []net.IP {
+	var ips []net.IP
+	for _, address := range node.Status.Addresses {
+		if address.Type != v1.NodeInternalIP {
+			continue
+		}
+		if nodeIP := net.ParseIP(address.Address); nodeIP != nil {
+			ips = append(ips, nodeIP)
+		}
+	}
+	if node.Spec.PodCIDR != "" {
+		_, podCIDR, err := net.ParseCIDR(node.Spec.PodCIDR)
+		if err != nil {
+			klog.Errorf("Invalid PodCIDR %s of Node %s: %v", node.Spec.PodCIDR, node.Name, err)
+		} else {
+			ips = append(ips, ip.NextIP(podCIDR.IP))
+		}
+	}
+	return ips
+}
+
+// addNode receives Node ADD events and processes again the
+// ClusterNetworkPolicies selecting the Node.
+func (n *NetworkPolicyController) addNode(obj interface{}) {
+	defer n.heartbeat("addNode")
+	node := obj.(*v1.Node)
+	klog.V(2).Infof("Processing Node %s ADD event, labels: %v", node.Name, node.Labels)
+	n.syncCNPsForNodes(node)
+}
+
+// updateNode receives Node UPDATE events and processes again the
+// ClusterNetworkPolicies selecting the Node before or after the update, if
+// its labels or its IPs changed.
+func (n *NetworkPolicyController) updateNode(oldObj, curObj interface{}) {
+	defer n.heartbeat("updateNode")
+	oldNode := oldObj.(*v1.Node)
+	curNode := curObj.(*v1.Node)
+	if labels.Equals(oldNode.Labels, curNode.Labels) && reflect.DeepEqual(getNodeIPs(oldNode), getNodeIPs(curNode)) {
+		klog.V(4).Infof("No change in labels or IPs of Node %s, skipping", curNode.Name)
+		return
+	}
+	klog.V(2).Infof("Processing Node %s UPDATE event, labels: %v", curNode.Name, curNode.Labels)
+	n.syncCNPsForNodes(oldNode, curNode)
+}
+
+// deleteNode receives Node DELETE events and processes again the
+// ClusterNetworkPolicies selecting the Node.
+func (n *NetworkPolicyController) deleteNode(old interface{}) {
+	node, ok := old.(*v1.Node)
+	if !ok {
+		tombstone, ok := old.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			klog.Errorf("Error decoding object when deleting Node, invalid type: %v", old)
+			return
+		}
+		node, ok = tombstone.Obj.(*v1.Node)
+		if !ok {
+			klog.Errorf("Error decoding object tombstone when deleting Node, invalid type: %v", tombstone.Obj)
+			return
+		}
+	}
+	defer n.heartbeat("deleteNode")
+	klog.V(2).Infof("Processing Node %s DELETE event, labels: %v", node.Name, node.Labels)
+	n.syncCNPsForNodes(node)
+}
+
+// syncCNPsForNodes processes again the ClusterNetworkPolicies whose
+// nodeSelector peers select any of the Nodes, so that the agents receive the
+// updated IPBlocks.
+func (n *NetworkPolicyController) syncCNPsForNodes(nodes ...*v1.Node) {
+	cnps, err := n.cnpLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Error listing ClusterNetworkPolicies: %v", err)
+		return
+	}
+	for _, cnp := range cnps {
+		if !cnpSelectsNodes(cnp, nodes...) {
+			continue
+		}
+		key, _ := keyFunc(cnp)
+		if _, exists, _ := n.internalNetworkPolicyStore.Get(key); !exists {
+			continue
+		}
+		klog.V(2).Infof("Processing ClusterNetworkPolicy %s after selected Nodes changed", key)
+		n.updateCNP(cnp, cnp)
+	}
+}
+
+// cnpSelectsNodes returns true if a nodeSelector peer of the
+// ClusterNetworkPolicy selects any of the Nodes.
+func cnpSelectsNodes(cnp *secv1alpha1.ClusterNetworkPolicy, nodes ...*v1.Node) bool {
+	for _, ingressRule := range cnp.Spec.Ingress {
+		for _, peer := range ingressRule.From {
+			if peer.NodeSelector == nil {
+				continue
+			}
+			selector, err := metav1.LabelSelectorAsSelector(peer.NodeSelector)
+			if err != nil {
+				continue
+			}
+			for _, node := range nodes {
+				if selector.Matches(labels.Set(node.Labels)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
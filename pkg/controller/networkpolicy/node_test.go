@@ -0,0 +1,98 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking"
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+)
+
+func newNode(name string, nodeLabels map[string]string, internalIP, podCIDR string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nodeLabels},
+		Spec:       v1.NodeSpec{PodCIDR: podCIDR},
+		Status: v1.NodeStatus{
+			Addresses: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: name},
+				{Type: v1.NodeInternalIP, Address: internalIP},
+			},
+		},
+	}
+}
+
+func hostIPBlock(ip string) networking.IPBlock {
+	return networking.IPBlock{
+		CIDR:   networking.IPNet{IP: ipStrToIPAddress(ip), PrefixLength: 32},
+		Except: []networking.IPNet{},
+	}
+}
+
+func TestToAntreaPeerForCRDNodeSelector(t *testing.T) {
+	cnp := &secv1alpha1.ClusterNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "cnpA"}}
+	_, npc := newController()
+	npc.nodeStore.Add(newNode("node2", map[string]string{"role": "worker"}, "192.168.0.2", "10.10.1.0/24"))
+	npc.nodeStore.Add(newNode("node1", map[string]string{"role": "worker"}, "192.168.0.1", "10.10.0.0/24"))
+	npc.nodeStore.Add(newNode("node3", map[string]string{"role": "master"}, "192.168.0.3", "10.10.2.0/24"))
+	peers := []secv1alpha1.NetworkPolicyPeer{
+		{NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"role": "worker"}}},
+	}
+
+	peer := npc.toAntreaPeerForCRD(peers, cnp, networking.DirectionIn)
+	assert.Empty(t, peer.AddressGroups)
+	expectedIPBlocks := []networking.IPBlock{
+		hostIPBlock("192.168.0.1"),
+		hostIPBlock("10.10.0.1"),
+		hostIPBlock("192.168.0.2"),
+		hostIPBlock("10.10.1.1"),
+	}
+	require.Equal(t, len(expectedIPBlocks), len(peer.IPBlocks))
+	for i := range expectedIPBlocks {
+		assert.True(t, compareIPBlocks(&expectedIPBlocks[i], &peer.IPBlocks[i]), "Expected %v, got %v", expectedIPBlocks[i], peer.IPBlocks[i])
+	}
+
+	// nodeSelector peers are ignored in egress rules.
+	peer = npc.toAntreaPeerForCRD(peers, cnp, networking.DirectionOut)
+	assert.Empty(t, peer.AddressGroups)
+	assert.Empty(t, peer.IPBlocks)
+}
+
+func TestCNPSelectsNodes(t *testing.T) {
+	cnp := &secv1alpha1.ClusterNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "cnpA"},
+		Spec: secv1alpha1.ClusterNetworkPolicySpec{
+			Ingress: []secv1alpha1.Rule{
+				{
+					From: []secv1alpha1.NetworkPolicyPeer{
+						{PodSelector: &metav1.LabelSelector{}},
+						{NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"role": "worker"}}},
+					},
+				},
+			},
+		},
+	}
+	worker := newNode("node1", map[string]string{"role": "worker"}, "192.168.0.1", "10.10.0.0/24")
+	master := newNode("node2", map[string]string{"role": "master"}, "192.168.0.2", "10.10.1.0/24")
+	assert.True(t, cnpSelectsNodes(cnp, worker))
+	assert.False(t, cnpSelectsNodes(cnp, master))
+	assert.True(t, cnpSelectsNodes(cnp, master, worker))
+	assert.False(t, cnpSelectsNodes(&secv1alpha1.ClusterNetworkPolicy{}, worker))
+}
@@ -0,0 +1,325 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"net"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking"
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+	"github.com/vmware-tanzu/antrea/pkg/controller/networkpolicy/store"
+	antreatypes "github.com/vmware-tanzu/antrea/pkg/controller/types"
+)
+
+// Simulator evaluates whether the connections between Pods are allowed by a
+// set of NetworkPolicies and ClusterNetworkPolicies, without enforcing them.
+// The policies are compiled into internal NetworkPolicies and their groups
+// select Pods with the same code as NetworkPolicyController, so that the
+// simulated verdicts are consistent with the rules computed for the agents.
+//
+// Rules with HTTP matches are evaluated by their action, as the HTTP requests
+// are not known in advance. FQDNs are not resolved, hence never match a Pod.
+type Simulator struct {
+	controller *NetworkPolicyController
+	// pods are the Pods whose connections are simulated.
+	pods       []*v1.Pod
+	namespaces map[string]*v1.Namespace
+	policies   []*antreatypes.NetworkPolicy
+}
+
+// simulatedRule is a rule of an internal NetworkPolicy with the Pods matched by
+// its peer and its services precomputed.
+type simulatedRule struct {
+	policy *antreatypes.NetworkPolicy
+	rule   *networking.NetworkPolicyRule
+	// peerPods are the indexes of the Pods matched by the From (ingress) or
+	// To (egress) peer of the rule.
+	peerPods []bool
+	// servicePods are the indexes of the destination Pods whose port matches
+	// the services of the rule.
+	servicePods []bool
+}
+
+func (r *simulatedRule) matches(peer, dst int) bool {
+	return r.peerPods[peer] && r.servicePods[dst]
+}
+
+func (r *simulatedRule) allowed() bool {
+	return r.rule.Action == nil || *r.rule.Action == secv1alpha1.RuleActionAllow
+}
+
+// simulatedPodRules are the rules applied to a Pod in one direction.
+type simulatedPodRules struct {
+	// cnpRules are the rules of ClusterNetworkPolicies, sorted by precedence.
+	cnpRules []*simulatedRule
+	// npRules are the rules of K8s NetworkPolicies.
+	npRules []*simulatedRule
+	// isolated is true if the Pod is selected by a K8s NetworkPolicy which
+	// isolates it in this direction.
+	isolated bool
+}
+
+// evaluate returns whether the traffic between the peer Pod and the destination
+// Pod is allowed by the rules. The first matching ClusterNetworkPolicy rule
+// decides the verdict. Otherwise the traffic must be allowed by a K8s
+// NetworkPolicy rule if the Pod is isolated.
+func (r *simulatedPodRules) evaluate(peer, dst int) bool {
+	for _, rule := range r.cnpRules {
+		if rule.matches(peer, dst) {
+			return rule.allowed()
+		}
+	}
+	if !r.isolated {
+		return true
+	}
+	for _, rule := range r.npRules {
+		if rule.matches(peer, dst) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSimulator returns a Simulator for the connections between the given Pods.
+// Pods which have no IP yet or run in the host network are not subject to
+// NetworkPolicies and are ignored.
+func NewSimulator(pods []*v1.Pod, namespaces []*v1.Namespace) *Simulator {
+	n := &NetworkPolicyController{
+		addressGroupStore:   store.NewAddressGroupStore(),
+		appliedToGroupStore: store.NewAppliedToGroupStore(),
+		appliedToGroupQueue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		fqdnResolver:        newFQDNResolver(DefaultFQDNResolveInterval, nil),
+	}
+	// The groups are never synced and the FQDNs are never resolved, so the
+	// queues are shut down to discard the keys added to them.
+	n.appliedToGroupQueue.ShutDown()
+	n.fqdnResolver.queue.ShutDown()
+	s := &Simulator{
+		controller: n,
+		namespaces: make(map[string]*v1.Namespace, len(namespaces)),
+	}
+	for _, pod := range pods {
+		if pod.Status.PodIP == "" || pod.Spec.HostNetwork {
+			continue
+		}
+		s.pods = append(s.pods, pod)
+	}
+	for _, ns := range namespaces {
+		s.namespaces[ns.Name] = ns
+	}
+	return s
+}
+
+// Pods returns the Pods whose connections are simulated, in the order of the
+// connectivity matrix returned by Simulate.
+func (s *Simulator) Pods() []*v1.Pod {
+	return s.pods
+}
+
+// AddNetworkPolicy compiles a K8s NetworkPolicy and adds it to the simulated
+// policies.
+func (s *Simulator) AddNetworkPolicy(np *networkingv1.NetworkPolicy) {
+	s.policies = append(s.policies, s.controller.processNetworkPolicy(np))
+}
+
+// AddClusterNetworkPolicy compiles a ClusterNetworkPolicy and adds it to the
+// simulated policies.
+func (s *Simulator) AddClusterNetworkPolicy(cnp *secv1alpha1.ClusterNetworkPolicy) {
+	s.policies = append(s.policies, s.controller.processClusterNetworkPolicy(cnp))
+}
+
+// selectPods returns the indexes of the Pods selected by a GroupSelector.
+func (s *Simulator) selectPods(sel *antreatypes.GroupSelector) []bool {
+	selected := make([]bool, len(s.pods))
+	for i, pod := range s.pods {
+		selected[i] = s.controller.labelsMatchGroupSelector(pod, s.namespaces[pod.Namespace], sel)
+	}
+	return selected
+}
+
+// peerPods returns the indexes of the Pods matched by a NetworkPolicyPeer.
+func (s *Simulator) peerPods(peer *networking.NetworkPolicyPeer, groupPods map[string][]bool) []bool {
+	matched := make([]bool, len(s.pods))
+	for _, name := range peer.AddressGroups {
+		selected, exists := groupPods[name]
+		if !exists {
+			obj, _, _ := s.controller.addressGroupStore.Get(name)
+			selected = s.selectPods(&obj.(*antreatypes.AddressGroup).Selector)
+			groupPods[name] = selected
+		}
+		for i := range matched {
+			matched[i] = matched[i] || selected[i]
+		}
+	}
+	for i := range peer.IPBlocks {
+		ipBlock := &peer.IPBlocks[i]
+		for j, pod := range s.pods {
+			matched[j] = matched[j] || ipBlockContains(ipBlock, net.ParseIP(pod.Status.PodIP))
+		}
+	}
+	return matched
+}
+
+// servicePods returns the indexes of the Pods whose port is matched by the
+// services of a rule.
+func (s *Simulator) servicePods(services []networking.Service, protocol networking.Protocol, port int32) []bool {
+	matched := make([]bool, len(s.pods))
+	for i, pod := range s.pods {
+		matched[i] = servicesMatchPodPort(services, pod, protocol, port)
+	}
+	return matched
+}
+
+// compileRules computes the rules applied to each Pod in both directions.
+func (s *Simulator) compileRules(protocol networking.Protocol, port int32) (ingressRules, egressRules []simulatedPodRules) {
+	ingressRules = make([]simulatedPodRules, len(s.pods))
+	egressRules = make([]simulatedPodRules, len(s.pods))
+	addressGroupPods := map[string][]bool{}
+	appliedToGroupPods := map[string][]bool{}
+	for _, policy := range s.policies {
+		appliedTo := make([]bool, len(s.pods))
+		for _, name := range policy.AppliedToGroups {
+			selected, exists := appliedToGroupPods[name]
+			if !exists {
+				obj, _, _ := s.controller.appliedToGroupStore.Get(name)
+				selected = s.selectPods(&obj.(*antreatypes.AppliedToGroup).Selector)
+				appliedToGroupPods[name] = selected
+			}
+			for i := range appliedTo {
+				appliedTo[i] = appliedTo[i] || selected[i]
+			}
+		}
+		for i := range policy.Rules {
+			rule := &policy.Rules[i]
+			peer := &rule.From
+			podRules := ingressRules
+			if rule.Direction == networking.DirectionOut {
+				peer = &rule.To
+				podRules = egressRules
+			}
+			r := &simulatedRule{
+				policy:      policy,
+				rule:        rule,
+				peerPods:    s.peerPods(peer, addressGroupPods),
+				servicePods: s.servicePods(rule.Services, protocol, port),
+			}
+			for j := range appliedTo {
+				if !appliedTo[j] {
+					continue
+				}
+				if policy.Priority != nil {
+					podRules[j].cnpRules = append(podRules[j].cnpRules, r)
+				} else {
+					podRules[j].npRules = append(podRules[j].npRules, r)
+					podRules[j].isolated = true
+				}
+			}
+		}
+	}
+	// ClusterNetworkPolicies with a lower priority value take precedence, and
+	// the rules of a ClusterNetworkPolicy take precedence in their order.
+	for _, podRules := range [][]simulatedPodRules{ingressRules, egressRules} {
+		for i := range podRules {
+			rules := podRules[i].cnpRules
+			sort.SliceStable(rules, func(a, b int) bool {
+				if pa, pb := *rules[a].policy.Priority, *rules[b].policy.Priority; pa != pb {
+					return pa < pb
+				}
+				return rules[a].rule.Priority < rules[b].rule.Priority
+			})
+		}
+	}
+	return ingressRules, egressRules
+}
+
+// Simulate returns the connectivity matrix of the Pods for the given
+// destination protocol and port: the connection from Pods()[i] to Pods()[j] is
+// allowed if and only if the element [i][j] is true. A connection must be
+// allowed by the egress rules applied to its source Pod and by the ingress
+// rules applied to its destination Pod.
+func (s *Simulator) Simulate(protocol networking.Protocol, port int32) [][]bool {
+	ingressRules, egressRules := s.compileRules(protocol, port)
+	allowed := make([][]bool, len(s.pods))
+	for src := range s.pods {
+		allowed[src] = make([]bool, len(s.pods))
+		for dst := range s.pods {
+			allowed[src][dst] = egressRules[src].evaluate(dst, dst) && ingressRules[dst].evaluate(src, dst)
+		}
+	}
+	return allowed
+}
+
+// servicesMatchPodPort returns whether the port of a Pod is matched by any of
+// the services. Named ports are resolved with the container ports of the Pod.
+func servicesMatchPodPort(services []networking.Service, pod *v1.Pod, protocol networking.Protocol, port int32) bool {
+	if len(services) == 0 {
+		return true
+	}
+	for _, service := range services {
+		if service.Protocol != nil && *service.Protocol != protocol {
+			continue
+		}
+		if service.Port == nil {
+			return true
+		}
+		if service.Port.Type == intstr.Int {
+			if service.Port.IntVal == port {
+				return true
+			}
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			for _, containerPort := range container.Ports {
+				containerProtocol := networking.ProtocolTCP
+				if containerPort.Protocol != "" {
+					containerProtocol = networking.Protocol(containerPort.Protocol)
+				}
+				if containerPort.Name == service.Port.StrVal && containerPort.ContainerPort == port && containerProtocol == protocol {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ipBlockContains returns whether the IP is in the CIDR of the IPBlock and not
+// in any of its exceptions.
+func ipBlockContains(ipBlock *networking.IPBlock, ip net.IP) bool {
+	if ip == nil || !ipNetContains(&ipBlock.CIDR, ip) {
+		return false
+	}
+	for i := range ipBlock.Except {
+		if ipNetContains(&ipBlock.Except[i], ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func ipNetContains(ipNet *networking.IPNet, ip net.IP) bool {
+	bits := 8 * net.IPv6len
+	if net.IP(ipNet.IP).To4() != nil {
+		bits = 8 * net.IPv4len
+	}
+	cidr := net.IPNet{IP: net.IP(ipNet.IP), Mask: net.CIDRMask(int(ipNet.PrefixLength), bits)}
+	return cidr.Contains(ip)
+}
@@ -0,0 +1,297 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/vmware-tanzu/antrea/pkg/apis/networking"
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+)
+
+func newSimulatorPod(namespace, name, ip string, labels map[string]string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Name:  "c1",
+				Ports: []v1.ContainerPort{{Name: "http", ContainerPort: 80, Protocol: v1.ProtocolTCP}},
+			}},
+		},
+		Status: v1.PodStatus{PodIP: ip},
+	}
+}
+
+// simulatorFixture returns the topology of the simulation tests: the "web" and
+// the "db" Pods in the production Namespace "ns1", and the "client" Pod in the
+// development Namespace "ns2".
+func simulatorFixture() ([]*v1.Pod, []*v1.Namespace) {
+	hostNetworkPod := newSimulatorPod("ns1", "host", "192.168.0.1", nil)
+	hostNetworkPod.Spec.HostNetwork = true
+	pods := []*v1.Pod{
+		newSimulatorPod("ns1", "web", "10.0.0.1", map[string]string{"app": "web"}),
+		newSimulatorPod("ns1", "db", "10.0.0.2", map[string]string{"app": "db"}),
+		newSimulatorPod("ns2", "client", "10.0.1.1", map[string]string{"app": "client"}),
+		// Pods without IP and Pods in the host network are ignored.
+		newSimulatorPod("ns2", "pending", "", map[string]string{"app": "client"}),
+		hostNetworkPod,
+	}
+	namespaces := []*v1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "ns1", Labels: map[string]string{"env": "prod"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "ns2", Labels: map[string]string{"env": "dev"}}},
+	}
+	return pods, namespaces
+}
+
+// deniedConnections returns the denied connections between different Pods in
+// the format "srcNamespace/srcName->dstNamespace/dstName".
+func deniedConnections(pods []*v1.Pod, allowed [][]bool) sets.String {
+	denied := sets.NewString()
+	for i := range pods {
+		for j := range pods {
+			if i != j && !allowed[i][j] {
+				denied.Insert(fmt.Sprintf("%s/%s->%s/%s", pods[i].Namespace, pods[i].Name, pods[j].Namespace, pods[j].Name))
+			}
+		}
+	}
+	return denied
+}
+
+func TestSimulatorSimulate(t *testing.T) {
+	tcp := v1.ProtocolTCP
+	port80 := intstr.FromInt(80)
+	namedPort := intstr.FromString("http")
+	allowAction := secv1alpha1.RuleActionAllow
+	dropAction := secv1alpha1.RuleActionDrop
+	denyAllIngressNS1 := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "deny-all"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+	allowWebFromDev := func(port *intstr.IntOrString) *networkingv1.NetworkPolicy {
+		return &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "allow-web"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					From: []networkingv1.NetworkPolicyPeer{{
+						NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}},
+					}},
+					Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: port}},
+				}},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			},
+		}
+	}
+	tests := []struct {
+		name           string
+		nps            []*networkingv1.NetworkPolicy
+		cnps           []*secv1alpha1.ClusterNetworkPolicy
+		port           int32
+		expectedDenied []string
+	}{
+		{
+			name: "no-policy",
+			port: 80,
+		},
+		{
+			name: "k8s-deny-all-ingress",
+			nps:  []*networkingv1.NetworkPolicy{denyAllIngressNS1},
+			port: 80,
+			expectedDenied: []string{
+				"ns1/web->ns1/db", "ns1/db->ns1/web", "ns2/client->ns1/web", "ns2/client->ns1/db",
+			},
+		},
+		{
+			name:           "k8s-allow-ingress-from-namespace",
+			nps:            []*networkingv1.NetworkPolicy{allowWebFromDev(&port80)},
+			port:           80,
+			expectedDenied: []string{"ns1/db->ns1/web"},
+		},
+		{
+			name:           "k8s-allow-ingress-unmatched-port",
+			nps:            []*networkingv1.NetworkPolicy{allowWebFromDev(&port80)},
+			port:           443,
+			expectedDenied: []string{"ns1/db->ns1/web", "ns2/client->ns1/web"},
+		},
+		{
+			name:           "k8s-allow-ingress-named-port",
+			nps:            []*networkingv1.NetworkPolicy{allowWebFromDev(&namedPort)},
+			port:           80,
+			expectedDenied: []string{"ns1/db->ns1/web"},
+		},
+		{
+			name: "k8s-egress-ip-block-except",
+			nps: []*networkingv1.NetworkPolicy{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "db-egress"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+					Egress: []networkingv1.NetworkPolicyEgressRule{{
+						To: []networkingv1.NetworkPolicyPeer{{
+							IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/16", Except: []string{"10.0.1.0/24"}},
+						}},
+					}},
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+				},
+			}},
+			port:           80,
+			expectedDenied: []string{"ns1/db->ns2/client"},
+		},
+		{
+			name: "cnp-allow-overrides-k8s-policy",
+			nps:  []*networkingv1.NetworkPolicy{denyAllIngressNS1},
+			cnps: []*secv1alpha1.ClusterNetworkPolicy{{
+				ObjectMeta: metav1.ObjectMeta{Name: "allow-client"},
+				Spec: secv1alpha1.ClusterNetworkPolicySpec{
+					Priority: 10,
+					AppliedTo: []secv1alpha1.NetworkPolicyPeer{
+						{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+					},
+					Ingress: []secv1alpha1.Rule{{
+						Action: &allowAction,
+						From: []secv1alpha1.NetworkPolicyPeer{
+							{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "client"}}},
+						},
+					}},
+				},
+			}},
+			port:           80,
+			expectedDenied: []string{"ns1/web->ns1/db", "ns1/db->ns1/web", "ns2/client->ns1/db"},
+		},
+		{
+			name: "cnp-priority",
+			cnps: []*secv1alpha1.ClusterNetworkPolicy{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "allow-dev"},
+					Spec: secv1alpha1.ClusterNetworkPolicySpec{
+						Priority: 10,
+						AppliedTo: []secv1alpha1.NetworkPolicyPeer{
+							{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}},
+						},
+						Ingress: []secv1alpha1.Rule{{
+							Action: &allowAction,
+							From: []secv1alpha1.NetworkPolicyPeer{
+								{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}},
+							},
+						}},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "drop-dev-to-db"},
+					Spec: secv1alpha1.ClusterNetworkPolicySpec{
+						Priority: 5,
+						AppliedTo: []secv1alpha1.NetworkPolicyPeer{
+							{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}},
+						},
+						Ingress: []secv1alpha1.Rule{{
+							Action: &dropAction,
+							From: []secv1alpha1.NetworkPolicyPeer{
+								{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}},
+							},
+						}},
+					},
+				},
+			},
+			port:           80,
+			expectedDenied: []string{"ns2/client->ns1/db"},
+		},
+		{
+			name: "cnp-rule-order",
+			cnps: []*secv1alpha1.ClusterNetworkPolicy{{
+				ObjectMeta: metav1.ObjectMeta{Name: "egress"},
+				Spec: secv1alpha1.ClusterNetworkPolicySpec{
+					Priority: 10,
+					AppliedTo: []secv1alpha1.NetworkPolicyPeer{
+						{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "client"}}},
+					},
+					Egress: []secv1alpha1.Rule{
+						{
+							Action: &allowAction,
+							To: []secv1alpha1.NetworkPolicyPeer{
+								{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+							},
+						},
+						{
+							Action: &dropAction,
+							To: []secv1alpha1.NetworkPolicyPeer{
+								{IPBlock: &secv1alpha1.IPBlock{CIDR: "10.0.0.0/24"}},
+							},
+						},
+					},
+				},
+			}},
+			port:           80,
+			expectedDenied: []string{"ns2/client->ns1/db"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pods, namespaces := simulatorFixture()
+			s := NewSimulator(pods, namespaces)
+			for _, np := range tt.nps {
+				s.AddNetworkPolicy(np)
+			}
+			for _, cnp := range tt.cnps {
+				s.AddClusterNetworkPolicy(cnp)
+			}
+			assert.Len(t, s.Pods(), 3)
+			allowed := s.Simulate(networking.ProtocolTCP, tt.port)
+			assert.Equal(t, sets.NewString(tt.expectedDenied...), deniedConnections(s.Pods(), allowed))
+		})
+	}
+}
+
+func BenchmarkSimulatorSimulate(b *testing.B) {
+	var pods []*v1.Pod
+	var namespaces []*v1.Namespace
+	// 1000 Pods in 10 Namespaces, each Pod with one of 10 app labels.
+	for i := 0; i < 10; i++ {
+		ns := fmt.Sprintf("ns%d", i)
+		namespaces = append(namespaces, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns, Labels: map[string]string{"env": ns}}})
+		for j := 0; j < 100; j++ {
+			labels := map[string]string{"app": fmt.Sprintf("app%d", j%10)}
+			pods = append(pods, newSimulatorPod(ns, fmt.Sprintf("pod%d", j), fmt.Sprintf("10.0.%d.%d", i, j+1), labels))
+		}
+	}
+	s := NewSimulator(pods, namespaces)
+	for i := 0; i < 10; i++ {
+		s.AddNetworkPolicy(&networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: fmt.Sprintf("ns%d", i), Name: "allow-app"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": fmt.Sprintf("app%d", i)}},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					From: []networkingv1.NetworkPolicyPeer{{
+						PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": fmt.Sprintf("app%d", (i+1)%10)}},
+					}},
+				}},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			},
+		})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Simulate(networking.ProtocolTCP, 80)
+	}
+}
@@ -113,6 +113,8 @@ func ToNetworkPolicyMsg(in *types.NetworkPolicy, out *networking.NetworkPolicy,
 	out.Rules = in.Rules
 	out.AppliedToGroups = in.AppliedToGroups
 	out.Priority = in.Priority
+	out.TierPriority = in.TierPriority
+	out.CreationTimestamp = in.CreationTimestamp
 }
 
 // NetworkPolicyKeyFunc knows how to get the key of a NetworkPolicy.
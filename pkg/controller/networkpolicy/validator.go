@@ -0,0 +1,206 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	admv1beta1 "k8s.io/api/admission/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+	"github.com/vmware-tanzu/antrea/pkg/apiserver/handlers/webhook"
+)
+
+const (
+	minPolicyPriority = 1
+	maxPolicyPriority = 10000
+)
+
+// NetworkPolicyValidator validates the Antrea NetworkPolicies and the
+// ClusterNetworkPolicies when they are created or updated. A policy is rejected
+// if its priority is out of range, if a rule has overlapping ports or an
+// unsupported protocol, if a CIDR has host bits set, if a peer sets both an
+// empty podSelector and an empty namespaceSelector, if a nodeSelector is used
+// outside of ClusterNetworkPolicy ingress peers, or if preserveSourceIP is set
+// in a rule other than an egress rule allowing all the matched traffic. It does
+// not depend on any other object, so that it can answer quickly.
+type NetworkPolicyValidator struct{}
+
+var _ webhook.Validator = new(NetworkPolicyValidator)
+
+// NewNetworkPolicyValidator creates a new NetworkPolicyValidator.
+func NewNetworkPolicyValidator() *NetworkPolicyValidator {
+	return &NetworkPolicyValidator{}
+}
+
+// Validate validates the policy of the admission request.
+func (v *NetworkPolicyValidator) Validate(request *admv1beta1.AdmissionRequest) *admv1beta1.AdmissionResponse {
+	if request.Kind.Group != secv1alpha1.GroupName {
+		return webhook.Allow()
+	}
+	if request.Operation != admv1beta1.Create && request.Operation != admv1beta1.Update {
+		return webhook.Allow()
+	}
+	var meta metav1.ObjectMeta
+	var err error
+	switch request.Kind.Kind {
+	case "ClusterNetworkPolicy":
+		var cnp secv1alpha1.ClusterNetworkPolicy
+		if err := json.Unmarshal(request.Object.Raw, &cnp); err != nil {
+			return webhook.Deny("Invalid ClusterNetworkPolicy: %v", err)
+		}
+		meta = cnp.ObjectMeta
+		err = validatePolicySpec(cnp.Spec.Priority, cnp.Spec.AppliedTo, cnp.Spec.Ingress, cnp.Spec.Egress, true)
+		if err == nil {
+			err = validateTier(cnp.Spec.Tier)
+		}
+	case "NetworkPolicy":
+		var np secv1alpha1.NetworkPolicy
+		if err := json.Unmarshal(request.Object.Raw, &np); err != nil {
+			return webhook.Deny("Invalid NetworkPolicy: %v", err)
+		}
+		meta = np.ObjectMeta
+		err = validatePolicySpec(np.Spec.Priority, np.Spec.AppliedTo, np.Spec.Ingress, np.Spec.Egress, false)
+	default:
+		return webhook.Allow()
+	}
+	if err != nil {
+		return webhook.Deny("%s %s is invalid: %v", request.Kind.Kind, meta.Name, err)
+	}
+	return webhook.Allow()
+}
+
+// validatePolicySpec validates the spec of an Antrea NetworkPolicy or
+// ClusterNetworkPolicy. The returned error includes the path of the invalid
+// field. nodeSelector peers are only accepted in the From fields of ingress
+// rules, and only if allowNodeSelector is true.
+func validatePolicySpec(priority float64, appliedTo []secv1alpha1.NetworkPolicyPeer, ingress, egress []secv1alpha1.Rule, allowNodeSelector bool) error {
+	if priority < minPolicyPriority || priority > maxPolicyPriority {
+		return fmt.Errorf("spec.priority: priority %v must be between %d and %d", priority, minPolicyPriority, maxPolicyPriority)
+	}
+	if err := validatePeers("spec.appliedTo", appliedTo, false); err != nil {
+		return err
+	}
+	for i, rule := range ingress {
+		path := fmt.Sprintf("spec.ingress[%d]", i)
+		if err := validatePorts(path+".ports", rule.Ports); err != nil {
+			return err
+		}
+		if err := validatePeers(path+".from", rule.From, allowNodeSelector); err != nil {
+			return err
+		}
+		if rule.PreserveSourceIP {
+			return fmt.Errorf("%s.preserveSourceIP: preserveSourceIP is only supported in egress rules", path)
+		}
+	}
+	for i, rule := range egress {
+		path := fmt.Sprintf("spec.egress[%d]", i)
+		if err := validatePorts(path+".ports", rule.Ports); err != nil {
+			return err
+		}
+		if err := validatePeers(path+".to", rule.To, false); err != nil {
+			return err
+		}
+		if rule.PreserveSourceIP {
+			if rule.Action != nil && *rule.Action == secv1alpha1.RuleActionDrop {
+				return fmt.Errorf("%s.preserveSourceIP: preserveSourceIP is not supported in rules with Drop action", path)
+			}
+			if len(rule.HTTPMatches) > 0 {
+				return fmt.Errorf("%s.preserveSourceIP: preserveSourceIP cannot be set with httpMatches", path)
+			}
+		}
+	}
+	return nil
+}
+
+// validateTier validates the tier of a ClusterNetworkPolicy, which must be empty
+// for the default tier or set to the baseline tier.
+func validateTier(tier string) error {
+	if tier != "" && tier != secv1alpha1.TierBaseline {
+		return fmt.Errorf("spec.tier: unsupported tier %q, only %q can be set", tier, secv1alpha1.TierBaseline)
+	}
+	return nil
+}
+
+// portProtocol returns the protocol of the port, which defaults to TCP.
+func portProtocol(port secv1alpha1.NetworkPolicyPort) v1.Protocol {
+	if port.Protocol == nil {
+		return v1.ProtocolTCP
+	}
+	return *port.Protocol
+}
+
+// portsOverlap returns true if some traffic matches both ports. A port which is
+// not set matches all the ports of its protocol.
+func portsOverlap(a, b secv1alpha1.NetworkPolicyPort) bool {
+	if portProtocol(a) != portProtocol(b) {
+		return false
+	}
+	if a.Port == nil || b.Port == nil {
+		return true
+	}
+	return a.Port.String() == b.Port.String()
+}
+
+func validatePorts(path string, ports []secv1alpha1.NetworkPolicyPort) error {
+	for i := range ports {
+		switch protocol := portProtocol(ports[i]); protocol {
+		case v1.ProtocolTCP, v1.ProtocolUDP, v1.ProtocolSCTP:
+		default:
+			return fmt.Errorf("%s[%d].protocol: unsupported protocol %q, must be one of TCP, UDP and SCTP", path, i, protocol)
+		}
+		for j := 0; j < i; j++ {
+			if portsOverlap(ports[j], ports[i]) {
+				return fmt.Errorf("%s[%d]: port overlaps with %s[%d] in the same rule", path, i, path, j)
+			}
+		}
+	}
+	return nil
+}
+
+func isEmptySelector(selector *metav1.LabelSelector) bool {
+	return selector != nil && len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0
+}
+
+func validatePeers(path string, peers []secv1alpha1.NetworkPolicyPeer, allowNodeSelector bool) error {
+	for i, peer := range peers {
+		if peer.NodeSelector != nil {
+			if !allowNodeSelector {
+				return fmt.Errorf("%s[%d].nodeSelector: nodeSelector is only supported in the from field of ClusterNetworkPolicy ingress rules", path, i)
+			}
+			if peer.IPBlock != nil || peer.PodSelector != nil || peer.NamespaceSelector != nil || peer.ExternalEntitySelector != nil || peer.FQDN != "" {
+				return fmt.Errorf("%s[%d]: nodeSelector cannot be set with any other field", path, i)
+			}
+			continue
+		}
+		if peer.IPBlock != nil {
+			ip, ipNet, err := net.ParseCIDR(peer.IPBlock.CIDR)
+			if err != nil {
+				return fmt.Errorf("%s[%d].ipBlock.cidr: invalid CIDR %q", path, i, peer.IPBlock.CIDR)
+			}
+			if !ip.Equal(ipNet.IP) {
+				return fmt.Errorf("%s[%d].ipBlock.cidr: CIDR %s has host bits set, did you mean %s?", path, i, peer.IPBlock.CIDR, ipNet)
+			}
+		}
+		if isEmptySelector(peer.PodSelector) && isEmptySelector(peer.NamespaceSelector) {
+			return fmt.Errorf("%s[%d]: podSelector and namespaceSelector cannot both be empty, set only namespaceSelector to {} to select all the Pods", path, i)
+		}
+	}
+	return nil
+}
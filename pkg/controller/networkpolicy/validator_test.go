@@ -0,0 +1,278 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admv1beta1 "k8s.io/api/admission/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+)
+
+func newCNPRequest(t *testing.T, operation admv1beta1.Operation, cnp *secv1alpha1.ClusterNetworkPolicy) *admv1beta1.AdmissionRequest {
+	raw, err := json.Marshal(cnp)
+	require.NoError(t, err)
+	return &admv1beta1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Group: secv1alpha1.GroupName, Version: "v1alpha1", Kind: "ClusterNetworkPolicy"},
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+}
+
+func newValidCNP() *secv1alpha1.ClusterNetworkPolicy {
+	protocolUDP := v1.ProtocolUDP
+	port80 := intstr.FromInt(80)
+	port53 := intstr.FromInt(53)
+	return &secv1alpha1.ClusterNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "cnp1"},
+		Spec: secv1alpha1.ClusterNetworkPolicySpec{
+			Priority: 10,
+			AppliedTo: []secv1alpha1.NetworkPolicyPeer{
+				{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+			},
+			Ingress: []secv1alpha1.Rule{
+				{
+					Ports: []secv1alpha1.NetworkPolicyPort{{Port: &port80}, {Protocol: &protocolUDP, Port: &port80}},
+					From: []secv1alpha1.NetworkPolicyPeer{
+						{IPBlock: &secv1alpha1.IPBlock{CIDR: "10.0.0.0/24"}},
+						{PodSelector: &metav1.LabelSelector{}, NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}},
+					},
+				},
+			},
+			Egress: []secv1alpha1.Rule{
+				{
+					Ports: []secv1alpha1.NetworkPolicyPort{{Protocol: &protocolUDP, Port: &port53}},
+					To:    []secv1alpha1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{}}},
+				},
+			},
+		},
+	}
+}
+
+func TestNetworkPolicyValidator(t *testing.T) {
+	drop := secv1alpha1.RuleActionDrop
+	protocolICMP := v1.Protocol("ICMP")
+	port80 := intstr.FromInt(80)
+	portHTTP := intstr.FromString("http")
+	tests := []struct {
+		name        string
+		operation   admv1beta1.Operation
+		mutate      func(cnp *secv1alpha1.ClusterNetworkPolicy)
+		expectedMsg string
+	}{
+		{
+			name:      "valid policy",
+			operation: admv1beta1.Create,
+			mutate:    func(cnp *secv1alpha1.ClusterNetworkPolicy) {},
+		},
+		{
+			name:      "priority too low",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Priority = 0.5
+			},
+			expectedMsg: "ClusterNetworkPolicy cnp1 is invalid: spec.priority: priority 0.5 must be between 1 and 10000",
+		},
+		{
+			name:      "priority too high",
+			operation: admv1beta1.Update,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Priority = 10001
+			},
+			expectedMsg: "ClusterNetworkPolicy cnp1 is invalid: spec.priority: priority 10001 must be between 1 and 10000",
+		},
+		{
+			name:      "baseline tier",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Tier = secv1alpha1.TierBaseline
+			},
+		},
+		{
+			name:      "unsupported tier",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Tier = "emergency"
+			},
+			expectedMsg: `ClusterNetworkPolicy cnp1 is invalid: spec.tier: unsupported tier "emergency", only "baseline" can be set`,
+		},
+		{
+			name:      "duplicate ports",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Ingress[0].Ports = append(cnp.Spec.Ingress[0].Ports, secv1alpha1.NetworkPolicyPort{Port: &port80})
+			},
+			expectedMsg: "ClusterNetworkPolicy cnp1 is invalid: spec.ingress[0].ports[2]: port overlaps with spec.ingress[0].ports[0] in the same rule",
+		},
+		{
+			name:      "all ports overlap with a named port",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Egress[0].Ports = []secv1alpha1.NetworkPolicyPort{{Port: &portHTTP}, {}}
+			},
+			expectedMsg: "ClusterNetworkPolicy cnp1 is invalid: spec.egress[0].ports[1]: port overlaps with spec.egress[0].ports[0] in the same rule",
+		},
+		{
+			name:      "unknown protocol",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Egress[0].Ports[0].Protocol = &protocolICMP
+			},
+			expectedMsg: `ClusterNetworkPolicy cnp1 is invalid: spec.egress[0].ports[0].protocol: unsupported protocol "ICMP", must be one of TCP, UDP and SCTP`,
+		},
+		{
+			name:      "CIDR with host bits",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Ingress[0].From[0].IPBlock.CIDR = "10.0.0.1/24"
+			},
+			expectedMsg: "ClusterNetworkPolicy cnp1 is invalid: spec.ingress[0].from[0].ipBlock.cidr: CIDR 10.0.0.1/24 has host bits set, did you mean 10.0.0.0/24?",
+		},
+		{
+			name:      "invalid CIDR",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Ingress[0].From[0].IPBlock.CIDR = "10.0.0.0"
+			},
+			expectedMsg: `ClusterNetworkPolicy cnp1 is invalid: spec.ingress[0].from[0].ipBlock.cidr: invalid CIDR "10.0.0.0"`,
+		},
+		{
+			name:      "empty podSelector and namespaceSelector",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Egress[0].To[0].PodSelector = &metav1.LabelSelector{}
+			},
+			expectedMsg: "ClusterNetworkPolicy cnp1 is invalid: spec.egress[0].to[0]: podSelector and namespaceSelector cannot both be empty, set only namespaceSelector to {} to select all the Pods",
+		},
+		{
+			name:      "empty selectors in appliedTo",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.AppliedTo = []secv1alpha1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}, NamespaceSelector: &metav1.LabelSelector{}}}
+			},
+			expectedMsg: "ClusterNetworkPolicy cnp1 is invalid: spec.appliedTo[0]: podSelector and namespaceSelector cannot both be empty, set only namespaceSelector to {} to select all the Pods",
+		},
+		{
+			name:      "nodeSelector in ingress rule",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Ingress[0].From = append(cnp.Spec.Ingress[0].From, secv1alpha1.NetworkPolicyPeer{NodeSelector: &metav1.LabelSelector{}})
+			},
+		},
+		{
+			name:      "nodeSelector in egress rule",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Egress[0].To[0] = secv1alpha1.NetworkPolicyPeer{NodeSelector: &metav1.LabelSelector{}}
+			},
+			expectedMsg: "ClusterNetworkPolicy cnp1 is invalid: spec.egress[0].to[0].nodeSelector: nodeSelector is only supported in the from field of ClusterNetworkPolicy ingress rules",
+		},
+		{
+			name:      "nodeSelector with podSelector",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Ingress[0].From[1].NodeSelector = &metav1.LabelSelector{}
+			},
+			expectedMsg: "ClusterNetworkPolicy cnp1 is invalid: spec.ingress[0].from[1]: nodeSelector cannot be set with any other field",
+		},
+		{
+			name:      "preserveSourceIP in egress rule",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Egress[0].PreserveSourceIP = true
+			},
+		},
+		{
+			name:      "preserveSourceIP in ingress rule",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Ingress[0].PreserveSourceIP = true
+			},
+			expectedMsg: "ClusterNetworkPolicy cnp1 is invalid: spec.ingress[0].preserveSourceIP: preserveSourceIP is only supported in egress rules",
+		},
+		{
+			name:      "preserveSourceIP in drop rule",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Egress[0].Action = &drop
+				cnp.Spec.Egress[0].PreserveSourceIP = true
+			},
+			expectedMsg: "ClusterNetworkPolicy cnp1 is invalid: spec.egress[0].preserveSourceIP: preserveSourceIP is not supported in rules with Drop action",
+		},
+		{
+			name:      "preserveSourceIP with httpMatches",
+			operation: admv1beta1.Create,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Egress[0].HTTPMatches = []secv1alpha1.HTTPMatch{{Method: "GET"}}
+				cnp.Spec.Egress[0].PreserveSourceIP = true
+			},
+			expectedMsg: "ClusterNetworkPolicy cnp1 is invalid: spec.egress[0].preserveSourceIP: preserveSourceIP cannot be set with httpMatches",
+		},
+		{
+			name:      "deletion is not validated",
+			operation: admv1beta1.Delete,
+			mutate: func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+				cnp.Spec.Priority = 0
+			},
+		},
+	}
+	v := NewNetworkPolicyValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnp := newValidCNP()
+			tt.mutate(cnp)
+			response := v.Validate(newCNPRequest(t, tt.operation, cnp))
+			if tt.expectedMsg == "" {
+				assert.True(t, response.Allowed)
+			} else {
+				assert.False(t, response.Allowed)
+				assert.Equal(t, tt.expectedMsg, response.Result.Message)
+			}
+		})
+	}
+}
+
+func TestNetworkPolicyValidatorNamespacedPolicy(t *testing.T) {
+	np := &secv1alpha1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "np1"},
+		Spec: secv1alpha1.NetworkPolicySpec{
+			Priority:  20000,
+			AppliedTo: []secv1alpha1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}},
+		},
+	}
+	raw, err := json.Marshal(np)
+	require.NoError(t, err)
+	request := &admv1beta1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Group: secv1alpha1.GroupName, Version: "v1alpha1", Kind: "NetworkPolicy"},
+		Operation: admv1beta1.Create,
+		Namespace: "ns1",
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+	response := NewNetworkPolicyValidator().Validate(request)
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "NetworkPolicy np1 is invalid: spec.priority: priority 20000 must be between 1 and 10000", response.Result.Message)
+
+	// A Kubernetes NetworkPolicy is not validated.
+	request.Kind.Group = "networking.k8s.io"
+	assert.True(t, NewNetworkPolicyValidator().Validate(request).Allowed)
+}
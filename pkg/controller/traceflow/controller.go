@@ -18,12 +18,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
+	"sort"
 	"sync"
 	"time"
 
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
@@ -48,33 +53,54 @@ const (
 	// dataplaneTag=15 is reserved.
 	minTagNum uint8 = 1
 	maxTagNum uint8 = 14
+
+	// Max number of live traceflows running at the same time in the cluster. Each captured packet is sent to the
+	// Antrea Agents, hence the limit avoids overwhelming the OpenFlow channels with live traffic.
+	maxLiveTraceflows = 10
+
+	// DefaultHistorySize is the default number of completed traceflows whose records are kept.
+	DefaultHistorySize = 100
 )
 
 var (
 	// Traceflow timeout period.
 	timeout = (300 * time.Second).Seconds()
+
+	errTooManyLiveTraceflows = fmt.Errorf("at most %d live traceflows can run at the same time", maxLiveTraceflows)
 )
 
 // Controller is for traceflow.
 type Controller struct {
-	client                 versioned.Interface
-	traceflowInformer      opsinformers.TraceflowInformer
-	traceflowLister        opslisters.TraceflowLister
-	traceflowListerSynced  cache.InformerSynced
-	queue                  workqueue.RateLimitingInterface
-	runningTraceflowsMutex sync.Mutex
-	runningTraceflows      map[uint8]string // tag->traceflowName if tf.Status.Phase is Running.
+	client                       versioned.Interface
+	traceflowInformer            opsinformers.TraceflowInformer
+	traceflowLister              opslisters.TraceflowLister
+	traceflowListerSynced        cache.InformerSynced
+	traceflowHistoryLister       opslisters.TraceflowHistoryLister
+	traceflowHistoryListerSynced cache.InformerSynced
+	queue                        workqueue.RateLimitingInterface
+	runningTraceflowsMutex       sync.Mutex
+	runningTraceflows            map[uint8]string // tag->traceflowName if tf.Status.Phase is Running.
+	runningLiveTraceflows        sets.String      // traceflowNames of the running traceflows which trace live traffic.
+	// historyMutex serializes the recording of traceflow histories, so that the workers do not evict the same
+	// records concurrently.
+	historyMutex sync.Mutex
+	// historySize is the max number of traceflow histories. The oldest ones are evicted first.
+	historySize int
 }
 
 // NewTraceflowController creates a new traceflow controller.
-func NewTraceflowController(client versioned.Interface, traceflowInformer opsinformers.TraceflowInformer) *Controller {
+func NewTraceflowController(client versioned.Interface, traceflowInformer opsinformers.TraceflowInformer, traceflowHistoryInformer opsinformers.TraceflowHistoryInformer, historySize int) *Controller {
 	c := &Controller{
-		client:                client,
-		traceflowInformer:     traceflowInformer,
-		traceflowLister:       traceflowInformer.Lister(),
-		traceflowListerSynced: traceflowInformer.Informer().HasSynced,
-		queue:                 workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "traceflow"),
-		runningTraceflows:     make(map[uint8]string)}
+		client:                       client,
+		traceflowInformer:            traceflowInformer,
+		traceflowLister:              traceflowInformer.Lister(),
+		traceflowListerSynced:        traceflowInformer.Informer().HasSynced,
+		traceflowHistoryLister:       traceflowHistoryInformer.Lister(),
+		traceflowHistoryListerSynced: traceflowHistoryInformer.Informer().HasSynced,
+		queue:                        workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "traceflow"),
+		runningTraceflows:            make(map[uint8]string),
+		runningLiveTraceflows:        sets.NewString(),
+		historySize:                  historySize}
 	// Add handlers for ClusterNetworkPolicy events.
 	traceflowInformer.Informer().AddEventHandlerWithResyncPeriod(
 		cache.ResourceEventHandlerFuncs{
@@ -99,7 +125,7 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 	defer klog.Info("Shutting down Traceflow controller")
 
 	klog.Info("Waiting for caches to sync for Traceflow controller")
-	if !cache.WaitForCacheSync(stopCh, c.traceflowListerSynced) {
+	if !cache.WaitForCacheSync(stopCh, c.traceflowListerSynced, c.traceflowHistoryListerSynced) {
 		klog.Error("Unable to sync caches for Traceflow controller")
 		return
 	}
@@ -207,10 +233,105 @@ func (c *Controller) syncTraceflow(traceflowName string) (retry bool, err error)
 		retry, err = c.checkTraceflowStatus(tf)
 	default:
 		c.deallocateTag(tf)
+		err = c.recordTraceflowHistory(tf)
 	}
 	return
 }
 
+// recordTraceflowHistory records a completed traceflow, and evicts the histories of the traceflows which completed
+// first if there are more than historySize of them. The history is named after the UID of the traceflow, so that it
+// is recorded only once and is not mixed up with a recreated traceflow with the same name.
+func (c *Controller) recordTraceflowHistory(tf *opsv1alpha1.Traceflow) error {
+	c.historyMutex.Lock()
+	defer c.historyMutex.Unlock()
+
+	name := string(tf.UID)
+	if _, err := c.traceflowHistoryLister.Get(name); err == nil {
+		return nil
+	}
+	history := &opsv1alpha1.TraceflowHistory{
+		ObjectMeta:     v1.ObjectMeta{Name: name},
+		TraceflowName:  tf.Name,
+		Creator:        traceflowCreator(tf),
+		StartTime:      tf.CreationTimestamp,
+		CompletionTime: traceflowCompletionTime(tf),
+		Spec:           tf.Spec,
+		Status:         tf.Status,
+	}
+	history, err := c.client.OpsV1alpha1().TraceflowHistories().Create(context.TODO(), history, v1.CreateOptions{})
+	if err != nil {
+		if k8serrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to record history of Traceflow %s: %v", tf.Name, err)
+	}
+
+	histories, err := c.traceflowHistoryLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	// The new history may not be in the informer cache yet.
+	for _, h := range histories {
+		if h.Name == history.Name {
+			history = nil
+			break
+		}
+	}
+	if history != nil {
+		histories = append(histories, history)
+	}
+	if len(histories) <= c.historySize {
+		return nil
+	}
+	sort.Slice(histories, func(i, j int) bool {
+		return histories[i].CompletionTime.Before(&histories[j].CompletionTime)
+	})
+	for _, h := range histories[:len(histories)-c.historySize] {
+		klog.V(2).Infof("Evicting history of Traceflow %s completed at %v", h.TraceflowName, h.CompletionTime)
+		if err := c.client.OpsV1alpha1().TraceflowHistories().Delete(context.TODO(), h.Name, v1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to evict history of Traceflow %s: %v", h.TraceflowName, err)
+		}
+	}
+	return nil
+}
+
+// traceflowCreator returns the field manager which created the traceflow, or an empty string if it is unknown. The
+// creation of an object is recorded as an Update operation of its field manager, so the creator is the manager of the
+// earliest Update entry.
+func traceflowCreator(tf *opsv1alpha1.Traceflow) string {
+	var creator *v1.ManagedFieldsEntry
+	for i := range tf.ManagedFields {
+		entry := &tf.ManagedFields[i]
+		if entry.Operation != v1.ManagedFieldsOperationUpdate || entry.Time == nil {
+			continue
+		}
+		if creator == nil || entry.Time.Before(creator.Time) {
+			creator = entry
+		}
+	}
+	if creator == nil {
+		return ""
+	}
+	return creator.Manager
+}
+
+// traceflowCompletionTime returns the time at which the traceflow was last updated, i.e. when the Antrea Controller
+// updated its phase, so that a traceflow recorded again after its history was evicted, e.g. after a restart of the
+// Antrea Controller, is still ordered by its actual completion time. The current time is returned if the update time
+// is unknown.
+func traceflowCompletionTime(tf *opsv1alpha1.Traceflow) v1.Time {
+	var completionTime *v1.Time
+	for _, entry := range tf.ManagedFields {
+		if entry.Time != nil && (completionTime == nil || completionTime.Before(entry.Time)) {
+			completionTime = entry.Time
+		}
+	}
+	if completionTime == nil {
+		return v1.Now()
+	}
+	return *completionTime
+}
+
 func (c *Controller) startTraceflow(tf *opsv1alpha1.Traceflow) (*opsv1alpha1.Traceflow, error) {
 	// Validate if the traceflow request meets requirement.
 	if err := validate(tf); err != nil {
@@ -220,6 +341,12 @@ func (c *Controller) startTraceflow(tf *opsv1alpha1.Traceflow) (*opsv1alpha1.Tra
 
 	// Allocate data plane tag.
 	tag, err := c.allocateTag(tf)
+	if err == errTooManyLiveTraceflows {
+		// Live traceflows are rejected instead of waiting for a running one to complete, as the live traffic
+		// to trace may be gone by then.
+		c.errorTraceflowCRD(tf, err.Error())
+		return nil, err
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -227,6 +354,9 @@ func (c *Controller) startTraceflow(tf *opsv1alpha1.Traceflow) (*opsv1alpha1.Tra
 }
 
 func (c *Controller) checkTraceflowStatus(tf *opsv1alpha1.Traceflow) (retry bool, err error) {
+	if tf.Spec.Live {
+		return c.checkLiveTraceflowStatus(tf)
+	}
 	retry = false
 	sender := false
 	receiver := false
@@ -253,6 +383,56 @@ func (c *Controller) checkTraceflowStatus(tf *opsv1alpha1.Traceflow) (retry bool
 	return
 }
 
+// checkLiveTraceflowStatus checks whether the requested number of live packets are captured. Each captured packet
+// is either delivered or dropped by the last Node it traverses. The live traceflow succeeds on timeout if some
+// packets are captured, as packets may not match the traceflow any more, e.g. the connection is closed.
+func (c *Controller) checkLiveTraceflowStatus(tf *opsv1alpha1.Traceflow) (retry bool, err error) {
+	var captured, completed int32
+	for _, nodeResult := range tf.Status.Results {
+		for _, ob := range nodeResult.Observations {
+			if ob.Component == opsv1alpha1.SpoofGuard {
+				captured++
+			}
+			if ob.Action == opsv1alpha1.Delivered || ob.Action == opsv1alpha1.Dropped {
+				completed++
+			}
+		}
+	}
+	packetCount := tf.Spec.PacketCount
+	if packetCount == 0 {
+		packetCount = opsv1alpha1.DefaultLivePacketCount
+	}
+	if completed >= packetCount {
+		_, err = c.succeededTraceflowCRD(tf, "")
+		return
+	}
+	liveTimeout := tf.Spec.Timeout
+	if liveTimeout == 0 {
+		liveTimeout = opsv1alpha1.DefaultLiveTimeout
+	}
+	if time.Now().UTC().Sub(tf.CreationTimestamp.UTC()).Seconds() > float64(liveTimeout) {
+		if captured == 0 {
+			_, err = c.errorTraceflowCRD(tf, "no live packet captured before timeout")
+		} else {
+			_, err = c.succeededTraceflowCRD(tf, fmt.Sprintf("%d out of %d live packets captured before timeout", captured, packetCount))
+		}
+		return
+	}
+	retry = true
+	return
+}
+
+func (c *Controller) succeededTraceflowCRD(tf *opsv1alpha1.Traceflow, reason string) (*opsv1alpha1.Traceflow, error) {
+	tf.Status.Phase = opsv1alpha1.Succeeded
+
+	type Traceflow struct {
+		Status opsv1alpha1.TraceflowStatus `json:"status,omitempty"`
+	}
+	patchData := Traceflow{Status: opsv1alpha1.TraceflowStatus{Phase: tf.Status.Phase, Reason: reason}}
+	payloads, _ := json.Marshal(patchData)
+	return c.client.OpsV1alpha1().Traceflows().Patch(context.TODO(), tf.Name, types.MergePatchType, payloads, v1.PatchOptions{})
+}
+
 func (c *Controller) runningTraceflowCRD(tf *opsv1alpha1.Traceflow, dataPlaneTag uint8) (*opsv1alpha1.Traceflow, error) {
 	tf.Status.DataplaneTag = dataPlaneTag
 	tf.Status.Phase = opsv1alpha1.Running
@@ -278,14 +458,53 @@ func (c *Controller) errorTraceflowCRD(tf *opsv1alpha1.Traceflow, reason string)
 
 // TODO: more restrictive validation in this function and CRD definition
 func validate(tf *opsv1alpha1.Traceflow) error {
-	if len(tf.Spec.Destination.Service) != 0 {
-		return errors.New("destination service is not supported")
+	destinations := 0
+	for _, d := range []string{tf.Spec.Destination.Pod, tf.Spec.Destination.Service, tf.Spec.Destination.IP} {
+		if len(d) != 0 {
+			destinations++
+		}
+	}
+	if destinations > 1 {
+		return errors.New("only one of destination pod, service and IP can be set")
+	}
+	if destinations == 0 {
+		return errors.New("one of destination pod, service and IP must be set")
+	}
+	if len(tf.Spec.Destination.Service) != 0 && len(tf.Spec.Destination.Namespace) == 0 {
+		return errors.New("destination namespace must be set for destination service")
+	}
+	if err := validateIPFamily(tf); err != nil {
+		return err
+	}
+	if !tf.Spec.Live && (tf.Spec.PacketCount != 0 || tf.Spec.Timeout != 0) {
+		return errors.New("packet count and timeout can only be set for live traceflow")
+	}
+	return nil
+}
+
+// validateIPFamily validates the IP addresses set in the traceflow spec. The source and destination IPs must be in
+// the same IP family.
+// IPv6 is not supported yet, as the OVS pipeline only forwards IPv4 traffic between Pods for now, and the packet
+// injected by the Agent is always an IPv4 packet.
+func validateIPFamily(tf *opsv1alpha1.Traceflow) error {
+	var srcIP, dstIP net.IP
+	if tf.Spec.Packet.IPHeader.SrcIP != "" {
+		if srcIP = net.ParseIP(tf.Spec.Packet.IPHeader.SrcIP); srcIP == nil {
+			return fmt.Errorf("invalid source IP %s", tf.Spec.Packet.IPHeader.SrcIP)
+		}
+	}
+	if tf.Spec.Destination.IP != "" {
+		if dstIP = net.ParseIP(tf.Spec.Destination.IP); dstIP == nil {
+			return fmt.Errorf("invalid destination IP %s", tf.Spec.Destination.IP)
+		}
 	}
-	if len(tf.Spec.Destination.Pod) != 0 && len(tf.Spec.Destination.IP) != 0 {
-		return errors.New("destination pod and IP cannot be both set")
+	if srcIP != nil && dstIP != nil && (srcIP.To4() == nil) != (dstIP.To4() == nil) {
+		return errors.New("source and destination IPs must be in the same IP family")
 	}
-	if len(tf.Spec.Destination.Pod) == 0 && len(tf.Spec.Destination.IP) == 0 {
-		return errors.New("destination pod and IP cannot be both not set")
+	for _, ip := range []net.IP{srcIP, dstIP} {
+		if ip != nil && ip.To4() == nil {
+			return errors.New("IPv6 is not supported")
+		}
 	}
 	return nil
 }
@@ -307,15 +526,24 @@ func (c *Controller) occupyTag(tf *opsv1alpha1.Traceflow) error {
 	}
 
 	c.runningTraceflows[tag] = tf.Name
+	if tf.Spec.Live {
+		c.runningLiveTraceflows.Insert(tf.Name)
+	}
 	return nil
 }
 
 func (c *Controller) allocateTag(tf *opsv1alpha1.Traceflow) (uint8, error) {
 	c.runningTraceflowsMutex.Lock()
 	defer c.runningTraceflowsMutex.Unlock()
+	if tf.Spec.Live && c.runningLiveTraceflows.Len() >= maxLiveTraceflows {
+		return 0, errTooManyLiveTraceflows
+	}
 	for i := minTagNum; i <= maxTagNum; i++ {
 		if _, ok := c.runningTraceflows[i]; !ok {
 			c.runningTraceflows[i] = tf.Name
+			if tf.Spec.Live {
+				c.runningLiveTraceflows.Insert(tf.Name)
+			}
 			return i, nil
 		}
 	}
@@ -332,6 +560,7 @@ func (c *Controller) deallocateTag(tf *opsv1alpha1.Traceflow) {
 	if existingTraceflowName, ok := c.runningTraceflows[tf.Status.DataplaneTag]; ok {
 		if tf.Name == existingTraceflowName {
 			delete(c.runningTraceflows, tf.Status.DataplaneTag)
+			c.runningLiveTraceflows.Delete(tf.Name)
 		}
 	}
 }
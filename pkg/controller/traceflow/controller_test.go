@@ -0,0 +1,299 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceflow
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	opsv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/ops/v1alpha1"
+	fakeversioned "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/fake"
+	crdinformers "github.com/vmware-tanzu/antrea/pkg/client/informers/externalversions"
+)
+
+func newController(objects ...*opsv1alpha1.Traceflow) *Controller {
+	client := fakeversioned.NewSimpleClientset()
+	for _, tf := range objects {
+		client.OpsV1alpha1().Traceflows().Create(context.TODO(), tf, metav1.CreateOptions{})
+	}
+	informerFactory := crdinformers.NewSharedInformerFactory(client, 0)
+	return NewTraceflowController(client, informerFactory.Ops().V1alpha1().Traceflows(), informerFactory.Ops().V1alpha1().TraceflowHistories(), DefaultHistorySize)
+}
+
+func newLiveTraceflow(name string, packetCount int32, created time.Time) *opsv1alpha1.Traceflow {
+	return &opsv1alpha1.Traceflow{
+		ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)},
+		Spec: opsv1alpha1.TraceflowSpec{
+			Source:      opsv1alpha1.Source{Namespace: "ns1", Pod: "pod1"},
+			Destination: opsv1alpha1.Destination{Namespace: "ns1", Pod: "pod2"},
+			Live:        true,
+			PacketCount: packetCount,
+		},
+		Status: opsv1alpha1.TraceflowStatus{Phase: opsv1alpha1.Running, DataplaneTag: 1},
+	}
+}
+
+func TestAllocateTagLiveTraceflows(t *testing.T) {
+	c := newController()
+	for i := 0; i < maxLiveTraceflows; i++ {
+		_, err := c.allocateTag(newLiveTraceflow(fmt.Sprintf("live-%d", i), 0, time.Now()))
+		require.NoError(t, err)
+	}
+	_, err := c.allocateTag(newLiveTraceflow("live-rejected", 0, time.Now()))
+	assert.Equal(t, errTooManyLiveTraceflows, err)
+	// The tags which are not used by live traceflows are still available to other traceflows.
+	tag, err := c.allocateTag(&opsv1alpha1.Traceflow{ObjectMeta: metav1.ObjectMeta{Name: "synthetic"}})
+	require.NoError(t, err)
+	assert.Equal(t, minTagNum+maxLiveTraceflows, tag)
+
+	// Completing a live traceflow releases its slot.
+	completed := newLiveTraceflow("live-0", 0, time.Now())
+	completed.Status.DataplaneTag = minTagNum
+	c.deallocateTag(completed)
+	_, err = c.allocateTag(newLiveTraceflow("live-accepted", 0, time.Now()))
+	assert.NoError(t, err)
+}
+
+func TestCheckLiveTraceflowStatus(t *testing.T) {
+	senderResult := opsv1alpha1.NodeResult{Observations: []opsv1alpha1.Observation{
+		{Component: opsv1alpha1.SpoofGuard, Action: opsv1alpha1.Forwarded},
+		{Component: opsv1alpha1.Forwarding, Action: opsv1alpha1.Forwarded, TunnelDstIP: "192.168.1.2"},
+	}}
+	receiverResult := opsv1alpha1.NodeResult{Observations: []opsv1alpha1.Observation{
+		{Component: opsv1alpha1.Forwarding, Action: opsv1alpha1.Received},
+		{Component: opsv1alpha1.Forwarding, Action: opsv1alpha1.Delivered},
+	}}
+	tests := []struct {
+		name          string
+		packetCount   int32
+		created       time.Time
+		results       []opsv1alpha1.NodeResult
+		expectedRetry bool
+		expectedPhase opsv1alpha1.TraceflowPhase
+	}{
+		{
+			name:          "capturing",
+			packetCount:   2,
+			created:       time.Now(),
+			results:       []opsv1alpha1.NodeResult{senderResult, receiverResult},
+			expectedRetry: true,
+			expectedPhase: opsv1alpha1.Running,
+		},
+		{
+			name:          "packets captured",
+			packetCount:   2,
+			created:       time.Now(),
+			results:       []opsv1alpha1.NodeResult{senderResult, receiverResult, senderResult, receiverResult},
+			expectedPhase: opsv1alpha1.Succeeded,
+		},
+		{
+			name:          "default packet count",
+			created:       time.Now(),
+			results:       []opsv1alpha1.NodeResult{senderResult, receiverResult},
+			expectedPhase: opsv1alpha1.Succeeded,
+		},
+		{
+			name:          "timeout with packets captured",
+			packetCount:   2,
+			created:       time.Now().Add(-time.Duration(opsv1alpha1.DefaultLiveTimeout+1) * time.Second),
+			results:       []opsv1alpha1.NodeResult{senderResult, receiverResult},
+			expectedPhase: opsv1alpha1.Succeeded,
+		},
+		{
+			name:          "timeout without packets captured",
+			created:       time.Now().Add(-time.Duration(opsv1alpha1.DefaultLiveTimeout+1) * time.Second),
+			expectedPhase: opsv1alpha1.Failed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf := newLiveTraceflow("tf", tt.packetCount, tt.created)
+			tf.Status.Results = tt.results
+			c := newController(tf)
+			retry, err := c.checkTraceflowStatus(tf)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedRetry, retry)
+			tf, err = c.client.OpsV1alpha1().Traceflows().Get(context.TODO(), "tf", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedPhase, tf.Status.Phase)
+		})
+	}
+}
+
+func TestValidateDestination(t *testing.T) {
+	tests := []struct {
+		name        string
+		destination opsv1alpha1.Destination
+		expectedErr string
+	}{
+		{
+			name:        "Pod",
+			destination: opsv1alpha1.Destination{Namespace: "ns1", Pod: "pod2"},
+		},
+		{
+			name:        "Service",
+			destination: opsv1alpha1.Destination{Namespace: "ns1", Service: "svc1"},
+		},
+		{
+			name:        "Service without Namespace",
+			destination: opsv1alpha1.Destination{Service: "svc1"},
+			expectedErr: "destination namespace must be set for destination service",
+		},
+		{
+			name:        "Service and IP",
+			destination: opsv1alpha1.Destination{Namespace: "ns1", Service: "svc1", IP: "10.10.1.1"},
+			expectedErr: "only one of destination pod, service and IP can be set",
+		},
+		{
+			name:        "no destination",
+			destination: opsv1alpha1.Destination{Namespace: "ns1"},
+			expectedErr: "one of destination pod, service and IP must be set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf := &opsv1alpha1.Traceflow{
+				Spec: opsv1alpha1.TraceflowSpec{
+					Source:      opsv1alpha1.Source{Namespace: "ns1", Pod: "pod1"},
+					Destination: tt.destination,
+				},
+			}
+			err := validate(tf)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestValidateIPFamily(t *testing.T) {
+	tests := []struct {
+		name        string
+		srcIP       string
+		dstIP       string
+		expectedErr string
+	}{
+		{
+			name:  "IPv4",
+			srcIP: "10.10.0.1",
+			dstIP: "10.10.1.1",
+		},
+		{
+			name:  "no source IP",
+			dstIP: "10.10.1.1",
+		},
+		{
+			name:        "invalid destination IP",
+			dstIP:       "10.10.1",
+			expectedErr: "invalid destination IP 10.10.1",
+		},
+		{
+			name:        "mixed IP families",
+			srcIP:       "10.10.0.1",
+			dstIP:       "fd00:10:10::1",
+			expectedErr: "source and destination IPs must be in the same IP family",
+		},
+		{
+			name:        "IPv6",
+			srcIP:       "fd00:10:10::2",
+			dstIP:       "fd00:10:10::1",
+			expectedErr: "IPv6 is not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf := &opsv1alpha1.Traceflow{
+				Spec: opsv1alpha1.TraceflowSpec{
+					Source:      opsv1alpha1.Source{Namespace: "ns1", Pod: "pod1"},
+					Destination: opsv1alpha1.Destination{IP: tt.dstIP},
+					Packet:      opsv1alpha1.Packet{IPHeader: opsv1alpha1.IPHeader{SrcIP: tt.srcIP}},
+				},
+			}
+			err := validate(tf)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRecordTraceflowHistory(t *testing.T) {
+	now := time.Now()
+	client := fakeversioned.NewSimpleClientset()
+	// The histories of 3 traceflows completed 1 to 3 minutes ago.
+	for i := 1; i <= 3; i++ {
+		client.OpsV1alpha1().TraceflowHistories().Create(context.TODO(), &opsv1alpha1.TraceflowHistory{
+			ObjectMeta:     metav1.ObjectMeta{Name: fmt.Sprintf("uid-%d", i)},
+			TraceflowName:  fmt.Sprintf("tf-%d", i),
+			CompletionTime: metav1.NewTime(now.Add(-time.Duration(i) * time.Minute)),
+		}, metav1.CreateOptions{})
+	}
+	informerFactory := crdinformers.NewSharedInformerFactory(client, 0)
+	c := NewTraceflowController(client, informerFactory.Ops().V1alpha1().Traceflows(), informerFactory.Ops().V1alpha1().TraceflowHistories(), 3)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informerFactory.Start(stopCh)
+	require.True(t, cache.WaitForCacheSync(stopCh, c.traceflowHistoryListerSynced))
+
+	created := metav1.NewTime(now.Add(-time.Minute))
+	updated := metav1.NewTime(now.Add(-30 * time.Second))
+	tf := &opsv1alpha1.Traceflow{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "tf-4",
+			UID:               types.UID("uid-4"),
+			CreationTimestamp: created,
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "antrea-controller", Operation: metav1.ManagedFieldsOperationUpdate, Time: &updated},
+				{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate, Time: &created},
+			},
+		},
+		Spec: opsv1alpha1.TraceflowSpec{
+			Source:      opsv1alpha1.Source{Namespace: "ns1", Pod: "pod1"},
+			Destination: opsv1alpha1.Destination{Namespace: "ns1", Pod: "pod2"},
+		},
+		Status: opsv1alpha1.TraceflowStatus{Phase: opsv1alpha1.Succeeded},
+	}
+	require.NoError(t, c.recordTraceflowHistory(tf))
+
+	history, err := client.OpsV1alpha1().TraceflowHistories().Get(context.TODO(), "uid-4", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "tf-4", history.TraceflowName)
+	assert.Equal(t, "kubectl", history.Creator)
+	assert.Equal(t, created, history.StartTime)
+	assert.Equal(t, updated, history.CompletionTime)
+	assert.Equal(t, tf.Spec, history.Spec)
+	assert.Equal(t, tf.Status, history.Status)
+
+	// The history of the traceflow which completed first is evicted.
+	histories, err := client.OpsV1alpha1().TraceflowHistories().List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	var names []string
+	for _, h := range histories.Items {
+		names = append(names, h.Name)
+	}
+	assert.ElementsMatch(t, []string{"uid-1", "uid-2", "uid-4"}, names)
+}
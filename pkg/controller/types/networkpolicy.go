@@ -15,6 +15,7 @@
 package types
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -93,9 +94,15 @@ type NetworkPolicy struct {
 	// Namespace of the original K8s Network Policy.
 	// An empty value indicates that the Network Policy is Cluster scoped.
 	Namespace string
+	// CreationTimestamp of the original Network Policy. It orders the Network
+	// Policies with the same Priority.
+	CreationTimestamp metav1.Time
 	// Priority represents the relative priority of this Network Policy as compared to
 	// other Network Policies. Priority will be unset (nil) for K8s Network Policy.
 	Priority *float64
+	// TierPriority represents the priority of the tier of this Network Policy. It
+	// will be unset (nil) for K8s Network Policy.
+	TierPriority *int32
 	// Rules is a list of rules to be applied to the selected Pods.
 	Rules []networking.NetworkPolicyRule
 	// AppliedToGroups is a list of names of AppliedToGroups to which this policy applies.
@@ -46,6 +46,27 @@ const (
 	// alpha: v0.9
 	// Flow exporter exports IPFIX flow records of Antrea flows seen in conntrack module.
 	FlowExporter featuregate.Feature = "FlowExporter"
+
+	// alpha: v0.9
+	// Make AntreaProxy track the Endpoints of Services from the EndpointSlice
+	// API instead of the Endpoints API. It requires AntreaProxy to be enabled.
+	EndpointSlice featuregate.Feature = "EndpointSlice"
+
+	// alpha: v0.9
+	// Allocate the IPs of the Pods from the IPPools selecting their Nodes or
+	// Namespaces, instead of the PodCIDR of the Nodes.
+	AntreaIPAM featuregate.Feature = "AntreaIPAM"
+
+	// alpha: v0.9
+	// SNAT the egress traffic of the Pods selected by Egresses to the Egress
+	// IPs, which are assigned to the Nodes by antrea-controller.
+	Egress featuregate.Feature = "Egress"
+
+	// alpha: v0.9
+	// Allows Multus to delegate the secondary network interfaces of the Pods
+	// to Antrea, which connects them to VLANs and allocates their IPs from
+	// IPPools. It requires AntreaIPAM to be enabled.
+	SecondaryNetwork featuregate.Feature = "SecondaryNetwork"
 )
 
 var (
@@ -64,6 +85,10 @@ var (
 		AntreaProxy:          {Default: false, PreRelease: featuregate.Alpha},
 		Traceflow:            {Default: false, PreRelease: featuregate.Alpha},
 		FlowExporter:         {Default: false, PreRelease: featuregate.Alpha},
+		EndpointSlice:        {Default: false, PreRelease: featuregate.Alpha},
+		AntreaIPAM:           {Default: false, PreRelease: featuregate.Alpha},
+		Egress:               {Default: false, PreRelease: featuregate.Alpha},
+		SecondaryNetwork:     {Default: false, PreRelease: featuregate.Alpha},
 	}
 )
 
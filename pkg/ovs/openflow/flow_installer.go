@@ -0,0 +1,117 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openflow
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+const (
+	// DefaultFlowInstallBatchSize is the default number of flows installed
+	// in a single bundle by a FlowInstaller.
+	DefaultFlowInstallBatchSize = 100
+	// DefaultFlowInstallWorkers is the default number of bundles sent
+	// concurrently by a FlowInstaller.
+	DefaultFlowInstallWorkers = 4
+)
+
+// FlowInstaller installs a large number of flows, for example when replaying
+// all the flows after OVS restarts. The flows are split into batches, and the
+// batches are installed in separate bundles sent concurrently by a pool of
+// workers. Unlike Bridge.AddFlowsInBundle, the installation is not atomic: if
+// a batch fails, the other batches are still installed.
+type FlowInstaller struct {
+	bridge    Bridge
+	batchSize int
+	workers   int
+}
+
+// NewFlowInstaller creates a FlowInstaller which installs batches of up to
+// batchSize flows with the given number of workers.
+func NewFlowInstaller(bridge Bridge, batchSize, workers int) *FlowInstaller {
+	return &FlowInstaller{
+		bridge:    bridge,
+		batchSize: batchSize,
+		workers:   workers,
+	}
+}
+
+// Install installs the flows table by table, from the last table of the
+// pipeline to the first one. All the flows of a table are installed before the
+// flows of the previous tables, whose goto_table and resubmit actions send the
+// packets to this table, so that a packet never reaches a table whose flows
+// are partially installed. The flows of a single table are installed
+// concurrently.
+func (i *FlowInstaller) Install(flows []Flow) error {
+	flowsByTable := make(map[TableIDType][]Flow)
+	for _, flow := range flows {
+		tableID := flow.TableID()
+		flowsByTable[tableID] = append(flowsByTable[tableID], flow)
+	}
+	tableIDs := make([]TableIDType, 0, len(flowsByTable))
+	for tableID := range flowsByTable {
+		tableIDs = append(tableIDs, tableID)
+	}
+	sort.Slice(tableIDs, func(a, b int) bool {
+		return tableIDs[a] > tableIDs[b]
+	})
+
+	var errs []error
+	for _, tableID := range tableIDs {
+		if err := i.installTableFlows(flowsByTable[tableID]); err != nil {
+			errs = append(errs, fmt.Errorf("error when installing flows in table %d: %v", tableID, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// installTableFlows installs the flows of a single table, and returns once all
+// the batches have been installed or have failed.
+func (i *FlowInstaller) installTableFlows(flows []Flow) error {
+	batchCh := make(chan []Flow)
+	go func() {
+		defer close(batchCh)
+		for start := 0; start < len(flows); start += i.batchSize {
+			end := start + i.batchSize
+			if end > len(flows) {
+				end = len(flows)
+			}
+			batchCh <- flows[start:end]
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var errsLock sync.Mutex
+	var errs []error
+	for w := 0; w < i.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batchCh {
+				if err := i.bridge.AddFlowsInBundle(batch, nil, nil); err != nil {
+					errsLock.Lock()
+					errs = append(errs, err)
+					errsLock.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return utilerrors.NewAggregate(errs)
+}
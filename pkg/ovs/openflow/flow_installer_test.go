@@ -0,0 +1,97 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openflow
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBridge records the flows installed with AddFlowsInBundle, and checks that
+// the flows of a table are never installed before all the flows of the later
+// tables.
+type fakeBridge struct {
+	Bridge
+	t             *testing.T
+	mutex         sync.Mutex
+	expectedFlows map[TableIDType]int
+	installed     map[TableIDType]int
+	batchSizes    []int
+	failedTable   TableIDType
+}
+
+func (b *fakeBridge) AddFlowsInBundle(addflows []Flow, modFlows []Flow, delFlows []Flow) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	tableID := addflows[0].TableID()
+	for _, flow := range addflows {
+		assert.Equal(b.t, tableID, flow.TableID(), "A batch must only include the flows of a single table")
+	}
+	for id, count := range b.expectedFlows {
+		if id > tableID {
+			assert.Equal(b.t, count, b.installed[id], "Flows of table %d installed before all the flows of table %d", tableID, id)
+		}
+	}
+	b.batchSizes = append(b.batchSizes, len(addflows))
+	if tableID == b.failedTable {
+		return errors.New("bundle error")
+	}
+	b.installed[tableID] += len(addflows)
+	return nil
+}
+
+func prepareInstallerFlows(flowsPerTable map[TableIDType]int) []Flow {
+	var flows []Flow
+	for tableID, count := range flowsPerTable {
+		table := &ofTable{id: tableID, next: tableID + 1}
+		for i := 0; i < count; i++ {
+			flows = append(flows, table.BuildFlow(uint16(200)).MatchProtocol(ProtocolIP).
+				MatchSrcIP(net.IP{10, 10, byte(i >> 8), byte(i)}).
+				Action().GotoTable(table.GetNext()).
+				Done())
+		}
+	}
+	return flows
+}
+
+func TestFlowInstaller(t *testing.T) {
+	flowsPerTable := map[TableIDType]int{10: 250, 31: 100, 70: 1, 105: 1234}
+	bridge := &fakeBridge{t: t, expectedFlows: flowsPerTable, installed: map[TableIDType]int{}}
+	installer := NewFlowInstaller(bridge, DefaultFlowInstallBatchSize, DefaultFlowInstallWorkers)
+
+	assert.NoError(t, installer.Install(prepareInstallerFlows(flowsPerTable)))
+	assert.Equal(t, flowsPerTable, bridge.installed)
+	// 3 batches for table 10, 1 for table 31, 1 for table 70 and 13 for table 105.
+	assert.Len(t, bridge.batchSizes, 18)
+	for _, size := range bridge.batchSizes {
+		assert.LessOrEqual(t, size, DefaultFlowInstallBatchSize)
+	}
+}
+
+func TestFlowInstallerError(t *testing.T) {
+	flowsPerTable := map[TableIDType]int{10: 150, 31: 300}
+	bridge := &fakeBridge{t: t, expectedFlows: map[TableIDType]int{10: 150}, installed: map[TableIDType]int{}, failedTable: 31}
+	installer := NewFlowInstaller(bridge, DefaultFlowInstallBatchSize, 2)
+
+	err := installer.Install(prepareInstallerFlows(flowsPerTable))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "error when installing flows in table 31")
+	// The flows of the other tables are installed even if a batch fails.
+	assert.Equal(t, map[TableIDType]int{10: 150}, bridge.installed)
+}
@@ -26,6 +26,7 @@ type TableIDType uint8
 type GroupIDType uint32
 
 type MissActionType uint32
+type GroupType int
 type Range [2]uint32
 type OFOperation int
 
@@ -41,6 +42,11 @@ const (
 	ProtocolUDP  Protocol = "udp"
 	ProtocolSCTP Protocol = "sctp"
 	ProtocolICMP Protocol = "icmp"
+
+	ProtocolIPv6   Protocol = "ipv6"
+	ProtocolTCPv6  Protocol = "tcp6"
+	ProtocolUDPv6  Protocol = "udp6"
+	ProtocolSCTPv6 Protocol = "sctp6"
 )
 
 const (
@@ -50,6 +56,15 @@ const (
 	TableMissActionNone
 )
 
+const (
+	// GroupSelect is the type of the groups which select one of their buckets
+	// according to their weights.
+	GroupSelect GroupType = iota
+	// GroupFastFailover is the type of the groups which execute their first
+	// live bucket, i.e. the first bucket whose watched port is up.
+	GroupFastFailover
+)
+
 const (
 	NxmFieldSrcMAC      = "NXM_OF_ETH_SRC"
 	NxmFieldDstMAC      = "NXM_OF_ETH_DST"
@@ -62,6 +77,7 @@ const (
 	NxmFieldARPOp       = "NXM_OF_ARP_OP"
 	NxmFieldReg         = "NXM_NX_REG"
 	NxmFieldTunMetadata = "NXM_NX_TUN_METADATA"
+	NxmFieldPktMark     = "NXM_NX_PKT_MARK"
 )
 
 const (
@@ -74,12 +90,21 @@ const (
 type Bridge interface {
 	CreateTable(id, next TableIDType, missAction MissActionType) Table
 	DeleteTable(id TableIDType) bool
+	// CreateGroup creates a select group.
 	CreateGroup(id GroupIDType) Group
+	// CreateGroupWithType creates a group of the provided type.
+	CreateGroupWithType(id GroupIDType, groupType GroupType) Group
 	DeleteGroup(id GroupIDType) bool
 	DumpTableStatus() []TableStatus
 	// DumpFlows queries the Openflow entries from OFSwitch. The filter of the query is Openflow cookieID; the result is
 	// a map from flow cookieID to FlowStates.
 	DumpFlows(cookieID, cookieMask uint64) (map[uint64]*FlowStates, error)
+	// DumpTableTrafficStats queries the statistics of all the Openflow entries from OFSwitch, and returns the
+	// numbers of packets and bytes which hit the flows of each table.
+	DumpTableTrafficStats() (map[TableIDType]*TableTrafficStats, error)
+	// DumpFlowTrafficStats queries the statistics of all the Openflow entries from OFSwitch, and returns the numbers
+	// of packets and bytes which hit each flow with the table and the cookie of the flow.
+	DumpFlowTrafficStats() ([]FlowTrafficStats, error)
 	// DeleteFlowsByCookie removes Openflow entries from OFSwitch. The removed Openflow entries use the specific CookieID.
 	DeleteFlowsByCookie(cookieID, cookieMask uint64) error
 	// AddFlowsInBundle syncs multiple Openflow entries in a single transaction. This operation could add new flows in
@@ -117,6 +142,29 @@ type TableStatus struct {
 	UpdateTime time.Time `json:"updateTime"`
 }
 
+// TableTrafficStats represents the traffic which hit the flows of a specific flow table. The traffic which hit the
+// table-miss flow, i.e. the flow with priority 0, is counted separately.
+type TableTrafficStats struct {
+	MatchPacketCount uint64
+	MatchByteCount   uint64
+	MissPacketCount  uint64
+	MissByteCount    uint64
+}
+
+// TrafficStats represents the traffic which hit a set of Openflow entries.
+type TrafficStats struct {
+	PacketCount uint64
+	ByteCount   uint64
+}
+
+// FlowTrafficStats represents the traffic which hit a specific Openflow entry.
+type FlowTrafficStats struct {
+	TableID     TableIDType
+	Cookie      uint64
+	PacketCount uint64
+	ByteCount   uint64
+}
+
 type Table interface {
 	GetID() TableIDType
 	BuildFlow(priority uint16) FlowBuilder
@@ -151,6 +199,8 @@ type Flow interface {
 	OFEntry
 	// Returns the flow priority associated with OFEntry
 	FlowPriority() uint16
+	// TableID returns the ID of the table of the flow.
+	TableID() TableIDType
 	MatchString() string
 	// CopyToBuilder returns a new FlowBuilder that copies the matches of the Flow, but does not copy the actions. It
 	// resets the priority of the new FlowBuilder if the provided value is not 0.
@@ -187,8 +237,10 @@ type Action interface {
 	Conjunction(conjID uint32, clauseID uint8, nClause uint8) FlowBuilder
 	Group(id GroupIDType) FlowBuilder
 	Learn(id TableIDType, priority uint16, idleTimeout, hardTimeout uint16, cookieID uint64) LearnAction
+	LearnWithFinTimeout(id TableIDType, priority uint16, finIdleTimeout uint16, cookieID uint64) LearnAction
 	GotoTable(table TableIDType) FlowBuilder
 	SendToController(reason uint8) FlowBuilder
+	SendToControllerUntruncated(reason uint8) FlowBuilder
 	Note(notes string) FlowBuilder
 }
 
@@ -217,7 +269,9 @@ type FlowBuilder interface {
 	MatchCTStateInv(isSet bool) FlowBuilder
 	MatchCTMark(value uint32) FlowBuilder
 	MatchConjID(value uint32) FlowBuilder
+	MatchTCPSrcPort(port uint16) FlowBuilder
 	MatchTCPDstPort(port uint16) FlowBuilder
+	MatchUDPSrcPort(port uint16) FlowBuilder
 	MatchUDPDstPort(port uint16) FlowBuilder
 	MatchSCTPDstPort(port uint16) FlowBuilder
 	MatchTunMetadata(index int, data uint32) FlowBuilder
@@ -246,11 +300,14 @@ type LearnAction interface {
 	DeleteLearned() LearnAction
 	MatchEthernetProtocolIP() LearnAction
 	MatchTransportDst(protocol Protocol) LearnAction
+	MatchLearnedTCPSrcPort() LearnAction
 	MatchLearnedTCPDstPort() LearnAction
 	MatchLearnedUDPDstPort() LearnAction
 	MatchLearnedSCTPDstPort() LearnAction
 	MatchLearnedSrcIP() LearnAction
 	MatchLearnedDstIP() LearnAction
+	MatchLearnedSrcIPv6() LearnAction
+	MatchLearnedDstIPv6() LearnAction
 	MatchReg(regID int, data uint32, rng Range) LearnAction
 	LoadReg(regID int, data uint32, rng Range) LearnAction
 	LoadRegToReg(fromRegID, toRegID int, fromRng, toRng Range) LearnAction
@@ -269,6 +326,7 @@ type BucketBuilder interface {
 	LoadReg(regID int, data uint32) BucketBuilder
 	LoadRegRange(regID int, data uint32, rng Range) BucketBuilder
 	ResubmitToTable(tableID TableIDType) BucketBuilder
+	WatchPort(port uint32) BucketBuilder
 	Done() Group
 }
 
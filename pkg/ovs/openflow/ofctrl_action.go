@@ -127,8 +127,13 @@ func (a *ofCTAction) natAction(isSNAT bool, ipRange *IPRange, portRange *PortRan
 
 	// ipRange should not be nil. The check here is for code safety.
 	if ipRange != nil {
-		action.SetRangeIPv4Min(ipRange.StartIP)
-		action.SetRangeIPv4Max(ipRange.EndIP)
+		if ipRange.StartIP.To4() == nil {
+			action.SetRangeIPv6Min(ipRange.StartIP)
+			action.SetRangeIPv6Max(ipRange.EndIP)
+		} else {
+			action.SetRangeIPv4Min(ipRange.StartIP)
+			action.SetRangeIPv4Max(ipRange.EndIP)
+		}
 	}
 	if portRange != nil {
 		action.SetRangeProtoMin(&portRange.StartPort)
@@ -326,6 +331,30 @@ func (a *ofFlowAction) SendToController(reason uint8) FlowBuilder {
 	return a.builder
 }
 
+// untruncatedController is the controller action with the largest max_len,
+// while ofctrl.NXController limits it to 128 bytes.
+type untruncatedController struct {
+	ofctrl.NXController
+}
+
+func (a *untruncatedController) GetActionMessage() openflow13.Action {
+	action := openflow13.NewNXActionController(a.ControllerID)
+	action.MaxLen = 0xffff
+	action.Reason = a.Reason
+	return action
+}
+
+// SendToControllerUntruncated is an action to send the whole packet to the
+// controller, for the handlers which need its payload.
+func (a *ofFlowAction) SendToControllerUntruncated(reason uint8) FlowBuilder {
+	controllerAct := &untruncatedController{ofctrl.NXController{
+		ControllerID: a.builder.ofFlow.Table.Switch.GetControllerID(),
+		Reason:       reason,
+	}}
+	a.builder.ApplyAction(controllerAct)
+	return a.builder
+}
+
 //  Learn is an action which adds or modifies a flow in an OpenFlow table.
 func (a *ofFlowAction) Learn(id TableIDType, priority uint16, idleTimeout, hardTimeout uint16, cookieID uint64) LearnAction {
 	la := &ofLearnAction{
@@ -335,6 +364,17 @@ func (a *ofFlowAction) Learn(id TableIDType, priority uint16, idleTimeout, hardT
 	return la
 }
 
+// LearnWithFinTimeout is like Learn, but the learned flow has no idle or hard
+// timeout, and is removed after it has been idle for finIdleTimeout seconds
+// once it has matched a TCP packet with the FIN or RST flag.
+func (a *ofFlowAction) LearnWithFinTimeout(id TableIDType, priority uint16, finIdleTimeout uint16, cookieID uint64) LearnAction {
+	la := &ofLearnAction{
+		flowBuilder: a.builder,
+		nxLearn:     ofctrl.NewLearnAction(uint8(id), priority, 0, 0, finIdleTimeout, 0, cookieID),
+	}
+	return la
+}
+
 // ofLearnAction is used to describe actions in the learn flow.
 type ofLearnAction struct {
 	flowBuilder *ofFlowBuilder
@@ -356,23 +396,66 @@ func (a *ofLearnAction) MatchEthernetProtocolIP() LearnAction {
 	return a
 }
 
+// MatchEthernetProtocolIPv6 specifies that the NXM_OF_ETH_TYPE field in the
+// learned flow must match IPv6(0x86dd).
+func (a *ofLearnAction) MatchEthernetProtocolIPv6() LearnAction {
+	ethTypeVal := make([]byte, 2)
+	binary.BigEndian.PutUint16(ethTypeVal, 0x86dd)
+	a.nxLearn.AddMatch(&ofctrl.LearnField{Name: "NXM_OF_ETH_TYPE"}, 2*8, nil, ethTypeVal)
+	return a
+}
+
 // MatchTransportDst specifies that the transport layer destination field
-// {tcp|udp}_dst in the learned flow must match the same field of the packet
-// currently being processed. It only accepts ProtocolTCP or ProtocolUDP,
-// otherwise this does nothing.
+// {tcp|udp|sctp}_dst in the learned flow must match the same field of the
+// packet currently being processed. It only accepts ProtocolTCP, ProtocolUDP,
+// ProtocolSCTP or their IPv6 variants, otherwise this does nothing.
 func (a *ofLearnAction) MatchTransportDst(protocol Protocol) LearnAction {
-	if protocol != ProtocolTCP && protocol != ProtocolUDP && protocol != ProtocolSCTP {
+	switch protocol {
+	case ProtocolTCP, ProtocolUDP, ProtocolSCTP:
+		a.MatchEthernetProtocolIP()
+	case ProtocolTCPv6:
+		a.MatchEthernetProtocolIPv6()
+		protocol = ProtocolTCP
+	case ProtocolUDPv6:
+		a.MatchEthernetProtocolIPv6()
+		protocol = ProtocolUDP
+	case ProtocolSCTPv6:
+		a.MatchEthernetProtocolIPv6()
+		protocol = ProtocolSCTP
+	default:
 		return a
 	}
-	a.MatchEthernetProtocolIP()
+	var ipProto byte
+	switch protocol {
+	case ProtocolTCP:
+		ipProto = byte(ofctrl.IP_PROTO_TCP)
+	case ProtocolUDP:
+		ipProto = byte(ofctrl.IP_PROTO_UDP)
+	case ProtocolSCTP:
+		ipProto = byte(ofctrl.IP_PROTO_SCTP)
+	}
 	ipTypeVal := make([]byte, 2)
-	ipTypeVal[1] = byte(ofctrl.IP_PROTO_TCP)
+	ipTypeVal[1] = ipProto
 	a.nxLearn.AddMatch(&ofctrl.LearnField{Name: "NXM_OF_IP_PROTO"}, 1*8, nil, ipTypeVal)
+	// The SCTP ports are only defined as OXM fields, there is no NXM field for
+	// them.
 	fieldName := fmt.Sprintf("NXM_OF_%s_DST", strings.ToUpper(string(protocol)))
+	if protocol == ProtocolSCTP {
+		fieldName = "OXM_OF_SCTP_DST"
+	}
 	a.nxLearn.AddMatch(&ofctrl.LearnField{Name: fieldName}, 2*8, &ofctrl.LearnField{Name: fieldName}, nil)
 	return a
 }
 
+// MatchLearnedTCPSrcPort specifies that the tcp_src field in the learned flow
+// must match the tcp_src of the packet currently being processed. The protocol
+// of the learned flow must be matched with MatchTransportDst or
+// MatchLearnedTCPDstPort as well.
+func (a *ofLearnAction) MatchLearnedTCPSrcPort() LearnAction {
+	a.nxLearn.AddMatch(&ofctrl.LearnField{Name: "NXM_OF_TCP_SRC"}, 2*8, &ofctrl.LearnField{Name: "NXM_OF_TCP_SRC"}, nil)
+	return a
+}
+
 // MatchLearnedTCPDstPort specifies that the tcp_dst field in the learned flow
 // must match the tcp_dst of the packet currently being processed.
 func (a *ofLearnAction) MatchLearnedTCPDstPort() LearnAction {
@@ -385,7 +468,7 @@ func (a *ofLearnAction) MatchLearnedUDPDstPort() LearnAction {
 	return a.MatchTransportDst(ProtocolUDP)
 }
 
-// MatchLearnedUDPDstPort specifies that the sctp_dst field in the learned flow
+// MatchLearnedSCTPDstPort specifies that the sctp_dst field in the learned flow
 // must match the sctp_dst of the packet currently being processed.
 func (a *ofLearnAction) MatchLearnedSCTPDstPort() LearnAction {
 	return a.MatchTransportDst(ProtocolSCTP)
@@ -403,6 +486,18 @@ func (a *ofLearnAction) MatchLearnedDstIP() LearnAction {
 	return a
 }
 
+// MatchLearnedSrcIPv6 makes the learned flow to match the ipv6_src of current IPv6 packet.
+func (a *ofLearnAction) MatchLearnedSrcIPv6() LearnAction {
+	a.nxLearn.AddMatch(&ofctrl.LearnField{Name: "NXM_NX_IPV6_SRC"}, 16*8, &ofctrl.LearnField{Name: "NXM_NX_IPV6_SRC"}, nil)
+	return a
+}
+
+// MatchLearnedDstIPv6 makes the learned flow to match the ipv6_dst of current IPv6 packet.
+func (a *ofLearnAction) MatchLearnedDstIPv6() LearnAction {
+	a.nxLearn.AddMatch(&ofctrl.LearnField{Name: "NXM_NX_IPV6_DST"}, 16*8, &ofctrl.LearnField{Name: "NXM_NX_IPV6_DST"}, nil)
+	return a
+}
+
 // MatchReg makes the learned flow to match the data in the reg of specific range.
 func (a *ofLearnAction) MatchReg(regID int, data uint32, rng Range) LearnAction {
 	toField := &ofctrl.LearnField{Name: fmt.Sprintf("NXM_NX_REG%d", regID), Start: uint16(rng[0])}
@@ -148,7 +148,15 @@ type OFBridge struct {
 }
 
 func (b *OFBridge) CreateGroup(id GroupIDType) Group {
-	ofctrlGroup, err := b.ofSwitch.NewGroup(uint32(id), ofctrl.GroupSelect)
+	return b.CreateGroupWithType(id, GroupSelect)
+}
+
+func (b *OFBridge) CreateGroupWithType(id GroupIDType, groupType GroupType) Group {
+	ofctrlGroupType := ofctrl.GroupSelect
+	if groupType == GroupFastFailover {
+		ofctrlGroupType = ofctrl.GroupFF
+	}
+	ofctrlGroup, err := b.ofSwitch.NewGroup(uint32(id), ofctrlGroupType)
 	if err != nil {
 		ofctrlGroup = b.ofSwitch.GetGroup(uint32(id))
 	}
@@ -206,12 +214,19 @@ func (b *OFBridge) DumpTableStatus() []TableStatus {
 
 // PacketRcvd is a callback when a packetIn is received on ofctrl.OFSwitch.
 func (b *OFBridge) PacketRcvd(sw *ofctrl.OFSwitch, packet *ofctrl.PacketIn) {
-	klog.Infof("Received packet: %+v", packet)
+	klog.V(2).Infof("Received packet: %+v", packet)
 	reason := packet.Reason
 	ch, found := b.pktConsumers.Load(reason)
 	if found {
 		pktCh, _ := ch.(chan *ofctrl.PacketIn)
-		pktCh <- packet
+		// Drop the packet instead of blocking the OFSwitch when the consumer
+		// cannot keep up, e.g. when a NetworkPolicy rule with logging enabled
+		// is matched by a flood of packets.
+		select {
+		case pktCh <- packet:
+		default:
+			klog.V(2).Infof("Dropped packetIn with reason %d as its consumer is busy", reason)
+		}
 	}
 }
 
@@ -317,6 +332,48 @@ func (b *OFBridge) DumpFlows(cookieID, cookieMask uint64) (map[uint64]*FlowState
 	return flowStats, nil
 }
 
+// DumpTableTrafficStats sums the counters of the Openflow entries of each table.
+func (b *OFBridge) DumpTableTrafficStats() (map[TableIDType]*TableTrafficStats, error) {
+	ofStats, err := b.ofSwitch.DumpFlowStats(0, 0, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	tableStats := make(map[TableIDType]*TableTrafficStats)
+	for _, stat := range ofStats {
+		s, ok := tableStats[TableIDType(stat.TableId)]
+		if !ok {
+			s = &TableTrafficStats{}
+			tableStats[TableIDType(stat.TableId)] = s
+		}
+		if stat.Priority == 0 {
+			s.MissPacketCount += stat.PacketCount
+			s.MissByteCount += stat.ByteCount
+		} else {
+			s.MatchPacketCount += stat.PacketCount
+			s.MatchByteCount += stat.ByteCount
+		}
+	}
+	return tableStats, nil
+}
+
+// DumpFlowTrafficStats returns the counters of all the Openflow entries.
+func (b *OFBridge) DumpFlowTrafficStats() ([]FlowTrafficStats, error) {
+	ofStats, err := b.ofSwitch.DumpFlowStats(0, 0, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	flowStats := make([]FlowTrafficStats, 0, len(ofStats))
+	for _, stat := range ofStats {
+		flowStats = append(flowStats, FlowTrafficStats{
+			TableID:     TableIDType(stat.TableId),
+			Cookie:      stat.Cookie,
+			PacketCount: stat.PacketCount,
+			ByteCount:   stat.ByteCount,
+		})
+	}
+	return flowStats, nil
+}
+
 // DeleteFlowsByCookie removes Openflow entries from OFSwitch. The removed Openflow entries use the specific CookieID.
 func (b *OFBridge) DeleteFlowsByCookie(cookieID, cookieMask uint64) error {
 	flowMod := openflow13.NewFlowMod()
@@ -485,7 +542,7 @@ func (b *OFBridge) AddOFEntriesInBundle(addEntries []OFEntry, modEntries []OFEnt
 		groupSet, flowSet,
 	} {
 		if err := addMessage(entries); err != nil {
-			return nil
+			return err
 		}
 	}
 
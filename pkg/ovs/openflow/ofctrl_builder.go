@@ -21,6 +21,7 @@ func (b *ofFlowBuilder) MatchTunMetadata(index int, data uint32) FlowBuilder {
 		Range: rng,
 	}
 	b.ofFlow.Match.TunMetadatas = append(b.ofFlow.Match.TunMetadatas, tm)
+	b.matchers = append(b.matchers, fmt.Sprintf("tun_metadata%d=0x%x", index, data))
 	return b
 }
 
@@ -68,6 +69,11 @@ func (b *ofFlowBuilder) MatchRegRange(regID int, data uint32, rng Range) FlowBui
 		Range: rng.ToNXRange(),
 	}
 	b.Match.NxRegs = append(b.Match.NxRegs, reg)
+	if mask := rng.ToMask(); mask == 0xffffffff {
+		b.matchers = append(b.matchers, fmt.Sprintf("reg%d=0x%x", regID, data))
+	} else {
+		b.matchers = append(b.matchers, fmt.Sprintf("reg%d=0x%x/0x%x", regID, data, mask))
+	}
 	return b
 }
 
@@ -191,8 +197,14 @@ func (b *ofFlowBuilder) MatchInPort(inPort uint32) FlowBuilder {
 	return b
 }
 
-// MatchDstIP adds match condition for matching destination IP address.
+// MatchDstIP adds match condition for matching destination IP address. Both
+// IPv4 and IPv6 addresses are accepted.
 func (b *ofFlowBuilder) MatchDstIP(ip net.IP) FlowBuilder {
+	if ip.To4() == nil {
+		b.matchers = append(b.matchers, fmt.Sprintf("ipv6_dst=%s", ip.String()))
+		b.Match.Ipv6Da = &ip
+		return b
+	}
 	b.matchers = append(b.matchers, fmt.Sprintf("nw_dst=%s", ip.String()))
 	b.Match.IpDa = &ip
 	return b
@@ -211,8 +223,14 @@ func maskToIPv4(mask net.IPMask) *net.IP {
 	return &ip
 }
 
-// MatchSrcIP adds match condition for matching source IP address.
+// MatchSrcIP adds match condition for matching source IP address. Both IPv4
+// and IPv6 addresses are accepted.
 func (b *ofFlowBuilder) MatchSrcIP(ip net.IP) FlowBuilder {
+	if ip.To4() == nil {
+		b.matchers = append(b.matchers, fmt.Sprintf("ipv6_src=%s", ip.String()))
+		b.Match.Ipv6Sa = &ip
+		return b
+	}
 	b.matchers = append(b.matchers, fmt.Sprintf("nw_src=%s", ip.String()))
 	b.Match.IpSa = &ip
 	return b
@@ -306,14 +324,44 @@ func (b *ofFlowBuilder) MatchProtocol(protocol Protocol) FlowBuilder {
 	case ProtocolICMP:
 		b.Match.Ethertype = 0x0800
 		b.Match.IpProto = 1
+	case ProtocolIPv6:
+		b.Match.Ethertype = 0x86dd
+	case ProtocolTCPv6:
+		b.Match.Ethertype = 0x86dd
+		b.Match.IpProto = 6
+	case ProtocolUDPv6:
+		b.Match.Ethertype = 0x86dd
+		b.Match.IpProto = 17
+	case ProtocolSCTPv6:
+		b.Match.Ethertype = 0x86dd
+		b.Match.IpProto = 132
 	}
 	b.protocol = protocol
 	return b
 }
 
+// matchTransportProtocol sets the transport protocol of the flow if it has not
+// been set to the IPv6 variant of the same protocol, so that the destination
+// port matches can be used by both IPv4 and IPv6 flows.
+func (b *ofFlowBuilder) matchTransportProtocol(protocol, protocolIPv6 Protocol) {
+	if b.protocol != protocolIPv6 {
+		b.MatchProtocol(protocol)
+	}
+}
+
+// MatchTCPSrcPort adds match condition for matching TCP source port.
+func (b *ofFlowBuilder) MatchTCPSrcPort(port uint16) FlowBuilder {
+	b.matchTransportProtocol(ProtocolTCP, ProtocolTCPv6)
+	b.Match.TcpSrcPort = port
+	// "tp_src" is used in flow matching string for the same reason as
+	// "tp_dst" in MatchTCPDstPort.
+	b.matchers = append(b.matchers, fmt.Sprintf("tp_src=%d", port))
+	return b
+}
+
 // MatchTCPDstPort adds match condition for matching TCP destination port.
 func (b *ofFlowBuilder) MatchTCPDstPort(port uint16) FlowBuilder {
-	b.MatchProtocol(ProtocolTCP)
+	b.matchTransportProtocol(ProtocolTCP, ProtocolTCPv6)
 	b.Match.TcpDstPort = port
 	// According to ovs-ofctl(8) man page, "tp_dst" is deprecated and "tcp_dst",
 	// "udp_dst", "sctp_dst" should be used for the destination port of TCP, UDP,
@@ -325,9 +373,17 @@ func (b *ofFlowBuilder) MatchTCPDstPort(port uint16) FlowBuilder {
 	return b
 }
 
+// MatchUDPSrcPort adds match condition for matching UDP source port.
+func (b *ofFlowBuilder) MatchUDPSrcPort(port uint16) FlowBuilder {
+	b.matchTransportProtocol(ProtocolUDP, ProtocolUDPv6)
+	b.Match.UdpSrcPort = port
+	b.matchers = append(b.matchers, fmt.Sprintf("tp_src=%d", port))
+	return b
+}
+
 // MatchUDPDstPort adds match condition for matching UDP destination port.
 func (b *ofFlowBuilder) MatchUDPDstPort(port uint16) FlowBuilder {
-	b.MatchProtocol(ProtocolUDP)
+	b.matchTransportProtocol(ProtocolUDP, ProtocolUDPv6)
 	b.Match.UdpDstPort = port
 	b.matchers = append(b.matchers, fmt.Sprintf("tp_dst=%d", port))
 	return b
@@ -335,7 +391,7 @@ func (b *ofFlowBuilder) MatchUDPDstPort(port uint16) FlowBuilder {
 
 // MatchSCTPDstPort adds match condition for matching SCTP destination port.
 func (b *ofFlowBuilder) MatchSCTPDstPort(port uint16) FlowBuilder {
-	b.MatchProtocol(ProtocolSCTP)
+	b.matchTransportProtocol(ProtocolSCTP, ProtocolSCTPv6)
 	b.Match.SctpDstPort = port
 	b.matchers = append(b.matchers, fmt.Sprintf("tp_dst=%d", port))
 	return b
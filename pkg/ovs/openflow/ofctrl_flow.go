@@ -89,6 +89,11 @@ func (f *ofFlow) MatchString() string {
 	return repr
 }
 
+// TableID returns the ID of the table of the flow.
+func (f *ofFlow) TableID() TableIDType {
+	return f.table.GetID()
+}
+
 func (f *ofFlow) FlowPriority() uint16 {
 	return f.Match.Priority
 }
@@ -150,3 +155,8 @@ func (r *Range) ToNXRange() *openflow13.NXRange {
 func (r *Range) Length() uint32 {
 	return r[1] - r[0] + 1
 }
+
+// ToMask returns the mask of the bits in the range of a 32-bit field.
+func (r *Range) ToMask() uint32 {
+	return uint32((uint64(1)<<r.Length() - 1) << r[0])
+}
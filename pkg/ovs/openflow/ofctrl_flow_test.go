@@ -1,6 +1,7 @@
 package openflow
 
 import (
+	"net"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -29,3 +30,33 @@ func TestCopyToBuilder(t *testing.T) {
 	newFlow2 := oriFlow.CopyToBuilder(newPriority)
 	assert.Equal(t, newPriority, newFlow2.Done().(*ofFlow).Match.Priority)
 }
+
+func TestIPv6MatchString(t *testing.T) {
+	table := &ofTable{
+		id:   41,
+		next: 42,
+	}
+	svcIP := net.ParseIP("fd00:10:96::a")
+	flow := table.BuildFlow(uint16(200)).MatchProtocol(ProtocolTCPv6).
+		MatchDstIP(svcIP).
+		MatchTCPDstPort(80).
+		Done()
+	assert.Equal(t, "table=41,tcp6,ipv6_dst=fd00:10:96::a,tp_dst=80", flow.MatchString())
+	assert.Equal(t, uint16(0x86dd), flow.(*ofFlow).Match.Ethertype)
+	assert.Equal(t, uint8(6), flow.(*ofFlow).Match.IpProto)
+	assert.Equal(t, svcIP, *flow.(*ofFlow).Match.Ipv6Da)
+	assert.Nil(t, flow.(*ofFlow).Match.IpDa)
+}
+
+func TestRegAndTunMetadataMatchString(t *testing.T) {
+	table := &ofTable{
+		id:   70,
+		next: 80,
+	}
+	flow := table.BuildFlow(uint16(200)).MatchProtocol(ProtocolIP).
+		MatchRegRange(0, 1, Range{19, 19}).
+		MatchRegRange(1, 0x101, Range{0, 31}).
+		MatchTunMetadata(0, 0x1234).
+		Done()
+	assert.Equal(t, "table=70,ip,reg0=0x80000/0x80000,reg1=0x101,tun_metadata0=0x1234", flow.MatchString())
+}
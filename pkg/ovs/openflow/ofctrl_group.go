@@ -100,6 +100,13 @@ func (b *bucketBuilder) ResubmitToTable(tableID TableIDType) BucketBuilder {
 	return b
 }
 
+// WatchPort sets the port whose liveness determines the liveness of a bucket.
+// It is only used by fast-failover groups.
+func (b *bucketBuilder) WatchPort(port uint32) BucketBuilder {
+	b.bucket.WatchPort = port
+	return b
+}
+
 // Weight sets the weight of a bucket.
 func (b *bucketBuilder) Weight(val uint16) BucketBuilder {
 	b.bucket.Weight = val
@@ -134,6 +134,20 @@ func (mr *MockBridgeMockRecorder) CreateGroup(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGroup", reflect.TypeOf((*MockBridge)(nil).CreateGroup), arg0)
 }
 
+// CreateGroupWithType mocks base method
+func (m *MockBridge) CreateGroupWithType(arg0 openflow.GroupIDType, arg1 openflow.GroupType) openflow.Group {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateGroupWithType", arg0, arg1)
+	ret0, _ := ret[0].(openflow.Group)
+	return ret0
+}
+
+// CreateGroupWithType indicates an expected call of CreateGroupWithType
+func (mr *MockBridgeMockRecorder) CreateGroupWithType(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGroupWithType", reflect.TypeOf((*MockBridge)(nil).CreateGroupWithType), arg0, arg1)
+}
+
 // CreateTable mocks base method
 func (m *MockBridge) CreateTable(arg0, arg1 openflow.TableIDType, arg2 openflow.MissActionType) openflow.Table {
 	m.ctrl.T.Helper()
@@ -204,6 +218,21 @@ func (mr *MockBridgeMockRecorder) Disconnect() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disconnect", reflect.TypeOf((*MockBridge)(nil).Disconnect))
 }
 
+// DumpFlowTrafficStats mocks base method
+func (m *MockBridge) DumpFlowTrafficStats() ([]openflow.FlowTrafficStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DumpFlowTrafficStats")
+	ret0, _ := ret[0].([]openflow.FlowTrafficStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DumpFlowTrafficStats indicates an expected call of DumpFlowTrafficStats
+func (mr *MockBridgeMockRecorder) DumpFlowTrafficStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DumpFlowTrafficStats", reflect.TypeOf((*MockBridge)(nil).DumpFlowTrafficStats))
+}
+
 // DumpFlows mocks base method
 func (m *MockBridge) DumpFlows(arg0, arg1 uint64) (map[uint64]*openflow.FlowStates, error) {
 	m.ctrl.T.Helper()
@@ -233,6 +262,21 @@ func (mr *MockBridgeMockRecorder) DumpTableStatus() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DumpTableStatus", reflect.TypeOf((*MockBridge)(nil).DumpTableStatus))
 }
 
+// DumpTableTrafficStats mocks base method
+func (m *MockBridge) DumpTableTrafficStats() (map[openflow.TableIDType]*openflow.TableTrafficStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DumpTableTrafficStats")
+	ret0, _ := ret[0].(map[openflow.TableIDType]*openflow.TableTrafficStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DumpTableTrafficStats indicates an expected call of DumpTableTrafficStats
+func (mr *MockBridgeMockRecorder) DumpTableTrafficStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DumpTableTrafficStats", reflect.TypeOf((*MockBridge)(nil).DumpTableTrafficStats))
+}
+
 // IsConnected mocks base method
 func (m *MockBridge) IsConnected() bool {
 	m.ctrl.T.Helper()
@@ -516,6 +560,20 @@ func (mr *MockFlowMockRecorder) Reset() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockFlow)(nil).Reset))
 }
 
+// TableID mocks base method
+func (m *MockFlow) TableID() openflow.TableIDType {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TableID")
+	ret0, _ := ret[0].(openflow.TableIDType)
+	return ret0
+}
+
+// TableID indicates an expected call of TableID
+func (mr *MockFlowMockRecorder) TableID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TableID", reflect.TypeOf((*MockFlow)(nil).TableID))
+}
+
 // ToBuilder mocks base method
 func (m *MockFlow) ToBuilder() openflow.FlowBuilder {
 	m.ctrl.T.Helper()
@@ -665,6 +723,20 @@ func (mr *MockActionMockRecorder) Learn(arg0, arg1, arg2, arg3, arg4 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Learn", reflect.TypeOf((*MockAction)(nil).Learn), arg0, arg1, arg2, arg3, arg4)
 }
 
+// LearnWithFinTimeout mocks base method
+func (m *MockAction) LearnWithFinTimeout(arg0 openflow.TableIDType, arg1, arg2 uint16, arg3 uint64) openflow.LearnAction {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LearnWithFinTimeout", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(openflow.LearnAction)
+	return ret0
+}
+
+// LearnWithFinTimeout indicates an expected call of LearnWithFinTimeout
+func (mr *MockActionMockRecorder) LearnWithFinTimeout(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LearnWithFinTimeout", reflect.TypeOf((*MockAction)(nil).LearnWithFinTimeout), arg0, arg1, arg2, arg3)
+}
+
 // LoadARPOperation mocks base method
 func (m *MockAction) LoadARPOperation(arg0 uint16) openflow.FlowBuilder {
 	m.ctrl.T.Helper()
@@ -861,6 +933,20 @@ func (mr *MockActionMockRecorder) SendToController(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendToController", reflect.TypeOf((*MockAction)(nil).SendToController), arg0)
 }
 
+// SendToControllerUntruncated mocks base method
+func (m *MockAction) SendToControllerUntruncated(arg0 byte) openflow.FlowBuilder {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendToControllerUntruncated", arg0)
+	ret0, _ := ret[0].(openflow.FlowBuilder)
+	return ret0
+}
+
+// SendToControllerUntruncated indicates an expected call of SendToControllerUntruncated
+func (mr *MockActionMockRecorder) SendToControllerUntruncated(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendToControllerUntruncated", reflect.TypeOf((*MockAction)(nil).SendToControllerUntruncated), arg0)
+}
+
 // SetARPSha mocks base method
 func (m *MockAction) SetARPSha(arg0 net.HardwareAddr) openflow.FlowBuilder {
 	m.ctrl.T.Helper()
@@ -1514,6 +1600,20 @@ func (mr *MockFlowBuilderMockRecorder) MatchTCPDstPort(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MatchTCPDstPort", reflect.TypeOf((*MockFlowBuilder)(nil).MatchTCPDstPort), arg0)
 }
 
+// MatchTCPSrcPort mocks base method
+func (m *MockFlowBuilder) MatchTCPSrcPort(arg0 uint16) openflow.FlowBuilder {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MatchTCPSrcPort", arg0)
+	ret0, _ := ret[0].(openflow.FlowBuilder)
+	return ret0
+}
+
+// MatchTCPSrcPort indicates an expected call of MatchTCPSrcPort
+func (mr *MockFlowBuilderMockRecorder) MatchTCPSrcPort(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MatchTCPSrcPort", reflect.TypeOf((*MockFlowBuilder)(nil).MatchTCPSrcPort), arg0)
+}
+
 // MatchTunMetadata mocks base method
 func (m *MockFlowBuilder) MatchTunMetadata(arg0 int, arg1 uint32) openflow.FlowBuilder {
 	m.ctrl.T.Helper()
@@ -1542,6 +1642,20 @@ func (mr *MockFlowBuilderMockRecorder) MatchUDPDstPort(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MatchUDPDstPort", reflect.TypeOf((*MockFlowBuilder)(nil).MatchUDPDstPort), arg0)
 }
 
+// MatchUDPSrcPort mocks base method
+func (m *MockFlowBuilder) MatchUDPSrcPort(arg0 uint16) openflow.FlowBuilder {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MatchUDPSrcPort", arg0)
+	ret0, _ := ret[0].(openflow.FlowBuilder)
+	return ret0
+}
+
+// MatchUDPSrcPort indicates an expected call of MatchUDPSrcPort
+func (mr *MockFlowBuilderMockRecorder) MatchUDPSrcPort(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MatchUDPSrcPort", reflect.TypeOf((*MockFlowBuilder)(nil).MatchUDPSrcPort), arg0)
+}
+
 // SetHardTimeout mocks base method
 func (m *MockFlowBuilder) SetHardTimeout(arg0 uint16) openflow.FlowBuilder {
 	m.ctrl.T.Helper()
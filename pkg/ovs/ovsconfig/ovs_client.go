@@ -357,7 +357,7 @@ func (br *OVSBridge) CreateInternalPort(name string, ofPortRequest int32, extern
 	if ofPortRequest < 0 || ofPortRequest > ofPortRequestMax {
 		return "", newInvalidArgumentsError(fmt.Sprint("invalid ofPortRequest value: ", ofPortRequest))
 	}
-	return br.createPort(name, name, "internal", ofPortRequest, externalIDs, nil)
+	return br.createPort(name, name, "internal", ofPortRequest, 0, externalIDs, nil)
 }
 
 // CreateTunnelPort creates a tunnel port with the specified name and type on
@@ -423,7 +423,7 @@ func (br *OVSBridge) createTunnelPort(
 		options["psk"] = psk
 	}
 
-	return br.createPort(name, name, string(tunnelType), ofPortRequest, externalIDs, options)
+	return br.createPort(name, name, string(tunnelType), ofPortRequest, 0, externalIDs, options)
 }
 
 // ParseTunnelInterfaceOptions reads remote IP and IPSec PSK from the tunnel
@@ -452,7 +452,7 @@ func ParseTunnelInterfaceOptions(portData *OVSPortData) (net.IP, net.IP, string)
 
 // CreateUplinkPort creates uplink port.
 func (br *OVSBridge) CreateUplinkPort(name string, ofPortRequest int32, externalIDs map[string]interface{}) (string, Error) {
-	return br.createPort(name, name, "", ofPortRequest, externalIDs, nil)
+	return br.createPort(name, name, "", ofPortRequest, 0, externalIDs, nil)
 }
 
 // CreatePort creates a port with the specified name on the bridge, and connects
@@ -460,10 +460,17 @@ func (br *OVSBridge) CreateUplinkPort(name string, ofPortRequest int32, external
 // If externalIDs is not empty, the map key/value pairs will be set to the
 // port's external_ids.
 func (br *OVSBridge) CreatePort(name, ifDev string, externalIDs map[string]interface{}) (string, Error) {
-	return br.createPort(name, ifDev, "", 0, externalIDs, nil)
+	return br.createPort(name, ifDev, "", 0, 0, externalIDs, nil)
 }
 
-func (br *OVSBridge) createPort(name, ifName, ifType string, ofPortRequest int32, externalIDs, options map[string]interface{}) (string, Error) {
+// CreateAccessPort creates a port like CreatePort, but the port is an access
+// port of the VLAN specified by vlanID: the packets it receives are tagged with
+// the VLAN, and only the packets of the VLAN are output to it.
+func (br *OVSBridge) CreateAccessPort(name, ifDev string, externalIDs map[string]interface{}, vlanID uint16) (string, Error) {
+	return br.createPort(name, ifDev, "", 0, vlanID, externalIDs, nil)
+}
+
+func (br *OVSBridge) createPort(name, ifName, ifType string, ofPortRequest int32, vlanID uint16, externalIDs, options map[string]interface{}) (string, Error) {
 	var externalIDMap []interface{}
 	var optionMap []interface{}
 
@@ -493,6 +500,7 @@ func (br *OVSBridge) createPort(name, ifName, ifType string, ofPortRequest int32
 			"named-uuid": []string{ifNamedUUID},
 		}),
 		ExternalIDs: externalIDMap,
+		Tag:         vlanID,
 	}
 	portNamedUUID := tx.Insert(dbtransaction.Insert{
 		Table: "Port",
@@ -708,6 +716,28 @@ func (br *OVSBridge) SetInterfaceMTU(name string, MTU int) error {
 	return nil
 }
 
+// SetInterfaceOptions replaces the options of the interface with the provided
+// ones.
+func (br *OVSBridge) SetInterfaceOptions(name string, options map[string]interface{}) Error {
+	tx := br.ovsdb.Transaction(openvSwitchSchema)
+
+	tx.Update(dbtransaction.Update{
+		Table: "Interface",
+		Where: [][]interface{}{{"name", "==", name}},
+		Row: map[string]interface{}{
+			"options": helpers.MakeOVSDBMap(options),
+		},
+	})
+
+	_, err, temporary := tx.Commit()
+	if err != nil {
+		klog.Error("Transaction failed: ", err)
+		return NewTransactionError(err, temporary)
+	}
+
+	return nil
+}
+
 func (br *OVSBridge) GetOVSVersion() (string, Error) {
 	tx := br.ovsdb.Transaction(openvSwitchSchema)
 
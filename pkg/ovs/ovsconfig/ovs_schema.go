@@ -24,6 +24,8 @@ type Port struct {
 	Name        string        `json:"name"`
 	Interfaces  []interface{} `json:"interfaces"`
 	ExternalIDs []interface{} `json:"external_ids,omitempty"`
+	// Tag is the VLAN of an access port, 0 for a trunk port.
+	Tag uint16 `json:"tag,omitempty"`
 }
 
 type Interface struct {
@@ -17,6 +17,8 @@ package ovsctl
 import (
 	"bytes"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -30,6 +32,10 @@ var (
 	// a common way, here we simply assume "dl_type=" is used for non-IP types
 	// only.
 	nonIPDLTypes = []string{"arp", "rarp", "ip6", "dl_type="}
+	// upcallFlowsRegex matches the flows line of each datapath in the output
+	// of "ovs-appctl upcall/show", e.g.
+	// "  flows         : (current 42) (avg 40) (max 120) (limit 200000)".
+	upcallFlowsRegex = regexp.MustCompile(`flows\s*:\s*\(current (\d+)\).*\(limit (\d+)\)`)
 )
 
 type ovsCtlClient struct {
@@ -121,7 +127,7 @@ func (c *ovsCtlClient) Trace(req *TracingRequest) (string, error) {
 }
 
 func (c *ovsCtlClient) runTracing(flow string) (string, error) {
-	out, execErr := c.runAppctlCmd("ofproto/trace", flow)
+	out, execErr := c.runAppctlCmd("ofproto/trace", true, flow)
 	if execErr != nil {
 		return "", execErr
 	}
@@ -130,11 +136,43 @@ func (c *ovsCtlClient) runTracing(flow string) (string, error) {
 	return string(out), nil
 }
 
-func (c *ovsCtlClient) runAppctlCmd(cmd string, args ...string) ([]byte, *ExecError) {
+func (c *ovsCtlClient) GetDatapathFlowStats() (*DatapathFlowStats, error) {
+	out, execErr := c.runAppctlCmd("upcall/show", false)
+	if execErr != nil {
+		return nil, execErr
+	}
+	return parseDatapathFlowStats(string(out))
+}
+
+// parseDatapathFlowStats parses the output of "ovs-appctl upcall/show". When
+// ovs-vswitchd manages several datapaths, the statistics of the most utilized
+// one are returned.
+func parseDatapathFlowStats(out string) (*DatapathFlowStats, error) {
+	var stats *DatapathFlowStats
+	for _, match := range upcallFlowsRegex.FindAllStringSubmatch(out, -1) {
+		current, _ := strconv.Atoi(match[1])
+		limit, _ := strconv.Atoi(match[2])
+		if limit <= 0 {
+			continue
+		}
+		if stats == nil || current*stats.Limit > stats.Flows*limit {
+			stats = &DatapathFlowStats{Flows: current, Limit: limit}
+		}
+	}
+	if stats == nil {
+		return nil, fmt.Errorf("no datapath flow statistics found in output: %s", out)
+	}
+	return stats, nil
+}
+
+func (c *ovsCtlClient) runAppctlCmd(cmd string, needsBridge bool, args ...string) ([]byte, *ExecError) {
 	// Use the control UNIX domain socket to connect to ovs-vswitchd, as Agent can
 	// run in a different PID namespace from ovs-vswitchd, and so might not be able
 	// to reach ovs-vswitchd using the PID.
-	cmdStr := fmt.Sprintf("ovs-appctl -t %s %s %s", ovsVSwitchdUDS, cmd, c.bridge)
+	cmdStr := fmt.Sprintf("ovs-appctl -t %s %s", ovsVSwitchdUDS, cmd)
+	if needsBridge {
+		cmdStr = cmdStr + " " + c.bridge
+	}
 	cmdStr = cmdStr + " " + strings.Join(args, " ")
 	out, err := getOVSCommand(cmdStr).CombinedOutput()
 	if err != nil {
@@ -0,0 +1,67 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsctl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDatapathFlowStats(t *testing.T) {
+	tests := []struct {
+		name          string
+		out           string
+		expectedStats *DatapathFlowStats
+		expectErr     bool
+	}{
+		{
+			name: "single datapath",
+			out: `system@ovs-system:
+  flows         : (current 42) (avg 40) (max 120) (limit 200000)
+  dump duration : 1ms
+  ufid enabled : true
+`,
+			expectedStats: &DatapathFlowStats{Flows: 42, Limit: 200000},
+		},
+		{
+			name: "most utilized datapath",
+			out: `netdev@ovs-netdev:
+  flows         : (current 10) (avg 10) (max 12) (limit 1000)
+  dump duration : 1ms
+system@ovs-system:
+  flows         : (current 900) (avg 800) (max 900) (limit 10000)
+  dump duration : 2ms
+`,
+			expectedStats: &DatapathFlowStats{Flows: 900, Limit: 10000},
+		},
+		{
+			name:      "no datapath",
+			out:       "",
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := parseDatapathFlowStats(tt.out)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedStats, stats)
+			}
+		})
+	}
+}
@@ -30,8 +30,8 @@ type TracingRequest struct {
 	AllowOverrideInPort bool
 }
 
-// OVSCtlClient is an interface for executing OVS "ovs-ofctl" and "ovs-appctl"
-// commands.
+// OVSCtlClient is an interface for executing OVS "ovs-ofctl", "ovs-appctl" and
+// "ovs-vsctl" commands.
 type OVSCtlClient interface {
 	// DumpFlows returns flows of the bridge.
 	DumpFlows(args ...string) ([]string, error)
@@ -43,10 +43,35 @@ type OVSCtlClient interface {
 	DumpGroups(args ...string) ([][]string, error)
 	// RunOfctlCmd executes "ovs-ofctl" command and returns the outputs.
 	RunOfctlCmd(cmd string, args ...string) ([]byte, error)
+	// RunVsctlCmd executes "ovs-vsctl" command and returns the outputs.
+	RunVsctlCmd(cmd string, args ...string) ([]byte, error)
 	// SetPortNoFlood sets the given port with config "no-flood". This configuration must work with OpenFlow10.
 	SetPortNoFlood(ofport int) error
 	// Trace executes "ovs-appctl ofproto/trace" to perform OVS packet tracing.
 	Trace(req *TracingRequest) (string, error)
+	// SetPortQoS shapes the traffic sent out of the given port with a
+	// linux-htb QoS, whose maximum rate is in bits per second and burst size
+	// in bits. It replaces the QoS previously set by it on the port.
+	SetPortQoS(port string, maxRate, burst int64) error
+	// ClearPortQoS removes the QoS set by SetPortQoS from the given port.
+	ClearPortQoS(port string) error
+	// GetDatapathFlowStats executes "ovs-appctl upcall/show" to get the number
+	// of flows installed in the OVS datapath and the maximum number of flows
+	// ovs-vswitchd installs in it.
+	GetDatapathFlowStats() (*DatapathFlowStats, error)
+	// SetInterfaceIngressPolicing polices the traffic received on the given
+	// interface, with the rate in kbps and burst size in kb. A rate of 0
+	// disables the policing.
+	SetInterfaceIngressPolicing(iface string, rate, burst int64) error
+}
+
+// DatapathFlowStats describes the utilization of the flow table of an OVS
+// datapath. When the number of flows reaches the limit, ovs-vswitchd stops
+// installing new flows in the datapath and the packets which miss the installed
+// flows are processed by the slow path.
+type DatapathFlowStats struct {
+	Flows int
+	Limit int
 }
 
 type BadRequestError string
@@ -17,9 +17,41 @@ package ovsctl
 import (
 	"bufio"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
+// DefaultFlowPriority is the priority of the flows for which ovs-ofctl omits it.
+const DefaultFlowPriority = 32768
+
+// flowMetadataFields are the fields printed by "ovs-ofctl dump-flows" which
+// are neither match conditions nor parsed into a DumpedFlow.
+var flowMetadataFields = map[string]bool{
+	"cookie":          true,
+	"duration":        true,
+	"idle_age":        true,
+	"hard_age":        true,
+	"importance":      true,
+	"send_flow_rem":   true,
+	"check_overlap":   true,
+	"reset_counts":    true,
+	"no_packet_count": true,
+	"no_byte_count":   true,
+}
+
+// DumpedFlow is a flow printed by "ovs-ofctl dump-flows".
+type DumpedFlow struct {
+	Table    uint8
+	Priority uint16
+	// Match are the match conditions of the flow, in the printed order.
+	Match       []string
+	Actions     string
+	Packets     uint64
+	Bytes       uint64
+	IdleTimeout uint16
+	HardTimeout uint16
+}
+
 func (c *ovsCtlClient) DumpFlows(args ...string) ([]string, error) {
 	// Print table and port names.
 	flowDump, err := c.RunOfctlCmd("dump-flows", append(args, "--names")...)
@@ -127,3 +159,80 @@ func flowExactMatch(matchStr, flowStr string) bool {
 	}
 	return true
 }
+
+// SplitFlowDump returns the flows printed by "ovs-ofctl dump-flows", without
+// the reply header printed by some OVS versions.
+func SplitFlowDump(flowDump []byte) []string {
+	flows := []string{}
+	scanner := bufio.NewScanner(strings.NewReader(string(flowDump)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.Contains(line, " actions=") {
+			flows = append(flows, line)
+		}
+	}
+	return flows
+}
+
+// ParseDumpedFlow parses a flow printed by "ovs-ofctl dump-flows".
+func ParseDumpedFlow(flowStr string) (*DumpedFlow, error) {
+	flowStr = strings.TrimSpace(flowStr)
+	i := strings.Index(flowStr, " actions=")
+	if i < 0 {
+		return nil, fmt.Errorf("no actions in flow %q", flowStr)
+	}
+	flow, err := ParseFlowMatch(flowStr[:i])
+	if err != nil {
+		return nil, fmt.Errorf("invalid flow %q: %v", flowStr, err)
+	}
+	flow.Actions = flowStr[i+len(" actions="):]
+	return flow, nil
+}
+
+// ParseFlowMatch parses the part of a flow printed by "ovs-ofctl dump-flows"
+// before its actions, or a flow given by its table, priority and match
+// conditions.
+func ParseFlowMatch(matchStr string) (*DumpedFlow, error) {
+	flow := &DumpedFlow{Priority: DefaultFlowPriority}
+	// Some fields, e.g. reset_counts, are separated by a space instead of a
+	// comma.
+	fields := strings.FieldsFunc(matchStr, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	for _, field := range fields {
+		kv := strings.SplitN(field, "=", 2)
+		if flowMetadataFields[kv[0]] {
+			continue
+		}
+		if len(kv) == 1 {
+			flow.Match = append(flow.Match, field)
+			continue
+		}
+		var n uint64
+		var err error
+		switch kv[0] {
+		case "table":
+			n, err = strconv.ParseUint(kv[1], 10, 8)
+			flow.Table = uint8(n)
+		case "priority":
+			n, err = strconv.ParseUint(kv[1], 10, 16)
+			flow.Priority = uint16(n)
+		case "n_packets":
+			flow.Packets, err = strconv.ParseUint(kv[1], 10, 64)
+		case "n_bytes":
+			flow.Bytes, err = strconv.ParseUint(kv[1], 10, 64)
+		case "idle_timeout":
+			n, err = strconv.ParseUint(kv[1], 10, 16)
+			flow.IdleTimeout = uint16(n)
+		case "hard_timeout":
+			n, err = strconv.ParseUint(kv[1], 10, 16)
+			flow.HardTimeout = uint16(n)
+		default:
+			flow.Match = append(flow.Match, field)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %q: %v", field, err)
+		}
+	}
+	return flow, nil
+}
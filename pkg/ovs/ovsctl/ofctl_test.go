@@ -0,0 +1,108 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsctl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitFlowDump(t *testing.T) {
+	flowDump := `OFPST_FLOW reply (OF1.3) (xid=0x2):
+ cookie=0x1, duration=1.5s, table=0, n_packets=12, n_bytes=1024, priority=190,in_port=3 actions=resubmit(,10)
+ cookie=0x1, duration=1.5s, table=10, n_packets=0, n_bytes=0, reset_counts priority=200,arp actions=drop
+`
+	assert.Equal(t, []string{
+		"cookie=0x1, duration=1.5s, table=0, n_packets=12, n_bytes=1024, priority=190,in_port=3 actions=resubmit(,10)",
+		"cookie=0x1, duration=1.5s, table=10, n_packets=0, n_bytes=0, reset_counts priority=200,arp actions=drop",
+	}, SplitFlowDump([]byte(flowDump)))
+}
+
+func TestParseDumpedFlow(t *testing.T) {
+	tests := []struct {
+		name         string
+		flowStr      string
+		expectedFlow *DumpedFlow
+		expectErr    bool
+	}{
+		{
+			name:    "statistics",
+			flowStr: " cookie=0x1, duration=1.5s, table=10, n_packets=5, n_bytes=210, idle_age=8, priority=200,arp,in_port=3 actions=ct(commit,table=20),output:3",
+			expectedFlow: &DumpedFlow{
+				Table:    10,
+				Priority: 200,
+				Match:    []string{"arp", "in_port=3"},
+				Actions:  "ct(commit,table=20),output:3",
+				Packets:  5,
+				Bytes:    210,
+			},
+		},
+		{
+			name:    "timeouts and space-separated fields",
+			flowStr: " cookie=0x1, duration=1.5s, table=40, n_packets=0, n_bytes=0, idle_timeout=60, hard_timeout=300, reset_counts priority=200,tcp,tp_dst=80 actions=drop",
+			expectedFlow: &DumpedFlow{
+				Table:       40,
+				Priority:    200,
+				Match:       []string{"tcp", "tp_dst=80"},
+				Actions:     "drop",
+				IdleTimeout: 60,
+				HardTimeout: 300,
+			},
+		},
+		{
+			name:    "default priority",
+			flowStr: " cookie=0x1, table=110, n_packets=0, n_bytes=0, ip,nw_dst=10.10.1.2 actions=output:3",
+			expectedFlow: &DumpedFlow{
+				Table:    110,
+				Priority: DefaultFlowPriority,
+				Match:    []string{"ip", "nw_dst=10.10.1.2"},
+				Actions:  "output:3",
+			},
+		},
+		{
+			name:      "no actions",
+			flowStr:   " cookie=0x1, table=10, priority=200,arp",
+			expectErr: true,
+		},
+		{
+			name:      "invalid table",
+			flowStr:   " cookie=0x1, table=300, priority=200,arp actions=drop",
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flow, err := ParseDumpedFlow(tt.flowStr)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedFlow, flow)
+		})
+	}
+}
+
+func TestParseFlowMatch(t *testing.T) {
+	flow, err := ParseFlowMatch("table=31,priority=190,ct_state=+inv+trk,ip")
+	require.NoError(t, err)
+	assert.Equal(t, &DumpedFlow{
+		Table:    31,
+		Priority: 190,
+		Match:    []string{"ct_state=+inv+trk", "ip"},
+	}, flow)
+}
@@ -48,6 +48,20 @@ func (m *MockOVSCtlClient) EXPECT() *MockOVSCtlClientMockRecorder {
 	return m.recorder
 }
 
+// ClearPortQoS mocks base method
+func (m *MockOVSCtlClient) ClearPortQoS(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearPortQoS", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearPortQoS indicates an expected call of ClearPortQoS
+func (mr *MockOVSCtlClientMockRecorder) ClearPortQoS(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearPortQoS", reflect.TypeOf((*MockOVSCtlClient)(nil).ClearPortQoS), arg0)
+}
+
 // DumpFlows mocks base method
 func (m *MockOVSCtlClient) DumpFlows(arg0 ...string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -116,6 +130,21 @@ func (mr *MockOVSCtlClientMockRecorder) DumpTableFlows(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DumpTableFlows", reflect.TypeOf((*MockOVSCtlClient)(nil).DumpTableFlows), arg0)
 }
 
+// GetDatapathFlowStats mocks base method
+func (m *MockOVSCtlClient) GetDatapathFlowStats() (*ovsctl.DatapathFlowStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDatapathFlowStats")
+	ret0, _ := ret[0].(*ovsctl.DatapathFlowStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDatapathFlowStats indicates an expected call of GetDatapathFlowStats
+func (mr *MockOVSCtlClientMockRecorder) GetDatapathFlowStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDatapathFlowStats", reflect.TypeOf((*MockOVSCtlClient)(nil).GetDatapathFlowStats))
+}
+
 // RunOfctlCmd mocks base method
 func (m *MockOVSCtlClient) RunOfctlCmd(arg0 string, arg1 ...string) ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -136,6 +165,40 @@ func (mr *MockOVSCtlClientMockRecorder) RunOfctlCmd(arg0 interface{}, arg1 ...in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunOfctlCmd", reflect.TypeOf((*MockOVSCtlClient)(nil).RunOfctlCmd), varargs...)
 }
 
+// RunVsctlCmd mocks base method
+func (m *MockOVSCtlClient) RunVsctlCmd(arg0 string, arg1 ...string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RunVsctlCmd", varargs...)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunVsctlCmd indicates an expected call of RunVsctlCmd
+func (mr *MockOVSCtlClientMockRecorder) RunVsctlCmd(arg0 interface{}, arg1 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunVsctlCmd", reflect.TypeOf((*MockOVSCtlClient)(nil).RunVsctlCmd), varargs...)
+}
+
+// SetInterfaceIngressPolicing mocks base method
+func (m *MockOVSCtlClient) SetInterfaceIngressPolicing(arg0 string, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetInterfaceIngressPolicing", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetInterfaceIngressPolicing indicates an expected call of SetInterfaceIngressPolicing
+func (mr *MockOVSCtlClientMockRecorder) SetInterfaceIngressPolicing(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetInterfaceIngressPolicing", reflect.TypeOf((*MockOVSCtlClient)(nil).SetInterfaceIngressPolicing), arg0, arg1, arg2)
+}
+
 // SetPortNoFlood mocks base method
 func (m *MockOVSCtlClient) SetPortNoFlood(arg0 int) error {
 	m.ctrl.T.Helper()
@@ -150,6 +213,20 @@ func (mr *MockOVSCtlClientMockRecorder) SetPortNoFlood(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPortNoFlood", reflect.TypeOf((*MockOVSCtlClient)(nil).SetPortNoFlood), arg0)
 }
 
+// SetPortQoS mocks base method
+func (m *MockOVSCtlClient) SetPortQoS(arg0 string, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPortQoS", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPortQoS indicates an expected call of SetPortQoS
+func (mr *MockOVSCtlClientMockRecorder) SetPortQoS(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPortQoS", reflect.TypeOf((*MockOVSCtlClient)(nil).SetPortQoS), arg0, arg1, arg2)
+}
+
 // Trace mocks base method
 func (m *MockOVSCtlClient) Trace(arg0 *ovsctl.TracingRequest) (string, error) {
 	m.ctrl.T.Helper()
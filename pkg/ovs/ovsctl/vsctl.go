@@ -0,0 +1,88 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsctl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// qosPortExternalID is the external ID set on the QoS and Queue records created
+// by SetPortQoS, whose value is the name of the port they are created for. It
+// is used to find the records to destroy when the QoS of the port is replaced
+// or cleared, as OVSDB does not garbage-collect them.
+const qosPortExternalID = "antrea-qos-port"
+
+func (c *ovsCtlClient) SetPortQoS(port string, maxRate, burst int64) error {
+	destroyCmds, err := c.destroyPortQoSCmds(port)
+	if err != nil {
+		return err
+	}
+	cmdStr := fmt.Sprintf("ovs-vsctl -- set Port %[1]s qos=@qos"+
+		" -- --id=@qos create QoS type=linux-htb other-config:max-rate=%[2]d queues:0=@queue external-ids:%[4]s=%[1]s"+
+		" -- --id=@queue create Queue other-config:max-rate=%[2]d other-config:burst=%[3]d external-ids:%[4]s=%[1]s%[5]s",
+		port, maxRate, burst, qosPortExternalID, destroyCmds)
+	return runVsctlCmd(cmdStr)
+}
+
+func (c *ovsCtlClient) ClearPortQoS(port string) error {
+	destroyCmds, err := c.destroyPortQoSCmds(port)
+	if err != nil {
+		return err
+	}
+	cmdStr := fmt.Sprintf("ovs-vsctl -- --if-exists clear Port %s qos%s", port, destroyCmds)
+	return runVsctlCmd(cmdStr)
+}
+
+func (c *ovsCtlClient) SetInterfaceIngressPolicing(iface string, rate, burst int64) error {
+	cmdStr := fmt.Sprintf("ovs-vsctl --if-exists set Interface %s ingress_policing_rate=%d ingress_policing_burst=%d", iface, rate, burst)
+	return runVsctlCmd(cmdStr)
+}
+
+// destroyPortQoSCmds returns the "ovs-vsctl" commands destroying the QoS and
+// Queue records created for the port. They must run in the same transaction as
+// the command removing the references to the records from the port.
+func (c *ovsCtlClient) destroyPortQoSCmds(port string) (string, error) {
+	var cmds []string
+	for _, table := range []string{"QoS", "Queue"} {
+		cmdStr := fmt.Sprintf("ovs-vsctl --bare --columns=_uuid find %s external-ids:%s=%s", table, qosPortExternalID, port)
+		out, err := getOVSCommand(cmdStr).Output()
+		if err != nil {
+			return "", err
+		}
+		for _, uuid := range strings.Fields(string(out)) {
+			cmds = append(cmds, fmt.Sprintf(" -- destroy %s %s", table, uuid))
+		}
+	}
+	return strings.Join(cmds, ""), nil
+}
+
+func runVsctlCmd(cmdStr string) error {
+	out, err := getOVSCommand(cmdStr).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error running %q: %v, output: %s", cmdStr, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (c *ovsCtlClient) RunVsctlCmd(cmd string, args ...string) ([]byte, error) {
+	cmdStr := fmt.Sprintf("ovs-vsctl %s", cmd)
+	cmdStr = cmdStr + " " + strings.Join(args, " ")
+	out, err := getOVSCommand(cmdStr).Output()
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -17,6 +17,7 @@ package querier
 import (
 	v1 "k8s.io/api/core/v1"
 
+	clusterinfov1beta1 "github.com/vmware-tanzu/antrea/pkg/apis/clusterinformation/v1beta1"
 	networkingv1beta1 "github.com/vmware-tanzu/antrea/pkg/apis/networking/v1beta1"
 	"github.com/vmware-tanzu/antrea/pkg/util/env"
 	"github.com/vmware-tanzu/antrea/pkg/version"
@@ -38,6 +39,12 @@ type AgentNetworkPolicyInfoQuerier interface {
 	GetAppliedNetworkPolicies(pod, namespace string) []networkingv1beta1.NetworkPolicy
 }
 
+// AgentNetworkPolicyStatsQuerier is the interface to query the traffic
+// statistics of the NetworkPolicies enforced by the agent.
+type AgentNetworkPolicyStatsQuerier interface {
+	GetNetworkPolicyStats() []clusterinfov1beta1.NetworkPolicyStats
+}
+
 type ControllerNetworkPolicyInfoQuerier interface {
 	NetworkPolicyInfoQuerier
 	GetConnectedAgentNum() int
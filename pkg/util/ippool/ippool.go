@@ -0,0 +1,147 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ippool
+
+import (
+	"fmt"
+	"math"
+	"net"
+
+	"github.com/containernetworking/plugins/pkg/ip"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/vmware-tanzu/antrea/pkg/apis/core/v1alpha1"
+)
+
+// StaticIPAnnotationKey is the annotation of the Pods which request a specific
+// IP from the IPPool matching them, instead of the next available one.
+const StaticIPAnnotationKey = "ipam.antrea.io/static-ip"
+
+// Range is a parsed IPRange of an IPPool.
+type Range struct {
+	CIDR    *net.IPNet
+	Gateway net.IP
+	VLAN    int32
+}
+
+// ParseRanges parses the ranges of the IPPool. It returns an error if a CIDR is
+// invalid, if a gateway is not in its CIDR, or if two ranges overlap. The gateway
+// of a range defaults to the first IP of its CIDR.
+func ParseRanges(pool *v1alpha1.IPPool) ([]Range, error) {
+	if len(pool.Spec.Ranges) == 0 {
+		return nil, fmt.Errorf("no range is specified")
+	}
+	ranges := make([]Range, 0, len(pool.Spec.Ranges))
+	for _, r := range pool.Spec.Ranges {
+		cidrIP, cidr, err := net.ParseCIDR(r.CIDR)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %s: %v", r.CIDR, err)
+		}
+		if !cidrIP.Equal(cidr.IP) {
+			return nil, fmt.Errorf("invalid CIDR %s: it should be %s", r.CIDR, cidr.String())
+		}
+		var gateway net.IP
+		if r.Gateway == "" {
+			gateway = ip.NextIP(cidr.IP)
+		} else if gateway = net.ParseIP(r.Gateway); gateway == nil {
+			return nil, fmt.Errorf("invalid gateway %s of CIDR %s", r.Gateway, r.CIDR)
+		}
+		if !cidr.Contains(gateway) {
+			return nil, fmt.Errorf("gateway %s is not in CIDR %s", gateway.String(), r.CIDR)
+		}
+		for _, parsed := range ranges {
+			if Overlaps(parsed.CIDR, cidr) {
+				return nil, fmt.Errorf("CIDR %s overlaps with CIDR %s", r.CIDR, parsed.CIDR.String())
+			}
+		}
+		ranges = append(ranges, Range{CIDR: cidr, Gateway: gateway, VLAN: r.VLAN})
+	}
+	return ranges, nil
+}
+
+// IsAllocatable returns whether the IP can be allocated to a Pod from the range,
+// i.e. whether it is in the CIDR of the range and is neither the network IP, the
+// last IP nor the gateway of the range.
+func IsAllocatable(r *Range, allocatedIP net.IP) bool {
+	return r.CIDR.Contains(allocatedIP) &&
+		!allocatedIP.Equal(r.CIDR.IP) &&
+		r.CIDR.Contains(ip.NextIP(allocatedIP)) &&
+		!allocatedIP.Equal(r.Gateway)
+}
+
+// Capacity returns the number of IPs which can be allocated from the ranges.
+func Capacity(ranges []Range) float64 {
+	var capacity float64
+	for i := range ranges {
+		r := &ranges[i]
+		ones, bits := r.CIDR.Mask.Size()
+		size := math.Pow(2, float64(bits-ones))
+		// The network IP and the last IP are never allocated, and neither is
+		// the gateway.
+		reserved := 2.0
+		if !r.Gateway.Equal(r.CIDR.IP) && r.CIDR.Contains(ip.NextIP(r.Gateway)) {
+			reserved++
+		}
+		if size > reserved {
+			capacity += size - reserved
+		}
+	}
+	return capacity
+}
+
+// Overlaps returns whether the two CIDRs have at least one IP in common.
+func Overlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// GetCondition returns the condition of the given type, or nil if the status
+// has no such condition.
+func GetCondition(status *v1alpha1.IPPoolStatus, conditionType v1alpha1.IPPoolConditionType) *v1alpha1.IPPoolCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == conditionType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsValid returns whether the IPPool has been validated by antrea-controller.
+func IsValid(pool *v1alpha1.IPPool) bool {
+	condition := GetCondition(&pool.Status, v1alpha1.IPPoolConditionValid)
+	return condition != nil && condition.Status == corev1.ConditionTrue
+}
+
+// SetCondition sets the condition in the status, and returns whether the status
+// has changed. The last transition time of the condition is only updated when
+// its status changes.
+func SetCondition(status *v1alpha1.IPPoolStatus, condition v1alpha1.IPPoolCondition) bool {
+	existing := GetCondition(status, condition.Type)
+	if existing == nil {
+		condition.LastTransitionTime = metav1.Now()
+		status.Conditions = append(status.Conditions, condition)
+		return true
+	}
+	if existing.Status == condition.Status && existing.Reason == condition.Reason && existing.Message == condition.Message {
+		return false
+	}
+	if existing.Status != condition.Status {
+		existing.LastTransitionTime = metav1.Now()
+	}
+	existing.Status = condition.Status
+	existing.Reason = condition.Reason
+	existing.Message = condition.Message
+	return true
+}
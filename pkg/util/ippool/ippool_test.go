@@ -0,0 +1,110 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ippool
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/vmware-tanzu/antrea/pkg/apis/core/v1alpha1"
+)
+
+func newIPPool(ranges ...v1alpha1.IPRange) *v1alpha1.IPPool {
+	return &v1alpha1.IPPool{Spec: v1alpha1.IPPoolSpec{Ranges: ranges}}
+}
+
+func TestParseRanges(t *testing.T) {
+	ranges, err := ParseRanges(newIPPool(
+		v1alpha1.IPRange{CIDR: "10.10.0.0/24"},
+		v1alpha1.IPRange{CIDR: "10.10.1.0/24", Gateway: "10.10.1.254", VLAN: 100},
+	))
+	require.NoError(t, err)
+	require.Len(t, ranges, 2)
+	assert.Equal(t, "10.10.0.0/24", ranges[0].CIDR.String())
+	assert.True(t, net.ParseIP("10.10.0.1").Equal(ranges[0].Gateway))
+	assert.Equal(t, "10.10.1.0/24", ranges[1].CIDR.String())
+	assert.True(t, net.ParseIP("10.10.1.254").Equal(ranges[1].Gateway))
+	assert.Equal(t, int32(100), ranges[1].VLAN)
+
+	for name, pool := range map[string]*v1alpha1.IPPool{
+		"no range":            newIPPool(),
+		"invalid CIDR":        newIPPool(v1alpha1.IPRange{CIDR: "10.10.0.0"}),
+		"host bits set":       newIPPool(v1alpha1.IPRange{CIDR: "10.10.0.1/24"}),
+		"invalid gateway":     newIPPool(v1alpha1.IPRange{CIDR: "10.10.0.0/24", Gateway: "10.10.0"}),
+		"gateway not in CIDR": newIPPool(v1alpha1.IPRange{CIDR: "10.10.0.0/24", Gateway: "10.10.1.1"}),
+		"no default gateway":  newIPPool(v1alpha1.IPRange{CIDR: "10.10.0.0/32"}),
+		"overlapping ranges":  newIPPool(v1alpha1.IPRange{CIDR: "10.10.0.0/16"}, v1alpha1.IPRange{CIDR: "10.10.1.0/24"}),
+	} {
+		_, err := ParseRanges(pool)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSetCondition(t *testing.T) {
+	status := &v1alpha1.IPPoolStatus{}
+	assert.True(t, SetCondition(status, v1alpha1.IPPoolCondition{Type: v1alpha1.IPPoolConditionValid, Status: corev1.ConditionFalse, Reason: "Overlapping"}))
+	require.Len(t, status.Conditions, 1)
+	transitionTime := status.Conditions[0].LastTransitionTime
+	assert.False(t, transitionTime.IsZero())
+	assert.False(t, IsValid(&v1alpha1.IPPool{Status: *status}))
+
+	assert.False(t, SetCondition(status, v1alpha1.IPPoolCondition{Type: v1alpha1.IPPoolConditionValid, Status: corev1.ConditionFalse, Reason: "Overlapping"}))
+	assert.True(t, SetCondition(status, v1alpha1.IPPoolCondition{Type: v1alpha1.IPPoolConditionValid, Status: corev1.ConditionFalse, Reason: "InvalidRange"}))
+	assert.Equal(t, "InvalidRange", status.Conditions[0].Reason)
+	assert.Equal(t, transitionTime, status.Conditions[0].LastTransitionTime)
+
+	assert.True(t, SetCondition(status, v1alpha1.IPPoolCondition{Type: v1alpha1.IPPoolConditionValid, Status: corev1.ConditionTrue}))
+	assert.True(t, IsValid(&v1alpha1.IPPool{Status: *status}))
+	assert.Nil(t, GetCondition(status, v1alpha1.IPPoolConditionPoolExhausted))
+
+	assert.True(t, SetCondition(status, v1alpha1.IPPoolCondition{Type: v1alpha1.IPPoolConditionPoolExhausted, Status: corev1.ConditionTrue}))
+	assert.Len(t, status.Conditions, 2)
+}
+
+func TestIsAllocatable(t *testing.T) {
+	ranges, err := ParseRanges(newIPPool(v1alpha1.IPRange{CIDR: "10.10.0.0/29", Gateway: "10.10.0.6"}))
+	require.NoError(t, err)
+	for ip, allocatable := range map[string]bool{
+		"10.10.0.0": false,
+		"10.10.0.1": true,
+		"10.10.0.5": true,
+		"10.10.0.6": false,
+		"10.10.0.7": false,
+		"10.10.0.8": false,
+	} {
+		assert.Equal(t, allocatable, IsAllocatable(&ranges[0], net.ParseIP(ip)), ip)
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	for name, tc := range map[string]struct {
+		pool     *v1alpha1.IPPool
+		capacity float64
+	}{
+		"default gateway": {newIPPool(v1alpha1.IPRange{CIDR: "10.10.0.0/29"}), 5},
+		"gateway last IP": {newIPPool(v1alpha1.IPRange{CIDR: "10.10.0.0/29", Gateway: "10.10.0.7"}), 6},
+		"several ranges":  {newIPPool(v1alpha1.IPRange{CIDR: "10.10.0.0/29"}, v1alpha1.IPRange{CIDR: "10.10.1.0/24"}), 258},
+		"point-to-point":  {newIPPool(v1alpha1.IPRange{CIDR: "10.10.0.0/31", Gateway: "10.10.0.0"}), 0},
+		"IPv6 /120 range": {newIPPool(v1alpha1.IPRange{CIDR: "fd00::/120"}), 253},
+	} {
+		ranges, err := ParseRanges(tc.pool)
+		require.NoError(t, err, name)
+		assert.Equal(t, tc.capacity, Capacity(ranges), name)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+)
+
+const (
+	// SecretName is the name of the Secret which stores the IPSec PSK, in the
+	// Namespace of Antrea.
+	SecretName = "antrea-ipsec"
+	// PSKKey is the key of the current PSK in the Secret data. The current PSK
+	// is used by the agents to establish new SAs.
+	PSKKey = "psk"
+	// PreviousPSKKey is the key of the previous PSK in the Secret data. It's
+	// only set in the RotationPhaseBoth phase.
+	PreviousPSKKey = "previousPSK"
+
+	// RotationPhaseAnnotationKey is the annotation of the Secret which holds
+	// the phase of the key rotation.
+	RotationPhaseAnnotationKey = "ipsec.antrea.io/rotation-phase"
+	// KeyGenerationAnnotationKey is the annotation which holds the generation
+	// of the current PSK. It's set on the Secret by the controller when the PSK
+	// is rotated, and on the Node by an agent once it has applied the PSK of
+	// that generation.
+	KeyGenerationAnnotationKey = "ipsec.antrea.io/key-generation"
+	// LastRotationTimeAnnotationKey is the annotation of the Secret which holds
+	// the time the last key rotation started, in RFC3339 format.
+	LastRotationTimeAnnotationKey = "ipsec.antrea.io/last-rotation-time"
+	// RotationRequestedAnnotationKey is the annotation of the Secret which is
+	// set by "antctl rotate-ipsec-key" to request a key rotation.
+	RotationRequestedAnnotationKey = "ipsec.antrea.io/rotation-requested"
+
+	pskLen = 32
+)
+
+// RotationPhase is the phase of the key rotation state machine, which goes
+// from RotationPhaseOldOnly to RotationPhaseBoth when a new PSK is generated,
+// and from RotationPhaseBoth to RotationPhaseNewOnly when all the agents have
+// applied the new PSK.
+type RotationPhase string
+
+const (
+	// RotationPhaseOldOnly means no key rotation has happened yet.
+	RotationPhaseOldOnly RotationPhase = "old_only"
+	// RotationPhaseBoth means the Secret holds both the previous and the
+	// current PSK, and the SAs established with either of them are active.
+	RotationPhaseBoth RotationPhase = "both"
+	// RotationPhaseNewOnly means all the agents have applied the current PSK
+	// and the previous PSK has been removed.
+	RotationPhaseNewOnly RotationPhase = "new_only"
+)
+
+// GetRotationPhase returns the rotation phase in the provided annotations of
+// the Secret. It defaults to RotationPhaseOldOnly.
+func GetRotationPhase(annotations map[string]string) RotationPhase {
+	switch phase := RotationPhase(annotations[RotationPhaseAnnotationKey]); phase {
+	case RotationPhaseBoth, RotationPhaseNewOnly:
+		return phase
+	default:
+		return RotationPhaseOldOnly
+	}
+}
+
+// GetKeyGeneration returns the key generation in the provided annotations of
+// the Secret or the Node. It defaults to 0, i.e. the initial PSK.
+func GetKeyGeneration(annotations map[string]string) int64 {
+	generation, err := strconv.ParseInt(annotations[KeyGenerationAnnotationKey], 10, 64)
+	if err != nil || generation < 0 {
+		return 0
+	}
+	return generation
+}
+
+// GeneratePSK returns a random PSK encoded in base64.
+func GeneratePSK() (string, error) {
+	psk := make([]byte, pskLen)
+	if _, err := rand.Read(psk); err != nil {
+		return "", fmt.Errorf("error generating PSK: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(psk), nil
+}
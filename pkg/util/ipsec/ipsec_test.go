@@ -0,0 +1,49 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRotationPhase(t *testing.T) {
+	assert.Equal(t, RotationPhaseOldOnly, GetRotationPhase(nil))
+	assert.Equal(t, RotationPhaseOldOnly, GetRotationPhase(map[string]string{RotationPhaseAnnotationKey: "unknown"}))
+	assert.Equal(t, RotationPhaseBoth, GetRotationPhase(map[string]string{RotationPhaseAnnotationKey: "both"}))
+	assert.Equal(t, RotationPhaseNewOnly, GetRotationPhase(map[string]string{RotationPhaseAnnotationKey: "new_only"}))
+}
+
+func TestGetKeyGeneration(t *testing.T) {
+	assert.Equal(t, int64(0), GetKeyGeneration(nil))
+	assert.Equal(t, int64(0), GetKeyGeneration(map[string]string{KeyGenerationAnnotationKey: "foo"}))
+	assert.Equal(t, int64(0), GetKeyGeneration(map[string]string{KeyGenerationAnnotationKey: "-1"}))
+	assert.Equal(t, int64(3), GetKeyGeneration(map[string]string{KeyGenerationAnnotationKey: "3"}))
+}
+
+func TestGeneratePSK(t *testing.T) {
+	psk, err := GeneratePSK()
+	require.NoError(t, err)
+	decoded, err := base64.StdEncoding.DecodeString(psk)
+	require.NoError(t, err)
+	assert.Len(t, decoded, pskLen)
+
+	otherPSK, err := GeneratePSK()
+	require.NoError(t, err)
+	assert.NotEqual(t, psk, otherPSK)
+}
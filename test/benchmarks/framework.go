@@ -0,0 +1,180 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package benchmarks contains the datapath benchmarks of Antrea. They run against an existing K8s
+// cluster with Antrea installed, and report their results with the Go benchmark metrics.
+package benchmarks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const (
+	antreaNamespace  = "kube-system"
+	ovsContainerName = "antrea-ovs"
+	// ovsHWOffloadKey is the key in the "other_config" column of the Open_vSwitch table which
+	// enables hardware offload.
+	ovsHWOffloadKey = "hw-offload"
+
+	// DatapathModeSoftware and DatapathModeOffload are the datapath modes reported with the
+	// results, so that runs with and without hwOffloadMode can be compared.
+	DatapathModeSoftware = "software"
+	DatapathModeOffload  = "offload"
+
+	defaultTimeout = 90 * time.Second
+)
+
+// BenchData holds the clients used to run the benchmarks, and the Namespace in which the
+// benchmark Pods are created.
+type BenchData struct {
+	kubeConfig *rest.Config
+	clientset  kubernetes.Interface
+	namespace  string
+}
+
+// NewBenchData creates the K8s clients from the provided kubeconfig file, or from the default
+// kubeconfig loading rules if it is empty, and creates the Namespace of the benchmark Pods.
+func NewBenchData(kubeconfigPath string, namespace string) (*BenchData, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfigPath
+	kubeConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error when building kube config: %v", err)
+	}
+	clientset, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error when creating kubernetes client: %v", err)
+	}
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	if _, err := clientset.CoreV1().Namespaces().Create(context.TODO(), ns, metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("error when creating Namespace '%s': %v", namespace, err)
+	}
+	return &BenchData{kubeConfig: kubeConfig, clientset: clientset, namespace: namespace}, nil
+}
+
+// Teardown deletes the Namespace of the benchmark Pods.
+func (data *BenchData) Teardown() error {
+	return data.clientset.CoreV1().Namespaces().Delete(context.TODO(), data.namespace, metav1.DeleteOptions{})
+}
+
+// NodeNames returns the names of the Nodes of the cluster.
+func (data *BenchData) NodeNames() ([]string, error) {
+	nodes, err := data.clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error when listing Nodes: %v", err)
+	}
+	names := make([]string, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		names = append(names, node.Name)
+	}
+	return names, nil
+}
+
+// CreatePodOnNode creates a Pod with a single container running the provided image on the Node,
+// and waits for it to be running. It returns the IP of the Pod.
+func (data *BenchData) CreatePodOnNode(name, nodeName, image string, ports []v1.ContainerPort) (string, error) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name:            name,
+					Image:           image,
+					ImagePullPolicy: v1.PullIfNotPresent,
+					Ports:           ports,
+				},
+			},
+			NodeSelector:  map[string]string{"kubernetes.io/hostname": nodeName},
+			RestartPolicy: v1.RestartPolicyNever,
+			// The benchmarks may use the master Node.
+			Tolerations: []v1.Toleration{{Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule}},
+		},
+	}
+	if _, err := data.clientset.CoreV1().Pods(data.namespace).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		return "", fmt.Errorf("error when creating Pod '%s': %v", name, err)
+	}
+	var podIP string
+	err := wait.Poll(time.Second, defaultTimeout, func() (bool, error) {
+		pod, err := data.clientset.CoreV1().Pods(data.namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		podIP = pod.Status.PodIP
+		return pod.Status.Phase == v1.PodRunning && podIP != "", nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error when waiting for Pod '%s' to be running: %v", name, err)
+	}
+	return podIP, nil
+}
+
+// RunCommandFromPod runs the provided command in a container of the Pod, and returns its stdout
+// and stderr.
+func (data *BenchData) RunCommandFromPod(podNamespace, podName, containerName string, cmd []string) (stdout string, stderr string, err error) {
+	request := data.clientset.CoreV1().RESTClient().Post().
+		Namespace(podNamespace).
+		Resource("pods").
+		Name(podName).
+		SubResource("exec").
+		Param("container", containerName).
+		VersionedParams(&v1.PodExecOptions{
+			Command: cmd,
+			Stdout:  true,
+			Stderr:  true,
+		}, scheme.ParameterCodec)
+	exec, err := remotecommand.NewSPDYExecutor(data.kubeConfig, "POST", request.URL())
+	if err != nil {
+		return "", "", err
+	}
+	var stdoutB, stderrB bytes.Buffer
+	err = exec.Stream(remotecommand.StreamOptions{Stdout: &stdoutB, Stderr: &stderrB})
+	return stdoutB.String(), stderrB.String(), err
+}
+
+// DatapathMode returns DatapathModeOffload if OVS hardware offload is enabled on the Node, and
+// DatapathModeSoftware otherwise.
+func (data *BenchData) DatapathMode(nodeName string) (string, error) {
+	pods, err := data.clientset.CoreV1().Pods(antreaNamespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: "app=antrea,component=antrea-agent",
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", nodeName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list Antrea Pods: %v", err)
+	}
+	if len(pods.Items) != 1 {
+		return "", fmt.Errorf("expected exactly one Antrea Agent Pod on Node '%s'", nodeName)
+	}
+	cmd := []string{"ovs-vsctl", "--if-exists", "get", "Open_vSwitch", ".", "other_config:" + ovsHWOffloadKey}
+	stdout, stderr, err := data.RunCommandFromPod(antreaNamespace, pods.Items[0].Name, ovsContainerName, cmd)
+	if err != nil {
+		return "", fmt.Errorf("error when querying OVS other_config: %v, stderr: %s", err, stderr)
+	}
+	if strings.Trim(strings.TrimSpace(stdout), `"`) == "true" {
+		return DatapathModeOffload, nil
+	}
+	return DatapathModeSoftware, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package benchmarks
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	perftoolImage = "antrea/perftool"
+	iperfPort     = 5201
+)
+
+var (
+	kubeconfigPath = flag.String("kubeconfig", "", "Path of the kubeconfig file of the cluster, the default loading rules are used if empty")
+	clientNodeName = flag.String("client-node", "", "Node running the client Pod, the first Node of the cluster is used if empty")
+	serverNodeName = flag.String("server-node", "", "Node running the server Pod, the second Node of the cluster is used if empty")
+)
+
+// iperfUDPResult is the subset of the JSON output of an iperf3 UDP client used to compute the packet rate.
+type iperfUDPResult struct {
+	End struct {
+		Sum struct {
+			Seconds     float64 `json:"seconds"`
+			Packets     int64   `json:"packets"`
+			LostPackets int64   `json:"lost_packets"`
+		} `json:"sum"`
+	} `json:"end"`
+}
+
+// parsePacketRate returns the rate of the packets received by the server, and the ratio of lost
+// packets, from the JSON output of an iperf3 UDP client.
+func parsePacketRate(output string) (pktsPerSec float64, lossRatio float64, err error) {
+	var result iperfUDPResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return 0, 0, fmt.Errorf("error when parsing iperf3 output: %v", err)
+	}
+	sum := result.End.Sum
+	if sum.Seconds == 0 || sum.Packets == 0 {
+		return 0, 0, fmt.Errorf("no packet sent in iperf3 output: %s", output)
+	}
+	return float64(sum.Packets-sum.LostPackets) / sum.Seconds, float64(sum.LostPackets) / float64(sum.Packets), nil
+}
+
+func TestParsePacketRate(t *testing.T) {
+	pktsPerSec, lossRatio, err := parsePacketRate(`{"end": {"sum": {"seconds": 10.0, "packets": 1000000, "lost_packets": 100000}}}`)
+	require.NoError(t, err)
+	assert.Equal(t, float64(90000), pktsPerSec)
+	assert.Equal(t, 0.1, lossRatio)
+
+	_, _, err = parsePacketRate(`{"error": "unable to connect to server"}`)
+	assert.Error(t, err)
+	_, _, err = parsePacketRate("iperf3: error")
+	assert.Error(t, err)
+}
+
+// BenchmarkPacketRateInterNode measures the packet rate (pkts/sec) between Pods on different Nodes,
+// using small UDP packets so that the result is bound by the packet rate rather than the bandwidth.
+// The datapath mode of the client Node is part of the benchmark name, so that runs with and without
+// hwOffloadMode can be compared.
+func BenchmarkPacketRateInterNode(b *testing.B) {
+	data, err := NewBenchData(*kubeconfigPath, "antrea-benchmarks")
+	if err != nil {
+		b.Skipf("Skipping benchmark, no usable cluster: %v", err)
+	}
+	defer func() {
+		if err := data.Teardown(); err != nil {
+			b.Errorf("Error when deleting the benchmark Namespace: %v", err)
+		}
+	}()
+
+	clientNode, serverNode := *clientNodeName, *serverNodeName
+	if clientNode == "" || serverNode == "" {
+		nodeNames, err := data.NodeNames()
+		require.NoError(b, err)
+		if len(nodeNames) < 2 {
+			b.Skipf("Skipping benchmark, it requires at least 2 Nodes")
+		}
+		if clientNode == "" {
+			clientNode = nodeNames[0]
+		}
+		if serverNode == "" {
+			serverNode = nodeNames[1]
+		}
+	}
+	mode, err := data.DatapathMode(clientNode)
+	require.NoError(b, err)
+
+	_, err = data.CreatePodOnNode("perftest-a", clientNode, perftoolImage, nil)
+	require.NoError(b, err)
+	serverIP, err := data.CreatePodOnNode("perftest-b", serverNode, perftoolImage, []v1.ContainerPort{{Protocol: v1.ProtocolUDP, ContainerPort: iperfPort}})
+	require.NoError(b, err)
+
+	b.Run(mode, func(b *testing.B) {
+		var totalRate, totalLoss float64
+		for i := 0; i < b.N; i++ {
+			// Unlimited bandwidth with 64-byte payloads.
+			cmd := []string{"iperf3", "-c", serverIP, "-u", "-b", "0", "-l", "64", "-J"}
+			stdout, stderr, err := data.RunCommandFromPod(data.namespace, "perftest-a", "perftest-a", cmd)
+			require.NoError(b, err, "stderr: %s", stderr)
+			pktsPerSec, lossRatio, err := parsePacketRate(stdout)
+			require.NoError(b, err)
+			totalRate += pktsPerSec
+			totalLoss += lossRatio
+		}
+		b.ReportMetric(totalRate/float64(b.N), "pkts/s")
+		b.ReportMetric(totalLoss/float64(b.N), "loss-ratio")
+	})
+}
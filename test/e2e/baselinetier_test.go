@@ -0,0 +1,133 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+)
+
+// TestBaselineTierPolicy verifies that the rules of a ClusterNetworkPolicy in the
+// baseline tier are enforced together with the K8s NetworkPolicies: a baseline
+// Drop rule cannot be overridden by a K8s NetworkPolicy, while the traffic
+// allowed by a baseline Allow rule is still subject to K8s NetworkPolicies.
+func TestBaselineTierPolicy(t *testing.T) {
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+	skipIfCNPDisabled(t, data)
+
+	serverPort := 80
+	serverName, serverIP, cleanupFunc := createAndWaitForPod(t, data, data.createNginxPodOnNode, "test-server-", "")
+	defer cleanupFunc()
+
+	clientName, _, cleanupFunc := createAndWaitForPod(t, data, data.createBusyboxPodOnNode, "test-client-", "")
+	defer cleanupFunc()
+
+	if err = data.runNetcatCommandFromTestPod(clientName, serverIP, serverPort); err != nil {
+		t.Fatalf("Pod %s should be able to connect %s:%d, but was not able to connect", clientName, serverIP, serverPort)
+	}
+
+	serverSelector := metav1.LabelSelector{MatchLabels: map[string]string{"antrea-e2e": serverName}}
+	clientSelector := metav1.LabelSelector{MatchLabels: map[string]string{"antrea-e2e": clientName}}
+	createBaselinePolicy := func(action secv1alpha1.RuleAction) *secv1alpha1.ClusterNetworkPolicy {
+		cnp := &secv1alpha1.ClusterNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: randName("cnp-baseline-")},
+			Spec: secv1alpha1.ClusterNetworkPolicySpec{
+				Tier:      secv1alpha1.TierBaseline,
+				Priority:  1,
+				AppliedTo: []secv1alpha1.NetworkPolicyPeer{{PodSelector: &serverSelector}},
+				Ingress: []secv1alpha1.Rule{{
+					Action: &action,
+					From:   []secv1alpha1.NetworkPolicyPeer{{PodSelector: &clientSelector}},
+				}},
+			},
+		}
+		cnp, err := data.securityClient.ClusterNetworkPolicies().Create(context.TODO(), cnp, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatalf("Error when creating ClusterNetworkPolicy: %v", err)
+		}
+		return cnp
+	}
+	deleteBaselinePolicy := func(cnp *secv1alpha1.ClusterNetworkPolicy) {
+		if err := data.securityClient.ClusterNetworkPolicies().Delete(context.TODO(), cnp.Name, metav1.DeleteOptions{}); err != nil {
+			t.Fatalf("Error when deleting ClusterNetworkPolicy %s: %v", cnp.Name, err)
+		}
+	}
+	// The flows are installed asynchronously by the agent.
+	waitForConnectivity := func(expectConnected bool) {
+		if err := wait.PollImmediate(time.Second, defaultTimeout, func() (bool, error) {
+			connected := data.runNetcatCommandFromTestPod(clientName, serverIP, serverPort) == nil
+			return connected == expectConnected, nil
+		}); err != nil {
+			t.Fatalf("Expected connectivity from Pod %s to %s:%d to be %t", clientName, serverIP, serverPort, expectConnected)
+		}
+	}
+
+	allowClientSpec := &networkingv1.NetworkPolicySpec{
+		PodSelector: serverSelector,
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		Ingress: []networkingv1.NetworkPolicyIngressRule{{
+			From: []networkingv1.NetworkPolicyPeer{{PodSelector: &clientSelector}},
+		}},
+	}
+	np, err := data.createNetworkPolicy(randName("test-networkpolicy-allow-client-"), allowClientSpec)
+	if err != nil {
+		t.Fatalf("Error when creating network policy: %v", err)
+	}
+	dropCNP := createBaselinePolicy(secv1alpha1.RuleActionDrop)
+	waitForConnectivity(false)
+	deleteBaselinePolicy(dropCNP)
+	waitForConnectivity(true)
+	if err = data.deleteNetworkpolicy(np); err != nil {
+		t.Fatalf("Error when deleting network policy: %v", err)
+	}
+
+	denyAllSpec := &networkingv1.NetworkPolicySpec{
+		PodSelector: serverSelector,
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		Ingress:     []networkingv1.NetworkPolicyIngressRule{},
+	}
+	np, err = data.createNetworkPolicy(randName("test-networkpolicy-deny-all-ingress-"), denyAllSpec)
+	if err != nil {
+		t.Fatalf("Error when creating network policy: %v", err)
+	}
+	defer func() {
+		if err = data.deleteNetworkpolicy(np); err != nil {
+			t.Fatalf("Error when deleting network policy: %v", err)
+		}
+	}()
+	waitForConnectivity(false)
+	allowCNP := createBaselinePolicy(secv1alpha1.RuleActionAllow)
+	defer deleteBaselinePolicy(allowCNP)
+	// A K8s NetworkPolicy is enforced after a baseline Allow rule, which
+	// hence doesn't allow the traffic denied by the K8s NetworkPolicy. As the
+	// flows are installed asynchronously, the traffic must stay denied for a
+	// while.
+	if err := wait.PollImmediate(time.Second, 15*time.Second, func() (bool, error) {
+		return data.runNetcatCommandFromTestPod(clientName, serverIP, serverPort) == nil, nil
+	}); err != wait.ErrWaitTimeout {
+		t.Fatalf("Pod %s should not be able to connect %s:%d, but was able to connect", clientName, serverIP, serverPort)
+	}
+}
@@ -15,6 +15,8 @@
 package e2e
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -22,6 +24,8 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
 	. "github.com/vmware-tanzu/antrea/test/e2e/utils"
@@ -40,6 +44,8 @@ var (
 const (
 	// provide enough time for policies to be enforced & deleted by the CNI plugin.
 	networkPolicyDelay = 2 * time.Second
+	// the file to which the Antrea Agent logs the packets matching the rules which enable logging.
+	auditLogPath = "/var/log/antrea/networkpolicy.log"
 )
 
 func failOnError(err error, t *testing.T) {
@@ -458,6 +464,171 @@ func testCNPRulePrioirty(t *testing.T) {
 	executeTests(t, testCase)
 }
 
+// testCNPEgressFQDN tests that an egress rule matching an FQDN applies to the IP the FQDN resolves
+// to, and follows the updates of the DNS record. The record is served from the /etc/hosts file of
+// the antrea-controller container, which is read by the resolver of the Antrea Controller.
+func testCNPEgressFQDN(t *testing.T, data *TestData) {
+	const fqdn = "fqdn-test.antrea.local"
+	// The FQDN is resolved every 30s by default.
+	const fqdnUpdateTimeout = 45 * time.Second
+	controllerPod, err := data.getAntreaController()
+	failOnError(err, t)
+	setRecord := func(ip string) {
+		cmd := fmt.Sprintf("grep -v ' %[1]s$' /etc/hosts > /tmp/hosts; cat /tmp/hosts > /etc/hosts", fqdn)
+		if ip != "" {
+			cmd += fmt.Sprintf(" && echo '%s %s' >> /etc/hosts", ip, fqdn)
+		}
+		_, stderr, err := data.runCommandFromPod(antreaNamespace, controllerPod.Name, antreaDeployment, []string{"sh", "-c", cmd})
+		if err != nil {
+			failOnError(fmt.Errorf("error when updating the DNS record of %s: %v, stderr: %s", fqdn, err, stderr), t)
+		}
+	}
+	defer setRecord("")
+
+	failOnError(k8sUtils.CleanCNPs(), t)
+	setRecord(podIPs["z/b"])
+	builder := &ClusterNetworkPolicySpecBuilder{}
+	builder = builder.SetName("cnp-fqdn-drop-a-egress").
+		SetPriority(1.0).
+		SetAppliedToGroup(map[string]string{"pod": "a"}, map[string]string{"ns": "x"}, nil, nil)
+	builder.AddEgressFQDN(v1.ProtocolTCP, &p80, fqdn, secv1alpha1.RuleActionDrop)
+	_, err = k8sUtils.CreateOrUpdateCNP(builder.Get())
+	failOnError(err, t)
+
+	validate := func(step *TestStep) {
+		log.Infof("running step %s of test case CNP Drop Egress To FQDN", step.Name)
+		start := time.Now()
+		k8sUtils.Validate(allPods, step.Reachability, step.Port)
+		step.Duration = time.Now().Sub(start)
+		step.Reachability.PrintSummary(true, true, true)
+		if _, wrong, _ := step.Reachability.Summary(); wrong != 0 {
+			t.Errorf("failure -- %d wrong results", wrong)
+		}
+	}
+	// The FQDN is resolved as soon as the CNP is created.
+	time.Sleep(networkPolicyDelay)
+	reachability1 := NewReachability(allPods, true)
+	reachability1.Expect(Pod("x/a"), Pod("z/b"), false)
+	step1 := &TestStep{"FQDN resolved to z/b", reachability1, nil, 80, 0}
+	validate(step1)
+
+	setRecord(podIPs["z/c"])
+	time.Sleep(fqdnUpdateTimeout)
+	reachability2 := NewReachability(allPods, true)
+	reachability2.Expect(Pod("x/a"), Pod("z/c"), false)
+	step2 := &TestStep{"FQDN record updated to z/c", reachability2, nil, 80, 0}
+	validate(step2)
+
+	allTestList = append(allTestList, &TestCase{"CNP Drop Egress To FQDN", []*TestStep{step1, step2}})
+	failOnError(k8sUtils.CleanCNPs(), t)
+}
+
+// testCNPAuditLogging tests that the packets dropped by a rule which enables logging are logged by the
+// Antrea Agent of the Node on which they are dropped.
+func testCNPAuditLogging(t *testing.T, data *TestData) {
+	const cnpName = "cnp-logging-deny-x-a-to-z-b"
+	failOnError(k8sUtils.CleanCNPs(), t)
+	builder := &ClusterNetworkPolicySpecBuilder{}
+	builder = builder.SetName(cnpName).
+		SetPriority(1.0).
+		SetAppliedToGroup(map[string]string{"pod": "a"}, map[string]string{"ns": "x"}, nil, nil)
+	builder.AddEgress(v1.ProtocolTCP, &p80, nil, nil, map[string]string{"pod": "b"}, map[string]string{"ns": "z"},
+		nil, nil, secv1alpha1.RuleActionDrop)
+	cnp := builder.Get()
+	cnp.Spec.Egress[0].EnableLogging = true
+	_, err := k8sUtils.CreateOrUpdateCNP(cnp)
+	failOnError(err, t)
+	defer func() {
+		failOnError(k8sUtils.CleanCNPs(), t)
+	}()
+	time.Sleep(networkPolicyDelay)
+
+	connected, err := k8sUtils.Probe("x", "a", "z", "b", p80)
+	failOnError(err, t)
+	if connected {
+		t.Fatalf("Expected traffic from x/a to z/b to be dropped")
+	}
+
+	pod, err := k8sUtils.GetPod("x", "a")
+	failOnError(err, t)
+	antreaPodName, err := data.getAntreaPodOnNode(pod.Spec.NodeName)
+	failOnError(err, t)
+	stdout, stderr, err := data.runCommandFromPod(antreaNamespace, antreaPodName, agentContainerName, []string{"cat", auditLogPath})
+	if err != nil {
+		t.Fatalf("Error when reading the audit log on Node %s: %v, stderr: %s", pod.Spec.NodeName, err, stderr)
+	}
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Invalid audit log entry %q: %v", line, err)
+		}
+		// The log file may contain the entries of the previous test runs.
+		if entry["policyName"] == cnpName && entry["sourceIP"] == podIPs["x/a"] {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		t.Fatalf("No audit log entry of CNP %s found in:\n%s", cnpName, stdout)
+	}
+	entry := entries[len(entries)-1]
+	expected := map[string]interface{}{
+		"direction":       "Out",
+		"decision":        "deny",
+		"protocol":        "TCP",
+		"sourcePod":       "x/a",
+		"destinationIP":   podIPs["z/b"],
+		"destinationPort": float64(p80),
+	}
+	for field, value := range expected {
+		if entry[field] != value {
+			t.Errorf("Expected field %s of the audit log entry to be %v, got %v", field, value, entry[field])
+		}
+	}
+}
+
+// testCNPHTTPMatches tests that an ingress rule with HTTP matches only drops the
+// connections whose first HTTP request matches the rule.
+func testCNPHTTPMatches(t *testing.T, data *TestData) {
+	serverName, serverIP, cleanupFunc := createAndWaitForPod(t, data, data.createNginxPodOnNode, "test-server-", "")
+	defer cleanupFunc()
+
+	failOnError(k8sUtils.CleanCNPs(), t)
+	builder := &ClusterNetworkPolicySpecBuilder{}
+	builder = builder.SetName("cnp-deny-x-to-admin-api").
+		SetPriority(1.0).
+		SetAppliedToGroup(map[string]string{"antrea-e2e": serverName}, nil, nil, nil)
+	builder.AddIngress(v1.ProtocolTCP, &p80, nil, nil, nil, map[string]string{"ns": "x"},
+		nil, nil, secv1alpha1.RuleActionDrop)
+	cnp := builder.Get()
+	cnp.Spec.Ingress[0].HTTPMatches = []secv1alpha1.HTTPMatch{{Method: "DELETE", Path: "/api/admin*"}}
+	_, err := k8sUtils.CreateOrUpdateCNP(cnp)
+	failOnError(err, t)
+	defer func() {
+		failOnError(k8sUtils.CleanCNPs(), t)
+	}()
+	time.Sleep(networkPolicyDelay)
+
+	clientPod, err := k8sUtils.GetPod("x", "a")
+	failOnError(err, t)
+	sendRequest := func(method, path string) string {
+		cmd := []string{
+			"/bin/sh",
+			"-c",
+			fmt.Sprintf("printf '%s %s HTTP/1.1\\r\\nHost: %s\\r\\nConnection: close\\r\\n\\r\\n' | ncat -w 5 -i 5s %s 80", method, path, serverIP, serverIP),
+		}
+		// The request of a denied connection times out, so the error is ignored.
+		stdout, _, _ := data.runCommandFromPod(clientPod.Namespace, clientPod.Name, "c80", cmd)
+		return stdout
+	}
+	if response := sendRequest("GET", "/api/admin/users"); !strings.Contains(response, "HTTP/1.1") {
+		t.Errorf("Expected GET request from x/a to Pod %s to be allowed, got response %q", serverName, response)
+	}
+	if response := sendRequest("DELETE", "/api/admin/users"); strings.Contains(response, "HTTP/1.1") {
+		t.Errorf("Expected DELETE request from x/a to Pod %s to be dropped, got response %q", serverName, response)
+	}
+}
+
 // executeTests runs all the tests in testList and prints results
 func executeTests(t *testing.T, testList []*TestCase) {
 	for _, testCase := range testList {
@@ -547,8 +718,81 @@ func TestClusterNetworkPolicy(t *testing.T) {
 		t.Run("Case=CNPPrioirtyOverride", func(t *testing.T) { testCNPPriorityOverride(t) })
 		t.Run("Case=CNPPriorityConflictingRule", func(t *testing.T) { testCNPPriorityConflictingRule(t) })
 		t.Run("Case=CNPRulePriority", func(t *testing.T) { testCNPRulePrioirty(t) })
+		t.Run("Case=CNPEgressFQDN", func(t *testing.T) { testCNPEgressFQDN(t, data) })
+		t.Run("Case=CNPAuditLogging", func(t *testing.T) { testCNPAuditLogging(t, data) })
+		t.Run("Case=CNPHTTPMatches", func(t *testing.T) { testCNPHTTPMatches(t, data) })
 	})
 
 	printResults()
 	k8sUtils.Cleanup(namespaces)
 }
+
+// TestNodeToPodPolicy tests that an ingress rule with a nodeSelector peer applies to the traffic
+// sent by the selected Node to its local Pods, and only to the ports of the rule.
+func TestNodeToPodPolicy(t *testing.T) {
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+	skipIfCNPDisabled(t, data)
+
+	serverNode := nodeName(0)
+	serverName := randName("test-server-")
+	cmd := []string{"sh", "-c", "httpd -p 8080 -h /tmp && httpd -f -p 80 -h /tmp"}
+	if err := data.createPodOnNode(serverName, serverNode, "busybox", cmd, nil, nil, nil); err != nil {
+		t.Fatalf("Error when creating server Pod: %v", err)
+	}
+	defer deletePodWrapper(t, data, serverName)
+	serverIP, err := data.podWaitForIP(defaultTimeout, serverName, testNamespace)
+	if err != nil {
+		t.Fatalf("Error when waiting for IP for Pod '%s': %v", serverName, err)
+	}
+
+	port8080 := intstr.FromInt(8080)
+	protocolTCP := v1.ProtocolTCP
+	drop := secv1alpha1.RuleActionDrop
+	cnp := &secv1alpha1.ClusterNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "cnp-deny-node-to-pod"},
+		Spec: secv1alpha1.ClusterNetworkPolicySpec{
+			Priority: 1,
+			AppliedTo: []secv1alpha1.NetworkPolicyPeer{
+				{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"antrea-e2e": serverName}}},
+			},
+			Ingress: []secv1alpha1.Rule{
+				{
+					Action: &drop,
+					Ports:  []secv1alpha1.NetworkPolicyPort{{Protocol: &protocolTCP, Port: &port8080}},
+					From: []secv1alpha1.NetworkPolicyPeer{
+						{NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/hostname": serverNode}}},
+					},
+				},
+			},
+		},
+	}
+	if _, err := data.securityClient.ClusterNetworkPolicies().Create(context.TODO(), cnp, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Error when creating ClusterNetworkPolicy: %v", err)
+	}
+	defer func() {
+		if err := data.securityClient.ClusterNetworkPolicies().Delete(context.TODO(), cnp.Name, metav1.DeleteOptions{}); err != nil {
+			t.Errorf("Error when deleting ClusterNetworkPolicy: %v", err)
+		}
+	}()
+	time.Sleep(networkPolicyDelay)
+
+	connect := func(port int) bool {
+		cmd := fmt.Sprintf("curl --connect-timeout 3 -s -o /dev/null http://%s:%d", serverIP, port)
+		rc, _, _, err := RunCommandOnNode(serverNode, cmd)
+		if err != nil {
+			t.Fatalf("Error when running command '%s' on Node %s: %v", cmd, serverNode, err)
+		}
+		// httpd answers 404 as /tmp has no index file, which curl does not treat as an error.
+		return rc == 0
+	}
+	if connect(8080) {
+		t.Errorf("Expected traffic from Node %s to Pod %s on port 8080 to be dropped", serverNode, serverName)
+	}
+	if !connect(80) {
+		t.Errorf("Expected traffic from Node %s to Pod %s on port 80 to be allowed", serverNode, serverName)
+	}
+}
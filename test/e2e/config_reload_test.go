@@ -0,0 +1,121 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var logLevelRegex = regexp.MustCompile(`The log level of .* is (\d+)`)
+
+// TestAgentConfigHotReload verifies that a hot-reloadable setting updated in
+// the antrea-agent configuration is applied within 30s, without restarting the
+// antrea-agent Pod.
+func TestAgentConfigHotReload(t *testing.T) {
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	agentPodName, err := data.getAntreaPodOnNode(nodeName(0))
+	if err != nil {
+		t.Fatalf("Error when getting the antrea-agent Pod: %v", err)
+	}
+	agentPod, err := data.clientset.CoreV1().Pods(antreaNamespace).Get(context.TODO(), agentPodName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Error when getting antrea-agent Pod %s: %v", agentPodName, err)
+	}
+	initialLevel, err := getAgentLogLevel(agentPodName, data)
+	if err != nil {
+		t.Fatalf("Error when getting the log level of antrea-agent Pod %s: %v", agentPodName, err)
+	}
+	level := 4
+	if initialLevel == level {
+		level = 5
+	}
+
+	disabledLine, enabledLine := "#logVerbosity: 0", fmt.Sprintf("logVerbosity: %d", level)
+	if err := data.updateAntreaAgentConfLine(disabledLine, enabledLine); err != nil {
+		t.Fatalf("Error when setting logVerbosity in the antrea-agent configuration: %v", err)
+	}
+	defer func() {
+		if err := data.updateAntreaAgentConfLine(enabledLine, disabledLine); err != nil {
+			t.Errorf("Error when restoring the antrea-agent configuration: %v", err)
+		}
+	}()
+
+	var currentLevel int
+	if err := wait.PollImmediate(time.Second, 30*time.Second, func() (bool, error) {
+		currentLevel, err = getAgentLogLevel(agentPodName, data)
+		if err != nil {
+			return false, err
+		}
+		return currentLevel == level, nil
+	}); err != nil {
+		t.Fatalf("The log level of antrea-agent Pod %s was not changed to %d within 30s, current level: %d, error: %v", agentPodName, level, currentLevel, err)
+	}
+
+	updatedPod, err := data.clientset.CoreV1().Pods(antreaNamespace).Get(context.TODO(), agentPodName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Error when getting antrea-agent Pod %s: %v", agentPodName, err)
+	}
+	if !updatedPod.Status.StartTime.Equal(agentPod.Status.StartTime) {
+		t.Errorf("antrea-agent Pod %s was restarted: start time changed from %v to %v", agentPodName, agentPod.Status.StartTime, updatedPod.Status.StartTime)
+	}
+	for i, status := range updatedPod.Status.ContainerStatuses {
+		if status.RestartCount != agentPod.Status.ContainerStatuses[i].RestartCount {
+			t.Errorf("Container %s of antrea-agent Pod %s was restarted", status.Name, agentPodName)
+		}
+	}
+}
+
+// getAgentLogLevel returns the current log level of an antrea-agent Pod.
+func getAgentLogLevel(agentPodName string, data *TestData) (int, error) {
+	stdout, stderr, err := runAntctl(agentPodName, []string{"antctl", "get", "log-level"}, data)
+	if err != nil {
+		return 0, fmt.Errorf("error when running antctl: %v, stderr: %s", err, stderr)
+	}
+	matches := logLevelRegex.FindStringSubmatch(stdout)
+	if matches == nil {
+		return 0, fmt.Errorf("unexpected antctl output: %s", stdout)
+	}
+	return strconv.Atoi(matches[1])
+}
+
+// updateAntreaAgentConfLine replaces a line of the antrea-agent configuration
+// in the antrea ConfigMap, without restarting the antrea-agent Pods.
+func (data *TestData) updateAntreaAgentConfLine(oldLine, newLine string) error {
+	configMap, err := data.GetAntreaConfigMap(antreaNamespace)
+	if err != nil {
+		return err
+	}
+	agentConf := configMap.Data["antrea-agent.conf"]
+	if !strings.Contains(agentConf, oldLine) {
+		return fmt.Errorf("line %q not found in the antrea-agent configuration", oldLine)
+	}
+	configMap.Data["antrea-agent.conf"] = strings.Replace(agentConf, oldLine, newLine, 1)
+	_, err = data.clientset.CoreV1().ConfigMaps(antreaNamespace).Update(context.TODO(), configMap, metav1.UpdateOptions{})
+	return err
+}
@@ -303,6 +303,64 @@ func TestOVSRestartSameNode(t *testing.T) {
 	}
 }
 
+// TestAgentRestartExistingConnection verifies that a TCP connection established between 2 Pods on
+// the same Node is not disrupted when the Antrea Agent Pod restarts: the flows which are still
+// required are re-installed in place and the stale flows are only deleted once the agent has
+// converged. The client sends one line every second during the restart, and the test checks that
+// the server received all of them.
+func TestAgentRestartExistingConnection(t *testing.T) {
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	workerNode := workerNodeName(1)
+	t.Logf("Creating two busybox test Pods on '%s'", workerNode)
+	podNames, podIPs, cleanupFn := createTestBusyboxPods(t, data, 2, workerNode)
+	defer cleanupFn()
+
+	// The duration is a bit arbitrary, we assume that restarting Antrea takes less than that time.
+	const numLines = 40
+	const port = 8080
+	serverCh := make(chan string, 1)
+	go func() {
+		cmd := []string{"nc", "-l", "-p", fmt.Sprint(port)}
+		stdout, stderr, err := data.runCommandFromPod(testNamespace, podNames[1], busyboxContainerName, cmd)
+		if err != nil {
+			t.Logf("Error when running nc server: %v - stderr: %s", err, stderr)
+		}
+		serverCh <- stdout
+	}()
+	// Give the server some time to listen.
+	time.Sleep(2 * time.Second)
+
+	clientCh := make(chan error, 1)
+	go func() {
+		cmd := fmt.Sprintf("for i in $(seq 1 %d); do echo $i; sleep 1; done | nc -w 5 %s %d", numLines, podIPs[1], port)
+		_, stderr, err := data.runCommandFromPod(testNamespace, podNames[0], busyboxContainerName, []string{"sh", "-c", cmd})
+		if err != nil {
+			err = fmt.Errorf("error when running nc client: %v - stderr: %s", err, stderr)
+		}
+		clientCh <- err
+	}()
+	// Make sure that the connection is established before the Antrea agent is deleted.
+	time.Sleep(3 * time.Second)
+
+	t.Logf("Restarting antrea-agent on Node '%s'", workerNode)
+	if _, err := data.deleteAntreaAgentOnNode(workerNode, 30 /* grace period in seconds */, defaultTimeout); err != nil {
+		t.Fatalf("Error when restarting antrea-agent on Node '%s': %v", workerNode, err)
+	}
+
+	if err := <-clientCh; err != nil {
+		t.Fatalf("The connection was disrupted: %v", err)
+	}
+	received := strings.Fields(<-serverCh)
+	if len(received) != numLines {
+		t.Errorf("Expected the server to receive %d lines, got %d", numLines, len(received))
+	}
+}
+
 // TestOVSFlowReplay checks that when OVS restarts unexpectedly the Antrea agent takes care of
 // replaying flows. More precisely this test checks that Pod connectivity still works after deleting
 // the flows and force-restarting the OVS dameons.
@@ -382,3 +440,51 @@ func TestPingLargeMTU(t *testing.T) {
 		t.Errorf("Error when running ping command: %v - stdout: %s - stderr: %s", err, stdout, stderr)
 	}
 }
+
+// TestMTUPropagation verifies that the MTU of the Pod interface matches the MTU of the Antrea gateway
+// interface on the Node, which is computed by the agent from the tunnel configuration.
+func TestMTUPropagation(t *testing.T) {
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	nodeName := nodeName(0)
+	podName := randName("test-pod-mtu-")
+	if err := data.createBusyboxPodOnNode(podName, nodeName); err != nil {
+		t.Fatalf("Error when creating busybox test Pod: %v", err)
+	}
+	defer deletePodWrapper(t, data, podName)
+	if err := data.podWaitForRunning(defaultTimeout, podName, testNamespace); err != nil {
+		t.Fatalf("Error when waiting for Pod '%s' to be running: %v", podName, err)
+	}
+
+	netNS, err := data.getPodNetNS(testNamespace, podName)
+	if err != nil {
+		t.Fatalf("Error when inspecting the network namespace of Pod '%s': %v", podName, err)
+	}
+	if len(netNS.ipAddresses) == 0 {
+		t.Errorf("No IP address assigned to interface %s of Pod '%s'", podInterfaceName, podName)
+	}
+
+	gwName, err := data.GetGatewayInterfaceName(antreaNamespace)
+	if err != nil {
+		t.Fatalf("Failed to detect gateway interface name from ConfigMap: %v", err)
+	}
+	antreaPodName, err := data.getAntreaPodOnNode(nodeName)
+	if err != nil {
+		t.Fatalf("Error when retrieving the name of the Antrea Pod running on Node '%s': %v", nodeName, err)
+	}
+	stdout, stderr, err := data.runCommandFromPod(antreaNamespace, antreaPodName, agentContainerName, []string{"ip", "link", "show", gwName})
+	if err != nil {
+		t.Fatalf("Error when running ip command in Antrea Pod '%s': %v - stderr: %s", antreaPodName, err, stderr)
+	}
+	gwMTU, err := parseIPLinkMTU(stdout, gwName)
+	if err != nil {
+		t.Fatalf("Error when parsing MTU of gateway interface %s: %v", gwName, err)
+	}
+	if netNS.mtu != gwMTU {
+		t.Errorf("MTU of Pod '%s' is %d but MTU of gateway interface %s is %d", podName, netNS.mtu, gwName, gwMTU)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/config"
+	"github.com/vmware-tanzu/antrea/pkg/apis/core/v1alpha1"
+)
+
+// TestEgressSNAT verifies that the egress traffic of a Pod selected by an
+// Egress is SNATed to the Egress IP. The Egress IP is the IP of the master
+// Node, the client Pod runs on the first worker Node and the server runs in
+// the host network of the second worker Node, so that the traffic is SNATed to
+// the IP of the client Node without the Egress.
+func TestEgressSNAT(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+	skipIfNumNodesLessThan(t, 3)
+
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	encapMode, err := data.GetEncapMode()
+	if err != nil {
+		t.Fatalf("Error when getting encap mode: %v", err)
+	}
+	if encapMode != config.TrafficEncapModeEncap {
+		t.Skipf("Skipping test as Egress requires the %s mode", config.TrafficEncapModeEncap)
+	}
+	if err = data.enableEgress(); err != nil {
+		t.Fatalf("Error when enabling Egress: %v", err)
+	}
+
+	egressIP, err := data.getNodeInternalIP(nodeName(0))
+	if err != nil {
+		t.Fatalf("Error when getting IP of Node %s: %v", nodeName(0), err)
+	}
+	serverIP, err := data.getNodeInternalIP(nodeName(2))
+	if err != nil {
+		t.Fatalf("Error when getting IP of Node %s: %v", nodeName(2), err)
+	}
+
+	serverName := randName("test-server-")
+	if err := data.createHostNetworkNetexecPodOnNode(serverName, nodeName(2), 8080); err != nil {
+		t.Fatalf("Error when creating server Pod: %v", err)
+	}
+	defer data.deletePodAndWait(defaultTimeout, serverName)
+	clientName := randName("test-client-")
+	if err := data.createBusyboxPodOnNode(clientName, nodeName(1)); err != nil {
+		t.Fatalf("Error when creating client Pod: %v", err)
+	}
+	defer data.deletePodAndWait(defaultTimeout, clientName)
+	for _, podName := range []string{serverName, clientName} {
+		if err := data.podWaitForRunning(defaultTimeout, podName, testNamespace); err != nil {
+			t.Fatalf("Error when waiting for Pod %s to be running: %v", podName, err)
+		}
+	}
+
+	// agnhost netexec replies to "/clientip" with the source IP and port of the
+	// request.
+	getClientIP := func() (string, error) {
+		cmd := []string{"wget", "-q", "-O", "-", fmt.Sprintf("http://%s:8080/clientip", serverIP)}
+		stdout, stderr, err := data.runCommandFromPod(testNamespace, clientName, busyboxContainerName, cmd)
+		if err != nil {
+			return "", fmt.Errorf("error when running wget: %v, stderr: %s", err, stderr)
+		}
+		return strings.Split(strings.TrimSpace(stdout), ":")[0], nil
+	}
+	clientNodeIP, err := data.getNodeInternalIP(nodeName(1))
+	if err != nil {
+		t.Fatalf("Error when getting IP of Node %s: %v", nodeName(1), err)
+	}
+	if clientIP, err := getClientIP(); err != nil {
+		t.Fatalf("Error when getting client IP: %v", err)
+	} else if clientIP != clientNodeIP {
+		t.Fatalf("Expected client IP to be %s without Egress, got %s", clientNodeIP, clientIP)
+	}
+
+	egress := &v1alpha1.Egress{
+		ObjectMeta: metav1.ObjectMeta{Name: randName("test-egress-")},
+		Spec: v1alpha1.EgressSpec{
+			AppliedTo: v1alpha1.EgressAppliedTo{
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"antrea-e2e": clientName}},
+			},
+			EgressIP: egressIP,
+		},
+	}
+	if _, err := data.crdClient.CoreV1alpha1().Egresses().Create(context.TODO(), egress, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Error when creating Egress: %v", err)
+	}
+	defer data.crdClient.CoreV1alpha1().Egresses().Delete(context.TODO(), egress.Name, metav1.DeleteOptions{})
+
+	// The Egress IP is assigned to the Node which owns it.
+	if err := wait.PollImmediate(time.Second, defaultTimeout, func() (bool, error) {
+		egress, err := data.crdClient.CoreV1alpha1().Egresses().Get(context.TODO(), egress.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return egress.Status.EgressNode == nodeName(0), nil
+	}); err != nil {
+		t.Fatalf("Error when waiting for Egress to be assigned to Node %s: %v", nodeName(0), err)
+	}
+	// The flows are installed asynchronously by the agents.
+	var clientIP string
+	if err := wait.PollImmediate(time.Second, defaultTimeout, func() (bool, error) {
+		clientIP, err = getClientIP()
+		if err != nil {
+			return false, err
+		}
+		return clientIP == egressIP, nil
+	}); err != nil {
+		t.Fatalf("Expected client IP to be Egress IP %s, got %s: %v", egressIP, clientIP, err)
+	}
+}
+
+// createHostNetworkNetexecPodOnNode creates an agnhost netexec Pod in the host
+// network of the Node, listening on the provided port.
+func (data *TestData) createHostNetworkNetexecPodOnNode(name string, nodeName string, port int32) error {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"antrea-e2e": name, "app": "agnhost"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:            "agnhost",
+				Image:           "gcr.io/kubernetes-e2e-test-images/agnhost:2.8",
+				ImagePullPolicy: corev1.PullIfNotPresent,
+				Command:         []string{"/agnhost", "netexec", fmt.Sprintf("--http-port=%d", port)},
+			}},
+			HostNetwork:   true,
+			NodeSelector:  map[string]string{"kubernetes.io/hostname": nodeName},
+			RestartPolicy: corev1.RestartPolicyNever,
+		},
+	}
+	_, err := data.clientset.CoreV1().Pods(testNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+	return err
+}
+
+// enableEgress enables the Egress feature in antrea-controller and
+// antrea-agent, and restarts them.
+func (data *TestData) enableEgress() error {
+	configMap, err := data.GetAntreaConfigMap(antreaNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to get ConfigMap: %v", err)
+	}
+	for _, conf := range []string{"antrea-controller.conf", "antrea-agent.conf"} {
+		configMap.Data[conf] = strings.Replace(configMap.Data[conf], "#  Egress: false", " Egress: true", 1)
+	}
+	if _, err := data.clientset.CoreV1().ConfigMaps(antreaNamespace).Update(context.TODO(), configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s: %v", configMap.Name, err)
+	}
+	if _, err := data.restartAntreaControllerPod(defaultTimeout); err != nil {
+		return fmt.Errorf("error when restarting antrea-controller Pod: %v", err)
+	}
+	if err := data.restartAntreaAgentPods(defaultTimeout); err != nil {
+		return fmt.Errorf("error when restarting antrea-agent Pod: %v", err)
+	}
+	return nil
+}
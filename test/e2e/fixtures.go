@@ -21,6 +21,19 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilnet "k8s.io/utils/net"
+
+	"github.com/vmware-tanzu/antrea/test/e2e/utils"
+)
+
+const (
+	// ovsFlowCleanupCheckEnv is the environment variable which, when set, makes teardownTest
+	// check that the OVS flows referencing the objects of the test have been removed.
+	ovsFlowCleanupCheckEnv = "OVS_FLOW_CLEANUP_CHECK"
+	// ovsFlowCleanupTimeout is how long verifyOVSFlowsCleanedUp waits for the flows to be removed.
+	ovsFlowCleanupTimeout = 30 * time.Second
 )
 
 func skipIfNotBenchmarkTest(tb testing.TB) {
@@ -41,6 +54,52 @@ func skipIfNumNodesLessThan(tb testing.TB, required int) {
 	}
 }
 
+func skipIfNotIPv4Cluster(tb testing.TB) {
+	if utilnet.IsIPv6CIDRString(clusterInfo.podNetworkCIDR) {
+		tb.Skipf("Skipping test as it requires IPv4 addresses but the Pod network is IPv6-only")
+	}
+}
+
+func skipIfNotIPv6Cluster(tb testing.TB) {
+	if clusterInfo.podV6NetworkCIDR == "" {
+		tb.Skipf("Skipping test as it requires IPv6 addresses but the Pod network has no IPv6 CIDR")
+	}
+}
+
+// skipIfKernelVersionLessThan skips the test if the kernel version of the first Node is lower than
+// major.minor.
+func skipIfKernelVersionLessThan(tb testing.TB, data *TestData, major, minor int) {
+	kernelMajor, kernelMinor := getKernelVersion(tb, data)
+	if kernelMajor < major || (kernelMajor == major && kernelMinor < minor) {
+		tb.Skipf("Skipping test as it requires kernel version %d.%d or higher but Node has kernel version %d.%d", major, minor, kernelMajor, kernelMinor)
+	}
+}
+
+// skipIfKernelVersionGreaterThan skips the test if the kernel version of the first Node is higher
+// than major.minor. It can be used to test fallback behaviors on old kernels.
+func skipIfKernelVersionGreaterThan(tb testing.TB, data *TestData, major, minor int) {
+	kernelMajor, kernelMinor := getKernelVersion(tb, data)
+	if kernelMajor > major || (kernelMajor == major && kernelMinor > minor) {
+		tb.Skipf("Skipping test as it requires kernel version %d.%d or lower but Node has kernel version %d.%d", major, minor, kernelMajor, kernelMinor)
+	}
+}
+
+// getKernelVersion returns the major and minor kernel versions of the first Node, and logs the full
+// kernel release so that it is recorded in the test logs. It calls Fatalf in case of error.
+func getKernelVersion(tb testing.TB, data *TestData) (major, minor int) {
+	nodeName := nodeName(0)
+	release, err := data.getNodeKernelRelease(nodeName)
+	if err != nil {
+		tb.Fatalf("Error when getting kernel version of Node '%s': %v", nodeName, err)
+	}
+	tb.Logf("Node '%s' has kernel version %s", nodeName, release)
+	major, minor, err = parseKernelVersion(release)
+	if err != nil {
+		tb.Fatalf("Error when parsing kernel version of Node '%s': %v", nodeName, err)
+	}
+	return major, minor
+}
+
 func ensureAntreaRunning(tb testing.TB, data *TestData) error {
 	tb.Logf("Applying Antrea YAML")
 	if err := data.deployAntrea(); err != nil {
@@ -203,6 +262,44 @@ func teardownTest(tb testing.TB, data *TestData) {
 	tb.Logf("Deleting '%s' K8s Namespace", testNamespace)
 	if err := data.deleteTestNamespace(defaultTimeout); err != nil {
 		tb.Logf("Error when tearing down test: %v", err)
+		return
+	}
+	if os.Getenv(ovsFlowCleanupCheckEnv) != "" {
+		keywords := data.getOVSFlowKeywords()
+		for idx := 0; idx < clusterInfo.numNodes; idx++ {
+			verifyOVSFlowsCleanedUp(tb, data, nodeName(idx), keywords)
+		}
+	}
+}
+
+// verifyOVSFlowsCleanedUp polls the OVS flows of all tables on the provided Node until none of them
+// contains any of the provided keywords, and fails the test if some of them are still present after
+// ovsFlowCleanupTimeout.
+func verifyOVSFlowsCleanedUp(tb testing.TB, data *TestData, nodeName string, keywords []string) {
+	if len(keywords) == 0 {
+		return
+	}
+	agentName, err := data.getAntreaPodOnNode(nodeName)
+	if err != nil {
+		tb.Errorf("Error when getting the antrea-agent Pod on Node '%s': %v", nodeName, err)
+		return
+	}
+	var remaining []string
+	err = wait.PollImmediate(time.Second, ovsFlowCleanupTimeout, func() (bool, error) {
+		flows, _, err := data.runCommandFromPodWithRetry(antreaNamespace, agentName, agentContainerName, []string{"ovs-ofctl", "dump-flows", defaultBridgeName}, 3, time.Second)
+		if err != nil {
+			return false, err
+		}
+		remaining = nil
+		for _, keyword := range keywords {
+			if utils.OVSFlowsContainKeyword(flows, keyword) {
+				remaining = append(remaining, keyword)
+			}
+		}
+		return len(remaining) == 0, nil
+	})
+	if err != nil {
+		tb.Errorf("OVS flows on Node '%s' were not cleaned up after teardown, remaining keywords %v: %v", nodeName, remaining, err)
 	}
 }
 
@@ -17,18 +17,23 @@ package e2e
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"net"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"testing"
 	"time"
 
+	"github.com/containernetworking/plugins/pkg/ip"
 	"k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
@@ -37,11 +42,13 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/remotecommand"
 	aggregatorclientset "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset"
+	utilnet "k8s.io/utils/net"
 
 	"github.com/vmware-tanzu/antrea/pkg/agent/config"
 	crdclientset "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned"
 	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/client/clientset/versioned/typed/security/v1alpha1"
 	"github.com/vmware-tanzu/antrea/test/e2e/providers"
+	"github.com/vmware-tanzu/antrea/test/e2e/utils"
 )
 
 const (
@@ -70,21 +77,23 @@ type ClusterNode struct {
 }
 
 type ClusterInfo struct {
-	numWorkerNodes int
-	numNodes       int
-	podNetworkCIDR string
-	masterNodeName string
-	nodes          map[int]ClusterNode
+	numWorkerNodes   int
+	numNodes         int
+	podNetworkCIDR   string
+	podV6NetworkCIDR string
+	masterNodeName   string
+	nodes            map[int]ClusterNode
 }
 
 var clusterInfo ClusterInfo
 
 type TestOptions struct {
-	providerName        string
-	providerConfigPath  string
-	logsExportDir       string
-	logsExportOnSuccess bool
-	withBench           bool
+	providerName           string
+	providerConfigPath     string
+	logsExportDir          string
+	logsExportOnSuccess    bool
+	withBench              bool
+	proxyStressParallelism int
 }
 
 var testOptions TestOptions
@@ -98,6 +107,62 @@ type TestData struct {
 	aggregatorClient aggregatorclientset.Interface
 	securityClient   secv1alpha1.SecurityV1alpha1Interface
 	crdClient        crdclientset.Interface
+
+	// podNetNSCacheMutex protects podNetNSCache, which stores the result of getPodNetNS by Pod UID.
+	podNetNSCacheMutex sync.Mutex
+	podNetNSCache      map[types.UID]NetNSInfo
+
+	// ovsFlowKeywordsMutex protects ovsFlowKeywords, which stores the strings (e.g. Service
+	// ClusterIPs) that must no longer appear in the OVS flows after teardownTest.
+	ovsFlowKeywordsMutex sync.Mutex
+	ovsFlowKeywords      []string
+}
+
+// registerOVSFlowKeyword adds a string which must no longer appear in the OVS flows of any Node
+// once the test Namespace has been deleted. It is checked by teardownTest when the
+// OVS_FLOW_CLEANUP_CHECK environment variable is set.
+func (data *TestData) registerOVSFlowKeyword(keyword string) {
+	data.ovsFlowKeywordsMutex.Lock()
+	defer data.ovsFlowKeywordsMutex.Unlock()
+	data.ovsFlowKeywords = append(data.ovsFlowKeywords, keyword)
+}
+
+// getOVSFlowKeywords returns the strings registered with registerOVSFlowKeyword.
+func (data *TestData) getOVSFlowKeywords() []string {
+	data.ovsFlowKeywordsMutex.Lock()
+	defer data.ovsFlowKeywordsMutex.Unlock()
+	return append([]string(nil), data.ovsFlowKeywords...)
+}
+
+// podInterfaceName is the name of the interface created by Antrea in the network namespace of
+// each Pod.
+const podInterfaceName = "eth0"
+
+// podRoute is a route installed in the network namespace of a Pod.
+type podRoute struct {
+	// destination is either "default" or a CIDR.
+	destination string
+	// gateway is nil for on-link routes.
+	gateway net.IP
+	device  string
+}
+
+// NetNSInfo describes the configuration of the Pod interface, as observed from inside the network
+// namespace of the Pod.
+type NetNSInfo struct {
+	ipAddresses []*net.IPNet
+	routes      []podRoute
+	mtu         int
+}
+
+// defaultRoute returns the default route of the Pod, or nil if there is none.
+func (info *NetNSInfo) defaultRoute() *podRoute {
+	for i := range info.routes {
+		if info.routes[i].destination == "default" {
+			return &info.routes[i]
+		}
+	}
+	return nil
 }
 
 // workerNodeName returns an empty string if there is no worker Node with the provided idx
@@ -202,7 +267,18 @@ func collectClusterInfo() error {
 		if matches := re.FindStringSubmatch(stdout); len(matches) == 0 {
 			return fmt.Errorf("cannot retrieve cluster CIDR, unexpected kubectl output: %s", stdout)
 		} else {
-			clusterInfo.podNetworkCIDR = matches[1]
+			// In a dual-stack cluster, the cluster CIDR includes both an IPv4 CIDR
+			// and an IPv6 CIDR, separated by a comma.
+			for _, cidr := range strings.Split(matches[1], ",") {
+				if utilnet.IsIPv6CIDRString(cidr) {
+					clusterInfo.podV6NetworkCIDR = cidr
+				} else {
+					clusterInfo.podNetworkCIDR = cidr
+				}
+			}
+			if clusterInfo.podNetworkCIDR == "" {
+				clusterInfo.podNetworkCIDR = clusterInfo.podV6NetworkCIDR
+			}
 		}
 		return nil
 	}(); err != nil {
@@ -513,6 +589,20 @@ func (data *TestData) createPodOnNode(name string, nodeName string, image string
 	return nil
 }
 
+// addPodLabels adds the provided labels to the Pod in the test Namespace.
+func (data *TestData) addPodLabels(name string, labels map[string]string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": labels,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = data.clientset.CoreV1().Pods(testNamespace).Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
 // createBusyboxPodOnNode creates a Pod in the test namespace with a single busybox container. The
 // Pod will be scheduled on the specified Node (if nodeName is not empty).
 func (data *TestData) createBusyboxPodOnNode(name string, nodeName string) error {
@@ -641,6 +731,24 @@ func (data *TestData) podWaitForIP(timeout time.Duration, name, namespace string
 	return pod.Status.PodIP, nil
 }
 
+// podWaitForIPv6 polls the K8s apiserver until the specified Pod is in the "running" state (or
+// until the provided timeout expires). The function then returns the IPv6 address assigned to the
+// Pod. An error is returned if the Pod has no IPv6 address.
+func (data *TestData) podWaitForIPv6(timeout time.Duration, name, namespace string) (string, error) {
+	pod, err := data.podWaitFor(timeout, name, namespace, func(pod *v1.Pod) (bool, error) {
+		return pod.Status.Phase == v1.PodRunning, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, podIP := range pod.Status.PodIPs {
+		if utilnet.IsIPv6String(podIP.IP) {
+			return podIP.IP, nil
+		}
+	}
+	return "", fmt.Errorf("pod is running but has no assigned IPv6 address")
+}
+
 // deleteAntreaAgentOnNode deletes the antrea-agent Pod on a specific Node and measure how long it
 // takes for the Pod not to be visible to the client any more. It also waits for a new antrea-agent
 // Pod to be running on the Node.
@@ -709,6 +817,20 @@ func (data *TestData) deleteAntreaAgentOnNode(nodeName string, gracePeriodSecond
 	return delay, nil
 }
 
+// getNodeInternalIP retrieves the InternalIP address of a specific Node.
+func (data *TestData) getNodeInternalIP(nodeName string) (string, error) {
+	node, err := data.clientset.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error when getting Node '%s': %v", nodeName, err)
+	}
+	for _, address := range node.Status.Addresses {
+		if address.Type == v1.NodeInternalIP {
+			return address.Address, nil
+		}
+	}
+	return "", fmt.Errorf("Node '%s' has no InternalIP address", nodeName)
+}
+
 // getAntreaPodOnNode retrieves the name of the Antrea Pod (antrea-agent-*) running on a specific Node.
 func (data *TestData) getAntreaPodOnNode(nodeName string) (podName string, err error) {
 	listOptions := metav1.ListOptions{
@@ -810,8 +932,19 @@ func validatePodIP(podNetworkCIDR, podIP string) (bool, error) {
 	return cidr.Contains(ip), nil
 }
 
-// createService creates a service with port and targetPort.
+// createService creates a TCP service with port and targetPort.
 func (data *TestData) createService(serviceName string, port, targetPort int, selector map[string]string, affinity bool) (*v1.Service, error) {
+	return data.createServiceWithProtocol(serviceName, int32(port), int32(targetPort), v1.ProtocolTCP, selector, affinity)
+}
+
+// createServiceWithProtocol creates a service with port, targetPort and protocol.
+func (data *TestData) createServiceWithProtocol(serviceName string, port, targetPort int32, protocol v1.Protocol, selector map[string]string, affinity bool) (*v1.Service, error) {
+	return data.createServiceWithIPFamily(serviceName, int(port), int(targetPort), protocol, selector, affinity, nil)
+}
+
+// createServiceWithIPFamily creates a service with port, targetPort and protocol. If ipFamily is
+// not nil, the ClusterIP of the service is allocated from the provided IP family.
+func (data *TestData) createServiceWithIPFamily(serviceName string, port, targetPort int, protocol v1.Protocol, selector map[string]string, affinity bool, ipFamily *v1.IPFamily) (*v1.Service, error) {
 	affinityType := v1.ServiceAffinityNone
 	if affinity {
 		affinityType = v1.ServiceAffinityClientIP
@@ -830,16 +963,120 @@ func (data *TestData) createService(serviceName string, port, targetPort int, se
 			Ports: []v1.ServicePort{{
 				Port:       int32(port),
 				TargetPort: intstr.FromInt(targetPort),
+				Protocol:   protocol,
 			}},
 			Selector: selector,
+			IPFamily: ipFamily,
 		},
 	}
-	return data.clientset.CoreV1().Services(testNamespace).Create(context.TODO(), &service, metav1.CreateOptions{})
+	return data.createServiceObject(&service)
 }
 
-// createNginxService create a nginx service with the given name.
+// createServiceObject creates the provided Service and registers its ClusterIP with
+// registerOVSFlowKeyword, so that teardownTest can check that the flows of the Service are removed.
+func (data *TestData) createServiceObject(service *v1.Service) (*v1.Service, error) {
+	svc, err := data.clientset.CoreV1().Services(testNamespace).Create(context.TODO(), service, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != v1.ClusterIPNone {
+		data.registerOVSFlowKeyword(svc.Spec.ClusterIP)
+	}
+	return svc, nil
+}
+
+// createServiceWithPorts creates a service with multiple ports. Ports of a
+// multi-port service must be named.
+func (data *TestData) createServiceWithPorts(serviceName string, ports []v1.ServicePort, selector map[string]string, affinity bool) (*v1.Service, error) {
+	affinityType := v1.ServiceAffinityNone
+	if affinity {
+		affinityType = v1.ServiceAffinityClientIP
+	}
+	service := v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      serviceName,
+			Namespace: testNamespace,
+			Labels: map[string]string{
+				"antrea-e2e": serviceName,
+				"app":        serviceName,
+			},
+		},
+		Spec: v1.ServiceSpec{
+			SessionAffinity: affinityType,
+			Ports:           ports,
+			Selector:        selector,
+		},
+	}
+	return data.createServiceObject(&service)
+}
+
+// createNginxService creates a TCP service named "nginx" for the nginx Pods.
 func (data *TestData) createNginxService(affinity bool) (*v1.Service, error) {
-	return data.createService("nginx", 80, 80, map[string]string{"app": "nginx"}, affinity)
+	return data.createServiceWithProtocol("nginx", 80, 80, v1.ProtocolTCP, map[string]string{"app": "nginx"}, affinity)
+}
+
+// createNginxServiceWithIPFamily creates a nginx service whose ClusterIP is allocated from the
+// provided IP family.
+func (data *TestData) createNginxServiceWithIPFamily(affinity bool, ipFamily v1.IPFamily) (*v1.Service, error) {
+	return data.createServiceWithIPFamily("nginx", 80, 80, v1.ProtocolTCP, map[string]string{"app": "nginx"}, affinity, &ipFamily)
+}
+
+// createNodePortService creates a NodePort service with port, targetPort, protocol and
+// externalTrafficPolicy.
+func (data *TestData) createNodePortService(serviceName string, port, targetPort int, protocol v1.Protocol, selector map[string]string, externalTrafficPolicy v1.ServiceExternalTrafficPolicyType) (*v1.Service, error) {
+	return data.createServiceWithType(serviceName, v1.ServiceTypeNodePort, port, targetPort, protocol, selector, externalTrafficPolicy)
+}
+
+// createLoadBalancerService creates a LoadBalancer service with port, targetPort, protocol and
+// externalTrafficPolicy.
+func (data *TestData) createLoadBalancerService(serviceName string, port, targetPort int, protocol v1.Protocol, selector map[string]string, externalTrafficPolicy v1.ServiceExternalTrafficPolicyType) (*v1.Service, error) {
+	return data.createServiceWithType(serviceName, v1.ServiceTypeLoadBalancer, port, targetPort, protocol, selector, externalTrafficPolicy)
+}
+
+// createServiceWithType creates a service of the provided type, which must be NodePort or
+// LoadBalancer, with port, targetPort, protocol and externalTrafficPolicy.
+func (data *TestData) createServiceWithType(serviceName string, serviceType v1.ServiceType, port, targetPort int, protocol v1.Protocol, selector map[string]string, externalTrafficPolicy v1.ServiceExternalTrafficPolicyType) (*v1.Service, error) {
+	service := v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      serviceName,
+			Namespace: testNamespace,
+			Labels: map[string]string{
+				"antrea-e2e": serviceName,
+				"app":        serviceName,
+			},
+		},
+		Spec: v1.ServiceSpec{
+			Type: serviceType,
+			Ports: []v1.ServicePort{{
+				Port:       int32(port),
+				TargetPort: intstr.FromInt(targetPort),
+				Protocol:   protocol,
+			}},
+			Selector:              selector,
+			ExternalTrafficPolicy: externalTrafficPolicy,
+		},
+	}
+	return data.createServiceObject(&service)
+}
+
+// setServiceLoadBalancerIngressIPs sets the ingress IPs in the LoadBalancer status of the
+// service, which simulates the assignment of the IPs by a cloud controller.
+func (data *TestData) setServiceLoadBalancerIngressIPs(serviceName string, ips ...string) (*v1.Service, error) {
+	service, err := data.clientset.CoreV1().Services(testNamespace).Get(context.TODO(), serviceName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	service.Status.LoadBalancer.Ingress = nil
+	for _, ip := range ips {
+		service.Status.LoadBalancer.Ingress = append(service.Status.LoadBalancer.Ingress, v1.LoadBalancerIngress{IP: ip})
+	}
+	return data.clientset.CoreV1().Services(testNamespace).UpdateStatus(context.TODO(), service, metav1.UpdateOptions{})
+}
+
+// createNginxNodePortService creates a nginx NodePort service with the provided
+// externalTrafficPolicy.
+func (data *TestData) createNginxNodePortService(externalTrafficPolicy v1.ServiceExternalTrafficPolicyType) (*v1.Service, error) {
+	return data.createNodePortService("nginx", 80, 80, v1.ProtocolTCP, map[string]string{"app": "nginx"}, externalTrafficPolicy)
 }
 
 // deleteService deletes the service.
@@ -919,6 +1156,243 @@ func (data *TestData) runCommandFromPod(podNamespace string, podName string, con
 	return stdoutB.String(), stderrB.String(), nil
 }
 
+// runCommandFromPodWithRetry is like runCommandFromPod, but runs the command again when the exec
+// fails because of a transient infrastructure error, up to maxRetries times. The delay between
+// attempts starts at retryDelay and doubles after each attempt. Errors caused by the command itself
+// are returned immediately.
+func (data *TestData) runCommandFromPodWithRetry(podNamespace string, podName string, containerName string, cmd []string, maxRetries int, retryDelay time.Duration) (stdout string, stderr string, err error) {
+	backoff := wait.Backoff{Duration: retryDelay, Factor: 2, Steps: maxRetries + 1}
+	// The last error of the command is returned, not the one of ExponentialBackoff.
+	wait.ExponentialBackoff(backoff, func() (bool, error) {
+		stdout, stderr, err = data.runCommandFromPod(podNamespace, podName, containerName, cmd)
+		return !utils.IsTransientExecError(err), nil
+	})
+	return stdout, stderr, err
+}
+
+// getPodNetNS runs "ip addr show", "ip route show" and "ip link show" in the first container of the
+// provided Pod and returns the parsed configuration of the Pod interface. The result is cached for
+// the lifetime of the Pod (identified by its UID), so the commands are only run once per Pod.
+func (data *TestData) getPodNetNS(namespace, name string) (NetNSInfo, error) {
+	pod, err := data.clientset.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return NetNSInfo{}, fmt.Errorf("error when getting Pod '%s/%s': %v", namespace, name, err)
+	}
+	data.podNetNSCacheMutex.Lock()
+	defer data.podNetNSCacheMutex.Unlock()
+	if info, ok := data.podNetNSCache[pod.UID]; ok {
+		return info, nil
+	}
+
+	containerName := pod.Spec.Containers[0].Name
+	run := func(cmd ...string) (string, error) {
+		stdout, stderr, err := data.runCommandFromPod(namespace, name, containerName, cmd)
+		if err != nil {
+			return "", fmt.Errorf("error when running '%s' in Pod '%s/%s': %v - stderr: %s", strings.Join(cmd, " "), namespace, name, err, stderr)
+		}
+		return stdout, nil
+	}
+	var info NetNSInfo
+	addrOutput, err := run("ip", "addr", "show")
+	if err != nil {
+		return NetNSInfo{}, err
+	}
+	if info.ipAddresses, err = parseIPAddrOutput(addrOutput, podInterfaceName); err != nil {
+		return NetNSInfo{}, err
+	}
+	routeOutput, err := run("ip", "route", "show")
+	if err != nil {
+		return NetNSInfo{}, err
+	}
+	if info.routes, err = parseIPRouteOutput(routeOutput); err != nil {
+		return NetNSInfo{}, err
+	}
+	linkOutput, err := run("ip", "link", "show")
+	if err != nil {
+		return NetNSInfo{}, err
+	}
+	if info.mtu, err = parseIPLinkMTU(linkOutput, podInterfaceName); err != nil {
+		return NetNSInfo{}, err
+	}
+
+	if data.podNetNSCache == nil {
+		data.podNetNSCache = make(map[types.UID]NetNSInfo)
+	}
+	data.podNetNSCache[pod.UID] = info
+	return info, nil
+}
+
+// ipLinkHeaderName returns the interface name if line is the first line of an interface in the
+// output of "ip addr show" or "ip link show", e.g. "3: eth0@if12: <BROADCAST,...> mtu 1450 ...".
+func ipLinkHeaderName(line string) (string, bool) {
+	if line == "" || line[0] < '0' || line[0] > '9' {
+		return "", false
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return "", false
+	}
+	name := strings.TrimSuffix(fields[1], ":")
+	// Strip the peer index of veth interfaces.
+	if idx := strings.Index(name, "@"); idx >= 0 {
+		name = name[:idx]
+	}
+	return name, true
+}
+
+// parseIPAddrOutput returns the addresses of interface ifName listed in the output of "ip addr show".
+func parseIPAddrOutput(output, ifName string) ([]*net.IPNet, error) {
+	var addresses []*net.IPNet
+	found, inInterface := false, false
+	for _, line := range strings.Split(output, "\n") {
+		if name, ok := ipLinkHeaderName(line); ok {
+			inInterface = name == ifName
+			found = found || inInterface
+			continue
+		}
+		fields := strings.Fields(line)
+		if !inInterface || len(fields) < 2 || (fields[0] != "inet" && fields[0] != "inet6") {
+			continue
+		}
+		ipAddr, ipNet, err := net.ParseCIDR(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid address '%s' for interface %s: %v", fields[1], ifName, err)
+		}
+		ipNet.IP = ipAddr
+		addresses = append(addresses, ipNet)
+	}
+	if !found {
+		return nil, fmt.Errorf("interface %s not found", ifName)
+	}
+	return addresses, nil
+}
+
+// parseIPLinkMTU returns the MTU of interface ifName from the output of "ip link show".
+func parseIPLinkMTU(output, ifName string) (int, error) {
+	for _, line := range strings.Split(output, "\n") {
+		if name, ok := ipLinkHeaderName(line); !ok || name != ifName {
+			continue
+		}
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields)-1; i++ {
+			if fields[i] == "mtu" {
+				return strconv.Atoi(fields[i+1])
+			}
+		}
+		return 0, fmt.Errorf("no MTU found for interface %s", ifName)
+	}
+	return 0, fmt.Errorf("interface %s not found", ifName)
+}
+
+// parseIPRouteOutput returns the routes listed in the output of "ip route show", e.g.
+// "default via 10.10.1.1 dev eth0".
+func parseIPRouteOutput(output string) ([]podRoute, error) {
+	var routes []podRoute
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		route := podRoute{destination: fields[0]}
+		for i := 1; i < len(fields)-1; i++ {
+			switch fields[i] {
+			case "via":
+				if route.gateway = net.ParseIP(fields[i+1]); route.gateway == nil {
+					return nil, fmt.Errorf("invalid gateway in route '%s'", line)
+				}
+			case "dev":
+				route.device = fields[i+1]
+			}
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// getNodeGatewayIP returns the IP address of the Antrea gateway interface of the provided Node,
+// i.e. the first address of the Node's Pod CIDR for the provided IP family.
+func (data *TestData) getNodeGatewayIP(nodeName string, ipFamily v1.IPFamily) (net.IP, error) {
+	node, err := data.clientset.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error when getting Node '%s': %v", nodeName, err)
+	}
+	podCIDRs := node.Spec.PodCIDRs
+	if len(podCIDRs) == 0 && node.Spec.PodCIDR != "" {
+		podCIDRs = []string{node.Spec.PodCIDR}
+	}
+	for _, podCIDR := range podCIDRs {
+		_, cidr, err := net.ParseCIDR(podCIDR)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Pod CIDR '%s' for Node '%s': %v", podCIDR, nodeName, err)
+		}
+		if utilnet.IsIPv6CIDR(cidr) == (ipFamily == v1.IPv6Protocol) {
+			return ip.NextIP(cidr.IP), nil
+		}
+	}
+	return nil, fmt.Errorf("no %s Pod CIDR for Node '%s'", ipFamily, nodeName)
+}
+
+// waitForOVSFlow polls the flows of the provided table of the OVS bridge on the provided Node every
+// 200ms, until one of them contains keyword (if present is true) or none of them does (if present is
+// false). It returns an error if the condition is not met before ctx is done. The number of polling
+// iterations is logged to help debugging slow flow updates.
+func (data *TestData) waitForOVSFlow(ctx context.Context, tb testing.TB, nodeName, bridge, table, keyword string, present bool) error {
+	antreaPodName, err := data.getAntreaPodOnNode(nodeName)
+	if err != nil {
+		return err
+	}
+	cmd := []string{"ovs-ofctl", "dump-flows", bridge, fmt.Sprintf("table=%s", table)}
+	iterations := 0
+	err = wait.PollImmediateUntil(200*time.Millisecond, func() (bool, error) {
+		iterations++
+		flows, stderr, err := data.runCommandFromPod(antreaNamespace, antreaPodName, agentContainerName, cmd)
+		if err != nil {
+			return false, fmt.Errorf("error when dumping flows of table %s on Node '%s': %v - stderr: %s", table, nodeName, err, stderr)
+		}
+		return strings.Contains(flows, keyword) == present, nil
+	}, ctx.Done())
+	tb.Logf("Polled flows of table %s on Node '%s' %d times waiting for '%s' (present: %t)", table, nodeName, iterations, keyword, present)
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for flow '%s' (present: %t) in table %s on Node '%s'", keyword, present, table, nodeName)
+	}
+	return err
+}
+
+// getNodeKernelRelease returns the output of "uname -r" for the provided Node. The command is run in
+// the Antrea agent Pod, which shares the kernel of the Node.
+func (data *TestData) getNodeKernelRelease(nodeName string) (string, error) {
+	antreaPodName, err := data.getAntreaPodOnNode(nodeName)
+	if err != nil {
+		return "", err
+	}
+	stdout, stderr, err := data.runCommandFromPod(antreaNamespace, antreaPodName, agentContainerName, []string{"uname", "-r"})
+	if err != nil {
+		return "", fmt.Errorf("error when running 'uname -r' in Pod '%s': %v - stderr: %s", antreaPodName, err, stderr)
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
+// parseKernelVersion returns the major and minor versions of a kernel release such as
+// "5.4.0-42-generic".
+func parseKernelVersion(release string) (major, minor int, err error) {
+	parts := strings.SplitN(release, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("invalid kernel release '%s'", release)
+	}
+	if major, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, fmt.Errorf("invalid major version in kernel release '%s'", release)
+	}
+	// The minor version may be directly followed by a suffix, e.g. "4.19-rc1".
+	minorStr := parts[1]
+	if idx := strings.IndexFunc(minorStr, func(r rune) bool { return r < '0' || r > '9' }); idx >= 0 {
+		minorStr = minorStr[:idx]
+	}
+	if minor, err = strconv.Atoi(minorStr); err != nil {
+		return 0, 0, fmt.Errorf("invalid minor version in kernel release '%s'", release)
+	}
+	return major, minor, nil
+}
+
 func forAllNodes(fn func(nodeName string) error) error {
 	for idx := 0; idx < clusterInfo.numNodes; idx++ {
 		name := nodeName(idx)
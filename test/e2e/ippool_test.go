@@ -0,0 +1,381 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/vmware-tanzu/antrea/pkg/apis/core/v1alpha1"
+	"github.com/vmware-tanzu/antrea/pkg/util/ippool"
+)
+
+// TestIPPoolAllocation verifies that the Pods in the Namespaces selected by an
+// IPPool get their IPs from the pool, and that the IPs are returned to the pool
+// when the Pods are deleted.
+func TestIPPoolAllocation(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	if err = data.enableAntreaIPAM(); err != nil {
+		t.Fatalf("Error when enabling AntreaIPAM: %v", err)
+	}
+
+	poolName := randName("test-ippool-")
+	if err = data.labelTestNamespace(map[string]string{"ippool": poolName}); err != nil {
+		t.Fatalf("Error when labelling test Namespace: %v", err)
+	}
+	// 13 IPs of the /28 CIDR can be allocated, as the network IP, the gateway
+	// and the broadcast IP are excluded.
+	_, poolCIDR, _ := net.ParseCIDR("192.168.240.0/28")
+	pool := &v1alpha1.IPPool{
+		ObjectMeta: metav1.ObjectMeta{Name: poolName},
+		Spec: v1alpha1.IPPoolSpec{
+			Ranges:            []v1alpha1.IPRange{{CIDR: poolCIDR.String()}},
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"ippool": poolName}},
+		},
+	}
+	if _, err = data.crdClient.CoreV1alpha1().IPPools().Create(context.TODO(), pool, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Error when creating IPPool: %v", err)
+	}
+	defer data.crdClient.CoreV1alpha1().IPPools().Delete(context.TODO(), poolName, metav1.DeleteOptions{})
+	if _, err = data.waitForIPPool(poolName, func(pool *v1alpha1.IPPool) bool {
+		for _, condition := range pool.Status.Conditions {
+			if condition.Type == v1alpha1.IPPoolConditionValid {
+				return condition.Status == corev1.ConditionTrue
+			}
+		}
+		return false
+	}); err != nil {
+		t.Fatalf("Error when waiting for IPPool to be validated: %v", err)
+	}
+
+	const numPods = 10
+	podIPs := make(map[string]string, numPods)
+	for i := 0; i < numPods; i++ {
+		podName := randName(fmt.Sprintf("test-pod-%d-", i))
+		if err := data.createBusyboxPod(podName); err != nil {
+			t.Fatalf("Error when creating busybox Pod: %v", err)
+		}
+		defer data.deletePodAndWait(defaultTimeout, podName)
+		podIPs[podName] = ""
+	}
+	for podName := range podIPs {
+		podIP, err := data.podWaitForIP(defaultTimeout, podName, testNamespace)
+		if err != nil {
+			t.Fatalf("Error when waiting for IP of Pod %s: %v", podName, err)
+		}
+		if !poolCIDR.Contains(net.ParseIP(podIP)) {
+			t.Errorf("IP %s of Pod %s is not in IPPool CIDR %s", podIP, podName, poolCIDR.String())
+		}
+		podIPs[podName] = podIP
+	}
+	allocatedIPs := func(pool *v1alpha1.IPPool) sets.String {
+		ips := sets.NewString()
+		for _, allocation := range pool.Status.Allocations {
+			ips.Insert(allocation.IP)
+		}
+		return ips
+	}
+	pool, err = data.crdClient.CoreV1alpha1().IPPools().Get(context.TODO(), poolName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Error when getting IPPool: %v", err)
+	}
+	expectedIPs := sets.NewString()
+	for _, podIP := range podIPs {
+		expectedIPs.Insert(podIP)
+	}
+	if !allocatedIPs(pool).Equal(expectedIPs) {
+		t.Fatalf("Expected IPs %v to be allocated from IPPool, got %v", expectedIPs.List(), allocatedIPs(pool).List())
+	}
+
+	deleted := 0
+	for podName, podIP := range podIPs {
+		if deleted == numPods/2 {
+			break
+		}
+		if err := data.deletePodAndWait(defaultTimeout, podName); err != nil {
+			t.Fatalf("Error when deleting Pod %s: %v", podName, err)
+		}
+		expectedIPs.Delete(podIP)
+		deleted++
+	}
+	if _, err = data.waitForIPPool(poolName, func(pool *v1alpha1.IPPool) bool {
+		return allocatedIPs(pool).Equal(expectedIPs)
+	}); err != nil {
+		t.Errorf("Error when waiting for the IPs of the deleted Pods to be returned to IPPool: %v", err)
+	}
+}
+
+// TestStaticIPAnnotation verifies that a Pod requesting a static IP with the
+// ipam.antrea.io/static-ip annotation gets the same IP after it is recreated, and
+// that another Pod requesting the same IP is rejected.
+func TestStaticIPAnnotation(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	if err = data.enableAntreaIPAM(); err != nil {
+		t.Fatalf("Error when enabling AntreaIPAM: %v", err)
+	}
+
+	poolName := randName("test-ippool-")
+	if err = data.labelTestNamespace(map[string]string{"ippool": poolName}); err != nil {
+		t.Fatalf("Error when labelling test Namespace: %v", err)
+	}
+	pool := &v1alpha1.IPPool{
+		ObjectMeta: metav1.ObjectMeta{Name: poolName},
+		Spec: v1alpha1.IPPoolSpec{
+			Ranges:            []v1alpha1.IPRange{{CIDR: "192.168.241.0/28"}},
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"ippool": poolName}},
+		},
+	}
+	if _, err = data.crdClient.CoreV1alpha1().IPPools().Create(context.TODO(), pool, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Error when creating IPPool: %v", err)
+	}
+	defer data.crdClient.CoreV1alpha1().IPPools().Delete(context.TODO(), poolName, metav1.DeleteOptions{})
+	if _, err = data.waitForIPPool(poolName, func(pool *v1alpha1.IPPool) bool {
+		for _, condition := range pool.Status.Conditions {
+			if condition.Type == v1alpha1.IPPoolConditionValid {
+				return condition.Status == corev1.ConditionTrue
+			}
+		}
+		return false
+	}); err != nil {
+		t.Fatalf("Error when waiting for IPPool to be validated: %v", err)
+	}
+
+	const staticIP = "192.168.241.10"
+	podName := randName("test-pod-static-ip-")
+	getPodIP := func() string {
+		if err := data.createStaticIPBusyboxPod(podName, staticIP); err != nil {
+			t.Fatalf("Error when creating busybox Pod: %v", err)
+		}
+		podIP, err := data.podWaitForIP(defaultTimeout, podName, testNamespace)
+		if err != nil {
+			t.Fatalf("Error when waiting for IP of Pod %s: %v", podName, err)
+		}
+		return podIP
+	}
+	podIP := getPodIP()
+	defer data.deletePodAndWait(defaultTimeout, podName)
+	if podIP != staticIP {
+		t.Fatalf("Expected Pod %s to get static IP %s, got %s", podName, staticIP, podIP)
+	}
+
+	// The static IP is already used, so the webhook must reject the Pod.
+	conflictingPodName := randName("test-pod-static-ip-")
+	if err := data.createStaticIPBusyboxPod(conflictingPodName, staticIP); err == nil {
+		defer data.deletePodAndWait(defaultTimeout, conflictingPodName)
+		t.Errorf("Expected Pod %s requesting in-use IP %s to be rejected", conflictingPodName, staticIP)
+	}
+
+	if err := data.deletePodAndWait(defaultTimeout, podName); err != nil {
+		t.Fatalf("Error when deleting Pod %s: %v", podName, err)
+	}
+	if podIP = getPodIP(); podIP != staticIP {
+		t.Errorf("Expected recreated Pod %s to get static IP %s, got %s", podName, staticIP, podIP)
+	}
+}
+
+// TestIPAMStatePersistence verifies that the IPs allocated from an IPPool are
+// persisted by antrea-agent, and that a running Pod keeps its IP and its
+// allocation after antrea-agent restarts.
+func TestIPAMStatePersistence(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	if err = data.enableAntreaIPAM(); err != nil {
+		t.Fatalf("Error when enabling AntreaIPAM: %v", err)
+	}
+
+	poolName := randName("test-ippool-")
+	if err = data.labelTestNamespace(map[string]string{"ippool": poolName}); err != nil {
+		t.Fatalf("Error when labelling test Namespace: %v", err)
+	}
+	pool := &v1alpha1.IPPool{
+		ObjectMeta: metav1.ObjectMeta{Name: poolName},
+		Spec: v1alpha1.IPPoolSpec{
+			Ranges:            []v1alpha1.IPRange{{CIDR: "192.168.242.0/28"}},
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"ippool": poolName}},
+		},
+	}
+	if _, err = data.crdClient.CoreV1alpha1().IPPools().Create(context.TODO(), pool, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Error when creating IPPool: %v", err)
+	}
+	defer data.crdClient.CoreV1alpha1().IPPools().Delete(context.TODO(), poolName, metav1.DeleteOptions{})
+	if _, err = data.waitForIPPool(poolName, func(pool *v1alpha1.IPPool) bool {
+		for _, condition := range pool.Status.Conditions {
+			if condition.Type == v1alpha1.IPPoolConditionValid {
+				return condition.Status == corev1.ConditionTrue
+			}
+		}
+		return false
+	}); err != nil {
+		t.Fatalf("Error when waiting for IPPool to be validated: %v", err)
+	}
+
+	node := nodeName(0)
+	podName := randName("test-pod-")
+	if err := data.createBusyboxPodOnNode(podName, node); err != nil {
+		t.Fatalf("Error when creating busybox Pod: %v", err)
+	}
+	defer data.deletePodAndWait(defaultTimeout, podName)
+	podIP, err := data.podWaitForIP(defaultTimeout, podName, testNamespace)
+	if err != nil {
+		t.Fatalf("Error when waiting for IP of Pod %s: %v", podName, err)
+	}
+
+	agentPodName, err := data.getAntreaPodOnNode(node)
+	if err != nil {
+		t.Fatalf("Error when getting antrea-agent Pod on Node %s: %v", node, err)
+	}
+	state, _, err := data.runCommandFromPod(antreaNamespace, agentPodName, agentContainerName, []string{"cat", "/var/lib/antrea/ipam-state.json"})
+	if err != nil {
+		t.Fatalf("Error when reading IPAM state of antrea-agent Pod %s: %v", agentPodName, err)
+	}
+	if !strings.Contains(state, fmt.Sprintf("%q", podIP)) {
+		t.Errorf("Expected IPAM state to contain IP %s of Pod %s, got %s", podIP, podName, state)
+	}
+
+	if _, err := data.deleteAntreaAgentOnNode(node, 30, defaultTimeout); err != nil {
+		t.Fatalf("Error when restarting antrea-agent on Node %s: %v", node, err)
+	}
+	if err := data.waitForAntreaDaemonSetPods(defaultTimeout); err != nil {
+		t.Fatalf("Error when waiting for antrea-agent Pods: %v", err)
+	}
+
+	newPodIP, err := data.podWaitForIP(defaultTimeout, podName, testNamespace)
+	if err != nil {
+		t.Fatalf("Error when waiting for IP of Pod %s: %v", podName, err)
+	}
+	if newPodIP != podIP {
+		t.Errorf("Expected Pod %s to keep IP %s after antrea-agent restarted, got %s", podName, podIP, newPodIP)
+	}
+	// The allocation of the running Pod must not be reclaimed when antrea-agent
+	// reconciles the allocations with the Pods of the Node.
+	time.Sleep(5 * time.Second)
+	pool, err = data.crdClient.CoreV1alpha1().IPPools().Get(context.TODO(), poolName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Error when getting IPPool: %v", err)
+	}
+	allocated := false
+	for _, allocation := range pool.Status.Allocations {
+		if allocation.IP == podIP && allocation.Pod == podName {
+			allocated = true
+		}
+	}
+	if !allocated {
+		t.Errorf("Expected IP %s to be still allocated to Pod %s, got allocations %v", podIP, podName, pool.Status.Allocations)
+	}
+}
+
+// createStaticIPBusyboxPod creates a busybox Pod in the test Namespace, which
+// requests the static IP with the ipam.antrea.io/static-ip annotation.
+func (data *TestData) createStaticIPBusyboxPod(name string, staticIP string) error {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      map[string]string{"antrea-e2e": name, "app": "busybox"},
+			Annotations: map[string]string{ippool.StaticIPAnnotationKey: staticIP},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:            "busybox",
+				Image:           "busybox",
+				ImagePullPolicy: corev1.PullIfNotPresent,
+				Command:         []string{"sleep", "3600"},
+			}},
+			RestartPolicy: corev1.RestartPolicyNever,
+		},
+	}
+	_, err := data.clientset.CoreV1().Pods(testNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+	return err
+}
+
+// enableAntreaIPAM enables the AntreaIPAM feature in antrea-controller and
+// antrea-agent, and restarts them.
+func (data *TestData) enableAntreaIPAM() error {
+	configMap, err := data.GetAntreaConfigMap(antreaNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to get ConfigMap: %v", err)
+	}
+	for _, conf := range []string{"antrea-controller.conf", "antrea-agent.conf"} {
+		configMap.Data[conf] = strings.Replace(configMap.Data[conf], "#  AntreaIPAM: false", " AntreaIPAM: true", 1)
+	}
+	if _, err := data.clientset.CoreV1().ConfigMaps(antreaNamespace).Update(context.TODO(), configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s: %v", configMap.Name, err)
+	}
+	if _, err := data.restartAntreaControllerPod(defaultTimeout); err != nil {
+		return fmt.Errorf("error when restarting antrea-controller Pod: %v", err)
+	}
+	if err := data.restartAntreaAgentPods(defaultTimeout); err != nil {
+		return fmt.Errorf("error when restarting antrea-agent Pod: %v", err)
+	}
+	return nil
+}
+
+// labelTestNamespace adds the labels to the test Namespace.
+func (data *TestData) labelTestNamespace(labels map[string]string) error {
+	ns, err := data.clientset.CoreV1().Namespaces().Get(context.TODO(), testNamespace, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if ns.Labels == nil {
+		ns.Labels = map[string]string{}
+	}
+	for k, v := range labels {
+		ns.Labels[k] = v
+	}
+	_, err = data.clientset.CoreV1().Namespaces().Update(context.TODO(), ns, metav1.UpdateOptions{})
+	return err
+}
+
+// waitForIPPool polls the IPPool until the condition predicate is met.
+func (data *TestData) waitForIPPool(name string, condition func(*v1alpha1.IPPool) bool) (*v1alpha1.IPPool, error) {
+	var pool *v1alpha1.IPPool
+	err := wait.PollImmediate(time.Second, defaultTimeout, func() (bool, error) {
+		var err error
+		pool, err = data.crdClient.CoreV1alpha1().IPPools().Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return condition(pool), nil
+	})
+	return pool, err
+}
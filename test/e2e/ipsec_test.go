@@ -15,6 +15,8 @@
 package e2e
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -102,3 +104,70 @@ func TestIPSecDeleteStaleTunnelPorts(t *testing.T) {
 		t.Fatalf("Error while waiting for OVS tunnel port to be	deleted")
 	}
 }
+
+// TestIPSecKeyRotation checks that rotating the IPSec PSK with "antctl
+// rotate-ipsec-key" does not disrupt the Pod traffic across Nodes, by having a
+// Pod continuously ping a Pod on another Node during the rotation.
+func TestIPSecKeyRotation(t *testing.T) {
+	skipIfProviderIs(t, "kind", "IPSec tunnel does not work with Kind")
+	skipIfNumNodesLessThan(t, 2)
+
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	t.Logf("Redeploy Antrea with IPSec tunnel enabled")
+	data.redeployAntrea(t, true)
+	// Restore normal Antrea deployment with IPSec disabled.
+	defer data.redeployAntrea(t, false)
+
+	podNames, deletePods := createPodsOnDifferentNodes(t, data, 2)
+	defer deletePods()
+	podIPs := make([]string, len(podNames))
+	for i, podName := range podNames {
+		if podIPs[i], err = data.podWaitForIP(defaultTimeout, podName, testNamespace); err != nil {
+			t.Fatalf("Error when waiting for IP of Pod '%s': %v", podName, err)
+		}
+	}
+	// Make sure the SAs are established before the rotation.
+	if err := data.runPingCommandFromTestPod(podNames[0], podIPs[1], 3); err != nil {
+		t.Fatalf("Error when pinging Pod '%s' from Pod '%s': %v", podNames[1], podNames[0], err)
+	}
+
+	pingCount := 60
+	pingDone := make(chan error, 1)
+	go func() {
+		cmd := []string{"ping", "-c", fmt.Sprint(pingCount), podIPs[1]}
+		stdout, stderr, err := data.runCommandFromPod(testNamespace, podNames[0], busyboxContainerName, cmd)
+		if err != nil {
+			pingDone <- fmt.Errorf("error when running ping: %v, stdout: %s, stderr: %s", err, stdout, stderr)
+		} else if !strings.Contains(stdout, fmt.Sprintf("%d packets transmitted, %d packets received", pingCount, pingCount)) {
+			pingDone <- fmt.Errorf("packets were lost during the key rotation: %s", stdout)
+		} else {
+			pingDone <- nil
+		}
+	}()
+
+	controllerPod, err := data.getAntreaController()
+	if err != nil {
+		t.Fatalf("Error when getting antrea-controller Pod: %v", err)
+	}
+	// Let some packets go through the SAs established with the initial PSK.
+	time.Sleep(5 * time.Second)
+	cmds := []string{"antctl", "rotate-ipsec-key", "--wait", "--timeout", "45s"}
+	stdout, stderr, err := runAntctl(controllerPod.Name, cmds, data)
+	antctlOutput(stdout, stderr, t)
+	if err != nil {
+		t.Fatalf("Error when running `antctl rotate-ipsec-key`: %v", err)
+	}
+
+	if err := <-pingDone; err != nil {
+		t.Errorf("Pod traffic was disrupted by the IPSec key rotation: %v", err)
+	}
+	// Pod traffic must still be forwarded once the previous PSK has been removed.
+	if err := data.runPingCommandFromTestPod(podNames[1], podIPs[0], 3); err != nil {
+		t.Errorf("Error when pinging Pod '%s' from Pod '%s' after the key rotation: %v", podNames[0], podNames[1], err)
+	}
+}
@@ -63,6 +63,7 @@ func testMain(m *testing.M) int {
 	flag.StringVar(&testOptions.logsExportDir, "logs-export-dir", "", "Export directory for test logs")
 	flag.BoolVar(&testOptions.logsExportOnSuccess, "logs-export-on-success", false, "Export logs even when a test is successful")
 	flag.BoolVar(&testOptions.withBench, "benchtest", false, "Run tests include benchmark tests")
+	flag.IntVar(&testOptions.proxyStressParallelism, "proxy-stress-parallelism", 10, "Number of concurrent workers used by TestProxyServiceStress")
 	flag.Parse()
 
 	if err := initProvider(); err != nil {
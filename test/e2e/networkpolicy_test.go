@@ -17,6 +17,7 @@ package e2e
 import (
 	"context"
 	"encoding/json"
+	"net"
 	"testing"
 	"time"
 
@@ -106,6 +107,21 @@ func TestDifferentNamedPorts(t *testing.T) {
 	if err = data.runNetcatCommandFromTestPod(client1Name, server1IP, server1Port); err == nil {
 		t.Fatalf("Pod %s should not be able to connect %s:%d, but was able to connect", client1Name, server1IP, server1Port)
 	}
+
+	// A server Pod created after the NetworkPolicy, which declares the named
+	// port "http" with another port number, is also selected by the rule.
+	server2Port := 8081
+	_, server2IP, cleanupFunc := createAndWaitForPod(t, data, func(name string, nodeName string) error {
+		return data.createServerPod(name, "http", server2Port, false)
+	}, "test-server-", "")
+	defer cleanupFunc()
+
+	if err = data.runNetcatCommandFromTestPod(client0Name, server2IP, server2Port); err != nil {
+		t.Fatalf("Pod %s should be able to connect %s:%d, but was not able to connect", client0Name, server2IP, server2Port)
+	}
+	if err = data.runNetcatCommandFromTestPod(client1Name, server2IP, server2Port); err == nil {
+		t.Fatalf("Pod %s should not be able to connect %s:%d, but was able to connect", client1Name, server2IP, server2Port)
+	}
 }
 
 func TestDefaultDenyEgressPolicy(t *testing.T) {
@@ -313,6 +329,66 @@ func TestIngressPolicyWithoutPortNumber(t *testing.T) {
 	}
 }
 
+// TestIngressPolicyWithIPBlockExcept tests that the except CIDRs of an ipBlock are excluded from the
+// CIDR matched by the rule.
+func TestIngressPolicyWithIPBlockExcept(t *testing.T) {
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	serverPort := 80
+	_, serverIP, cleanupFunc := createAndWaitForPod(t, data, data.createNginxPodOnNode, "test-server-", "")
+	defer cleanupFunc()
+
+	client0Name, client0IP, cleanupFunc := createAndWaitForPod(t, data, data.createBusyboxPodOnNode, "test-client-", "")
+	defer cleanupFunc()
+
+	client1Name, client1IP, cleanupFunc := createAndWaitForPod(t, data, data.createBusyboxPodOnNode, "test-client-", "")
+	defer cleanupFunc()
+
+	if net.ParseIP(client0IP).To4() == nil {
+		t.Skipf("Skipping test as ipBlock except is only supported for IPv4")
+	}
+	// Allow the /8 CIDR which includes both clients, except the IP of client1. A
+	// narrower except CIDR could include client0 if both clients run on the same Node.
+	cidr := net.IPNet{IP: net.ParseIP(client0IP).Mask(net.CIDRMask(8, 32)), Mask: net.CIDRMask(8, 32)}
+	exceptCIDR := net.IPNet{IP: net.ParseIP(client1IP), Mask: net.CIDRMask(32, 32)}
+	spec := &networkingv1.NetworkPolicySpec{
+		PodSelector: metav1.LabelSelector{},
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		Ingress: []networkingv1.NetworkPolicyIngressRule{
+			{
+				From: []networkingv1.NetworkPolicyPeer{{
+					IPBlock: &networkingv1.IPBlock{
+						CIDR:   cidr.String(),
+						Except: []string{exceptCIDR.String()},
+					}},
+				},
+			},
+		},
+	}
+	np, err := data.createNetworkPolicy("test-networkpolicy-ingress-ipblock-except", spec)
+	if err != nil {
+		t.Fatalf("Error when creating network policy: %v", err)
+	}
+	defer func() {
+		if err = data.deleteNetworkpolicy(np); err != nil {
+			t.Fatalf("Error when deleting network policy: %v", err)
+		}
+	}()
+
+	// Client0 is in the allowed CIDR and can access server.
+	if err = data.runNetcatCommandFromTestPod(client0Name, serverIP, serverPort); err != nil {
+		t.Fatalf("Pod %s should be able to connect %s:%d, but was not able to connect", client0Name, serverIP, serverPort)
+	}
+	// Client1 is in the except CIDR and can't access server.
+	if err = data.runNetcatCommandFromTestPod(client1Name, serverIP, serverPort); err == nil {
+		t.Fatalf("Pod %s should not be able to connect %s:%d, but was able to connect", client1Name, serverIP, serverPort)
+	}
+}
+
 func createAndWaitForPod(t *testing.T, data *TestData, createFunc func(name string, nodeName string) error, namePrefix string, nodeName string) (string, string, func()) {
 	name := randName(namePrefix)
 	if err := createFunc(name, nodeName); err != nil {
@@ -0,0 +1,131 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	secv1alpha1 "github.com/vmware-tanzu/antrea/pkg/apis/security/v1alpha1"
+)
+
+// TestPreserveSourceIP verifies that the traffic matched by an egress rule with
+// preserveSourceIP is not masqueraded. The client Pod runs on the first Node and
+// accesses a Service whose Endpoint runs in the host network of the second Node,
+// so that the server sees the IP of the client Node by default.
+func TestPreserveSourceIP(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+	skipIfNumNodesLessThan(t, 2)
+
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+	skipIfCNPDisabled(t, data)
+
+	serverIP, err := data.getNodeInternalIP(nodeName(1))
+	if err != nil {
+		t.Fatalf("Error when getting IP of Node %s: %v", nodeName(1), err)
+	}
+	clientNodeIP, err := data.getNodeInternalIP(nodeName(0))
+	if err != nil {
+		t.Fatalf("Error when getting IP of Node %s: %v", nodeName(0), err)
+	}
+
+	serverName := randName("test-server-")
+	if err := data.createHostNetworkNetexecPodOnNode(serverName, nodeName(1), 8080); err != nil {
+		t.Fatalf("Error when creating server Pod: %v", err)
+	}
+	defer data.deletePodAndWait(defaultTimeout, serverName)
+	svc, err := data.createService(serverName, 8080, 8080, map[string]string{"antrea-e2e": serverName}, false)
+	if err != nil {
+		t.Fatalf("Error when creating Service: %v", err)
+	}
+	defer data.deleteService(svc.Name)
+	clientName := randName("test-client-")
+	if err := data.createBusyboxPodOnNode(clientName, nodeName(0)); err != nil {
+		t.Fatalf("Error when creating client Pod: %v", err)
+	}
+	defer data.deletePodAndWait(defaultTimeout, clientName)
+	if err := data.podWaitForRunning(defaultTimeout, serverName, testNamespace); err != nil {
+		t.Fatalf("Error when waiting for Pod %s to be running: %v", serverName, err)
+	}
+	clientPodIP, err := data.podWaitForIP(defaultTimeout, clientName, testNamespace)
+	if err != nil {
+		t.Fatalf("Error when waiting for IP of Pod %s: %v", clientName, err)
+	}
+
+	// agnhost netexec replies to "/clientip" with the source IP and port of the
+	// request.
+	getClientIP := func() (string, error) {
+		cmd := []string{"wget", "-q", "-O", "-", fmt.Sprintf("http://%s:8080/clientip", svc.Spec.ClusterIP)}
+		stdout, stderr, err := data.runCommandFromPod(testNamespace, clientName, busyboxContainerName, cmd)
+		if err != nil {
+			return "", fmt.Errorf("error when running wget: %v, stderr: %s", err, stderr)
+		}
+		return strings.Split(strings.TrimSpace(stdout), ":")[0], nil
+	}
+	if clientIP, err := getClientIP(); err != nil {
+		t.Fatalf("Error when getting client IP: %v", err)
+	} else if clientIP != clientNodeIP {
+		t.Fatalf("Expected client IP to be %s without preserveSourceIP, got %s", clientNodeIP, clientIP)
+	}
+
+	// The rule matches both the ClusterIP of the Service, which is the
+	// destination of the packets when the Service is load-balanced by
+	// kube-proxy, and the Endpoint, which is the destination of the packets
+	// when it is load-balanced by AntreaProxy.
+	allow := secv1alpha1.RuleActionAllow
+	cnp := &secv1alpha1.ClusterNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: randName("cnp-preserve-source-ip-")},
+		Spec: secv1alpha1.ClusterNetworkPolicySpec{
+			Priority: 1,
+			AppliedTo: []secv1alpha1.NetworkPolicyPeer{{
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"antrea-e2e": clientName}},
+			}},
+			Egress: []secv1alpha1.Rule{{
+				Action: &allow,
+				To: []secv1alpha1.NetworkPolicyPeer{
+					{IPBlock: &secv1alpha1.IPBlock{CIDR: svc.Spec.ClusterIP + "/32"}},
+					{IPBlock: &secv1alpha1.IPBlock{CIDR: serverIP + "/32"}},
+				},
+				PreserveSourceIP: true,
+			}},
+		},
+	}
+	if _, err := data.securityClient.ClusterNetworkPolicies().Create(context.TODO(), cnp, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Error when creating ClusterNetworkPolicy %s: %v", cnp.Name, err)
+	}
+	defer data.securityClient.ClusterNetworkPolicies().Delete(context.TODO(), cnp.Name, metav1.DeleteOptions{})
+
+	// The flows are installed asynchronously by the agent.
+	var clientIP string
+	if err := wait.PollImmediate(time.Second, defaultTimeout, func() (bool, error) {
+		clientIP, err = getClientIP()
+		if err != nil {
+			return false, err
+		}
+		return clientIP == clientPodIP, nil
+	}); err != nil {
+		t.Fatalf("Expected client IP to be Pod IP %s, got %s: %v", clientPodIP, clientIP, err)
+	}
+}
@@ -41,6 +41,8 @@ var antreaAgentMetrics = []string{
 	"antrea_agent_networkpolicy_count",
 	"antrea_agent_ovs_total_flow_count",
 	"antrea_agent_ovs_flow_count",
+	"antrea_agent_ovs_table_packets_total",
+	"antrea_agent_ovs_table_bytes_total",
 	"antrea_agent_runtime_info",
 }
 
@@ -55,6 +57,7 @@ var antreaControllerMetrics = []string{
 	"antrea_controller_length_network_policy_queue",
 	"antrea_controller_network_policy_processed",
 	"antrea_controller_network_policy_sync_duration_milliseconds",
+	"antrea_controller_reconcile_queue_depth",
 	"antrea_controller_runtime_info",
 }
 
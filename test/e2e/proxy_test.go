@@ -15,18 +15,72 @@
 package e2e
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"net"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	utilnet "k8s.io/utils/net"
 )
 
+const (
+	// ovsCommandMaxRetries and ovsCommandRetryDelay are used when running the commands which
+	// check the OVS state, to tolerate transient exec failures.
+	ovsCommandMaxRetries = 3
+	ovsCommandRetryDelay = time.Second
+)
+
+// TestCreateServiceWithProtocol checks that the Service helpers set the expected protocol. The
+// Services are created with a fake clientset.
+func TestCreateServiceWithProtocol(t *testing.T) {
+	data := &TestData{clientset: fake.NewSimpleClientset()}
+	selector := map[string]string{"antrea-e2e": "server"}
+	for _, protocol := range []v1.Protocol{v1.ProtocolTCP, v1.ProtocolUDP, v1.ProtocolSCTP} {
+		name := "server-" + strings.ToLower(string(protocol))
+		svc, err := data.createServiceWithProtocol(name, 80, 8080, protocol, selector, true)
+		require.NoError(t, err)
+		require.Len(t, svc.Spec.Ports, 1)
+		assert.Equal(t, protocol, svc.Spec.Ports[0].Protocol)
+		assert.Equal(t, int32(80), svc.Spec.Ports[0].Port)
+		assert.Equal(t, int32(8080), svc.Spec.Ports[0].TargetPort.IntVal)
+		assert.Equal(t, selector, svc.Spec.Selector)
+		assert.Equal(t, v1.ServiceAffinityClientIP, svc.Spec.SessionAffinity)
+	}
+
+	svc, err := data.createService("server", 80, 80, selector, false)
+	require.NoError(t, err)
+	assert.Equal(t, v1.ProtocolTCP, svc.Spec.Ports[0].Protocol)
+	assert.Equal(t, v1.ServiceAffinityNone, svc.Spec.SessionAffinity)
+
+	svc, err = data.createNginxService(false)
+	require.NoError(t, err)
+	assert.Equal(t, v1.ProtocolTCP, svc.Spec.Ports[0].Protocol)
+	assert.Equal(t, map[string]string{"app": "nginx"}, svc.Spec.Selector)
+
+	ports := []v1.ServicePort{
+		{Name: "http", Port: 80, TargetPort: intstr.FromInt(80), Protocol: v1.ProtocolTCP},
+		{Name: "http-alt", Port: 81, TargetPort: intstr.FromInt(80), Protocol: v1.ProtocolTCP},
+	}
+	svc, err = data.createServiceWithPorts("server-multiport", ports, selector, true)
+	require.NoError(t, err)
+	assert.Equal(t, ports, svc.Spec.Ports)
+	assert.Equal(t, v1.ServiceAffinityClientIP, svc.Spec.SessionAffinity)
+}
+
 func skipIfProxyDisabled(t *testing.T, data *TestData) {
 	if enabled, err := proxyEnabled(data); err != nil {
 		t.Fatalf("Error when detecting proxy: %v", err)
@@ -35,43 +89,286 @@ func skipIfProxyDisabled(t *testing.T, data *TestData) {
 	}
 }
 
+func skipIfProxyIPv6Disabled(t *testing.T, data *TestData) {
+	if enabled, err := proxyIPv6Enabled(data); err != nil {
+		t.Fatalf("Error when detecting IPv6 proxy: %v", err)
+	} else if !enabled {
+		t.Skip()
+	}
+}
+
+// proxyEnabled checks that AntreaProxy is enabled, i.e. that both TCP and UDP packets are sent to
+// the SessionAffinity (40) and ServiceLB (41) tables from the ConntrackState table (31).
 func proxyEnabled(data *TestData) (bool, error) {
-	key := "resubmit(,40),resubmit(,41)"
+	keys := []string{
+		"tcp actions=resubmit(,40),resubmit(,41)",
+		"udp actions=resubmit(,40),resubmit(,41)",
+	}
 	agentName, err := data.getAntreaPodOnNode(masterNodeName())
 	if err != nil {
 		return false, err
 	}
-	table31Output, _, err := data.runCommandFromPod(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=31"})
-	return strings.Contains(table31Output, key), err
+	table31Output, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=31"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+	if err != nil {
+		return false, err
+	}
+	for _, key := range keys {
+		if !strings.Contains(table31Output, key) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// proxyIPv6Enabled checks that AntreaProxy is enabled and that IPv6 packets are sent to the
+// ConntrackState table (31) with NAT, where they go through the same resubmit chain as IPv4
+// packets.
+func proxyIPv6Enabled(data *TestData) (bool, error) {
+	if enabled, err := proxyEnabled(data); err != nil || !enabled {
+		return enabled, err
+	}
+	key := "ipv6 actions=ct(table=31,zone=65520,nat)"
+	agentName, err := data.getAntreaPodOnNode(masterNodeName())
+	if err != nil {
+		return false, err
+	}
+	table30Output, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=30"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+	return strings.Contains(table30Output, key), err
+}
+
+func skipIfEndpointSliceAPIUnavailable(t *testing.T, data *TestData) {
+	resources, err := data.clientset.Discovery().ServerResourcesForGroupVersion("discovery.k8s.io/v1beta1")
+	if err != nil || len(resources.APIResources) == 0 {
+		t.Skipf("Skipping test as the EndpointSlice API is not available: %v", err)
+	}
+}
+
+// setEndpointSliceFeature enables or disables the EndpointSlice feature in the antrea-agent
+// ConfigMap, and restarts the antrea-agent Pods for the change to take effect.
+func (data *TestData) setEndpointSliceFeature(enabled bool) error {
+	disabledLine, enabledLine := "#  EndpointSlice: false", "  EndpointSlice: true"
+	if enabled {
+		return data.replaceAntreaAgentConfLine(disabledLine, enabledLine)
+	}
+	return data.replaceAntreaAgentConfLine(enabledLine, disabledLine)
+}
+
+// setFastFailoverGroups makes AntreaProxy program fast-failover groups instead of select groups,
+// or the opposite, and restarts the antrea-agent Pods for the change to take effect.
+func (data *TestData) setFastFailoverGroups(enabled bool) error {
+	disabledLine, enabledLine := "#fastFailoverGroups: false", "fastFailoverGroups: true"
+	if enabled {
+		return data.replaceAntreaAgentConfLine(disabledLine, enabledLine)
+	}
+	return data.replaceAntreaAgentConfLine(enabledLine, disabledLine)
+}
+
+// replaceAntreaAgentConfLine replaces a line of the antrea-agent configuration in the antrea
+// ConfigMap, and restarts the antrea-agent Pods for the change to take effect.
+func (data *TestData) replaceAntreaAgentConfLine(oldLine, newLine string) error {
+	configMap, err := data.GetAntreaConfigMap(antreaNamespace)
+	if err != nil {
+		return err
+	}
+	configMap.Data["antrea-agent.conf"] = strings.Replace(configMap.Data["antrea-agent.conf"], oldLine, newLine, 1)
+	if _, err := data.clientset.CoreV1().ConfigMaps(antreaNamespace).Update(context.TODO(), configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s: %v", configMap.Name, err)
+	}
+	if err := data.restartAntreaAgentPods(defaultTimeout); err != nil {
+		return fmt.Errorf("error when restarting antrea-agent Pods: %v", err)
+	}
+	return nil
+}
+
+// endpointIPRegValue returns the value that is loaded into NXM_NX_REG3 for the Endpoint IP, as
+// displayed by ovs-ofctl. For an IPv6 Endpoint, REG3 stores the lower 32 bits of the address.
+func endpointIPRegValue(endpointIP string) string {
+	ip := net.ParseIP(endpointIP)
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return strings.TrimLeft(hex.EncodeToString(ipv4), "0")
+	}
+	return strings.TrimLeft(hex.EncodeToString(ip.To16()[12:]), "0")
+}
+
+// serviceIPKeyword returns the match string of a flow matching the destination IP and port of a
+// Service, as displayed by ovs-ofctl.
+func serviceIPKeyword(clusterIP string, port int) string {
+	if utilnet.IsIPv6String(clusterIP) {
+		return fmt.Sprintf("ipv6_dst=%s,tp_dst=%d", clusterIP, port)
+	}
+	return fmt.Sprintf("nw_dst=%s,tp_dst=%d", clusterIP, port)
+}
+
+// waitForNginxIP waits for the nginx Pod to get an IP address from the provided IP family.
+func waitForNginxIP(data *TestData, ipFamily v1.IPFamily) (string, error) {
+	if ipFamily == v1.IPv6Protocol {
+		return data.podWaitForIPv6(defaultTimeout, "nginx", testNamespace)
+	}
+	return data.podWaitForIP(defaultTimeout, "nginx", testNamespace)
 }
 
 func TestProxyServiceSessionAffinity(t *testing.T) {
 	skipIfProviderIs(t, "kind", "#881 Does not work in Kind, needs to be investigated.")
+	skipIfNotIPv4Cluster(t)
+	testProxyServiceSessionAffinity(t, v1.IPv4Protocol)
+}
+
+func TestProxyServiceSessionAffinityIPv6(t *testing.T) {
+	skipIfProviderIs(t, "kind", "#881 Does not work in Kind, needs to be investigated.")
+	skipIfNotIPv6Cluster(t)
+	testProxyServiceSessionAffinity(t, v1.IPv6Protocol)
+}
+
+func testProxyServiceSessionAffinity(t *testing.T, ipFamily v1.IPFamily) {
 	data, err := setupTest(t)
 	if err != nil {
 		t.Fatalf("Error when setting up test: %v", err)
 	}
 	defer teardownTest(t, data)
 
-	skipIfProxyDisabled(t, data)
+	if ipFamily == v1.IPv6Protocol {
+		skipIfProxyIPv6Disabled(t, data)
+	} else {
+		skipIfProxyDisabled(t, data)
+	}
 
 	nodeName := nodeName(1)
 	require.NoError(t, data.createNginxPod("nginx", nodeName))
-	nginxIP, err := data.podWaitForIP(defaultTimeout, "nginx", testNamespace)
+	nginxIP, err := waitForNginxIP(data, ipFamily)
 	require.NoError(t, err)
 	require.NoError(t, data.podWaitForRunning(defaultTimeout, "nginx", testNamespace))
-	svc, err := data.createNginxService(true)
+	svc, err := data.createNginxServiceWithIPFamily(true, ipFamily)
 	require.NoError(t, err)
 	require.NoError(t, data.createBusyboxPodOnNode("busybox", nodeName))
 	require.NoError(t, data.podWaitForRunning(defaultTimeout, "busybox", testNamespace))
-	stdout, stderr, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"wget", "-O", "-", svc.Spec.ClusterIP, "-T", "1"})
+	if ipFamily == v1.IPv4Protocol {
+		// "ip route show" only lists IPv4 routes: the default route of the Pod must point to the
+		// gateway of the Node's Pod CIDR.
+		netNS, err := data.getPodNetNS(testNamespace, "busybox")
+		require.NoError(t, err)
+		gatewayIP, err := data.getNodeGatewayIP(nodeName, ipFamily)
+		require.NoError(t, err)
+		defaultRoute := netNS.defaultRoute()
+		require.NotNil(t, defaultRoute, "No default route in busybox Pod")
+		assert.True(t, gatewayIP.Equal(defaultRoute.gateway), "Default route of busybox Pod is via %s instead of %s", defaultRoute.gateway, gatewayIP)
+		assert.Equal(t, podInterfaceName, defaultRoute.device)
+	}
+	stdout, stderr, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"wget", "-O", "-", net.JoinHostPort(svc.Spec.ClusterIP, "80"), "-T", "1"})
 	require.NoError(t, err, fmt.Sprintf("stdout: %s\n, stderr: %s", stdout, stderr))
 	agentName, err := data.getAntreaPodOnNode(nodeName)
 	require.NoError(t, err)
-	table40Output, _, err := data.runCommandFromPod(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=40"})
+	table40Output, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=40"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+	require.NoError(t, err)
+	require.Contains(t, table40Output, serviceIPKeyword(svc.Spec.ClusterIP, 80))
+	require.Contains(t, table40Output, fmt.Sprintf("load:0x%s->NXM_NX_REG3[]", endpointIPRegValue(nginxIP)))
+}
+
+func TestProxySessionAffinityTimeout(t *testing.T) {
+	skipIfProviderIs(t, "kind", "#881 Does not work in Kind, needs to be investigated.")
+	skipIfNotIPv4Cluster(t)
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	skipIfProxyDisabled(t, data)
+
+	nodeName := nodeName(1)
+	// agnhost netexec replies to "/hostname" with the name of the Pod, which
+	// identifies the Endpoint selected for a connection.
+	for _, podName := range []string{"server-0", "server-1"} {
+		err = data.createPodOnNode(podName, nodeName, "gcr.io/kubernetes-e2e-test-images/agnhost:2.8", []string{"/agnhost", "netexec", "--http-port=80"}, nil, nil, []v1.ContainerPort{{ContainerPort: 80, Protocol: v1.ProtocolTCP}})
+		require.NoError(t, err)
+		require.NoError(t, data.podWaitForRunning(defaultTimeout, podName, testNamespace))
+	}
+	svc, err := data.createService("server", 80, 80, map[string]string{"app": "agnhost"}, true)
+	require.NoError(t, err)
+	require.NoError(t, data.createBusyboxPodOnNode("busybox", nodeName))
+	require.NoError(t, data.podWaitForRunning(defaultTimeout, "busybox", testNamespace))
+
+	// Update the timeout of the live Service, the learn flow must be
+	// reinstalled with the new idle timeout.
+	timeoutSeconds := int32(5)
+	svc.Spec.SessionAffinityConfig = &v1.SessionAffinityConfig{ClientIP: &v1.ClientIPConfig{TimeoutSeconds: &timeoutSeconds}}
+	svc, err = data.clientset.CoreV1().Services(testNamespace).Update(context.TODO(), svc, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	err = data.waitForOVSFlow(ctx, t, nodeName, defaultBridgeName, "41", fmt.Sprintf("learn(table=40,idle_timeout=%d,", timeoutSeconds), true)
+	require.NoError(t, err, "Learn flow was not updated with the new session affinity timeout")
+
+	getHostname := func() string {
+		stdout, stderr, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"wget", "-O", "-", fmt.Sprintf("http://%s/hostname", net.JoinHostPort(svc.Spec.ClusterIP, "80")), "-T", "1"})
+		require.NoError(t, err, fmt.Sprintf("stdout: %s\n, stderr: %s", stdout, stderr))
+		return strings.TrimSpace(stdout)
+	}
+	firstEndpoint := getHostname()
+	require.Equal(t, firstEndpoint, getHostname(), "Requests within the session affinity timeout should be sent to the same Endpoint")
+	// Once the timeout has expired, the learned flow is removed and a new
+	// Endpoint is selected randomly, so several attempts may be needed before
+	// a different one is picked.
+	waitForLearnedFlowExpiry := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		defer cancel()
+		return data.waitForOVSFlow(ctx, t, nodeName, defaultBridgeName, "40", serviceIPKeyword(svc.Spec.ClusterIP, 80), false)
+	}
+	endpointChanged := false
+	for i := 0; i < 10 && !endpointChanged; i++ {
+		require.NoError(t, waitForLearnedFlowExpiry(), "Learned flow was not removed after the session affinity timeout expired")
+		endpointChanged = getHostname() != firstEndpoint
+	}
+	require.True(t, endpointChanged, "Endpoint was not changed after the session affinity timeout expired")
+}
+
+// TestProxyMultiPortSessionAffinity verifies that session affinity is tracked
+// independently for each port of a multi-port Service: the learned flows match
+// the destination port, so the Endpoint selected for one port does not
+// constrain the Endpoint selected for another port.
+func TestProxyMultiPortSessionAffinity(t *testing.T) {
+	skipIfProviderIs(t, "kind", "#881 Does not work in Kind, needs to be investigated.")
+	skipIfNotIPv4Cluster(t)
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	skipIfProxyDisabled(t, data)
+
+	nodeName := nodeName(1)
+	for _, podName := range []string{"server-0", "server-1"} {
+		err = data.createPodOnNode(podName, nodeName, "gcr.io/kubernetes-e2e-test-images/agnhost:2.8", []string{"/agnhost", "netexec", "--http-port=80"}, nil, nil, []v1.ContainerPort{{ContainerPort: 80, Protocol: v1.ProtocolTCP}})
+		require.NoError(t, err)
+		require.NoError(t, data.podWaitForRunning(defaultTimeout, podName, testNamespace))
+	}
+	ports := []v1.ServicePort{
+		{Name: "http", Port: 80, TargetPort: intstr.FromInt(80), Protocol: v1.ProtocolTCP},
+		{Name: "http-alt", Port: 81, TargetPort: intstr.FromInt(80), Protocol: v1.ProtocolTCP},
+	}
+	svc, err := data.createServiceWithPorts("server", ports, map[string]string{"app": "agnhost"}, true)
+	require.NoError(t, err)
+	require.NoError(t, data.createBusyboxPodOnNode("busybox", nodeName))
+	require.NoError(t, data.podWaitForRunning(defaultTimeout, "busybox", testNamespace))
+
+	getHostname := func(port string) string {
+		stdout, stderr, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"wget", "-O", "-", fmt.Sprintf("http://%s/hostname", net.JoinHostPort(svc.Spec.ClusterIP, port)), "-T", "1"})
+		require.NoError(t, err, fmt.Sprintf("stdout: %s\n, stderr: %s", stdout, stderr))
+		return strings.TrimSpace(stdout)
+	}
+	for _, port := range []string{"80", "81"} {
+		endpoint := getHostname(port)
+		for i := 0; i < 5; i++ {
+			require.Equal(t, endpoint, getHostname(port), "Requests to port %s should be sent to the same Endpoint", port)
+		}
+	}
+
+	agentName, err := data.getAntreaPodOnNode(nodeName)
+	require.NoError(t, err)
+	table40Output, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=40"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
 	require.NoError(t, err)
-	require.Contains(t, table40Output, fmt.Sprintf("nw_dst=%s,tp_dst=80", svc.Spec.ClusterIP))
-	require.Contains(t, table40Output, fmt.Sprintf("load:0x%s->NXM_NX_REG3[]", strings.TrimLeft(hex.EncodeToString(net.ParseIP(nginxIP).To4()), "0")))
+	require.Contains(t, table40Output, serviceIPKeyword(svc.Spec.ClusterIP, 80))
+	require.Contains(t, table40Output, serviceIPKeyword(svc.Spec.ClusterIP, 81))
 }
 
 func TestProxyHairpin(t *testing.T) {
@@ -87,13 +384,110 @@ func TestProxyHairpin(t *testing.T) {
 	err = data.createPodOnNode("busybox", nodeName, "busybox", []string{"nc", "-lk", "-p", "80"}, nil, nil, []v1.ContainerPort{{ContainerPort: 80, Protocol: v1.ProtocolTCP}})
 	require.NoError(t, err)
 	require.NoError(t, data.podWaitForRunning(defaultTimeout, "busybox", testNamespace))
+	podIP, err := data.podWaitForIP(defaultTimeout, "busybox", testNamespace)
+	require.NoError(t, err)
 	svc, err := data.createService("busybox", 80, 80, map[string]string{"antrea-e2e": "busybox"}, false)
 	require.NoError(t, err)
 	stdout, stderr, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"nc", svc.Spec.ClusterIP, "80", "-w", "1", "-e", "ls", "/"})
 	require.NoError(t, err, fmt.Sprintf("stdout: %s\n, stderr: %s", stdout, stderr))
+
+	// Hairpin packets, whose source and destination are both the local
+	// Endpoint after DNAT, are SNATed to the virtual hairpin IP in the
+	// HairpinSNATTable, so that the replies are sent back to OVS.
+	if net.ParseIP(podIP).To4() != nil {
+		agentName, err := data.getAntreaPodOnNode(nodeName)
+		require.NoError(t, err)
+		table106Output, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=106"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+		require.NoError(t, err)
+		require.Regexp(t, fmt.Sprintf(`ip,nw_src=%s,nw_dst=%s actions=mod_nw_src:169.254.169.252,`, podIP, podIP), table106Output)
+	}
 }
 
-func TestProxyEndpointLifeCycle(t *testing.T) {
+func TestProxyUDPService(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	skipIfProxyDisabled(t, data)
+
+	nodeName := nodeName(1)
+	// agnhost netexec replies to the "echo <msg>" UDP command with <msg>.
+	err = data.createPodOnNode("udp-server", nodeName, "gcr.io/kubernetes-e2e-test-images/agnhost:2.8", []string{"/agnhost", "netexec", "--http-port=8080", "--udp-port=80"}, nil, nil, []v1.ContainerPort{{ContainerPort: 80, Protocol: v1.ProtocolUDP}})
+	require.NoError(t, err)
+	serverIP, err := data.podWaitForIP(defaultTimeout, "udp-server", testNamespace)
+	require.NoError(t, err)
+	svc, err := data.createServiceWithProtocol("udp-server", 80, 80, v1.ProtocolUDP, map[string]string{"antrea-e2e": "udp-server"}, false)
+	require.NoError(t, err)
+	require.NoError(t, data.createBusyboxPodOnNode("busybox", nodeName))
+	require.NoError(t, data.podWaitForRunning(defaultTimeout, "busybox", testNamespace))
+
+	cmd := fmt.Sprintf("echo 'echo hello' | nc -u -w 1 %s 80", svc.Spec.ClusterIP)
+	stdout, stderr, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"sh", "-c", cmd})
+	require.NoError(t, err, fmt.Sprintf("stdout: %s\n, stderr: %s", stdout, stderr))
+	require.Contains(t, stdout, "hello")
+
+	agentName, err := data.getAntreaPodOnNode(nodeName)
+	require.NoError(t, err)
+	table41Output, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=41"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+	require.NoError(t, err)
+	require.Regexp(t, fmt.Sprintf(`udp,.*%s`, serviceIPKeyword(svc.Spec.ClusterIP, 80)), table41Output)
+	table42Output, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=42"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+	require.NoError(t, err)
+	require.Contains(t, table42Output, fmt.Sprintf("nat(dst=%s:80)", serverIP))
+}
+
+func TestProxySCTPService(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	skipIfProxyDisabled(t, data)
+
+	nodeName := nodeName(1)
+	// The nc applet of busybox doesn't support SCTP, ncat from the perftool
+	// image is used for both the server and the client.
+	err = data.createPodOnNode("sctp-server", nodeName, perftoolImage, []string{"ncat", "--sctp", "-lk", "80", "-e", "/bin/cat"}, nil, nil, []v1.ContainerPort{{ContainerPort: 80, Protocol: v1.ProtocolSCTP}})
+	require.NoError(t, err)
+	serverIP, err := data.podWaitForIP(defaultTimeout, "sctp-server", testNamespace)
+	require.NoError(t, err)
+	svc, err := data.createServiceWithProtocol("sctp-server", 80, 80, v1.ProtocolSCTP, map[string]string{"antrea-e2e": "sctp-server"}, false)
+	if err != nil {
+		// SCTP Services are rejected if the SCTPSupport feature gate of the
+		// K8s cluster is not enabled.
+		t.Skipf("Skipping test as SCTP Services are not supported by the cluster: %v", err)
+	}
+	err = data.createPodOnNode("sctp-client", nodeName, perftoolImage, []string{"sleep", "3600"}, nil, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, data.podWaitForRunning(defaultTimeout, "sctp-client", testNamespace))
+
+	// Keep the association open for a while so that the echoed message can be
+	// received before ncat exits.
+	cmd := fmt.Sprintf("(echo hello; sleep 1) | ncat --sctp %s 80", svc.Spec.ClusterIP)
+	stdout, stderr, err := data.runCommandFromPod(testNamespace, "sctp-client", perftoolContainerName, []string{"bash", "-c", cmd})
+	require.NoError(t, err, fmt.Sprintf("stdout: %s\n, stderr: %s", stdout, stderr))
+	require.Contains(t, stdout, "hello")
+
+	agentName, err := data.getAntreaPodOnNode(nodeName)
+	require.NoError(t, err)
+	table41Output, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=41"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+	require.NoError(t, err)
+	require.Regexp(t, fmt.Sprintf(`sctp,.*%s`, serviceIPKeyword(svc.Spec.ClusterIP, 80)), table41Output)
+	table42Output, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=42"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+	require.NoError(t, err)
+	require.Regexp(t, fmt.Sprintf(`sctp,.*actions=ct\(commit,table=50,zone=65520,nat\(dst=%s:80\)`, strings.ReplaceAll(serverIP, ".", `\.`)), table42Output)
+}
+
+// TestProxyNodePort checks that AntreaProxy serves the traffic sent by a Pod to the NodePort of its
+// own Node. The NodePort traffic received on the uplink interface is served by kube-proxy, and is
+// not covered by this test.
+func TestProxyNodePort(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
 	data, err := setupTest(t)
 	if err != nil {
 		t.Fatalf("Error when setting up test: %v", err)
@@ -104,33 +498,526 @@ func TestProxyEndpointLifeCycle(t *testing.T) {
 
 	nodeName := nodeName(1)
 	require.NoError(t, data.createNginxPod("nginx", nodeName))
+	require.NoError(t, data.podWaitForRunning(defaultTimeout, "nginx", testNamespace))
+	svc, err := data.createNginxNodePortService(v1.ServiceExternalTrafficPolicyTypeCluster)
+	require.NoError(t, err)
+	nodePort := int(svc.Spec.Ports[0].NodePort)
+	require.NoError(t, data.createBusyboxPodOnNode("busybox", nodeName))
+	require.NoError(t, data.podWaitForRunning(defaultTimeout, "busybox", testNamespace))
+	nodeIP, err := data.getNodeInternalIP(nodeName)
+	require.NoError(t, err)
+	stdout, stderr, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"wget", "-O", "-", net.JoinHostPort(nodeIP, fmt.Sprint(nodePort)), "-T", "1"})
+	require.NoError(t, err, fmt.Sprintf("stdout: %s\n, stderr: %s", stdout, stderr))
+
+	agentName, err := data.getAntreaPodOnNode(nodeName)
+	require.NoError(t, err)
+	keyword := serviceIPKeyword(nodeIP, nodePort)
+	table41Output, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=41"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+	require.NoError(t, err)
+	require.Contains(t, table41Output, keyword)
+
+	require.NoError(t, data.deleteService("nginx"))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, data.waitForOVSFlow(ctx, t, nodeName, defaultBridgeName, "41", keyword, false))
+}
+
+func TestProxyNodePortLocal(t *testing.T) {
+	skipIfNumNodesLessThan(t, 2)
+	skipIfNotIPv4Cluster(t)
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	skipIfProxyDisabled(t, data)
+
+	// The backend Pod runs on Node 1, and no backend Pod runs on the master Node.
+	backendNodeName := nodeName(1)
+	require.NoError(t, data.createNginxPod("nginx", backendNodeName))
 	nginxIP, err := data.podWaitForIP(defaultTimeout, "nginx", testNamespace)
 	require.NoError(t, err)
-	_, err = data.createNginxService(false)
+	svc, err := data.createNginxNodePortService(v1.ServiceExternalTrafficPolicyTypeLocal)
+	require.NoError(t, err)
+	nodePort := int(svc.Spec.Ports[0].NodePort)
+	require.NoError(t, data.createBusyboxPodOnNode("busybox", backendNodeName))
+	require.NoError(t, data.podWaitForRunning(defaultTimeout, "busybox", testNamespace))
+
+	// dumpNodePortGroup checks that the NodePort flow of the Node does not use the group of the
+	// ClusterIP, and returns the group it uses, as displayed by ovs-ofctl.
+	dumpNodePortGroup := func(nodeName, nodeIP string) string {
+		agentName, err := data.getAntreaPodOnNode(nodeName)
+		require.NoError(t, err)
+		table41Output, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=41"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+		require.NoError(t, err)
+		var nodePortGroupID, clusterIPGroupID string
+		for _, flow := range strings.Split(table41Output, "\n") {
+			match := flowGroupRegexp.FindStringSubmatch(flow)
+			if match == nil {
+				continue
+			}
+			if strings.Contains(flow, serviceIPKeyword(nodeIP, nodePort)) {
+				nodePortGroupID = match[1]
+			} else if strings.Contains(flow, serviceIPKeyword(svc.Spec.ClusterIP, 80)) {
+				clusterIPGroupID = match[1]
+			}
+		}
+		require.NotEmpty(t, nodePortGroupID, "NodePort flow not found on Node %s: %s", nodeName, table41Output)
+		require.NotEqual(t, clusterIPGroupID, nodePortGroupID, "NodePort flow on Node %s should use the node-local group", nodeName)
+		groupOutput, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-groups", defaultBridgeName, nodePortGroupID}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+		require.NoError(t, err)
+		return groupOutput
+	}
+	bucketKeyword := fmt.Sprintf("load:0x%s->NXM_NX_REG3[]", endpointIPRegValue(nginxIP))
+
+	backendNodeIP, err := data.getNodeInternalIP(backendNodeName)
+	require.NoError(t, err)
+	stdout, stderr, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"wget", "-O", "-", net.JoinHostPort(backendNodeIP, fmt.Sprint(nodePort)), "-T", "1"})
+	require.NoError(t, err, fmt.Sprintf("stdout: %s\n, stderr: %s", stdout, stderr))
+	groupOutput := dumpNodePortGroup(backendNodeName, backendNodeIP)
+	assert.Contains(t, groupOutput, bucketKeyword, "Node-local group should contain the local Endpoint")
+
+	// The traffic sent to a Node without local Endpoints must not be forwarded to another Node.
+	// A Pod connecting to the NodePort of another Node would be handled by kube-proxy on that
+	// Node, not by AntreaProxy, so the check is done on the OVS group of that Node instead.
+	emptyNodeIP, err := data.getNodeInternalIP(masterNodeName())
 	require.NoError(t, err)
+	groupOutput = dumpNodePortGroup(masterNodeName(), emptyNodeIP)
+	assert.NotContains(t, groupOutput, bucketKeyword, "Node-local group of a Node without local Endpoints should not contain the remote Endpoint")
+	assert.NotContains(t, groupOutput, "bucket", "Node-local group of a Node without local Endpoints should have no bucket")
+}
+
+func TestProxyLoadBalancerService(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	skipIfProxyDisabled(t, data)
+
+	serverNodeName := nodeName(1)
+	require.NoError(t, data.createNginxPod("nginx", serverNodeName))
+	_, err = data.podWaitForIP(defaultTimeout, "nginx", testNamespace)
+	require.NoError(t, err)
+	_, err = data.createLoadBalancerService("nginx", 80, 80, v1.ProtocolTCP, map[string]string{"app": "nginx"}, v1.ServiceExternalTrafficPolicyTypeCluster)
+	require.NoError(t, err)
+	// There is no cloud controller in the test cluster, the ingress IP is
+	// assigned by updating the status of the Service directly.
+	ingressIP := "192.0.2.100"
+	_, err = data.setServiceLoadBalancerIngressIPs("nginx", ingressIP)
+	require.NoError(t, err)
+	// The client runs on a different Node than the Endpoint, all Nodes must
+	// load-balance the traffic sent to the ingress IP in Cluster mode.
+	clientNodeName := nodeName(0)
+	require.NoError(t, data.createBusyboxPodOnNode("busybox", clientNodeName))
+	require.NoError(t, data.podWaitForRunning(defaultTimeout, "busybox", testNamespace))
+
+	agentName, err := data.getAntreaPodOnNode(clientNodeName)
+	require.NoError(t, err)
+	err = wait.PollImmediate(time.Second, defaultTimeout, func() (bool, error) {
+		table41Output, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=41"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+		if err != nil {
+			return false, err
+		}
+		return strings.Contains(table41Output, serviceIPKeyword(ingressIP, 80)), nil
+	})
+	require.NoError(t, err, "Flow of the ingress IP was not installed")
+	stdout, stderr, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"wget", "-O", "-", net.JoinHostPort(ingressIP, "80"), "-T", "1"})
+	require.NoError(t, err, fmt.Sprintf("stdout: %s\n, stderr: %s", stdout, stderr))
+
+	// The flow must be removed once the ingress IP is released.
+	_, err = data.setServiceLoadBalancerIngressIPs("nginx")
+	require.NoError(t, err)
+	err = wait.PollImmediate(time.Second, defaultTimeout, func() (bool, error) {
+		table41Output, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=41"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+		if err != nil {
+			return false, err
+		}
+		return !strings.Contains(table41Output, serviceIPKeyword(ingressIP, 80)), nil
+	})
+	require.NoError(t, err, "Flow of the ingress IP was not removed")
+}
+
+func TestProxyGracefulTermination(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	skipIfProxyDisabled(t, data)
+
+	nodeName := nodeName(1)
+	// The nc process runs as PID 1 and ignores SIGTERM, so the server keeps
+	// running until the grace period of the Pod expires.
+	err = data.createPodOnNode("server", nodeName, "busybox", []string{"nc", "-lk", "-p", "80", "-e", "cat"}, nil, nil, []v1.ContainerPort{{ContainerPort: 80, Protocol: v1.ProtocolTCP}})
+	require.NoError(t, err)
+	serverIP, err := data.podWaitForIP(defaultTimeout, "server", testNamespace)
+	require.NoError(t, err)
+	svc, err := data.createService("server", 80, 80, map[string]string{"antrea-e2e": "server"}, false)
+	require.NoError(t, err)
+	require.NoError(t, data.createBusyboxPodOnNode("busybox", nodeName))
+	require.NoError(t, data.podWaitForRunning(defaultTimeout, "busybox", testNamespace))
+
+	// Send a message every second over a single long-lived connection, every
+	// message is echoed back by the server.
+	streamSeconds := 15
+	cmd := fmt.Sprintf("for i in $(seq %d); do echo message-$i; sleep 1; done | nc %s 80 | tee /tmp/stream", streamSeconds, svc.Spec.ClusterIP)
+	type result struct {
+		stdout, stderr string
+		err            error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		stdout, stderr, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"sh", "-c", cmd})
+		resultCh <- result{stdout, stderr, err}
+	}()
+	require.NoError(t, waitForBusyboxOutput(data, "/tmp/stream"), "No message was echoed back by the server")
+
+	// Delete the server Pod while the connection is open, the grace period is
+	// longer than the remaining duration of the stream.
+	gracePeriodSeconds := int64(60)
+	err = data.clientset.CoreV1().Pods(testNamespace).Delete(context.TODO(), "server", metav1.DeleteOptions{GracePeriodSeconds: &gracePeriodSeconds})
+	require.NoError(t, err)
+
+	// The Endpoint must be removed from the group while its NAT flow is kept.
 	agentName, err := data.getAntreaPodOnNode(nodeName)
 	require.NoError(t, err)
+	err = wait.PollImmediate(time.Second, 10*time.Second, func() (bool, error) {
+		groupOutput, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-groups", defaultBridgeName}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+		if err != nil {
+			return false, err
+		}
+		return !strings.Contains(groupOutput, fmt.Sprintf("load:0x%s->NXM_NX_REG3[]", endpointIPRegValue(serverIP))), nil
+	})
+	require.NoError(t, err, "Terminating Endpoint was not removed from the group")
+	table42Output, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=42"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+	require.NoError(t, err)
+	require.Contains(t, table42Output, fmt.Sprintf("nat(dst=%s:80)", serverIP), "NAT flow of the terminating Endpoint was removed before the drain period expired")
+
+	res := <-resultCh
+	require.NoError(t, res.err, fmt.Sprintf("stdout: %s\n, stderr: %s", res.stdout, res.stderr))
+	require.Contains(t, res.stdout, fmt.Sprintf("message-%d", streamSeconds), "Connection was interrupted by the termination of the Endpoint")
+}
 
-	keywords := map[int]string{
-		42: fmt.Sprintf("nat(dst=%s:80)", nginxIP), // endpointNATTable
+func TestProxyEndpointLifeCycle(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+	testProxyEndpointLifeCycle(t, v1.IPv4Protocol, false, false)
+}
+
+func TestProxyEndpointLifeCycleIPv6(t *testing.T) {
+	skipIfNotIPv6Cluster(t)
+	testProxyEndpointLifeCycle(t, v1.IPv6Protocol, false, false)
+}
+
+// TestProxyEndpointLifeCycleEndpointSlice runs the same test as TestProxyEndpointLifeCycle, with
+// AntreaProxy tracking Endpoints from the EndpointSlice API instead of the Endpoints API.
+func TestProxyEndpointLifeCycleEndpointSlice(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+	testProxyEndpointLifeCycle(t, v1.IPv4Protocol, true, false)
+}
+
+// TestProxyEndpointLifeCycleFastFailover runs the same test as TestProxyEndpointLifeCycle, with
+// AntreaProxy programming fast-failover groups instead of select groups. It also checks that the
+// traffic fails over to the other Endpoint within a second after the deleted one is removed.
+func TestProxyEndpointLifeCycleFastFailover(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+	testProxyEndpointLifeCycle(t, v1.IPv4Protocol, false, true)
+}
+
+func testProxyEndpointLifeCycle(t *testing.T, ipFamily v1.IPFamily, useEndpointSlice, useFastFailoverGroups bool) {
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	if ipFamily == v1.IPv6Protocol {
+		skipIfProxyIPv6Disabled(t, data)
+	} else {
+		skipIfProxyDisabled(t, data)
+	}
+	if useEndpointSlice {
+		skipIfEndpointSliceAPIUnavailable(t, data)
+		require.NoError(t, data.setEndpointSliceFeature(true))
+		defer func() {
+			if err := data.setEndpointSliceFeature(false); err != nil {
+				t.Errorf("Error when disabling EndpointSlice feature: %v", err)
+			}
+		}()
+	}
+	if useFastFailoverGroups {
+		require.NoError(t, data.setFastFailoverGroups(true))
+		defer func() {
+			if err := data.setFastFailoverGroups(false); err != nil {
+				t.Errorf("Error when disabling fast-failover groups: %v", err)
+			}
+		}()
+	}
+
+	nodeName := nodeName(1)
+	// busybox does not handle SIGTERM when running as PID 1, so a deleted
+	// server keeps serving until it is killed at the end of its termination
+	// grace period. A failed request can then only be caused by the flows of
+	// AntreaProxy. Each server replies with its name.
+	serverIPs := map[string]string{}
+	for _, serverName := range []string{"server-1", "server-2"} {
+		serverCmd := fmt.Sprintf("echo %s > /tmp/index.html && httpd -f -p 80 -h /tmp", serverName)
+		require.NoError(t, data.createPodOnNode(serverName, nodeName, "busybox", []string{"sh", "-c", serverCmd}, nil, nil, []v1.ContainerPort{{ContainerPort: 80, Protocol: v1.ProtocolTCP}}))
+		require.NoError(t, data.podWaitForRunning(defaultTimeout, serverName, testNamespace))
+		// The client Pod has the same "app" label, the servers are selected
+		// by another one.
+		require.NoError(t, data.addPodLabels(serverName, map[string]string{"proxy-endpoint": "true"}))
+		if ipFamily == v1.IPv6Protocol {
+			serverIPs[serverName], err = data.podWaitForIPv6(defaultTimeout, serverName, testNamespace)
+		} else {
+			serverIPs[serverName], err = data.podWaitForIP(defaultTimeout, serverName, testNamespace)
+		}
+		require.NoError(t, err)
 	}
+	svc, err := data.createServiceWithIPFamily("server", 80, 80, v1.ProtocolTCP, map[string]string{"proxy-endpoint": "true"}, false, &ipFamily)
+	require.NoError(t, err)
+	require.NoError(t, data.createBusyboxPodOnNode("busybox", nodeName))
+	require.NoError(t, data.podWaitForRunning(defaultTimeout, "busybox", testNamespace))
+	agentName, err := data.getAntreaPodOnNode(nodeName)
+	require.NoError(t, err)
 
-	for tableID, keyword := range keywords {
-		tableOutput, _, err := data.runCommandFromPod(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, fmt.Sprintf("table=%d", tableID)})
+	svcURL := fmt.Sprintf("http://%s/", net.JoinHostPort(svc.Spec.ClusterIP, "80"))
+	deletedServer := "server-1"
+	if useFastFailoverGroups {
+		// A fast-failover group sends all the requests to its first live
+		// Endpoint, which is the one to delete.
+		deletedServer, err = requestServerName(data, svcURL)
 		require.NoError(t, err)
-		require.Contains(t, tableOutput, keyword)
+		require.Contains(t, serverIPs, deletedServer)
 	}
 
-	require.NoError(t, data.deletePodAndWait(defaultTimeout, "nginx"))
+	keyword := fmt.Sprintf("nat(dst=%s)", net.JoinHostPort(serverIPs[deletedServer], "80")) // endpointNATTable
+	dumpEndpointDNATFlows := func() string {
+		tableOutput, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=42"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+		require.NoError(t, err)
+		return tableOutput
+	}
+	require.Contains(t, dumpEndpointDNATFlows(), keyword)
 
-	for tableID, keyword := range keywords {
-		tableOutput, _, err := data.runCommandFromPod(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, fmt.Sprintf("table=%d", tableID)})
+	if useFastFailoverGroups {
+		failoverTime, err := measureFailoverTime(data, svc.Name, svcURL, deletedServer, serverIPs[deletedServer])
 		require.NoError(t, err)
-		require.NotContains(t, tableOutput, keyword)
+		t.Logf("The traffic failed over %v after the Endpoint of %s was removed", failoverTime, deletedServer)
+		assert.Less(t, int64(failoverTime), int64(time.Second), "The traffic did not fail over within a second")
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		require.NoError(t, data.waitForOVSFlow(ctx, t, nodeName, defaultBridgeName, "42", keyword, false), "The flows of the deleted Endpoint were not removed")
+		return
+	}
+
+	// Request the Service continuously while one of its Endpoints is
+	// deleted, no request must fail.
+	cmd := fmt.Sprintf("for i in $(seq 1 200); do wget -q -O /dev/null -T 1 %s || echo FAILED; echo $i > /tmp/requests; sleep 0.1; done", svcURL)
+	type result struct {
+		stdout string
+		err    error
 	}
+	resultCh := make(chan result, 1)
+	go func() {
+		stdout, _, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"sh", "-c", cmd})
+		resultCh <- result{stdout, err}
+	}()
+	require.NoError(t, waitForBusyboxOutput(data, "/tmp/requests"), "No request was sent to the Service")
+	require.NoError(t, data.deletePodAndWait(defaultTimeout, deletedServer))
+	res := <-resultCh
+	require.NoError(t, res.err)
+	require.NotContains(t, res.stdout, "FAILED", "Requests to the Service failed while one of its Endpoints was being deleted")
+
+	// The flows of the deleted Endpoint may be kept until the drain period
+	// expires.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	require.NoError(t, data.waitForOVSFlow(ctx, t, nodeName, defaultBridgeName, "42", keyword, false), "The flows of the deleted Endpoint were not removed")
 }
 
-func TestProxyServiceLifeCycle(t *testing.T) {
+// requestServerName requests the Service from the busybox Pod and returns the name of the
+// server which replied.
+func requestServerName(data *TestData, svcURL string) (string, error) {
+	stdout, _, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"wget", "-q", "-O", "-", "-T", "1", svcURL})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
+// waitForBusyboxOutput polls the busybox Pod until the file is not empty. It is used to wait for a
+// command running in the background in the Pod to have made progress, e.g. for a client to have
+// sent its first request.
+func waitForBusyboxOutput(data *TestData, path string) error {
+	return wait.PollImmediate(200*time.Millisecond, defaultTimeout, func() (bool, error) {
+		_, _, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"test", "-s", path})
+		return err == nil, nil
+	})
+}
+
+// measureFailoverTime deletes a server and returns how long after its Endpoint has been removed
+// from the Service the requests are answered by another server. No request must fail meanwhile.
+// The time is measured from the test, so it includes the latency of the requests.
+func measureFailoverTime(data *TestData, svcName, svcURL, serverName, serverIP string) (time.Duration, error) {
+	removedCh := make(chan time.Time, 1)
+	go func() {
+		wait.PollImmediate(50*time.Millisecond, defaultTimeout, func() (bool, error) {
+			endpoints, err := data.clientset.CoreV1().Endpoints(testNamespace).Get(context.TODO(), svcName, metav1.GetOptions{})
+			if err != nil {
+				return false, nil
+			}
+			for _, subset := range endpoints.Subsets {
+				for _, address := range subset.Addresses {
+					if address.IP == serverIP {
+						return false, nil
+					}
+				}
+			}
+			removedCh <- time.Now()
+			return true, nil
+		})
+	}()
+	if err := data.deletePod(serverName); err != nil {
+		return 0, err
+	}
+	var failedOverAt time.Time
+	if err := wait.PollImmediate(10*time.Millisecond, defaultTimeout, func() (bool, error) {
+		name, err := requestServerName(data, svcURL)
+		if err != nil {
+			return false, fmt.Errorf("request to the Service failed during the failover: %v", err)
+		}
+		if name == serverName {
+			return false, nil
+		}
+		failedOverAt = time.Now()
+		return true, nil
+	}); err != nil {
+		return 0, err
+	}
+	select {
+	case removedAt := <-removedCh:
+		return failedOverAt.Sub(removedAt), nil
+	case <-time.After(defaultTimeout):
+		return 0, fmt.Errorf("the Endpoint of %s was not removed from Service %s", serverName, svcName)
+	}
+}
+
+// TestProxyEndpointLifeCycleMulti checks that the group of a Service with multiple Endpoints is
+// updated incrementally: when the Endpoints are deleted one by one, only the bucket of the deleted
+// Endpoint is removed and the Service stays reachable through the remaining ones.
+func TestProxyEndpointLifeCycleMulti(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+	skipIfNumNodesLessThan(t, 2)
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	skipIfProxyDisabled(t, data)
+
+	// Like in testProxyEndpointLifeCycle, the servers are busybox Pods which keep serving until
+	// the end of their termination grace period.
+	serverNames := []string{"server-0", "server-1", "server-2"}
+	serverIPs := map[string]string{}
+	for idx, serverName := range serverNames {
+		serverNode := nodeName(idx % clusterInfo.numNodes)
+		require.NoError(t, data.createPodOnNode(serverName, serverNode, "busybox", []string{"sh", "-c", "echo ok > /tmp/index.html && httpd -f -p 80 -h /tmp"}, nil, nil, []v1.ContainerPort{{ContainerPort: 80, Protocol: v1.ProtocolTCP}}))
+		require.NoError(t, data.podWaitForRunning(defaultTimeout, serverName, testNamespace))
+		require.NoError(t, data.addPodLabels(serverName, map[string]string{"proxy-endpoint": "true"}))
+		serverIPs[serverName], err = data.podWaitForIP(defaultTimeout, serverName, testNamespace)
+		require.NoError(t, err)
+	}
+	svc, err := data.createService("server", 80, 80, map[string]string{"proxy-endpoint": "true"}, false)
+	require.NoError(t, err)
+	nodeName := nodeName(0)
+	require.NoError(t, data.createBusyboxPodOnNode("busybox", nodeName))
+	require.NoError(t, data.podWaitForRunning(defaultTimeout, "busybox", testNamespace))
+	agentName, err := data.getAntreaPodOnNode(nodeName)
+	require.NoError(t, err)
+
+	bucketKeyword := func(serverName string) string {
+		return fmt.Sprintf("load:0x%s->NXM_NX_REG3[]", endpointIPRegValue(serverIPs[serverName]))
+	}
+	// dumpServiceGroup returns the group of the Service, as displayed by ovs-ofctl.
+	dumpServiceGroup := func() (string, error) {
+		groups, err := getServiceLBGroups(data, agentName)
+		if err != nil {
+			return "", err
+		}
+		groupID, ok := groups[svc.Spec.ClusterIP]
+		if !ok {
+			return "", nil
+		}
+		groupOutput, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-groups", defaultBridgeName, groupID}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+		return groupOutput, err
+	}
+	// waitForBuckets waits until the group of the Service has exactly one bucket for each of the
+	// provided servers.
+	waitForBuckets := func(expected []string) error {
+		expectedSet := sets.NewString(expected...)
+		var groupOutput string
+		err := wait.PollImmediate(time.Second, 10*time.Second, func() (bool, error) {
+			var err error
+			if groupOutput, err = dumpServiceGroup(); err != nil {
+				return false, err
+			}
+			for _, serverName := range serverNames {
+				expectedCount := 0
+				if expectedSet.Has(serverName) {
+					expectedCount = 1
+				}
+				if strings.Count(groupOutput, bucketKeyword(serverName)) != expectedCount {
+					return false, nil
+				}
+			}
+			return true, nil
+		})
+		if err != nil {
+			return fmt.Errorf("buckets of Service group do not match Endpoints %v: %v - group: %s", expected, err, groupOutput)
+		}
+		return nil
+	}
+	require.NoError(t, waitForBuckets(serverNames))
+
+	svcURL := fmt.Sprintf("http://%s/", net.JoinHostPort(svc.Spec.ClusterIP, "80"))
+	for idx, serverName := range serverNames {
+		remaining := serverNames[idx+1:]
+		if len(remaining) == 0 {
+			require.NoError(t, data.deletePod(serverName))
+			require.NoError(t, waitForBuckets(remaining))
+			break
+		}
+		// Request the Service continuously while the Endpoint is deleted, no request must fail
+		// as there are remaining Endpoints.
+		progressFile := fmt.Sprintf("/tmp/requests-%s", serverName)
+		cmd := fmt.Sprintf("for i in $(seq 1 50); do wget -q -O /dev/null -T 1 %s || echo FAILED; echo $i > %s; sleep 0.1; done", svcURL, progressFile)
+		resultCh := make(chan error, 1)
+		go func() {
+			stdout, _, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"sh", "-c", cmd})
+			if err == nil && strings.Contains(stdout, "FAILED") {
+				err = fmt.Errorf("some requests failed")
+			}
+			resultCh <- err
+		}()
+		require.NoError(t, waitForBusyboxOutput(data, progressFile), "No request was sent to the Service")
+		t.Logf("Deleting Endpoint '%s'", serverName)
+		require.NoError(t, data.deletePod(serverName))
+		require.NoError(t, waitForBuckets(remaining))
+		assert.NoError(t, <-resultCh, "Requests to the Service failed while Endpoint '%s' was being deleted", serverName)
+	}
+}
+
+// TestProxyEndpointHealthCheck checks that, when active health checking is enabled for a Service,
+// the bucket of an Endpoint which stops accepting connections is removed from the group of the
+// Service, and that it is added back once the Endpoint recovers.
+func TestProxyEndpointHealthCheck(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
 	data, err := setupTest(t)
 	if err != nil {
 		t.Fatalf("Error when setting up test: %v", err)
@@ -139,38 +1026,370 @@ func TestProxyServiceLifeCycle(t *testing.T) {
 
 	skipIfProxyDisabled(t, data)
 
+	nodeName := nodeName(0)
+	serverNames := []string{"server-0", "server-1"}
+	serverIPs := map[string]string{}
+	for _, serverName := range serverNames {
+		require.NoError(t, data.createPodOnNode(serverName, nodeName, "busybox", []string{"sh", "-c", "echo ok > /tmp/index.html && httpd -f -p 80 -h /tmp"}, nil, nil, []v1.ContainerPort{{ContainerPort: 80, Protocol: v1.ProtocolTCP}}))
+		require.NoError(t, data.podWaitForRunning(defaultTimeout, serverName, testNamespace))
+		require.NoError(t, data.addPodLabels(serverName, map[string]string{"proxy-endpoint": "true"}))
+		serverIPs[serverName], err = data.podWaitForIP(defaultTimeout, serverName, testNamespace)
+		require.NoError(t, err)
+	}
+	svc, err := data.createService("server", 80, 80, map[string]string{"proxy-endpoint": "true"}, false)
+	require.NoError(t, err)
+	svc, err = data.clientset.CoreV1().Services(testNamespace).Get(context.TODO(), svc.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	if svc.Annotations == nil {
+		svc.Annotations = map[string]string{}
+	}
+	svc.Annotations["antrea.io/endpoint-healthcheck"] = "true"
+	_, err = data.clientset.CoreV1().Services(testNamespace).Update(context.TODO(), svc, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	agentName, err := data.getAntreaPodOnNode(nodeName)
+	require.NoError(t, err)
+
+	bucketKeyword := func(serverName string) string {
+		return fmt.Sprintf("load:0x%s->NXM_NX_REG3[]", endpointIPRegValue(serverIPs[serverName]))
+	}
+	// waitForBuckets waits until the group of the Service has exactly one bucket for each of the
+	// provided servers. Detecting a failure takes several probe intervals, hence the timeout.
+	waitForBuckets := func(expected ...string) error {
+		expectedSet := sets.NewString(expected...)
+		var groupOutput string
+		err := wait.PollImmediate(time.Second, 60*time.Second, func() (bool, error) {
+			groups, err := getServiceLBGroups(data, agentName)
+			if err != nil {
+				return false, err
+			}
+			groupID, ok := groups[svc.Spec.ClusterIP]
+			if !ok {
+				return false, nil
+			}
+			if groupOutput, _, err = data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-groups", defaultBridgeName, groupID}, ovsCommandMaxRetries, ovsCommandRetryDelay); err != nil {
+				return false, err
+			}
+			for _, serverName := range serverNames {
+				expectedCount := 0
+				if expectedSet.Has(serverName) {
+					expectedCount = 1
+				}
+				if strings.Count(groupOutput, bucketKeyword(serverName)) != expectedCount {
+					return false, nil
+				}
+			}
+			return true, nil
+		})
+		if err != nil {
+			return fmt.Errorf("buckets of Service group do not match healthy Endpoints %v: %v - group: %s", expected, err, groupOutput)
+		}
+		return nil
+	}
+	require.NoError(t, waitForBuckets(serverNames...))
+
+	// The probes are sent by the Antrea Agent from the host network namespace, dropping them in
+	// the OUTPUT chain makes server-0 look unhealthy without deleting the Pod.
+	iptablesRule := []string{"OUTPUT", "-d", serverIPs["server-0"], "-p", "tcp", "--dport", "80", "-j", "DROP"}
+	runIPTables := func(op string) error {
+		_, stderr, err := data.runCommandFromPod(metav1.NamespaceSystem, agentName, "antrea-agent", append([]string{"iptables", op}, iptablesRule...))
+		if err != nil {
+			return fmt.Errorf("error when running iptables %s: %v - stderr: %s", op, err, stderr)
+		}
+		return nil
+	}
+	require.NoError(t, runIPTables("-I"))
+	ruleDeleted := false
+	defer func() {
+		if !ruleDeleted {
+			runIPTables("-D")
+		}
+	}()
+	t.Logf("Blocked health check probes to Endpoint '%s'", serverIPs["server-0"])
+	require.NoError(t, waitForBuckets("server-1"))
+
+	require.NoError(t, runIPTables("-D"))
+	ruleDeleted = true
+	t.Logf("Unblocked health check probes to Endpoint '%s'", serverIPs["server-0"])
+	require.NoError(t, waitForBuckets(serverNames...))
+}
+
+func TestProxyServiceLifeCycle(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+	testProxyServiceLifeCycle(t, v1.IPv4Protocol)
+}
+
+func TestProxyServiceLifeCycleIPv6(t *testing.T) {
+	skipIfNotIPv6Cluster(t)
+	testProxyServiceLifeCycle(t, v1.IPv6Protocol)
+}
+
+func testProxyServiceLifeCycle(t *testing.T, ipFamily v1.IPFamily) {
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	if ipFamily == v1.IPv6Protocol {
+		skipIfProxyIPv6Disabled(t, data)
+	} else {
+		skipIfProxyDisabled(t, data)
+	}
+
 	nodeName := nodeName(1)
 	require.NoError(t, data.createNginxPod("nginx", nodeName))
-	nginxIP, err := data.podWaitForIP(defaultTimeout, "nginx", testNamespace)
+	nginxIP, err := waitForNginxIP(data, ipFamily)
 	require.NoError(t, err)
-	svc, err := data.createNginxService(false)
+	svc, err := data.createNginxServiceWithIPFamily(false, ipFamily)
+	require.NoError(t, err)
+	// Turn the Service into a LoadBalancer Service and assign it an ingress IP.
+	svc.Spec.Type = v1.ServiceTypeLoadBalancer
+	_, err = data.clientset.CoreV1().Services(testNamespace).Update(context.TODO(), svc, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	ingressIP := "192.0.2.100"
+	if ipFamily == v1.IPv6Protocol {
+		ingressIP = "2001:db8::100"
+	}
+	_, err = data.setServiceLoadBalancerIngressIPs("nginx", ingressIP)
 	require.NoError(t, err)
 	agentName, err := data.getAntreaPodOnNode(nodeName)
 	require.NoError(t, err)
 
-	keywords := map[int]string{
-		41: fmt.Sprintf("nw_dst=%s,tp_dst=80", svc.Spec.ClusterIP), // serviceLBTable
-		42: fmt.Sprintf("nat(dst=%s:80)", nginxIP),                 // endpointNATTable
+	keywords := map[string][]string{
+		"41": {serviceIPKeyword(svc.Spec.ClusterIP, 80), serviceIPKeyword(ingressIP, 80)}, // serviceLBTable
+		"42": {fmt.Sprintf("nat(dst=%s)", net.JoinHostPort(nginxIP, "80"))},               // endpointNATTable
 	}
-	groupKeyword := fmt.Sprintf("load:0x%s->NXM_NX_REG3[],load:0x%x->NXM_NX_REG4[0..15],load:0x2->NXM_NX_REG4[16..18]", strings.TrimLeft(string(hex.EncodeToString(net.ParseIP(nginxIP).To4())), "0"), 80)
-	groupOutput, _, err := data.runCommandFromPod(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-groups", defaultBridgeName})
+	// For an IPv6 Endpoint, the upper 96 bits of the address are loaded into REG12-REG14 before
+	// the lower 32 bits are loaded into REG3.
+	groupKeyword := fmt.Sprintf("load:0x%s->NXM_NX_REG3[],load:0x%x->NXM_NX_REG4[0..15],load:0x2->NXM_NX_REG4[16..18]", endpointIPRegValue(nginxIP), 80)
+	if ipFamily == v1.IPv6Protocol {
+		groupKeyword = fmt.Sprintf("->NXM_NX_REG14[],%s", groupKeyword)
+	}
+	// Each flow gets its own timeout.
+	waitForFlow := func(table, keyword string, present bool) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return data.waitForOVSFlow(ctx, t, nodeName, defaultBridgeName, table, keyword, present)
+	}
+	// The group is installed before the flows referencing it, and removed after them.
+	for table, tableKeywords := range keywords {
+		for _, keyword := range tableKeywords {
+			require.NoError(t, waitForFlow(table, keyword, true))
+		}
+	}
+	groupOutput, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-groups", defaultBridgeName}, ovsCommandMaxRetries, ovsCommandRetryDelay)
 	require.NoError(t, err)
 	require.Contains(t, groupOutput, groupKeyword)
-	for tableID, keyword := range keywords {
-		tableOutput, _, err := data.runCommandFromPod(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, fmt.Sprintf("table=%d", tableID)})
-		require.NoError(t, err)
-		require.Contains(t, tableOutput, keyword)
-	}
 
 	require.NoError(t, data.deleteService("nginx"))
-	time.Sleep(time.Second)
+	for table, tableKeywords := range keywords {
+		for _, keyword := range tableKeywords {
+			require.NoError(t, waitForFlow(table, keyword, false))
+		}
+	}
+	err = wait.PollImmediate(200*time.Millisecond, 10*time.Second, func() (bool, error) {
+		groupOutput, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-groups", defaultBridgeName}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+		if err != nil {
+			return false, err
+		}
+		return !strings.Contains(groupOutput, groupKeyword), nil
+	})
+	require.NoError(t, err, "Group of the deleted Service was not removed")
+}
+
+const (
+	stressNumServices = 100
+	// stressMaxInstallLatency is the maximum time allowed between the creation of a Service and
+	// the installation of its group in OVS.
+	stressMaxInstallLatency = 5 * time.Second
+)
+
+// serviceLBGroupRegexp matches the flows of the ServiceLB table for ClusterIPs on port 80 and
+// captures the ClusterIP and the ID of the group selecting the Endpoint.
+var serviceLBGroupRegexp = regexp.MustCompile(`nw_dst=([^,\s]+),tp_dst=80\s.*group:(\d+)`)
+
+// flowGroupRegexp matches the flows which send the packets to a group and captures the ID of the
+// group.
+var flowGroupRegexp = regexp.MustCompile(`group:(\d+)`)
+
+// runWithWorkerPool calls fn for each index in [0, n), with at most parallelism concurrent calls.
+// Errors returned by fn do not stop the other calls, they are aggregated in the returned error.
+func runWithWorkerPool(parallelism, n int, fn func(idx int) error) error {
+	idxCh := make(chan int)
+	errCh := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range idxCh {
+				if err := fn(idx); err != nil {
+					errCh <- err
+				}
+			}
+		}()
+	}
+	for idx := 0; idx < n; idx++ {
+		idxCh <- idx
+	}
+	close(idxCh)
+	wg.Wait()
+	close(errCh)
+	var errs []error
+	for err := range errCh {
+		errs = append(errs, err)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// getServiceLBGroups returns the ID of the group used to load-balance the traffic of each IPv4
+// ClusterIP on port 80, limited to the groups which are present in OVS.
+func getServiceLBGroups(data *TestData, agentName string) (map[string]string, error) {
+	flowOutput, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, "table=41"}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+	if err != nil {
+		return nil, err
+	}
+	groupOutput, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-groups", defaultBridgeName}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string]string)
+	for _, matches := range serviceLBGroupRegexp.FindAllStringSubmatch(flowOutput, -1) {
+		if strings.Contains(groupOutput, fmt.Sprintf("group_id=%s,", matches[2])) {
+			groups[matches[1]] = matches[2]
+		}
+	}
+	return groups, nil
+}
+
+// TestProxyServiceStress concurrently creates many ClusterIP Services, checks that their groups are
+// installed in OVS quickly enough and that they are all reachable, then concurrently deletes them
+// and checks that their groups are removed.
+func TestProxyServiceStress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping stress test in short mode")
+	}
+	skipIfNotIPv4Cluster(t)
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
 
-	groupOutput, _, err = data.runCommandFromPod(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-groups", defaultBridgeName})
+	skipIfProxyDisabled(t, data)
+
+	parallelism := testOptions.proxyStressParallelism
+	if parallelism <= 0 {
+		t.Fatalf("Invalid parallelism %d, it must be positive", parallelism)
+	}
+	nodeName := nodeName(1)
+	require.NoError(t, data.createNginxPod("nginx", nodeName))
+	_, err = data.podWaitForIP(defaultTimeout, "nginx", testNamespace)
 	require.NoError(t, err)
-	require.NotContains(t, groupOutput, groupKeyword)
-	for tableID, keyword := range keywords {
-		tableOutput, _, err := data.runCommandFromPod(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-flows", defaultBridgeName, fmt.Sprintf("table=%d", tableID)})
-		require.NoError(t, err)
-		require.NotContains(t, tableOutput, keyword)
+	require.NoError(t, data.createBusyboxPodOnNode("busybox", nodeName))
+	require.NoError(t, data.podWaitForRunning(defaultTimeout, "busybox", testNamespace))
+	agentName, err := data.getAntreaPodOnNode(nodeName)
+	require.NoError(t, err)
+	serviceName := func(idx int) string {
+		return fmt.Sprintf("stress-%03d", idx)
+	}
+
+	var createdAtMutex sync.Mutex
+	// createdAt stores the time at which each Service was created, by ClusterIP.
+	createdAt := make(map[string]time.Time, stressNumServices)
+	createErrCh := make(chan error, 1)
+	t.Logf("Creating %d Services with %d workers", stressNumServices, parallelism)
+	go func() {
+		createErrCh <- runWithWorkerPool(parallelism, stressNumServices, func(idx int) error {
+			svc, err := data.createService(serviceName(idx), 80, 80, map[string]string{"app": "nginx"}, false)
+			if err != nil {
+				return fmt.Errorf("error when creating Service %s: %v", serviceName(idx), err)
+			}
+			createdAtMutex.Lock()
+			defer createdAtMutex.Unlock()
+			createdAt[svc.Spec.ClusterIP] = time.Now()
+			return nil
+		})
+	}()
+
+	// installedAt stores the time at which the group of each Service was first observed.
+	installedAt := make(map[string]time.Time, stressNumServices)
+	groupIDs := make(map[string]string, stressNumServices)
+	pollErr := wait.PollImmediate(200*time.Millisecond, defaultTimeout, func() (bool, error) {
+		groups, err := getServiceLBGroups(data, agentName)
+		if err != nil {
+			return false, err
+		}
+		now := time.Now()
+		for clusterIP, groupID := range groups {
+			if _, ok := installedAt[clusterIP]; !ok {
+				installedAt[clusterIP] = now
+				groupIDs[clusterIP] = groupID
+			}
+		}
+		createdAtMutex.Lock()
+		defer createdAtMutex.Unlock()
+		if len(createdAt) < stressNumServices {
+			return false, nil
+		}
+		for clusterIP := range createdAt {
+			if _, ok := installedAt[clusterIP]; !ok {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	require.NoError(t, <-createErrCh)
+	require.NoError(t, pollErr, "Not all Service groups were installed in OVS")
+
+	var errs []error
+	var maxLatency time.Duration
+	for clusterIP, created := range createdAt {
+		latency := installedAt[clusterIP].Sub(created)
+		if latency > maxLatency {
+			maxLatency = latency
+		}
+		if latency > stressMaxInstallLatency {
+			errs = append(errs, fmt.Errorf("group of Service %s was installed after %v", clusterIP, latency))
+		}
+	}
+	t.Logf("All %d Service groups installed, max latency: %v", stressNumServices, maxLatency)
+	assert.NoError(t, utilerrors.NewAggregate(errs), "Service groups were not installed in %v", stressMaxInstallLatency)
+
+	clusterIPs := make([]string, 0, len(createdAt))
+	for clusterIP := range createdAt {
+		clusterIPs = append(clusterIPs, clusterIP)
 	}
+	t.Logf("Sending a request to each Service")
+	err = runWithWorkerPool(parallelism, len(clusterIPs), func(idx int) error {
+		url := net.JoinHostPort(clusterIPs[idx], "80")
+		stdout, stderr, err := data.runCommandFromPod(testNamespace, "busybox", busyboxContainerName, []string{"wget", "-O", "-", url, "-T", "1"})
+		if err != nil {
+			return fmt.Errorf("error when connecting to %s: %v - stdout: %s - stderr: %s", url, err, stdout, stderr)
+		}
+		return nil
+	})
+	assert.NoError(t, err, "Some Services were not reachable")
+
+	t.Logf("Deleting %d Services with %d workers", stressNumServices, parallelism)
+	err = runWithWorkerPool(parallelism, stressNumServices, func(idx int) error {
+		if err := data.deleteService(serviceName(idx)); err != nil {
+			return fmt.Errorf("error when deleting Service %s: %v", serviceName(idx), err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	err = wait.PollImmediate(time.Second, defaultTimeout, func() (bool, error) {
+		groupOutput, _, err := data.runCommandFromPodWithRetry(metav1.NamespaceSystem, agentName, "antrea-agent", []string{"ovs-ofctl", "dump-groups", defaultBridgeName}, ovsCommandMaxRetries, ovsCommandRetryDelay)
+		if err != nil {
+			return false, err
+		}
+		for _, groupID := range groupIDs {
+			if strings.Contains(groupOutput, fmt.Sprintf("group_id=%s,", groupID)) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	assert.NoError(t, err, "Not all Service groups were removed from OVS")
 }
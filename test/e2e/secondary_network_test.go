@@ -0,0 +1,232 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/vmware-tanzu/antrea/pkg/apis/core/v1alpha1"
+)
+
+const secondaryInterfaceName = "net1"
+
+var networkAttachmentDefinitionGVR = schema.GroupVersionResource{
+	Group:    "k8s.cni.cncf.io",
+	Version:  "v1",
+	Resource: "network-attachment-definitions",
+}
+
+// TestSecondaryNetwork verifies that Antrea provides the secondary network
+// interfaces delegated by Multus: the Pods get a secondary interface with an IP
+// of the IPPool of the network, in addition to their primary interface. The
+// Pods can reach each other on both interfaces, but not on the secondary
+// interfaces of another VLAN. The test is skipped if Multus is not deployed.
+func TestSecondaryNetwork(t *testing.T) {
+	skipIfNotIPv4Cluster(t)
+
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	if _, err := data.clientset.Discovery().ServerResourcesForGroupVersion(networkAttachmentDefinitionGVR.GroupVersion().String()); err != nil {
+		t.Skipf("Skipping test as the NetworkAttachmentDefinition API is not available: %v", err)
+	}
+	if err = data.enableSecondaryNetwork(); err != nil {
+		t.Fatalf("Error when enabling SecondaryNetwork: %v", err)
+	}
+
+	// The IPPool does not select any Namespace, so that it is only used for
+	// the secondary interfaces.
+	poolName := randName("test-secondary-ippool-")
+	_, poolCIDR, _ := net.ParseCIDR("192.168.242.0/28")
+	pool := &v1alpha1.IPPool{
+		ObjectMeta: metav1.ObjectMeta{Name: poolName},
+		Spec: v1alpha1.IPPoolSpec{
+			Ranges:            []v1alpha1.IPRange{{CIDR: poolCIDR.String()}},
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"ippool": poolName}},
+		},
+	}
+	if _, err = data.crdClient.CoreV1alpha1().IPPools().Create(context.TODO(), pool, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Error when creating IPPool: %v", err)
+	}
+	defer data.crdClient.CoreV1alpha1().IPPools().Delete(context.TODO(), poolName, metav1.DeleteOptions{})
+	if _, err = data.waitForIPPool(poolName, func(pool *v1alpha1.IPPool) bool {
+		for _, condition := range pool.Status.Conditions {
+			if condition.Type == v1alpha1.IPPoolConditionValid {
+				return condition.Status == corev1.ConditionTrue
+			}
+		}
+		return false
+	}); err != nil {
+		t.Fatalf("Error when waiting for IPPool to be validated: %v", err)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(data.kubeConfig)
+	if err != nil {
+		t.Fatalf("Error when creating dynamic client: %v", err)
+	}
+	networks := map[string]int{"vlan101": 101, "vlan102": 102}
+	for name, vlan := range networks {
+		if err := createNetworkAttachmentDefinition(dynamicClient, name, poolName, vlan); err != nil {
+			t.Fatalf("Error when creating NetworkAttachmentDefinition %s: %v", name, err)
+		}
+		defer dynamicClient.Resource(networkAttachmentDefinitionGVR).Namespace(testNamespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+	}
+
+	// The secondary networks are local to the Node, so all the Pods run on
+	// the same Node.
+	podNetworks := map[string]string{
+		randName("test-pod-vlan101-a-"): "vlan101",
+		randName("test-pod-vlan101-b-"): "vlan101",
+		randName("test-pod-vlan102-"):   "vlan102",
+	}
+	for podName, network := range podNetworks {
+		if err := data.createBusyboxPodWithNetworks(podName, nodeName(0), network); err != nil {
+			t.Fatalf("Error when creating busybox Pod: %v", err)
+		}
+		defer data.deletePodAndWait(defaultTimeout, podName)
+	}
+	primaryIPs := make(map[string]string, len(podNetworks))
+	secondaryIPs := make(map[string]string, len(podNetworks))
+	for podName := range podNetworks {
+		if primaryIPs[podName], err = data.podWaitForIP(defaultTimeout, podName, testNamespace); err != nil {
+			t.Fatalf("Error when waiting for IP of Pod %s: %v", podName, err)
+		}
+		secondaryIP, err := data.getSecondaryInterfaceIP(podName)
+		if err != nil {
+			t.Fatalf("Error when getting secondary IP of Pod %s: %v", podName, err)
+		}
+		if !poolCIDR.Contains(secondaryIP) {
+			t.Errorf("Secondary IP %s of Pod %s is not in IPPool CIDR %s", secondaryIP, podName, poolCIDR.String())
+		}
+		if poolCIDR.Contains(net.ParseIP(primaryIPs[podName])) {
+			t.Errorf("Primary IP %s of Pod %s should not be allocated from the secondary IPPool", primaryIPs[podName], podName)
+		}
+		secondaryIPs[podName] = secondaryIP.String()
+	}
+
+	for podName1, network1 := range podNetworks {
+		for podName2, network2 := range podNetworks {
+			if podName1 == podName2 {
+				continue
+			}
+			if err := data.runPingCommandFromTestPod(podName1, primaryIPs[podName2], pingCount); err != nil {
+				t.Errorf("Ping '%s' -> '%s' on the primary interface: ERROR (%v)", podName1, podName2, err)
+			}
+			err := data.runPingCommandFromTestPod(podName1, secondaryIPs[podName2], pingCount)
+			if network1 == network2 && err != nil {
+				t.Errorf("Ping '%s' -> '%s' on the secondary interface: ERROR (%v)", podName1, podName2, err)
+			} else if network1 != network2 && err == nil {
+				t.Errorf("Ping '%s' -> '%s' on the secondary interface of another VLAN should fail", podName1, podName2)
+			}
+		}
+	}
+}
+
+func createNetworkAttachmentDefinition(client dynamic.Interface, name, poolName string, vlan int) error {
+	config := fmt.Sprintf(`{"cniVersion": "0.3.0", "type": "antrea", "attachmentType": "secondary", "secondaryIPPool": %q, "vlan": %d}`, poolName, vlan)
+	nad := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": networkAttachmentDefinitionGVR.GroupVersion().String(),
+		"kind":       "NetworkAttachmentDefinition",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": testNamespace,
+		},
+		"spec": map[string]interface{}{
+			"config": config,
+		},
+	}}
+	_, err := client.Resource(networkAttachmentDefinitionGVR).Namespace(testNamespace).Create(context.TODO(), nad, metav1.CreateOptions{})
+	return err
+}
+
+// createBusyboxPodWithNetworks creates a Pod in the test namespace with a single
+// busybox container, which requests the secondary networks with the Multus
+// annotation.
+func (data *TestData) createBusyboxPodWithNetworks(name, nodeName, networks string) error {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				"antrea-e2e": name,
+				"app":        busyboxContainerName,
+			},
+			Annotations: map[string]string{
+				"k8s.v1.cni.cncf.io/networks": networks,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:            busyboxContainerName,
+					Image:           "busybox",
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Command:         []string{"sleep", strconv.Itoa(3600)},
+				},
+			},
+			RestartPolicy: corev1.RestartPolicyNever,
+			NodeSelector:  map[string]string{"kubernetes.io/hostname": nodeName},
+		},
+	}
+	_, err := data.clientset.CoreV1().Pods(testNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+	return err
+}
+
+// getSecondaryInterfaceIP returns the IPv4 address of the secondary interface
+// of the Pod.
+func (data *TestData) getSecondaryInterfaceIP(podName string) (net.IP, error) {
+	stdout, stderr, err := data.runCommandFromPod(testNamespace, podName, busyboxContainerName, []string{"ip", "addr", "show", secondaryInterfaceName})
+	if err != nil {
+		return nil, fmt.Errorf("error when running 'ip addr show %s': %v - stderr: %s", secondaryInterfaceName, err, stderr)
+	}
+	ipNets, err := parseIPAddrOutput(stdout, secondaryInterfaceName)
+	if err != nil {
+		return nil, err
+	}
+	for _, ipNet := range ipNets {
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("no IPv4 address on interface %s", secondaryInterfaceName)
+}
+
+// enableSecondaryNetwork enables the AntreaIPAM feature, which is required by
+// the SecondaryNetwork feature enabled in the Agent configuration, and restarts
+// the Antrea Pods.
+func (data *TestData) enableSecondaryNetwork() error {
+	configMap, err := data.GetAntreaConfigMap(antreaNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to get ConfigMap: %v", err)
+	}
+	configMap.Data["antrea-agent.conf"] = strings.Replace(configMap.Data["antrea-agent.conf"], "#  SecondaryNetwork: false", "  SecondaryNetwork: true", 1)
+	if _, err := data.clientset.CoreV1().ConfigMaps(antreaNamespace).Update(context.TODO(), configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s: %v", configMap.Name, err)
+	}
+	return data.enableAntreaIPAM()
+}
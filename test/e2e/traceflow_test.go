@@ -228,6 +228,256 @@ func TestTraceflow(t *testing.T) {
 	}
 }
 
+// TestTraceflowLive verifies that live traceflow traces the packets of the real traffic, and that the number of
+// live traceflows running at the same time is limited.
+func TestTraceflowLive(t *testing.T) {
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	if err = data.enableTraceflow(t); err != nil {
+		t.Fatal("Error when enabling Traceflow")
+	}
+
+	node1 := workerNodeName(1)
+	node1Pods, node1IPs, node1CleanupFn := createTestBusyboxPods(t, data, 2, node1)
+	defer node1CleanupFn()
+
+	deleteTraceflow := func(name string) {
+		if err := data.crdClient.OpsV1alpha1().Traceflows().Delete(context.TODO(), name, v1.DeleteOptions{}); err != nil {
+			t.Errorf("Error when deleting traceflow: %v", err)
+		}
+	}
+
+	t.Run("testTraceflowLiveICMP", func(t *testing.T) {
+		packetCount := int32(2)
+		tf := newTraceflow(node1Pods[0], node1Pods[1])
+		tf.Spec.Packet = v1alpha1.Packet{IPHeader: v1alpha1.IPHeader{Protocol: 1}}
+		tf.Spec.Live = true
+		tf.Spec.PacketCount = packetCount
+		tf.Spec.Timeout = 30
+		if tf, err = data.crdClient.OpsV1alpha1().Traceflows().Create(context.TODO(), tf, v1.CreateOptions{}); err != nil {
+			t.Fatalf("Error when creating traceflow: %v", err)
+		}
+		defer deleteTraceflow(tf.Name)
+
+		// Packets sent before the capture flow is installed are not traced, hence the ping runs until the
+		// traceflow completes.
+		stopCh := make(chan struct{})
+		defer close(stopCh)
+		go func() {
+			for {
+				select {
+				case <-stopCh:
+					return
+				default:
+					data.runPingCommandFromTestPod(node1Pods[0], node1IPs[1], 2)
+				}
+			}
+		}()
+
+		if err = wait.Poll(1*time.Second, traceflowTimeout, func() (bool, error) {
+			if tf, err = data.crdClient.OpsV1alpha1().Traceflows().Get(context.TODO(), tf.Name, v1.GetOptions{}); err != nil {
+				return false, nil
+			}
+			return tf.Status.Phase == v1alpha1.Succeeded || tf.Status.Phase == v1alpha1.Failed, nil
+		}); err != nil {
+			t.Fatalf("Error when waiting for live traceflow to complete: %v", err)
+		}
+		if tf.Status.Phase != v1alpha1.Succeeded {
+			t.Fatalf("Live traceflow should succeed, but got phase %s with reason: %s", tf.Status.Phase, tf.Status.Reason)
+		}
+		expectedResult := v1alpha1.NodeResult{
+			Node: node1,
+			Observations: []v1alpha1.Observation{
+				{
+					Component: v1alpha1.SpoofGuard,
+					Action:    v1alpha1.Forwarded,
+				},
+				{
+					Component:     v1alpha1.Forwarding,
+					ComponentInfo: "Output",
+					Action:        v1alpha1.Delivered,
+				},
+			},
+		}
+		if len(tf.Status.Results) < int(packetCount) {
+			t.Fatalf("Live traceflow should capture %d packets, but got results %v", packetCount, tf.Status.Results)
+		}
+		for _, result := range tf.Status.Results {
+			if err = compareObservations(expectedResult, result, t); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	t.Run("testTraceflowLiveLimit", func(t *testing.T) {
+		skipIfProviderIs(t, "kind", "Inter nodes test needs Geneve tunnel")
+		// The live traceflows capture the traffic to an IP without any traffic, so that they keep running until
+		// the timeout.
+		newLiveTraceflow := func() *v1alpha1.Traceflow {
+			tf := newTraceflow(node1Pods[0], "")
+			tf.Spec.Destination = v1alpha1.Destination{IP: "192.0.2.1"}
+			tf.Spec.Live = true
+			tf.Spec.Timeout = 60
+			return tf
+		}
+		for i := 0; i < 10; i++ {
+			tf, err := data.crdClient.OpsV1alpha1().Traceflows().Create(context.TODO(), newLiveTraceflow(), v1.CreateOptions{})
+			if err != nil {
+				t.Fatalf("Error when creating traceflow: %v", err)
+			}
+			defer deleteTraceflow(tf.Name)
+			if err = wait.Poll(1*time.Second, traceflowTimeout, func() (bool, error) {
+				if tf, err = data.crdClient.OpsV1alpha1().Traceflows().Get(context.TODO(), tf.Name, v1.GetOptions{}); err != nil {
+					return false, nil
+				}
+				return tf.Status.Phase == v1alpha1.Running, nil
+			}); err != nil {
+				t.Fatalf("Error when waiting for live traceflow to run: %v", err)
+			}
+		}
+
+		tf, err := data.crdClient.OpsV1alpha1().Traceflows().Create(context.TODO(), newLiveTraceflow(), v1.CreateOptions{})
+		if err != nil {
+			t.Fatalf("Error when creating traceflow: %v", err)
+		}
+		defer deleteTraceflow(tf.Name)
+		if err = wait.Poll(1*time.Second, traceflowTimeout, func() (bool, error) {
+			if tf, err = data.crdClient.OpsV1alpha1().Traceflows().Get(context.TODO(), tf.Name, v1.GetOptions{}); err != nil {
+				return false, nil
+			}
+			return tf.Status.Phase == v1alpha1.Failed, nil
+		}); err != nil {
+			t.Fatalf("Live traceflow exceeding the limit should fail: %v", err)
+		}
+	})
+}
+
+// TestTraceflowService verifies that traceflow can trace the traffic to a Service ClusterIP through AntreaProxy, which
+// selects an Endpoint of the Service, or drops the traffic if the Service has no Endpoints.
+func TestTraceflowService(t *testing.T) {
+	skipIfProviderIs(t, "kind", "Service traceflow needs Geneve tunnel")
+	skipIfNotIPv4Cluster(t)
+
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	skipIfProxyDisabled(t, data)
+	if err = data.enableTraceflow(t); err != nil {
+		t.Fatal("Error when enabling Traceflow")
+	}
+
+	node1 := workerNodeName(1)
+	node1Pods, _, node1CleanupFn := createTestBusyboxPods(t, data, 1, node1)
+	defer node1CleanupFn()
+	if err = data.createNginxPod("nginx", node1); err != nil {
+		t.Fatalf("Error when creating nginx Pod: %v", err)
+	}
+	defer data.deletePodAndWait(defaultTimeout, "nginx")
+	nginxIP, err := data.podWaitForIP(defaultTimeout, "nginx", testNamespace)
+	if err != nil {
+		t.Fatalf("Error when waiting for nginx Pod IP: %v", err)
+	}
+	if _, err = data.createNginxService(false); err != nil {
+		t.Fatalf("Error when creating nginx Service: %v", err)
+	}
+	defer data.deleteService("nginx")
+	if _, err = data.createService("no-endpoints", 80, 80, map[string]string{"app": "no-endpoints"}, false); err != nil {
+		t.Fatalf("Error when creating Service without Endpoints: %v", err)
+	}
+	defer data.deleteService("no-endpoints")
+
+	newServiceTraceflow := func(service string) *v1alpha1.Traceflow {
+		tf := newTraceflow(node1Pods[0], "")
+		tf.Spec.Destination = v1alpha1.Destination{Namespace: testNamespace, Service: service}
+		return tf
+	}
+	testcases := []testcase{
+		{
+			tf:            newServiceTraceflow("nginx"),
+			expectedPhase: v1alpha1.Succeeded,
+			expectedResults: []v1alpha1.NodeResult{
+				{
+					Node: node1,
+					Observations: []v1alpha1.Observation{
+						{
+							Component: v1alpha1.SpoofGuard,
+							Action:    v1alpha1.Forwarded,
+						},
+						{
+							Component:       v1alpha1.LB,
+							ComponentInfo:   "EndpointDNAT",
+							Action:          v1alpha1.Forwarded,
+							TranslatedDstIP: nginxIP,
+						},
+						{
+							Component:     v1alpha1.Forwarding,
+							ComponentInfo: "Output",
+							Action:        v1alpha1.Delivered,
+						},
+					},
+				},
+			},
+		},
+		{
+			tf:            newServiceTraceflow("no-endpoints"),
+			expectedPhase: v1alpha1.Succeeded,
+			expectedResults: []v1alpha1.NodeResult{
+				{
+					Node: node1,
+					Observations: []v1alpha1.Observation{
+						{
+							Component: v1alpha1.SpoofGuard,
+							Action:    v1alpha1.Forwarded,
+						},
+						{
+							Component:     v1alpha1.LB,
+							ComponentInfo: "ServiceLB",
+							Action:        v1alpha1.Dropped,
+							Reason:        v1alpha1.ReasonNoEndpoints,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tf, err := data.crdClient.OpsV1alpha1().Traceflows().Create(context.TODO(), tc.tf, v1.CreateOptions{})
+		if err != nil {
+			t.Fatalf("Error when creating traceflow: %v", err)
+		}
+		defer func() {
+			if err := data.crdClient.OpsV1alpha1().Traceflows().Delete(context.TODO(), tf.Name, v1.DeleteOptions{}); err != nil {
+				t.Errorf("Error when deleting traceflow: %v", err)
+			}
+		}()
+		if err = wait.Poll(1*time.Second, traceflowTimeout, func() (bool, error) {
+			if tf, err = data.crdClient.OpsV1alpha1().Traceflows().Get(context.TODO(), tf.Name, v1.GetOptions{}); err != nil {
+				return false, nil
+			}
+			return tf.Status.Phase == tc.expectedPhase && len(tf.Status.Results) == len(tc.expectedResults), nil
+		}); err != nil {
+			t.Fatalf("Error when waiting for traceflow %s: %v, status: %+v", tf.Name, err, tf.Status)
+		}
+		if err = compareObservations(tc.expectedResults[0], tf.Status.Results[0], t); err != nil {
+			t.Error(err)
+		}
+		for i, ob := range tc.expectedResults[0].Observations {
+			actual := tf.Status.Results[0].Observations[i]
+			if ob.TranslatedDstIP != actual.TranslatedDstIP || ob.Reason != actual.Reason {
+				t.Errorf("Observation %d should be %+v, but got %+v", i, ob, actual)
+			}
+		}
+	}
+}
+
 func (data *TestData) enableTraceflow(t *testing.T) error {
 	configMap, err := data.GetAntreaConfigMap(antreaNamespace)
 	if err != nil {
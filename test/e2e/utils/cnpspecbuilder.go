@@ -199,6 +199,24 @@ func (b *ClusterNetworkPolicySpecBuilder) AddEgress(protoc v1.Protocol,
 	return b
 }
 
+// AddEgressFQDN adds an egress rule matching the IPs the FQDN resolves to.
+func (b *ClusterNetworkPolicySpecBuilder) AddEgressFQDN(protoc v1.Protocol, port *int, fqdn string,
+	action secv1alpha1.RuleAction) *ClusterNetworkPolicySpecBuilder {
+	var ports []secv1alpha1.NetworkPolicyPort
+	if port != nil {
+		ports = append(ports, secv1alpha1.NetworkPolicyPort{
+			Protocol: &protoc,
+			Port:     &intstr.IntOrString{IntVal: int32(*port)},
+		})
+	}
+	b.Spec.Egress = append(b.Spec.Egress, secv1alpha1.Rule{
+		To:     []secv1alpha1.NetworkPolicyPeer{{FQDN: fqdn}},
+		Ports:  ports,
+		Action: &action,
+	})
+	return b
+}
+
 // AddEgressDNS mutates the nth policy rule to allow DNS, convenience method
 func (b *ClusterNetworkPolicySpecBuilder) WithEgressDNS() *ClusterNetworkPolicySpecBuilder {
 	protocolUDP := v1.ProtocolUDP
@@ -0,0 +1,57 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"net"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// transientExecErrors are the substrings of the errors returned by the exec API which denote an
+// infrastructure failure, as opposed to a failure of the command itself.
+var transientExecErrors = []string{
+	"connection refused",
+	"connection reset by peer",
+	"TLS handshake timeout",
+	"i/o timeout",
+	"unexpected EOF",
+}
+
+// IsTransientExecError returns true if the error returned by the exec API was caused by the
+// infrastructure (apiserver, kubelet, network) rather than by the command exiting with a non-zero
+// code, in which case running the command again may succeed.
+func IsTransientExecError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := err.(utilexec.ExitError); ok {
+		return false
+	}
+	if errors.IsTooManyRequests(err) || errors.IsServerTimeout(err) || errors.IsTimeout(err) {
+		return true
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return true
+	}
+	for _, msg := range transientExecErrors {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+func TestIsTransientExecError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{"no error", nil, false},
+		{"non-zero exit code", utilexec.CodeExitError{Err: fmt.Errorf("command terminated with exit code 1"), Code: 1}, false},
+		{"connection refused", fmt.Errorf("error dialing backend: dial tcp 172.18.0.2:10250: connect: connection refused"), true},
+		{"TLS handshake timeout", fmt.Errorf("error sending request: Post https://172.18.0.2:6443: net/http: TLS handshake timeout"), true},
+		{"throttled", errors.NewTooManyRequests("too many requests", 1), true},
+		{"forbidden", errors.NewForbidden(v1.Resource("pods"), "busybox", fmt.Errorf("denied")), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.transient, IsTransientExecError(tt.err))
+		})
+	}
+}
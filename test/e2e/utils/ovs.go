@@ -0,0 +1,27 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"regexp"
+)
+
+// OVSFlowsContainKeyword returns true if the output of "ovs-ofctl dump-flows" contains the provided
+// keyword as a whole value, e.g. the IP address "10.96.0.1" does not match "10.96.0.10".
+func OVSFlowsContainKeyword(flows, keyword string) bool {
+	const valueChars = `0-9a-fA-F.:`
+	re := regexp.MustCompile(`(^|[^` + valueChars + `])` + regexp.QuoteMeta(keyword) + `($|[^` + valueChars + `])`)
+	return re.MatchString(flows)
+}
@@ -0,0 +1,29 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOVSFlowsContainKeyword(t *testing.T) {
+	flows := ` cookie=0x1, table=41, priority=200,tcp,reg4=0x10000/0x70000,nw_dst=10.96.0.10,tp_dst=80 actions=group:1`
+	assert.True(t, OVSFlowsContainKeyword(flows, "10.96.0.10"))
+	assert.False(t, OVSFlowsContainKeyword(flows, "10.96.0.1"))
+	assert.False(t, OVSFlowsContainKeyword(flows, "0.96.0.10"))
+	assert.True(t, OVSFlowsContainKeyword("nw_dst=10.96.0.1", "10.96.0.1"))
+}
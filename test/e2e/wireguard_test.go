@@ -0,0 +1,91 @@
+// Copyright 2020 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/vmware-tanzu/antrea/pkg/agent/wireguard"
+)
+
+// TestWireGuardKeyRotation checks that rotating the WireGuard key pairs of the
+// Nodes does not disrupt the Pod traffic across Nodes, by having a Pod
+// continuously ping a Pod on another Node while the key pairs are rotated.
+func TestWireGuardKeyRotation(t *testing.T) {
+	skipIfNumNodesLessThan(t, 2)
+
+	data, err := setupTest(t)
+	if err != nil {
+		t.Fatalf("Error when setting up test: %v", err)
+	}
+	defer teardownTest(t, data)
+
+	configMap, err := data.GetAntreaConfigMap(antreaNamespace)
+	if err != nil {
+		t.Fatalf("Error when getting the Antrea ConfigMap: %v", err)
+	}
+	if !strings.Contains(configMap.Data["antrea-agent.conf"], "\ntrafficEncryptionMode: wireGuard") {
+		t.Skipf("Skipping test as WireGuard encryption is not enabled")
+	}
+
+	// The next public key is published 15s after the antrea-agent starts and
+	// the switch happens 30s later, so the key pairs are rotated about every
+	// 45s.
+	defaultIntervalLine := "#wireGuardKeyRotationInterval: 24h"
+	shortIntervalLine := "wireGuardKeyRotationInterval: 15s"
+	if err := data.replaceAntreaAgentConfLine(defaultIntervalLine, shortIntervalLine); err != nil {
+		t.Fatalf("Error when setting wireGuardKeyRotationInterval: %v", err)
+	}
+	defer func() {
+		if err := data.replaceAntreaAgentConfLine(shortIntervalLine, defaultIntervalLine); err != nil {
+			t.Errorf("Error when restoring wireGuardKeyRotationInterval: %v", err)
+		}
+	}()
+
+	podNames, deletePods := createPodsOnDifferentNodes(t, data, 2)
+	defer deletePods()
+	podIPs := make([]string, len(podNames))
+	for i, podName := range podNames {
+		if podIPs[i], err = data.podWaitForIP(defaultTimeout, podName, testNamespace); err != nil {
+			t.Fatalf("Error when waiting for IP of Pod '%s': %v", podName, err)
+		}
+	}
+	getPublicKey := func() string {
+		node, err := data.clientset.CoreV1().Nodes().Get(context.TODO(), nodeName(1), metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Error when getting Node '%s': %v", nodeName(1), err)
+		}
+		return node.Annotations[wireguard.PublicKeyAnnotationKey]
+	}
+	initialPublicKey := getPublicKey()
+
+	pingCount := 90
+	cmd := []string{"ping", "-c", fmt.Sprint(pingCount), podIPs[1]}
+	stdout, stderr, err := data.runCommandFromPod(testNamespace, podNames[0], busyboxContainerName, cmd)
+	if err != nil {
+		t.Fatalf("Error when running ping: %v, stdout: %s, stderr: %s", err, stdout, stderr)
+	}
+	if getPublicKey() == initialPublicKey {
+		t.Fatalf("WireGuard key pair of Node '%s' was not rotated", nodeName(1))
+	}
+	if !strings.Contains(stdout, fmt.Sprintf("%d packets transmitted, %d packets received", pingCount, pingCount)) {
+		t.Errorf("Pod traffic was disrupted by the WireGuard key rotation: %s", stdout)
+	}
+}
@@ -544,6 +544,8 @@ func newTester() *cmdAddDelTester {
 		k8sFake.NewSimpleClientset(),
 		make(chan v1beta1.PodReference, 100),
 		false,
+		nil,
+		nil,
 		nil)
 	tester.server.Initialize(ovsServiceMock, ofServiceMock, ifaceStore, "")
 	ctx, _ := context.WithCancel(context.Background())
@@ -684,7 +686,9 @@ func setupChainTest(
 			k8sFake.NewSimpleClientset(),
 			make(chan v1beta1.PodReference, 100),
 			true,
-			routeMock)
+			routeMock,
+			nil,
+			nil)
 	} else {
 		server = inServer
 	}
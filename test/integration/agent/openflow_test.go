@@ -38,7 +38,7 @@ import (
 var (
 	br             = "br01"
 	c              ofClient.Client
-	roundInfo      = types.RoundInfo{0, nil}
+	roundInfo      = types.RoundInfo{RoundNum: 0}
 	ovsCtlClient   = ovsctl.NewClient(br)
 	bridgeMgmtAddr = ofconfig.GetMgmtAddress(ovsconfig.DefaultOVSRunDir, br)
 )
@@ -579,7 +579,7 @@ func preparePodFlows(podIP net.IP, podMAC net.HardwareAddr, podOFPort uint32, gw
 			[]*ofTestUtils.ExpectFlow{
 				{
 					fmt.Sprintf("priority=200,ip,reg0=0x80000/0x80000,nw_dst=%s", podIP.String()),
-					fmt.Sprintf("set_field:%s->eth_src,set_field:%s->eth_dst,dec_ttl,goto_table:80", gwMAC.String(), podMAC.String())},
+					fmt.Sprintf("set_field:%s->eth_src,set_field:%s->eth_dst,dec_ttl,goto_table:71", gwMAC.String(), podMAC.String())},
 			},
 		},
 		{
@@ -621,7 +621,7 @@ func prepareGatewayFlows(gwIP net.IP, gwMAC net.HardwareAddr, gwOFPort uint32, v
 			[]*ofTestUtils.ExpectFlow{
 				{
 					fmt.Sprintf("priority=200,ip,dl_dst=%s,nw_dst=%s", vMAC.String(), gwIP.String()),
-					fmt.Sprintf("set_field:%s->eth_dst,goto_table:80", gwMAC.String())},
+					fmt.Sprintf("set_field:%s->eth_dst,goto_table:71", gwMAC.String())},
 			},
 		},
 		{
@@ -708,6 +708,7 @@ func prepareDefaultFlows() []expectTableFlows {
 			uint8(30),
 			[]*ofTestUtils.ExpectFlow{
 				{"priority=200,ip", "ct(table=31,zone=65520,nat)"},
+				{"priority=200,ipv6", "ct(table=31,zone=65520,nat)"},
 			},
 		},
 		{
@@ -715,7 +716,13 @@ func prepareDefaultFlows() []expectTableFlows {
 			[]*ofTestUtils.ExpectFlow{
 				{"priority=210,ct_state=-new+trk,ct_mark=0x20,ip,reg0=0x1/0xffff", "goto_table:42"},
 				{"priority=190,ct_state=+inv+trk,ip", "drop"},
-				{"priority=0", "resubmit(,40),resubmit(,41)"},
+				{"priority=80,tcp", "resubmit(,40),resubmit(,41)"},
+				{"priority=80,udp", "resubmit(,40),resubmit(,41)"},
+				{"priority=80,sctp", "resubmit(,40),resubmit(,41)"},
+				{"priority=80,tcp6", "resubmit(,40),resubmit(,41)"},
+				{"priority=80,udp6", "resubmit(,40),resubmit(,41)"},
+				{"priority=80,sctp6", "resubmit(,40),resubmit(,41)"},
+				{"priority=0", "goto_table:42"},
 			},
 		},
 		{
@@ -736,6 +743,10 @@ func prepareDefaultFlows() []expectTableFlows {
 		},
 		{
 			uint8(70),
+			[]*ofTestUtils.ExpectFlow{{"priority=0", "goto_table:71"}},
+		},
+		{
+			uint8(71),
 			[]*ofTestUtils.ExpectFlow{{"priority=0", "goto_table:80"}},
 		},
 		{
@@ -757,8 +768,8 @@ func prepareDefaultFlows() []expectTableFlows {
 		{
 			uint8(105),
 			[]*ofTestUtils.ExpectFlow{
-				{"priority=200,ct_state=+new+trk,ip,reg0=0x1/0xffff", "ct(commit,table=106,zone=65520,exec(load:0x20->NXM_NX_CT_MARK[])"},
-				{"priority=190,ct_state=+new+trk,ip", "ct(commit,table=106,zone=65520)"},
+				{"priority=200,ct_state=+new+trk,ip,reg0=0x1/0xffff", "ct(commit,table=106,zone=65520,exec(load:0x20->NXM_NX_CT_MARK[],move:NXM_NX_REG6[]->NXM_NX_CT_LABEL[0..31],move:NXM_NX_REG5[]->NXM_NX_CT_LABEL[32..63])"},
+				{"priority=190,ct_state=+new+trk,ip", "ct(commit,table=106,zone=65520,exec(move:NXM_NX_REG6[]->NXM_NX_CT_LABEL[0..31],move:NXM_NX_REG5[]->NXM_NX_CT_LABEL[32..63]))"},
 				{"priority=0", "goto_table:106"}},
 		},
 		{
@@ -877,6 +877,17 @@ func prepareFlows(table binding.Table) ([]binding.Flow, []*ExpectFlow) {
 			Done(). // Finish learn action.
 			Action().ResubmitToTable(table.GetID()).
 			Done(),
+		table.BuildFlow(priorityNormal).MatchProtocol(binding.ProtocolSCTP).
+			Cookie(getCookieID()).
+			Action().Learn(table.GetID(), priorityNormal-10, 10, 0, 1).
+			DeleteLearned().
+			MatchLearnedSCTPDstPort().
+			MatchReg(0, 0x0fff, binding.Range{0, 15}).
+			LoadRegToReg(0, 0, binding.Range{0, 15}, binding.Range{0, 15}).
+			LoadReg(0, 0x0ffe, binding.Range{16, 31}).
+			Done(). // Finish learn action.
+			Action().ResubmitToTable(table.GetID()).
+			Done(),
 		table.BuildFlow(priorityNormal).MatchProtocol(binding.ProtocolIP).
 			Cookie(getCookieID()).
 			Action().CT(false, table.GetNext(), ctZone).CTDone().
@@ -981,6 +992,7 @@ func prepareFlows(table binding.Table) ([]binding.Flow, []*ExpectFlow) {
 		&ExpectFlow{"priority=200,arp,arp_tpa=192.168.2.1,arp_op=1", "move:NXM_OF_ETH_SRC[]->NXM_OF_ETH_DST[],set_field:aa:bb:cc:dd:ee:ff->eth_src,load:0x2->NXM_OF_ARP_OP[],move:NXM_NX_ARP_SHA[]->NXM_NX_ARP_THA[],set_field:aa:bb:cc:dd:ee:ff->arp_sha,move:NXM_OF_ARP_SPA[]->NXM_OF_ARP_TPA[],set_field:192.168.2.1->arp_spa,IN_PORT"},
 		&ExpectFlow{"priority=190,arp", "NORMAL"},
 		&ExpectFlow{"priority=200,tcp", fmt.Sprintf("learn(table=%d,idle_timeout=10,priority=190,delete_learned,cookie=0x1,eth_type=0x800,nw_proto=6,NXM_OF_TCP_DST[],NXM_NX_REG0[0..15]=0xfff,load:NXM_NX_REG0[0..15]->NXM_NX_REG0[0..15],load:0xffe->NXM_NX_REG0[16..31]),resubmit(,%d)", table.GetID(), table.GetID())},
+		&ExpectFlow{"priority=200,sctp", fmt.Sprintf("learn(table=%d,idle_timeout=10,priority=190,delete_learned,cookie=0x1,eth_type=0x800,nw_proto=132,OXM_OF_SCTP_DST[],NXM_NX_REG0[0..15]=0xfff,load:NXM_NX_REG0[0..15]->NXM_NX_REG0[0..15],load:0xffe->NXM_NX_REG0[16..31]),resubmit(,%d)", table.GetID(), table.GetID())},
 		&ExpectFlow{"priority=200,ip", fmt.Sprintf("ct(table=%d,zone=65520)", table.GetNext())},
 		&ExpectFlow{"priority=210,ct_state=-new+trk,ct_mark=0x20,ip,reg0=0x1/0xffff", gotoTableAction},
 		&ExpectFlow{"priority=200,ct_state=+new+trk,ip,reg0=0x1/0xffff", fmt.Sprintf("ct(commit,table=%d,zone=65520,exec(load:0x20->NXM_NX_CT_MARK[])", table.GetNext())},
@@ -1078,6 +1090,77 @@ func prepareNATflows(table binding.Table) ([]binding.Flow, []*ExpectFlow) {
 	return flows, flowStrs
 }
 
+// BenchmarkFlowInstaller compares the time to install 10000 flows with a bundle
+// per flow, as when the cached flows were replayed one cache entry at a time,
+// with the time to install them with a FlowInstaller, and reports the speedup.
+func BenchmarkFlowInstaller(b *testing.B) {
+	br := "br12"
+	err := PrepareOVSBridge(br)
+	if err != nil {
+		b.Fatalf("Failed to prepare OVS bridge: %v", err)
+	}
+	defer func() {
+		err = DeleteOVSBridge(br)
+		if err != nil {
+			b.Errorf("error while deleting OVS bridge: %v", err)
+		}
+	}()
+
+	bridge := newOFBridge(br)
+	tables := []binding.Table{
+		bridge.CreateTable(1, 2, binding.TableMissActionNext),
+		bridge.CreateTable(2, 3, binding.TableMissActionNext),
+	}
+	err = bridge.Connect(maxRetry, make(chan struct{}))
+	if err != nil {
+		b.Fatal("Failed to start OFService")
+	}
+	defer bridge.Disconnect()
+
+	const flowCount = 10000
+	cookieID, cookieMask := getCookieIDMask()
+	flows := make([]binding.Flow, 0, flowCount)
+	for i := 0; i < flowCount; i++ {
+		table := tables[i%len(tables)]
+		flows = append(flows, table.BuildFlow(priorityNormal).MatchProtocol(binding.ProtocolIP).
+			Cookie(getCookieID()).
+			MatchSrcIP(net.IP{10, byte(i >> 16), byte(i >> 8), byte(i)}).
+			Action().GotoTable(table.GetNext()).
+			Done())
+	}
+	installer := binding.NewFlowInstaller(bridge, binding.DefaultFlowInstallBatchSize, binding.DefaultFlowInstallWorkers)
+	deleteFlows := func() {
+		b.StopTimer()
+		defer b.StartTimer()
+		if err := bridge.DeleteFlowsByCookie(cookieID, cookieMask); err != nil {
+			b.Fatalf("Failed to delete flows: %v", err)
+		}
+	}
+
+	var sequential, concurrent time.Duration
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		start := time.Now()
+		for _, flow := range flows {
+			if err := bridge.AddFlowsInBundle([]binding.Flow{flow}, nil, nil); err != nil {
+				b.Fatalf("Failed to install flow: %v", err)
+			}
+		}
+		sequential += time.Since(start)
+		deleteFlows()
+
+		start = time.Now()
+		if err := installer.Install(flows); err != nil {
+			b.Fatalf("Failed to install flows: %v", err)
+		}
+		concurrent += time.Since(start)
+		deleteFlows()
+	}
+	b.ReportMetric(float64(sequential.Milliseconds())/float64(b.N), "sequential-ms/op")
+	b.ReportMetric(float64(concurrent.Milliseconds())/float64(b.N), "concurrent-ms/op")
+	b.ReportMetric(float64(sequential)/float64(concurrent), "speedup")
+}
+
 func getCookieID() uint64 {
 	roundID := uint64(100) << 48
 	cateID := uint64(2) << 40